@@ -189,8 +189,13 @@ func initLoggerFromConfig(cfg *config.Config, verbose bool) (*utils.DefaultLogge
 	if verbose {
 		logLevel = utils.LevelDebug
 	}
+	logFormat, err := utils.ParseLogFormat(cfg.Log.Format)
+	if err != nil {
+		return nil, err
+	}
 
 	// If output_path is configured, write to file
+	logger := utils.NewDefaultLogger(logLevel, os.Stdout)
 	if cfg.Log.OutputPath != "" && cfg.Log.OutputPath != "stdout" {
 		// Create log directory
 		if err := os.MkdirAll(cfg.Log.OutputPath, 0755); err != nil {
@@ -199,11 +204,13 @@ func initLoggerFromConfig(cfg *config.Config, verbose bool) (*utils.DefaultLogge
 
 		// Create log file with date suffix
 		logFile := filepath.Join(cfg.Log.OutputPath, "perf-analyzer.log")
-		return utils.NewFileLogger(logLevel, logFile)
+		if logger, err = utils.NewFileLogger(logLevel, logFile); err != nil {
+			return nil, err
+		}
 	}
 
-	// Default to stdout
-	return utils.NewDefaultLogger(logLevel, os.Stdout), nil
+	logger.SetFormat(logFormat)
+	return logger, nil
 }
 
 func main() {
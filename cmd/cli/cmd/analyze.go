@@ -13,7 +13,13 @@ import (
 
 	"github.com/perf-analysis/internal/analyzer"
 	"github.com/perf-analysis/internal/formatter"
+	"github.com/perf-analysis/internal/parser/hprof"
+	"github.com/perf-analysis/internal/rules"
+	"github.com/perf-analysis/internal/symbol"
+	"github.com/perf-analysis/internal/webui"
 	"github.com/perf-analysis/pkg/model"
+	"github.com/perf-analysis/pkg/utils"
+	"github.com/perf-analysis/pkg/writer"
 )
 
 var (
@@ -26,14 +32,34 @@ var (
 	topN            int
 	serveAfter      bool
 	servePort       int
+	rollupBiggest   bool
+	jvmLayout       string
+	g1RegionSize    string
+	ownershipMap    string
+	tableFormat     string
+	sqliteExport    bool
+	parquetExport   bool
+	pluginDir       string
+	sampleInterval  time.Duration
+	totalCounts     bool
+	mergeProfiles   bool
+	ruleFiles       []string
+
+	// Symbol source flags, for perf script frames perf could not symbolize
+	symbolVmlinux  string
+	symbolDirs     []string
+	debuginfodURLs []string
+	symbolBuildIDs string
+	symbolCacheDir string
 )
 
 // analyzeCmd represents the analyze command
 var analyzeCmd = &cobra.Command{
-	Use:   "analyze",
-	Short: "Analyze profiling data from input file",
-	Long:  buildAnalyzeLongHelp(),
-	RunE:  runAnalyze,
+	Use:     "analyze",
+	GroupID: groupAnalysis,
+	Short:   "Analyze profiling data from input file",
+	Long:    buildAnalyzeLongHelp(),
+	RunE:    runAnalyze,
 }
 
 func init() {
@@ -47,42 +73,159 @@ func init() {
   # Analyze Java memory allocation data
   %s analyze -i ./alloc.data -m java-alloc
 
+  # Analyze async-profiler wall-clock data sampled every 20ms
+  %s analyze -i ./wall.collapsed -m java-wall --interval 20ms
+
+  # Analyze async-profiler lock data collected with --total
+  %s analyze -i ./lock.collapsed -m java-lock --total
+
+  # Import a profile saved by speedscope, or exported for it
+  %s analyze -i ./profile.speedscope.json -m cpu
+
+  # Name the kernel and stripped frames of perf script output (perf script -F+dsoff),
+  # downloading symbols of the build IDs listed by perf buildid-list
+  %s analyze -i ./perf.script -m cpu --vmlinux /boot/System.map --build-ids ./buildids.txt --debuginfod https://debuginfod.elfutils.org
+
+  # Analyze off-CPU time recorded with bcc (offcputime -f -p <pid> 30 > offcpu.folded)
+  %s analyze -i ./offcpu.folded -m offcpu
+
+  # Analyze the inuse growth between consecutive Go heap profiles
+  %s analyze -i ./heap-1.pb.gz,./heap-2.pb.gz,./heap-3.pb.gz -m pprof-heap
+
+  # Merge the Go heap profiles of several instances of a service
+  %s analyze -i ./heap-a.pb.gz,./heap-b.pb.gz -m pprof-heap --merge
+
+  # Analyze a Java Flight Recorder recording (CPU, allocation and lock profiles)
+  %s analyze -i ./recording.jfr -m java-jfr
+
   # Analyze Java heap dump
   %s analyze -i ./heap.hprof -m java-heap
 
+  # Analyze thread dumps taken with jstack, for thread states, deadlocks and hot monitors
+  %s analyze -i ./threads.txt -m java-threaddump
+
+  # Analyze a GC log, placing a heap dump of the same JVM in it
+  %s analyze -i ./gc.log,./heap.hprof -m java-gclog
+
   # Use detailed analysis profile for deep investigation
   %s analyze -i ./data.collapsed -m java-cpu --profile detailed
 
   # Quick analysis for fast results
   %s analyze -i ./data.collapsed -m java-cpu --profile quick
 
+  # Analyze Java heap dump and export it to SQLite for ad-hoc queries
+  %s analyze -i ./heap.hprof -m java-heap --sqlite
+
   # Analyze and start web server to view results
   %s analyze -i ./test/origin.data -m java-cpu --serve --port 8080
 
+  # Fail a CI job (exit code 2) when a class retains more than 500MB
+  %s analyze -i ./heap.hprof -m java-heap --fail-if-class-retained 'com.example.Cache>500MB'
+
   # Specify custom output directory and task UUID
   %s analyze -i ./data.txt -m cpu -o ./results --uuid my-analysis-001`,
-		binName, binName, binName, binName, binName, binName, binName)
+		binName, binName, binName, binName, binName, binName, binName, binName, binName, binName, binName, binName, binName,
+		binName, binName, binName, binName, binName, binName)
 
-	// Input/Output flags
-	analyzeCmd.Flags().StringVarP(&inputFile, "input", "i", "", "Input profiling data file (required)")
-	analyzeCmd.Flags().StringVarP(&outputDir, "output", "o", "./output", "Output directory for generated files")
+	// Input flag
+	analyzeCmd.Flags().StringVarP(&inputFile, "input", "i", "", "Input profiling data file (required); pprof-heap takes a comma-separated list of profiles, oldest first, and java-gclog of GC logs and heap dumps")
 	analyzeCmd.MarkFlagRequired("input")
 
 	// Analysis mode flag (replaces type + profiler)
 	analyzeCmd.Flags().StringVarP(&analysisMode, "mode", "m", "java-cpu",
 		fmt.Sprintf("Analysis mode: %s", analyzer.ValidModes()))
+	analyzeCmd.Flags().StringVar(&pluginDir, "plugin-dir", "",
+		"Directory of analyzer plugins (*.so files) providing extra analysis modes")
+	analyzeCmd.Flags().DurationVar(&sampleInterval, "interval", 0,
+		"java-wall: sampling interval of the profile (async-profiler -i), 50ms if not set")
+	analyzeCmd.Flags().BoolVar(&totalCounts, "total", false,
+		"java-wall, java-lock: the counts of the stacks are nanoseconds (async-profiler --total)")
+	analyzeCmd.Flags().BoolVar(&mergeProfiles, "merge", false,
+		"pprof-heap: the input profiles are of different instances, merge them rather than comparing them")
+
+	// Symbol source flags
+	analyzeCmd.Flags().StringVar(&symbolVmlinux, "vmlinux", "",
+		"perf script: vmlinux, kallsyms or System.map naming unsymbolized kernel frames")
+	analyzeCmd.Flags().StringSliceVar(&symbolDirs, "symbol-dir", nil,
+		"perf script: directories of symbol files, by build ID (/usr/lib/debug, ~/.debug) or module path")
+	analyzeCmd.Flags().StringSliceVar(&debuginfodURLs, "debuginfod", strings.Fields(os.Getenv("DEBUGINFOD_URLS")),
+		"perf script: debuginfod servers queried for the symbols of build IDs")
+	analyzeCmd.Flags().StringVar(&symbolBuildIDs, "build-ids", "",
+		"perf script: output of perf buildid-list, giving the build IDs of modules")
+	analyzeCmd.Flags().StringVar(&symbolCacheDir, "symbol-cache", "",
+		"perf script: directory keeping debuginfod downloads (default in the temporary directory)")
+
+	addAnalysisFlags(analyzeCmd)
+
+	// Shell completion of flag values
+	analyzeCmd.MarkFlagFilename("input")
+	analyzeCmd.MarkFlagFilename("vmlinux")
+	analyzeCmd.MarkFlagDirname("symbol-dir")
+	analyzeCmd.MarkFlagFilename("build-ids")
+	analyzeCmd.MarkFlagDirname("symbol-cache")
+	analyzeCmd.RegisterFlagCompletionFunc("mode", completeAnalysisModes)
+}
+
+// addAnalysisFlags adds the flags shared by analyze and heap analyze.
+func addAnalysisFlags(c *cobra.Command) {
+	addAnalysisOptionFlags(c)
+
+	c.Flags().StringVar(&taskUUID, "uuid", "", "Task UUID (auto-generated if empty)")
+
+	// Output flags
+	addOutputFormatFlag(c)
+	addThresholdFlags(c)
+
+	// Serve flags
+	c.Flags().BoolVar(&serveAfter, "serve", false, "Start web server during analysis and keep serving the results")
+	c.Flags().IntVar(&servePort, "port", 8080, "Port for web server (used with --serve)")
+}
+
+// addAnalysisOptionFlags adds the flags controlling how inputs are analyzed
+// and where the results go, shared by the analysis commands and watch.
+func addAnalysisOptionFlags(c *cobra.Command) {
+	c.Flags().StringVarP(&outputDir, "output", "o", "./output", "Output directory for generated files")
 
 	// Analysis profile flag (controls analysis depth)
-	analyzeCmd.Flags().StringVar(&analysisProfile, "profile", "standard",
+	c.Flags().StringVar(&analysisProfile, "profile", "standard",
 		"Analysis depth: quick (fast), standard (balanced), detailed (comprehensive)")
 
 	// Other flags
-	analyzeCmd.Flags().StringVar(&taskUUID, "uuid", "", "Task UUID (auto-generated if empty)")
-	analyzeCmd.Flags().IntVarP(&topN, "top", "n", 50, "Number of top functions to report")
+	c.Flags().IntVarP(&topN, "top", "n", 50, "Number of top functions to report")
+	c.Flags().BoolVar(&rollupBiggest, "rollup-biggest", false,
+		"Java heap: show the nearest non-JDK dominator instead of arrays/collections in Biggest Objects")
+	c.Flags().StringVar(&jvmLayout, "jvm-layout", "auto",
+		"Java heap: object layout for shallow sizes: auto, compressed-oops, uncompressed-oops, compact-headers, 32-bit,\n"+
+			"optionally with overrides, e.g. compressed-oops,align=16")
+	c.Flags().StringVar(&g1RegionSize, "g1-region-size", "",
+		"Java heap: G1 region size humongous objects are reported for, e.g. 4MB (default: chosen from the heap size like G1)")
+	c.Flags().StringVar(&ownershipMap, "ownership-map", "",
+		"Java heap: YAML file mapping package prefixes to teams, to report retained memory by team")
+	c.Flags().StringVar(&tableFormat, "table-format", "csv",
+		"Java heap: format of histogram/retainer/dominator table exports: csv, tsv, none")
+	c.Flags().BoolVar(&sqliteExport, "sqlite", false,
+		"Java heap: also export objects, classes, references and dominators to heap.sqlite")
+	c.Flags().BoolVar(&parquetExport, "parquet", false,
+		"Java heap: also export the object table and class histogram as Parquet files")
+	c.Flags().StringSliceVar(&ruleFiles, "rules", nil,
+		"YAML rule sets adding, replacing or disabling suggestion rules")
+
+	// Shell completion of flag values
+	c.MarkFlagDirname("output")
+	c.MarkFlagFilename("rules", "yaml", "yml")
+	c.RegisterFlagCompletionFunc("profile", cobra.FixedCompletions(
+		[]string{"quick", "standard", "detailed"}, cobra.ShellCompDirectiveNoFileComp))
+	c.RegisterFlagCompletionFunc("table-format", cobra.FixedCompletions(
+		[]string{"csv", "tsv", "none"}, cobra.ShellCompDirectiveNoFileComp))
+}
 
-	// Serve flags
-	analyzeCmd.Flags().BoolVar(&serveAfter, "serve", false, "Start web server after analysis")
-	analyzeCmd.Flags().IntVar(&servePort, "port", 8080, "Port for web server (used with --serve)")
+// completeAnalysisModes completes --mode with the registered analysis modes.
+func completeAnalysisModes(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	var modes []string
+	for _, info := range analyzer.AllModes() {
+		modes = append(modes, string(info.Mode)+"\t"+info.Description)
+	}
+	return modes, cobra.ShellCompDirectiveNoFileComp
 }
 
 // buildAnalyzeLongHelp builds the long help message with mode descriptions.
@@ -113,11 +256,28 @@ Analysis profiles control the depth of analysis:
 }
 
 func runAnalyze(cmd *cobra.Command, args []string) error {
+	if err := checkOutputFormat(cmd); err != nil {
+		return err
+	}
 	log := GetLogger()
 
-	// Validate input file
-	if _, err := os.Stat(inputFile); os.IsNotExist(err) {
-		return fmt.Errorf("input file not found: %s", inputFile)
+	// Validate input files
+	inputFiles := strings.Split(inputFile, ",")
+	for _, path := range inputFiles {
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			return fmt.Errorf("input file not found: %s", path)
+		}
+	}
+
+	// Load analyzer plugins before parsing the mode, which they may register
+	if pluginDir != "" {
+		plugins, err := analyzer.LoadPlugins(pluginDir)
+		if err != nil {
+			return err
+		}
+		for _, path := range plugins {
+			log.Debug("Loaded analyzer plugin %s", path)
+		}
 	}
 
 	// Parse analysis mode
@@ -132,6 +292,47 @@ func runAnalyze(cmd *cobra.Command, args []string) error {
 		return err
 	}
 
+	// Validate the JVM layout
+	if jvmLayout != "auto" {
+		if _, err := hprof.ParseJVMLayout(jvmLayout); err != nil {
+			return err
+		}
+	}
+
+	// Parse the G1 region size
+	var regionSize int64
+	if g1RegionSize != "" {
+		if regionSize, err = utils.ParseByteSize(g1RegionSize); err != nil {
+			return err
+		}
+		if err := hprof.ValidateG1RegionSize(regionSize); err != nil {
+			return err
+		}
+	}
+
+	// Load the package ownership map
+	var owners *hprof.OwnershipMap
+	if ownershipMap != "" {
+		if owners, err = hprof.LoadOwnershipMapFile(ownershipMap); err != nil {
+			return err
+		}
+	}
+
+	// Parse table export format
+	var exportFormat writer.TableFormat
+	if tableFormat != "none" {
+		exportFormat, err = writer.ParseTableFormat(tableFormat)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Load suggestion rules
+	ruleEngine, err := suggestionRules()
+	if err != nil {
+		return err
+	}
+
 	// Get mode info for display
 	modeInfo := mode.Info()
 
@@ -147,6 +348,22 @@ func runAnalyze(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("failed to create output directory: %w", err)
 	}
 
+	// Keep the analyzer log with the task, so the web UI can tail it
+	if defaultLog, ok := log.(*utils.DefaultLogger); ok {
+		logFile, err := os.OpenFile(filepath.Join(taskOutputDir, webui.AnalysisLogFileName), os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+		if err != nil {
+			log.Warn("Failed to create analysis log: %v", err)
+		} else {
+			defer logFile.Close()
+			if structuredOutput() {
+				// The console only gets problems, the task log keeps everything
+				log = newLogger(logLevel(), logFile)
+			} else {
+				log = defaultLog.Tee(logFile)
+			}
+		}
+	}
+
 	log.Info("=== Perf Analysis CLI ===")
 	log.Info("Input file:    %s", inputFile)
 	log.Info("Output dir:    %s", taskOutputDir)
@@ -162,6 +379,38 @@ func runAnalyze(cmd *cobra.Command, args []string) error {
 		Logger:          log,
 		Verbose:         verbose,
 		AnalysisProfile: profile,
+
+		RollupBiggestObjects: rollupBiggest,
+		JVMLayout:            jvmLayout,
+		G1RegionSize:         regionSize,
+		OwnershipMap:         owners,
+		TableExportFormat:    exportFormat,
+		SQLiteExport:         sqliteExport,
+		ParquetExport:        parquetExport,
+	}
+
+	symbols, err := symbolResolver()
+	if err != nil {
+		return err
+	}
+	config.Symbols = symbols
+
+	// In serve mode the web server starts before analysis, so the browser can
+	// follow progress through /api/progress
+	var progress *utils.ProgressReporter
+	var serveErr chan error
+	if serveAfter {
+		// The server logs to the console only, not to the task's analysis log
+		server := newServeServer(outputDir, servePort, GetLogger())
+		serveErr = make(chan error, 1)
+		go func() {
+			serveErr <- runServer(server, outputDir, servePort, GetLogger())
+		}()
+		progress = utils.NewProgressReporter(func(update utils.ProgressUpdate) {
+			server.Progress().Publish(webui.NewProgressEvent(uuid, update))
+		})
+		config.ProgressCallback = progress.Report
+		progress.Report("analyzing", 0, 1)
 	}
 
 	// Create analyzer using factory
@@ -180,8 +429,16 @@ func runAnalyze(cmd *cobra.Command, args []string) error {
 		TaskUUID:     uuid,
 		TaskType:     mode.ToTaskType(),
 		ProfilerType: mode.ToProfilerType(),
-		InputFile:    inputFile,
+		InputFile:    inputFiles[0],
 		OutputDir:    taskOutputDir,
+		RequestParams: model.RequestParams{
+			Interval: sampleInterval.Nanoseconds(),
+			Total:    totalCounts,
+			Merge:    mergeProfiles,
+		},
+	}
+	if len(inputFiles) > 1 {
+		req.InputFiles = inputFiles
 	}
 
 	// Run analysis
@@ -191,38 +448,66 @@ func runAnalyze(cmd *cobra.Command, args []string) error {
 	result, err := ana.Analyze(ctx, req)
 	analysisTime := time.Since(startTime)
 	if err != nil {
+		if serveAfter {
+			progress.Done(err)
+			log.Error("Analysis failed: %v", err)
+			log.Info("The web server keeps running so the failure is visible; press Ctrl+C to stop")
+			return <-serveErr
+		}
 		return fmt.Errorf("analysis failed: %w", err)
 	}
 
 	log.Info("Analysis completed successfully!")
 	log.Info("")
+	ruleEngine.Apply(result)
 
 	// Print results
-	printResults(log, result)
+	if !structuredOutput() {
+		printResults(log, result)
+		if result.Health != nil {
+			log.Info("Health score: %d/100 (%s)", result.Health.Score, result.Health.Grade)
+		}
+	}
 
 	// Save result summary with metadata
 	metadata := &AnalysisMetadata{
 		Mode:           string(mode),
 		ModeDesc:       modeInfo.Description,
 		Profile:        string(profile),
-		InputFile:      filepath.Base(inputFile),
+		InputFile:      filepath.Base(inputFiles[0]),
 		CreatedAt:      startTime.Format(time.RFC3339),
 		AnalysisTimeMs: analysisTime.Milliseconds(),
 	}
-	saveSummary(result, taskOutputDir, metadata)
+	summary := saveSummary(result, taskOutputDir, metadata)
+	violations, err := checkThresholds(taskOutputDir)
+	if err != nil {
+		return err
+	}
+	if violations != nil {
+		summary["threshold_violations"] = violations
+	}
 
 	log.Info("")
 	log.Info("=== Analysis Complete ===")
 	log.Info("Output files are in: %s", taskOutputDir)
 
-	// If serve mode is enabled, start the web server
+	if structuredOutput() {
+		summary["output_dir"] = taskOutputDir
+		if err := writeResult(cmd.OutOrStdout(), summary); err != nil {
+			return err
+		}
+	}
+
+	// In serve mode, keep serving the results
 	if serveAfter {
-		log.Info("")
-		log.Info("Starting web server...")
-		return startServeMode(outputDir, servePort, log)
+		for _, violation := range violations {
+			log.Error("Threshold exceeded: %s", violation)
+		}
+		progress.Done(nil)
+		return <-serveErr
 	}
 
-	return nil
+	return thresholdFailure(cmd, violations)
 }
 
 // parseAnalysisProfile parses the profile string into AnalysisProfile.
@@ -249,7 +534,8 @@ func printResults(_ any, result *model.AnalysisResponse) {
 	registry.Format(result, GetLogger())
 }
 
-func saveSummary(result *model.AnalysisResponse, outputDir string, metadata *AnalysisMetadata) {
+// saveSummary writes summary.json to outputDir and returns the summary.
+func saveSummary(result *model.AnalysisResponse, outputDir string, metadata *AnalysisMetadata) map[string]any {
 	// Use the formatter registry to generate summary
 	registry := formatter.NewRegistry()
 	summary := registry.FormatSummary(result)
@@ -278,6 +564,7 @@ func saveSummary(result *model.AnalysisResponse, outputDir string, metadata *Ana
 			GetLogger().Warn("Failed to write detailed retainer file: %v", err)
 		}
 	}
+	return summary
 }
 
 // AnalysisMetadata holds metadata about the analysis task.
@@ -289,3 +576,42 @@ type AnalysisMetadata struct {
 	CreatedAt      string `json:"created_at"`
 	AnalysisTimeMs int64  `json:"analysis_time_ms"`
 }
+
+// symbolResolver returns the resolver of the symbol source flags, nil if
+// none is set.
+// suggestionRules returns the built-in suggestion rules with the rule sets
+// of --rules.
+func suggestionRules() (*rules.Engine, error) {
+	engine := rules.Default()
+	for _, path := range ruleFiles {
+		set, err := rules.LoadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		engine.Load(set)
+	}
+	return engine, nil
+}
+
+func symbolResolver() (*symbol.Resolver, error) {
+	config := symbol.Config{
+		Vmlinux:        symbolVmlinux,
+		Dirs:           symbolDirs,
+		DebuginfodURLs: debuginfodURLs,
+		CacheDir:       symbolCacheDir,
+	}
+	if !config.Enabled() {
+		return nil, nil
+	}
+	if symbolBuildIDs != "" {
+		f, err := os.Open(symbolBuildIDs)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		if config.BuildIDs, err = symbol.ParseBuildIDList(f); err != nil {
+			return nil, fmt.Errorf("failed to read build IDs: %w", err)
+		}
+	}
+	return symbol.NewResolver(config), nil
+}
@@ -0,0 +1,181 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/spf13/cobra"
+
+	"github.com/perf-analysis/internal/flamegraph"
+	"github.com/perf-analysis/internal/webui"
+	"github.com/perf-analysis/pkg/writer"
+)
+
+var (
+	// Convert flags
+	convertFormat string
+	convertOutput string
+	convertType   string
+	// convertTraceType has its own default: trace exports are mostly of
+	// tracing tasks
+	convertTraceType string
+)
+
+// convertCmd groups the commands converting analysis results to other formats
+var convertCmd = &cobra.Command{
+	Use:     "convert",
+	GroupID: groupResults,
+	Short:   "Convert analysis results to other formats",
+	Long: `Convert the results in an analysis task directory to formats understood
+by other tools.`,
+}
+
+// convertHistogramCmd converts a class histogram to CSV or TSV
+var convertHistogramCmd = &cobra.Command{
+	Use:               "histogram <task-dir>",
+	Short:             "Convert the class histogram of a heap analysis to CSV or TSV",
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: completeTaskDirs,
+	RunE:              runConvertHistogram,
+}
+
+// convertSpeedscopeCmd converts a flame graph to the speedscope format
+var convertSpeedscopeCmd = &cobra.Command{
+	Use:               "speedscope <task-dir>",
+	Short:             "Convert a flame graph of a task to a speedscope file",
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: completeTaskDirs,
+	RunE:              runConvertSpeedscope,
+}
+
+// convertTraceCmd converts a flame graph to Chrome trace events
+var convertTraceCmd = &cobra.Command{
+	Use:   "trace <task-dir>",
+	Short: "Convert a flame graph of a task to Chrome trace events, for Perfetto",
+	Long: `Convert a flame graph of a task to the Chrome trace event JSON format, to
+open in https://ui.perfetto.dev or chrome://tracing. Each thread of the flame
+graph is a track whose frames are nested slices, heaviest first.`,
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: completeTaskDirs,
+	RunE:              runConvertTrace,
+}
+
+func init() {
+	rootCmd.AddCommand(convertCmd)
+	convertCmd.AddCommand(convertHistogramCmd)
+	convertCmd.AddCommand(convertSpeedscopeCmd)
+	convertCmd.AddCommand(convertTraceCmd)
+
+	binName := BinName()
+	convertCmd.Example = `  # Convert a class histogram to TSV
+  ` + binName + ` convert histogram ./output/my-heap --format tsv -o histogram.tsv`
+	convertHistogramCmd.Example = `  # Write the class histogram as CSV to stdout
+  ` + binName + ` convert histogram ./output/my-heap
+
+  # Write it as TSV to a file
+  ` + binName + ` convert histogram ./output/my-heap --format tsv -o histogram.tsv`
+	convertSpeedscopeCmd.Example = `  # Write the CPU flame graph as a speedscope file, to open in speedscope.app
+  ` + binName + ` convert speedscope ./output/my-cpu -o profile.speedscope.json
+
+  # Convert the allocation flame graph
+  ` + binName + ` convert speedscope ./output/my-alloc --type memory -o alloc.speedscope.json`
+	convertTraceCmd.Example = `  # Write the latency flame graph of a tracing task as Chrome trace events
+  ` + binName + ` convert trace ./output/my-tracing -o latency.trace.json
+
+  # Convert a CPU flame graph
+  ` + binName + ` convert trace ./output/my-cpu --type cpu -o cpu.trace.json`
+
+	convertHistogramCmd.Flags().StringVar(&convertFormat, "format", "csv", "Output format: csv, tsv")
+	convertHistogramCmd.Flags().StringVarP(&convertOutput, "output", "o", "", "Output file (default stdout)")
+	convertHistogramCmd.RegisterFlagCompletionFunc("format", cobra.FixedCompletions(
+		[]string{"csv", "tsv"}, cobra.ShellCompDirectiveNoFileComp))
+	convertHistogramCmd.MarkFlagFilename("output")
+
+	convertSpeedscopeCmd.Flags().StringVar(&convertType, "type", "cpu", "Flame graph type, e.g. cpu, memory, tracing, pprof-goroutine")
+	convertSpeedscopeCmd.Flags().StringVarP(&convertOutput, "output", "o", "", "Output file (default stdout)")
+	convertSpeedscopeCmd.MarkFlagFilename("output")
+
+	convertTraceCmd.Flags().StringVar(&convertTraceType, "type", "tracing", "Flame graph type, e.g. tracing, cpu, memory")
+	convertTraceCmd.Flags().StringVarP(&convertOutput, "output", "o", "", "Output file (default stdout)")
+	convertTraceCmd.MarkFlagFilename("output")
+}
+
+func runConvertHistogram(cmd *cobra.Command, args []string) error {
+	format, err := writer.ParseTableFormat(convertFormat)
+	if err != nil {
+		return err
+	}
+	histogram, err := loadClassHistogram(args[0])
+	if err != nil {
+		return err
+	}
+
+	table := &writer.Table{
+		Header: []string{"class_name", "instance_count", "shallow_size", "retained_size", "percentage"},
+		Rows:   make([][]string, 0, len(histogram.Classes)),
+	}
+	for _, class := range histogram.Classes {
+		table.Rows = append(table.Rows, []string{
+			class.ClassName,
+			strconv.FormatInt(class.InstanceCount, 10),
+			strconv.FormatInt(class.TotalSize, 10),
+			strconv.FormatInt(class.RetainedSize, 10),
+			strconv.FormatFloat(class.Percentage, 'f', 2, 64),
+		})
+	}
+
+	if convertOutput == "" {
+		return writer.NewTableWriter(format).Write(table, cmd.OutOrStdout())
+	}
+	f, err := os.Create(convertOutput)
+	if err != nil {
+		return err
+	}
+	if err := writer.NewTableWriter(format).Write(table, f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func runConvertSpeedscope(cmd *cobra.Command, args []string) error {
+	return convertFlameGraph(cmd, args[0], convertType, func(fg *flamegraph.FlameGraph, name string) any {
+		return flamegraph.ToSpeedscope(fg, name)
+	})
+}
+
+func runConvertTrace(cmd *cobra.Command, args []string) error {
+	return convertFlameGraph(cmd, args[0], convertTraceType, func(fg *flamegraph.FlameGraph, name string) any {
+		return flamegraph.ToTraceEvents(fg, name)
+	})
+}
+
+// convertFlameGraph writes a flame graph of a task converted to another
+// format as JSON.
+func convertFlameGraph(cmd *cobra.Command, taskDir, typeName string, convert func(fg *flamegraph.FlameGraph, name string) any) error {
+	fgType, ok := webui.ParseFlameGraphType(typeName)
+	if !ok {
+		return fmt.Errorf("unknown flame graph type: %s", typeName)
+	}
+	fg, err := webui.LoadTaskFlameGraph(cmd.Context(), taskDir, fgType)
+	if err != nil {
+		return err
+	}
+	file := convert(fg, filepath.Base(filepath.Clean(taskDir))+" "+string(fgType))
+
+	if convertOutput == "" {
+		return json.NewEncoder(cmd.OutOrStdout()).Encode(file)
+	}
+	f, err := os.Create(convertOutput)
+	if err != nil {
+		return err
+	}
+	if err := json.NewEncoder(f).Encode(file); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
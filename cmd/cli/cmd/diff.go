@@ -0,0 +1,217 @@
+package cmd
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/spf13/cobra"
+
+	"github.com/perf-analysis/internal/parser/hprof"
+	"github.com/perf-analysis/pkg/writer"
+)
+
+var (
+	// Heap diff flags
+	diffTop              int
+	diffIncludeUnchanged bool
+	diffObjects          bool
+)
+
+// diffCmd groups the commands comparing two analyses
+var diffCmd = &cobra.Command{
+	Use:     "diff",
+	GroupID: groupAnalysis,
+	Short:   "Compare the results of two analyses",
+	Long: `Compare two analysis task directories or profiles, e.g. a baseline and a
+later capture of the same service, to find what grew.`,
+}
+
+// diffHeapCmd compares the class histograms of two heap analyses
+var diffHeapCmd = &cobra.Command{
+	Use:   "heap <base-task-dir> <target-task-dir>",
+	Short: "Compare the class histograms of two analyzed heap dumps",
+	Long: `Compare the class histograms of two analyzed heap dumps. Classes are
+listed by the absolute change of their shallow size, largest first.
+
+With --objects, compare the retained sizes of individual objects instead,
+matched by ID, class and shallow size. The dominators of the target dump are
+derived incrementally from those of the base dump, recomputing only the part
+of the heap the changes can affect. Both task directories need the reference
+graph (refgraph.bin) of the analysis.`,
+	Args:              cobra.ExactArgs(2),
+	ValidArgsFunction: completeTaskDirs,
+	RunE:              runDiffHeap,
+}
+
+func init() {
+	rootCmd.AddCommand(diffCmd)
+	diffCmd.AddCommand(diffHeapCmd)
+
+	binName := BinName()
+	diffCmd.Example = `  # Compare two analyzed heap dumps
+  ` + binName + ` diff heap ./output/before ./output/after
+
+  # Compare two CPU profiles
+  ` + binName + ` diff cpu before.data after.data`
+	diffHeapCmd.Example = `  # Show the 20 classes that changed most
+  ` + binName + ` diff heap ./output/before ./output/after -n 20
+
+  # Show the 20 objects whose retained size changed most
+  ` + binName + ` diff heap ./output/before ./output/after --objects -n 20
+
+  # Export the full comparison as CSV
+  ` + binName + ` diff heap ./output/before ./output/after --format csv -n 0 > heap_diff.csv
+
+  # Fail a CI job when the heap grew by 100 MB or more
+  ` + binName + ` diff heap ./output/before ./output/after --format json | jq -e '.delta_total_size < 104857600'`
+
+	diffHeapCmd.Flags().IntVarP(&diffTop, "top", "n", 30, "Number of classes or objects to print (0 for all)")
+	diffHeapCmd.Flags().BoolVar(&diffIncludeUnchanged, "include-unchanged", false, "Also list classes or objects whose size did not change")
+	diffHeapCmd.Flags().BoolVar(&diffObjects, "objects", false, "Compare the retained sizes of objects instead of the class histograms")
+	addOutputFormatFlag(diffHeapCmd, "csv", "tsv")
+}
+
+func runDiffHeap(cmd *cobra.Command, args []string) error {
+	if err := checkOutputFormat(cmd); err != nil {
+		return err
+	}
+	if diffObjects {
+		return runDiffHeapObjects(cmd, args)
+	}
+	base, err := loadClassHistogram(args[0])
+	if err != nil {
+		return err
+	}
+	target, err := loadClassHistogram(args[1])
+	if err != nil {
+		return err
+	}
+
+	analyzer := &hprof.DiffAnalyzer{IncludeUnchanged: diffIncludeUnchanged}
+	diff := analyzer.Diff(base.Classes, target.Classes)
+	if diffTop > 0 && len(diff.Classes) > diffTop {
+		diff.Classes = diff.Classes[:diffTop]
+	}
+
+	out := cmd.OutOrStdout()
+	switch outputFormat {
+	case formatText:
+	case formatJSON, formatNDJSON:
+		return writeRows(out, diff, diff.Classes)
+	default:
+		format, err := writer.ParseTableFormat(outputFormat)
+		if err != nil {
+			return err
+		}
+		return writer.NewTableWriter(format).Write(heapDiffTable(diff), out)
+	}
+
+	fmt.Fprintf(out, "Heap size: %s -> %s (%s)\n", hprof.FormatBytesSize(diff.BaseTotalSize),
+		hprof.FormatBytesSize(diff.TargetTotalSize), signedBytes(diff.DeltaTotalSize))
+	fmt.Fprintf(out, "Instances: %d -> %d (%+d)\n", diff.BaseTotalInstances, diff.TargetTotalInstances, diff.DeltaTotalInstances)
+	fmt.Fprintf(out, "Classes:   %d new, %d removed, %d grown, %d shrunk\n\n",
+		diff.NewClasses, diff.RemovedClasses, diff.GrownClasses, diff.ShrunkClasses)
+	fmt.Fprintf(out, "%-9s %12s %16s %9s  %s\n", "STATUS", "INSTANCES", "SIZE", "GROWTH", "CLASS")
+	for _, d := range diff.Classes {
+		fmt.Fprintf(out, "%-9s %+12d %16s %8.1f%%  %s\n", d.Status, d.DeltaInstances,
+			signedBytes(d.DeltaSize), d.GrowthPercent, d.ClassName)
+	}
+	return nil
+}
+
+// runDiffHeapObjects compares the retained sizes of the objects of two heap
+// analyses.
+func runDiffHeapObjects(cmd *cobra.Command, args []string) error {
+	base, err := hprof.LoadReferenceGraph(args[0])
+	if err != nil {
+		return fmt.Errorf("%s: %w", args[0], err)
+	}
+	target, err := hprof.LoadReferenceGraph(args[1])
+	if err != nil {
+		return fmt.Errorf("%s: %w", args[1], err)
+	}
+
+	analyzer := &hprof.DiffAnalyzer{IncludeUnchanged: diffIncludeUnchanged}
+	diff, err := analyzer.DiffObjects(cmd.Context(), base, target, diffTop)
+	if err != nil {
+		return err
+	}
+
+	out := cmd.OutOrStdout()
+	switch outputFormat {
+	case formatText:
+	case formatJSON, formatNDJSON:
+		return writeRows(out, diff, diff.Objects)
+	default:
+		format, err := writer.ParseTableFormat(outputFormat)
+		if err != nil {
+			return err
+		}
+		return writer.NewTableWriter(format).Write(heapObjectDiffTable(diff), out)
+	}
+
+	update := diff.Dominators
+	fmt.Fprintf(out, "Objects:    %d matched, %d new, %d removed\n", update.MatchedObjects, update.NewObjects, update.RemovedObjects)
+	if update.FullRecompute {
+		fmt.Fprintf(out, "Dominators: computed from scratch, too many objects changed\n\n")
+	} else {
+		fmt.Fprintf(out, "Dominators: %d reused, %d recomputed\n\n", update.ReusedObjects, update.RecomputedObjects)
+	}
+	fmt.Fprintf(out, "%-9s %16s %16s  %-18s %s\n", "STATUS", "RETAINED", "DELTA", "OBJECT", "CLASS")
+	for _, d := range diff.Objects {
+		fmt.Fprintf(out, "%-9s %16s %16s  %-18s %s\n", d.Status, hprof.FormatBytesSize(d.TargetRetained),
+			signedBytes(d.DeltaRetained), formatObjectID(d.ObjectID), d.ClassName)
+	}
+	return nil
+}
+
+// heapObjectDiffTable converts an object-level heap diff into a table, one
+// row per object.
+func heapObjectDiffTable(diff *hprof.HeapObjectDiff) *writer.Table {
+	table := &writer.Table{
+		Header: []string{"object_id", "class_name", "status", "base_retained", "target_retained", "delta_retained"},
+		Rows:   make([][]string, 0, len(diff.Objects)),
+	}
+	for _, d := range diff.Objects {
+		table.Rows = append(table.Rows, []string{
+			formatObjectID(d.ObjectID),
+			d.ClassName,
+			string(d.Status),
+			strconv.FormatInt(d.BaseRetained, 10),
+			strconv.FormatInt(d.TargetRetained, 10),
+			strconv.FormatInt(d.DeltaRetained, 10),
+		})
+	}
+	return table
+}
+
+// heapDiffTable converts a heap diff into a table, one row per class.
+func heapDiffTable(diff *hprof.HeapDiff) *writer.Table {
+	table := &writer.Table{
+		Header: []string{"class_name", "status", "base_instances", "target_instances", "delta_instances",
+			"base_size", "target_size", "delta_size", "growth_percent"},
+		Rows: make([][]string, 0, len(diff.Classes)),
+	}
+	for _, d := range diff.Classes {
+		table.Rows = append(table.Rows, []string{
+			d.ClassName,
+			string(d.Status),
+			strconv.FormatInt(d.BaseInstances, 10),
+			strconv.FormatInt(d.TargetInstances, 10),
+			strconv.FormatInt(d.DeltaInstances, 10),
+			strconv.FormatInt(d.BaseSize, 10),
+			strconv.FormatInt(d.TargetSize, 10),
+			strconv.FormatInt(d.DeltaSize, 10),
+			strconv.FormatFloat(d.GrowthPercent, 'f', 2, 64),
+		})
+	}
+	return table
+}
+
+// signedBytes formats a size change with its sign.
+func signedBytes(delta int64) string {
+	if delta < 0 {
+		return "-" + hprof.FormatBytesSize(-delta)
+	}
+	return "+" + hprof.FormatBytesSize(delta)
+}
@@ -0,0 +1,180 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/spf13/cobra"
+
+	"github.com/perf-analysis/internal/flamegraph"
+	"github.com/perf-analysis/internal/parser/collapsed"
+	"github.com/perf-analysis/internal/webui"
+	"github.com/perf-analysis/pkg/writer"
+)
+
+var (
+	// CPU diff flags
+	diffCPUTop    int
+	diffCPUOutput string
+	diffCPUHTML   string
+)
+
+// diffCPUCmd compares two CPU profiles in collapsed stack format
+var diffCPUCmd = &cobra.Command{
+	Use:   "cpu <before.data> <after.data>",
+	Short: "Compare two CPU profiles with a differential flame graph",
+	Long: `Compare two CPU profiles in collapsed stack format, e.g. before and after a
+change, and list the functions whose self time grew most.
+
+Profiles are compared by the share of all samples of each frame, so profiles
+of different durations can be compared. The differential flame graph is
+written as JSON: frames are sized by the samples of the second profile and
+carry the samples of the first one and the change of their share ("delta",
+in percentage points). Use --html to render it, red for frames that grew and
+blue for frames that shrank.`,
+	Args: cobra.ExactArgs(2),
+	RunE: runDiffCPU,
+}
+
+func init() {
+	diffCmd.AddCommand(diffCPUCmd)
+
+	binName := BinName()
+	diffCPUCmd.Example = `  # Show the 20 functions that regressed most
+  ` + binName + ` diff cpu before.data after.data -n 20
+
+  # Render the differential flame graph as a standalone HTML page
+  ` + binName + ` diff cpu before.data after.data --html cpu_diff.html
+
+  # Export the regressed functions as CSV
+  ` + binName + ` diff cpu before.data after.data --format csv -n 0 > cpu_diff.csv`
+
+	diffCPUCmd.Flags().IntVarP(&diffCPUTop, "top", "n", 30, "Number of regressed functions to print (0 for all)")
+	diffCPUCmd.Flags().StringVarP(&diffCPUOutput, "output", "o", "cpu_diff.json", "Differential flame graph JSON file (empty to skip)")
+	diffCPUCmd.Flags().StringVar(&diffCPUHTML, "html", "", "Also render the differential flame graph to this HTML file")
+	addOutputFormatFlag(diffCPUCmd, "csv", "tsv")
+}
+
+// cpuDiff is the result of diff cpu.
+type cpuDiff struct {
+	BaseTotalSamples int64                      `json:"base_total_samples"`
+	TotalSamples     int64                      `json:"total_samples"`
+	FlameGraphFile   string                     `json:"flame_graph_file,omitempty"`
+	Functions        []*flamegraph.FunctionDiff `json:"functions"`
+}
+
+func runDiffCPU(cmd *cobra.Command, args []string) error {
+	if err := checkOutputFormat(cmd); err != nil {
+		return err
+	}
+	log := GetLogger()
+	ctx := context.Background()
+
+	base, err := loadCollapsedFlameGraph(ctx, args[0])
+	if err != nil {
+		return err
+	}
+	target, err := loadCollapsedFlameGraph(ctx, args[1])
+	if err != nil {
+		return err
+	}
+	diff := flamegraph.NewDiffFlameGraph(base, target)
+
+	result := &cpuDiff{
+		BaseTotalSamples: diff.BaseTotalSamples,
+		TotalSamples:     diff.TotalSamples,
+		FlameGraphFile:   diffCPUOutput,
+		Functions:        []*flamegraph.FunctionDiff{},
+	}
+	for _, fn := range diff.Functions() {
+		if fn.DeltaSelfPercent <= 0 {
+			break
+		}
+		if diffCPUTop > 0 && len(result.Functions) == diffCPUTop {
+			break
+		}
+		result.Functions = append(result.Functions, fn)
+	}
+
+	if diffCPUOutput != "" {
+		if err := writer.NewJSONWriter[*flamegraph.DiffFlameGraph]().WriteToFile(diff, diffCPUOutput); err != nil {
+			return fmt.Errorf("failed to write differential flame graph: %w", err)
+		}
+		log.Info("Differential flame graph written to %s", diffCPUOutput)
+	}
+	if diffCPUHTML != "" {
+		title := fmt.Sprintf("%s vs %s", filepath.Base(args[0]), filepath.Base(args[1]))
+		if err := writeStaticReportFile(webui.BuildDiffStaticReport(title, diff), diffCPUHTML); err != nil {
+			return err
+		}
+	}
+
+	out := cmd.OutOrStdout()
+	switch outputFormat {
+	case formatText:
+	case formatJSON, formatNDJSON:
+		return writeRows(out, result, result.Functions)
+	default:
+		format, err := writer.ParseTableFormat(outputFormat)
+		if err != nil {
+			return err
+		}
+		return writer.NewTableWriter(format).Write(cpuDiffTable(result), out)
+	}
+
+	fmt.Fprintf(out, "Samples: %d -> %d\n\n", result.BaseTotalSamples, result.TotalSamples)
+	if len(result.Functions) == 0 {
+		fmt.Fprintln(out, "No function regressed.")
+		return nil
+	}
+	fmt.Fprintln(out, "Top regressed functions (self time, % of all samples):")
+	fmt.Fprintf(out, "%9s %8s %8s  %s\n", "DELTA", "BEFORE", "AFTER", "FUNCTION")
+	for _, fn := range result.Functions {
+		fmt.Fprintf(out, "%+8.2f%% %7.2f%% %7.2f%%  %s\n", fn.DeltaSelfPercent, fn.BaseSelfPercent, fn.SelfPercent, fn.Name)
+	}
+	return nil
+}
+
+// loadCollapsedFlameGraph parses a profile in collapsed stack format into a
+// flame graph of all threads, without pruning, so frames match across
+// profiles.
+func loadCollapsedFlameGraph(ctx context.Context, path string) (*flamegraph.FlameGraph, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	result, err := collapsed.NewParser(nil).Parse(ctx, f)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	generator := flamegraph.NewGenerator(&flamegraph.GeneratorOptions{})
+	return generator.Generate(ctx, result.Samples)
+}
+
+// cpuDiffTable converts a CPU diff into a table, one row per function.
+func cpuDiffTable(diff *cpuDiff) *writer.Table {
+	table := &writer.Table{
+		Header: []string{"function", "base_self", "self", "base_self_percent", "self_percent", "delta_self_percent",
+			"base_total_percent", "total_percent", "delta_total_percent"},
+		Rows: make([][]string, 0, len(diff.Functions)),
+	}
+	for _, fn := range diff.Functions {
+		table.Rows = append(table.Rows, []string{
+			fn.Name,
+			strconv.FormatInt(fn.BaseSelf, 10),
+			strconv.FormatInt(fn.Self, 10),
+			strconv.FormatFloat(fn.BaseSelfPercent, 'f', 2, 64),
+			strconv.FormatFloat(fn.SelfPercent, 'f', 2, 64),
+			strconv.FormatFloat(fn.DeltaSelfPercent, 'f', 2, 64),
+			strconv.FormatFloat(fn.BaseTotalPercent, 'f', 2, 64),
+			strconv.FormatFloat(fn.TotalPercent, 'f', 2, 64),
+			strconv.FormatFloat(fn.DeltaTotalPercent, 'f', 2, 64),
+		})
+	}
+	return table
+}
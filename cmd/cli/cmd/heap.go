@@ -0,0 +1,200 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+
+	"github.com/spf13/cobra"
+
+	"github.com/perf-analysis/internal/analyzer"
+	"github.com/perf-analysis/internal/parser/hprof"
+)
+
+var (
+	// Heap histogram flags
+	histogramTop    int
+	histogramFilter string
+)
+
+// heapCmd groups the Java heap dump commands
+var heapCmd = &cobra.Command{
+	Use:     "heap",
+	GroupID: groupAnalysis,
+	Short:   "Inspect Java heap dump analysis results",
+	Long: `Commands for working with Java heap dumps (HPROF) and the results of
+their analysis.
+
+A task directory is the per-task output directory written by
+"analyze -m java-heap", e.g. ./output/<uuid>.`,
+}
+
+// heapAnalyzeCmd analyzes an HPROF heap dump
+var heapAnalyzeCmd = &cobra.Command{
+	Use:   "analyze <dump.hprof>",
+	Short: "Analyze a Java heap dump",
+	Long: `Analyze an HPROF heap dump, e.g. one written by jmap or
+-XX:+HeapDumpOnOutOfMemoryError, without going through the analysis service.
+
+The task directory receives the summary (summary.json), class histogram,
+biggest objects (biggest_objects.json), retainer and dominator analyses, and
+the reference graph and dominator tree (refgraph.bin, domtree.bin) used by the
+interactive heap views of serve mode.
+
+This is the same as "analyze -m java-heap -i <dump.hprof>".`,
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: completeHeapDumps,
+	RunE:              runHeapAnalyze,
+}
+
+// heapHistogramCmd prints the class histogram of an analyzed heap dump
+var heapHistogramCmd = &cobra.Command{
+	Use:   "histogram <task-dir>",
+	Short: "Print the class histogram of an analyzed heap dump",
+	Long: `Print the classes using the most heap in an analyzed heap dump, with
+instance counts, shallow and retained sizes.`,
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: completeTaskDirs,
+	RunE:              runHeapHistogram,
+}
+
+func init() {
+	rootCmd.AddCommand(heapCmd)
+	heapCmd.AddCommand(heapAnalyzeCmd)
+	heapCmd.AddCommand(heapHistogramCmd)
+	heapCmd.AddCommand(heapShellCmd)
+	heapCmd.AddCommand(heapRedactCmd)
+
+	binName := BinName()
+	heapCmd.Example = `  # Analyze a heap dump
+  ` + binName + ` heap analyze ./heap.hprof
+
+  # Dump and analyze the heap of a running JVM
+  ` + binName + ` heap capture --pid 1234
+
+  # Print the 20 largest classes of an analyzed heap dump
+  ` + binName + ` heap histogram ./output/my-heap -n 20
+
+  # Explore a heap dump interactively
+  ` + binName + ` heap shell ./heap.hprof
+
+  # Remove String contents before sharing a heap dump
+  ` + binName + ` heap redact ./heap.hprof -o ./heap-redacted.hprof`
+	heapAnalyzeCmd.Example = `  # Analyze a heap dump into ./output/<uuid>
+  ` + binName + ` heap analyze ./heap.hprof
+
+  # Analyze into ./results/my-heap and browse the results while it runs
+  ` + binName + ` heap analyze ./heap.hprof -o ./results --uuid my-heap --serve --port 8080
+
+  # Deep analysis with a SQLite export for ad-hoc queries
+  ` + binName + ` heap analyze ./heap.hprof --profile detailed --sqlite`
+	heapHistogramCmd.Example = `  # Print the 20 largest classes
+  ` + binName + ` heap histogram ./output/my-heap -n 20
+
+  # Only classes of a package
+  ` + binName + ` heap histogram ./output/my-heap --filter '^com\.example\.'
+
+  # One JSON object per class, e.g. for jq
+  ` + binName + ` heap histogram ./output/my-heap --format ndjson`
+
+	addAnalysisFlags(heapAnalyzeCmd)
+
+	heapHistogramCmd.Flags().IntVarP(&histogramTop, "top", "n", 30, "Number of classes to print (0 for all)")
+	heapHistogramCmd.Flags().StringVar(&histogramFilter, "filter", "", "Only print classes matching this regular expression")
+	addOutputFormatFlag(heapHistogramCmd)
+}
+
+func runHeapAnalyze(cmd *cobra.Command, args []string) error {
+	inputFile = args[0]
+	analysisMode = string(analyzer.ModeJavaHeap)
+	return runAnalyze(cmd, args)
+}
+
+// classHistogram mirrors the class_histogram.json written by heap analysis.
+type classHistogram struct {
+	TotalClasses   int                 `json:"total_classes"`
+	TotalInstances int64               `json:"total_instances"`
+	TotalSize      int64               `json:"total_size"`
+	Classes        []*hprof.ClassStats `json:"classes"`
+}
+
+// loadClassHistogram reads the class histogram of a heap analysis task directory.
+func loadClassHistogram(taskDir string) (*classHistogram, error) {
+	data, err := os.ReadFile(filepath.Join(taskDir, "class_histogram.json"))
+	if os.IsNotExist(err) {
+		return nil, fmt.Errorf("no class histogram in %s: not a heap analysis task directory", taskDir)
+	}
+	if err != nil {
+		return nil, err
+	}
+	var histogram classHistogram
+	if err := json.Unmarshal(data, &histogram); err != nil {
+		return nil, fmt.Errorf("invalid class histogram in %s: %w", taskDir, err)
+	}
+	return &histogram, nil
+}
+
+func runHeapHistogram(cmd *cobra.Command, args []string) error {
+	if err := checkOutputFormat(cmd); err != nil {
+		return err
+	}
+	histogram, err := loadClassHistogram(args[0])
+	if err != nil {
+		return err
+	}
+
+	var filter *regexp.Regexp
+	if histogramFilter != "" {
+		if filter, err = regexp.Compile(histogramFilter); err != nil {
+			return fmt.Errorf("invalid filter: %w", err)
+		}
+	}
+
+	out := cmd.OutOrStdout()
+	if structuredOutput() {
+		histogram.Classes = filterClassHistogram(histogram.Classes, histogramTop, filter)
+		return writeRows(out, histogram, histogram.Classes)
+	}
+	fmt.Fprintf(out, "Classes: %d, Instances: %d, Heap size: %s\n\n",
+		histogram.TotalClasses, histogram.TotalInstances, hprof.FormatBytesSize(histogram.TotalSize))
+	printClassHistogram(out, histogram.Classes, histogramTop, filter)
+	return nil
+}
+
+// printClassHistogram prints up to top classes (0 for all) matching filter.
+func printClassHistogram(out io.Writer, classes []*hprof.ClassStats, top int, filter *regexp.Regexp) {
+	fmt.Fprintf(out, "%12s %14s %14s %7s  %s\n", "INSTANCES", "SHALLOW", "RETAINED", "%", "CLASS")
+	for _, class := range filterClassHistogram(classes, top, filter) {
+		fmt.Fprintf(out, "%12d %14s %14s %6.2f%%  %s\n", class.InstanceCount,
+			hprof.FormatBytesSize(class.TotalSize), hprof.FormatBytesSize(class.RetainedSize),
+			class.Percentage, class.ClassName)
+	}
+}
+
+// filterClassHistogram returns up to top classes (0 for all) matching filter.
+func filterClassHistogram(classes []*hprof.ClassStats, top int, filter *regexp.Regexp) []*hprof.ClassStats {
+	selected := []*hprof.ClassStats{}
+	for _, class := range classes {
+		if filter != nil && !filter.MatchString(class.ClassName) {
+			continue
+		}
+		if top > 0 && len(selected) >= top {
+			break
+		}
+		selected = append(selected, class)
+	}
+	return selected
+}
+
+// completeHeapDumps completes heap dump arguments.
+func completeHeapDumps(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	return []string{"hprof", "bin"}, cobra.ShellCompDirectiveFilterFileExt
+}
+
+// completeTaskDirs completes task directory arguments.
+func completeTaskDirs(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	return nil, cobra.ShellCompDirectiveFilterDirs
+}
@@ -0,0 +1,115 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/perf-analysis/internal/analyzer"
+	"github.com/perf-analysis/internal/jvm"
+	"github.com/perf-analysis/internal/parser/hprof"
+)
+
+var (
+	// Heap capture flags
+	capturePid     int
+	captureAll     bool
+	captureMethod  string
+	captureTimeout time.Duration
+)
+
+// heapCaptureCmd dumps the heap of a running JVM and analyzes it
+var heapCaptureCmd = &cobra.Command{
+	Use:   "capture --pid <pid>",
+	Short: "Capture and analyze the heap of a running JVM",
+	Long: `Make a running JVM dump its heap into a new task directory, then analyze
+the dump like "heap analyze" does.
+
+By default the dump is requested through the HotSpot attach mechanism, which
+needs no JDK tools, falling back to jcmd and jmap (looked up in PATH,
+JAVA_HOME and the JVM's own installation). Run the command as the user of the
+JVM.
+
+The JVM writes the dump itself, so the output directory must be writable by
+it, under the same path. Unless --all is given, the JVM runs a full GC first
+and only dumps live objects, which pauses it for a while on large heaps.`,
+	Args: cobra.NoArgs,
+	RunE: runHeapCapture,
+}
+
+func init() {
+	heapCmd.AddCommand(heapCaptureCmd)
+
+	binName := BinName()
+	heapCaptureCmd.Example = `  # Dump and analyze the heap of JVM 1234 into ./output/<uuid>
+  ` + binName + ` heap capture --pid 1234
+
+  # Keep unreachable objects, and browse the results while the analysis runs
+  ` + binName + ` heap capture --pid 1234 --all --serve
+
+  # Use jcmd, e.g. when the attach mechanism is blocked
+  ` + binName + ` heap capture --pid 1234 --method jcmd -o /srv/perf --uuid checkout-oom`
+
+	heapCaptureCmd.Flags().IntVar(&capturePid, "pid", 0, "Process ID of the JVM (required)")
+	heapCaptureCmd.Flags().BoolVar(&captureAll, "all", false, "Also dump unreachable objects, skipping the full GC")
+	heapCaptureCmd.Flags().StringVar(&captureMethod, "method", string(jvm.DumpMethodAuto), "How to request the dump: auto, attach, jcmd, jmap")
+	heapCaptureCmd.Flags().DurationVar(&captureTimeout, "timeout", 10*time.Minute, "Maximum time to wait for the dump")
+	heapCaptureCmd.MarkFlagRequired("pid")
+	heapCaptureCmd.RegisterFlagCompletionFunc("method", cobra.FixedCompletions(
+		[]string{"auto", "attach", "jcmd", "jmap"}, cobra.ShellCompDirectiveNoFileComp))
+
+	addAnalysisFlags(heapCaptureCmd)
+}
+
+func runHeapCapture(cmd *cobra.Command, args []string) error {
+	log := GetLogger()
+
+	method, err := jvm.ParseDumpMethod(captureMethod)
+	if err != nil {
+		return err
+	}
+	if capturePid <= 0 {
+		return fmt.Errorf("invalid pid: %d", capturePid)
+	}
+
+	// The dump goes into the task directory, next to its analysis
+	if taskUUID == "" {
+		taskUUID = fmt.Sprintf("pid%d-%s", capturePid, time.Now().Format("20060102-150405"))
+	}
+	taskOutputDir, err := filepath.Abs(filepath.Join(outputDir, taskUUID))
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(taskOutputDir, 0755); err != nil {
+		return fmt.Errorf("failed to create output directory: %w", err)
+	}
+	dumpFile := filepath.Join(taskOutputDir, "heap.hprof")
+
+	log.Info("Capturing heap dump of JVM %d to %s", capturePid, dumpFile)
+	ctx, cancel := context.WithTimeout(context.Background(), captureTimeout)
+	defer cancel()
+	startTime := time.Now()
+	used, err := jvm.DumpHeap(ctx, capturePid, dumpFile, jvm.HeapDumpOptions{
+		Method: method,
+		All:    captureAll,
+		Logger: log,
+	})
+	if err != nil {
+		// Leave no empty task behind for serve mode
+		os.Remove(taskOutputDir)
+		return err
+	}
+	if info, err := os.Stat(dumpFile); err == nil {
+		log.Info("Heap dump captured with %s in %s: %s", used,
+			time.Since(startTime).Round(time.Millisecond), hprof.FormatBytesSize(info.Size()))
+	}
+	log.Info("")
+
+	inputFile = dumpFile
+	analysisMode = string(analyzer.ModeJavaHeap)
+	return runAnalyze(cmd, args)
+}
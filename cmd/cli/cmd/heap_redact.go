@@ -0,0 +1,148 @@
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+
+	"github.com/perf-analysis/internal/parser/hprof"
+)
+
+var (
+	// Heap redact flags
+	redactOutput      string
+	redactMode        string
+	redactKeyFile     string
+	redactAllArrays   bool
+	redactCopyUnknown bool
+)
+
+// heapRedactCmd writes a copy of a heap dump without its array contents
+var heapRedactCmd = &cobra.Command{
+	Use:   "redact <dump.hprof> -o <redacted.hprof>",
+	Short: "Remove String and array contents from a heap dump",
+	Long: `Write a copy of an HPROF heap dump with the contents of its byte[] and
+char[] arrays, which hold the text of Strings, zeroed or hashed, so dumps
+containing personal data can be shared for analysis.
+
+The copy has the same objects, references and sizes as the dump, so it
+analyzes the same. Class, field and method names, and primitive fields of
+objects are kept. --all-arrays also redacts int[], long[] and the other
+primitive arrays.
+
+With --mode hash, arrays are filled with hex digits of a keyed hash of their
+contents instead of zeros: equal Strings stay equal, so duplicate String
+analysis still works. Dumps redacted with the same --key-file hash alike; a
+random key is used otherwise.
+
+A heap dump record of a type this tool does not know fails the redaction,
+since the arrays following it cannot be found. --copy-unknown copies the rest
+of such records unchanged instead: the copy may then hold unredacted data.`,
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: completeHeapDumps,
+	RunE:              runHeapRedact,
+}
+
+func init() {
+	binName := BinName()
+	heapRedactCmd.Example = `  # Zero the String contents of a heap dump
+  ` + binName + ` heap redact ./heap.hprof -o ./heap-redacted.hprof
+
+  # Keep equal Strings equal, comparably across dumps
+  ` + binName + ` heap redact ./heap.hprof -o ./heap-redacted.hprof --mode hash --key-file ./redact.key
+
+  # Also zero numeric arrays
+  ` + binName + ` heap redact ./heap.hprof -o ./heap-redacted.hprof --all-arrays`
+
+	heapRedactCmd.Flags().StringVarP(&redactOutput, "output", "o", "", "Redacted heap dump to write (required)")
+	heapRedactCmd.Flags().StringVar(&redactMode, "mode", string(hprof.RedactZero), "How to redact arrays: zero or hash")
+	heapRedactCmd.Flags().StringVar(&redactKeyFile, "key-file", "", "File holding the key of --mode hash (random if empty)")
+	heapRedactCmd.Flags().BoolVar(&redactAllArrays, "all-arrays", false, "Redact every primitive array, not only byte[] and char[]")
+	heapRedactCmd.Flags().BoolVar(&redactCopyUnknown, "copy-unknown", false, "Copy records following unknown heap dump records unredacted instead of failing")
+	heapRedactCmd.MarkFlagRequired("output")
+	heapRedactCmd.RegisterFlagCompletionFunc("mode", cobra.FixedCompletions(
+		[]string{string(hprof.RedactZero), string(hprof.RedactHash)}, cobra.ShellCompDirectiveNoFileComp))
+}
+
+func runHeapRedact(cmd *cobra.Command, args []string) error {
+	log := GetLogger()
+
+	mode, err := hprof.ParseRedactMode(redactMode)
+	if err != nil {
+		return err
+	}
+	opts := hprof.RedactOptions{Mode: mode, AllArrays: redactAllArrays, CopyUnknown: redactCopyUnknown}
+	if redactKeyFile != "" {
+		if mode != hprof.RedactHash {
+			return fmt.Errorf("--key-file needs --mode hash")
+		}
+		key, err := os.ReadFile(redactKeyFile)
+		if err != nil {
+			return fmt.Errorf("failed to read key: %w", err)
+		}
+		if opts.Key = bytes.TrimSpace(key); len(opts.Key) == 0 {
+			return fmt.Errorf("empty key in %s", redactKeyFile)
+		}
+	}
+
+	in, out := args[0], redactOutput
+	if same, err := samePath(in, out); err != nil {
+		return err
+	} else if same {
+		return fmt.Errorf("the redacted dump cannot replace %s", in)
+	}
+	src, err := os.Open(in)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	// Write next to the output and rename, leaving no partial dump behind
+	tmp := out + ".tmp"
+	dst, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	stats, err := hprof.Redact(dst, src, opts)
+	if errors.Is(err, hprof.ErrBadTag) {
+		err = fmt.Errorf("%w (--copy-unknown copies such records unredacted)", err)
+	}
+	if closeErr := dst.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp, out)
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to redact %s: %w", in, err)
+	}
+
+	log.Info("Redacted %d arrays (%s) into %s", stats.Arrays, hprof.FormatBytesSize(stats.Bytes), out)
+	if stats.UnparsedBytes > 0 {
+		log.Warn("%s after unknown heap dump records were copied unredacted", hprof.FormatBytesSize(stats.UnparsedBytes))
+	}
+	return nil
+}
+
+// samePath reports whether two paths name the same file.
+func samePath(a, b string) (bool, error) {
+	absA, err := filepath.Abs(a)
+	if err != nil {
+		return false, err
+	}
+	absB, err := filepath.Abs(b)
+	if err != nil {
+		return false, err
+	}
+	if absA == absB {
+		return true, nil
+	}
+	infoA, errA := os.Stat(a)
+	infoB, errB := os.Stat(b)
+	return errA == nil && errB == nil && os.SameFile(infoA, infoB), nil
+}
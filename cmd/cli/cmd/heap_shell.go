@@ -0,0 +1,627 @@
+package cmd
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+
+	"github.com/perf-analysis/internal/parser/hprof"
+	"github.com/perf-analysis/pkg/utils"
+)
+
+// heapShellCmd explores a heap dump in an interactive shell
+var heapShellCmd = &cobra.Command{
+	Use:   "shell <dump.hprof | task-dir>",
+	Short: "Explore a heap dump in an interactive shell",
+	Long: `Open an interactive shell over a heap dump, for quick triage over SSH
+where the web UI is not practical.
+
+The argument is either an HPROF heap dump, which is parsed first, or the task
+directory of a heap dump analyzed before ("heap analyze"), which loads much
+faster. Class names complete with Tab; type "help" for the commands.`,
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: completeHeapDumps,
+	RunE:              runHeapShell,
+}
+
+func init() {
+	binName := BinName()
+	heapShellCmd.Example = `  # Parse a heap dump and explore it
+  ` + binName + ` heap shell ./heap.hprof
+
+  # Explore a heap dump analyzed before
+  ` + binName + ` heap shell ./output/my-heap
+
+  # Run commands from a script
+  echo "histogram 10" | ` + binName + ` heap shell ./output/my-heap`
+}
+
+func runHeapShell(cmd *cobra.Command, args []string) error {
+	log := GetLogger()
+	snapshot, err := loadShellSnapshot(args[0], log)
+	if err != nil {
+		return err
+	}
+
+	shell := newHeapShell(snapshot, cmd.OutOrStdout())
+	fmt.Fprintf(shell.out, "Loaded %d objects of %d classes. Type \"help\" for commands, Tab completes class names.\n",
+		snapshot.ObjectCount(), len(shell.classNames))
+	return shell.run(newLineReader(os.Stdin, shell.out, "heap> "))
+}
+
+// loadShellSnapshot loads the heap snapshot of a task directory, or parses a
+// heap dump.
+func loadShellSnapshot(path string, log utils.Logger) (*hprof.HeapSnapshot, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if info.IsDir() {
+		log.Info("Loading reference graph from %s...", path)
+		return hprof.LoadHeapSnapshot(path)
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	log.Info("Parsing heap dump %s...", path)
+	opts := hprof.DefaultParserOptions()
+	opts.Logger = log
+	opts.Verbose = verbose
+	result, err := hprof.NewParser(opts).Parse(context.Background(), file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse heap dump: %w", err)
+	}
+	if result.ObjectIndex != nil {
+		if err := result.ObjectIndex.SetSource(path); err != nil {
+			log.Warn("Field values are unavailable: %v", err)
+			result.ObjectIndex = nil
+		}
+	}
+	snapshot := result.Snapshot()
+	if snapshot == nil {
+		return nil, errors.New("heap dump has no reference graph")
+	}
+	return snapshot, nil
+}
+
+// heapShellCommand is a command of the heap shell.
+type heapShellCommand struct {
+	usage string
+	help  string
+	// completeClass completes the arguments with class names
+	completeClass bool
+	run           func(sh *heapShell, args []string, rest string) error
+}
+
+// errShellExit ends the shell.
+var errShellExit = errors.New("exit")
+
+// heapShell runs commands against a heap snapshot.
+type heapShell struct {
+	snapshot   *hprof.HeapSnapshot
+	out        io.Writer
+	classNames []string
+	histogram  []*hprof.ClassStats
+}
+
+// newHeapShell creates a shell for a snapshot.
+func newHeapShell(snapshot *hprof.HeapSnapshot, out io.Writer) *heapShell {
+	return &heapShell{snapshot: snapshot, out: out, classNames: snapshot.ClassNames()}
+}
+
+// heapShellCommands are the commands of the heap shell, by name.
+var heapShellCommands map[string]*heapShellCommand
+
+func init() {
+	heapShellCommands = map[string]*heapShellCommand{
+		"help": {
+			usage: "help",
+			help:  "List the commands",
+			run:   (*heapShell).help,
+		},
+		"info": {
+			usage: "info",
+			help:  "Summary of the heap",
+			run:   (*heapShell).info,
+		},
+		"histogram": {
+			usage: "histogram [n] [regex]",
+			help:  "Classes by shallow size, optionally filtered by a regular expression",
+			run:   (*heapShell).classHistogram,
+		},
+		"objects": {
+			usage:         "objects <class> [n]",
+			help:          "Biggest instances of a class by retained size",
+			completeClass: true,
+			run:           (*heapShell).objects,
+		},
+		"retainers": {
+			usage:         "retainers <class> [n]",
+			help:          "Classes and fields referencing instances of a class",
+			completeClass: true,
+			run:           (*heapShell).retainers,
+		},
+		"retainedby": {
+			usage:         "retainedby <class> [n]",
+			help:          "Classes whose instances dominate the instances of a class",
+			completeClass: true,
+			run:           (*heapShell).retainedBy,
+		},
+		"paths": {
+			usage: "paths <objid> [n]",
+			help:  "Paths from GC roots to an object",
+			run:   (*heapShell).paths,
+		},
+		"dominators": {
+			usage: "dominators <objid | root> [n]",
+			help:  "Objects dominated by an object, or the top of the dominator tree",
+			run:   (*heapShell).dominators,
+		},
+		"fields": {
+			usage: "fields <objid>",
+			help:  "Fields of an object",
+			run:   (*heapShell).fields,
+		},
+		"payload": {
+			usage: "payload <objid> [bytes] [offset]",
+			help:  "Raw contents of a primitive array or String as hex and text",
+			run:   (*heapShell).payload,
+		},
+		"extract": {
+			usage: "extract <objid> <file>",
+			help:  "Write the raw contents of a primitive array or String to a file",
+			run:   (*heapShell).extract,
+		},
+		"oql": {
+			usage:         "oql <query>",
+			help:          "Run an OQL query, e.g. oql SELECT * FROM java.lang.String s WHERE s.@retainedHeapSize > 1024",
+			completeClass: true,
+			run:           (*heapShell).oql,
+		},
+		"exit": {
+			usage: "exit",
+			help:  "Leave the shell (also quit or Ctrl-D)",
+			run:   func(*heapShell, []string, string) error { return errShellExit },
+		},
+	}
+	heapShellCommands["quit"] = heapShellCommands["exit"]
+}
+
+// run reads and executes commands until the input ends or exit is entered.
+func (sh *heapShell) run(reader *lineReader) error {
+	reader.complete = sh.complete
+	for {
+		line, err := reader.readLine()
+		if err == io.EOF {
+			fmt.Fprintln(sh.out)
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if err := sh.execute(line); err == errShellExit {
+			return nil
+		} else if err != nil {
+			fmt.Fprintf(sh.out, "Error: %v\n", err)
+		}
+	}
+}
+
+// execute runs one command line.
+func (sh *heapShell) execute(line string) error {
+	line = strings.TrimSpace(line)
+	if line == "" || strings.HasPrefix(line, "#") {
+		return nil
+	}
+	args := strings.Fields(line)
+	command, ok := heapShellCommands[strings.ToLower(args[0])]
+	if !ok {
+		return fmt.Errorf("unknown command %q, type \"help\" for commands", args[0])
+	}
+	rest := strings.TrimSpace(line[len(args[0]):])
+	return command.run(sh, args[1:], rest)
+}
+
+// complete completes command names and class name arguments.
+func (sh *heapShell) complete(line, word string) []string {
+	fields := strings.Fields(line)
+	if len(fields) == 0 || (len(fields) == 1 && word != "") {
+		var names []string
+		for name := range heapShellCommands {
+			if strings.HasPrefix(name, strings.ToLower(word)) {
+				names = append(names, name)
+			}
+		}
+		sort.Strings(names)
+		return names
+	}
+
+	command, ok := heapShellCommands[strings.ToLower(fields[0])]
+	if !ok || !command.completeClass {
+		return nil
+	}
+	i := sort.SearchStrings(sh.classNames, word)
+	var names []string
+	for ; i < len(sh.classNames) && strings.HasPrefix(sh.classNames[i], word); i++ {
+		names = append(names, sh.classNames[i])
+	}
+	return names
+}
+
+func (sh *heapShell) help(args []string, rest string) error {
+	names := make([]string, 0, len(heapShellCommands))
+	for name := range heapShellCommands {
+		if name != "quit" {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	w := tabwriter.NewWriter(sh.out, 0, 0, 2, ' ', 0)
+	for _, name := range names {
+		command := heapShellCommands[name]
+		fmt.Fprintf(w, "  %s\t%s\n", command.usage, command.help)
+	}
+	w.Flush()
+	fmt.Fprintln(sh.out, "Object IDs are hexadecimal, e.g. 0x7f3a2b10.")
+	return nil
+}
+
+func (sh *heapShell) info(args []string, rest string) error {
+	var totalSize, instances int64
+	for _, class := range sh.classHistogramStats() {
+		totalSize += class.TotalSize
+		instances += class.InstanceCount
+	}
+	fmt.Fprintf(sh.out, "Objects:   %d (%d reachable)\n", sh.snapshot.ObjectCount(), sh.snapshot.ReachableObjectCount())
+	fmt.Fprintf(sh.out, "Classes:   %d\n", len(sh.classNames))
+	fmt.Fprintf(sh.out, "Heap size: %s\n", hprof.FormatBytesSize(totalSize))
+	fmt.Fprintf(sh.out, "GC roots:  %d\n", len(sh.snapshot.GCRootsList()))
+	return nil
+}
+
+// classHistogramStats returns the class histogram, computed on first use.
+func (sh *heapShell) classHistogramStats() []*hprof.ClassStats {
+	if sh.histogram == nil {
+		sh.histogram = sh.snapshot.ClassHistogram()
+	}
+	return sh.histogram
+}
+
+func (sh *heapShell) classHistogram(args []string, rest string) error {
+	top := 20
+	var filter *regexp.Regexp
+	for _, arg := range args {
+		if n, err := strconv.Atoi(arg); err == nil {
+			top = n
+			continue
+		}
+		re, err := regexp.Compile(arg)
+		if err != nil {
+			return fmt.Errorf("invalid regular expression: %w", err)
+		}
+		filter = re
+	}
+	printClassHistogram(sh.out, sh.classHistogramStats(), top, filter)
+	return nil
+}
+
+func (sh *heapShell) objects(args []string, rest string) error {
+	if len(args) == 0 {
+		return errors.New("usage: objects <class> [n]")
+	}
+	top, err := optionalCount(args, 1, 20)
+	if err != nil {
+		return err
+	}
+	objects := sh.snapshot.BiggestObjectsByClass(args[0], top, "retained")
+	if len(objects) == 0 {
+		return fmt.Errorf("no instances of %s", args[0])
+	}
+	fmt.Fprintf(sh.out, "%-18s %14s %14s\n", "OBJECT", "SHALLOW", "RETAINED")
+	for _, obj := range objects {
+		fmt.Fprintf(sh.out, "%-18s %14s %14s\n", formatObjectID(obj.ObjectID),
+			hprof.FormatBytesSize(obj.ShallowSize), hprof.FormatBytesSize(obj.RetainedSize))
+	}
+	return nil
+}
+
+func (sh *heapShell) retainers(args []string, rest string) error {
+	if len(args) == 0 {
+		return errors.New("usage: retainers <class> [n]")
+	}
+	top, err := optionalCount(args, 1, 20)
+	if err != nil {
+		return err
+	}
+	retainers := sh.snapshot.ClassRetainers(args[0], top)
+	if retainers == nil {
+		return fmt.Errorf("no instances of %s", args[0])
+	}
+	fmt.Fprintf(sh.out, "%s: %d instances, %s shallow, %s retained\n\n", retainers.ClassName,
+		retainers.InstanceCount, hprof.FormatBytesSize(retainers.TotalSize), hprof.FormatBytesSize(retainers.RetainedSize))
+	fmt.Fprintf(sh.out, "%10s %14s %7s  %s\n", "REFS", "SIZE", "%", "RETAINER")
+	for _, r := range retainers.Retainers {
+		retainer := r.RetainerClass
+		if r.FieldName != "" {
+			retainer += "." + r.FieldName
+		}
+		fmt.Fprintf(sh.out, "%10d %14s %6.2f%%  %s\n", r.RetainedCount,
+			hprof.FormatBytesSize(r.RetainedSize), r.Percentage, retainer)
+	}
+	return nil
+}
+
+func (sh *heapShell) retainedBy(args []string, rest string) error {
+	if len(args) == 0 {
+		return errors.New("usage: retainedby <class> [n]")
+	}
+	top, err := optionalCount(args, 1, 20)
+	if err != nil {
+		return err
+	}
+	retainers := sh.snapshot.DominatorRetainers(args[0], top)
+	if retainers == nil {
+		return fmt.Errorf("no reachable instances of %s", args[0])
+	}
+	fmt.Fprintf(sh.out, "%s: %d of %d instances reachable, %s shallow, %s retained\n\n", retainers.ClassName,
+		retainers.AnalyzedObjects, retainers.InstanceCount, hprof.FormatBytesSize(retainers.TotalSize),
+		hprof.FormatBytesSize(retainers.RetainedSize))
+	fmt.Fprintf(sh.out, "%10s %10s %14s %7s  %s\n", "OBJECTS", "RETAINERS", "SIZE", "%", "RETAINED BY")
+	for _, r := range retainers.Retainers {
+		fmt.Fprintf(sh.out, "%10d %10d %14s %6.2f%%  %s\n", r.RetainedCount, r.Dominators,
+			hprof.FormatBytesSize(r.RetainedSize), r.Percentage, r.RetainerClass)
+	}
+	return nil
+}
+
+func (sh *heapShell) paths(args []string, rest string) error {
+	if len(args) == 0 {
+		return errors.New("usage: paths <objid> [n]")
+	}
+	objectID, err := sh.objectArg(args[0])
+	if err != nil {
+		return err
+	}
+	top, err := optionalCount(args, 1, 3)
+	if err != nil {
+		return err
+	}
+	paths := sh.snapshot.PathsToGCRoot(objectID, top, 20)
+	if len(paths) == 0 {
+		fmt.Fprintln(sh.out, "No path to a GC root found")
+		return nil
+	}
+	for i, path := range paths {
+		fmt.Fprintf(sh.out, "Path %d (%s):\n", i+1, path.RootType)
+		for depth, node := range path.Path {
+			field := ""
+			if node.FieldName != "" {
+				field = "." + node.FieldName + " -> "
+			}
+			fmt.Fprintf(sh.out, "  %s%s%s %s (%s)\n", strings.Repeat("  ", depth), field,
+				node.ClassName, formatObjectID(node.ObjectID), hprof.FormatBytesSize(node.Size))
+		}
+	}
+	return nil
+}
+
+func (sh *heapShell) dominators(args []string, rest string) error {
+	if len(args) == 0 {
+		return errors.New("usage: dominators <objid | root> [n]")
+	}
+	var children []uint64
+	if strings.EqualFold(args[0], "root") {
+		children = sh.snapshot.DominatorRoots()
+	} else {
+		objectID, err := sh.objectArg(args[0])
+		if err != nil {
+			return err
+		}
+		sh.printObject("", objectID)
+		children = sh.snapshot.DominatorChildren(objectID)
+	}
+	top, err := optionalCount(args, 1, 20)
+	if err != nil {
+		return err
+	}
+	for i, child := range children {
+		if i == top {
+			fmt.Fprintf(sh.out, "  ... and %d more\n", len(children)-i)
+			break
+		}
+		sh.printObject("  ", child)
+	}
+	return nil
+}
+
+func (sh *heapShell) fields(args []string, rest string) error {
+	if len(args) != 1 {
+		return errors.New("usage: fields <objid>")
+	}
+	objectID, err := sh.objectArg(args[0])
+	if err != nil {
+		return err
+	}
+	sh.printObject("", objectID)
+	w := tabwriter.NewWriter(sh.out, 0, 0, 2, ' ', 0)
+	for _, field := range sh.snapshot.ObjectFields(objectID) {
+		name := field.Name
+		if field.IsStatic {
+			name = "static " + name
+		}
+		value := ""
+		switch {
+		case field.RefID != 0:
+			value = fmt.Sprintf("%s %s (retained %s)", field.RefClass, formatObjectID(field.RefID),
+				hprof.FormatBytesSize(field.RetainedSize))
+			if field.StringValue != nil {
+				value += fmt.Sprintf(" %q", *field.StringValue)
+			}
+		case field.Value != nil:
+			value = fmt.Sprint(field.Value)
+		case field.Type == "object":
+			value = "null"
+		}
+		fmt.Fprintf(w, "  %s\t%s\t%s\n", field.Type, name, value)
+	}
+	w.Flush()
+	return nil
+}
+
+func (sh *heapShell) payload(args []string, rest string) error {
+	if len(args) == 0 || len(args) > 3 {
+		return errors.New("usage: payload <objid> [bytes] [offset]")
+	}
+	objectID, err := sh.objectArg(args[0])
+	if err != nil {
+		return err
+	}
+	maxBytes, err := optionalCount(args, 1, hprof.DefaultPayloadBytes)
+	if err != nil {
+		return err
+	}
+	if maxBytes > hprof.MaxPayloadBytes {
+		return fmt.Errorf("at most %d bytes can be shown, use extract for more", hprof.MaxPayloadBytes)
+	}
+	var offset int64
+	if len(args) > 2 {
+		if offset, err = strconv.ParseInt(args[2], 0, 64); err != nil || offset < 0 {
+			return fmt.Errorf("invalid offset %q", args[2])
+		}
+	}
+
+	payload, err := sh.snapshot.ObjectPayload(objectID, offset, maxBytes)
+	if err != nil {
+		return err
+	}
+	sh.printObject("", objectID)
+	if payload.ArrayID != payload.ObjectID {
+		fmt.Fprintf(sh.out, "value %s\n", formatObjectID(payload.ArrayID))
+	}
+	fmt.Fprintf(sh.out, "%s[], %s, bytes %d-%d\n\n", payload.ElementType, hprof.FormatBytesSize(payload.Size),
+		payload.Offset, payload.Offset+int64(len(payload.Data)))
+	fmt.Fprint(sh.out, hex.Dump(payload.Data))
+	if payload.Text != "" {
+		fmt.Fprintf(sh.out, "\n%s\n", payload.Text)
+	}
+	if payload.Truncated {
+		fmt.Fprintf(sh.out, "\n(truncated, continue at offset %d)\n", payload.Offset+int64(len(payload.Data)))
+	}
+	return nil
+}
+
+func (sh *heapShell) extract(args []string, rest string) error {
+	if len(args) != 2 {
+		return errors.New("usage: extract <objid> <file>")
+	}
+	objectID, err := sh.objectArg(args[0])
+	if err != nil {
+		return err
+	}
+	// Check the object first, so no file is left behind for bad ones
+	if _, err := sh.snapshot.ObjectPayload(objectID, 0, 1); err != nil {
+		return err
+	}
+
+	f, err := os.Create(args[1])
+	if err != nil {
+		return err
+	}
+	n, err := sh.snapshot.WriteObjectPayload(f, objectID)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(args[1])
+		return err
+	}
+	fmt.Fprintf(sh.out, "Wrote %s to %s\n", hprof.FormatBytesSize(n), args[1])
+	return nil
+}
+
+func (sh *heapShell) oql(args []string, rest string) error {
+	if rest == "" {
+		return errors.New("usage: oql <query>")
+	}
+	engine := hprof.NewQueryEngine(sh.snapshot)
+	engine.MaxRows = 100
+	result, err := engine.Execute(rest)
+	if err != nil {
+		return err
+	}
+	w := tabwriter.NewWriter(sh.out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, strings.Join(result.Columns, "\t"))
+	for _, row := range result.Rows {
+		values := make([]string, len(row.Values))
+		for i, v := range row.Values {
+			switch v := v.(type) {
+			case nil:
+				values[i] = "null"
+			case uint64:
+				values[i] = formatObjectID(v)
+			default:
+				values[i] = fmt.Sprint(v)
+			}
+		}
+		fmt.Fprintln(w, strings.Join(values, "\t"))
+	}
+	w.Flush()
+	if result.Truncated {
+		fmt.Fprintf(sh.out, "(%d of %d rows)\n", len(result.Rows), result.Total)
+	} else {
+		fmt.Fprintf(sh.out, "(%d rows)\n", result.Total)
+	}
+	return nil
+}
+
+// printObject prints the class and sizes of an object.
+func (sh *heapShell) printObject(indent string, objectID uint64) {
+	classID, _ := sh.snapshot.ObjectClassID(objectID)
+	fmt.Fprintf(sh.out, "%s%s %s  shallow %s, retained %s\n", indent, sh.snapshot.ClassName(classID),
+		formatObjectID(objectID), hprof.FormatBytesSize(sh.snapshot.ObjectSize(objectID)),
+		hprof.FormatBytesSize(sh.snapshot.RetainedSize(objectID)))
+}
+
+// objectArg parses an object ID argument and checks that the object exists.
+func (sh *heapShell) objectArg(arg string) (uint64, error) {
+	objectID, err := strconv.ParseUint(strings.TrimPrefix(strings.ToLower(arg), "0x"), 16, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid object ID %q", arg)
+	}
+	if _, ok := sh.snapshot.ObjectClassID(objectID); !ok {
+		return 0, fmt.Errorf("object %s not found", formatObjectID(objectID))
+	}
+	return objectID, nil
+}
+
+// optionalCount parses the optional count argument at index i.
+func optionalCount(args []string, i, defaultCount int) (int, error) {
+	if i >= len(args) {
+		return defaultCount, nil
+	}
+	n, err := strconv.Atoi(args[i])
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("invalid count %q", args[i])
+	}
+	return n, nil
+}
+
+// formatObjectID formats an object ID as shown by the web UI.
+func formatObjectID(id uint64) string {
+	return fmt.Sprintf("0x%x", id)
+}
@@ -0,0 +1,236 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// maxCompletionList caps the candidates listed when tab completion is ambiguous.
+const maxCompletionList = 60
+
+// lineReader reads the input lines of an interactive shell. On a terminal it
+// supports line editing, history and tab completion; otherwise, e.g. when
+// commands are piped in, it reads plain lines.
+type lineReader struct {
+	in     *os.File
+	out    io.Writer
+	reader *bufio.Reader
+	prompt string
+	// complete returns the candidates for the word being typed; line is the
+	// text before the cursor
+	complete func(line, word string) []string
+	history  []string
+}
+
+// newLineReader creates a line reader for in, echoing to out.
+func newLineReader(in *os.File, out io.Writer, prompt string) *lineReader {
+	return &lineReader{in: in, out: out, reader: bufio.NewReader(in), prompt: prompt}
+}
+
+// readLine reads the next line. It returns io.EOF at the end of the input or
+// when Ctrl-D is pressed on an empty line.
+func (r *lineReader) readLine() (string, error) {
+	restore, err := makeRaw(int(r.in.Fd()))
+	if err != nil {
+		return r.readPlainLine()
+	}
+	defer restore()
+
+	line, err := r.editLine()
+	fmt.Fprint(r.out, "\r\n")
+	if err == nil && strings.TrimSpace(line) != "" {
+		if n := len(r.history); n == 0 || r.history[n-1] != line {
+			r.history = append(r.history, line)
+		}
+	}
+	return line, err
+}
+
+// readPlainLine reads a line without editing support.
+func (r *lineReader) readPlainLine() (string, error) {
+	fmt.Fprint(r.out, r.prompt)
+	line, err := r.reader.ReadString('\n')
+	if err == io.EOF && line != "" {
+		err = nil
+	}
+	return strings.TrimRight(line, "\r\n"), err
+}
+
+// editLine reads a line from a terminal in raw mode.
+func (r *lineReader) editLine() (string, error) {
+	var line []rune
+	cursor := 0
+	historyPos := len(r.history)
+
+	redraw := func() {
+		fmt.Fprintf(r.out, "\r%s%s\x1b[K", r.prompt, string(line))
+		if back := len(line) - cursor; back > 0 {
+			fmt.Fprintf(r.out, "\x1b[%dD", back)
+		}
+	}
+	setLine := func(s string) {
+		line = []rune(s)
+		cursor = len(line)
+		redraw()
+	}
+	redraw()
+
+	for {
+		c, _, err := r.reader.ReadRune()
+		if err != nil {
+			return "", err
+		}
+		switch c {
+		case '\r', '\n':
+			return string(line), nil
+		case 3: // Ctrl-C drops the line
+			fmt.Fprint(r.out, "^C")
+			return "", nil
+		case 4: // Ctrl-D
+			if len(line) == 0 {
+				return "", io.EOF
+			}
+			if cursor < len(line) {
+				line = append(line[:cursor], line[cursor+1:]...)
+				redraw()
+			}
+		case 127, 8: // Backspace
+			if cursor > 0 {
+				line = append(line[:cursor-1], line[cursor:]...)
+				cursor--
+				redraw()
+			}
+		case 1: // Ctrl-A
+			cursor = 0
+			redraw()
+		case 5: // Ctrl-E
+			cursor = len(line)
+			redraw()
+		case 21: // Ctrl-U
+			line = line[cursor:]
+			cursor = 0
+			redraw()
+		case 11: // Ctrl-K
+			line = line[:cursor]
+			redraw()
+		case 12: // Ctrl-L
+			fmt.Fprint(r.out, "\x1b[H\x1b[2J")
+			redraw()
+		case '\t':
+			line, cursor = r.completeLine(line, cursor)
+			redraw()
+		case 27: // Escape sequences: arrows, Home, End, Delete
+			seq := r.readEscape()
+			switch seq {
+			case "[A": // Up
+				if historyPos > 0 {
+					historyPos--
+					setLine(r.history[historyPos])
+				}
+			case "[B": // Down
+				if historyPos < len(r.history)-1 {
+					historyPos++
+					setLine(r.history[historyPos])
+				} else if historyPos < len(r.history) {
+					historyPos = len(r.history)
+					setLine("")
+				}
+			case "[C":
+				if cursor < len(line) {
+					cursor++
+					redraw()
+				}
+			case "[D":
+				if cursor > 0 {
+					cursor--
+					redraw()
+				}
+			case "[H", "OH", "[1~":
+				cursor = 0
+				redraw()
+			case "[F", "OF", "[4~":
+				cursor = len(line)
+				redraw()
+			case "[3~":
+				if cursor < len(line) {
+					line = append(line[:cursor], line[cursor+1:]...)
+					redraw()
+				}
+			}
+		default:
+			if unicode.IsPrint(c) {
+				line = append(line[:cursor], append([]rune{c}, line[cursor:]...)...)
+				cursor++
+				redraw()
+			}
+		}
+	}
+}
+
+// readEscape reads the rest of an escape sequence, e.g. "[A" for the up arrow.
+func (r *lineReader) readEscape() string {
+	var seq []rune
+	for len(seq) < 8 {
+		c, _, err := r.reader.ReadRune()
+		if err != nil {
+			break
+		}
+		seq = append(seq, c)
+		// Sequences end with a letter or '~', after the introducer
+		if len(seq) > 1 && (unicode.IsLetter(c) || c == '~') {
+			break
+		}
+	}
+	return string(seq)
+}
+
+// completeLine completes the word before the cursor. A single candidate
+// replaces the word; several are extended to their common prefix, or listed
+// when there is nothing to extend.
+func (r *lineReader) completeLine(line []rune, cursor int) ([]rune, int) {
+	if r.complete == nil {
+		return line, cursor
+	}
+	before := string(line[:cursor])
+	start := strings.LastIndexAny(before, " \t") + 1
+	word := before[start:]
+	candidates := r.complete(before, word)
+	if len(candidates) == 0 {
+		return line, cursor
+	}
+
+	replacement := commonPrefix(candidates)
+	if len(candidates) == 1 {
+		replacement += " "
+	} else if replacement == word {
+		fmt.Fprint(r.out, "\r\n")
+		for i, candidate := range candidates {
+			if i == maxCompletionList {
+				fmt.Fprintf(r.out, "... and %d more\r\n", len(candidates)-i)
+				break
+			}
+			fmt.Fprintf(r.out, "%s\r\n", candidate)
+		}
+		return line, cursor
+	}
+
+	completed := []rune(before[:start] + replacement)
+	return append(completed, line[cursor:]...), len(completed)
+}
+
+// commonPrefix returns the longest common prefix of the strings.
+func commonPrefix(values []string) string {
+	prefix := values[0]
+	for _, v := range values[1:] {
+		for !strings.HasPrefix(v, prefix) {
+			_, size := utf8.DecodeLastRuneInString(prefix)
+			prefix = prefix[:len(prefix)-size]
+		}
+	}
+	return prefix
+}
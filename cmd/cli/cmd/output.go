@@ -0,0 +1,77 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/perf-analysis/pkg/writer"
+)
+
+// Output formats of the analysis commands
+const (
+	formatText   = "text"
+	formatJSON   = "json"
+	formatNDJSON = "ndjson"
+)
+
+var (
+	// Output format flag, shared by the analysis commands
+	outputFormat string
+)
+
+// addOutputFormatFlag adds --format to c. Besides text, json and ndjson, the
+// command accepts the extra formats, e.g. csv and tsv for tables.
+func addOutputFormatFlag(c *cobra.Command, extra ...string) {
+	formats := append([]string{formatText, formatJSON, formatNDJSON}, extra...)
+	c.Flags().StringVar(&outputFormat, "format", formatText,
+		fmt.Sprintf("Output format: %s", strings.Join(formats, ", ")))
+	c.Flags().SetAnnotation("format", "formats", formats)
+	c.RegisterFlagCompletionFunc("format", cobra.FixedCompletions(formats, cobra.ShellCompDirectiveNoFileComp))
+}
+
+// checkOutputFormat validates the --format of c.
+func checkOutputFormat(c *cobra.Command) error {
+	flag := c.Flags().Lookup("format")
+	if flag == nil {
+		return nil
+	}
+	formats := flag.Annotations["formats"]
+	if !slices.Contains(formats, outputFormat) {
+		return fmt.Errorf("unknown output format: %q (valid: %s)", outputFormat, strings.Join(formats, ", "))
+	}
+	return nil
+}
+
+// structuredOutput reports whether results are printed as JSON or NDJSON.
+// The log then only reports warnings and errors, on stderr, so stdout can be
+// piped to tools like jq.
+func structuredOutput() bool {
+	return outputFormat == formatJSON || outputFormat == formatNDJSON
+}
+
+// writeResult prints a single result as indented JSON, or as one line for
+// NDJSON.
+func writeResult(out io.Writer, v any) error {
+	if outputFormat == formatNDJSON {
+		return writer.NewJSONWriter[any]().Write(v, out)
+	}
+	return writer.NewPrettyJSONWriter[any]().Write(v, out)
+}
+
+// writeRows prints v as indented JSON, or its rows as NDJSON, one per line.
+func writeRows[T any](out io.Writer, v any, rows []T) error {
+	if outputFormat != formatNDJSON {
+		return writer.NewPrettyJSONWriter[any]().Write(v, out)
+	}
+	w := writer.NewJSONWriter[T]()
+	for _, row := range rows {
+		if err := w.Write(row, out); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,165 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+
+	"github.com/perf-analysis/internal/webui"
+)
+
+var (
+	// Report flags
+	reportJSON bool
+	reportHTML string
+	reportTop  int
+)
+
+// reportCmd prints the summary of an analysis task
+var reportCmd = &cobra.Command{
+	Use:     "report <task-dir>",
+	GroupID: groupResults,
+	Short:   "Print the summary of an analysis",
+	Long: `Print the summary of an analysis task directory: how it was analyzed,
+the number of records and the optimization suggestions.
+
+With --html the analysis is rendered into a single self-contained HTML file
+instead: the overview, leak suspects or suggestions, top classes and biggest
+objects of heap dumps, and the flame graph of profiles. The file embeds its
+styles, script and data, so it can be attached to a ticket and opened
+without running the server.`,
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: completeTaskDirs,
+	RunE:              runReport,
+}
+
+func init() {
+	rootCmd.AddCommand(reportCmd)
+
+	binName := BinName()
+	reportCmd.Example = `  # Print the summary of an analysis
+  ` + binName + ` report ./output/my-analysis
+
+  # Print the full stored summary as JSON
+  ` + binName + ` report ./output/my-analysis --json
+
+  # Render a standalone HTML report
+  ` + binName + ` report ./output/my-analysis --html report.html
+
+  # Exit with code 2 when the hottest function takes more than 30% of the CPU
+  ` + binName + ` report ./output/my-analysis --fail-if-top-func-self '>30%'`
+
+	reportCmd.Flags().BoolVar(&reportJSON, "json", false, "Print the full stored summary as JSON")
+	reportCmd.Flags().StringVar(&reportHTML, "html", "", "Write a self-contained HTML report to this file")
+	reportCmd.Flags().IntVarP(&reportTop, "top", "n", 50, "Number of classes and biggest objects in the HTML report")
+	addThresholdFlags(reportCmd)
+	reportCmd.MarkFlagsMutuallyExclusive("json", "html")
+	reportCmd.MarkFlagFilename("html", "html")
+}
+
+// taskSummary mirrors the summary.json written by analyze.
+type taskSummary struct {
+	TaskUUID     string            `json:"task_uuid"`
+	TotalRecords int64             `json:"total_records"`
+	Metadata     *AnalysisMetadata `json:"metadata"`
+	Suggestions  []struct {
+		Suggestion string `json:"suggestion"`
+		Func       string `json:"func"`
+	} `json:"suggestions"`
+	OutputFiles []struct {
+		Name      string `json:"name"`
+		LocalPath string `json:"local_path"`
+	} `json:"output_files"`
+}
+
+func runReport(cmd *cobra.Command, args []string) error {
+	taskDir := args[0]
+	if err := printReport(cmd, taskDir); err != nil {
+		return err
+	}
+	violations, err := checkThresholds(taskDir)
+	if err != nil {
+		return err
+	}
+	return thresholdFailure(cmd, violations)
+}
+
+// printReport prints the summary of taskDir, or writes it as HTML.
+func printReport(cmd *cobra.Command, taskDir string) error {
+	if reportHTML != "" {
+		return writeHTMLReport(taskDir, reportHTML)
+	}
+
+	data, err := os.ReadFile(filepath.Join(taskDir, "summary.json"))
+	if os.IsNotExist(err) {
+		return fmt.Errorf("no summary in %s: not an analysis task directory", taskDir)
+	}
+	if err != nil {
+		return err
+	}
+
+	out := cmd.OutOrStdout()
+	if reportJSON {
+		_, err := out.Write(data)
+		return err
+	}
+
+	var summary taskSummary
+	if err := json.Unmarshal(data, &summary); err != nil {
+		return fmt.Errorf("invalid summary in %s: %w", taskDir, err)
+	}
+
+	fmt.Fprintf(out, "Task:          %s\n", summary.TaskUUID)
+	if m := summary.Metadata; m != nil {
+		fmt.Fprintf(out, "Analysis mode: %s (%s)\n", m.Mode, m.ModeDesc)
+		fmt.Fprintf(out, "Profile:       %s\n", m.Profile)
+		fmt.Fprintf(out, "Input file:    %s\n", m.InputFile)
+		fmt.Fprintf(out, "Created at:    %s\n", m.CreatedAt)
+		fmt.Fprintf(out, "Analysis time: %d ms\n", m.AnalysisTimeMs)
+	}
+	fmt.Fprintf(out, "Records:       %d\n", summary.TotalRecords)
+
+	if len(summary.Suggestions) > 0 {
+		fmt.Fprintf(out, "\nSuggestions:\n")
+		for _, s := range summary.Suggestions {
+			fmt.Fprintf(out, "  - %s\n", s.Suggestion)
+		}
+	}
+	if len(summary.OutputFiles) > 0 {
+		fmt.Fprintf(out, "\nOutput files:\n")
+		for _, f := range summary.OutputFiles {
+			fmt.Fprintf(out, "  %-20s %s\n", f.Name, f.LocalPath)
+		}
+	}
+	return nil
+}
+
+// writeHTMLReport renders the analysis in taskDir into a standalone HTML file.
+func writeHTMLReport(taskDir, path string) error {
+	report, err := webui.BuildStaticReport(context.Background(), taskDir, webui.StaticReportOptions{TopN: reportTop})
+	if err != nil {
+		return err
+	}
+	return writeStaticReportFile(report, path)
+}
+
+// writeStaticReportFile renders a static report to the HTML file path.
+func writeStaticReportFile(report *webui.StaticReport, path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := webui.WriteStaticReport(f, report); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	GetLogger().Info("Report written to %s", path)
+	return nil
+}
@@ -1,7 +1,10 @@
 package cmd
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"time"
@@ -9,13 +12,21 @@ import (
 	"github.com/spf13/cobra"
 
 	"github.com/perf-analysis/pkg/pprof"
+	"github.com/perf-analysis/pkg/telemetry"
 	"github.com/perf-analysis/pkg/utils"
 )
 
+// Help groups of the subcommands
+const (
+	groupAnalysis = "analysis"
+	groupResults  = "results"
+)
+
 var (
 	// Global flags
-	verbose bool
-	logger  utils.Logger
+	verbose   bool
+	logFormat string
+	logger    utils.Logger
 
 	// Pprof flags
 	pprofEnabled     bool
@@ -29,6 +40,9 @@ var (
 
 	// Pprof collector
 	pprofCollector *pprof.Collector
+
+	// Flushes the spans of the analysis, when tracing is enabled with OTEL_ENABLED
+	telemetryShutdown telemetry.ShutdownFunc
 )
 
 // rootCmd represents the base command
@@ -41,12 +55,16 @@ It supports multiple profiler types including perf, async-profiler (alloc mode),
 and pprof. The tool generates flame graphs, call graphs, and provides
 performance optimization suggestions.`,
 	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
-		// Setup logger based on verbose flag
-		logLevel := utils.LevelInfo
-		if verbose {
-			logLevel = utils.LevelDebug
+		if _, err := utils.ParseLogFormat(logFormat); err != nil {
+			return err
+		}
+		// Setup logger based on verbose flag. With JSON output stdout is
+		// reserved for the results, so only problems are logged, to stderr.
+		if structuredOutput() {
+			logger = newLogger(utils.LevelWarn, os.Stderr)
+		} else {
+			logger = newLogger(logLevel(), os.Stdout)
 		}
-		logger = utils.NewDefaultLogger(logLevel, os.Stdout)
 
 		// Initialize pprof if enabled
 		if pprofEnabled {
@@ -68,6 +86,13 @@ performance optimization suggestions.`,
 			logger.Info("pprof collection started (mode: %s, dir: %s)", cfg.Mode, cfg.OutputDir)
 		}
 
+		// Initialize OpenTelemetry (configured by the standard OTEL_* variables)
+		shutdown, err := telemetry.Init(cmd.Context())
+		if err != nil {
+			logger.Warn("Failed to initialize telemetry: %v", err)
+		}
+		telemetryShutdown = shutdown
+
 		return nil
 	},
 	PersistentPostRunE: func(cmd *cobra.Command, args []string) error {
@@ -86,14 +111,29 @@ performance optimization suggestions.`,
 // Execute adds all child commands to the root command and sets flags appropriately.
 func Execute() {
 	err := rootCmd.Execute()
+	if telemetryShutdown != nil {
+		if err := telemetryShutdown(context.Background()); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to shutdown telemetry: %v\n", err)
+		}
+	}
 	if err != nil {
-		os.Exit(1)
+		os.Exit(exitCode(err))
+	}
+}
+
+// exitCode returns the exit code of a failed command.
+func exitCode(err error) int {
+	var thresholdErr *thresholdError
+	if errors.As(err, &thresholdErr) {
+		return exitThresholdExceeded
 	}
+	return 1
 }
 
 func init() {
 	// Global flags
 	rootCmd.PersistentFlags().BoolVarP(&verbose, "verbose", "v", false, "Enable verbose output")
+	rootCmd.PersistentFlags().StringVar(&logFormat, "log-format", "text", "Log format: text or json")
 
 	// Pprof flags
 	rootCmd.PersistentFlags().BoolVar(&pprofEnabled, "pprof", false, "Enable pprof performance profiling")
@@ -105,13 +145,19 @@ func init() {
 	rootCmd.PersistentFlags().IntVar(&pprofCPURate, "pprof-cpu-rate", 100, "CPU profiling rate in Hz")
 	rootCmd.PersistentFlags().StringVar(&pprofAddr, "pprof-addr", ":6060", "HTTP listen address for http mode")
 
+	// Command groups shown in help
+	rootCmd.AddGroup(
+		&cobra.Group{ID: groupAnalysis, Title: "Analysis Commands:"},
+		&cobra.Group{ID: groupResults, Title: "Result Commands:"},
+	)
+
 	// Set dynamic example using actual binary name
 	binName := BinName()
 	rootCmd.Example = `  # Analyze Java CPU profiling data
-  ` + binName + ` analyze -i ./test/origin.data -t java -p perf
+  ` + binName + ` analyze -i ./test/origin.data -m java-cpu
 
   # Analyze memory allocation data
-  ` + binName + ` analyze -i ./alloc.data -t java -p async_alloc
+  ` + binName + ` analyze -i ./alloc.data -m java-alloc
 
   # Start web server to view results
   ` + binName + ` serve -d ./output -p 8080
@@ -119,11 +165,23 @@ func init() {
   # Analyze and immediately view results
   ` + binName + ` analyze -i ./test/origin.data --serve
 
+  # Compare two analyzed heap dumps
+  ` + binName + ` diff heap ./output/before ./output/after
+
   # Enable pprof profiling during analysis
   ` + binName + ` analyze -i ./test/origin.data --pprof --pprof-profiles cpu,heap
 
   # Use HTTP mode for pprof (useful for long-running operations)
-  ` + binName + ` analyze -i ./test/origin.data --pprof --pprof-mode http --pprof-addr :6060`
+  ` + binName + ` analyze -i ./test/origin.data --pprof --pprof-mode http --pprof-addr :6060
+
+  # Enable shell completion (bash; see "completion --help" for other shells)
+  source <(` + binName + ` completion bash)`
+
+	rootCmd.RegisterFlagCompletionFunc("log-format", cobra.FixedCompletions(
+		[]string{"text", "json"}, cobra.ShellCompDirectiveNoFileComp))
+	rootCmd.RegisterFlagCompletionFunc("pprof-mode", cobra.FixedCompletions(
+		[]string{"file", "http"}, cobra.ShellCompDirectiveNoFileComp))
+	rootCmd.MarkPersistentFlagDirname("pprof-dir")
 }
 
 // GetLogger returns the configured logger
@@ -131,6 +189,23 @@ func GetLogger() utils.Logger {
 	return logger
 }
 
+// newLogger creates a logger in the format selected by the log-format flag
+func newLogger(level utils.LogLevel, w io.Writer) *utils.DefaultLogger {
+	l := utils.NewDefaultLogger(level, w)
+	if format, err := utils.ParseLogFormat(logFormat); err == nil {
+		l.SetFormat(format)
+	}
+	return l
+}
+
+// logLevel returns the log level selected by the verbose flag
+func logLevel() utils.LogLevel {
+	if verbose {
+		return utils.LevelDebug
+	}
+	return utils.LevelInfo
+}
+
 // BinName returns the base name of the current executable
 func BinName() string {
 	return filepath.Base(os.Args[0])
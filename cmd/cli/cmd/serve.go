@@ -4,13 +4,16 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
 	"syscall"
 
 	"github.com/spf13/cobra"
+	"google.golang.org/grpc"
 
+	"github.com/perf-analysis/internal/grpcapi"
 	"github.com/perf-analysis/internal/webui"
 	"github.com/perf-analysis/pkg/utils"
 )
@@ -19,12 +22,26 @@ var (
 	// Serve command flags
 	dataDir string
 	port    int
+
+	// Snapshot cache flags
+	cacheMaxSnapshots int
+	cacheMaxMB        int64
+
+	// gRPC API flags
+	grpcPort int
+
+	// Access control flags
+	authUser     string
+	authPassword string
+	authToken    string
+	readOnly     bool
 )
 
 // serveCmd represents the serve command
 var serveCmd = &cobra.Command{
-	Use:   "serve",
-	Short: "Start web server to view analysis results",
+	Use:     "serve",
+	GroupID: groupResults,
+	Short:   "Start web server to view analysis results",
 	Long: `Start an HTTP server to interactively view and explore analysis results.
 
 The serve command starts a lightweight web server that provides:
@@ -50,10 +67,27 @@ func init() {
   ` + binName + ` serve -d ./my-output -p 9090
 
   # Start server with verbose logging
-  ` + binName + ` serve -d ./output -v`
+  ` + binName + ` serve -d ./output -v
+
+  # Keep up to 5 heap snapshots resident, within 8GB
+  ` + binName + ` serve --cache-max-snapshots 5 --cache-max-mb 8192
+
+  # Also expose the heap analysis gRPC API on port 9091
+  ` + binName + ` serve --grpc-port 9091
+
+  # Share on a team host: token auth (open the UI with ?token=...) and no recomputation
+  ` + webui.EnvAuthToken + `=s3cret ` + binName + ` serve --read-only`
 
 	serveCmd.Flags().StringVarP(&dataDir, "data-dir", "d", "./output", "Data directory containing analysis results")
 	serveCmd.Flags().IntVarP(&port, "port", "p", 8080, "Port for web server")
+	serveCmd.Flags().IntVar(&cacheMaxSnapshots, "cache-max-snapshots", webui.DefaultSnapshotManagerConfig().MaxSnapshots, "Maximum number of heap snapshots kept in memory (0 = unlimited)")
+	serveCmd.Flags().IntVar(&grpcPort, "grpc-port", 0, "Port for the heap analysis gRPC API, which takes the auth credentials as authorization metadata (0 = disabled)")
+	serveCmd.Flags().Int64Var(&cacheMaxMB, "cache-max-mb", 0, "Maximum estimated memory of cached heap snapshots in MB (0 = unlimited)")
+	serveCmd.Flags().StringVar(&authUser, "auth-user", "", "Require HTTP basic auth with this user (env "+webui.EnvAuthUser+")")
+	serveCmd.Flags().StringVar(&authPassword, "auth-password", "", "Basic auth password (env "+webui.EnvAuthPassword+")")
+	serveCmd.Flags().StringVar(&authToken, "auth-token", "", "Require this bearer token, or ?token= in the browser (env "+webui.EnvAuthToken+")")
+	serveCmd.Flags().BoolVar(&readOnly, "read-only", false, "Disable endpoints that trigger recomputation or modify data, e.g. OQL queries, cache flushes and task metadata edits (env "+webui.EnvReadOnly+")")
+	serveCmd.MarkFlagDirname("data-dir")
 }
 
 func runServe(cmd *cobra.Command, args []string) error {
@@ -68,7 +102,59 @@ func startServeMode(dataDirectory string, serverPort int, log utils.Logger) erro
 		return fmt.Errorf("data directory not found: %s", dataDirectory)
 	}
 
+	return runServer(newServeServer(dataDirectory, serverPort, log), dataDirectory, serverPort, log)
+}
+
+// newServeServer creates the web UI server with the snapshot cache flags applied.
+func newServeServer(dataDirectory string, serverPort int, log utils.Logger) *webui.Server {
 	server := webui.NewServer(dataDirectory, serverPort, log)
+	server.SetSnapshotCacheConfig(webui.SnapshotManagerConfig{
+		MaxSnapshots: cacheMaxSnapshots,
+		MaxBytes:     cacheMaxMB << 20,
+	})
+	server.SetAccessConfig(accessConfig())
+	return server
+}
+
+// accessConfig merges the access control flags over the environment.
+func accessConfig() webui.AccessConfig {
+	config := webui.AccessConfigFromEnv()
+	if authUser != "" {
+		config.Username = authUser
+	}
+	if authPassword != "" {
+		config.Password = authPassword
+	}
+	if authToken != "" {
+		config.Token = authToken
+	}
+	if readOnly {
+		config.ReadOnly = true
+	}
+	return config
+}
+
+// runServer serves the web UI, and the gRPC API when enabled, until the
+// process is interrupted.
+func runServer(server *webui.Server, dataDirectory string, serverPort int, log utils.Logger) error {
+	// The gRPC API shares the web UI's snapshot cache
+	var grpcServer *grpc.Server
+	if grpcPort > 0 {
+		lis, err := net.Listen("tcp", fmt.Sprintf(":%d", grpcPort))
+		if err != nil {
+			return fmt.Errorf("failed to listen on gRPC port: %w", err)
+		}
+		// The gRPC API enforces the same authentication and read-only mode
+		api := grpcapi.NewServer(dataDirectory, server.Snapshots(), log)
+		api.SetAccessConfig(server.AccessConfig())
+		grpcServer = grpc.NewServer(api.ServerOptions()...)
+		api.Register(grpcServer)
+		go func() {
+			if err := grpcServer.Serve(lis); err != nil {
+				log.Error("gRPC server error: %v", err)
+			}
+		}()
+	}
 
 	// Handle graceful shutdown
 	sigChan := make(chan os.Signal, 1)
@@ -77,6 +163,9 @@ func startServeMode(dataDirectory string, serverPort int, log utils.Logger) erro
 	go func() {
 		<-sigChan
 		log.Info("\nShutting down server...")
+		if grpcServer != nil {
+			grpcServer.GracefulStop()
+		}
 		ctx, cancel := context.WithTimeout(context.Background(), 5)
 		defer cancel()
 		server.Shutdown(ctx)
@@ -90,6 +179,9 @@ func startServeMode(dataDirectory string, serverPort int, log utils.Logger) erro
 	log.Info("║                                                        ║")
 	log.Info("║  Open in browser: http://localhost:%-5d               ║", serverPort)
 	log.Info("║  Data directory:  %-36s ║", truncateString(dataDirectory, 36))
+	if grpcPort > 0 {
+		log.Info("║  gRPC API:        localhost:%-26d ║", grpcPort)
+	}
 	log.Info("║                                                        ║")
 	log.Info("║  Press Ctrl+C to stop                                  ║")
 	log.Info("╚════════════════════════════════════════════════════════╝")
@@ -0,0 +1,202 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/perf-analysis/internal/parser/hprof"
+	"github.com/perf-analysis/pkg/utils"
+)
+
+// exitThresholdExceeded is the exit code when a --fail-if threshold is
+// exceeded, to tell regressions apart from failures (exit code 1).
+const exitThresholdExceeded = 2
+
+var (
+	// Threshold flags
+	failIfClassRetained []string
+	failIfTopFuncSelf   []string
+)
+
+// addThresholdFlags adds the --fail-if flags gating on analysis results.
+func addThresholdFlags(c *cobra.Command) {
+	c.Flags().StringArrayVar(&failIfClassRetained, "fail-if-class-retained", nil,
+		`Fail when classes retain more than a size, e.g. "com.example.Cache>500MB" (* matches any characters)`)
+	c.Flags().StringArrayVar(&failIfTopFuncSelf, "fail-if-top-func-self", nil,
+		`Fail when the top function's self time exceeds a share, e.g. ">30%", or that of matching functions, e.g. "*.parseJson*>10%"`)
+}
+
+// thresholdError reports exceeded thresholds.
+type thresholdError struct {
+	violations []string
+}
+
+func (e *thresholdError) Error() string {
+	return fmt.Sprintf("%d threshold(s) exceeded:\n  %s", len(e.violations), strings.Join(e.violations, "\n  "))
+}
+
+// threshold is a limit on an analysis result, parsed from <subject>>[=]<limit>.
+type threshold struct {
+	spec    string
+	subject *regexp.Regexp // nil when the spec has no subject
+	orEqual bool
+	limit   string
+}
+
+// parseThreshold splits a threshold spec. The subject may contain '>', e.g.
+// C++ templates, so the limit follows the last one.
+func parseThreshold(spec string) (*threshold, error) {
+	i := strings.LastIndex(spec, ">")
+	if i < 0 {
+		return nil, fmt.Errorf("invalid threshold %q: expected <subject>><limit>", spec)
+	}
+	t := &threshold{spec: spec, limit: strings.TrimSpace(spec[i+1:])}
+	if rest, ok := strings.CutPrefix(t.limit, "="); ok {
+		t.orEqual, t.limit = true, strings.TrimSpace(rest)
+	}
+	if subject := strings.TrimSpace(spec[:i]); subject != "" {
+		pattern := strings.ReplaceAll(regexp.QuoteMeta(subject), `\*`, ".*")
+		t.subject = regexp.MustCompile("^" + pattern + "$")
+	}
+	return t, nil
+}
+
+// exceeds reports whether value is above the limit.
+func (t *threshold) exceeds(value, limit float64) bool {
+	return value > limit || (t.orEqual && value == limit)
+}
+
+// thresholdFailure returns the error failing cmd for the exceeded
+// thresholds, if any.
+func thresholdFailure(cmd *cobra.Command, violations []string) error {
+	if len(violations) == 0 {
+		return nil
+	}
+	// A regression, not a usage mistake
+	cmd.SilenceUsage = true
+	return &thresholdError{violations: violations}
+}
+
+// checkThresholds evaluates the --fail-if flags against the results in
+// taskDir. It returns the exceeded thresholds, or an error if they can't be
+// evaluated, e.g. class thresholds on a CPU profile.
+func checkThresholds(taskDir string) ([]string, error) {
+	var violations []string
+	if len(failIfClassRetained) > 0 {
+		histogram, err := loadClassHistogram(taskDir)
+		if err != nil {
+			return nil, fmt.Errorf("--fail-if-class-retained: %w", err)
+		}
+		for _, spec := range failIfClassRetained {
+			found, err := checkClassRetained(spec, histogram.Classes)
+			if err != nil {
+				return nil, err
+			}
+			violations = append(violations, found...)
+		}
+	}
+	if len(failIfTopFuncSelf) > 0 {
+		funcs, err := loadTopFunctions(taskDir)
+		if err != nil {
+			return nil, fmt.Errorf("--fail-if-top-func-self: %w", err)
+		}
+		for _, spec := range failIfTopFuncSelf {
+			found, err := checkTopFuncSelf(spec, funcs)
+			if err != nil {
+				return nil, err
+			}
+			violations = append(violations, found...)
+		}
+	}
+	return violations, nil
+}
+
+// checkClassRetained checks a class retained size threshold.
+func checkClassRetained(spec string, classes []*hprof.ClassStats) ([]string, error) {
+	t, err := parseThreshold(spec)
+	if err != nil {
+		return nil, err
+	}
+	if t.subject == nil {
+		return nil, fmt.Errorf("invalid threshold %q: missing class name", spec)
+	}
+	limit, err := utils.ParseByteSize(t.limit)
+	if err != nil {
+		return nil, fmt.Errorf("invalid threshold %q: %w", spec, err)
+	}
+
+	var violations []string
+	for _, class := range classes {
+		if t.subject.MatchString(class.ClassName) && t.exceeds(float64(class.RetainedSize), float64(limit)) {
+			violations = append(violations, fmt.Sprintf("%s: class %s retains %s", spec,
+				class.ClassName, hprof.FormatBytesSize(class.RetainedSize)))
+		}
+	}
+	return violations, nil
+}
+
+// topFunction is an entry of the top_items of a profile summary.
+type topFunction struct {
+	Name       string  `json:"name"`
+	Percentage float64 `json:"percentage"`
+}
+
+// loadTopFunctions reads the functions with the most self time from the
+// summary of a profile analysis.
+func loadTopFunctions(taskDir string) ([]topFunction, error) {
+	data, err := os.ReadFile(filepath.Join(taskDir, "summary.json"))
+	if err != nil {
+		return nil, err
+	}
+	var summary struct {
+		TopItems []topFunction `json:"top_items"`
+	}
+	if err := json.Unmarshal(data, &summary); err != nil {
+		return nil, fmt.Errorf("invalid summary in %s: %w", taskDir, err)
+	}
+	if summary.TopItems == nil {
+		return nil, fmt.Errorf("no top functions in %s: not a profile analysis", taskDir)
+	}
+	return summary.TopItems, nil
+}
+
+// checkTopFuncSelf checks a function self time threshold. Without a subject
+// it applies to the top function only.
+func checkTopFuncSelf(spec string, funcs []topFunction) ([]string, error) {
+	t, err := parseThreshold(spec)
+	if err != nil {
+		return nil, err
+	}
+	limit, err := strconv.ParseFloat(strings.TrimSuffix(t.limit, "%"), 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid threshold %q: invalid percentage %q", spec, t.limit)
+	}
+
+	if t.subject == nil && len(funcs) > 0 {
+		top := funcs[0]
+		for _, fn := range funcs[1:] {
+			if fn.Percentage > top.Percentage {
+				top = fn
+			}
+		}
+		funcs = []topFunction{top}
+	}
+
+	var violations []string
+	for _, fn := range funcs {
+		if t.subject != nil && !t.subject.MatchString(fn.Name) {
+			continue
+		}
+		if t.exceeds(fn.Percentage, limit) {
+			violations = append(violations, fmt.Sprintf("%s: function %s has %.2f%% self time", spec, fn.Name, fn.Percentage))
+		}
+	}
+	return violations, nil
+}
@@ -0,0 +1,263 @@
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/perf-analysis/internal/parser/hprof"
+)
+
+func TestParseThreshold(t *testing.T) {
+	tests := []struct {
+		spec        string
+		wantOrEqual bool
+		wantLimit   string
+		match       []string // subjects matched, none for a spec without subject
+		noMatch     []string
+		wantErr     bool
+	}{
+		{spec: "com.example.Cache>500MB", wantLimit: "500MB",
+			match: []string{"com.example.Cache"}, noMatch: []string{"com.example.CacheEntry", "comXexample.Cache", "x.com.example.Cache"}},
+		{spec: " com.example.Cache >= 500 MB ", wantOrEqual: true, wantLimit: "500 MB",
+			match: []string{"com.example.Cache"}},
+		{spec: ">30%", wantLimit: "30%"},
+		{spec: ">=30%", wantOrEqual: true, wantLimit: "30%"},
+		{spec: "  > 30% ", wantLimit: "30%"},
+		{spec: "*.parseJson*>10%", wantLimit: "10%",
+			match: []string{"com.Foo.parseJson", "com.Foo.parseJsonBody_[j]"}, noMatch: []string{"parseJson", "com.Foo.parse"}},
+		{spec: "java.util.HashMap$Node[]>1GB", wantLimit: "1GB",
+			match: []string{"java.util.HashMap$Node[]"}, noMatch: []string{"java.util.HashMap$Node"}},
+		// Subjects may contain '>': the limit follows the last one
+		{spec: "std::vector<std::pair<int, int>>::push_back>5%", wantLimit: "5%",
+			match: []string{"std::vector<std::pair<int, int>>::push_back"}},
+		{spec: "Foo<Bar>>=5%", wantOrEqual: true, wantLimit: "5%", match: []string{"Foo<Bar>"}},
+		{spec: "Foo<Bar>>5%", wantLimit: "5%", match: []string{"Foo<Bar>"}, noMatch: []string{"Foo<Bar>>"}},
+		{spec: "com.example.Cache>", wantLimit: "", match: []string{"com.example.Cache"}},
+		{spec: "30%", wantErr: true},
+		{spec: "com.example.Cache<500MB", wantErr: true},
+		{spec: "", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			th, err := parseThreshold(tt.spec)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.spec, th.spec)
+			assert.Equal(t, tt.wantOrEqual, th.orEqual)
+			assert.Equal(t, tt.wantLimit, th.limit)
+			if tt.match == nil {
+				assert.Nil(t, th.subject)
+				return
+			}
+			require.NotNil(t, th.subject)
+			for _, name := range tt.match {
+				assert.True(t, th.subject.MatchString(name), name)
+			}
+			for _, name := range tt.noMatch {
+				assert.False(t, th.subject.MatchString(name), name)
+			}
+		})
+	}
+}
+
+func TestCheckClassRetained(t *testing.T) {
+	const mb = 1 << 20
+	classes := []*hprof.ClassStats{
+		{ClassName: "com.example.Cache", RetainedSize: 600 * mb},
+		{ClassName: "com.example.CacheEntry", RetainedSize: 100 * mb},
+		{ClassName: "java.lang.String", RetainedSize: 500 * mb},
+	}
+
+	tests := []struct {
+		spec    string
+		want    []string // violating classes
+		wantErr bool
+	}{
+		{spec: "com.example.Cache>500MB", want: []string{"com.example.Cache"}},
+		{spec: "com.example.Cache>600MB"},
+		{spec: "com.example.Cache>=600MB", want: []string{"com.example.Cache"}},
+		{spec: "com.example.Cache*>50MB", want: []string{"com.example.Cache", "com.example.CacheEntry"}},
+		{spec: "java.lang.String>500MB"},
+		{spec: "java.lang.String>=500MB", want: []string{"java.lang.String"}},
+		{spec: "java.lang.String>0.48GiB", want: []string{"java.lang.String"}},
+		{spec: "java.lang.String>524287999", want: []string{"java.lang.String"}},
+		{spec: "java.lang.String>500m"},
+		{spec: "*>100MB", want: []string{"com.example.Cache", "java.lang.String"}},
+		{spec: "com.example.Missing>0"},
+		{spec: ">500MB", wantErr: true},
+		{spec: "com.example.Cache>lots", wantErr: true},
+		{spec: "com.example.Cache>-1MB", wantErr: true},
+		{spec: "com.example.Cache>", wantErr: true},
+		{spec: "com.example.Cache", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			violations, err := checkClassRetained(tt.spec, classes)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			var want []string
+			for _, name := range tt.want {
+				for _, c := range classes {
+					if c.ClassName == name {
+						want = append(want, fmt.Sprintf("%s: class %s retains %s", tt.spec, name, hprof.FormatBytesSize(c.RetainedSize)))
+					}
+				}
+			}
+			assert.Equal(t, want, violations)
+		})
+	}
+}
+
+func TestCheckTopFuncSelf(t *testing.T) {
+	// Not sorted: the top function is the one with the most self time
+	funcs := []topFunction{
+		{Name: "a", Percentage: 20},
+		{Name: "com.Foo.parseJsonBody", Percentage: 35},
+		{Name: "b", Percentage: 10},
+	}
+
+	tests := []struct {
+		spec    string
+		funcs   []topFunction
+		want    []string
+		wantErr bool
+	}{
+		{spec: ">30%", want: []string{">30%: function com.Foo.parseJsonBody has 35.00% self time"}},
+		{spec: ">35%"},
+		{spec: ">=35%", want: []string{">=35%: function com.Foo.parseJsonBody has 35.00% self time"}},
+		{spec: ">34.9", want: []string{">34.9: function com.Foo.parseJsonBody has 35.00% self time"}},
+		{spec: ">5%", want: []string{">5%: function com.Foo.parseJsonBody has 35.00% self time"}},
+		{spec: "a>10%", want: []string{"a>10%: function a has 20.00% self time"}},
+		{spec: "a>20%"},
+		{spec: "*.parseJson*>10%", want: []string{"*.parseJson*>10%: function com.Foo.parseJsonBody has 35.00% self time"}},
+		{spec: "*>15%", want: []string{
+			"*>15%: function a has 20.00% self time",
+			"*>15%: function com.Foo.parseJsonBody has 35.00% self time",
+		}},
+		{spec: ">1%", funcs: []topFunction{}},
+		{spec: ">x%", wantErr: true},
+		{spec: ">%", wantErr: true},
+		{spec: "a>10 percent", wantErr: true},
+		{spec: "a", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			in := funcs
+			if tt.funcs != nil {
+				in = tt.funcs
+			}
+			violations, err := checkTopFuncSelf(tt.spec, in)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, violations)
+		})
+	}
+}
+
+// setThresholdFlags sets the --fail-if flags, restoring them when the test
+// ends.
+func setThresholdFlags(t *testing.T, classRetained, topFuncSelf []string) {
+	prevClass, prevFunc := failIfClassRetained, failIfTopFuncSelf
+	t.Cleanup(func() { failIfClassRetained, failIfTopFuncSelf = prevClass, prevFunc })
+	failIfClassRetained, failIfTopFuncSelf = classRetained, topFuncSelf
+}
+
+// writeThresholdTask writes a task directory with the given summary and,
+// unless empty, class histogram.
+func writeThresholdTask(t *testing.T, summary, histogram string) string {
+	taskDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(taskDir, "summary.json"), []byte(summary), 0o644))
+	if histogram != "" {
+		require.NoError(t, os.WriteFile(filepath.Join(taskDir, "class_histogram.json"), []byte(histogram), 0o644))
+	}
+	return taskDir
+}
+
+func TestRunReport_Thresholds(t *testing.T) {
+	const (
+		heapSummary = `{"task_uuid": "heap-1", "total_records": 3}`
+		histogram   = `{"classes": [{"class_name": "com.example.Cache", "retained_size": 629145600}]}`
+		cpuSummary  = `{"task_uuid": "cpu-1", "top_items": [{"name": "main", "percentage": 12.5}]}`
+	)
+
+	tests := []struct {
+		name          string
+		summary       string
+		histogram     string
+		classRetained []string
+		topFuncSelf   []string
+		wantExit      int // 0 when the report passes
+		wantErr       string
+	}{
+		{name: "no thresholds", summary: heapSummary, histogram: histogram},
+		{name: "class below limit", summary: heapSummary, histogram: histogram,
+			classRetained: []string{"com.example.Cache>600MB"}},
+		{name: "class above limit", summary: heapSummary, histogram: histogram,
+			classRetained: []string{"com.example.Cache>500MB", "java.lang.String>1MB"}, wantExit: exitThresholdExceeded,
+			wantErr: "1 threshold(s) exceeded:\n  com.example.Cache>500MB: class com.example.Cache retains 600.00 MB"},
+		{name: "function below limit", summary: cpuSummary, topFuncSelf: []string{">12.5%"}},
+		{name: "function at limit", summary: cpuSummary, topFuncSelf: []string{">=12.5%"}, wantExit: exitThresholdExceeded,
+			wantErr: "1 threshold(s) exceeded:\n  >=12.5%: function main has 12.50% self time"},
+		{name: "both exceeded", summary: `{"top_items": [{"name": "main", "percentage": 50}]}`, histogram: histogram,
+			classRetained: []string{"*>1KB"}, topFuncSelf: []string{">10%"}, wantExit: exitThresholdExceeded,
+			wantErr: "2 threshold(s) exceeded"},
+		{name: "class threshold on a profile", summary: cpuSummary, classRetained: []string{"*>1KB"}, wantExit: 1,
+			wantErr: "--fail-if-class-retained: no class histogram"},
+		{name: "function threshold on a heap dump", summary: heapSummary, histogram: histogram, topFuncSelf: []string{">10%"}, wantExit: 1,
+			wantErr: "--fail-if-top-func-self: no top functions"},
+		{name: "invalid threshold", summary: heapSummary, histogram: histogram, classRetained: []string{"com.example.Cache"}, wantExit: 1,
+			wantErr: "invalid threshold"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setThresholdFlags(t, tt.classRetained, tt.topFuncSelf)
+			taskDir := writeThresholdTask(t, tt.summary, tt.histogram)
+			var out bytes.Buffer
+			reportCmd.SetOut(&out)
+			t.Cleanup(func() { reportCmd.SetOut(nil) })
+
+			err := runReport(reportCmd, []string{taskDir})
+			if tt.wantExit == 0 {
+				assert.NoError(t, err)
+				return
+			}
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.wantErr)
+			assert.Equal(t, tt.wantExit, exitCode(err))
+			assert.Contains(t, out.String(), "Task:", "the report is printed before the thresholds are checked")
+		})
+	}
+}
+
+func TestThresholdFailure(t *testing.T) {
+	prev := reportCmd.SilenceUsage
+	t.Cleanup(func() { reportCmd.SilenceUsage = prev })
+	reportCmd.SilenceUsage = false
+
+	assert.NoError(t, thresholdFailure(reportCmd, nil))
+	assert.False(t, reportCmd.SilenceUsage)
+
+	err := thresholdFailure(reportCmd, []string{"a", "b"})
+	require.Error(t, err)
+	assert.True(t, reportCmd.SilenceUsage, "exceeded thresholds are not usage errors")
+	assert.Equal(t, "2 threshold(s) exceeded:\n  a\n  b", err.Error())
+	assert.Equal(t, exitThresholdExceeded, exitCode(err))
+	assert.Equal(t, exitThresholdExceeded, exitCode(fmt.Errorf("report: %w", err)))
+	assert.Equal(t, 1, exitCode(errors.New("no summary")))
+}
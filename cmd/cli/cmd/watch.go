@@ -0,0 +1,241 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/perf-analysis/internal/analyzer"
+)
+
+var (
+	// Watch command flags
+	watchDir      string
+	watchInterval time.Duration
+	watchRules    []string
+	watchServe    bool
+)
+
+// defaultWatchRules map the usual dump file names to analysis modes.
+var defaultWatchRules = []string{
+	"*.hprof=" + string(analyzer.ModeJavaHeap),
+	"*alloc*.data=" + string(analyzer.ModeJavaAlloc),
+	"*.data=" + string(analyzer.ModeJavaCPU),
+}
+
+// watchCmd analyzes the dumps arriving in a directory
+var watchCmd = &cobra.Command{
+	Use:     "watch",
+	GroupID: groupAnalysis,
+	Short:   "Watch a directory and analyze new dumps",
+	Long: `Watch a directory for new heap dumps and profiling data, and analyze each
+of them into the output directory read by serve mode.
+
+Files are matched against the rules in order, and analyzed with the mode of
+the first matching rule; other files are ignored. A file is analyzed once it
+has stopped growing, so dumps still being copied in are not picked up early.
+
+Each file is analyzed into a task named after it and its modification time,
+e.g. heap-20240105-101500. Files whose task already has results are skipped,
+so the watch can be restarted without analyzing everything again.`,
+	Args: cobra.NoArgs,
+	RunE: runWatch,
+}
+
+func init() {
+	rootCmd.AddCommand(watchCmd)
+
+	binName := BinName()
+	watchCmd.Example = `  # Analyze the dumps arriving in /dumps into ./output
+  ` + binName + ` watch -d /dumps
+
+  # Also serve the results
+  ` + binName + ` watch -d /dumps -o /srv/perf --serve --port 8080
+
+  # Only analyze heap dumps and collapsed generic CPU profiles
+  ` + binName + ` watch -d /dumps --rule '*.hprof=java-heap' --rule '*.collapsed=cpu'`
+
+	watchCmd.Flags().StringVarP(&watchDir, "dir", "d", "", "Directory to watch (required)")
+	watchCmd.Flags().DurationVar(&watchInterval, "interval", 5*time.Second, "How often the directory is scanned")
+	watchCmd.Flags().StringArrayVar(&watchRules, "rule", defaultWatchRules,
+		"Analysis mode of matching files, as <glob>=<mode>; the first matching rule applies (replaces the defaults)")
+	watchCmd.MarkFlagRequired("dir")
+	watchCmd.MarkFlagDirname("dir")
+
+	addAnalysisOptionFlags(watchCmd)
+
+	// Serve flags
+	watchCmd.Flags().BoolVar(&watchServe, "serve", false, "Also serve the output directory")
+	watchCmd.Flags().IntVar(&servePort, "port", 8080, "Port for web server (used with --serve)")
+}
+
+// watchRule selects the analysis mode of files matching a glob pattern.
+type watchRule struct {
+	pattern string
+	mode    analyzer.AnalysisMode
+}
+
+// parseWatchRules parses <glob>=<mode> rules.
+func parseWatchRules(specs []string) ([]watchRule, error) {
+	rules := make([]watchRule, 0, len(specs))
+	for _, spec := range specs {
+		pattern, modeName, ok := strings.Cut(spec, "=")
+		if !ok || pattern == "" {
+			return nil, fmt.Errorf("invalid rule %q: expected <glob>=<mode>", spec)
+		}
+		if _, err := filepath.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("invalid rule %q: %w", spec, err)
+		}
+		mode, err := analyzer.ParseMode(modeName)
+		if err != nil {
+			return nil, fmt.Errorf("invalid rule %q: %w", spec, err)
+		}
+		rules = append(rules, watchRule{pattern: pattern, mode: mode})
+	}
+	return rules, nil
+}
+
+// modeFor returns the mode of the first rule matching the file name.
+func modeFor(rules []watchRule, name string) (analyzer.AnalysisMode, bool) {
+	for _, rule := range rules {
+		if ok, _ := filepath.Match(rule.pattern, name); ok {
+			return rule.mode, true
+		}
+	}
+	return "", false
+}
+
+// watchedFile is the last seen state of a file in the watched directory.
+type watchedFile struct {
+	size    int64
+	modTime time.Time
+	// done is set once the file was analyzed, or failed to
+	done bool
+}
+
+func runWatch(cmd *cobra.Command, args []string) error {
+	log := GetLogger()
+
+	if info, err := os.Stat(watchDir); err != nil || !info.IsDir() {
+		return fmt.Errorf("watch directory not found: %s", watchDir)
+	}
+	rules, err := parseWatchRules(watchRules)
+	if err != nil {
+		return err
+	}
+	if watchInterval <= 0 {
+		return fmt.Errorf("invalid interval: %s", watchInterval)
+	}
+	if err := os.MkdirAll(outputDir, 0755); err != nil {
+		return fmt.Errorf("failed to create output directory: %w", err)
+	}
+
+	serveErr := make(chan error, 1)
+	if watchServe {
+		go func() {
+			serveErr <- runServer(newServeServer(outputDir, servePort, log), outputDir, servePort, log)
+		}()
+	}
+
+	log.Info("Watching %s every %s, results go to %s", watchDir, watchInterval, outputDir)
+	files := make(map[string]*watchedFile)
+	ticker := time.NewTicker(watchInterval)
+	defer ticker.Stop()
+	for {
+		for _, path := range scanWatchDir(rules, files) {
+			mode, _ := modeFor(rules, filepath.Base(path))
+			analyzeWatchedFile(cmd, path, mode, files[path])
+		}
+		select {
+		case err := <-serveErr:
+			return err
+		case <-ticker.C:
+		}
+	}
+}
+
+// scanWatchDir updates the state of the files in the watched directory and
+// returns the files that are ready for analysis: matched by a rule, not
+// analyzed yet, and unchanged since the previous scan.
+func scanWatchDir(rules []watchRule, files map[string]*watchedFile) []string {
+	entries, err := os.ReadDir(watchDir)
+	if err != nil {
+		GetLogger().Warn("Failed to scan %s: %v", watchDir, err)
+		return nil
+	}
+
+	var ready []string
+	present := make(map[string]bool, len(entries))
+	for _, entry := range entries {
+		if !entry.Type().IsRegular() {
+			continue
+		}
+		if _, ok := modeFor(rules, entry.Name()); !ok {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		path := filepath.Join(watchDir, entry.Name())
+		present[path] = true
+		file, seen := files[path]
+		if !seen || file.size != info.Size() || !file.modTime.Equal(info.ModTime()) {
+			// New or still being written (or replaced): check again next scan
+			files[path] = &watchedFile{size: info.Size(), modTime: info.ModTime()}
+			continue
+		}
+		if !file.done {
+			ready = append(ready, path)
+		}
+	}
+	// Forget removed files
+	for path := range files {
+		if !present[path] {
+			delete(files, path)
+		}
+	}
+	sort.Strings(ready)
+	return ready
+}
+
+// analyzeWatchedFile analyzes a file of the watched directory, unless its
+// task already has results.
+func analyzeWatchedFile(cmd *cobra.Command, path string, mode analyzer.AnalysisMode, file *watchedFile) {
+	log := GetLogger()
+	file.done = true
+
+	uuid := watchTaskUUID(filepath.Base(path), file.modTime)
+	if _, err := os.Stat(filepath.Join(outputDir, uuid, "summary.json")); err == nil {
+		log.Debug("Skipping %s: already analyzed as %s", path, uuid)
+		return
+	}
+
+	log.Info("New file %s, analyzing as %s into task %s", path, mode, uuid)
+	inputFile = path
+	analysisMode = string(mode)
+	taskUUID = uuid
+	if err := runAnalyze(cmd, nil); err != nil {
+		log.Error("Failed to analyze %s: %v", path, err)
+	}
+}
+
+// taskNameChars matches the characters not kept in task names.
+var taskNameChars = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// watchTaskUUID names the task of a watched file after the file and its
+// modification time, so a file replaced by a newer dump gets a new task.
+func watchTaskUUID(name string, modTime time.Time) string {
+	name = strings.TrimSuffix(name, filepath.Ext(name))
+	name = strings.Trim(taskNameChars.ReplaceAllString(name, "-"), "-.")
+	if name == "" {
+		name = "dump"
+	}
+	return name + "-" + modTime.Format("20060102-150405")
+}
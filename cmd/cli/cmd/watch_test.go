@@ -0,0 +1,175 @@
+package cmd
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/perf-analysis/internal/analyzer"
+	"github.com/perf-analysis/pkg/utils"
+)
+
+// setWatchGlobals points the watch command at dir and output, with a quiet
+// logger, restoring the previous flag values when the test ends.
+func setWatchGlobals(t *testing.T, dir, output string) {
+	prevDir, prevOutput, prevLogger := watchDir, outputDir, logger
+	t.Cleanup(func() { watchDir, outputDir, logger = prevDir, prevOutput, prevLogger })
+	watchDir, outputDir = dir, output
+	logger = newLogger(utils.LevelError, io.Discard)
+}
+
+func TestParseWatchRules(t *testing.T) {
+	tests := []struct {
+		name    string
+		specs   []string
+		want    []watchRule
+		wantErr bool
+	}{
+		{"defaults", defaultWatchRules, []watchRule{
+			{"*.hprof", analyzer.ModeJavaHeap},
+			{"*alloc*.data", analyzer.ModeJavaAlloc},
+			{"*.data", analyzer.ModeJavaCPU},
+		}, false},
+		{"mode case and spaces", []string{"*.collapsed= CPU "}, []watchRule{{"*.collapsed", analyzer.ModeCPU}}, false},
+		{"none", nil, []watchRule{}, false},
+		{"no mode", []string{"*.hprof"}, nil, true},
+		{"no pattern", []string{"=java-heap"}, nil, true},
+		{"unknown mode", []string{"*.hprof=heap"}, nil, true},
+		{"bad glob", []string{"[*.hprof=java-heap"}, nil, true},
+		{"one bad rule", []string{"*.hprof=java-heap", "*.data="}, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rules, err := parseWatchRules(tt.specs)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, rules)
+		})
+	}
+}
+
+func TestModeFor(t *testing.T) {
+	rules, err := parseWatchRules(defaultWatchRules)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name   string
+		want   analyzer.AnalysisMode
+		wantOK bool
+	}{
+		{"heap.hprof", analyzer.ModeJavaHeap, true},
+		{"app-alloc-1.data", analyzer.ModeJavaAlloc, true},
+		{"alloc.data", analyzer.ModeJavaAlloc, true},
+		{"cpu.data", analyzer.ModeJavaCPU, true},
+		{"heap.hprof.gz", "", false},
+		{"notes.txt", "", false},
+		{".data", analyzer.ModeJavaCPU, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mode, ok := modeFor(rules, tt.name)
+			assert.Equal(t, tt.wantOK, ok)
+			assert.Equal(t, tt.want, mode)
+		})
+	}
+}
+
+func TestScanWatchDir(t *testing.T) {
+	dir := t.TempDir()
+	setWatchGlobals(t, dir, t.TempDir())
+	rules, err := parseWatchRules(defaultWatchRules)
+	require.NoError(t, err)
+
+	heap := filepath.Join(dir, "heap.hprof")
+	cpu := filepath.Join(dir, "cpu.data")
+	require.NoError(t, os.WriteFile(heap, []byte("JAVA PROFILE"), 0o644))
+	require.NoError(t, os.WriteFile(cpu, []byte("main;run 1\n"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "notes.txt"), nil, 0o644))
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "old.hprof"), 0o755))
+
+	// New files are ready once they did not change between two scans
+	files := make(map[string]*watchedFile)
+	assert.Empty(t, scanWatchDir(rules, files))
+	assert.Len(t, files, 2)
+	assert.Equal(t, []string{cpu, heap}, scanWatchDir(rules, files))
+
+	// A file still growing waits for the next scan
+	f, err := os.OpenFile(heap, os.O_APPEND|os.O_WRONLY, 0)
+	require.NoError(t, err)
+	_, err = f.WriteString(" 1.0.2")
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+	assert.Equal(t, []string{cpu}, scanWatchDir(rules, files))
+	assert.Equal(t, []string{cpu, heap}, scanWatchDir(rules, files))
+
+	// Analyzed files are not returned again, until replaced
+	files[cpu].done = true
+	files[heap].done = true
+	assert.Empty(t, scanWatchDir(rules, files))
+	later := time.Now().Add(time.Hour)
+	require.NoError(t, os.Chtimes(heap, later, later))
+	assert.Empty(t, scanWatchDir(rules, files))
+	assert.False(t, files[heap].done)
+	assert.Equal(t, []string{heap}, scanWatchDir(rules, files))
+
+	// Removed files are forgotten
+	require.NoError(t, os.Remove(cpu))
+	scanWatchDir(rules, files)
+	assert.NotContains(t, files, cpu)
+	assert.Contains(t, files, heap)
+}
+
+func TestScanWatchDir_Missing(t *testing.T) {
+	setWatchGlobals(t, filepath.Join(t.TempDir(), "missing"), t.TempDir())
+	rules, err := parseWatchRules(defaultWatchRules)
+	require.NoError(t, err)
+	assert.Empty(t, scanWatchDir(rules, make(map[string]*watchedFile)))
+}
+
+func TestWatchTaskUUID(t *testing.T) {
+	modTime := time.Date(2024, 1, 5, 10, 15, 0, 0, time.UTC)
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"heap.hprof", "heap-20240105-101500"},
+		{"heap.tar.gz", "heap.tar-20240105-101500"},
+		{"java_pid123.hprof", "java_pid123-20240105-101500"},
+		{"my heap (copy).hprof", "my-heap-copy-20240105-101500"},
+		{"..hprof", "dump-20240105-101500"},
+		{".hprof", "dump-20240105-101500"},
+		{"堆.hprof", "dump-20240105-101500"},
+		{"-cpu-.data", "cpu-20240105-101500"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, watchTaskUUID(tt.name, modTime))
+		})
+	}
+}
+
+func TestAnalyzeWatchedFile_SkipsAnalyzedTask(t *testing.T) {
+	output := t.TempDir()
+	setWatchGlobals(t, t.TempDir(), output)
+	modTime := time.Date(2024, 1, 5, 10, 15, 0, 0, time.UTC)
+	taskDir := filepath.Join(output, "heap-20240105-101500")
+	require.NoError(t, os.Mkdir(taskDir, 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(taskDir, "summary.json"), []byte("{}"), 0o644))
+
+	prevInput := inputFile
+	t.Cleanup(func() { inputFile = prevInput })
+	inputFile = ""
+
+	file := &watchedFile{size: 1, modTime: modTime}
+	analyzeWatchedFile(watchCmd, filepath.Join(watchDir, "heap.hprof"), analyzer.ModeJavaHeap, file)
+	assert.True(t, file.done)
+	assert.Empty(t, inputFile, "analysis must not start")
+}
@@ -7,13 +7,17 @@ import (
 	"io"
 	"os"
 	"path/filepath"
+	"time"
 
 	"github.com/perf-analysis/internal/callgraph"
 	"github.com/perf-analysis/internal/flamegraph"
 	"github.com/perf-analysis/internal/parser/collapsed"
+	"github.com/perf-analysis/internal/parser/hprof"
 	"github.com/perf-analysis/internal/statistics"
+	"github.com/perf-analysis/internal/symbol"
 	"github.com/perf-analysis/pkg/model"
 	"github.com/perf-analysis/pkg/utils"
+	"github.com/perf-analysis/pkg/writer"
 )
 
 // AnalysisProfile defines preset analysis configurations for different use cases.
@@ -54,6 +58,47 @@ type BaseAnalyzerConfig struct {
 
 	// AnalysisProfile selects preset analysis configuration.
 	AnalysisProfile AnalysisProfile
+
+	// RollupBiggestObjects shows the nearest non-JDK dominator instead of
+	// JDK objects (byte[], Object[], ...) in the heap Biggest Objects view.
+	RollupBiggestObjects bool
+
+	// TableExportFormat selects the format of tabular heap exports (class histogram,
+	// retainers, dominator tree). Empty disables the exports.
+	TableExportFormat writer.TableFormat
+
+	// SQLiteExport writes the Java heap model (objects, classes, references,
+	// dominators) to heap.sqlite in the task directory for ad-hoc SQL queries.
+	SQLiteExport bool
+
+	// ParquetExport writes the Java heap object table and class histogram as
+	// Parquet files in the task directory for ingestion into Spark/ClickHouse.
+	ParquetExport bool
+
+	// ProgressCallback receives phase progress while analysis runs, e.g. to
+	// stream it to the web UI. Only the Java heap analyzer reports phases.
+	ProgressCallback func(phase string, current, total int)
+
+	// Symbols resolves the frames of perf script samples that perf could not
+	// symbolize, e.g. from a vmlinux or debuginfod. Nil leaves them unnamed.
+	Symbols *symbol.Resolver
+
+	// HeapStageTimeouts bound the stages of Java heap analyses. Stages
+	// without a timeout run until the analysis is canceled.
+	HeapStageTimeouts map[hprof.JobState]time.Duration
+
+	// JVMLayout is the object layout Java heap shallow sizes are computed
+	// with (see hprof.ParseJVMLayout), or "auto" to detect it from the dump.
+	// Empty uses compressed oops.
+	JVMLayout string
+
+	// G1RegionSize is the G1 region size Java heap humongous objects are
+	// detected for. 0 uses the size G1 would choose for the heap.
+	G1RegionSize int64
+
+	// OwnershipMap attributes Java heap retained memory to the teams owning
+	// package prefixes. Nil skips the ownership report.
+	OwnershipMap *hprof.OwnershipMap
 }
 
 // DefaultBaseAnalyzerConfig returns default configuration.
@@ -65,6 +110,7 @@ func DefaultBaseAnalyzerConfig() *BaseAnalyzerConfig {
 		TopFuncsN:         50,
 		IncludeSwapper:    false,
 		AnalysisProfile:   ProfileStandard,
+		TableExportFormat: writer.TableFormatCSV,
 	}
 }
 
@@ -246,6 +292,108 @@ func (a *BaseAnalyzer) WriteCallGraphGzip(cg *callgraph.CallGraph, outputPath st
 	return writer.WriteToFile(cg, outputPath)
 }
 
+// profileFiles names the output files of a profile.
+type profileFiles struct {
+	name       string // prefix of the names of the output files
+	flameGraph string
+	callGraph  string
+	timeline   string
+	unit       string // unit of the values of the flame graph, samples if empty
+}
+
+// timelineMaxBuckets is the number of periods of time above which the
+// resolution of timelines is lowered.
+const timelineMaxBuckets = 600
+
+// writeProfile writes the flame graph and call graph of the samples of a
+// profile, and returns the flame graph and the output files.
+func (a *BaseAnalyzer) writeProfile(ctx context.Context, taskUUID, taskDir string, samples []*model.Sample, names profileFiles) (*flamegraph.FlameGraph, []model.OutputFile, error) {
+	fg, err := a.GenerateFlameGraphWithAnalysis(ctx, samples)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to generate %sflame graph: %w", names.name, err)
+	}
+	fg.Unit = names.unit
+	flameGraphFile := filepath.Join(taskDir, names.flameGraph)
+	if err := a.WriteFlameGraphGzip(fg, flameGraphFile); err != nil {
+		return nil, nil, fmt.Errorf("failed to write %sflame graph: %w", names.name, err)
+	}
+
+	cg, err := a.GenerateCallGraphWithAnalysis(ctx, samples)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to generate %scall graph: %w", names.name, err)
+	}
+	callGraphFile := filepath.Join(taskDir, names.callGraph)
+	if err := a.WriteCallGraphGzip(cg, callGraphFile); err != nil {
+		return nil, nil, fmt.Errorf("failed to write %scall graph: %w", names.name, err)
+	}
+
+	return fg, []model.OutputFile{
+		{
+			Name:        names.name + "Flame Graph",
+			LocalPath:   flameGraphFile,
+			COSKey:      taskUUID + "/" + names.flameGraph,
+			ContentType: "application/gzip",
+		},
+		{
+			Name:        names.name + "Call Graph",
+			LocalPath:   callGraphFile,
+			COSKey:      taskUUID + "/" + names.callGraph,
+			ContentType: "application/gzip",
+		},
+	}, nil
+}
+
+// writeTimeline writes the timeline of the timestamped samples of a
+// profile, for the flame graphs of time windows. It returns nil if no
+// sample has a timestamp.
+func (a *BaseAnalyzer) writeTimeline(taskUUID, taskDir string, samples []*model.Sample, minResolution time.Duration, names profileFiles) (*model.OutputFile, error) {
+	t := flamegraph.NewTimeline(samples, minResolution, timelineMaxBuckets)
+	if t == nil {
+		return nil, nil
+	}
+	t.Unit = names.unit
+
+	path := filepath.Join(taskDir, names.timeline)
+	if err := flamegraph.WriteTimelineGzip(t, path); err != nil {
+		return nil, fmt.Errorf("failed to write %stimeline: %w", names.name, err)
+	}
+	return &model.OutputFile{
+		Name:        names.name + "Timeline",
+		LocalPath:   path,
+		COSKey:      taskUUID + "/" + names.timeline,
+		ContentType: "application/gzip",
+	}, nil
+}
+
+// flameGraphTopFuncs returns the top functions of the thread analysis of a
+// flame graph.
+func flameGraphTopFuncs(fg *flamegraph.FlameGraph) model.TopFuncsMap {
+	topFuncs := make(model.TopFuncsMap)
+	if fg.ThreadAnalysis != nil {
+		for _, tf := range fg.ThreadAnalysis.TopFunctions {
+			topFuncs[tf.Name] = model.TopFuncValue{Self: tf.Percentage}
+		}
+	}
+	return topFuncs
+}
+
+// flameGraphThreadStats returns the threads of the thread analysis of a
+// flame graph.
+func flameGraphThreadStats(fg *flamegraph.FlameGraph) []model.ThreadInfo {
+	threadStats := make([]model.ThreadInfo, 0)
+	if fg.ThreadAnalysis != nil {
+		for _, t := range fg.ThreadAnalysis.Threads {
+			threadStats = append(threadStats, model.ThreadInfo{
+				TID:        t.TID,
+				ThreadName: t.Name,
+				Samples:    t.Samples,
+				Percentage: t.Percentage,
+			})
+		}
+	}
+	return threadStats
+}
+
 // BuildNamespaceResult builds the namespace result from analysis outputs.
 func (a *BaseAnalyzer) BuildNamespaceResult(
 	taskUUID string,
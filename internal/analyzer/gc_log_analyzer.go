@@ -0,0 +1,286 @@
+package analyzer
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/perf-analysis/internal/parser/gclog"
+	"github.com/perf-analysis/internal/parser/hprof"
+	"github.com/perf-analysis/pkg/model"
+)
+
+func init() {
+	Register(model.TaskTypeGCLog, AnyProfiler, func(c *BaseAnalyzerConfig) Analyzer { return NewGCLogAnalyzer(c) })
+}
+
+const (
+	// gcLogFile is the analysis of the GC logs, served to the web UI.
+	gcLogFile = "gclog.json"
+	// gcLogMaxHeapPoints is the number of collections of the heap trend
+	// above which it is downsampled.
+	gcLogMaxHeapPoints = 1000
+)
+
+// hprofMagic is the start of the format string of HPROF heap dumps.
+var hprofMagic = []byte("JAVA PROFILE")
+
+// GCLogAnalyzer analyzes the unified GC logs of the JVM: pause histogram,
+// allocation and promotion rates and heap occupancy trend. Heap dumps of
+// the same task are placed in the log by their timestamp.
+type GCLogAnalyzer struct {
+	*BaseAnalyzer
+}
+
+// NewGCLogAnalyzer creates a new GC log analyzer.
+func NewGCLogAnalyzer(config *BaseAnalyzerConfig) *GCLogAnalyzer {
+	if config == nil {
+		config = DefaultBaseAnalyzerConfig()
+	}
+
+	return &GCLogAnalyzer{
+		BaseAnalyzer: NewBaseAnalyzer(config),
+	}
+}
+
+// Name returns the analyzer name.
+func (a *GCLogAnalyzer) Name() string {
+	return "gc_log_analyzer"
+}
+
+// SupportedTypes returns the task types supported by this analyzer.
+func (a *GCLogAnalyzer) SupportedTypes() []model.TaskType {
+	return []model.TaskType{model.TaskTypeGCLog}
+}
+
+// CanHandle checks if this analyzer can handle the given request.
+func (a *GCLogAnalyzer) CanHandle(req *model.AnalysisRequest) bool {
+	return req.TaskType == model.TaskTypeGCLog
+}
+
+// Analyze performs GC log analysis of the input files: GC logs, rotated
+// files oldest first, and HPROF heap dumps to correlate with them.
+func (a *GCLogAnalyzer) Analyze(ctx context.Context, req *model.AnalysisRequest) (*model.AnalysisResponse, error) {
+	files := req.InputFiles
+	if len(files) == 0 {
+		files = []string{req.InputFile}
+	}
+
+	// Step 1: Parse the GC logs and read the timestamps of the heap dumps
+	var (
+		log   *gclog.Log
+		dumps []heapDumpTime
+	)
+	for _, path := range files {
+		parsed, dump, err := a.parseFile(ctx, path)
+		if err != nil {
+			return nil, err
+		}
+		if dump != nil {
+			dumps = append(dumps, *dump)
+			continue
+		}
+		log = appendGCLog(log, parsed)
+	}
+	if log == nil {
+		return nil, ErrEmptyData
+	}
+
+	return a.analyzeLog(req, log, dumps)
+}
+
+// AnalyzeFromReader performs GC log analysis from a reader of a GC log.
+func (a *GCLogAnalyzer) AnalyzeFromReader(ctx context.Context, req *model.AnalysisRequest, dataReader io.Reader) (*model.AnalysisResponse, error) {
+	log, err := gclog.NewParser().Parse(ctx, dataReader)
+	if err != nil {
+		return nil, gcLogError(err)
+	}
+	return a.analyzeLog(req, log, nil)
+}
+
+// heapDumpTime is the timestamp of a heap dump.
+type heapDumpTime struct {
+	file string
+	time time.Time
+}
+
+// parseFile parses a GC log, or reads the timestamp of a heap dump.
+func (a *GCLogAnalyzer) parseFile(ctx context.Context, path string) (*gclog.Log, *heapDumpTime, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to open input file: %w", err)
+	}
+	defer file.Close()
+
+	r := bufio.NewReader(file)
+	header, _ := r.Peek(len(hprofMagic))
+	if bytes.Equal(header, hprofMagic) {
+		h, err := hprof.NewReader(r).ReadHeader()
+		if err != nil {
+			return nil, nil, fmt.Errorf("%w: %s: %v", ErrParseError, filepath.Base(path), err)
+		}
+		return nil, &heapDumpTime{file: filepath.Base(path), time: h.Timestamp}, nil
+	}
+
+	log, err := gclog.NewParser().Parse(ctx, r)
+	if err != nil {
+		return nil, nil, gcLogError(err)
+	}
+	return log, nil, nil
+}
+
+// gcLogError maps the errors of the GC log parser to the analyzer errors.
+func gcLogError(err error) error {
+	if errors.Is(err, gclog.ErrNoEvents) {
+		return ErrEmptyData
+	}
+	return fmt.Errorf("%w: %v", ErrParseError, err)
+}
+
+// appendGCLog appends the events of a rotated GC log to the previous ones.
+func appendGCLog(log, next *gclog.Log) *gclog.Log {
+	if log == nil {
+		return next
+	}
+	if log.Collector == "" {
+		log.Collector = next.Collector
+	}
+	if log.JVMStart.IsZero() {
+		log.JVMStart = next.JVMStart
+	}
+	log.Pauses = append(log.Pauses, next.Pauses...)
+	log.Collections = append(log.Collections, next.Collections...)
+	return log
+}
+
+// analyzeLog analyzes a parsed GC log and writes the analysis.
+func (a *GCLogAnalyzer) analyzeLog(req *model.AnalysisRequest, log *gclog.Log, dumps []heapDumpTime) (*model.AnalysisResponse, error) {
+	// Step 2: Determine output directory
+	taskDir := req.OutputDir
+	if taskDir == "" {
+		var err error
+		taskDir, err = a.EnsureOutputDir(req.TaskUUID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create output directory: %w", err)
+		}
+	}
+
+	// Step 3: Analyze the log
+	data := buildGCLogData(log, dumps)
+
+	// Step 4: Write the analysis for the web UI
+	path := filepath.Join(taskDir, gcLogFile)
+	content, err := json.Marshal(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal GC log analysis: %w", err)
+	}
+	if err := os.WriteFile(path, content, 0644); err != nil {
+		return nil, fmt.Errorf("failed to write GC log analysis: %w", err)
+	}
+
+	return &model.AnalysisResponse{
+		TaskUUID:     req.TaskUUID,
+		TaskType:     req.TaskType,
+		TotalRecords: len(log.Pauses),
+		OutputFiles: []model.OutputFile{{
+			Name:        "GC Log Analysis",
+			LocalPath:   path,
+			COSKey:      req.TaskUUID + "/" + gcLogFile,
+			ContentType: "application/json",
+		}},
+		Data: data,
+	}, nil
+}
+
+// buildGCLogData converts the statistics of a GC log to the analysis data.
+func buildGCLogData(log *gclog.Log, dumps []heapDumpTime) *model.GCLogData {
+	s := log.Summarize()
+	data := &model.GCLogData{
+		Collector:          log.Collector,
+		DurationSeconds:    s.Duration.Seconds(),
+		PauseCount:         s.PauseCount,
+		TotalPauseMs:       millis(s.TotalPause),
+		MaxPauseMs:         millis(s.MaxPause),
+		P50PauseMs:         millis(s.P50),
+		P90PauseMs:         millis(s.P90),
+		P95PauseMs:         millis(s.P95),
+		P99PauseMs:         millis(s.P99),
+		Throughput:         s.Throughput,
+		Causes:             log.Causes(),
+		AllocatedBytes:     s.AllocatedBytes,
+		AllocationRate:     s.AllocationRate,
+		PeakAllocationRate: s.PeakAllocationRate,
+		PromotedBytes:      s.PromotedBytes,
+		PromotionRate:      s.PromotionRate,
+		HasPromotion:       s.HasPromotion,
+		LiveSetGrowthRate:  s.LiveSetGrowthRate,
+	}
+
+	for _, b := range log.PauseHistogram() {
+		data.PauseHistogram = append(data.PauseHistogram, model.GCPauseBucket{
+			UpperBoundMs: millis(b.UpperBound),
+			Count:        b.Count,
+		})
+	}
+	for _, t := range log.PauseTypes() {
+		data.PauseTypes = append(data.PauseTypes, model.GCPauseType{
+			Type:    t.Type,
+			Count:   t.Count,
+			TotalMs: millis(t.Total),
+			MaxMs:   millis(t.Max),
+		})
+	}
+
+	stride := (len(log.Collections) + gcLogMaxHeapPoints - 1) / gcLogMaxHeapPoints
+	for i := 0; i < len(log.Collections); i += max(stride, 1) {
+		c := log.Collections[i]
+		data.HeapTrend = append(data.HeapTrend, model.GCHeapPoint{
+			UptimeSeconds: c.Uptime.Seconds(),
+			GCID:          c.GCID,
+			Type:          c.Type,
+			Before:        c.Before,
+			After:         c.After,
+			Capacity:      c.Capacity,
+		})
+	}
+
+	for _, dump := range dumps {
+		data.HeapDumps = append(data.HeapDumps, correlateHeapDump(log, dump))
+	}
+	return data
+}
+
+// correlateHeapDump places a heap dump in a GC log.
+func correlateHeapDump(log *gclog.Log, dump heapDumpTime) model.GCHeapDumpCorrelation {
+	result := model.GCHeapDumpCorrelation{File: dump.file, Time: dump.time}
+	c, ok := log.Correlate(dump.time)
+	if !ok {
+		return result
+	}
+
+	result.Correlated = true
+	result.InLog = c.InLog
+	result.UptimeSeconds = c.Uptime.Seconds()
+	if c.Preceding != nil {
+		id := c.Preceding.GCID
+		result.PrecedingGCID = &id
+		result.PrecedingGCType = c.Preceding.Type
+		result.HeapAfterGC = c.Preceding.After
+	}
+	if c.Next != nil {
+		result.HeapBeforeNextGC = c.Next.Before
+	}
+	return result
+}
+
+// millis returns a duration in milliseconds.
+func millis(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
@@ -0,0 +1,113 @@
+package analyzer
+
+import (
+	"context"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/perf-analysis/pkg/model"
+)
+
+// gcLogInput is a G1 log decorated with the time and the uptime.
+const gcLogInput = `[2024-05-01T10:00:00.010+0000][0.010s][info][gc] Using G1
+[2024-05-01T10:00:01.000+0000][1.000s][info][gc] GC(0) Pause Young (Normal) (G1 Evacuation Pause) 24M->4M(256M) 4.000ms
+[2024-05-01T10:00:03.000+0000][3.000s][info][gc] GC(1) Pause Young (Normal) (G1 Evacuation Pause) 44M->8M(256M) 6.000ms
+[2024-05-01T10:00:06.000+0000][6.000s][info][gc] GC(2) Pause Full (System.gc()) 30M->10M(256M) 120.000ms
+`
+
+// writeHeapDumpHeader writes the header of an HPROF heap dump taken at t.
+func writeHeapDumpHeader(t *testing.T, path string, at time.Time) {
+	t.Helper()
+	content := append([]byte("JAVA PROFILE 1.0.2\x00"), make([]byte, 12)...)
+	binary.BigEndian.PutUint32(content[19:], 8)
+	binary.BigEndian.PutUint64(content[23:], uint64(at.UnixMilli()))
+	require.NoError(t, os.WriteFile(path, content, 0644))
+}
+
+func TestGCLogAnalyzer_CanHandle(t *testing.T) {
+	analyzer := NewGCLogAnalyzer(nil)
+
+	assert.Equal(t, "gc_log_analyzer", analyzer.Name())
+	assert.True(t, analyzer.CanHandle(&model.AnalysisRequest{TaskType: model.TaskTypeGCLog}))
+	assert.False(t, analyzer.CanHandle(&model.AnalysisRequest{TaskType: model.TaskTypeJavaHeap}))
+}
+
+func TestGCLogAnalyzer_AnalyzeFromReader(t *testing.T) {
+	analyzer := NewGCLogAnalyzer(nil)
+	req := &model.AnalysisRequest{
+		TaskUUID:  "test-gclog-uuid",
+		TaskType:  model.TaskTypeGCLog,
+		OutputDir: t.TempDir(),
+	}
+
+	result, err := analyzer.AnalyzeFromReader(context.Background(), req, strings.NewReader(gcLogInput))
+	require.NoError(t, err)
+	assert.Equal(t, 3, result.TotalRecords)
+
+	data, ok := result.Data.(*model.GCLogData)
+	require.True(t, ok, "Data should be GCLogData")
+	assert.Equal(t, "G1", data.Collector)
+	assert.Equal(t, 3, data.PauseCount)
+	assert.InDelta(t, 130.0, data.TotalPauseMs, 0.001)
+	assert.InDelta(t, 120.0, data.MaxPauseMs, 0.001)
+	assert.True(t, data.Throughput > 97 && data.Throughput < 98)
+	assert.Equal(t, map[string]int{"G1 Evacuation Pause": 2, "System.gc()": 1}, data.Causes)
+	assert.False(t, data.HasPromotion)
+
+	require.Len(t, data.PauseTypes, 2)
+	assert.Equal(t, "Pause Full", data.PauseTypes[0].Type)
+	require.Len(t, data.HeapTrend, 3)
+	assert.Equal(t, int64(44<<20), data.HeapTrend[1].Before)
+	assert.Equal(t, 3.0, data.HeapTrend[1].UptimeSeconds)
+
+	require.Len(t, result.OutputFiles, 1)
+	assert.Equal(t, "test-gclog-uuid/gclog.json", result.OutputFiles[0].COSKey)
+	_, err = os.Stat(result.OutputFiles[0].LocalPath)
+	assert.NoError(t, err)
+}
+
+func TestGCLogAnalyzer_AnalyzeWithHeapDump(t *testing.T) {
+	dir := t.TempDir()
+	logPath := filepath.Join(dir, "gc.log")
+	require.NoError(t, os.WriteFile(logPath, []byte(gcLogInput), 0644))
+	dumpPath := filepath.Join(dir, "heap.hprof")
+	writeHeapDumpHeader(t, dumpPath, time.Date(2024, 5, 1, 10, 0, 4, 0, time.UTC))
+
+	analyzer := NewGCLogAnalyzer(nil)
+	req := &model.AnalysisRequest{
+		TaskType:   model.TaskTypeGCLog,
+		InputFile:  logPath,
+		InputFiles: []string{logPath, dumpPath},
+		OutputDir:  t.TempDir(),
+	}
+
+	result, err := analyzer.Analyze(context.Background(), req)
+	require.NoError(t, err)
+
+	data := result.Data.(*model.GCLogData)
+	require.Len(t, data.HeapDumps, 1)
+	dump := data.HeapDumps[0]
+	assert.Equal(t, "heap.hprof", dump.File)
+	assert.True(t, dump.Correlated)
+	assert.True(t, dump.InLog)
+	assert.InDelta(t, 4.0, dump.UptimeSeconds, 0.001)
+	require.NotNil(t, dump.PrecedingGCID)
+	assert.Equal(t, 1, *dump.PrecedingGCID)
+	assert.Equal(t, int64(8<<20), dump.HeapAfterGC)
+	assert.Equal(t, int64(30<<20), dump.HeapBeforeNextGC)
+}
+
+func TestGCLogAnalyzer_Analyze_EmptyData(t *testing.T) {
+	analyzer := NewGCLogAnalyzer(nil)
+	req := &model.AnalysisRequest{TaskType: model.TaskTypeGCLog, OutputDir: t.TempDir()}
+
+	_, err := analyzer.AnalyzeFromReader(context.Background(), req, strings.NewReader("not a GC log\n"))
+	assert.ErrorIs(t, err, ErrEmptyData)
+}
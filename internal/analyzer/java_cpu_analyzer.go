@@ -1,16 +1,51 @@
 package analyzer
 
 import (
+	"bufio"
 	"context"
 	"fmt"
 	"io"
 	"os"
 	"path/filepath"
+	"time"
 
+	"github.com/perf-analysis/internal/parser/perfscript"
+	"github.com/perf-analysis/internal/parser/pprof"
+	"github.com/perf-analysis/internal/parser/speedscope"
 	"github.com/perf-analysis/pkg/model"
+	"github.com/perf-analysis/pkg/profiling"
 )
 
-// JavaCPUAnalyzer analyzes Java async-profiler CPU data.
+func init() {
+	// Collapsed CPU stacks, of Java and of other languages
+	Register(model.TaskTypeJava, model.ProfilerTypePerf, func(c *BaseAnalyzerConfig) Analyzer { return NewJavaCPUAnalyzer(c) })
+	Register(model.TaskTypeGeneric, model.ProfilerTypePerf, func(c *BaseAnalyzerConfig) Analyzer { return NewJavaCPUAnalyzer(c) })
+}
+
+const (
+	// perfScriptPeekSize is the size of the start of the input looked at to
+	// tell perf script output from collapsed stacks
+	perfScriptPeekSize = 4096
+	// perfScriptTimelineResolution is the minimum period of the timelines
+	// of perf script samples
+	perfScriptTimelineResolution = 100 * time.Millisecond
+)
+
+// perfScriptFiles names the timeline of perf script samples.
+var perfScriptFiles = profileFiles{timeline: "timeline_data.json.gz"}
+
+// pprofProfileFile is the pprof export of the samples, for go tool pprof.
+const pprofProfileFile = "profile.pb.gz"
+
+// interpretedSuggestionPercent is the share of samples in interpreted
+// frames above which the JIT is suspected not to compile hot methods.
+const interpretedSuggestionPercent = 10.0
+
+// JavaCPUAnalyzer analyzes Java async-profiler CPU data, as collapsed stacks
+// or as the output of perf script whose timestamps give a timeline. It also
+// imports speedscope files, and exports the samples as a pprof profile.
+// Stacks annotated with frame types by async-profiler get a breakdown of
+// JIT-compiled, interpreted and native samples.
 type JavaCPUAnalyzer struct {
 	*BaseAnalyzer
 }
@@ -61,8 +96,25 @@ func (a *JavaCPUAnalyzer) AnalyzeFromReader(ctx context.Context, req *model.Anal
 		return nil, fmt.Errorf("java cpu analyzer only supports profiler type perf, got %v", req.ProfilerType)
 	}
 
-	// Step 1: Parse the collapsed data
-	parseResult, err := a.Parse(ctx, dataReader)
+	// Step 1: Parse the collapsed data, the perf script output or the
+	// speedscope file
+	r := bufio.NewReaderSize(dataReader, perfScriptPeekSize)
+	head, _ := r.Peek(perfScriptPeekSize)
+	var parseResult *model.ParseResult
+	var unit string
+	var err error
+	switch {
+	case perfscript.IsPerfScript(head):
+		p := perfscript.NewParser()
+		p.Resolver = a.config.Symbols
+		parseResult, err = p.Parse(ctx, r)
+	case speedscope.IsSpeedscope(head):
+		p := speedscope.NewParser()
+		parseResult, err = p.Parse(ctx, r)
+		unit = p.Unit
+	default:
+		parseResult, err = a.Parse(ctx, r)
+	}
 	if err != nil {
 		return nil, fmt.Errorf("%w: %v", ErrParseError, err)
 	}
@@ -85,6 +137,7 @@ func (a *JavaCPUAnalyzer) AnalyzeFromReader(ctx context.Context, req *model.Anal
 	if err != nil {
 		return nil, fmt.Errorf("failed to generate flame graph: %w", err)
 	}
+	fg.Unit = unit
 
 	// Step 4: Write flame graph (gzipped JSON)
 	flameGraphFile := filepath.Join(taskDir, "collapsed_data.json.gz")
@@ -104,7 +157,13 @@ func (a *JavaCPUAnalyzer) AnalyzeFromReader(ctx context.Context, req *model.Anal
 		return nil, fmt.Errorf("failed to write call graph: %w", err)
 	}
 
-	// Step 7: Calculate statistics from flame graph
+	// Step 7: Export the samples as a pprof profile
+	pprofFile := filepath.Join(taskDir, pprofProfileFile)
+	if err := writePprofProfile(parseResult.Samples, unit, pprofFile); err != nil {
+		return nil, fmt.Errorf("failed to write pprof profile: %w", err)
+	}
+
+	// Step 8: Calculate statistics from flame graph
 	topFuncsMap := make(model.TopFuncsMap)
 	if fg.ThreadAnalysis != nil {
 		for _, tf := range fg.ThreadAnalysis.TopFunctions {
@@ -112,7 +171,7 @@ func (a *JavaCPUAnalyzer) AnalyzeFromReader(ctx context.Context, req *model.Anal
 		}
 	}
 
-	// Step 8: Build thread stats from flame graph
+	// Step 9: Build thread stats from flame graph
 	threadStats := make([]model.ThreadInfo, 0)
 	if fg.ThreadAnalysis != nil {
 		for _, t := range fg.ThreadAnalysis.Threads {
@@ -125,7 +184,7 @@ func (a *JavaCPUAnalyzer) AnalyzeFromReader(ctx context.Context, req *model.Anal
 		}
 	}
 
-	// Step 9: Build CPUProfilingData
+	// Step 10: Build CPUProfilingData
 	cpuData := &model.CPUProfilingData{
 		FlameGraphFile: flameGraphFile,
 		CallGraphFile:  callGraphFile,
@@ -133,8 +192,17 @@ func (a *JavaCPUAnalyzer) AnalyzeFromReader(ctx context.Context, req *model.Anal
 		TopFuncs:       topFuncsMap,
 		TotalSamples:   parseResult.TotalSamples,
 	}
+	if fg.ThreadAnalysis != nil {
+		for _, ft := range fg.ThreadAnalysis.FrameTypes {
+			cpuData.FrameTypes = append(cpuData.FrameTypes, model.FrameTypeStats{
+				Type:       string(ft.Type),
+				Samples:    ft.Samples,
+				Percentage: ft.Percentage,
+			})
+		}
+	}
 
-	// Step 10: Build output files
+	// Step 11: Build output files
 	outputFiles := []model.OutputFile{
 		{
 			Name:        "Flame Graph",
@@ -148,9 +216,24 @@ func (a *JavaCPUAnalyzer) AnalyzeFromReader(ctx context.Context, req *model.Anal
 			COSKey:      req.TaskUUID + "/callgraph_data.json.gz",
 			ContentType: "application/gzip",
 		},
+		{
+			Name:        "pprof Profile",
+			LocalPath:   pprofFile,
+			COSKey:      req.TaskUUID + "/" + pprofProfileFile,
+			ContentType: "application/gzip",
+		},
 	}
 
-	// Step 11: Convert suggestions
+	// Step 12: Write the timeline of timestamped samples
+	timeline, err := a.writeTimeline(req.TaskUUID, taskDir, parseResult.Samples, perfScriptTimelineResolution, perfScriptFiles)
+	if err != nil {
+		return nil, err
+	}
+	if timeline != nil {
+		outputFiles = append(outputFiles, *timeline)
+	}
+
+	// Step 13: Convert suggestions
 	suggestions := make([]model.SuggestionItem, 0, len(parseResult.Suggestions))
 	for _, sug := range parseResult.Suggestions {
 		suggestions = append(suggestions, model.SuggestionItem{
@@ -159,8 +242,9 @@ func (a *JavaCPUAnalyzer) AnalyzeFromReader(ctx context.Context, req *model.Anal
 			Namespace:  sug.Namespace,
 		})
 	}
+	suggestions = append(suggestions, frameTypeSuggestions(cpuData.FrameTypes)...)
 
-	// Step 12: Build response
+	// Step 14: Build response
 	return &model.AnalysisResponse{
 		TaskUUID:     req.TaskUUID,
 		TaskType:     req.TaskType,
@@ -186,5 +270,45 @@ func (a *JavaCPUAnalyzer) GetOutputFiles(taskUUID, taskDir string) []model.Outpu
 			COSKey:      taskUUID + "/callgraph_data.json.gz",
 			ContentType: "application/gzip",
 		},
+		{
+			Name:        "pprof Profile",
+			LocalPath:   filepath.Join(taskDir, pprofProfileFile),
+			COSKey:      taskUUID + "/" + pprofProfileFile,
+			ContentType: "application/gzip",
+		},
+	}
+}
+
+// frameTypeSuggestions suggests checking the JIT when many samples ran in
+// the interpreter.
+func frameTypeSuggestions(frameTypes []model.FrameTypeStats) []model.SuggestionItem {
+	for _, ft := range frameTypes {
+		if ft.Type == string(profiling.FrameInterpreted) && ft.Percentage > interpretedSuggestionPercent {
+			return []model.SuggestionItem{{
+				Suggestion: fmt.Sprintf("%.2f%% 的样本在解释器中执行，建议检查 JIT 预热是否完成，或热点方法是否因过大而未被编译 (-XX:-DontCompileHugeMethods)", ft.Percentage),
+			}}
+		}
+	}
+	return nil
+}
+
+// writePprofProfile writes samples as a gzipped pprof profile. Their values
+// are sample counts, or nanoseconds or bytes as told by unit.
+func writePprofProfile(samples []*model.Sample, unit, outputPath string) error {
+	sampleType, pprofUnit := "samples", "count"
+	switch unit {
+	case "ns":
+		sampleType, pprofUnit = "cpu", "nanoseconds"
+	case "bytes":
+		sampleType, pprofUnit = "space", "bytes"
+	}
+	file, err := os.Create(outputPath)
+	if err != nil {
+		return err
+	}
+	if err := pprof.FromSamples(samples, sampleType, pprofUnit).Write(file); err != nil {
+		file.Close()
+		return err
 	}
+	return file.Close()
 }
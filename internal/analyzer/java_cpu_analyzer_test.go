@@ -7,9 +7,11 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/google/pprof/profile"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
+	"github.com/perf-analysis/internal/flamegraph"
 	"github.com/perf-analysis/pkg/model"
 )
 
@@ -70,6 +72,91 @@ main-thread;java.lang.Thread.run;com.example.App.init 30`
 	assert.Contains(t, cpuData.CallGraphFile, "callgraph_data.json.gz")
 }
 
+func TestJavaCPUAnalyzer_Analyze_FrameTypes(t *testing.T) {
+	tempDir := t.TempDir()
+	analyzer := NewJavaCPUAnalyzer(&BaseAnalyzerConfig{OutputDir: tempDir, TopFuncsN: 10})
+
+	input := `main-1/1;java/lang/Thread.run_[j];com/example/App.main_[j];com/example/App.parse_[0] 70
+main-1/1;java/lang/Thread.run_[j];com/example/App.main_[j] 20
+main-1/1;java/lang/Thread.run_[j];Interpreter 10`
+
+	req := &model.AnalysisRequest{
+		TaskUUID:     "test-frame-types",
+		TaskType:     model.TaskTypeJava,
+		ProfilerType: model.ProfilerTypePerf,
+		OutputDir:    tempDir,
+	}
+	result, err := analyzer.AnalyzeFromReader(context.Background(), req, strings.NewReader(input))
+	require.NoError(t, err)
+
+	cpuData := result.Data.(*model.CPUProfilingData)
+	assert.Equal(t, []model.FrameTypeStats{
+		{Type: "interpreted", Samples: 70, Percentage: 70},
+		{Type: "jit", Samples: 20, Percentage: 20},
+		{Type: "native", Samples: 10, Percentage: 10},
+	}, cpuData.FrameTypes)
+
+	// Most samples ran in the interpreter
+	require.Len(t, result.Suggestions, 1)
+	assert.Contains(t, result.Suggestions[0].Suggestion, "70.00%")
+}
+
+func TestJavaCPUAnalyzer_Analyze_PerfScript(t *testing.T) {
+	analyzer := NewJavaCPUAnalyzer(nil)
+
+	input := `java 4321/4330 [002] 100.100000: 10101010 cpu-clock:pppH:
+	    7f3a1c2b4e10 malloc+0x20 (/usr/lib64/libc.so.6)
+	    7f3a1c000789 start_thread+0xd9 (/usr/lib64/libpthread.so.0)
+
+java 4321/4330 [002] 101.300000: 10101010 cpu-clock:pppH:
+	    7f3a1c000789 start_thread+0xd9 (/usr/lib64/libpthread.so.0)
+`
+
+	req := &model.AnalysisRequest{
+		TaskUUID:     "test-perf-script-uuid",
+		TaskType:     model.TaskTypeGeneric,
+		ProfilerType: model.ProfilerTypePerf,
+		OutputDir:    t.TempDir(),
+	}
+
+	result, err := analyzer.AnalyzeFromReader(context.Background(), req, strings.NewReader(input))
+	require.NoError(t, err)
+	assert.Equal(t, 2, result.TotalRecords)
+
+	require.Len(t, result.OutputFiles, 4)
+	assert.Equal(t, "test-perf-script-uuid/timeline_data.json.gz", result.OutputFiles[3].COSKey)
+	timeline, err := flamegraph.ReadTimelineGzip(result.OutputFiles[3].LocalPath)
+	require.NoError(t, err)
+	assert.Len(t, timeline.Buckets, 13)
+	assert.Equal(t, []string{"start_thread", "malloc"}, timeline.Stacks[0].CallStack)
+}
+
+func TestJavaCPUAnalyzer_Analyze_Speedscope(t *testing.T) {
+	analyzer := NewJavaCPUAnalyzer(nil)
+
+	input := `{
+  "$schema": "https://www.speedscope.app/file-format-schema.json",
+  "shared": {"frames": [{"name": "main"}, {"name": "parse"}]},
+  "profiles": [{"type": "sampled", "name": "app", "unit": "microseconds", "startValue": 0, "endValue": 3,
+    "samples": [[0, 1], [0]], "weights": [2, 1]}]
+}`
+
+	req := &model.AnalysisRequest{
+		TaskUUID:     "test-speedscope-uuid",
+		TaskType:     model.TaskTypeGeneric,
+		ProfilerType: model.ProfilerTypePerf,
+		OutputDir:    t.TempDir(),
+	}
+
+	result, err := analyzer.AnalyzeFromReader(context.Background(), req, strings.NewReader(input))
+	require.NoError(t, err)
+	assert.Equal(t, 3000, result.TotalRecords)
+
+	fg := readFlameGraph(t, result.OutputFiles[0].LocalPath)
+	assert.Equal(t, "ns", fg.Unit)
+	assert.Equal(t, int64(3000), fg.TotalSamples)
+}
+
 func TestJavaCPUAnalyzer_Analyze_EmptyData(t *testing.T) {
 	tempDir := t.TempDir()
 	config := &BaseAnalyzerConfig{
@@ -141,7 +228,7 @@ func TestJavaCPUAnalyzer_GetOutputFiles(t *testing.T) {
 
 	files := analyzer.GetOutputFiles("test-uuid", "/tmp/test-uuid")
 
-	assert.Len(t, files, 2)
+	assert.Len(t, files, 3)
 
 	// Check flame graph file
 	assert.Equal(t, "/tmp/test-uuid/collapsed_data.json.gz", files[0].LocalPath)
@@ -150,6 +237,49 @@ func TestJavaCPUAnalyzer_GetOutputFiles(t *testing.T) {
 	// Check call graph file
 	assert.Equal(t, "/tmp/test-uuid/callgraph_data.json.gz", files[1].LocalPath)
 	assert.Equal(t, "test-uuid/callgraph_data.json.gz", files[1].COSKey)
+
+	// Check pprof profile
+	assert.Equal(t, "/tmp/test-uuid/profile.pb.gz", files[2].LocalPath)
+	assert.Equal(t, "test-uuid/profile.pb.gz", files[2].COSKey)
+}
+
+func TestJavaCPUAnalyzer_Analyze_PprofExport(t *testing.T) {
+	analyzer := NewJavaCPUAnalyzer(nil)
+
+	input := `main-?/1234;java/lang/Thread.run_[j];com/example/App.main:42_[j];com/example/App.parse_[i] 30
+main-?/1234;java/lang/Thread.run_[j];malloc 10
+`
+	req := &model.AnalysisRequest{
+		TaskUUID:     "test-pprof-uuid",
+		TaskType:     model.TaskTypeJava,
+		ProfilerType: model.ProfilerTypePerf,
+		OutputDir:    t.TempDir(),
+	}
+
+	result, err := analyzer.AnalyzeFromReader(context.Background(), req, strings.NewReader(input))
+	require.NoError(t, err)
+	require.Len(t, result.OutputFiles, 3)
+	assert.Equal(t, "test-pprof-uuid/profile.pb.gz", result.OutputFiles[2].COSKey)
+
+	f, err := os.Open(result.OutputFiles[2].LocalPath)
+	require.NoError(t, err)
+	defer f.Close()
+	p, err := profile.Parse(f)
+	require.NoError(t, err)
+
+	require.Len(t, p.SampleType, 1)
+	assert.Equal(t, "samples", p.SampleType[0].Type)
+	require.Len(t, p.Sample, 2)
+	assert.Equal(t, []int64{30}, p.Sample[0].Value)
+	assert.Equal(t, []string{"main"}, p.Sample[0].Label["thread"])
+
+	// The inlined frame is a line of the location of its caller
+	leaf := p.Sample[0].Location[0]
+	require.Len(t, leaf.Line, 2)
+	assert.Equal(t, "com/example/App.parse", leaf.Line[0].Function.Name)
+	assert.Equal(t, "com/example/App.main", leaf.Line[1].Function.Name)
+	assert.Equal(t, "com/example/App.java", leaf.Line[1].Function.Filename)
+	assert.Equal(t, int64(42), leaf.Line[1].Line)
 }
 
 func TestJavaCPUAnalyzer_OutputFilesCreated(t *testing.T) {
@@ -15,6 +15,10 @@ import (
 	"github.com/perf-analysis/pkg/utils"
 )
 
+func init() {
+	Register(model.TaskTypeJavaHeap, AnyProfiler, func(c *BaseAnalyzerConfig) Analyzer { return NewJavaHeapAnalyzer(c) })
+}
+
 // JavaHeapAnalyzer analyzes Java heap dump (HPROF) files.
 type JavaHeapAnalyzer struct {
 	config     *BaseAnalyzerConfig
@@ -40,10 +44,26 @@ func NewJavaHeapAnalyzer(config *BaseAnalyzerConfig, opts ...JavaHeapAnalyzerOpt
 	hprofOpts := hprof.DefaultParserOptions()
 	// Pass logger to hprof parser
 	if config.Logger != nil {
-		hprofOpts.Logger = config.Logger
+		hprofOpts.Logger = utils.Scoped(config.Logger, utils.ScopeParser)
 	}
 	// Pass verbose flag to hprof parser (dependency injection)
 	hprofOpts.Verbose = config.Verbose
+	hprofOpts.RollupBiggestObjects = config.RollupBiggestObjects
+	switch config.JVMLayout {
+	case "":
+	case "auto":
+		hprofOpts.SizeMode = hprof.SizeModeAuto
+	default:
+		layout, err := hprof.ParseJVMLayout(config.JVMLayout)
+		if err != nil {
+			if config.Logger != nil {
+				config.Logger.Warn("Ignoring JVM layout: %v", err)
+			}
+			break
+		}
+		hprofOpts.Layout = &layout
+	}
+	hprofOpts.ParallelConfig.ProgressCallback = config.ProgressCallback
 
 	a := &JavaHeapAnalyzer{
 		config:    config,
@@ -91,19 +111,9 @@ func (a *JavaHeapAnalyzer) AnalyzeFromReader(ctx context.Context, req *model.Ana
 	// Create timer for post-parse operations (uses dependency injection via Logger)
 	timer := utils.NewTimer("Post-Parse Operations", utils.WithLogger(a.config.Logger), utils.WithEnabled(a.config.Logger != nil))
 
-	// Step 1: Parse the HPROF data (has its own internal timer)
-	parser := hprof.NewParser(a.hprofOpts)
-	heapResult, err := parser.Parse(ctx, dataReader)
-	if err != nil {
-		return nil, fmt.Errorf("%w: %v", ErrParseError, err)
-	}
-
-	if heapResult.TotalInstances == 0 {
-		return nil, ErrEmptyData
-	}
-
-	// Step 2: Determine output directory
+	// Step 1: Determine output directory
 	var taskDir string
+	var err error
 	timer.TimeFunc("Ensure output directory", func() {
 		taskDir = req.OutputDir
 		if taskDir == "" {
@@ -114,6 +124,47 @@ func (a *JavaHeapAnalyzer) AnalyzeFromReader(ctx context.Context, req *model.Ana
 		return nil, fmt.Errorf("failed to create output directory: %w", err)
 	}
 
+	// Step 2: Parse, compute dominators and serialize the ReferenceGraph as a staged job.
+	// The job persists its progress to job.json so serve mode can display it.
+	serializeOpts := hprof.FastSerializeOptions() // Use fast options with zstd
+	serializeOpts.SourceFile = req.InputFile
+	job, err := hprof.NewAnalysisJob(hprof.AnalysisJobConfig{
+		ID:               req.TaskUUID,
+		TaskDir:          taskDir,
+		InputFile:        req.InputFile,
+		ParserOptions:    a.hprofOpts,
+		SerializeOptions: serializeOpts,
+		StageTimeouts:    a.config.HeapStageTimeouts,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create analysis job: %w", err)
+	}
+
+	heapResult, err := job.Run(ctx, dataReader)
+	if err != nil && job.Status().FailedStage == hprof.JobSerializing {
+		// The refgraph.bin is only needed for serve mode; retry once without reparsing
+		if a.config.Logger != nil {
+			a.config.Logger.Warn("Reference graph serialization failed, retrying: %v", err)
+		}
+		if _, err = job.Retry(ctx, nil); err != nil && a.config.Logger != nil {
+			a.config.Logger.Warn("Reference graph serialization failed: %v", err)
+		}
+		err = nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrParseError, err)
+	}
+
+	if heapResult.TotalInstances == 0 {
+		return nil, ErrEmptyData
+	}
+
+	if stats := job.SerializationStats(); stats != nil && a.config.Logger != nil {
+		a.config.Logger.Info("Reference graph serialized: %d objects, %d refs, %.2f KB (ratio: %.2fx)",
+			stats.Objects, stats.References,
+			float64(stats.CompressedSize)/1024, stats.CompressionRatio)
+	}
+
 	// Step 3: Generate heap analysis report
 	heapReportFile := filepath.Join(taskDir, "heap_analysis.json")
 	timer.TimeFuncWithError("Write heap report", func() error {
@@ -137,6 +188,46 @@ func (a *JavaHeapAnalyzer) AnalyzeFromReader(ctx context.Context, req *model.Ana
 	var suggestions []model.SuggestionItem
 	var heapData *model.HeapAnalysisData
 
+	timer.TimeFunc("Compute string and class loader statistics", func() {
+		// Reading every String of the dump is skipped by quick analyses
+		if a.config.AnalysisProfile != ProfileQuick {
+			if _, statsErr := hprof.ComputeStringStats(heapResult, hprof.DefaultStringStatsLimit); statsErr != nil && a.config.Logger != nil {
+				a.config.Logger.Debug("Skipping string statistics: %v", statsErr)
+			}
+		}
+		hprof.ComputeClassLoaderStats(heapResult)
+	})
+
+	timer.TimeFunc("Compute weak reachability", func() {
+		hprof.ComputeWeakReachability(heapResult)
+	})
+
+	timer.TimeFunc("Detect retention motifs", func() {
+		hprof.ComputeRetentionMotifs(heapResult)
+	})
+
+	timer.TimeFunc("Analyze framework objects", func() {
+		hprof.ComputeFrameworkStats(heapResult, hprof.DefaultFrameworkStatsLimit)
+	})
+
+	timer.TimeFunc("Detect humongous objects", func() {
+		if _, humongousErr := hprof.ComputeHumongousObjects(heapResult, a.config.G1RegionSize, hprof.DefaultHumongousObjectsLimit); humongousErr != nil && a.config.Logger != nil {
+			a.config.Logger.Warn("Skipping humongous objects: %v", humongousErr)
+		}
+	})
+
+	timer.TimeFunc("Detect object cycles", func() {
+		if _, cyclesErr := hprof.ComputeObjectCycles(heapResult, hprof.DefaultObjectCyclesLimit); cyclesErr != nil && a.config.Logger != nil {
+			a.config.Logger.Warn("Skipping object cycles: %v", cyclesErr)
+		}
+	})
+
+	if a.config.OwnershipMap != nil {
+		timer.TimeFunc("Attribute memory to teams", func() {
+			hprof.ComputeMemoryOwnership(heapResult, a.config.OwnershipMap)
+		})
+	}
+
 	timer.TimeFunc("Build top classes", func() {
 		topClasses = a.buildTopClasses(heapResult)
 	})
@@ -157,6 +248,14 @@ func (a *JavaHeapAnalyzer) AnalyzeFromReader(ctx context.Context, req *model.Ana
 			BiggestObjects:    a.buildBiggestObjects(heapResult),
 			ReferenceGraphs:   a.buildReferenceGraphs(heapResult),
 			BusinessRetainers: a.buildBusinessRetainers(heapResult),
+			StringStats:       buildStringStats(heapResult.StringStats),
+			ClassLoaders:      buildClassLoaders(heapResult.ClassLoaders),
+			WeakReachability:  buildWeakReachability(heapResult.WeakReachability),
+			RetentionMotifs:   buildRetentionMotifs(heapResult.RetentionMotifs),
+			FrameworkStats:    buildFrameworkStats(heapResult.FrameworkStats),
+			HumongousObjects:  buildHumongousObjects(heapResult.HumongousObjects),
+			ObjectCycles:      buildObjectCycles(heapResult.ObjectCycles),
+			MemoryOwnership:   buildMemoryOwnership(heapResult.MemoryOwnership),
 		}
 
 		if heapResult.Header != nil {
@@ -197,40 +296,61 @@ func (a *JavaHeapAnalyzer) AnalyzeFromReader(ctx context.Context, req *model.Ana
 		})
 	}
 
-	// Step 9: Serialize ReferenceGraph for advanced analysis in serve mode
-	// Uses async serialization to avoid blocking the main analysis flow
-	var serializeResultChan <-chan *hprof.AsyncSerializationResult
-	if heapResult.RefGraph != nil {
-		timer.TimeFunc("Serialize reference graph", func() {
-			refGraphFile := filepath.Join(taskDir, "refgraph.bin")
-			opts := hprof.FastSerializeOptions() // Use fast options with zstd
-			opts.SourceFile = req.InputFile
-			
-			// Start async serialization - this returns immediately
-			var serializeErr error
-			serializeResultChan, serializeErr = hprof.SerializeToFileAsync(context.Background(), heapResult.RefGraph, refGraphFile, opts)
-			if serializeErr != nil {
+	// Step 8.55: Write thread overview file
+	if heapResult.Threads != nil && heapResult.Threads.TotalThreads > 0 {
+		timer.TimeFunc("Write threads file", func() {
+			threadsFile := filepath.Join(taskDir, "threads.json")
+			if writeErr := a.writeThreads(heapResult.Threads, threadsFile); writeErr != nil {
 				if a.config.Logger != nil {
-					a.config.Logger.Warn("Failed to start reference graph serialization: %v", serializeErr)
+					a.config.Logger.Warn("Failed to write threads file: %v", writeErr)
 				}
-			} else if a.config.Logger != nil {
-				a.config.Logger.Info("Reference graph serialization started (async)")
 			}
 		})
 	}
 
-	// Wait for async serialization to complete before printing summary
-	if serializeResultChan != nil {
-		result := <-serializeResultChan
-		if result.Error != nil {
-			if a.config.Logger != nil {
-				a.config.Logger.Warn("Reference graph serialization failed: %v", result.Error)
+	// Step 8.6: Write CSV/TSV table exports
+	if a.config.TableExportFormat != "" {
+		timer.TimeFunc("Write table exports", func() {
+			if _, writeErr := a.writeTableExports(heapResult, taskDir, a.config.TableExportFormat); writeErr != nil {
+				if a.config.Logger != nil {
+					a.config.Logger.Warn("Failed to write table exports: %v", writeErr)
+				}
 			}
-		} else if result.Stats != nil && a.config.Logger != nil {
-			a.config.Logger.Info("Reference graph serialized: %d objects, %d refs, %.2f KB (ratio: %.2fx)",
-				result.Stats.Objects, result.Stats.References, 
-				float64(result.Stats.CompressedSize)/1024, result.Stats.CompressionRatio)
-		}
+		})
+	}
+
+	// Step 8.7: Write SQLite export
+	if a.config.SQLiteExport && heapResult.RefGraph != nil {
+		timer.TimeFunc("Write SQLite export", func() {
+			sqliteFile := filepath.Join(taskDir, hprof.SQLiteExportFileName)
+			stats, writeErr := heapResult.RefGraph.ExportSQLite(ctx, sqliteFile, hprof.DefaultSQLiteExportOptions())
+			if a.config.Logger == nil {
+				return
+			}
+			if writeErr != nil {
+				a.config.Logger.Warn("Failed to write SQLite export: %v", writeErr)
+				return
+			}
+			a.config.Logger.Info("SQLite export written: %s (%d objects, %d refs, %.2f MB)",
+				sqliteFile, stats.Objects, stats.References, float64(stats.FileSize)/(1024*1024))
+		})
+	}
+
+	// Step 8.8: Write Parquet export
+	if a.config.ParquetExport && heapResult.RefGraph != nil {
+		timer.TimeFunc("Write Parquet export", func() {
+			opts := hprof.DefaultParquetExportOptions()
+			opts.DumpID = req.TaskUUID
+			stats, writeErr := heapResult.RefGraph.ExportParquet(ctx, taskDir, opts)
+			if a.config.Logger == nil {
+				return
+			}
+			if writeErr != nil {
+				a.config.Logger.Warn("Failed to write Parquet export: %v", writeErr)
+				return
+			}
+			a.config.Logger.Info("Parquet export written: %d classes, %d objects", stats.Classes, stats.Objects)
+		})
 	}
 
 	// Print timing summary for post-parse operations
@@ -291,13 +411,18 @@ func (a *JavaHeapAnalyzer) writeHeapReport(result *hprof.HeapAnalysisResult, out
 	return encoder.Encode(result)
 }
 
-// writeClassHistogram writes the class histogram.
+// writeClassHistogram writes the class histogram with every class, so the
+// web UI can page through classes beyond the top N of the summary.
 func (a *JavaHeapAnalyzer) writeClassHistogram(result *hprof.HeapAnalysisResult, outputPath string) error {
+	classes := result.AllClasses
+	if classes == nil {
+		classes = result.TopClasses
+	}
 	histogram := &ClassHistogram{
 		TotalClasses:   result.TotalClasses,
 		TotalInstances: result.TotalInstances,
 		TotalSize:      result.TotalHeapSize,
-		Classes:        result.TopClasses,
+		Classes:        classes,
 	}
 
 	file, err := os.Create(outputPath)
@@ -340,6 +465,7 @@ func (a *JavaHeapAnalyzer) buildTopClasses(result *hprof.HeapAnalysisResult) []m
 				}
 				
 				// Add retainers with depth info
+				heapClass.RetainerMode = string(retainers.Mode)
 				if len(retainers.Retainers) > 0 {
 					for _, r := range retainers.Retainers {
 						heapClass.Retainers = append(heapClass.Retainers, model.HeapRetainer{
@@ -459,26 +585,8 @@ func (a *JavaHeapAnalyzer) buildBiggestObjects(result *hprof.HeapAnalysisResult)
 			ClassName:    obj.ClassName,
 			ShallowSize:  obj.ShallowSize,
 			RetainedSize: obj.RetainedSize,
-		}
-		
-		// Convert fields with size information
-		if len(obj.Fields) > 0 {
-			for _, f := range obj.Fields {
-				field := model.HeapObjectField{
-					Name:         f.Name,
-					Type:         f.Type,
-					Value:        f.Value,
-					ShallowSize:  f.ShallowSize,
-					RetainedSize: f.RetainedSize,
-					HasChildren:  f.HasChildren,
-					IsStatic:     f.IsStatic,
-				}
-				if f.RefID != 0 {
-					field.RefID = formatObjectID(f.RefID)
-					field.RefClass = f.RefClass
-				}
-				bigObj.Fields = append(bigObj.Fields, field)
-			}
+			RolledUpObjects: obj.RolledUpObjects,
+			HasChildren:  obj.HasChildren,
 		}
 		
 		// Convert GC root path
@@ -559,6 +667,350 @@ func (a *JavaHeapAnalyzer) buildGCRootsData(analysis *hprof.GCRootsAnalysis) *mo
 	return data
 }
 
+// buildStringStats converts hprof.StringStats to model.HeapStringStats.
+func buildStringStats(stats *hprof.StringStats) *model.HeapStringStats {
+	if stats == nil {
+		return nil
+	}
+	data := &model.HeapStringStats{
+		TotalCount:     stats.TotalCount,
+		TotalSize:      stats.TotalSize,
+		UniqueCount:    stats.UniqueCount,
+		DuplicateCount: stats.DuplicateCount,
+		DuplicateWaste: stats.DuplicateWaste,
+		Truncated:      stats.Truncated,
+	}
+	for _, d := range stats.TopDuplicates {
+		data.TopDuplicates = append(data.TopDuplicates, model.HeapDuplicateString{
+			Value: d.Value,
+			Count: d.Count,
+			Waste: d.Waste,
+		})
+	}
+	for _, c := range stats.InternCandidates {
+		candidate := model.HeapStringInternCandidate{
+			ClassName: c.ClassName,
+			Field:     c.Field,
+			Static:    c.Static,
+			Count:     c.Count,
+			Distinct:  c.Distinct,
+			Size:      c.Size,
+			Savings:   c.Savings,
+		}
+		for _, v := range c.TopValues {
+			candidate.TopValues = append(candidate.TopValues, model.HeapDuplicateString{
+				Value: v.Value,
+				Count: v.Count,
+				Waste: v.Waste,
+			})
+		}
+		data.InternCandidates = append(data.InternCandidates, candidate)
+	}
+	return data
+}
+
+// buildClassLoaders converts hprof.ClassLoaderStats to model.HeapClassLoaderStats.
+func buildClassLoaders(loaders []*hprof.ClassLoaderStats) []model.HeapClassLoaderStats {
+	data := make([]model.HeapClassLoaderStats, 0, len(loaders))
+	for _, l := range loaders {
+		data = append(data, model.HeapClassLoaderStats{
+			ClassName:      l.ClassName,
+			Instances:      l.Instances,
+			DefinedClasses: l.DefinedClasses,
+		})
+	}
+	return data
+}
+
+// maxWeakReachableClasses is the number of classes of the weak reachability
+// report kept in the analysis data.
+const maxWeakReachableClasses = 20
+
+// buildWeakReachability converts hprof.WeakReachability to model.HeapWeakReachability.
+func buildWeakReachability(report *hprof.WeakReachability) *model.HeapWeakReachability {
+	if report == nil || report.SoftObjects+report.WeakObjects == 0 {
+		return nil
+	}
+	data := &model.HeapWeakReachability{
+		SoftObjects: report.SoftObjects,
+		SoftSize:    report.SoftSize,
+		WeakObjects: report.WeakObjects,
+		WeakSize:    report.WeakSize,
+	}
+	for i, cls := range report.Classes {
+		if i >= maxWeakReachableClasses {
+			break
+		}
+		data.Classes = append(data.Classes, model.HeapWeakReachableClass{
+			ClassName: cls.ClassName,
+			SoftCount: cls.SoftCount,
+			SoftSize:  cls.SoftSize,
+			WeakCount: cls.WeakCount,
+			WeakSize:  cls.WeakSize,
+		})
+	}
+	return data
+}
+
+// maxHumongousEntries is the number of humongous classes and objects kept in
+// the analysis data.
+const maxHumongousEntries = 20
+
+// buildHumongousObjects converts hprof.HumongousObjects to model.HeapHumongousObjects.
+func buildHumongousObjects(report *hprof.HumongousObjects) *model.HeapHumongousObjects {
+	if report == nil || report.Count == 0 {
+		return nil
+	}
+	data := &model.HeapHumongousObjects{
+		RegionSize: report.RegionSize,
+		Count:      report.Count,
+		TotalSize:  report.TotalSize,
+		Regions:    report.Regions,
+		Waste:      report.Waste,
+	}
+	for i, cls := range report.Classes {
+		if i >= maxHumongousEntries {
+			break
+		}
+		data.Classes = append(data.Classes, model.HeapHumongousClass{
+			ClassName: cls.ClassName,
+			Count:     cls.Count,
+			TotalSize: cls.TotalSize,
+			Waste:     cls.Waste,
+		})
+	}
+	for i, obj := range report.Objects {
+		if i >= maxHumongousEntries {
+			break
+		}
+		data.Objects = append(data.Objects, model.HeapHumongousObject{
+			ObjectID:  formatObjectID(obj.ObjectID),
+			ClassName: obj.ClassName,
+			Size:      obj.Size,
+			Regions:   obj.Regions,
+			Waste:     obj.Waste,
+		})
+	}
+	return data
+}
+
+// buildMemoryOwnership converts hprof.MemoryOwnership to model.HeapMemoryOwnership.
+func buildMemoryOwnership(report *hprof.MemoryOwnership) *model.HeapMemoryOwnership {
+	if report == nil {
+		return nil
+	}
+	data := &model.HeapMemoryOwnership{TotalSize: report.TotalSize}
+	for _, t := range report.Teams {
+		team := model.HeapTeamOwnership{
+			Team:          t.Team,
+			ClassCount:    t.ClassCount,
+			InstanceCount: t.InstanceCount,
+			ShallowSize:   t.ShallowSize,
+			RetainedSize:  t.RetainedSize,
+			Percentage:    t.Percentage,
+		}
+		for _, cls := range t.TopClasses {
+			team.TopClasses = append(team.TopClasses, model.HeapClassOwnership{
+				ClassName:    cls.ClassName,
+				RetainedSize: cls.RetainedSize,
+			})
+		}
+		data.Teams = append(data.Teams, team)
+	}
+	return data
+}
+
+// buildObjectCycles converts hprof.ObjectCycles to model.HeapObjectCycles.
+func buildObjectCycles(report *hprof.ObjectCycles) *model.HeapObjectCycles {
+	if report == nil || report.Count == 0 {
+		return nil
+	}
+	data := &model.HeapObjectCycles{
+		Count:     report.Count,
+		Objects:   report.Objects,
+		TotalSize: report.TotalSize,
+	}
+	for _, cycle := range report.Cycles {
+		c := model.HeapObjectCycle{
+			ObjectID:  formatObjectID(cycle.ObjectID),
+			Objects:   cycle.Objects,
+			TotalSize: cycle.TotalSize,
+			Entries:   cycle.Entries,
+		}
+		for _, cls := range cycle.Classes {
+			c.Classes = append(c.Classes, model.HeapCycleClass{
+				ClassName: cls.ClassName,
+				Count:     cls.Count,
+				TotalSize: cls.TotalSize,
+			})
+		}
+		data.Cycles = append(data.Cycles, c)
+	}
+	return data
+}
+
+// maxRetentionMotifs is the number of retention motifs kept in the analysis data.
+const maxRetentionMotifs = 20
+
+// buildRetentionMotifs converts hprof.RetentionMotifs to model.HeapRetentionMotif.
+func buildRetentionMotifs(motifs *hprof.RetentionMotifs) []model.HeapRetentionMotif {
+	if motifs == nil {
+		return nil
+	}
+	var data []model.HeapRetentionMotif
+	for i, m := range motifs.Motifs {
+		if i >= maxRetentionMotifs {
+			break
+		}
+		motif := model.HeapRetentionMotif{
+			Kind:            string(m.Kind),
+			Holder:          m.Holder,
+			CollectionClass: m.CollectionClass,
+			Count:           m.Count,
+			Entries:         m.Entries,
+			RetainedSize:    m.RetainedSize,
+		}
+		for _, e := range m.Examples {
+			motif.Examples = append(motif.Examples, model.HeapRetentionMotifExample{
+				ObjectID:      formatObjectID(e.ObjectID),
+				HolderID:      formatObjectID(e.HolderID),
+				Entries:       e.Entries,
+				RetainedSize:  e.RetainedSize,
+				DuplicateKeys: e.DuplicateKeys,
+			})
+		}
+		data = append(data, motif)
+	}
+	return data
+}
+
+// buildFrameworkStats converts hprof.FrameworkStats to model.HeapFrameworkStats.
+func buildFrameworkStats(stats *hprof.FrameworkStats) *model.HeapFrameworkStats {
+	if stats == nil || (stats.Sessions == nil && stats.Caches == nil && stats.PersistenceContexts == nil &&
+		stats.Netty == nil && stats.Kafka == nil) {
+		return nil
+	}
+	data := &model.HeapFrameworkStats{FieldsRead: stats.FieldsRead}
+	if s := stats.Sessions; s != nil {
+		data.Sessions = &model.HeapSessionStats{
+			Count:        s.Count,
+			Attributes:   s.Attributes,
+			RetainedSize: s.RetainedSize,
+			AverageSize:  s.AverageSize,
+		}
+		for _, session := range s.Largest {
+			data.Sessions.Largest = append(data.Sessions.Largest, model.HeapSessionInfo{
+				ObjectID:     formatObjectID(session.ObjectID),
+				ClassName:    session.ClassName,
+				ID:           session.ID,
+				Attributes:   session.Attributes,
+				RetainedSize: session.RetainedSize,
+			})
+		}
+	}
+	if c := stats.Caches; c != nil {
+		data.Caches = &model.HeapCacheStats{Count: c.Count, Entries: c.Entries, RetainedSize: c.RetainedSize}
+		for _, cache := range c.Largest {
+			data.Caches.Largest = append(data.Caches.Largest, model.HeapCacheInfo{
+				ObjectID:     formatObjectID(cache.ObjectID),
+				Library:      cache.Library,
+				ClassName:    cache.ClassName,
+				Holder:       cache.Holder,
+				Entries:      cache.Entries,
+				Weight:       cache.Weight,
+				MaxWeight:    cache.MaxWeight,
+				RetainedSize: cache.RetainedSize,
+			})
+		}
+	}
+	if p := stats.PersistenceContexts; p != nil {
+		data.PersistenceContexts = &model.HeapPersistenceContextStats{
+			Count:        p.Count,
+			Entities:     p.Entities,
+			Collections:  p.Collections,
+			RetainedSize: p.RetainedSize,
+		}
+		for _, context := range p.Largest {
+			info := model.HeapPersistenceContextInfo{
+				ObjectID:     formatObjectID(context.ObjectID),
+				Entities:     context.Entities,
+				Collections:  context.Collections,
+				RetainedSize: context.RetainedSize,
+			}
+			if context.SessionID != 0 {
+				info.SessionID = formatObjectID(context.SessionID)
+			}
+			data.PersistenceContexts.Largest = append(data.PersistenceContexts.Largest, info)
+		}
+	}
+	if n := stats.Netty; n != nil {
+		data.Netty = &model.HeapNettyStats{
+			EventLoops:       n.EventLoops,
+			BuffersChecked:   n.BuffersChecked,
+			BuffersUnchecked: n.BuffersUnchecked,
+			LeakedBuffers:    n.LeakedBuffers,
+			LeakedBytes:      n.LeakedBytes,
+		}
+		for _, pool := range n.Pools {
+			data.Netty.Pools = append(data.Netty.Pools, model.HeapNettyPoolStats{
+				Kind:        pool.Kind,
+				Arenas:      pool.Arenas,
+				Chunks:      pool.Chunks,
+				Capacity:    pool.Capacity,
+				Used:        pool.Used,
+				Utilization: pool.Utilization,
+				HugeChunks:  pool.HugeChunks,
+				HugeSize:    pool.HugeSize,
+			})
+		}
+		for _, leak := range n.Leaks {
+			data.Netty.Leaks = append(data.Netty.Leaks, model.HeapNettyLeakedBuffer{
+				ObjectID:  formatObjectID(leak.ObjectID),
+				ClassName: leak.ClassName,
+				RefCnt:    leak.RefCnt,
+				Capacity:  leak.Capacity,
+				Reachable: leak.Reachable,
+			})
+		}
+	}
+	if k := stats.Kafka; k != nil {
+		data.Kafka = &model.HeapKafkaStats{
+			Producers:         k.Producers,
+			Fetchers:          k.Fetchers,
+			ProducerBatches:   k.ProducerBatches,
+			ProducerBytes:     k.ProducerBytes,
+			CompletedFetches:  k.CompletedFetches,
+			FetchedBytes:      k.FetchedBytes,
+			Records:           k.Records,
+			RecordBytes:       k.RecordBytes,
+			UnattributedBytes: k.UnattributedBytes,
+		}
+		for _, pool := range k.Pools {
+			data.Kafka.Pools = append(data.Kafka.Pools, model.HeapKafkaMemoryPool{
+				ObjectID:  formatObjectID(pool.ObjectID),
+				ClassName: pool.ClassName,
+				Kind:      pool.Kind,
+				Capacity:  pool.Capacity,
+				Available: pool.Available,
+				Used:      pool.Used,
+			})
+		}
+		for _, p := range k.Partitions {
+			data.Kafka.Partitions = append(data.Kafka.Partitions, model.HeapKafkaPartitionBuffer{
+				Topic:            p.Topic,
+				Partition:        p.Partition,
+				ProducerBatches:  p.ProducerBatches,
+				ProducerBytes:    p.ProducerBytes,
+				CompletedFetches: p.CompletedFetches,
+				FetchedBytes:     p.FetchedBytes,
+				Records:          p.Records,
+				RecordBytes:      p.RecordBytes,
+			})
+		}
+	}
+	return data
+}
+
 // writeGCRoots writes the GC roots data to a JSON file.
 func (a *JavaHeapAnalyzer) writeGCRoots(data *model.HeapGCRootsData, outputPath string) error {
 	if data == nil {
@@ -576,21 +1028,56 @@ func (a *JavaHeapAnalyzer) writeGCRoots(data *model.HeapGCRootsData, outputPath
 	return encoder.Encode(data)
 }
 
+// writeThreads writes the thread overview to a JSON file.
+func (a *JavaHeapAnalyzer) writeThreads(overview *hprof.ThreadOverview, outputPath string) error {
+	file, err := os.Create(outputPath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	return json.NewEncoder(file).Encode(overview)
+}
+
 // formatObjectID formats an object ID as a hex string.
 func formatObjectID(id uint64) string {
 	return fmt.Sprintf("0x%x", id)
 }
 
+// minInternSavings is the savings from interning the Strings of a field
+// worth a suggestion.
+const minInternSavings = 1 << 20
+
 // generateSuggestions generates heap-specific suggestions.
 func (a *JavaHeapAnalyzer) generateSuggestions(result *hprof.HeapAnalysisResult) []model.SuggestionItem {
 	var suggestions []model.SuggestionItem
 
+	// Bytes of each class only reachable through soft or weak references
+	collectable := make(map[string]int64)
+	if weak := result.WeakReachability; weak != nil {
+		for _, cls := range weak.Classes {
+			collectable[cls.ClassName] = cls.SoftSize + cls.WeakSize
+		}
+	}
+
 	// Analyze top classes for potential issues
 	for i, cls := range result.TopClasses {
 		if i >= 10 {
 			break
 		}
 
+		// Classes mostly held by soft or weak references are caches the GC
+		// clears under memory pressure, not leaks
+		if size := collectable[cls.ClassName]; cls.TotalSize > 0 && size*2 >= cls.TotalSize &&
+			(cls.Percentage > 10.0 || (a.isPotentialLeakClass(cls.ClassName) && cls.InstanceCount > 10000)) {
+			suggestions = append(suggestions, model.SuggestionItem{
+				Suggestion: fmt.Sprintf("类 %s 有 %.2f MB 仅通过软/弱引用可达，属于内存压力下可被 GC 回收的缓存，通常不是内存泄漏，建议确认缓存容量是否合理",
+					cls.ClassName, float64(size)/(1024*1024)),
+				FuncName: cls.ClassName,
+			})
+			continue
+		}
+
 		// Large memory consumers
 		if cls.Percentage > 10.0 {
 			suggestions = append(suggestions, model.SuggestionItem{
@@ -637,6 +1124,85 @@ func (a *JavaHeapAnalyzer) generateSuggestions(result *hprof.HeapAnalysisResult)
 		}
 	}
 
+	// Soft and weak caches holding a large part of the heap
+	if weak := result.WeakReachability; weak != nil && result.TotalHeapSize > 0 {
+		if size := weak.SoftSize + weak.WeakSize; size*10 > result.TotalHeapSize {
+			suggestions = append(suggestions, model.SuggestionItem{
+				Suggestion: fmt.Sprintf("%.2f MB (%.2f%%) 的对象仅通过软/弱引用可达 (软引用 %d 个, 弱引用 %d 个)，可在内存不足时回收，分析内存泄漏时可排除这部分",
+					float64(size)/(1024*1024), float64(size)*100/float64(result.TotalHeapSize), weak.SoftObjects, weak.WeakObjects),
+			})
+		}
+	}
+
+	// Fields holding many copies of few String values
+	if strs := result.StringStats; strs != nil {
+		for i, c := range strs.InternCandidates {
+			if i >= 3 || c.Savings < minInternSavings {
+				break
+			}
+			field := c.ClassName + "." + c.Field
+			if c.Field == "[]" {
+				field = c.ClassName
+			}
+			suggestions = append(suggestions, model.SuggestionItem{
+				Suggestion: fmt.Sprintf("%s 引用了 %d 个 String，但只有 %d 个不同取值，对其 intern 或去重 (如改用枚举) 可节省约 %.2f MB",
+					field, c.Count, c.Distinct, float64(c.Savings)/(1024*1024)),
+				FuncName: field,
+			})
+		}
+	}
+
+	// Humongous objects fragmenting G1 regions
+	if h := result.HumongousObjects; h != nil && h.Count > 0 && result.TotalHeapSize > 0 && h.TotalSize*20 > result.TotalHeapSize {
+		suggestion := fmt.Sprintf("%d 个对象超过 G1 Region (%d MB) 的一半，按 Humongous 对象分配，共占用 %d 个 Region (%.2f MB)，其中 %.2f MB 为 Region 尾部浪费",
+			h.Count, h.RegionSize>>20, h.Regions, float64(h.TotalSize)/(1024*1024), float64(h.Waste)/(1024*1024))
+		if h.RegionSize < 32<<20 {
+			suggestion += "，可考虑增大 -XX:G1HeapRegionSize 或减小大数组的分配"
+		} else {
+			suggestion += "，建议减小大数组的分配"
+		}
+		item := model.SuggestionItem{Suggestion: suggestion}
+		if len(h.Classes) > 0 {
+			item.FuncName = h.Classes[0].ClassName
+		}
+		suggestions = append(suggestions, item)
+	}
+
+	// Large cycles entered from several places have no owner in the dominator tree
+	if result.ObjectCycles != nil && result.TotalHeapSize > 0 {
+		for _, cycle := range result.ObjectCycles.Cycles {
+			if cycle.TotalSize*20 <= result.TotalHeapSize {
+				break
+			}
+			if cycle.Entries < 2 || len(cycle.Classes) == 0 {
+				continue
+			}
+			suggestions = append(suggestions, model.SuggestionItem{
+				Suggestion: fmt.Sprintf("%d 个对象 (%.2f MB) 组成引用环，并从 %d 个入口被外部引用，环内对象互相持有，Retained Size 归属于它们的共同支配者而非环内任一对象，可从对象 %s 检查环的引用关系",
+					cycle.Objects, float64(cycle.TotalSize)/(1024*1024), cycle.Entries, formatObjectID(cycle.ObjectID)),
+				FuncName: cycle.Classes[0].ClassName,
+			})
+		}
+	}
+
+	// Recurring retention patterns
+	if result.RetentionMotifs != nil {
+		for i, m := range result.RetentionMotifs.Motifs {
+			if i >= 5 {
+				break
+			}
+			suggestions = append(suggestions, model.SuggestionItem{
+				Suggestion: retentionMotifSuggestion(m),
+				FuncName:   m.Holder,
+			})
+		}
+	}
+
+	// Framework objects holding a large part of the heap
+	if result.FrameworkStats != nil && result.TotalHeapSize > 0 {
+		suggestions = append(suggestions, frameworkSuggestions(result.FrameworkStats, result.TotalHeapSize)...)
+	}
+
 	// Overall heap size warning
 	if result.TotalHeapSize > 1024*1024*1024 { // > 1GB
 		suggestions = append(suggestions, model.SuggestionItem{
@@ -653,6 +1219,173 @@ func (a *JavaHeapAnalyzer) generateSuggestions(result *hprof.HeapAnalysisResult)
 		})
 	}
 
+	// Many empty arrays (wasted headers, usually from eager collection allocation)
+	for _, h := range result.ArrayLengthHistograms {
+		if h.EmptyCount > 100000 {
+			suggestions = append(suggestions, model.SuggestionItem{
+				Suggestion: fmt.Sprintf("%s 有 %d 个空数组，浪费 %.2f MB，建议复用共享的空数组常量或延迟分配",
+					h.ClassName, h.EmptyCount, float64(h.EmptySize)/(1024*1024)),
+				FuncName: h.ClassName,
+			})
+		}
+	}
+
+	return suggestions
+}
+
+// retentionMotifSuggestion describes a retention motif for the suggestions.
+func retentionMotifSuggestion(m *hprof.RetentionMotif) string {
+	size := float64(m.RetainedSize) / (1024 * 1024)
+	switch m.Kind {
+	case hprof.MotifListenerList:
+		return fmt.Sprintf("%s 的 %d 个监听器集合共持有 %d 个元素 (%.2f MB)，监听器注册后未注销，建议在对象销毁时移除监听器或使用弱引用",
+			m.Holder, m.Count, m.Entries, size)
+	case hprof.MotifIdentityMapKeys:
+		return fmt.Sprintf("%s 的 %d 个 Map 中存在内容相同的重复 key (%.2f MB)，key 类可能未重写 equals/hashCode 或 key 在插入后被修改，导致条目无法被查找和删除",
+			m.Holder, m.Count, size)
+	default:
+		return fmt.Sprintf("%s 的 %d 个缓存共持有 %d 个条目 (%.2f MB)，缓存没有容量上限或淘汰策略，建议使用有界缓存 (如 Caffeine) 或 LinkedHashMap.removeEldestEntry",
+			m.Holder, m.Count, m.Entries, size)
+	}
+}
+
+// minPersistenceContextEntities is the number of entities of a Hibernate
+// persistence context worth a suggestion, however small it is.
+const minPersistenceContextEntities = 10000
+
+// Netty pools of at least minNettyPoolCapacity bytes used below
+// maxNettyPoolUtilization percent are worth a suggestion.
+const (
+	minNettyPoolCapacity    = 64 << 20
+	maxNettyPoolUtilization = 25.0
+)
+
+// maxKafkaPoolUsage is the percentage of a Kafka producer buffer pool in use
+// worth a suggestion.
+const maxKafkaPoolUsage = 90.0
+
+// frameworkSuggestions describes the sessions, caches and persistence
+// contexts holding over a tenth of the heap, leaked Netty buffers, oversized
+// Netty pools and Kafka client buffers.
+func frameworkSuggestions(stats *hprof.FrameworkStats, heapSize int64) []model.SuggestionItem {
+	var suggestions []model.SuggestionItem
+	if s := stats.Sessions; s != nil && s.RetainedSize*10 > heapSize {
+		item := model.SuggestionItem{
+			Suggestion: fmt.Sprintf("%d 个 HTTP Session 共持有 %.2f MB (平均 %.2f KB，%d 个属性)，建议缩短 Session 超时时间，或避免在 Session 中存放大对象",
+				s.Count, float64(s.RetainedSize)/(1024*1024), float64(s.AverageSize)/1024, s.Attributes),
+		}
+		if len(s.Largest) > 0 {
+			item.FuncName = s.Largest[0].ClassName
+		}
+		suggestions = append(suggestions, item)
+	}
+
+	if c := stats.Caches; c != nil {
+		for _, cache := range c.Largest {
+			if cache.RetainedSize*10 <= heapSize {
+				break
+			}
+			name := cache.Holder
+			if name == "" {
+				name = cache.ClassName
+			}
+			suggestion := fmt.Sprintf("%s 缓存 %s 有 %d 个条目，占用 %.2f MB",
+				cache.Library, name, cache.Entries, float64(cache.RetainedSize)/(1024*1024))
+			if cache.MaxWeight > 0 {
+				suggestion += fmt.Sprintf("，容量上限 %d，建议评估上限或改用按内存大小计算的 weigher", cache.MaxWeight)
+			} else if stats.FieldsRead {
+				suggestion += "，没有容量上限，建议设置 maximumSize/maximumWeight 或过期时间"
+			}
+			suggestions = append(suggestions, model.SuggestionItem{Suggestion: suggestion, FuncName: name})
+		}
+	}
+
+	if p := stats.PersistenceContexts; p != nil && len(p.Largest) > 0 {
+		largest := p.Largest[0]
+		if largest.RetainedSize*10 > heapSize || largest.Entities >= minPersistenceContextEntities {
+			suggestions = append(suggestions, model.SuggestionItem{
+				Suggestion: fmt.Sprintf("Hibernate Session 的持久化上下文持有 %d 个实体、%d 个集合 (%.2f MB)，一级缓存随 Session 增长，批量处理时建议定期 flush() 和 clear()，或使用 StatelessSession",
+					largest.Entities, largest.Collections, float64(largest.RetainedSize)/(1024*1024)),
+				FuncName: "org.hibernate.engine.internal.StatefulPersistenceContext",
+			})
+		}
+	}
+
+	if n := stats.Netty; n != nil {
+		if n.LeakedBuffers > 0 {
+			item := model.SuggestionItem{
+				Suggestion: fmt.Sprintf("发现 %d 个未释放的 Netty ByteBuf (共 %.2f MB)，引用计数仍为正但已不被 EventLoop 持有，池化内存无法归还；建议在读写完成后调用 release() 或 ReferenceCountUtil.release()，并通过 -Dio.netty.leakDetection.level=paranoid 定位泄漏点",
+					n.LeakedBuffers, float64(n.LeakedBytes)/(1024*1024)),
+			}
+			if len(n.Leaks) > 0 {
+				item.FuncName = n.Leaks[0].ClassName
+			}
+			suggestions = append(suggestions, item)
+		}
+		for _, pool := range n.Pools {
+			if pool.Capacity < minNettyPoolCapacity || pool.Utilization >= maxNettyPoolUtilization {
+				continue
+			}
+			suggestions = append(suggestions, model.SuggestionItem{
+				Suggestion: fmt.Sprintf("Netty %s 内存池共 %d 个 Chunk (%.2f MB)，使用率仅 %.1f%%，建议减少 arena 数量 (io.netty.allocator.numDirectArenas/numHeapArenas) 或调小 chunk 大小",
+					pool.Kind, pool.Chunks, float64(pool.Capacity)/(1024*1024), pool.Utilization),
+				FuncName: "io.netty.buffer.PoolArena",
+			})
+		}
+	}
+
+	if k := stats.Kafka; k != nil {
+		suggestions = append(suggestions, kafkaSuggestions(k, heapSize)...)
+	}
+	return suggestions
+}
+
+// kafkaSuggestions describes the Kafka producer pools close to exhaustion
+// and the producer and consumer buffers holding over a tenth of the heap.
+func kafkaSuggestions(k *hprof.KafkaStats, heapSize int64) []model.SuggestionItem {
+	var suggestions []model.SuggestionItem
+	for _, pool := range k.Pools {
+		if pool.Kind != hprof.KafkaPoolProducer || pool.Capacity == 0 ||
+			float64(pool.Used)*100 < float64(pool.Capacity)*maxKafkaPoolUsage {
+			continue
+		}
+		suggestions = append(suggestions, model.SuggestionItem{
+			Suggestion: fmt.Sprintf("Kafka Producer 缓冲池已使用 %.2f MB / %.2f MB，send() 可能阻塞直至 max.block.ms 超时，建议检查 Broker 吞吐与网络，或调大 buffer.memory",
+				float64(pool.Used)/(1024*1024), float64(pool.Capacity)/(1024*1024)),
+			FuncName: pool.ClassName,
+		})
+	}
+
+	// Name the partition buffering the most, when topics were read
+	top := func(bytes func(*hprof.KafkaPartitionBuffer) int64) string {
+		var largest *hprof.KafkaPartitionBuffer
+		for _, p := range k.Partitions {
+			if largest == nil || bytes(p) > bytes(largest) {
+				largest = p
+			}
+		}
+		if largest == nil || bytes(largest) == 0 {
+			return ""
+		}
+		return fmt.Sprintf("，其中 %s-%d 占 %.2f MB", largest.Topic, largest.Partition, float64(bytes(largest))/(1024*1024))
+	}
+
+	if k.ProducerBytes*10 > heapSize {
+		suggestions = append(suggestions, model.SuggestionItem{
+			Suggestion: fmt.Sprintf("Kafka Producer 积压 %d 个待发送批次，共 %.2f MB%s；建议检查 Broker 可用性与 acks 延迟，或调小 buffer.memory、batch.size",
+				k.ProducerBatches, float64(k.ProducerBytes)/(1024*1024),
+				top(func(p *hprof.KafkaPartitionBuffer) int64 { return p.ProducerBytes })),
+			FuncName: "org.apache.kafka.clients.producer.internals.RecordAccumulator",
+		})
+	}
+	if consumed := k.FetchedBytes + k.RecordBytes; consumed*10 > heapSize {
+		suggestions = append(suggestions, model.SuggestionItem{
+			Suggestion: fmt.Sprintf("Kafka Consumer 缓存了 %d 个未消费的拉取结果和 %d 条已反序列化的记录，共 %.2f MB%s；建议调小 max.poll.records、fetch.max.bytes 或 max.partition.fetch.bytes，并避免在 poll() 之间长时间持有 ConsumerRecords",
+				k.CompletedFetches, k.Records, float64(consumed)/(1024*1024),
+				top(func(p *hprof.KafkaPartitionBuffer) int64 { return p.FetchedBytes + p.RecordBytes })),
+			FuncName: "org.apache.kafka.clients.consumer.internals.Fetcher",
+		})
+	}
 	return suggestions
 }
 
@@ -11,6 +11,7 @@ import (
 
 	"github.com/perf-analysis/internal/parser/hprof"
 	"github.com/perf-analysis/pkg/model"
+	"github.com/perf-analysis/pkg/writer"
 )
 
 func TestNewJavaHeapAnalyzer(t *testing.T) {
@@ -173,6 +174,233 @@ func TestJavaHeapAnalyzer_isPotentialLeakClass(t *testing.T) {
 	}
 }
 
+func TestJavaHeapAnalyzer_generateSuggestions_SoftCaches(t *testing.T) {
+	analyzer := NewJavaHeapAnalyzer(nil)
+	result := &hprof.HeapAnalysisResult{
+		TotalHeapSize: 1000 << 20,
+		TopClasses: []*hprof.ClassStats{
+			{ClassName: "byte[]", InstanceCount: 100, TotalSize: 400 << 20, Percentage: 40},
+			{ClassName: "com.example.Session", InstanceCount: 100, TotalSize: 200 << 20, Percentage: 20},
+		},
+		WeakReachability: &hprof.WeakReachability{
+			SoftObjects: 80,
+			SoftSize:    300 << 20,
+			Classes: []*hprof.WeakReachableClass{
+				{ClassName: "byte[]", SoftCount: 80, SoftSize: 300 << 20},
+			},
+		},
+	}
+
+	suggestions := analyzer.generateSuggestions(result)
+	require.Len(t, suggestions, 3)
+	assert.Equal(t, "byte[]", suggestions[0].FuncName)
+	assert.Contains(t, suggestions[0].Suggestion, "软/弱引用")
+	assert.Equal(t, "com.example.Session", suggestions[1].FuncName)
+	assert.Contains(t, suggestions[1].Suggestion, "内存泄漏")
+	assert.Contains(t, suggestions[2].Suggestion, "300.00 MB (30.00%)")
+}
+
+func TestJavaHeapAnalyzer_generateSuggestions_RetentionMotifs(t *testing.T) {
+	analyzer := NewJavaHeapAnalyzer(nil)
+	result := &hprof.HeapAnalysisResult{
+		RetentionMotifs: &hprof.RetentionMotifs{Motifs: []*hprof.RetentionMotif{
+			{Kind: hprof.MotifListenerList, Holder: "com.example.Button.listeners", Count: 3, Entries: 900, RetainedSize: 2 << 20},
+			{Kind: hprof.MotifIdentityMapKeys, Holder: "com.example.Registry.byKey", Count: 1, Entries: 500},
+		}},
+	}
+
+	suggestions := analyzer.generateSuggestions(result)
+	require.Len(t, suggestions, 2)
+	assert.Equal(t, "com.example.Button.listeners", suggestions[0].FuncName)
+	assert.Contains(t, suggestions[0].Suggestion, "900 个元素 (2.00 MB)")
+	assert.Contains(t, suggestions[1].Suggestion, "equals/hashCode")
+}
+
+func TestJavaHeapAnalyzer_generateSuggestions_Frameworks(t *testing.T) {
+	analyzer := NewJavaHeapAnalyzer(nil)
+	result := &hprof.HeapAnalysisResult{
+		TotalHeapSize: 100 << 20,
+		FrameworkStats: &hprof.FrameworkStats{
+			FieldsRead: true,
+			Sessions: &hprof.SessionStats{Count: 500, Attributes: 4000, RetainedSize: 20 << 20, AverageSize: 40 << 10,
+				Largest: []*hprof.SessionInfo{{ObjectID: 0x10, ClassName: "org.apache.catalina.session.StandardSession"}}},
+			Caches: &hprof.CacheStats{Count: 3, Largest: []*hprof.CacheInfo{
+				{ObjectID: 0x20, Library: "guava", Holder: "com.example.UserService.users", Entries: 90000, RetainedSize: 30 << 20},
+				{ObjectID: 0x21, Library: "caffeine", ClassName: "com.github.benmanes.caffeine.cache.SSMSW", Entries: 100, MaxWeight: 1000, RetainedSize: 15 << 20},
+				{ObjectID: 0x22, Library: "caffeine", Entries: 10, RetainedSize: 1 << 20},
+			}},
+			PersistenceContexts: &hprof.PersistenceContextStats{Count: 1, Largest: []*hprof.PersistenceContextInfo{
+				{ObjectID: 0x30, Entities: 50000, Collections: 200, RetainedSize: 5 << 20},
+			}},
+		},
+	}
+
+	suggestions := analyzer.generateSuggestions(result)
+	require.Len(t, suggestions, 4)
+	assert.Equal(t, "org.apache.catalina.session.StandardSession", suggestions[0].FuncName)
+	assert.Contains(t, suggestions[0].Suggestion, "500 个 HTTP Session 共持有 20.00 MB (平均 40.00 KB")
+	assert.Equal(t, "com.example.UserService.users", suggestions[1].FuncName)
+	assert.Contains(t, suggestions[1].Suggestion, "没有容量上限")
+	assert.Equal(t, "com.github.benmanes.caffeine.cache.SSMSW", suggestions[2].FuncName)
+	assert.Contains(t, suggestions[2].Suggestion, "容量上限 1000")
+	assert.Contains(t, suggestions[3].Suggestion, "50000 个实体")
+
+	data := buildFrameworkStats(result.FrameworkStats)
+	require.NotNil(t, data)
+	assert.Equal(t, "0x10", data.Sessions.Largest[0].ObjectID)
+	assert.Len(t, data.Caches.Largest, 3)
+	assert.Equal(t, "0x30", data.PersistenceContexts.Largest[0].ObjectID)
+	assert.Empty(t, data.PersistenceContexts.Largest[0].SessionID)
+	assert.Nil(t, buildFrameworkStats(&hprof.FrameworkStats{FieldsRead: true}))
+}
+
+func TestJavaHeapAnalyzer_generateSuggestions_Netty(t *testing.T) {
+	analyzer := NewJavaHeapAnalyzer(nil)
+	result := &hprof.HeapAnalysisResult{
+		TotalHeapSize: 100 << 20,
+		FrameworkStats: &hprof.FrameworkStats{
+			FieldsRead: true,
+			Netty: &hprof.NettyStats{
+				Pools: []*hprof.NettyPoolStats{
+					{Kind: hprof.NettyPoolHeap, Arenas: 8, Chunks: 2, Capacity: 32 << 20, Used: 1 << 20, Utilization: 3.125},
+					{Kind: hprof.NettyPoolDirect, Arenas: 8, Chunks: 16, Capacity: 256 << 20, Used: 16 << 20, Utilization: 6.25},
+				},
+				EventLoops:     8,
+				BuffersChecked: 100,
+				LeakedBuffers:  3,
+				LeakedBytes:    3 << 20,
+				Leaks: []*hprof.NettyLeakedBuffer{
+					{ObjectID: 0x40, ClassName: "io.netty.buffer.PooledUnsafeDirectByteBuf", RefCnt: 1, Capacity: 2 << 20},
+				},
+			},
+		},
+	}
+
+	suggestions := analyzer.generateSuggestions(result)
+	require.Len(t, suggestions, 2)
+	assert.Equal(t, "io.netty.buffer.PooledUnsafeDirectByteBuf", suggestions[0].FuncName)
+	assert.Contains(t, suggestions[0].Suggestion, "3 个未释放的 Netty ByteBuf (共 3.00 MB)")
+	// Only the direct pool is large enough to be worth a suggestion
+	assert.Contains(t, suggestions[1].Suggestion, "Netty direct 内存池共 16 个 Chunk (256.00 MB)，使用率仅 6.2%")
+
+	data := buildFrameworkStats(result.FrameworkStats)
+	require.NotNil(t, data)
+	require.NotNil(t, data.Netty)
+	assert.Len(t, data.Netty.Pools, 2)
+	assert.Equal(t, "0x40", data.Netty.Leaks[0].ObjectID)
+	assert.Nil(t, data.Sessions)
+}
+
+func TestJavaHeapAnalyzer_generateSuggestions_Kafka(t *testing.T) {
+	analyzer := NewJavaHeapAnalyzer(nil)
+	result := &hprof.HeapAnalysisResult{
+		TotalHeapSize: 100 << 20,
+		FrameworkStats: &hprof.FrameworkStats{
+			FieldsRead: true,
+			Kafka: &hprof.KafkaStats{
+				Producers: 1,
+				Fetchers:  1,
+				Pools: []*hprof.KafkaMemoryPool{
+					{ObjectID: 0x50, ClassName: "org.apache.kafka.clients.producer.internals.BufferPool", Kind: hprof.KafkaPoolProducer,
+						Capacity: 32 << 20, Available: 1 << 20, Used: 31 << 20},
+					{ObjectID: 0x51, Kind: hprof.KafkaPoolNetwork, Capacity: 1000, Used: 1000},
+				},
+				ProducerBatches:  200,
+				ProducerBytes:    2 << 20,
+				CompletedFetches: 40,
+				FetchedBytes:     8 << 20,
+				Records:          50000,
+				RecordBytes:      12 << 20,
+				Partitions: []*hprof.KafkaPartitionBuffer{
+					{Topic: "orders", Partition: 3, Records: 30000, RecordBytes: 9 << 20},
+					{Topic: "orders", Partition: 0, ProducerBatches: 200, ProducerBytes: 2 << 20, FetchedBytes: 4 << 20},
+				},
+			},
+		},
+	}
+
+	suggestions := analyzer.generateSuggestions(result)
+	require.Len(t, suggestions, 2)
+	assert.Equal(t, "org.apache.kafka.clients.producer.internals.BufferPool", suggestions[0].FuncName)
+	assert.Contains(t, suggestions[0].Suggestion, "已使用 31.00 MB / 32.00 MB")
+	// The producer batches are too small to be worth a suggestion
+	assert.Equal(t, "org.apache.kafka.clients.consumer.internals.Fetcher", suggestions[1].FuncName)
+	assert.Contains(t, suggestions[1].Suggestion, "40 个未消费的拉取结果和 50000 条已反序列化的记录，共 20.00 MB，其中 orders-3 占 9.00 MB")
+
+	data := buildFrameworkStats(result.FrameworkStats)
+	require.NotNil(t, data)
+	require.NotNil(t, data.Kafka)
+	assert.Equal(t, "0x50", data.Kafka.Pools[0].ObjectID)
+	require.Len(t, data.Kafka.Partitions, 2)
+	assert.Equal(t, "orders", data.Kafka.Partitions[0].Topic)
+	assert.Equal(t, int64(3), data.Kafka.Partitions[0].Partition)
+	assert.Nil(t, data.Netty)
+}
+
+func TestJavaHeapAnalyzer_generateSuggestions_InternCandidates(t *testing.T) {
+	analyzer := NewJavaHeapAnalyzer(nil)
+	result := &hprof.HeapAnalysisResult{
+		StringStats: &hprof.StringStats{InternCandidates: []*hprof.StringInternCandidate{
+			{ClassName: "com.example.Order", Field: "status", Count: 50000, Distinct: 4, Savings: 3 << 20},
+			{ClassName: "java.lang.String[]", Field: "[]", Count: 20000, Distinct: 10, Savings: 1 << 20},
+			{ClassName: "com.example.Order", Field: "note", Count: 100, Distinct: 90, Savings: 1 << 10},
+		}},
+	}
+
+	suggestions := analyzer.generateSuggestions(result)
+	require.Len(t, suggestions, 2)
+	assert.Equal(t, "com.example.Order.status", suggestions[0].FuncName)
+	assert.Contains(t, suggestions[0].Suggestion, "50000 个 String，但只有 4 个不同取值")
+	assert.Contains(t, suggestions[0].Suggestion, "3.00 MB")
+	assert.Equal(t, "java.lang.String[]", suggestions[1].FuncName)
+}
+
+func TestJavaHeapAnalyzer_generateSuggestions_Humongous(t *testing.T) {
+	analyzer := NewJavaHeapAnalyzer(nil)
+	result := &hprof.HeapAnalysisResult{
+		TotalHeapSize: 100 << 20,
+		HumongousObjects: &hprof.HumongousObjects{
+			RegionSize: 4 << 20, Count: 3, TotalSize: 10 << 20, Regions: 4, Waste: 6 << 20,
+			Classes: []*hprof.HumongousClass{{ClassName: "byte[]", Count: 3, TotalSize: 10 << 20}},
+		},
+	}
+
+	suggestions := analyzer.generateSuggestions(result)
+	require.Len(t, suggestions, 1)
+	assert.Equal(t, "byte[]", suggestions[0].FuncName)
+	assert.Contains(t, suggestions[0].Suggestion, "4 个 Region (10.00 MB)，其中 6.00 MB")
+	assert.Contains(t, suggestions[0].Suggestion, "G1HeapRegionSize")
+
+	// A few humongous objects in a large heap are not worth a suggestion
+	result.TotalHeapSize = 1 << 30
+	assert.Empty(t, analyzer.generateSuggestions(result))
+}
+
+func TestJavaHeapAnalyzer_generateSuggestions_ObjectCycles(t *testing.T) {
+	analyzer := NewJavaHeapAnalyzer(nil)
+	result := &hprof.HeapAnalysisResult{
+		TotalHeapSize: 100 << 20,
+		ObjectCycles: &hprof.ObjectCycles{
+			Count: 3, Objects: 1200, TotalSize: 18 << 20,
+			Cycles: []*hprof.ObjectCycle{
+				{Objects: 1000, TotalSize: 10 << 20, Entries: 3, ObjectID: 0x10,
+					Classes: []*hprof.CycleClass{{ClassName: "com.example.Session", Count: 1000, TotalSize: 10 << 20}}},
+				// A cycle with one entry is dominated by it
+				{Objects: 100, TotalSize: 6 << 20, Entries: 1, ObjectID: 0x20,
+					Classes: []*hprof.CycleClass{{ClassName: "com.example.Node", Count: 100, TotalSize: 6 << 20}}},
+				{Objects: 100, TotalSize: 2 << 20, Entries: 2, ObjectID: 0x30,
+					Classes: []*hprof.CycleClass{{ClassName: "com.example.Peer", Count: 100, TotalSize: 2 << 20}}},
+			},
+		},
+	}
+
+	suggestions := analyzer.generateSuggestions(result)
+	require.Len(t, suggestions, 1)
+	assert.Equal(t, "com.example.Session", suggestions[0].FuncName)
+	assert.Contains(t, suggestions[0].Suggestion, "1000 个对象 (10.00 MB) 组成引用环，并从 3 个入口")
+	assert.Contains(t, suggestions[0].Suggestion, "0x10")
+}
+
 func TestFormatBytes(t *testing.T) {
 	tests := []struct {
 		bytes    int64
@@ -240,3 +468,61 @@ func TestFactory_CreateManager_IncludesJavaHeapAnalyzer(t *testing.T) {
 	assert.NotNil(t, analyzer)
 	assert.Equal(t, "java_heap_analyzer", analyzer.Name())
 }
+
+func TestJavaHeapAnalyzer_WriteTableExports(t *testing.T) {
+	result := &hprof.HeapAnalysisResult{
+		TopClasses: []*hprof.ClassStats{
+			{ClassName: "byte[]", InstanceCount: 10, TotalSize: 4096, AvgSize: 409.6, Percentage: 80},
+		},
+		ClassRetainers: map[string]*hprof.ClassRetainers{
+			"byte[]": {
+				ClassName: "byte[]",
+				TotalSize: 4096,
+				Retainers: []*hprof.RetainerInfo{
+					{RetainerClass: "com.example.Cache", FieldName: "data", RetainedCount: 10, RetainedSize: 4096, Percentage: 100, Depth: 1},
+				},
+			},
+		},
+	}
+
+	taskDir := t.TempDir()
+	analyzer := NewJavaHeapAnalyzer(nil)
+	paths, err := analyzer.writeTableExports(result, taskDir, writer.TableFormatTSV)
+	require.NoError(t, err)
+
+	// No dominator tree slice, so only two tables are written
+	require.Len(t, paths, 2)
+	assert.Equal(t, filepath.Join(taskDir, "class_histogram.tsv"), paths[0])
+
+	data, err := os.ReadFile(filepath.Join(taskDir, "class_retainers.tsv"))
+	require.NoError(t, err)
+	assert.Equal(t,
+		"class_name\tretainer_class\tfield_name\tdepth\tretained_count\tretained_size\tpercentage\n"+
+			"byte[]\tcom.example.Cache\tdata\t1\t10\t4096\t100.0000\n",
+		string(data))
+}
+
+func TestJavaHeapAnalyzer_WriteTableExports_MemoryOwnership(t *testing.T) {
+	result := &hprof.HeapAnalysisResult{
+		MemoryOwnership: &hprof.MemoryOwnership{
+			TotalSize: 4096,
+			Teams: []*hprof.TeamOwnership{
+				{Team: "payments", ClassCount: 3, InstanceCount: 10, ShallowSize: 512, RetainedSize: 3072, Percentage: 75},
+				{Team: hprof.UnownedTeam, ClassCount: 1, InstanceCount: 2, ShallowSize: 1024, RetainedSize: 1024, Percentage: 25},
+			},
+		},
+	}
+
+	taskDir := t.TempDir()
+	paths, err := NewJavaHeapAnalyzer(nil).writeTableExports(result, taskDir, writer.TableFormatCSV)
+	require.NoError(t, err)
+	require.Equal(t, []string{filepath.Join(taskDir, "memory_ownership.csv")}, paths)
+
+	data, err := os.ReadFile(paths[0])
+	require.NoError(t, err)
+	assert.Equal(t,
+		"team,class_count,instance_count,shallow_size,retained_size,percentage\n"+
+			"payments,3,10,512,3072,75.0000\n"+
+			"(unowned),1,2,1024,1024,25.0000\n",
+		string(data))
+}
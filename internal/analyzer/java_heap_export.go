@@ -0,0 +1,145 @@
+package analyzer
+
+import (
+	"path/filepath"
+	"sort"
+	"strconv"
+
+	"github.com/perf-analysis/internal/parser/hprof"
+	"github.com/perf-analysis/pkg/writer"
+)
+
+// Base names of the tabular heap exports; the extension follows the table format.
+const (
+	classHistogramTableName  = "class_histogram"
+	classRetainersTableName  = "class_retainers"
+	dominatorTreeTableName   = "dominator_tree"
+	memoryOwnershipTableName = "memory_ownership"
+)
+
+// writeTableExports writes the class histogram, retainer tables, dominator tree
+// slice and team memory ownership as CSV/TSV files for spreadsheet-based offline analysis.
+// Returns the paths of the files written.
+func (a *JavaHeapAnalyzer) writeTableExports(result *hprof.HeapAnalysisResult, taskDir string, format writer.TableFormat) ([]string, error) {
+	tables := []struct {
+		name  string
+		table *writer.Table
+	}{
+		{classHistogramTableName, buildClassHistogramTable(result.TopClasses)},
+		{classRetainersTableName, buildClassRetainersTable(result.ClassRetainers)},
+		{dominatorTreeTableName, buildDominatorTreeTable(result.DominatorTree)},
+		{memoryOwnershipTableName, buildMemoryOwnershipTable(result.MemoryOwnership)},
+	}
+
+	tw := writer.NewTableWriter(format)
+	var paths []string
+	for _, t := range tables {
+		if len(t.table.Rows) == 0 {
+			continue
+		}
+		path := filepath.Join(taskDir, t.name+format.Extension())
+		if err := tw.WriteToFile(t.table, path); err != nil {
+			return paths, err
+		}
+		paths = append(paths, path)
+	}
+	return paths, nil
+}
+
+// buildClassHistogramTable converts the class histogram into a table.
+func buildClassHistogramTable(classes []*hprof.ClassStats) *writer.Table {
+	table := &writer.Table{
+		Header: []string{"class_name", "instance_count", "shallow_size", "retained_size", "avg_size", "percentage"},
+		Rows:   make([][]string, 0, len(classes)),
+	}
+	for _, cls := range classes {
+		table.Rows = append(table.Rows, []string{
+			cls.ClassName,
+			strconv.FormatInt(cls.InstanceCount, 10),
+			strconv.FormatInt(cls.TotalSize, 10),
+			strconv.FormatInt(cls.RetainedSize, 10),
+			strconv.FormatFloat(cls.AvgSize, 'f', 2, 64),
+			strconv.FormatFloat(cls.Percentage, 'f', 4, 64),
+		})
+	}
+	return table
+}
+
+// buildClassRetainersTable flattens per-class retainers into one row per (class, retainer).
+// Classes are ordered by total size descending so the output is stable.
+func buildClassRetainersTable(classRetainers map[string]*hprof.ClassRetainers) *writer.Table {
+	table := &writer.Table{
+		Header: []string{"class_name", "retainer_class", "field_name", "depth", "retained_count", "retained_size", "percentage"},
+	}
+
+	classes := make([]*hprof.ClassRetainers, 0, len(classRetainers))
+	for _, cr := range classRetainers {
+		classes = append(classes, cr)
+	}
+	sort.Slice(classes, func(i, j int) bool {
+		if classes[i].TotalSize != classes[j].TotalSize {
+			return classes[i].TotalSize > classes[j].TotalSize
+		}
+		return classes[i].ClassName < classes[j].ClassName
+	})
+
+	for _, cr := range classes {
+		for _, r := range cr.Retainers {
+			table.Rows = append(table.Rows, []string{
+				cr.ClassName,
+				r.RetainerClass,
+				r.FieldName,
+				strconv.Itoa(r.Depth),
+				strconv.FormatInt(r.RetainedCount, 10),
+				strconv.FormatInt(r.RetainedSize, 10),
+				strconv.FormatFloat(r.Percentage, 'f', 4, 64),
+			})
+		}
+	}
+	return table
+}
+
+// buildDominatorTreeTable converts a flattened dominator tree slice into a table.
+func buildDominatorTreeTable(nodes []*hprof.DominatorTreeNode) *writer.Table {
+	table := &writer.Table{
+		Header: []string{"object_id", "parent_id", "depth", "class_name", "shallow_size", "retained_size", "percentage"},
+		Rows:   make([][]string, 0, len(nodes)),
+	}
+	for _, n := range nodes {
+		parentID := ""
+		if n.ParentID != 0 {
+			parentID = formatObjectID(n.ParentID)
+		}
+		table.Rows = append(table.Rows, []string{
+			formatObjectID(n.ObjectID),
+			parentID,
+			strconv.Itoa(n.Depth),
+			n.ClassName,
+			strconv.FormatInt(n.ShallowSize, 10),
+			strconv.FormatInt(n.RetainedSize, 10),
+			strconv.FormatFloat(n.Percentage, 'f', 4, 64),
+		})
+	}
+	return table
+}
+
+// buildMemoryOwnershipTable converts the team memory ownership into a table.
+func buildMemoryOwnershipTable(report *hprof.MemoryOwnership) *writer.Table {
+	table := &writer.Table{
+		Header: []string{"team", "class_count", "instance_count", "shallow_size", "retained_size", "percentage"},
+	}
+	if report == nil {
+		return table
+	}
+	for _, t := range report.Teams {
+		table.Rows = append(table.Rows, []string{
+			t.Team,
+			strconv.Itoa(t.ClassCount),
+			strconv.FormatInt(t.InstanceCount, 10),
+			strconv.FormatInt(t.ShallowSize, 10),
+			strconv.FormatInt(t.RetainedSize, 10),
+			strconv.FormatFloat(t.Percentage, 'f', 4, 64),
+		})
+	}
+	return table
+}
@@ -0,0 +1,182 @@
+package analyzer
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/perf-analysis/internal/parser/jfr"
+	"github.com/perf-analysis/pkg/model"
+)
+
+func init() {
+	Register(model.TaskTypeJava, model.ProfilerTypeJFR, func(c *BaseAnalyzerConfig) Analyzer { return NewJavaJFRAnalyzer(c) })
+}
+
+var (
+	jfrCPUFiles   = profileFiles{name: "", flameGraph: "collapsed_data.json.gz", callGraph: "callgraph_data.json.gz", timeline: "timeline_data.json.gz"}
+	jfrAllocFiles = profileFiles{name: "Allocation ", flameGraph: "alloc_data.json.gz", callGraph: "alloc_callgraph_data.json.gz", timeline: "alloc_timeline_data.json.gz"}
+	jfrLockFiles  = profileFiles{name: "Lock ", flameGraph: "lock_data.json.gz", callGraph: "lock_callgraph_data.json.gz", timeline: "lock_timeline_data.json.gz", unit: "ns"}
+)
+
+// jfrTimelineResolution is the period by which events are aggregated into
+// the timelines of recordings.
+const jfrTimelineResolution = time.Second
+
+// JavaJFRAnalyzer analyzes Java Flight Recorder recordings, of the JDK or of
+// async-profiler, into CPU, allocation and lock flame graphs.
+type JavaJFRAnalyzer struct {
+	*BaseAnalyzer
+}
+
+// NewJavaJFRAnalyzer creates a new Java JFR analyzer.
+func NewJavaJFRAnalyzer(config *BaseAnalyzerConfig) *JavaJFRAnalyzer {
+	if config == nil {
+		config = DefaultBaseAnalyzerConfig()
+	}
+	if config.AnalysisProfile == "" {
+		config.AnalysisProfile = ProfileStandard
+	}
+
+	return &JavaJFRAnalyzer{
+		BaseAnalyzer: NewBaseAnalyzer(config),
+	}
+}
+
+// Name returns the analyzer name.
+func (a *JavaJFRAnalyzer) Name() string {
+	return "java_jfr_analyzer"
+}
+
+// SupportedTypes returns the task types supported by this analyzer.
+func (a *JavaJFRAnalyzer) SupportedTypes() []model.TaskType {
+	return []model.TaskType{model.TaskTypeJava}
+}
+
+// CanHandle checks if this analyzer can handle the given request.
+func (a *JavaJFRAnalyzer) CanHandle(req *model.AnalysisRequest) bool {
+	return req.TaskType == model.TaskTypeJava && req.ProfilerType == model.ProfilerTypeJFR
+}
+
+// Analyze performs JFR analysis using an input file.
+func (a *JavaJFRAnalyzer) Analyze(ctx context.Context, req *model.AnalysisRequest) (*model.AnalysisResponse, error) {
+	file, err := os.Open(req.InputFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open input file: %w", err)
+	}
+	defer file.Close()
+
+	return a.AnalyzeFromReader(ctx, req, file)
+}
+
+// AnalyzeFromReader performs JFR analysis from a reader. A flame graph and a
+// call graph are written for each kind of events the recording holds.
+func (a *JavaJFRAnalyzer) AnalyzeFromReader(ctx context.Context, req *model.AnalysisRequest, dataReader io.Reader) (*model.AnalysisResponse, error) {
+	// Step 1: Parse the recording
+	parser := jfr.NewParser()
+	parser.TimelineResolution = jfrTimelineResolution
+	rec, err := parser.Parse(ctx, dataReader)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrParseError, err)
+	}
+
+	totalEvents := rec.CPU.Events + rec.Allocation.Events + rec.Lock.Events
+	if totalEvents == 0 {
+		return nil, ErrEmptyData
+	}
+
+	// Step 2: Determine output directory
+	taskDir := req.OutputDir
+	if taskDir == "" {
+		taskDir, err = a.EnsureOutputDir(req.TaskUUID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create output directory: %w", err)
+		}
+	}
+
+	// Step 3: Analyze each profile of the recording
+	data := &model.JFRData{
+		StartTime:     rec.StartTime,
+		DurationNanos: rec.Duration.Nanoseconds(),
+	}
+	var outputFiles []model.OutputFile
+
+	if rec.CPU.Events > 0 {
+		fg, files, err := a.writeProfile(ctx, req.TaskUUID, taskDir, rec.CPU.Samples, jfrCPUFiles)
+		if err != nil {
+			return nil, err
+		}
+		outputFiles = append(outputFiles, files...)
+		timeline, err := a.writeTimeline(req.TaskUUID, taskDir, rec.CPU.Timeline, jfrTimelineResolution, jfrCPUFiles)
+		if err != nil {
+			return nil, err
+		}
+		if timeline != nil {
+			outputFiles = append(outputFiles, *timeline)
+		}
+		data.CPU = &model.CPUProfilingData{
+			FlameGraphFile: files[0].LocalPath,
+			CallGraphFile:  files[1].LocalPath,
+			ThreadStats:    flameGraphThreadStats(fg),
+			TopFuncs:       flameGraphTopFuncs(fg),
+			TotalSamples:   rec.CPU.Events,
+		}
+	}
+
+	if rec.Allocation.Events > 0 {
+		fg, files, err := a.writeProfile(ctx, req.TaskUUID, taskDir, rec.Allocation.Samples, jfrAllocFiles)
+		if err != nil {
+			return nil, err
+		}
+		outputFiles = append(outputFiles, files...)
+		timeline, err := a.writeTimeline(req.TaskUUID, taskDir, rec.Allocation.Timeline, jfrTimelineResolution, jfrAllocFiles)
+		if err != nil {
+			return nil, err
+		}
+		if timeline != nil {
+			outputFiles = append(outputFiles, *timeline)
+		}
+		data.Allocation = &model.AllocationData{
+			FlameGraphFile:   files[0].LocalPath,
+			CallGraphFile:    files[1].LocalPath,
+			ThreadStats:      flameGraphThreadStats(fg),
+			TopAllocators:    flameGraphTopFuncs(fg),
+			TotalAllocations: rec.Allocation.Events,
+			TotalBytes:       rec.Allocation.Total,
+		}
+	}
+
+	if rec.Lock.Events > 0 {
+		fg, files, err := a.writeProfile(ctx, req.TaskUUID, taskDir, rec.Lock.Samples, jfrLockFiles)
+		if err != nil {
+			return nil, err
+		}
+		outputFiles = append(outputFiles, files...)
+		timeline, err := a.writeTimeline(req.TaskUUID, taskDir, rec.Lock.Timeline, jfrTimelineResolution, jfrLockFiles)
+		if err != nil {
+			return nil, err
+		}
+		if timeline != nil {
+			outputFiles = append(outputFiles, *timeline)
+		}
+		data.Lock = &model.LockContentionData{
+			FlameGraphFile:  files[0].LocalPath,
+			CallGraphFile:   files[1].LocalPath,
+			ThreadStats:     flameGraphThreadStats(fg),
+			TopContended:    flameGraphTopFuncs(fg),
+			TotalEvents:     rec.Lock.Events,
+			TotalDelayNanos: rec.Lock.Total,
+		}
+	}
+
+	// Step 4: Build response
+	return &model.AnalysisResponse{
+		TaskUUID:     req.TaskUUID,
+		TaskType:     req.TaskType,
+		TotalRecords: int(totalEvents),
+		OutputFiles:  outputFiles,
+		Data:         data,
+	}, nil
+}
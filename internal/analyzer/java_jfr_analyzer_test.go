@@ -0,0 +1,67 @@
+package analyzer
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/perf-analysis/pkg/model"
+)
+
+func TestJavaJFRAnalyzer_CanHandle(t *testing.T) {
+	analyzer := NewJavaJFRAnalyzer(nil)
+
+	assert.True(t, analyzer.CanHandle(&model.AnalysisRequest{TaskType: model.TaskTypeJava, ProfilerType: model.ProfilerTypeJFR}))
+	assert.False(t, analyzer.CanHandle(&model.AnalysisRequest{TaskType: model.TaskTypeJava, ProfilerType: model.ProfilerTypePerf}))
+}
+
+func TestJavaJFRAnalyzer_Analyze(t *testing.T) {
+	taskDir := t.TempDir()
+	analyzer := NewJavaJFRAnalyzer(&BaseAnalyzerConfig{OutputDir: taskDir, TopFuncsN: 10})
+
+	req := &model.AnalysisRequest{
+		TaskUUID:     "test-jfr-uuid",
+		TaskType:     model.TaskTypeJava,
+		ProfilerType: model.ProfilerTypeJFR,
+		InputFile:    "../../test/profile-cpu.jfr",
+		OutputDir:    taskDir,
+	}
+
+	result, err := analyzer.Analyze(context.Background(), req)
+	require.NoError(t, err)
+
+	assert.Equal(t, 2173, result.TotalRecords)
+	data, ok := result.Data.(*model.JFRData)
+	require.True(t, ok, "Data should be JFRData")
+	require.NotNil(t, data.CPU)
+	assert.Nil(t, data.Allocation, "no allocation events")
+	assert.Nil(t, data.Lock, "no lock events")
+	assert.Equal(t, int64(2173), data.CPU.TotalSamples)
+	assert.NotEmpty(t, data.CPU.ThreadStats)
+	assert.NotEmpty(t, data.CPU.TopFuncs)
+	assert.False(t, data.StartTime.IsZero())
+
+	require.Len(t, result.OutputFiles, 3)
+	assert.Equal(t, "Flame Graph", result.OutputFiles[0].Name)
+	assert.Equal(t, "test-jfr-uuid/timeline_data.json.gz", result.OutputFiles[2].COSKey)
+	assert.Equal(t, "test-jfr-uuid/collapsed_data.json.gz", result.OutputFiles[0].COSKey)
+	for _, file := range result.OutputFiles {
+		_, err := os.Stat(file.LocalPath)
+		assert.NoError(t, err, file.Name)
+	}
+	assert.Equal(t, filepath.Join(taskDir, "callgraph_data.json.gz"), data.CPU.CallGraphFile)
+}
+
+func TestJavaJFRAnalyzer_Analyze_InvalidData(t *testing.T) {
+	analyzer := NewJavaJFRAnalyzer(&BaseAnalyzerConfig{OutputDir: t.TempDir()})
+	req := &model.AnalysisRequest{TaskUUID: "test-jfr-invalid", TaskType: model.TaskTypeJava, ProfilerType: model.ProfilerTypeJFR}
+
+	_, err := analyzer.AnalyzeFromReader(context.Background(), req, strings.NewReader("[main tid=1];main 1\n"))
+	assert.True(t, errors.Is(err, ErrParseError))
+}
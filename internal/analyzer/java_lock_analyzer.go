@@ -0,0 +1,145 @@
+package analyzer
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/perf-analysis/pkg/model"
+)
+
+func init() {
+	Register(model.TaskTypeJava, model.ProfilerTypeAsyncLock, func(c *BaseAnalyzerConfig) Analyzer { return NewJavaLockAnalyzer(c) })
+}
+
+var (
+	lockFiles      = profileFiles{name: "Lock ", flameGraph: "lock_data.json.gz", callGraph: "lock_callgraph_data.json.gz"}
+	lockTotalFiles = profileFiles{name: "Lock ", flameGraph: "lock_data.json.gz", callGraph: "lock_callgraph_data.json.gz", unit: "ns"}
+)
+
+// JavaLockAnalyzer analyzes Java async-profiler lock data, of threads
+// contending for monitors and java.util.concurrent locks.
+type JavaLockAnalyzer struct {
+	*BaseAnalyzer
+}
+
+// NewJavaLockAnalyzer creates a new Java lock analyzer.
+func NewJavaLockAnalyzer(config *BaseAnalyzerConfig) *JavaLockAnalyzer {
+	if config == nil {
+		config = DefaultBaseAnalyzerConfig()
+	}
+	if config.AnalysisProfile == "" {
+		config.AnalysisProfile = ProfileStandard
+	}
+
+	return &JavaLockAnalyzer{
+		BaseAnalyzer: NewBaseAnalyzer(config),
+	}
+}
+
+// Name returns the analyzer name.
+func (a *JavaLockAnalyzer) Name() string {
+	return "java_lock_analyzer"
+}
+
+// SupportedTypes returns the task types supported by this analyzer.
+func (a *JavaLockAnalyzer) SupportedTypes() []model.TaskType {
+	return []model.TaskType{model.TaskTypeJava}
+}
+
+// CanHandle checks if this analyzer can handle the given request.
+func (a *JavaLockAnalyzer) CanHandle(req *model.AnalysisRequest) bool {
+	return req.TaskType == model.TaskTypeJava && req.ProfilerType == model.ProfilerTypeAsyncLock
+}
+
+// Analyze performs Java lock contention analysis using an input file.
+func (a *JavaLockAnalyzer) Analyze(ctx context.Context, req *model.AnalysisRequest) (*model.AnalysisResponse, error) {
+	if req.ProfilerType != model.ProfilerTypeAsyncLock {
+		return nil, fmt.Errorf("java lock analyzer only supports profiler type async_lock, got %v", req.ProfilerType)
+	}
+
+	file, err := os.Open(req.InputFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open input file: %w", err)
+	}
+	defer file.Close()
+
+	return a.AnalyzeFromReader(ctx, req, file)
+}
+
+// AnalyzeFromReader performs Java lock contention analysis from a reader.
+// The counts of the collapsed stacks are numbers of contended lock
+// acquisitions, or contention time in nanoseconds if the request sets
+// RequestParams.Total.
+func (a *JavaLockAnalyzer) AnalyzeFromReader(ctx context.Context, req *model.AnalysisRequest, dataReader io.Reader) (*model.AnalysisResponse, error) {
+	if req.ProfilerType != model.ProfilerTypeAsyncLock {
+		return nil, fmt.Errorf("java lock analyzer only supports profiler type async_lock, got %v", req.ProfilerType)
+	}
+
+	// Step 1: Parse the collapsed data
+	parseResult, err := a.Parse(ctx, dataReader)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrParseError, err)
+	}
+
+	if parseResult.TotalSamples == 0 {
+		return nil, ErrEmptyData
+	}
+
+	// Step 2: Determine output directory
+	taskDir := req.OutputDir
+	if taskDir == "" {
+		taskDir, err = a.EnsureOutputDir(req.TaskUUID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create output directory: %w", err)
+		}
+	}
+
+	// Step 3: Account contention events or time to threads
+	table := newLatencyTable()
+	var totalEvents, totalNanos int64
+	for _, sample := range parseResult.Samples {
+		if req.RequestParams.Total {
+			table.add(sample, 0, sample.Value)
+			totalNanos += sample.Value
+		} else {
+			table.add(sample, sample.Value, 0)
+			totalEvents += sample.Value
+		}
+	}
+
+	// Step 4: Generate and write the flame graph and call graph
+	names := lockFiles
+	if req.RequestParams.Total {
+		names = lockTotalFiles
+	}
+	fg, outputFiles, err := a.writeProfile(ctx, req.TaskUUID, taskDir, parseResult.Samples, names)
+	if err != nil {
+		return nil, err
+	}
+
+	// Step 5: Build LockContentionData
+	lockData := &model.LockContentionData{
+		FlameGraphFile:  outputFiles[0].LocalPath,
+		CallGraphFile:   outputFiles[1].LocalPath,
+		ThreadStats:     flameGraphThreadStats(fg),
+		TopContended:    flameGraphTopFuncs(fg),
+		TotalEvents:     totalEvents,
+		TotalDelayNanos: totalNanos,
+		ThreadLatencies: table.latencies(),
+	}
+
+	totalRecords := totalEvents
+	if req.RequestParams.Total {
+		totalRecords = int64(len(parseResult.Samples))
+	}
+
+	return &model.AnalysisResponse{
+		TaskUUID:     req.TaskUUID,
+		TaskType:     req.TaskType,
+		TotalRecords: int(totalRecords),
+		OutputFiles:  outputFiles,
+		Data:         lockData,
+	}, nil
+}
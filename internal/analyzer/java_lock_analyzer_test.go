@@ -0,0 +1,77 @@
+package analyzer
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/perf-analysis/pkg/model"
+)
+
+const lockInput = `[main tid=1];java/lang/Thread.run;com/example/Cache.get;java.lang.Object_[i] 4
+[worker-1 tid=2];java/lang/Thread.run;com/example/Cache.put;java.lang.Object_[i] 1
+[worker-1 tid=2];java/lang/Thread.run;com/example/Queue.take;java.util.concurrent.locks.ReentrantLock$NonfairSync_[i] 5`
+
+func TestJavaLockAnalyzer_CanHandle(t *testing.T) {
+	analyzer := NewJavaLockAnalyzer(nil)
+
+	assert.Equal(t, "java_lock_analyzer", analyzer.Name())
+	assert.True(t, analyzer.CanHandle(&model.AnalysisRequest{TaskType: model.TaskTypeJava, ProfilerType: model.ProfilerTypeAsyncLock}))
+	assert.False(t, analyzer.CanHandle(&model.AnalysisRequest{TaskType: model.TaskTypeJava, ProfilerType: model.ProfilerTypeAsyncWall}))
+}
+
+func TestJavaLockAnalyzer_Analyze(t *testing.T) {
+	analyzer := NewJavaLockAnalyzer(&BaseAnalyzerConfig{OutputDir: t.TempDir()})
+
+	req := &model.AnalysisRequest{
+		TaskUUID:     "test-lock-uuid",
+		TaskType:     model.TaskTypeJava,
+		ProfilerType: model.ProfilerTypeAsyncLock,
+		OutputDir:    t.TempDir(),
+	}
+
+	result, err := analyzer.AnalyzeFromReader(context.Background(), req, strings.NewReader(lockInput))
+	require.NoError(t, err)
+	assert.Equal(t, 10, result.TotalRecords)
+
+	data, ok := result.Data.(*model.LockContentionData)
+	require.True(t, ok, "Data should be LockContentionData")
+	assert.Contains(t, data.FlameGraphFile, "lock_data.json.gz")
+	assert.Equal(t, int64(10), data.TotalEvents)
+	assert.Zero(t, data.TotalDelayNanos)
+
+	require.Len(t, data.ThreadLatencies, 2)
+	assert.Equal(t, "worker-1", data.ThreadLatencies[0].ThreadName)
+	assert.Equal(t, int64(6), data.ThreadLatencies[0].Samples)
+	assert.InDelta(t, 60.0, data.ThreadLatencies[0].Percentage, 0.001)
+	assert.Equal(t, int64(4), data.ThreadLatencies[1].Samples)
+
+	assert.Empty(t, readFlameGraph(t, data.FlameGraphFile).Unit)
+}
+
+func TestJavaLockAnalyzer_Analyze_Total(t *testing.T) {
+	analyzer := NewJavaLockAnalyzer(&BaseAnalyzerConfig{OutputDir: t.TempDir()})
+
+	req := &model.AnalysisRequest{
+		TaskUUID:      "test-lock-total-uuid",
+		TaskType:      model.TaskTypeJava,
+		ProfilerType:  model.ProfilerTypeAsyncLock,
+		OutputDir:     t.TempDir(),
+		RequestParams: model.RequestParams{Total: true},
+	}
+
+	result, err := analyzer.AnalyzeFromReader(context.Background(), req, strings.NewReader(lockInput))
+	require.NoError(t, err)
+	assert.Equal(t, 3, result.TotalRecords)
+
+	data := result.Data.(*model.LockContentionData)
+	assert.Zero(t, data.TotalEvents)
+	assert.Equal(t, int64(10), data.TotalDelayNanos)
+	assert.Equal(t, int64(6), data.ThreadLatencies[0].TotalNanos)
+	assert.Zero(t, data.ThreadLatencies[0].Samples)
+
+	assert.Equal(t, "ns", readFlameGraph(t, data.FlameGraphFile).Unit)
+}
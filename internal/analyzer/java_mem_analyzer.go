@@ -10,6 +10,10 @@ import (
 	"github.com/perf-analysis/pkg/model"
 )
 
+func init() {
+	Register(model.TaskTypeJava, model.ProfilerTypeAsyncAlloc, func(c *BaseAnalyzerConfig) Analyzer { return NewJavaMemAnalyzer(c) })
+}
+
 // JavaMemAnalyzer analyzes Java async-profiler allocation/memory data.
 type JavaMemAnalyzer struct {
 	*BaseAnalyzer
@@ -0,0 +1,151 @@
+package analyzer
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/perf-analysis/pkg/model"
+	"github.com/perf-analysis/pkg/profiling"
+)
+
+func init() {
+	Register(model.TaskTypeJava, model.ProfilerTypeAsyncWall, func(c *BaseAnalyzerConfig) Analyzer { return NewJavaWallAnalyzer(c) })
+}
+
+// defaultWallInterval is the default wall-clock sampling interval of
+// async-profiler, used when requests do not give the interval.
+const defaultWallInterval = 50 * time.Millisecond
+
+var wallFiles = profileFiles{name: "Wall Clock ", flameGraph: "collapsed_data.json.gz", callGraph: "callgraph_data.json.gz", unit: "ns"}
+
+// JavaWallAnalyzer analyzes Java async-profiler wall-clock data, of threads
+// on and off the CPU. Samples are weighted by the sampling interval, and
+// colored by the state of their thread.
+type JavaWallAnalyzer struct {
+	*BaseAnalyzer
+}
+
+// NewJavaWallAnalyzer creates a new Java wall-clock analyzer.
+func NewJavaWallAnalyzer(config *BaseAnalyzerConfig) *JavaWallAnalyzer {
+	if config == nil {
+		config = DefaultBaseAnalyzerConfig()
+	}
+	if config.AnalysisProfile == "" {
+		config.AnalysisProfile = ProfileStandard
+	}
+
+	return &JavaWallAnalyzer{
+		BaseAnalyzer: NewBaseAnalyzer(config),
+	}
+}
+
+// Name returns the analyzer name.
+func (a *JavaWallAnalyzer) Name() string {
+	return "java_wall_analyzer"
+}
+
+// SupportedTypes returns the task types supported by this analyzer.
+func (a *JavaWallAnalyzer) SupportedTypes() []model.TaskType {
+	return []model.TaskType{model.TaskTypeJava}
+}
+
+// CanHandle checks if this analyzer can handle the given request.
+func (a *JavaWallAnalyzer) CanHandle(req *model.AnalysisRequest) bool {
+	return req.TaskType == model.TaskTypeJava && req.ProfilerType == model.ProfilerTypeAsyncWall
+}
+
+// Analyze performs Java wall-clock profiling analysis using an input file.
+func (a *JavaWallAnalyzer) Analyze(ctx context.Context, req *model.AnalysisRequest) (*model.AnalysisResponse, error) {
+	if req.ProfilerType != model.ProfilerTypeAsyncWall {
+		return nil, fmt.Errorf("java wall analyzer only supports profiler type async_wall, got %v", req.ProfilerType)
+	}
+
+	file, err := os.Open(req.InputFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open input file: %w", err)
+	}
+	defer file.Close()
+
+	return a.AnalyzeFromReader(ctx, req, file)
+}
+
+// AnalyzeFromReader performs Java wall-clock profiling analysis from a
+// reader. The counts of the collapsed stacks are numbers of samples, or
+// nanoseconds if the request sets RequestParams.Total.
+func (a *JavaWallAnalyzer) AnalyzeFromReader(ctx context.Context, req *model.AnalysisRequest, dataReader io.Reader) (*model.AnalysisResponse, error) {
+	if req.ProfilerType != model.ProfilerTypeAsyncWall {
+		return nil, fmt.Errorf("java wall analyzer only supports profiler type async_wall, got %v", req.ProfilerType)
+	}
+
+	// Step 1: Parse the collapsed data
+	parseResult, err := a.Parse(ctx, dataReader)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrParseError, err)
+	}
+
+	if parseResult.TotalSamples == 0 {
+		return nil, ErrEmptyData
+	}
+
+	// Step 2: Determine output directory
+	taskDir := req.OutputDir
+	if taskDir == "" {
+		taskDir, err = a.EnsureOutputDir(req.TaskUUID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create output directory: %w", err)
+		}
+	}
+
+	// Step 3: Weight the samples by the interval and classify their states
+	interval := req.RequestParams.Interval
+	if interval <= 0 {
+		interval = defaultWallInterval.Nanoseconds()
+	}
+	table := newLatencyTable()
+	states := make(map[string]int64)
+	var totalSamples, totalNanos int64
+	for _, sample := range parseResult.Samples {
+		samples := sample.Value
+		if req.RequestParams.Total {
+			samples = sample.Value / interval
+		} else {
+			sample.Value *= interval
+		}
+		sample.State = profiling.ThreadState(sample.CallStack)
+
+		table.add(sample, samples, sample.Value)
+		states[sample.State] += sample.Value
+		totalSamples += samples
+		totalNanos += sample.Value
+	}
+
+	// Step 4: Generate and write the flame graph and call graph
+	fg, outputFiles, err := a.writeProfile(ctx, req.TaskUUID, taskDir, parseResult.Samples, wallFiles)
+	if err != nil {
+		return nil, err
+	}
+
+	// Step 5: Build WallClockData
+	wallData := &model.WallClockData{
+		FlameGraphFile:  outputFiles[0].LocalPath,
+		CallGraphFile:   outputFiles[1].LocalPath,
+		ThreadStats:     flameGraphThreadStats(fg),
+		TopFuncs:        flameGraphTopFuncs(fg),
+		TotalSamples:    totalSamples,
+		IntervalNanos:   interval,
+		TotalNanos:      totalNanos,
+		States:          states,
+		ThreadLatencies: table.latencies(),
+	}
+
+	return &model.AnalysisResponse{
+		TaskUUID:     req.TaskUUID,
+		TaskType:     req.TaskType,
+		TotalRecords: int(totalSamples),
+		OutputFiles:  outputFiles,
+		Data:         wallData,
+	}, nil
+}
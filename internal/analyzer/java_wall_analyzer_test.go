@@ -0,0 +1,119 @@
+package analyzer
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/perf-analysis/internal/flamegraph"
+	"github.com/perf-analysis/pkg/model"
+	"github.com/perf-analysis/pkg/profiling"
+)
+
+const wallInput = `[main tid=1];java/lang/Thread.run;com/example/App.compute 3
+[main tid=1];java/lang/Thread.run;java/lang/Thread.sleep;JVM_Sleep 1
+[worker-1 tid=2];java/lang/Thread.run;jdk/internal/misc/Unsafe.park;Unsafe_Park;pthread_cond_wait 6`
+
+func TestJavaWallAnalyzer_CanHandle(t *testing.T) {
+	analyzer := NewJavaWallAnalyzer(nil)
+
+	assert.Equal(t, "java_wall_analyzer", analyzer.Name())
+	assert.True(t, analyzer.CanHandle(&model.AnalysisRequest{TaskType: model.TaskTypeJava, ProfilerType: model.ProfilerTypeAsyncWall}))
+	assert.False(t, analyzer.CanHandle(&model.AnalysisRequest{TaskType: model.TaskTypeJava, ProfilerType: model.ProfilerTypePerf}))
+}
+
+func TestJavaWallAnalyzer_Analyze(t *testing.T) {
+	analyzer := NewJavaWallAnalyzer(&BaseAnalyzerConfig{OutputDir: t.TempDir()})
+
+	req := &model.AnalysisRequest{
+		TaskUUID:      "test-wall-uuid",
+		TaskType:      model.TaskTypeJava,
+		ProfilerType:  model.ProfilerTypeAsyncWall,
+		OutputDir:     t.TempDir(),
+		RequestParams: model.RequestParams{Interval: 10_000_000},
+	}
+
+	result, err := analyzer.AnalyzeFromReader(context.Background(), req, strings.NewReader(wallInput))
+	require.NoError(t, err)
+	assert.Equal(t, 10, result.TotalRecords)
+
+	data, ok := result.Data.(*model.WallClockData)
+	require.True(t, ok, "Data should be WallClockData")
+	assert.Equal(t, int64(10), data.TotalSamples)
+	assert.Equal(t, int64(100_000_000), data.TotalNanos)
+	assert.Equal(t, int64(30_000_000), data.States[profiling.StateRunning])
+	assert.Equal(t, int64(10_000_000), data.States[profiling.StateSleeping])
+	assert.Equal(t, int64(60_000_000), data.States[profiling.StateWaiting])
+
+	// Threads are listed the slowest first
+	require.Len(t, data.ThreadLatencies, 2)
+	worker := data.ThreadLatencies[0]
+	assert.Equal(t, "worker-1", worker.ThreadName)
+	assert.Equal(t, int64(6), worker.Samples)
+	assert.Equal(t, int64(60_000_000), worker.TotalNanos)
+	assert.InDelta(t, 60.0, worker.Percentage, 0.001)
+	assert.Equal(t, "pthread_cond_wait", worker.TopFrame)
+	main := data.ThreadLatencies[1]
+	assert.Equal(t, map[string]int64{profiling.StateRunning: 30_000_000, profiling.StateSleeping: 10_000_000}, main.States)
+
+	// The flame graph is in nanoseconds, its frames colored by thread state
+	fg := readFlameGraph(t, data.FlameGraphFile)
+	assert.Equal(t, "ns", fg.Unit)
+	assert.Equal(t, int64(100_000_000), fg.Root.Value)
+	assert.Equal(t, profiling.StateWaiting, fg.Root.State)
+}
+
+func TestJavaWallAnalyzer_Analyze_Total(t *testing.T) {
+	analyzer := NewJavaWallAnalyzer(&BaseAnalyzerConfig{OutputDir: t.TempDir()})
+
+	req := &model.AnalysisRequest{
+		TaskUUID:      "test-wall-total-uuid",
+		TaskType:      model.TaskTypeJava,
+		ProfilerType:  model.ProfilerTypeAsyncWall,
+		OutputDir:     t.TempDir(),
+		RequestParams: model.RequestParams{Total: true},
+	}
+
+	input := `[main tid=1];java/lang/Thread.run;com/example/App.compute 150000000`
+	result, err := analyzer.AnalyzeFromReader(context.Background(), req, strings.NewReader(input))
+	require.NoError(t, err)
+
+	data := result.Data.(*model.WallClockData)
+	assert.Equal(t, defaultWallInterval.Nanoseconds(), data.IntervalNanos)
+	assert.Equal(t, int64(150_000_000), data.TotalNanos)
+	assert.Equal(t, int64(3), data.TotalSamples)
+}
+
+func TestJavaWallAnalyzer_Analyze_EmptyData(t *testing.T) {
+	analyzer := NewJavaWallAnalyzer(&BaseAnalyzerConfig{OutputDir: t.TempDir()})
+
+	req := &model.AnalysisRequest{
+		TaskUUID:     "test-empty-uuid",
+		TaskType:     model.TaskTypeJava,
+		ProfilerType: model.ProfilerTypeAsyncWall,
+	}
+
+	_, err := analyzer.AnalyzeFromReader(context.Background(), req, strings.NewReader(""))
+	assert.Equal(t, ErrEmptyData, err)
+}
+
+// readFlameGraph reads a gzipped flame graph written by an analyzer.
+func readFlameGraph(t *testing.T, path string) *flamegraph.FlameGraph {
+	t.Helper()
+
+	f, err := os.Open(path)
+	require.NoError(t, err)
+	defer f.Close()
+	zr, err := gzip.NewReader(f)
+	require.NoError(t, err)
+
+	var fg flamegraph.FlameGraph
+	require.NoError(t, json.NewDecoder(zr).Decode(&fg))
+	return &fg
+}
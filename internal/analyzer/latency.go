@@ -0,0 +1,104 @@
+package analyzer
+
+import (
+	"sort"
+
+	"github.com/perf-analysis/pkg/model"
+	"github.com/perf-analysis/pkg/profiling"
+)
+
+// latencyThread accumulates the latency of a thread.
+type latencyThread struct {
+	latency model.ThreadLatency
+	leaves  map[string]int64 // leaf frame -> weight
+}
+
+// latencyTable accumulates the per-thread latencies of the samples of a
+// latency profile, e.g. wall-clock or lock.
+type latencyTable struct {
+	threads    map[latencyKey]*latencyThread
+	totalNanos int64
+}
+
+// latencyKey identifies the threads of a latency table; threads of
+// collapsed stacks without thread IDs are told apart by their names.
+type latencyKey struct {
+	tid  int
+	name string
+}
+
+func newLatencyTable() *latencyTable {
+	return &latencyTable{threads: make(map[latencyKey]*latencyThread)}
+}
+
+// add accounts a sample of samples samples or events, and of nanos
+// nanoseconds, either of which may be 0 if the profile has none.
+func (t *latencyTable) add(sample *model.Sample, samples, nanos int64) {
+	key := latencyKey{tid: sample.TID, name: sample.ThreadName}
+	th, ok := t.threads[key]
+	if !ok {
+		th = &latencyThread{
+			latency: model.ThreadLatency{TID: sample.TID, ThreadName: sample.ThreadName},
+			leaves:  make(map[string]int64),
+		}
+		t.threads[key] = th
+	}
+
+	th.latency.Samples += samples
+	th.latency.TotalNanos += nanos
+	t.totalNanos += nanos
+
+	weight := nanos
+	if weight == 0 {
+		weight = samples
+	}
+	if sample.State != "" {
+		if th.latency.States == nil {
+			th.latency.States = make(map[string]int64)
+		}
+		th.latency.States[sample.State] += weight
+	}
+	if len(sample.CallStack) > 0 {
+		leaf, _ := profiling.SplitFuncAndModule(sample.CallStack[len(sample.CallStack)-1])
+		th.leaves[leaf] += weight
+	}
+}
+
+// latencies returns the latencies of the threads, the slowest first.
+func (t *latencyTable) latencies() []model.ThreadLatency {
+	weight := func(l *model.ThreadLatency) int64 {
+		if t.totalNanos > 0 {
+			return l.TotalNanos
+		}
+		return l.Samples
+	}
+
+	var total int64
+	for _, th := range t.threads {
+		total += weight(&th.latency)
+	}
+
+	result := make([]model.ThreadLatency, 0, len(t.threads))
+	for _, th := range t.threads {
+		l := th.latency
+		if total > 0 {
+			l.Percentage = float64(weight(&l)) / float64(total) * 100
+		}
+		var best int64
+		for leaf, w := range th.leaves {
+			if w > best || (w == best && leaf < l.TopFrame) {
+				l.TopFrame, best = leaf, w
+			}
+		}
+		result = append(result, l)
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		wi, wj := weight(&result[i]), weight(&result[j])
+		if wi != wj {
+			return wi > wj
+		}
+		return result[i].TID < result[j].TID
+	})
+	return result
+}
@@ -2,7 +2,9 @@ package analyzer
 
 import (
 	"fmt"
+	"sort"
 	"strings"
+	"sync"
 
 	"github.com/perf-analysis/pkg/model"
 )
@@ -18,12 +20,30 @@ const (
 	// ModeJavaAlloc analyzes Java memory allocation from async-profiler alloc data.
 	ModeJavaAlloc AnalysisMode = "java-alloc"
 
+	// ModeJavaWall analyzes Java wall-clock profiles from async-profiler wall data.
+	ModeJavaWall AnalysisMode = "java-wall"
+
+	// ModeJavaLock analyzes Java lock contention from async-profiler lock data.
+	ModeJavaLock AnalysisMode = "java-lock"
+
+	// ModeJavaJFR analyzes Java Flight Recorder recordings.
+	ModeJavaJFR AnalysisMode = "java-jfr"
+
 	// ModeJavaHeap analyzes Java heap dump (HPROF format).
 	ModeJavaHeap AnalysisMode = "java-heap"
 
+	// ModeJavaThreadDump analyzes Java thread dumps (jstack, JFR).
+	ModeJavaThreadDump AnalysisMode = "java-threaddump"
+
+	// ModeJavaGCLog analyzes JVM unified GC logs.
+	ModeJavaGCLog AnalysisMode = "java-gclog"
+
 	// ModeCPU analyzes generic CPU profiling data (collapsed format).
 	ModeCPU AnalysisMode = "cpu"
 
+	// ModeOffCPU analyzes eBPF off-CPU profiles from bcc offcputime.
+	ModeOffCPU AnalysisMode = "offcpu"
+
 	// ModePProfCPU analyzes Go pprof CPU profile.
 	ModePProfCPU AnalysisMode = "pprof-cpu"
 
@@ -52,12 +72,20 @@ type ModeInfo struct {
 	Profiler    model.ProfilerType
 }
 
+// modeConstructors holds the constructors of the modes analyzed by their
+// own analyzer rather than the analyzer registered for their task and
+// profiler types. modeRegistryMu guards it and modeRegistry.
+var (
+	modeRegistryMu   sync.RWMutex
+	modeConstructors = make(map[AnalysisMode]Constructor)
+)
+
 // modeRegistry maps mode names to their metadata.
 var modeRegistry = map[AnalysisMode]*ModeInfo{
 	ModeJavaCPU: {
 		Mode:        ModeJavaCPU,
 		Description: "Java CPU hotspot analysis (async-profiler/perf)",
-		InputFormat: "Collapsed stack format (.collapsed, .data, .txt)",
+		InputFormat: "Collapsed stack format (.collapsed, .data, .txt), perf script output, or speedscope JSON",
 		TaskType:    model.TaskTypeJava,
 		Profiler:    model.ProfilerTypePerf,
 	},
@@ -68,6 +96,27 @@ var modeRegistry = map[AnalysisMode]*ModeInfo{
 		TaskType:    model.TaskTypeJava,
 		Profiler:    model.ProfilerTypeAsyncAlloc,
 	},
+	ModeJavaWall: {
+		Mode:        ModeJavaWall,
+		Description: "Java wall-clock analysis, of threads on and off the CPU (async-profiler wall)",
+		InputFormat: "Collapsed stack format (.collapsed, .data, .txt)",
+		TaskType:    model.TaskTypeJava,
+		Profiler:    model.ProfilerTypeAsyncWall,
+	},
+	ModeJavaLock: {
+		Mode:        ModeJavaLock,
+		Description: "Java lock contention analysis (async-profiler lock)",
+		InputFormat: "Collapsed stack format (.collapsed, .data, .txt)",
+		TaskType:    model.TaskTypeJava,
+		Profiler:    model.ProfilerTypeAsyncLock,
+	},
+	ModeJavaJFR: {
+		Mode:        ModeJavaJFR,
+		Description: "Java Flight Recorder analysis (CPU, allocation and lock profiles)",
+		InputFormat: "JFR recording (.jfr)",
+		TaskType:    model.TaskTypeJava,
+		Profiler:    model.ProfilerTypeJFR,
+	},
 	ModeJavaHeap: {
 		Mode:        ModeJavaHeap,
 		Description: "Java heap dump analysis (HPROF)",
@@ -75,13 +124,34 @@ var modeRegistry = map[AnalysisMode]*ModeInfo{
 		TaskType:    model.TaskTypeJavaHeap,
 		Profiler:    model.ProfilerTypePerf, // Not used for heap
 	},
+	ModeJavaThreadDump: {
+		Mode:        ModeJavaThreadDump,
+		Description: "Java thread dump analysis (thread states, deadlocks, hot monitors)",
+		InputFormat: "jstack or jcmd Thread.print output, or JFR recording with jdk.ThreadDump events",
+		TaskType:    model.TaskTypeThreadDump,
+		Profiler:    model.ProfilerTypePerf, // Not used for thread dumps
+	},
+	ModeJavaGCLog: {
+		Mode:        ModeJavaGCLog,
+		Description: "JVM GC log analysis (pause histogram, allocation and promotion rates, heap trend)",
+		InputFormat: "Unified GC log (-Xlog:gc*), optionally with HPROF heap dumps to correlate",
+		TaskType:    model.TaskTypeGCLog,
+		Profiler:    model.ProfilerTypePerf, // Not used for GC logs
+	},
 	ModeCPU: {
 		Mode:        ModeCPU,
 		Description: "Generic CPU profiling analysis",
-		InputFormat: "Collapsed stack format (.collapsed, .data, .txt)",
+		InputFormat: "Collapsed stack format (.collapsed, .data, .txt), perf script output, or speedscope JSON",
 		TaskType:    model.TaskTypeGeneric,
 		Profiler:    model.ProfilerTypePerf,
 	},
+	ModeOffCPU: {
+		Mode:        ModeOffCPU,
+		Description: "Off-CPU analysis of blocked time by thread and wait reason (bcc offcputime)",
+		InputFormat: "Collapsed stack format of offcputime -f, in microseconds",
+		TaskType:    model.TaskTypeOffCPU,
+		Profiler:    model.ProfilerTypePerf, // Not used for off-CPU
+	},
 	ModePProfCPU: {
 		Mode:        ModePProfCPU,
 		Description: "Go pprof CPU profile analysis",
@@ -117,19 +187,43 @@ var modeRegistry = map[AnalysisMode]*ModeInfo{
 		TaskType:    model.TaskTypePProfMutex,
 		Profiler:    model.ProfilerTypePProf,
 	},
-	ModePProfAll: {
-		Mode:        ModePProfAll,
-		Description: "Batch analysis of all pprof profiles in a directory",
-		InputFormat: "Directory containing pprof subdirectories (cpu/, heap/, goroutine/, etc.)",
-		TaskType:    model.TaskTypePProfCPU, // Primary type
-		Profiler:    model.ProfilerTypePProf,
-	},
+}
+
+// RegisterMode registers an analysis mode, e.g. for the CLI, replacing any
+// previous mode of the same name. A nil constructor analyzes the mode with
+// the analyzer registered for its task and profiler types. This is
+// typically called in init() functions, next to Register.
+func RegisterMode(info ModeInfo, constructor Constructor) {
+	modeRegistryMu.Lock()
+	defer modeRegistryMu.Unlock()
+	modeRegistry[info.Mode] = &info
+	if constructor != nil {
+		modeConstructors[info.Mode] = constructor
+	} else {
+		delete(modeConstructors, info.Mode)
+	}
+}
+
+// modeConstructor returns the constructor of the analyzer of a mode.
+func modeConstructor(mode AnalysisMode) (Constructor, bool) {
+	modeRegistryMu.RLock()
+	constructor, ok := modeConstructors[mode]
+	info := modeRegistry[mode]
+	modeRegistryMu.RUnlock()
+
+	if ok {
+		return constructor, true
+	}
+	if info == nil {
+		return nil, false
+	}
+	return Lookup(info.TaskType, info.Profiler)
 }
 
 // ParseMode parses a mode string into AnalysisMode.
 func ParseMode(s string) (AnalysisMode, error) {
 	mode := AnalysisMode(strings.ToLower(strings.TrimSpace(s)))
-	if _, ok := modeRegistry[mode]; ok {
+	if _, ok := GetModeInfo(mode); ok {
 		return mode, nil
 	}
 	return "", fmt.Errorf("unknown analysis mode: %q (valid: %s)", s, ValidModes())
@@ -137,33 +231,50 @@ func ParseMode(s string) (AnalysisMode, error) {
 
 // GetModeInfo returns the metadata for a mode.
 func GetModeInfo(mode AnalysisMode) (*ModeInfo, bool) {
+	modeRegistryMu.RLock()
+	defer modeRegistryMu.RUnlock()
 	info, ok := modeRegistry[mode]
 	return info, ok
 }
 
 // ValidModes returns a comma-separated list of valid mode names.
 func ValidModes() string {
-	modes := make([]string, 0, len(modeRegistry))
-	for mode := range modeRegistry {
-		modes = append(modes, string(mode))
+	infos := AllModes()
+	modes := make([]string, 0, len(infos))
+	for _, info := range infos {
+		modes = append(modes, string(info.Mode))
 	}
 	return strings.Join(modes, ", ")
 }
 
-// AllModes returns all registered mode information.
+// AllModes returns all registered mode information, the built-in modes
+// first.
 func AllModes() []*ModeInfo {
+	modeRegistryMu.RLock()
+	defer modeRegistryMu.RUnlock()
+
 	result := make([]*ModeInfo, 0, len(modeRegistry))
 	// Return in a consistent order
 	order := []AnalysisMode{
-		ModeJavaCPU, ModeJavaAlloc, ModeJavaHeap, ModeCPU,
+		ModeJavaCPU, ModeJavaAlloc, ModeJavaWall, ModeJavaLock, ModeJavaJFR, ModeJavaHeap, ModeJavaThreadDump, ModeJavaGCLog, ModeCPU, ModeOffCPU,
 		ModePProfCPU, ModePProfHeap, ModePProfGoroutine, ModePProfBlock, ModePProfMutex, ModePProfAll,
 	}
+	builtin := make(map[AnalysisMode]bool, len(order))
 	for _, mode := range order {
+		builtin[mode] = true
 		if info, ok := modeRegistry[mode]; ok {
 			result = append(result, info)
 		}
 	}
-	return result
+
+	var others []*ModeInfo
+	for mode, info := range modeRegistry {
+		if !builtin[mode] {
+			others = append(others, info)
+		}
+	}
+	sort.Slice(others, func(i, j int) bool { return others[i].Mode < others[j].Mode })
+	return append(result, others...)
 }
 
 // String returns the string representation of the mode.
@@ -17,8 +17,13 @@ func TestParseMode(t *testing.T) {
 		{"java-cpu uppercase", "JAVA-CPU", ModeJavaCPU, false},
 		{"java-cpu with spaces", "  java-cpu  ", ModeJavaCPU, false},
 		{"java-alloc", "java-alloc", ModeJavaAlloc, false},
+		{"java-wall", "java-wall", ModeJavaWall, false},
+		{"java-lock", "java-lock", ModeJavaLock, false},
 		{"java-heap", "java-heap", ModeJavaHeap, false},
+		{"java-threaddump", "java-threaddump", ModeJavaThreadDump, false},
+		{"java-gclog", "java-gclog", ModeJavaGCLog, false},
 		{"cpu", "cpu", ModeCPU, false},
+		{"offcpu", "offcpu", ModeOffCPU, false},
 		{"pprof-cpu", "pprof-cpu", ModePProfCPU, false},
 		{"pprof-heap", "pprof-heap", ModePProfHeap, false},
 		{"pprof-goroutine", "pprof-goroutine", ModePProfGoroutine, false},
@@ -51,7 +56,10 @@ func TestAnalysisMode_ToTaskType(t *testing.T) {
 		{ModeJavaCPU, model.TaskTypeJava},
 		{ModeJavaAlloc, model.TaskTypeJava},
 		{ModeJavaHeap, model.TaskTypeJavaHeap},
+		{ModeJavaThreadDump, model.TaskTypeThreadDump},
+		{ModeJavaGCLog, model.TaskTypeGCLog},
 		{ModeCPU, model.TaskTypeGeneric},
+		{ModeOffCPU, model.TaskTypeOffCPU},
 		{ModePProfCPU, model.TaskTypePProfCPU},
 		{ModePProfHeap, model.TaskTypePProfHeap},
 		{ModePProfGoroutine, model.TaskTypePProfGoroutine},
@@ -76,6 +84,8 @@ func TestAnalysisMode_ToProfilerType(t *testing.T) {
 	}{
 		{ModeJavaCPU, model.ProfilerTypePerf},
 		{ModeJavaAlloc, model.ProfilerTypeAsyncAlloc},
+		{ModeJavaWall, model.ProfilerTypeAsyncWall},
+		{ModeJavaLock, model.ProfilerTypeAsyncLock},
 		{ModeJavaHeap, model.ProfilerTypePerf},
 		{ModeCPU, model.ProfilerTypePerf},
 		{ModePProfCPU, model.ProfilerTypePProf},
@@ -129,13 +139,13 @@ func TestGetModeInfo(t *testing.T) {
 
 func TestAllModes(t *testing.T) {
 	modes := AllModes()
-	if len(modes) != 10 {
-		t.Errorf("AllModes() returned %d modes, want 10", len(modes))
+	if len(modes) != 16 {
+		t.Errorf("AllModes() returned %d modes, want 16", len(modes))
 	}
 
 	// Verify order
 	expectedOrder := []AnalysisMode{
-		ModeJavaCPU, ModeJavaAlloc, ModeJavaHeap, ModeCPU,
+		ModeJavaCPU, ModeJavaAlloc, ModeJavaWall, ModeJavaLock, ModeJavaJFR, ModeJavaHeap, ModeJavaThreadDump, ModeJavaGCLog, ModeCPU, ModeOffCPU,
 		ModePProfCPU, ModePProfHeap, ModePProfGoroutine, ModePProfBlock, ModePProfMutex, ModePProfAll,
 	}
 	for i, info := range modes {
@@ -148,7 +158,7 @@ func TestAllModes(t *testing.T) {
 func TestValidModes(t *testing.T) {
 	valid := ValidModes()
 	expectedModes := []string{
-		"java-cpu", "java-alloc", "java-heap", "cpu",
+		"java-cpu", "java-alloc", "java-wall", "java-lock", "java-jfr", "java-heap", "java-threaddump", "java-gclog", "cpu", "offcpu",
 		"pprof-cpu", "pprof-heap", "pprof-goroutine", "pprof-block", "pprof-mutex", "pprof-all",
 	}
 	for _, mode := range expectedModes {
@@ -181,8 +191,14 @@ func TestFactory_CreateAnalyzerForMode(t *testing.T) {
 	}{
 		{ModeJavaCPU, "java_cpu_analyzer", false},
 		{ModeJavaAlloc, "java_mem_analyzer", false},
+		{ModeJavaWall, "java_wall_analyzer", false},
+		{ModeJavaLock, "java_lock_analyzer", false},
+		{ModeJavaJFR, "java_jfr_analyzer", false},
 		{ModeJavaHeap, "java_heap_analyzer", false},
+		{ModeJavaThreadDump, "thread_dump_analyzer", false},
+		{ModeJavaGCLog, "gc_log_analyzer", false},
 		{ModeCPU, "java_cpu_analyzer", false}, // Generic uses same analyzer
+		{ModeOffCPU, "offcpu_analyzer", false},
 		{ModePProfCPU, "pprof_cpu_analyzer", false},
 		{ModePProfHeap, "pprof_heap_analyzer", false},
 		{ModePProfGoroutine, "pprof_goroutine_analyzer", false},
@@ -0,0 +1,228 @@
+package analyzer
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+
+	"github.com/perf-analysis/pkg/model"
+	"github.com/perf-analysis/pkg/profiling"
+)
+
+func init() {
+	Register(model.TaskTypeOffCPU, AnyProfiler, func(c *BaseAnalyzerConfig) Analyzer { return NewOffCPUAnalyzer(c) })
+}
+
+const (
+	// offCPUTopGroups is the number of blocking groups reported.
+	offCPUTopGroups = 20
+	// offCPUTopStacks is the number of stacks reported per blocking group.
+	offCPUTopStacks = 5
+)
+
+var offCPUFiles = profileFiles{name: "Off-CPU ", flameGraph: "collapsed_data.json.gz", callGraph: "callgraph_data.json.gz", unit: "ns"}
+
+// OffCPUAnalyzer analyzes eBPF off-CPU profiles: the collapsed output of
+// bcc offcputime -f, of the time threads spent blocked in microseconds.
+type OffCPUAnalyzer struct {
+	*BaseAnalyzer
+}
+
+// NewOffCPUAnalyzer creates a new off-CPU analyzer.
+func NewOffCPUAnalyzer(config *BaseAnalyzerConfig) *OffCPUAnalyzer {
+	if config == nil {
+		config = DefaultBaseAnalyzerConfig()
+	}
+	if config.AnalysisProfile == "" {
+		config.AnalysisProfile = ProfileStandard
+	}
+
+	return &OffCPUAnalyzer{
+		BaseAnalyzer: NewBaseAnalyzer(config),
+	}
+}
+
+// Name returns the analyzer name.
+func (a *OffCPUAnalyzer) Name() string {
+	return "offcpu_analyzer"
+}
+
+// SupportedTypes returns the task types supported by this analyzer.
+func (a *OffCPUAnalyzer) SupportedTypes() []model.TaskType {
+	return []model.TaskType{model.TaskTypeOffCPU}
+}
+
+// CanHandle checks if this analyzer can handle the given request.
+func (a *OffCPUAnalyzer) CanHandle(req *model.AnalysisRequest) bool {
+	return req.TaskType == model.TaskTypeOffCPU
+}
+
+// Analyze performs off-CPU analysis using an input file.
+func (a *OffCPUAnalyzer) Analyze(ctx context.Context, req *model.AnalysisRequest) (*model.AnalysisResponse, error) {
+	file, err := os.Open(req.InputFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open input file: %w", err)
+	}
+	defer file.Close()
+
+	return a.AnalyzeFromReader(ctx, req, file)
+}
+
+// AnalyzeFromReader performs off-CPU analysis from a reader.
+func (a *OffCPUAnalyzer) AnalyzeFromReader(ctx context.Context, req *model.AnalysisRequest, dataReader io.Reader) (*model.AnalysisResponse, error) {
+	// Step 1: Parse the collapsed data
+	parseResult, err := a.Parse(ctx, dataReader)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrParseError, err)
+	}
+
+	if parseResult.TotalSamples == 0 {
+		return nil, ErrEmptyData
+	}
+
+	// Step 2: Determine output directory
+	taskDir := req.OutputDir
+	if taskDir == "" {
+		taskDir, err = a.EnsureOutputDir(req.TaskUUID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create output directory: %w", err)
+		}
+	}
+
+	// Step 3: Convert blocked time to nanoseconds and classify wait reasons
+	table := newLatencyTable()
+	groups := newBlockingGroups()
+	reasons := make(map[string]int64)
+	var totalNanos int64
+	for _, sample := range parseResult.Samples {
+		sample.Value *= 1000
+		sample.State = profiling.WaitReason(sample.CallStack)
+
+		table.add(sample, 0, sample.Value)
+		groups.add(sample)
+		reasons[sample.State] += sample.Value
+		totalNanos += sample.Value
+	}
+
+	// Step 4: Generate and write the flame graph and call graph
+	fg, outputFiles, err := a.writeProfile(ctx, req.TaskUUID, taskDir, parseResult.Samples, offCPUFiles)
+	if err != nil {
+		return nil, err
+	}
+
+	// Step 5: Build OffCPUData
+	offCPUData := &model.OffCPUData{
+		FlameGraphFile:  outputFiles[0].LocalPath,
+		CallGraphFile:   outputFiles[1].LocalPath,
+		TopFuncs:        flameGraphTopFuncs(fg),
+		TotalNanos:      totalNanos,
+		Reasons:         reasons,
+		ThreadLatencies: table.latencies(),
+		BlockingGroups:  groups.top(totalNanos, offCPUTopGroups, offCPUTopStacks),
+	}
+
+	return &model.AnalysisResponse{
+		TaskUUID:     req.TaskUUID,
+		TaskType:     req.TaskType,
+		TotalRecords: len(parseResult.Samples),
+		OutputFiles:  outputFiles,
+		Data:         offCPUData,
+	}, nil
+}
+
+// blockingGroupKey identifies the blocking groups of a thread and reason.
+type blockingGroupKey struct {
+	thread string
+	reason string
+}
+
+// blockingGroups accumulates the blocked time of samples by thread and wait
+// reason, and by stack within each group.
+type blockingGroups struct {
+	totals map[blockingGroupKey]int64
+	stacks map[blockingGroupKey]map[string]int64 // stack string -> nanoseconds
+}
+
+func newBlockingGroups() *blockingGroups {
+	return &blockingGroups{
+		totals: make(map[blockingGroupKey]int64),
+		stacks: make(map[blockingGroupKey]map[string]int64),
+	}
+}
+
+// add accounts a sample, whose State is its wait reason.
+func (g *blockingGroups) add(sample *model.Sample) {
+	key := blockingGroupKey{thread: sample.ThreadName, reason: sample.State}
+	g.totals[key] += sample.Value
+	stacks, ok := g.stacks[key]
+	if !ok {
+		stacks = make(map[string]int64)
+		g.stacks[key] = stacks
+	}
+	stacks[profiling.StackToString(sample.CallStack)] += sample.Value
+}
+
+// top returns the maxGroups longest groups, with their maxStacks longest
+// stacks.
+func (g *blockingGroups) top(totalNanos int64, maxGroups, maxStacks int) []model.BlockingGroup {
+	percentage := func(nanos int64) float64 {
+		if totalNanos == 0 {
+			return 0
+		}
+		return float64(nanos) / float64(totalNanos) * 100
+	}
+
+	keys := make([]blockingGroupKey, 0, len(g.totals))
+	for key := range g.totals {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if g.totals[keys[i]] != g.totals[keys[j]] {
+			return g.totals[keys[i]] > g.totals[keys[j]]
+		}
+		if keys[i].thread != keys[j].thread {
+			return keys[i].thread < keys[j].thread
+		}
+		return keys[i].reason < keys[j].reason
+	})
+	if len(keys) > maxGroups {
+		keys = keys[:maxGroups]
+	}
+
+	result := make([]model.BlockingGroup, 0, len(keys))
+	for _, key := range keys {
+		stacks := g.stacks[key]
+		names := make([]string, 0, len(stacks))
+		for stack := range stacks {
+			names = append(names, stack)
+		}
+		sort.Slice(names, func(i, j int) bool {
+			if stacks[names[i]] != stacks[names[j]] {
+				return stacks[names[i]] > stacks[names[j]]
+			}
+			return names[i] < names[j]
+		})
+		if len(names) > maxStacks {
+			names = names[:maxStacks]
+		}
+
+		group := model.BlockingGroup{
+			ThreadName: key.thread,
+			Reason:     key.reason,
+			TotalNanos: g.totals[key],
+			Percentage: percentage(g.totals[key]),
+			TopStacks:  make([]model.BlockingStack, 0, len(names)),
+		}
+		for _, stack := range names {
+			group.TopStacks = append(group.TopStacks, model.BlockingStack{
+				CallStack:  profiling.StringToStack(stack),
+				TotalNanos: stacks[stack],
+				Percentage: percentage(stacks[stack]),
+			})
+		}
+		result = append(result, group)
+	}
+	return result
+}
@@ -0,0 +1,77 @@
+package analyzer
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/perf-analysis/pkg/model"
+	"github.com/perf-analysis/pkg/profiling"
+)
+
+// offCPUInput is bcc offcputime -f output, in microseconds.
+const offCPUInput = `java;start_thread;Unsafe_Park;pthread_cond_wait;entry_SYSCALL_64;do_futex;futex_wait;futex_wait_queue_me;schedule 6000
+java;start_thread;Unsafe_Park;pthread_cond_wait;entry_SYSCALL_64;do_futex;futex_wait;futex_wait_queue_me;schedule 1000
+nginx;main;epoll_wait;entry_SYSCALL_64;do_epoll_wait;ep_poll;schedule_hrtimeout_range 2500
+nginx;main;read;entry_SYSCALL_64;ksys_read;filemap_read;folio_wait_bit_common;io_schedule 500`
+
+func TestOffCPUAnalyzer_CanHandle(t *testing.T) {
+	analyzer := NewOffCPUAnalyzer(nil)
+
+	assert.Equal(t, "offcpu_analyzer", analyzer.Name())
+	assert.True(t, analyzer.CanHandle(&model.AnalysisRequest{TaskType: model.TaskTypeOffCPU}))
+	assert.False(t, analyzer.CanHandle(&model.AnalysisRequest{TaskType: model.TaskTypeGeneric}))
+}
+
+func TestOffCPUAnalyzer_Analyze(t *testing.T) {
+	analyzer := NewOffCPUAnalyzer(&BaseAnalyzerConfig{OutputDir: t.TempDir()})
+
+	req := &model.AnalysisRequest{
+		TaskUUID:  "test-offcpu-uuid",
+		TaskType:  model.TaskTypeOffCPU,
+		OutputDir: t.TempDir(),
+	}
+
+	result, err := analyzer.AnalyzeFromReader(context.Background(), req, strings.NewReader(offCPUInput))
+	require.NoError(t, err)
+
+	data, ok := result.Data.(*model.OffCPUData)
+	require.True(t, ok, "Data should be OffCPUData")
+	assert.Equal(t, int64(10_000_000), data.TotalNanos)
+	assert.Equal(t, map[string]int64{
+		profiling.WaitLock: 7_000_000,
+		profiling.WaitPoll: 2_500_000,
+		profiling.WaitDisk: 500_000,
+	}, data.Reasons)
+
+	// Blocking stacks are grouped by thread and wait reason, the longest first
+	require.Len(t, data.BlockingGroups, 3)
+	lock := data.BlockingGroups[0]
+	assert.Equal(t, "java", lock.ThreadName)
+	assert.Equal(t, profiling.WaitLock, lock.Reason)
+	assert.Equal(t, int64(7_000_000), lock.TotalNanos)
+	assert.InDelta(t, 70.0, lock.Percentage, 0.001)
+	require.Len(t, lock.TopStacks, 1)
+	assert.Equal(t, "schedule", lock.TopStacks[0].CallStack[len(lock.TopStacks[0].CallStack)-1])
+	assert.Equal(t, profiling.WaitPoll, data.BlockingGroups[1].Reason)
+	assert.Equal(t, profiling.WaitDisk, data.BlockingGroups[2].Reason)
+
+	require.Len(t, data.ThreadLatencies, 2)
+	assert.Equal(t, int64(3_000_000), data.ThreadLatencies[1].TotalNanos)
+
+	fg := readFlameGraph(t, data.FlameGraphFile)
+	assert.Equal(t, "ns", fg.Unit)
+	assert.Equal(t, profiling.WaitLock, fg.Root.State)
+}
+
+func TestOffCPUAnalyzer_Analyze_EmptyData(t *testing.T) {
+	analyzer := NewOffCPUAnalyzer(&BaseAnalyzerConfig{OutputDir: t.TempDir()})
+
+	req := &model.AnalysisRequest{TaskUUID: "test-empty-uuid", TaskType: model.TaskTypeOffCPU}
+
+	_, err := analyzer.AnalyzeFromReader(context.Background(), req, strings.NewReader(""))
+	assert.Equal(t, ErrEmptyData, err)
+}
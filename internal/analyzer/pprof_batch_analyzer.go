@@ -18,6 +18,17 @@ import (
 	"github.com/perf-analysis/pkg/utils"
 )
 
+func init() {
+	// The batch mode has no task type of its own
+	RegisterMode(ModeInfo{
+		Mode:        ModePProfAll,
+		Description: "Batch analysis of all pprof profiles in a directory",
+		InputFormat: "Directory containing pprof subdirectories (cpu/, heap/, goroutine/, etc.)",
+		TaskType:    model.TaskTypePProfCPU, // Primary type
+		Profiler:    model.ProfilerTypePProf,
+	}, func(c *BaseAnalyzerConfig) Analyzer { return NewPProfBatchAnalyzer(c) })
+}
+
 // PProfBatchAnalyzer analyzes a directory of pprof files.
 type PProfBatchAnalyzer struct {
 	name              string
@@ -469,6 +480,16 @@ func (a *PProfBatchAnalyzer) buildBatchData(results *BatchAnalysisResult, output
 			TotalGrowthPercent: lr.TotalGrowthPct,
 			GrowthItems:        growthItems,
 		}
+		for _, c := range lr.LeakClusters {
+			detailedLeakReports[name].LeakClusters = append(detailedLeakReports[name].LeakClusters, model.PProfGoroutineCluster{
+				CreatedBy:     c.CreatedBy,
+				CreationStack: c.CreationStack,
+				BlockingPoint: c.BlockingPoint,
+				WaitCall:      c.WaitCall,
+				Counts:        c.Counts,
+				CountDelta:    c.CountDelta,
+			})
+		}
 	}
 
 	// Build top functions from CPU profile if available
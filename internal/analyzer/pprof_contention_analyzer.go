@@ -11,6 +11,11 @@ import (
 	"github.com/perf-analysis/pkg/model"
 )
 
+func init() {
+	Register(model.TaskTypePProfBlock, model.ProfilerTypePProf, func(c *BaseAnalyzerConfig) Analyzer { return NewPProfBlockAnalyzer(c) })
+	Register(model.TaskTypePProfMutex, model.ProfilerTypePProf, func(c *BaseAnalyzerConfig) Analyzer { return NewPProfMutexAnalyzer(c) })
+}
+
 // PProfContentionAnalyzer analyzes Go pprof Block/Mutex profile data.
 // It handles both block and mutex profiles as they have similar structure.
 type PProfContentionAnalyzer struct {
@@ -11,6 +11,11 @@ import (
 	"github.com/perf-analysis/pkg/model"
 )
 
+func init() {
+	Register(model.TaskTypePProfCPU, model.ProfilerTypePProf, func(c *BaseAnalyzerConfig) Analyzer { return NewPProfCPUAnalyzer(c) })
+	Register(model.TaskTypeGeneric, model.ProfilerTypePProf, func(c *BaseAnalyzerConfig) Analyzer { return NewPProfCPUAnalyzer(c) })
+}
+
 // PProfCPUAnalyzer analyzes Go pprof CPU profile data.
 type PProfCPUAnalyzer struct {
 	*BaseAnalyzer
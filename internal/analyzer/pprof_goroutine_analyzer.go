@@ -15,6 +15,10 @@ import (
 	"github.com/perf-analysis/pkg/model"
 )
 
+func init() {
+	Register(model.TaskTypePProfGoroutine, model.ProfilerTypePProf, func(c *BaseAnalyzerConfig) Analyzer { return NewPProfGoroutineAnalyzer(c) })
+}
+
 // PProfGoroutineAnalyzer analyzes Go pprof Goroutine profile data.
 type PProfGoroutineAnalyzer struct {
 	*BaseAnalyzer
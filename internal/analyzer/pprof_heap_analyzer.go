@@ -11,6 +11,10 @@ import (
 	"github.com/perf-analysis/pkg/model"
 )
 
+func init() {
+	Register(model.TaskTypePProfHeap, model.ProfilerTypePProf, func(c *BaseAnalyzerConfig) Analyzer { return NewPProfHeapAnalyzer(c) })
+}
+
 // PProfHeapAnalyzer analyzes Go pprof Heap profile data.
 type PProfHeapAnalyzer struct {
 	*BaseAnalyzer
@@ -40,8 +44,14 @@ func (a *PProfHeapAnalyzer) SupportedTypes() []model.TaskType {
 	return []model.TaskType{model.TaskTypePProfHeap}
 }
 
-// Analyze performs pprof Heap analysis using an input file.
+// Analyze performs pprof Heap analysis using an input file. Requests of
+// several input files either merge them, if RequestParams.Merge is set, or
+// analyze the latest and the inuse growth between consecutive files.
 func (a *PProfHeapAnalyzer) Analyze(ctx context.Context, req *model.AnalysisRequest) (*model.AnalysisResponse, error) {
+	if len(req.InputFiles) > 1 {
+		return a.analyzeProfiles(ctx, req)
+	}
+
 	file, err := os.Open(req.InputFile)
 	if err != nil {
 		return nil, fmt.Errorf("failed to open input file: %w", err)
@@ -60,15 +70,42 @@ func (a *PProfHeapAnalyzer) AnalyzeFromReader(ctx context.Context, req *model.An
 	}
 
 	// Step 2: Determine output directory
-	taskDir := req.OutputDir
-	if taskDir == "" {
-		var err error
-		taskDir, err = a.EnsureOutputDir(req.TaskUUID)
-		if err != nil {
-			return nil, fmt.Errorf("failed to create output directory: %w", err)
-		}
+	taskDir, err := a.taskDir(req)
+	if err != nil {
+		return nil, err
+	}
+
+	heapData, outputFiles, totalRecords, err := a.analyzeProfile(ctx, req, parser, taskDir)
+	if err != nil {
+		return nil, err
 	}
 
+	// Step 4: Build response
+	return &model.AnalysisResponse{
+		TaskUUID:     req.TaskUUID,
+		TaskType:     req.TaskType,
+		TotalRecords: int(totalRecords),
+		OutputFiles:  outputFiles,
+		Data:         heapData,
+	}, nil
+}
+
+// taskDir returns the output directory of a request.
+func (a *PProfHeapAnalyzer) taskDir(req *model.AnalysisRequest) (string, error) {
+	if req.OutputDir != "" {
+		return req.OutputDir, nil
+	}
+	taskDir, err := a.EnsureOutputDir(req.TaskUUID)
+	if err != nil {
+		return "", fmt.Errorf("failed to create output directory: %w", err)
+	}
+	return taskDir, nil
+}
+
+// analyzeProfile analyzes each sample type of a parsed heap profile,
+// writing its flame graphs to taskDir.
+func (a *PProfHeapAnalyzer) analyzeProfile(ctx context.Context, req *model.AnalysisRequest, parser *pprofparser.Parser, taskDir string) (*model.PProfHeapData, []model.OutputFile, int64, error) {
+
 	topFuncsN := a.config.TopFuncsN
 	if topFuncsN <= 0 {
 		topFuncsN = 50
@@ -163,15 +200,8 @@ func (a *PProfHeapAnalyzer) AnalyzeFromReader(ctx context.Context, req *model.An
 	}
 
 	if heapData.InuseSpace == nil && heapData.AllocSpace == nil {
-		return nil, ErrEmptyData
+		return nil, nil, 0, ErrEmptyData
 	}
 
-	// Step 4: Build response
-	return &model.AnalysisResponse{
-		TaskUUID:     req.TaskUUID,
-		TaskType:     req.TaskType,
-		TotalRecords: int(totalRecords),
-		OutputFiles:  outputFiles,
-		Data:         heapData,
-	}, nil
+	return heapData, outputFiles, totalRecords, nil
 }
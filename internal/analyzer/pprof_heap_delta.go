@@ -0,0 +1,179 @@
+package analyzer
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	pprofparser "github.com/perf-analysis/internal/parser/pprof"
+	"github.com/perf-analysis/pkg/model"
+)
+
+// heapDeltaFlameGraphFile is the flame graph of the inuse growth from the
+// first to the latest of several heap profiles.
+const heapDeltaFlameGraphFile = "inuse_space_delta_flamegraph.json.gz"
+
+// analyzeProfiles analyzes the heap profiles of req.InputFiles: their merge
+// if RequestParams.Merge is set, otherwise the latest profile and the inuse
+// growth between consecutive profiles.
+func (a *PProfHeapAnalyzer) analyzeProfiles(ctx context.Context, req *model.AnalysisRequest) (*model.AnalysisResponse, error) {
+	// Step 1: Parse the profiles
+	parsers := make([]*pprofparser.Parser, 0, len(req.InputFiles))
+	for _, path := range req.InputFiles {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		parser, err := parsePProfFile(path)
+		if err != nil {
+			return nil, err
+		}
+		parsers = append(parsers, parser)
+	}
+
+	// Step 2: Determine output directory
+	taskDir, err := a.taskDir(req)
+	if err != nil {
+		return nil, err
+	}
+
+	// Step 3: Analyze the merged or the latest profile
+	latest := parsers[len(parsers)-1]
+	if req.RequestParams.Merge {
+		latest, err = pprofparser.MergeProfiles(parsers)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrParseError, err)
+		}
+	}
+	heapData, outputFiles, totalRecords, err := a.analyzeProfile(ctx, req, latest, taskDir)
+	if err != nil {
+		return nil, err
+	}
+	heapData.ProfileCount = len(parsers)
+	heapData.Merged = req.RequestParams.Merge
+
+	// Step 4: Compute the inuse growth between consecutive profiles
+	if !req.RequestParams.Merge {
+		topN := a.config.TopFuncsN
+		if topN <= 0 {
+			topN = 50
+		}
+		for i := 1; i < len(parsers); i++ {
+			delta, err := heapDelta(parsers[i-1], parsers[i], topN)
+			if err != nil {
+				return nil, fmt.Errorf("%w: %v", ErrParseError, err)
+			}
+			delta.BaselineFile = filepath.Base(req.InputFiles[i-1])
+			delta.CurrentFile = filepath.Base(req.InputFiles[i])
+			heapData.Deltas = append(heapData.Deltas, *delta)
+		}
+
+		file, err := a.writeDeltaFlameGraph(ctx, req, parsers[0], latest, taskDir)
+		if err != nil {
+			return nil, err
+		}
+		if file != nil {
+			heapData.DeltaFlameGraphFile = file.LocalPath
+			outputFiles = append(outputFiles, *file)
+		}
+	}
+
+	return &model.AnalysisResponse{
+		TaskUUID:     req.TaskUUID,
+		TaskType:     req.TaskType,
+		TotalRecords: int(totalRecords),
+		OutputFiles:  outputFiles,
+		Data:         heapData,
+	}, nil
+}
+
+// parsePProfFile parses a pprof profile file, gzipped or not.
+func parsePProfFile(path string) (*pprofparser.Parser, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open input file: %w", err)
+	}
+	defer file.Close()
+
+	parser := pprofparser.NewParser()
+	if err := parser.Parse(file); err != nil {
+		return nil, fmt.Errorf("%w: %s: %v", ErrParseError, path, err)
+	}
+	return parser, nil
+}
+
+// heapDelta returns the inuse growth between two heap profiles, with the
+// topN stacks that grew most.
+func heapDelta(baseline, current *pprofparser.Parser, topN int) (*model.PProfHeapDelta, error) {
+	growth, err := pprofparser.StackDeltas(baseline, current, pprofparser.SampleTypeInuseSpace)
+	if err != nil {
+		return nil, err
+	}
+
+	delta := &model.PProfHeapDelta{
+		BaselineTotal: baseline.GetTotalSamples(pprofparser.SampleTypeInuseSpace),
+		CurrentTotal:  current.GetTotalSamples(pprofparser.SampleTypeInuseSpace),
+	}
+	delta.TotalGrowth = delta.CurrentTotal - delta.BaselineTotal
+
+	var grown int64
+	for _, g := range growth {
+		if g.Growth > 0 {
+			grown += g.Growth
+		}
+	}
+	for _, g := range growth {
+		if g.Growth <= 0 || len(delta.Stacks) >= topN {
+			break
+		}
+		delta.Stacks = append(delta.Stacks, model.PProfStackGrowth{
+			Stack:         strings.Split(g.Stack, ";"),
+			BaselineValue: g.BaselineValue,
+			CurrentValue:  g.CurrentValue,
+			Growth:        g.Growth,
+			GrowthPercent: float64(g.Growth) * 100.0 / float64(grown),
+		})
+	}
+	return delta, nil
+}
+
+// writeDeltaFlameGraph writes the flame graph of the stacks whose inuse
+// space grew from baseline to current. It returns nil if none grew.
+func (a *PProfHeapAnalyzer) writeDeltaFlameGraph(ctx context.Context, req *model.AnalysisRequest, baseline, current *pprofparser.Parser, taskDir string) (*model.OutputFile, error) {
+	growth, err := pprofparser.StackDeltas(baseline, current, pprofparser.SampleTypeInuseSpace)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrParseError, err)
+	}
+
+	var samples []*model.Sample
+	for _, g := range growth {
+		if g.Growth <= 0 {
+			break
+		}
+		samples = append(samples, &model.Sample{
+			CallStack: strings.Split(g.Stack, ";"),
+			Value:     g.Growth,
+		})
+	}
+	if len(samples) == 0 {
+		return nil, nil
+	}
+
+	fg, err := a.GenerateFlameGraphWithAnalysis(ctx, samples)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate delta flame graph: %w", err)
+	}
+	fg.Unit = "bytes"
+
+	path := filepath.Join(taskDir, heapDeltaFlameGraphFile)
+	if err := a.WriteFlameGraphGzip(fg, path); err != nil {
+		return nil, fmt.Errorf("failed to write delta flame graph: %w", err)
+	}
+	return &model.OutputFile{
+		Name:        "Flame Graph (inuse_space_delta)",
+		LocalPath:   path,
+		COSKey:      req.TaskUUID + "/" + heapDeltaFlameGraphFile,
+		ContentType: "application/gzip",
+	}, nil
+}
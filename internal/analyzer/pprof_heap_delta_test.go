@@ -0,0 +1,135 @@
+package analyzer
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/pprof/profile"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/perf-analysis/pkg/model"
+)
+
+// writeHeapProfile writes a heap profile of one sample per leaf function,
+// called from main, of the given inuse_space values.
+func writeHeapProfile(t *testing.T, path string, inuse map[string]int64) {
+	t.Helper()
+
+	prof := &profile.Profile{
+		SampleType: []*profile.ValueType{
+			{Type: "inuse_objects", Unit: "count"},
+			{Type: "inuse_space", Unit: "bytes"},
+		},
+		PeriodType: &profile.ValueType{Type: "space", Unit: "bytes"},
+	}
+	mainFn := &profile.Function{ID: 1, Name: "main.main"}
+	mainLoc := &profile.Location{ID: 1, Line: []profile.Line{{Function: mainFn}}}
+	prof.Function = append(prof.Function, mainFn)
+	prof.Location = append(prof.Location, mainLoc)
+	for name, value := range inuse {
+		id := uint64(len(prof.Function) + 1)
+		fn := &profile.Function{ID: id, Name: name}
+		loc := &profile.Location{ID: id, Line: []profile.Line{{Function: fn}}}
+		prof.Function = append(prof.Function, fn)
+		prof.Location = append(prof.Location, loc)
+		prof.Sample = append(prof.Sample, &profile.Sample{
+			Value:    []int64{1, value},
+			Location: []*profile.Location{loc, mainLoc},
+		})
+	}
+
+	f, err := os.Create(path)
+	require.NoError(t, err)
+	defer f.Close()
+	require.NoError(t, prof.Write(f))
+}
+
+func TestPProfHeapAnalyzer_Deltas(t *testing.T) {
+	dir := t.TempDir()
+	files := []string{
+		filepath.Join(dir, "heap-1.pb.gz"),
+		filepath.Join(dir, "heap-2.pb.gz"),
+		filepath.Join(dir, "heap-3.pb.gz"),
+	}
+	writeHeapProfile(t, files[0], map[string]int64{"cache.Put": 1000, "buf.Grow": 500})
+	writeHeapProfile(t, files[1], map[string]int64{"cache.Put": 3000, "buf.Grow": 500})
+	writeHeapProfile(t, files[2], map[string]int64{"cache.Put": 6000, "buf.Grow": 200, "conn.Read": 100})
+
+	a := NewPProfHeapAnalyzer(nil)
+	resp, err := a.Analyze(context.Background(), &model.AnalysisRequest{
+		TaskUUID:   "heap-delta",
+		TaskType:   model.TaskTypePProfHeap,
+		InputFile:  files[0],
+		InputFiles: files,
+		OutputDir:  dir,
+	})
+	require.NoError(t, err)
+
+	data := resp.Data.(*model.PProfHeapData)
+	assert.Equal(t, 3, data.ProfileCount)
+	assert.False(t, data.Merged)
+	assert.Equal(t, int64(6300), data.HeapSummary.TotalInuseBytes, "latest profile is analyzed")
+
+	require.Len(t, data.Deltas, 2)
+	first := data.Deltas[0]
+	assert.Equal(t, "heap-1.pb.gz", first.BaselineFile)
+	assert.Equal(t, "heap-2.pb.gz", first.CurrentFile)
+	assert.Equal(t, int64(2000), first.TotalGrowth)
+	require.Len(t, first.Stacks, 1)
+	assert.Equal(t, []string{"main.main", "cache.Put"}, first.Stacks[0].Stack)
+	assert.Equal(t, 100.0, first.Stacks[0].GrowthPercent)
+
+	second := data.Deltas[1]
+	assert.Equal(t, int64(2800), second.TotalGrowth)
+	require.Len(t, second.Stacks, 2, "shrinking stacks are not reported")
+	assert.Equal(t, int64(3000), second.Stacks[0].Growth)
+	assert.Equal(t, int64(100), second.Stacks[1].Growth)
+
+	require.NotEmpty(t, data.DeltaFlameGraphFile)
+	fg := readFlameGraph(t, data.DeltaFlameGraphFile)
+	assert.Equal(t, int64(5100), fg.TotalSamples)
+	assert.Equal(t, "bytes", fg.Unit)
+}
+
+func TestPProfHeapAnalyzer_Merge(t *testing.T) {
+	dir := t.TempDir()
+	files := []string{filepath.Join(dir, "heap-a.pb.gz"), filepath.Join(dir, "heap-b.pb.gz")}
+	writeHeapProfile(t, files[0], map[string]int64{"cache.Put": 1000})
+	writeHeapProfile(t, files[1], map[string]int64{"cache.Put": 2000, "buf.Grow": 500})
+
+	a := NewPProfHeapAnalyzer(nil)
+	resp, err := a.Analyze(context.Background(), &model.AnalysisRequest{
+		TaskUUID:      "heap-merge",
+		TaskType:      model.TaskTypePProfHeap,
+		InputFile:     files[0],
+		InputFiles:    files,
+		OutputDir:     dir,
+		RequestParams: model.RequestParams{Merge: true},
+	})
+	require.NoError(t, err)
+
+	data := resp.Data.(*model.PProfHeapData)
+	assert.Equal(t, 2, data.ProfileCount)
+	assert.True(t, data.Merged)
+	assert.Equal(t, int64(3500), data.HeapSummary.TotalInuseBytes)
+	assert.Empty(t, data.Deltas)
+	assert.Empty(t, data.DeltaFlameGraphFile)
+}
+
+func TestPProfHeapAnalyzer_MissingProfile(t *testing.T) {
+	dir := t.TempDir()
+	existing := filepath.Join(dir, "heap-1.pb.gz")
+	writeHeapProfile(t, existing, map[string]int64{"cache.Put": 1000})
+
+	a := NewPProfHeapAnalyzer(nil)
+	_, err := a.Analyze(context.Background(), &model.AnalysisRequest{
+		TaskType:   model.TaskTypePProfHeap,
+		InputFile:  existing,
+		InputFiles: []string{existing, filepath.Join(dir, "missing.pb.gz")},
+		OutputDir:  dir,
+	})
+	assert.Error(t, err)
+}
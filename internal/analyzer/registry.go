@@ -0,0 +1,89 @@
+package analyzer
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"plugin"
+	"sort"
+	"sync"
+
+	"github.com/perf-analysis/pkg/model"
+)
+
+// Constructor creates an analyzer with the given configuration.
+type Constructor func(config *BaseAnalyzerConfig) Analyzer
+
+// AnyProfiler registers an analyzer for all the profiler types of a task
+// type, e.g. heap dumps, which do not depend on the profiler. Analyzers
+// registered for the exact profiler type take precedence.
+const AnyProfiler model.ProfilerType = -1
+
+// registry holds the registered analyzer constructors.
+var (
+	registry   = make(map[AnalyzerKey]Constructor)
+	registryMu sync.RWMutex
+)
+
+// Register registers the constructor of the analyzer of a task type and
+// profiler type, replacing any previous one. This is typically called in the
+// init() function of each analyzer implementation, including plugins.
+func Register(taskType model.TaskType, profilerType model.ProfilerType, constructor Constructor) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	registry[AnalyzerKey{TaskType: taskType, ProfilerType: profilerType}] = constructor
+}
+
+// Lookup returns the constructor of the analyzer of a task type and profiler
+// type.
+func Lookup(taskType model.TaskType, profilerType model.ProfilerType) (Constructor, bool) {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	if constructor, ok := registry[AnalyzerKey{TaskType: taskType, ProfilerType: profilerType}]; ok {
+		return constructor, true
+	}
+	constructor, ok := registry[AnalyzerKey{TaskType: taskType, ProfilerType: AnyProfiler}]
+	return constructor, ok
+}
+
+// RegisteredKeys returns the task and profiler types with a registered
+// analyzer, ordered by task type then profiler type.
+func RegisteredKeys() []AnalyzerKey {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	keys := make([]AnalyzerKey, 0, len(registry))
+	for key := range registry {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].TaskType != keys[j].TaskType {
+			return keys[i].TaskType < keys[j].TaskType
+		}
+		return keys[i].ProfilerType < keys[j].ProfilerType
+	})
+	return keys
+}
+
+// LoadPlugins opens the Go plugins (*.so files) of dir, in name order, and
+// returns their paths. Plugins register their analyzers, and their analysis
+// modes, with Register and RegisterMode from their init functions.
+//
+// Plugins must be built with -buildmode=plugin from the same module version
+// as the analyzer, e.g. from a package of this repository's plugins/ tree.
+func LoadPlugins(dir string) ([]string, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.so"))
+	if err != nil {
+		return nil, err
+	}
+	if _, err := os.Stat(dir); err != nil {
+		return nil, fmt.Errorf("failed to read plugin directory: %w", err)
+	}
+
+	sort.Strings(paths)
+	for _, path := range paths {
+		if _, err := plugin.Open(path); err != nil {
+			return nil, fmt.Errorf("failed to load analyzer plugin %s: %w", filepath.Base(path), err)
+		}
+	}
+	return paths, nil
+}
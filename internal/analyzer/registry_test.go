@@ -0,0 +1,140 @@
+package analyzer
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/perf-analysis/pkg/model"
+)
+
+// testTaskType is a task type no built-in analyzer handles.
+const testTaskType model.TaskType = 99
+
+// stubAnalyzer is an analyzer registered by the tests.
+type stubAnalyzer struct {
+	name string
+}
+
+func (a *stubAnalyzer) Analyze(context.Context, *model.AnalysisRequest) (*model.AnalysisResponse, error) {
+	return &model.AnalysisResponse{}, nil
+}
+
+func (a *stubAnalyzer) AnalyzeFromReader(context.Context, *model.AnalysisRequest, io.Reader) (*model.AnalysisResponse, error) {
+	return &model.AnalysisResponse{}, nil
+}
+
+func (a *stubAnalyzer) SupportedTypes() []model.TaskType { return []model.TaskType{testTaskType} }
+func (a *stubAnalyzer) Name() string                     { return a.name }
+
+// registerStub registers a stub analyzer until the end of the test.
+func registerStub(t *testing.T, profilerType model.ProfilerType, name string) {
+	Register(testTaskType, profilerType, func(*BaseAnalyzerConfig) Analyzer { return &stubAnalyzer{name: name} })
+	t.Cleanup(func() {
+		registryMu.Lock()
+		delete(registry, AnalyzerKey{TaskType: testTaskType, ProfilerType: profilerType})
+		registryMu.Unlock()
+	})
+}
+
+func TestRegister(t *testing.T) {
+	factory := NewFactory(nil)
+
+	_, err := factory.CreateAnalyzer(testTaskType, model.ProfilerTypePerf)
+	assert.ErrorIs(t, err, ErrUnsupportedTaskType)
+
+	registerStub(t, AnyProfiler, "any")
+	registerStub(t, model.ProfilerTypePProf, "pprof")
+
+	a, err := factory.CreateAnalyzer(testTaskType, model.ProfilerTypePProf)
+	require.NoError(t, err)
+	assert.Equal(t, "pprof", a.Name(), "exact profiler type first")
+
+	a, err = factory.CreateAnalyzer(testTaskType, model.ProfilerTypePerf)
+	require.NoError(t, err)
+	assert.Equal(t, "any", a.Name())
+
+	manager := factory.CreateManager()
+	a, ok := manager.GetAnalyzerForRequest(&model.AnalysisRequest{TaskType: testTaskType, ProfilerType: model.ProfilerTypePProf})
+	require.True(t, ok)
+	assert.Equal(t, "pprof", a.Name())
+}
+
+func TestFactory_CreateAnalyzer_BuiltIn(t *testing.T) {
+	factory := NewFactory(nil)
+
+	tests := []struct {
+		taskType     model.TaskType
+		profilerType model.ProfilerType
+		wantName     string
+	}{
+		{model.TaskTypeJava, model.ProfilerTypePerf, "java_cpu_analyzer"},
+		{model.TaskTypeJava, model.ProfilerTypeAsyncAlloc, "java_mem_analyzer"},
+		{model.TaskTypeJavaHeap, model.ProfilerTypePerf, "java_heap_analyzer"},
+		{model.TaskTypeJavaHeap, model.ProfilerTypeAsyncAlloc, "java_heap_analyzer"},
+		{model.TaskTypeGeneric, model.ProfilerTypePerf, "java_cpu_analyzer"},
+		{model.TaskTypeGeneric, model.ProfilerTypePProf, "pprof_cpu_analyzer"},
+		{model.TaskTypePProfCPU, model.ProfilerTypePProf, "pprof_cpu_analyzer"},
+		{model.TaskTypePProfMutex, model.ProfilerTypePProf, "pprof_mutex_analyzer"},
+	}
+	for _, tt := range tests {
+		a, err := factory.CreateAnalyzer(tt.taskType, tt.profilerType)
+		require.NoError(t, err, "%s/%s", tt.taskType, tt.profilerType)
+		assert.Equal(t, tt.wantName, a.Name(), "%s/%s", tt.taskType, tt.profilerType)
+	}
+
+	_, err := factory.CreateAnalyzer(model.TaskTypeJava, model.ProfilerTypePProf)
+	assert.ErrorIs(t, err, ErrUnsupportedTaskType)
+}
+
+func TestRegisterMode(t *testing.T) {
+	t.Cleanup(func() {
+		modeRegistryMu.Lock()
+		delete(modeRegistry, "test-mode")
+		delete(modeRegistry, "test-batch")
+		delete(modeConstructors, "test-batch")
+		modeRegistryMu.Unlock()
+	})
+	registerStub(t, model.ProfilerTypePerf, "stub")
+
+	RegisterMode(ModeInfo{Mode: "test-mode", Description: "Test", TaskType: testTaskType, Profiler: model.ProfilerTypePerf}, nil)
+	RegisterMode(ModeInfo{Mode: "test-batch", Description: "Test batch"},
+		func(*BaseAnalyzerConfig) Analyzer { return &stubAnalyzer{name: "batch"} })
+
+	mode, err := ParseMode("TEST-MODE")
+	require.NoError(t, err)
+
+	factory := NewFactory(nil)
+	a, err := factory.CreateAnalyzerForMode(mode)
+	require.NoError(t, err)
+	assert.Equal(t, "stub", a.Name(), "analyzed by the analyzer of its task type")
+
+	a, err = factory.CreateAnalyzerForMode("test-batch")
+	require.NoError(t, err)
+	assert.Equal(t, "batch", a.Name())
+
+	modes := AllModes()
+	assert.Equal(t, ModeJavaCPU, modes[0].Mode, "built-in modes first")
+	assert.Equal(t, AnalysisMode("test-batch"), modes[len(modes)-2].Mode)
+	assert.Equal(t, AnalysisMode("test-mode"), modes[len(modes)-1].Mode)
+	assert.Contains(t, ValidModes(), "test-mode")
+}
+
+func TestLoadPlugins(t *testing.T) {
+	dir := t.TempDir()
+	paths, err := LoadPlugins(dir)
+	require.NoError(t, err)
+	assert.Empty(t, paths)
+
+	_, err = LoadPlugins(filepath.Join(dir, "missing"))
+	assert.ErrorContains(t, err, "failed to read plugin directory")
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "broken.so"), []byte("not a plugin"), 0644))
+	_, err = LoadPlugins(dir)
+	assert.ErrorContains(t, err, "failed to load analyzer plugin broken.so")
+}
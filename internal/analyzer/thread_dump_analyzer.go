@@ -0,0 +1,258 @@
+package analyzer
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/perf-analysis/internal/parser/jfr"
+	"github.com/perf-analysis/internal/parser/jstack"
+	"github.com/perf-analysis/pkg/model"
+)
+
+func init() {
+	Register(model.TaskTypeThreadDump, AnyProfiler, func(c *BaseAnalyzerConfig) Analyzer { return NewThreadDumpAnalyzer(c) })
+}
+
+const (
+	// threadDumpFile is the analysis of the thread dumps, served to the web UI.
+	threadDumpFile = "threaddump.json"
+	// threadDumpTopMonitors is the number of hot monitors reported.
+	threadDumpTopMonitors = 50
+)
+
+// ThreadDumpAnalyzer analyzes Java thread dumps, of jstack or jcmd
+// Thread.print, or the jdk.ThreadDump events of a JFR recording: thread
+// states, deadlocks and the monitors threads are blocked on.
+type ThreadDumpAnalyzer struct {
+	*BaseAnalyzer
+}
+
+// NewThreadDumpAnalyzer creates a new thread dump analyzer.
+func NewThreadDumpAnalyzer(config *BaseAnalyzerConfig) *ThreadDumpAnalyzer {
+	if config == nil {
+		config = DefaultBaseAnalyzerConfig()
+	}
+
+	return &ThreadDumpAnalyzer{
+		BaseAnalyzer: NewBaseAnalyzer(config),
+	}
+}
+
+// Name returns the analyzer name.
+func (a *ThreadDumpAnalyzer) Name() string {
+	return "thread_dump_analyzer"
+}
+
+// SupportedTypes returns the task types supported by this analyzer.
+func (a *ThreadDumpAnalyzer) SupportedTypes() []model.TaskType {
+	return []model.TaskType{model.TaskTypeThreadDump}
+}
+
+// CanHandle checks if this analyzer can handle the given request.
+func (a *ThreadDumpAnalyzer) CanHandle(req *model.AnalysisRequest) bool {
+	return req.TaskType == model.TaskTypeThreadDump
+}
+
+// Analyze performs thread dump analysis using an input file.
+func (a *ThreadDumpAnalyzer) Analyze(ctx context.Context, req *model.AnalysisRequest) (*model.AnalysisResponse, error) {
+	file, err := os.Open(req.InputFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open input file: %w", err)
+	}
+	defer file.Close()
+
+	return a.AnalyzeFromReader(ctx, req, file)
+}
+
+// AnalyzeFromReader performs thread dump analysis from a reader of one or
+// more thread dumps, oldest first, or of a JFR recording.
+func (a *ThreadDumpAnalyzer) AnalyzeFromReader(ctx context.Context, req *model.AnalysisRequest, dataReader io.Reader) (*model.AnalysisResponse, error) {
+	// Step 1: Parse the thread dumps
+	dumps, err := a.parseDumps(ctx, bufio.NewReader(dataReader))
+	if err != nil {
+		if errors.Is(err, jstack.ErrNoThreads) {
+			return nil, ErrEmptyData
+		}
+		return nil, fmt.Errorf("%w: %v", ErrParseError, err)
+	}
+
+	// Step 2: Determine output directory
+	taskDir := req.OutputDir
+	if taskDir == "" {
+		taskDir, err = a.EnsureOutputDir(req.TaskUUID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create output directory: %w", err)
+		}
+	}
+
+	// Step 3: Analyze the dumps
+	data := buildThreadDumpData(dumps)
+	totalThreads := 0
+	for _, dump := range dumps {
+		totalThreads += len(dump.Threads)
+	}
+
+	// Step 4: Write the analysis for the web UI
+	path := filepath.Join(taskDir, threadDumpFile)
+	content, err := json.Marshal(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal thread dump analysis: %w", err)
+	}
+	if err := os.WriteFile(path, content, 0644); err != nil {
+		return nil, fmt.Errorf("failed to write thread dump analysis: %w", err)
+	}
+
+	return &model.AnalysisResponse{
+		TaskUUID:     req.TaskUUID,
+		TaskType:     req.TaskType,
+		TotalRecords: totalThreads,
+		OutputFiles: []model.OutputFile{{
+			Name:        "Thread Dump Analysis",
+			LocalPath:   path,
+			COSKey:      req.TaskUUID + "/" + threadDumpFile,
+			ContentType: "application/json",
+		}},
+		Data: data,
+	}, nil
+}
+
+// parseDumps parses the thread dumps of a JFR recording or of jstack text.
+func (a *ThreadDumpAnalyzer) parseDumps(ctx context.Context, r *bufio.Reader) ([]*jstack.ThreadDump, error) {
+	header, _ := r.Peek(4)
+	if !jfr.IsJFR(header) {
+		return jstack.NewParser().Parse(ctx, r)
+	}
+
+	rec, err := jfr.NewParser().Parse(ctx, r)
+	if err != nil {
+		return nil, err
+	}
+	var dumps []*jstack.ThreadDump
+	for _, text := range rec.ThreadDumps {
+		parsed, err := jstack.NewParser().Parse(ctx, strings.NewReader(text))
+		if errors.Is(err, jstack.ErrNoThreads) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		dumps = append(dumps, parsed...)
+	}
+	if len(dumps) == 0 {
+		return nil, jstack.ErrNoThreads
+	}
+	return dumps, nil
+}
+
+// buildThreadDumpData aggregates the deadlocks and hot monitors of the
+// dumps, oldest first, and reports the states and threads of the latest.
+func buildThreadDumpData(dumps []*jstack.ThreadDump) *model.ThreadDumpData {
+	latest := dumps[len(dumps)-1]
+	data := &model.ThreadDumpData{
+		DumpCount:    len(dumps),
+		TotalThreads: len(latest.Threads),
+		States:       latest.StateCounts(),
+	}
+
+	deadlocks := make(map[string]int)
+	monitors := make(map[string]int)
+	for _, dump := range dumps {
+		data.StateHistory = append(data.StateHistory, dump.StateCounts())
+
+		for _, d := range dump.Deadlocks() {
+			names := make([]string, len(d.Threads))
+			for i, t := range d.Threads {
+				names[i] = t.Thread.Name
+			}
+			key := strings.Join(names, "\x00")
+			i, ok := deadlocks[key]
+			if !ok {
+				i = len(data.Deadlocks)
+				deadlocks[key] = i
+				data.Deadlocks = append(data.Deadlocks, model.Deadlock{})
+			}
+			// Report the threads of the latest dump the deadlock is in
+			data.Deadlocks[i].Threads = deadlockThreads(d)
+			data.Deadlocks[i].DumpCount++
+		}
+
+		for _, m := range dump.HotMonitors() {
+			i, ok := monitors[m.Address]
+			if !ok {
+				i = len(data.HotMonitors)
+				monitors[m.Address] = i
+				data.HotMonitors = append(data.HotMonitors, model.HotMonitor{Address: m.Address})
+			}
+			hot := &data.HotMonitors[i]
+			hot.Class = m.Class
+			hot.Owner = ""
+			if m.Owner != nil {
+				hot.Owner = m.Owner.Name
+			}
+			hot.Waiters = len(m.Waiters)
+			hot.MaxWaiters = max(hot.MaxWaiters, len(m.Waiters))
+			hot.WaiterThreads = hot.WaiterThreads[:0]
+			for _, t := range m.Waiters {
+				hot.WaiterThreads = append(hot.WaiterThreads, t.Name)
+			}
+			hot.DumpCount++
+		}
+	}
+
+	sort.SliceStable(data.HotMonitors, func(i, j int) bool {
+		a, b := data.HotMonitors[i], data.HotMonitors[j]
+		if a.MaxWaiters != b.MaxWaiters {
+			return a.MaxWaiters > b.MaxWaiters
+		}
+		return a.DumpCount > b.DumpCount
+	})
+	if len(data.HotMonitors) > threadDumpTopMonitors {
+		data.HotMonitors = data.HotMonitors[:threadDumpTopMonitors]
+	}
+
+	for _, t := range latest.Threads {
+		thread := model.DumpedThread{
+			Name:        t.Name,
+			ID:          t.ID,
+			Daemon:      t.Daemon,
+			State:       t.State,
+			StateDetail: t.StateDetail,
+			Stack:       t.Stack,
+		}
+		if thread.State == "" {
+			thread.State = jstack.StateUnknown
+		}
+		if lock := t.Blocker(); lock != nil {
+			thread.BlockedOn = lock.Address
+		}
+		for _, lock := range t.Locked {
+			thread.Locked = append(thread.Locked, lock.Address)
+		}
+		data.Threads = append(data.Threads, thread)
+	}
+
+	return data
+}
+
+// deadlockThreads converts the threads of a deadlock.
+func deadlockThreads(d jstack.Deadlock) []model.DeadlockThread {
+	threads := make([]model.DeadlockThread, 0, len(d.Threads))
+	for _, t := range d.Threads {
+		threads = append(threads, model.DeadlockThread{
+			Name:        t.Thread.Name,
+			LockAddress: t.WaitingFor.Address,
+			LockClass:   t.WaitingFor.Class,
+			HeldBy:      t.HeldBy.Name,
+			Stack:       t.Thread.Stack,
+		})
+	}
+	return threads
+}
@@ -0,0 +1,124 @@
+package analyzer
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/perf-analysis/pkg/model"
+)
+
+// threadDumpInput is two jstack dumps: two threads contending for a lock
+// held by a third, then deadlocked with it.
+const threadDumpInput = `2024-05-01 10:00:00
+Full thread dump OpenJDK 64-Bit Server VM (17.0.2+8 mixed mode):
+
+"worker-1" #20 prio=5 nid=0x10 waiting for monitor entry
+   java.lang.Thread.State: BLOCKED (on object monitor)
+	at app.Store.put(Store.java:30)
+	- waiting to lock <0x01> (a app.Store)
+	at app.Worker.run(Worker.java:12)
+
+"worker-2" #21 prio=5 nid=0x11 waiting for monitor entry
+   java.lang.Thread.State: BLOCKED (on object monitor)
+	at app.Store.put(Store.java:30)
+	- waiting to lock <0x01> (a app.Store)
+	at app.Worker.run(Worker.java:12)
+
+"flusher" #22 daemon prio=5 nid=0x12 runnable
+   java.lang.Thread.State: RUNNABLE
+	at app.Store.flush(Store.java:50)
+	- locked <0x01> (a app.Store)
+
+2024-05-01 10:00:10
+Full thread dump OpenJDK 64-Bit Server VM (17.0.2+8 mixed mode):
+
+"worker-1" #20 prio=5 nid=0x10 waiting for monitor entry
+   java.lang.Thread.State: BLOCKED (on object monitor)
+	at app.Store.put(Store.java:30)
+	- waiting to lock <0x01> (a app.Store)
+	- locked <0x02> (a app.Index)
+	at app.Worker.run(Worker.java:12)
+
+"flusher" #22 daemon prio=5 nid=0x12 waiting for monitor entry
+   java.lang.Thread.State: BLOCKED (on object monitor)
+	at app.Index.update(Index.java:20)
+	- waiting to lock <0x02> (a app.Index)
+	at app.Store.flush(Store.java:50)
+	- locked <0x01> (a app.Store)
+`
+
+func TestThreadDumpAnalyzer_CanHandle(t *testing.T) {
+	analyzer := NewThreadDumpAnalyzer(nil)
+
+	assert.Equal(t, "thread_dump_analyzer", analyzer.Name())
+	assert.True(t, analyzer.CanHandle(&model.AnalysisRequest{TaskType: model.TaskTypeThreadDump}))
+	assert.False(t, analyzer.CanHandle(&model.AnalysisRequest{TaskType: model.TaskTypeJava}))
+}
+
+func TestThreadDumpAnalyzer_Analyze(t *testing.T) {
+	analyzer := NewThreadDumpAnalyzer(nil)
+	req := &model.AnalysisRequest{
+		TaskUUID:  "test-threaddump-uuid",
+		TaskType:  model.TaskTypeThreadDump,
+		OutputDir: t.TempDir(),
+	}
+
+	result, err := analyzer.AnalyzeFromReader(context.Background(), req, strings.NewReader(threadDumpInput))
+	require.NoError(t, err)
+	assert.Equal(t, 5, result.TotalRecords)
+
+	data, ok := result.Data.(*model.ThreadDumpData)
+	require.True(t, ok, "Data should be ThreadDumpData")
+	assert.Equal(t, 2, data.DumpCount)
+	assert.Equal(t, 2, data.TotalThreads)
+	assert.Equal(t, map[string]int{"BLOCKED": 2}, data.States)
+	assert.Equal(t, []map[string]int{{"BLOCKED": 2, "RUNNABLE": 1}, {"BLOCKED": 2}}, data.StateHistory)
+
+	require.Len(t, data.Deadlocks, 1)
+	deadlock := data.Deadlocks[0]
+	assert.Equal(t, 1, deadlock.DumpCount)
+	require.Len(t, deadlock.Threads, 2)
+	assert.Equal(t, "flusher", deadlock.Threads[0].Name)
+	assert.Equal(t, "0x02", deadlock.Threads[0].LockAddress)
+	assert.Equal(t, "app.Index", deadlock.Threads[0].LockClass)
+	assert.Equal(t, "worker-1", deadlock.Threads[0].HeldBy)
+	assert.Equal(t, []string{"app.Store.flush", "app.Index.update"}, deadlock.Threads[0].Stack)
+
+	// The store lock had the most waiters, in the first dump
+	require.Len(t, data.HotMonitors, 2)
+	store := data.HotMonitors[0]
+	assert.Equal(t, "0x01", store.Address)
+	assert.Equal(t, "app.Store", store.Class)
+	assert.Equal(t, "flusher", store.Owner)
+	assert.Equal(t, 1, store.Waiters)
+	assert.Equal(t, 2, store.MaxWaiters)
+	assert.Equal(t, []string{"worker-1"}, store.WaiterThreads)
+	assert.Equal(t, 2, store.DumpCount)
+
+	require.Len(t, data.Threads, 2)
+	assert.Equal(t, "0x01", data.Threads[0].BlockedOn)
+	assert.Equal(t, []string{"0x02"}, data.Threads[0].Locked)
+
+	// The analysis is written for the web UI
+	require.Len(t, result.OutputFiles, 1)
+	assert.Equal(t, "test-threaddump-uuid/threaddump.json", result.OutputFiles[0].COSKey)
+	content, err := os.ReadFile(result.OutputFiles[0].LocalPath)
+	require.NoError(t, err)
+	var written model.ThreadDumpData
+	require.NoError(t, json.Unmarshal(content, &written))
+	assert.Equal(t, data.Deadlocks, written.Deadlocks)
+}
+
+func TestThreadDumpAnalyzer_Analyze_EmptyData(t *testing.T) {
+	analyzer := NewThreadDumpAnalyzer(nil)
+	req := &model.AnalysisRequest{TaskType: model.TaskTypeThreadDump, OutputDir: t.TempDir()}
+
+	_, err := analyzer.AnalyzeFromReader(context.Background(), req, strings.NewReader("no threads here\n"))
+	assert.ErrorIs(t, err, ErrEmptyData)
+}
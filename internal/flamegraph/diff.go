@@ -0,0 +1,201 @@
+package flamegraph
+
+import "sort"
+
+// DiffNode is a frame of a differential flame graph. It is drawn with the
+// samples of the target profile, like a Node, and colored by Delta: red for
+// frames that grew, blue for frames that shrank.
+type DiffNode struct {
+	Name     string      `json:"name"`
+	Value    int64       `json:"value"`          // Target samples including children
+	Self     int64       `json:"self,omitempty"` // Target self samples
+	Children []*DiffNode `json:"children,omitempty"`
+
+	BaseValue int64 `json:"base_value"`          // Base samples including children
+	BaseSelf  int64 `json:"base_self,omitempty"` // Base self samples
+
+	// Delta is the change of the frame's share of all samples, in percentage
+	// points. Shares rather than counts are compared, so profiles of different
+	// durations can be compared.
+	Delta float64 `json:"delta"`
+}
+
+// DiffFlameGraph compares the flame graphs of two profiles, e.g. before and
+// after a change.
+type DiffFlameGraph struct {
+	Root             *DiffNode `json:"root"`
+	BaseTotalSamples int64     `json:"base_total_samples"`
+	TotalSamples     int64     `json:"total_samples"`
+	MaxDepth         int       `json:"max_depth,omitempty"`
+}
+
+// FunctionDiff is the change of the time spent in a function between two
+// profiles, as shares of all samples.
+type FunctionDiff struct {
+	Name              string  `json:"name"`
+	BaseSelf          int64   `json:"base_self"`
+	Self              int64   `json:"self"`
+	BaseSelfPercent   float64 `json:"base_self_percent"`
+	SelfPercent       float64 `json:"self_percent"`
+	DeltaSelfPercent  float64 `json:"delta_self_percent"`
+	BaseTotalPercent  float64 `json:"base_total_percent"`
+	TotalPercent      float64 `json:"total_percent"`
+	DeltaTotalPercent float64 `json:"delta_total_percent"`
+}
+
+// NewDiffFlameGraph merges the flame graphs of a base and a target profile.
+// Frames are matched by name along their call path; frames only in the base
+// profile are kept with a zero Value.
+func NewDiffFlameGraph(base, target *FlameGraph) *DiffFlameGraph {
+	diff := &DiffFlameGraph{
+		Root:             &DiffNode{Name: "root"},
+		BaseTotalSamples: base.TotalSamples,
+		TotalSamples:     target.TotalSamples,
+	}
+	if target.Root != nil {
+		diff.Root.Name = target.Root.Name
+		mergeDiffNode(diff.Root, target.Root, false)
+	}
+	if base.Root != nil {
+		mergeDiffNode(diff.Root, base.Root, true)
+	}
+	diff.Root.computeDelta(diff.BaseTotalSamples, diff.TotalSamples)
+	diff.MaxDepth = diff.Root.depth() - 1
+	return diff
+}
+
+// mergeDiffNode adds the samples of n and its descendants to d.
+func mergeDiffNode(d *DiffNode, n *Node, isBase bool) {
+	if isBase {
+		d.BaseValue += n.Value
+		d.BaseSelf += n.Self
+	} else {
+		d.Value += n.Value
+		d.Self += n.Self
+	}
+	if len(n.Children) == 0 {
+		return
+	}
+
+	index := make(map[string]*DiffNode, len(d.Children))
+	for _, child := range d.Children {
+		index[child.Name] = child
+	}
+	for _, child := range n.Children {
+		dc, ok := index[child.Name]
+		if !ok {
+			dc = &DiffNode{Name: child.Name}
+			index[child.Name] = dc
+			d.Children = append(d.Children, dc)
+		}
+		mergeDiffNode(dc, child, isBase)
+	}
+}
+
+// computeDelta sets the Delta of d and its descendants.
+func (d *DiffNode) computeDelta(baseTotal, total int64) {
+	d.Delta = percentOf(d.Value, total) - percentOf(d.BaseValue, baseTotal)
+	for _, child := range d.Children {
+		child.computeDelta(baseTotal, total)
+	}
+}
+
+// depth returns the number of levels of the subtree of d.
+func (d *DiffNode) depth() int {
+	deepest := 0
+	for _, child := range d.Children {
+		if depth := child.depth(); depth > deepest {
+			deepest = depth
+		}
+	}
+	return deepest + 1
+}
+
+// Prune returns a copy of the tree without the frames having less than
+// minFraction of all samples in both profiles.
+func (fg *DiffFlameGraph) Prune(minFraction float64) *DiffNode {
+	minBase := int64(float64(fg.BaseTotalSamples) * minFraction)
+	minTarget := int64(float64(fg.TotalSamples) * minFraction)
+	return fg.Root.prune(max(minBase, 1), max(minTarget, 1))
+}
+
+func (d *DiffNode) prune(minBase, minTarget int64) *DiffNode {
+	pruned := &DiffNode{
+		Name: d.Name, Value: d.Value, Self: d.Self,
+		BaseValue: d.BaseValue, BaseSelf: d.BaseSelf, Delta: d.Delta,
+	}
+	for _, child := range d.Children {
+		if child.BaseValue >= minBase || child.Value >= minTarget {
+			pruned.Children = append(pruned.Children, child.prune(minBase, minTarget))
+		}
+	}
+	return pruned
+}
+
+// Functions returns the change of the self and total time of every function,
+// most regressed self time first. The total time of recursive functions
+// counts each sample once.
+func (fg *DiffFlameGraph) Functions() []*FunctionDiff {
+	type funcSamples struct {
+		baseSelf, self, baseTotal, total int64
+	}
+	funcs := make(map[string]*funcSamples)
+	onStack := make(map[string]int)
+
+	var walk func(d *DiffNode)
+	walk = func(d *DiffNode) {
+		f := funcs[d.Name]
+		if f == nil {
+			f = &funcSamples{}
+			funcs[d.Name] = f
+		}
+		f.baseSelf += d.BaseSelf
+		f.self += d.Self
+		if onStack[d.Name] == 0 {
+			f.baseTotal += d.BaseValue
+			f.total += d.Value
+		}
+		onStack[d.Name]++
+		for _, child := range d.Children {
+			walk(child)
+		}
+		onStack[d.Name]--
+	}
+	for _, child := range fg.Root.Children {
+		walk(child)
+	}
+
+	result := make([]*FunctionDiff, 0, len(funcs))
+	for name, f := range funcs {
+		fd := &FunctionDiff{
+			Name:             name,
+			BaseSelf:         f.baseSelf,
+			Self:             f.self,
+			BaseSelfPercent:  percentOf(f.baseSelf, fg.BaseTotalSamples),
+			SelfPercent:      percentOf(f.self, fg.TotalSamples),
+			BaseTotalPercent: percentOf(f.baseTotal, fg.BaseTotalSamples),
+			TotalPercent:     percentOf(f.total, fg.TotalSamples),
+		}
+		fd.DeltaSelfPercent = fd.SelfPercent - fd.BaseSelfPercent
+		fd.DeltaTotalPercent = fd.TotalPercent - fd.BaseTotalPercent
+		result = append(result, fd)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].DeltaSelfPercent != result[j].DeltaSelfPercent {
+			return result[i].DeltaSelfPercent > result[j].DeltaSelfPercent
+		}
+		if result[i].DeltaTotalPercent != result[j].DeltaTotalPercent {
+			return result[i].DeltaTotalPercent > result[j].DeltaTotalPercent
+		}
+		return result[i].Name < result[j].Name
+	})
+	return result
+}
+
+// percentOf returns value as a percentage of total.
+func percentOf(value, total int64) float64 {
+	if total == 0 {
+		return 0
+	}
+	return float64(value) * 100 / float64(total)
+}
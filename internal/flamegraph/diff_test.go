@@ -0,0 +1,129 @@
+package flamegraph
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// buildFlameGraph builds a flame graph from stacks and their sample counts.
+func buildFlameGraph(stacks map[string]int64) *FlameGraph {
+	b := NewNodeBuilder("root")
+	for stack, value := range stacks {
+		b.AddStack(StringToStack(stack), value)
+	}
+	root := b.Build()
+	return &FlameGraph{Root: root, TotalSamples: root.Value}
+}
+
+func findDiffNode(t *testing.T, n *DiffNode, path ...string) *DiffNode {
+	t.Helper()
+	for _, name := range path {
+		var next *DiffNode
+		for _, child := range n.Children {
+			if child.Name == name {
+				next = child
+			}
+		}
+		require.NotNil(t, next, "no frame %s under %s", name, n.Name)
+		n = next
+	}
+	return n
+}
+
+func TestNewDiffFlameGraph(t *testing.T) {
+	base := buildFlameGraph(map[string]int64{
+		"main;parse": 50,
+		"main;write": 30,
+		"main;gc":    20,
+	})
+	target := buildFlameGraph(map[string]int64{
+		"main;parse":      100,
+		"main;write":      60,
+		"main;parse;json": 40,
+	})
+
+	diff := NewDiffFlameGraph(base, target)
+
+	assert.Equal(t, int64(100), diff.BaseTotalSamples)
+	assert.Equal(t, int64(200), diff.TotalSamples)
+	assert.Equal(t, 3, diff.MaxDepth)
+	assert.Equal(t, int64(200), diff.Root.Value)
+	assert.Equal(t, int64(100), diff.Root.BaseValue)
+	assert.InDelta(t, 0, diff.Root.Delta, 1e-9)
+
+	parse := findDiffNode(t, diff.Root, "main", "parse")
+	assert.Equal(t, int64(140), parse.Value)
+	assert.Equal(t, int64(100), parse.Self)
+	assert.Equal(t, int64(50), parse.BaseValue)
+	assert.InDelta(t, 20, parse.Delta, 1e-9) // 50% -> 70%
+
+	write := findDiffNode(t, diff.Root, "main", "write")
+	assert.InDelta(t, 0, write.Delta, 1e-9) // 30% of both profiles
+
+	// Frames that disappeared are kept with no target samples
+	gc := findDiffNode(t, diff.Root, "main", "gc")
+	assert.Equal(t, int64(0), gc.Value)
+	assert.Equal(t, int64(20), gc.BaseValue)
+	assert.InDelta(t, -20, gc.Delta, 1e-9)
+
+	json := findDiffNode(t, diff.Root, "main", "parse", "json")
+	assert.Equal(t, int64(0), json.BaseValue)
+	assert.InDelta(t, 20, json.Delta, 1e-9)
+}
+
+func TestDiffFlameGraph_Functions(t *testing.T) {
+	base := buildFlameGraph(map[string]int64{
+		"main;parse;parse": 50,
+		"main;gc":          50,
+	})
+	target := buildFlameGraph(map[string]int64{
+		"main;parse;parse": 75,
+		"main;gc":          25,
+	})
+
+	funcs := NewDiffFlameGraph(base, target).Functions()
+	require.Len(t, funcs, 3)
+
+	assert.Equal(t, "parse", funcs[0].Name)
+	assert.Equal(t, int64(50), funcs[0].BaseSelf)
+	assert.Equal(t, int64(75), funcs[0].Self)
+	assert.InDelta(t, 25, funcs[0].DeltaSelfPercent, 1e-9)
+	// Recursive frames count once
+	assert.InDelta(t, 75, funcs[0].TotalPercent, 1e-9)
+
+	assert.Equal(t, "main", funcs[1].Name)
+	assert.InDelta(t, 0, funcs[1].DeltaSelfPercent, 1e-9)
+	assert.InDelta(t, 100, funcs[1].TotalPercent, 1e-9)
+
+	assert.Equal(t, "gc", funcs[2].Name)
+	assert.InDelta(t, -25, funcs[2].DeltaSelfPercent, 1e-9)
+}
+
+func TestDiffFlameGraph_Prune(t *testing.T) {
+	base := buildFlameGraph(map[string]int64{"main;old": 900, "main;rare": 5, "main;tiny": 1})
+	target := buildFlameGraph(map[string]int64{"main;new": 90, "main;rare": 1, "main;tiny": 1})
+
+	// 1% is 9 base samples, or less than 1 target sample
+	pruned := NewDiffFlameGraph(base, target).Prune(0.01)
+
+	main := findDiffNode(t, pruned, "main")
+	require.Len(t, main.Children, 4)
+	findDiffNode(t, main, "old")
+	findDiffNode(t, main, "new")
+
+	pruned = NewDiffFlameGraph(base, target).Prune(0.05)
+	main = findDiffNode(t, pruned, "main")
+	require.Len(t, main.Children, 2)
+	findDiffNode(t, main, "old")
+	findDiffNode(t, main, "new")
+}
+
+func TestNewDiffFlameGraph_Empty(t *testing.T) {
+	diff := NewDiffFlameGraph(NewFlameGraph(), buildFlameGraph(map[string]int64{"main": 10}))
+
+	assert.Equal(t, int64(0), diff.BaseTotalSamples)
+	assert.InDelta(t, 100, findDiffNode(t, diff.Root, "main").Delta, 1e-9)
+	assert.Len(t, NewDiffFlameGraph(NewFlameGraph(), NewFlameGraph()).Functions(), 0)
+}
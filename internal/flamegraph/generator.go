@@ -42,22 +42,27 @@ type GeneratorOptions struct {
 	// IncludeThreadInStack prepends thread name as the first frame in call stacks.
 	// This enables searching for threads in the flame graph visualization.
 	IncludeThreadInStack bool
+
+	// BuildPerProcessFlameGraphs builds individual flame graphs for each
+	// process and cgroup of profiles covering several of them.
+	BuildPerProcessFlameGraphs bool
 }
 
 // DefaultGeneratorOptions returns default generator options.
 // These defaults are optimized for typical Java/Go applications.
 func DefaultGeneratorOptions() *GeneratorOptions {
 	return &GeneratorOptions{
-		MinPercent:                0.1,   // Filter noise below 0.1%
-		IncludeModule:             true,  // Include module info for better analysis
-		EnableThreadAnalysis:      true,  // Enable thread-level insights
-		TopNPerThread:             15,    // Top 15 functions per thread
-		TopNGlobal:                50,    // Top 50 global hotspots
-		MaxCallStacksPerThread:    200,   // Sufficient for most thread patterns
-		MaxCallStacksPerFunc:      10,    // Preserve call path diversity
-		IncludeSwapper:            false, // Exclude idle threads
-		BuildPerThreadFlameGraphs: true,  // Generate per-thread flame graphs
-		IncludeThreadInStack:      true,  // Include thread name as first frame for searchability
+		MinPercent:                 0.1,   // Filter noise below 0.1%
+		IncludeModule:              true,  // Include module info for better analysis
+		EnableThreadAnalysis:       true,  // Enable thread-level insights
+		TopNPerThread:              15,    // Top 15 functions per thread
+		TopNGlobal:                 50,    // Top 50 global hotspots
+		MaxCallStacksPerThread:     200,   // Sufficient for most thread patterns
+		MaxCallStacksPerFunc:       10,    // Preserve call path diversity
+		IncludeSwapper:             false, // Exclude idle threads
+		BuildPerThreadFlameGraphs:  true,  // Generate per-thread flame graphs
+		IncludeThreadInStack:       true,  // Include thread name as first frame for searchability
+		BuildPerProcessFlameGraphs: true,  // Generate per-process flame graphs of multi-process profiles
 	}
 }
 
@@ -130,11 +135,14 @@ func (g *Generator) generateWithAnalysis(ctx context.Context, fg *FlameGraph, sa
 	// Intermediate storage
 	threads := make(map[int]*threadData)
 	globalFuncCounts := make(map[string]int64)
-	globalFuncThreads := make(map[string]map[int]int64) // func -> tid -> samples
+	globalFuncThreads := make(map[string]map[int]int64)   // func -> tid -> samples
 	globalCallStacks := make(map[string]map[string]int64) // func -> stack -> count
 	var totalSamples, totalSamplesWithSwapper int64
 	var maxDepth int
 	uniqueFuncs := make(map[string]struct{})
+	frameTypes := make(map[profiling.FrameType]int64) // leaf frame type -> samples
+	annotated := false
+	processes := newProcessAnalysis(g.opts)
 
 	// Process samples
 	for _, sample := range samples {
@@ -211,6 +219,13 @@ func (g *Generator) generateWithAnalysis(ctx context.Context, fg *FlameGraph, sa
 					globalCallStacks[topFunc] = make(map[string]int64)
 				}
 				globalCallStacks[topFunc][stackStr] += sample.Value
+
+				frameTypes[profiling.ClassifyFrame(topFunc)] += sample.Value
+				if _, _, ok := profiling.SplitFrameType(topFunc); ok {
+					annotated = true
+				}
+
+				processes.add(sample, topFunc)
 			}
 		}
 	}
@@ -312,6 +327,18 @@ func (g *Generator) generateWithAnalysis(ctx context.Context, fg *FlameGraph, sa
 		g.opts.MaxCallStacksPerFunc,
 	)
 
+	// Group the samples by process and cgroup, if there are several
+	if processes.multiple() {
+		fg.ThreadAnalysis.ProcessGroups = processes.build(totalSamples)
+		processes.addFunctionBreakdown(fg.ThreadAnalysis.TopFunctions)
+	}
+
+	// Build the frame type breakdown, meaningful if async-profiler
+	// annotated the frames
+	if annotated {
+		fg.ThreadAnalysis.FrameTypes = buildFrameTypes(frameTypes, totalSamples)
+	}
+
 	// Cleanup flame graph
 	fg.Cleanup(g.opts.MinPercent)
 
@@ -326,6 +353,9 @@ func (g *Generator) appendStack(fg *FlameGraph, sample *model.Sample) {
 
 	node := fg.Root
 	node.Value += sample.Value
+	if sample.State != "" {
+		node.addState(sample.State, sample.Value)
+	}
 
 	// If IncludeThreadInStack is enabled, add thread name as the first frame
 	// This allows searching for threads in the flame graph visualization
@@ -336,6 +366,9 @@ func (g *Generator) appendStack(fg *FlameGraph, sample *model.Sample) {
 			node.AddChild(threadNode)
 		}
 		threadNode.Value += sample.Value
+		if sample.State != "" {
+			threadNode.addState(sample.State, sample.Value)
+		}
 		node = threadNode
 	}
 
@@ -358,6 +391,9 @@ func (g *Generator) appendStack(fg *FlameGraph, sample *model.Sample) {
 		}
 
 		child.Value += sample.Value
+		if sample.State != "" {
+			child.addState(sample.State, sample.Value)
+		}
 		node = child
 	}
 
@@ -583,3 +619,23 @@ func buildGlobalTopFunctions(
 
 	return result
 }
+
+// buildFrameTypes returns the frame types of leaf frames by samples
+// descending.
+func buildFrameTypes(frameTypes map[profiling.FrameType]int64, totalSamples int64) []*FrameTypeInfo {
+	result := make([]*FrameTypeInfo, 0, len(frameTypes))
+	for typ, samples := range frameTypes {
+		info := &FrameTypeInfo{Type: typ, Samples: samples}
+		if totalSamples > 0 {
+			info.Percentage = float64(samples) / float64(totalSamples) * 100
+		}
+		result = append(result, info)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Samples != result[j].Samples {
+			return result[i].Samples > result[j].Samples
+		}
+		return result[i].Type < result[j].Type
+	})
+	return result
+}
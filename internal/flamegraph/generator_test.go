@@ -155,6 +155,61 @@ func TestGenerator_Generate_Aggregation(t *testing.T) {
 	assert.Equal(t, int64(100), fg.Root.Children[0].Value)
 }
 
+func TestGenerator_Generate_ThreadStates(t *testing.T) {
+	samples := []*model.Sample{
+		{ThreadName: "main", TID: 1, CallStack: []string{"func1", "func2"}, Value: 30, State: profiling.StateRunning},
+		{ThreadName: "main", TID: 1, CallStack: []string{"func1", "sleep"}, Value: 50, State: profiling.StateSleeping},
+		{ThreadName: "worker", TID: 2, CallStack: []string{"func3"}, Value: 10},
+	}
+
+	opts := DefaultGeneratorOptions()
+	opts.EnableThreadAnalysis = false
+	opts.IncludeThreadInStack = false
+	fg, err := NewGenerator(opts).Generate(context.Background(), samples)
+	require.NoError(t, err)
+
+	// Nodes take the state of most of their samples
+	assert.Equal(t, profiling.StateSleeping, fg.Root.State)
+	func1 := childNamed(fg.Root, "func1")
+	require.NotNil(t, func1)
+	assert.Equal(t, profiling.StateSleeping, func1.State)
+	assert.Equal(t, profiling.StateRunning, childNamed(func1, "func2").State)
+	assert.Empty(t, childNamed(fg.Root, "func3").State)
+}
+
+// childNamed returns the child of a node with the given name, nil if none.
+func TestGenerator_Generate_FrameTypes(t *testing.T) {
+	samples := []*model.Sample{
+		{ThreadName: "main", TID: 1, CallStack: []string{"com/example/App.main_[j]", "com/example/App.parse_[i]"}, Value: 60},
+		{ThreadName: "main", TID: 1, CallStack: []string{"com/example/App.main_[j]", "com/example/App.write_[0]"}, Value: 30},
+		{ThreadName: "gc", TID: 2, CallStack: []string{"GCTaskThread::run"}, Value: 10},
+	}
+
+	fg, err := NewGenerator(nil).Generate(context.Background(), samples)
+	require.NoError(t, err)
+
+	require.Len(t, fg.ThreadAnalysis.FrameTypes, 3)
+	assert.Equal(t, FrameTypeInfo{Type: profiling.FrameInlined, Samples: 60, Percentage: 60}, *fg.ThreadAnalysis.FrameTypes[0])
+	assert.Equal(t, profiling.FrameInterpreted, fg.ThreadAnalysis.FrameTypes[1].Type)
+	assert.Equal(t, profiling.FrameNative, fg.ThreadAnalysis.FrameTypes[2].Type)
+
+	// Without annotations the frame types are unknown
+	fg, err = NewGenerator(nil).Generate(context.Background(), []*model.Sample{
+		{ThreadName: "main", TID: 1, CallStack: []string{"com/example/App.main"}, Value: 10},
+	})
+	require.NoError(t, err)
+	assert.Empty(t, fg.ThreadAnalysis.FrameTypes)
+}
+
+func childNamed(n *Node, name string) *Node {
+	for _, child := range n.Children {
+		if child.Name == name {
+			return child
+		}
+	}
+	return nil
+}
+
 func TestGenerator_ThreadGroups(t *testing.T) {
 	samples := []*model.Sample{
 		{ThreadName: "pool-1-thread-1", TID: 1, CallStack: []string{"func"}, Value: 50},
@@ -0,0 +1,87 @@
+package flamegraph
+
+import (
+	"github.com/perf-analysis/pkg/profiling"
+)
+
+// CollapseInlined returns a copy of the flame graph without the frames the
+// JIT inlined into their caller, marked "_[i]" by async-profiler: their
+// self value and children are merged into the caller, so that the graph
+// shows the methods the JIT compiled. The flame graph is not modified.
+func (fg *FlameGraph) CollapseInlined() *FlameGraph {
+	collapsed := *fg
+	if fg.Root != nil {
+		collapsed.Root = collapseInlined(fg.Root)
+		collapsed.CalculateMaxDepth()
+	}
+	if fg.ThreadAnalysis != nil {
+		analysis := *fg.ThreadAnalysis
+		analysis.Threads = make([]*ThreadInfo, len(fg.ThreadAnalysis.Threads))
+		for i, t := range fg.ThreadAnalysis.Threads {
+			thread := *t
+			if t.FlameRoot != nil {
+				thread.FlameRoot = collapseInlined(t.FlameRoot)
+			}
+			analysis.Threads[i] = &thread
+		}
+		analysis.ProcessGroups = make([]*ProcessGroupInfo, len(fg.ThreadAnalysis.ProcessGroups))
+		for i, g := range fg.ThreadAnalysis.ProcessGroups {
+			group := *g
+			if g.FlameRoot != nil {
+				group.FlameRoot = collapseInlined(g.FlameRoot)
+			}
+			analysis.ProcessGroups[i] = &group
+		}
+		collapsed.ThreadAnalysis = &analysis
+	}
+	return &collapsed
+}
+
+// collapseInlined returns a copy of a node whose inlined descendants are
+// replaced by their children.
+func collapseInlined(n *Node) *Node {
+	collapsed := &Node{
+		Name:    n.Name,
+		Value:   n.Value,
+		Self:    n.Self,
+		Module:  n.Module,
+		TID:     n.TID,
+		Process: n.Process,
+		State:   n.State,
+	}
+	var add func(children []*Node)
+	add = func(children []*Node) {
+		for _, child := range children {
+			if profiling.ClassifyFrame(child.Name) == profiling.FrameInlined {
+				collapsed.Self += child.Self
+				add(child.Children)
+				continue
+			}
+			collapsed.Children = append(collapsed.Children, collapseInlined(child))
+		}
+	}
+	add(n.Children)
+	collapsed.Children = mergeSiblings(collapsed.Children)
+	return collapsed
+}
+
+// mergeSiblings merges the nodes of the same frame among siblings, e.g. of
+// a method called both directly and from an inlined frame, keeping the
+// order of their first occurrence.
+func mergeSiblings(nodes []*Node) []*Node {
+	index := make(map[string]int, len(nodes))
+	merged := nodes[:0]
+	for _, n := range nodes {
+		key := makeChildKey(n.Name, n.Module, n.Process, n.TID)
+		if i, ok := index[key]; ok {
+			dst := merged[i]
+			dst.Value += n.Value
+			dst.Self += n.Self
+			dst.Children = mergeSiblings(append(dst.Children, n.Children...))
+			continue
+		}
+		index[key] = len(merged)
+		merged = append(merged, n)
+	}
+	return merged
+}
@@ -0,0 +1,49 @@
+package flamegraph
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFlameGraph_CollapseInlined(t *testing.T) {
+	fg := buildFlameGraph(map[string]int64{
+		"App.main_[j];Parser.parse_[i];Lexer.next_[i];Reader.read_[j]": 40,
+		"App.main_[j];Parser.parse_[i];Lexer.next_[i]":                 10,
+		"App.main_[j];Reader.read_[j]":                                 20,
+		"App.main_[j];Parser.parse_[i]":                                5,
+		"App.main_[j];Writer.write_[0]":                                25,
+	})
+	fg.CalculateMaxDepth()
+	fg.ThreadAnalysis = &ThreadAnalysisData{Threads: []*ThreadInfo{{TID: 1, Name: "main", FlameRoot: fg.Root}}}
+
+	collapsed := fg.CollapseInlined()
+
+	// Inlined frames count in their compiled caller, whose calls they
+	// made are merged with its own
+	main := childNamed(collapsed.Root, "App.main_[j]")
+	require.NotNil(t, main)
+	assert.Equal(t, int64(100), main.Value)
+	assert.Equal(t, int64(15), main.Self)
+	require.Len(t, main.Children, 2)
+	read := childNamed(main, "Reader.read_[j]")
+	require.NotNil(t, read)
+	assert.Equal(t, int64(60), read.Value)
+	assert.Equal(t, int64(60), read.Self)
+	assert.Equal(t, int64(25), childNamed(main, "Writer.write_[0]").Value)
+	assert.Equal(t, 2, collapsed.MaxDepth)
+
+	thread := collapsed.ThreadAnalysis.Threads[0]
+	assert.Len(t, childNamed(thread.FlameRoot, "App.main_[j]").Children, 2)
+
+	// The flame graph is not modified
+	assert.Equal(t, 4, fg.MaxDepth)
+	assert.Len(t, childNamed(fg.Root, "App.main_[j]").Children, 3)
+	assert.Same(t, fg.Root, fg.ThreadAnalysis.Threads[0].FlameRoot)
+}
+
+func TestFlameGraph_CollapseInlined_Empty(t *testing.T) {
+	collapsed := (&FlameGraph{}).CollapseInlined()
+	assert.Nil(t, collapsed.Root)
+}
@@ -23,9 +23,11 @@ type Node struct {
 	Module  string `json:"module,omitempty"`  // Module/library name
 	TID     int    `json:"tid,omitempty"`     // Thread ID (0 means aggregated)
 	Process string `json:"process,omitempty"` // Process/thread name
+	State   string `json:"state,omitempty"`   // Dominant thread state, e.g. of wall-clock profiles
 
 	// Internal use only, not serialized
-	childrenMap map[string]int `json:"-"`
+	childrenMap map[string]int   `json:"-"`
+	states      map[string]int64 `json:"-"` // state -> value, resolved into State by Cleanup
 }
 
 // NewNode creates a new flame graph node.
@@ -107,9 +109,33 @@ func makeChildKey(name, module, process string, tid int) string {
 	return name + "\x1E" + module + "\x1E" + process + "\x1E" + itoa(tid)
 }
 
-// Cleanup removes internal maps and optionally filters nodes below threshold.
+// addState accounts value to a thread state of the node.
+func (n *Node) addState(state string, value int64) {
+	if n.states == nil {
+		n.states = make(map[string]int64)
+	}
+	n.states[state] += value
+}
+
+// resolveState sets State to the state with the largest value, the first
+// in name order on ties.
+func (n *Node) resolveState() {
+	var best int64
+	for state, value := range n.states {
+		if value > best || (value == best && state < n.State) {
+			n.State, best = state, value
+		}
+	}
+	n.states = nil
+}
+
+// Cleanup removes internal maps, resolves thread states and optionally
+// filters nodes below threshold.
 func (n *Node) Cleanup(threshold int64) {
 	n.childrenMap = nil
+	if n.states != nil {
+		n.resolveState()
+	}
 
 	if len(n.Children) == 0 {
 		n.Children = nil
@@ -149,6 +175,7 @@ func (n *Node) Clone() *Node {
 		Module:  n.Module,
 		TID:     n.TID,
 		Process: n.Process,
+		State:   n.State,
 	}
 	if len(n.Children) > 0 {
 		clone.Children = make([]*Node, len(n.Children))
@@ -192,6 +219,8 @@ type FlameGraph struct {
 	Root         *Node `json:"root"`
 	TotalSamples int64 `json:"total_samples"`
 	MaxDepth     int   `json:"max_depth,omitempty"`
+	// Unit of the values of the nodes, samples if empty, e.g. "ns"
+	Unit string `json:"unit,omitempty"`
 
 	// Thread-level analysis (optional, for detailed analysis)
 	ThreadAnalysis *ThreadAnalysisData `json:"thread_analysis,omitempty"`
@@ -214,6 +243,13 @@ type ThreadAnalysisData struct {
 
 	// Thread groups
 	ThreadGroups []*ThreadGroupInfo `json:"thread_groups,omitempty"`
+
+	// How the leaf frames ran, e.g. JIT-compiled or interpreted, for
+	// stacks annotated with frame types by async-profiler
+	FrameTypes []*FrameTypeInfo `json:"frame_types,omitempty"`
+
+	// Processes and cgroups, for profiles of several of them
+	ProcessGroups []*ProcessGroupInfo `json:"process_groups,omitempty"`
 }
 
 // ThreadInfo represents detailed CPU analysis for a single thread.
@@ -263,6 +299,9 @@ type TopFunction struct {
 
 	// Top call stacks for this function
 	TopCallStacks []string `json:"top_call_stacks,omitempty"`
+
+	// Per-process breakdown, for profiles of several processes
+	Processes []*ProcessFunctionInfo `json:"processes,omitempty"`
 }
 
 // ThreadFunctionInfo shows function statistics per thread.
@@ -282,6 +321,14 @@ type ThreadGroupInfo struct {
 	TopThread    string  `json:"top_thread,omitempty"`
 }
 
+// FrameTypeInfo is the share of the samples whose leaf frame is of a frame
+// type, e.g. profiling.FrameInterpreted.
+type FrameTypeInfo struct {
+	Type       profiling.FrameType `json:"type"`
+	Samples    int64               `json:"samples"`
+	Percentage float64             `json:"percentage"`
+}
+
 // NewFlameGraph creates a new flame graph with a root node.
 func NewFlameGraph() *FlameGraph {
 	return &FlameGraph{
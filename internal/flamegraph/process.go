@@ -0,0 +1,230 @@
+package flamegraph
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+
+	"github.com/perf-analysis/pkg/model"
+)
+
+// Kinds of process groups.
+const (
+	ProcessGroupProcess = "process" // the threads of a process
+	ProcessGroupCgroup  = "cgroup"  // the processes of a cgroup, e.g. a container
+)
+
+// ProcessGroupInfo is the CPU analysis of the samples of a process, or of
+// the processes of a cgroup, for profiles covering several of them, e.g.
+// perf record -a on a host running containers.
+type ProcessGroupInfo struct {
+	Kind         string  `json:"kind"` // ProcessGroupProcess or ProcessGroupCgroup
+	Key          string  `json:"key"`  // PID or cgroup path
+	Name         string  `json:"name"`
+	PID          int     `json:"pid,omitempty"`
+	Cgroup       string  `json:"cgroup,omitempty"`
+	Samples      int64   `json:"samples"`
+	Percentage   float64 `json:"percentage"`
+	ThreadCount  int     `json:"thread_count"`
+	ProcessCount int     `json:"process_count,omitempty"`
+
+	// Top functions within the group
+	TopFunctions []*ThreadTopFunction `json:"top_functions,omitempty"`
+
+	// Flame graph of the group, whose root has the threads of a process,
+	// or the processes of a cgroup, as children
+	FlameRoot *Node `json:"flame_root,omitempty"`
+}
+
+// ProcessFunctionInfo shows function statistics per process.
+type ProcessFunctionInfo struct {
+	PID        int     `json:"pid"`
+	Name       string  `json:"name"`
+	Cgroup     string  `json:"cgroup,omitempty"`
+	Samples    int64   `json:"samples"`
+	Percentage float64 `json:"percentage"` // of the samples of the process
+}
+
+// processData holds intermediate data for a single process during analysis (internal use).
+type processData struct {
+	pid          int
+	name         string // of the main thread, else of the first thread seen
+	cgroup       string
+	samples      int64
+	threads      map[int]struct{}
+	funcCounts   map[string]int64 // function -> sample count
+	flameBuilder *NodeBuilder
+}
+
+// processAnalysis groups the samples of a profile by process and cgroup.
+type processAnalysis struct {
+	opts          *GeneratorOptions
+	processes     map[int]*processData
+	funcProcesses map[string]map[int]int64 // func -> pid -> samples
+}
+
+func newProcessAnalysis(opts *GeneratorOptions) *processAnalysis {
+	return &processAnalysis{
+		opts:          opts,
+		processes:     make(map[int]*processData),
+		funcProcesses: make(map[string]map[int]int64),
+	}
+}
+
+// add accounts a sample with a call stack to its process, if known.
+func (pa *processAnalysis) add(sample *model.Sample, topFunc string) {
+	if sample.PID <= 0 {
+		return
+	}
+	pd, ok := pa.processes[sample.PID]
+	if !ok {
+		pd = &processData{
+			pid:        sample.PID,
+			name:       sample.ThreadName,
+			cgroup:     sample.Cgroup,
+			threads:    make(map[int]struct{}),
+			funcCounts: make(map[string]int64),
+		}
+		if pa.opts.BuildPerProcessFlameGraphs {
+			pd.flameBuilder = NewNodeBuilder("")
+		}
+		pa.processes[sample.PID] = pd
+	}
+	if sample.TID == sample.PID {
+		pd.name = sample.ThreadName
+	}
+	pd.samples += sample.Value
+	pd.threads[sample.TID] = struct{}{}
+	pd.funcCounts[topFunc] += sample.Value
+
+	if pd.flameBuilder != nil {
+		stack := sample.CallStack
+		if pa.opts.IncludeThreadInStack && sample.ThreadName != "" {
+			stack = append([]string{sample.ThreadName}, stack...)
+		}
+		pd.flameBuilder.AddStack(stack, sample.Value)
+	}
+
+	if pa.funcProcesses[topFunc] == nil {
+		pa.funcProcesses[topFunc] = make(map[int]int64)
+	}
+	pa.funcProcesses[topFunc][sample.PID] += sample.Value
+}
+
+// multiple reports whether the samples span several processes or cgroups.
+func (pa *processAnalysis) multiple() bool {
+	if len(pa.processes) > 1 {
+		return true
+	}
+	for _, pd := range pa.processes {
+		return pd.cgroup != ""
+	}
+	return false
+}
+
+// build returns the processes, then the cgroups, by samples descending.
+func (pa *processAnalysis) build(totalSamples int64) []*ProcessGroupInfo {
+	percentage := func(samples int64) float64 {
+		if totalSamples == 0 {
+			return 0
+		}
+		return float64(samples) / float64(totalSamples) * 100
+	}
+
+	processes := make([]*ProcessGroupInfo, 0, len(pa.processes))
+	for _, pd := range pa.processes {
+		info := &ProcessGroupInfo{
+			Kind:         ProcessGroupProcess,
+			Key:          strconv.Itoa(pd.pid),
+			Name:         pd.name,
+			PID:          pd.pid,
+			Cgroup:       pd.cgroup,
+			Samples:      pd.samples,
+			Percentage:   percentage(pd.samples),
+			ThreadCount:  len(pd.threads),
+			TopFunctions: buildThreadTopFunctions(pd.funcCounts, pd.samples, pa.opts.TopNPerThread),
+		}
+		if pd.flameBuilder != nil {
+			info.FlameRoot = pd.flameBuilder.Build()
+			info.FlameRoot.Name = processLabel(pd.name, pd.pid)
+		}
+		processes = append(processes, info)
+	}
+	sortProcessGroups(processes)
+
+	// Cgroups gather their processes, heaviest first
+	var cgroups []*ProcessGroupInfo
+	byCgroup := make(map[string]*ProcessGroupInfo)
+	cgroupFuncs := make(map[string]map[string]int64)
+	for _, process := range processes {
+		if process.Cgroup == "" {
+			continue
+		}
+		cgroup, ok := byCgroup[process.Cgroup]
+		if !ok {
+			cgroup = &ProcessGroupInfo{Kind: ProcessGroupCgroup, Key: process.Cgroup, Name: process.Cgroup, Cgroup: process.Cgroup}
+			if pa.opts.BuildPerProcessFlameGraphs {
+				cgroup.FlameRoot = NewNode(process.Cgroup, 0)
+			}
+			byCgroup[process.Cgroup] = cgroup
+			cgroupFuncs[process.Cgroup] = make(map[string]int64)
+			cgroups = append(cgroups, cgroup)
+		}
+		cgroup.Samples += process.Samples
+		cgroup.ThreadCount += process.ThreadCount
+		cgroup.ProcessCount++
+		for function, samples := range pa.processes[process.PID].funcCounts {
+			cgroupFuncs[process.Cgroup][function] += samples
+		}
+		if cgroup.FlameRoot != nil && process.FlameRoot != nil {
+			cgroup.FlameRoot.Value += process.FlameRoot.Value
+			cgroup.FlameRoot.Children = append(cgroup.FlameRoot.Children, process.FlameRoot)
+		}
+	}
+	for _, cgroup := range cgroups {
+		cgroup.Percentage = percentage(cgroup.Samples)
+		cgroup.TopFunctions = buildThreadTopFunctions(cgroupFuncs[cgroup.Key], cgroup.Samples, pa.opts.TopNPerThread)
+	}
+	sortProcessGroups(cgroups)
+
+	return append(processes, cgroups...)
+}
+
+// sortProcessGroups sorts process groups by samples descending.
+func sortProcessGroups(groups []*ProcessGroupInfo) {
+	sort.Slice(groups, func(i, j int) bool {
+		if groups[i].Samples != groups[j].Samples {
+			return groups[i].Samples > groups[j].Samples
+		}
+		return groups[i].Key < groups[j].Key
+	})
+}
+
+// addFunctionBreakdown adds to top functions their samples by process.
+func (pa *processAnalysis) addFunctionBreakdown(topFunctions []*TopFunction) {
+	for _, tf := range topFunctions {
+		key := tf.Name
+		if tf.Module != "" {
+			key += "(" + tf.Module + ")"
+		}
+		for pid, samples := range pa.funcProcesses[key] {
+			pd := pa.processes[pid]
+			info := &ProcessFunctionInfo{PID: pid, Name: pd.name, Cgroup: pd.cgroup, Samples: samples}
+			if pd.samples > 0 {
+				info.Percentage = float64(samples) / float64(pd.samples) * 100
+			}
+			tf.Processes = append(tf.Processes, info)
+		}
+		sort.Slice(tf.Processes, func(i, j int) bool {
+			if tf.Processes[i].Samples != tf.Processes[j].Samples {
+				return tf.Processes[i].Samples > tf.Processes[j].Samples
+			}
+			return tf.Processes[i].PID < tf.Processes[j].PID
+		})
+	}
+}
+
+// processLabel names a process in flame graphs, e.g. "java [4321]".
+func processLabel(name string, pid int) string {
+	return fmt.Sprintf("%s [%d]", name, pid)
+}
@@ -0,0 +1,87 @@
+package flamegraph
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/perf-analysis/pkg/model"
+)
+
+func TestGenerator_Generate_ProcessGroups(t *testing.T) {
+	samples := []*model.Sample{
+		{ThreadName: "java", TID: 100, PID: 100, Cgroup: "/docker/app", CallStack: []string{"main", "parse"}, Value: 40},
+		{ThreadName: "worker", TID: 101, PID: 100, Cgroup: "/docker/app", CallStack: []string{"run", "malloc"}, Value: 20},
+		{ThreadName: "sidecar", TID: 200, PID: 200, Cgroup: "/docker/app", CallStack: []string{"loop", "malloc"}, Value: 10},
+		{ThreadName: "nginx", TID: 300, PID: 300, CallStack: []string{"epoll_wait"}, Value: 30},
+	}
+
+	fg, err := NewGenerator(nil).Generate(context.Background(), samples)
+	require.NoError(t, err)
+
+	groups := fg.ThreadAnalysis.ProcessGroups
+	require.Len(t, groups, 4)
+
+	// Processes come first, heaviest first
+	java := groups[0]
+	assert.Equal(t, ProcessGroupProcess, java.Kind)
+	assert.Equal(t, "100", java.Key)
+	assert.Equal(t, "java", java.Name)
+	assert.Equal(t, int64(60), java.Samples)
+	assert.Equal(t, 60.0, java.Percentage)
+	assert.Equal(t, 2, java.ThreadCount)
+	require.NotNil(t, java.FlameRoot)
+	assert.Equal(t, "java [100]", java.FlameRoot.Name)
+	assert.Equal(t, int64(60), java.FlameRoot.Value)
+	assert.Equal(t, "300", groups[1].Key)
+	assert.Equal(t, "200", groups[2].Key)
+
+	// Then cgroups, with their processes as children
+	app := groups[3]
+	assert.Equal(t, ProcessGroupCgroup, app.Kind)
+	assert.Equal(t, "/docker/app", app.Key)
+	assert.Equal(t, int64(70), app.Samples)
+	assert.Equal(t, 2, app.ProcessCount)
+	assert.Equal(t, 3, app.ThreadCount)
+	require.Len(t, app.FlameRoot.Children, 2)
+	assert.Equal(t, "java [100]", app.FlameRoot.Children[0].Name)
+	require.NotEmpty(t, app.TopFunctions)
+	assert.Equal(t, "parse", app.TopFunctions[0].Name)
+	assert.Equal(t, int64(30), app.TopFunctions[1].Samples, "malloc in both processes")
+
+	// Top functions are broken down by process
+	var malloc *TopFunction
+	for _, tf := range fg.ThreadAnalysis.TopFunctions {
+		if tf.Name == "malloc" {
+			malloc = tf
+		}
+	}
+	require.NotNil(t, malloc)
+	require.Len(t, malloc.Processes, 2)
+	assert.Equal(t, ProcessFunctionInfo{PID: 100, Name: "java", Cgroup: "/docker/app", Samples: 20, Percentage: float64(20) / float64(60) * 100}, *malloc.Processes[0])
+	assert.Equal(t, 200, malloc.Processes[1].PID)
+	assert.Equal(t, 100.0, malloc.Processes[1].Percentage)
+}
+
+func TestGenerator_Generate_SingleProcess(t *testing.T) {
+	samples := []*model.Sample{
+		{ThreadName: "java", TID: 100, PID: 100, CallStack: []string{"main"}, Value: 10},
+		{ThreadName: "worker", TID: 101, PID: 100, CallStack: []string{"run"}, Value: 10},
+	}
+
+	fg, err := NewGenerator(nil).Generate(context.Background(), samples)
+	require.NoError(t, err)
+	assert.Nil(t, fg.ThreadAnalysis.ProcessGroups)
+	for _, tf := range fg.ThreadAnalysis.TopFunctions {
+		assert.Nil(t, tf.Processes)
+	}
+
+	// A single containerized process is still grouped by cgroup
+	samples[0].Cgroup, samples[1].Cgroup = "/docker/app", "/docker/app"
+	fg, err = NewGenerator(nil).Generate(context.Background(), samples)
+	require.NoError(t, err)
+	require.Len(t, fg.ThreadAnalysis.ProcessGroups, 2)
+	assert.Equal(t, ProcessGroupCgroup, fg.ThreadAnalysis.ProcessGroups[1].Kind)
+}
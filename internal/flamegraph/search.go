@@ -0,0 +1,76 @@
+package flamegraph
+
+import (
+	"regexp"
+	"sort"
+)
+
+// FrameMatch is a frame matching a search, with its call path.
+type FrameMatch struct {
+	// Path holds the frame names from below the root to the matching frame
+	Path  []string `json:"path"`
+	Value int64    `json:"value"`
+	Self  int64    `json:"self,omitempty"`
+}
+
+// FrameSearchResult holds the frames of a flame graph matching a pattern.
+type FrameSearchResult struct {
+	Pattern string `json:"pattern"`
+	// Matches holds the heaviest matching frames, largest value first
+	Matches []*FrameMatch `json:"matches"`
+	// MatchCount is the number of matching frames, including those not in
+	// Matches
+	MatchCount int `json:"match_count"`
+	// MatchedValue is the value of the stacks with a matching frame. Frames
+	// nested in another match are counted once, so it is at most TotalValue.
+	MatchedValue int64 `json:"matched_value"`
+	// MatchedSelf is the self value of the matching frames
+	MatchedSelf    int64   `json:"matched_self"`
+	TotalValue     int64   `json:"total_value"`
+	MatchedPercent float64 `json:"matched_percent"`
+}
+
+// Search returns the frames whose name matches re, the limit heaviest ones
+// with their call path. A limit of 0 or less returns all of them.
+func (fg *FlameGraph) Search(re *regexp.Regexp, limit int) *FrameSearchResult {
+	result := &FrameSearchResult{Pattern: re.String(), Matches: []*FrameMatch{}}
+	if fg.Root == nil {
+		return result
+	}
+	result.TotalValue = fg.Root.Value
+
+	var path []string
+	var walk func(n *Node, inMatch bool)
+	walk = func(n *Node, inMatch bool) {
+		path = append(path, n.Name)
+		matched := re.MatchString(n.Name)
+		if matched {
+			result.MatchCount++
+			result.MatchedSelf += n.Self
+			if !inMatch {
+				result.MatchedValue += n.Value
+			}
+			result.Matches = append(result.Matches, &FrameMatch{
+				Path:  append([]string(nil), path...),
+				Value: n.Value,
+				Self:  n.Self,
+			})
+		}
+		for _, child := range n.Children {
+			walk(child, inMatch || matched)
+		}
+		path = path[:len(path)-1]
+	}
+	for _, child := range fg.Root.Children {
+		walk(child, false)
+	}
+
+	sort.SliceStable(result.Matches, func(i, j int) bool {
+		return result.Matches[i].Value > result.Matches[j].Value
+	})
+	if limit > 0 && len(result.Matches) > limit {
+		result.Matches = result.Matches[:limit]
+	}
+	result.MatchedPercent = percentOf(result.MatchedValue, result.TotalValue)
+	return result
+}
@@ -0,0 +1,40 @@
+package flamegraph
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFlameGraph_Search(t *testing.T) {
+	fg := buildFlameGraph(map[string]int64{
+		"main;parse;json.Decode":          40,
+		"main;parse;json.Decode;json.Get": 10,
+		"main;write;json.Encode":          30,
+		"main;gc":                         20,
+	})
+
+	result := fg.Search(regexp.MustCompile(`^json\.`), 0)
+	assert.Equal(t, `^json\.`, result.Pattern)
+	assert.Equal(t, 3, result.MatchCount)
+	assert.Equal(t, int64(80), result.MatchedValue, "json.Get is nested in json.Decode")
+	assert.Equal(t, int64(80), result.MatchedSelf)
+	assert.Equal(t, int64(100), result.TotalValue)
+	assert.InDelta(t, 80, result.MatchedPercent, 1e-9)
+
+	require.Len(t, result.Matches, 3)
+	assert.Equal(t, &FrameMatch{Path: []string{"main", "parse", "json.Decode"}, Value: 50, Self: 40}, result.Matches[0])
+	assert.Equal(t, []string{"main", "write", "json.Encode"}, result.Matches[1].Path)
+	assert.Equal(t, []string{"main", "parse", "json.Decode", "json.Get"}, result.Matches[2].Path)
+
+	limited := fg.Search(regexp.MustCompile(`json`), 1)
+	assert.Equal(t, 3, limited.MatchCount)
+	assert.Len(t, limited.Matches, 1)
+
+	none := fg.Search(regexp.MustCompile(`^nothing$`), 10)
+	assert.Zero(t, none.MatchCount)
+	assert.Empty(t, none.Matches)
+	assert.Zero(t, none.MatchedPercent)
+}
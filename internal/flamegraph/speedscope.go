@@ -0,0 +1,71 @@
+package flamegraph
+
+import (
+	"github.com/perf-analysis/internal/parser/speedscope"
+)
+
+// exporterName names this tool in the files it exports.
+const exporterName = "perf-analysis"
+
+// ToSpeedscope converts a flame graph into a speedscope file with a single
+// sampled profile: one sample per frame with self samples, weighing them.
+// Frames are named as in the flame graph, including thread frames.
+func ToSpeedscope(fg *FlameGraph, name string) *speedscope.File {
+	unit := "none"
+	switch fg.Unit {
+	case "ns":
+		unit = "nanoseconds"
+	case "bytes":
+		unit = "bytes"
+	}
+	profile := &speedscope.Profile{
+		Type:    speedscope.ProfileTypeSampled,
+		Name:    name,
+		Unit:    unit,
+		Samples: [][]int{},
+		Weights: []float64{},
+	}
+	file := &speedscope.File{
+		Schema:   speedscope.Schema,
+		Shared:   speedscope.Shared{Frames: []*speedscope.Frame{}},
+		Profiles: []*speedscope.Profile{profile},
+		Name:     name,
+		Exporter: exporterName,
+	}
+	if fg.Root == nil {
+		return file
+	}
+
+	frames := make(map[string]int)
+	var path []int
+	var walk func(n *Node)
+	walk = func(n *Node) {
+		index, ok := frames[n.Name]
+		if !ok {
+			index = len(file.Shared.Frames)
+			frames[n.Name] = index
+			file.Shared.Frames = append(file.Shared.Frames, &speedscope.Frame{Name: n.Name})
+		}
+		path = append(path, index)
+		// Frames whose children were pruned keep their samples as self
+		self := n.Self
+		var children int64
+		for _, child := range n.Children {
+			children += child.Value
+		}
+		self = max(self, n.Value-children)
+		if self > 0 {
+			profile.Samples = append(profile.Samples, append([]int(nil), path...))
+			profile.Weights = append(profile.Weights, float64(self))
+			profile.EndValue += float64(self)
+		}
+		for _, child := range n.Children {
+			walk(child)
+		}
+		path = path[:len(path)-1]
+	}
+	for _, child := range fg.Root.Children {
+		walk(child)
+	}
+	return file
+}
@@ -0,0 +1,91 @@
+package flamegraph
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/perf-analysis/internal/parser/speedscope"
+)
+
+func TestToSpeedscope(t *testing.T) {
+	fg := buildFlameGraph(map[string]int64{
+		"main;parse":       50,
+		"main;parse;alloc": 20,
+		"main":             10,
+	})
+	fg.Unit = "ns"
+
+	file := ToSpeedscope(fg, "task cpu")
+	assert.Equal(t, speedscope.Schema, file.Schema)
+	assert.Equal(t, "task cpu", file.Name)
+	require.Len(t, file.Profiles, 1)
+	profile := file.Profiles[0]
+	assert.Equal(t, speedscope.ProfileTypeSampled, profile.Type)
+	assert.Equal(t, "nanoseconds", profile.Unit)
+	assert.Equal(t, float64(80), profile.EndValue)
+
+	// One sample per frame with self samples
+	weights := make(map[string]float64)
+	for i, sample := range profile.Samples {
+		names := make([]string, len(sample))
+		for j, index := range sample {
+			names[j] = file.Shared.Frames[index].Name
+		}
+		weights[strings.Join(names, ";")] = profile.Weights[i]
+	}
+	assert.Equal(t, map[string]float64{
+		"main":             10,
+		"main;parse":       50,
+		"main;parse;alloc": 20,
+	}, weights)
+	assert.Len(t, file.Shared.Frames, 3)
+}
+
+func TestToSpeedscope_PrunedChildren(t *testing.T) {
+	// Samples of pruned children count as self samples of their parent
+	fg := &FlameGraph{Root: &Node{Name: "root", Value: 30, Children: []*Node{
+		{Name: "main", Value: 30, Children: []*Node{{Name: "parse", Value: 10, Self: 10}}},
+	}}}
+
+	file := ToSpeedscope(fg, "pruned")
+	profile := file.Profiles[0]
+	assert.Equal(t, "none", profile.Unit)
+	assert.Equal(t, []float64{20, 10}, profile.Weights)
+}
+
+func TestToSpeedscope_Empty(t *testing.T) {
+	file := ToSpeedscope(&FlameGraph{}, "empty")
+	require.Len(t, file.Profiles, 1)
+	assert.Empty(t, file.Profiles[0].Samples)
+}
+
+func TestToSpeedscope_RoundTrip(t *testing.T) {
+	stacks := map[string]int64{
+		"main;parse;alloc": 20,
+		"main;write":       30,
+		"gc":               5,
+	}
+	fg := buildFlameGraph(stacks)
+	fg.Unit = "bytes"
+
+	var buf bytes.Buffer
+	require.NoError(t, json.NewEncoder(&buf).Encode(ToSpeedscope(fg, "alloc")))
+	require.True(t, speedscope.IsSpeedscope(buf.Bytes()))
+
+	p := speedscope.NewParser()
+	result, err := p.Parse(context.Background(), &buf)
+	require.NoError(t, err)
+	assert.Equal(t, "bytes", p.Unit)
+
+	parsed := make(map[string]int64)
+	for _, s := range result.Samples {
+		parsed[strings.Join(s.CallStack, ";")] += s.Value
+	}
+	assert.Equal(t, stacks, parsed)
+}
@@ -0,0 +1,277 @@
+package flamegraph
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/perf-analysis/pkg/model"
+	"github.com/perf-analysis/pkg/writer"
+)
+
+// timelineResolutions are the widths of the buckets of timelines, the
+// smallest one keeping the number of buckets under the maximum is chosen.
+var timelineResolutions = []time.Duration{
+	100 * time.Millisecond, 250 * time.Millisecond, 500 * time.Millisecond,
+	time.Second, 2 * time.Second, 5 * time.Second, 10 * time.Second, 15 * time.Second, 30 * time.Second,
+	time.Minute, 2 * time.Minute, 5 * time.Minute, 10 * time.Minute, 15 * time.Minute, 30 * time.Minute,
+	time.Hour,
+}
+
+// Timeline holds the samples of a profile by period of time, to build the
+// flame graphs of time windows and the series of the top functions.
+type Timeline struct {
+	// Start is the start of the first bucket, in nanoseconds of the clock
+	// of the samples
+	Start int64 `json:"start"`
+	// Resolution is the width of the buckets, in nanoseconds
+	Resolution int64 `json:"resolution"`
+	// Unit of the values, samples if empty, e.g. "ns"
+	Unit string `json:"unit,omitempty"`
+
+	Stacks []*TimelineStack `json:"stacks"`
+	// Buckets holds the values of the stacks in each period, as pairs of
+	// an index in Stacks and a value
+	Buckets [][]int64 `json:"buckets"`
+}
+
+// TimelineStack is a call stack of a thread, from the root to the leaf.
+type TimelineStack struct {
+	ThreadName string   `json:"thread_name"`
+	TID        int      `json:"tid,omitempty"`
+	CallStack  []string `json:"callstack"`
+	State      string   `json:"state,omitempty"`
+}
+
+// TimelineSeries holds the values over time of the top functions of a
+// timeline, for stacked area charts.
+type TimelineSeries struct {
+	Start      int64  `json:"start"`
+	Resolution int64  `json:"resolution"`
+	Unit       string `json:"unit,omitempty"`
+	// Totals holds the values of all samples in each bucket
+	Totals    []int64           `json:"totals"`
+	Functions []*FunctionSeries `json:"functions"`
+}
+
+// FunctionSeries holds the self values of a function in each bucket.
+type FunctionSeries struct {
+	Name   string  `json:"name"`
+	Total  int64   `json:"total"`
+	Values []int64 `json:"values"`
+}
+
+// NewTimeline buckets the samples with a timestamp, by the smallest
+// resolution of at least minResolution keeping the number of buckets under
+// maxBuckets. It returns nil if no sample has a timestamp.
+func NewTimeline(samples []*model.Sample, minResolution time.Duration, maxBuckets int) *Timeline {
+	first, last := int64(0), int64(0)
+	found := false
+	for _, s := range samples {
+		if s.Timestamp == 0 {
+			continue
+		}
+		if !found || s.Timestamp < first {
+			first = s.Timestamp
+		}
+		if !found || s.Timestamp > last {
+			last = s.Timestamp
+		}
+		found = true
+	}
+	if !found {
+		return nil
+	}
+
+	resolution := timelineResolutions[len(timelineResolutions)-1]
+	for _, r := range timelineResolutions {
+		if r >= minResolution && (last-first)/int64(r) < int64(maxBuckets) {
+			resolution = r
+			break
+		}
+	}
+
+	t := &Timeline{Start: first - first%int64(resolution), Resolution: int64(resolution)}
+	t.Buckets = make([][]int64, (last-t.Start)/t.Resolution+1)
+
+	stacks := make(map[string]int64)
+	values := make([]map[int64]int64, len(t.Buckets))
+	for _, s := range samples {
+		if s.Timestamp == 0 || len(s.CallStack) == 0 {
+			continue
+		}
+		key := s.ThreadName + "\x00" + s.State + "\x00" + StackToString(s.CallStack)
+		index, ok := stacks[key]
+		if !ok {
+			index = int64(len(t.Stacks))
+			stacks[key] = index
+			t.Stacks = append(t.Stacks, &TimelineStack{ThreadName: s.ThreadName, TID: s.TID, CallStack: s.CallStack, State: s.State})
+		}
+		b := (s.Timestamp - t.Start) / t.Resolution
+		if values[b] == nil {
+			values[b] = make(map[int64]int64)
+		}
+		values[b][index] += s.Value
+	}
+
+	for b, bucket := range values {
+		indexes := make([]int64, 0, len(bucket))
+		for index := range bucket {
+			indexes = append(indexes, index)
+		}
+		sort.Slice(indexes, func(i, j int) bool { return indexes[i] < indexes[j] })
+		t.Buckets[b] = make([]int64, 0, 2*len(indexes))
+		for _, index := range indexes {
+			t.Buckets[b] = append(t.Buckets[b], index, bucket[index])
+		}
+	}
+	return t
+}
+
+// Duration returns the time covered by the buckets of the timeline.
+func (t *Timeline) Duration() time.Duration {
+	return time.Duration(int64(len(t.Buckets)) * t.Resolution)
+}
+
+// Window returns the samples of the buckets overlapping the window from
+// start to end, in time since the start of the timeline, aggregated by
+// stack. An end of 0 or less stands for the end of the timeline.
+func (t *Timeline) Window(start, end time.Duration) []*model.Sample {
+	first, last := t.bucketRange(start, end)
+
+	values := make(map[int64]int64)
+	for b := first; b < last; b++ {
+		bucket := t.Buckets[b]
+		for i := 0; i+1 < len(bucket); i += 2 {
+			values[bucket[i]] += bucket[i+1]
+		}
+	}
+
+	samples := make([]*model.Sample, 0, len(values))
+	for index, value := range values {
+		if index < 0 || index >= int64(len(t.Stacks)) {
+			continue
+		}
+		stack := t.Stacks[index]
+		samples = append(samples, &model.Sample{
+			ThreadName: stack.ThreadName,
+			TID:        stack.TID,
+			CallStack:  stack.CallStack,
+			State:      stack.State,
+			Value:      value,
+		})
+	}
+	// Stable order, for stable flame graphs
+	sort.Slice(samples, func(i, j int) bool {
+		if samples[i].ThreadName != samples[j].ThreadName {
+			return samples[i].ThreadName < samples[j].ThreadName
+		}
+		return StackToString(samples[i].CallStack) < StackToString(samples[j].CallStack)
+	})
+	return samples
+}
+
+// bucketRange returns the indexes of the first bucket and after the last
+// bucket overlapping a window.
+func (t *Timeline) bucketRange(start, end time.Duration) (int, int) {
+	first := int(int64(max(start, 0)) / t.Resolution)
+	last := len(t.Buckets)
+	if end > 0 {
+		last = min(last, int((int64(end)+t.Resolution-1)/t.Resolution))
+	}
+	return min(first, len(t.Buckets)), max(last, 0)
+}
+
+// Series returns the self values over time of the topN functions with the
+// largest self value: the leaf frames of the stacks.
+func (t *Timeline) Series(topN int) *TimelineSeries {
+	series := &TimelineSeries{
+		Start:      t.Start,
+		Resolution: t.Resolution,
+		Unit:       t.Unit,
+		Totals:     make([]int64, len(t.Buckets)),
+	}
+
+	totals := make(map[string]int64)
+	for b, bucket := range t.Buckets {
+		for i := 0; i+1 < len(bucket); i += 2 {
+			series.Totals[b] += bucket[i+1]
+			if leaf := t.leaf(bucket[i]); leaf != "" {
+				totals[leaf] += bucket[i+1]
+			}
+		}
+	}
+
+	names := make([]string, 0, len(totals))
+	for name := range totals {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		if totals[names[i]] != totals[names[j]] {
+			return totals[names[i]] > totals[names[j]]
+		}
+		return names[i] < names[j]
+	})
+	if topN > 0 && len(names) > topN {
+		names = names[:topN]
+	}
+
+	index := make(map[string]*FunctionSeries, len(names))
+	for _, name := range names {
+		f := &FunctionSeries{Name: name, Total: totals[name], Values: make([]int64, len(t.Buckets))}
+		index[name] = f
+		series.Functions = append(series.Functions, f)
+	}
+	for b, bucket := range t.Buckets {
+		for i := 0; i+1 < len(bucket); i += 2 {
+			if f, ok := index[t.leaf(bucket[i])]; ok {
+				f.Values[b] += bucket[i+1]
+			}
+		}
+	}
+	return series
+}
+
+// leaf returns the leaf frame of a stack, empty if none.
+func (t *Timeline) leaf(index int64) string {
+	if index < 0 || index >= int64(len(t.Stacks)) {
+		return ""
+	}
+	stack := t.Stacks[index].CallStack
+	if len(stack) == 0 {
+		return ""
+	}
+	return stack[len(stack)-1]
+}
+
+// WriteTimelineGzip writes a timeline as gzipped JSON.
+func WriteTimelineGzip(t *Timeline, path string) error {
+	return writer.NewGzipWriter[*Timeline]().WriteToFile(t, path)
+}
+
+// ReadTimelineGzip reads a timeline written by WriteTimelineGzip.
+func ReadTimelineGzip(path string) (*Timeline, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	gz, err := gzip.NewReader(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create gzip reader: %w", err)
+	}
+	defer gz.Close()
+
+	var t Timeline
+	if err := json.NewDecoder(gz).Decode(&t); err != nil {
+		return nil, fmt.Errorf("failed to decode timeline: %w", err)
+	}
+	if t.Resolution <= 0 {
+		return nil, fmt.Errorf("invalid timeline resolution %d", t.Resolution)
+	}
+	return &t, nil
+}
@@ -0,0 +1,90 @@
+package flamegraph
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/perf-analysis/pkg/model"
+)
+
+// timedSamples returns samples of main over 3 seconds: a, then b, then a
+// and c.
+func timedSamples() []*model.Sample {
+	const start = int64(100 * time.Second)
+	sample := func(at time.Duration, value int64, stack ...string) *model.Sample {
+		return &model.Sample{ThreadName: "main", TID: 1, CallStack: stack, Value: value, Timestamp: start + int64(at)}
+	}
+	return []*model.Sample{
+		sample(100*time.Millisecond, 2, "main", "a"),
+		sample(900*time.Millisecond, 1, "main", "a"),
+		sample(1500*time.Millisecond, 5, "main", "b"),
+		sample(2100*time.Millisecond, 1, "main", "a"),
+		sample(2200*time.Millisecond, 3, "main", "c"),
+		{ThreadName: "main", CallStack: []string{"untimed"}, Value: 10},
+	}
+}
+
+func TestNewTimeline(t *testing.T) {
+	tl := NewTimeline(timedSamples(), time.Second, 100)
+	require.NotNil(t, tl)
+
+	assert.Equal(t, int64(100*time.Second), tl.Start)
+	assert.Equal(t, int64(time.Second), tl.Resolution)
+	assert.Equal(t, 3*time.Second, tl.Duration())
+	require.Len(t, tl.Stacks, 3, "samples without timestamp are ignored")
+	assert.Equal(t, [][]int64{{0, 3}, {1, 5}, {0, 1, 2, 3}}, tl.Buckets)
+
+	// The resolution grows to keep the number of buckets under the maximum
+	tl = NewTimeline(timedSamples(), 100*time.Millisecond, 10)
+	assert.Equal(t, int64(250*time.Millisecond), tl.Resolution)
+
+	assert.Nil(t, NewTimeline([]*model.Sample{{CallStack: []string{"a"}, Value: 1}}, time.Second, 10))
+}
+
+func TestTimeline_Window(t *testing.T) {
+	tl := NewTimeline(timedSamples(), time.Second, 100)
+
+	samples := tl.Window(time.Second, 0)
+	require.Len(t, samples, 3)
+	values := make(map[string]int64)
+	for _, s := range samples {
+		values[StackToString(s.CallStack)] = s.Value
+	}
+	assert.Equal(t, map[string]int64{"main;a": 1, "main;b": 5, "main;c": 3}, values)
+
+	// Buckets overlapping the window are included
+	samples = tl.Window(500*time.Millisecond, 1100*time.Millisecond)
+	require.Len(t, samples, 2)
+
+	fg, err := NewGenerator(&GeneratorOptions{IncludeThreadInStack: true}).Generate(context.Background(), tl.Window(0, time.Second))
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), fg.TotalSamples)
+
+	assert.Empty(t, tl.Window(time.Hour, 0))
+}
+
+func TestTimeline_Series(t *testing.T) {
+	tl := NewTimeline(timedSamples(), time.Second, 100)
+
+	series := tl.Series(2)
+	assert.Equal(t, []int64{3, 5, 4}, series.Totals)
+	require.Len(t, series.Functions, 2)
+	assert.Equal(t, &FunctionSeries{Name: "b", Total: 5, Values: []int64{0, 5, 0}}, series.Functions[0])
+	assert.Equal(t, &FunctionSeries{Name: "a", Total: 4, Values: []int64{3, 0, 1}}, series.Functions[1])
+}
+
+func TestTimeline_WriteRead(t *testing.T) {
+	tl := NewTimeline(timedSamples(), time.Second, 100)
+	tl.Unit = "ns"
+	path := filepath.Join(t.TempDir(), "timeline.json.gz")
+
+	require.NoError(t, WriteTimelineGzip(tl, path))
+	read, err := ReadTimelineGzip(path)
+	require.NoError(t, err)
+	assert.Equal(t, tl, read)
+}
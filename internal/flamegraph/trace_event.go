@@ -0,0 +1,123 @@
+package flamegraph
+
+import (
+	"sort"
+)
+
+// Phases of trace events.
+const (
+	TracePhaseComplete = "X"
+	TracePhaseMetadata = "M"
+)
+
+// traceEventCategory is the category of the events of flame graph frames.
+const traceEventCategory = "flamegraph"
+
+// TraceEvent is an event of the Chrome trace event format, read by
+// chrome://tracing and https://ui.perfetto.dev. Times are in microseconds.
+type TraceEvent struct {
+	Name string         `json:"name"`
+	Cat  string         `json:"cat,omitempty"`
+	Ph   string         `json:"ph"`
+	Ts   float64        `json:"ts"`
+	Dur  float64        `json:"dur,omitempty"`
+	Pid  int            `json:"pid"`
+	Tid  int            `json:"tid"`
+	Args map[string]any `json:"args,omitempty"`
+}
+
+// TraceFile is a trace in the JSON object format of Chrome trace events.
+type TraceFile struct {
+	TraceEvents     []*TraceEvent     `json:"traceEvents"`
+	DisplayTimeUnit string            `json:"displayTimeUnit,omitempty"`
+	OtherData       map[string]string `json:"otherData,omitempty"`
+}
+
+// ToTraceEvents converts a flame graph into Chrome trace events, a track per
+// thread of the flame graph, or a single track if it has no thread frames.
+// Frames are complete events nested in their caller, heaviest first, whose
+// durations are their values: nanoseconds for flame graphs in ns, else one
+// microsecond per sample.
+func ToTraceEvents(fg *FlameGraph, name string) *TraceFile {
+	file := &TraceFile{
+		TraceEvents:     []*TraceEvent{},
+		DisplayTimeUnit: "ms",
+		OtherData:       map[string]string{"name": name, "exporter": exporterName},
+	}
+	if fg.Root == nil {
+		return file
+	}
+	scale := 1.0
+	if fg.Unit == "ns" {
+		scale = 1e-3
+	} else {
+		file.OtherData["unit"] = "1us per sample"
+	}
+	file.TraceEvents = append(file.TraceEvents, &TraceEvent{
+		Name: "process_name", Ph: TracePhaseMetadata, Pid: 1,
+		Args: map[string]any{"name": name},
+	})
+
+	var walk func(n *Node, ts float64, tid int)
+	walk = func(n *Node, ts float64, tid int) {
+		args := map[string]any{"value": n.Value}
+		if n.Self > 0 {
+			args["self"] = n.Self
+		}
+		if n.State != "" {
+			args["state"] = n.State
+		}
+		file.TraceEvents = append(file.TraceEvents, &TraceEvent{
+			Name: n.Name, Cat: traceEventCategory, Ph: TracePhaseComplete,
+			Ts: ts, Dur: float64(n.Value) * scale, Pid: 1, Tid: tid, Args: args,
+		})
+		for _, child := range heaviestFirst(n.Children) {
+			walk(child, ts, tid)
+			ts += float64(child.Value) * scale
+		}
+	}
+
+	threads := false
+	for _, child := range fg.Root.Children {
+		threads = threads || child.TID != 0
+	}
+	if !threads {
+		var ts float64
+		for _, child := range heaviestFirst(fg.Root.Children) {
+			walk(child, ts, 1)
+			ts += float64(child.Value) * scale
+		}
+		return file
+	}
+
+	// Thread frames are the roots of the tracks of their threads, named
+	// after them; threads without a TID get one past the largest
+	nextTID := 0
+	for _, child := range fg.Root.Children {
+		nextTID = max(nextTID, child.TID)
+	}
+	for _, thread := range heaviestFirst(fg.Root.Children) {
+		tid := thread.TID
+		if tid == 0 {
+			nextTID++
+			tid = nextTID
+		}
+		threadName := thread.Name
+		if thread.Process != "" {
+			threadName = thread.Process
+		}
+		file.TraceEvents = append(file.TraceEvents, &TraceEvent{
+			Name: "thread_name", Ph: TracePhaseMetadata, Pid: 1, Tid: tid,
+			Args: map[string]any{"name": threadName},
+		})
+		walk(thread, 0, tid)
+	}
+	return file
+}
+
+// heaviestFirst returns nodes sorted by decreasing value.
+func heaviestFirst(nodes []*Node) []*Node {
+	sorted := append([]*Node(nil), nodes...)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Value > sorted[j].Value })
+	return sorted
+}
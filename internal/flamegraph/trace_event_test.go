@@ -0,0 +1,80 @@
+package flamegraph
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// completeEvents returns the complete events of a trace by track and name.
+func completeEvents(file *TraceFile) map[int]map[string]*TraceEvent {
+	events := make(map[int]map[string]*TraceEvent)
+	for _, e := range file.TraceEvents {
+		if e.Ph != TracePhaseComplete {
+			continue
+		}
+		if events[e.Tid] == nil {
+			events[e.Tid] = make(map[string]*TraceEvent)
+		}
+		events[e.Tid][e.Name] = e
+	}
+	return events
+}
+
+func TestToTraceEvents(t *testing.T) {
+	fg := buildFlameGraph(map[string]int64{
+		"main;parse": 50_000,
+		"main;write": 30_000,
+		"gc":         20_000,
+	})
+	fg.Unit = "ns"
+
+	file := ToTraceEvents(fg, "task tracing")
+	assert.Equal(t, "task tracing", file.OtherData["name"])
+	assert.NotContains(t, file.OtherData, "unit")
+
+	events := completeEvents(file)
+	require.Len(t, events, 1)
+	track := events[1]
+	require.Len(t, track, 4)
+
+	// Frames are nested in their caller, heaviest first, in microseconds
+	assert.Equal(t, TraceEvent{Name: "main", Cat: traceEventCategory, Ph: TracePhaseComplete, Ts: 0, Dur: 80, Pid: 1, Tid: 1,
+		Args: map[string]any{"value": int64(80_000)}}, *track["main"])
+	assert.Equal(t, float64(0), track["parse"].Ts)
+	assert.Equal(t, float64(50), track["parse"].Dur)
+	assert.Equal(t, int64(50_000), track["parse"].Args["self"])
+	assert.Equal(t, float64(50), track["write"].Ts)
+	assert.Equal(t, float64(30), track["write"].Dur)
+	assert.Equal(t, float64(80), track["gc"].Ts)
+}
+
+func TestToTraceEvents_Threads(t *testing.T) {
+	fg := &FlameGraph{Root: &Node{Name: "root", Value: 40, Children: []*Node{
+		{Name: "worker-1", TID: 7, Process: "worker-1", Value: 10, Children: []*Node{{Name: "run", Value: 10, Self: 10}}},
+		{Name: "main", Value: 30, Children: []*Node{{Name: "run", Value: 30, Self: 30}}},
+	}}}
+
+	file := ToTraceEvents(fg, "threads")
+	assert.Equal(t, "1us per sample", file.OtherData["unit"])
+
+	names := make(map[int]string)
+	for _, e := range file.TraceEvents {
+		if e.Ph == TracePhaseMetadata && e.Name == "thread_name" {
+			names[e.Tid] = e.Args["name"].(string)
+		}
+	}
+	// The thread without a TID gets one past the largest
+	assert.Equal(t, map[int]string{7: "worker-1", 8: "main"}, names)
+
+	events := completeEvents(file)
+	assert.Equal(t, float64(30), events[8]["run"].Dur)
+	assert.Equal(t, float64(10), events[7]["run"].Dur)
+	assert.Equal(t, float64(0), events[7]["worker-1"].Ts)
+}
+
+func TestToTraceEvents_Empty(t *testing.T) {
+	file := ToTraceEvents(&FlameGraph{}, "empty")
+	assert.Empty(t, file.TraceEvents)
+}
@@ -48,6 +48,15 @@ func (f *CPUFormatter) Format(resp *model.AnalysisResponse, log utils.Logger) {
 	}
 	log.Info("")
 
+	// Print how the sampled code ran
+	if len(data.FrameTypes) > 0 {
+		log.Info("=== Frame Types ===")
+		for _, ft := range data.FrameTypes {
+			log.Info("  %-12s %6.2f%%  (%d samples)", ft.Type, ft.Percentage, ft.Samples)
+		}
+		log.Info("")
+	}
+
 	// Print output files
 	f.printOutputFiles(resp, log)
 
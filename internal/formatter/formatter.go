@@ -34,10 +34,13 @@ func NewRegistry() *Registry {
 	// Register default formatters
 	r.Register(&CPUFormatter{})
 	r.Register(&AllocationFormatter{})
+	r.Register(&LatencyFormatter{})
 	r.Register(&HeapFormatter{})
 	r.Register(&MemLeakFormatter{})
 	r.Register(&TracingFormatter{})
 	r.Register(&PProfBatchFormatter{})
+	r.Register(&ThreadDumpFormatter{})
+	r.Register(&GCLogFormatter{})
 
 	return r
 }
@@ -78,10 +81,14 @@ func (r *Registry) FormatSummary(resp *model.AnalysisResponse) map[string]interf
 		return nil
 	}
 
+	var summary map[string]interface{}
 	if resp.Data == nil {
-		return r.fallback.FormatSummary(resp)
+		summary = r.fallback.FormatSummary(resp)
+	} else {
+		summary = r.Get(resp.Data.Type()).FormatSummary(resp)
 	}
-
-	f := r.Get(resp.Data.Type())
-	return f.FormatSummary(resp)
+	if summary != nil && resp.Health != nil {
+		summary["health"] = resp.Health
+	}
+	return summary
 }
@@ -0,0 +1,133 @@
+package formatter
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/perf-analysis/pkg/model"
+	"github.com/perf-analysis/pkg/utils"
+)
+
+// GCLogFormatter formats JVM GC log analysis results.
+type GCLogFormatter struct{}
+
+// SupportedTypes returns the data types this formatter supports.
+func (f *GCLogFormatter) SupportedTypes() []model.AnalysisDataType {
+	return []model.AnalysisDataType{model.DataTypeGCLog}
+}
+
+// Format outputs the GC log analysis result to the logger.
+func (f *GCLogFormatter) Format(resp *model.AnalysisResponse, log utils.Logger) {
+	log.Info("=== GC Log Analysis Results ===")
+	log.Info("Task UUID:      %s", resp.TaskUUID)
+	log.Info("Task Type:      %s", resp.TaskType.String())
+	log.Info("")
+
+	data, ok := resp.Data.(*model.GCLogData)
+	if !ok {
+		log.Info("(No detailed data available)")
+		return
+	}
+
+	// Print pause statistics
+	collector := data.Collector
+	if collector == "" {
+		collector = "unknown"
+	}
+	log.Info("=== Pauses ===")
+	log.Info("  Collector:  %s", collector)
+	log.Info("  Duration:   %.1fs", data.DurationSeconds)
+	log.Info("  Pauses:     %d, %.1fms total", data.PauseCount, data.TotalPauseMs)
+	log.Info("  Throughput: %.2f%%", data.Throughput)
+	log.Info("  p50 %.2fms  p90 %.2fms  p95 %.2fms  p99 %.2fms  max %.2fms",
+		data.P50PauseMs, data.P90PauseMs, data.P95PauseMs, data.P99PauseMs, data.MaxPauseMs)
+	log.Info("")
+
+	// Print pause histogram, without the empty buckets
+	log.Info("=== Pause Histogram ===")
+	lower := 0.0
+	for _, b := range data.PauseHistogram {
+		if b.Count > 0 {
+			label := fmt.Sprintf("%gms-%gms", lower, b.UpperBoundMs)
+			if b.UpperBoundMs == 0 {
+				label = fmt.Sprintf(">%gms", lower)
+			}
+			log.Info("  %-16s %d", label, b.Count)
+		}
+		lower = b.UpperBoundMs
+	}
+	log.Info("")
+
+	// Print pause types
+	if len(data.PauseTypes) > 0 {
+		log.Info("=== Pause Types ===")
+		for i, t := range data.PauseTypes {
+			log.Info("  %2d. %-40s %5d pauses  %10.2fms total  %8.2fms max", i+1, truncateString(t.Type, 40), t.Count, t.TotalMs, t.MaxMs)
+		}
+		log.Info("")
+	}
+
+	// Print allocation and promotion rates
+	log.Info("=== Heap ===")
+	log.Info("  Allocation rate: %s/s (peak %s/s)", formatBytes(int64(data.AllocationRate)), formatBytes(int64(data.PeakAllocationRate)))
+	if data.HasPromotion {
+		log.Info("  Promotion rate:  %s/s", formatBytes(int64(data.PromotionRate)))
+	}
+	log.Info("  Live set trend:  %s/s", formatSignedBytes(int64(data.LiveSetGrowthRate)))
+	log.Info("")
+
+	// Print heap dump correlations
+	if len(data.HeapDumps) > 0 {
+		log.Info("=== Heap Dumps ===")
+		for _, d := range data.HeapDumps {
+			switch {
+			case !d.Correlated:
+				log.Info("  %s: taken at %s, the GC log has no timestamps to correlate", d.File, d.Time.Format("2006-01-02 15:04:05"))
+			case !d.InLog:
+				log.Info("  %s: taken at uptime %.1fs, outside of the GC log", d.File, d.UptimeSeconds)
+			case d.PrecedingGCID != nil:
+				log.Info("  %s: taken at uptime %.1fs, after GC(%d) %s left %s", d.File, d.UptimeSeconds,
+					*d.PrecedingGCID, d.PrecedingGCType, formatBytes(d.HeapAfterGC))
+			default:
+				log.Info("  %s: taken at uptime %.1fs, before any collection", d.File, d.UptimeSeconds)
+			}
+		}
+		log.Info("")
+	}
+
+	// Print output files
+	log.Info("=== Output Files ===")
+	for _, file := range resp.OutputFiles {
+		log.Info("  %s: %s", file.Name, file.LocalPath)
+		if info, err := os.Stat(file.LocalPath); err == nil {
+			log.Info("    Size: %d bytes", info.Size())
+		}
+	}
+}
+
+// formatSignedBytes formats a byte count that may be negative.
+func formatSignedBytes(bytes int64) string {
+	if bytes < 0 {
+		return "-" + formatBytes(-bytes)
+	}
+	return "+" + formatBytes(bytes)
+}
+
+// FormatSummary returns a summary map for serialization.
+func (f *GCLogFormatter) FormatSummary(resp *model.AnalysisResponse) map[string]interface{} {
+	summary := map[string]interface{}{
+		"task_uuid":     resp.TaskUUID,
+		"task_type":     resp.TaskType.String(),
+		"total_records": resp.TotalRecords,
+	}
+
+	if resp.Data != nil {
+		summary["data"] = resp.Data.Summary()
+		summary["top_items"] = resp.Data.TopItems()
+	}
+
+	summary["output_files"] = resp.OutputFiles
+	summary["suggestions_count"] = len(resp.Suggestions)
+
+	return summary
+}
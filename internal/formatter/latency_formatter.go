@@ -0,0 +1,181 @@
+package formatter
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/perf-analysis/pkg/model"
+	"github.com/perf-analysis/pkg/utils"
+)
+
+// LatencyFormatter formats wall-clock, lock contention and off-CPU analysis
+// results, with the time each thread spent.
+type LatencyFormatter struct{}
+
+// SupportedTypes returns the data types this formatter supports.
+func (f *LatencyFormatter) SupportedTypes() []model.AnalysisDataType {
+	return []model.AnalysisDataType{model.DataTypeWallClock, model.DataTypeLockContention, model.DataTypeOffCPU}
+}
+
+// Format outputs the latency analysis result to the logger.
+func (f *LatencyFormatter) Format(resp *model.AnalysisResponse, log utils.Logger) {
+	var latencies []model.ThreadLatency
+	switch data := resp.Data.(type) {
+	case *model.WallClockData:
+		log.Info("=== Wall Clock Analysis Results ===")
+		log.Info("Task UUID:     %s", resp.TaskUUID)
+		log.Info("Total Samples: %d", data.TotalSamples)
+		log.Info("Total Time:    %s", formatNanos(data.TotalNanos))
+		log.Info("Interval:      %s", formatNanos(data.IntervalNanos))
+		log.Info("")
+		f.printStates("Thread States", data.States, data.TotalNanos, log)
+		latencies = data.ThreadLatencies
+	case *model.LockContentionData:
+		log.Info("=== Lock Contention Analysis Results ===")
+		log.Info("Task UUID:     %s", resp.TaskUUID)
+		if data.TotalEvents > 0 {
+			log.Info("Total Events:  %d", data.TotalEvents)
+		}
+		if data.TotalDelayNanos > 0 {
+			log.Info("Total Time:    %s", formatNanos(data.TotalDelayNanos))
+		}
+		log.Info("")
+		latencies = data.ThreadLatencies
+	case *model.OffCPUData:
+		log.Info("=== Off-CPU Analysis Results ===")
+		log.Info("Task UUID:     %s", resp.TaskUUID)
+		log.Info("Total Blocked: %s", formatNanos(data.TotalNanos))
+		log.Info("")
+		f.printStates("Wait Reasons", data.Reasons, data.TotalNanos, log)
+		f.printBlockingGroups(data.BlockingGroups, log)
+		latencies = data.ThreadLatencies
+	default:
+		log.Info("(No detailed data available)")
+		return
+	}
+
+	// Print top functions
+	log.Info("=== Top Functions ===")
+	topItems := resp.Data.TopItems()
+	count := min(10, len(topItems))
+	for i := 0; i < count; i++ {
+		item := topItems[i]
+		log.Info("  %2d. %6.2f%%  %s", i+1, item.Percentage, truncateString(item.Name, 80))
+	}
+	log.Info("")
+
+	// Print per-thread latencies
+	if len(latencies) > 0 {
+		log.Info("=== Thread Latencies ===")
+		threadCount := min(10, len(latencies))
+		for i := 0; i < threadCount; i++ {
+			t := latencies[i]
+			if t.TotalNanos > 0 {
+				log.Info("  %6.2f%%  %10s  %s", t.Percentage, formatNanos(t.TotalNanos), threadLabel(t))
+			} else {
+				log.Info("  %6.2f%%  %10d  %s", t.Percentage, t.Samples, threadLabel(t))
+			}
+			if t.TopFrame != "" {
+				log.Info("           in %s", truncateString(t.TopFrame, 80))
+			}
+		}
+		log.Info("")
+	}
+
+	f.printOutputFiles(resp, log)
+}
+
+// printStates prints the time spent in each thread state or wait reason,
+// the longest first.
+func (f *LatencyFormatter) printStates(title string, states map[string]int64, total int64, log utils.Logger) {
+	if len(states) == 0 || total == 0 {
+		return
+	}
+	names := make([]string, 0, len(states))
+	for name := range states {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool { return states[names[i]] > states[names[j]] })
+
+	log.Info("=== %s ===", title)
+	for _, name := range names {
+		log.Info("  %-10s %10s  %6.2f%%", name, formatNanos(states[name]), float64(states[name])/float64(total)*100)
+	}
+	log.Info("")
+}
+
+// printBlockingGroups prints the top blocking stacks by thread and wait
+// reason.
+func (f *LatencyFormatter) printBlockingGroups(groups []model.BlockingGroup, log utils.Logger) {
+	if len(groups) == 0 {
+		return
+	}
+	log.Info("=== Top Blocking Stacks ===")
+	for i, g := range groups {
+		if i >= 5 {
+			break
+		}
+		log.Info("  %6.2f%%  %10s  %s [%s]", g.Percentage, formatNanos(g.TotalNanos), truncateString(g.ThreadName, 40), g.Reason)
+		if len(g.TopStacks) > 0 && len(g.TopStacks[0].CallStack) > 0 {
+			stack := g.TopStacks[0].CallStack
+			log.Info("           in %s", truncateString(stack[len(stack)-1], 80))
+		}
+	}
+	log.Info("")
+}
+
+// FormatSummary returns a summary map for serialization.
+func (f *LatencyFormatter) FormatSummary(resp *model.AnalysisResponse) map[string]interface{} {
+	summary := map[string]interface{}{
+		"task_uuid":     resp.TaskUUID,
+		"task_type":     resp.TaskType.String(),
+		"total_records": resp.TotalRecords,
+	}
+
+	if resp.Data != nil {
+		summary["data"] = resp.Data.Summary()
+		summary["top_items"] = resp.Data.TopItems()
+
+		switch data := resp.Data.(type) {
+		case *model.WallClockData:
+			summary["threads"] = data.ThreadLatencies
+		case *model.LockContentionData:
+			summary["threads"] = data.ThreadLatencies
+		case *model.OffCPUData:
+			summary["threads"] = data.ThreadLatencies
+			summary["blocking_groups"] = data.BlockingGroups
+		}
+	}
+
+	summary["output_files"] = resp.OutputFiles
+	summary["suggestions_count"] = len(resp.Suggestions)
+	summary["suggestions"] = resp.Suggestions
+
+	return summary
+}
+
+func (f *LatencyFormatter) printOutputFiles(resp *model.AnalysisResponse, log utils.Logger) {
+	log.Info("=== Output Files ===")
+	for _, file := range resp.OutputFiles {
+		log.Info("  %s: %s", file.Name, file.LocalPath)
+		if info, err := os.Stat(file.LocalPath); err == nil {
+			log.Info("    Size: %d bytes", info.Size())
+		}
+	}
+}
+
+// threadLabel returns the name of a thread, with its TID if known.
+func threadLabel(t model.ThreadLatency) string {
+	name := truncateString(t.ThreadName, 40)
+	if t.TID > 0 {
+		return fmt.Sprintf("%s (tid %d)", name, t.TID)
+	}
+	return name
+}
+
+// formatNanos formats nanoseconds to a human-readable duration.
+func formatNanos(nanos int64) string {
+	return time.Duration(nanos).Round(time.Microsecond).String()
+}
@@ -45,6 +45,18 @@ func (f *PProfBatchFormatter) Format(resp *model.AnalysisResponse, log utils.Log
 			if lr.GrowthPercent != 0 {
 				log.Info("    Growth:     %.2f%%", lr.GrowthPercent)
 			}
+			if detailed := data.DetailedLeakReports[name]; detailed != nil && len(detailed.LeakClusters) > 0 {
+				log.Info("    Growing goroutine clusters:")
+				count := min(5, len(detailed.LeakClusters))
+				for i := 0; i < count; i++ {
+					c := detailed.LeakClusters[i]
+					log.Info("      +%-6d created by %s", c.CountDelta, truncateString(c.CreatedBy, 70))
+					log.Info("              blocked at %s", truncateString(c.BlockingPoint, 70))
+					if c.WaitCall != "" {
+						log.Info("              waiting in %s", truncateString(c.WaitCall, 70))
+					}
+				}
+			}
 		}
 		log.Info("")
 	}
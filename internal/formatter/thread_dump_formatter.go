@@ -0,0 +1,108 @@
+package formatter
+
+import (
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/perf-analysis/pkg/model"
+	"github.com/perf-analysis/pkg/utils"
+)
+
+// ThreadDumpFormatter formats Java thread dump analysis results.
+type ThreadDumpFormatter struct{}
+
+// SupportedTypes returns the data types this formatter supports.
+func (f *ThreadDumpFormatter) SupportedTypes() []model.AnalysisDataType {
+	return []model.AnalysisDataType{model.DataTypeThreadDump}
+}
+
+// Format outputs the thread dump analysis result to the logger.
+func (f *ThreadDumpFormatter) Format(resp *model.AnalysisResponse, log utils.Logger) {
+	log.Info("=== Thread Dump Analysis Results ===")
+	log.Info("Task UUID:      %s", resp.TaskUUID)
+	log.Info("Task Type:      %s", resp.TaskType.String())
+	log.Info("")
+
+	data, ok := resp.Data.(*model.ThreadDumpData)
+	if !ok {
+		log.Info("(No detailed data available)")
+		return
+	}
+
+	// Print thread states of the latest dump
+	log.Info("=== Thread States ===")
+	log.Info("  Dumps:   %d", data.DumpCount)
+	log.Info("  Threads: %d (latest dump)", data.TotalThreads)
+	states := make([]string, 0, len(data.States))
+	for state := range data.States {
+		states = append(states, state)
+	}
+	sort.Slice(states, func(i, j int) bool {
+		if data.States[states[i]] != data.States[states[j]] {
+			return data.States[states[i]] > data.States[states[j]]
+		}
+		return states[i] < states[j]
+	})
+	for _, state := range states {
+		log.Info("  %-14s %d", state, data.States[state])
+	}
+	log.Info("")
+
+	// Print deadlocks
+	if len(data.Deadlocks) > 0 {
+		log.Info("=== Deadlocks (%d) ===", len(data.Deadlocks))
+		for i, d := range data.Deadlocks {
+			log.Info("  %d. found in %d of %d dumps", i+1, d.DumpCount, data.DumpCount)
+			for _, t := range d.Threads {
+				log.Info("     %q waits for %s <%s>, held by %q", t.Name, t.LockClass, t.LockAddress, t.HeldBy)
+			}
+		}
+		log.Info("")
+	}
+
+	// Print hot monitors
+	if len(data.HotMonitors) > 0 {
+		log.Info("=== Hot Monitors ===")
+		count := min(10, len(data.HotMonitors))
+		for i := 0; i < count; i++ {
+			m := data.HotMonitors[i]
+			owner := m.Owner
+			if owner == "" {
+				owner = "unknown"
+			}
+			log.Info("  %2d. %3d waiters (max %d)  %s <%s>", i+1, m.Waiters, m.MaxWaiters, truncateString(m.Class, 60), m.Address)
+			log.Info("              Owner: %s", owner)
+			log.Info("              Waiters: %s", truncateString(strings.Join(m.WaiterThreads, ", "), 80))
+		}
+		log.Info("")
+	}
+
+	// Print output files
+	log.Info("=== Output Files ===")
+	for _, file := range resp.OutputFiles {
+		log.Info("  %s: %s", file.Name, file.LocalPath)
+		if info, err := os.Stat(file.LocalPath); err == nil {
+			log.Info("    Size: %d bytes", info.Size())
+		}
+	}
+}
+
+// FormatSummary returns a summary map for serialization.
+func (f *ThreadDumpFormatter) FormatSummary(resp *model.AnalysisResponse) map[string]interface{} {
+	summary := map[string]interface{}{
+		"task_uuid":     resp.TaskUUID,
+		"task_type":     resp.TaskType.String(),
+		"total_records": resp.TotalRecords,
+	}
+
+	if resp.Data != nil {
+		summary["data"] = resp.Data.Summary()
+		summary["top_items"] = resp.Data.TopItems()
+	}
+
+	summary["output_files"] = resp.OutputFiles
+	summary["suggestions_count"] = len(resp.Suggestions)
+
+	return summary
+}
@@ -0,0 +1,52 @@
+package grpcapi
+
+import (
+	"context"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/perf-analysis/internal/webui"
+)
+
+// SetAccessConfig sets authentication and read-only mode, shared with the
+// web UI. It must be called before ServerOptions.
+func (s *Server) SetAccessConfig(config webui.AccessConfig) {
+	s.access = config
+}
+
+// ServerOptions returns the interceptors enforcing the access configuration,
+// to pass to grpc.NewServer. Clients send basic auth or bearer token
+// credentials in the authorization metadata, as in the HTTP header.
+func (s *Server) ServerOptions() []grpc.ServerOption {
+	if !s.access.AuthEnabled() {
+		return nil
+	}
+	return []grpc.ServerOption{
+		grpc.ChainUnaryInterceptor(func(ctx context.Context, req any, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+			if err := s.authorize(ctx); err != nil {
+				return nil, err
+			}
+			return handler(ctx, req)
+		}),
+		grpc.ChainStreamInterceptor(func(srv any, ss grpc.ServerStream, _ *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+			if err := s.authorize(ss.Context()); err != nil {
+				return err
+			}
+			return handler(srv, ss)
+		}),
+	}
+}
+
+// authorize checks the credentials of a call's authorization metadata.
+func (s *Server) authorize(ctx context.Context) error {
+	md, _ := metadata.FromIncomingContext(ctx)
+	for _, header := range md.Get("authorization") {
+		if s.access.AuthorizedHeader(header) {
+			return nil
+		}
+	}
+	return status.Error(codes.Unauthenticated, "missing or invalid credentials")
+}
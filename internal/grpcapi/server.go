@@ -0,0 +1,250 @@
+// Package grpcapi exposes heap analysis results over gRPC.
+//
+// The service reads the same task directories as the web UI and shares its heap
+// snapshot cache, so a task queried over both front ends is loaded only once.
+package grpcapi
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/perf-analysis/internal/analyzer"
+	pb "github.com/perf-analysis/internal/grpcapi/proto"
+	"github.com/perf-analysis/internal/parser/hprof"
+	"github.com/perf-analysis/internal/webui"
+	"github.com/perf-analysis/pkg/model"
+	"github.com/perf-analysis/pkg/utils"
+)
+
+// Defaults applied when a request leaves a limit at zero.
+const (
+	defaultMaxRetainers = 20
+	defaultMaxPaths     = 3
+	defaultMaxDepth     = 15
+)
+
+// Server implements the HeapAnalysisService gRPC service.
+type Server struct {
+	pb.UnimplementedHeapAnalysisServiceServer
+
+	dataDir   string
+	snapshots *webui.SnapshotManager
+	logger    utils.Logger
+
+	// access is shared with the web UI; read-only mode disables AnalyzeDump
+	access webui.AccessConfig
+
+	// analyzeMu serializes AnalyzeDump calls; heap analysis is memory-bound
+	analyzeMu sync.Mutex
+}
+
+// NewServer creates a gRPC heap analysis server over dataDir.
+// snapshots is the heap snapshot cache shared with the web UI.
+func NewServer(dataDir string, snapshots *webui.SnapshotManager, logger utils.Logger) *Server {
+	return &Server{
+		dataDir:   dataDir,
+		snapshots: snapshots,
+		logger:    logger,
+	}
+}
+
+// Register registers the service on a gRPC server.
+func (s *Server) Register(registrar grpc.ServiceRegistrar) {
+	pb.RegisterHeapAnalysisServiceServer(registrar, s)
+}
+
+// AnalyzeDump analyzes a heap dump on the server's file system into a new task directory.
+func (s *Server) AnalyzeDump(ctx context.Context, req *pb.AnalyzeDumpRequest) (*pb.AnalyzeDumpResponse, error) {
+	if s.access.ReadOnly {
+		return nil, status.Error(codes.PermissionDenied, "AnalyzeDump is disabled in read-only mode")
+	}
+	if req.GetInputFile() == "" {
+		return nil, status.Error(codes.InvalidArgument, "input_file is required")
+	}
+	if _, err := os.Stat(req.GetInputFile()); err != nil {
+		return nil, status.Errorf(codes.NotFound, "input file not found: %s", req.GetInputFile())
+	}
+
+	taskID := req.GetTaskId()
+	if taskID == "" {
+		taskID = fmt.Sprintf("grpc-%s", time.Now().Format("20060102-150405.000"))
+	}
+	taskDir, err := s.taskDir(taskID)
+	if err != nil {
+		return nil, err
+	}
+
+	s.analyzeMu.Lock()
+	defer s.analyzeMu.Unlock()
+
+	config := analyzer.DefaultBaseAnalyzerConfig()
+	config.OutputDir = s.dataDir
+	config.Logger = s.logger
+	resp, err := analyzer.NewJavaHeapAnalyzer(config).Analyze(ctx, &model.AnalysisRequest{
+		TaskUUID:  taskID,
+		TaskType:  model.TaskTypeJavaHeap,
+		InputFile: req.GetInputFile(),
+		OutputDir: taskDir,
+	})
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "heap analysis failed: %v", err)
+	}
+	// A previous snapshot of the same task is stale now
+	s.snapshots.Evict(taskID)
+
+	result := &pb.AnalyzeDumpResponse{TaskId: taskID}
+	if data, ok := resp.Data.(*model.HeapAnalysisData); ok {
+		result.TotalClasses = int32(data.TotalClasses)
+		result.TotalInstances = data.TotalInstances
+		result.TotalHeapSize = data.TotalHeapSize
+	}
+	return result, nil
+}
+
+// GetClassHistogram returns the class histogram written by the analysis.
+func (s *Server) GetClassHistogram(ctx context.Context, req *pb.GetClassHistogramRequest) (*pb.GetClassHistogramResponse, error) {
+	taskDir, err := s.taskDir(req.GetTaskId())
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(filepath.Join(taskDir, "class_histogram.json"))
+	if err != nil {
+		return nil, status.Errorf(codes.NotFound, "class histogram not found for task %s", req.GetTaskId())
+	}
+	var histogram analyzer.ClassHistogram
+	if err := json.Unmarshal(data, &histogram); err != nil {
+		return nil, status.Errorf(codes.Internal, "invalid class histogram: %v", err)
+	}
+
+	classes := histogram.Classes
+	sort.SliceStable(classes, func(i, j int) bool {
+		return classes[i].TotalSize > classes[j].TotalSize
+	})
+	if limit := int(req.GetLimit()); limit > 0 && len(classes) > limit {
+		classes = classes[:limit]
+	}
+
+	resp := &pb.GetClassHistogramResponse{
+		TotalClasses:   int32(histogram.TotalClasses),
+		TotalInstances: histogram.TotalInstances,
+		TotalSize:      histogram.TotalSize,
+		Classes:        make([]*pb.ClassHistogramEntry, 0, len(classes)),
+	}
+	for _, cls := range classes {
+		resp.Classes = append(resp.Classes, &pb.ClassHistogramEntry{
+			ClassName:     cls.ClassName,
+			InstanceCount: cls.InstanceCount,
+			ShallowSize:   cls.TotalSize,
+			RetainedSize:  cls.RetainedSize,
+			Percentage:    cls.Percentage,
+		})
+	}
+	return resp, nil
+}
+
+// GetRetainers returns the objects directly referencing an object.
+func (s *Server) GetRetainers(ctx context.Context, req *pb.GetRetainersRequest) (*pb.GetRetainersResponse, error) {
+	snapshot, err := s.snapshot(req.GetTaskId(), req.GetObjectId())
+	if err != nil {
+		return nil, err
+	}
+
+	limit := int(req.GetLimit())
+	if limit <= 0 {
+		limit = defaultMaxRetainers
+	}
+
+	refs := snapshot.IncomingRefs(req.GetObjectId())
+	if len(refs) > limit {
+		refs = refs[:limit]
+	}
+	resp := &pb.GetRetainersResponse{Retainers: make([]*pb.Retainer, 0, len(refs))}
+	for _, ref := range refs {
+		resp.Retainers = append(resp.Retainers, &pb.Retainer{
+			ObjectId:     ref.FromObjectID,
+			ClassName:    snapshot.ClassName(ref.FromClassID),
+			FieldName:    ref.FieldName,
+			ShallowSize:  snapshot.ObjectSize(ref.FromObjectID),
+			RetainedSize: snapshot.RetainedSize(ref.FromObjectID),
+		})
+	}
+	return resp, nil
+}
+
+// GetPathsToRoot streams paths from GC roots to an object, shortest first.
+func (s *Server) GetPathsToRoot(req *pb.GetPathsToRootRequest, stream grpc.ServerStreamingServer[pb.GCRootPath]) error {
+	snapshot, err := s.snapshot(req.GetTaskId(), req.GetObjectId())
+	if err != nil {
+		return err
+	}
+
+	maxPaths := int(req.GetMaxPaths())
+	if maxPaths <= 0 {
+		maxPaths = defaultMaxPaths
+	}
+	maxDepth := int(req.GetMaxDepth())
+	if maxDepth <= 0 {
+		maxDepth = defaultMaxDepth
+	}
+
+	for _, path := range snapshot.PathsToGCRoot(req.GetObjectId(), maxPaths, maxDepth) {
+		if path == nil {
+			continue
+		}
+		msg := &pb.GCRootPath{
+			RootType: string(path.RootType),
+			Nodes:    make([]*pb.PathNode, 0, len(path.Path)),
+		}
+		for _, node := range path.Path {
+			msg.Nodes = append(msg.Nodes, &pb.PathNode{
+				ObjectId:  node.ObjectID,
+				ClassName: node.ClassName,
+				FieldName: node.FieldName,
+				Size:      node.Size,
+			})
+		}
+		if err := stream.Send(msg); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// snapshot returns a task's heap snapshot, checking that the object exists.
+func (s *Server) snapshot(taskID string, objectID uint64) (*hprof.HeapSnapshot, error) {
+	if _, err := s.taskDir(taskID); err != nil {
+		return nil, err
+	}
+	snapshot, err := s.snapshots.Get(taskID)
+	if err != nil {
+		return nil, status.Errorf(codes.NotFound, "reference graph not available for task %s: %v", taskID, err)
+	}
+	if _, ok := snapshot.ObjectClassID(objectID); !ok {
+		return nil, status.Errorf(codes.NotFound, "object 0x%x not found", objectID)
+	}
+	return snapshot, nil
+}
+
+// taskDir validates a task ID and returns its directory.
+// Task IDs are plain directory names; anything that could escape dataDir is rejected.
+func (s *Server) taskDir(taskID string) (string, error) {
+	if taskID == "" {
+		return "", status.Error(codes.InvalidArgument, "task_id is required")
+	}
+	if taskID != filepath.Base(taskID) || taskID == "." || taskID == ".." || strings.ContainsAny(taskID, `/\`) {
+		return "", status.Errorf(codes.InvalidArgument, "invalid task_id: %q", taskID)
+	}
+	return filepath.Join(s.dataDir, taskID), nil
+}
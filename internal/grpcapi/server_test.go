@@ -0,0 +1,140 @@
+package grpcapi
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+
+	"github.com/perf-analysis/internal/analyzer"
+	pb "github.com/perf-analysis/internal/grpcapi/proto"
+	"github.com/perf-analysis/internal/parser/hprof"
+	"github.com/perf-analysis/internal/webui"
+)
+
+// newTestSnapshot builds root -> Cache(0x300) -> HashMap(0x200) -> byte[](0x400).
+func newTestSnapshot() *hprof.HeapSnapshot {
+	g := hprof.NewReferenceGraphWithCapacity(8)
+	g.SetClassName(1, "com.example.Cache")
+	g.SetClassName(2, "java.util.HashMap")
+	g.SetClassName(3, "byte[]")
+	g.SetObjectInfo(0x300, 1, 32)
+	g.SetObjectInfo(0x200, 2, 48)
+	g.SetObjectInfo(0x400, 3, 4096)
+	g.AddReference(hprof.ObjectReference{FromObjectID: 0x300, ToObjectID: 0x200, FromClassID: 1, FieldName: "map"})
+	g.AddReference(hprof.ObjectReference{FromObjectID: 0x200, ToObjectID: 0x400, FromClassID: 2, FieldName: "table"})
+	g.AddGCRoot(&hprof.GCRoot{ObjectID: 0x300, Type: hprof.GCRootJNIGlobal})
+	g.ComputeDominatorTree()
+	return hprof.NewHeapSnapshot(g, nil, nil)
+}
+
+// newTestClient starts the service on an in-memory listener.
+func newTestClient(t *testing.T, dataDir string) pb.HeapAnalysisServiceClient {
+	return newAccessTestClient(t, dataDir, webui.AccessConfig{})
+}
+
+// newAccessTestClient starts the service with an access configuration.
+func newAccessTestClient(t *testing.T, dataDir string, access webui.AccessConfig) pb.HeapAnalysisServiceClient {
+	snapshots := webui.NewSnapshotManager(webui.DefaultSnapshotManagerConfig(), func(taskID string) (*hprof.HeapSnapshot, error) {
+		return newTestSnapshot(), nil
+	})
+
+	lis := bufconn.Listen(1 << 20)
+	api := NewServer(dataDir, snapshots, nil)
+	api.SetAccessConfig(access)
+	gs := grpc.NewServer(api.ServerOptions()...)
+	api.Register(gs)
+	go gs.Serve(lis)
+	t.Cleanup(gs.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	return pb.NewHeapAnalysisServiceClient(conn)
+}
+
+func TestServer_GetRetainers(t *testing.T) {
+	client := newTestClient(t, t.TempDir())
+
+	resp, err := client.GetRetainers(context.Background(), &pb.GetRetainersRequest{TaskId: "task", ObjectId: 0x400})
+	require.NoError(t, err)
+	require.Len(t, resp.Retainers, 1)
+	assert.Equal(t, uint64(0x200), resp.Retainers[0].ObjectId)
+	assert.Equal(t, "java.util.HashMap", resp.Retainers[0].ClassName)
+	assert.Equal(t, "table", resp.Retainers[0].FieldName)
+	assert.Equal(t, int64(48+4096), resp.Retainers[0].RetainedSize)
+
+	_, err = client.GetRetainers(context.Background(), &pb.GetRetainersRequest{TaskId: "task", ObjectId: 0x999})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+}
+
+func TestServer_GetPathsToRoot(t *testing.T) {
+	client := newTestClient(t, t.TempDir())
+
+	stream, err := client.GetPathsToRoot(context.Background(), &pb.GetPathsToRootRequest{TaskId: "task", ObjectId: 0x400})
+	require.NoError(t, err)
+
+	var paths []*pb.GCRootPath
+	for {
+		path, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		paths = append(paths, path)
+	}
+	require.Len(t, paths, 1)
+	assert.Equal(t, string(hprof.GCRootJNIGlobal), paths[0].RootType)
+	require.Len(t, paths[0].Nodes, 3)
+	assert.Equal(t, uint64(0x300), paths[0].Nodes[0].ObjectId)
+	assert.Equal(t, uint64(0x400), paths[0].Nodes[2].ObjectId)
+}
+
+func TestServer_GetClassHistogram(t *testing.T) {
+	dataDir := t.TempDir()
+	require.NoError(t, os.Mkdir(filepath.Join(dataDir, "task"), 0755))
+	data, err := json.Marshal(&analyzer.ClassHistogram{
+		TotalClasses: 2,
+		Classes: []*hprof.ClassStats{
+			{ClassName: "java.lang.String", InstanceCount: 10, TotalSize: 240},
+			{ClassName: "byte[]", InstanceCount: 3, TotalSize: 4096},
+		},
+	})
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(dataDir, "task", "class_histogram.json"), data, 0644))
+
+	client := newTestClient(t, dataDir)
+	resp, err := client.GetClassHistogram(context.Background(), &pb.GetClassHistogramRequest{TaskId: "task", Limit: 1})
+	require.NoError(t, err)
+	assert.Equal(t, int32(2), resp.TotalClasses)
+	require.Len(t, resp.Classes, 1)
+	assert.Equal(t, "byte[]", resp.Classes[0].ClassName)
+
+	_, err = client.GetClassHistogram(context.Background(), &pb.GetClassHistogramRequest{TaskId: "missing"})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+}
+
+func TestServer_InvalidTaskID(t *testing.T) {
+	client := newTestClient(t, t.TempDir())
+
+	for _, taskID := range []string{"", "..", "../etc", "a/b"} {
+		_, err := client.GetClassHistogram(context.Background(), &pb.GetClassHistogramRequest{TaskId: taskID})
+		assert.Equal(t, codes.InvalidArgument, status.Code(err), "task ID %q", taskID)
+	}
+
+	_, err := client.AnalyzeDump(context.Background(), &pb.AnalyzeDumpRequest{})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
@@ -0,0 +1,93 @@
+package jvm
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"path/filepath"
+	"strings"
+)
+
+// attachProtocolVersion is the version of the HotSpot attach protocol.
+const attachProtocolVersion = "1"
+
+// attachArgs is the number of arguments of every attach request.
+const attachArgs = 3
+
+// errAttachUnsupported is returned by attach on platforms without support for
+// the HotSpot attach mechanism.
+var errAttachUnsupported = errors.New("the JVM attach mechanism is not supported on this platform")
+
+// virtualMachine is a JVM reachable through the HotSpot attach mechanism:
+// once signalled, the JVM listens on a UNIX socket in its temp directory and
+// executes the commands written to it, like jcmd does.
+type virtualMachine struct {
+	// pid in the JVM's PID namespace, which names the socket
+	nsPid int
+	// tmpDir is the JVM's temp directory, as seen from this process
+	tmpDir string
+}
+
+// socketPath returns the path of the attach socket.
+func (vm *virtualMachine) socketPath() string {
+	return filepath.Join(vm.tmpDir, fmt.Sprintf(".java_pid%d", vm.nsPid))
+}
+
+// execute runs an attach command, e.g. dumpheap, and returns its output.
+func (vm *virtualMachine) execute(ctx context.Context, command string, args ...string) (string, error) {
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "unix", vm.socketPath())
+	if err != nil {
+		return "", fmt.Errorf("failed to connect to the JVM: %w", err)
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	if err := writeAttachRequest(conn, command, args); err != nil {
+		return "", fmt.Errorf("failed to send %s to the JVM: %w", command, err)
+	}
+	response, err := io.ReadAll(conn)
+	if err != nil {
+		return "", fmt.Errorf("failed to read the response to %s: %w", command, err)
+	}
+	return parseAttachResponse(command, response)
+}
+
+// writeAttachRequest writes a request: the protocol version, the command and
+// exactly attachArgs arguments, each NUL terminated.
+func writeAttachRequest(w io.Writer, command string, args []string) error {
+	if len(args) > attachArgs {
+		return fmt.Errorf("too many arguments for %s: %d", command, len(args))
+	}
+	var sb strings.Builder
+	sb.WriteString(attachProtocolVersion + "\x00")
+	sb.WriteString(command + "\x00")
+	for i := 0; i < attachArgs; i++ {
+		if i < len(args) {
+			sb.WriteString(args[i])
+		}
+		sb.WriteString("\x00")
+	}
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+// parseAttachResponse checks the return code on the first line of a
+// response and returns the rest, the command output.
+func parseAttachResponse(command string, response []byte) (string, error) {
+	reader := bufio.NewReader(strings.NewReader(string(response)))
+	code, err := reader.ReadString('\n')
+	if err != nil && code == "" {
+		return "", fmt.Errorf("empty response to %s", command)
+	}
+	output, _ := io.ReadAll(reader)
+	if code = strings.TrimSpace(code); code != "0" {
+		return "", fmt.Errorf("%s failed with code %s: %s", command, code, strings.TrimSpace(string(output)))
+	}
+	return strings.TrimSpace(string(output)), nil
+}
@@ -0,0 +1,114 @@
+//go:build linux
+
+package jvm
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// attachPollInterval is how often attach checks whether the JVM opened its
+// attach socket.
+const attachPollInterval = 100 * time.Millisecond
+
+// attach connects to the JVM with the given pid, starting its attach
+// listener if needed. The JVM must run as the same user.
+func attach(ctx context.Context, pid int) (*virtualMachine, error) {
+	if err := checkJVM(pid); err != nil {
+		return nil, err
+	}
+	nsPid, err := namespacePid(pid)
+	if err != nil {
+		return nil, err
+	}
+	// The JVM may run in a container: use its view of the filesystem
+	root := fmt.Sprintf("/proc/%d/root", pid)
+	vm := &virtualMachine{nsPid: nsPid, tmpDir: filepath.Join(root, "tmp")}
+	if _, err := os.Stat(vm.socketPath()); err == nil {
+		return vm, nil
+	}
+
+	// The JVM starts the attach listener on SIGQUIT when it finds an attach
+	// file in its working or temp directory; otherwise it prints a thread dump
+	attachFile := fmt.Sprintf(".attach_pid%d", nsPid)
+	attachPath := filepath.Join(fmt.Sprintf("/proc/%d/cwd", pid), attachFile)
+	if err := os.WriteFile(attachPath, nil, 0600); err != nil {
+		attachPath = filepath.Join(vm.tmpDir, attachFile)
+		if err := os.WriteFile(attachPath, nil, 0600); err != nil {
+			return nil, fmt.Errorf("failed to create attach file: %w", err)
+		}
+	}
+	defer os.Remove(attachPath)
+
+	if err := syscall.Kill(pid, syscall.SIGQUIT); err != nil {
+		return nil, fmt.Errorf("failed to signal JVM %d: %w", pid, err)
+	}
+
+	ticker := time.NewTicker(attachPollInterval)
+	defer ticker.Stop()
+	for {
+		if _, err := os.Stat(vm.socketPath()); err == nil {
+			return vm, nil
+		}
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("JVM %d did not start its attach listener (is it started with -XX:+DisableAttachMechanism?): %w",
+				pid, ctx.Err())
+		case <-ticker.C:
+		}
+	}
+}
+
+// checkJVM checks that pid is a HotSpot JVM, as SIGQUIT terminates other
+// processes.
+func checkJVM(pid int) error {
+	maps, err := os.ReadFile(fmt.Sprintf("/proc/%d/maps", pid))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return fmt.Errorf("no process with pid %d", pid)
+		}
+		return fmt.Errorf("failed to inspect process %d: %w", pid, err)
+	}
+	if !strings.Contains(string(maps), "/libjvm.so") {
+		return fmt.Errorf("process %d is not a HotSpot JVM", pid)
+	}
+	return nil
+}
+
+// namespacePid returns the pid of a process in its own PID namespace, e.g.
+// 1 for the main process of a container.
+func namespacePid(pid int) (int, error) {
+	f, err := os.Open(fmt.Sprintf("/proc/%d/status", pid))
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+	return parseNamespacePid(pid, f)
+}
+
+// parseNamespacePid reads the innermost pid from the NSpid line of a
+// /proc/<pid>/status file. Kernels before 4.1 have no NSpid line; pid is
+// returned as is then.
+func parseNamespacePid(pid int, status io.Reader) (int, error) {
+	scanner := bufio.NewScanner(status)
+	for scanner.Scan() {
+		fields, ok := strings.CutPrefix(scanner.Text(), "NSpid:")
+		if !ok {
+			continue
+		}
+		pids := strings.Fields(fields)
+		if len(pids) == 0 {
+			break
+		}
+		return strconv.Atoi(pids[len(pids)-1])
+	}
+	return pid, scanner.Err()
+}
@@ -0,0 +1,29 @@
+//go:build linux
+
+package jvm
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseNamespacePid(t *testing.T) {
+	status := "Name:\tjava\nTgid:\t31337\nPid:\t31337\nNSpid:\t31337\t1\n"
+	pid, err := parseNamespacePid(31337, strings.NewReader(status))
+	require.NoError(t, err)
+	assert.Equal(t, 1, pid, "innermost namespace")
+
+	pid, err = parseNamespacePid(31337, strings.NewReader("Name:\tjava\nPid:\t31337\n"))
+	require.NoError(t, err)
+	assert.Equal(t, 31337, pid, "no NSpid line")
+}
+
+func TestAttach_NotAJVM(t *testing.T) {
+	// The test binary is no JVM, and must not get SIGQUIT
+	_, err := attach(t.Context(), os.Getpid())
+	assert.ErrorContains(t, err, "not a HotSpot JVM")
+}
@@ -0,0 +1,11 @@
+//go:build !linux
+
+package jvm
+
+import "context"
+
+// attach is only implemented for Linux; elsewhere heap dumps go through jcmd
+// or jmap.
+func attach(ctx context.Context, pid int) (*virtualMachine, error) {
+	return nil, errAttachUnsupported
+}
@@ -0,0 +1,88 @@
+package jvm
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteAttachRequest(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, writeAttachRequest(&buf, "dumpheap", []string{"/tmp/heap.hprof", "-live"}))
+	assert.Equal(t, "1\x00dumpheap\x00/tmp/heap.hprof\x00-live\x00\x00", buf.String())
+
+	assert.Error(t, writeAttachRequest(&buf, "jcmd", []string{"a", "b", "c", "d"}))
+}
+
+func TestParseAttachResponse(t *testing.T) {
+	output, err := parseAttachResponse("dumpheap", []byte("0\nHeap dump file created [1234 bytes in 0.010 secs]\n"))
+	require.NoError(t, err)
+	assert.Equal(t, "Heap dump file created [1234 bytes in 0.010 secs]", output)
+
+	_, err = parseAttachResponse("dumpheap", []byte("-1\nFile exists\n"))
+	assert.ErrorContains(t, err, "File exists")
+
+	_, err = parseAttachResponse("dumpheap", nil)
+	assert.Error(t, err)
+}
+
+func TestVirtualMachine_Execute(t *testing.T) {
+	vm := &virtualMachine{nsPid: 4242, tmpDir: t.TempDir()}
+	listener, err := net.Listen("unix", vm.socketPath())
+	require.NoError(t, err)
+	defer listener.Close()
+
+	// A fake JVM answering one request
+	requests := make(chan string, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		request := make([]byte, 0, 64)
+		buf := make([]byte, 64)
+		// The request ends with the 5th NUL: version, command and 3 arguments
+		for bytes.Count(request, []byte{0}) < 5 {
+			n, err := conn.Read(buf)
+			if err != nil {
+				return
+			}
+			request = append(request, buf[:n]...)
+		}
+		requests <- string(request)
+		io.WriteString(conn, "0\nHeap dump file created\n")
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	output, err := vm.execute(ctx, "dumpheap", "/dumps/heap.hprof", "-all")
+	require.NoError(t, err)
+	assert.Equal(t, "Heap dump file created", output)
+	assert.Equal(t, "1\x00dumpheap\x00/dumps/heap.hprof\x00-all\x00\x00", <-requests)
+}
+
+func TestDumpHeap_ExistingFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "heap.hprof")
+	require.NoError(t, os.WriteFile(path, []byte("JAVA PROFILE"), 0644))
+
+	_, err := DumpHeap(context.Background(), os.Getpid(), path, HeapDumpOptions{})
+	assert.ErrorContains(t, err, "already exists")
+}
+
+func TestParseDumpMethod(t *testing.T) {
+	method, err := ParseDumpMethod("jcmd")
+	require.NoError(t, err)
+	assert.Equal(t, DumpMethodJcmd, method)
+
+	_, err = ParseDumpMethod("jstack")
+	assert.Error(t, err)
+}
@@ -0,0 +1,178 @@
+// Package jvm captures diagnostic data, such as heap dumps, from running JVMs.
+package jvm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/perf-analysis/pkg/utils"
+)
+
+// DumpMethod selects how a heap dump is requested from a JVM.
+type DumpMethod string
+
+const (
+	// DumpMethodAuto uses the attach mechanism where supported, and falls
+	// back to jcmd and jmap.
+	DumpMethodAuto DumpMethod = "auto"
+	// DumpMethodAttach talks to the JVM through the HotSpot attach mechanism
+	// directly, so no JDK tools are needed, e.g. in JRE-only containers.
+	DumpMethodAttach DumpMethod = "attach"
+	// DumpMethodJcmd runs jcmd <pid> GC.heap_dump.
+	DumpMethodJcmd DumpMethod = "jcmd"
+	// DumpMethodJmap runs jmap -dump.
+	DumpMethodJmap DumpMethod = "jmap"
+)
+
+// DumpMethods returns the valid dump methods.
+func DumpMethods() []DumpMethod {
+	return []DumpMethod{DumpMethodAuto, DumpMethodAttach, DumpMethodJcmd, DumpMethodJmap}
+}
+
+// ParseDumpMethod parses a dump method name.
+func ParseDumpMethod(s string) (DumpMethod, error) {
+	for _, method := range DumpMethods() {
+		if string(method) == s {
+			return method, nil
+		}
+	}
+	return "", fmt.Errorf("unknown dump method: %q (valid: auto, attach, jcmd, jmap)", s)
+}
+
+// HeapDumpOptions configures DumpHeap.
+type HeapDumpOptions struct {
+	Method DumpMethod
+	// All also dumps unreachable objects. By default the JVM runs a full GC
+	// and only dumps live objects.
+	All    bool
+	Logger utils.Logger
+}
+
+// DumpHeap makes the JVM with the given pid write an HPROF heap dump to path,
+// which must not exist yet. The JVM writes the file itself, so path must be
+// writable by the JVM's user, and resolves in its filesystem. It returns
+// the method that succeeded.
+func DumpHeap(ctx context.Context, pid int, path string, opts HeapDumpOptions) (DumpMethod, error) {
+	if opts.Logger == nil {
+		opts.Logger = &utils.NullLogger{}
+	}
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+	if _, err := os.Stat(path); err == nil {
+		return "", fmt.Errorf("dump file already exists: %s", path)
+	}
+
+	methods := []DumpMethod{opts.Method}
+	if opts.Method == DumpMethodAuto || opts.Method == "" {
+		methods = []DumpMethod{DumpMethodAttach, DumpMethodJcmd, DumpMethodJmap}
+	}
+
+	var errs []error
+	for _, method := range methods {
+		opts.Logger.Debug("Requesting heap dump of JVM %d with %s", pid, method)
+		output, err := dumpHeapWith(ctx, method, pid, path, opts.All)
+		if err == nil {
+			err = checkDumpFile(path)
+		}
+		if err == nil {
+			if output != "" {
+				opts.Logger.Debug("%s: %s", method, output)
+			}
+			return method, nil
+		}
+		opts.Logger.Debug("Heap dump with %s failed: %v", method, err)
+		errs = append(errs, fmt.Errorf("%s: %w", method, err))
+		if ctx.Err() != nil {
+			break
+		}
+		// Don't hand a partial dump to the next method
+		os.Remove(path)
+	}
+	return "", fmt.Errorf("failed to dump the heap of JVM %d: %w", pid, errors.Join(errs...))
+}
+
+// dumpHeapWith requests a heap dump with one method and returns its output.
+func dumpHeapWith(ctx context.Context, method DumpMethod, pid int, path string, all bool) (string, error) {
+	switch method {
+	case DumpMethodAttach:
+		vm, err := attach(ctx, pid)
+		if err != nil {
+			return "", err
+		}
+		scope := "-live"
+		if all {
+			scope = "-all"
+		}
+		return vm.execute(ctx, "dumpheap", path, scope)
+	case DumpMethodJcmd:
+		args := []string{strconv.Itoa(pid), "GC.heap_dump"}
+		if all {
+			args = append(args, "-all")
+		}
+		return runJDKTool(ctx, pid, "jcmd", append(args, path)...)
+	case DumpMethodJmap:
+		option := "-dump:live,format=b,file=" + path
+		if all {
+			option = "-dump:format=b,file=" + path
+		}
+		return runJDKTool(ctx, pid, "jmap", option, strconv.Itoa(pid))
+	default:
+		return "", fmt.Errorf("unknown dump method: %q", method)
+	}
+}
+
+// runJDKTool runs a JDK tool such as jcmd and returns its output.
+func runJDKTool(ctx context.Context, pid int, name string, args ...string) (string, error) {
+	tool, err := findJDKTool(pid, name)
+	if err != nil {
+		return "", err
+	}
+	output, err := exec.CommandContext(ctx, tool, args...).CombinedOutput()
+	if err != nil {
+		return "", fmt.Errorf("%w: %s", err, strings.TrimSpace(string(output)))
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+// findJDKTool looks for a JDK tool in PATH, in $JAVA_HOME/bin, and next to
+// the java executable of the target JVM, so the tool matches its version.
+func findJDKTool(pid int, name string) (string, error) {
+	if path, err := exec.LookPath(name); err == nil {
+		return path, nil
+	}
+	var dirs []string
+	if javaHome := os.Getenv("JAVA_HOME"); javaHome != "" {
+		dirs = append(dirs, filepath.Join(javaHome, "bin"))
+	}
+	if java, err := os.Readlink(fmt.Sprintf("/proc/%d/exe", pid)); err == nil {
+		dirs = append(dirs, filepath.Dir(java))
+	}
+	for _, dir := range dirs {
+		path := filepath.Join(dir, name)
+		if info, err := os.Stat(path); err == nil && !info.IsDir() {
+			return path, nil
+		}
+	}
+	return "", fmt.Errorf("%s not found in PATH or JAVA_HOME", name)
+}
+
+// checkDumpFile checks that a dump was written. Tools report some failures
+// only in their output.
+func checkDumpFile(path string) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return fmt.Errorf("no dump was written to %s", path)
+	}
+	if info.Size() == 0 {
+		return fmt.Errorf("empty dump written to %s", path)
+	}
+	return nil
+}
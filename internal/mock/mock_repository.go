@@ -183,3 +183,112 @@ func (m *MockMasterTaskRepository) CheckAndCompleteIfReady(ctx context.Context,
 	args := m.Called(ctx, masterTID)
 	return args.Error(0)
 }
+
+// MockSummaryRepository is a mock implementation of the SummaryRepository interface.
+type MockSummaryRepository struct {
+	mock.Mock
+}
+
+// SaveSummary mocks the SaveSummary method.
+func (m *MockSummaryRepository) SaveSummary(ctx context.Context, summary *model.AnalysisSummary) error {
+	args := m.Called(ctx, summary)
+	return args.Error(0)
+}
+
+// GetSummary mocks the GetSummary method.
+func (m *MockSummaryRepository) GetSummary(ctx context.Context, taskUUID string) (*model.AnalysisSummary, error) {
+	args := m.Called(ctx, taskUUID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.AnalysisSummary), args.Error(1)
+}
+
+// ListSummaries mocks the ListSummaries method.
+func (m *MockSummaryRepository) ListSummaries(ctx context.Context, query *model.SummaryQuery) ([]*model.AnalysisSummary, error) {
+	args := m.Called(ctx, query)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*model.AnalysisSummary), args.Error(1)
+}
+
+// ExpectSaveSummary sets up an expectation for SaveSummary.
+func (m *MockSummaryRepository) ExpectSaveSummary(err error) *mock.Call {
+	return m.On("SaveSummary", mock.Anything, mock.Anything).Return(err)
+}
+
+// MockBaselineRepository is a mock implementation of the BaselineRepository interface.
+type MockBaselineRepository struct {
+	mock.Mock
+}
+
+// SetBaseline mocks the SetBaseline method.
+func (m *MockBaselineRepository) SetBaseline(ctx context.Context, baseline *model.Baseline) error {
+	args := m.Called(ctx, baseline)
+	return args.Error(0)
+}
+
+// GetBaseline mocks the GetBaseline method.
+func (m *MockBaselineRepository) GetBaseline(ctx context.Context, service string, taskType model.TaskType) (*model.Baseline, error) {
+	args := m.Called(ctx, service, taskType)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.Baseline), args.Error(1)
+}
+
+// ListBaselines mocks the ListBaselines method.
+func (m *MockBaselineRepository) ListBaselines(ctx context.Context, service string) ([]*model.Baseline, error) {
+	args := m.Called(ctx, service)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*model.Baseline), args.Error(1)
+}
+
+// DeleteBaseline mocks the DeleteBaseline method.
+func (m *MockBaselineRepository) DeleteBaseline(ctx context.Context, taskUUID string) error {
+	args := m.Called(ctx, taskUUID)
+	return args.Error(0)
+}
+
+// MockDeadLetterRepository is a mock implementation of the DeadLetterRepository interface.
+type MockDeadLetterRepository struct {
+	mock.Mock
+}
+
+// SaveDeadLetter mocks the SaveDeadLetter method.
+func (m *MockDeadLetterRepository) SaveDeadLetter(ctx context.Context, letter *model.DeadLetter) error {
+	args := m.Called(ctx, letter)
+	return args.Error(0)
+}
+
+// GetDeadLetter mocks the GetDeadLetter method.
+func (m *MockDeadLetterRepository) GetDeadLetter(ctx context.Context, taskUUID string) (*model.DeadLetter, error) {
+	args := m.Called(ctx, taskUUID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.DeadLetter), args.Error(1)
+}
+
+// ListDeadLetters mocks the ListDeadLetters method.
+func (m *MockDeadLetterRepository) ListDeadLetters(ctx context.Context, limit int) ([]*model.DeadLetter, error) {
+	args := m.Called(ctx, limit)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*model.DeadLetter), args.Error(1)
+}
+
+// DeleteDeadLetter mocks the DeleteDeadLetter method.
+func (m *MockDeadLetterRepository) DeleteDeadLetter(ctx context.Context, taskUUID string) error {
+	args := m.Called(ctx, taskUUID)
+	return args.Error(0)
+}
+
+// ExpectSaveDeadLetter sets up an expectation for SaveDeadLetter.
+func (m *MockDeadLetterRepository) ExpectSaveDeadLetter(err error) *mock.Call {
+	return m.On("SaveDeadLetter", mock.Anything, mock.Anything).Return(err)
+}
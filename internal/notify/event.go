@@ -0,0 +1,117 @@
+// Package notify notifies webhooks, e.g. Slack or Feishu bots, of the results
+// of analysis tasks.
+package notify
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/perf-analysis/pkg/config"
+	"github.com/perf-analysis/pkg/model"
+)
+
+// EventType is the outcome of a task notified to webhooks.
+type EventType string
+
+const (
+	EventCompleted EventType = config.WebhookEventCompleted
+	EventFailed    EventType = config.WebhookEventFailed
+)
+
+// Event describes the result of a task.
+type Event struct {
+	Type         EventType          `json:"event"`
+	TaskUUID     string             `json:"tid"`
+	TaskType     model.TaskType     `json:"task_type"`
+	ProfilerType model.ProfilerType `json:"profiler_type"`
+	Service      string             `json:"service,omitempty"`
+	UserName     string             `json:"user_name,omitempty"`
+
+	// Error is the reason of the failure of failed tasks
+	Error string `json:"error,omitempty"`
+
+	// Highlights and Summary are set for completed tasks
+	Highlights *Highlights            `json:"highlights,omitempty"`
+	Summary    *model.AnalysisSummary `json:"summary,omitempty"`
+
+	// Link opens the results of the task in the web UI, set by the Notifier
+	// when the web UI URL is configured
+	Link string    `json:"link,omitempty"`
+	Time time.Time `json:"time"`
+}
+
+// Highlights are the headline findings of an analysis, one line each.
+type Highlights struct {
+	// TopLeakSuspect is the class retaining the most memory of heap dumps
+	TopLeakSuspect string `json:"top_leak_suspect,omitempty"`
+	// TopFunction is the hottest function of profiles
+	TopFunction string `json:"top_function,omitempty"`
+}
+
+// CompletedEvent returns the event of a task analyzed into summary.
+func CompletedEvent(summary *model.AnalysisSummary) *Event {
+	return &Event{
+		Type:         EventCompleted,
+		TaskUUID:     summary.TaskUUID,
+		TaskType:     summary.TaskType,
+		ProfilerType: summary.ProfilerType,
+		Service:      summary.Service,
+		UserName:     summary.UserName,
+		Highlights:   summarize(summary),
+		Summary:      summary,
+	}
+}
+
+// summarize returns the highlights of an analysis summary, nil if none.
+func summarize(summary *model.AnalysisSummary) *Highlights {
+	highlights := &Highlights{}
+
+	// The class retaining the most memory, the largest one if retained
+	// sizes were not computed
+	var suspect *model.HeapClassStats
+	for i := range summary.TopClasses {
+		class := &summary.TopClasses[i]
+		if suspect == nil || class.RetainedSize > suspect.RetainedSize {
+			suspect = class
+		}
+	}
+	if suspect != nil {
+		if suspect.RetainedSize > 0 {
+			highlights.TopLeakSuspect = fmt.Sprintf("%s: %s retained by %d instances",
+				suspect.ClassName, formatBytes(suspect.RetainedSize), suspect.InstanceCount)
+		} else {
+			highlights.TopLeakSuspect = fmt.Sprintf("%s: %s in %d instances (%.1f%%)",
+				suspect.ClassName, formatBytes(suspect.TotalSize), suspect.InstanceCount, suspect.Percentage)
+		}
+	}
+
+	if len(summary.TopFuncs) > 0 {
+		top := summary.TopFuncs[0]
+		highlights.TopFunction = fmt.Sprintf("%s (%.1f%%)", top.Name, top.Percentage)
+	}
+
+	if *highlights == (Highlights{}) {
+		return nil
+	}
+	return highlights
+}
+
+// formatBytes formats bytes to human-readable string.
+func formatBytes(bytes int64) string {
+	const (
+		KB = 1024
+		MB = KB * 1024
+		GB = MB * 1024
+	)
+
+	switch {
+	case bytes >= GB:
+		return fmt.Sprintf("%.2f GB", float64(bytes)/GB)
+	case bytes >= MB:
+		return fmt.Sprintf("%.2f MB", float64(bytes)/MB)
+	case bytes >= KB:
+		return fmt.Sprintf("%.2f KB", float64(bytes)/KB)
+	default:
+		return fmt.Sprintf("%d B", bytes)
+	}
+}
@@ -0,0 +1,227 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/perf-analysis/pkg/config"
+	"github.com/perf-analysis/pkg/utils"
+)
+
+const (
+	// defaultTimeout bounds each delivery attempt of webhooks without a timeout
+	defaultTimeout = 10 * time.Second
+
+	// maxAttempts is the number of deliveries attempted per webhook
+	maxAttempts = 3
+)
+
+// Notifier sends task events to the configured webhooks. Deliveries run in
+// the background, and are retried on network and server errors.
+type Notifier struct {
+	webhooks []config.WebhookConfig
+	webUIURL string
+	client   *http.Client
+	logger   utils.Logger
+
+	// retryDelay is the delay before the second attempt, doubled after
+	retryDelay time.Duration
+
+	wg sync.WaitGroup
+}
+
+// New creates a Notifier, nil if no webhook is configured.
+func New(cfg *config.NotificationsConfig, logger utils.Logger) *Notifier {
+	if len(cfg.Webhooks) == 0 {
+		return nil
+	}
+	if logger == nil {
+		logger = utils.NewDefaultLogger(utils.LevelInfo, nil)
+	}
+	return &Notifier{
+		webhooks:   cfg.Webhooks,
+		webUIURL:   strings.TrimSuffix(cfg.WebUIURL, "/"),
+		client:     &http.Client{},
+		logger:     logger,
+		retryDelay: time.Second,
+	}
+}
+
+// Link returns the link to the results of a task in the web UI, empty if
+// the web UI URL is not configured.
+func (n *Notifier) Link(taskUUID string) string {
+	if n.webUIURL == "" {
+		return ""
+	}
+	return n.webUIURL + "/?task=" + url.QueryEscape(taskUUID)
+}
+
+// Notify sends an event to the webhooks subscribed to its type, in the
+// background. A nil Notifier sends nothing.
+func (n *Notifier) Notify(event *Event) {
+	if n == nil {
+		return
+	}
+	if event.Link == "" {
+		event.Link = n.Link(event.TaskUUID)
+	}
+	if event.Time.IsZero() {
+		event.Time = time.Now()
+	}
+
+	for _, webhook := range n.webhooks {
+		if !subscribed(webhook, event.Type) {
+			continue
+		}
+		body, err := payload(webhook.Format, event)
+		if err != nil {
+			n.logger.Error("Failed to encode notification of task %s: %v", event.TaskUUID, err)
+			continue
+		}
+
+		n.wg.Add(1)
+		go func(webhook config.WebhookConfig) {
+			defer n.wg.Done()
+			if err := n.deliver(webhook, body); err != nil {
+				n.logger.Warn("Failed to notify %s of task %s: %v", redact(webhook.URL), event.TaskUUID, err)
+			}
+		}(webhook)
+	}
+}
+
+// Close waits for the deliveries in progress.
+func (n *Notifier) Close() {
+	if n == nil {
+		return
+	}
+	n.wg.Wait()
+}
+
+// deliver posts a payload to a webhook, retrying on failures which may be
+// transient.
+func (n *Notifier) deliver(webhook config.WebhookConfig, body []byte) error {
+	timeout := defaultTimeout
+	if webhook.Timeout > 0 {
+		timeout = time.Duration(webhook.Timeout) * time.Second
+	}
+
+	delay := n.retryDelay
+	var err error
+	for attempt := 1; attempt <= maxAttempts; attempt++ {
+		var retry bool
+		if retry, err = n.post(webhook, body, timeout); err == nil || !retry {
+			return err
+		}
+		if attempt < maxAttempts {
+			time.Sleep(delay)
+			delay *= 2
+		}
+	}
+	return fmt.Errorf("%w (after %d attempts)", err, maxAttempts)
+}
+
+// post posts a payload to a webhook once, and reports whether a failure may
+// be retried.
+func (n *Notifier) post(webhook config.WebhookConfig, body []byte, timeout time.Duration) (retry bool, err error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook.URL, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for key, value := range webhook.Headers {
+		req.Header.Set(key, value)
+	}
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		retry := resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests
+		return retry, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return false, nil
+}
+
+// subscribed reports whether a webhook is notified of events of a type.
+// Webhooks listing no events get them all.
+func subscribed(webhook config.WebhookConfig, eventType EventType) bool {
+	if len(webhook.Events) == 0 {
+		return true
+	}
+	for _, event := range webhook.Events {
+		if EventType(event) == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// redact strips the path and query of a webhook URL for logs, as they often
+// hold the token of the webhook.
+func redact(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Host == "" {
+		return "webhook"
+	}
+	return u.Scheme + "://" + u.Host
+}
+
+// payload encodes an event in the format of a webhook.
+func payload(format string, event *Event) ([]byte, error) {
+	switch format {
+	case config.WebhookFormatSlack:
+		text := message(event)
+		if event.Link != "" {
+			text += fmt.Sprintf("\n<%s|View results>", event.Link)
+		}
+		return json.Marshal(map[string]interface{}{"text": text})
+	case config.WebhookFormatFeishu:
+		text := message(event)
+		if event.Link != "" {
+			text += "\nView results: " + event.Link
+		}
+		return json.Marshal(map[string]interface{}{
+			"msg_type": "text",
+			"content":  map[string]string{"text": text},
+		})
+	default:
+		return json.Marshal(event)
+	}
+}
+
+// message returns the text of chat notifications of an event, without the
+// link.
+func message(event *Event) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Analysis of task %s (%s", event.TaskUUID, event.TaskType)
+	if event.Service != "" {
+		fmt.Fprintf(&b, ", service %s", event.Service)
+	}
+	fmt.Fprintf(&b, ") %s", event.Type)
+
+	if event.Error != "" {
+		fmt.Fprintf(&b, "\nError: %s", event.Error)
+	}
+	if h := event.Highlights; h != nil {
+		if h.TopLeakSuspect != "" {
+			fmt.Fprintf(&b, "\nTop leak suspect: %s", h.TopLeakSuspect)
+		}
+		if h.TopFunction != "" {
+			fmt.Fprintf(&b, "\nTop function: %s", h.TopFunction)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,215 @@
+package notify
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/perf-analysis/pkg/config"
+	"github.com/perf-analysis/pkg/model"
+)
+
+// webhookServer records the requests of webhooks, answering with the
+// statuses in turn and 200 after.
+type webhookServer struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	bodies   [][]byte
+	headers  []http.Header
+	statuses []int
+}
+
+func newWebhookServer(t *testing.T, statuses ...int) *webhookServer {
+	s := &webhookServer{statuses: statuses}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.bodies = append(s.bodies, body)
+		s.headers = append(s.headers, r.Header.Clone())
+		if len(s.statuses) > 0 {
+			w.WriteHeader(s.statuses[0])
+			s.statuses = s.statuses[1:]
+		}
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *webhookServer) requests() [][]byte {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.bodies
+}
+
+func newTestNotifier(cfg *config.NotificationsConfig) *Notifier {
+	n := New(cfg, nil)
+	n.retryDelay = time.Millisecond
+	return n
+}
+
+func heapSummary() *model.AnalysisSummary {
+	return &model.AnalysisSummary{
+		TaskUUID: "task-1",
+		TaskType: model.TaskTypeJavaHeap,
+		Service:  "checkout",
+		TopClasses: []model.HeapClassStats{
+			{ClassName: "byte[]", InstanceCount: 100, TotalSize: 3 << 30, Percentage: 60},
+			{ClassName: "com.example.SessionCache", InstanceCount: 1, TotalSize: 64, RetainedSize: 2 << 30},
+		},
+	}
+}
+
+func TestNew_NoWebhooks(t *testing.T) {
+	n := New(&config.NotificationsConfig{WebUIURL: "http://perf"}, nil)
+	assert.Nil(t, n)
+
+	// A nil notifier is a no-op
+	n.Notify(CompletedEvent(heapSummary()))
+	n.Close()
+}
+
+func TestNotifier_JSON(t *testing.T) {
+	server := newWebhookServer(t)
+	n := newTestNotifier(&config.NotificationsConfig{
+		WebUIURL: "http://perf.example.com/",
+		Webhooks: []config.WebhookConfig{{URL: server.URL, Headers: map[string]string{"x-token": "secret"}}},
+	})
+
+	n.Notify(CompletedEvent(heapSummary()))
+	n.Close()
+
+	require.Len(t, server.requests(), 1)
+	var event Event
+	require.NoError(t, json.Unmarshal(server.requests()[0], &event))
+	assert.Equal(t, EventCompleted, event.Type)
+	assert.Equal(t, "task-1", event.TaskUUID)
+	assert.Equal(t, "checkout", event.Service)
+	assert.Equal(t, "http://perf.example.com/?task=task-1", event.Link)
+	assert.False(t, event.Time.IsZero())
+	require.NotNil(t, event.Highlights)
+	assert.Equal(t, "com.example.SessionCache: 2.00 GB retained by 1 instances", event.Highlights.TopLeakSuspect)
+	require.NotNil(t, event.Summary)
+	assert.Len(t, event.Summary.TopClasses, 2)
+
+	assert.Equal(t, "secret", server.headers[0].Get("X-Token"))
+	assert.Equal(t, "application/json", server.headers[0].Get("Content-Type"))
+}
+
+func TestNotifier_ChatFormats(t *testing.T) {
+	slack := newWebhookServer(t)
+	feishu := newWebhookServer(t)
+	n := newTestNotifier(&config.NotificationsConfig{
+		WebUIURL: "http://perf",
+		Webhooks: []config.WebhookConfig{
+			{URL: slack.URL, Format: config.WebhookFormatSlack},
+			{URL: feishu.URL, Format: config.WebhookFormatFeishu},
+		},
+	})
+
+	n.Notify(CompletedEvent(&model.AnalysisSummary{
+		TaskUUID: "task-2",
+		TaskType: model.TaskTypeJava,
+		TopFuncs: []model.TopItem{{Name: "com.example.Codec.encode", Percentage: 42.5}},
+	}))
+	n.Close()
+
+	var slackMsg struct {
+		Text string `json:"text"`
+	}
+	require.Len(t, slack.requests(), 1)
+	require.NoError(t, json.Unmarshal(slack.requests()[0], &slackMsg))
+	assert.Equal(t, "Analysis of task task-2 (java) completed\n"+
+		"Top function: com.example.Codec.encode (42.5%)\n"+
+		"<http://perf/?task=task-2|View results>", slackMsg.Text)
+
+	var feishuMsg struct {
+		MsgType string `json:"msg_type"`
+		Content struct {
+			Text string `json:"text"`
+		} `json:"content"`
+	}
+	require.Len(t, feishu.requests(), 1)
+	require.NoError(t, json.Unmarshal(feishu.requests()[0], &feishuMsg))
+	assert.Equal(t, "text", feishuMsg.MsgType)
+	assert.Contains(t, feishuMsg.Content.Text, "Top function: com.example.Codec.encode (42.5%)")
+	assert.Contains(t, feishuMsg.Content.Text, "View results: http://perf/?task=task-2")
+}
+
+func TestNotifier_Events(t *testing.T) {
+	failures := newWebhookServer(t)
+	all := newWebhookServer(t)
+	n := newTestNotifier(&config.NotificationsConfig{
+		Webhooks: []config.WebhookConfig{
+			{URL: failures.URL, Format: config.WebhookFormatSlack, Events: []string{config.WebhookEventFailed}},
+			{URL: all.URL},
+		},
+	})
+
+	n.Notify(CompletedEvent(heapSummary()))
+	n.Notify(&Event{Type: EventFailed, TaskUUID: "task-3", TaskType: model.TaskTypeJavaHeap, Error: "empty input file"})
+	n.Close()
+
+	assert.Len(t, all.requests(), 2)
+	require.Len(t, failures.requests(), 1)
+	assert.Contains(t, string(failures.requests()[0]), "Analysis of task task-3 (java_heap) failed\\nError: empty input file")
+	assert.NotContains(t, string(failures.requests()[0]), "View results", "no link without a web UI URL")
+}
+
+func TestNotifier_Retry(t *testing.T) {
+	t.Run("ServerErrors", func(t *testing.T) {
+		server := newWebhookServer(t, http.StatusBadGateway, http.StatusTooManyRequests)
+		n := newTestNotifier(&config.NotificationsConfig{Webhooks: []config.WebhookConfig{{URL: server.URL}}})
+
+		n.Notify(CompletedEvent(heapSummary()))
+		n.Close()
+
+		assert.Len(t, server.requests(), 3)
+	})
+
+	t.Run("GivesUp", func(t *testing.T) {
+		server := newWebhookServer(t, 500, 500, 500, 500)
+		n := newTestNotifier(&config.NotificationsConfig{Webhooks: []config.WebhookConfig{{URL: server.URL}}})
+
+		n.Notify(CompletedEvent(heapSummary()))
+		n.Close()
+
+		assert.Len(t, server.requests(), maxAttempts)
+	})
+
+	t.Run("ClientErrors", func(t *testing.T) {
+		server := newWebhookServer(t, http.StatusNotFound)
+		n := newTestNotifier(&config.NotificationsConfig{Webhooks: []config.WebhookConfig{{URL: server.URL}}})
+
+		n.Notify(CompletedEvent(heapSummary()))
+		n.Close()
+
+		assert.Len(t, server.requests(), 1, "client errors are not retried")
+	})
+}
+
+func TestCompletedEvent_Highlights(t *testing.T) {
+	event := CompletedEvent(&model.AnalysisSummary{
+		TaskUUID:   "task-4",
+		TopClasses: []model.HeapClassStats{{ClassName: "char[]", InstanceCount: 10, TotalSize: 5 << 20, Percentage: 12.5}},
+	})
+	require.NotNil(t, event.Highlights)
+	assert.Equal(t, "char[]: 5.00 MB in 10 instances (12.5%)", event.Highlights.TopLeakSuspect,
+		"the largest class without retained sizes")
+
+	event = CompletedEvent(&model.AnalysisSummary{TaskUUID: "task-5"})
+	assert.Nil(t, event.Highlights)
+}
+
+func TestRedact(t *testing.T) {
+	assert.Equal(t, "https://hooks.slack.com", redact("https://hooks.slack.com/services/T0/B0/secret"))
+	assert.Equal(t, "webhook", redact("not a url"))
+}
@@ -233,12 +233,16 @@ func (p *Parser) parseLine(line string) (*model.Sample, error) {
 		callStack = append(callStack, funcName)
 	}
 
-	return &model.Sample{
+	sample := &model.Sample{
 		ThreadName: threadInfo.ThreadName,
 		TID:        threadInfo.TID,
 		CallStack:  callStack,
 		Value:      count,
-	}, nil
+	}
+	if threadInfo.PID > 0 {
+		sample.PID = threadInfo.PID
+	}
+	return sample, nil
 }
 
 // threadStats holds intermediate thread statistics.
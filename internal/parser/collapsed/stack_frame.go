@@ -95,6 +95,13 @@ func ExtractThreadInfo(threadFrame string) *ThreadInfo {
 		}
 	}
 
+	// Extract PID (between the last '-' and the last '/'), "?" if unknown
+	if lastDash > 0 && lastSlash > lastDash {
+		if pid, err := strconv.Atoi(threadFrame[lastDash+1 : lastSlash]); err == nil {
+			info.PID = pid
+		}
+	}
+
 	return info
 }
 
@@ -73,48 +73,56 @@ func TestExtractThreadInfo(t *testing.T) {
 		input          string
 		wantThreadName string
 		wantTID        int
+		wantPID        int
 	}{
 		{
 			name:           "standard perf format",
 			input:          "sap1009-?/1088670",
 			wantThreadName: "sap1009",
 			wantTID:        1088670,
+			wantPID:        -1,
 		},
 		{
 			name:           "APM format",
 			input:          "[Thread-7 tid=1060369]",
 			wantThreadName: "Thread-7",
 			wantTID:        1060369,
+			wantPID:        -1,
 		},
 		{
 			name:           "process with pid",
 			input:          "java-12345/67890",
 			wantThreadName: "java",
 			wantTID:        67890,
+			wantPID:        12345,
 		},
 		{
 			name:           "swapper thread",
 			input:          "swapper-?/0",
 			wantThreadName: "swapper",
 			wantTID:        0,
+			wantPID:        -1,
 		},
 		{
 			name:           "complex thread name",
 			input:          "pool-1-thread-1-12345/67890",
 			wantThreadName: "pool-1-thread-1",
 			wantTID:        67890,
+			wantPID:        12345,
 		},
 		{
 			name:           "APM format with spaces",
 			input:          "[main thread tid=12345]",
 			wantThreadName: "main thread",
 			wantTID:        12345,
+			wantPID:        -1,
 		},
 		{
 			name:           "no tid info",
 			input:          "process_name",
 			wantThreadName: "process_name",
 			wantTID:        -1,
+			wantPID:        -1,
 		},
 	}
 
@@ -123,6 +131,7 @@ func TestExtractThreadInfo(t *testing.T) {
 			info := ExtractThreadInfo(tt.input)
 			assert.Equal(t, tt.wantThreadName, info.ThreadName)
 			assert.Equal(t, tt.wantTID, info.TID)
+			assert.Equal(t, tt.wantPID, info.PID)
 		})
 	}
 }
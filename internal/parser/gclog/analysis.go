@@ -0,0 +1,264 @@
+package gclog
+
+import (
+	"sort"
+	"time"
+)
+
+// pauseBuckets are the upper bounds of the pause histogram buckets. Longer
+// pauses are counted in a last, unbounded bucket.
+var pauseBuckets = []time.Duration{
+	time.Millisecond,
+	2 * time.Millisecond,
+	5 * time.Millisecond,
+	10 * time.Millisecond,
+	20 * time.Millisecond,
+	50 * time.Millisecond,
+	100 * time.Millisecond,
+	200 * time.Millisecond,
+	500 * time.Millisecond,
+	time.Second,
+	2 * time.Second,
+	5 * time.Second,
+}
+
+// Summary holds the pause statistics and the allocation and promotion
+// rates of a GC log.
+type Summary struct {
+	// Duration is the time from the first to the last event
+	Duration time.Duration
+
+	PauseCount int
+	TotalPause time.Duration
+	MaxPause   time.Duration
+	P50        time.Duration
+	P90        time.Duration
+	P95        time.Duration
+	P99        time.Duration
+	// Throughput is the percentage of Duration the application was not
+	// paused
+	Throughput float64
+
+	// AllocatedBytes is the heap growth between consecutive collections,
+	// and AllocationRate its rate over Duration in bytes per second
+	AllocatedBytes     int64
+	AllocationRate     float64
+	PeakAllocationRate float64
+
+	// PromotedBytes is the old generation growth of young collections,
+	// known if HasPromotion is set
+	PromotedBytes int64
+	PromotionRate float64
+	HasPromotion  bool
+
+	// LiveSetGrowthRate is the slope of the heap occupancy after the
+	// collections, in bytes per second, a leak when steadily positive
+	LiveSetGrowthRate float64
+}
+
+// HistogramBucket is a bucket of the pause histogram.
+type HistogramBucket struct {
+	UpperBound time.Duration // 0 for the last, unbounded bucket
+	Count      int
+}
+
+// PauseTypeStats are the statistics of a type of pauses.
+type PauseTypeStats struct {
+	Type  string
+	Count int
+	Total time.Duration
+	Max   time.Duration
+}
+
+// DumpCorrelation places a heap dump in the GC log.
+type DumpCorrelation struct {
+	// Uptime is the uptime of the JVM at the dump
+	Uptime time.Duration
+	// InLog reports whether the dump was taken between the first and the
+	// last events of the log
+	InLog bool
+	// Preceding and Next are the collections before and after the dump,
+	// nil if none
+	Preceding *Collection
+	Next      *Collection
+}
+
+// Summarize computes the summary of the log.
+func (l *Log) Summarize() Summary {
+	var s Summary
+	first, last := l.span()
+	s.Duration = last - first
+
+	durations := make([]time.Duration, 0, len(l.Pauses))
+	for _, p := range l.Pauses {
+		durations = append(durations, p.Duration)
+		s.TotalPause += p.Duration
+	}
+	sort.Slice(durations, func(i, j int) bool { return durations[i] < durations[j] })
+	s.PauseCount = len(durations)
+	if len(durations) > 0 {
+		s.MaxPause = durations[len(durations)-1]
+		s.P50 = percentile(durations, 50)
+		s.P90 = percentile(durations, 90)
+		s.P95 = percentile(durations, 95)
+		s.P99 = percentile(durations, 99)
+	}
+	if s.Duration > 0 {
+		s.Throughput = 100 - float64(s.TotalPause)*100/float64(s.Duration)
+	}
+
+	for i := 1; i < len(l.Collections); i++ {
+		prev, cur := l.Collections[i-1], l.Collections[i]
+		allocated := cur.Before - prev.After
+		if allocated <= 0 {
+			continue
+		}
+		s.AllocatedBytes += allocated
+		if elapsed := (cur.Uptime - prev.Uptime).Seconds(); elapsed > 0 {
+			s.PeakAllocationRate = max(s.PeakAllocationRate, float64(allocated)/elapsed)
+		}
+	}
+
+	for _, c := range l.Collections {
+		if c.HasOld && c.Young() {
+			s.HasPromotion = true
+			if promoted := c.OldAfter - c.OldBefore; promoted > 0 {
+				s.PromotedBytes += promoted
+			}
+		}
+	}
+
+	if seconds := s.Duration.Seconds(); seconds > 0 {
+		s.AllocationRate = float64(s.AllocatedBytes) / seconds
+		s.PromotionRate = float64(s.PromotedBytes) / seconds
+	}
+	s.LiveSetGrowthRate = l.liveSetSlope()
+	return s
+}
+
+// span returns the uptimes of the first and the last events.
+func (l *Log) span() (first, last time.Duration) {
+	set := false
+	visit := func(t time.Duration) {
+		if !set || t < first {
+			first = t
+		}
+		if !set || t > last {
+			last = t
+		}
+		set = true
+	}
+	for _, p := range l.Pauses {
+		visit(p.Uptime - p.Duration)
+	}
+	for _, c := range l.Collections {
+		visit(c.Uptime)
+	}
+	return first, last
+}
+
+// liveSetSlope returns the least-squares slope of the heap occupancy after
+// the collections over time, in bytes per second.
+func (l *Log) liveSetSlope() float64 {
+	n := float64(len(l.Collections))
+	if n < 2 {
+		return 0
+	}
+	var sumX, sumY, sumXY, sumXX float64
+	for _, c := range l.Collections {
+		x, y := c.Uptime.Seconds(), float64(c.After)
+		sumX += x
+		sumY += y
+		sumXY += x * y
+		sumXX += x * x
+	}
+	denom := n*sumXX - sumX*sumX
+	if denom == 0 {
+		return 0
+	}
+	return (n*sumXY - sumX*sumY) / denom
+}
+
+// percentile returns the p-th percentile of sorted durations, by the
+// nearest-rank method.
+func percentile(sorted []time.Duration, p int) time.Duration {
+	rank := (p*len(sorted) + 99) / 100
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
+
+// PauseHistogram returns the number of pauses by duration.
+func (l *Log) PauseHistogram() []HistogramBucket {
+	buckets := make([]HistogramBucket, len(pauseBuckets)+1)
+	for i, bound := range pauseBuckets {
+		buckets[i].UpperBound = bound
+	}
+	for _, p := range l.Pauses {
+		i := sort.Search(len(pauseBuckets), func(i int) bool { return p.Duration <= pauseBuckets[i] })
+		buckets[i].Count++
+	}
+	return buckets
+}
+
+// PauseTypes returns the statistics of each type of pauses, longest total
+// first.
+func (l *Log) PauseTypes() []PauseTypeStats {
+	byType := make(map[string]*PauseTypeStats)
+	for _, p := range l.Pauses {
+		s, ok := byType[p.Type]
+		if !ok {
+			s = &PauseTypeStats{Type: p.Type}
+			byType[p.Type] = s
+		}
+		s.Count++
+		s.Total += p.Duration
+		s.Max = max(s.Max, p.Duration)
+	}
+
+	result := make([]PauseTypeStats, 0, len(byType))
+	for _, s := range byType {
+		result = append(result, *s)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Total != result[j].Total {
+			return result[i].Total > result[j].Total
+		}
+		return result[i].Type < result[j].Type
+	})
+	return result
+}
+
+// Causes returns the number of pauses of each cause.
+func (l *Log) Causes() map[string]int {
+	causes := make(map[string]int)
+	for _, p := range l.Pauses {
+		if p.Cause != "" {
+			causes[p.Cause]++
+		}
+	}
+	return causes
+}
+
+// Correlate places a heap dump taken at t in the log. It returns false if
+// the start time of the JVM is not known, i.e. lines are not decorated with
+// both the time and the uptime.
+func (l *Log) Correlate(t time.Time) (DumpCorrelation, bool) {
+	if l.JVMStart.IsZero() {
+		return DumpCorrelation{}, false
+	}
+
+	c := DumpCorrelation{Uptime: t.Sub(l.JVMStart)}
+	first, last := l.span()
+	c.InLog = c.Uptime >= first && c.Uptime <= last
+	for i := range l.Collections {
+		col := &l.Collections[i]
+		if col.Uptime <= c.Uptime {
+			c.Preceding = col
+		} else if c.Next == nil {
+			c.Next = col
+		}
+	}
+	return c, true
+}
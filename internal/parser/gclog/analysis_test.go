@@ -0,0 +1,88 @@
+package gclog
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLog_Summarize(t *testing.T) {
+	s := parse(t, g1Log).Summarize()
+
+	// From the start of the first pause to the full GC
+	assert.Equal(t, 5004*time.Millisecond, s.Duration)
+	assert.Equal(t, 5, s.PauseCount)
+	assert.Equal(t, 131500*time.Microsecond, s.TotalPause)
+	assert.Equal(t, 120*time.Millisecond, s.MaxPause)
+	assert.Equal(t, 4*time.Millisecond, s.P50)
+	assert.Equal(t, 120*time.Millisecond, s.P99)
+	assert.InDelta(t, 97.37, s.Throughput, 0.01)
+
+	// 44M-4M, 9M-8M, 30M-9M
+	assert.Equal(t, int64(62<<20), s.AllocatedBytes)
+	assert.InDelta(t, float64(62<<20)/5.004, s.AllocationRate, 1)
+	assert.InDelta(t, float64(40<<20)/2, s.PeakAllocationRate, 1)
+
+	// 0->2 and 2->6 old regions of 1M
+	assert.True(t, s.HasPromotion)
+	assert.Equal(t, int64(6<<20), s.PromotedBytes)
+	assert.Greater(t, s.LiveSetGrowthRate, 0.0)
+}
+
+func TestLog_SummarizeWithoutGenerations(t *testing.T) {
+	s := parse(t, zgcLog).Summarize()
+	assert.False(t, s.HasPromotion)
+	assert.Equal(t, int64(200<<20), s.AllocatedBytes)
+}
+
+func TestLog_PauseHistogram(t *testing.T) {
+	buckets := parse(t, g1Log).PauseHistogram()
+	require.Len(t, buckets, len(pauseBuckets)+1)
+
+	counts := make(map[time.Duration]int)
+	for _, b := range buckets {
+		counts[b.UpperBound] += b.Count
+	}
+	assert.Equal(t, 2, counts[time.Millisecond], "1ms and 0.5ms")
+	assert.Equal(t, 1, counts[5*time.Millisecond])
+	assert.Equal(t, 1, counts[10*time.Millisecond])
+	assert.Equal(t, 1, counts[200*time.Millisecond])
+	assert.Equal(t, time.Duration(0), buckets[len(buckets)-1].UpperBound)
+}
+
+func TestLog_PauseTypesAndCauses(t *testing.T) {
+	log := parse(t, g1Log)
+
+	types := log.PauseTypes()
+	require.Len(t, types, 5)
+	assert.Equal(t, PauseTypeStats{Type: "Pause Full", Count: 1, Total: 120 * time.Millisecond, Max: 120 * time.Millisecond}, types[0])
+
+	assert.Equal(t, map[string]int{
+		"G1 Evacuation Pause":     1,
+		"G1 Humongous Allocation": 1,
+		"System.gc()":             1,
+	}, log.Causes())
+}
+
+func TestLog_Correlate(t *testing.T) {
+	log := parse(t, g1Log)
+
+	c, ok := log.Correlate(time.Date(2024, 5, 1, 10, 0, 4, 0, time.UTC))
+	require.True(t, ok)
+	assert.Equal(t, 4*time.Second, c.Uptime)
+	assert.True(t, c.InLog)
+	require.NotNil(t, c.Preceding)
+	assert.Equal(t, "Pause Cleanup", c.Preceding.Type)
+	require.NotNil(t, c.Next)
+	assert.Equal(t, 3, c.Next.GCID)
+
+	c, ok = log.Correlate(time.Date(2024, 5, 1, 11, 0, 0, 0, time.UTC))
+	require.True(t, ok)
+	assert.False(t, c.InLog)
+	assert.Nil(t, c.Next)
+
+	_, ok = parse(t, parallelLog).Correlate(time.Now())
+	assert.False(t, ok, "the JVM start time is not known")
+}
@@ -0,0 +1,403 @@
+// Package gclog parses the unified GC logs of the JVM (-Xlog:gc*, JDK 9 and
+// later) of the G1, ZGC, Shenandoah, Parallel and Serial collectors, and
+// computes pause statistics, allocation and promotion rates and heap
+// occupancy trends.
+package gclog
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// cancelCheckInterval is the number of lines between context checks.
+const cancelCheckInterval = 10000
+
+// Collectors, as reported by Log.Collector.
+const (
+	CollectorG1         = "G1"
+	CollectorZGC        = "ZGC"
+	CollectorShenandoah = "Shenandoah"
+	CollectorParallel   = "Parallel"
+	CollectorSerial     = "Serial"
+)
+
+// ErrNoEvents is returned when the input holds no GC event.
+var ErrNoEvents = errors.New("no GC events in log")
+
+// Log holds the GC events of a GC log.
+type Log struct {
+	// Collector is the garbage collector of the JVM, empty if not known
+	Collector string
+	// JVMStart is the start time of the JVM, known if lines are decorated
+	// with both the time and the uptime
+	JVMStart time.Time
+
+	Pauses      []Pause
+	Collections []Collection
+}
+
+// Pause is a stop-the-world pause.
+type Pause struct {
+	GCID int
+	// Uptime is the uptime of the JVM at the end of the pause
+	Uptime   time.Duration
+	Type     string // e.g. "Pause Young (Normal)"
+	Cause    string // e.g. "G1 Evacuation Pause", empty if not logged
+	Duration time.Duration
+}
+
+// Collection is the heap occupancy before and after a collection, which is
+// a pause for most collectors and a concurrent cycle for ZGC.
+type Collection struct {
+	GCID     int
+	Uptime   time.Duration
+	Type     string
+	Before   int64 // bytes
+	After    int64
+	Capacity int64 // 0 if not logged
+
+	// OldBefore and OldAfter are the occupancy of the old generation,
+	// known if HasOld is set
+	OldBefore int64
+	OldAfter  int64
+	HasOld    bool
+}
+
+// Young reports whether the collection is a young collection.
+func (c *Collection) Young() bool {
+	return strings.HasPrefix(c.Type, "Pause Young") || strings.HasPrefix(c.Type, "Minor Collection")
+}
+
+// Full reports whether the collection is a full collection.
+func (c *Collection) Full() bool {
+	return strings.HasPrefix(c.Type, "Pause Full")
+}
+
+var (
+	// gcSizesRe matches the summary of a collection, such as "GC(3) Pause
+	// Young (Normal) (G1 Evacuation Pause) 24M->4M(256M) 3.456ms", or of a
+	// ZGC cycle, such as "GC(3) Garbage Collection (Warmup) 20M(2%)->10M(1%)"
+	gcSizesRe = regexp.MustCompile(`GC\((\d+)\) (.+?) (\d+[BKMGT])(?:\(\d+%\))?->(\d+[BKMGT])(?:\(\d+%\))?(?:\((\d+[BKMGT])\))?(?: ([\d.]+)(ms|s))?$`)
+	// gcPauseRe matches a pause without heap sizes, such as the ZGC "GC(3)
+	// Pause Mark Start 0.012ms"
+	gcPauseRe = regexp.MustCompile(`GC\((\d+)\) (?:[A-Za-z]: )?(Pause .+?) ([\d.]+)ms$`)
+	// gcGenerationRe matches the old generation of a collection, such as
+	// "GC(3) ParOldGen: 0K->8K(171008K)" or "GC(3) Old regions: 2->5"
+	gcGenerationRe = regexp.MustCompile(`GC\((\d+)\) (ParOldGen|PSOldGen|Tenured|Old regions): (\d+[BKMGT]?)->(\d+[BKMGT]?)`)
+	// generationRe matches the generation prefix of the generational ZGC
+	// phases, such as "Y: Young Generation"
+	generationRe = regexp.MustCompile(`^[A-Za-z]: `)
+	// regionSizeRe matches the G1 region size, "Heap Region Size: 1M"
+	regionSizeRe = regexp.MustCompile(`(?i)Heap Region Size: (\d+[BKMGT])`)
+)
+
+// Parser parses GC logs.
+type Parser struct{}
+
+// NewParser creates a new GC log parser.
+func NewParser() *Parser {
+	return &Parser{}
+}
+
+// oldGeneration is the occupancy of the old generation around a collection.
+type oldGeneration struct {
+	before, after int64
+	regions       bool // in G1 regions rather than bytes
+}
+
+// Parse parses a unified GC log.
+func (p *Parser) Parse(ctx context.Context, r io.Reader) (*Log, error) {
+	log := &Log{}
+	var (
+		regionSize int64
+		old        = make(map[int]oldGeneration)
+		start      time.Time // time of the first line, for logs without uptime
+	)
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for n := 0; scanner.Scan(); n++ {
+		if n%cancelCheckInterval == 0 {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+		}
+
+		d, msg, ok := parseDecorations(scanner.Text())
+		if !ok {
+			continue
+		}
+		if !d.time.IsZero() {
+			if d.hasUptime && log.JVMStart.IsZero() {
+				log.JVMStart = d.time.Add(-d.uptime)
+			}
+			if !d.hasUptime {
+				if start.IsZero() {
+					start = d.time
+				}
+				d.uptime = d.time.Sub(start)
+			}
+		}
+
+		if log.Collector == "" {
+			log.Collector = detectCollector(msg)
+		}
+		if m := regionSizeRe.FindStringSubmatch(msg); m != nil {
+			regionSize = parseSize(m[1])
+			continue
+		}
+
+		if m := gcGenerationRe.FindStringSubmatch(msg); m != nil && d.is("gc,heap") {
+			id, _ := strconv.Atoi(m[1])
+			if m[2] == "Old regions" {
+				before, _ := strconv.ParseInt(m[3], 10, 64)
+				after, _ := strconv.ParseInt(m[4], 10, 64)
+				old[id] = oldGeneration{before: before, after: after, regions: true}
+			} else {
+				old[id] = oldGeneration{before: parseSize(m[3]), after: parseSize(m[4])}
+			}
+			continue
+		}
+
+		if m := gcSizesRe.FindStringSubmatch(msg); m != nil && d.is("gc") && !generationRe.MatchString(m[2]) {
+			id, _ := strconv.Atoi(m[1])
+			kind := m[2]
+			c := Collection{
+				GCID:     id,
+				Uptime:   d.uptime,
+				Before:   parseSize(m[3]),
+				After:    parseSize(m[4]),
+				Capacity: parseSize(m[5]),
+			}
+			c.Type, _ = splitCause(kind)
+			log.Collections = append(log.Collections, c)
+
+			if strings.HasPrefix(kind, "Pause ") && m[6] != "" && m[7] == "ms" {
+				ms, _ := strconv.ParseFloat(m[6], 64)
+				pauseType, cause := splitCause(kind)
+				log.Pauses = append(log.Pauses, Pause{
+					GCID:     id,
+					Uptime:   d.uptime,
+					Type:     pauseType,
+					Cause:    cause,
+					Duration: time.Duration(ms * float64(time.Millisecond)),
+				})
+			}
+			continue
+		}
+
+		if m := gcPauseRe.FindStringSubmatch(msg); m != nil && d.is("gc", "gc,phases") {
+			id, _ := strconv.Atoi(m[1])
+			ms, _ := strconv.ParseFloat(m[3], 64)
+			pauseType, cause := splitCause(m[2])
+			log.Pauses = append(log.Pauses, Pause{
+				GCID:     id,
+				Uptime:   d.uptime,
+				Type:     pauseType,
+				Cause:    cause,
+				Duration: time.Duration(ms * float64(time.Millisecond)),
+			})
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	// The generations of a collection are logged before its summary
+	seen := make(map[int]bool)
+	for i := range log.Collections {
+		c := &log.Collections[i]
+		g, ok := old[c.GCID]
+		if !ok || seen[c.GCID] {
+			continue
+		}
+		seen[c.GCID] = true
+		if g.regions {
+			if regionSize == 0 {
+				continue
+			}
+			g.before *= regionSize
+			g.after *= regionSize
+		}
+		c.OldBefore, c.OldAfter, c.HasOld = g.before, g.after, true
+	}
+
+	if len(log.Pauses) == 0 && len(log.Collections) == 0 {
+		return nil, ErrNoEvents
+	}
+	return log, nil
+}
+
+// decorations are the decorations of a log line.
+type decorations struct {
+	time      time.Time
+	uptime    time.Duration
+	hasUptime bool
+	tags      string // e.g. "gc,heap", empty if not decorated with tags
+}
+
+// is reports whether the line is of the given tag set, or of unknown tags.
+func (d *decorations) is(tags ...string) bool {
+	if d.tags == "" {
+		return true
+	}
+	for _, t := range tags {
+		if d.tags == t {
+			return true
+		}
+	}
+	return false
+}
+
+// timeLayouts are the layouts of the time decorations: time, utctime.
+var timeLayouts = []string{"2006-01-02T15:04:05.000-0700", "2006-01-02T15:04:05.000Z0700"}
+
+// logLevels are the levels of the level decoration.
+var logLevels = map[string]bool{"trace": true, "debug": true, "info": true, "warning": true, "error": true}
+
+// processIDRe matches the pid and tid decorations, e.g. "1234p".
+var processIDRe = regexp.MustCompile(`^\d+[pt]$`)
+
+// parseDecorations parses the leading [...] decorations of a line, e.g.
+// "[2024-05-01T10:00:00.123+0000][0.456s][info][gc,heap]", and returns the
+// message following them. It returns false for lines without decorations
+// or of tags other than gc ones.
+func parseDecorations(line string) (decorations, string, bool) {
+	var d decorations
+	decorated := false
+	for strings.HasPrefix(line, "[") {
+		end := strings.IndexByte(line, ']')
+		if end < 0 {
+			break
+		}
+		field := strings.TrimSpace(line[1:end])
+		line = line[end+1:]
+		decorated = true
+
+		switch {
+		case logLevels[field], processIDRe.MatchString(field):
+		case parseUptime(field, &d):
+		case strings.Contains(field, "T") && strings.Count(field, "-") >= 2:
+			for _, layout := range timeLayouts {
+				if t, err := time.Parse(layout, field); err == nil {
+					d.time = t
+					break
+				}
+			}
+		default:
+			d.tags = field
+		}
+	}
+	if !decorated || (d.tags != "" && d.tags != "gc" && !strings.HasPrefix(d.tags, "gc,")) {
+		return d, "", false
+	}
+	return d, strings.TrimSpace(line), true
+}
+
+// parseUptime parses an uptime decoration: uptime "0.456s", uptimemillis
+// "456ms" or uptimenanos "456000000ns".
+func parseUptime(field string, d *decorations) bool {
+	units := []struct {
+		suffix string
+		unit   float64
+	}{{"ns", 1}, {"ms", float64(time.Millisecond)}, {"s", float64(time.Second)}}
+	for _, u := range units {
+		if !strings.HasSuffix(field, u.suffix) {
+			continue
+		}
+		v, err := strconv.ParseFloat(strings.Replace(field[:len(field)-len(u.suffix)], ",", ".", 1), 64)
+		if err != nil {
+			return false
+		}
+		d.uptime = time.Duration(v * u.unit)
+		d.hasUptime = true
+		return true
+	}
+	return false
+}
+
+// splitCause splits the type of a pause or collection and its cause, the
+// last parenthesized part, e.g. "Pause Young (Normal) (G1 Evacuation Pause)"
+// into "Pause Young (Normal)" and "G1 Evacuation Pause". A lone G1 kind of
+// young pause, e.g. "(Mixed)", is part of the type.
+func splitCause(kind string) (string, string) {
+	if !strings.HasSuffix(kind, ")") {
+		return kind, ""
+	}
+	depth := 0
+	for i := len(kind) - 1; i >= 0; i-- {
+		switch kind[i] {
+		case ')':
+			depth++
+		case '(':
+			depth--
+			if depth == 0 {
+				rest := strings.TrimSpace(kind[:i])
+				cause := kind[i+1 : len(kind)-1]
+				if strings.HasSuffix(rest, ")") || !g1Qualifiers[cause] {
+					return rest, cause
+				}
+				return kind, ""
+			}
+		}
+	}
+	return kind, ""
+}
+
+// g1Qualifiers are the G1 kinds of young pauses, logged before the cause.
+var g1Qualifiers = map[string]bool{
+	"Normal":           true,
+	"Concurrent Start": true,
+	"Prepare Mixed":    true,
+	"Mixed":            true,
+}
+
+// detectCollector returns the collector a log message reports or implies,
+// or "".
+func detectCollector(msg string) string {
+	switch {
+	case strings.HasPrefix(msg, "Using G1"), strings.Contains(msg, "(G1 "):
+		return CollectorG1
+	case strings.HasPrefix(msg, "Using The Z Garbage Collector"), strings.Contains(msg, "Garbage Collection ("):
+		return CollectorZGC
+	case strings.HasPrefix(msg, "Using Shenandoah"), strings.Contains(msg, "Pause Init Mark"):
+		return CollectorShenandoah
+	case strings.HasPrefix(msg, "Using Parallel"), strings.Contains(msg, "PSYoungGen"):
+		return CollectorParallel
+	case strings.HasPrefix(msg, "Using Serial"), strings.Contains(msg, "DefNew"):
+		return CollectorSerial
+	}
+	return ""
+}
+
+// parseSize parses a size of the GC log, e.g. "24M", in bytes. Sizes
+// without a unit are in bytes.
+func parseSize(s string) int64 {
+	if s == "" {
+		return 0
+	}
+	shift := 0
+	switch s[len(s)-1] {
+	case 'B':
+		s = s[:len(s)-1]
+	case 'K':
+		shift, s = 10, s[:len(s)-1]
+	case 'M':
+		shift, s = 20, s[:len(s)-1]
+	case 'G':
+		shift, s = 30, s[:len(s)-1]
+	case 'T':
+		shift, s = 40, s[:len(s)-1]
+	}
+	v, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return 0
+	}
+	return v << shift
+}
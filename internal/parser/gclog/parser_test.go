@@ -0,0 +1,157 @@
+package gclog
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// g1Log is a G1 log of -Xlog:gc*:file:time,uptime,level,tags with 1M regions.
+const g1Log = `[2024-05-01T10:00:00.010+0000][0.010s][info][gc,init] Version: 17.0.2+8 (release)
+[2024-05-01T10:00:00.010+0000][0.010s][info][gc] Using G1
+[2024-05-01T10:00:00.011+0000][0.011s][info][gc,init] Heap Region Size: 1M
+[2024-05-01T10:00:01.000+0000][1.000s][info][gc,start] GC(0) Pause Young (Normal) (G1 Evacuation Pause)
+[2024-05-01T10:00:01.000+0000][1.000s][info][gc,heap] GC(0) Eden regions: 24->0(20)
+[2024-05-01T10:00:01.000+0000][1.000s][info][gc,heap] GC(0) Old regions: 0->2
+[2024-05-01T10:00:01.000+0000][1.000s][info][gc,heap] GC(0) Humongous regions: 0->0
+[2024-05-01T10:00:01.000+0000][1.000s][info][gc] GC(0) Pause Young (Normal) (G1 Evacuation Pause) 24M->4M(256M) 4.000ms
+[2024-05-01T10:00:01.000+0000][1.000s][info][gc,cpu] GC(0) User=0.01s Sys=0.00s Real=0.00s
+[2024-05-01T10:00:03.000+0000][3.000s][info][gc,heap] GC(1) Old regions: 2->6
+[2024-05-01T10:00:03.000+0000][3.000s][info][gc] GC(1) Pause Young (Concurrent Start) (G1 Humongous Allocation) 44M->8M(256M) 6.000ms
+[2024-05-01T10:00:03.100+0000][3.100s][info][gc] GC(2) Concurrent Mark Cycle
+[2024-05-01T10:00:03.200+0000][3.200s][info][gc] GC(2) Pause Remark 9M->9M(256M) 1.000ms
+[2024-05-01T10:00:03.300+0000][3.300s][info][gc] GC(2) Pause Cleanup 9M->9M(256M) 0.500ms
+[2024-05-01T10:00:03.400+0000][3.400s][info][gc] GC(2) Concurrent Mark Cycle 300.000ms
+[2024-05-01T10:00:06.000+0000][6.000s][info][gc] GC(3) Pause Full (System.gc()) 30M->10M(256M) 120.000ms
+[2024-05-01T10:00:06.000+0000][6.000s][info][safepoint] Safepoint "G1CollectFull", Time since last: 1 ns, Reaching safepoint: 1 ns, At safepoint: 120000000 ns, Total: 120000001 ns
+`
+
+// parallelLog is a Parallel log of the default decorations.
+const parallelLog = `[0.004s][info][gc] Using Parallel
+[0.500s][info][gc,start    ] GC(0) Pause Young (Allocation Failure)
+[0.500s][info][gc,heap     ] GC(0) PSYoungGen: 65536K->10240K(76288K)
+[0.500s][info][gc,heap     ] GC(0) ParOldGen: 0K->8192K(175104K)
+[0.500s][info][gc          ] GC(0) Pause Young (Allocation Failure) 64M->18M(245M) 5.500ms
+[1.500s][info][gc,heap     ] GC(1) PSYoungGen: 75776K->10240K(76288K)
+[1.500s][info][gc,heap     ] GC(1) ParOldGen: 8192K->16384K(175104K)
+[1.500s][info][gc          ] GC(1) Pause Young (Allocation Failure) 82M->26M(245M) 7.500ms
+`
+
+// zgcLog is a ZGC log of JDK 17 and of generational ZGC.
+const zgcLog = `[0.010s][info][gc,init] Using The Z Garbage Collector
+[1.000s][info][gc,phases] GC(0) Pause Mark Start 0.010ms
+[1.050s][info][gc,phases] GC(0) Pause Mark End 0.020ms
+[1.080s][info][gc,phases] GC(0) Pause Relocate Start 0.015ms
+[1.100s][info][gc       ] GC(0) Garbage Collection (Warmup) 200M(10%)->120M(6%)
+[2.000s][info][gc,phases] GC(1) Y: Pause Mark Start 0.012ms
+[2.050s][info][gc,phases] GC(1) Y: Young Generation 320M(16%)->150M(7%) 0.050s
+[2.100s][info][gc       ] GC(1) Minor Collection (Allocation Rate) 320M(16%)->150M(7%) 0.100s
+`
+
+func parse(t *testing.T, text string) *Log {
+	t.Helper()
+	log, err := NewParser().Parse(context.Background(), strings.NewReader(text))
+	require.NoError(t, err)
+	return log
+}
+
+func TestParser_ParseG1(t *testing.T) {
+	log := parse(t, g1Log)
+	assert.Equal(t, CollectorG1, log.Collector)
+	assert.Equal(t, time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC), log.JVMStart.UTC())
+
+	require.Len(t, log.Pauses, 5)
+	assert.Equal(t, Pause{
+		GCID:     0,
+		Uptime:   time.Second,
+		Type:     "Pause Young (Normal)",
+		Cause:    "G1 Evacuation Pause",
+		Duration: 4 * time.Millisecond,
+	}, log.Pauses[0])
+	assert.Equal(t, "Pause Young (Concurrent Start)", log.Pauses[1].Type)
+	assert.Equal(t, "Pause Remark", log.Pauses[2].Type)
+	assert.Empty(t, log.Pauses[2].Cause)
+	assert.Equal(t, "Pause Full", log.Pauses[4].Type)
+	assert.Equal(t, "System.gc()", log.Pauses[4].Cause)
+
+	require.Len(t, log.Collections, 5)
+	young := log.Collections[0]
+	assert.Equal(t, int64(24<<20), young.Before)
+	assert.Equal(t, int64(4<<20), young.After)
+	assert.Equal(t, int64(256<<20), young.Capacity)
+	assert.True(t, young.Young())
+	assert.True(t, young.HasOld)
+	assert.Equal(t, int64(0), young.OldBefore)
+	assert.Equal(t, int64(2<<20), young.OldAfter)
+	assert.False(t, log.Collections[2].HasOld, "the remark has no generation sizes")
+	assert.True(t, log.Collections[4].Full())
+}
+
+func TestParser_ParseParallel(t *testing.T) {
+	log := parse(t, parallelLog)
+	assert.Equal(t, CollectorParallel, log.Collector)
+	assert.True(t, log.JVMStart.IsZero(), "lines are not decorated with the time")
+
+	require.Len(t, log.Collections, 2)
+	assert.Equal(t, 1500*time.Millisecond, log.Collections[1].Uptime)
+	assert.Equal(t, int64(8<<20), log.Collections[1].OldBefore)
+	assert.Equal(t, int64(16<<20), log.Collections[1].OldAfter)
+	require.Len(t, log.Pauses, 2)
+	assert.Equal(t, "Pause Young", log.Pauses[0].Type)
+	assert.Equal(t, "Allocation Failure", log.Pauses[0].Cause)
+}
+
+func TestParser_ParseZGC(t *testing.T) {
+	log := parse(t, zgcLog)
+	assert.Equal(t, CollectorZGC, log.Collector)
+
+	require.Len(t, log.Pauses, 4)
+	assert.Equal(t, "Pause Mark Start", log.Pauses[0].Type)
+	assert.Equal(t, 10*time.Microsecond, log.Pauses[0].Duration)
+
+	require.Len(t, log.Collections, 2, "generation phases are not collections")
+	assert.Equal(t, "Garbage Collection", log.Collections[0].Type)
+	assert.Equal(t, int64(200<<20), log.Collections[0].Before)
+	assert.Equal(t, int64(0), log.Collections[0].Capacity)
+	assert.True(t, log.Collections[1].Young())
+}
+
+func TestParser_ParseWithoutTags(t *testing.T) {
+	log := parse(t, "[0.500s] GC(0) Pause Young (Allocation Failure) 64M->18M(245M) 5.500ms\n")
+	require.Len(t, log.Pauses, 1)
+	assert.Equal(t, 5500*time.Microsecond, log.Pauses[0].Duration)
+}
+
+func TestParser_ParseNoEvents(t *testing.T) {
+	_, err := NewParser().Parse(context.Background(), strings.NewReader("[0.004s][info][gc] Using G1\nnot a GC log\n"))
+	assert.ErrorIs(t, err, ErrNoEvents)
+}
+
+func TestSplitCause(t *testing.T) {
+	tests := []struct {
+		kind, wantType, wantCause string
+	}{
+		{"Pause Young (Normal) (G1 Evacuation Pause)", "Pause Young (Normal)", "G1 Evacuation Pause"},
+		{"Pause Young (Mixed)", "Pause Young (Mixed)", ""},
+		{"Pause Young (Allocation Failure)", "Pause Young", "Allocation Failure"},
+		{"Pause Full (System.gc())", "Pause Full", "System.gc()"},
+		{"Pause Remark", "Pause Remark", ""},
+	}
+	for _, tt := range tests {
+		gotType, gotCause := splitCause(tt.kind)
+		assert.Equal(t, tt.wantType, gotType, tt.kind)
+		assert.Equal(t, tt.wantCause, gotCause, tt.kind)
+	}
+}
+
+func TestParseSize(t *testing.T) {
+	assert.Equal(t, int64(512), parseSize("512B"))
+	assert.Equal(t, int64(65536*1024), parseSize("65536K"))
+	assert.Equal(t, int64(2<<30), parseSize("2G"))
+	assert.Equal(t, int64(12), parseSize("12"))
+	assert.Equal(t, int64(0), parseSize("xM"))
+}
@@ -0,0 +1,115 @@
+package hprof
+
+import (
+	"sort"
+)
+
+// Flags of ALLOC_SITES records.
+const (
+	// AllocSitesIncremental marks sites allocated since the previous record,
+	// rather than since the start of the JVM
+	AllocSitesIncremental uint16 = 0x1
+	// AllocSitesByAllocation marks sites sorted by allocated rather than live bytes
+	AllocSitesByAllocation uint16 = 0x2
+	// AllocSitesForceGC marks records written after forcing a garbage collection
+	AllocSitesForceGC uint16 = 0x4
+)
+
+// AllocationSites is the allocation site table of an ALLOC_SITES record,
+// written by the legacy hprof agent (-agentlib:hprof=heap=sites).
+type AllocationSites struct {
+	Flags               uint16  `json:"flags"`
+	CutoffRatio         float32 `json:"cutoff_ratio"`
+	TotalLiveBytes      int64   `json:"total_live_bytes"`
+	TotalLiveInstances  int64   `json:"total_live_instances"`
+	TotalAllocBytes     int64   `json:"total_alloc_bytes"`
+	TotalAllocInstances int64   `json:"total_alloc_instances"`
+	// Sites are sorted by live bytes descending
+	Sites []*AllocationSite `json:"sites"`
+}
+
+// AllocationSite is the objects of one class allocated at one stack trace.
+type AllocationSite struct {
+	ClassID        uint64 `json:"class_id,omitempty"`
+	ClassName      string `json:"class_name"`
+	LiveBytes      int64  `json:"live_bytes"`
+	LiveInstances  int64  `json:"live_instances"`
+	AllocBytes     int64  `json:"alloc_bytes"`
+	AllocInstances int64  `json:"alloc_instances"`
+	// StackTraceSerial identifies the allocating stack trace, Frames resolves it
+	StackTraceSerial uint32         `json:"stack_trace_serial"`
+	Frames           []*ThreadFrame `json:"frames,omitempty"`
+}
+
+// CPUSamples is the CPU sampling profile of the CPU_SAMPLES records,
+// written by the legacy hprof agent (-agentlib:hprof=cpu=samples).
+type CPUSamples struct {
+	TotalSamples int64 `json:"total_samples"`
+	// Traces are sorted by samples descending
+	Traces []*CPUSampleTrace `json:"traces"`
+}
+
+// CPUSampleTrace is the number of samples of one stack trace.
+type CPUSampleTrace struct {
+	Samples          int64          `json:"samples"`
+	StackTraceSerial uint32         `json:"stack_trace_serial"`
+	Frames           []*ThreadFrame `json:"frames,omitempty"`
+}
+
+// allocSitesRecord is an ALLOC_SITES record with unresolved class serials.
+type allocSitesRecord struct {
+	sites *AllocationSites
+	// classSerials holds the class serial of each site
+	classSerials []uint32
+	// arrayTypes holds the array indicator of each site: 0 for instances,
+	// else the basic type of the elements
+	arrayTypes []BasicType
+}
+
+// cpuSamplesRecord accumulates CPU_SAMPLES records.
+type cpuSamplesRecord struct {
+	total   int64
+	samples map[uint32]int64 // by stack trace serial
+}
+
+// resolveAllocationSites resolves the classes and stack traces of the last
+// ALLOC_SITES record.
+func resolveAllocationSites(rec *allocSitesRecord, stacks *threadStackCollector, r threadStackResolver) *AllocationSites {
+	sites := rec.sites
+	for i, site := range sites.Sites {
+		if classID, ok := stacks.classSerials[rec.classSerials[i]]; ok {
+			site.ClassID = classID
+			site.ClassName = r.className(classID)
+		}
+		if site.ClassName == "" && rec.arrayTypes[i] != 0 && rec.arrayTypes[i] != TypeObject {
+			site.ClassName = primitiveArrayTypeName(rec.arrayTypes[i])
+		}
+		if trace, ok := stacks.traces[site.StackTraceSerial]; ok {
+			site.Frames = stacks.resolveFrames(trace, r)
+		}
+	}
+	sort.SliceStable(sites.Sites, func(i, j int) bool {
+		return sites.Sites[i].LiveBytes > sites.Sites[j].LiveBytes
+	})
+	return sites
+}
+
+// resolveCPUSamples resolves the stack traces of the CPU_SAMPLES records.
+func resolveCPUSamples(rec *cpuSamplesRecord, stacks *threadStackCollector, r threadStackResolver) *CPUSamples {
+	samples := &CPUSamples{TotalSamples: rec.total, Traces: make([]*CPUSampleTrace, 0, len(rec.samples))}
+	for serial, count := range rec.samples {
+		trace := &CPUSampleTrace{Samples: count, StackTraceSerial: serial}
+		if st, ok := stacks.traces[serial]; ok {
+			trace.Frames = stacks.resolveFrames(st, r)
+		}
+		samples.Traces = append(samples.Traces, trace)
+	}
+	sort.Slice(samples.Traces, func(i, j int) bool {
+		a, b := samples.Traces[i], samples.Traces[j]
+		if a.Samples != b.Samples {
+			return a.Samples > b.Samples
+		}
+		return a.StackTraceSerial < b.StackTraceSerial
+	})
+	return samples
+}
@@ -0,0 +1,104 @@
+package hprof
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testAllocSite is one site of an ALLOC_SITES record.
+type testAllocSite struct {
+	arrayType                                    BasicType
+	classSerial, traceSerial                     uint32
+	liveBytes, liveInstances, allocBytes, allocs uint32
+}
+
+// allocSites emits an ALLOC_SITES record.
+func allocSites(b *testHprofBuilder, flags uint16, sites ...testAllocSite) {
+	var body bytes.Buffer
+	binary.Write(&body, binary.BigEndian, flags)
+	binary.Write(&body, binary.BigEndian, math.Float32bits(0.5))
+	binary.Write(&body, binary.BigEndian, []uint32{1000, 10})
+	binary.Write(&body, binary.BigEndian, []uint64{5000, 50})
+	binary.Write(&body, binary.BigEndian, uint32(len(sites)))
+	for _, s := range sites {
+		body.WriteByte(byte(s.arrayType))
+		binary.Write(&body, binary.BigEndian, []uint32{s.classSerial, s.traceSerial, s.liveBytes, s.liveInstances, s.allocBytes, s.allocs})
+	}
+	b.record(TagAllocSites, body.Bytes())
+}
+
+// cpuSamples emits a CPU_SAMPLES record of (samples, trace serial) pairs.
+func cpuSamples(b *testHprofBuilder, total uint32, traces ...uint32) {
+	var body bytes.Buffer
+	binary.Write(&body, binary.BigEndian, total)
+	binary.Write(&body, binary.BigEndian, uint32(len(traces)/2))
+	binary.Write(&body, binary.BigEndian, traces)
+	b.record(TagCPUSamples, body.Bytes())
+}
+
+func TestParser_AllocationSites(t *testing.T) {
+	b := newTestHprofBuilder()
+	b.loadClassSerial(1, 0x10, "com/example/Order")
+	b.loadClassSerial(2, 0x20, "com/example/OrderService")
+	b.stackFrame(0xF1, "create", "()V", "OrderService.java", 2, 17)
+	b.stackTrace(5, 0, 0xF1)
+
+	// The first table is replaced by the second
+	allocSites(b, 0, testAllocSite{classSerial: 1, traceSerial: 5, liveBytes: 1})
+	allocSites(b, AllocSitesForceGC,
+		testAllocSite{classSerial: 1, traceSerial: 5, liveBytes: 240, liveInstances: 10, allocBytes: 480, allocs: 20},
+		testAllocSite{arrayType: TypeByte, classSerial: 99, traceSerial: 6, liveBytes: 760, liveInstances: 1, allocBytes: 760, allocs: 1},
+	)
+	cpuSamples(b, 10, 3, 5, 1, 6)
+	cpuSamples(b, 4, 4, 6)
+
+	b.classDump(0x10, 0, 0)
+	b.instanceDump(0x1000, 0x10, nil)
+	b.rootJNIGlobal(0x1000)
+
+	result, err := NewParser(nil).Parse(context.Background(), bytes.NewReader(b.bytes()))
+	require.NoError(t, err)
+
+	sites := result.AllocationSites
+	require.NotNil(t, sites)
+	assert.Equal(t, AllocSitesForceGC, sites.Flags)
+	assert.Equal(t, float32(0.5), sites.CutoffRatio)
+	assert.Equal(t, int64(1000), sites.TotalLiveBytes)
+	assert.Equal(t, int64(50), sites.TotalAllocInstances)
+	require.Len(t, sites.Sites, 2)
+
+	// Sorted by live bytes; a class without LOAD_CLASS is named by its array type
+	assert.Equal(t, "byte[]", sites.Sites[0].ClassName)
+	assert.Empty(t, sites.Sites[0].Frames)
+	order := sites.Sites[1]
+	assert.Equal(t, "com.example.Order", order.ClassName)
+	assert.Equal(t, uint64(0x10), order.ClassID)
+	assert.Equal(t, int64(10), order.LiveInstances)
+	assert.Equal(t, int64(480), order.AllocBytes)
+	require.Len(t, order.Frames, 1)
+	assert.Equal(t, "com.example.OrderService", order.Frames[0].ClassName)
+	assert.Equal(t, "create", order.Frames[0].MethodName)
+	assert.Equal(t, int32(17), order.Frames[0].LineNumber)
+
+	samples := result.CPUSamples
+	require.NotNil(t, samples)
+	assert.Equal(t, int64(14), samples.TotalSamples)
+	require.Len(t, samples.Traces, 2)
+	assert.Equal(t, uint32(6), samples.Traces[0].StackTraceSerial)
+	assert.Equal(t, int64(5), samples.Traces[0].Samples)
+	assert.Equal(t, int64(3), samples.Traces[1].Samples)
+	require.Len(t, samples.Traces[1].Frames, 1)
+	assert.Equal(t, "create", samples.Traces[1].Frames[0].MethodName)
+}
+
+func TestParser_NoAllocationSites(t *testing.T) {
+	result := runTestJob(t, motifTestDump())
+	assert.Nil(t, result.AllocationSites)
+	assert.Nil(t, result.CPUSamples)
+}
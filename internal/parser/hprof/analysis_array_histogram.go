@@ -0,0 +1,145 @@
+// Package hprof provides parsing functionality for Java HPROF heap dump files.
+package hprof
+
+import (
+	"fmt"
+	"sort"
+)
+
+// arrayLengthBucketBounds defines the upper bound (inclusive) of each length bucket.
+// Buckets grow by powers of ten: 0, 1-10, 11-100, ... with a final open-ended bucket.
+var arrayLengthBucketBounds = []int{0, 10, 100, 1000, 10000, 100000, 1000000}
+
+// ArrayLengthBucket holds the count and cumulative size of arrays within a length range.
+type ArrayLengthBucket struct {
+	Label     string `json:"label"`
+	MinLength int    `json:"min_length"`
+	MaxLength int    `json:"max_length"` // -1 means unbounded
+	Count     int64  `json:"count"`
+	TotalSize int64  `json:"total_size"`
+}
+
+// ArrayLengthHistogram holds the length distribution for a single array class.
+// It helps spot millions of empty arrays or a handful of giant ones.
+type ArrayLengthHistogram struct {
+	ClassName  string               `json:"class_name"`
+	TotalCount int64                `json:"total_count"`
+	TotalSize  int64                `json:"total_size"`
+	EmptyCount int64                `json:"empty_count"`
+	EmptySize  int64                `json:"empty_size"`
+	MaxLength  int                  `json:"max_length"`
+	Buckets    []*ArrayLengthBucket `json:"buckets"`
+}
+
+// newArrayLengthHistogram creates an empty histogram with all buckets initialized.
+func newArrayLengthHistogram(className string) *ArrayLengthHistogram {
+	h := &ArrayLengthHistogram{
+		ClassName: className,
+		Buckets:   make([]*ArrayLengthBucket, 0, len(arrayLengthBucketBounds)+1),
+	}
+	minLen := 0
+	for _, maxLen := range arrayLengthBucketBounds {
+		h.Buckets = append(h.Buckets, &ArrayLengthBucket{
+			Label:     arrayBucketLabel(minLen, maxLen),
+			MinLength: minLen,
+			MaxLength: maxLen,
+		})
+		minLen = maxLen + 1
+	}
+	h.Buckets = append(h.Buckets, &ArrayLengthBucket{
+		Label:     arrayBucketLabel(minLen, -1),
+		MinLength: minLen,
+		MaxLength: -1,
+	})
+	return h
+}
+
+// add records a single array of the given length and shallow size.
+func (h *ArrayLengthHistogram) add(length int, size int64) {
+	h.TotalCount++
+	h.TotalSize += size
+	if length == 0 {
+		h.EmptyCount++
+		h.EmptySize += size
+	}
+	if length > h.MaxLength {
+		h.MaxLength = length
+	}
+	bucket := h.Buckets[arrayLengthBucketIndex(length)]
+	bucket.Count++
+	bucket.TotalSize += size
+}
+
+// arrayLengthBucketIndex returns the bucket index for an array length.
+func arrayLengthBucketIndex(length int) int {
+	for i, maxLen := range arrayLengthBucketBounds {
+		if length <= maxLen {
+			return i
+		}
+	}
+	return len(arrayLengthBucketBounds)
+}
+
+// arrayBucketLabel formats a human-readable bucket label.
+func arrayBucketLabel(minLen, maxLen int) string {
+	switch {
+	case maxLen < 0:
+		return fmt.Sprintf(">%d", minLen-1)
+	case minLen == maxLen:
+		return fmt.Sprintf("%d", minLen)
+	default:
+		return fmt.Sprintf("%d-%d", minLen, maxLen)
+	}
+}
+
+// ArrayHistogramCollector accumulates array length histograms during parsing.
+type ArrayHistogramCollector struct {
+	byClass map[string]*ArrayLengthHistogram
+}
+
+// NewArrayHistogramCollector creates a new ArrayHistogramCollector.
+func NewArrayHistogramCollector() *ArrayHistogramCollector {
+	return &ArrayHistogramCollector{
+		byClass: make(map[string]*ArrayLengthHistogram),
+	}
+}
+
+// Add records an array instance of the given class, length and shallow size.
+func (c *ArrayHistogramCollector) Add(className string, length int, size int64) {
+	h, ok := c.byClass[className]
+	if !ok {
+		h = newArrayLengthHistogram(className)
+		c.byClass[className] = h
+	}
+	h.add(length, size)
+}
+
+// Histograms returns all histograms sorted by total size descending.
+func (c *ArrayHistogramCollector) Histograms() []*ArrayLengthHistogram {
+	result := make([]*ArrayLengthHistogram, 0, len(c.byClass))
+	for _, h := range c.byClass {
+		result = append(result, h)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].TotalSize != result[j].TotalSize {
+			return result[i].TotalSize > result[j].TotalSize
+		}
+		return result[i].ClassName < result[j].ClassName
+	})
+	return result
+}
+
+// ArrayStats summarizes the collected histograms into the aggregate ArrayStats view.
+func (c *ArrayHistogramCollector) ArrayStats() *ArrayStats {
+	stats := &ArrayStats{
+		ByType: make(map[string]int64, len(c.byClass)),
+	}
+	for name, h := range c.byClass {
+		stats.TotalArrays += h.TotalCount
+		stats.TotalSize += h.TotalSize
+		stats.EmptyArrays += h.EmptyCount
+		stats.EmptyArraysWaste += h.EmptySize
+		stats.ByType[name] = h.TotalSize
+	}
+	return stats
+}
@@ -0,0 +1,84 @@
+package hprof
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestArrayLengthBucketIndex(t *testing.T) {
+	tests := []struct {
+		length   int
+		expected int
+	}{
+		{0, 0},
+		{1, 1},
+		{10, 1},
+		{11, 2},
+		{100, 2},
+		{1000, 3},
+		{1000000, 6},
+		{1000001, 7},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.expected, arrayLengthBucketIndex(tt.length), "length %d", tt.length)
+	}
+}
+
+func TestArrayHistogramCollector(t *testing.T) {
+	c := NewArrayHistogramCollector()
+	c.Add("byte[]", 0, 16)
+	c.Add("byte[]", 0, 16)
+	c.Add("byte[]", 5000, 5016)
+	c.Add("java.lang.Object[]", 3, 40)
+
+	histograms := c.Histograms()
+	require.Len(t, histograms, 2)
+
+	h := histograms[0]
+	assert.Equal(t, "byte[]", h.ClassName)
+	assert.Equal(t, int64(3), h.TotalCount)
+	assert.Equal(t, int64(2), h.EmptyCount)
+	assert.Equal(t, int64(32), h.EmptySize)
+	assert.Equal(t, 5000, h.MaxLength)
+	assert.Equal(t, "0", h.Buckets[0].Label)
+	assert.Equal(t, int64(2), h.Buckets[0].Count)
+	assert.Equal(t, "1001-10000", h.Buckets[4].Label)
+	assert.Equal(t, int64(1), h.Buckets[4].Count)
+	assert.Equal(t, ">1000000", h.Buckets[len(h.Buckets)-1].Label)
+
+	stats := c.ArrayStats()
+	assert.Equal(t, int64(4), stats.TotalArrays)
+	assert.Equal(t, int64(2), stats.EmptyArrays)
+	assert.Equal(t, int64(32), stats.EmptyArraysWaste)
+	assert.Equal(t, int64(5048), stats.ByType["byte[]"])
+}
+
+func TestParser_ArrayLengthHistograms(t *testing.T) {
+	b := newTestHprofBuilder()
+	b.loadClass(0x10, "[Ljava/lang/Object;")
+	b.classDump(0x10, 0, 0)
+	b.objectArrayDump(0x1000, 0x10)
+	b.objectArrayDump(0x1001, 0x10, 0x1000, 0x1000)
+	b.primitiveArrayDump(0x2000, TypeInt, 0, nil)
+	b.primitiveArrayDump(0x2001, TypeInt, 20, nil)
+
+	result, err := NewParser(nil).Parse(context.Background(), bytes.NewReader(b.bytes()))
+	require.NoError(t, err)
+	require.Len(t, result.ArrayLengthHistograms, 2)
+	require.NotNil(t, result.ArrayStats)
+	assert.Equal(t, int64(4), result.ArrayStats.TotalArrays)
+	assert.Equal(t, int64(2), result.ArrayStats.EmptyArrays)
+
+	byName := make(map[string]*ArrayLengthHistogram)
+	for _, h := range result.ArrayLengthHistograms {
+		byName[h.ClassName] = h
+	}
+	require.Contains(t, byName, "int[]")
+	assert.Equal(t, 20, byName["int[]"].MaxLength)
+	assert.Equal(t, int64(1), byName["int[]"].Buckets[2].Count)
+}
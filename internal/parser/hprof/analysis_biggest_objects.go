@@ -13,6 +13,9 @@ type BiggestObjectsBuilder struct {
 	refGraph     *ReferenceGraph
 	classLayouts map[uint64]*ClassFieldLayout
 	strings      map[uint64]string
+	// dominatorRollup replaces JDK objects (arrays, collections, ...) with
+	// their nearest non-JDK dominator in the top-level view.
+	dominatorRollup bool
 }
 
 // shouldFilterTopLevelClass checks if a class should be filtered from top-level Biggest Objects.
@@ -31,6 +34,13 @@ func NewBiggestObjectsBuilder(refGraph *ReferenceGraph, classLayouts map[uint64]
 	}
 }
 
+// SetDominatorRollup enables or disables rolling up JDK objects to their nearest
+// non-JDK dominator. When enabled, the Biggest Objects view shows the business
+// objects owning large byte[]/Object[] instead of the arrays themselves.
+func (b *BiggestObjectsBuilder) SetDominatorRollup(enabled bool) {
+	b.dominatorRollup = enabled
+}
+
 // objectWithSize is a helper struct for sorting objects by size.
 type objectWithSize struct {
 	objectID     uint64
@@ -121,22 +131,33 @@ func (b *BiggestObjectsBuilder) BuildBiggestObjectsFiltered(topN int, sortBy str
 	}
 	heap.Init(h)
 
+	var rollup *dominatorRollup
+	if b.dominatorRollup {
+		rollup = newDominatorRollup(b.refGraph)
+	}
+
 	// Iterate through all objects and maintain top-N in heap
-	for objID := range b.refGraph.objectClass {
+	for objID, classID := range b.refGraph.objectClass {
 		// Only include reachable objects
 		if !b.refGraph.IsObjectReachable(objID) {
 			continue
 		}
 
-		// Filter basic types if requested
-		if filterBasicTypes {
-			classID := b.refGraph.objectClass[objID]
-			className := b.refGraph.GetClassName(classID)
-			if shouldFilterTopLevelClass(className) {
+		className := b.refGraph.GetClassName(classID)
+
+		// Attribute JDK objects to their owner; the owner is visited on its own
+		if rollup != nil && !isRollupOwnerClass(className) {
+			if owner := rollup.ownerOf(objID); owner != 0 {
+				rollup.rolledUp[owner]++
 				continue
 			}
 		}
 
+		// Filter basic types if requested
+		if filterBasicTypes && shouldFilterTopLevelClass(className) {
+			continue
+		}
+
 		obj := objectWithSize{
 			objectID:     objID,
 			shallowSize:  b.refGraph.objectSize[objID],
@@ -175,6 +196,9 @@ func (b *BiggestObjectsBuilder) BuildBiggestObjectsFiltered(topN int, sortBy str
 	for _, obj := range objects {
 		bigObj := b.buildBiggestObject(obj.objectID)
 		if bigObj != nil {
+			if rollup != nil {
+				bigObj.RolledUpObjects = rollup.rolledUp[obj.objectID]
+			}
 			result = append(result, bigObj)
 		}
 	}
@@ -182,6 +206,67 @@ func (b *BiggestObjectsBuilder) BuildBiggestObjectsFiltered(topN int, sortBy str
 	return result
 }
 
+// isRollupOwnerClass reports whether objects of a class may own rolled-up objects.
+// JDK classes, arrays and top-level filtered containers never qualify.
+func isRollupOwnerClass(className string) bool {
+	return className != "" && !filter.IsJDK(className) && !filter.IsPrimitive(className) &&
+		!filter.ShouldFilterTopLevel(className)
+}
+
+// dominatorRollup resolves the nearest non-JDK dominator of objects.
+// Results are memoized along each dominator chain, so resolving every object
+// in the heap is linear in the size of the dominator tree.
+type dominatorRollup struct {
+	refGraph *ReferenceGraph
+	owners   map[uint64]uint64 // objectID -> owner objectID (0 = none)
+	rolledUp map[uint64]int64  // owner objectID -> number of objects rolled up into it
+}
+
+// newDominatorRollup creates a dominatorRollup for a graph with a computed dominator tree.
+func newDominatorRollup(refGraph *ReferenceGraph) *dominatorRollup {
+	return &dominatorRollup{
+		refGraph: refGraph,
+		owners:   make(map[uint64]uint64),
+		rolledUp: make(map[uint64]int64),
+	}
+}
+
+// ownerOf returns the nearest strict dominator of objectID whose class is a rollup owner,
+// or 0 if the object is only dominated by JDK objects and the super root.
+func (r *dominatorRollup) ownerOf(objectID uint64) uint64 {
+	var path []uint64
+	owner := uint64(0)
+	cur := objectID
+	for {
+		dom, ok := r.refGraph.dominators[cur]
+		if !ok || dom == superRootID || dom == 0 {
+			break
+		}
+		if isRollupOwnerClass(r.className(dom)) {
+			owner = dom
+			break
+		}
+		if cached, ok := r.owners[dom]; ok {
+			owner = cached
+			break
+		}
+		path = append(path, dom)
+		cur = dom
+	}
+
+	// Every JDK object on the walked chain shares the same owner
+	r.owners[objectID] = owner
+	for _, id := range path {
+		r.owners[id] = owner
+	}
+	return owner
+}
+
+// className returns the class name of an object.
+func (r *dominatorRollup) className(objectID uint64) string {
+	return r.refGraph.GetClassName(r.refGraph.objectClass[objectID])
+}
+
 // BuildBiggestObjectsByClass builds the list of biggest objects for a specific class.
 // OPTIMIZATION: Uses a min-heap for O(n log k) top-N selection.
 func (b *BiggestObjectsBuilder) BuildBiggestObjectsByClass(className string, topN int, sortBy string) []*BiggestObject {
@@ -279,12 +364,8 @@ func (b *BiggestObjectsBuilder) buildBiggestObject(objectID uint64) *BiggestObje
 		RetainedSize: b.refGraph.GetRetainedSize(objectID),
 	}
 
-	// Extract field information if class layout is available
-	if b.classLayouts != nil {
-		if layout, ok := b.classLayouts[classID]; ok {
-			bigObj.Fields = b.extractFields(objectID, layout)
-		}
-	}
+	// Fields are expanded on demand from the snapshot, see GetObjectFields
+	bigObj.HasChildren = len(b.refGraph.outgoingRefs[objectID]) > 0
 
 	// Add GC root path (limited to 1 path for performance)
 	paths := b.refGraph.FindPathsToGCRoot(objectID, 1, 15)
@@ -295,93 +376,6 @@ func (b *BiggestObjectsBuilder) buildBiggestObject(objectID uint64) *BiggestObje
 	return bigObj
 }
 
-// extractFields extracts field values from an object using its class layout.
-// Also populates ShallowSize and RetainedSize for reference fields.
-// This method now traverses the entire class hierarchy to get all fields including inherited ones.
-func (b *BiggestObjectsBuilder) extractFields(objectID uint64, layout *ClassFieldLayout) []*ObjectField {
-	if layout == nil {
-		return nil
-	}
-
-	// Ensure dominator tree is computed for retained sizes
-	b.refGraph.ComputeDominatorTree()
-
-	var fields []*ObjectField
-
-	// Add static fields first
-	for _, sf := range layout.StaticFields {
-		field := &ObjectField{
-			Name:     sf.Name,
-			Type:     basicTypeToString(sf.Type),
-			IsStatic: true,
-		}
-		if sf.Type == TypeObject && sf.RefID != 0 {
-			field.RefID = sf.RefID
-			if refClassID, ok := b.refGraph.objectClass[sf.RefID]; ok {
-				field.RefClass = b.refGraph.GetClassName(refClassID)
-				field.ShallowSize = b.refGraph.objectSize[sf.RefID]
-				field.RetainedSize = b.refGraph.GetRetainedSize(sf.RefID)
-				field.HasChildren = len(b.refGraph.outgoingRefs[sf.RefID]) > 0
-			}
-		} else {
-			field.Value = sf.Value
-		}
-		fields = append(fields, field)
-	}
-
-	// Add instance fields (from outgoing references)
-	refs := b.refGraph.outgoingRefs[objectID]
-	refByField := make(map[string]ObjectReference)
-	for _, ref := range refs {
-		if ref.FieldName != "" {
-			refByField[ref.FieldName] = ref
-		}
-	}
-
-	// Collect all instance fields from the class hierarchy (including parent classes)
-	allInstanceFields := b.getClassHierarchyFields(layout)
-
-	// Process all instance fields from class hierarchy
-	for _, f := range allInstanceFields {
-		field := &ObjectField{
-			Name: f.Name,
-			Type: basicTypeToString(f.Type),
-		}
-		if f.Type == TypeObject {
-			if ref, ok := refByField[f.Name]; ok {
-				field.RefID = ref.ToObjectID
-				if refClassID, ok := b.refGraph.objectClass[ref.ToObjectID]; ok {
-					field.RefClass = b.refGraph.GetClassName(refClassID)
-					field.ShallowSize = b.refGraph.objectSize[ref.ToObjectID]
-					field.RetainedSize = b.refGraph.GetRetainedSize(ref.ToObjectID)
-					field.HasChildren = len(b.refGraph.outgoingRefs[ref.ToObjectID]) > 0
-				}
-			}
-		}
-		fields = append(fields, field)
-	}
-
-	// If no layout fields, extract from outgoing references directly
-	if len(allInstanceFields) == 0 && len(refs) > 0 {
-		for _, ref := range refs {
-			field := &ObjectField{
-				Name:  ref.FieldName,
-				Type:  "object",
-				RefID: ref.ToObjectID,
-			}
-			if refClassID, ok := b.refGraph.objectClass[ref.ToObjectID]; ok {
-				field.RefClass = b.refGraph.GetClassName(refClassID)
-				field.ShallowSize = b.refGraph.objectSize[ref.ToObjectID]
-				field.RetainedSize = b.refGraph.GetRetainedSize(ref.ToObjectID)
-				field.HasChildren = len(b.refGraph.outgoingRefs[ref.ToObjectID]) > 0
-			}
-			fields = append(fields, field)
-		}
-	}
-
-	return fields
-}
-
 // getClassHierarchyFields returns all instance fields from the class hierarchy.
 // This includes fields from the current class and all parent classes.
 func (b *BiggestObjectsBuilder) getClassHierarchyFields(layout *ClassFieldLayout) []FieldInfo {
@@ -393,7 +387,7 @@ func (b *BiggestObjectsBuilder) getClassHierarchyFields(layout *ClassFieldLayout
 
 	// Traverse class hierarchy from current class to root
 	currentLayout := layout
-	for currentLayout != nil {
+	for depth := 0; currentLayout != nil && depth < maxClassHierarchyDepth; depth++ {
 		// Add fields from current class
 		allFields = append(allFields, currentLayout.InstanceFields...)
 
@@ -0,0 +1,88 @@
+package hprof
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newRollupTestGraph builds:
+//
+//	root -> com.example.Cache(300) -> java.util.HashMap(200) -> byte[](400)
+//	root -> java.lang.Thread(500) -> byte[](600)
+func newRollupTestGraph() *ReferenceGraph {
+	g := NewReferenceGraphWithCapacity(16)
+	g.SetClassName(1000, "com.example.Cache")
+	g.SetClassName(2000, "java.util.HashMap")
+	g.SetClassName(3000, "byte[]")
+	g.SetClassName(4000, "java.lang.Thread")
+
+	g.SetObjectInfo(300, 1000, 32)
+	g.SetObjectInfo(200, 2000, 48)
+	g.SetObjectInfo(400, 3000, 4096)
+	g.SetObjectInfo(500, 4000, 120)
+	g.SetObjectInfo(600, 3000, 2048)
+
+	g.AddReference(ObjectReference{FromObjectID: 300, ToObjectID: 200, FromClassID: 1000, FieldName: "map"})
+	g.AddReference(ObjectReference{FromObjectID: 200, ToObjectID: 400, FromClassID: 2000, FieldName: "table"})
+	g.AddReference(ObjectReference{FromObjectID: 500, ToObjectID: 600, FromClassID: 4000, FieldName: "buf"})
+
+	g.AddGCRoot(&GCRoot{ObjectID: 300, Type: GCRootJNIGlobal})
+	g.AddGCRoot(&GCRoot{ObjectID: 500, Type: GCRootJNIGlobal})
+	return g
+}
+
+func TestBiggestObjects_NoRollup(t *testing.T) {
+	builder := NewBiggestObjectsBuilder(newRollupTestGraph(), nil, nil)
+
+	objects := builder.BuildBiggestObjectsFiltered(10, "retained", false)
+	assert.Len(t, objects, 5)
+	for _, obj := range objects {
+		assert.Zero(t, obj.RolledUpObjects)
+	}
+}
+
+func TestBiggestObjects_DominatorRollup(t *testing.T) {
+	builder := NewBiggestObjectsBuilder(newRollupTestGraph(), nil, nil)
+	builder.SetDominatorRollup(true)
+
+	objects := builder.BuildBiggestObjectsFiltered(10, "retained", false)
+	require.Len(t, objects, 3)
+
+	byID := make(map[uint64]*BiggestObject)
+	for _, obj := range objects {
+		byID[obj.ObjectID] = obj
+	}
+
+	// HashMap and its byte[] are attributed to the business owner
+	require.Contains(t, byID, uint64(300))
+	assert.Equal(t, int64(2), byID[300].RolledUpObjects)
+	assert.Equal(t, int64(32+48+4096), byID[300].RetainedSize)
+	assert.NotContains(t, byID, uint64(200))
+	assert.NotContains(t, byID, uint64(400))
+
+	// Objects without a non-JDK dominator are kept as-is
+	assert.Contains(t, byID, uint64(500))
+	assert.Contains(t, byID, uint64(600))
+}
+
+func TestBiggestObjects_LazyFields(t *testing.T) {
+	builder := NewBiggestObjectsBuilder(newRollupTestGraph(), nil, nil)
+
+	objects := builder.BuildBiggestObjectsFiltered(10, "retained", false)
+	byID := make(map[uint64]*BiggestObject)
+	for _, obj := range objects {
+		byID[obj.ObjectID] = obj
+	}
+	assert.True(t, byID[300].HasChildren)
+	assert.False(t, byID[400].HasChildren)
+
+	// The children are expanded on demand with their retained sizes
+	fields := builder.GetObjectFields(300)
+	require.Len(t, fields, 1)
+	assert.Equal(t, "map", fields[0].Name)
+	assert.Equal(t, uint64(200), fields[0].RefID)
+	assert.Equal(t, int64(48+4096), fields[0].RetainedSize)
+	assert.True(t, fields[0].HasChildren)
+}
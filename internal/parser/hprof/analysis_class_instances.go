@@ -0,0 +1,141 @@
+package hprof
+
+import (
+	"container/heap"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ClassInstance is one instance of a class with its sizes.
+type ClassInstance struct {
+	ObjectID     uint64 `json:"object_id"`
+	ShallowSize  int64  `json:"shallow_size"`
+	RetainedSize int64  `json:"retained_size"`
+	Reachable    bool   `json:"reachable"`
+}
+
+// ClassInstancesPage is one page of the instances of a class, sorted by
+// size descending and then by object ID.
+type ClassInstancesPage struct {
+	ClassName string `json:"class_name"`
+	// Total is the number of instances of the class
+	Total int64            `json:"total"`
+	Items []*ClassInstance `json:"items"`
+	// Next is the cursor of the following page, empty on the last page
+	Next string `json:"next,omitempty"`
+}
+
+// InstanceCursor is the position after the last instance of a page: its size
+// in the sort order and its object ID. Since instances are ordered by both,
+// a cursor stays valid for the lifetime of the snapshot.
+type InstanceCursor struct {
+	Size     int64
+	ObjectID uint64
+}
+
+// String encodes the cursor as "<size>:<hex object ID>".
+func (c InstanceCursor) String() string {
+	return fmt.Sprintf("%d:%x", c.Size, c.ObjectID)
+}
+
+// ParseInstanceCursor decodes a cursor returned in ClassInstancesPage.Next.
+func ParseInstanceCursor(s string) (InstanceCursor, error) {
+	size, id, ok := strings.Cut(s, ":")
+	if !ok {
+		return InstanceCursor{}, fmt.Errorf("invalid cursor %q", s)
+	}
+	var c InstanceCursor
+	var err error
+	if c.Size, err = strconv.ParseInt(size, 10, 64); err != nil {
+		return InstanceCursor{}, fmt.Errorf("invalid cursor %q: %w", s, err)
+	}
+	if c.ObjectID, err = strconv.ParseUint(id, 16, 64); err != nil {
+		return InstanceCursor{}, fmt.Errorf("invalid cursor %q: %w", s, err)
+	}
+	return c, nil
+}
+
+// ClassInstances returns the limit instances of a class following after (the
+// first page when after is nil), sorted by retained or shallow size. Pages
+// are selected with a bounded heap, so a page costs one pass over the
+// instances and no state is kept between pages.
+func (s *HeapSnapshot) ClassInstances(className, sortBy string, after *InstanceCursor, limit int) (*ClassInstancesPage, error) {
+	classID, ok := s.graph.getClassIDByName(className)
+	if !ok {
+		return nil, fmt.Errorf("class not found: %s", className)
+	}
+	if sortBy != "retained" && sortBy != "shallow" {
+		return nil, fmt.Errorf("invalid sort %q", sortBy)
+	}
+	if limit <= 0 {
+		return nil, fmt.Errorf("limit must be positive")
+	}
+
+	page := &ClassInstancesPage{ClassName: className, Items: []*ClassInstance{}}
+	// One more instance than requested tells whether there is a next page
+	h := &instanceHeap{}
+	for _, objID := range s.graph.getObjectsByClass(classID) {
+		// Class objects are instances of themselves until java.lang.Class is known
+		if objID == classID {
+			continue
+		}
+		page.Total++
+		size := s.graph.objectSize[objID]
+		if sortBy == "retained" {
+			size = s.graph.GetRetainedSize(objID)
+		}
+		item := InstanceCursor{Size: size, ObjectID: objID}
+		if after != nil && !instanceBefore(*after, item) {
+			continue
+		}
+		if h.Len() <= limit {
+			heap.Push(h, item)
+		} else if instanceBefore(item, (*h)[0]) {
+			(*h)[0] = item
+			heap.Fix(h, 0)
+		}
+	}
+
+	items := []InstanceCursor(*h)
+	sort.Slice(items, func(i, j int) bool { return instanceBefore(items[i], items[j]) })
+	if len(items) > limit {
+		items = items[:limit]
+		page.Next = items[limit-1].String()
+	}
+	for _, item := range items {
+		page.Items = append(page.Items, &ClassInstance{
+			ObjectID:     item.ObjectID,
+			ShallowSize:  s.graph.objectSize[item.ObjectID],
+			RetainedSize: s.graph.GetRetainedSize(item.ObjectID),
+			Reachable:    s.graph.IsObjectReachable(item.ObjectID),
+		})
+	}
+	return page, nil
+}
+
+// instanceBefore reports whether a comes before b: bigger first, then by
+// ascending object ID.
+func instanceBefore(a, b InstanceCursor) bool {
+	if a.Size != b.Size {
+		return a.Size > b.Size
+	}
+	return a.ObjectID < b.ObjectID
+}
+
+// instanceHeap keeps the first instances of a page, the last one on top.
+type instanceHeap []InstanceCursor
+
+func (h instanceHeap) Len() int           { return len(h) }
+func (h instanceHeap) Less(i, j int) bool { return instanceBefore(h[j], h[i]) }
+func (h instanceHeap) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+
+func (h *instanceHeap) Push(x interface{}) { *h = append(*h, x.(InstanceCursor)) }
+
+func (h *instanceHeap) Pop() interface{} {
+	old := *h
+	x := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return x
+}
@@ -0,0 +1,63 @@
+package hprof
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHeapSnapshot_ClassInstances(t *testing.T) {
+	g := NewReferenceGraphWithCapacity(16)
+	g.SetClassName(1, "byte[]")
+	g.SetClassName(2, "com.example.Holder")
+	g.SetObjectInfo(100, 2, 16)
+	g.AddGCRoot(&GCRoot{ObjectID: 100, Type: GCRootJNIGlobal})
+	// 25 arrays of 5 distinct sizes, the last one unreachable
+	for i := uint64(0); i < 25; i++ {
+		g.SetObjectInfo(1000+i, 1, int64(16*(i%5+1)))
+		if i < 24 {
+			g.AddReference(ObjectReference{FromObjectID: 100, ToObjectID: 1000 + i, FromClassID: 2, FieldName: "[0]"})
+		}
+	}
+	snapshot := NewHeapSnapshot(g, nil, nil)
+
+	var seen []*ClassInstance
+	var after *InstanceCursor
+	pages := 0
+	for {
+		page, err := snapshot.ClassInstances("byte[]", "shallow", after, 7)
+		require.NoError(t, err)
+		assert.Equal(t, int64(25), page.Total)
+		seen = append(seen, page.Items...)
+		pages++
+		if page.Next == "" {
+			break
+		}
+		cursor, err := ParseInstanceCursor(page.Next)
+		require.NoError(t, err)
+		after = &cursor
+	}
+	assert.Equal(t, 4, pages)
+	require.Len(t, seen, 25)
+	for i := 1; i < len(seen); i++ {
+		a, b := seen[i-1], seen[i]
+		assert.True(t, a.ShallowSize > b.ShallowSize || (a.ShallowSize == b.ShallowSize && a.ObjectID < b.ObjectID))
+	}
+	assert.Equal(t, uint64(1004), seen[0].ObjectID)
+	assert.Equal(t, uint64(1024), seen[4].ObjectID)
+	assert.False(t, seen[4].Reachable)
+	assert.True(t, seen[0].Reachable)
+
+	page, err := snapshot.ClassInstances("byte[]", "retained", nil, 25)
+	require.NoError(t, err)
+	assert.Len(t, page.Items, 25)
+	assert.Empty(t, page.Next)
+
+	_, err = snapshot.ClassInstances("com.example.Missing", "retained", nil, 10)
+	assert.Error(t, err)
+	_, err = snapshot.ClassInstances("byte[]", "count", nil, 10)
+	assert.Error(t, err)
+	_, err = ParseInstanceCursor("12")
+	assert.Error(t, err)
+}
@@ -0,0 +1,75 @@
+package hprof
+
+import (
+	"sort"
+)
+
+// ClassLoaderStats counts the class loaders of a class and the classes they
+// define, e.g. the web application class loaders of a servlet container:
+// several instances defining the same classes usually mean that redeployed
+// applications leak their class loader.
+type ClassLoaderStats struct {
+	ClassName      string `json:"class_name"`
+	Instances      int    `json:"instances"` // loaders defining classes
+	DefinedClasses int    `json:"defined_classes"`
+}
+
+// ComputeClassLoaderStats sets the ClassLoaders of a result: the classes of
+// the heap dump grouped by the class of their loader, most classes first. Classes of the bootstrap loader are not
+// counted.
+func ComputeClassLoaderStats(result *HeapAnalysisResult) []*ClassLoaderStats {
+	g := result.RefGraph
+	byClass := make(map[string]*ClassLoaderStats)
+	loaders := make(map[uint64]bool)
+	for _, layout := range result.ClassLayouts {
+		if layout.ClassLoaderID == 0 {
+			continue
+		}
+		name := classLoaderName(g, layout.ClassLoaderID)
+		stats, ok := byClass[name]
+		if !ok {
+			stats = &ClassLoaderStats{ClassName: name}
+			byClass[name] = stats
+		}
+		stats.DefinedClasses++
+		if !loaders[layout.ClassLoaderID] {
+			loaders[layout.ClassLoaderID] = true
+			stats.Instances++
+		}
+	}
+
+	result.ClassLoaders = make([]*ClassLoaderStats, 0, len(byClass))
+	for _, stats := range byClass {
+		result.ClassLoaders = append(result.ClassLoaders, stats)
+	}
+	sort.Slice(result.ClassLoaders, func(i, j int) bool {
+		a, b := result.ClassLoaders[i], result.ClassLoaders[j]
+		if a.DefinedClasses != b.DefinedClasses {
+			return a.DefinedClasses > b.DefinedClasses
+		}
+		return a.ClassName < b.ClassName
+	})
+	return result.ClassLoaders
+}
+
+// classLoaderName returns the class name of a class loader object, or
+// "<unknown>" when the loader is not in the dump.
+func classLoaderName(g *ReferenceGraph, loaderID uint64) string {
+	if g != nil {
+		if classID, ok := g.GetObjectClassID(loaderID); ok {
+			if name := g.GetClassName(classID); name != "" {
+				return name
+			}
+		}
+	}
+	return "<unknown>"
+}
+
+// setClassLoader sets the defining loader of the class of the stats. Classes
+// of the bootstrap loader are left unset.
+func (c *ClassStats) setClassLoader(g *ReferenceGraph, layouts map[uint64]*ClassFieldLayout, classID uint64) {
+	if layout, ok := layouts[classID]; ok && layout.ClassLoaderID != 0 {
+		c.ClassLoaderID = layout.ClassLoaderID
+		c.ClassLoader = classLoaderName(g, layout.ClassLoaderID)
+	}
+}
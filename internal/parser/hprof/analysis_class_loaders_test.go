@@ -0,0 +1,75 @@
+package hprof
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestComputeClassLoaderStats(t *testing.T) {
+	b := newTestHprofBuilder()
+	b.loadClass(0x10, "com/example/WebappClassLoader")
+	b.loadClass(0x20, "com/example/App")
+	b.loadClass(0x21, "com/example/App")
+	b.loadClass(0x22, "com/example/Servlet")
+	b.loadClass(0x30, "java/lang/Object")
+	b.classDump(0x10, 0, 0)
+	b.classDump(0x30, 0, 0)
+
+	// A redeployed application: two loaders of the same class define App
+	b.classDumpLoader(0x20, 0, 0x1000, 0)
+	b.classDumpLoader(0x21, 0, 0x1100, 0)
+	b.classDumpLoader(0x22, 0, 0x1100, 0)
+	b.instanceDump(0x1000, 0x10, nil)
+	b.instanceDump(0x1100, 0x10, nil)
+	b.rootJNIGlobal(0x1000)
+	b.rootJNIGlobal(0x1100)
+	result := runTestJob(t, b.bytes())
+
+	stats := ComputeClassLoaderStats(result)
+	assert.Equal(t, stats, result.ClassLoaders)
+	require.Len(t, stats, 1)
+	assert.Equal(t, ClassLoaderStats{ClassName: "com.example.WebappClassLoader", Instances: 2, DefinedClasses: 3}, *stats[0])
+}
+
+func TestClassStats_ClassLoader(t *testing.T) {
+	b := newTestHprofBuilder()
+	b.loadClass(0x10, "com/example/WebappClassLoader")
+	b.loadClass(0x20, "com/example/App")
+	b.loadClass(0x21, "com/example/App")
+	b.classDump(0x10, 0, 0)
+	b.classDumpLoader(0x20, 0, 0x1000, 0)
+	b.classDumpLoader(0x21, 0, 0x1100, 0)
+	b.instanceDump(0x1000, 0x10, nil)
+	b.instanceDump(0x1100, 0x10, nil)
+	b.instanceDump(0x2000, 0x20, nil)
+	b.instanceDump(0x2100, 0x21, nil)
+	for _, id := range []uint64{0x1000, 0x1100, 0x2000, 0x2100} {
+		b.rootJNIGlobal(id)
+	}
+	result := runTestJob(t, b.bytes())
+
+	// Classes of the same name defined by two loaders are separate rows
+	loaders := make(map[uint64]string)
+	for _, cls := range result.AllClasses {
+		switch cls.ClassName {
+		case "com.example.App":
+			loaders[cls.ClassLoaderID] = cls.ClassLoader
+		case "com.example.WebappClassLoader":
+			assert.Zero(t, cls.ClassLoaderID)
+			assert.Empty(t, cls.ClassLoader)
+		}
+	}
+	assert.Equal(t, map[uint64]string{
+		0x1000: "com.example.WebappClassLoader",
+		0x1100: "com.example.WebappClassLoader",
+	}, loaders)
+
+	snapshot := NewHeapSnapshot(result.RefGraph, result.ClassLayouts, nil)
+	for _, cls := range snapshot.ClassHistogram() {
+		if cls.ClassName == "com.example.App" {
+			assert.Contains(t, loaders, cls.ClassLoaderID)
+		}
+	}
+}
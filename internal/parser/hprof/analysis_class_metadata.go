@@ -0,0 +1,179 @@
+package hprof
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ClassMetadata describes a class as declared in the heap dump: its place in
+// the class hierarchy, its instance fields and the values of its static fields.
+type ClassMetadata struct {
+	ClassID   uint64 `json:"class_id"`
+	ClassName string `json:"class_name"`
+	// ClassLoaderID is the class loader defining the class, 0 for the bootstrap loader
+	ClassLoaderID uint64 `json:"class_loader_id,omitempty"`
+	// InstanceSize is the size of the field data of an instance, as declared by the dump
+	InstanceSize  int   `json:"instance_size"`
+	InstanceCount int64 `json:"instance_count"`
+	ShallowSize   int64 `json:"shallow_size"`
+	RetainedSize  int64 `json:"retained_size"`
+	// Superclasses is the superclass chain, nearest first
+	Superclasses []*ClassRef `json:"superclasses,omitempty"`
+	// Subclasses are the direct subclasses, sorted by name
+	Subclasses []*ClassRef `json:"subclasses,omitempty"`
+	// Fields are the instance fields, own class first, then superclasses
+	Fields       []*ClassField `json:"fields,omitempty"`
+	StaticFields []*FieldValue `json:"static_fields,omitempty"`
+}
+
+// ClassRef names a class.
+type ClassRef struct {
+	ClassID   uint64 `json:"class_id"`
+	ClassName string `json:"class_name"`
+}
+
+// ClassField is an instance field and the class declaring it.
+type ClassField struct {
+	Name           string `json:"name"`
+	Type           string `json:"type"`
+	DeclaringClass string `json:"declaring_class"`
+}
+
+// ClassID returns the ID of a class by name. When several class loaders
+// define the class, any of them is returned.
+func (s *HeapSnapshot) ClassID(className string) (uint64, bool) {
+	return s.graph.getClassIDByName(className)
+}
+
+// ClassMetadata returns the metadata of a class. Fields and the hierarchy
+// are only known when the snapshot has class layouts.
+func (s *HeapSnapshot) ClassMetadata(classID uint64) (*ClassMetadata, error) {
+	layouts := s.builder.classLayouts
+	layout := layouts[classID]
+	name := s.graph.GetClassName(classID)
+	if layout == nil && name == "" {
+		return nil, fmt.Errorf("class 0x%x not found", classID)
+	}
+
+	meta := &ClassMetadata{
+		ClassID:      classID,
+		ClassName:    name,
+		RetainedSize: s.graph.classRetainedSizes[classID],
+	}
+	for _, objID := range s.graph.getObjectsByClass(classID) {
+		// Class objects are instances of themselves until java.lang.Class is known
+		if objID == classID {
+			continue
+		}
+		meta.InstanceCount++
+		meta.ShallowSize += s.graph.objectSize[objID]
+	}
+	if layout == nil {
+		meta.StaticFields = s.staticFieldRefs(classID)
+		return meta, nil
+	}
+	if meta.ClassName == "" {
+		meta.ClassName = layout.ClassName
+	}
+	meta.ClassLoaderID = layout.ClassLoaderID
+	meta.InstanceSize = layout.InstanceSize
+
+	// The seen set guards against malformed dumps with superclass cycles
+	seen := map[uint64]bool{classID: true}
+	for current := layout; current != nil; {
+		for _, f := range current.InstanceFields {
+			meta.Fields = append(meta.Fields, &ClassField{
+				Name:           f.Name,
+				Type:           basicTypeToString(f.Type),
+				DeclaringClass: s.layoutClassName(current),
+			})
+		}
+		superID := current.SuperClassID
+		if superID == 0 || seen[superID] {
+			break
+		}
+		seen[superID] = true
+		current = layouts[superID]
+		ref := &ClassRef{ClassID: superID, ClassName: s.graph.GetClassName(superID)}
+		if ref.ClassName == "" && current != nil {
+			ref.ClassName = current.ClassName
+		}
+		meta.Superclasses = append(meta.Superclasses, ref)
+	}
+
+	for id, other := range layouts {
+		if other.SuperClassID == classID && id != classID {
+			meta.Subclasses = append(meta.Subclasses, &ClassRef{ClassID: id, ClassName: s.layoutClassName(other)})
+		}
+	}
+	sort.Slice(meta.Subclasses, func(i, j int) bool {
+		a, b := meta.Subclasses[i], meta.Subclasses[j]
+		if a.ClassName != b.ClassName {
+			return a.ClassName < b.ClassName
+		}
+		return a.ClassID < b.ClassID
+	})
+
+	if len(layout.StaticFields) == 0 {
+		// Layouts written before static values were recorded
+		meta.StaticFields = s.staticFieldRefs(classID)
+		return meta, nil
+	}
+	for _, sf := range layout.StaticFields {
+		v := &FieldValue{Name: sf.Name, Type: basicTypeToString(sf.Type)}
+		if sf.Type == TypeObject {
+			v.RefID = sf.RefID
+			s.describeRef(v)
+		} else {
+			v.Value = sf.Value
+		}
+		meta.StaticFields = append(meta.StaticFields, v)
+	}
+	return meta, nil
+}
+
+// staticFieldRefs returns the non-null static reference fields of a class,
+// from the references of its Class object.
+func (s *HeapSnapshot) staticFieldRefs(classID uint64) []*FieldValue {
+	var fields []*FieldValue
+	for _, ref := range s.graph.outgoingRefs[classID] {
+		if ref.FromClassID != classID || strings.HasPrefix(ref.FieldName, "<") {
+			continue
+		}
+		v := &FieldValue{Name: ref.FieldName, Type: basicTypeToString(TypeObject), RefID: ref.ToObjectID}
+		s.describeRef(v)
+		fields = append(fields, v)
+	}
+	return fields
+}
+
+// describeRef fills the class and sizes of a reference, and a preview of the
+// text of Strings when the heap dump is indexed.
+func (s *HeapSnapshot) describeRef(v *FieldValue) {
+	refClassID, ok := s.graph.objectClass[v.RefID]
+	if v.RefID == 0 || !ok {
+		return
+	}
+	v.RefClass = s.graph.GetClassName(refClassID)
+	v.ShallowSize = s.graph.objectSize[v.RefID]
+	v.RetainedSize = s.graph.GetRetainedSize(v.RefID)
+	if v.RefClass != "java.lang.String" || s.objects == nil {
+		return
+	}
+	if text, truncated, err := s.objects.ReadString(v.RefID, StringPreviewLength); err == nil {
+		if truncated {
+			text += "…"
+		}
+		v.StringValue = &text
+	}
+}
+
+// layoutClassName returns the name of a layout's class, preferring the
+// reference graph's name.
+func (s *HeapSnapshot) layoutClassName(layout *ClassFieldLayout) string {
+	if name := s.graph.GetClassName(layout.ClassID); name != "" {
+		return name
+	}
+	return layout.ClassName
+}
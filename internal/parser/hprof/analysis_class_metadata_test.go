@@ -0,0 +1,68 @@
+package hprof
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHeapSnapshot_ClassMetadata(t *testing.T) {
+	b := newTestHprofBuilder()
+	b.loadClass(0x10, "com/example/Base")
+	b.loadClass(0x11, "com/example/Service")
+	b.loadClass(0x12, "com/example/FastService")
+	b.loadClass(0x30, "java/lang/String")
+	b.classDump(0x30, 0, 0, testField{"value", TypeObject}, testField{"coder", TypeByte})
+	b.classDump(0x10, 0, 4, testField{"id", TypeInt})
+	b.classDumpStatics(0x11, 0x10, 0x99, 12, []testStatic{
+		{"COUNT", TypeInt, []byte{0, 0, 0, 42}},
+		{"NAME", TypeObject, refBytes(0x3000)},
+		{"ENABLED", TypeBoolean, []byte{1}},
+		{"EMPTY", TypeObject, refBytes(0)},
+	}, testField{"owner", TypeObject})
+	b.classDump(0x12, 0x11, 12)
+
+	b.instanceDump(0x3000, 0x30, append(refBytes(0x3100), 0))
+	b.primitiveArrayDump(0x3100, TypeByte, 4, []byte("main"))
+	b.instanceDump(0x1000, 0x11, append(refBytes(0), 0, 0, 0, 7))
+	b.instanceDump(0x1001, 0x11, append(refBytes(0), 0, 0, 0, 8))
+	b.rootStickyClass(0x11)
+	b.rootJNIGlobal(0x1000)
+	b.rootJNIGlobal(0x1001)
+
+	snapshot := runTestJob(t, b.bytes()).Snapshot()
+	require.NotNil(t, snapshot)
+	classID, ok := snapshot.ClassID("com.example.Service")
+	require.True(t, ok)
+	assert.Equal(t, uint64(0x11), classID)
+
+	meta, err := snapshot.ClassMetadata(classID)
+	require.NoError(t, err)
+	assert.Equal(t, "com.example.Service", meta.ClassName)
+	assert.Equal(t, uint64(0x99), meta.ClassLoaderID)
+	assert.Equal(t, 12, meta.InstanceSize)
+	assert.Equal(t, int64(2), meta.InstanceCount)
+	assert.Equal(t, []*ClassRef{{ClassID: 0x10, ClassName: "com.example.Base"}}, meta.Superclasses)
+	assert.Equal(t, []*ClassRef{{ClassID: 0x12, ClassName: "com.example.FastService"}}, meta.Subclasses)
+	assert.Equal(t, []*ClassField{
+		{Name: "owner", Type: "object", DeclaringClass: "com.example.Service"},
+		{Name: "id", Type: "int", DeclaringClass: "com.example.Base"},
+	}, meta.Fields)
+
+	require.Len(t, meta.StaticFields, 4)
+	statics := make(map[string]*FieldValue)
+	for _, v := range meta.StaticFields {
+		statics[v.Name] = v
+	}
+	assert.Equal(t, int32(42), statics["COUNT"].Value)
+	assert.Equal(t, true, statics["ENABLED"].Value)
+	assert.Equal(t, uint64(0x3000), statics["NAME"].RefID)
+	assert.Equal(t, "java.lang.String", statics["NAME"].RefClass)
+	require.NotNil(t, statics["NAME"].StringValue)
+	assert.Equal(t, "main", *statics["NAME"].StringValue)
+	assert.Zero(t, statics["EMPTY"].RefID)
+
+	_, err = snapshot.ClassMetadata(0x777)
+	assert.Error(t, err)
+}
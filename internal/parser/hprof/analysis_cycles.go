@@ -0,0 +1,165 @@
+package hprof
+
+import (
+	"sort"
+)
+
+// DefaultObjectCyclesLimit is the number of cycles listed.
+const DefaultObjectCyclesLimit = 20
+
+// maxCycleClasses is the number of classes listed per cycle.
+const maxCycleClasses = 10
+
+// ObjectCycles reports the reference cycles of the heap: the strongly
+// connected components of more than one object. Objects of a cycle entered
+// through several of them are not dominated by any one of them, so the
+// retained size of the cycle is spread over its common dominator instead of
+// the object that intuitively owns it.
+type ObjectCycles struct {
+	// Count is the number of cycles
+	Count int64 `json:"count"`
+	// Objects is the number of objects in cycles
+	Objects   int64 `json:"objects"`
+	TotalSize int64 `json:"total_size"`
+	// Cycles are the largest cycles by total size
+	Cycles []*ObjectCycle `json:"cycles,omitempty"`
+}
+
+// ObjectCycle is one strongly connected component of the object graph.
+type ObjectCycle struct {
+	Objects   int64 `json:"objects"`
+	TotalSize int64 `json:"total_size"`
+	// Entries is the number of objects of the cycle that are GC roots or
+	// referenced from outside of it
+	Entries int64 `json:"entries"`
+	// ObjectID is the object of the cycle with the largest retained size
+	ObjectID uint64 `json:"object_id"`
+	// Classes are the largest classes of the cycle by total size
+	Classes []*CycleClass `json:"classes"`
+}
+
+// CycleClass is the objects of a class in a cycle.
+type CycleClass struct {
+	ClassName string `json:"class_name"`
+	Count     int64  `json:"count"`
+	TotalSize int64  `json:"total_size"`
+}
+
+// ComputeObjectCycles sets the ObjectCycles of a result, listing at most
+// limit cycles.
+func ComputeObjectCycles(result *HeapAnalysisResult, limit int) (*ObjectCycles, error) {
+	if result.RefGraph == nil {
+		return nil, nil
+	}
+	cycles, err := result.RefGraph.ObjectCycles(limit)
+	if err != nil {
+		return nil, err
+	}
+	result.ObjectCycles = cycles
+	return cycles, nil
+}
+
+// ObjectCycles returns the reference cycles of g, listing the limit largest
+// by total size.
+func (g *ReferenceGraph) ObjectCycles(limit int) (*ObjectCycles, error) {
+	scc, err := g.ComputeSCCs()
+	if err != nil {
+		return nil, err
+	}
+	return scc.cycles(limit), nil
+}
+
+// cycles builds the ObjectCycles report of the components of x.
+func (x *SCCIndex) cycles(limit int) *ObjectCycles {
+	g := x.g
+	report := &ObjectCycles{}
+	sizes := make([]int64, len(x.sizes))
+	for v, c := range x.component {
+		if x.sizes[c] > 1 {
+			sizes[c] += g.objectSize[g.indexToObjectID[v]]
+		}
+	}
+	var components []int32
+	for c, n := range x.sizes {
+		if n > 1 {
+			report.Count++
+			report.Objects += int64(n)
+			report.TotalSize += sizes[c]
+			components = append(components, int32(c))
+		}
+	}
+	sort.Slice(components, func(i, j int) bool {
+		a, b := components[i], components[j]
+		if sizes[a] != sizes[b] {
+			return sizes[a] > sizes[b]
+		}
+		return a < b
+	})
+	if limit > 0 && len(components) > limit {
+		components = components[:limit]
+	}
+
+	byComponent := make(map[int32]*ObjectCycle, len(components))
+	byClass := make(map[int32]map[uint64]*CycleClass, len(components))
+	retained := make(map[int32]int64, len(components))
+	// entries are the object indexes entering the listed cycles
+	entries := make(map[int32]bool)
+	for _, c := range components {
+		cycle := &ObjectCycle{Objects: int64(x.sizes[c]), TotalSize: sizes[c]}
+		byComponent[c] = cycle
+		byClass[c] = make(map[uint64]*CycleClass)
+		report.Cycles = append(report.Cycles, cycle)
+	}
+	for v, c := range x.component {
+		cycle, ok := byComponent[c]
+		if !ok {
+			continue
+		}
+		objID := g.indexToObjectID[v]
+		classID := g.objectClass[objID]
+		cls, ok := byClass[c][classID]
+		if !ok {
+			className := g.GetClassName(classID)
+			if className == "" {
+				className = "Unknown"
+			}
+			cls = &CycleClass{ClassName: className}
+			byClass[c][classID] = cls
+			cycle.Classes = append(cycle.Classes, cls)
+		}
+		cls.Count++
+		cls.TotalSize += g.objectSize[objID]
+
+		if r := g.GetRetainedSize(objID); cycle.ObjectID == 0 || r > retained[c] || r == retained[c] && objID < cycle.ObjectID {
+			cycle.ObjectID, retained[c] = objID, r
+		}
+		if g.IsGCRoot(objID) {
+			entries[int32(v)] = true
+		}
+	}
+	for v, refs := range g.outgoingRefsByIndex {
+		for _, ref := range refs {
+			to := int32(ref.ToIndex)
+			if c := x.component[to]; byComponent[c] != nil && c != x.component[v] {
+				entries[to] = true
+			}
+		}
+	}
+	for v := range entries {
+		byComponent[x.component[v]].Entries++
+	}
+
+	for _, cycle := range report.Cycles {
+		sort.Slice(cycle.Classes, func(i, j int) bool {
+			a, b := cycle.Classes[i], cycle.Classes[j]
+			if a.TotalSize != b.TotalSize {
+				return a.TotalSize > b.TotalSize
+			}
+			return a.ClassName < b.ClassName
+		})
+		if len(cycle.Classes) > maxCycleClasses {
+			cycle.Classes = cycle.Classes[:maxCycleClasses]
+		}
+	}
+	return report
+}
@@ -0,0 +1,50 @@
+package hprof
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReferenceGraph_ObjectCycles(t *testing.T) {
+	g := newSCCTestGraph()
+	g.SetClassName(1001, "com.example.Peer")
+	g.SetObjectInfo(4, 1001, 100)
+	g.SetObjectInfo(5, 1001, 100)
+	g.AddGCRoot(&GCRoot{ObjectID: 7, Type: GCRootJNIGlobal})
+	g.ComputeDominatorTree()
+
+	report, err := g.ObjectCycles(1)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), report.Count)
+	assert.Equal(t, int64(5), report.Objects)
+	assert.Equal(t, int64(3*16+2*100), report.TotalSize)
+
+	// {4,5} is larger than {1,2,3} and entered through 4 from 2 and 3
+	require.Len(t, report.Cycles, 1)
+	cycle := report.Cycles[0]
+	assert.Equal(t, int64(2), cycle.Objects)
+	assert.Equal(t, int64(200), cycle.TotalSize)
+	assert.Equal(t, int64(1), cycle.Entries)
+	assert.Equal(t, uint64(4), cycle.ObjectID)
+	assert.Equal(t, []*CycleClass{{ClassName: "com.example.Peer", Count: 2, TotalSize: 200}}, cycle.Classes)
+
+	report, err = g.ObjectCycles(DefaultObjectCyclesLimit)
+	require.NoError(t, err)
+	require.Len(t, report.Cycles, 2)
+	// {1,2,3} is entered through 1 from 7
+	assert.Equal(t, int64(3), report.Cycles[1].Objects)
+	assert.Equal(t, int64(1), report.Cycles[1].Entries)
+}
+
+func TestComputeObjectCycles(t *testing.T) {
+	result := &HeapAnalysisResult{RefGraph: newSCCTestGraph()}
+	report, err := ComputeObjectCycles(result, DefaultObjectCyclesLimit)
+	require.NoError(t, err)
+	assert.Same(t, report, result.ObjectCycles)
+
+	report, err = ComputeObjectCycles(&HeapAnalysisResult{}, DefaultObjectCyclesLimit)
+	assert.NoError(t, err)
+	assert.Nil(t, report)
+}
@@ -0,0 +1,105 @@
+// Package hprof provides parsing functionality for Java HPROF heap dump files.
+package hprof
+
+import (
+	"sort"
+)
+
+// Default limits for dominator tree slices stored in the analysis result.
+const (
+	DefaultDominatorSliceDepth    = 3
+	DefaultDominatorSliceChildren = 10
+)
+
+// DominatorTreeNode is one node of a flattened dominator tree slice.
+// ParentID is 0 for top-level nodes (immediately dominated by the super root).
+type DominatorTreeNode struct {
+	ObjectID     uint64  `json:"object_id"`
+	ParentID     uint64  `json:"parent_id,omitempty"`
+	Depth        int     `json:"depth"`
+	ClassName    string  `json:"class_name"`
+	ShallowSize  int64   `json:"shallow_size"`
+	RetainedSize int64   `json:"retained_size"`
+	Percentage   float64 `json:"percentage"`
+}
+
+// buildDominatorChildren builds the dominator -> children index.
+// Children are sorted by retained size descending.
+func (g *ReferenceGraph) buildDominatorChildren() {
+	g.dominatorChildrenOnce.Do(func() {
+		g.ComputeDominatorTree()
+
+		children := make(map[uint64][]uint64)
+		for objID, domID := range g.dominators {
+			if domID == 0 {
+				continue
+			}
+			children[domID] = append(children[domID], objID)
+		}
+		for _, ids := range children {
+			sort.Slice(ids, func(i, j int) bool {
+				ri, rj := g.GetRetainedSize(ids[i]), g.GetRetainedSize(ids[j])
+				if ri != rj {
+					return ri > rj
+				}
+				return ids[i] < ids[j]
+			})
+		}
+		g.dominatorChildren = children
+	})
+}
+
+// GetDominatorChildren returns the objects immediately dominated by objectID,
+// sorted by retained size descending. Pass superRootID for the top level.
+func (g *ReferenceGraph) GetDominatorChildren(objectID uint64) []uint64 {
+	g.buildDominatorChildren()
+	return g.dominatorChildren[objectID]
+}
+
+// GetDominatorTreeSlice returns the top of the dominator tree in depth-first order.
+// At most maxChildren children are kept per node, down to maxDepth levels.
+func (g *ReferenceGraph) GetDominatorTreeSlice(maxDepth, maxChildren int) []*DominatorTreeNode {
+	if maxDepth <= 0 {
+		maxDepth = DefaultDominatorSliceDepth
+	}
+	if maxChildren <= 0 {
+		maxChildren = DefaultDominatorSliceChildren
+	}
+	g.buildDominatorChildren()
+
+	var totalRetained int64
+	for _, id := range g.dominatorChildren[superRootID] {
+		totalRetained += g.GetRetainedSize(id)
+	}
+
+	var nodes []*DominatorTreeNode
+	var walk func(parentID uint64, depth int)
+	walk = func(parentID uint64, depth int) {
+		children := g.dominatorChildren[parentID]
+		if len(children) > maxChildren {
+			children = children[:maxChildren]
+		}
+		for _, objID := range children {
+			node := &DominatorTreeNode{
+				ObjectID:     objID,
+				Depth:        depth,
+				ClassName:    g.GetClassName(g.objectClass[objID]),
+				ShallowSize:  g.objectSize[objID],
+				RetainedSize: g.GetRetainedSize(objID),
+			}
+			if parentID != superRootID {
+				node.ParentID = parentID
+			}
+			if totalRetained > 0 {
+				node.Percentage = float64(node.RetainedSize) * 100 / float64(totalRetained)
+			}
+			nodes = append(nodes, node)
+			if depth < maxDepth {
+				walk(objID, depth+1)
+			}
+		}
+	}
+	walk(superRootID, 1)
+
+	return nodes
+}
@@ -0,0 +1,55 @@
+package hprof
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetDominatorChildren(t *testing.T) {
+	g := newRollupTestGraph()
+
+	// Top level is sorted by retained size: Cache(4176) before Thread(2168)
+	assert.Equal(t, []uint64{300, 500}, g.GetDominatorChildren(superRootID))
+	assert.Equal(t, []uint64{200}, g.GetDominatorChildren(300))
+	assert.Empty(t, g.GetDominatorChildren(400))
+}
+
+func TestGetDominatorTreeSlice(t *testing.T) {
+	g := newRollupTestGraph()
+
+	nodes := g.GetDominatorTreeSlice(2, 10)
+	require.Len(t, nodes, 4)
+
+	// Depth-first order, children limited to two levels
+	assert.Equal(t, uint64(300), nodes[0].ObjectID)
+	assert.Equal(t, 1, nodes[0].Depth)
+	assert.Zero(t, nodes[0].ParentID)
+	assert.Equal(t, "com.example.Cache", nodes[0].ClassName)
+
+	assert.Equal(t, uint64(200), nodes[1].ObjectID)
+	assert.Equal(t, 2, nodes[1].Depth)
+	assert.Equal(t, uint64(300), nodes[1].ParentID)
+
+	assert.Equal(t, uint64(500), nodes[2].ObjectID)
+	assert.Equal(t, uint64(600), nodes[3].ObjectID)
+
+	assert.InDelta(t, 100.0, nodes[0].Percentage+nodes[2].Percentage, 0.001)
+
+	// Child limit applies to every level
+	assert.Len(t, g.GetDominatorTreeSlice(1, 1), 1)
+}
+
+func TestComputeDominatorTreeContext_Canceled(t *testing.T) {
+	g := newRollupTestGraph()
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	assert.ErrorIs(t, g.ComputeDominatorTreeContext(ctx), context.Canceled)
+	assert.False(t, g.dominatorComputed)
+
+	require.NoError(t, g.ComputeDominatorTreeContext(context.Background()))
+	assert.Equal(t, []uint64{200}, g.GetDominatorChildren(300))
+}
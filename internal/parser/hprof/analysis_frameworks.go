@@ -0,0 +1,508 @@
+package hprof
+
+import (
+	"os"
+	"sort"
+	"strings"
+)
+
+// DefaultFrameworkStatsLimit is the number of sessions, caches and
+// persistence contexts listed by the framework reports.
+const DefaultFrameworkStatsLimit = 10
+
+// Limits of the framework analyzers.
+const (
+	// maxFrameworkReads bounds the objects read from the heap dump for
+	// primitive and String fields
+	maxFrameworkReads = 100000
+	// maxHolderDepth bounds the framework objects skipped to find the
+	// application field holding a cache
+	maxHolderDepth = 4
+)
+
+// FrameworkStats holds the reports of the framework analyzers: the memory
+// held by the HTTP sessions, caches, ORM sessions, buffer pools and message
+// client buffers of common Java frameworks. Reports of frameworks absent from
+// the heap are nil.
+type FrameworkStats struct {
+	Sessions            *SessionStats            `json:"sessions,omitempty"`
+	Caches              *CacheStats              `json:"caches,omitempty"`
+	PersistenceContexts *PersistenceContextStats `json:"persistence_contexts,omitempty"`
+	Netty               *NettyStats              `json:"netty,omitempty"`
+	Kafka               *KafkaStats              `json:"kafka,omitempty"`
+	// FieldsRead is set when session IDs, cache weights, chunk sizes,
+	// reference counts and topics were read from the heap dump, which needs
+	// an indexed dump
+	FieldsRead bool `json:"fields_read"`
+}
+
+// SessionStats reports the HTTP sessions of servlet containers (Tomcat,
+// Jetty, Undertow) and Spring Session.
+type SessionStats struct {
+	Count        int64 `json:"count"`
+	Attributes   int64 `json:"attributes"`
+	RetainedSize int64 `json:"retained_size"`
+	AverageSize  int64 `json:"average_size"`
+	// Largest are the largest sessions by retained size
+	Largest []*SessionInfo `json:"largest"`
+}
+
+// SessionInfo is one HTTP session.
+type SessionInfo struct {
+	ObjectID  uint64 `json:"object_id"`
+	ClassName string `json:"class_name"`
+	// ID is the session ID, read from the heap dump
+	ID           string `json:"id,omitempty"`
+	Attributes   int    `json:"attributes"`
+	RetainedSize int64  `json:"retained_size"`
+}
+
+// CacheStats reports the Guava and Caffeine caches.
+type CacheStats struct {
+	Count        int64 `json:"count"`
+	Entries      int64 `json:"entries"`
+	RetainedSize int64 `json:"retained_size"`
+	// Largest are the largest caches by retained size
+	Largest []*CacheInfo `json:"largest"`
+}
+
+// CacheInfo is one Guava or Caffeine cache.
+type CacheInfo struct {
+	ObjectID uint64 `json:"object_id"`
+	// Library is guava or caffeine
+	Library   string `json:"library"`
+	ClassName string `json:"class_name"`
+	// Holder is the class and field referencing the cache, e.g.
+	// com.example.UserService.users
+	Holder  string `json:"holder,omitempty"`
+	Entries int64  `json:"entries"`
+	// Weight is the total weight of the entries, the entry count unless a
+	// weigher is set, and MaxWeight the bound of the cache; both are read
+	// from the heap dump and are 0 when unknown or unbounded
+	Weight       int64 `json:"weight,omitempty"`
+	MaxWeight    int64 `json:"max_weight,omitempty"`
+	RetainedSize int64 `json:"retained_size"`
+}
+
+// PersistenceContextStats reports the Hibernate persistence contexts, the
+// first-level caches of the entities loaded by ORM sessions.
+type PersistenceContextStats struct {
+	Count        int64 `json:"count"`
+	Entities     int64 `json:"entities"`
+	Collections  int64 `json:"collections"`
+	RetainedSize int64 `json:"retained_size"`
+	// Largest are the largest persistence contexts by retained size
+	Largest []*PersistenceContextInfo `json:"largest"`
+}
+
+// PersistenceContextInfo is the persistence context of one ORM session.
+type PersistenceContextInfo struct {
+	ObjectID uint64 `json:"object_id"`
+	// SessionID is the Hibernate session owning the context
+	SessionID    uint64 `json:"session_id,omitempty"`
+	Entities     int    `json:"entities"`
+	Collections  int    `json:"collections"`
+	RetainedSize int64  `json:"retained_size"`
+}
+
+// sessionShape tells how the attributes map and the ID of a session class
+// are reached through its fields. The last field of id is a String read from
+// the heap dump.
+type sessionShape struct {
+	attributes []string
+	id         []string
+}
+
+// sessionShapes are the shapes of the session classes of frameworkClasses.
+var sessionShapes = map[string]sessionShape{
+	"org.apache.catalina.session.StandardSession":                   {attributes: []string{"attributes"}, id: []string{"id"}},
+	"org.eclipse.jetty.server.session.Session":                      {attributes: []string{"_sessionData", "_attributes"}, id: []string{"_sessionData", "_id"}},
+	"io.undertow.server.session.InMemorySessionManager$SessionImpl": {attributes: []string{"attributes"}, id: []string{"sessionId"}},
+	"org.springframework.session.MapSession":                        {attributes: []string{"sessionAttrs"}, id: []string{"id"}},
+}
+
+// Kinds of framework objects.
+const (
+	frameworkSession   = "session"
+	frameworkGuava     = "guava"
+	frameworkCaffeine  = "caffeine"
+	frameworkHibernate = "hibernate"
+
+	frameworkNettyArena     = "netty_arena"
+	frameworkNettyChunk     = "netty_chunk"
+	frameworkNettyByteBuf   = "netty_bytebuf"
+	frameworkNettyEventLoop = "netty_event_loop"
+
+	frameworkKafkaAccumulator = "kafka_accumulator"
+	frameworkKafkaFetcher     = "kafka_fetcher"
+	frameworkKafkaBatch       = "kafka_batch"
+	frameworkKafkaFetch       = "kafka_fetch"
+	frameworkKafkaRecord      = "kafka_record"
+	frameworkKafkaBufferPool  = "kafka_buffer_pool"
+	frameworkKafkaMemoryPool  = "kafka_memory_pool"
+)
+
+// frameworkClasses are the kinds of the framework classes, which are matched
+// with their subclasses.
+var frameworkClasses = map[string]string{
+	"org.apache.catalina.session.StandardSession":                        frameworkSession,
+	"org.eclipse.jetty.server.session.Session":                           frameworkSession,
+	"io.undertow.server.session.InMemorySessionManager$SessionImpl":      frameworkSession,
+	"org.springframework.session.MapSession":                             frameworkSession,
+	"com.google.common.cache.LocalCache":                                 frameworkGuava,
+	"com.github.benmanes.caffeine.cache.BoundedLocalCache":               frameworkCaffeine,
+	"com.github.benmanes.caffeine.cache.UnboundedLocalCache":             frameworkCaffeine,
+	"org.hibernate.engine.internal.StatefulPersistenceContext":           frameworkHibernate,
+	"io.netty.buffer.PoolArena":                                          frameworkNettyArena,
+	"io.netty.buffer.PoolChunk":                                          frameworkNettyChunk,
+	"io.netty.buffer.AbstractReferenceCountedByteBuf":                    frameworkNettyByteBuf,
+	"io.netty.util.concurrent.SingleThreadEventExecutor":                 frameworkNettyEventLoop,
+	"org.apache.kafka.clients.producer.internals.RecordAccumulator":      frameworkKafkaAccumulator,
+	"org.apache.kafka.clients.consumer.internals.Fetcher":                frameworkKafkaFetcher,
+	"org.apache.kafka.clients.consumer.internals.AbstractFetch":          frameworkKafkaFetcher,
+	"org.apache.kafka.clients.producer.internals.ProducerBatch":          frameworkKafkaBatch,
+	"org.apache.kafka.clients.consumer.internals.Fetcher$CompletedFetch": frameworkKafkaFetch,
+	"org.apache.kafka.clients.consumer.internals.CompletedFetch":         frameworkKafkaFetch,
+	"org.apache.kafka.clients.consumer.ConsumerRecord":                   frameworkKafkaRecord,
+	"org.apache.kafka.clients.producer.internals.BufferPool":             frameworkKafkaBufferPool,
+	"org.apache.kafka.common.memory.SimpleMemoryPool":                    frameworkKafkaMemoryPool,
+}
+
+// Packages of the internal objects of the caches.
+const (
+	guavaCachePackage    = "com.google.common.cache."
+	caffeineCachePackage = "com.github.benmanes.caffeine.cache."
+)
+
+// ComputeFrameworkStats sets the FrameworkStats of a result, listing at most
+// limit objects per report. Primitive and String fields, such as session IDs,
+// cache weights, buffer reference counts and topics, are read when the heap dump was
+// indexed.
+func ComputeFrameworkStats(result *HeapAnalysisResult, limit int) *FrameworkStats {
+	g := result.RefGraph
+	if g == nil {
+		return nil
+	}
+	a := &frameworkAnalyzer{g: g, layouts: result.ClassLayouts, limit: limit}
+	if result.ObjectIndex != nil && result.ObjectIndex.SourceFile != "" {
+		a.reader = NewObjectReader(result.ObjectIndex, result.ClassLayouts)
+		if f, err := a.reader.open(); err == nil {
+			a.file = f
+			defer f.Close()
+		}
+	}
+
+	result.FrameworkStats = &FrameworkStats{
+		Sessions:            a.sessions(),
+		Caches:              a.caches(),
+		PersistenceContexts: a.persistenceContexts(),
+		Netty:               a.netty(),
+		Kafka:               a.kafka(),
+		FieldsRead:          a.file != nil,
+	}
+	return result.FrameworkStats
+}
+
+// frameworkAnalyzer finds the framework objects of a graph.
+type frameworkAnalyzer struct {
+	g       *ReferenceGraph
+	layouts map[uint64]*ClassFieldLayout
+	limit   int
+	reader  *ObjectReader
+	file    *os.File
+	reads   int
+}
+
+// sessions reports the HTTP sessions.
+func (a *frameworkAnalyzer) sessions() *SessionStats {
+	objects := a.instancesOf(frameworkSession)
+	if len(objects) == 0 {
+		return nil
+	}
+
+	stats := &SessionStats{}
+	for _, objID := range objects {
+		classID, _ := a.g.GetObjectClassID(objID)
+		shape := sessionShapes[a.baseClass(classID)]
+
+		session := &SessionInfo{
+			ObjectID:     objID,
+			ClassName:    a.g.GetClassName(classID),
+			Attributes:   a.collectionSize(a.follow(objID, shape.attributes)),
+			RetainedSize: a.g.GetRetainedSize(objID),
+		}
+		stats.Count++
+		stats.Attributes += int64(session.Attributes)
+		stats.RetainedSize += session.RetainedSize
+		stats.Largest = append(stats.Largest, session)
+	}
+	stats.AverageSize = stats.RetainedSize / stats.Count
+	sort.Slice(stats.Largest, func(i, j int) bool {
+		x, y := stats.Largest[i], stats.Largest[j]
+		if x.RetainedSize != y.RetainedSize {
+			return x.RetainedSize > y.RetainedSize
+		}
+		return x.ObjectID < y.ObjectID
+	})
+	if len(stats.Largest) > a.limit {
+		stats.Largest = stats.Largest[:a.limit]
+	}
+	// Only the IDs of the sessions listed are read
+	for _, session := range stats.Largest {
+		classID, _ := a.g.GetObjectClassID(session.ObjectID)
+		path := sessionShapes[a.baseClass(classID)].id
+		holder := a.follow(session.ObjectID, path[:len(path)-1])
+		if v := a.readFields(holder)[path[len(path)-1]]; v != nil && v.StringValue != nil {
+			session.ID = *v.StringValue
+		}
+	}
+	return stats
+}
+
+// caches reports the Guava and Caffeine caches.
+func (a *frameworkAnalyzer) caches() *CacheStats {
+	objects := a.instancesOf(frameworkGuava, frameworkCaffeine)
+	if len(objects) == 0 {
+		return nil
+	}
+
+	stats := &CacheStats{}
+	for _, objID := range objects {
+		classID, _ := a.g.GetObjectClassID(objID)
+		library := frameworkClasses[a.baseClass(classID)]
+
+		cache := &CacheInfo{
+			ObjectID:     objID,
+			Library:      library,
+			ClassName:    a.g.GetClassName(classID),
+			RetainedSize: a.g.GetRetainedSize(objID),
+		}
+		if library == frameworkGuava {
+			cache.Holder = a.holder(objID, guavaCachePackage)
+			cache.Entries = a.guavaEntries(objID)
+		} else {
+			cache.Holder = a.holder(objID, caffeineCachePackage)
+			cache.Entries = int64(a.collectionSize(a.g.fieldRef(objID, "data")))
+		}
+		stats.Count++
+		stats.Entries += cache.Entries
+		stats.RetainedSize += cache.RetainedSize
+		stats.Largest = append(stats.Largest, cache)
+	}
+	sort.Slice(stats.Largest, func(i, j int) bool {
+		x, y := stats.Largest[i], stats.Largest[j]
+		if x.RetainedSize != y.RetainedSize {
+			return x.RetainedSize > y.RetainedSize
+		}
+		return x.ObjectID < y.ObjectID
+	})
+	if len(stats.Largest) > a.limit {
+		stats.Largest = stats.Largest[:a.limit]
+	}
+	for _, cache := range stats.Largest {
+		a.readCacheWeights(cache)
+	}
+	return stats
+}
+
+// guavaEntries counts the entries of a Guava LocalCache: the chains of the
+// hash tables of its segments.
+func (a *frameworkAnalyzer) guavaEntries(objID uint64) int64 {
+	var entries int64
+	seen := make(map[uint64]bool)
+	for _, segment := range a.g.outgoingRefs[a.g.fieldRef(objID, "segments")] {
+		table := a.follow(segment.ToObjectID, []string{"table", "array"})
+		for _, head := range a.g.outgoingRefs[table] {
+			for node := head.ToObjectID; node != 0 && !seen[node]; node = a.g.fieldRef(node, "next") {
+				seen[node] = true
+				entries++
+			}
+		}
+	}
+	return entries
+}
+
+// readCacheWeights reads the weight and bound of a cache from the heap dump.
+func (a *frameworkAnalyzer) readCacheWeights(cache *CacheInfo) {
+	if cache.Library == frameworkCaffeine {
+		// Fields of the generated subclasses of bounded caches
+		fields := a.readFields(cache.ObjectID)
+		cache.Weight, _ = longField(fields, "weightedSize")
+		cache.MaxWeight, _ = longField(fields, "maximum")
+		return
+	}
+
+	for _, segment := range a.g.outgoingRefs[a.g.fieldRef(cache.ObjectID, "segments")] {
+		weight, ok := longField(a.readFields(segment.ToObjectID), "totalWeight")
+		if !ok {
+			return
+		}
+		cache.Weight += weight
+	}
+	// maxWeight is -1 when unbounded
+	if maxWeight, _ := longField(a.readFields(cache.ObjectID), "maxWeight"); maxWeight > 0 {
+		cache.MaxWeight = maxWeight
+	}
+}
+
+// persistenceContexts reports the Hibernate persistence contexts.
+func (a *frameworkAnalyzer) persistenceContexts() *PersistenceContextStats {
+	objects := a.instancesOf(frameworkHibernate)
+	if len(objects) == 0 {
+		return nil
+	}
+
+	stats := &PersistenceContextStats{}
+	for _, objID := range objects {
+		context := &PersistenceContextInfo{
+			ObjectID:     objID,
+			SessionID:    a.g.fieldRef(objID, "session"),
+			Entities:     a.collectionSize(a.g.fieldRef(objID, "entitiesByKey")),
+			Collections:  a.collectionSize(a.g.fieldRef(objID, "collectionsByKey")),
+			RetainedSize: a.g.GetRetainedSize(objID),
+		}
+		stats.Count++
+		stats.Entities += int64(context.Entities)
+		stats.Collections += int64(context.Collections)
+		stats.RetainedSize += context.RetainedSize
+		stats.Largest = append(stats.Largest, context)
+	}
+	sort.Slice(stats.Largest, func(i, j int) bool {
+		x, y := stats.Largest[i], stats.Largest[j]
+		if x.RetainedSize != y.RetainedSize {
+			return x.RetainedSize > y.RetainedSize
+		}
+		return x.ObjectID < y.ObjectID
+	})
+	if len(stats.Largest) > a.limit {
+		stats.Largest = stats.Largest[:a.limit]
+	}
+	return stats
+}
+
+// instancesOf returns the instances of the framework classes of the given
+// kinds, in ascending order.
+func (a *frameworkAnalyzer) instancesOf(kinds ...string) []uint64 {
+	var objects []uint64
+	for classID := range a.g.classNames {
+		base := a.baseClass(classID)
+		for _, kind := range kinds {
+			if base == "" || frameworkClasses[base] != kind {
+				continue
+			}
+			for _, objID := range a.g.getObjectsByClass(classID) {
+				// Class objects are recorded as instances of themselves
+				if !a.g.classObjectIDs[objID] {
+					objects = append(objects, objID)
+				}
+			}
+		}
+	}
+	sort.Slice(objects, func(i, j int) bool { return objects[i] < objects[j] })
+	return objects
+}
+
+// baseClass returns the name of the framework class that is classID or one
+// of its superclasses, or "".
+func (a *frameworkAnalyzer) baseClass(classID uint64) string {
+	for depth := 0; classID != 0 && depth < maxClassHierarchyDepth; depth++ {
+		name := a.g.GetClassName(classID)
+		if _, ok := frameworkClasses[name]; ok {
+			return name
+		}
+		layout, ok := a.layouts[classID]
+		if !ok {
+			break
+		}
+		classID = layout.SuperClassID
+	}
+	return ""
+}
+
+// follow returns the object reached from objID through a path of fields,
+// or 0.
+func (a *frameworkAnalyzer) follow(objID uint64, path []string) uint64 {
+	for _, name := range path {
+		if objID == 0 {
+			break
+		}
+		objID = a.g.fieldRef(objID, name)
+	}
+	return objID
+}
+
+// collectionSize returns the number of entries of a JDK collection, or 0
+// for other objects.
+func (a *frameworkAnalyzer) collectionSize(objID uint64) int {
+	classID, ok := a.g.GetObjectClassID(objID)
+	if !ok {
+		return 0
+	}
+	shape, ok := collectionShapes[a.g.GetClassName(classID)]
+	if !ok {
+		return 0
+	}
+	return len(a.g.collectionEntries(objID, shape))
+}
+
+// holder returns the class and field referencing an object, skipping the
+// objects of an internal package, or "".
+func (a *frameworkAnalyzer) holder(objID uint64, internal string) string {
+	for depth := 0; depth < maxHolderDepth; depth++ {
+		refs := a.g.incomingRefs[objID]
+		if len(refs) == 0 {
+			return ""
+		}
+		ref := refs[0]
+		if a.g.classObjectIDs[ref.FromObjectID] {
+			return a.g.GetClassName(ref.FromObjectID) + "." + ref.FieldName
+		}
+		fromClass, _ := a.g.GetObjectClassID(ref.FromObjectID)
+		className := a.g.GetClassName(fromClass)
+		if !strings.HasPrefix(className, internal) {
+			if ref.FieldName == "" || strings.HasPrefix(ref.FieldName, "[") {
+				return ""
+			}
+			return className + "." + ref.FieldName
+		}
+		objID = ref.FromObjectID
+	}
+	return ""
+}
+
+// readFields reads the fields of an instance from the heap dump, by name.
+// It returns nil when the dump is not indexed or the read budget is spent.
+func (a *frameworkAnalyzer) readFields(objID uint64) map[string]*FieldValue {
+	if a.file == nil || objID == 0 || a.reads >= maxFrameworkReads {
+		return nil
+	}
+	a.reads++
+	rec, err := a.reader.readRecord(a.file, objID, 0)
+	if err != nil || rec.tag != HeapTagInstanceDump {
+		return nil
+	}
+	fields := make(map[string]*FieldValue)
+	for _, v := range a.reader.instanceFields(a.file, rec) {
+		// Fields of subclasses come first and shadow their superclasses
+		if _, ok := fields[v.Name]; !ok {
+			fields[v.Name] = v
+		}
+	}
+	return fields
+}
+
+// longField returns an int or long field value.
+func longField(fields map[string]*FieldValue, name string) (int64, bool) {
+	v, ok := fields[name]
+	if !ok {
+		return 0, false
+	}
+	switch n := v.Value.(type) {
+	case int64:
+		return n, true
+	case int32:
+		return int64(n), true
+	default:
+		return 0, false
+	}
+}
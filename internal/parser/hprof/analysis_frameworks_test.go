@@ -0,0 +1,197 @@
+package hprof
+
+import (
+	"encoding/binary"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// longBytes encodes long field values.
+func longBytes(values ...int64) []byte {
+	out := make([]byte, 8*len(values))
+	for i, v := range values {
+		binary.BigEndian.PutUint64(out[i*8:], uint64(v))
+	}
+	return out
+}
+
+// frameworkTestDump returns a heap dump with two Tomcat sessions, a Guava
+// and a Caffeine cache and a Hibernate session.
+func frameworkTestDump() []byte {
+	b := newTestHprofBuilder()
+	for id, name := range map[uint64]string{
+		0x10: "java/lang/String",
+		0x20: "org/apache/catalina/session/StandardSession", 0x21: "org/apache/catalina/ha/session/DeltaSession",
+		0x22: "java/util/concurrent/ConcurrentHashMap", 0x23: "java/util/concurrent/ConcurrentHashMap$Node",
+		0x24: "[Ljava/util/concurrent/ConcurrentHashMap$Node;",
+		0x30: "com/google/common/cache/LocalCache", 0x31: "com/google/common/cache/LocalCache$Segment",
+		0x32: "java/util/concurrent/atomic/AtomicReferenceArray", 0x33: "[Ljava/lang/Object;",
+		0x34: "com/google/common/cache/LocalCache$StrongEntry", 0x35: "com/google/common/cache/LocalCache$LocalManualCache",
+		0x36: "com/example/UserService", 0x37: "[Lcom/google/common/cache/LocalCache$Segment;",
+		0x38: "com/example/Config",
+		0x40: "com/github/benmanes/caffeine/cache/BoundedLocalCache", 0x41: "com/github/benmanes/caffeine/cache/SSMSW",
+		0x50: "org/hibernate/engine/internal/StatefulPersistenceContext", 0x51: "org/hibernate/internal/SessionImpl",
+		0x52: "java/util/HashMap", 0x53: "java/util/HashMap$Node", 0x54: "[Ljava/util/HashMap$Node;",
+	} {
+		b.loadClass(id, name)
+	}
+	node := []testField{{"key", TypeObject}, {"value", TypeObject}, {"next", TypeObject}}
+	b.classDump(0x10, 0, 9, testField{"value", TypeObject}, testField{"coder", TypeByte})
+	b.classDump(0x20, 0, 16, testField{"id", TypeObject}, testField{"attributes", TypeObject})
+	b.classDump(0x21, 0x20, 0)
+	b.classDump(0x22, 0, 8, testField{"table", TypeObject})
+	b.classDump(0x23, 0, 24, node...)
+	b.classDump(0x24, 0, 0)
+	b.classDump(0x30, 0, 16, testField{"segments", TypeObject}, testField{"maxWeight", TypeLong})
+	b.classDump(0x31, 0, 16, testField{"table", TypeObject}, testField{"totalWeight", TypeLong})
+	b.classDump(0x32, 0, 8, testField{"array", TypeObject})
+	b.classDump(0x33, 0, 0)
+	b.classDump(0x34, 0, 8, testField{"next", TypeObject})
+	b.classDump(0x35, 0, 8, testField{"localCache", TypeObject})
+	b.classDump(0x36, 0, 8, testField{"users", TypeObject})
+	b.classDump(0x37, 0, 0)
+	b.classDumpStatics(0x38, 0, 0, 0, []testStatic{{name: "CACHE", typ: TypeObject, value: refBytes(0x3000)}})
+	b.classDump(0x40, 0, 8, testField{"data", TypeObject})
+	b.classDump(0x41, 0x40, 16, testField{"maximum", TypeLong}, testField{"weightedSize", TypeLong})
+	b.classDump(0x50, 0, 24, testField{"session", TypeObject}, testField{"entitiesByKey", TypeObject}, testField{"collectionsByKey", TypeObject})
+	b.classDump(0x51, 0, 8, testField{"persistenceContext", TypeObject})
+	b.classDump(0x52, 0, 8, testField{"table", TypeObject})
+	b.classDump(0x53, 0, 24, node...)
+	b.classDump(0x54, 0, 0)
+
+	// A session with 3 attributes, and a replicated one holding 1000 bytes
+	b.instanceDump(0x1000, 0x20, refBytes(0x1010, 0x1020))
+	b.instanceDump(0x1010, 0x10, append(refBytes(0x1011), 0))
+	b.primitiveArrayDump(0x1011, TypeByte, 3, []byte("ABC"))
+	b.instanceDump(0x1020, 0x22, refBytes(0x1030))
+	b.objectArrayDump(0x1030, 0x24, 0x1040, 0x1041, 0x1042)
+	for i := uint64(0); i < 3; i++ {
+		b.instanceDump(0x1040+i, 0x23, refBytes(0, 0, 0))
+	}
+	b.instanceDump(0x1100, 0x21, refBytes(0x1110, 0x1120))
+	b.instanceDump(0x1110, 0x10, append(refBytes(0x1111), 0))
+	b.primitiveArrayDump(0x1111, TypeByte, 3, []byte("DEF"))
+	b.instanceDump(0x1120, 0x22, refBytes(0x1130))
+	b.objectArrayDump(0x1130, 0x24, 0x1140)
+	b.instanceDump(0x1140, 0x23, refBytes(0, 0x1190, 0))
+	b.primitiveArrayDump(0x1190, TypeByte, 1000, make([]byte, 1000))
+	b.rootJNIGlobal(0x1000)
+	b.rootJNIGlobal(0x1100)
+
+	// A Guava cache of 3 entries weighing 7 in 2 segments
+	b.instanceDump(0x2000, 0x36, refBytes(0x2010))
+	b.instanceDump(0x2010, 0x35, refBytes(0x2020))
+	b.instanceDump(0x2020, 0x30, append(refBytes(0x2030), longBytes(100)...))
+	b.objectArrayDump(0x2030, 0x37, 0x2040, 0x2041)
+	b.instanceDump(0x2040, 0x31, append(refBytes(0x2050), longBytes(2)...))
+	b.instanceDump(0x2041, 0x31, append(refBytes(0x2051), longBytes(5)...))
+	b.instanceDump(0x2050, 0x32, refBytes(0x2060))
+	b.instanceDump(0x2051, 0x32, refBytes(0x2061))
+	b.objectArrayDump(0x2060, 0x33, 0x2070, 0)
+	b.objectArrayDump(0x2061, 0x33, 0x2072)
+	b.instanceDump(0x2070, 0x34, refBytes(0x2071))
+	b.instanceDump(0x2071, 0x34, refBytes(0))
+	b.instanceDump(0x2072, 0x34, refBytes(0))
+	b.rootJNIGlobal(0x2000)
+
+	// A bounded Caffeine cache of 2 entries, held by a static field
+	b.instanceDump(0x3000, 0x41, append(longBytes(500, 2), refBytes(0x3010)...))
+	b.instanceDump(0x3010, 0x22, refBytes(0x3020))
+	b.objectArrayDump(0x3020, 0x24, 0x3030, 0x3031)
+	b.instanceDump(0x3030, 0x23, refBytes(0, 0, 0))
+	b.instanceDump(0x3031, 0x23, refBytes(0, 0, 0))
+	b.rootStickyClass(0x38)
+
+	// A Hibernate session with 2 entities and 1 collection
+	b.instanceDump(0x4000, 0x51, refBytes(0x4010))
+	b.instanceDump(0x4010, 0x50, refBytes(0x4000, 0x4020, 0x4030))
+	b.instanceDump(0x4020, 0x52, refBytes(0x4021))
+	b.objectArrayDump(0x4021, 0x54, 0x4022, 0)
+	b.instanceDump(0x4022, 0x53, refBytes(0, 0, 0x4023))
+	b.instanceDump(0x4023, 0x53, refBytes(0, 0, 0))
+	b.instanceDump(0x4030, 0x52, refBytes(0x4031))
+	b.objectArrayDump(0x4031, 0x54, 0x4032)
+	b.instanceDump(0x4032, 0x53, refBytes(0, 0, 0))
+	b.rootJNIGlobal(0x4000)
+	return b.bytes()
+}
+
+func TestComputeFrameworkStats(t *testing.T) {
+	result := runTestJob(t, frameworkTestDump())
+
+	stats := ComputeFrameworkStats(result, DefaultFrameworkStatsLimit)
+	require.NotNil(t, stats)
+	assert.Same(t, stats, result.FrameworkStats)
+	assert.True(t, stats.FieldsRead)
+
+	sessions := stats.Sessions
+	require.NotNil(t, sessions)
+	assert.Equal(t, int64(2), sessions.Count)
+	assert.Equal(t, int64(4), sessions.Attributes)
+	assert.Equal(t, sessions.RetainedSize/2, sessions.AverageSize)
+	require.Len(t, sessions.Largest, 2)
+	largest := sessions.Largest[0]
+	assert.Equal(t, uint64(0x1100), largest.ObjectID)
+	assert.Equal(t, "org.apache.catalina.ha.session.DeltaSession", largest.ClassName)
+	assert.Equal(t, "DEF", largest.ID)
+	assert.Equal(t, 1, largest.Attributes)
+	assert.Greater(t, largest.RetainedSize, int64(1000))
+	assert.Equal(t, "ABC", sessions.Largest[1].ID)
+	assert.Equal(t, 3, sessions.Largest[1].Attributes)
+
+	caches := stats.Caches
+	require.NotNil(t, caches)
+	assert.Equal(t, int64(2), caches.Count)
+	assert.Equal(t, int64(5), caches.Entries)
+	byLibrary := make(map[string]*CacheInfo)
+	for _, cache := range caches.Largest {
+		byLibrary[cache.Library] = cache
+	}
+	guava := byLibrary["guava"]
+	require.NotNil(t, guava)
+	assert.Equal(t, uint64(0x2020), guava.ObjectID)
+	assert.Equal(t, "com.example.UserService.users", guava.Holder)
+	assert.Equal(t, int64(3), guava.Entries)
+	assert.Equal(t, int64(7), guava.Weight)
+	assert.Equal(t, int64(100), guava.MaxWeight)
+	caffeine := byLibrary["caffeine"]
+	require.NotNil(t, caffeine)
+	assert.Equal(t, "com.github.benmanes.caffeine.cache.SSMSW", caffeine.ClassName)
+	assert.Equal(t, "com.example.Config.CACHE", caffeine.Holder)
+	assert.Equal(t, int64(2), caffeine.Entries)
+	assert.Equal(t, int64(2), caffeine.Weight)
+	assert.Equal(t, int64(500), caffeine.MaxWeight)
+
+	contexts := stats.PersistenceContexts
+	require.NotNil(t, contexts)
+	assert.Equal(t, int64(1), contexts.Count)
+	assert.Equal(t, int64(2), contexts.Entities)
+	assert.Equal(t, int64(1), contexts.Collections)
+	require.Len(t, contexts.Largest, 1)
+	assert.Equal(t, uint64(0x4010), contexts.Largest[0].ObjectID)
+	assert.Equal(t, uint64(0x4000), contexts.Largest[0].SessionID)
+}
+
+func TestComputeFrameworkStats_NotIndexed(t *testing.T) {
+	result := runTestJob(t, frameworkTestDump())
+	result.ObjectIndex = nil
+
+	stats := ComputeFrameworkStats(result, 1)
+	assert.False(t, stats.FieldsRead)
+	require.Len(t, stats.Sessions.Largest, 1)
+	assert.Empty(t, stats.Sessions.Largest[0].ID)
+	assert.Equal(t, int64(2), stats.Sessions.Count)
+	for _, cache := range stats.Caches.Largest {
+		assert.Zero(t, cache.Weight)
+		assert.NotZero(t, cache.Entries)
+	}
+
+	// Heaps without framework objects have no reports
+	empty := ComputeFrameworkStats(runTestJob(t, motifTestDump()), DefaultFrameworkStatsLimit)
+	assert.Nil(t, empty.Sessions)
+	assert.Nil(t, empty.Caches)
+	assert.Nil(t, empty.PersistenceContexts)
+	assert.Nil(t, ComputeFrameworkStats(&HeapAnalysisResult{}, DefaultFrameworkStatsLimit))
+}
@@ -0,0 +1,246 @@
+// Package hprof provides parsing functionality for Java HPROF heap dump files.
+package hprof
+
+import (
+	"context"
+	"sort"
+)
+
+// ClassDiffStatus classifies how a class changed between two heap dumps.
+type ClassDiffStatus string
+
+const (
+	ClassDiffNew       ClassDiffStatus = "new"
+	ClassDiffRemoved   ClassDiffStatus = "removed"
+	ClassDiffGrown     ClassDiffStatus = "grown"
+	ClassDiffShrunk    ClassDiffStatus = "shrunk"
+	ClassDiffUnchanged ClassDiffStatus = "unchanged"
+)
+
+// ClassDiff is the change of one class between a base and a target heap dump.
+type ClassDiff struct {
+	ClassName string          `json:"class_name"`
+	Status    ClassDiffStatus `json:"status"`
+
+	BaseInstances   int64 `json:"base_instances"`
+	TargetInstances int64 `json:"target_instances"`
+	DeltaInstances  int64 `json:"delta_instances"`
+
+	BaseSize   int64 `json:"base_size"`
+	TargetSize int64 `json:"target_size"`
+	DeltaSize  int64 `json:"delta_size"`
+
+	BaseRetained   int64 `json:"base_retained,omitempty"`
+	TargetRetained int64 `json:"target_retained,omitempty"`
+	DeltaRetained  int64 `json:"delta_retained,omitempty"`
+
+	// GrowthPercent is DeltaSize relative to BaseSize; 0 for new classes.
+	GrowthPercent float64 `json:"growth_percent"`
+}
+
+// HeapDiff is the class-level comparison of two heap dumps.
+type HeapDiff struct {
+	BaseTotalSize        int64 `json:"base_total_size"`
+	TargetTotalSize      int64 `json:"target_total_size"`
+	DeltaTotalSize       int64 `json:"delta_total_size"`
+	BaseTotalInstances   int64 `json:"base_total_instances"`
+	TargetTotalInstances int64 `json:"target_total_instances"`
+	DeltaTotalInstances  int64 `json:"delta_total_instances"`
+
+	NewClasses       int `json:"new_classes"`
+	RemovedClasses   int `json:"removed_classes"`
+	GrownClasses     int `json:"grown_classes"`
+	ShrunkClasses    int `json:"shrunk_classes"`
+	UnchangedClasses int `json:"unchanged_classes"`
+
+	// Classes are sorted by absolute size delta descending
+	Classes []*ClassDiff `json:"classes"`
+}
+
+// DiffAnalyzer compares the class histograms of two heap dumps.
+type DiffAnalyzer struct {
+	// IncludeUnchanged keeps classes whose size and instance count did not change
+	IncludeUnchanged bool
+}
+
+// NewDiffAnalyzer creates a diff analyzer that drops unchanged classes.
+func NewDiffAnalyzer() *DiffAnalyzer {
+	return &DiffAnalyzer{}
+}
+
+// Diff compares the base and target class histograms. Classes are matched by
+// name, so the same class loaded by different class loaders is merged.
+func (a *DiffAnalyzer) Diff(base, target []*ClassStats) *HeapDiff {
+	diffs := make(map[string]*ClassDiff, len(target))
+	var order []string
+	get := func(name string) *ClassDiff {
+		d, ok := diffs[name]
+		if !ok {
+			d = &ClassDiff{ClassName: name}
+			diffs[name] = d
+			order = append(order, name)
+		}
+		return d
+	}
+
+	result := &HeapDiff{}
+	for _, cls := range base {
+		d := get(cls.ClassName)
+		d.BaseInstances += cls.InstanceCount
+		d.BaseSize += cls.TotalSize
+		d.BaseRetained += cls.RetainedSize
+		result.BaseTotalSize += cls.TotalSize
+		result.BaseTotalInstances += cls.InstanceCount
+	}
+	for _, cls := range target {
+		d := get(cls.ClassName)
+		d.TargetInstances += cls.InstanceCount
+		d.TargetSize += cls.TotalSize
+		d.TargetRetained += cls.RetainedSize
+		result.TargetTotalSize += cls.TotalSize
+		result.TargetTotalInstances += cls.InstanceCount
+	}
+	result.DeltaTotalSize = result.TargetTotalSize - result.BaseTotalSize
+	result.DeltaTotalInstances = result.TargetTotalInstances - result.BaseTotalInstances
+
+	result.Classes = make([]*ClassDiff, 0, len(order))
+	for _, name := range order {
+		d := diffs[name]
+		d.DeltaInstances = d.TargetInstances - d.BaseInstances
+		d.DeltaSize = d.TargetSize - d.BaseSize
+		d.DeltaRetained = d.TargetRetained - d.BaseRetained
+		if d.BaseSize > 0 {
+			d.GrowthPercent = float64(d.DeltaSize) * 100 / float64(d.BaseSize)
+		}
+
+		switch {
+		case d.BaseInstances == 0 && d.TargetInstances > 0:
+			d.Status = ClassDiffNew
+			result.NewClasses++
+		case d.BaseInstances > 0 && d.TargetInstances == 0:
+			d.Status = ClassDiffRemoved
+			result.RemovedClasses++
+		case d.DeltaSize > 0 || (d.DeltaSize == 0 && d.DeltaInstances > 0):
+			d.Status = ClassDiffGrown
+			result.GrownClasses++
+		case d.DeltaSize < 0 || d.DeltaInstances < 0:
+			d.Status = ClassDiffShrunk
+			result.ShrunkClasses++
+		default:
+			d.Status = ClassDiffUnchanged
+			result.UnchangedClasses++
+			if !a.IncludeUnchanged {
+				continue
+			}
+		}
+		result.Classes = append(result.Classes, d)
+	}
+
+	sort.SliceStable(result.Classes, func(i, j int) bool {
+		di, dj := abs64(result.Classes[i].DeltaSize), abs64(result.Classes[j].DeltaSize)
+		if di != dj {
+			return di > dj
+		}
+		return result.Classes[i].ClassName < result.Classes[j].ClassName
+	})
+	return result
+}
+
+// ObjectDiff is the change of the retained size of one object between a base
+// and a target heap dump. Objects are matched by ID, class and shallow size.
+type ObjectDiff struct {
+	ObjectID  uint64          `json:"object_id"`
+	ClassName string          `json:"class_name"`
+	Status    ClassDiffStatus `json:"status"`
+
+	BaseRetained   int64 `json:"base_retained"`
+	TargetRetained int64 `json:"target_retained"`
+	DeltaRetained  int64 `json:"delta_retained"`
+}
+
+// HeapObjectDiff is the object-level comparison of two heap dumps.
+type HeapObjectDiff struct {
+	// Dominators describes how the target dominators were derived from the base
+	Dominators *DominatorUpdate `json:"dominators"`
+
+	// Objects are sorted by absolute retained size delta descending
+	Objects []*ObjectDiff `json:"objects"`
+}
+
+// DiffObjects compares the retained sizes of the objects of two heap dumps,
+// returning the limit objects that changed most (0 for all). The dominators
+// of target are derived from those of base with ComputeDominatorTreeFrom.
+func (a *DiffAnalyzer) DiffObjects(ctx context.Context, base, target *ReferenceGraph, limit int) (*HeapObjectDiff, error) {
+	update, err := target.ComputeDominatorTreeFrom(ctx, base)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &HeapObjectDiff{Dominators: update}
+	for objID, classID := range target.objectClass {
+		d := &ObjectDiff{
+			ObjectID:       objID,
+			ClassName:      target.GetClassName(classID),
+			TargetRetained: target.retainedSizes[objID],
+		}
+		baseClassID, ok := base.objectClass[objID]
+		if ok && base.objectSize[objID] == target.objectSize[objID] && base.GetClassName(baseClassID) == d.ClassName {
+			d.BaseRetained = base.retainedSizes[objID]
+		} else {
+			d.Status = ClassDiffNew
+		}
+		result.add(d, a.IncludeUnchanged)
+	}
+	for objID, classID := range base.objectClass {
+		targetClassID, ok := target.objectClass[objID]
+		className := base.GetClassName(classID)
+		if ok && base.objectSize[objID] == target.objectSize[objID] && target.GetClassName(targetClassID) == className {
+			continue
+		}
+		result.add(&ObjectDiff{
+			ObjectID:     objID,
+			ClassName:    className,
+			Status:       ClassDiffRemoved,
+			BaseRetained: base.retainedSizes[objID],
+		}, a.IncludeUnchanged)
+	}
+
+	sort.Slice(result.Objects, func(i, j int) bool {
+		di, dj := abs64(result.Objects[i].DeltaRetained), abs64(result.Objects[j].DeltaRetained)
+		if di != dj {
+			return di > dj
+		}
+		return result.Objects[i].ObjectID < result.Objects[j].ObjectID
+	})
+	if limit > 0 && len(result.Objects) > limit {
+		result.Objects = result.Objects[:limit]
+	}
+	return result, nil
+}
+
+// add classifies an object diff without status by its retained size delta
+// and appends it, dropping unchanged objects unless includeUnchanged is set.
+func (r *HeapObjectDiff) add(d *ObjectDiff, includeUnchanged bool) {
+	d.DeltaRetained = d.TargetRetained - d.BaseRetained
+	if d.Status == "" {
+		switch {
+		case d.DeltaRetained > 0:
+			d.Status = ClassDiffGrown
+		case d.DeltaRetained < 0:
+			d.Status = ClassDiffShrunk
+		default:
+			d.Status = ClassDiffUnchanged
+			if !includeUnchanged {
+				return
+			}
+		}
+	}
+	r.Objects = append(r.Objects, d)
+}
+
+func abs64(v int64) int64 {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
@@ -0,0 +1,96 @@
+package hprof
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiffAnalyzer_Diff(t *testing.T) {
+	base := []*ClassStats{
+		{ClassName: "byte[]", InstanceCount: 10, TotalSize: 1000},
+		{ClassName: "java.lang.String", InstanceCount: 5, TotalSize: 120},
+		{ClassName: "com.example.Old", InstanceCount: 2, TotalSize: 64},
+		{ClassName: "java.lang.Object", InstanceCount: 1, TotalSize: 16},
+	}
+	target := []*ClassStats{
+		{ClassName: "byte[]", InstanceCount: 30, TotalSize: 5000},
+		{ClassName: "java.lang.String", InstanceCount: 4, TotalSize: 96},
+		{ClassName: "com.example.New", InstanceCount: 1, TotalSize: 48},
+		{ClassName: "java.lang.Object", InstanceCount: 1, TotalSize: 16},
+	}
+
+	diff := NewDiffAnalyzer().Diff(base, target)
+	assert.Equal(t, int64(1200), diff.BaseTotalSize)
+	assert.Equal(t, int64(5160), diff.TargetTotalSize)
+	assert.Equal(t, int64(3960), diff.DeltaTotalSize)
+	assert.Equal(t, 1, diff.NewClasses)
+	assert.Equal(t, 1, diff.RemovedClasses)
+	assert.Equal(t, 1, diff.GrownClasses)
+	assert.Equal(t, 1, diff.ShrunkClasses)
+	assert.Equal(t, 1, diff.UnchangedClasses)
+
+	// Unchanged classes are dropped; the rest is ordered by |delta size|
+	require.Len(t, diff.Classes, 4)
+	assert.Equal(t, "byte[]", diff.Classes[0].ClassName)
+	assert.Equal(t, ClassDiffGrown, diff.Classes[0].Status)
+	assert.Equal(t, int64(20), diff.Classes[0].DeltaInstances)
+	assert.InDelta(t, 400.0, diff.Classes[0].GrowthPercent, 0.001)
+	assert.Equal(t, "com.example.Old", diff.Classes[1].ClassName)
+	assert.Equal(t, ClassDiffRemoved, diff.Classes[1].Status)
+	assert.Equal(t, "com.example.New", diff.Classes[2].ClassName)
+	assert.Equal(t, ClassDiffNew, diff.Classes[2].Status)
+	assert.Equal(t, ClassDiffShrunk, diff.Classes[3].Status)
+
+	all := (&DiffAnalyzer{IncludeUnchanged: true}).Diff(base, target)
+	assert.Len(t, all.Classes, 5)
+}
+
+func TestDiffAnalyzer_DiffObjects(t *testing.T) {
+	// root(1) -> cache(2) -> entry(3); the target adds entry(4) to the cache
+	// and drops the unreachable object 5
+	newGraph := func() *ReferenceGraph {
+		g := NewReferenceGraph()
+		g.SetClassName(10, "com.example.Root")
+		g.SetClassName(11, "com.example.Cache")
+		g.SetClassName(12, "com.example.Entry")
+		g.SetObjectInfo(1, 10, 16)
+		g.SetObjectInfo(2, 11, 32)
+		g.SetObjectInfo(3, 12, 100)
+		g.AddReference(ObjectReference{FromObjectID: 1, ToObjectID: 2, FromClassID: 10})
+		g.AddReference(ObjectReference{FromObjectID: 2, ToObjectID: 3, FromClassID: 11})
+		g.AddGCRoot(&GCRoot{ObjectID: 1, Type: GCRootJNIGlobal})
+		return g
+	}
+	base := newGraph()
+	base.SetObjectInfo(5, 12, 8)
+	target := newGraph()
+	target.SetObjectInfo(4, 12, 200)
+	target.AddReference(ObjectReference{FromObjectID: 2, ToObjectID: 4, FromClassID: 11})
+
+	diff, err := NewDiffAnalyzer().DiffObjects(context.Background(), base, target, 0)
+	require.NoError(t, err)
+	assert.Equal(t, 3, diff.Dominators.MatchedObjects)
+	assert.Equal(t, 1, diff.Dominators.NewObjects)
+	assert.Equal(t, 1, diff.Dominators.RemovedObjects)
+
+	// Root and cache grew by the new entry; entry 3 is unchanged and dropped
+	require.Len(t, diff.Objects, 4)
+	assert.Equal(t, uint64(1), diff.Objects[0].ObjectID)
+	assert.Equal(t, ClassDiffGrown, diff.Objects[0].Status)
+	assert.Equal(t, int64(148), diff.Objects[0].BaseRetained)
+	assert.Equal(t, int64(200), diff.Objects[0].DeltaRetained)
+	assert.Equal(t, uint64(2), diff.Objects[1].ObjectID)
+	assert.Equal(t, uint64(4), diff.Objects[2].ObjectID)
+	assert.Equal(t, ClassDiffNew, diff.Objects[2].Status)
+	assert.Equal(t, uint64(5), diff.Objects[3].ObjectID)
+	assert.Equal(t, ClassDiffRemoved, diff.Objects[3].Status)
+	assert.Equal(t, int64(-8), diff.Objects[3].DeltaRetained)
+
+	top, err := NewDiffAnalyzer().DiffObjects(context.Background(), base, newGraph(), 1)
+	require.NoError(t, err)
+	require.Len(t, top.Objects, 1)
+	assert.Equal(t, uint64(5), top.Objects[0].ObjectID)
+}
@@ -0,0 +1,156 @@
+package hprof
+
+import (
+	"fmt"
+	"sort"
+)
+
+// G1 region sizes. Without -XX:G1HeapRegionSize, G1 sizes regions so that
+// the heap has about g1TargetRegions of them, between 1MB and 32MB.
+const (
+	minG1RegionSize = 1 << 20
+	maxG1RegionSize = 512 << 20
+	// maxErgonomicG1RegionSize is the largest region size G1 picks itself
+	maxErgonomicG1RegionSize = 32 << 20
+	g1TargetRegions          = 2048
+)
+
+// DefaultHumongousObjectsLimit is the number of humongous objects listed.
+const DefaultHumongousObjectsLimit = 100
+
+// HumongousObjects reports the objects G1 allocates as humongous: objects
+// larger than half a region get regions of their own, and the end of the
+// last of them is wasted.
+type HumongousObjects struct {
+	RegionSize int64 `json:"region_size"`
+	// Threshold is half a region: larger objects are humongous
+	Threshold int64 `json:"threshold"`
+	Count     int64 `json:"count"`
+	TotalSize int64 `json:"total_size"`
+	// Regions is the number of regions the humongous objects occupy
+	Regions int64 `json:"regions"`
+	// Waste is the unused end of the last region of every humongous object
+	Waste int64 `json:"waste"`
+	// Classes are sorted by total size descending
+	Classes []*HumongousClass `json:"classes,omitempty"`
+	// Objects are the largest humongous objects, largest first
+	Objects []*HumongousObject `json:"objects,omitempty"`
+}
+
+// HumongousClass is the humongous instances of a class.
+type HumongousClass struct {
+	ClassName string `json:"class_name"`
+	Count     int64  `json:"count"`
+	TotalSize int64  `json:"total_size"`
+	Regions   int64  `json:"regions"`
+	Waste     int64  `json:"waste"`
+}
+
+// HumongousObject is one humongous object.
+type HumongousObject struct {
+	ObjectID  uint64 `json:"object_id"`
+	ClassName string `json:"class_name"`
+	Size      int64  `json:"size"`
+	Regions   int64  `json:"regions"`
+	Waste     int64  `json:"waste"`
+}
+
+// G1RegionSize returns the region size G1 chooses for a heap of heapSize
+// bytes when -XX:G1HeapRegionSize is not set. Heap dumps only hold the live
+// objects, so the region size of the JVM may be larger.
+func G1RegionSize(heapSize int64) int64 {
+	size := int64(minG1RegionSize)
+	for size < maxErgonomicG1RegionSize && size*2*g1TargetRegions <= heapSize {
+		size *= 2
+	}
+	return size
+}
+
+// ValidateG1RegionSize checks that size is a valid -XX:G1HeapRegionSize: a
+// power of two between 1MB and 512MB.
+func ValidateG1RegionSize(size int64) error {
+	if size < minG1RegionSize || size > maxG1RegionSize || size&(size-1) != 0 {
+		return fmt.Errorf("invalid G1 region size %d: must be a power of two between 1MB and 512MB", size)
+	}
+	return nil
+}
+
+// ComputeHumongousObjects sets the HumongousObjects of a result for G1
+// regions of regionSize bytes, or of the size G1 would choose for the heap
+// when regionSize is 0. At most limit objects are listed.
+func ComputeHumongousObjects(result *HeapAnalysisResult, regionSize int64, limit int) (*HumongousObjects, error) {
+	if result.RefGraph == nil {
+		return nil, nil
+	}
+	if regionSize == 0 {
+		regionSize = G1RegionSize(result.TotalHeapSize)
+	}
+	if err := ValidateG1RegionSize(regionSize); err != nil {
+		return nil, err
+	}
+	result.HumongousObjects = result.RefGraph.HumongousObjects(regionSize, limit)
+	return result.HumongousObjects, nil
+}
+
+// HumongousObjects returns the objects of g larger than half a region of
+// regionSize bytes, listing at most limit of them.
+func (g *ReferenceGraph) HumongousObjects(regionSize int64, limit int) *HumongousObjects {
+	report := &HumongousObjects{RegionSize: regionSize, Threshold: regionSize / 2}
+	byClass := make(map[uint64]*HumongousClass)
+	var objects []*HumongousObject
+	for objID, size := range g.objectSize {
+		if size <= report.Threshold {
+			continue
+		}
+		classID := g.objectClass[objID]
+		className := g.GetClassName(classID)
+		if className == "" {
+			className = "Unknown"
+		}
+		regions := (size + regionSize - 1) / regionSize
+		obj := &HumongousObject{
+			ObjectID:  objID,
+			ClassName: className,
+			Size:      size,
+			Regions:   regions,
+			Waste:     regions*regionSize - size,
+		}
+		objects = append(objects, obj)
+
+		cls, ok := byClass[classID]
+		if !ok {
+			cls = &HumongousClass{ClassName: className}
+			byClass[classID] = cls
+			report.Classes = append(report.Classes, cls)
+		}
+		cls.Count++
+		cls.TotalSize += size
+		cls.Regions += regions
+		cls.Waste += obj.Waste
+
+		report.Count++
+		report.TotalSize += size
+		report.Regions += regions
+		report.Waste += obj.Waste
+	}
+
+	sort.Slice(objects, func(i, j int) bool {
+		if objects[i].Size != objects[j].Size {
+			return objects[i].Size > objects[j].Size
+		}
+		return objects[i].ObjectID < objects[j].ObjectID
+	})
+	if limit > 0 && len(objects) > limit {
+		objects = objects[:limit]
+	}
+	report.Objects = objects
+
+	sort.Slice(report.Classes, func(i, j int) bool {
+		a, b := report.Classes[i], report.Classes[j]
+		if a.TotalSize != b.TotalSize {
+			return a.TotalSize > b.TotalSize
+		}
+		return a.ClassName < b.ClassName
+	})
+	return report
+}
@@ -0,0 +1,73 @@
+package hprof
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestG1RegionSize(t *testing.T) {
+	assert.Equal(t, int64(1<<20), G1RegionSize(0))
+	assert.Equal(t, int64(1<<20), G1RegionSize(3<<30))
+	assert.Equal(t, int64(2<<20), G1RegionSize(4<<30))
+	assert.Equal(t, int64(8<<20), G1RegionSize(20<<30))
+	assert.Equal(t, int64(32<<20), G1RegionSize(1<<40))
+
+	assert.NoError(t, ValidateG1RegionSize(4<<20))
+	assert.Error(t, ValidateG1RegionSize(3<<20))
+	assert.Error(t, ValidateG1RegionSize(512<<10))
+	assert.Error(t, ValidateG1RegionSize(1<<30))
+}
+
+func TestReferenceGraph_HumongousObjects(t *testing.T) {
+	const region = 1 << 20
+	g := NewReferenceGraphWithCapacity(8)
+	g.SetClassName(1, "byte[]")
+	g.SetClassName(2, "long[]")
+	g.SetObjectInfo(10, 1, region/2)   // exactly half a region is not humongous
+	g.SetObjectInfo(11, 1, region/2+8) // one region, almost half wasted
+	g.SetObjectInfo(12, 1, 2*region+16)
+	g.SetObjectInfo(13, 2, region)
+	g.SetObjectInfo(14, 2, 1024)
+
+	report := g.HumongousObjects(region, 2)
+	assert.Equal(t, int64(region/2), report.Threshold)
+	assert.Equal(t, int64(3), report.Count)
+	assert.Equal(t, int64(region/2+8+2*region+16+region), report.TotalSize)
+	assert.Equal(t, int64(1+3+1), report.Regions)
+	assert.Equal(t, report.Regions*region-report.TotalSize, report.Waste)
+
+	require.Len(t, report.Objects, 2)
+	assert.Equal(t, uint64(12), report.Objects[0].ObjectID)
+	assert.Equal(t, int64(3), report.Objects[0].Regions)
+	assert.Equal(t, int64(region-16), report.Objects[0].Waste)
+	assert.Equal(t, uint64(13), report.Objects[1].ObjectID)
+	assert.Zero(t, report.Objects[1].Waste)
+
+	require.Len(t, report.Classes, 2)
+	assert.Equal(t, HumongousClass{ClassName: "byte[]", Count: 2, TotalSize: region/2 + 8 + 2*region + 16,
+		Regions: 4, Waste: 4*region - (region/2 + 8 + 2*region + 16)}, *report.Classes[0])
+	assert.Equal(t, "long[]", report.Classes[1].ClassName)
+}
+
+func TestComputeHumongousObjects(t *testing.T) {
+	g := NewReferenceGraphWithCapacity(1)
+	g.SetClassName(1, "byte[]")
+	g.SetObjectInfo(10, 1, 3<<20)
+	result := &HeapAnalysisResult{RefGraph: g, TotalHeapSize: 3 << 20}
+
+	report, err := ComputeHumongousObjects(result, 0, DefaultHumongousObjectsLimit)
+	require.NoError(t, err)
+	assert.Same(t, report, result.HumongousObjects)
+	assert.Equal(t, int64(1<<20), report.RegionSize)
+	assert.Equal(t, int64(3), report.Regions)
+
+	report, err = ComputeHumongousObjects(result, 4<<20, DefaultHumongousObjectsLimit)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), report.Count)
+	assert.Equal(t, int64(1<<20), report.Waste)
+
+	_, err = ComputeHumongousObjects(result, 3<<20, DefaultHumongousObjectsLimit)
+	assert.Error(t, err)
+}
@@ -0,0 +1,214 @@
+package hprof
+
+import "sort"
+
+// Kinds of the Kafka client memory pools.
+const (
+	KafkaPoolProducer = "producer"
+	KafkaPoolNetwork  = "network"
+)
+
+// KafkaStats reports the records buffered by Kafka clients: the batches of
+// producers waiting to be sent, and the fetched and deserialized records of
+// consumers waiting to be polled.
+type KafkaStats struct {
+	// Producers and Fetchers are the numbers of record accumulators and
+	// consumer fetchers
+	Producers int64 `json:"producers"`
+	Fetchers  int64 `json:"fetchers"`
+	// Pools are the memory pools of the clients, largest use first
+	Pools []*KafkaMemoryPool `json:"pools,omitempty"`
+	// ProducerBatches are the batches of the record accumulators, and
+	// ProducerBytes their retained size
+	ProducerBatches int64 `json:"producer_batches"`
+	ProducerBytes   int64 `json:"producer_bytes"`
+	// CompletedFetches are the fetch responses not yet polled, and
+	// FetchedBytes their retained size
+	CompletedFetches int64 `json:"completed_fetches"`
+	FetchedBytes     int64 `json:"fetched_bytes"`
+	// Records are the deserialized ConsumerRecords, and RecordBytes their
+	// retained size
+	Records     int64 `json:"records"`
+	RecordBytes int64 `json:"record_bytes"`
+	// Partitions are the partitions buffering the most bytes; buffers whose
+	// topic could not be read from the heap dump are counted in
+	// UnattributedBytes instead
+	Partitions        []*KafkaPartitionBuffer `json:"partitions,omitempty"`
+	UnattributedBytes int64                   `json:"unattributed_bytes,omitempty"`
+}
+
+// KafkaMemoryPool is a producer buffer pool or a network memory pool.
+type KafkaMemoryPool struct {
+	ObjectID  uint64 `json:"object_id"`
+	ClassName string `json:"class_name"`
+	// Kind is producer or network
+	Kind string `json:"kind"`
+	// Capacity, Available and Used are the bytes of the pool, read from the
+	// heap dump
+	Capacity  int64 `json:"capacity"`
+	Available int64 `json:"available"`
+	Used      int64 `json:"used"`
+}
+
+// KafkaPartitionBuffer is the memory buffered for one topic partition.
+type KafkaPartitionBuffer struct {
+	Topic            string `json:"topic"`
+	Partition        int64  `json:"partition"`
+	ProducerBatches  int64  `json:"producer_batches"`
+	ProducerBytes    int64  `json:"producer_bytes"`
+	CompletedFetches int64  `json:"completed_fetches"`
+	FetchedBytes     int64  `json:"fetched_bytes"`
+	Records          int64  `json:"records"`
+	RecordBytes      int64  `json:"record_bytes"`
+}
+
+// Bytes returns the bytes buffered for the partition.
+func (p *KafkaPartitionBuffer) Bytes() int64 {
+	return p.ProducerBytes + p.FetchedBytes + p.RecordBytes
+}
+
+// kafkaPartitionKey identifies a topic partition.
+type kafkaPartitionKey struct {
+	topic     string
+	partition int64
+}
+
+// kafka reports the Kafka client buffers.
+func (a *frameworkAnalyzer) kafka() *KafkaStats {
+	producers := a.instancesOf(frameworkKafkaAccumulator)
+	fetchers := a.instancesOf(frameworkKafkaFetcher)
+	batches := a.instancesOf(frameworkKafkaBatch)
+	fetches := a.instancesOf(frameworkKafkaFetch)
+	records := a.instancesOf(frameworkKafkaRecord)
+	pools := a.instancesOf(frameworkKafkaBufferPool, frameworkKafkaMemoryPool)
+	if len(producers)+len(fetchers)+len(batches)+len(fetches)+len(records)+len(pools) == 0 {
+		return nil
+	}
+
+	stats := &KafkaStats{Producers: int64(len(producers)), Fetchers: int64(len(fetchers))}
+	for _, objID := range pools {
+		stats.Pools = append(stats.Pools, a.kafkaPool(objID))
+	}
+	sort.Slice(stats.Pools, func(i, j int) bool {
+		x, y := stats.Pools[i], stats.Pools[j]
+		if x.Used != y.Used {
+			return x.Used > y.Used
+		}
+		return x.ObjectID < y.ObjectID
+	})
+	if len(stats.Pools) > a.limit {
+		stats.Pools = stats.Pools[:a.limit]
+	}
+
+	partitions := make(map[kafkaPartitionKey]*KafkaPartitionBuffer)
+	topics := make(map[uint64]*kafkaPartitionKey)
+	partition := func(key *kafkaPartitionKey, size int64) *KafkaPartitionBuffer {
+		if key == nil {
+			stats.UnattributedBytes += size
+			return &KafkaPartitionBuffer{}
+		}
+		p, ok := partitions[*key]
+		if !ok {
+			p = &KafkaPartitionBuffer{Topic: key.topic, Partition: key.partition}
+			partitions[*key] = p
+		}
+		return p
+	}
+
+	for _, objID := range batches {
+		size := a.g.GetRetainedSize(objID)
+		stats.ProducerBatches++
+		stats.ProducerBytes += size
+		p := partition(a.kafkaTopicPartition(a.g.fieldRef(objID, "topicPartition"), topics), size)
+		p.ProducerBatches++
+		p.ProducerBytes += size
+	}
+	for _, objID := range fetches {
+		size := a.g.GetRetainedSize(objID)
+		stats.CompletedFetches++
+		stats.FetchedBytes += size
+		p := partition(a.kafkaTopicPartition(a.g.fieldRef(objID, "partition"), topics), size)
+		p.CompletedFetches++
+		p.FetchedBytes += size
+	}
+	for _, objID := range records {
+		size := a.g.GetRetainedSize(objID)
+		stats.Records++
+		stats.RecordBytes += size
+		p := partition(kafkaPartition(a.readFields(objID)), size)
+		p.Records++
+		p.RecordBytes += size
+	}
+
+	for _, p := range partitions {
+		stats.Partitions = append(stats.Partitions, p)
+	}
+	sort.Slice(stats.Partitions, func(i, j int) bool {
+		x, y := stats.Partitions[i], stats.Partitions[j]
+		if x.Bytes() != y.Bytes() {
+			return x.Bytes() > y.Bytes()
+		}
+		if x.Topic != y.Topic {
+			return x.Topic < y.Topic
+		}
+		return x.Partition < y.Partition
+	})
+	if len(stats.Partitions) > a.limit {
+		stats.Partitions = stats.Partitions[:a.limit]
+	}
+	return stats
+}
+
+// kafkaPool reads the capacity and free memory of a memory pool.
+func (a *frameworkAnalyzer) kafkaPool(objID uint64) *KafkaMemoryPool {
+	classID, _ := a.g.GetObjectClassID(objID)
+	pool := &KafkaMemoryPool{ObjectID: objID, ClassName: a.g.GetClassName(classID)}
+	fields := a.readFields(objID)
+
+	if frameworkClasses[a.baseClass(classID)] == frameworkKafkaBufferPool {
+		// Released buffers of batch.size bytes are kept in the free deque,
+		// the rest of buffer.memory is allocated on demand
+		pool.Kind = KafkaPoolProducer
+		pool.Capacity, _ = longField(fields, "totalMemory")
+		nonPooled, ok := longField(fields, "nonPooledAvailableMemory")
+		if !ok {
+			// Before Kafka 2.0
+			nonPooled, _ = longField(fields, "availableMemory")
+		}
+		poolable, _ := longField(fields, "poolableSize")
+		pool.Available = nonPooled + int64(a.collectionSize(a.g.fieldRef(objID, "free")))*poolable
+	} else {
+		pool.Kind = KafkaPoolNetwork
+		pool.Capacity, _ = longField(fields, "sizeBytes")
+		pool.Available, _ = longField(a.readFields(a.g.fieldRef(objID, "availableMemory")), "value")
+	}
+	if pool.Capacity > 0 {
+		pool.Used = pool.Capacity - pool.Available
+	}
+	return pool
+}
+
+// kafkaTopicPartition reads a TopicPartition, caching it by object. It
+// returns nil when the topic cannot be read.
+func (a *frameworkAnalyzer) kafkaTopicPartition(objID uint64, cache map[uint64]*kafkaPartitionKey) *kafkaPartitionKey {
+	if objID == 0 {
+		return nil
+	}
+	if key, ok := cache[objID]; ok {
+		return key
+	}
+	key := kafkaPartition(a.readFields(objID))
+	cache[objID] = key
+	return key
+}
+
+// kafkaPartition returns the topic and partition fields of an object, or
+// nil.
+func kafkaPartition(fields map[string]*FieldValue) *kafkaPartitionKey {
+	v, ok := fields["topic"]
+	if !ok || v.StringValue == nil {
+		return nil
+	}
+	partition, _ := longField(fields, "partition")
+	return &kafkaPartitionKey{topic: *v.StringValue, partition: partition}
+}
@@ -0,0 +1,142 @@
+package hprof
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// kafkaTestDump returns a heap dump with a producer buffering three batches
+// for two partitions of a topic, and a consumer holding a fetch response and
+// two deserialized records of the second partition.
+func kafkaTestDump() []byte {
+	b := newTestHprofBuilder()
+	for id, name := range map[uint64]string{
+		0x10: "java/lang/String", 0x11: "[Ljava/lang/Object;",
+		0x20: "org/apache/kafka/common/TopicPartition", 0x21: "org/apache/kafka/clients/producer/internals/ProducerBatch",
+		0x22: "org/apache/kafka/clients/producer/internals/RecordAccumulator",
+		0x23: "org/apache/kafka/clients/producer/internals/BufferPool", 0x24: "java/util/ArrayDeque",
+		0x26: "org/apache/kafka/clients/consumer/internals/Fetcher$CompletedFetch",
+		0x27: "org/apache/kafka/clients/consumer/internals/Fetcher",
+		0x28: "org/apache/kafka/clients/consumer/ConsumerRecord",
+		0x29: "org/apache/kafka/common/memory/SimpleMemoryPool", 0x2a: "java/util/concurrent/atomic/AtomicLong",
+	} {
+		b.loadClass(id, name)
+	}
+	b.classDump(0x10, 0, 9, testField{"value", TypeObject}, testField{"coder", TypeByte})
+	b.classDump(0x11, 0, 0)
+	b.classDump(0x20, 0, 12, testField{"partition", TypeInt}, testField{"topic", TypeObject})
+	b.classDump(0x21, 0, 16, testField{"topicPartition", TypeObject}, testField{"buffer", TypeObject})
+	b.classDump(0x22, 0, 16, testField{"free", TypeObject}, testField{"batches", TypeObject})
+	b.classDump(0x23, 0, 28, testField{"totalMemory", TypeLong}, testField{"poolableSize", TypeInt},
+		testField{"nonPooledAvailableMemory", TypeLong}, testField{"free", TypeObject})
+	b.classDump(0x24, 0, 8, testField{"elements", TypeObject})
+	b.classDump(0x26, 0, 16, testField{"partition", TypeObject}, testField{"records", TypeObject})
+	b.classDump(0x27, 0, 8, testField{"completedFetches", TypeObject})
+	b.classDump(0x28, 0, 20, testField{"topic", TypeObject}, testField{"partition", TypeInt}, testField{"value", TypeObject})
+	b.classDump(0x29, 0, 16, testField{"sizeBytes", TypeLong}, testField{"availableMemory", TypeObject})
+	b.classDump(0x2a, 0, 8, testField{"value", TypeLong})
+
+	// The partitions orders-0 and orders-1
+	b.instanceDump(0x1000, 0x20, append(intBytes(0), refBytes(0x1010)...))
+	b.instanceDump(0x1001, 0x20, append(intBytes(1), refBytes(0x1010)...))
+	b.instanceDump(0x1010, 0x10, append(refBytes(0x1011), 0))
+	b.primitiveArrayDump(0x1011, TypeByte, 6, []byte("orders"))
+
+	// A producer with a 32MB buffer pool, 2 free 16KB buffers and 30MB
+	// never allocated
+	b.instanceDump(0x2000, 0x22, refBytes(0x2100, 0x2010))
+	b.objectArrayDump(0x2010, 0x11, 0x2020, 0x2021, 0x2022)
+	batch := func(id, partition, buffer uint64, size int) {
+		b.instanceDump(id, 0x21, refBytes(partition, buffer))
+		b.primitiveArrayDump(buffer, TypeByte, size, make([]byte, size))
+	}
+	batch(0x2020, 0x1000, 0x2030, 16384)
+	batch(0x2021, 0x1000, 0x2031, 4096)
+	batch(0x2022, 0x1001, 0x2032, 1024)
+	b.instanceDump(0x2100, 0x23, append(append(append(longBytes(32<<20), intBytes(16384)...), longBytes(30<<20)...), refBytes(0x2110)...))
+	b.instanceDump(0x2110, 0x24, refBytes(0x2111))
+	b.objectArrayDump(0x2111, 0x11, 0x2112, 0x2113, 0)
+	b.primitiveArrayDump(0x2112, TypeByte, 1, []byte{0})
+	b.primitiveArrayDump(0x2113, TypeByte, 1, []byte{0})
+	b.rootJNIGlobal(0x2000)
+
+	// A consumer with a fetch response and 2 polled records of orders-1
+	b.instanceDump(0x3000, 0x27, refBytes(0x3010))
+	b.objectArrayDump(0x3010, 0x11, 0x3020)
+	b.instanceDump(0x3020, 0x26, refBytes(0x1001, 0x3030))
+	b.primitiveArrayDump(0x3030, TypeByte, 2048, make([]byte, 2048))
+	b.rootJNIGlobal(0x3000)
+	b.instanceDump(0x4000, 0x28, append(append(refBytes(0x1010), intBytes(1)...), refBytes(0x4010)...))
+	b.instanceDump(0x4001, 0x28, append(append(refBytes(0x1010), intBytes(1)...), refBytes(0x4011)...))
+	b.primitiveArrayDump(0x4010, TypeByte, 100, make([]byte, 100))
+	b.primitiveArrayDump(0x4011, TypeByte, 100, make([]byte, 100))
+	b.objectArrayDump(0x4020, 0x11, 0x4000, 0x4001)
+	b.rootJNIGlobal(0x4020)
+
+	// A network memory pool with 600 of 1000 bytes in use
+	b.instanceDump(0x5000, 0x29, append(longBytes(1000), refBytes(0x5010)...))
+	b.instanceDump(0x5010, 0x2a, longBytes(400))
+	b.rootJNIGlobal(0x5000)
+	return b.bytes()
+}
+
+func TestComputeFrameworkStats_Kafka(t *testing.T) {
+	result := runTestJob(t, kafkaTestDump())
+	g := result.RefGraph
+
+	stats := ComputeFrameworkStats(result, DefaultFrameworkStatsLimit)
+	require.NotNil(t, stats)
+	kafka := stats.Kafka
+	require.NotNil(t, kafka)
+	assert.Equal(t, int64(1), kafka.Producers)
+	assert.Equal(t, int64(1), kafka.Fetchers)
+
+	require.Len(t, kafka.Pools, 2)
+	producer := kafka.Pools[0]
+	assert.Equal(t, uint64(0x2100), producer.ObjectID)
+	assert.Equal(t, KafkaPoolProducer, producer.Kind)
+	assert.Equal(t, int64(32<<20), producer.Capacity)
+	assert.Equal(t, int64(30<<20+2*16384), producer.Available)
+	assert.Equal(t, int64(2<<20-2*16384), producer.Used)
+	network := kafka.Pools[1]
+	assert.Equal(t, KafkaPoolNetwork, network.Kind)
+	assert.Equal(t, "org.apache.kafka.common.memory.SimpleMemoryPool", network.ClassName)
+	assert.Equal(t, int64(1000), network.Capacity)
+	assert.Equal(t, int64(600), network.Used)
+
+	assert.Equal(t, int64(3), kafka.ProducerBatches)
+	assert.Equal(t, g.GetRetainedSize(0x2020)+g.GetRetainedSize(0x2021)+g.GetRetainedSize(0x2022), kafka.ProducerBytes)
+	assert.Equal(t, int64(1), kafka.CompletedFetches)
+	assert.Equal(t, g.GetRetainedSize(0x3020), kafka.FetchedBytes)
+	assert.Greater(t, kafka.FetchedBytes, int64(2048))
+	assert.Equal(t, int64(2), kafka.Records)
+	assert.Equal(t, g.GetRetainedSize(0x4000)+g.GetRetainedSize(0x4001), kafka.RecordBytes)
+	assert.Zero(t, kafka.UnattributedBytes)
+
+	require.Len(t, kafka.Partitions, 2)
+	first, second := kafka.Partitions[0], kafka.Partitions[1]
+	assert.Equal(t, "orders", first.Topic)
+	assert.Equal(t, int64(0), first.Partition)
+	assert.Equal(t, int64(2), first.ProducerBatches)
+	assert.Equal(t, g.GetRetainedSize(0x2020)+g.GetRetainedSize(0x2021), first.ProducerBytes)
+	assert.Zero(t, first.Records)
+	assert.Equal(t, "orders", second.Topic)
+	assert.Equal(t, int64(1), second.Partition)
+	assert.Equal(t, int64(1), second.ProducerBatches)
+	assert.Equal(t, int64(1), second.CompletedFetches)
+	assert.Equal(t, int64(2), second.Records)
+	assert.Equal(t, second.ProducerBytes+second.FetchedBytes+second.RecordBytes, second.Bytes())
+
+	// Without the index, topics cannot be read
+	result.ObjectIndex = nil
+	kafka = ComputeFrameworkStats(result, 1).Kafka
+	require.NotNil(t, kafka)
+	assert.Empty(t, kafka.Partitions)
+	assert.Equal(t, kafka.ProducerBytes+kafka.FetchedBytes+kafka.RecordBytes, kafka.UnattributedBytes)
+	require.Len(t, kafka.Pools, 1)
+	assert.Zero(t, kafka.Pools[0].Capacity)
+
+	assert.Nil(t, ComputeFrameworkStats(runTestJob(t, nettyTestDump()), DefaultFrameworkStatsLimit).Kafka)
+}
@@ -0,0 +1,375 @@
+package hprof
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// RetentionMotifKind names a recurring retention pattern.
+type RetentionMotifKind string
+
+const (
+	// MotifListenerList is a listener, observer or callback collection that
+	// keeps growing because listeners are added but never removed.
+	MotifListenerList RetentionMotifKind = "listener_list"
+	// MotifIdentityMapKeys is a hash map holding keys of equal content as
+	// distinct entries: the key class does not override equals and hashCode,
+	// or keys were mutated after insertion, so lookups never find them again.
+	MotifIdentityMapKeys RetentionMotifKind = "identity_map_keys"
+	// MotifUnboundedCache is a plain map or set used as a cache, which has no
+	// size bound or eviction.
+	MotifUnboundedCache RetentionMotifKind = "unbounded_cache"
+)
+
+// Thresholds of the retention motifs.
+const (
+	// MinListenerEntries is the size from which a listener collection is reported
+	MinListenerEntries = 100
+	// MinCacheEntries is the size from which a cache without eviction is reported
+	MinCacheEntries = 1000
+	// MinIdentityMapEntries is the size from which map keys are compared
+	MinIdentityMapEntries = 100
+	// MinDuplicateKeys is the number of duplicate keys from which a map is reported
+	MinDuplicateKeys = 10
+
+	// maxKeysPerMap and maxKeyReads bound the keys read from the heap dump
+	maxKeysPerMap = 1000
+	maxKeyReads   = 100000
+	// maxMotifExamples is the number of example collections kept per motif
+	maxMotifExamples = 5
+)
+
+// RetentionMotif is a retention pattern recurring in the collections held by
+// one field of one class, e.g. the listener lists of every instance of a
+// widget class.
+type RetentionMotif struct {
+	Kind RetentionMotifKind `json:"kind"`
+	// Holder is the class and field referencing the collections, e.g.
+	// com.example.Button.listeners
+	Holder          string `json:"holder"`
+	CollectionClass string `json:"collection_class"`
+	// Count is the number of collections showing the pattern
+	Count        int   `json:"count"`
+	Entries      int64 `json:"entries"`
+	RetainedSize int64 `json:"retained_size"`
+	// Examples are the largest collections by retained size
+	Examples []*RetentionMotifExample `json:"examples"`
+}
+
+// RetentionMotifExample is one collection showing a retention pattern.
+type RetentionMotifExample struct {
+	ObjectID     uint64 `json:"object_id"`
+	HolderID     uint64 `json:"holder_id"`
+	Entries      int    `json:"entries"`
+	RetainedSize int64  `json:"retained_size"`
+	// DuplicateKeys is the number of keys equal to an earlier key of the
+	// same map, among the keys compared
+	DuplicateKeys int `json:"duplicate_keys,omitempty"`
+}
+
+// collectionShape tells how the entries of a collection class are reached.
+type collectionShape struct {
+	// array is the field of the backing array; its elements are the entries
+	array string
+	// chain is the field linking hash bucket nodes or list nodes
+	chain string
+	// first is the field of the first node of a linked list
+	first string
+	// inner is the field of the collection backing a set
+	inner string
+	isMap bool
+	// bounded collections evict entries by themselves
+	bounded bool
+}
+
+// collectionShapes are the JDK collections whose entries are counted.
+var collectionShapes = map[string]collectionShape{
+	"java.util.ArrayList":                               {array: "elementData"},
+	"java.util.Vector":                                  {array: "elementData"},
+	"java.util.ArrayDeque":                              {array: "elements"},
+	"java.util.concurrent.CopyOnWriteArrayList":         {array: "array"},
+	"java.util.LinkedList":                              {first: "first", chain: "next"},
+	"java.util.HashMap":                                 {array: "table", chain: "next", isMap: true},
+	"java.util.LinkedHashMap":                           {array: "table", chain: "next", isMap: true},
+	"java.util.Hashtable":                               {array: "table", chain: "next", isMap: true},
+	"java.util.concurrent.ConcurrentHashMap":            {array: "table", chain: "next", isMap: true},
+	"java.util.WeakHashMap":                             {array: "table", chain: "next", isMap: true, bounded: true},
+	"java.util.HashSet":                                 {inner: "map"},
+	"java.util.LinkedHashSet":                           {inner: "map"},
+	"java.util.concurrent.CopyOnWriteArraySet":          {inner: "al"},
+	"java.util.concurrent.ConcurrentHashMap$KeySetView": {inner: "map"},
+}
+
+var (
+	listenerFieldPattern = regexp.MustCompile(`(?i)(listener|observer|callback|subscriber|watcher)`)
+	cacheFieldPattern    = regexp.MustCompile(`(?i)cache`)
+)
+
+// RetentionMotifs holds the retention patterns found in a heap.
+type RetentionMotifs struct {
+	Motifs []*RetentionMotif `json:"motifs"`
+	// KeysCompared is set when map keys were read from the heap dump to find
+	// identity_map_keys motifs, which needs an indexed dump
+	KeysCompared bool `json:"keys_compared"`
+}
+
+// ComputeRetentionMotifs sets the RetentionMotifs of a result: recurring
+// retention patterns found from the shape of the reference graph and its
+// field names. Map keys are compared when the heap dump was indexed.
+func ComputeRetentionMotifs(result *HeapAnalysisResult) *RetentionMotifs {
+	g := result.RefGraph
+	if g == nil {
+		return nil
+	}
+	detector := &motifDetector{g: g, groups: make(map[string]*RetentionMotif)}
+	if result.ObjectIndex != nil && result.ObjectIndex.SourceFile != "" {
+		detector.reader = NewObjectReader(result.ObjectIndex, result.ClassLayouts)
+	}
+	result.RetentionMotifs = detector.detect()
+	return result.RetentionMotifs
+}
+
+// motifDetector finds the retention motifs of a graph.
+type motifDetector struct {
+	g      *ReferenceGraph
+	reader *ObjectReader
+	groups map[string]*RetentionMotif
+	reads  int
+}
+
+// detect checks every collection of a known class.
+func (d *motifDetector) detect() *RetentionMotifs {
+	names := make([]string, 0, len(collectionShapes))
+	for name := range collectionShapes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		classID, ok := d.g.getClassIDByName(name)
+		if !ok {
+			continue
+		}
+		for _, objID := range d.g.getObjectsByClass(classID) {
+			d.check(objID, name)
+		}
+	}
+
+	motifs := &RetentionMotifs{KeysCompared: d.reader != nil, Motifs: make([]*RetentionMotif, 0, len(d.groups))}
+	for _, motif := range d.groups {
+		sort.Slice(motif.Examples, func(i, j int) bool {
+			return motif.Examples[i].RetainedSize > motif.Examples[j].RetainedSize
+		})
+		if len(motif.Examples) > maxMotifExamples {
+			motif.Examples = motif.Examples[:maxMotifExamples]
+		}
+		motifs.Motifs = append(motifs.Motifs, motif)
+	}
+	sort.Slice(motifs.Motifs, func(i, j int) bool {
+		a, b := motifs.Motifs[i], motifs.Motifs[j]
+		if a.RetainedSize != b.RetainedSize {
+			return a.RetainedSize > b.RetainedSize
+		}
+		return a.Holder+string(a.Kind) < b.Holder+string(b.Kind)
+	})
+	return motifs
+}
+
+// check reports the motifs a collection shows.
+func (d *motifDetector) check(objID uint64, className string) {
+	shape := collectionShapes[className]
+	holderID, field, ok := d.holder(objID)
+	if !ok {
+		return // internal to another collection, or unreachable
+	}
+	holderClass, _ := d.g.GetObjectClassID(holderID)
+	holder := d.g.GetClassName(holderClass) + "." + field
+	if d.g.classObjectIDs[holderID] {
+		holder = d.g.GetClassName(holderID) + "." + field
+	}
+
+	entries := d.g.collectionEntries(objID, shape)
+	isMap := shape.isMap
+	bounded := shape.bounded
+	if shape.inner != "" {
+		if inner := d.g.fieldRef(objID, shape.inner); inner != 0 {
+			innerClass, _ := d.g.GetObjectClassID(inner)
+			innerShape := collectionShapes[d.g.GetClassName(innerClass)]
+			isMap = innerShape.isMap
+			bounded = innerShape.bounded
+		}
+	}
+	example := &RetentionMotifExample{
+		ObjectID:     objID,
+		HolderID:     holderID,
+		Entries:      len(entries),
+		RetainedSize: d.g.GetRetainedSize(objID),
+	}
+
+	if len(entries) >= MinListenerEntries && (listenerFieldPattern.MatchString(field) || d.holdsListeners(entries)) {
+		d.add(MotifListenerList, holder, className, example)
+	}
+	if len(entries) >= MinCacheEntries && !bounded && cacheFieldPattern.MatchString(field) {
+		d.add(MotifUnboundedCache, holder, className, example)
+	}
+	if isMap && len(entries) >= MinIdentityMapEntries && d.reader != nil {
+		if dup := d.duplicateKeys(entries); dup >= MinDuplicateKeys {
+			keyed := *example
+			keyed.DuplicateKeys = dup
+			d.add(MotifIdentityMapKeys, holder, className, &keyed)
+		}
+	}
+}
+
+// add records a collection showing a motif.
+func (d *motifDetector) add(kind RetentionMotifKind, holder, className string, example *RetentionMotifExample) {
+	key := string(kind) + "|" + holder + "|" + className
+	motif, ok := d.groups[key]
+	if !ok {
+		motif = &RetentionMotif{Kind: kind, Holder: holder, CollectionClass: className}
+		d.groups[key] = motif
+	}
+	motif.Count++
+	motif.Entries += int64(example.Entries)
+	motif.RetainedSize += example.RetainedSize
+	motif.Examples = append(motif.Examples, example)
+}
+
+// holder returns the object and field referencing a collection, skipping
+// collections backing other collections.
+func (d *motifDetector) holder(objID uint64) (uint64, string, bool) {
+	for _, ref := range d.g.incomingRefs[objID] {
+		fromClass, _ := d.g.GetObjectClassID(ref.FromObjectID)
+		if shape, ok := collectionShapes[d.g.GetClassName(fromClass)]; ok && shape.inner == ref.FieldName {
+			return 0, "", false
+		}
+	}
+	for _, ref := range d.g.incomingRefs[objID] {
+		if ref.FieldName != "" && !strings.HasPrefix(ref.FieldName, "[") {
+			return ref.FromObjectID, ref.FieldName, true
+		}
+	}
+	return 0, "", false
+}
+
+// collectionEntries returns the entries of a collection: its elements, or
+// the nodes of a map.
+func (g *ReferenceGraph) collectionEntries(objID uint64, shape collectionShape) []uint64 {
+	if shape.inner != "" {
+		inner := g.fieldRef(objID, shape.inner)
+		innerClass, _ := g.GetObjectClassID(inner)
+		innerShape, ok := collectionShapes[g.GetClassName(innerClass)]
+		if inner == 0 || !ok || innerShape.inner != "" {
+			return nil
+		}
+		return g.collectionEntries(inner, innerShape)
+	}
+
+	var heads []uint64
+	if shape.first != "" {
+		if first := g.fieldRef(objID, shape.first); first != 0 {
+			heads = append(heads, first)
+		}
+	} else if array := g.fieldRef(objID, shape.array); array != 0 {
+		for _, ref := range g.outgoingRefs[array] {
+			heads = append(heads, ref.ToObjectID)
+		}
+	}
+	if shape.chain == "" {
+		return heads
+	}
+
+	var entries []uint64
+	seen := make(map[uint64]bool)
+	for _, node := range heads {
+		for ; node != 0 && !seen[node]; node = g.fieldRef(node, shape.chain) {
+			seen[node] = true
+			entries = append(entries, node)
+		}
+	}
+	if shape.first != "" {
+		// Linked list entries are the nodes' items
+		items := entries[:0]
+		for _, node := range entries {
+			if item := g.fieldRef(node, "item"); item != 0 {
+				items = append(items, item)
+			}
+		}
+		return items
+	}
+	return entries
+}
+
+// fieldRef returns the object referenced by a field of an object, or 0.
+func (g *ReferenceGraph) fieldRef(objID uint64, name string) uint64 {
+	for _, ref := range g.outgoingRefs[objID] {
+		if ref.FieldName == name {
+			return ref.ToObjectID
+		}
+	}
+	return 0
+}
+
+// holdsListeners reports whether the first entry of a collection is a
+// listener by its class name.
+func (d *motifDetector) holdsListeners(entries []uint64) bool {
+	classID, ok := d.g.GetObjectClassID(entries[0])
+	return ok && listenerFieldPattern.MatchString(d.g.GetClassName(classID))
+}
+
+// duplicateKeys returns the number of keys of map nodes that have the same
+// class and field values as an earlier key.
+func (d *motifDetector) duplicateKeys(nodes []uint64) int {
+	f, err := d.reader.open()
+	if err != nil {
+		return 0
+	}
+	defer f.Close()
+
+	seen := make(map[string]bool)
+	duplicates := 0
+	for i, node := range nodes {
+		if i == maxKeysPerMap || d.reads == maxKeyReads {
+			break
+		}
+		key := d.g.fieldRef(node, "key")
+		if key == 0 {
+			continue
+		}
+		d.reads++
+		rec, err := d.reader.readRecord(f, key, 0)
+		if err != nil || rec.tag != HeapTagInstanceDump {
+			continue
+		}
+		signature := d.keySignature(key, d.reader.instanceFields(f, rec))
+		if seen[signature] {
+			duplicates++
+		}
+		seen[signature] = true
+	}
+	return duplicates
+}
+
+// keySignature returns the class and field values of a key, with Strings
+// compared by their text unless it was cut.
+func (d *motifDetector) keySignature(key uint64, fields []*FieldValue) string {
+	classID, _ := d.g.GetObjectClassID(key)
+	var b strings.Builder
+	b.WriteString(d.g.GetClassName(classID))
+	for _, v := range fields {
+		b.WriteByte('|')
+		b.WriteString(v.Name)
+		b.WriteByte('=')
+		switch {
+		case v.StringValue != nil && !strings.HasSuffix(*v.StringValue, "…"):
+			fmt.Fprintf(&b, "%q", *v.StringValue)
+		case v.RefID != 0:
+			fmt.Fprintf(&b, "@%x", v.RefID)
+		default:
+			fmt.Fprint(&b, v.Value)
+		}
+	}
+	return b.String()
+}
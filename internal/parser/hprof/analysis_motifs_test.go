@@ -0,0 +1,101 @@
+package hprof
+
+import (
+	"encoding/binary"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// motifTestDump returns a heap dump with a Button holding 100 listeners and
+// a Registry caching 1000 entries whose 1000 keys have 500 distinct values.
+func motifTestDump() []byte {
+	b := newTestHprofBuilder()
+	for id, name := range map[uint64]string{
+		0x10: "com/example/Button", 0x11: "java/util/ArrayList", 0x12: "[Ljava/lang/Object;",
+		0x13: "com/example/ClickListener", 0x14: "com/example/Registry", 0x15: "java/util/HashMap",
+		0x16: "java/util/HashMap$Node", 0x17: "[Ljava/util/HashMap$Node;", 0x18: "com/example/Key",
+	} {
+		b.loadClass(id, name)
+	}
+	b.classDump(0x10, 0, 8, testField{"listeners", TypeObject})
+	b.classDump(0x11, 0, 8, testField{"elementData", TypeObject})
+	b.classDump(0x12, 0, 0)
+	b.classDump(0x13, 0, 0)
+	b.classDump(0x14, 0, 8, testField{"cache", TypeObject})
+	b.classDump(0x15, 0, 8, testField{"table", TypeObject})
+	b.classDump(0x16, 0, 24, testField{"key", TypeObject}, testField{"value", TypeObject}, testField{"next", TypeObject})
+	b.classDump(0x17, 0, 0)
+	b.classDump(0x18, 0, 4, testField{"id", TypeInt})
+
+	b.instanceDump(0x1000, 0x10, refBytes(0x1100))
+	b.instanceDump(0x1100, 0x11, refBytes(0x1200))
+	var listeners []uint64
+	for i := uint64(0); i < 100; i++ {
+		b.instanceDump(0x2000+i, 0x13, nil)
+		listeners = append(listeners, 0x2000+i)
+	}
+	b.objectArrayDump(0x1200, 0x12, listeners...)
+	b.rootJNIGlobal(0x1000)
+
+	b.instanceDump(0x1500, 0x14, refBytes(0x1600))
+	b.instanceDump(0x1600, 0x15, refBytes(0x1700))
+	var nodes []uint64
+	for i := uint64(0); i < 1000; i++ {
+		b.instanceDump(0x10000+i, 0x16, refBytes(0x20000+i, 0, 0))
+		id := make([]byte, 4)
+		binary.BigEndian.PutUint32(id, uint32(i%500))
+		b.instanceDump(0x20000+i, 0x18, id)
+		nodes = append(nodes, 0x10000+i)
+	}
+	b.objectArrayDump(0x1700, 0x17, nodes...)
+	b.rootJNIGlobal(0x1500)
+	return b.bytes()
+}
+
+func TestComputeRetentionMotifs(t *testing.T) {
+	result := runTestJob(t, motifTestDump())
+
+	motifs := ComputeRetentionMotifs(result)
+	require.NotNil(t, motifs)
+	assert.Same(t, motifs, result.RetentionMotifs)
+	assert.True(t, motifs.KeysCompared)
+
+	byKind := make(map[RetentionMotifKind]*RetentionMotif)
+	for _, motif := range motifs.Motifs {
+		byKind[motif.Kind] = motif
+	}
+	require.Len(t, byKind, 3)
+
+	listeners := byKind[MotifListenerList]
+	assert.Equal(t, "com.example.Button.listeners", listeners.Holder)
+	assert.Equal(t, "java.util.ArrayList", listeners.CollectionClass)
+	assert.Equal(t, 1, listeners.Count)
+	assert.Equal(t, int64(100), listeners.Entries)
+	require.Len(t, listeners.Examples, 1)
+	assert.Equal(t, uint64(0x1100), listeners.Examples[0].ObjectID)
+	assert.Equal(t, uint64(0x1000), listeners.Examples[0].HolderID)
+
+	cache := byKind[MotifUnboundedCache]
+	assert.Equal(t, "com.example.Registry.cache", cache.Holder)
+	assert.Equal(t, int64(1000), cache.Entries)
+
+	keys := byKind[MotifIdentityMapKeys]
+	assert.Equal(t, "com.example.Registry.cache", keys.Holder)
+	require.Len(t, keys.Examples, 1)
+	assert.Equal(t, 500, keys.Examples[0].DuplicateKeys)
+}
+
+func TestComputeRetentionMotifs_NotIndexed(t *testing.T) {
+	result := runTestJob(t, motifTestDump())
+	result.ObjectIndex = nil
+
+	motifs := ComputeRetentionMotifs(result)
+	assert.False(t, motifs.KeysCompared)
+	for _, motif := range motifs.Motifs {
+		assert.NotEqual(t, MotifIdentityMapKeys, motif.Kind)
+	}
+	assert.Len(t, motifs.Motifs, 2)
+	assert.Nil(t, ComputeRetentionMotifs(&HeapAnalysisResult{}))
+}
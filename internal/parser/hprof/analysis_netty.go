@@ -0,0 +1,214 @@
+package hprof
+
+import (
+	"sort"
+	"strings"
+)
+
+// nettyRefCntUpdaterClass is loaded by Netty 4.1.32 and later, which store
+// twice the reference count of buffers, odd once released.
+const nettyRefCntUpdaterClass = "io.netty.util.internal.ReferenceCountUpdater"
+
+// Kinds of the pooled memory of Netty arenas.
+const (
+	NettyPoolHeap   = "heap"
+	NettyPoolDirect = "direct"
+)
+
+// NettyStats reports the pooled memory of Netty allocators and the buffers
+// that may leak it.
+type NettyStats struct {
+	// Pools are the heap and direct memory of the pool arenas
+	Pools []*NettyPoolStats `json:"pools"`
+	// EventLoops is the number of event loops the buffers are checked against
+	EventLoops int64 `json:"event_loops"`
+	// BuffersChecked is the number of buffers unreachable from the event
+	// loops whose reference count was read from the heap dump, and
+	// BuffersUnchecked the number left once the read budget was spent
+	BuffersChecked   int64 `json:"buffers_checked"`
+	BuffersUnchecked int64 `json:"buffers_unchecked,omitempty"`
+	// LeakedBuffers are the buffers that were not released, as their
+	// reference count is positive, but are unreachable from the event
+	// loops; their pooled memory is never returned to the arenas
+	LeakedBuffers int64 `json:"leaked_buffers"`
+	LeakedBytes   int64 `json:"leaked_bytes"`
+	// Leaks are the largest leaked buffers by capacity
+	Leaks []*NettyLeakedBuffer `json:"leaks,omitempty"`
+}
+
+// NettyPoolStats is the memory of the pool arenas of one kind.
+type NettyPoolStats struct {
+	// Kind is heap or direct
+	Kind   string `json:"kind"`
+	Arenas int64  `json:"arenas"`
+	Chunks int64  `json:"chunks"`
+	// Capacity and Used are the bytes of the pooled chunks and the bytes
+	// allocated from them, read from the heap dump
+	Capacity    int64   `json:"capacity"`
+	Used        int64   `json:"used"`
+	Utilization float64 `json:"utilization"`
+	// HugeChunks are the unpooled chunks of allocations larger than a chunk
+	HugeChunks int64 `json:"huge_chunks"`
+	HugeSize   int64 `json:"huge_size"`
+}
+
+// NettyLeakedBuffer is a buffer holding memory that was never released.
+type NettyLeakedBuffer struct {
+	ObjectID  uint64 `json:"object_id"`
+	ClassName string `json:"class_name"`
+	RefCnt    int64  `json:"ref_cnt"`
+	Capacity  int64  `json:"capacity"`
+	// Reachable is set when the buffer is still reachable from GC roots
+	Reachable bool `json:"reachable"`
+}
+
+// netty reports the Netty pools and leaked buffers.
+func (a *frameworkAnalyzer) netty() *NettyStats {
+	chunks := a.instancesOf(frameworkNettyChunk)
+	buffers := a.instancesOf(frameworkNettyByteBuf)
+	if len(chunks) == 0 && len(buffers) == 0 {
+		return nil
+	}
+
+	stats := &NettyStats{}
+	a.nettyPools(stats, chunks)
+	if len(buffers) > 0 {
+		a.nettyLeaks(stats, buffers)
+	}
+	return stats
+}
+
+// nettyPools sums the chunks of the arenas by kind.
+func (a *frameworkAnalyzer) nettyPools(stats *NettyStats, chunks []uint64) {
+	pools := map[string]*NettyPoolStats{
+		NettyPoolHeap:   {Kind: NettyPoolHeap},
+		NettyPoolDirect: {Kind: NettyPoolDirect},
+	}
+	for _, arena := range a.instancesOf(frameworkNettyArena) {
+		pools[a.nettyArenaKind(arena)].Arenas++
+	}
+
+	for _, chunk := range chunks {
+		pool := pools[a.nettyArenaKind(a.g.fieldRef(chunk, "arena"))]
+		fields := a.readFields(chunk)
+		size, _ := longField(fields, "chunkSize")
+		if v, ok := fields["unpooled"]; ok && v.Value == true {
+			pool.HugeChunks++
+			pool.HugeSize += size
+			continue
+		}
+		pool.Chunks++
+		pool.Capacity += size
+		if free, ok := longField(fields, "freeBytes"); ok {
+			pool.Used += size - free
+		}
+	}
+
+	for _, kind := range []string{NettyPoolHeap, NettyPoolDirect} {
+		pool := pools[kind]
+		if pool.Arenas == 0 && pool.Chunks == 0 && pool.HugeChunks == 0 {
+			continue
+		}
+		if pool.Capacity > 0 {
+			pool.Utilization = float64(pool.Used) * 100 / float64(pool.Capacity)
+		}
+		stats.Pools = append(stats.Pools, pool)
+	}
+}
+
+// nettyArenaKind returns whether an arena pools heap or direct memory.
+func (a *frameworkAnalyzer) nettyArenaKind(arena uint64) string {
+	classID, _ := a.g.GetObjectClassID(arena)
+	if strings.HasSuffix(a.g.GetClassName(classID), "$DirectArena") {
+		return NettyPoolDirect
+	}
+	return NettyPoolHeap
+}
+
+// nettyLeaks finds the buffers with a positive reference count that are
+// unreachable from the event loops.
+func (a *frameworkAnalyzer) nettyLeaks(stats *NettyStats, buffers []uint64) {
+	loops := a.instancesOf(frameworkNettyEventLoop)
+	stats.EventLoops = int64(len(loops))
+	reachable := a.reachableFrom(loops)
+	_, doubled := a.g.getClassIDByName(nettyRefCntUpdaterClass)
+
+	for _, buf := range buffers {
+		if reachable[buf] {
+			continue
+		}
+		fields := a.readFields(buf)
+		raw, ok := longField(fields, "refCnt")
+		if !ok {
+			stats.BuffersUnchecked++
+			continue
+		}
+		stats.BuffersChecked++
+		refCnt := raw
+		if doubled {
+			refCnt = 0
+			if raw&1 == 0 {
+				refCnt = raw >> 1
+			}
+		}
+		if refCnt <= 0 {
+			continue
+		}
+
+		// Pooled buffers have a length, unpooled ones a capacity or array
+		capacity, ok := longField(fields, "length")
+		if !ok {
+			capacity, _ = longField(fields, "capacity")
+		}
+		if capacity == 0 {
+			if array := a.g.fieldRef(buf, "array"); array != 0 {
+				capacity = a.g.GetObjectSize(array)
+			}
+		}
+		classID, _ := a.g.GetObjectClassID(buf)
+		stats.LeakedBuffers++
+		stats.LeakedBytes += capacity
+		stats.Leaks = append(stats.Leaks, &NettyLeakedBuffer{
+			ObjectID:  buf,
+			ClassName: a.g.GetClassName(classID),
+			RefCnt:    refCnt,
+			Capacity:  capacity,
+			Reachable: a.g.IsObjectReachable(buf),
+		})
+	}
+
+	sort.Slice(stats.Leaks, func(i, j int) bool {
+		x, y := stats.Leaks[i], stats.Leaks[j]
+		if x.Capacity != y.Capacity {
+			return x.Capacity > y.Capacity
+		}
+		return x.ObjectID < y.ObjectID
+	})
+	if len(stats.Leaks) > a.limit {
+		stats.Leaks = stats.Leaks[:a.limit]
+	}
+}
+
+// reachableFrom returns the objects reachable from roots through instance
+// fields and array elements. Class objects are not entered: their static
+// fields reach most of the heap.
+func (a *frameworkAnalyzer) reachableFrom(roots []uint64) map[uint64]bool {
+	reachable := make(map[uint64]bool)
+	stack := append([]uint64(nil), roots...)
+	for _, root := range roots {
+		reachable[root] = true
+	}
+	for len(stack) > 0 {
+		objID := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		for _, ref := range a.g.outgoingRefs[objID] {
+			to := ref.ToObjectID
+			if reachable[to] || a.g.classObjectIDs[to] {
+				continue
+			}
+			reachable[to] = true
+			stack = append(stack, to)
+		}
+	}
+	return reachable
+}
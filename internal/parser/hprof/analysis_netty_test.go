@@ -0,0 +1,133 @@
+package hprof
+
+import (
+	"encoding/binary"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// intBytes encodes int field values.
+func intBytes(values ...int32) []byte {
+	out := make([]byte, 4*len(values))
+	for i, v := range values {
+		binary.BigEndian.PutUint32(out[i*4:], uint32(v))
+	}
+	return out
+}
+
+// nettyTestDump returns a heap dump with direct and heap Netty arenas and
+// four pooled buffers: one held by an event loop, one released and two
+// leaked, of which one is still referenced by the application.
+func nettyTestDump() []byte {
+	b := newTestHprofBuilder()
+	for id, name := range map[uint64]string{
+		0x10: "io/netty/buffer/PoolArena", 0x11: "io/netty/buffer/PoolArena$DirectArena",
+		0x12: "io/netty/buffer/PoolArena$HeapArena", 0x13: "io/netty/buffer/PoolChunk",
+		0x14: "io/netty/buffer/AbstractReferenceCountedByteBuf", 0x15: "io/netty/buffer/PooledUnsafeDirectByteBuf",
+		0x16: "io/netty/util/concurrent/SingleThreadEventExecutor", 0x17: "io/netty/channel/nio/NioEventLoop",
+		0x18: "io/netty/util/internal/ReferenceCountUpdater", 0x19: "[Ljava/lang/Object;",
+		0x1a: "com/example/Handler",
+	} {
+		b.loadClass(id, name)
+	}
+	b.classDump(0x10, 0, 0)
+	b.classDump(0x11, 0x10, 0)
+	b.classDump(0x12, 0x10, 0)
+	b.classDump(0x13, 0, 25, testField{"arena", TypeObject}, testField{"memory", TypeObject},
+		testField{"chunkSize", TypeInt}, testField{"freeBytes", TypeInt}, testField{"unpooled", TypeBoolean})
+	b.classDump(0x14, 0, 4, testField{"refCnt", TypeInt})
+	b.classDump(0x15, 0x14, 12, testField{"chunk", TypeObject}, testField{"length", TypeInt})
+	b.classDump(0x16, 0, 0)
+	b.classDump(0x17, 0x16, 8, testField{"tasks", TypeObject})
+	b.classDump(0x18, 0, 0)
+	b.classDump(0x19, 0, 0)
+	b.classDump(0x1a, 0, 8, testField{"buffer", TypeObject})
+
+	const mb = 1 << 20
+	chunk := func(id, arena uint64, size, free int32, unpooled bool) {
+		flag := byte(0)
+		if unpooled {
+			flag = 1
+		}
+		data := append(refBytes(arena, 0), intBytes(size, free)...)
+		b.instanceDump(id, 0x13, append(data, flag))
+	}
+	b.instanceDump(0x1000, 0x11, nil)
+	b.instanceDump(0x1001, 0x12, nil)
+	chunk(0x1100, 0x1000, 16*mb, 12*mb, false)
+	chunk(0x1101, 0x1000, 16*mb, 0, false)
+	chunk(0x1102, 0x1001, mb, mb, false)
+	chunk(0x1103, 0x1000, 20*mb, 0, true)
+	b.rootJNIGlobal(0x1000)
+	b.rootJNIGlobal(0x1001)
+
+	// Buffers store twice their reference count, odd once released
+	buf := func(id uint64, length, raw int32) {
+		b.instanceDump(id, 0x15, append(append(refBytes(0x1100), intBytes(length)...), intBytes(raw)...))
+	}
+	buf(0x2000, 1024, 2)
+	buf(0x2001, 8192, 2)
+	buf(0x2002, 4096, 1)
+	buf(0x2003, 512, 4)
+
+	b.instanceDump(0x3000, 0x17, refBytes(0x3001))
+	b.objectArrayDump(0x3001, 0x19, 0x2000)
+	b.rootJNIGlobal(0x3000)
+	b.instanceDump(0x4000, 0x1a, refBytes(0x2003))
+	b.rootJNIGlobal(0x4000)
+	return b.bytes()
+}
+
+func TestComputeFrameworkStats_Netty(t *testing.T) {
+	result := runTestJob(t, nettyTestDump())
+
+	stats := ComputeFrameworkStats(result, DefaultFrameworkStatsLimit)
+	require.NotNil(t, stats)
+	netty := stats.Netty
+	require.NotNil(t, netty)
+
+	require.Len(t, netty.Pools, 2)
+	heap, direct := netty.Pools[0], netty.Pools[1]
+	assert.Equal(t, NettyPoolHeap, heap.Kind)
+	assert.Equal(t, int64(1), heap.Arenas)
+	assert.Equal(t, int64(1), heap.Chunks)
+	assert.Zero(t, heap.Used)
+	assert.Equal(t, NettyPoolDirect, direct.Kind)
+	assert.Equal(t, int64(1), direct.Arenas)
+	assert.Equal(t, int64(2), direct.Chunks)
+	assert.Equal(t, int64(32<<20), direct.Capacity)
+	assert.Equal(t, int64(20<<20), direct.Used)
+	assert.InDelta(t, 62.5, direct.Utilization, 0.001)
+	assert.Equal(t, int64(1), direct.HugeChunks)
+	assert.Equal(t, int64(20<<20), direct.HugeSize)
+
+	assert.Equal(t, int64(1), netty.EventLoops)
+	assert.Equal(t, int64(3), netty.BuffersChecked)
+	assert.Zero(t, netty.BuffersUnchecked)
+	assert.Equal(t, int64(2), netty.LeakedBuffers)
+	assert.Equal(t, int64(8192+512), netty.LeakedBytes)
+	require.Len(t, netty.Leaks, 2)
+	leak := netty.Leaks[0]
+	assert.Equal(t, uint64(0x2001), leak.ObjectID)
+	assert.Equal(t, "io.netty.buffer.PooledUnsafeDirectByteBuf", leak.ClassName)
+	assert.Equal(t, int64(1), leak.RefCnt)
+	assert.Equal(t, int64(8192), leak.Capacity)
+	assert.False(t, leak.Reachable)
+	assert.Equal(t, uint64(0x2003), netty.Leaks[1].ObjectID)
+	assert.Equal(t, int64(2), netty.Leaks[1].RefCnt)
+	assert.True(t, netty.Leaks[1].Reachable)
+
+	// Without the index, reference counts cannot be read
+	result.ObjectIndex = nil
+	netty = ComputeFrameworkStats(result, 1).Netty
+	require.NotNil(t, netty)
+	assert.Equal(t, int64(3), netty.BuffersUnchecked)
+	assert.Zero(t, netty.LeakedBuffers)
+	// and unpooled chunks are not told apart
+	assert.Equal(t, int64(3), netty.Pools[1].Chunks)
+	assert.Zero(t, netty.Pools[1].Capacity)
+
+	assert.Nil(t, ComputeFrameworkStats(runTestJob(t, frameworkTestDump()), DefaultFrameworkStatsLimit).Netty)
+}
@@ -0,0 +1,731 @@
+// Package hprof provides parsing functionality for Java HPROF heap dump files.
+package hprof
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// DefaultQueryMaxRows caps the rows returned by a query when the engine has no
+// explicit limit.
+const DefaultQueryMaxRows = 10000
+
+// QueryEngine runs OQL-style queries against a heap snapshot. It supports a
+// subset of Eclipse MAT's OQL:
+//
+//	SELECT * | column [, column ...]
+//	FROM [INSTANCEOF] <class name | "class regex"> [alias]
+//	[WHERE condition [AND | OR condition ...]]
+//	[ORDER BY column [ASC | DESC]]
+//	[LIMIT n]
+//
+// A column is a built-in attribute (@objectId, @className, @usedHeapSize,
+// @retainedHeapSize, @gcRoot) or the name of a reference field, optionally
+// prefixed by the alias ("s.next", "s.@retainedHeapSize"). A reference field
+// evaluates to the referenced object ID, or null. Primitive field values are
+// not kept in the snapshot and cannot be queried.
+//
+// A condition compares a column with a number, string, true/false or null
+// literal using =, !=, <, <=, >, >= or LIKE (a regular expression). AND binds
+// tighter than OR.
+type QueryEngine struct {
+	snapshot *HeapSnapshot
+	// MaxRows caps the rows of a result regardless of LIMIT
+	MaxRows int
+}
+
+// NewQueryEngine creates a query engine for a snapshot.
+func NewQueryEngine(snapshot *HeapSnapshot) *QueryEngine {
+	return &QueryEngine{snapshot: snapshot, MaxRows: DefaultQueryMaxRows}
+}
+
+// QueryResult is the result of a query. Every row is one matching object.
+type QueryResult struct {
+	Columns []string    `json:"columns"`
+	Rows    []*QueryRow `json:"rows"`
+	// Total is the number of matching objects before LIMIT and MaxRows
+	Total     int  `json:"total"`
+	Truncated bool `json:"truncated"`
+}
+
+// QueryRow is one object of a query result.
+type QueryRow struct {
+	ObjectID uint64        `json:"object_id"`
+	Values   []interface{} `json:"values"`
+}
+
+// QueryError is returned for a query that cannot be parsed or evaluated.
+type QueryError struct {
+	// Pos is the byte offset of the offending token in the query
+	Pos int
+	Msg string
+}
+
+// Error implements the error interface.
+func (e *QueryError) Error() string {
+	return fmt.Sprintf("query error at position %d: %s", e.Pos, e.Msg)
+}
+
+// Execute parses and runs a query.
+func (e *QueryEngine) Execute(query string) (*QueryResult, error) {
+	q, err := parseQuery(query)
+	if err != nil {
+		return nil, err
+	}
+
+	classIDs, err := e.matchClasses(q)
+	if err != nil {
+		return nil, err
+	}
+
+	var matched []uint64
+	for _, classID := range classIDs {
+		for _, objectID := range e.snapshot.graph.getObjectsByClass(classID) {
+			if q.where.matches(e, objectID) {
+				matched = append(matched, objectID)
+			}
+		}
+	}
+
+	if q.orderBy != nil {
+		col, desc := q.orderBy, q.desc
+		sort.SliceStable(matched, func(i, j int) bool {
+			c := compareOrdered(e.value(col, matched[i]), e.value(col, matched[j]))
+			if desc {
+				return c > 0
+			}
+			return c < 0
+		})
+	}
+
+	result := &QueryResult{Total: len(matched)}
+	for _, col := range q.columns {
+		result.Columns = append(result.Columns, col.name)
+	}
+
+	limit := len(matched)
+	if q.limit >= 0 && q.limit < limit {
+		limit = q.limit
+	}
+	if e.MaxRows > 0 && e.MaxRows < limit {
+		limit = e.MaxRows
+	}
+	result.Truncated = limit < len(matched)
+
+	result.Rows = make([]*QueryRow, 0, limit)
+	for _, objectID := range matched[:limit] {
+		row := &QueryRow{ObjectID: objectID, Values: make([]interface{}, len(q.columns))}
+		for i, col := range q.columns {
+			row.Values[i] = e.value(col, objectID)
+		}
+		result.Rows = append(result.Rows, row)
+	}
+	return result, nil
+}
+
+// matchClasses returns the IDs of the classes selected by the FROM clause, in
+// ascending order.
+func (e *QueryEngine) matchClasses(q *parsedQuery) ([]uint64, error) {
+	g := e.snapshot.graph
+	matches := func(name string) bool { return name == q.className }
+	if q.classRegex {
+		re, err := regexp.Compile("^(?:" + q.className + ")$")
+		if err != nil {
+			return nil, &QueryError{Pos: q.classPos, Msg: fmt.Sprintf("invalid class pattern: %v", err)}
+		}
+		matches = re.MatchString
+	}
+
+	selected := make(map[uint64]bool)
+	for classID, name := range g.classNames {
+		if matches(name) {
+			selected[classID] = true
+		}
+	}
+
+	if q.instanceOf {
+		layouts := e.snapshot.builder.classLayouts
+		for classID := range g.classNames {
+			layout := layouts[classID]
+			for depth := 0; layout != nil && depth < maxClassHierarchyDepth; depth++ {
+				if selected[layout.SuperClassID] {
+					selected[classID] = true
+					break
+				}
+				layout = layouts[layout.SuperClassID]
+			}
+		}
+	}
+
+	classIDs := make([]uint64, 0, len(selected))
+	for classID := range selected {
+		classIDs = append(classIDs, classID)
+	}
+	sort.Slice(classIDs, func(i, j int) bool { return classIDs[i] < classIDs[j] })
+	return classIDs, nil
+}
+
+// value evaluates a column for an object.
+func (e *QueryEngine) value(col *queryColumn, objectID uint64) interface{} {
+	switch col.attr {
+	case attrObjectID:
+		return objectID
+	case attrClassName:
+		classID, _ := e.snapshot.ObjectClassID(objectID)
+		return e.snapshot.ClassName(classID)
+	case attrShallowSize:
+		return e.snapshot.ObjectSize(objectID)
+	case attrRetainedSize:
+		return e.snapshot.RetainedSize(objectID)
+	case attrGCRoot:
+		return e.snapshot.IsGCRoot(objectID)
+	}
+	for _, ref := range e.snapshot.graph.outgoingRefs[objectID] {
+		if ref.FieldName == col.field {
+			return ref.ToObjectID
+		}
+	}
+	return nil
+}
+
+// ============================================================================
+// Parsing
+// ============================================================================
+
+type queryAttr int
+
+const (
+	attrField queryAttr = iota
+	attrObjectID
+	attrClassName
+	attrShallowSize
+	attrRetainedSize
+	attrGCRoot
+)
+
+// queryAttributes maps lower-cased attribute names, including MAT aliases, to
+// attributes and their canonical column names.
+var queryAttributes = map[string]struct {
+	attr queryAttr
+	name string
+}{
+	"@objectid":         {attrObjectID, "@objectId"},
+	"@classname":        {attrClassName, "@className"},
+	"@usedheapsize":     {attrShallowSize, "@usedHeapSize"},
+	"@shallowsize":      {attrShallowSize, "@usedHeapSize"},
+	"@retainedheapsize": {attrRetainedSize, "@retainedHeapSize"},
+	"@retainedsize":     {attrRetainedSize, "@retainedHeapSize"},
+	"@gcroot":           {attrGCRoot, "@gcRoot"},
+}
+
+type queryColumn struct {
+	name  string
+	attr  queryAttr
+	field string
+}
+
+type queryCondition struct {
+	col   *queryColumn
+	op    string
+	value interface{}
+	re    *regexp.Regexp
+}
+
+// queryWhere is a disjunction of conjunctions; an empty clause matches everything.
+type queryWhere [][]*queryCondition
+
+type parsedQuery struct {
+	columns    []*queryColumn
+	className  string
+	classRegex bool
+	classPos   int
+	instanceOf bool
+	alias      string
+	where      queryWhere
+	orderBy    *queryColumn
+	desc       bool
+	limit      int
+}
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokIdent
+	tokString
+	tokNumber
+	tokOp
+	tokComma
+	tokStar
+)
+
+type queryToken struct {
+	kind tokenKind
+	text string
+	pos  int
+}
+
+func isIdentRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || strings.ContainsRune("_$@.[]", r)
+}
+
+// tokenizeQuery splits a query into tokens.
+func tokenizeQuery(query string) ([]queryToken, error) {
+	var tokens []queryToken
+	runes := []rune(query)
+	offsets := make([]int, len(runes)+1)
+	for i, off := 0, 0; i < len(runes); i++ {
+		offsets[i] = off
+		off += len(string(runes[i]))
+		offsets[i+1] = off
+	}
+
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		start := i
+		switch {
+		case unicode.IsSpace(r):
+			i++
+			continue
+		case r == ',':
+			tokens = append(tokens, queryToken{tokComma, ",", offsets[start]})
+			i++
+		case r == '*':
+			tokens = append(tokens, queryToken{tokStar, "*", offsets[start]})
+			i++
+		case r == '\'' || r == '"':
+			i++
+			var sb strings.Builder
+			for i < len(runes) && runes[i] != r {
+				if runes[i] == '\\' && i+1 < len(runes) && runes[i+1] == r {
+					i++
+				}
+				sb.WriteRune(runes[i])
+				i++
+			}
+			if i >= len(runes) {
+				return nil, &QueryError{Pos: offsets[start], Msg: "unterminated string"}
+			}
+			i++
+			tokens = append(tokens, queryToken{tokString, sb.String(), offsets[start]})
+		case strings.ContainsRune("=!<>", r):
+			i++
+			if i < len(runes) && (runes[i] == '=' || (r == '<' && runes[i] == '>')) {
+				i++
+			}
+			op := string(runes[start:i])
+			if op == "!" {
+				return nil, &QueryError{Pos: offsets[start], Msg: "unexpected '!'"}
+			}
+			if op == "<>" {
+				op = "!="
+			}
+			tokens = append(tokens, queryToken{tokOp, op, offsets[start]})
+		case unicode.IsDigit(r) || (r == '-' && i+1 < len(runes) && unicode.IsDigit(runes[i+1])):
+			i++
+			for i < len(runes) && (unicode.IsDigit(runes[i]) || unicode.IsLetter(runes[i])) {
+				i++
+			}
+			tokens = append(tokens, queryToken{tokNumber, string(runes[start:i]), offsets[start]})
+		case isIdentRune(r):
+			for i < len(runes) && isIdentRune(runes[i]) {
+				i++
+			}
+			tokens = append(tokens, queryToken{tokIdent, string(runes[start:i]), offsets[start]})
+		default:
+			return nil, &QueryError{Pos: offsets[start], Msg: fmt.Sprintf("unexpected character %q", r)}
+		}
+	}
+	return append(tokens, queryToken{tokEOF, "", len(query)}), nil
+}
+
+type queryParser struct {
+	tokens []queryToken
+	pos    int
+	query  *parsedQuery
+}
+
+// parseQuery parses a query. Column references are resolved after the FROM
+// clause, once the alias is known.
+func parseQuery(query string) (*parsedQuery, error) {
+	tokens, err := tokenizeQuery(query)
+	if err != nil {
+		return nil, err
+	}
+	p := &queryParser{tokens: tokens, query: &parsedQuery{limit: -1}}
+
+	if err := p.expectKeyword("SELECT"); err != nil {
+		return nil, err
+	}
+	var columnTokens []queryToken
+	if p.peek().kind == tokStar {
+		p.next()
+	} else {
+		for {
+			tok := p.next()
+			if tok.kind != tokIdent {
+				return nil, p.errorAt(tok, "expected a column")
+			}
+			columnTokens = append(columnTokens, tok)
+			if p.peek().kind != tokComma {
+				break
+			}
+			p.next()
+		}
+	}
+
+	if err := p.expectKeyword("FROM"); err != nil {
+		return nil, err
+	}
+	if p.isKeyword("INSTANCEOF") {
+		p.next()
+		p.query.instanceOf = true
+	}
+	tok := p.next()
+	switch tok.kind {
+	case tokIdent:
+		p.query.className = tok.text
+	case tokString:
+		p.query.className = tok.text
+		p.query.classRegex = true
+	default:
+		return nil, p.errorAt(tok, "expected a class name or pattern")
+	}
+	p.query.classPos = tok.pos
+	if tok := p.peek(); tok.kind == tokIdent && !isQueryKeyword(tok.text) {
+		p.query.alias = p.next().text
+	}
+
+	if len(columnTokens) == 0 {
+		for _, name := range []string{"@objectid", "@classname", "@usedheapsize", "@retainedheapsize"} {
+			a := queryAttributes[name]
+			p.query.columns = append(p.query.columns, &queryColumn{name: a.name, attr: a.attr})
+		}
+	}
+	for _, tok := range columnTokens {
+		col, err := p.resolveColumn(tok)
+		if err != nil {
+			return nil, err
+		}
+		p.query.columns = append(p.query.columns, col)
+	}
+
+	if p.isKeyword("WHERE") {
+		p.next()
+		if err := p.parseWhere(); err != nil {
+			return nil, err
+		}
+	}
+
+	if p.isKeyword("ORDER") {
+		p.next()
+		if err := p.expectKeyword("BY"); err != nil {
+			return nil, err
+		}
+		col, err := p.resolveColumn(p.next())
+		if err != nil {
+			return nil, err
+		}
+		p.query.orderBy = col
+		if p.isKeyword("DESC") {
+			p.next()
+			p.query.desc = true
+		} else if p.isKeyword("ASC") {
+			p.next()
+		}
+	}
+
+	if p.isKeyword("LIMIT") {
+		p.next()
+		tok := p.next()
+		n, err := strconv.Atoi(tok.text)
+		if tok.kind != tokNumber || err != nil || n < 0 {
+			return nil, p.errorAt(tok, "expected a non-negative row count")
+		}
+		p.query.limit = n
+	}
+
+	if tok := p.peek(); tok.kind != tokEOF {
+		return nil, p.errorAt(tok, fmt.Sprintf("unexpected %q", tok.text))
+	}
+	return p.query, nil
+}
+
+// parseWhere parses conditions joined by AND/OR into the query's where clause.
+func (p *queryParser) parseWhere() error {
+	group := []*queryCondition{}
+	for {
+		cond, err := p.parseCondition()
+		if err != nil {
+			return err
+		}
+		group = append(group, cond)
+
+		switch {
+		case p.isKeyword("AND"):
+			p.next()
+		case p.isKeyword("OR"):
+			p.next()
+			p.query.where = append(p.query.where, group)
+			group = []*queryCondition{}
+		default:
+			p.query.where = append(p.query.where, group)
+			return nil
+		}
+	}
+}
+
+func (p *queryParser) parseCondition() (*queryCondition, error) {
+	tok := p.next()
+	if tok.kind != tokIdent || isQueryKeyword(tok.text) {
+		return nil, p.errorAt(tok, "expected a column")
+	}
+	col, err := p.resolveColumn(tok)
+	if err != nil {
+		return nil, err
+	}
+	cond := &queryCondition{col: col}
+
+	opTok := p.next()
+	switch {
+	case opTok.kind == tokOp:
+		cond.op = opTok.text
+	case opTok.kind == tokIdent && strings.EqualFold(opTok.text, "LIKE"):
+		cond.op = "LIKE"
+	default:
+		return nil, p.errorAt(opTok, "expected a comparison operator")
+	}
+
+	lit := p.next()
+	switch {
+	case cond.op == "LIKE":
+		if lit.kind != tokString {
+			return nil, p.errorAt(lit, "LIKE expects a string pattern")
+		}
+		re, err := regexp.Compile(lit.text)
+		if err != nil {
+			return nil, p.errorAt(lit, fmt.Sprintf("invalid pattern: %v", err))
+		}
+		cond.re = re
+	case lit.kind == tokString:
+		cond.value = lit.text
+	case lit.kind == tokNumber:
+		v, ok := parseQueryNumber(lit.text)
+		if !ok {
+			return nil, p.errorAt(lit, fmt.Sprintf("invalid number %q", lit.text))
+		}
+		cond.value = v
+	case lit.kind == tokIdent && strings.EqualFold(lit.text, "null"):
+		cond.value = nil
+	case lit.kind == tokIdent && (strings.EqualFold(lit.text, "true") || strings.EqualFold(lit.text, "false")):
+		cond.value = strings.EqualFold(lit.text, "true")
+	default:
+		return nil, p.errorAt(lit, "expected a literal")
+	}
+	return cond, nil
+}
+
+// resolveColumn turns an identifier into a column, stripping the alias.
+func (p *queryParser) resolveColumn(tok queryToken) (*queryColumn, error) {
+	if tok.kind != tokIdent {
+		return nil, p.errorAt(tok, "expected a column")
+	}
+	name := tok.text
+	if alias := p.query.alias; alias != "" {
+		if name == alias {
+			name = "@objectId"
+		} else {
+			name = strings.TrimPrefix(name, alias+".")
+		}
+	}
+	if strings.HasPrefix(name, "@") {
+		a, ok := queryAttributes[strings.ToLower(name)]
+		if !ok {
+			return nil, p.errorAt(tok, fmt.Sprintf("unknown attribute %q", name))
+		}
+		return &queryColumn{name: a.name, attr: a.attr}, nil
+	}
+	if strings.ContainsAny(name, ".[]@") {
+		return nil, p.errorAt(tok, fmt.Sprintf("unknown column %q", tok.text))
+	}
+	return &queryColumn{name: name, attr: attrField, field: name}, nil
+}
+
+func (p *queryParser) peek() queryToken {
+	return p.tokens[p.pos]
+}
+
+func (p *queryParser) next() queryToken {
+	tok := p.tokens[p.pos]
+	if tok.kind != tokEOF {
+		p.pos++
+	}
+	return tok
+}
+
+func (p *queryParser) isKeyword(keyword string) bool {
+	tok := p.peek()
+	return tok.kind == tokIdent && strings.EqualFold(tok.text, keyword)
+}
+
+func (p *queryParser) expectKeyword(keyword string) error {
+	if !p.isKeyword(keyword) {
+		return p.errorAt(p.peek(), "expected "+keyword)
+	}
+	p.next()
+	return nil
+}
+
+func (p *queryParser) errorAt(tok queryToken, msg string) error {
+	return &QueryError{Pos: tok.pos, Msg: msg}
+}
+
+func isQueryKeyword(text string) bool {
+	switch strings.ToUpper(text) {
+	case "SELECT", "FROM", "INSTANCEOF", "WHERE", "AND", "OR", "ORDER", "BY", "ASC", "DESC", "LIMIT", "LIKE":
+		return true
+	}
+	return false
+}
+
+// parseQueryNumber parses a decimal or 0x-prefixed hexadecimal literal.
+// Non-negative values are uint64 so object IDs compare exactly.
+func parseQueryNumber(text string) (interface{}, bool) {
+	if strings.HasPrefix(text, "-") {
+		v, err := strconv.ParseInt(text, 10, 64)
+		return v, err == nil
+	}
+	v, err := strconv.ParseUint(text, 0, 64)
+	return v, err == nil
+}
+
+// ============================================================================
+// Evaluation
+// ============================================================================
+
+func (w queryWhere) matches(e *QueryEngine, objectID uint64) bool {
+	if len(w) == 0 {
+		return true
+	}
+	for _, group := range w {
+		ok := true
+		for _, cond := range group {
+			if !cond.matches(e.value(cond.col, objectID)) {
+				ok = false
+				break
+			}
+		}
+		if ok {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *queryCondition) matches(v interface{}) bool {
+	if c.op == "LIKE" {
+		return v != nil && c.re.MatchString(fmt.Sprint(v))
+	}
+	if v == nil || c.value == nil {
+		switch c.op {
+		case "=":
+			return v == nil && c.value == nil
+		case "!=":
+			return (v == nil) != (c.value == nil)
+		}
+		return false
+	}
+
+	cmp, ok := compareQueryValues(v, c.value)
+	if !ok {
+		return c.op == "!="
+	}
+	switch c.op {
+	case "=":
+		return cmp == 0
+	case "!=":
+		return cmp != 0
+	case "<":
+		return cmp < 0
+	case "<=":
+		return cmp <= 0
+	case ">":
+		return cmp > 0
+	case ">=":
+		return cmp >= 0
+	}
+	return false
+}
+
+// queryNumber splits a numeric value into sign and magnitude so int64 sizes
+// and uint64 object IDs compare exactly.
+func queryNumber(v interface{}) (mag uint64, neg bool, ok bool) {
+	switch n := v.(type) {
+	case int64:
+		if n < 0 {
+			return uint64(-n), true, true
+		}
+		return uint64(n), false, true
+	case uint64:
+		return n, false, true
+	}
+	return 0, false, false
+}
+
+// compareQueryValues compares two non-nil values of compatible types.
+func compareQueryValues(a, b interface{}) (int, bool) {
+	if am, an, ok := queryNumber(a); ok {
+		bm, bn, ok := queryNumber(b)
+		if !ok {
+			return 0, false
+		}
+		switch {
+		case an != bn:
+			if an {
+				return -1, true
+			}
+			return 1, true
+		case am == bm:
+			return 0, true
+		case (am < bm) != an:
+			return -1, true
+		default:
+			return 1, true
+		}
+	}
+	switch av := a.(type) {
+	case string:
+		if bv, ok := b.(string); ok {
+			return strings.Compare(av, bv), true
+		}
+	case bool:
+		if bv, ok := b.(bool); ok {
+			if av == bv {
+				return 0, true
+			}
+			if !av {
+				return -1, true
+			}
+			return 1, true
+		}
+	}
+	return 0, false
+}
+
+// compareOrdered orders values for ORDER BY; nulls sort first.
+func compareOrdered(a, b interface{}) int {
+	switch {
+	case a == nil && b == nil:
+		return 0
+	case a == nil:
+		return -1
+	case b == nil:
+		return 1
+	}
+	cmp, _ := compareQueryValues(a, b)
+	return cmp
+}
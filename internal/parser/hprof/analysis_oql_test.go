@@ -0,0 +1,109 @@
+package hprof
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newQueryTestEngine() *QueryEngine {
+	return NewQueryEngine(NewHeapSnapshot(newRollupTestGraph(), nil, nil))
+}
+
+func resultIDs(result *QueryResult) []uint64 {
+	ids := make([]uint64, 0, len(result.Rows))
+	for _, row := range result.Rows {
+		ids = append(ids, row.ObjectID)
+	}
+	return ids
+}
+
+func TestQueryEngine_SelectStar(t *testing.T) {
+	result, err := newQueryTestEngine().Execute(`SELECT * FROM byte[] ORDER BY @usedHeapSize DESC`)
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"@objectId", "@className", "@usedHeapSize", "@retainedHeapSize"}, result.Columns)
+	assert.Equal(t, 2, result.Total)
+	assert.False(t, result.Truncated)
+	require.Len(t, result.Rows, 2)
+	assert.Equal(t, []interface{}{uint64(400), "byte[]", int64(4096), int64(4096)}, result.Rows[0].Values)
+	assert.Equal(t, uint64(600), result.Rows[1].ObjectID)
+}
+
+func TestQueryEngine_WhereAndFields(t *testing.T) {
+	engine := newQueryTestEngine()
+
+	result, err := engine.Execute(`select s.map, s.@retainedHeapSize from com.example.Cache s where s.map != null`)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"map", "@retainedHeapSize"}, result.Columns)
+	require.Len(t, result.Rows, 1)
+	assert.Equal(t, []interface{}{uint64(200), int64(32 + 48 + 4096)}, result.Rows[0].Values)
+
+	result, err = engine.Execute(`SELECT * FROM "java\.util\..*|byte\[\]" WHERE @usedHeapSize >= 2048 AND @gcRoot = false OR @className = 'java.util.HashMap'`)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []uint64{200, 400, 600}, resultIDs(result))
+
+	result, err = engine.Execute(`SELECT @objectId FROM ".*" WHERE @className LIKE "^java\.lang\." LIMIT 5`)
+	require.NoError(t, err)
+	assert.Equal(t, []uint64{500}, resultIDs(result))
+
+	result, err = engine.Execute(`SELECT @objectId FROM ".*" WHERE @objectId = 0x190`)
+	require.NoError(t, err)
+	assert.Equal(t, []uint64{400}, resultIDs(result))
+}
+
+func TestQueryEngine_Limit(t *testing.T) {
+	engine := newQueryTestEngine()
+
+	result, err := engine.Execute(`SELECT * FROM ".*" ORDER BY @retainedHeapSize DESC LIMIT 2`)
+	require.NoError(t, err)
+	assert.Equal(t, 5, result.Total)
+	assert.True(t, result.Truncated)
+	assert.Equal(t, []uint64{300, 200}, resultIDs(result))
+
+	engine.MaxRows = 1
+	result, err = engine.Execute(`SELECT * FROM ".*" ORDER BY @retainedHeapSize DESC`)
+	require.NoError(t, err)
+	assert.Equal(t, []uint64{300}, resultIDs(result))
+}
+
+func TestQueryEngine_InstanceOf(t *testing.T) {
+	g := newRollupTestGraph()
+	g.SetClassName(1001, "com.example.LruCache")
+	g.SetObjectInfo(700, 1001, 40)
+	layouts := map[uint64]*ClassFieldLayout{
+		1000: {ClassID: 1000, ClassName: "com.example.Cache"},
+		1001: {ClassID: 1001, ClassName: "com.example.LruCache", SuperClassID: 1000},
+	}
+	engine := NewQueryEngine(NewHeapSnapshot(g, layouts, nil))
+
+	result, err := engine.Execute(`SELECT * FROM com.example.Cache`)
+	require.NoError(t, err)
+	assert.Equal(t, []uint64{300}, resultIDs(result))
+
+	result, err = engine.Execute(`SELECT * FROM INSTANCEOF com.example.Cache`)
+	require.NoError(t, err)
+	assert.Equal(t, []uint64{300, 700}, resultIDs(result))
+}
+
+func TestQueryEngine_Errors(t *testing.T) {
+	engine := newQueryTestEngine()
+
+	for _, query := range []string{
+		``,
+		`SELECT`,
+		`SELECT * FROM`,
+		`SELECT @nope FROM byte[]`,
+		`SELECT * FROM byte[] WHERE @usedHeapSize >`,
+		`SELECT * FROM byte[] WHERE @className LIKE 5`,
+		`SELECT * FROM "(" `,
+		`SELECT * FROM byte[] LIMIT -1`,
+		`SELECT * FROM byte[] trailing junk`,
+		`SELECT * FROM 'unterminated`,
+	} {
+		_, err := engine.Execute(query)
+		var qerr *QueryError
+		assert.ErrorAs(t, err, &qerr, "query %q", query)
+	}
+}
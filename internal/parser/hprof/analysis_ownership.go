@@ -0,0 +1,199 @@
+package hprof
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// UnownedTeam is the team of classes no ownership prefix matches.
+const UnownedTeam = "(unowned)"
+
+// OwnershipMap maps package prefixes to the teams or components owning
+// them, loaded from a YAML file like:
+//
+//	teams:
+//	  com.example.billing: payments
+//	  com.example.search: search
+//	  io.netty: platform
+//
+// A prefix matches its package and the packages below it; the longest
+// matching prefix wins.
+type OwnershipMap struct {
+	// rules are sorted by prefix length descending
+	rules []ownershipRule
+}
+
+// ownershipRule assigns a package prefix to a team.
+type ownershipRule struct {
+	prefix string
+	team   string
+}
+
+// ownershipMapSpec is an ownership map file.
+type ownershipMapSpec struct {
+	Teams map[string]string `yaml:"teams"`
+}
+
+// LoadOwnershipMapFile loads an ownership map from a YAML file.
+func LoadOwnershipMapFile(path string) (*OwnershipMap, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open ownership map: %w", err)
+	}
+	defer f.Close()
+
+	m, err := LoadOwnershipMap(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return m, nil
+}
+
+// LoadOwnershipMap loads an ownership map from YAML.
+func LoadOwnershipMap(r io.Reader) (*OwnershipMap, error) {
+	var spec ownershipMapSpec
+	dec := yaml.NewDecoder(r)
+	dec.KnownFields(true)
+	if err := dec.Decode(&spec); err != nil && err != io.EOF {
+		return nil, fmt.Errorf("failed to parse ownership map: %w", err)
+	}
+
+	m := &OwnershipMap{}
+	for prefix, team := range spec.Teams {
+		prefix = strings.TrimSuffix(strings.TrimSpace(prefix), ".")
+		if prefix == "" || team == "" {
+			return nil, fmt.Errorf("ownership map: empty package prefix or team")
+		}
+		m.rules = append(m.rules, ownershipRule{prefix: prefix, team: team})
+	}
+	sort.Slice(m.rules, func(i, j int) bool {
+		if len(m.rules[i].prefix) != len(m.rules[j].prefix) {
+			return len(m.rules[i].prefix) > len(m.rules[j].prefix)
+		}
+		return m.rules[i].prefix < m.rules[j].prefix
+	})
+	return m, nil
+}
+
+// Team returns the team owning a class, or UnownedTeam.
+func (m *OwnershipMap) Team(className string) string {
+	pkg := ClassPackage(className)
+	for _, rule := range m.rules {
+		if pkg == rule.prefix || strings.HasPrefix(pkg, rule.prefix+".") {
+			return rule.team
+		}
+	}
+	return UnownedTeam
+}
+
+// MemoryOwnership attributes the heap to the teams owning its classes.
+// Classes are charged their attributed retained size: every object counts
+// toward the class of its nearest dominator of another class, so the team
+// sizes do not overlap and add up to the heap.
+type MemoryOwnership struct {
+	TotalSize int64 `json:"total_size"`
+	// Teams are sorted by retained size descending
+	Teams []*TeamOwnership `json:"teams"`
+}
+
+// TeamOwnership is the memory owned by one team.
+type TeamOwnership struct {
+	Team          string  `json:"team"`
+	ClassCount    int     `json:"class_count"`
+	InstanceCount int64   `json:"instance_count"`
+	ShallowSize   int64   `json:"shallow_size"`
+	RetainedSize  int64   `json:"retained_size"`
+	Percentage    float64 `json:"percentage"`
+	// TopClasses are the classes owning the most memory
+	TopClasses []*ClassOwnership `json:"top_classes,omitempty"`
+}
+
+// ClassOwnership is the memory a team owns through one class.
+type ClassOwnership struct {
+	ClassName    string `json:"class_name"`
+	RetainedSize int64  `json:"retained_size"`
+}
+
+// maxOwnershipClasses is the number of classes listed per team.
+const maxOwnershipClasses = 10
+
+// ComputeMemoryOwnership sets the MemoryOwnership of a result. It requires
+// the dominator tree of the result's reference graph.
+func ComputeMemoryOwnership(result *HeapAnalysisResult, m *OwnershipMap) *MemoryOwnership {
+	if result.RefGraph == nil || m == nil {
+		return nil
+	}
+	classes := result.AllClasses
+	if classes == nil {
+		classes = result.TopClasses
+	}
+	result.MemoryOwnership = result.RefGraph.MemoryOwnership(m, classes)
+	return result.MemoryOwnership
+}
+
+// MemoryOwnership attributes the heap of g to teams. classes provide the
+// instance counts and shallow sizes of the classes.
+func (g *ReferenceGraph) MemoryOwnership(m *OwnershipMap, classes []*ClassStats) *MemoryOwnership {
+	if !g.dominatorComputed {
+		g.ComputeDominatorTree()
+	}
+
+	report := &MemoryOwnership{}
+	byTeam := make(map[string]*TeamOwnership)
+	team := func(className string) *TeamOwnership {
+		name := m.Team(className)
+		t, ok := byTeam[name]
+		if !ok {
+			t = &TeamOwnership{Team: name}
+			byTeam[name] = t
+			report.Teams = append(report.Teams, t)
+		}
+		return t
+	}
+
+	for _, cls := range classes {
+		t := team(cls.ClassName)
+		t.ClassCount++
+		t.InstanceCount += cls.InstanceCount
+		t.ShallowSize += cls.TotalSize
+	}
+	for classID, size := range g.classRetainedSizesAttributed {
+		className := g.GetClassName(classID)
+		if className == "" || size == 0 {
+			continue
+		}
+		t := team(className)
+		t.RetainedSize += size
+		t.TopClasses = append(t.TopClasses, &ClassOwnership{ClassName: className, RetainedSize: size})
+		report.TotalSize += size
+	}
+
+	for _, t := range report.Teams {
+		if report.TotalSize > 0 {
+			t.Percentage = float64(t.RetainedSize) * 100 / float64(report.TotalSize)
+		}
+		sort.Slice(t.TopClasses, func(i, j int) bool {
+			a, b := t.TopClasses[i], t.TopClasses[j]
+			if a.RetainedSize != b.RetainedSize {
+				return a.RetainedSize > b.RetainedSize
+			}
+			return a.ClassName < b.ClassName
+		})
+		if len(t.TopClasses) > maxOwnershipClasses {
+			t.TopClasses = t.TopClasses[:maxOwnershipClasses]
+		}
+	}
+	sort.Slice(report.Teams, func(i, j int) bool {
+		a, b := report.Teams[i], report.Teams[j]
+		if a.RetainedSize != b.RetainedSize {
+			return a.RetainedSize > b.RetainedSize
+		}
+		return a.Team < b.Team
+	})
+	return report
+}
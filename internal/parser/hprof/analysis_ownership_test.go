@@ -0,0 +1,84 @@
+package hprof
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadOwnershipMap(t *testing.T) {
+	m, err := LoadOwnershipMap(strings.NewReader(`
+teams:
+  com.example: platform
+  com.example.billing.: payments
+`))
+	require.NoError(t, err)
+	assert.Equal(t, "payments", m.Team("com.example.billing.Invoice"))
+	assert.Equal(t, "payments", m.Team("com.example.billing.tax.Rate[]"))
+	assert.Equal(t, "platform", m.Team("com.example.search.Index"))
+	assert.Equal(t, "platform", m.Team("com.example.Main"))
+	assert.Equal(t, UnownedTeam, m.Team("com.examplex.Main"))
+	assert.Equal(t, UnownedTeam, m.Team("byte[]"))
+
+	_, err = LoadOwnershipMap(strings.NewReader("owners:\n  com.example: platform\n"))
+	assert.Error(t, err)
+	_, err = LoadOwnershipMap(strings.NewReader("teams:\n  com.example: \"\"\n"))
+	assert.Error(t, err)
+}
+
+func TestComputeMemoryOwnership(t *testing.T) {
+	g := NewReferenceGraphWithCapacity(8)
+	g.SetClassName(1, "com.example.billing.Invoice")
+	g.SetClassName(2, "byte[]")
+	g.SetClassName(3, "com.example.search.Index")
+	g.SetClassName(4, "org.other.Thing")
+	g.SetObjectInfo(10, 1, 32)
+	g.SetObjectInfo(11, 2, 100)
+	g.SetObjectInfo(20, 3, 48)
+	g.SetObjectInfo(21, 2, 200)
+	g.SetObjectInfo(30, 4, 16)
+	g.AddReference(ObjectReference{FromObjectID: 10, ToObjectID: 11, FieldName: "data", FromClassID: 1})
+	g.AddReference(ObjectReference{FromObjectID: 20, ToObjectID: 21, FieldName: "data", FromClassID: 3})
+	for _, id := range []uint64{10, 20, 30} {
+		g.AddGCRoot(&GCRoot{ObjectID: id, Type: GCRootJNIGlobal})
+	}
+	result := &HeapAnalysisResult{
+		RefGraph: g,
+		AllClasses: []*ClassStats{
+			{ClassName: "byte[]", InstanceCount: 2, TotalSize: 300},
+			{ClassName: "com.example.search.Index", InstanceCount: 1, TotalSize: 48},
+			{ClassName: "com.example.billing.Invoice", InstanceCount: 1, TotalSize: 32},
+			{ClassName: "org.other.Thing", InstanceCount: 1, TotalSize: 16},
+		},
+	}
+	m, err := LoadOwnershipMap(strings.NewReader("teams:\n  com.example: platform\n  com.example.billing: payments\n"))
+	require.NoError(t, err)
+
+	report := ComputeMemoryOwnership(result, m)
+	require.NotNil(t, report)
+	assert.Same(t, report, result.MemoryOwnership)
+	assert.Equal(t, int64(396), report.TotalSize)
+
+	require.Len(t, report.Teams, 3)
+	platform := report.Teams[0]
+	assert.Equal(t, "platform", platform.Team)
+	assert.Equal(t, int64(248), platform.RetainedSize)
+	assert.Equal(t, int64(48), platform.ShallowSize)
+	assert.InDelta(t, 248*100.0/396, platform.Percentage, 1e-9)
+	assert.Equal(t, []*ClassOwnership{{ClassName: "com.example.search.Index", RetainedSize: 248}}, platform.TopClasses)
+
+	assert.Equal(t, "payments", report.Teams[1].Team)
+	assert.Equal(t, int64(132), report.Teams[1].RetainedSize)
+
+	// byte[] is owned by the classes holding it, not by its own team
+	unowned := report.Teams[2]
+	assert.Equal(t, UnownedTeam, unowned.Team)
+	assert.Equal(t, 2, unowned.ClassCount)
+	assert.Equal(t, int64(3), unowned.InstanceCount)
+	assert.Equal(t, int64(316), unowned.ShallowSize)
+	assert.Equal(t, int64(16), unowned.RetainedSize)
+
+	assert.Nil(t, ComputeMemoryOwnership(result, nil))
+}
@@ -2,7 +2,10 @@
 package hprof
 
 import (
+	"context"
+	"slices"
 	"sort"
+	"time"
 
 	"github.com/perf-analysis/pkg/filter"
 )
@@ -15,6 +18,8 @@ type RetainerInfo struct {
 	RetainedCount int64   `json:"retained_count"`
 	Percentage    float64 `json:"percentage"`
 	Depth         int     `json:"depth,omitempty"` // Distance from target (1 = direct, 2+ = indirect)
+	// Dominators is the number of distinct retainer objects, set by dominator attribution
+	Dominators int64 `json:"dominators,omitempty"`
 }
 
 // ClassRetainers holds retainer information for a class.
@@ -25,8 +30,29 @@ type ClassRetainers struct {
 	Retainers     []*RetainerInfo `json:"retainers"`
 	RetainedSize  int64           `json:"retained_size,omitempty"` // Dominator tree retained size
 	GCRootPaths   []*GCRootPath   `json:"gc_root_paths,omitempty"` // Sample paths to GC roots
+	// Mode tells whether the retainers count every instance or are scaled from a sample
+	Mode RetainerMode `json:"mode,omitempty"`
+	// AnalyzedObjects is the number of instances whose retainers were walked
+	AnalyzedObjects int64 `json:"analyzed_objects,omitempty"`
 }
 
+// RetainerMode tells how the numbers of a retainer analysis were produced.
+type RetainerMode string
+
+const (
+	// RetainerModeExact counts the retainers of every instance.
+	RetainerModeExact RetainerMode = "exact"
+	// RetainerModeSampled scales the retainers of a stratified sample.
+	RetainerModeSampled RetainerMode = "sampled"
+	// RetainerModeDominator attributes every instance to its nearest
+	// dominator of another class.
+	RetainerModeDominator RetainerMode = "dominator"
+)
+
+// DominatorRetainerGCRoots is the retainer class of instances that no single
+// object of another class dominates.
+const DominatorRetainerGCRoots = "<GC roots>"
+
 // BusinessRetainer represents a business-level retainer with full path information.
 type BusinessRetainer struct {
 	ClassName     string   `json:"class_name"`
@@ -166,6 +192,14 @@ func (g *ReferenceGraph) stratifiedSample(objects []uint64, config SamplingConfi
 // - Uses index-based BFS traversal to eliminate GetObjectIndex map lookups (~20% CPU reduction)
 // - Plan G: Uses array-based retainer tracking to eliminate map lookups in hot path (~30% CPU reduction)
 func (g *ReferenceGraph) ComputeMultiLevelRetainers(targetClassName string, maxDepth, topN int) *ClassRetainers {
+	// A background context is never canceled
+	retainers, _ := g.ComputeMultiLevelRetainersContext(context.Background(), targetClassName, maxDepth, topN)
+	return retainers
+}
+
+// ComputeMultiLevelRetainersContext is ComputeMultiLevelRetainers stopping
+// with the error of ctx when ctx is done.
+func (g *ReferenceGraph) ComputeMultiLevelRetainersContext(ctx context.Context, targetClassName string, maxDepth, topN int) (*ClassRetainers, error) {
 	if maxDepth <= 0 {
 		maxDepth = 5
 	}
@@ -177,13 +211,13 @@ func (g *ReferenceGraph) ComputeMultiLevelRetainers(targetClassName string, maxD
 	// Use optimized index lookup instead of linear scan
 	targetClassID, found := g.getClassIDByName(targetClassName)
 	if !found {
-		return nil
+		return nil, nil
 	}
 
 	// Use indexed lookup for target objects
 	targetObjects := g.getObjectsByClass(targetClassID)
 	if len(targetObjects) == 0 {
-		return nil
+		return nil, nil
 	}
 
 	// Calculate total size using index-based lookup
@@ -208,6 +242,26 @@ func (g *ReferenceGraph) ComputeMultiLevelRetainers(targetClassName string, maxD
 		}
 	}
 
+	// Exact mode walks the other instances after the sample, and falls back
+	// to the sample's numbers when they cannot all be walked in the budget
+	indices := sampleIndices
+	exact := sampleRatio == 1.0
+	var deadline time.Time
+	if !exact && g.retainerExactBudget > 0 {
+		sampled := make(map[uint64]bool, len(sampleObjects))
+		for _, objID := range sampleObjects {
+			sampled[objID] = true
+		}
+		indices = make([]int, len(sampleIndices), len(targetObjects))
+		copy(indices, sampleIndices)
+		for _, objID := range targetObjects {
+			if idx := g.GetObjectIndex(objID); idx >= 0 && !sampled[objID] {
+				indices = append(indices, idx)
+			}
+		}
+		deadline = time.Now().Add(g.retainerExactBudget)
+	}
+
 	// Plan G: Array-based retainer tracking
 	// retainerDataSlice stores retainer info indexed by sequential assignment
 	type retainerDataEntry struct {
@@ -227,7 +281,7 @@ func (g *ReferenceGraph) ComputeMultiLevelRetainers(targetClassName string, maxD
 	if maxRetainerKeys < 100000 {
 		maxRetainerKeys = 100000
 	}
-	ctx := NewRetainerBFSContext(objectCount, maxRetainerKeys)
+	bfs := NewRetainerBFSContext(objectCount, maxRetainerKeys)
 
 	// Optimized retainer key: pack classID, fieldNameID, and depth into uint64
 	// Layout: classID (40 bits) | fieldNameID (16 bits) | depth (8 bits)
@@ -240,27 +294,43 @@ func (g *ReferenceGraph) ComputeMultiLevelRetainers(targetClassName string, maxD
 	retainerCount := make([]int64, 0, 1024)
 	retainerSize := make([]int64, 0, 1024)
 
-	for _, startIdx := range sampleIndices {
+	// Counts of the sample, kept while exact mode walks the other instances
+	var sampledCount, sampledSize []int64
+	analyzed := len(indices)
+	for i, startIdx := range indices {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		if i == len(sampleIndices) {
+			sampledCount, sampledSize = slices.Clone(retainerCount), slices.Clone(retainerSize)
+		}
+		if i > len(sampleIndices) && i%64 == 0 && time.Now().After(deadline) {
+			g.debugf("Exact retainers of %s exceeded %v after %d of %d instances, using the sample",
+				targetClassName, g.retainerExactBudget, i, len(indices))
+			analyzed = i
+			break
+		}
+
 		// O(1) reset for new sample object (instead of O(V) map clearing)
-		ctx.ResetVisitedOnly()
-		ctx.ResetCountedOnly()
+		bfs.ResetVisitedOnly()
+		bfs.ResetCountedOnly()
 
 		// Mark starting object as visited
-		ctx.MarkVisited(startIdx)
+		bfs.MarkVisited(startIdx)
 
 		// Initialize current level with index (not objectID)
-		ctx.AddToCurrentLevelIdx(startIdx)
+		bfs.AddToCurrentLevelIdx(startIdx)
 		// Use index-based size lookup (no map access)
 		objSize := g.GetObjectSizeByIndex(startIdx)
 
-		for depth := 1; depth <= maxDepth && len(ctx.CurrentLevelIdx()) > 0; depth++ {
-			ctx.ClearNextLevelIdx()
+		for depth := 1; depth <= maxDepth && len(bfs.CurrentLevelIdx()) > 0; depth++ {
+			bfs.ClearNextLevelIdx()
 
-			for _, currentIdx := range ctx.CurrentLevelIdx() {
+			for _, currentIdx := range bfs.CurrentLevelIdx() {
 				// Use indexed incoming refs - no map lookup needed!
 				for _, ref := range g.GetIndexedIncomingRefs(currentIdx) {
 					// TestAndMarkVisited combines IsVisited + MarkVisited
-					if ctx.TestAndMarkVisited(ref.FromIndex) {
+					if bfs.TestAndMarkVisited(ref.FromIndex) {
 						continue
 					}
 
@@ -304,26 +374,40 @@ func (g *ReferenceGraph) ComputeMultiLevelRetainers(targetClassName string, maxD
 
 					// Only count this target object once per retainer key (O(1) check)
 					// sliceIdx is used directly as keyIndex for Bitset
-					if !ctx.IsRetainerCounted(sliceIdx) {
-						ctx.MarkRetainerCounted(sliceIdx)
+					if !bfs.IsRetainerCounted(sliceIdx) {
+						bfs.MarkRetainerCounted(sliceIdx)
 						// Update arrays directly (no map lookup!)
 						retainerCount[sliceIdx]++
 						retainerSize[sliceIdx] += objSize
 					}
 
 					// Add to next level using index
-					ctx.AddToNextLevelIdx(ref.FromIndex)
+					bfs.AddToNextLevelIdx(ref.FromIndex)
 				}
 			}
 
 			// Swap levels for next iteration
-			ctx.SwapLevelsIdx()
+			bfs.SwapLevelsIdx()
 		}
 	}
 
+	mode := RetainerModeSampled
+	switch {
+	case exact || analyzed == len(indices) && len(indices) > len(sampleIndices):
+		mode = RetainerModeExact
+		sampleRatio = 1.0
+	case sampledCount != nil:
+		// Exact mode ran out of budget: report the sample
+		retainerCount, retainerSize = sampledCount, sampledSize
+		analyzed = len(sampleIndices)
+	}
+
 	// Convert to slice and calculate percentages (array-based, no map iteration)
 	retainers := make([]*RetainerInfo, 0, len(retainerDataSlice))
 	for i, data := range retainerDataSlice {
+		if i >= len(retainerCount) || retainerCount[i] == 0 {
+			continue // only reached by instances outside the sample
+		}
 		r := data.info
 		// Copy accumulated count/size from arrays to info struct
 		r.RetainedCount = retainerCount[i]
@@ -374,12 +458,14 @@ func (g *ReferenceGraph) ComputeMultiLevelRetainers(targetClassName string, maxD
 	}
 
 	return &ClassRetainers{
-		ClassName:     targetClassName,
-		TotalSize:     totalSize,
-		InstanceCount: int64(len(targetObjects)),
-		Retainers:     retainers,
-		GCRootPaths:   gcRootPaths,
-	}
+		ClassName:       targetClassName,
+		TotalSize:       totalSize,
+		InstanceCount:   int64(len(targetObjects)),
+		Retainers:       retainers,
+		GCRootPaths:     gcRootPaths,
+		Mode:            mode,
+		AnalyzedObjects: int64(analyzed),
+	}, nil
 }
 
 // ComputeRetainersForClass computes what classes retain instances of the given class.
@@ -465,6 +551,98 @@ func (g *ReferenceGraph) ComputeRetainersForClass(targetClassName string, topN i
 	}
 }
 
+// ComputeDominatorRetainers attributes every reachable instance of a class
+// to the first object of another class on its dominator chain: the object
+// whose collection would free it. Chains of same-class objects, such as
+// linked list nodes, are skipped. Unlike the reference-based analyses every
+// instance is counted, so the report is exact and deterministic. Retainers
+// are sorted by shallow size, count and class name; nil is returned if the
+// class has no reachable instances.
+func (g *ReferenceGraph) ComputeDominatorRetainers(targetClassName string, topN int) *ClassRetainers {
+	targetClassID, ok := g.getClassIDByName(targetClassName)
+	if !ok {
+		return nil
+	}
+	if !g.dominatorComputed {
+		g.ComputeDominatorTree()
+	}
+
+	var totalSize, instances, analyzed int64
+	stats := make(map[uint64]*RetainerInfo)
+	dominators := make(map[uint64]map[uint64]struct{})
+	for _, objID := range g.getObjectsByClass(targetClassID) {
+		size := g.objectSize[objID]
+		instances++
+		domID, ok := g.dominators[objID]
+		if !ok || domID == 0 || !g.reachableObjects[objID] {
+			continue
+		}
+		for domID != superRootID && domID != 0 && g.objectClass[domID] == targetClassID {
+			domID = g.dominators[domID]
+		}
+		if domID == 0 {
+			continue
+		}
+
+		retainerClassID := superRootID
+		if domID != superRootID {
+			retainerClassID = g.objectClass[domID]
+		}
+		info, ok := stats[retainerClassID]
+		if !ok {
+			info = &RetainerInfo{RetainerClass: DominatorRetainerGCRoots, Depth: 1}
+			if retainerClassID != superRootID {
+				info.RetainerClass = g.GetClassName(retainerClassID)
+				if info.RetainerClass == "" {
+					info.RetainerClass = "(unknown)"
+				}
+			}
+			stats[retainerClassID] = info
+			dominators[retainerClassID] = make(map[uint64]struct{})
+		}
+		info.RetainedCount++
+		info.RetainedSize += size
+		dominators[retainerClassID][domID] = struct{}{}
+		totalSize += size
+		analyzed++
+	}
+	if analyzed == 0 {
+		return nil
+	}
+
+	retainers := make([]*RetainerInfo, 0, len(stats))
+	for classID, info := range stats {
+		info.Percentage = float64(info.RetainedSize) * 100.0 / float64(max(totalSize, 1))
+		if classID != superRootID {
+			info.Dominators = int64(len(dominators[classID]))
+		}
+		retainers = append(retainers, info)
+	}
+	sort.Slice(retainers, func(i, j int) bool {
+		a, b := retainers[i], retainers[j]
+		if a.RetainedSize != b.RetainedSize {
+			return a.RetainedSize > b.RetainedSize
+		}
+		if a.RetainedCount != b.RetainedCount {
+			return a.RetainedCount > b.RetainedCount
+		}
+		return a.RetainerClass < b.RetainerClass
+	})
+	if topN > 0 && len(retainers) > topN {
+		retainers = retainers[:topN]
+	}
+
+	return &ClassRetainers{
+		ClassName:       targetClassName,
+		TotalSize:       totalSize,
+		InstanceCount:   instances,
+		Retainers:       retainers,
+		RetainedSize:    g.classRetainedSizes[targetClassID],
+		Mode:            RetainerModeDominator,
+		AnalyzedObjects: analyzed,
+	}
+}
+
 // ComputeTopRetainers computes retainer info for the top memory-consuming classes.
 func (g *ReferenceGraph) ComputeTopRetainers(topClasses []*ClassStats, topN int) map[string]*ClassRetainers {
 	result := make(map[string]*ClassRetainers)
@@ -495,6 +673,14 @@ func (g *ReferenceGraph) ComputeTopRetainers(topClasses []*ClassStats, topN int)
 // 3. Early termination when enough retainers are found
 // 4. Reduced path tracking overhead
 func (g *ReferenceGraph) ComputeBusinessRetainers(targetClassName string, maxDepth, topN int) []*BusinessRetainer {
+	// A background context is never canceled
+	retainers, _ := g.ComputeBusinessRetainersContext(context.Background(), targetClassName, maxDepth, topN)
+	return retainers
+}
+
+// ComputeBusinessRetainersContext is ComputeBusinessRetainers stopping with
+// the error of ctx when ctx is done.
+func (g *ReferenceGraph) ComputeBusinessRetainersContext(ctx context.Context, targetClassName string, maxDepth, topN int) ([]*BusinessRetainer, error) {
 	if maxDepth <= 0 {
 		maxDepth = 15
 	}
@@ -502,12 +688,12 @@ func (g *ReferenceGraph) ComputeBusinessRetainers(targetClassName string, maxDep
 	// Use index to find target class objects - O(1) lookup
 	targetClassID, found := g.getClassIDByName(targetClassName)
 	if !found {
-		return nil
+		return nil, nil
 	}
 
 	targetObjects := g.getObjectsByClass(targetClassID)
 	if len(targetObjects) == 0 {
-		return nil
+		return nil, nil
 	}
 
 	// Calculate total size
@@ -537,6 +723,9 @@ func (g *ReferenceGraph) ComputeBusinessRetainers(targetClassName string, maxDep
 
 	// Process samples with shared state
 	for _, objID := range sampleObjects {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
 		objSize := g.objectSize[objID]
 
 		// BFS with optimized structure - no path tracking for performance
@@ -697,7 +886,7 @@ func (g *ReferenceGraph) ComputeBusinessRetainers(targetClassName string, maxDep
 		retainers = retainers[:topN]
 	}
 
-	return retainers
+	return retainers, nil
 }
 
 // GetReferenceGraphData returns data for visualization.
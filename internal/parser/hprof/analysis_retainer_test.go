@@ -0,0 +1,108 @@
+package hprof
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newRetainerTestGraph returns a graph of 5000 byte[] instances, each held by
+// a Holder except for the last one, held by a Rare object.
+func newRetainerTestGraph() *ReferenceGraph {
+	g := NewReferenceGraphWithCapacity(16)
+	g.SetClassName(1, "byte[]")
+	g.SetClassName(2, "com.example.Holder")
+	g.SetClassName(3, "com.example.Rare")
+	for i := uint64(0); i < 5000; i++ {
+		g.SetObjectInfo(10000+i, 1, 16)
+		holderClass := uint64(2)
+		if i == 4999 {
+			holderClass = 3
+		}
+		g.SetObjectInfo(20000+i, holderClass, 16)
+		g.AddReference(ObjectReference{FromObjectID: 20000 + i, ToObjectID: 10000 + i, FromClassID: holderClass, FieldName: "data"})
+	}
+	return g
+}
+
+func TestComputeMultiLevelRetainers_Modes(t *testing.T) {
+	// Without a budget large classes are sampled
+	retainers := newRetainerTestGraph().ComputeMultiLevelRetainers("byte[]", 1, 10)
+	require.NotNil(t, retainers)
+	assert.Equal(t, RetainerModeSampled, retainers.Mode)
+	assert.Equal(t, int64(1000), retainers.AnalyzedObjects)
+
+	// Exact mode counts every instance
+	g := newRetainerTestGraph()
+	g.SetRetainerExactBudget(time.Minute)
+	retainers = g.ComputeMultiLevelRetainers("byte[]", 1, 10)
+	assert.Equal(t, RetainerModeExact, retainers.Mode)
+	assert.Equal(t, int64(5000), retainers.AnalyzedObjects)
+	require.Len(t, retainers.Retainers, 2)
+	assert.Equal(t, "com.example.Holder", retainers.Retainers[0].RetainerClass)
+	assert.Equal(t, int64(4999), retainers.Retainers[0].RetainedCount)
+	assert.Equal(t, "com.example.Rare", retainers.Retainers[1].RetainerClass)
+	assert.Equal(t, int64(1), retainers.Retainers[1].RetainedCount)
+	assert.Equal(t, int64(16), retainers.Retainers[1].RetainedSize)
+
+	// Running out of budget falls back to the sample
+	g = newRetainerTestGraph()
+	g.SetRetainerExactBudget(time.Nanosecond)
+	retainers = g.ComputeMultiLevelRetainers("byte[]", 1, 10)
+	assert.Equal(t, RetainerModeSampled, retainers.Mode)
+	assert.Equal(t, int64(1000), retainers.AnalyzedObjects)
+	for _, r := range retainers.Retainers {
+		assert.LessOrEqual(t, r.RetainedCount, int64(5000))
+	}
+}
+
+func TestComputeDominatorRetainers(t *testing.T) {
+	g := NewReferenceGraphWithCapacity(16)
+	g.SetClassName(1, "com.example.Node")
+	g.SetClassName(2, "com.example.Cache")
+	g.SetClassName(3, "java.lang.Thread")
+	g.SetObjectInfo(1, 2, 24)
+	g.SetObjectInfo(2, 2, 24)
+	g.SetObjectInfo(3, 3, 120)
+	for id := uint64(10); id <= 16; id++ {
+		g.SetObjectInfo(id, 1, 16)
+	}
+	ref := func(from, to, classID uint64, field string) {
+		g.AddReference(ObjectReference{FromObjectID: from, ToObjectID: to, FromClassID: classID, FieldName: field})
+	}
+
+	// Cache 1 -> 10 -> 11 -> 12, Cache 2 -> 13, Thread 3 -> 16, 14 is a
+	// root and 15 is unreachable
+	ref(1, 10, 2, "head")
+	ref(10, 11, 1, "next")
+	ref(11, 12, 1, "next")
+	ref(2, 13, 2, "head")
+	ref(3, 16, 3, "local")
+	for _, id := range []uint64{1, 2, 3, 14} {
+		g.AddGCRoot(&GCRoot{ObjectID: id, Type: GCRootJNIGlobal})
+	}
+
+	retainers := g.ComputeDominatorRetainers("com.example.Node", 10)
+	require.NotNil(t, retainers)
+	assert.Equal(t, RetainerModeDominator, retainers.Mode)
+	assert.Equal(t, int64(7), retainers.InstanceCount)
+	assert.Equal(t, int64(6), retainers.AnalyzedObjects)
+	assert.Equal(t, int64(96), retainers.TotalSize)
+
+	require.Len(t, retainers.Retainers, 3)
+	cache := retainers.Retainers[0]
+	assert.Equal(t, "com.example.Cache", cache.RetainerClass)
+	assert.Equal(t, int64(4), cache.RetainedCount)
+	assert.Equal(t, int64(64), cache.RetainedSize)
+	assert.Equal(t, int64(2), cache.Dominators)
+	assert.InDelta(t, 66.67, cache.Percentage, 0.01)
+	assert.Equal(t, DominatorRetainerGCRoots, retainers.Retainers[1].RetainerClass)
+	assert.Equal(t, int64(1), retainers.Retainers[1].RetainedCount)
+	assert.Zero(t, retainers.Retainers[1].Dominators)
+	assert.Equal(t, "java.lang.Thread", retainers.Retainers[2].RetainerClass)
+
+	assert.Len(t, g.ComputeDominatorRetainers("com.example.Node", 1).Retainers, 1)
+	assert.Nil(t, g.ComputeDominatorRetainers("com.example.Missing", 10))
+}
@@ -0,0 +1,259 @@
+package hprof
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// DefaultStringStatsLimit is the number of java.lang.String objects read by
+// default to find duplicated values.
+const DefaultStringStatsLimit = 1 << 20
+
+// duplicateStringsTopN is the number of duplicated values kept in StringStats.
+const duplicateStringsTopN = 20
+
+// internCandidatesTopN is the number of fields kept in StringStats.InternCandidates,
+// and internCandidateValuesTopN the number of values listed for each.
+const (
+	internCandidatesTopN      = 20
+	internCandidateValuesTopN = 5
+)
+
+// fieldKey identifies a field referencing Strings.
+type fieldKey struct {
+	classID uint64
+	field   string
+	static  bool
+}
+
+// fieldStrings accumulates the String values referenced by one field.
+type fieldStrings struct {
+	count  int64
+	size   int64
+	values map[string]*fieldStringValue
+}
+
+// fieldStringValue is one value of a field: the value array of its first
+// copy, its copies and the bytes interning them would save.
+type fieldStringValue struct {
+	valueID uint64
+	count   int64
+	savings int64
+}
+
+// add records a String referenced by the field.
+func (f *fieldStrings) add(text string, valueID uint64, stringSize, arraySize int64) {
+	f.count++
+	f.size += stringSize + arraySize
+	v, ok := f.values[text]
+	if !ok {
+		f.values[text] = &fieldStringValue{valueID: valueID, count: 1}
+		return
+	}
+	v.count++
+	v.savings += stringSize
+	if valueID != v.valueID {
+		v.savings += arraySize
+	}
+}
+
+// stringField returns the field of a reference to a String. Array elements
+// are the field "[]" of the array class; references of the JVM, such as
+// those of a class to its loader, are not fields.
+func stringField(ref ObjectReference) (fieldKey, bool) {
+	switch {
+	case ref.FieldName == "" || strings.HasPrefix(ref.FieldName, "<"):
+		return fieldKey{}, false
+	case strings.HasPrefix(ref.FieldName, "["):
+		return fieldKey{classID: ref.FromClassID, field: "[]"}, true
+	default:
+		// Static fields are referenced by their class object
+		return fieldKey{classID: ref.FromClassID, field: ref.FieldName, static: ref.FromObjectID == ref.FromClassID}, true
+	}
+}
+
+// ComputeStringStats sets the StringStats of a result from the values of up
+// to limit java.lang.String objects (all if limit <= 0), read from the heap
+// dump. The waste of a duplicated value is the String objects of its copies
+// but one, and their value arrays unless shared with the first copy, e.g. by
+// G1 string deduplication. The same waste within the Strings of each field
+// ranks the fields worth interning. Values longer than MaxStringLength are
+// counted but not compared. It fails when the heap dump was not indexed.
+func ComputeStringStats(result *HeapAnalysisResult, limit int) (*StringStats, error) {
+	g := result.RefGraph
+	if g == nil || result.ObjectIndex == nil || result.ObjectIndex.SourceFile == "" {
+		return nil, fmt.Errorf("string values are not available: heap dump was not indexed")
+	}
+	stats := &StringStats{}
+	classID, ok := g.getClassIDByName("java.lang.String")
+	if !ok {
+		return stats, nil
+	}
+
+	reader := NewObjectReader(result.ObjectIndex, result.ClassLayouts)
+	f, err := reader.open()
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	// Read in object ID order, which is usually the order of the dump
+	ids := append([]uint64(nil), g.getObjectsByClass(classID)...)
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	type valueStats struct {
+		valueID uint64 // value array of the first copy
+		count   int64
+		waste   int64
+	}
+	values := make(map[string]*valueStats)
+	fields := make(map[fieldKey]*fieldStrings)
+	var totalLength int64
+	for _, id := range ids {
+		if limit > 0 && stats.TotalCount == int64(limit) {
+			stats.Truncated = true
+			break
+		}
+		rec, err := reader.readRecord(f, id, 0)
+		if err != nil || rec.tag != HeapTagInstanceDump {
+			continue
+		}
+		text, valueID, truncated, ok := reader.stringValue(f, rec, MaxStringLength)
+		if !ok {
+			continue
+		}
+		stringSize, arraySize := g.GetObjectSize(id), g.GetObjectSize(valueID)
+		length := utf8.RuneCountInString(text)
+		stats.TotalCount++
+		stats.TotalSize += stringSize + arraySize
+		totalLength += int64(length)
+		if length > stats.MaxLength {
+			stats.MaxLength = length
+		}
+		if truncated {
+			continue
+		}
+
+		for _, ref := range g.GetIncomingRefs(id) {
+			key, ok := stringField(ref)
+			if !ok {
+				continue
+			}
+			f, ok := fields[key]
+			if !ok {
+				f = &fieldStrings{values: make(map[string]*fieldStringValue)}
+				fields[key] = f
+			}
+			f.add(text, valueID, stringSize, arraySize)
+		}
+
+		v, ok := values[text]
+		if !ok {
+			values[text] = &valueStats{valueID: valueID, count: 1}
+			continue
+		}
+		v.count++
+		v.waste += stringSize
+		if valueID != v.valueID {
+			v.waste += arraySize
+		}
+	}
+	if stats.TotalCount > 0 {
+		stats.AvgLength = float64(totalLength) / float64(stats.TotalCount)
+	}
+
+	stats.UniqueCount = int64(len(values))
+	for text, v := range values {
+		if v.count == 1 {
+			continue
+		}
+		stats.DuplicateCount += v.count - 1
+		stats.DuplicateWaste += v.waste
+		stats.TopDuplicates = append(stats.TopDuplicates, &DuplicateString{Value: text, Count: v.count, Waste: v.waste})
+	}
+	sort.Slice(stats.TopDuplicates, func(i, j int) bool {
+		a, b := stats.TopDuplicates[i], stats.TopDuplicates[j]
+		if a.Waste != b.Waste {
+			return a.Waste > b.Waste
+		}
+		return a.Value < b.Value
+	})
+	if len(stats.TopDuplicates) > duplicateStringsTopN {
+		stats.TopDuplicates = stats.TopDuplicates[:duplicateStringsTopN]
+	}
+	for _, d := range stats.TopDuplicates {
+		d.Value = previewString(d.Value)
+	}
+	stats.InternCandidates = internCandidates(g, fields)
+	result.StringStats = stats
+	return stats, nil
+}
+
+// internCandidates ranks the fields referencing Strings by the bytes
+// interning their values would save.
+func internCandidates(g *ReferenceGraph, fields map[fieldKey]*fieldStrings) []*StringInternCandidate {
+	var candidates []*StringInternCandidate
+	for key, f := range fields {
+		c := &StringInternCandidate{
+			ClassName: g.GetClassName(key.classID),
+			Field:     key.field,
+			Static:    key.static,
+			Count:     f.count,
+			Distinct:  int64(len(f.values)),
+			Size:      f.size,
+		}
+		for text, v := range f.values {
+			c.Savings += v.savings
+			if v.count > 1 {
+				c.TopValues = append(c.TopValues, &DuplicateString{Value: text, Count: v.count, Waste: v.savings})
+			}
+		}
+		if c.Savings == 0 {
+			continue
+		}
+		sort.Slice(c.TopValues, func(i, j int) bool {
+			a, b := c.TopValues[i], c.TopValues[j]
+			if a.Waste != b.Waste {
+				return a.Waste > b.Waste
+			}
+			return a.Value < b.Value
+		})
+		if len(c.TopValues) > internCandidateValuesTopN {
+			c.TopValues = c.TopValues[:internCandidateValuesTopN]
+		}
+		for _, d := range c.TopValues {
+			d.Value = previewString(d.Value)
+		}
+		candidates = append(candidates, c)
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		a, b := candidates[i], candidates[j]
+		if a.Savings != b.Savings {
+			return a.Savings > b.Savings
+		}
+		if a.ClassName != b.ClassName {
+			return a.ClassName < b.ClassName
+		}
+		return a.Field < b.Field
+	})
+	if len(candidates) > internCandidatesTopN {
+		candidates = candidates[:internCandidatesTopN]
+	}
+	return candidates
+}
+
+// previewString cuts a value after StringPreviewLength characters.
+func previewString(s string) string {
+	i, n := 0, 0
+	for i < len(s) {
+		if n == StringPreviewLength {
+			return s[:i] + "…"
+		}
+		_, size := utf8.DecodeRuneInString(s[i:])
+		i += size
+		n++
+	}
+	return s
+}
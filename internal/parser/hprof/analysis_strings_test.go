@@ -0,0 +1,134 @@
+package hprof
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// runTestJob analyzes a heap dump from a file, so that it is indexed.
+func runTestJob(t *testing.T, data []byte) *HeapAnalysisResult {
+	dir := t.TempDir()
+	input := filepath.Join(dir, "heap.hprof")
+	require.NoError(t, os.WriteFile(input, data, 0644))
+
+	job, err := NewAnalysisJob(AnalysisJobConfig{TaskDir: filepath.Join(dir, "task"), InputFile: input, SerializeOptions: FastSerializeOptions()})
+	require.NoError(t, err)
+	f, err := os.Open(input)
+	require.NoError(t, err)
+	defer f.Close()
+	result, err := job.Run(context.Background(), f)
+	require.NoError(t, err)
+	return result
+}
+
+func TestComputeStringStats(t *testing.T) {
+	b := newTestHprofBuilder()
+	b.loadClass(0x30, "java/lang/String")
+	b.classDump(0x30, 0, 0, testField{"value", TypeObject}, testField{"coder", TypeByte})
+
+	// "dup" three times, two of them sharing their value array, and "solo"
+	b.instanceDump(0x3000, 0x30, append(refBytes(0x3100), 0))
+	b.primitiveArrayDump(0x3100, TypeByte, 3, []byte("dup"))
+	b.instanceDump(0x3200, 0x30, append(refBytes(0x3100), 0))
+	b.instanceDump(0x3300, 0x30, append(refBytes(0x3400), 0))
+	b.primitiveArrayDump(0x3400, TypeByte, 3, []byte("dup"))
+	b.instanceDump(0x3500, 0x30, append(refBytes(0x3600), 0))
+	b.primitiveArrayDump(0x3600, TypeByte, 4, []byte("solo"))
+	for _, id := range []uint64{0x3000, 0x3200, 0x3300, 0x3500} {
+		b.rootJNIGlobal(id)
+	}
+	result := runTestJob(t, b.bytes())
+
+	stats, err := ComputeStringStats(result, 0)
+	require.NoError(t, err)
+	assert.Same(t, stats, result.StringStats)
+	assert.Equal(t, int64(4), stats.TotalCount)
+	assert.Equal(t, int64(2), stats.UniqueCount)
+	assert.Equal(t, int64(2), stats.DuplicateCount)
+	assert.Equal(t, 4, stats.MaxLength)
+	assert.InDelta(t, 3.25, stats.AvgLength, 1e-9)
+	assert.False(t, stats.Truncated)
+
+	g := result.RefGraph
+	stringSize, arraySize := g.GetObjectSize(0x3200), g.GetObjectSize(0x3400)
+	require.Len(t, stats.TopDuplicates, 1)
+	assert.Equal(t, DuplicateString{Value: "dup", Count: 3, Waste: 2*stringSize + arraySize}, *stats.TopDuplicates[0])
+	assert.Equal(t, stats.TopDuplicates[0].Waste, stats.DuplicateWaste)
+
+	// Limited to the first Strings
+	stats, err = ComputeStringStats(result, 2)
+	require.NoError(t, err)
+	assert.True(t, stats.Truncated)
+	assert.Equal(t, int64(2), stats.TotalCount)
+	assert.Equal(t, int64(1), stats.DuplicateCount)
+}
+
+func TestComputeStringStats_NotIndexed(t *testing.T) {
+	_, err := ComputeStringStats(&HeapAnalysisResult{}, 0)
+	assert.Error(t, err)
+}
+
+func TestPreviewString(t *testing.T) {
+	assert.Equal(t, "héllo", previewString("héllo"))
+	long := strings.Repeat("é", StringPreviewLength+10)
+	assert.Equal(t, strings.Repeat("é", StringPreviewLength)+"…", previewString(long))
+}
+
+func TestComputeStringStats_InternCandidates(t *testing.T) {
+	b := newTestHprofBuilder()
+	b.loadClass(0x30, "java/lang/String")
+	b.loadClass(0x40, "com/example/Order")
+	b.loadClass(0x50, "java/lang/String[]")
+	b.classDump(0x30, 0, 0, testField{"value", TypeObject}, testField{"coder", TypeByte})
+	b.classDump(0x40, 0, 0, testField{"status", TypeObject}, testField{"id", TypeObject})
+
+	// Three orders with the status "NEW" or "PAID" and distinct ids
+	str := func(id, arrayID uint64, text string) {
+		b.instanceDump(id, 0x30, append(refBytes(arrayID), 0))
+		b.primitiveArrayDump(arrayID, TypeByte, len(text), []byte(text))
+	}
+	str(0x3000, 0x3001, "NEW")
+	str(0x3010, 0x3011, "NEW")
+	str(0x3020, 0x3021, "PAID")
+	str(0x3100, 0x3101, "1")
+	str(0x3110, 0x3111, "2")
+	str(0x3120, 0x3121, "3")
+	b.instanceDump(0x4000, 0x40, append(refBytes(0x3000), refBytes(0x3100)...))
+	b.instanceDump(0x4010, 0x40, append(refBytes(0x3010), refBytes(0x3110)...))
+	b.instanceDump(0x4020, 0x40, append(refBytes(0x3020), refBytes(0x3120)...))
+	// An array of copies of "NEW"
+	str(0x3200, 0x3201, "NEW")
+	b.objectArrayDump(0x5000, 0x50, 0x3200, 0x3000)
+	for _, id := range []uint64{0x4000, 0x4010, 0x4020, 0x5000} {
+		b.rootJNIGlobal(id)
+	}
+	result := runTestJob(t, b.bytes())
+
+	stats, err := ComputeStringStats(result, 0)
+	require.NoError(t, err)
+	g := result.RefGraph
+	copySize := g.GetObjectSize(0x3010) + g.GetObjectSize(0x3011)
+
+	// The id field holds distinct values and is not a candidate
+	require.Len(t, stats.InternCandidates, 2)
+	status := stats.InternCandidates[0]
+	assert.Equal(t, "com.example.Order", status.ClassName)
+	assert.Equal(t, "status", status.Field)
+	assert.False(t, status.Static)
+	assert.Equal(t, int64(3), status.Count)
+	assert.Equal(t, int64(2), status.Distinct)
+	assert.Equal(t, copySize, status.Savings)
+	require.Len(t, status.TopValues, 1)
+	assert.Equal(t, DuplicateString{Value: "NEW", Count: 2, Waste: copySize}, *status.TopValues[0])
+
+	elements := stats.InternCandidates[1]
+	assert.Equal(t, "java.lang.String[]", elements.ClassName)
+	assert.Equal(t, "[]", elements.Field)
+	assert.Equal(t, int64(2), elements.Count)
+}
@@ -0,0 +1,274 @@
+package hprof
+
+import (
+	"sort"
+)
+
+// Line number markers of STACK_FRAME records.
+const (
+	LineNumberNone     int32 = 0
+	LineNumberUnknown  int32 = -1
+	LineNumberCompiled int32 = -2
+	LineNumberNative   int32 = -3
+)
+
+// ThreadOverview lists the threads of a heap dump with their stacks, like
+// MAT's Thread Overview. Threads are sorted by TotalRetainedSize descending.
+type ThreadOverview struct {
+	TotalThreads  int           `json:"total_threads"`
+	TotalRetained int64         `json:"total_retained"`
+	Threads       []*ThreadInfo `json:"threads"`
+}
+
+// ThreadInfo is one thread: its java.lang.Thread object and its stack.
+// Thread names are not available since string contents are not retained.
+type ThreadInfo struct {
+	ThreadSerial uint32 `json:"thread_serial"`
+	ObjectID     uint64 `json:"object_id,omitempty"`
+	ClassName    string `json:"class_name,omitempty"`
+	ShallowSize  int64  `json:"shallow_size"`
+	// RetainedSize is the retained size of the thread object
+	RetainedSize int64 `json:"retained_size"`
+	// LocalsRetainedSize is the retained size of the objects referenced from
+	// the stack, each counted once
+	LocalsRetainedSize int64 `json:"locals_retained_size"`
+	// TotalRetainedSize is RetainedSize + LocalsRetainedSize
+	TotalRetainedSize int64          `json:"total_retained_size"`
+	Frames            []*ThreadFrame `json:"frames"`
+	// UnattributedLocals are stack locals whose frame is not in the stack trace
+	UnattributedLocals []*ThreadLocal `json:"unattributed_locals,omitempty"`
+}
+
+// ThreadFrame is one stack frame, top of stack first.
+type ThreadFrame struct {
+	Depth           int    `json:"depth"`
+	ClassName       string `json:"class_name"`
+	MethodName      string `json:"method_name"`
+	MethodSignature string `json:"method_signature,omitempty"`
+	SourceFile      string `json:"source_file,omitempty"`
+	// LineNumber is the source line, or one of the LineNumber* markers
+	LineNumber int32          `json:"line_number"`
+	Locals     []*ThreadLocal `json:"locals,omitempty"`
+}
+
+// ThreadLocal is an object referenced from a stack frame (a JAVA_FRAME or
+// JNI_LOCAL GC root).
+type ThreadLocal struct {
+	ObjectID     uint64     `json:"object_id"`
+	ClassName    string     `json:"class_name"`
+	RootType     GCRootType `json:"root_type"`
+	ShallowSize  int64      `json:"shallow_size"`
+	RetainedSize int64      `json:"retained_size"`
+}
+
+// stackFrameRecord is a STACK_FRAME record with unresolved string IDs.
+type stackFrameRecord struct {
+	methodNameID uint64
+	signatureID  uint64
+	sourceFileID uint64
+	classSerial  uint32
+	lineNumber   int32
+}
+
+// stackTraceRecord is a STACK_TRACE record.
+type stackTraceRecord struct {
+	threadSerial uint32
+	frameIDs     []uint64
+}
+
+// threadObjectRecord is a ROOT_THREAD_OBJECT sub-record.
+type threadObjectRecord struct {
+	objectID    uint64
+	traceSerial uint32
+}
+
+// stackLocalRecord is a JAVA_FRAME or JNI_LOCAL root of a thread.
+type stackLocalRecord struct {
+	objectID   uint64
+	frameIndex uint32
+	rootType   GCRootType
+}
+
+// threadStackCollector gathers thread, stack trace and stack frame records
+// during parsing and resolves them into a ThreadOverview.
+type threadStackCollector struct {
+	frames       map[uint64]*stackFrameRecord
+	traces       map[uint32]*stackTraceRecord
+	threads      map[uint32]*threadObjectRecord // by thread serial
+	locals       map[uint32][]stackLocalRecord  // by thread serial
+	classSerials map[uint32]uint64              // class serial -> class object ID
+}
+
+// newThreadStackCollector creates an empty collector.
+func newThreadStackCollector() *threadStackCollector {
+	return &threadStackCollector{
+		frames:       make(map[uint64]*stackFrameRecord),
+		traces:       make(map[uint32]*stackTraceRecord),
+		threads:      make(map[uint32]*threadObjectRecord),
+		locals:       make(map[uint32][]stackLocalRecord),
+		classSerials: make(map[uint32]uint64),
+	}
+}
+
+// addClassSerial records the class serial number of a LOAD_CLASS record.
+func (c *threadStackCollector) addClassSerial(serial uint32, classID uint64) {
+	c.classSerials[serial] = classID
+}
+
+// addFrame records a STACK_FRAME record.
+func (c *threadStackCollector) addFrame(frameID uint64, frame *stackFrameRecord) {
+	c.frames[frameID] = frame
+}
+
+// addTrace records a STACK_TRACE record.
+func (c *threadStackCollector) addTrace(serial uint32, trace *stackTraceRecord) {
+	c.traces[serial] = trace
+}
+
+// addThread records a ROOT_THREAD_OBJECT sub-record.
+func (c *threadStackCollector) addThread(threadSerial uint32, objectID uint64, traceSerial uint32) {
+	c.threads[threadSerial] = &threadObjectRecord{objectID: objectID, traceSerial: traceSerial}
+}
+
+// addLocal records a JAVA_FRAME or JNI_LOCAL root of a thread.
+func (c *threadStackCollector) addLocal(threadSerial uint32, objectID uint64, frameIndex uint32, rootType GCRootType) {
+	c.locals[threadSerial] = append(c.locals[threadSerial], stackLocalRecord{
+		objectID:   objectID,
+		frameIndex: frameIndex,
+		rootType:   rootType,
+	})
+}
+
+// threadStackResolver supplies names and sizes when building the overview.
+type threadStackResolver struct {
+	// str resolves a string ID
+	str func(id uint64) string
+	// className resolves a class object ID
+	className func(classID uint64) string
+	// objectClassName, shallowSize and retainedSize describe heap objects;
+	// they may be nil when no reference graph was built
+	objectClassName func(objectID uint64) string
+	shallowSize     func(objectID uint64) int64
+	retainedSize    func(objectID uint64) int64
+}
+
+// overview resolves the collected records into a ThreadOverview. Threads
+// come from ROOT_THREAD_OBJECT records and from stack traces of threads
+// without one.
+func (c *threadStackCollector) overview(r threadStackResolver) *ThreadOverview {
+	serials := make(map[uint32]bool, len(c.threads))
+	for serial := range c.threads {
+		serials[serial] = true
+	}
+	for _, trace := range c.traces {
+		// Serial 0 is the dummy trace of allocation sites without a thread
+		if trace.threadSerial != 0 {
+			serials[trace.threadSerial] = true
+		}
+	}
+
+	overview := &ThreadOverview{Threads: make([]*ThreadInfo, 0, len(serials))}
+	for serial := range serials {
+		thread := c.buildThread(serial, r)
+		overview.Threads = append(overview.Threads, thread)
+		overview.TotalRetained += thread.TotalRetainedSize
+	}
+	overview.TotalThreads = len(overview.Threads)
+
+	sort.Slice(overview.Threads, func(i, j int) bool {
+		a, b := overview.Threads[i], overview.Threads[j]
+		if a.TotalRetainedSize != b.TotalRetainedSize {
+			return a.TotalRetainedSize > b.TotalRetainedSize
+		}
+		return a.ThreadSerial < b.ThreadSerial
+	})
+	return overview
+}
+
+// buildThread resolves one thread, its frames and its locals.
+func (c *threadStackCollector) buildThread(serial uint32, r threadStackResolver) *ThreadInfo {
+	thread := &ThreadInfo{ThreadSerial: serial, Frames: []*ThreadFrame{}}
+
+	trace := c.traceOfThread(serial)
+	if obj, ok := c.threads[serial]; ok {
+		thread.ObjectID = obj.objectID
+		thread.ClassName, thread.ShallowSize, thread.RetainedSize = r.describe(obj.objectID)
+	}
+
+	if trace != nil {
+		thread.Frames = c.resolveFrames(trace, r)
+	}
+
+	counted := make(map[uint64]bool)
+	for _, rec := range c.locals[serial] {
+		local := &ThreadLocal{ObjectID: rec.objectID, RootType: rec.rootType}
+		local.ClassName, local.ShallowSize, local.RetainedSize = r.describe(rec.objectID)
+		if int(rec.frameIndex) < len(thread.Frames) {
+			frame := thread.Frames[rec.frameIndex]
+			frame.Locals = append(frame.Locals, local)
+		} else {
+			thread.UnattributedLocals = append(thread.UnattributedLocals, local)
+		}
+		if !counted[rec.objectID] {
+			counted[rec.objectID] = true
+			thread.LocalsRetainedSize += local.RetainedSize
+		}
+	}
+
+	thread.TotalRetainedSize = thread.RetainedSize + thread.LocalsRetainedSize
+	return thread
+}
+
+// resolveFrames resolves the frames of a stack trace, top of stack first.
+func (c *threadStackCollector) resolveFrames(trace *stackTraceRecord, r threadStackResolver) []*ThreadFrame {
+	frames := make([]*ThreadFrame, 0, len(trace.frameIDs))
+	for depth, frameID := range trace.frameIDs {
+		frame := &ThreadFrame{Depth: depth, LineNumber: LineNumberUnknown}
+		if rec, ok := c.frames[frameID]; ok {
+			frame.MethodName = r.str(rec.methodNameID)
+			frame.MethodSignature = r.str(rec.signatureID)
+			frame.SourceFile = r.str(rec.sourceFileID)
+			frame.LineNumber = rec.lineNumber
+			if classID, ok := c.classSerials[rec.classSerial]; ok {
+				frame.ClassName = r.className(classID)
+			}
+		}
+		frames = append(frames, frame)
+	}
+	return frames
+}
+
+// traceOfThread returns the stack trace of a thread: the one named by its
+// ROOT_THREAD_OBJECT record, else any trace recorded for its serial.
+func (c *threadStackCollector) traceOfThread(serial uint32) *stackTraceRecord {
+	if obj, ok := c.threads[serial]; ok {
+		if trace, ok := c.traces[obj.traceSerial]; ok {
+			return trace
+		}
+	}
+	var found *stackTraceRecord
+	var foundSerial uint32
+	for traceSerial, trace := range c.traces {
+		// Pick deterministically when a thread has several traces
+		if trace.threadSerial == serial && (found == nil || traceSerial < foundSerial) {
+			found, foundSerial = trace, traceSerial
+		}
+	}
+	return found
+}
+
+// describe returns the class name, shallow size and retained size of an object.
+func (r threadStackResolver) describe(objectID uint64) (string, int64, int64) {
+	var className string
+	var shallow, retained int64
+	if r.objectClassName != nil {
+		className = r.objectClassName(objectID)
+	}
+	if r.shallowSize != nil {
+		shallow = r.shallowSize(objectID)
+	}
+	if r.retainedSize != nil {
+		retained = r.retainedSize(objectID)
+	}
+	return className, shallow, retained
+}
@@ -0,0 +1,70 @@
+package hprof
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParser_ThreadOverview(t *testing.T) {
+	b := newTestHprofBuilder()
+	b.loadClassSerial(1, 0x10, "java/lang/Thread")
+	b.loadClassSerial(2, 0x20, "com/example/Worker")
+	b.stackFrame(0xF1, "run", "()V", "Worker.java", 2, 42)
+	b.stackFrame(0xF2, "run", "()V", "Thread.java", 1, LineNumberNative)
+	b.stackTrace(7, 1, 0xF1, 0xF2)
+	b.stackTrace(8, 2)
+
+	b.classDump(0x10, 0, 0)
+	b.classDump(0x20, 0, 0)
+	b.instanceDump(0x1000, 0x10, nil)
+	b.instanceDump(0x2000, 0x20, nil)
+	b.primitiveArrayDump(0x3000, TypeByte, 100, nil)
+	b.primitiveArrayDump(0x4000, TypeByte, 10, nil)
+	b.rootThreadObject(0x1000, 1, 7)
+	b.rootJavaFrame(0x2000, 1, 0)
+	b.rootJavaFrame(0x3000, 1, 1)
+	b.rootJavaFrame(0x3000, 1, 1)
+	b.rootJavaFrame(0x4000, 1, 0xFFFFFFFF)
+
+	result, err := NewParser(nil).Parse(context.Background(), bytes.NewReader(b.bytes()))
+	require.NoError(t, err)
+	require.NotNil(t, result.Threads)
+	require.Equal(t, 2, result.Threads.TotalThreads)
+
+	thread := result.Threads.Threads[0]
+	assert.Equal(t, uint32(1), thread.ThreadSerial)
+	assert.Equal(t, uint64(0x1000), thread.ObjectID)
+	assert.Equal(t, "java.lang.Thread", thread.ClassName)
+	require.Len(t, thread.Frames, 2)
+
+	top := thread.Frames[0]
+	assert.Equal(t, "com.example.Worker", top.ClassName)
+	assert.Equal(t, "run", top.MethodName)
+	assert.Equal(t, "Worker.java", top.SourceFile)
+	assert.Equal(t, int32(42), top.LineNumber)
+	require.Len(t, top.Locals, 1)
+	assert.Equal(t, uint64(0x2000), top.Locals[0].ObjectID)
+	assert.Equal(t, GCRootJavaFrame, top.Locals[0].RootType)
+
+	assert.Equal(t, LineNumberNative, thread.Frames[1].LineNumber)
+	require.Len(t, thread.Frames[1].Locals, 2)
+	assert.Equal(t, "byte[]", thread.Frames[1].Locals[0].ClassName)
+	require.Len(t, thread.UnattributedLocals, 1)
+	assert.Equal(t, uint64(0x4000), thread.UnattributedLocals[0].ObjectID)
+
+	// The local referenced twice is counted once
+	expected := top.Locals[0].RetainedSize + thread.Frames[1].Locals[0].RetainedSize + thread.UnattributedLocals[0].RetainedSize
+	assert.Equal(t, expected, thread.LocalsRetainedSize)
+	assert.Equal(t, thread.RetainedSize+thread.LocalsRetainedSize, thread.TotalRetainedSize)
+	assert.Positive(t, thread.Frames[1].Locals[0].RetainedSize)
+
+	// A thread known only from its stack trace
+	other := result.Threads.Threads[1]
+	assert.Equal(t, uint32(2), other.ThreadSerial)
+	assert.Zero(t, other.ObjectID)
+	assert.Empty(t, other.Frames)
+}
@@ -0,0 +1,154 @@
+package hprof
+
+import (
+	"sort"
+)
+
+// Reachability strengths, ordered like java.lang.ref: an object is as
+// reachable as the strongest of its paths from a GC root, and a path is as
+// strong as its weakest Reference referent.
+const (
+	weaklyReachable uint8 = iota + 1
+	softlyReachable
+	stronglyReachable
+)
+
+// WeakReachableClass is the size of the instances of a class that are not
+// strongly reachable, and are thus collected under memory pressure.
+type WeakReachableClass struct {
+	ClassName string `json:"class_name"`
+	// SoftCount and SoftSize cover instances reachable through SoftReferences
+	SoftCount int64 `json:"soft_count"`
+	SoftSize  int64 `json:"soft_size"`
+	// WeakCount and WeakSize cover instances only reachable through WeakReferences
+	WeakCount int64 `json:"weak_count"`
+	WeakSize  int64 `json:"weak_size"`
+}
+
+// WeakReachability summarizes the objects of a heap that are reachable only
+// through the referents of soft or weak references: soft caches and weak maps
+// that the GC clears before throwing OutOfMemoryError, as opposed to leaks.
+// Objects only reachable through phantom or final references are not counted.
+type WeakReachability struct {
+	SoftObjects int64 `json:"soft_objects"`
+	SoftSize    int64 `json:"soft_size"`
+	WeakObjects int64 `json:"weak_objects"`
+	WeakSize    int64 `json:"weak_size"`
+	// Classes are sorted by soft and weak size descending
+	Classes []*WeakReachableClass `json:"classes,omitempty"`
+}
+
+// ComputeWeakReachability sets the WeakReachability of a result.
+func ComputeWeakReachability(result *HeapAnalysisResult) *WeakReachability {
+	if result.RefGraph == nil {
+		return nil
+	}
+	result.WeakReachability = result.RefGraph.WeakReachability()
+	return result.WeakReachability
+}
+
+// WeakReachability returns the objects of g that are only softly or weakly
+// reachable, per class.
+func (g *ReferenceGraph) WeakReachability() *WeakReachability {
+	strength := g.reachabilityStrengths()
+
+	report := &WeakReachability{}
+	byClass := make(map[uint64]*WeakReachableClass)
+	for objID, s := range strength {
+		if s == stronglyReachable {
+			continue
+		}
+		classID := g.objectClass[objID]
+		cls, ok := byClass[classID]
+		if !ok {
+			cls = &WeakReachableClass{ClassName: g.GetClassName(classID)}
+			if cls.ClassName == "" {
+				cls.ClassName = "Unknown"
+			}
+			byClass[classID] = cls
+		}
+		size := g.objectSize[objID]
+		if s == softlyReachable {
+			cls.SoftCount++
+			cls.SoftSize += size
+			report.SoftObjects++
+			report.SoftSize += size
+		} else {
+			cls.WeakCount++
+			cls.WeakSize += size
+			report.WeakObjects++
+			report.WeakSize += size
+		}
+	}
+
+	report.Classes = make([]*WeakReachableClass, 0, len(byClass))
+	for _, cls := range byClass {
+		report.Classes = append(report.Classes, cls)
+	}
+	sort.Slice(report.Classes, func(i, j int) bool {
+		a, b := report.Classes[i], report.Classes[j]
+		if sa, sb := a.SoftSize+a.WeakSize, b.SoftSize+b.WeakSize; sa != sb {
+			return sa > sb
+		}
+		return a.ClassName < b.ClassName
+	})
+	return report
+}
+
+// reachabilityStrengths returns the reachability strength of every object
+// reachable from the GC roots without crossing phantom or final referents.
+// Strong references are followed first, then soft referents from the
+// strongly reachable objects, then weak referents, so every object gets the
+// strength of its strongest path.
+func (g *ReferenceGraph) reachabilityStrengths() map[uint64]uint8 {
+	strength := make(map[uint64]uint8, len(g.objectClass))
+	kinds := make(map[uint64]PathExclusion)
+
+	var queue, deferredSoft, deferredWeak []uint64
+	visit := func(objID uint64, s uint8) {
+		if _, ok := g.objectClass[objID]; !ok {
+			return
+		}
+		if _, ok := strength[objID]; !ok {
+			strength[objID] = s
+			queue = append(queue, objID)
+		}
+	}
+
+	for objID := range g.dominatorRoots() {
+		visit(objID, stronglyReachable)
+	}
+	for _, s := range []uint8{stronglyReachable, softlyReachable, weaklyReachable} {
+		switch s {
+		case softlyReachable:
+			for _, objID := range deferredSoft {
+				visit(objID, s)
+			}
+		case weaklyReachable:
+			for _, objID := range deferredWeak {
+				visit(objID, s)
+			}
+		}
+
+		for len(queue) > 0 {
+			objID := queue[len(queue)-1]
+			queue = queue[:len(queue)-1]
+			for _, ref := range g.outgoingRefs[objID] {
+				var kind PathExclusion
+				if ref.FieldName == "referent" {
+					kind = g.referenceKind(ref.FromClassID, kinds)
+				}
+				switch {
+				case kind&ExcludePhantomRefs != 0:
+				case kind&ExcludeSoftRefs != 0 && s > softlyReachable:
+					deferredSoft = append(deferredSoft, ref.ToObjectID)
+				case kind&ExcludeWeakRefs != 0 && s > weaklyReachable:
+					deferredWeak = append(deferredWeak, ref.ToObjectID)
+				default:
+					visit(ref.ToObjectID, s)
+				}
+			}
+		}
+	}
+	return strength
+}
@@ -0,0 +1,51 @@
+package hprof
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWeakReachability(t *testing.T) {
+	g := NewReferenceGraphWithCapacity(16)
+	g.SetClassName(1, "com.example.App")
+	g.SetClassName(2, "java.lang.ref.SoftReference")
+	g.SetClassName(3, "java.lang.ref.WeakReference")
+	g.SetClassName(4, "byte[]")
+	g.SetClassName(5, "com.example.Entry")
+	g.SetObjectInfo(100, 1, 16)
+	g.SetObjectInfo(200, 2, 32)
+	g.SetObjectInfo(300, 3, 32)
+	g.SetObjectInfo(400, 4, 4096)
+	g.SetObjectInfo(500, 5, 24)
+	g.SetObjectInfo(600, 4, 1024)
+	g.SetObjectInfo(700, 4, 512)
+
+	// 100 -> soft 200 -> 400 -> 500 -> weak 300 -> 600, 100 -> 700 -> 500
+	ref := func(from, to, classID uint64, field string) {
+		g.AddReference(ObjectReference{FromObjectID: from, ToObjectID: to, FromClassID: classID, FieldName: field})
+	}
+	ref(100, 200, 1, "cache")
+	ref(200, 400, 2, "referent")
+	ref(400, 500, 4, "[0]")
+	ref(500, 300, 5, "ref")
+	ref(300, 600, 3, "referent")
+	ref(100, 700, 1, "buf")
+	ref(700, 500, 4, "[0]")
+	g.AddGCRoot(&GCRoot{ObjectID: 100, Type: GCRootJNIGlobal})
+
+	report := g.WeakReachability()
+	assert.Equal(t, int64(1), report.SoftObjects)
+	assert.Equal(t, int64(4096), report.SoftSize)
+	assert.Equal(t, int64(1), report.WeakObjects)
+	assert.Equal(t, int64(1024), report.WeakSize)
+
+	// 500 is strongly reachable through 700, so the soft path does not count
+	require.Len(t, report.Classes, 1)
+	assert.Equal(t, &WeakReachableClass{ClassName: "byte[]", SoftCount: 1, SoftSize: 4096, WeakCount: 1, WeakSize: 1024}, report.Classes[0])
+
+	result := &HeapAnalysisResult{RefGraph: g}
+	assert.Same(t, result.WeakReachability, ComputeWeakReachability(result))
+	assert.Nil(t, ComputeWeakReachability(&HeapAnalysisResult{}))
+}
@@ -0,0 +1,25 @@
+package hprof
+
+import (
+	"errors"
+)
+
+// Errors of malformed heap dumps. Parse errors wrap one of them with the
+// position of the problem, so callers can tell corrupt dumps apart with
+// errors.Is.
+var (
+	// ErrTruncated reports a dump ending in the middle of a record.
+	ErrTruncated = errors.New("heap dump truncated")
+
+	// ErrBadTag reports a heap dump sub-record of an unknown tag. Its size
+	// is unknown, so the rest of its record cannot be parsed.
+	ErrBadTag = errors.New("unknown heap dump tag")
+
+	// ErrInconsistentLength reports a length or count that contradicts the
+	// record holding it.
+	ErrInconsistentLength = errors.New("inconsistent record length")
+
+	// ErrBadHeader reports a file that does not start with a valid HPROF
+	// header.
+	ErrBadHeader = errors.New("invalid heap dump header")
+)
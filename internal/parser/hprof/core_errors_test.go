@@ -0,0 +1,126 @@
+package hprof
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// parseTestHprof parses a dump with the default options.
+func parseTestHprof(data []byte) (*HeapAnalysisResult, error) {
+	return NewParser(nil).Parse(context.Background(), bytes.NewReader(data))
+}
+
+func TestParse_Truncated(t *testing.T) {
+	data := newFuzzSeedHprof()
+	for _, n := range []int{10, 30, len(data) / 2, len(data) - 1} {
+		_, err := parseTestHprof(data[:n])
+		assert.ErrorIs(t, err, ErrTruncated, "truncated at %d", n)
+	}
+
+	result, err := parseTestHprof(data)
+	require.NoError(t, err)
+	assert.Equal(t, int64(5), result.TotalInstances)
+}
+
+func TestParse_BadHeader(t *testing.T) {
+	var buf bytes.Buffer
+	buf.WriteString("JAVA PROFILE 1.0.2")
+	buf.WriteByte(0)
+	binary.Write(&buf, binary.BigEndian, uint32(5))
+	binary.Write(&buf, binary.BigEndian, uint64(0))
+	_, err := parseTestHprof(buf.Bytes())
+	assert.ErrorIs(t, err, ErrBadHeader)
+
+	_, err = parseTestHprof(bytes.Repeat([]byte("x"), 1000))
+	assert.ErrorIs(t, err, ErrBadHeader)
+}
+
+func TestParse_BadTagSkipsRecord(t *testing.T) {
+	b := newTestHprofBuilder()
+	b.loadClass(0x10, "com/example/Foo")
+	b.classDump(0x10, 0, 0)
+	b.instanceDump(0x1000, 0x10, nil)
+	b.heap.Write([]byte{0x42, 1, 2, 3})
+	b.instanceDump(0x1001, 0x10, nil)
+	b.bytes()
+	b.instanceDump(0x1002, 0x10, nil)
+
+	result, err := parseTestHprof(b.bytes())
+	require.NoError(t, err)
+	// The instance after the unknown tag is skipped with the rest of its
+	// segment, the next segment is parsed
+	assert.Equal(t, int64(2), result.TotalInstances)
+	_, ok := result.RefGraph.GetObjectClassID(0x1001)
+	assert.False(t, ok)
+	_, ok = result.RefGraph.GetObjectClassID(0x1002)
+	assert.True(t, ok)
+}
+
+func TestParse_InconsistentLength(t *testing.T) {
+	t.Run("stack trace", func(t *testing.T) {
+		b := newTestHprofBuilder()
+		var body bytes.Buffer
+		binary.Write(&body, binary.BigEndian, []uint32{1, 1, 1 << 30})
+		binary.Write(&body, binary.BigEndian, uint64(0xF1))
+		b.record(TagStackTrace, body.Bytes())
+		_, err := parseTestHprof(b.bytes())
+		assert.ErrorIs(t, err, ErrInconsistentLength)
+	})
+
+	t.Run("sub-record overrun", func(t *testing.T) {
+		b := newTestHprofBuilder()
+		b.classDump(0x10, 0, 0)
+		b.instanceDump(0x1000, 0x10, make([]byte, 16))
+		heap := b.heap.Bytes()
+		// The segment ends in the middle of the instance, followed by a record
+		b.buf.WriteByte(byte(TagHeapDumpSegment))
+		binary.Write(&b.buf, binary.BigEndian, uint32(0))
+		binary.Write(&b.buf, binary.BigEndian, uint32(len(heap)-8))
+		b.buf.Write(heap)
+		b.heap.Reset()
+		b.str("tail")
+		_, err := parseTestHprof(b.bytes())
+		assert.ErrorIs(t, err, ErrInconsistentLength)
+	})
+
+	t.Run("string", func(t *testing.T) {
+		b := newTestHprofBuilder()
+		b.record(TagString, []byte{0, 0, 0, 1})
+		_, err := parseTestHprof(b.bytes())
+		assert.ErrorIs(t, err, ErrInconsistentLength)
+	})
+}
+
+func TestParse_CyclicSuperclasses(t *testing.T) {
+	b := newTestHprofBuilder()
+	b.loadClass(0x10, "com/example/A")
+	b.loadClass(0x20, "com/example/B")
+	b.instanceDump(0x1000, 0x10, refBytes(0x1001))
+	b.classDump(0x10, 0x20, 8, testField{name: "a", typ: TypeObject})
+	b.classDump(0x20, 0x10, 8)
+	b.instanceDump(0x1001, 0x10, refBytes(0x1000))
+
+	result, err := parseTestHprof(b.bytes())
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), result.TotalInstances)
+}
+
+func TestReader_ReadBytesTruncated(t *testing.T) {
+	r := NewReader(bytes.NewReader(make([]byte, 10)))
+	_, err := r.ReadBytes(1 << 30)
+	assert.ErrorIs(t, err, ErrTruncated)
+
+	_, err = r.ReadBytes(-1)
+	assert.ErrorIs(t, err, ErrInconsistentLength)
+	assert.ErrorIs(t, r.Skip(-1), ErrInconsistentLength)
+
+	r = NewReader(bytes.NewReader(make([]byte, 3*maxEagerRead+5)))
+	data, err := r.ReadBytes(3*maxEagerRead + 5)
+	require.NoError(t, err)
+	assert.Len(t, data, 3*maxEagerRead+5)
+}
@@ -0,0 +1,237 @@
+package hprof
+
+import (
+	"io"
+)
+
+// Memory model of a parse, to estimate the memory a heap dump needs before
+// parsing it: the peak heap of parses of dumps with about two references
+// per object was 1.5-1.9KB per object, for the object maps, the reference
+// graph and the dominator tree.
+const (
+	parseBytesPerObject = 2048
+	parseBaseBytes      = 64 << 20
+)
+
+// HeapDumpSample holds the object counts of the beginning of a heap dump,
+// read by SampleHeapDump to estimate the size of the whole dump.
+type HeapDumpSample struct {
+	// Bytes is the number of bytes of the dump sampled.
+	Bytes int64
+
+	// HeapBytes is the number of bytes of heap dump records sampled.
+	HeapBytes int64
+
+	// Objects is the number of objects (instances, arrays and classes) in
+	// the sampled heap dump records.
+	Objects int64
+
+	// Complete reports whether the whole dump was sampled.
+	Complete bool
+}
+
+// SampleHeapDump counts the objects in the first maxBytes of a heap dump,
+// skipping over their contents.
+func SampleHeapDump(r io.Reader, maxBytes int64) (*HeapDumpSample, error) {
+	reader := NewReader(r)
+	if _, err := reader.ReadHeader(); err != nil {
+		return nil, err
+	}
+
+	sample := &HeapDumpSample{}
+	for reader.Offset() < maxBytes {
+		tag, _, length, err := reader.ReadRecordHeader()
+		if err == io.EOF {
+			sample.Complete = true
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if tag != TagHeapDump && tag != TagHeapDumpSegment {
+			if err := reader.Skip(int64(length)); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		start := reader.Offset()
+		end := start + int64(length)
+		err = sampleHeapDumpRecord(reader, end, maxBytes, sample)
+		sample.HeapBytes += reader.Offset() - start
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	sample.Bytes = reader.Offset()
+	return sample, nil
+}
+
+// sampleHeapDumpRecord counts the objects of a heap dump record ending at
+// end, stopping early at maxBytes. Sub-records of unknown tags end the
+// record, as their size is unknown.
+func sampleHeapDumpRecord(reader *Reader, end, maxBytes int64, sample *HeapDumpSample) error {
+	idSize := int64(reader.IDSize())
+
+	for reader.Offset() < end && reader.Offset() < maxBytes {
+		tagByte, err := reader.ReadByte()
+		if err != nil {
+			return err
+		}
+
+		tag := HeapDumpTag(tagByte)
+		size, ok := rootSubRecordSize(tag, idSize)
+		switch {
+		case tag == 0x00:
+			continue
+		case ok:
+			// A GC root, skipped below
+		case tag == HeapTagClassDump:
+			sample.Objects++
+			if err := skipClassDump(reader); err != nil {
+				return err
+			}
+			continue
+		case tag == HeapTagInstanceDump:
+			sample.Objects++
+			if err := reader.Skip(2*idSize + 4); err != nil {
+				return err
+			}
+			if size, err = readUint32Size(reader); err != nil {
+				return err
+			}
+		case tag == HeapTagObjectArrayDump:
+			sample.Objects++
+			if err := reader.Skip(idSize + 4); err != nil {
+				return err
+			}
+			length, err := readUint32Size(reader)
+			if err != nil {
+				return err
+			}
+			size = idSize + length*idSize
+		case tag == HeapTagPrimitiveArrayDump:
+			sample.Objects++
+			if err := reader.Skip(idSize + 4); err != nil {
+				return err
+			}
+			length, err := readUint32Size(reader)
+			if err != nil {
+				return err
+			}
+			elemType, err := reader.ReadByte()
+			if err != nil {
+				return err
+			}
+			size = length * int64(BasicTypeSize(BasicType(elemType), int(idSize)))
+		default:
+			return reader.Skip(end - reader.Offset())
+		}
+
+		if err := reader.Skip(size); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// rootSubRecordSize returns the size after the tag of the GC root
+// sub-records, and false for other sub-records.
+func rootSubRecordSize(tag HeapDumpTag, idSize int64) (int64, bool) {
+	switch tag {
+	case HeapTagRootUnknown, HeapTagRootStickyClass, HeapTagRootMonitorUsed,
+		0x89, 0x8A, 0x8B, 0x8C, 0x8D, 0xFE:
+		return idSize, true
+	case HeapTagRootJNIGlobal:
+		return 2 * idSize, true
+	case HeapTagRootNativeStack, HeapTagRootThreadBlock:
+		return idSize + 4, true
+	case HeapTagRootJNILocal, HeapTagRootJavaFrame, HeapTagRootThreadObject, 0x8E:
+		return idSize + 8, true
+	case 0xC3:
+		return 4 + idSize, true
+	default:
+		return 0, false
+	}
+}
+
+// skipClassDump skips a CLASS_DUMP sub-record after its tag.
+func skipClassDump(reader *Reader) error {
+	idSize := int64(reader.IDSize())
+
+	// Class, stack trace serial, super class, loader, signers, protection
+	// domain, 2 reserved IDs and instance size
+	if err := reader.Skip(7*idSize + 4 + 4); err != nil {
+		return err
+	}
+
+	// Constant pool: index and typed value
+	count, err := reader.ReadUint16()
+	if err != nil {
+		return err
+	}
+	for i := 0; i < int(count); i++ {
+		if err := reader.Skip(2); err != nil {
+			return err
+		}
+		if err := skipTypedValue(reader); err != nil {
+			return err
+		}
+	}
+
+	// Static fields: name ID and typed value
+	if count, err = reader.ReadUint16(); err != nil {
+		return err
+	}
+	for i := 0; i < int(count); i++ {
+		if err := reader.Skip(idSize); err != nil {
+			return err
+		}
+		if err := skipTypedValue(reader); err != nil {
+			return err
+		}
+	}
+
+	// Instance fields: name ID and type
+	if count, err = reader.ReadUint16(); err != nil {
+		return err
+	}
+	return reader.Skip(int64(count) * (idSize + 1))
+}
+
+// skipTypedValue skips a basic type tag and its value.
+func skipTypedValue(reader *Reader) error {
+	t, err := reader.ReadByte()
+	if err != nil {
+		return err
+	}
+	return reader.Skip(int64(BasicTypeSize(BasicType(t), reader.IDSize())))
+}
+
+// readUint32Size reads an unsigned 32-bit count or length.
+func readUint32Size(reader *Reader) (int64, error) {
+	v, err := reader.ReadUint32()
+	return int64(v), err
+}
+
+// EstimateObjects extrapolates the number of objects of a dump of fileSize
+// bytes from the object density of the sample.
+func (s *HeapDumpSample) EstimateObjects(fileSize int64) int64 {
+	if s.Complete || s.Bytes <= 0 || fileSize <= s.Bytes {
+		return s.Objects
+	}
+	if s.HeapBytes == 0 {
+		// Only metadata was sampled: assume 64 bytes per object record
+		return fileSize / 64
+	}
+	density := float64(s.Objects) / float64(s.HeapBytes)
+	return s.Objects + int64(density*float64(fileSize-s.Bytes))
+}
+
+// EstimateParseMemory returns the memory needed to parse a heap dump with
+// the given number of objects.
+func EstimateParseMemory(objects int64) int64 {
+	return parseBaseBytes + objects*parseBytesPerObject
+}
@@ -0,0 +1,71 @@
+package hprof
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newEstimateTestHprof returns a heap dump of n instances and arrays, in
+// segments of 100 objects.
+func newEstimateTestHprof(n int) []byte {
+	b := newTestHprofBuilder()
+	b.loadClass(0x10, "com/example/Node")
+	b.loadClass(0x20, "[Ljava/lang/Object;")
+	b.classDump(0x10, 0, 8, testField{"next", TypeObject})
+	b.rootStickyClass(0x10)
+	b.rootJNIGlobal(0x1000)
+
+	for i := 0; i < n; i++ {
+		id := uint64(0x1000 + i)
+		switch i % 3 {
+		case 0:
+			b.instanceDump(id, 0x10, refBytes(id+1))
+		case 1:
+			b.objectArrayDump(id, 0x20, id-1, id+1)
+		default:
+			b.primitiveArrayDump(id, TypeInt, 4, nil)
+		}
+		if i%100 == 99 {
+			b.bytes()
+		}
+	}
+	return b.bytes()
+}
+
+func TestSampleHeapDump_Complete(t *testing.T) {
+	data := newEstimateTestHprof(300)
+
+	sample, err := SampleHeapDump(bytes.NewReader(data), int64(len(data))+1)
+	require.NoError(t, err)
+
+	assert.True(t, sample.Complete)
+	assert.Equal(t, int64(301), sample.Objects, "instances, arrays and the class")
+	assert.Equal(t, int64(len(data)), sample.Bytes)
+	assert.Equal(t, int64(301), sample.EstimateObjects(int64(len(data))))
+}
+
+func TestSampleHeapDump_Extrapolates(t *testing.T) {
+	data := newEstimateTestHprof(3000)
+
+	sample, err := SampleHeapDump(bytes.NewReader(data), int64(len(data))/10)
+	require.NoError(t, err)
+
+	assert.False(t, sample.Complete)
+	assert.Less(t, sample.Objects, int64(1000))
+	assert.InDelta(t, 3001, sample.EstimateObjects(int64(len(data))), 150)
+}
+
+func TestSampleHeapDump_Truncated(t *testing.T) {
+	data := newEstimateTestHprof(300)
+
+	_, err := SampleHeapDump(bytes.NewReader(data[:len(data)-3]), int64(len(data)))
+	assert.ErrorContains(t, err, "truncated")
+}
+
+func TestEstimateParseMemory(t *testing.T) {
+	assert.Equal(t, int64(parseBaseBytes), EstimateParseMemory(0))
+	assert.Greater(t, EstimateParseMemory(2000000), EstimateParseMemory(1000000))
+}
@@ -0,0 +1,187 @@
+package hprof
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// JVMLayout describes how a JVM lays out objects in memory. Heap dumps only
+// record field values, so shallow sizes are computed from the layout of the
+// JVM that wrote the dump.
+type JVMLayout struct {
+	// Name identifies the layout in logs and results
+	Name string `json:"name"`
+	// ObjectHeaderSize is the size of the mark word and class pointer
+	ObjectHeaderSize int64 `json:"object_header_size"`
+	// ArrayHeaderSize is the size of the object header and length of arrays
+	ArrayHeaderSize int64 `json:"array_header_size"`
+	// ReferenceSize is the size of reference fields and array elements
+	ReferenceSize int64 `json:"reference_size"`
+	// Alignment is the object alignment (-XX:ObjectAlignmentInBytes)
+	Alignment int64 `json:"alignment"`
+}
+
+// Layouts of HotSpot JVMs.
+var (
+	// LayoutCompressedOops is a 64-bit JVM with compressed oops and class
+	// pointers, the default for heaps below 32GB. This matches IDEA.
+	LayoutCompressedOops = JVMLayout{Name: "compressed-oops", ObjectHeaderSize: 12, ArrayHeaderSize: 16, ReferenceSize: 4, Alignment: 8}
+	// LayoutUncompressedOops is a 64-bit JVM without compressed oops, the
+	// default for heaps of 32GB and more. This matches MAT.
+	LayoutUncompressedOops = JVMLayout{Name: "uncompressed-oops", ObjectHeaderSize: 16, ArrayHeaderSize: 20, ReferenceSize: 8, Alignment: 8}
+	// LayoutCompactHeaders is a 64-bit JVM with compact object headers
+	// (-XX:+UseCompactObjectHeaders, JDK 24+).
+	LayoutCompactHeaders = JVMLayout{Name: "compact-headers", ObjectHeaderSize: 8, ArrayHeaderSize: 12, ReferenceSize: 4, Alignment: 8}
+	// Layout32Bit is a 32-bit JVM.
+	Layout32Bit = JVMLayout{Name: "32-bit", ObjectHeaderSize: 8, ArrayHeaderSize: 12, ReferenceSize: 4, Alignment: 8}
+)
+
+// jvmLayouts are the layouts selectable by name.
+var jvmLayouts = []JVMLayout{LayoutCompressedOops, LayoutUncompressedOops, LayoutCompactHeaders, Layout32Bit}
+
+// ParseJVMLayout parses a layout name, optionally followed by overrides of
+// its sizes, e.g. "compressed-oops,align=16". The overrides are header,
+// array-header, ref and align.
+func ParseJVMLayout(spec string) (JVMLayout, error) {
+	parts := strings.Split(spec, ",")
+	var layout JVMLayout
+	found := false
+	for _, l := range jvmLayouts {
+		if l.Name == strings.TrimSpace(parts[0]) {
+			layout, found = l, true
+			break
+		}
+	}
+	if !found {
+		names := make([]string, len(jvmLayouts))
+		for i, l := range jvmLayouts {
+			names[i] = l.Name
+		}
+		return JVMLayout{}, fmt.Errorf("unknown JVM layout %q (expected one of %s)", parts[0], strings.Join(names, ", "))
+	}
+
+	for _, part := range parts[1:] {
+		key, value, ok := strings.Cut(strings.TrimSpace(part), "=")
+		size, err := strconv.ParseInt(value, 10, 64)
+		if !ok || err != nil {
+			return JVMLayout{}, fmt.Errorf("invalid JVM layout override %q", part)
+		}
+		switch key {
+		case "header":
+			layout.ObjectHeaderSize = size
+		case "array-header":
+			layout.ArrayHeaderSize = size
+		case "ref":
+			layout.ReferenceSize = size
+		case "align":
+			layout.Alignment = size
+		default:
+			return JVMLayout{}, fmt.Errorf("unknown JVM layout override %q", key)
+		}
+		layout.Name += "," + strings.TrimSpace(part)
+	}
+	return layout, layout.Validate()
+}
+
+// Validate checks that the sizes of the layout are consistent.
+func (l JVMLayout) Validate() error {
+	if l.Alignment < 8 || l.Alignment&(l.Alignment-1) != 0 {
+		return fmt.Errorf("JVM layout %s: alignment must be a power of two of at least 8, got %d", l.Name, l.Alignment)
+	}
+	if l.ReferenceSize != 4 && l.ReferenceSize != 8 {
+		return fmt.Errorf("JVM layout %s: reference size must be 4 or 8, got %d", l.Name, l.ReferenceSize)
+	}
+	if l.ObjectHeaderSize <= 0 || l.ArrayHeaderSize < l.ObjectHeaderSize {
+		return fmt.Errorf("JVM layout %s: invalid header sizes %d and %d", l.Name, l.ObjectHeaderSize, l.ArrayHeaderSize)
+	}
+	return nil
+}
+
+// align rounds a size up to the object alignment.
+func (l *JVMLayout) align(size int64) int64 {
+	return (size + l.Alignment - 1) &^ (l.Alignment - 1)
+}
+
+// instanceSize returns the shallow size of an instance given the size of
+// its field data in the heap dump, where references take idSize bytes, and
+// its number of reference fields.
+func (l *JVMLayout) instanceSize(dataSize int64, refFields int, idSize int) int64 {
+	fieldBytes := dataSize - int64(refFields)*(int64(idSize)-l.ReferenceSize)
+	return l.align(l.ObjectHeaderSize + fieldBytes)
+}
+
+// arraySize returns the shallow size of an array of length elements.
+func (l *JVMLayout) arraySize(length int64, elemSize int64) int64 {
+	return l.align(l.ArrayHeaderSize + length*elemSize)
+}
+
+// classObjectSize returns the shallow size of a java.lang.Class object.
+// Class objects have a complex internal structure that varies by JVM version.
+// MAT calculates this as: object header + internal fields
+// For HotSpot JVM, Class objects contain:
+// - Object header
+// - Various internal fields (classLoader, module, name, etc.)
+// - Static field values storage
+func (l *JVMLayout) classObjectSize(staticFieldsCount int) int64 {
+	// Core fields in java.lang.Class (approximate):
+	// - classLoader, module, name, packageName, etc. (reference fields)
+	// - Various int/boolean fields for flags
+	// Estimate: ~15 reference fields + ~20 bytes of primitives
+	coreFields := int64(15)*l.ReferenceSize + 20
+
+	// Static fields are stored in the Class object
+	staticStorage := int64(staticFieldsCount) * l.ReferenceSize
+
+	return l.align(l.ObjectHeaderSize + coreFields + staticStorage)
+}
+
+// layoutHints collects the evidence of the layout of the JVM that wrote a
+// heap dump. Object IDs of HotSpot dumps are object addresses, so their
+// alignment and spread hint at the object alignment and whether the heap
+// is small enough for compressed oops.
+type layoutHints struct {
+	count    int
+	lowBits  uint64
+	min, max uint64
+}
+
+// minAlignmentHints is the number of addresses needed to conclude from all
+// of them being 16-byte aligned that objects are: with 8-byte alignment the
+// odds of it are 2^-64.
+const minAlignmentHints = 64
+
+// add records the address of an object.
+func (h *layoutHints) add(id uint64) {
+	if h.count == 0 || id < h.min {
+		h.min = id
+	}
+	if id > h.max {
+		h.max = id
+	}
+	h.lowBits |= id
+	h.count++
+}
+
+// detectJVMLayout returns the most likely layout of the JVM that wrote a heap
+// dump with the ID size and hints. Compact headers cannot be told apart from
+// compressed oops and have to be configured.
+func detectJVMLayout(idSize int, hints *layoutHints) JVMLayout {
+	if idSize == 4 {
+		return Layout32Bit
+	}
+	alignment := int64(8)
+	if hints.count >= minAlignmentHints && hints.lowBits&15 == 0 {
+		alignment = 16
+	}
+	layout := LayoutCompressedOops
+	// Compressed oops address 4G object slots, 32GB with 8-byte alignment
+	if hints.count > 0 && hints.max-hints.min >= uint64(alignment)<<32 {
+		layout = LayoutUncompressedOops
+	}
+	if alignment != layout.Alignment {
+		layout.Alignment = alignment
+		layout.Name += ",align=" + strconv.FormatInt(alignment, 10)
+	}
+	return layout
+}
@@ -0,0 +1,102 @@
+package hprof
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseJVMLayout(t *testing.T) {
+	layout, err := ParseJVMLayout("uncompressed-oops")
+	require.NoError(t, err)
+	assert.Equal(t, LayoutUncompressedOops, layout)
+
+	layout, err = ParseJVMLayout("compressed-oops,align=16")
+	require.NoError(t, err)
+	assert.Equal(t, int64(16), layout.Alignment)
+	assert.Equal(t, int64(12), layout.ObjectHeaderSize)
+	assert.Equal(t, "compressed-oops,align=16", layout.Name)
+
+	for _, spec := range []string{"lilliput", "compressed-oops,align=12", "compressed-oops,ref=2", "compressed-oops,align", "compressed-oops,size=8"} {
+		_, err := ParseJVMLayout(spec)
+		assert.Error(t, err, spec)
+	}
+}
+
+func TestJVMLayout_Sizes(t *testing.T) {
+	// An instance with one reference and an int, as dumped with 8-byte IDs
+	assert.Equal(t, int64(24), LayoutCompressedOops.instanceSize(12, 1, 8))
+	assert.Equal(t, int64(32), LayoutUncompressedOops.instanceSize(12, 1, 8))
+	assert.Equal(t, int64(16), LayoutCompactHeaders.instanceSize(12, 1, 8))
+
+	aligned := LayoutCompressedOops
+	aligned.Alignment = 16
+	assert.Equal(t, int64(32), aligned.instanceSize(12, 1, 8))
+	assert.Equal(t, int64(16), aligned.arraySize(0, 1))
+	assert.Equal(t, int64(32), aligned.arraySize(3, 4))
+	assert.Equal(t, int64(24), LayoutUncompressedOops.arraySize(0, 8))
+}
+
+func TestDetectJVMLayout(t *testing.T) {
+	assert.Equal(t, Layout32Bit, detectJVMLayout(4, &layoutHints{}))
+	assert.Equal(t, LayoutCompressedOops, detectJVMLayout(8, &layoutHints{}))
+
+	// Too few 16-byte aligned addresses to tell
+	hints := &layoutHints{}
+	for i := uint64(0); i < 10; i++ {
+		hints.add(0x7000000000 + i*32)
+	}
+	assert.Equal(t, LayoutCompressedOops, detectJVMLayout(8, hints))
+
+	for i := uint64(10); i < minAlignmentHints; i++ {
+		hints.add(0x7000000000 + i*32)
+	}
+	layout := detectJVMLayout(8, hints)
+	assert.Equal(t, int64(16), layout.Alignment)
+	assert.Equal(t, int64(4), layout.ReferenceSize)
+	assert.Equal(t, "compressed-oops,align=16", layout.Name)
+
+	// Addresses 40GB apart cannot be compressed with 8-byte alignment
+	hints = &layoutHints{}
+	hints.add(0x1000008)
+	hints.add(0x1000008 + 40<<30)
+	assert.Equal(t, LayoutUncompressedOops, detectJVMLayout(8, hints))
+}
+
+func TestParser_Layout(t *testing.T) {
+	b := newTestHprofBuilder()
+	b.loadClass(0x10, "com/example/Node")
+	b.classDump(0x10, 0, 12, testField{"next", TypeObject}, testField{"value", TypeInt})
+	b.instanceDump(0x1000, 0x10, append(refBytes(0), 0, 0, 0, 1))
+	b.objectArrayDump(0x2000, 0x10, 0x1000, 0x1000, 0x1000)
+	b.rootJNIGlobal(0x1000)
+	b.rootJNIGlobal(0x2000)
+	data := b.bytes()
+
+	parse := func(opts *ParserOptions) *HeapAnalysisResult {
+		result, err := NewParser(opts).Parse(context.Background(), bytes.NewReader(data))
+		require.NoError(t, err)
+		return result
+	}
+
+	result := parse(DefaultParserOptions())
+	require.NotNil(t, result.JVMLayout)
+	assert.Equal(t, LayoutCompressedOops.Name, result.JVMLayout.Name)
+	assert.Equal(t, int64(24), result.RefGraph.objectSize[0x1000])
+	assert.Equal(t, int64(32), result.RefGraph.objectSize[0x2000])
+
+	opts := DefaultParserOptions()
+	opts.Layout = &LayoutUncompressedOops
+	result = parse(opts)
+	assert.Equal(t, int64(32), result.RefGraph.objectSize[0x1000])
+	assert.Equal(t, int64(48), result.RefGraph.objectSize[0x2000])
+
+	opts = DefaultParserOptions()
+	opts.SizeMode = SizeModeAuto
+	result = parse(opts)
+	assert.Equal(t, LayoutCompressedOops, *result.JVMLayout)
+	assert.Equal(t, LayoutCompressedOops.classObjectSize(0), result.RefGraph.objectSize[0x10])
+}
@@ -0,0 +1,210 @@
+package hprof
+
+import (
+	"fmt"
+	"runtime"
+	"sort"
+	"strings"
+	"unsafe"
+)
+
+// MemoryUsage is the estimated memory held by an internal structure of the
+// analyzer.
+type MemoryUsage struct {
+	Name    string
+	Entries int
+	Bytes   int64
+}
+
+// MemoryReport is the estimated memory of the internal structures of an
+// analysis, biggest first, to see where analysis RAM goes.
+type MemoryReport struct {
+	Structures []MemoryUsage
+	// Total is the sum of the estimates of the structures
+	Total int64
+	// HeapInUse is the heap in use by the Go runtime when the report was made
+	HeapInUse uint64
+}
+
+// Sizes of the building blocks of the estimates.
+const (
+	sliceHeaderBytes  = int64(unsafe.Sizeof([]byte(nil)))
+	stringHeaderBytes = int64(unsafe.Sizeof(""))
+	pointerBytes      = int64(unsafe.Sizeof(uintptr(0)))
+)
+
+// mapBytes estimates the memory of a map of n entries of keyBytes and
+// valueBytes: a control byte per slot and slots at most 7/8 full.
+func mapBytes(n int, keyBytes, valueBytes int64) int64 {
+	return int64(n) * (keyBytes + valueBytes + 1) * 8 / 7
+}
+
+// newMemoryReport sorts structures biggest first and totals them.
+func newMemoryReport(structures []MemoryUsage) *MemoryReport {
+	sort.SliceStable(structures, func(i, j int) bool { return structures[i].Bytes > structures[j].Bytes })
+	report := &MemoryReport{Structures: structures}
+	for _, s := range structures {
+		report.Total += s.Bytes
+	}
+	var stats runtime.MemStats
+	runtime.ReadMemStats(&stats)
+	report.HeapInUse = stats.HeapInuse
+	return report
+}
+
+// String formats the report as a table.
+func (r *MemoryReport) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%-32s %12s %12s\n", "Structure", "Entries", "Bytes")
+	for _, s := range r.Structures {
+		if s.Entries == 0 && s.Bytes == 0 {
+			continue
+		}
+		fmt.Fprintf(&b, "%-32s %12d %12s\n", s.Name, s.Entries, formatMemoryBytes(s.Bytes))
+	}
+	fmt.Fprintf(&b, "%-32s %12s %12s\n", "Total (estimated)", "", formatMemoryBytes(r.Total))
+	fmt.Fprintf(&b, "%-32s %12s %12s", "Go heap in use", "", formatMemoryBytes(int64(r.HeapInUse)))
+	return b.String()
+}
+
+// formatMemoryBytes formats a byte count in binary units.
+func formatMemoryBytes(n int64) string {
+	switch {
+	case n >= 1<<30:
+		return fmt.Sprintf("%.2f GiB", float64(n)/(1<<30))
+	case n >= 1<<20:
+		return fmt.Sprintf("%.2f MiB", float64(n)/(1<<20))
+	case n >= 1<<10:
+		return fmt.Sprintf("%.2f KiB", float64(n)/(1<<10))
+	}
+	return fmt.Sprintf("%d B", n)
+}
+
+// refListsUsage estimates a map of reference lists.
+func refListsUsage(name string, lists map[uint64][]ObjectReference) MemoryUsage {
+	usage := MemoryUsage{Name: name, Entries: len(lists), Bytes: mapBytes(len(lists), 8, sliceHeaderBytes)}
+	refBytes := int64(unsafe.Sizeof(ObjectReference{}))
+	for _, refs := range lists {
+		usage.Bytes += int64(cap(refs)) * refBytes
+	}
+	return usage
+}
+
+// indexListsUsage estimates a slice of per-object lists.
+func indexListsUsage[T any](name string, lists [][]T) MemoryUsage {
+	var elem T
+	usage := MemoryUsage{Name: name, Entries: len(lists), Bytes: int64(cap(lists)) * sliceHeaderBytes}
+	for _, list := range lists {
+		usage.Bytes += int64(cap(list)) * int64(unsafe.Sizeof(elem))
+	}
+	return usage
+}
+
+// sliceUsage estimates a flat slice.
+func sliceUsage[T any](name string, s []T) MemoryUsage {
+	var elem T
+	return MemoryUsage{Name: name, Entries: len(s), Bytes: int64(cap(s)) * int64(unsafe.Sizeof(elem))}
+}
+
+// stringMapUsage estimates a map of strings, their bytes included.
+func stringMapUsage[K comparable](name string, m map[K]string) MemoryUsage {
+	var key K
+	usage := MemoryUsage{Name: name, Entries: len(m), Bytes: mapBytes(len(m), int64(unsafe.Sizeof(key)), stringHeaderBytes)}
+	for _, s := range m {
+		usage.Bytes += int64(len(s))
+	}
+	return usage
+}
+
+// MemoryUsage estimates the memory of the maps and index arrays of the
+// graph. Structures not built yet are reported empty.
+func (g *ReferenceGraph) MemoryUsage() []MemoryUsage {
+	usage := []MemoryUsage{
+		refListsUsage("graph.outgoingRefs", g.outgoingRefs),
+		refListsUsage("graph.incomingRefs", g.incomingRefs),
+		{Name: "graph.objectClass", Entries: len(g.objectClass), Bytes: mapBytes(len(g.objectClass), 8, 8)},
+		{Name: "graph.objectSize", Entries: len(g.objectSize), Bytes: mapBytes(len(g.objectSize), 8, 8)},
+		stringMapUsage("graph.classNames", g.classNames),
+		{Name: "graph.gcRoots", Entries: len(g.gcRoots),
+			Bytes: int64(cap(g.gcRoots))*pointerBytes + int64(len(g.gcRoots))*int64(unsafe.Sizeof(GCRoot{})) +
+				mapBytes(len(g.gcRootSet), 8, stringHeaderBytes)},
+		{Name: "graph.classObjectIDs", Entries: len(g.classObjectIDs), Bytes: mapBytes(len(g.classObjectIDs), 8, 1)},
+		{Name: "graph.dominators", Entries: len(g.dominators), Bytes: mapBytes(len(g.dominators), 8, 8)},
+		{Name: "graph.retainedSizes", Entries: len(g.retainedSizes), Bytes: mapBytes(len(g.retainedSizes), 8, 8)},
+		{Name: "graph.computedRetainedSizes", Entries: len(g.computedRetainedSizes), Bytes: mapBytes(len(g.computedRetainedSizes), 8, 8)},
+		{Name: "graph.classRetainedSizes", Entries: len(g.classRetainedSizes) + len(g.classRetainedSizesAttributed),
+			Bytes: mapBytes(len(g.classRetainedSizes)+len(g.classRetainedSizesAttributed), 8, 8)},
+		{Name: "graph.reachableObjects", Entries: len(g.reachableObjects), Bytes: mapBytes(len(g.reachableObjects), 8, 1)},
+		{Name: "graph.objectIDToIndex", Entries: len(g.objectIDToIndex), Bytes: mapBytes(len(g.objectIDToIndex), 8, 8)},
+		sliceUsage("graph.indexToObjectID", g.indexToObjectID),
+		sliceUsage("graph.objectClassByIndex", g.objectClassByIndex),
+		sliceUsage("graph.objectSizeByIndex", g.objectSizeByIndex),
+		sliceUsage("graph.dominatorByIndex", g.dominatorByIndex),
+		indexListsUsage("graph.indexedIncomingRefs", g.indexedIncomingRefs),
+		indexListsUsage("graph.outgoingRefsByIndex", g.outgoingRefsByIndex),
+		indexListsUsage("graph.incomingRefsByIndex", g.incomingRefsByIndex),
+	}
+
+	children := MemoryUsage{Name: "graph.dominatorChildren", Entries: len(g.dominatorChildren),
+		Bytes: mapBytes(len(g.dominatorChildren), 8, sliceHeaderBytes)}
+	for _, ids := range g.dominatorChildren {
+		children.Bytes += int64(cap(ids)) * 8
+	}
+	byClass := MemoryUsage{Name: "graph.classToObjects", Entries: len(g.classToObjects),
+		Bytes: mapBytes(len(g.classToObjects), 8, sliceHeaderBytes)}
+	for _, ids := range g.classToObjects {
+		byClass.Bytes += int64(cap(ids)) * 8
+	}
+	names := MemoryUsage{Name: "graph.fieldNames", Entries: len(g.fieldNames),
+		Bytes: int64(cap(g.fieldNames))*stringHeaderBytes + mapBytes(len(g.fieldNameToID), stringHeaderBytes, 4)}
+	for _, name := range g.fieldNames {
+		names.Bytes += int64(len(name))
+	}
+	classIDs := MemoryUsage{Name: "graph.classNameToID", Entries: len(g.classNameToID),
+		Bytes: mapBytes(len(g.classNameToID), stringHeaderBytes, 8)}
+	return append(usage, children, byClass, names, classIDs)
+}
+
+// memoryUsage estimates the memory of the tables of the parser state.
+func (s *parserState) memoryUsage() []MemoryUsage {
+	usage := []MemoryUsage{
+		stringMapUsage("parser.strings", s.strings),
+		{Name: "parser.classNames", Entries: len(s.classNames), Bytes: mapBytes(len(s.classNames), 8, 8)},
+		{Name: "parser.classInfo", Entries: len(s.classInfo),
+			Bytes: mapBytes(len(s.classInfo), 8, pointerBytes) + int64(len(s.classInfo))*int64(unsafe.Sizeof(ClassInfo{})) +
+				mapBytes(len(s.classByName), stringHeaderBytes, pointerBytes)},
+		{Name: "parser.refFieldCounts", Entries: len(s.refFieldCounts), Bytes: mapBytes(len(s.refFieldCounts), 8, 8)},
+	}
+
+	fields := MemoryUsage{Name: "parser.classFields", Entries: len(s.classFields),
+		Bytes: mapBytes(len(s.classFields), 8, sliceHeaderBytes)}
+	for _, fds := range s.classFields {
+		fields.Bytes += int64(cap(fds)) * int64(unsafe.Sizeof(FieldDescriptor{}))
+	}
+	layouts := MemoryUsage{Name: "parser.classLayouts", Entries: len(s.classLayouts),
+		Bytes: mapBytes(len(s.classLayouts), 8, pointerBytes) + int64(len(s.classLayouts))*int64(unsafe.Sizeof(ClassFieldLayout{}))}
+	for _, layout := range s.classLayouts {
+		layouts.Bytes += int64(cap(layout.InstanceFields))*int64(unsafe.Sizeof(FieldInfo{})) +
+			int64(cap(layout.StaticFields))*int64(unsafe.Sizeof(StaticFieldInfo{}))
+	}
+	deferred := MemoryUsage{Name: "parser.deferredInstances", Entries: len(s.deferredInstances),
+		Bytes: int64(cap(s.deferredInstances)) * int64(unsafe.Sizeof(deferredInstance{}))}
+	for _, d := range s.deferredInstances {
+		deferred.Bytes += int64(cap(d.data))
+	}
+	usage = append(usage, fields, layouts, deferred)
+	if s.objectIndex != nil {
+		usage = append(usage, MemoryUsage{Name: "parser.objectIndex", Entries: len(s.objectIndex.ids),
+			Bytes: int64(cap(s.objectIndex.ids))*8 + int64(cap(s.objectIndex.offsets))*8})
+	}
+	return usage
+}
+
+// memoryReport estimates the memory of the parser state and its graph.
+func (s *parserState) memoryReport() *MemoryReport {
+	usage := s.memoryUsage()
+	if s.refGraph != nil {
+		usage = append(usage, s.refGraph.MemoryUsage()...)
+	}
+	return newMemoryReport(usage)
+}
@@ -0,0 +1,62 @@
+package hprof
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReferenceGraph_MemoryUsage(t *testing.T) {
+	g := NewReferenceGraphWithCapacity(16)
+	g.SetClassName(1, "com.example.Node")
+	for id := uint64(10); id < 110; id++ {
+		g.SetObjectInfo(id, 1, 16)
+		if id > 10 {
+			g.AddReference(ObjectReference{FromObjectID: id - 1, ToObjectID: id, FromClassID: 1, FieldName: "next"})
+		}
+	}
+	g.AddGCRoot(&GCRoot{ObjectID: 10, Type: GCRootJNIGlobal})
+
+	usage := make(map[string]MemoryUsage)
+	for _, u := range g.MemoryUsage() {
+		usage[u.Name] = u
+	}
+	assert.Equal(t, 100, usage["graph.objectSize"].Entries)
+	assert.Equal(t, 99, usage["graph.outgoingRefs"].Entries)
+	assert.Greater(t, usage["graph.outgoingRefs"].Bytes, usage["graph.objectSize"].Bytes)
+	assert.Equal(t, 1, usage["graph.gcRoots"].Entries)
+	assert.Zero(t, usage["graph.dominators"].Entries)
+
+	// Dominators and their lazily built indexes show up once computed
+	g.ComputeDominatorTree()
+	for _, u := range g.MemoryUsage() {
+		usage[u.Name] = u
+	}
+	assert.Equal(t, 100, usage["graph.reachableObjects"].Entries)
+	assert.Positive(t, usage["graph.dominators"].Bytes)
+
+	report := newMemoryReport(g.MemoryUsage())
+	require.NotEmpty(t, report.Structures)
+	var total int64
+	for i, s := range report.Structures {
+		total += s.Bytes
+		if i > 0 {
+			assert.LessOrEqual(t, s.Bytes, report.Structures[i-1].Bytes)
+		}
+	}
+	assert.Equal(t, total, report.Total)
+	assert.Positive(t, report.HeapInUse)
+
+	text := report.String()
+	assert.Contains(t, text, "graph.outgoingRefs")
+	assert.Contains(t, text, "Total (estimated)")
+	assert.NotContains(t, text, "graph.classToObjects")
+}
+
+func TestFormatMemoryBytes(t *testing.T) {
+	assert.Equal(t, "512 B", formatMemoryBytes(512))
+	assert.Equal(t, "1.50 KiB", formatMemoryBytes(1536))
+	assert.Equal(t, "2.00 MiB", formatMemoryBytes(2<<20))
+	assert.Equal(t, "3.00 GiB", formatMemoryBytes(3<<30))
+}
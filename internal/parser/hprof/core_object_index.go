@@ -0,0 +1,260 @@
+package hprof
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/perf-analysis/pkg/compression"
+)
+
+// ============================================================================
+// Object Offset Index
+// ============================================================================
+
+// File layout of objindex.bin:
+//
+//	magic "OIDX" | version u8 | compression u8 | compressed payload
+//
+// The payload holds the source heap dump's identity (path, size, modification
+// time, ID size) followed by (object ID delta, record offset) uvarint pairs
+// sorted by object ID. Offsets point at the sub-record tag byte of the
+// object's INSTANCE_DUMP, OBJ_ARRAY_DUMP or PRIM_ARRAY_DUMP record, so field
+// values and array contents can be read from the dump on demand instead of
+// being kept in memory.
+
+const (
+	// ObjectIndexFileName is the name of the object offset index in the task directory.
+	ObjectIndexFileName = "objindex.bin"
+
+	// ClassLayoutsFileName is the name of the JSON-encoded class field layouts
+	// in the task directory, needed to decode instance fields.
+	ClassLayoutsFileName = "class_layouts.json"
+
+	// ObjectIndexMagic identifies objindex.bin files.
+	ObjectIndexMagic = "OIDX"
+
+	// ObjectIndexVersion is the current objindex.bin format version.
+	ObjectIndexVersion = 1
+)
+
+// ObjectIndex maps object IDs to the offsets of their records in the heap dump.
+// It is immutable after construction and safe for concurrent use.
+type ObjectIndex struct {
+	// SourceFile is the absolute path of the indexed heap dump
+	SourceFile string
+	// SourceSize and SourceModTime (Unix nanoseconds) detect a replaced or
+	// modified dump; SourceSize is the number of bytes parsed
+	SourceSize    int64
+	SourceModTime int64
+	IDSize        int
+
+	ids     []uint64 // sorted
+	offsets []int64
+}
+
+// objectIndexBuilder collects record offsets during parsing.
+type objectIndexBuilder struct {
+	ids     []uint64
+	offsets []int64
+}
+
+// add records the offset of an object's sub-record tag byte.
+func (b *objectIndexBuilder) add(objectID uint64, offset int64) {
+	b.ids = append(b.ids, objectID)
+	b.offsets = append(b.offsets, offset)
+}
+
+// build sorts the collected offsets into an index. The builder must not be
+// used afterwards.
+func (b *objectIndexBuilder) build(idSize int, sourceSize int64) *ObjectIndex {
+	sort.Sort(byObjectID{b})
+	return &ObjectIndex{IDSize: idSize, SourceSize: sourceSize, ids: b.ids, offsets: b.offsets}
+}
+
+// byObjectID sorts the builder's parallel slices by object ID.
+type byObjectID struct{ b *objectIndexBuilder }
+
+func (s byObjectID) Len() int           { return len(s.b.ids) }
+func (s byObjectID) Less(i, j int) bool { return s.b.ids[i] < s.b.ids[j] }
+func (s byObjectID) Swap(i, j int) {
+	s.b.ids[i], s.b.ids[j] = s.b.ids[j], s.b.ids[i]
+	s.b.offsets[i], s.b.offsets[j] = s.b.offsets[j], s.b.offsets[i]
+}
+
+// Len returns the number of indexed objects.
+func (x *ObjectIndex) Len() int {
+	return len(x.ids)
+}
+
+// Offset returns the record offset of an object.
+func (x *ObjectIndex) Offset(objectID uint64) (int64, bool) {
+	i := sort.Search(len(x.ids), func(i int) bool { return x.ids[i] >= objectID })
+	if i < len(x.ids) && x.ids[i] == objectID {
+		return x.offsets[i], true
+	}
+	return 0, false
+}
+
+// SetSource records the heap dump the index was built from.
+func (x *ObjectIndex) SetSource(filename string) error {
+	abs, err := filepath.Abs(filename)
+	if err != nil {
+		return err
+	}
+	info, err := os.Stat(abs)
+	if err != nil {
+		return err
+	}
+	x.SourceFile = abs
+	x.SourceModTime = info.ModTime().UnixNano()
+	return nil
+}
+
+// CheckSource verifies that the heap dump is still the one that was indexed.
+func (x *ObjectIndex) CheckSource() error {
+	if x.SourceFile == "" {
+		return fmt.Errorf("object index has no source heap dump")
+	}
+	info, err := os.Stat(x.SourceFile)
+	if err != nil {
+		return fmt.Errorf("heap dump not available: %w", err)
+	}
+	if info.Size() != x.SourceSize || info.ModTime().UnixNano() != x.SourceModTime {
+		return fmt.Errorf("heap dump %s changed since it was analyzed", x.SourceFile)
+	}
+	return nil
+}
+
+// MarshalBinary encodes the index in the objindex.bin format using zstd.
+func (x *ObjectIndex) MarshalBinary() ([]byte, error) {
+	var raw bytes.Buffer
+	raw.Grow(64 + len(x.SourceFile) + len(x.ids)*8)
+	var tmp [binary.MaxVarintLen64]byte
+	putUvarint := func(v uint64) {
+		raw.Write(tmp[:binary.PutUvarint(tmp[:], v)])
+	}
+	putUvarint(uint64(len(x.SourceFile)))
+	raw.WriteString(x.SourceFile)
+	putUvarint(uint64(x.SourceSize))
+	putUvarint(uint64(x.SourceModTime))
+	putUvarint(uint64(x.IDSize))
+	putUvarint(uint64(len(x.ids)))
+	var prev uint64
+	for i, id := range x.ids {
+		putUvarint(id - prev)
+		putUvarint(uint64(x.offsets[i]))
+		prev = id
+	}
+
+	compressor, err := compression.New(compression.TypeZstd, compression.LevelDefault)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create compressor: %w", err)
+	}
+	defer compression.Close(compressor)
+	compressed, err := compressor.Compress(raw.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("failed to compress object index: %w", err)
+	}
+
+	var buf bytes.Buffer
+	buf.Grow(6 + len(compressed))
+	buf.WriteString(ObjectIndexMagic)
+	buf.WriteByte(ObjectIndexVersion)
+	buf.WriteByte(byte(compression.TypeZstd))
+	buf.Write(compressed)
+	return buf.Bytes(), nil
+}
+
+// UnmarshalObjectIndex decodes an index in the objindex.bin format.
+func UnmarshalObjectIndex(data []byte) (*ObjectIndex, error) {
+	if len(data) < 6 {
+		return nil, fmt.Errorf("object index data too short: %d bytes", len(data))
+	}
+	if string(data[:4]) != ObjectIndexMagic {
+		return nil, fmt.Errorf("invalid magic bytes: expected %q, got %q", ObjectIndexMagic, string(data[:4]))
+	}
+	if version := data[4]; version > ObjectIndexVersion {
+		return nil, fmt.Errorf("unsupported object index version %d (max %d)", version, ObjectIndexVersion)
+	}
+
+	compressor, err := compression.New(compression.Type(data[5]), compression.LevelDefault)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create decompressor: %w", err)
+	}
+	defer compression.Close(compressor)
+	raw, err := compressor.Decompress(data[6:])
+	if err != nil {
+		return nil, fmt.Errorf("failed to decompress object index: %w", err)
+	}
+
+	r := bytes.NewReader(raw)
+	var readErr error
+	uvarint := func() uint64 {
+		if readErr != nil {
+			return 0
+		}
+		v, err := binary.ReadUvarint(r)
+		if err != nil {
+			readErr = fmt.Errorf("truncated object index: %w", err)
+		}
+		return v
+	}
+
+	x := &ObjectIndex{}
+	pathLen := uvarint()
+	if readErr == nil && pathLen > uint64(r.Len()) {
+		return nil, fmt.Errorf("invalid source path length %d", pathLen)
+	}
+	path := make([]byte, pathLen)
+	r.Read(path)
+	x.SourceFile = string(path)
+	x.SourceSize = int64(uvarint())
+	x.SourceModTime = int64(uvarint())
+	x.IDSize = int(uvarint())
+	count := uvarint()
+	if readErr != nil {
+		return nil, readErr
+	}
+	// Each entry takes at least two bytes
+	if count > uint64(r.Len())/2 {
+		return nil, fmt.Errorf("invalid object count %d", count)
+	}
+
+	x.ids = make([]uint64, count)
+	x.offsets = make([]int64, count)
+	var prev uint64
+	for i := range x.ids {
+		prev += uvarint()
+		x.ids[i] = prev
+		x.offsets[i] = int64(uvarint())
+	}
+	if readErr != nil {
+		return nil, readErr
+	}
+	return x, nil
+}
+
+// WriteFile writes the index to an objindex.bin file.
+func (x *ObjectIndex) WriteFile(filename string) error {
+	data, err := x.MarshalBinary()
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(filename, data, 0644); err != nil {
+		return fmt.Errorf("failed to write file: %w", err)
+	}
+	return nil
+}
+
+// ReadObjectIndexFile reads an objindex.bin file.
+func ReadObjectIndexFile(filename string) (*ObjectIndex, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	return UnmarshalObjectIndex(data)
+}
@@ -0,0 +1,592 @@
+package hprof
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strings"
+	"unicode/utf16"
+)
+
+// Limits of ObjectReader.
+const (
+	// DefaultObjectElements is the number of array elements returned by default.
+	DefaultObjectElements = 100
+	// MaxStringLength is the number of characters decoded from a String object.
+	MaxStringLength = 16 * 1024
+	// StringPreviewLength is the number of characters decoded from Strings
+	// referenced by fields and array elements.
+	StringPreviewLength = 256
+	// DefaultPayloadBytes is the number of payload bytes returned by default.
+	DefaultPayloadBytes = 4096
+	// MaxPayloadBytes is the number of payload bytes returned at most.
+	MaxPayloadBytes = 1 << 20
+)
+
+// Kinds of ObjectContent.
+const (
+	ObjectKindInstance       = "instance"
+	ObjectKindObjectArray    = "object_array"
+	ObjectKindPrimitiveArray = "primitive_array"
+)
+
+// ObjectContent holds the field values or array elements of one object, read
+// from the heap dump.
+type ObjectContent struct {
+	ObjectID     uint64 `json:"object_id"`
+	ClassName    string `json:"class_name"`
+	Kind         string `json:"kind"`
+	ShallowSize  int64  `json:"shallow_size"`
+	RetainedSize int64  `json:"retained_size"`
+	// Fields are the instance fields, own class first, then superclasses
+	Fields []*FieldValue `json:"fields,omitempty"`
+	// ElementType, Length and Elements describe arrays; Elements holds at most
+	// the requested number of elements and Truncated is set when there are more
+	ElementType string        `json:"element_type,omitempty"`
+	Length      int           `json:"length"`
+	Elements    []*FieldValue `json:"elements,omitempty"`
+	Truncated   bool          `json:"truncated,omitempty"`
+	// StringValue is the text of java.lang.String objects and char[] arrays,
+	// cut after MaxStringLength characters
+	StringValue     *string `json:"string_value,omitempty"`
+	StringTruncated bool    `json:"string_truncated,omitempty"`
+}
+
+// FieldValue is the value of one field or array element. Primitives are
+// decoded into Value: chars as strings, longs outside the exact float64 range
+// and non-finite floating point values as strings. References are returned
+// as RefID, with a preview of the text when they point to a String.
+type FieldValue struct {
+	Name         string      `json:"name"`
+	Type         string      `json:"type"`
+	Value        interface{} `json:"value,omitempty"`
+	RefID        uint64      `json:"ref_id,omitempty"`
+	RefClass     string      `json:"ref_class,omitempty"`
+	ShallowSize  int64       `json:"shallow_size,omitempty"`
+	RetainedSize int64       `json:"retained_size,omitempty"`
+	StringValue  *string     `json:"string_value,omitempty"`
+}
+
+// ObjectPayload is a window of the raw contents of a primitive array, or of
+// the value array of a String.
+type ObjectPayload struct {
+	ObjectID uint64 `json:"object_id"`
+	// ArrayID is the value array of Strings, else the object itself
+	ArrayID     uint64 `json:"array_id"`
+	ClassName   string `json:"class_name"`
+	ElementType string `json:"element_type"`
+	// Size is the number of bytes of the whole contents
+	Size int64 `json:"size"`
+	// Offset is the position of Data in the contents, and Truncated is set
+	// when the contents continue after it
+	Offset    int64  `json:"offset"`
+	Data      []byte `json:"-"`
+	Truncated bool   `json:"truncated,omitempty"`
+	// Text decodes Data: char[] as UTF-16, String values with their coder and
+	// other byte[] as UTF-8. It is empty for other arrays.
+	Text string `json:"text"`
+}
+
+// ObjectReader reads field values and array contents of objects from the heap
+// dump on demand, using the offsets recorded in an ObjectIndex. The dump is
+// opened per call, so the reader holds no file handles and is safe for
+// concurrent use.
+type ObjectReader struct {
+	index   *ObjectIndex
+	layouts map[uint64]*ClassFieldLayout
+}
+
+// NewObjectReader creates a reader for the heap dump an index was built from.
+func NewObjectReader(index *ObjectIndex, classLayouts map[uint64]*ClassFieldLayout) *ObjectReader {
+	return &ObjectReader{index: index, layouts: classLayouts}
+}
+
+// ReadObject reads an object's fields, or up to maxElements elements of an
+// array (DefaultObjectElements if maxElements <= 0).
+func (r *ObjectReader) ReadObject(objectID uint64, maxElements int) (*ObjectContent, error) {
+	if maxElements <= 0 {
+		maxElements = DefaultObjectElements
+	}
+	f, err := r.open()
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	rec, err := r.readRecord(f, objectID, maxElements)
+	if err != nil {
+		return nil, err
+	}
+
+	content := &ObjectContent{ObjectID: objectID}
+	switch rec.tag {
+	case HeapTagInstanceDump:
+		content.Kind = ObjectKindInstance
+		content.ClassName = r.className(rec.classID)
+		content.Fields = r.instanceFields(f, rec)
+		if content.ClassName == "java.lang.String" {
+			if text, truncated, ok := r.stringOf(f, rec, MaxStringLength); ok {
+				content.StringValue = &text
+				content.StringTruncated = truncated
+			}
+		}
+	case HeapTagObjectArrayDump:
+		content.Kind = ObjectKindObjectArray
+		content.ElementType = basicTypeToString(TypeObject)
+		if name := r.className(rec.classID); name != "" {
+			content.ClassName = name + "[]"
+		}
+		content.Length = rec.length
+		content.Elements = make([]*FieldValue, 0, rec.count)
+		for i := 0; i < rec.count; i++ {
+			elem := &FieldValue{Name: fmt.Sprintf("[%d]", i), Type: content.ElementType}
+			r.setValue(f, elem, TypeObject, rec.data[i*r.index.IDSize:])
+			content.Elements = append(content.Elements, elem)
+		}
+		content.Truncated = rec.count < rec.length
+	case HeapTagPrimitiveArrayDump:
+		content.Kind = ObjectKindPrimitiveArray
+		content.ElementType = basicTypeToString(rec.elemType)
+		content.ClassName = content.ElementType + "[]"
+		size := BasicTypeSize(rec.elemType, r.index.IDSize)
+		content.Length = rec.length
+		content.Elements = make([]*FieldValue, 0, rec.count)
+		for i := 0; i < rec.count; i++ {
+			elem := &FieldValue{Name: fmt.Sprintf("[%d]", i), Type: content.ElementType}
+			r.setValue(f, elem, rec.elemType, rec.data[i*size:])
+			content.Elements = append(content.Elements, elem)
+		}
+		content.Truncated = rec.count < rec.length
+		if rec.elemType == TypeChar {
+			if text, truncated, err := r.arrayText(f, rec.offset, stringCoderLatin1, MaxStringLength); err == nil {
+				content.StringValue = &text
+				content.StringTruncated = truncated
+			}
+		}
+	}
+	return content, nil
+}
+
+// ReadString returns up to maxChars characters of a java.lang.String object.
+func (r *ObjectReader) ReadString(objectID uint64, maxChars int) (string, bool, error) {
+	f, err := r.open()
+	if err != nil {
+		return "", false, err
+	}
+	defer f.Close()
+
+	rec, err := r.readRecord(f, objectID, 0)
+	if err != nil {
+		return "", false, err
+	}
+	if rec.tag != HeapTagInstanceDump || r.className(rec.classID) != "java.lang.String" {
+		return "", false, fmt.Errorf("object 0x%x is not a java.lang.String", objectID)
+	}
+	text, truncated, ok := r.stringOf(f, rec, maxChars)
+	if !ok {
+		return "", false, fmt.Errorf("value of String 0x%x not found", objectID)
+	}
+	return text, truncated, nil
+}
+
+// ReadPayload returns up to maxBytes bytes (DefaultPayloadBytes if
+// maxBytes <= 0, at most MaxPayloadBytes) of the contents of a primitive
+// array or String, from offset on. Offset and length are rounded down to
+// whole elements.
+func (r *ObjectReader) ReadPayload(objectID uint64, offset int64, maxBytes int) (*ObjectPayload, error) {
+	if maxBytes <= 0 {
+		maxBytes = DefaultPayloadBytes
+	}
+	maxBytes = min(maxBytes, MaxPayloadBytes)
+	f, err := r.open()
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	payload, rec, coder, err := r.payloadArray(f, objectID)
+	if err != nil {
+		return nil, err
+	}
+	if offset < 0 || offset > payload.Size {
+		return nil, fmt.Errorf("offset %d is outside of the %d bytes of object 0x%x", offset, payload.Size, objectID)
+	}
+	elemSize := int64(BasicTypeSize(rec.elemType, r.index.IDSize))
+	offset -= offset % elemSize
+	n := min(payload.Size-offset, max(int64(maxBytes)/elemSize, 1)*elemSize)
+
+	payload.Offset = offset
+	payload.Data = make([]byte, n)
+	if _, err := f.ReadAt(payload.Data, rec.dataOffset+offset); err != nil {
+		return nil, fmt.Errorf("failed to read object 0x%x: %w", payload.ArrayID, err)
+	}
+	payload.Truncated = offset+n < payload.Size
+	payload.Text = payloadText(rec.elemType, payload.ArrayID != objectID, coder, payload.Data)
+	return payload, nil
+}
+
+// WritePayload writes the whole contents of a primitive array or String to
+// w, returning their size.
+func (r *ObjectReader) WritePayload(w io.Writer, objectID uint64) (int64, error) {
+	f, err := r.open()
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	payload, rec, _, err := r.payloadArray(f, objectID)
+	if err != nil {
+		return 0, err
+	}
+	return io.Copy(w, io.NewSectionReader(f, rec.dataOffset, payload.Size))
+}
+
+// payloadArray returns the payload of an object without its data, the
+// record of the array holding it and the coder of Strings.
+func (r *ObjectReader) payloadArray(ra io.ReaderAt, objectID uint64) (*ObjectPayload, *objectRecord, int, error) {
+	rec, err := r.readRecord(ra, objectID, 0)
+	if err != nil {
+		return nil, nil, 0, err
+	}
+	payload := &ObjectPayload{ObjectID: objectID, ArrayID: objectID}
+	coder := stringCoderLatin1
+	if rec.tag == HeapTagInstanceDump && r.className(rec.classID) == "java.lang.String" {
+		payload.ClassName = "java.lang.String"
+		if payload.ArrayID, coder = r.stringArray(rec); payload.ArrayID == 0 {
+			return nil, nil, 0, fmt.Errorf("value of String 0x%x not found", objectID)
+		}
+		if rec, err = r.readRecord(ra, payload.ArrayID, 0); err != nil {
+			return nil, nil, 0, err
+		}
+	}
+	if rec.tag != HeapTagPrimitiveArrayDump {
+		return nil, nil, 0, fmt.Errorf("object 0x%x is not a primitive array or String", objectID)
+	}
+
+	payload.ElementType = basicTypeToString(rec.elemType)
+	if payload.ClassName == "" {
+		payload.ClassName = payload.ElementType + "[]"
+	}
+	payload.Size = int64(rec.length) * int64(BasicTypeSize(rec.elemType, r.index.IDSize))
+	return payload, rec, coder, nil
+}
+
+// payloadText decodes the data of an array of elemType, the value of a
+// String with the given coder if isString. UTF-16 byte[] values are in the
+// dumping JVM's byte order, which is assumed to be little-endian.
+func payloadText(elemType BasicType, isString bool, coder int, data []byte) string {
+	switch {
+	case elemType == TypeChar:
+		units := make([]uint16, len(data)/2)
+		for i := range units {
+			units[i] = binary.BigEndian.Uint16(data[i*2:])
+		}
+		return string(utf16.Decode(units))
+	case elemType != TypeByte:
+		return ""
+	case isString && coder == stringCoderUTF16:
+		units := make([]uint16, len(data)/2)
+		for i := range units {
+			units[i] = binary.LittleEndian.Uint16(data[i*2:])
+		}
+		return string(utf16.Decode(units))
+	case isString:
+		var sb strings.Builder
+		sb.Grow(len(data))
+		for _, c := range data {
+			sb.WriteRune(rune(c))
+		}
+		return sb.String()
+	default:
+		return strings.ToValidUTF8(string(data), "\uFFFD")
+	}
+}
+
+// open opens the indexed heap dump after checking it is unchanged.
+func (r *ObjectReader) open() (*os.File, error) {
+	if err := r.index.CheckSource(); err != nil {
+		return nil, err
+	}
+	return os.Open(r.index.SourceFile)
+}
+
+// className returns the name of a class, or "" if its layout is unknown.
+func (r *ObjectReader) className(classID uint64) string {
+	if layout, ok := r.layouts[classID]; ok {
+		return layout.ClassName
+	}
+	return ""
+}
+
+// objectRecord is a decoded object sub-record.
+type objectRecord struct {
+	tag    HeapDumpTag
+	offset int64
+	// classID is the instance class, or the element class of object arrays
+	classID  uint64
+	elemType BasicType
+	// length is the number of elements of arrays, or of instance data bytes
+	length int
+	// count is the number of elements (or bytes) read into data
+	count int
+	data  []byte
+	// dataOffset is the position of the elements (or instance data)
+	dataOffset int64
+}
+
+// readRecord reads the sub-record of an object, including at most
+// maxElements array elements.
+func (r *ObjectReader) readRecord(ra io.ReaderAt, objectID uint64, maxElements int) (*objectRecord, error) {
+	offset, ok := r.index.Offset(objectID)
+	if !ok {
+		return nil, fmt.Errorf("object 0x%x not found in object index", objectID)
+	}
+	rec, err := r.readRecordAt(ra, offset, maxElements)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read object 0x%x: %w", objectID, err)
+	}
+	return rec, nil
+}
+
+// readRecordAt reads the sub-record starting at offset.
+func (r *ObjectReader) readRecordAt(ra io.ReaderAt, offset int64, maxElements int) (*objectRecord, error) {
+	idSize := r.index.IDSize
+	// tag, object ID, stack trace serial, then up to an ID, a u32 and a type byte
+	head := make([]byte, 1+2*idSize+9)
+	n, err := ra.ReadAt(head, offset)
+	if n < 1+idSize+4+4+1 && err != nil {
+		return nil, err
+	}
+	head = head[:n]
+
+	rec := &objectRecord{tag: HeapDumpTag(head[0]), offset: offset}
+	pos := 1 + idSize + 4
+	var elemSize int
+	switch rec.tag {
+	case HeapTagInstanceDump:
+		if len(head) < pos+idSize+4 {
+			return nil, io.ErrUnexpectedEOF
+		}
+		rec.classID = decodeID(head[pos:], idSize)
+		pos += idSize
+		rec.length = int(binary.BigEndian.Uint32(head[pos:]))
+		pos += 4
+		rec.count = rec.length
+		elemSize = 1
+	case HeapTagObjectArrayDump:
+		if len(head) < pos+4+idSize {
+			return nil, io.ErrUnexpectedEOF
+		}
+		rec.length = int(binary.BigEndian.Uint32(head[pos:]))
+		pos += 4
+		rec.classID = decodeID(head[pos:], idSize)
+		pos += idSize
+		rec.count = min(rec.length, maxElements)
+		elemSize = idSize
+	case HeapTagPrimitiveArrayDump:
+		if len(head) < pos+5 {
+			return nil, io.ErrUnexpectedEOF
+		}
+		rec.length = int(binary.BigEndian.Uint32(head[pos:]))
+		pos += 4
+		rec.elemType = BasicType(head[pos])
+		pos++
+		rec.count = min(rec.length, maxElements)
+		elemSize = BasicTypeSize(rec.elemType, idSize)
+		if elemSize == 0 {
+			return nil, fmt.Errorf("invalid array element type %d", rec.elemType)
+		}
+	default:
+		return nil, fmt.Errorf("unexpected sub-record tag 0x%02x", rec.tag)
+	}
+
+	rec.dataOffset = offset + int64(pos)
+	rec.data = make([]byte, rec.count*elemSize)
+	if _, err := ra.ReadAt(rec.data, rec.dataOffset); err != nil {
+		return nil, err
+	}
+	return rec, nil
+}
+
+// instanceFields decodes the fields of an instance, walking the class
+// hierarchy the way instance data is laid out: own fields first. String
+// previews of references are read from ra when it is not nil.
+func (r *ObjectReader) instanceFields(ra io.ReaderAt, rec *objectRecord) []*FieldValue {
+	var fields []*FieldValue
+	pos := 0
+	for depth, classID := 0, rec.classID; classID != 0 && depth < maxClassHierarchyDepth; depth++ {
+		layout, ok := r.layouts[classID]
+		if !ok {
+			break
+		}
+		for _, fi := range layout.InstanceFields {
+			size := BasicTypeSize(fi.Type, r.index.IDSize)
+			if pos+size > len(rec.data) {
+				return fields
+			}
+			field := &FieldValue{Name: fi.Name, Type: basicTypeToString(fi.Type)}
+			r.setValue(ra, field, fi.Type, rec.data[pos:])
+			fields = append(fields, field)
+			pos += size
+		}
+		classID = layout.SuperClassID
+	}
+	return fields
+}
+
+// setValue decodes a value of type t from the start of b. References to
+// Strings get a preview of their text unless ra is nil.
+func (r *ObjectReader) setValue(ra io.ReaderAt, v *FieldValue, t BasicType, b []byte) {
+	if t != TypeObject {
+		v.Value = decodePrimitive(t, b)
+		return
+	}
+	v.RefID = decodeID(b, r.index.IDSize)
+	if v.RefID == 0 || ra == nil {
+		return
+	}
+	offset, ok := r.index.Offset(v.RefID)
+	if !ok {
+		return
+	}
+	rec, err := r.readRecordAt(ra, offset, 0)
+	if err != nil || rec.tag != HeapTagInstanceDump {
+		return
+	}
+	if v.RefClass = r.className(rec.classID); v.RefClass != "java.lang.String" {
+		return
+	}
+	if text, truncated, ok := r.stringOf(ra, rec, StringPreviewLength); ok {
+		if truncated {
+			text += "…"
+		}
+		v.StringValue = &text
+	}
+}
+
+// String coders of compact strings (JDK 9+).
+const (
+	stringCoderLatin1 = 0
+	stringCoderUTF16  = 1
+)
+
+// stringOf decodes a java.lang.String instance from its value array: a char[]
+// before JDK 9, else a byte[] in Latin-1 or UTF-16 as given by coder.
+func (r *ObjectReader) stringOf(ra io.ReaderAt, rec *objectRecord, maxChars int) (string, bool, bool) {
+	text, _, truncated, ok := r.stringValue(ra, rec, maxChars)
+	return text, truncated, ok
+}
+
+// stringValue is stringOf, also returning the ID of the value array.
+func (r *ObjectReader) stringValue(ra io.ReaderAt, rec *objectRecord, maxChars int) (string, uint64, bool, bool) {
+	valueID, coder := r.stringArray(rec)
+	if valueID == 0 {
+		return "", 0, false, false
+	}
+	offset, ok := r.index.Offset(valueID)
+	if !ok {
+		return "", 0, false, false
+	}
+	text, truncated, err := r.arrayText(ra, offset, coder, maxChars)
+	if err != nil {
+		return "", 0, false, false
+	}
+	return text, valueID, truncated, true
+}
+
+// stringArray returns the value array of a String instance and its coder.
+func (r *ObjectReader) stringArray(rec *objectRecord) (uint64, int) {
+	var valueID uint64
+	coder := stringCoderLatin1
+	for _, field := range r.instanceFields(nil, rec) {
+		switch field.Name {
+		case "value":
+			valueID = field.RefID
+		case "coder":
+			if c, ok := field.Value.(int8); ok {
+				coder = int(c)
+			}
+		}
+	}
+	return valueID, coder
+}
+
+// arrayText decodes up to maxChars characters of the char[] or byte[] record
+// at offset. byte[] contents are decoded with the compact string coder;
+// UTF-16 byte[] values are in the dumping JVM's byte order, which is assumed
+// to be little-endian.
+func (r *ObjectReader) arrayText(ra io.ReaderAt, offset int64, coder int, maxChars int) (string, bool, error) {
+	unitBytes := 1
+	if coder == stringCoderUTF16 {
+		unitBytes = 2
+	}
+	// Read enough elements for maxChars characters
+	rec, err := r.readRecordAt(ra, offset, maxChars*unitBytes)
+	if err != nil {
+		return "", false, err
+	}
+	if rec.tag != HeapTagPrimitiveArrayDump {
+		return "", false, fmt.Errorf("string value is not a primitive array")
+	}
+
+	switch rec.elemType {
+	case TypeChar, TypeByte:
+		return payloadText(rec.elemType, true, coder, rec.data), rec.count < rec.length, nil
+	default:
+		return "", false, fmt.Errorf("string value is a %s[] array", basicTypeToString(rec.elemType))
+	}
+}
+
+// decodeID decodes a big-endian identifier of idSize bytes.
+func decodeID(b []byte, idSize int) uint64 {
+	if idSize == 4 {
+		return uint64(binary.BigEndian.Uint32(b))
+	}
+	return binary.BigEndian.Uint64(b)
+}
+
+// maxExactInt is the largest integer a float64 (and JavaScript) represents exactly.
+const maxExactInt = 1 << 53
+
+// decodePrimitive decodes a big-endian primitive value into a JSON-friendly value.
+func decodePrimitive(t BasicType, b []byte) interface{} {
+	switch t {
+	case TypeBoolean:
+		return b[0] != 0
+	case TypeByte:
+		return int8(b[0])
+	case TypeChar:
+		return string(utf16.Decode([]uint16{binary.BigEndian.Uint16(b)}))
+	case TypeShort:
+		return int16(binary.BigEndian.Uint16(b))
+	case TypeInt:
+		return int32(binary.BigEndian.Uint32(b))
+	case TypeLong:
+		v := int64(binary.BigEndian.Uint64(b))
+		if v > maxExactInt || v < -maxExactInt {
+			return fmt.Sprintf("%d", v)
+		}
+		return v
+	case TypeFloat:
+		return finiteOrString(float64(math.Float32frombits(binary.BigEndian.Uint32(b))))
+	case TypeDouble:
+		return finiteOrString(math.Float64frombits(binary.BigEndian.Uint64(b)))
+	default:
+		return nil
+	}
+}
+
+// finiteOrString returns v, or its name if JSON cannot encode it.
+func finiteOrString(v float64) interface{} {
+	switch {
+	case math.IsNaN(v):
+		return "NaN"
+	case math.IsInf(v, 1):
+		return "Infinity"
+	case math.IsInf(v, -1):
+		return "-Infinity"
+	}
+	return v
+}
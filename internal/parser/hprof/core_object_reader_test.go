@@ -0,0 +1,249 @@
+package hprof
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newObjectReaderTestHprof returns a heap dump with primitive fields, Strings
+// in both compact string coders and arrays.
+func newObjectReaderTestHprof() []byte {
+	b := newTestHprofBuilder()
+	b.loadClass(0x10, "com/example/Base")
+	b.loadClass(0x20, "com/example/Sample")
+	b.loadClass(0x30, "java/lang/String")
+	b.loadClass(0x40, "[Ljava/lang/String;")
+
+	b.classDump(0x10, 0, 2, testField{"id", TypeShort})
+	b.classDump(0x20, 0x10, 0,
+		testField{"count", TypeInt},
+		testField{"flag", TypeBoolean},
+		testField{"ratio", TypeDouble},
+		testField{"big", TypeLong},
+		testField{"letter", TypeChar},
+		testField{"missing", TypeFloat},
+		testField{"name", TypeObject},
+		testField{"data", TypeObject})
+	b.classDump(0x30, 0, 0, testField{"value", TypeObject}, testField{"coder", TypeByte})
+	b.classDump(0x40, 0, 0)
+
+	var data bytes.Buffer
+	binary.Write(&data, binary.BigEndian, int32(-42))
+	data.WriteByte(1)
+	binary.Write(&data, binary.BigEndian, 2.5)
+	binary.Write(&data, binary.BigEndian, int64(1)<<60)
+	binary.Write(&data, binary.BigEndian, uint16('Z'))
+	binary.Write(&data, binary.BigEndian, float32(math.NaN()))
+	data.Write(refBytes(0x3000, 0x5000))
+	binary.Write(&data, binary.BigEndian, int16(7)) // Base.id
+	b.instanceDump(0x2000, 0x20, data.Bytes())
+
+	// Latin-1 "hello" and UTF-16 "héllo→"
+	b.instanceDump(0x3000, 0x30, append(refBytes(0x3100), 0))
+	b.primitiveArrayDump(0x3100, TypeByte, 5, []byte("hello"))
+	utf16le := []byte{'h', 0, 0xE9, 0, 'l', 0, 'l', 0, 'o', 0, 0x92, 0x21}
+	b.instanceDump(0x3200, 0x30, append(refBytes(0x3300), 1))
+	b.primitiveArrayDump(0x3300, TypeByte, len(utf16le), utf16le)
+
+	ints := make([]byte, 4*300)
+	for i := 0; i < 300; i++ {
+		binary.BigEndian.PutUint32(ints[i*4:], uint32(i*i))
+	}
+	b.primitiveArrayDump(0x5000, TypeInt, 300, ints)
+	b.primitiveArrayDump(0x6000, TypeChar, 2, []byte{0, 'o', 0, 'k'})
+	b.objectArrayDump(0x7000, 0x30, 0x3000, 0, 0x3200)
+
+	b.rootJNIGlobal(0x2000)
+	b.rootJNIGlobal(0x3200)
+	b.rootJNIGlobal(0x6000)
+	b.rootJNIGlobal(0x7000)
+	return b.bytes()
+}
+
+// runObjectReaderTestJob analyzes the test dump from a file and returns its task directory.
+func runObjectReaderTestJob(t *testing.T) (string, *HeapAnalysisResult) {
+	dir := t.TempDir()
+	input := filepath.Join(dir, "heap.hprof")
+	require.NoError(t, os.WriteFile(input, newObjectReaderTestHprof(), 0644))
+
+	taskDir := filepath.Join(dir, "task")
+	job, err := NewAnalysisJob(AnalysisJobConfig{TaskDir: taskDir, InputFile: input, SerializeOptions: FastSerializeOptions()})
+	require.NoError(t, err)
+	f, err := os.Open(input)
+	require.NoError(t, err)
+	defer f.Close()
+	result, err := job.Run(context.Background(), f)
+	require.NoError(t, err)
+	return taskDir, result
+}
+
+func TestHeapSnapshot_ObjectContent(t *testing.T) {
+	taskDir, result := runObjectReaderTestJob(t)
+	assert.FileExists(t, filepath.Join(taskDir, ObjectIndexFileName))
+	assert.FileExists(t, filepath.Join(taskDir, ClassLayoutsFileName))
+
+	snapshot := result.Snapshot()
+	content, err := snapshot.ObjectContent(0x2000, 0)
+	require.NoError(t, err)
+	assert.Equal(t, "com.example.Sample", content.ClassName)
+	assert.Equal(t, ObjectKindInstance, content.Kind)
+
+	values := make(map[string]*FieldValue)
+	for _, f := range content.Fields {
+		values[f.Name] = f
+	}
+	require.Len(t, values, 9)
+	assert.Equal(t, "count", content.Fields[0].Name, "own fields come first")
+	assert.Equal(t, int32(-42), values["count"].Value)
+	assert.Equal(t, true, values["flag"].Value)
+	assert.Equal(t, 2.5, values["ratio"].Value)
+	assert.Equal(t, "1152921504606846976", values["big"].Value, "longs beyond 2^53 are strings")
+	assert.Equal(t, "Z", values["letter"].Value)
+	assert.Equal(t, "NaN", values["missing"].Value)
+	assert.Equal(t, int16(7), values["id"].Value)
+
+	name := values["name"]
+	assert.Equal(t, uint64(0x3000), name.RefID)
+	assert.Equal(t, "java.lang.String", name.RefClass)
+	require.NotNil(t, name.StringValue)
+	assert.Equal(t, "hello", *name.StringValue)
+	assert.Equal(t, "int[]", values["data"].RefClass)
+
+	// Strings decode their value array
+	str, err := snapshot.ObjectContent(0x3200, 0)
+	require.NoError(t, err)
+	require.NotNil(t, str.StringValue)
+	assert.Equal(t, "héllo→", *str.StringValue)
+
+	// Arrays are truncated
+	arr, err := snapshot.ObjectContent(0x5000, 10)
+	require.NoError(t, err)
+	assert.Equal(t, ObjectKindPrimitiveArray, arr.Kind)
+	assert.Equal(t, "int", arr.ElementType)
+	assert.Equal(t, 300, arr.Length)
+	require.Len(t, arr.Elements, 10)
+	assert.True(t, arr.Truncated)
+	assert.Equal(t, int32(81), arr.Elements[9].Value)
+
+	chars, err := snapshot.ObjectContent(0x6000, 0)
+	require.NoError(t, err)
+	require.NotNil(t, chars.StringValue)
+	assert.Equal(t, "ok", *chars.StringValue)
+	assert.False(t, chars.Truncated)
+
+	objs, err := snapshot.ObjectContent(0x7000, 0)
+	require.NoError(t, err)
+	assert.Equal(t, ObjectKindObjectArray, objs.Kind)
+	require.Len(t, objs.Elements, 3)
+	assert.Equal(t, "[2]", objs.Elements[2].Name)
+	require.NotNil(t, objs.Elements[2].StringValue)
+	assert.Equal(t, "héllo→", *objs.Elements[2].StringValue)
+	assert.Zero(t, objs.Elements[1].RefID)
+
+	// Fields for tree expansion carry values too
+	for _, field := range snapshot.ObjectFields(0x2000) {
+		switch field.Name {
+		case "count":
+			assert.Equal(t, int32(-42), field.Value)
+		case "name":
+			require.NotNil(t, field.StringValue)
+			assert.Equal(t, "hello", *field.StringValue)
+		}
+	}
+
+	_, err = snapshot.ObjectContent(0xDEAD, 0)
+	assert.Error(t, err)
+}
+
+func TestHeapSnapshot_ObjectPayload(t *testing.T) {
+	_, result := runObjectReaderTestJob(t)
+	snapshot := result.Snapshot()
+
+	// Strings read their value array, decoded with their coder
+	payload, err := snapshot.ObjectPayload(0x3200, 0, 0)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(0x3300), payload.ArrayID)
+	assert.Equal(t, "java.lang.String", payload.ClassName)
+	assert.Equal(t, "byte", payload.ElementType)
+	assert.Equal(t, int64(12), payload.Size)
+	assert.Equal(t, "héllo→", payload.Text)
+	assert.False(t, payload.Truncated)
+
+	payload, err = snapshot.ObjectPayload(0x3000, 1, 3)
+	require.NoError(t, err)
+	assert.Equal(t, []byte("ell"), payload.Data)
+	assert.Equal(t, "ell", payload.Text)
+	assert.True(t, payload.Truncated)
+
+	// byte[] decode as UTF-8, char[] as UTF-16
+	payload, err = snapshot.ObjectPayload(0x3300, 0, 0)
+	require.NoError(t, err)
+	assert.Equal(t, "byte[]", payload.ClassName)
+	assert.Equal(t, "h\x00\uFFFD\x00l\x00l\x00o\x00\uFFFD!", payload.Text)
+	payload, err = snapshot.ObjectPayload(0x6000, 0, 0)
+	require.NoError(t, err)
+	assert.Equal(t, "ok", payload.Text)
+
+	// Windows are aligned to whole elements
+	payload, err = snapshot.ObjectPayload(0x5000, 6, 10)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1200), payload.Size)
+	assert.Equal(t, int64(4), payload.Offset)
+	assert.Equal(t, []byte{0, 0, 0, 1, 0, 0, 0, 4}, payload.Data)
+	assert.Empty(t, payload.Text)
+	payload, err = snapshot.ObjectPayload(0x5000, 1200, 0)
+	require.NoError(t, err)
+	assert.Empty(t, payload.Data)
+
+	var buf bytes.Buffer
+	n, err := snapshot.WriteObjectPayload(&buf, 0x3200)
+	require.NoError(t, err)
+	assert.Equal(t, int64(12), n)
+	assert.Equal(t, []byte{'h', 0, 0xE9, 0, 'l', 0, 'l', 0, 'o', 0, 0x92, 0x21}, buf.Bytes())
+
+	_, err = snapshot.ObjectPayload(0x2000, 0, 0)
+	assert.ErrorContains(t, err, "not a primitive array or String")
+	_, err = snapshot.ObjectPayload(0x5000, 1201, 0)
+	assert.Error(t, err)
+	_, err = snapshot.WriteObjectPayload(&buf, 0xDEAD)
+	assert.Error(t, err)
+}
+
+func TestObjectIndex_FileRoundTrip(t *testing.T) {
+	taskDir, result := runObjectReaderTestJob(t)
+
+	index, err := ReadObjectIndexFile(filepath.Join(taskDir, ObjectIndexFileName))
+	require.NoError(t, err)
+	assert.Equal(t, result.ObjectIndex.Len(), index.Len())
+	assert.Equal(t, result.ObjectIndex.SourceFile, index.SourceFile)
+	assert.Equal(t, 8, index.IDSize)
+	for _, id := range []uint64{0x2000, 0x3100, 0x7000} {
+		want, ok := result.ObjectIndex.Offset(id)
+		require.True(t, ok)
+		got, ok := index.Offset(id)
+		require.True(t, ok)
+		assert.Equal(t, want, got)
+	}
+	_, ok := index.Offset(0x10)
+	assert.False(t, ok, "classes are not indexed")
+	require.NoError(t, index.CheckSource())
+
+	// A modified heap dump is rejected
+	later := time.Now().Add(time.Hour)
+	require.NoError(t, os.Chtimes(index.SourceFile, later, later))
+	assert.Error(t, index.CheckSource())
+	_, err = NewObjectReader(index, result.ClassLayouts).ReadObject(0x2000, 0)
+	assert.Error(t, err)
+
+	_, err = UnmarshalObjectIndex([]byte("OIDX"))
+	assert.Error(t, err)
+}
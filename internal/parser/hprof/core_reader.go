@@ -5,25 +5,55 @@ import (
 	"encoding/binary"
 	"fmt"
 	"io"
+	"slices"
 	"time"
 )
 
+// maxEagerRead is the largest ReadBytes length allocated up front. Longer
+// reads grow their buffer as the data arrives, so a corrupt length cannot
+// allocate more memory than the dump holds.
+const maxEagerRead = 1 << 20
+
+// maxFormatLength bounds the format string of the header.
+const maxFormatLength = 256
+
 // Reader provides buffered reading of HPROF binary data.
 type Reader struct {
 	r       *bufio.Reader
+	src     *countingReader
 	idSize  int
 	byteBuf []byte
 }
 
+// countingReader counts the bytes read from the underlying reader.
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
 // NewReader creates a new HPROF reader.
 func NewReader(r io.Reader) *Reader {
+	src := &countingReader{r: r}
 	return &Reader{
-		r:       bufio.NewReaderSize(r, 64*1024), // 64KB buffer
-		idSize:  8,                                // Default to 8, will be set from header
+		r:       bufio.NewReaderSize(src, 64*1024), // 64KB buffer
+		src:     src,
+		idSize:  8, // Default to 8, will be set from header
 		byteBuf: make([]byte, 8),
 	}
 }
 
+// Offset returns the position of the next byte to be read, relative to the
+// start of the stream.
+func (r *Reader) Offset() int64 {
+	return r.src.n - int64(r.r.Buffered())
+}
+
 // SetIDSize sets the identifier size (4 or 8 bytes).
 func (r *Reader) SetIDSize(size int) {
 	r.idSize = size
@@ -47,6 +77,9 @@ func (r *Reader) ReadHeader() (*Header, error) {
 	if err != nil {
 		return nil, fmt.Errorf("failed to read ID size: %w", err)
 	}
+	if idSize != 4 && idSize != 8 {
+		return nil, fmt.Errorf("%w: identifier size %d", ErrBadHeader, idSize)
+	}
 	r.idSize = int(idSize)
 
 	// Read timestamp (8 bytes, milliseconds since epoch)
@@ -62,9 +95,11 @@ func (r *Reader) ReadHeader() (*Header, error) {
 	}, nil
 }
 
-// ReadRecordHeader reads a record header (tag, time delta, length).
+// ReadRecordHeader reads a record header (tag, time delta, length). It
+// returns io.EOF at the end of the dump, and ErrTruncated for a dump ending
+// within the header.
 func (r *Reader) ReadRecordHeader() (tag RecordTag, timeDelta uint32, length uint32, err error) {
-	tagByte, err := r.ReadByte()
+	tagByte, err := r.r.ReadByte()
 	if err != nil {
 		return 0, 0, 0, err
 	}
@@ -85,21 +120,44 @@ func (r *Reader) ReadRecordHeader() (tag RecordTag, timeDelta uint32, length uin
 
 // ReadByte reads a single byte.
 func (r *Reader) ReadByte() (byte, error) {
-	return r.r.ReadByte()
+	b, err := r.r.ReadByte()
+	if err != nil {
+		return 0, r.truncated(err)
+	}
+	return b, nil
 }
 
 // ReadBytes reads n bytes into a new slice.
 func (r *Reader) ReadBytes(n int) ([]byte, error) {
-	buf := make([]byte, n)
-	_, err := io.ReadFull(r.r, buf)
-	return buf, err
+	if n < 0 {
+		return nil, fmt.Errorf("%w: negative length %d at byte %d", ErrInconsistentLength, n, r.Offset())
+	}
+	if n <= maxEagerRead {
+		buf := make([]byte, n)
+		if _, err := io.ReadFull(r.r, buf); err != nil {
+			return nil, r.truncated(err)
+		}
+		return buf, nil
+	}
+
+	buf := make([]byte, 0, maxEagerRead)
+	for len(buf) < n {
+		chunk := min(n-len(buf), maxEagerRead)
+		buf = slices.Grow(buf, chunk)
+		read, err := io.ReadFull(r.r, buf[len(buf):len(buf)+chunk])
+		buf = buf[:len(buf)+read]
+		if err != nil {
+			return nil, r.truncated(err)
+		}
+	}
+	return buf, nil
 }
 
 // ReadUint16 reads a big-endian uint16.
 func (r *Reader) ReadUint16() (uint16, error) {
 	_, err := io.ReadFull(r.r, r.byteBuf[:2])
 	if err != nil {
-		return 0, err
+		return 0, r.truncated(err)
 	}
 	return binary.BigEndian.Uint16(r.byteBuf[:2]), nil
 }
@@ -108,7 +166,7 @@ func (r *Reader) ReadUint16() (uint16, error) {
 func (r *Reader) ReadUint32() (uint32, error) {
 	_, err := io.ReadFull(r.r, r.byteBuf[:4])
 	if err != nil {
-		return 0, err
+		return 0, r.truncated(err)
 	}
 	return binary.BigEndian.Uint32(r.byteBuf[:4]), nil
 }
@@ -117,7 +175,7 @@ func (r *Reader) ReadUint32() (uint32, error) {
 func (r *Reader) ReadUint64() (uint64, error) {
 	_, err := io.ReadFull(r.r, r.byteBuf[:8])
 	if err != nil {
-		return 0, err
+		return 0, r.truncated(err)
 	}
 	return binary.BigEndian.Uint64(r.byteBuf[:8]), nil
 }
@@ -133,7 +191,21 @@ func (r *Reader) ReadID() (uint64, error) {
 
 // Skip skips n bytes.
 func (r *Reader) Skip(n int64) error {
-	_, err := r.r.Discard(int(n))
+	if n < 0 {
+		return fmt.Errorf("%w: negative length %d at byte %d", ErrInconsistentLength, n, r.Offset())
+	}
+	if _, err := r.r.Discard(int(n)); err != nil {
+		return r.truncated(err)
+	}
+	return nil
+}
+
+// truncated returns ErrTruncated for the end of the dump in the middle of
+// a read, and other errors unchanged.
+func (r *Reader) truncated(err error) error {
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		return fmt.Errorf("%w at byte %d", ErrTruncated, r.Offset())
+	}
 	return err
 }
 
@@ -143,11 +215,14 @@ func (r *Reader) readNullTerminatedString() (string, error) {
 	for {
 		b, err := r.r.ReadByte()
 		if err != nil {
-			return "", err
+			return "", r.truncated(err)
 		}
 		if b == 0 {
 			break
 		}
+		if len(result) == maxFormatLength {
+			return "", fmt.Errorf("%w: no null-terminated format string", ErrBadHeader)
+		}
 		result = append(result, b)
 	}
 	return string(result), nil
@@ -6,21 +6,27 @@ import (
 	"context"
 	"sort"
 
+	"go.opentelemetry.io/otel/attribute"
+
+	"github.com/perf-analysis/pkg/telemetry"
 	"github.com/perf-analysis/pkg/utils"
 )
 
 // ResultBuilder builds the final HeapAnalysisResult from parsed state.
 // This separates the result construction logic from the parsing logic.
 type ResultBuilder struct {
+	ctx    context.Context
 	state  *parserState
 	opts   *ParserOptions
 	timer  *utils.Timer
 	logger utils.Logger
 }
 
-// NewResultBuilder creates a new ResultBuilder.
-func NewResultBuilder(state *parserState, opts *ParserOptions, timer *utils.Timer) *ResultBuilder {
+// NewResultBuilder creates a new ResultBuilder. ctx carries the trace the
+// spans of the result phases belong to.
+func NewResultBuilder(ctx context.Context, state *parserState, opts *ParserOptions, timer *utils.Timer) *ResultBuilder {
 	return &ResultBuilder{
+		ctx:    ctx,
 		state:  state,
 		opts:   opts,
 		timer:  timer,
@@ -35,10 +41,14 @@ func (rb *ResultBuilder) debugf(format string, args ...interface{}) {
 	}
 }
 
-// Build constructs the HeapAnalysisResult from the parsed state.
-func (rb *ResultBuilder) Build() *HeapAnalysisResult {
+// Build constructs the HeapAnalysisResult from the parsed state. It returns
+// the context error if ctx is canceled during the dominator tree computation
+// or the retainer analysis.
+func (rb *ResultBuilder) Build() (*HeapAnalysisResult, error) {
 	// Compute dominator tree first if retainer analysis is enabled
-	rb.computeDominatorTree()
+	if err := rb.computeDominatorTree(); err != nil {
+		return nil, err
+	}
 
 	// Collect class statistics
 	classes, totalHeapSize, totalInstances := rb.collectClassStatistics()
@@ -51,13 +61,18 @@ func (rb *ResultBuilder) Build() *HeapAnalysisResult {
 		Header:         rb.state.header,
 		Summary:        rb.state.heapSummary,
 		TopClasses:     topClasses,
+		AllClasses:     classes,
 		TotalClasses:   len(rb.state.classByName),
 		TotalInstances: totalInstances,
 		TotalHeapSize:  totalHeapSize,
+		JVMLayout:      rb.state.layout,
 	}
 
 	// Compute retainer analysis and reference graphs
 	rb.computeRetainerAnalysis(result, topClasses)
+	if err := rb.ctx.Err(); err != nil {
+		return nil, err
+	}
 
 	// Build BiggestObjects
 	rb.buildBiggestObjects(result)
@@ -65,13 +80,30 @@ func (rb *ResultBuilder) Build() *HeapAnalysisResult {
 	// Build GC Roots analysis
 	rb.buildGCRoots(result)
 
-	return result
+	// Build dominator tree slice
+	rb.buildDominatorTree(result)
+
+	// Build array length histograms
+	rb.buildArrayStats(result)
+
+	// Build thread overview
+	rb.buildThreads(result)
+
+	// Resolve allocation sites and CPU samples of the legacy hprof agent
+	rb.buildAllocationSites(result)
+
+	// Report where analysis memory goes, at its peak
+	if rb.opts.Verbose {
+		rb.debugf("Analyzer memory by structure:\n%s", rb.state.memoryReport())
+	}
+
+	return result, nil
 }
 
 // computeDominatorTree computes the dominator tree if retainer analysis is enabled.
-func (rb *ResultBuilder) computeDominatorTree() {
+func (rb *ResultBuilder) computeDominatorTree() error {
 	if rb.state.refGraph == nil || !rb.opts.AnalyzeRetainers {
-		return
+		return nil
 	}
 
 	// Debug: print parsing stats
@@ -97,9 +129,14 @@ func (rb *ResultBuilder) computeDominatorTree() {
 	rb.debugf("ClassInfo entries: %d, ClassFields entries: %d", len(rb.state.classInfo), len(rb.state.classFields))
 
 	// Compute dominator tree to get retained sizes
+	var err error
 	rb.timer.TimeFunc("Dominator tree computation", func() {
-		rb.state.refGraph.ComputeDominatorTree()
+		ctx, span := telemetry.StartSpan(rb.ctx, "hprof.compute_dominators",
+			attribute.Int("hprof.objects", objects), attribute.Int("hprof.references", refs))
+		defer func() { telemetry.EndSpan(span, err) }()
+		err = rb.state.refGraph.ComputeDominatorTreeContext(ctx)
 	})
+	return err
 }
 
 // collectClassStatistics collects class statistics from the parsed state.
@@ -189,7 +226,7 @@ func (rb *ResultBuilder) buildClassStatsFromMap(statsMap map[uint64]struct {
 		// Get retained size from dominator tree
 		retainedSize := rb.state.refGraph.GetClassRetainedSize(className)
 
-		classes = append(classes, &ClassStats{
+		cls := &ClassStats{
 			ClassName:     className,
 			InstanceCount: stats.InstanceCount,
 			TotalSize:     stats.TotalSize,
@@ -197,7 +234,9 @@ func (rb *ResultBuilder) buildClassStatsFromMap(statsMap map[uint64]struct {
 			Percentage:    pct,
 			ShallowSize:   stats.TotalSize,
 			RetainedSize:  retainedSize,
-		})
+		}
+		cls.setClassLoader(rb.state.refGraph, rb.state.classLayouts, classID)
+		classes = append(classes, cls)
 	}
 
 	return classes
@@ -275,8 +314,7 @@ func (rb *ResultBuilder) computeRetainerAnalysis(result *HeapAnalysisResult, top
 		}
 
 		// Run all analysis in parallel
-		ctx := context.Background()
-		analysisResult := analyzer.RunFullAnalysis(ctx, topClasses, analysisOpts)
+		analysisResult := analyzer.RunFullAnalysis(rb.ctx, topClasses, analysisOpts)
 
 		result.ClassRetainers = analysisResult.ClassRetainers
 		result.ReferenceGraphs = analysisResult.ReferenceGraphs
@@ -292,12 +330,17 @@ func (rb *ResultBuilder) buildBiggestObjects(result *HeapAnalysisResult) {
 
 	rb.timer.TimeFunc("Biggest objects analysis", func() {
 		builder := NewBiggestObjectsBuilder(rb.state.refGraph, rb.state.classLayouts, rb.state.strings)
+		builder.SetDominatorRollup(rb.opts.RollupBiggestObjects)
 		result.BiggestObjects = builder.GetBiggestObjectsByRetainedSize(rb.opts.MaxLargestObjects)
 		// Store class layouts and strings for later use (e.g., API queries)
 		result.ClassLayouts = rb.state.classLayouts
 		result.Strings = rb.state.strings
 		// Store reference graph for serialization and advanced analysis
 		result.RefGraph = rb.state.refGraph
+		if rb.state.objectIndex != nil {
+			result.ObjectIndex = rb.state.objectIndex.build(rb.state.reader.IDSize(), rb.state.reader.Offset())
+			rb.state.objectIndex = nil
+		}
 
 		// Debug: Analyze ClassLoader retained size differences (only in verbose mode)
 		if rb.opts.Verbose {
@@ -353,3 +396,77 @@ func (rb *ResultBuilder) buildGCRoots(result *HeapAnalysisResult) {
 		result.GCRootsAnalysis = analysis
 	})
 }
+
+// buildArrayStats attaches array length histograms and aggregate array statistics.
+func (rb *ResultBuilder) buildArrayStats(result *HeapAnalysisResult) {
+	if rb.state.arrayHistograms == nil {
+		return
+	}
+
+	rb.timer.TimeFunc("Array length histograms", func() {
+		result.ArrayLengthHistograms = rb.state.arrayHistograms.Histograms()
+		result.ArrayStats = rb.state.arrayHistograms.ArrayStats()
+	})
+}
+
+// buildDominatorTree builds the top slice of the dominator tree for export.
+func (rb *ResultBuilder) buildDominatorTree(result *HeapAnalysisResult) {
+	if rb.state.refGraph == nil || !rb.opts.AnalyzeRetainers {
+		return
+	}
+
+	rb.timer.TimeFunc("Dominator tree slice", func() {
+		result.DominatorTree = rb.state.refGraph.GetDominatorTreeSlice(DefaultDominatorSliceDepth, DefaultDominatorSliceChildren)
+	})
+}
+
+// buildThreads resolves thread, stack trace and stack frame records into the thread overview.
+func (rb *ResultBuilder) buildThreads(result *HeapAnalysisResult) {
+	if rb.state.threadStacks == nil {
+		return
+	}
+
+	rb.timer.TimeFunc("Thread overview", func() {
+		result.Threads = rb.state.threadStacks.overview(rb.threadStackResolver())
+	})
+}
+
+// buildAllocationSites resolves the ALLOC_SITES and CPU_SAMPLES records.
+func (rb *ResultBuilder) buildAllocationSites(result *HeapAnalysisResult) {
+	if rb.state.allocSites == nil && rb.state.cpuSamples == nil {
+		return
+	}
+	resolver := rb.threadStackResolver()
+	if rb.state.allocSites != nil {
+		result.AllocationSites = resolveAllocationSites(rb.state.allocSites, rb.state.threadStacks, resolver)
+	}
+	if rb.state.cpuSamples != nil {
+		result.CPUSamples = resolveCPUSamples(rb.state.cpuSamples, rb.state.threadStacks, resolver)
+	}
+}
+
+// threadStackResolver resolves names and sizes from the parsed state.
+func (rb *ResultBuilder) threadStackResolver() threadStackResolver {
+	resolver := threadStackResolver{
+		str: func(id uint64) string {
+			return rb.state.strings[id]
+		},
+		className: func(classID uint64) string {
+			if nameID, ok := rb.state.classNames[classID]; ok {
+				return normalizeClassName(rb.state.strings[nameID])
+			}
+			return ""
+		},
+	}
+	if g := rb.state.refGraph; g != nil {
+		resolver.objectClassName = func(objectID uint64) string {
+			classID, _ := g.GetObjectClassID(objectID)
+			return g.GetClassName(classID)
+		}
+		resolver.shallowSize = g.GetObjectSize
+		if rb.opts.AnalyzeRetainers {
+			resolver.retainedSize = g.GetRetainedSize
+		}
+	}
+	return resolver
+}
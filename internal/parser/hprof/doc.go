@@ -8,31 +8,68 @@
 //   - types.go: Core type definitions (RecordTag, HeapDumpTag, ClassInfo, etc.)
 //   - parser.go: Main HPROF parser implementation
 //   - core_reader.go: Binary data reader for HPROF format
+//   - core_errors.go: Typed errors of malformed dumps (ErrTruncated, ErrBadTag, ErrInconsistentLength, ErrBadHeader)
 //   - core_result_builder.go: Analysis result builder
+//   - core_object_index.go: Object ID to heap dump offset index (objindex.bin)
+//   - core_object_reader.go: On-demand decoding of field values, arrays and Strings from the dump
+//   - core_estimate.go: Object count sampling and parse memory estimation ahead of parsing
+//   - core_layout.go: JVM object layouts (headers, reference size, alignment) for shallow sizes, and their detection
+//   - core_memory.go: Estimated memory of the parser state and reference graph by structure (logged with -v)
 //
 // ## Reference Graph (graph_*.go)
 //   - graph_reference.go: Core ReferenceGraph data structure
 //   - graph_gc_root.go: GC root types and path finding
 //   - graph_indexed.go: High-performance indexed graph (CSR format)
 //   - graph_buffer_pool.go: Memory pools for BFS/DFS traversal
+//   - graph_snapshot.go: Immutable HeapSnapshot for concurrent read-only queries
+//   - graph_scc.go: Strongly connected components and bulk reachability queries
 //
 // ## Dominator Tree (dom_*.go)
 //   - dom_dominator.go: Standard Lengauer-Tarjan dominator algorithm
 //   - dom_hierarchical.go: Hierarchical parallel dominator algorithm
 //   - dom_parallel.go: Parallel computation helpers
+//   - dom_csr.go: Node index capacity checks and int32/int64 CSR offsets
+//   - dom_persist.go: Persisted index-based dominator tree (domtree.bin) with lazy retained sizes
+//   - dom_treemap.go: Depth-limited, aggregated retained-size treemap of the dominator tree
+//   - dom_subtree.go: Resumable depth-first iteration over the dominator subtree of an object, and its class histogram
+//   - dom_incremental.go: Dominator tree update from the dominator tree of a similar dump
 //
 // ## Analysis (analysis_*.go)
+//   - analysis_alloc_sites.go: Allocation sites and CPU samples recorded by the legacy hprof agent
 //   - analysis_biggest_objects.go: Biggest objects analysis (like IDEA's view)
-//   - analysis_retainer.go: Retainer analysis (who holds references)
+//   - analysis_array_histogram.go: Per-class array length histograms
+//   - analysis_humongous.go: G1 humongous objects and their region waste
+//   - analysis_cycles.go: Largest reference cycles (strongly connected components)
+//   - analysis_ownership.go: Package prefix to team ownership of retained memory
+//   - analysis_dominator_tree.go: Dominator tree children and flattened slices
+//   - analysis_heap_diff.go: Class- and object-level comparison of two heap dumps (DiffAnalyzer)
+//   - analysis_class_instances.go: Cursor-paged instances of a class by size
+//   - analysis_class_metadata.go: Class hierarchy, declared fields and static field values
+//   - analysis_motifs.go: Recurring retention patterns (listener lists, identity map keys, caches without eviction)
+//   - analysis_frameworks.go: Framework reports (servlet sessions, Guava/Caffeine caches, Hibernate persistence contexts)
+//   - analysis_netty.go: Netty pooled heap/direct memory by arena kind and leaked ByteBufs
+//   - analysis_kafka.go: Kafka client buffers (producer batches, fetched and polled records) by topic partition
+//   - analysis_oql.go: OQL-style object queries over a heap snapshot (QueryEngine)
+//   - analysis_retainer.go: Retainer analysis (who holds references, and who dominates instances)
+//   - analysis_threads.go: Thread overview with stack frames and stack locals
+//   - analysis_weak_reachability.go: Objects only reachable through soft or weak references
 //   - analysis_retained_calc.go: Retained size calculation strategies
 //   - analysis_retained_debug.go: Retained size debugging/comparison
 //
 // ## Serialization (serial_*.go)
 //   - serial_serializer.go: Protobuf serialization/deserialization
 //   - serial_async.go: Async serialization support
+//   - serial_envelope.go: File envelope (format version, feature flags, writer version)
+//   - serial_chunked.go: Chunked layout with manifest and incremental chunk loading
+//
+// ## Export (export_*.go)
+//   - export_sqlite.go: SQLite export of objects, classes, references and dominators
+//   - export_parquet.go: Parquet export of the object table and class histogram
+//   - export_redact.go: Copy of a heap dump with its primitive array contents zeroed or hashed
 //
 // ## Parallel Processing (parallel_*.go)
 //   - parallel_analyzer.go: Parallel analysis coordinator
+//   - parallel_job.go: Staged analysis jobs with persisted status and stage retry
 //
 // ## Utilities (util_*.go)
 //   - util_bitset.go: Bitset type aliases (-> pkg/collections)
@@ -0,0 +1,107 @@
+// Package hprof provides parsing functionality for Java HPROF heap dump files.
+package hprof
+
+import (
+	"errors"
+	"fmt"
+	"math"
+)
+
+// ============================================================================
+// Index Capacity
+// ============================================================================
+//
+// The dominator algorithms index nodes with int32 to halve the size of their
+// per-node arrays, which limits graphs to math.MaxInt32 nodes, the super
+// root included. Edge counts reach that limit long before node counts do, so
+// the CSR offsets of the hierarchical algorithm switch to int64 when the
+// edges of a graph do not fit int32 indexes.
+
+// ErrGraphTooLarge is returned when a graph has more objects than the
+// dominator algorithms can index.
+var ErrGraphTooLarge = errors.New("graph too large for dominator computation")
+
+// maxDominatorNodes is the number of nodes, super root included, the
+// dominator algorithms can index. It is a variable so that tests can reach
+// it with small graphs.
+var maxDominatorNodes int64 = math.MaxInt32
+
+// csrNarrowLimit is the edge count up to which CSR offsets are stored as
+// int32. It is a variable so that tests can cross it with small graphs.
+var csrNarrowLimit int64 = math.MaxInt32
+
+// checkDominatorCapacity returns ErrGraphTooLarge if objectCount objects and
+// the super root exceed the node indexes of the dominator algorithms.
+func checkDominatorCapacity(objectCount int) error {
+	if int64(objectCount)+1 > maxDominatorNodes {
+		return fmt.Errorf("%w: %d objects, at most %d supported", ErrGraphTooLarge, objectCount, maxDominatorNodes-1)
+	}
+	return nil
+}
+
+// csrOffsets are the offsets of the edges of every node in a CSR target
+// array: the edges of node v are targets[start:end] with start, end =
+// span(v). They are stored as int32 while the edge count fits, and as int64
+// beyond.
+type csrOffsets struct {
+	narrow []int32
+	wide   []int64
+}
+
+// newCSROffsets returns the offsets of nodes with the given edge counts.
+func newCSROffsets(counts []int32) csrOffsets {
+	var total int64
+	for _, count := range counts {
+		total += int64(count)
+	}
+
+	if total <= csrNarrowLimit {
+		narrow := make([]int32, len(counts)+1)
+		for i, count := range counts {
+			narrow[i+1] = narrow[i] + count
+		}
+		return csrOffsets{narrow: narrow}
+	}
+
+	wide := make([]int64, len(counts)+1)
+	for i, count := range counts {
+		wide[i+1] = wide[i] + int64(count)
+	}
+	return csrOffsets{wide: wide}
+}
+
+// span returns the range of the edges of node v in the target array.
+func (o csrOffsets) span(v int32) (int, int) {
+	if o.wide != nil {
+		return int(o.wide[v]), int(o.wide[v+1])
+	}
+	return int(o.narrow[v]), int(o.narrow[v+1])
+}
+
+// total returns the number of edges.
+func (o csrOffsets) total() int {
+	if o.wide != nil {
+		return int(o.wide[len(o.wide)-1])
+	}
+	return int(o.narrow[len(o.narrow)-1])
+}
+
+// isWide reports whether the offsets are stored as int64.
+func (o csrOffsets) isWide() bool {
+	return o.wide != nil
+}
+
+// writePositions returns the start offset of every node, to fill the target
+// array at.
+func (o csrOffsets) writePositions() []int64 {
+	n := len(o.narrow) - 1
+	if o.wide != nil {
+		n = len(o.wide) - 1
+	}
+	pos := make([]int64, n)
+	for v := range pos {
+		start, _ := o.span(int32(v))
+		pos[v] = int64(start)
+	}
+	return pos
+}
@@ -0,0 +1,97 @@
+package hprof
+
+import (
+	"context"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// setCSRNarrowLimit lowers the int32 CSR offset limit for the test.
+func setCSRNarrowLimit(t *testing.T, limit int64) {
+	saved := csrNarrowLimit
+	csrNarrowLimit = limit
+	t.Cleanup(func() { csrNarrowLimit = saved })
+}
+
+func TestCheckDominatorCapacity(t *testing.T) {
+	assert.NoError(t, checkDominatorCapacity(0))
+	assert.NoError(t, checkDominatorCapacity(math.MaxInt32-2))
+	assert.NoError(t, checkDominatorCapacity(math.MaxInt32-1))
+	assert.ErrorIs(t, checkDominatorCapacity(math.MaxInt32), ErrGraphTooLarge)
+	assert.ErrorIs(t, checkDominatorCapacity(math.MaxInt32+1), ErrGraphTooLarge)
+	assert.ErrorIs(t, checkDominatorCapacity(math.MaxInt64-1), ErrGraphTooLarge)
+}
+
+func TestNewCSROffsets_Boundary(t *testing.T) {
+	setCSRNarrowLimit(t, 6)
+
+	for _, tt := range []struct {
+		counts []int32
+		wide   bool
+	}{
+		{counts: nil},
+		{counts: []int32{0, 0}},
+		{counts: []int32{1, 2, 2}},
+		{counts: []int32{1, 2, 3}},
+		{counts: []int32{1, 2, 4}, wide: true},
+		{counts: []int32{0, 7, 0}, wide: true},
+	} {
+		offsets := newCSROffsets(tt.counts)
+		assert.Equal(t, tt.wide, offsets.isWide(), "counts %v", tt.counts)
+
+		pos := offsets.writePositions()
+		require.Len(t, pos, len(tt.counts))
+		want := 0
+		for v, count := range tt.counts {
+			start, end := offsets.span(int32(v))
+			assert.Equal(t, want, start, "start of %d in %v", v, tt.counts)
+			assert.Equal(t, want+int(count), end, "end of %d in %v", v, tt.counts)
+			assert.Equal(t, int64(start), pos[v])
+			want = end
+		}
+		assert.Equal(t, want, offsets.total())
+	}
+}
+
+func TestNewCSROffsets_Int32Boundary(t *testing.T) {
+	// Offsets up to math.MaxInt32 stay int32, one more edge switches to int64
+	counts := []int32{math.MaxInt32 - 1, 1}
+	offsets := newCSROffsets(counts)
+	assert.False(t, offsets.isWide())
+	assert.Equal(t, math.MaxInt32, offsets.total())
+
+	counts[1] = 2
+	offsets = newCSROffsets(counts)
+	assert.True(t, offsets.isWide())
+	start, end := offsets.span(1)
+	assert.Equal(t, math.MaxInt32-1, start)
+	assert.Equal(t, math.MaxInt32+1, end)
+}
+
+func TestHierarchicalDominators_WideOffsets(t *testing.T) {
+	// Large enough for the parallel graph build on multi-core machines
+	const n = 100_001
+	want := newRandomGraph(n)
+	require.NoError(t, want.computeLengauerTarjan(context.Background()))
+
+	setCSRNarrowLimit(t, 0)
+	state := NewLevelDominatorState(n+1, DefaultHierarchicalDominatorConfig())
+	state.BuildFromReferenceGraph(newRandomGraph(n))
+	assert.True(t, state.successorOffsets.isWide())
+	assert.True(t, state.predecessorOffsets.isWide())
+	require.NoError(t, state.Close())
+
+	for _, locality := range []bool{false, true} {
+		config := DefaultHierarchicalDominatorConfig()
+		config.LocalityOrder = locality
+		got := newRandomGraph(n)
+		require.NoError(t, ComputeHierarchicalDominators(context.Background(), got, config))
+		for id := uint64(1); id <= n; id++ {
+			require.Equal(t, want.dominators[id], got.dominators[id], "dominator of %d", id)
+			require.Equal(t, want.retainedSizes[id], got.retainedSizes[id], "retained size of %d", id)
+		}
+	}
+}
@@ -0,0 +1,182 @@
+package hprof
+
+import (
+	"bytes"
+	"context"
+	"math/rand"
+	"os"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/perf-analysis/pkg/utils"
+)
+
+// newDenseRandomGraph returns newRandomGraph(n) with refs extra references
+// from every object to random objects.
+func newDenseRandomGraph(n, refs int) *ReferenceGraph {
+	g := newRandomGraph(n)
+	r := rand.New(rand.NewSource(2))
+	for i := 1; i <= n; i++ {
+		for j := 0; j < refs; j++ {
+			g.AddReference(ObjectReference{FromObjectID: uint64(i), ToObjectID: uint64(r.Intn(n) + 1), FromClassID: 1})
+		}
+	}
+	return g
+}
+
+func TestSemiNCA_SameDominators(t *testing.T) {
+	graphs := map[string]func() *ReferenceGraph{
+		"tree":  func() *ReferenceGraph { return newRandomGraph(20000) },
+		"dense": func() *ReferenceGraph { return newDenseRandomGraph(20000, 4) },
+	}
+	for name, newGraph := range graphs {
+		t.Run(name, func(t *testing.T) {
+			want := newGraph()
+			require.NoError(t, want.computeLengauerTarjan(context.Background()))
+
+			got := newGraph()
+			require.NoError(t, got.computeSemiNCA(context.Background()))
+
+			for id := range want.objectClass {
+				require.Equal(t, want.dominators[id], got.dominators[id], "dominator of %d", id)
+				require.Equal(t, want.retainedSizes[id], got.retainedSizes[id], "retained size of %d", id)
+			}
+		})
+	}
+}
+
+func TestComputeDominatorTree_ForcedAlgorithm(t *testing.T) {
+	// root -> a -> b, root -> c -> b: a and c share b, so the root retains it
+	g := NewReferenceGraph()
+	g.SetClassName(1, "com.example.Node")
+	for id := uint64(1); id <= 4; id++ {
+		g.SetObjectInfo(id, 1, 16)
+	}
+	g.AddReference(ObjectReference{FromObjectID: 1, ToObjectID: 2, FromClassID: 1})
+	g.AddReference(ObjectReference{FromObjectID: 2, ToObjectID: 3, FromClassID: 1})
+	g.AddReference(ObjectReference{FromObjectID: 1, ToObjectID: 4, FromClassID: 1})
+	g.AddReference(ObjectReference{FromObjectID: 4, ToObjectID: 3, FromClassID: 1})
+	g.AddGCRoot(&GCRoot{ObjectID: 1, Type: GCRootJNIGlobal})
+	g.SetDominatorAlgorithm(DominatorAlgorithmSemiNCA)
+
+	require.NoError(t, g.ComputeDominatorTreeContext(context.Background()))
+	assert.Equal(t, uint64(1), g.dominators[2])
+	assert.Equal(t, uint64(1), g.dominators[3])
+	assert.Equal(t, uint64(1), g.dominators[4])
+	assert.Equal(t, int64(64), g.GetRetainedSize(1))
+	assert.Equal(t, int64(16), g.GetRetainedSize(2))
+}
+
+func TestComputeDominatorTree_AllObjectsReachable(t *testing.T) {
+	// root -> a -> b: with the super root, the DFS numbers every node, so
+	// the last DFS number is the number of nodes
+	g := NewReferenceGraph()
+	g.SetClassName(1, "com.example.Node")
+	for id := uint64(1); id <= 3; id++ {
+		g.SetObjectInfo(id, 1, 16)
+	}
+	g.AddReference(ObjectReference{FromObjectID: 1, ToObjectID: 2, FromClassID: 1})
+	g.AddReference(ObjectReference{FromObjectID: 2, ToObjectID: 3, FromClassID: 1})
+	g.AddGCRoot(&GCRoot{ObjectID: 1, Type: GCRootJNIGlobal})
+
+	g.ComputeDominatorTree()
+	assert.Equal(t, uint64(1), g.dominators[2])
+	assert.Equal(t, uint64(2), g.dominators[3])
+	assert.Equal(t, int64(48), g.GetRetainedSize(1))
+	assert.Equal(t, int64(16), g.GetRetainedSize(3))
+}
+
+func TestComputeDominatorTree_GraphTooLarge(t *testing.T) {
+	newGraph := func() *ReferenceGraph {
+		g := NewReferenceGraph()
+		g.SetClassName(1, "com.example.Node")
+		for id := uint64(1); id <= 3; id++ {
+			g.SetObjectInfo(id, 1, 16)
+		}
+		g.AddReference(ObjectReference{FromObjectID: 1, ToObjectID: 2, FromClassID: 1})
+		g.AddReference(ObjectReference{FromObjectID: 2, ToObjectID: 3, FromClassID: 1})
+		g.AddGCRoot(&GCRoot{ObjectID: 1, Type: GCRootJNIGlobal})
+		return g
+	}
+	defer func(limit int64) { maxDominatorNodes = limit }(maxDominatorNodes)
+	maxDominatorNodes = 3
+
+	g := newGraph()
+	err := g.ComputeDominatorTreeWithConfig(DefaultHierarchicalDominatorConfig())
+	require.ErrorIs(t, err, ErrGraphTooLarge)
+	assert.False(t, g.dominatorComputed)
+	assert.Empty(t, g.dominators)
+
+	// Without an error to return, the failure is logged
+	var log bytes.Buffer
+	g = newGraph()
+	g.SetLogger(utils.NewDefaultLogger(utils.LevelWarn, &log))
+	g.ComputeDominatorTree()
+	assert.False(t, g.dominatorComputed)
+	assert.Contains(t, log.String(), ErrGraphTooLarge.Error())
+
+	maxDominatorNodes = 4
+	g = newGraph()
+	require.NoError(t, g.ComputeDominatorTreeWithConfig(DefaultHierarchicalDominatorConfig()))
+	assert.Equal(t, uint64(2), g.dominators[3])
+	assert.Equal(t, int64(48), g.GetRetainedSize(1))
+}
+
+// BenchmarkDominatorAlgorithms compares the dominator algorithms on random
+// graphs of HPROF_BENCH_OBJECTS objects (default 1M), or on the heap dump at
+// HPROF_BENCH_DUMP.
+func BenchmarkDominatorAlgorithms(b *testing.B) {
+	graphs := map[string]func() *ReferenceGraph{}
+	if path := os.Getenv("HPROF_BENCH_DUMP"); path != "" {
+		graphs["dump"] = func() *ReferenceGraph { return parseBenchDump(b, path) }
+	} else {
+		n := 1_000_000
+		if v, err := strconv.Atoi(os.Getenv("HPROF_BENCH_OBJECTS")); err == nil && v > 0 {
+			n = v
+		}
+		graphs["tree"] = func() *ReferenceGraph { return newRandomGraph(n) }
+		graphs["dense"] = func() *ReferenceGraph { return newDenseRandomGraph(n, 4) }
+	}
+
+	algorithms := map[string]func(g *ReferenceGraph) error{
+		"lengauer-tarjan": func(g *ReferenceGraph) error { return g.computeLengauerTarjan(context.Background()) },
+		"semi-nca":        func(g *ReferenceGraph) error { return g.computeSemiNCA(context.Background()) },
+		"hierarchical": func(g *ReferenceGraph) error {
+			return ComputeHierarchicalDominators(context.Background(), g, DefaultHierarchicalDominatorConfig())
+		},
+	}
+
+	for graphName, newGraph := range graphs {
+		for name, compute := range algorithms {
+			b.Run(graphName+"/"+name, func(b *testing.B) {
+				for i := 0; i < b.N; i++ {
+					b.StopTimer()
+					g := newGraph()
+					b.StartTimer()
+					if err := compute(g); err != nil {
+						b.Fatal(err)
+					}
+				}
+			})
+		}
+	}
+}
+
+// parseBenchDump parses the reference graph of the heap dump at path.
+func parseBenchDump(b *testing.B, path string) *ReferenceGraph {
+	f, err := os.Open(path)
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer f.Close()
+
+	p := NewParser(DefaultParserOptions())
+	state, err := p.parseState(context.Background(), f, utils.NewTimer("bench", utils.WithEnabled(false)))
+	if err != nil {
+		b.Fatal(err)
+	}
+	return state.refGraph
+}
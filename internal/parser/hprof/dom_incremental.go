@@ -0,0 +1,307 @@
+// Package hprof provides parsing functionality for Java HPROF heap dump files.
+package hprof
+
+import (
+	"context"
+)
+
+// ============================================================================
+// Incremental Dominator Update
+// ============================================================================
+//
+// Two dumps of the same process taken a short time apart share most of their
+// objects. Objects are matched by ID, class name and shallow size; the
+// objects that changed, together with the targets of added or removed
+// references and the GC roots that appeared or disappeared, seed the
+// affected region: everything reachable from them in either dump. Objects
+// outside the region are reached by exactly the same paths in both dumps, so
+// they keep their baseline dominator. The region is closed under references,
+// so every path into it goes through unchanged objects first: the dominators
+// of the region are computed on the region alone, entered through the
+// baseline dominator tree of the unchanged objects referencing it, which
+// preserves dominance.
+//
+// Object IDs are addresses, so objects moved by a GC between the dumps do
+// not match; after a full GC the region covers most of the heap and the
+// update falls back to computing dominators from scratch.
+
+// incrementalMaxAffected is the fraction of objects above which the affected
+// region is too large for an incremental update to pay off.
+const incrementalMaxAffected = 0.5
+
+// DominatorUpdate describes how the dominators of a graph were derived from
+// a baseline graph.
+type DominatorUpdate struct {
+	// MatchedObjects have the same ID, class and shallow size in both graphs
+	MatchedObjects int `json:"matched_objects"`
+	// NewObjects are only in the target graph, RemovedObjects only in the baseline
+	NewObjects     int `json:"new_objects"`
+	RemovedObjects int `json:"removed_objects"`
+	// ReusedObjects kept their baseline dominator
+	ReusedObjects int `json:"reused_objects"`
+	// RecomputedObjects are in the affected region and had their dominator recomputed
+	RecomputedObjects int `json:"recomputed_objects"`
+	// FullRecompute is set when the affected region was too large and the
+	// dominators were computed from scratch
+	FullRecompute bool `json:"full_recompute"`
+}
+
+// ComputeDominatorTreeFrom computes the dominator tree of g reusing the
+// dominator tree of base, a dump of the same process, for the objects the
+// changes between the dumps cannot affect. base's dominators are computed
+// first if necessary. Retained sizes are recomputed for all objects.
+func (g *ReferenceGraph) ComputeDominatorTreeFrom(ctx context.Context, base *ReferenceGraph) (*DominatorUpdate, error) {
+	if err := base.ComputeDominatorTreeContext(ctx); err != nil {
+		return nil, err
+	}
+
+	update := &DominatorUpdate{}
+	affected, err := g.affectedRegion(ctx, base, update)
+	if err != nil {
+		return nil, err
+	}
+
+	if float64(len(affected)) > incrementalMaxAffected*float64(len(g.objectClass)) {
+		g.debugf("Affected region covers %d of %d objects, computing dominators from scratch", len(affected), len(g.objectClass))
+		update.FullRecompute = true
+		update.RecomputedObjects = len(g.objectClass)
+		g.dominatorComputed = false
+		return update, g.ComputeDominatorTreeContext(ctx)
+	}
+
+	reachable := g.reachableFrom(g.dominatorRoots())
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	region, err := g.affectedSubgraph(ctx, base, affected, reachable)
+	if err != nil {
+		return nil, err
+	}
+	if err := region.computeLengauerTarjan(ctx); err != nil {
+		return nil, err
+	}
+
+	g.dominators = make(map[uint64]uint64, len(g.objectClass))
+	for objID := range g.objectClass {
+		if affected[objID] {
+			g.dominators[objID] = region.dominators[objID]
+			update.RecomputedObjects++
+		} else if domID, ok := base.dominators[objID]; ok {
+			g.dominators[objID] = domID
+			update.ReusedObjects++
+		} else {
+			g.dominators[objID] = superRootID
+			update.ReusedObjects++
+		}
+	}
+	g.reachableObjects = reachable
+	g.debugf("Incremental dominators: %d reused, %d recomputed", update.ReusedObjects, update.RecomputedObjects)
+
+	g.computeRetainedSizes()
+	g.dominatorComputed = true
+	retainedSizeEstimated = false
+	return update, nil
+}
+
+// affectedRegion returns the objects of g whose dominator may differ from
+// their dominator in base, counting matched, new and removed objects.
+func (g *ReferenceGraph) affectedRegion(ctx context.Context, base *ReferenceGraph, update *DominatorUpdate) (map[uint64]bool, error) {
+	// seeds holds object IDs of either graph whose references into the
+	// graph changed: changed objects, targets of changed references and
+	// changed roots
+	seeds := make(map[uint64]bool)
+
+	i := 0
+	for objID, classID := range g.objectClass {
+		i++
+		if canceled(ctx, i) {
+			return nil, ctx.Err()
+		}
+		baseClassID, ok := base.objectClass[objID]
+		if !ok || base.objectSize[objID] != g.objectSize[objID] ||
+			base.GetClassName(baseClassID) != g.GetClassName(classID) {
+			seeds[objID] = true
+			update.NewObjects++
+			continue
+		}
+		update.MatchedObjects++
+		diffReferenceTargets(g.outgoingRefs[objID], base.outgoingRefs[objID], seeds)
+	}
+	update.RemovedObjects = len(base.objectClass) - update.MatchedObjects
+	for objID := range base.objectClass {
+		if _, ok := g.objectClass[objID]; !ok {
+			seeds[objID] = true
+		}
+	}
+
+	roots, baseRoots := g.dominatorRoots(), base.dominatorRoots()
+	for objID := range roots {
+		if !baseRoots[objID] {
+			seeds[objID] = true
+		}
+	}
+	for objID := range baseRoots {
+		if !roots[objID] {
+			seeds[objID] = true
+		}
+	}
+
+	// Everything reachable from the seeds in base, then closed under the
+	// references of g
+	affected := make(map[uint64]bool)
+	var queue []uint64
+	for objID := range base.reachableFrom(seeds) {
+		if _, ok := g.objectClass[objID]; ok {
+			affected[objID] = true
+			queue = append(queue, objID)
+		}
+	}
+	for objID := range seeds {
+		if _, ok := g.objectClass[objID]; ok && !affected[objID] {
+			affected[objID] = true
+			queue = append(queue, objID)
+		}
+	}
+	for head := 0; head < len(queue); head++ {
+		if canceled(ctx, head+1) {
+			return nil, ctx.Err()
+		}
+		for _, ref := range g.outgoingRefs[queue[head]] {
+			if _, ok := g.objectClass[ref.ToObjectID]; ok && !affected[ref.ToObjectID] {
+				affected[ref.ToObjectID] = true
+				queue = append(queue, ref.ToObjectID)
+			}
+		}
+	}
+	return affected, nil
+}
+
+// diffReferenceTargets adds to seeds the targets referenced by only one of
+// the reference lists of an object.
+func diffReferenceTargets(refs, baseRefs []ObjectReference, seeds map[uint64]bool) {
+	if len(refs) == len(baseRefs) {
+		same := true
+		for i := range refs {
+			if refs[i].ToObjectID != baseRefs[i].ToObjectID {
+				same = false
+				break
+			}
+		}
+		if same {
+			return
+		}
+	}
+
+	targets := make(map[uint64]bool, len(refs))
+	for _, ref := range refs {
+		targets[ref.ToObjectID] = true
+	}
+	baseTargets := make(map[uint64]bool, len(baseRefs))
+	for _, ref := range baseRefs {
+		baseTargets[ref.ToObjectID] = true
+		if !targets[ref.ToObjectID] {
+			seeds[ref.ToObjectID] = true
+		}
+	}
+	for target := range targets {
+		if !baseTargets[target] {
+			seeds[target] = true
+		}
+	}
+}
+
+// dominatorRoots returns the objects the dominator computation starts from:
+// the GC roots and the Class objects.
+func (g *ReferenceGraph) dominatorRoots() map[uint64]bool {
+	roots := make(map[uint64]bool, len(g.gcRoots)+len(g.classObjectIDs))
+	for _, root := range g.gcRoots {
+		if _, ok := g.objectClass[root.ObjectID]; ok {
+			roots[root.ObjectID] = true
+		}
+	}
+	for objID := range g.classObjectIDs {
+		if _, ok := g.objectClass[objID]; ok {
+			roots[objID] = true
+		}
+	}
+	return roots
+}
+
+// reachableFrom returns the objects reachable from the given objects, which
+// are included when they are objects of g.
+func (g *ReferenceGraph) reachableFrom(start map[uint64]bool) map[uint64]bool {
+	reached := make(map[uint64]bool, len(start))
+	queue := make([]uint64, 0, len(start))
+	for objID := range start {
+		if _, ok := g.objectClass[objID]; ok {
+			reached[objID] = true
+			queue = append(queue, objID)
+		}
+	}
+	for head := 0; head < len(queue); head++ {
+		for _, ref := range g.outgoingRefs[queue[head]] {
+			if _, ok := g.objectClass[ref.ToObjectID]; ok && !reached[ref.ToObjectID] {
+				reached[ref.ToObjectID] = true
+				queue = append(queue, ref.ToObjectID)
+			}
+		}
+	}
+	return reached
+}
+
+// affectedSubgraph returns the graph the dominators of the affected objects
+// are computed on: the affected objects and their references, entered
+// through the reachable unchanged objects referencing them, which are linked
+// to the super root along their dominator chain in base.
+func (g *ReferenceGraph) affectedSubgraph(ctx context.Context, base *ReferenceGraph, affected, reachable map[uint64]bool) (*ReferenceGraph, error) {
+	region := NewReferenceGraphWithCapacity(len(affected))
+	region.SetMaxWorkers(g.maxWorkers)
+	region.SetLogger(g.logger)
+
+	include := func(objID uint64) bool {
+		if _, ok := region.objectClass[objID]; ok {
+			return false
+		}
+		region.SetObjectInfo(objID, g.objectClass[objID], g.objectSize[objID])
+		return true
+	}
+
+	roots := g.dominatorRoots()
+	i := 0
+	for objID := range affected {
+		i++
+		if canceled(ctx, i) {
+			return nil, ctx.Err()
+		}
+		include(objID)
+		if roots[objID] {
+			region.AddGCRoot(&GCRoot{ObjectID: objID, Type: GCRootUnknown})
+		}
+		for _, ref := range g.outgoingRefs[objID] {
+			if affected[ref.ToObjectID] {
+				region.AddReference(ObjectReference{FromObjectID: objID, ToObjectID: ref.ToObjectID})
+			}
+		}
+
+		for _, ref := range g.incomingRefs[objID] {
+			from := ref.FromObjectID
+			if affected[from] || !reachable[from] {
+				continue
+			}
+			region.AddReference(ObjectReference{FromObjectID: from, ToObjectID: objID})
+
+			// Link the unchanged referrer to the super root along its dominators
+			for id := from; include(id); {
+				domID, ok := base.dominators[id]
+				if !ok || domID == superRootID {
+					region.AddGCRoot(&GCRoot{ObjectID: id, Type: GCRootUnknown})
+					break
+				}
+				region.AddReference(ObjectReference{FromObjectID: domID, ToObjectID: id})
+				id = domID
+			}
+		}
+	}
+	return region, nil
+}
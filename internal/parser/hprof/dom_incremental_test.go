@@ -0,0 +1,62 @@
+package hprof
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestComputeDominatorTreeFrom(t *testing.T) {
+	const n = 20000
+	mutate := func(g *ReferenceGraph) {
+		// A new object, a resized object and a new reference near the leaves
+		g.SetObjectInfo(n+1, 1, 4096)
+		g.AddReference(ObjectReference{FromObjectID: n - 5, ToObjectID: n + 1, FromClassID: 1})
+		g.SetObjectInfo(n-3, 1, 999)
+		g.AddReference(ObjectReference{FromObjectID: n - 10, ToObjectID: n - 20, FromClassID: 1})
+	}
+
+	base := newRandomGraph(n)
+	require.NoError(t, base.ComputeDominatorTreeContext(context.Background()))
+
+	want := newRandomGraph(n)
+	mutate(want)
+	require.NoError(t, want.ComputeDominatorTreeContext(context.Background()))
+
+	got := newRandomGraph(n)
+	mutate(got)
+	update, err := got.ComputeDominatorTreeFrom(context.Background(), base)
+	require.NoError(t, err)
+
+	assert.False(t, update.FullRecompute)
+	assert.Equal(t, n-1, update.MatchedObjects)
+	assert.Equal(t, 2, update.NewObjects)
+	assert.Equal(t, 1, update.RemovedObjects)
+	assert.Equal(t, n+1, update.ReusedObjects+update.RecomputedObjects)
+	assert.Greater(t, update.ReusedObjects, update.RecomputedObjects)
+
+	for id := range want.objectClass {
+		require.Equal(t, want.dominators[id], got.dominators[id], "dominator of %d", id)
+		require.Equal(t, want.retainedSizes[id], got.retainedSizes[id], "retained size of %d", id)
+		require.Equal(t, want.reachableObjects[id], got.reachableObjects[id], "reachability of %d", id)
+	}
+}
+
+func TestComputeDominatorTreeFrom_FullRecompute(t *testing.T) {
+	base := newRandomGraph(1000)
+
+	// A new root above the whole heap affects every object
+	target := newRandomGraph(1000)
+	target.SetObjectInfo(5000, 1, 16)
+	target.AddReference(ObjectReference{FromObjectID: 5000, ToObjectID: 1, FromClassID: 1})
+	target.gcRoots = nil
+	target.AddGCRoot(&GCRoot{ObjectID: 5000, Type: GCRootJNIGlobal})
+
+	update, err := target.ComputeDominatorTreeFrom(context.Background(), base)
+	require.NoError(t, err)
+	assert.True(t, update.FullRecompute)
+	assert.Equal(t, uint64(5000), target.dominators[1])
+	assert.Equal(t, target.GetRetainedSize(1)+16, target.GetRetainedSize(5000))
+}
@@ -0,0 +1,88 @@
+package hprof
+
+import (
+	"context"
+	"math/rand"
+	"os"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newRandomGraph returns a graph of n objects forming a random tree from a
+// GC root, with an extra reference to every third object.
+func newRandomGraph(n int) *ReferenceGraph {
+	r := rand.New(rand.NewSource(1))
+	g := NewReferenceGraphWithCapacity(n)
+	g.SetClassName(1, "com.example.Node")
+	for i := 1; i <= n; i++ {
+		g.SetObjectInfo(uint64(i), 1, 16+int64(i%8)*8)
+	}
+	for i := 2; i <= n; i++ {
+		g.AddReference(ObjectReference{FromObjectID: uint64(r.Intn(i-1) + 1), ToObjectID: uint64(i), FromClassID: 1})
+		if i%3 == 0 {
+			g.AddReference(ObjectReference{FromObjectID: uint64(r.Intn(n) + 1), ToObjectID: uint64(i), FromClassID: 1})
+		}
+	}
+	g.AddGCRoot(&GCRoot{ObjectID: 1, Type: GCRootJNIGlobal})
+	return g
+}
+
+func TestBFSOrder(t *testing.T) {
+	// 0 -> 2 -> 1, 3 unreachable
+	successors := [][]int32{{2}, {}, {1}, {0}}
+	order := bfsOrder(4, func(v int32) []int32 { return successors[v] })
+	assert.Equal(t, []int32{0, 2, 1, 3}, order)
+
+	offsets, targets := renumberCSR(newCSROffsets([]int32{1, 0, 1, 1}), []int32{2, 1, 0}, order)
+	assert.Equal(t, []int32{0, 1, 2, 2, 3}, offsets.narrow)
+	assert.Equal(t, []int32{1, 2, 0}, targets)
+}
+
+func TestLocalityOrder_SameDominators(t *testing.T) {
+	const n = 20000
+	want := newRandomGraph(n)
+	require.NoError(t, want.computeLengauerTarjan(context.Background()))
+
+	lt := newRandomGraph(n)
+	lt.SetLocalityOrder(true)
+	require.NoError(t, lt.computeLengauerTarjan(context.Background()))
+
+	config := DefaultHierarchicalDominatorConfig()
+	config.LocalityOrder = true
+	hierarchical := newRandomGraph(n)
+	require.NoError(t, ComputeHierarchicalDominators(context.Background(), hierarchical, config))
+
+	for id := uint64(1); id <= n; id++ {
+		require.Equal(t, want.dominators[id], lt.dominators[id], "dominator of %d", id)
+		require.Equal(t, want.retainedSizes[id], lt.retainedSizes[id], "retained size of %d", id)
+		require.Equal(t, want.dominators[id], hierarchical.dominators[id], "dominator of %d", id)
+		require.Equal(t, want.retainedSizes[id], hierarchical.retainedSizes[id], "retained size of %d", id)
+	}
+}
+
+// BenchmarkDominatorLocalityOrder compares the dominator computation with and
+// without locality ordering. Set HPROF_BENCH_OBJECTS to benchmark larger
+// graphs, e.g. 10000000.
+func BenchmarkDominatorLocalityOrder(b *testing.B) {
+	n := 1_000_000
+	if v, err := strconv.Atoi(os.Getenv("HPROF_BENCH_OBJECTS")); err == nil && v > 0 {
+		n = v
+	}
+
+	for _, locality := range []bool{false, true} {
+		b.Run("locality="+strconv.FormatBool(locality), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				b.StopTimer()
+				g := newRandomGraph(n)
+				g.SetLocalityOrder(locality)
+				b.StartTimer()
+				if err := g.computeLengauerTarjan(context.Background()); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
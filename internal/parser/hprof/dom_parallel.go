@@ -5,6 +5,8 @@ import (
 	"context"
 	"sync"
 	"sync/atomic"
+
+	"github.com/perf-analysis/pkg/parallel"
 )
 
 // ============================================================================
@@ -464,13 +466,15 @@ func (m *ConcurrentMap[K, V]) Update(key K, fn func(existing V, exists bool) V)
 // Parallel Predecessors Building
 // ============================================================================
 
-// buildPredecessorsParallel builds the predecessors list in parallel.
+// buildPredecessorsParallel builds the predecessors list in parallel on up to
+// maxWorkers workers of the shared pool (0 for no cap).
 // This is a two-phase algorithm:
 // Phase 1: Count predecessors for each node in parallel
 // Phase 2: Populate predecessors using CSR format with atomic write positions
-func buildPredecessorsParallel(successors [][]int32, totalNodes int) [][]int32 {
-	config := DefaultPoolConfig()
-	numWorkers := config.MaxWorkers
+func buildPredecessorsParallel(successors [][]int32, totalNodes int, maxWorkers int) [][]int32 {
+	ctx := context.Background()
+	pool := parallel.Shared()
+	numWorkers := pool.Workers(maxWorkers)
 
 	// For small graphs, use sequential processing
 	if totalNodes < 50000 || numWorkers == 1 {
@@ -478,41 +482,23 @@ func buildPredecessorsParallel(successors [][]int32, totalNodes int) [][]int32 {
 	}
 
 	// Phase 1: Count predecessors in parallel
-	// Each worker counts predecessors for a chunk of successors
+	// Each worker counts predecessors for chunks of source nodes
 	predCounts := make([]int32, totalNodes)
 
-	// Divide work by source nodes
-	chunkSize := (totalNodes + numWorkers - 1) / numWorkers
-
-	var wg sync.WaitGroup
 	// Use per-worker local counts to avoid atomic operations
 	workerCounts := make([][]int32, numWorkers)
 	for w := 0; w < numWorkers; w++ {
 		workerCounts[w] = make([]int32, totalNodes)
 	}
 
-	for w := 0; w < numWorkers; w++ {
-		start := w * chunkSize
-		end := start + chunkSize
-		if end > totalNodes {
-			end = totalNodes
-		}
-		if start >= end {
-			continue
-		}
-
-		wg.Add(1)
-		go func(workerID, start, end int) {
-			defer wg.Done()
-			localCounts := workerCounts[workerID]
-			for v := start; v < end; v++ {
-				for _, w := range successors[v] {
-					localCounts[w]++
-				}
+	pool.Range(ctx, totalNodes, numWorkers, func(workerID, start, end int) {
+		localCounts := workerCounts[workerID]
+		for v := start; v < end; v++ {
+			for _, w := range successors[v] {
+				localCounts[w]++
 			}
-		}(w, start, end)
-	}
-	wg.Wait()
+		}
+	})
 
 	// Merge worker counts (can be parallelized for very large graphs)
 	for w := 0; w < numWorkers; w++ {
@@ -540,28 +526,14 @@ func buildPredecessorsParallel(successors [][]int32, totalNodes int) [][]int32 {
 	}
 
 	// Parallel populate using atomic write positions
-	for w := 0; w < numWorkers; w++ {
-		start := w * chunkSize
-		end := start + chunkSize
-		if end > totalNodes {
-			end = totalNodes
-		}
-		if start >= end {
-			continue
-		}
-
-		wg.Add(1)
-		go func(start, end int) {
-			defer wg.Done()
-			for v := start; v < end; v++ {
-				for _, target := range successors[v] {
-					pos := writePos[target].Add(1) - 1
-					flatPreds[pos] = int32(v)
-				}
+	pool.Range(ctx, totalNodes, numWorkers, func(_, start, end int) {
+		for v := start; v < end; v++ {
+			for _, target := range successors[v] {
+				pos := writePos[target].Add(1) - 1
+				flatPreds[pos] = int32(v)
 			}
-		}(start, end)
-	}
-	wg.Wait()
+		}
+	})
 
 	// Convert flat array to slice-of-slices (zero-copy using subslices)
 	predecessors := make([][]int32, totalNodes)
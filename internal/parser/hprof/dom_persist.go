@@ -0,0 +1,422 @@
+// Package hprof provides parsing functionality for Java HPROF heap dump files.
+package hprof
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+
+	pb "github.com/perf-analysis/internal/parser/hprof/proto"
+	"github.com/perf-analysis/pkg/collections"
+	"github.com/perf-analysis/pkg/compression"
+	"google.golang.org/protobuf/proto"
+)
+
+// ============================================================================
+// Persisted Dominator Tree
+// ============================================================================
+//
+// File layout of domtree.bin:
+//
+//	Magic(4) "DOMT" | Version(1) | Compression(1) | compressed DominatorTreeProto
+//
+// The tree is stored as an idom array over reachable objects sorted by ID, so
+// reloading it needs neither the reference graph nor a dominator computation.
+// Retained sizes, depths and child lists are rebuilt lazily from the idom and
+// shallow size arrays on first use.
+
+const (
+	// DominatorTreeFileName is the name of the dominator tree file in the task directory.
+	DominatorTreeFileName = "domtree.bin"
+
+	// DominatorTreeMagic identifies domtree.bin files.
+	DominatorTreeMagic = "DOMT"
+
+	// DominatorTreeVersion is the current domtree.bin format version.
+	DominatorTreeVersion = 1
+
+	// noDominator marks nodes immediately dominated by the virtual root.
+	noDominator int32 = -1
+)
+
+// DominatorTree is a compact, index-based dominator tree of the reachable objects.
+// It is immutable after construction and safe for concurrent use.
+type DominatorTree struct {
+	objectIDs  []uint64 // sorted; position = node index
+	idom       []int32
+	shallow    []int64
+	classIDs   []uint64
+	classNames map[uint64]string
+
+	// Derived lazily
+	derivedOnce   sync.Once
+	retained      []int64
+	depth         []int32
+	childOffsets  []int32 // children of node i: childIndex[childOffsets[i]:childOffsets[i+1]]
+	childIndex    []int32
+	roots         []int32
+	totalRetained int64
+}
+
+// NewDominatorTree builds the persisted form of g's dominator tree,
+// computing dominators first if necessary.
+func NewDominatorTree(g *ReferenceGraph) *DominatorTree {
+	g.ComputeDominatorTree()
+
+	objectIDs := make([]uint64, 0, len(g.dominators))
+	for objID, domID := range g.dominators {
+		if domID != 0 {
+			objectIDs = append(objectIDs, objID)
+		}
+	}
+	sort.Slice(objectIDs, func(i, j int) bool { return objectIDs[i] < objectIDs[j] })
+
+	t := &DominatorTree{
+		objectIDs:  objectIDs,
+		idom:       make([]int32, len(objectIDs)),
+		shallow:    make([]int64, len(objectIDs)),
+		classIDs:   make([]uint64, len(objectIDs)),
+		classNames: make(map[uint64]string),
+	}
+	for i, objID := range objectIDs {
+		t.idom[i] = t.index(g.dominators[objID])
+		t.shallow[i] = g.objectSize[objID]
+		classID := g.objectClass[objID]
+		t.classIDs[i] = classID
+		if _, ok := t.classNames[classID]; !ok {
+			t.classNames[classID] = g.GetClassName(classID)
+		}
+	}
+	return t
+}
+
+// index returns the node index of an object, or noDominator if it is not in the tree.
+func (t *DominatorTree) index(objectID uint64) int32 {
+	i := sort.Search(len(t.objectIDs), func(i int) bool { return t.objectIDs[i] >= objectID })
+	if i < len(t.objectIDs) && t.objectIDs[i] == objectID {
+		return int32(i)
+	}
+	return noDominator
+}
+
+// Len returns the number of objects in the tree.
+func (t *DominatorTree) Len() int {
+	return len(t.objectIDs)
+}
+
+// Contains reports whether an object is in the tree (i.e. reachable).
+func (t *DominatorTree) Contains(objectID uint64) bool {
+	return t.index(objectID) >= 0
+}
+
+// Dominator returns the immediate dominator of an object.
+// Objects dominated only by the virtual root return (0, true).
+func (t *DominatorTree) Dominator(objectID uint64) (uint64, bool) {
+	idx := t.index(objectID)
+	if idx < 0 {
+		return 0, false
+	}
+	if dom := t.idom[idx]; dom >= 0 {
+		return t.objectIDs[dom], true
+	}
+	return 0, true
+}
+
+// RetainedSize returns the retained size of an object, or 0 if it is not in the tree.
+func (t *DominatorTree) RetainedSize(objectID uint64) int64 {
+	idx := t.index(objectID)
+	if idx < 0 {
+		return 0
+	}
+	t.derive()
+	return t.retained[idx]
+}
+
+// TotalRetainedSize returns the retained size of all reachable objects.
+func (t *DominatorTree) TotalRetainedSize() int64 {
+	t.derive()
+	return t.totalRetained
+}
+
+// Roots returns the objects immediately dominated by the virtual root,
+// sorted by retained size descending.
+func (t *DominatorTree) Roots(limit int) []*DominatorTreeNode {
+	t.derive()
+	return t.nodes(t.roots, noDominator, limit)
+}
+
+// Children returns the objects immediately dominated by objectID,
+// sorted by retained size descending. Returns nil if the object is not in the tree.
+func (t *DominatorTree) Children(objectID uint64, limit int) []*DominatorTreeNode {
+	idx := t.index(objectID)
+	if idx < 0 {
+		return nil
+	}
+	t.derive()
+	return t.nodes(t.childIndex[t.childOffsets[idx]:t.childOffsets[idx+1]], idx, limit)
+}
+
+// Chain returns the dominator chain of an object, from its top-level dominator
+// down to the object itself. Returns nil if the object is not in the tree.
+func (t *DominatorTree) Chain(objectID uint64) []*DominatorTreeNode {
+	idx := t.index(objectID)
+	if idx < 0 {
+		return nil
+	}
+	t.derive()
+
+	chain := make([]*DominatorTreeNode, t.depth[idx])
+	for i := len(chain) - 1; i >= 0 && idx >= 0; i-- {
+		chain[i] = t.nodes([]int32{idx}, t.idom[idx], 0)[0]
+		idx = t.idom[idx]
+	}
+	return chain
+}
+
+// nodes converts node indexes to DominatorTreeNodes (limit <= 0 = all).
+func (t *DominatorTree) nodes(indexes []int32, parent int32, limit int) []*DominatorTreeNode {
+	if limit > 0 && len(indexes) > limit {
+		indexes = indexes[:limit]
+	}
+	result := make([]*DominatorTreeNode, len(indexes))
+	for i, idx := range indexes {
+		node := &DominatorTreeNode{
+			ObjectID:     t.objectIDs[idx],
+			Depth:        int(t.depth[idx]),
+			ClassName:    t.classNames[t.classIDs[idx]],
+			ShallowSize:  t.shallow[idx],
+			RetainedSize: t.retained[idx],
+		}
+		if parent >= 0 {
+			node.ParentID = t.objectIDs[parent]
+		}
+		if t.totalRetained > 0 {
+			node.Percentage = float64(node.RetainedSize) * 100 / float64(t.totalRetained)
+		}
+		result[i] = node
+	}
+	return result
+}
+
+// RetainedSet summarizes the objects retained by a selection.
+type RetainedSet struct {
+	// Selected is the number of selected objects found in the tree.
+	Selected int `json:"selected"`
+	// Objects is the number of objects in the retained set, including the selection.
+	Objects int `json:"objects"`
+	// ShallowSize is the total shallow size of the retained set.
+	ShallowSize int64 `json:"shallow_size"`
+	// Missing lists selected objects that are not in the tree (unreachable or unknown).
+	Missing []uint64 `json:"missing,omitempty"`
+}
+
+// RetainedSet returns the union of the dominator subtrees of the selected objects.
+// For a single object this is exactly its retained set; for several objects it
+// excludes objects that are only kept alive by the selection jointly.
+func (t *DominatorTree) RetainedSet(objectIDs []uint64) *RetainedSet {
+	t.derive()
+
+	result := &RetainedSet{}
+	visited := collections.NewBitset(len(t.objectIDs))
+	stack := make([]int32, 0, 64)
+	for _, objID := range objectIDs {
+		idx := t.index(objID)
+		if idx < 0 {
+			result.Missing = append(result.Missing, objID)
+			continue
+		}
+		result.Selected++
+		if visited.Test(int(idx)) {
+			continue // already covered by another selected object's subtree
+		}
+
+		stack = append(stack[:0], idx)
+		for len(stack) > 0 {
+			n := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			if visited.Test(int(n)) {
+				continue
+			}
+			visited.Set(int(n))
+			result.Objects++
+			result.ShallowSize += t.shallow[n]
+			stack = append(stack, t.childIndex[t.childOffsets[n]:t.childOffsets[n+1]]...)
+		}
+	}
+	return result
+}
+
+// derive rebuilds child lists, depths and retained sizes from the idom array.
+func (t *DominatorTree) derive() {
+	t.derivedOnce.Do(func() {
+		n := len(t.objectIDs)
+
+		// Child lists in CSR form
+		t.childOffsets = make([]int32, n+1)
+		for _, dom := range t.idom {
+			if dom >= 0 {
+				t.childOffsets[dom+1]++
+			}
+		}
+		for i := 0; i < n; i++ {
+			t.childOffsets[i+1] += t.childOffsets[i]
+		}
+		t.childIndex = make([]int32, t.childOffsets[n])
+		writePos := make([]int32, n)
+		copy(writePos, t.childOffsets[:n])
+		for i, dom := range t.idom {
+			if dom >= 0 {
+				t.childIndex[writePos[dom]] = int32(i)
+				writePos[dom]++
+			} else {
+				t.roots = append(t.roots, int32(i))
+			}
+		}
+
+		// Top-down order gives depths; its reverse accumulates retained sizes bottom-up
+		t.depth = make([]int32, n)
+		order := make([]int32, 0, n)
+		for _, root := range t.roots {
+			t.depth[root] = 1
+			order = append(order, root)
+		}
+		for head := 0; head < len(order); head++ {
+			node := order[head]
+			for _, child := range t.childIndex[t.childOffsets[node]:t.childOffsets[node+1]] {
+				t.depth[child] = t.depth[node] + 1
+				order = append(order, child)
+			}
+		}
+
+		t.retained = make([]int64, n)
+		copy(t.retained, t.shallow)
+		for i := len(order) - 1; i >= 0; i-- {
+			node := order[i]
+			if dom := t.idom[node]; dom >= 0 {
+				t.retained[dom] += t.retained[node]
+			} else {
+				t.totalRetained += t.retained[node]
+			}
+		}
+
+		// Sort children and roots by retained size descending
+		byRetained := func(ids []int32) {
+			sort.Slice(ids, func(i, j int) bool {
+				ri, rj := t.retained[ids[i]], t.retained[ids[j]]
+				if ri != rj {
+					return ri > rj
+				}
+				return ids[i] < ids[j]
+			})
+		}
+		byRetained(t.roots)
+		for i := 0; i < n; i++ {
+			byRetained(t.childIndex[t.childOffsets[i]:t.childOffsets[i+1]])
+		}
+	})
+}
+
+// MarshalBinary encodes the tree in the domtree.bin format using zstd.
+func (t *DominatorTree) MarshalBinary() ([]byte, error) {
+	raw, err := proto.Marshal(&pb.DominatorTreeProto{
+		ObjectIds:    t.objectIDs,
+		Idom:         t.idom,
+		ShallowSizes: t.shallow,
+		ClassIds:     t.classIDs,
+		ClassNames:   t.classNames,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal dominator tree: %w", err)
+	}
+
+	compressor, err := compression.New(compression.TypeZstd, compression.LevelDefault)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create compressor: %w", err)
+	}
+	defer compression.Close(compressor)
+	compressed, err := compressor.Compress(raw)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compress dominator tree: %w", err)
+	}
+
+	var buf bytes.Buffer
+	buf.Grow(6 + len(compressed))
+	buf.WriteString(DominatorTreeMagic)
+	buf.WriteByte(DominatorTreeVersion)
+	buf.WriteByte(byte(compression.TypeZstd))
+	buf.Write(compressed)
+	return buf.Bytes(), nil
+}
+
+// UnmarshalDominatorTree decodes a tree in the domtree.bin format.
+func UnmarshalDominatorTree(data []byte) (*DominatorTree, error) {
+	if len(data) < 6 {
+		return nil, fmt.Errorf("dominator tree data too short: %d bytes", len(data))
+	}
+	if string(data[:4]) != DominatorTreeMagic {
+		return nil, fmt.Errorf("invalid magic bytes: expected %q, got %q", DominatorTreeMagic, string(data[:4]))
+	}
+	if version := data[4]; version > DominatorTreeVersion {
+		return nil, fmt.Errorf("unsupported dominator tree version %d (max %d)", version, DominatorTreeVersion)
+	}
+
+	compressor, err := compression.New(compression.Type(data[5]), compression.LevelDefault)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create decompressor: %w", err)
+	}
+	defer compression.Close(compressor)
+	raw, err := compressor.Decompress(data[6:])
+	if err != nil {
+		return nil, fmt.Errorf("failed to decompress dominator tree: %w", err)
+	}
+
+	var pbTree pb.DominatorTreeProto
+	if err := proto.Unmarshal(raw, &pbTree); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal dominator tree: %w", err)
+	}
+	n := len(pbTree.ObjectIds)
+	if len(pbTree.Idom) != n || len(pbTree.ShallowSizes) != n || len(pbTree.ClassIds) != n {
+		return nil, fmt.Errorf("inconsistent dominator tree: %d objects, %d idom, %d sizes, %d classes",
+			n, len(pbTree.Idom), len(pbTree.ShallowSizes), len(pbTree.ClassIds))
+	}
+	for i, dom := range pbTree.Idom {
+		if dom < noDominator || int(dom) >= n {
+			return nil, fmt.Errorf("invalid dominator index %d for node %d", dom, i)
+		}
+	}
+
+	classNames := pbTree.ClassNames
+	if classNames == nil {
+		classNames = make(map[uint64]string)
+	}
+	return &DominatorTree{
+		objectIDs:  pbTree.ObjectIds,
+		idom:       pbTree.Idom,
+		shallow:    pbTree.ShallowSizes,
+		classIDs:   pbTree.ClassIds,
+		classNames: classNames,
+	}, nil
+}
+
+// WriteFile writes the tree to a domtree.bin file.
+func (t *DominatorTree) WriteFile(filename string) error {
+	data, err := t.MarshalBinary()
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(filename, data, 0644); err != nil {
+		return fmt.Errorf("failed to write file: %w", err)
+	}
+	return nil
+}
+
+// ReadDominatorTreeFile reads a domtree.bin file.
+func ReadDominatorTreeFile(filename string) (*DominatorTree, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	return UnmarshalDominatorTree(data)
+}
@@ -0,0 +1,122 @@
+package hprof
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDominatorTree_RoundTrip(t *testing.T) {
+	g := newRollupTestGraph()
+	filename := filepath.Join(t.TempDir(), DominatorTreeFileName)
+	require.NoError(t, NewDominatorTree(g).WriteFile(filename))
+
+	tree, err := ReadDominatorTreeFile(filename)
+	require.NoError(t, err)
+	assert.Equal(t, 5, tree.Len())
+
+	// Retained sizes are recomputed from the idom array
+	for _, objID := range []uint64{200, 300, 400, 500, 600} {
+		assert.Equal(t, g.GetRetainedSize(objID), tree.RetainedSize(objID), "retained size of %d", objID)
+	}
+	assert.Equal(t, int64(32+48+4096+120+2048), tree.TotalRetainedSize())
+
+	dom, ok := tree.Dominator(400)
+	assert.True(t, ok)
+	assert.Equal(t, uint64(200), dom)
+	dom, ok = tree.Dominator(300)
+	assert.True(t, ok)
+	assert.Zero(t, dom)
+	_, ok = tree.Dominator(999)
+	assert.False(t, ok)
+
+	roots := tree.Roots(0)
+	require.Len(t, roots, 2)
+	assert.Equal(t, uint64(300), roots[0].ObjectID)
+	assert.Equal(t, "com.example.Cache", roots[0].ClassName)
+	assert.Equal(t, 1, roots[0].Depth)
+
+	children := tree.Children(200, 10)
+	require.Len(t, children, 1)
+	assert.Equal(t, uint64(400), children[0].ObjectID)
+	assert.Equal(t, uint64(200), children[0].ParentID)
+	assert.Equal(t, 3, children[0].Depth)
+	assert.Nil(t, tree.Children(999, 10))
+}
+
+func TestDominatorTree_Chain(t *testing.T) {
+	tree := NewDominatorTree(newRollupTestGraph())
+
+	chain := tree.Chain(400)
+	require.Len(t, chain, 3)
+	assert.Equal(t, uint64(300), chain[0].ObjectID)
+	assert.Zero(t, chain[0].ParentID)
+	assert.Equal(t, uint64(200), chain[1].ObjectID)
+	assert.Equal(t, uint64(300), chain[1].ParentID)
+	assert.Equal(t, uint64(400), chain[2].ObjectID)
+	assert.Equal(t, 3, chain[2].Depth)
+
+	require.Len(t, tree.Chain(500), 1)
+	assert.Nil(t, tree.Chain(999))
+}
+
+func TestDominatorTree_RetainedSet(t *testing.T) {
+	tree := NewDominatorTree(newRollupTestGraph())
+
+	set := tree.RetainedSet([]uint64{200})
+	assert.Equal(t, 1, set.Selected)
+	assert.Equal(t, 2, set.Objects)
+	assert.Equal(t, int64(48+4096), set.ShallowSize)
+
+	// Nested selections are counted once; unknown objects are reported
+	set = tree.RetainedSet([]uint64{400, 300, 600, 999})
+	assert.Equal(t, 3, set.Selected)
+	assert.Equal(t, 4, set.Objects)
+	assert.Equal(t, int64(32+48+4096+2048), set.ShallowSize)
+	assert.Equal(t, []uint64{999}, set.Missing)
+}
+
+func TestUnmarshalDominatorTree_Invalid(t *testing.T) {
+	_, err := UnmarshalDominatorTree([]byte("REFG\x01\x01"))
+	assert.Error(t, err)
+
+	data, err := NewDominatorTree(newRollupTestGraph()).MarshalBinary()
+	require.NoError(t, err)
+	data[4] = DominatorTreeVersion + 1
+	_, err = UnmarshalDominatorTree(data)
+	assert.Error(t, err)
+}
+
+func TestDominatorTree_Treemap(t *testing.T) {
+	tree := NewDominatorTree(newRollupTestGraph())
+
+	heap := tree.Treemap(0, TreemapOptions{MaxDepth: 2})
+	require.NotNil(t, heap)
+	assert.Equal(t, tree.TotalRetainedSize(), heap.RetainedSize)
+	require.Len(t, heap.Children, 2)
+
+	cache := heap.Children[0]
+	assert.Equal(t, uint64(300), cache.ObjectID)
+	assert.Equal(t, "com.example", cache.Package)
+	require.Len(t, cache.Children, 1)
+	// The HashMap is at the depth limit; its byte[] is not expanded
+	assert.Equal(t, uint64(200), cache.Children[0].ObjectID)
+	assert.True(t, cache.Children[0].HasMore)
+	assert.Empty(t, cache.Children[0].Children)
+
+	// Children below the size threshold are aggregated
+	heap = tree.Treemap(0, TreemapOptions{MaxChildren: 1})
+	require.Len(t, heap.Children, 2)
+	other := heap.Children[1]
+	assert.Zero(t, other.ObjectID)
+	assert.Equal(t, 1, other.Other)
+	assert.Equal(t, int64(120+2048), other.RetainedSize)
+
+	subtree := tree.Treemap(200, TreemapOptions{})
+	require.NotNil(t, subtree)
+	require.Len(t, subtree.Children, 1)
+	assert.Equal(t, "(default)", subtree.Children[0].Package)
+	assert.Nil(t, tree.Treemap(999, TreemapOptions{}))
+}
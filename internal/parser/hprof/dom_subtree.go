@@ -0,0 +1,175 @@
+// Package hprof provides parsing functionality for Java HPROF heap dump files.
+package hprof
+
+import (
+	"fmt"
+	"sort"
+)
+
+// ============================================================================
+// Dominator Subtree Iteration
+// ============================================================================
+//
+// The dominator subtree of an object is the set of objects it exclusively
+// retains: the objects that become unreachable when it does. For a GC root
+// this is everything owned through that root. Subtrees of large roots hold
+// millions of objects, so they are walked lazily in depth-first pre-order,
+// children by retained size descending, and a walk can be resumed after any
+// object of the subtree, which makes the object ID a stable paging cursor.
+
+// SubtreeIterator walks the dominator subtree of an object in depth-first
+// pre-order. It is not safe for concurrent use.
+type SubtreeIterator struct {
+	tree *DominatorTree
+	// stack holds the nodes left to visit at every depth, top last
+	stack [][]int32
+}
+
+// Subtree returns an iterator over the dominator subtree of objectID, the
+// object itself first. With a non-zero after, the iteration resumes after
+// that object, which must be in the subtree.
+func (t *DominatorTree) Subtree(objectID, after uint64) (*SubtreeIterator, error) {
+	root := t.index(objectID)
+	if root < 0 {
+		return nil, fmt.Errorf("object 0x%x not in dominator tree", objectID)
+	}
+	t.derive()
+
+	it := &SubtreeIterator{tree: t}
+	if after == 0 {
+		it.stack = append(it.stack, []int32{root})
+		return it, nil
+	}
+
+	// Chain from after up to the root, after first
+	node := t.index(after)
+	var chain []int32
+	for n := node; n != root; n = t.idom[n] {
+		if n < 0 {
+			return nil, fmt.Errorf("object 0x%x not in the dominator subtree of 0x%x", after, objectID)
+		}
+		chain = append(chain, n)
+	}
+
+	// Below every ancestor, the siblings after the chain node are left
+	for i := len(chain) - 1; i >= 0; i-- {
+		siblings := t.children(t.idom[chain[i]])
+		for j, sibling := range siblings {
+			if sibling == chain[i] {
+				it.stack = append(it.stack, siblings[j+1:])
+				break
+			}
+		}
+	}
+	it.stack = append(it.stack, t.children(node))
+	return it, nil
+}
+
+// Next returns the next object of the subtree, or false when the walk is done.
+func (it *SubtreeIterator) Next() (*DominatorTreeNode, bool) {
+	for len(it.stack) > 0 {
+		top := len(it.stack) - 1
+		if len(it.stack[top]) == 0 {
+			it.stack = it.stack[:top]
+			continue
+		}
+		node := it.stack[top][0]
+		it.stack[top] = it.stack[top][1:]
+		if children := it.tree.children(node); len(children) > 0 {
+			it.stack = append(it.stack, children)
+		}
+		return it.tree.nodes([]int32{node}, it.tree.idom[node], 0)[0], true
+	}
+	return nil, false
+}
+
+// children returns the children of a node, sorted by retained size descending.
+func (t *DominatorTree) children(node int32) []int32 {
+	return t.childIndex[t.childOffsets[node]:t.childOffsets[node+1]]
+}
+
+// SubtreeClass is the instances of one class in a dominator subtree.
+type SubtreeClass struct {
+	ClassName   string `json:"class_name"`
+	Count       int64  `json:"count"`
+	ShallowSize int64  `json:"shallow_size"`
+	// RetainedSize counts the instances not dominated by another instance of
+	// the class within the subtree, so nested instances are not counted twice
+	RetainedSize int64 `json:"retained_size"`
+}
+
+// SubtreeHistogram is the class histogram of the dominator subtree of an
+// object: what the object retains, by class.
+type SubtreeHistogram struct {
+	Root *DominatorTreeNode `json:"root"`
+	// Objects and ShallowSize cover the whole subtree, the root included
+	Objects     int64 `json:"objects"`
+	ShallowSize int64 `json:"shallow_size"`
+	// ClassCount is the number of classes in the subtree; Classes holds the
+	// biggest ones by shallow size
+	ClassCount int             `json:"class_count"`
+	Classes    []*SubtreeClass `json:"classes"`
+}
+
+// SubtreeHistogram returns the class histogram of the dominator subtree of
+// objectID, keeping the topN classes with the biggest shallow size (all of
+// them if topN <= 0).
+func (t *DominatorTree) SubtreeHistogram(objectID uint64, topN int) (*SubtreeHistogram, error) {
+	root := t.index(objectID)
+	if root < 0 {
+		return nil, fmt.Errorf("object 0x%x not in dominator tree", objectID)
+	}
+	t.derive()
+
+	result := &SubtreeHistogram{Root: t.nodes([]int32{root}, t.idom[root], 0)[0]}
+	byClass := make(map[uint64]*SubtreeClass)
+	// open counts the instances of each class on the current path; a node
+	// exits once its subtree is done, encoded as ^index on the stack
+	open := make(map[uint64]int)
+	stack := []int32{root}
+	for len(stack) > 0 {
+		n := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if n < 0 {
+			open[t.classIDs[^n]]--
+			continue
+		}
+
+		classID := t.classIDs[n]
+		cls, ok := byClass[classID]
+		if !ok {
+			cls = &SubtreeClass{ClassName: t.classNames[classID]}
+			if cls.ClassName == "" {
+				cls.ClassName = "Unknown"
+			}
+			byClass[classID] = cls
+		}
+		cls.Count++
+		cls.ShallowSize += t.shallow[n]
+		if open[classID] == 0 {
+			cls.RetainedSize += t.retained[n]
+		}
+		open[classID]++
+		result.Objects++
+		result.ShallowSize += t.shallow[n]
+		stack = append(stack, ^n)
+		stack = append(stack, t.children(n)...)
+	}
+
+	result.ClassCount = len(byClass)
+	result.Classes = make([]*SubtreeClass, 0, len(byClass))
+	for _, cls := range byClass {
+		result.Classes = append(result.Classes, cls)
+	}
+	sort.Slice(result.Classes, func(i, j int) bool {
+		a, b := result.Classes[i], result.Classes[j]
+		if a.ShallowSize != b.ShallowSize {
+			return a.ShallowSize > b.ShallowSize
+		}
+		return a.ClassName < b.ClassName
+	})
+	if topN > 0 && len(result.Classes) > topN {
+		result.Classes = result.Classes[:topN]
+	}
+	return result, nil
+}
@@ -0,0 +1,123 @@
+package hprof
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// collectSubtree returns the object IDs of a subtree walk.
+func collectSubtree(it *SubtreeIterator) []uint64 {
+	ids := []uint64{}
+	for node, ok := it.Next(); ok; node, ok = it.Next() {
+		ids = append(ids, node.ObjectID)
+	}
+	return ids
+}
+
+func TestDominatorTree_Subtree(t *testing.T) {
+	// 1 -> {2, 3}, 2 -> 4, 3 -> 5, 2 and 3 share 6, so 1 retains it
+	g := NewReferenceGraph()
+	g.SetClassName(1, "com.example.Node")
+	sizes := map[uint64]int64{1: 16, 2: 400, 3: 100, 4: 32, 5: 64, 6: 8}
+	for id, size := range sizes {
+		g.SetObjectInfo(id, 1, size)
+	}
+	for _, edge := range [][2]uint64{{1, 2}, {1, 3}, {2, 4}, {3, 5}, {2, 6}, {3, 6}} {
+		g.AddReference(ObjectReference{FromObjectID: edge[0], ToObjectID: edge[1], FromClassID: 1})
+	}
+	g.AddGCRoot(&GCRoot{ObjectID: 1, Type: GCRootJNIGlobal})
+	tree := NewDominatorTree(g)
+
+	it, err := tree.Subtree(1, 0)
+	require.NoError(t, err)
+	all := collectSubtree(it)
+	assert.Equal(t, []uint64{1, 2, 4, 3, 5, 6}, all)
+
+	// Resuming after any object continues the same walk
+	for i, after := range all {
+		it, err := tree.Subtree(1, after)
+		require.NoError(t, err)
+		assert.Equal(t, all[i+1:], collectSubtree(it), "after %d", after)
+	}
+
+	it, err = tree.Subtree(3, 0)
+	require.NoError(t, err)
+	node, ok := it.Next()
+	require.True(t, ok)
+	assert.Equal(t, uint64(3), node.ObjectID)
+	assert.Equal(t, uint64(1), node.ParentID)
+	assert.Equal(t, int64(164), node.RetainedSize)
+	assert.Equal(t, []uint64{5}, collectSubtree(it))
+
+	_, err = tree.Subtree(999, 0)
+	assert.Error(t, err)
+	_, err = tree.Subtree(3, 4)
+	assert.Error(t, err)
+	_, err = tree.Subtree(3, 999)
+	assert.Error(t, err)
+}
+
+func TestDominatorTree_SubtreeHistogram(t *testing.T) {
+	// HashMap 10 -> table 11 -> Node 12 -> Node 13, each Node holding a byte[]
+	g := NewReferenceGraph()
+	g.SetClassName(1, "java.util.HashMap")
+	g.SetClassName(2, "java.util.HashMap$Node[]")
+	g.SetClassName(3, "java.util.HashMap$Node")
+	g.SetClassName(4, "byte[]")
+	g.SetObjectInfo(10, 1, 48)
+	g.SetObjectInfo(11, 2, 80)
+	g.SetObjectInfo(12, 3, 32)
+	g.SetObjectInfo(13, 3, 32)
+	g.SetObjectInfo(20, 4, 1000)
+	g.SetObjectInfo(21, 4, 500)
+	for _, edge := range [][3]uint64{{10, 11, 1}, {11, 12, 2}, {12, 13, 3}, {12, 20, 3}, {13, 21, 3}} {
+		g.AddReference(ObjectReference{FromObjectID: edge[0], ToObjectID: edge[1], FromClassID: edge[2]})
+	}
+	g.AddGCRoot(&GCRoot{ObjectID: 10, Type: GCRootJNIGlobal})
+	tree := NewDominatorTree(g)
+
+	histogram, err := tree.SubtreeHistogram(10, 0)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(10), histogram.Root.ObjectID)
+	assert.Equal(t, int64(6), histogram.Objects)
+	assert.Equal(t, int64(1692), histogram.ShallowSize)
+	assert.Equal(t, 4, histogram.ClassCount)
+	assert.Equal(t, []*SubtreeClass{
+		{ClassName: "byte[]", Count: 2, ShallowSize: 1500, RetainedSize: 1500},
+		{ClassName: "java.util.HashMap$Node[]", Count: 1, ShallowSize: 80, RetainedSize: 1644},
+		// Node 13 is inside Node 12's retained size
+		{ClassName: "java.util.HashMap$Node", Count: 2, ShallowSize: 64, RetainedSize: 1564},
+		{ClassName: "java.util.HashMap", Count: 1, ShallowSize: 48, RetainedSize: 1692},
+	}, histogram.Classes)
+
+	histogram, err = tree.SubtreeHistogram(13, 1)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), histogram.Objects)
+	assert.Equal(t, 2, histogram.ClassCount)
+	require.Len(t, histogram.Classes, 1)
+	assert.Equal(t, "byte[]", histogram.Classes[0].ClassName)
+
+	_, err = tree.SubtreeHistogram(999, 0)
+	assert.Error(t, err)
+}
+
+func TestReferenceGraph_GetGCRootInfo(t *testing.T) {
+	g := newRollupTestGraph()
+	g.classObjectIDs[900] = true
+	g.SetObjectInfo(900, 1000, 16)
+
+	info, ok := g.GetGCRootInfo(300)
+	require.True(t, ok)
+	assert.Equal(t, GCRootJNIGlobal, info.RootType)
+	assert.Equal(t, "com.example.Cache", info.ClassName)
+	assert.Equal(t, int64(32+48+4096), info.RetainedSize)
+
+	info, ok = g.GetGCRootInfo(900)
+	require.True(t, ok)
+	assert.Equal(t, GCRootStickyClass, info.RootType)
+
+	_, ok = g.GetGCRootInfo(200)
+	assert.False(t, ok)
+}
@@ -0,0 +1,125 @@
+package hprof
+
+import (
+	"strings"
+)
+
+// Default limits of DominatorTree.Treemap.
+const (
+	DefaultTreemapDepth    = 4
+	DefaultTreemapChildren = 20
+	// DefaultTreemapMinPercent is the share of the parent's retained size
+	// below which children are folded into the "other" node.
+	DefaultTreemapMinPercent = 0.5
+)
+
+// TreemapNode is one rectangle of the retained-size treemap. Object nodes
+// are dominator tree nodes; aggregate nodes (Other > 0) stand for the
+// children that were too small or too many to show individually.
+type TreemapNode struct {
+	ObjectID     uint64 `json:"object_id,omitempty"`
+	ClassName    string `json:"class_name"`
+	Package      string `json:"package"`
+	ShallowSize  int64  `json:"shallow_size"`
+	RetainedSize int64  `json:"retained_size"`
+	// Other is the number of objects folded into an aggregate node
+	Other int `json:"other,omitempty"`
+	// HasMore is set on nodes at the depth limit that have children
+	HasMore  bool           `json:"has_more,omitempty"`
+	Children []*TreemapNode `json:"children,omitempty"`
+}
+
+// TreemapOptions limits the size of a treemap.
+type TreemapOptions struct {
+	MaxDepth    int
+	MaxChildren int
+	MinPercent  float64
+}
+
+// ClassPackage returns the package of a class name. Array classes belong to
+// their element type's package; primitives and the default package yield
+// "(default)".
+func ClassPackage(className string) string {
+	name := strings.TrimRight(className, "[]")
+	if i := strings.LastIndex(name, "."); i > 0 {
+		return name[:i]
+	}
+	return "(default)"
+}
+
+// Treemap returns the dominator subtree of objectID as a treemap, or of the
+// whole heap when objectID is 0. Each node keeps at most MaxChildren children
+// of at least MinPercent of its retained size, the rest being aggregated into
+// one "other" node. Returns nil if the object is not in the tree.
+func (t *DominatorTree) Treemap(objectID uint64, opts TreemapOptions) *TreemapNode {
+	if opts.MaxDepth <= 0 {
+		opts.MaxDepth = DefaultTreemapDepth
+	}
+	if opts.MaxChildren <= 0 {
+		opts.MaxChildren = DefaultTreemapChildren
+	}
+	if opts.MinPercent < 0 {
+		opts.MinPercent = 0
+	}
+	t.derive()
+
+	if objectID == 0 {
+		root := &TreemapNode{ClassName: "<heap>", RetainedSize: t.totalRetained}
+		root.Children = t.treemapChildren(t.roots, t.totalRetained, 1, opts)
+		return root
+	}
+
+	idx := t.index(objectID)
+	if idx < 0 {
+		return nil
+	}
+	return t.treemapNode(idx, 0, opts)
+}
+
+// treemapNode builds the treemap of one node; depth counts levels below the
+// requested root.
+func (t *DominatorTree) treemapNode(idx int32, depth int, opts TreemapOptions) *TreemapNode {
+	className := t.classNames[t.classIDs[idx]]
+	node := &TreemapNode{
+		ObjectID:     t.objectIDs[idx],
+		ClassName:    className,
+		Package:      ClassPackage(className),
+		ShallowSize:  t.shallow[idx],
+		RetainedSize: t.retained[idx],
+	}
+	children := t.childIndex[t.childOffsets[idx]:t.childOffsets[idx+1]]
+	if len(children) == 0 {
+		return node
+	}
+	if depth >= opts.MaxDepth {
+		node.HasMore = true
+		return node
+	}
+	node.Children = t.treemapChildren(children, node.RetainedSize, depth+1, opts)
+	return node
+}
+
+// treemapChildren builds the treemap nodes of children sorted by retained
+// size descending, aggregating the tail.
+func (t *DominatorTree) treemapChildren(children []int32, parentRetained int64, depth int, opts TreemapOptions) []*TreemapNode {
+	minSize := int64(float64(parentRetained) * opts.MinPercent / 100)
+
+	var nodes []*TreemapNode
+	var other *TreemapNode
+	for i, child := range children {
+		if i < opts.MaxChildren && t.retained[child] >= minSize {
+			nodes = append(nodes, t.treemapNode(child, depth, opts))
+			continue
+		}
+		if other == nil {
+			other = &TreemapNode{ClassName: "<other>"}
+		}
+		other.Other++
+		other.ShallowSize += t.shallow[child]
+		other.RetainedSize += t.retained[child]
+	}
+	if other != nil {
+		nodes = append(nodes, other)
+	}
+	return nodes
+}
@@ -0,0 +1,188 @@
+package hprof
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/parquet-go/parquet-go"
+)
+
+// File names of the Parquet heap export.
+const (
+	ParquetObjectsFileName        = "objects.parquet"
+	ParquetClassHistogramFileName = "class_histogram.parquet"
+)
+
+// ParquetObjectRow is one row of the Parquet object table.
+// DumpID identifies the heap dump so that tables from many dumps can be
+// queried together; DominatorID is 0 for top-level objects.
+type ParquetObjectRow struct {
+	DumpID       string `parquet:"dump_id,dict"`
+	ObjectID     uint64 `parquet:"object_id"`
+	ClassName    string `parquet:"class_name,dict"`
+	ShallowSize  int64  `parquet:"shallow_size"`
+	RetainedSize int64  `parquet:"retained_size"`
+	DominatorID  uint64 `parquet:"dominator_id"`
+	Reachable    bool   `parquet:"reachable"`
+	GCRootType   string `parquet:"gc_root_type,dict"`
+}
+
+// ParquetClassHistogramRow is one row of the Parquet class histogram.
+type ParquetClassHistogramRow struct {
+	DumpID        string `parquet:"dump_id,dict"`
+	ClassName     string `parquet:"class_name"`
+	InstanceCount int64  `parquet:"instance_count"`
+	ShallowSize   int64  `parquet:"shallow_size"`
+	RetainedSize  int64  `parquet:"retained_size"`
+}
+
+// ParquetExportOptions configures the Parquet export.
+type ParquetExportOptions struct {
+	// DumpID is written to every row (typically the task UUID).
+	DumpID string
+	// SkipObjects writes only the class histogram.
+	SkipObjects bool
+	// RowGroupSize is the maximum number of rows per row group.
+	RowGroupSize int64
+}
+
+// DefaultParquetExportOptions returns the default Parquet export options.
+func DefaultParquetExportOptions() ParquetExportOptions {
+	return ParquetExportOptions{
+		RowGroupSize: 1 << 20,
+	}
+}
+
+// ParquetExportStats contains statistics about a Parquet export.
+type ParquetExportStats struct {
+	Classes  int
+	Objects  int
+	Files    []string
+	Duration time.Duration
+}
+
+// parquetWriteBatch is the number of rows buffered before each Write call.
+const parquetWriteBatch = 8192
+
+// ExportParquet writes the class histogram and object table as zstd-compressed
+// Parquet files into dir. The dominator tree is computed first if needed.
+func (g *ReferenceGraph) ExportParquet(ctx context.Context, dir string, opts ParquetExportOptions) (*ParquetExportStats, error) {
+	start := time.Now()
+	if opts.RowGroupSize <= 0 {
+		opts.RowGroupSize = DefaultParquetExportOptions().RowGroupSize
+	}
+	if !g.dominatorComputed {
+		g.ComputeDominatorTree()
+	}
+
+	stats := &ParquetExportStats{}
+
+	classStats := g.GetAllClassStats()
+	classRetained := g.GetClassRetainedSizes()
+	histogramFile := filepath.Join(dir, ParquetClassHistogramFileName)
+	err := writeParquetFile(ctx, histogramFile, opts, func(emit func(ParquetClassHistogramRow) error) error {
+		for classID, cs := range classStats {
+			name := g.classNames[classID]
+			if err := emit(ParquetClassHistogramRow{
+				DumpID:        opts.DumpID,
+				ClassName:     name,
+				InstanceCount: cs.InstanceCount,
+				ShallowSize:   cs.TotalSize,
+				RetainedSize:  classRetained[name],
+			}); err != nil {
+				return err
+			}
+			stats.Classes++
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to export class histogram: %w", err)
+	}
+	stats.Files = append(stats.Files, histogramFile)
+
+	if !opts.SkipObjects {
+		objectsFile := filepath.Join(dir, ParquetObjectsFileName)
+		err = writeParquetFile(ctx, objectsFile, opts, func(emit func(ParquetObjectRow) error) error {
+			for objID, classID := range g.objectClass {
+				row := ParquetObjectRow{
+					DumpID:       opts.DumpID,
+					ObjectID:     objID,
+					ClassName:    g.classNames[classID],
+					ShallowSize:  g.objectSize[objID],
+					RetainedSize: g.retainedSizes[objID],
+					Reachable:    g.reachableObjects[objID],
+				}
+				if dom, ok := g.dominators[objID]; ok && dom != superRootID {
+					row.DominatorID = dom
+				}
+				if rootType, ok := g.gcRootSet[objID]; ok {
+					row.GCRootType = string(rootType)
+				}
+				if err := emit(row); err != nil {
+					return err
+				}
+				stats.Objects++
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to export objects: %w", err)
+		}
+		stats.Files = append(stats.Files, objectsFile)
+	}
+
+	stats.Duration = time.Since(start)
+	return stats, nil
+}
+
+// writeParquetFile writes the rows produced by rows to filename.
+// The file is written under a temporary name and renamed on success.
+func writeParquetFile[T any](ctx context.Context, filename string, opts ParquetExportOptions, rows func(emit func(T) error) error) error {
+	tmpFile := filename + ".tmp"
+	f, err := os.Create(tmpFile)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmpFile)
+	defer f.Close()
+
+	w := parquet.NewGenericWriter[T](f,
+		parquet.Compression(&parquet.Zstd),
+		parquet.MaxRowsPerRowGroup(opts.RowGroupSize),
+	)
+
+	buf := make([]T, 0, parquetWriteBatch)
+	flush := func() error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		_, err := w.Write(buf)
+		buf = buf[:0]
+		return err
+	}
+
+	err = rows(func(row T) error {
+		buf = append(buf, row)
+		if len(buf) == cap(buf) {
+			return flush()
+		}
+		return nil
+	})
+	if err == nil {
+		err = flush()
+	}
+	if err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmpFile, filename)
+}
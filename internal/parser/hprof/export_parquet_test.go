@@ -0,0 +1,64 @@
+package hprof
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/parquet-go/parquet-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExportParquet(t *testing.T) {
+	dir := t.TempDir()
+	opts := DefaultParquetExportOptions()
+	opts.DumpID = "dump-1"
+
+	stats, err := newRollupTestGraph().ExportParquet(context.Background(), dir, opts)
+	require.NoError(t, err)
+	assert.Equal(t, 4, stats.Classes)
+	assert.Equal(t, 5, stats.Objects)
+	assert.Len(t, stats.Files, 2)
+
+	histogram, err := parquet.ReadFile[ParquetClassHistogramRow](filepath.Join(dir, ParquetClassHistogramFileName))
+	require.NoError(t, err)
+	require.Len(t, histogram, 4)
+	for _, row := range histogram {
+		assert.Equal(t, "dump-1", row.DumpID)
+		if row.ClassName == "byte[]" {
+			assert.Equal(t, int64(2), row.InstanceCount)
+			assert.Equal(t, int64(4096+2048), row.ShallowSize)
+		}
+	}
+
+	objects, err := parquet.ReadFile[ParquetObjectRow](filepath.Join(dir, ParquetObjectsFileName))
+	require.NoError(t, err)
+	require.Len(t, objects, 5)
+	byID := make(map[uint64]ParquetObjectRow)
+	for _, row := range objects {
+		byID[row.ObjectID] = row
+	}
+	assert.Equal(t, uint64(200), byID[400].DominatorID)
+	assert.Equal(t, "byte[]", byID[400].ClassName)
+	assert.Zero(t, byID[300].DominatorID)
+	assert.Equal(t, string(GCRootJNIGlobal), byID[300].GCRootType)
+	assert.Equal(t, int64(48+4096), byID[200].RetainedSize)
+	assert.True(t, byID[600].Reachable)
+}
+
+func TestExportParquet_SkipObjects(t *testing.T) {
+	dir := t.TempDir()
+	opts := DefaultParquetExportOptions()
+	opts.SkipObjects = true
+
+	stats, err := newRollupTestGraph().ExportParquet(context.Background(), dir, opts)
+	require.NoError(t, err)
+	assert.Zero(t, stats.Objects)
+
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	require.Len(t, entries, 1, "temporary files are removed")
+	assert.Equal(t, ParquetClassHistogramFileName, entries[0].Name())
+}
@@ -0,0 +1,373 @@
+package hprof
+
+import (
+	"bufio"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+)
+
+// RedactMode is how Redact rewrites the contents of primitive arrays.
+type RedactMode string
+
+const (
+	// RedactZero zeroes the arrays.
+	RedactZero RedactMode = "zero"
+	// RedactHash fills the arrays with a keyed hash of their contents: equal
+	// arrays stay equal, so duplicate analyses still work, and byte[] and
+	// char[] hold hex digits, so Strings stay printable.
+	RedactHash RedactMode = "hash"
+)
+
+// ParseRedactMode parses a redaction mode name.
+func ParseRedactMode(s string) (RedactMode, error) {
+	switch mode := RedactMode(s); mode {
+	case RedactZero, RedactHash:
+		return mode, nil
+	default:
+		return "", fmt.Errorf("invalid redaction mode %q (want zero or hash)", s)
+	}
+}
+
+// RedactOptions configures Redact.
+type RedactOptions struct {
+	Mode RedactMode
+	// Key is the key of the hashes of RedactHash. Dumps redacted with the
+	// same key hash equal contents alike; a random key is used when empty.
+	Key []byte
+	// AllArrays redacts every primitive array instead of the byte[] and
+	// char[] holding the text of Strings.
+	AllArrays bool
+	// CopyUnknown copies the rest of a heap dump record following a
+	// sub-record of an unknown tag unchanged, primitive arrays included,
+	// instead of failing with ErrBadTag. The copy may then hold the data
+	// redaction is meant to remove.
+	CopyUnknown bool
+}
+
+// RedactStats counts what Redact rewrote.
+type RedactStats struct {
+	Arrays int64 `json:"arrays"`
+	Bytes  int64 `json:"bytes"`
+	// UnparsedBytes is the size of the heap dump records following
+	// sub-records of unknown tags, copied unchanged with CopyUnknown
+	UnparsedBytes int64 `json:"unparsed_bytes"`
+}
+
+// redactChunkSize is the size of the array data hashed at once, which
+// bounds the memory of RedactHash. Arrays up to this size are hashed whole.
+const redactChunkSize = 64 << 10
+
+// Redact copies the heap dump of r to w with the contents of primitive
+// arrays redacted, so the dump can be shared without the data it holds.
+// Everything else is copied unchanged: objects, their references and sizes,
+// class and field names, and primitive instance and static fields. A heap
+// dump sub-record of an unknown tag fails with ErrBadTag, since the arrays
+// following it cannot be found, unless opts.CopyUnknown is set.
+func Redact(w io.Writer, r io.Reader, opts RedactOptions) (*RedactStats, error) {
+	if opts.Mode == "" {
+		opts.Mode = RedactZero
+	}
+	if _, err := ParseRedactMode(string(opts.Mode)); err != nil {
+		return nil, err
+	}
+	x := &redactor{
+		r:     NewReader(r),
+		w:     bufio.NewWriterSize(w, 64*1024),
+		opts:  opts,
+		stats: &RedactStats{},
+		buf:   make([]byte, redactChunkSize),
+	}
+	if opts.Mode == RedactHash {
+		key := opts.Key
+		if len(key) == 0 {
+			key = make([]byte, 32)
+			if _, err := rand.Read(key); err != nil {
+				return nil, fmt.Errorf("failed to generate redaction key: %w", err)
+			}
+		}
+		x.mac = hmac.New(sha256.New, key)
+	}
+
+	if err := x.redact(); err != nil {
+		return nil, err
+	}
+	if err := x.w.Flush(); err != nil {
+		return nil, err
+	}
+	return x.stats, nil
+}
+
+// redactor streams a heap dump from r to w, redacting its arrays.
+type redactor struct {
+	r     *Reader
+	w     *bufio.Writer
+	opts  RedactOptions
+	stats *RedactStats
+	mac   hash.Hash
+	buf   []byte
+}
+
+// redact copies the header and the records of the dump.
+func (x *redactor) redact() error {
+	header, err := x.r.ReadHeader()
+	if err != nil {
+		return fmt.Errorf("failed to read header: %w", err)
+	}
+	x.w.WriteString(header.Format)
+	x.w.WriteByte(0)
+	x.writeUint32(uint32(header.IDSize))
+	x.writeUint64(uint64(header.Timestamp.UnixMilli()))
+
+	for {
+		tag, timeDelta, length, err := x.r.ReadRecordHeader()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		x.w.WriteByte(byte(tag))
+		x.writeUint32(timeDelta)
+		x.writeUint32(length)
+
+		if tag == TagHeapDump || tag == TagHeapDumpSegment {
+			err = x.redactHeapDumpRecord(x.r.Offset() + int64(length))
+		} else {
+			err = x.copyN(int64(length))
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// redactHeapDumpRecord copies the sub-records of a heap dump record ending
+// at end, redacting its primitive arrays.
+func (x *redactor) redactHeapDumpRecord(end int64) error {
+	idSize := int64(x.r.IDSize())
+
+	for x.r.Offset() < end {
+		tagByte, err := x.copyByte()
+		if err != nil {
+			return err
+		}
+
+		tag := HeapDumpTag(tagByte)
+		size, ok := rootSubRecordSize(tag, idSize)
+		switch {
+		case tag == 0x00:
+			continue
+		case ok:
+			err = x.copyN(size)
+		case tag == HeapTagClassDump:
+			err = x.copyClassDump()
+		case tag == HeapTagInstanceDump:
+			// Object ID, stack trace serial, class ID and field data
+			if err = x.copyN(2*idSize + 4); err == nil {
+				if size, err = x.copyUint32(); err == nil {
+					err = x.copyN(size)
+				}
+			}
+		case tag == HeapTagObjectArrayDump:
+			// Object ID, stack trace serial, length, class ID and elements
+			if err = x.copyN(idSize + 4); err == nil {
+				if size, err = x.copyUint32(); err == nil {
+					err = x.copyN(idSize + size*idSize)
+				}
+			}
+		case tag == HeapTagPrimitiveArrayDump:
+			err = x.redactPrimitiveArray()
+		default:
+			// The size of the sub-record is unknown, so are the arrays after it
+			if !x.opts.CopyUnknown {
+				return fmt.Errorf("%w 0x%02X at byte %d", ErrBadTag, tagByte, x.r.Offset()-1)
+			}
+			x.stats.UnparsedBytes += end - x.r.Offset()
+			err = x.copyN(end - x.r.Offset())
+		}
+		if err != nil {
+			return err
+		}
+	}
+	if x.r.Offset() > end {
+		return fmt.Errorf("%w: sub-records overrun their heap dump record by %d bytes at byte %d",
+			ErrInconsistentLength, x.r.Offset()-end, x.r.Offset())
+	}
+	return nil
+}
+
+// copyClassDump copies a CLASS_DUMP sub-record after its tag.
+func (x *redactor) copyClassDump() error {
+	idSize := int64(x.r.IDSize())
+
+	// Class, stack trace serial, super class, loader, signers, protection
+	// domain, 2 reserved IDs and instance size
+	if err := x.copyN(7*idSize + 4 + 4); err != nil {
+		return err
+	}
+
+	// Constant pool: index and typed value
+	count, err := x.copyUint16()
+	if err != nil {
+		return err
+	}
+	for i := 0; i < count; i++ {
+		if err := x.copyN(2); err != nil {
+			return err
+		}
+		if err := x.copyTypedValue(); err != nil {
+			return err
+		}
+	}
+
+	// Static fields: name ID and typed value
+	if count, err = x.copyUint16(); err != nil {
+		return err
+	}
+	for i := 0; i < count; i++ {
+		if err := x.copyN(idSize); err != nil {
+			return err
+		}
+		if err := x.copyTypedValue(); err != nil {
+			return err
+		}
+	}
+
+	// Instance fields: name ID and type
+	if count, err = x.copyUint16(); err != nil {
+		return err
+	}
+	return x.copyN(int64(count) * (idSize + 1))
+}
+
+// copyTypedValue copies a basic type tag and its value.
+func (x *redactor) copyTypedValue() error {
+	t, err := x.copyByte()
+	if err != nil {
+		return err
+	}
+	return x.copyN(int64(BasicTypeSize(BasicType(t), x.r.IDSize())))
+}
+
+// redactPrimitiveArray copies a PRIMITIVE_ARRAY_DUMP sub-record after its
+// tag, redacting its elements.
+func (x *redactor) redactPrimitiveArray() error {
+	// Object ID and stack trace serial
+	if err := x.copyN(int64(x.r.IDSize()) + 4); err != nil {
+		return err
+	}
+	length, err := x.copyUint32()
+	if err != nil {
+		return err
+	}
+	t, err := x.copyByte()
+	if err != nil {
+		return err
+	}
+	elemType := BasicType(t)
+	size := length * int64(BasicTypeSize(elemType, x.r.IDSize()))
+	if !x.opts.AllArrays && elemType != TypeByte && elemType != TypeChar {
+		return x.copyN(size)
+	}
+
+	x.stats.Arrays++
+	x.stats.Bytes += size
+	for size > 0 {
+		chunk := x.buf[:min(size, redactChunkSize)]
+		if _, err := io.ReadFull(x.r.r, chunk); err != nil {
+			return x.r.truncated(err)
+		}
+		x.redactChunk(elemType, chunk)
+		x.w.Write(chunk)
+		size -= int64(len(chunk))
+	}
+	return nil
+}
+
+// redactChunk redacts array data in place.
+func (x *redactor) redactChunk(elemType BasicType, chunk []byte) {
+	if x.mac == nil {
+		clear(chunk)
+		return
+	}
+
+	x.mac.Reset()
+	x.mac.Write(chunk)
+	sum := x.mac.Sum(nil)
+	switch elemType {
+	case TypeByte:
+		// Latin-1 String text
+		fill(chunk, []byte(hex.EncodeToString(sum)))
+	case TypeChar:
+		// UTF-16 String text: big-endian hex digits
+		digits := hex.EncodeToString(sum)
+		pattern := make([]byte, 0, 2*len(digits))
+		for i := 0; i < len(digits); i++ {
+			pattern = append(pattern, 0, digits[i])
+		}
+		fill(chunk, pattern)
+	default:
+		fill(chunk, sum)
+	}
+}
+
+// fill fills b with repetitions of pattern.
+func fill(b, pattern []byte) {
+	for i := 0; i < len(b); i += len(pattern) {
+		copy(b[i:], pattern)
+	}
+}
+
+// copyN copies n bytes from r to w.
+func (x *redactor) copyN(n int64) error {
+	if n < 0 {
+		return fmt.Errorf("%w: negative length %d at byte %d", ErrInconsistentLength, n, x.r.Offset())
+	}
+	if _, err := io.CopyN(x.w, x.r.r, n); err != nil {
+		return x.r.truncated(err)
+	}
+	return nil
+}
+
+// copyByte copies a byte and returns it.
+func (x *redactor) copyByte() (byte, error) {
+	b, err := x.r.ReadByte()
+	if err != nil {
+		return 0, err
+	}
+	return b, x.w.WriteByte(b)
+}
+
+// copyUint16 copies a count.
+func (x *redactor) copyUint16() (int, error) {
+	v, err := x.r.ReadUint16()
+	if err != nil {
+		return 0, err
+	}
+	x.w.Write(binary.BigEndian.AppendUint16(nil, v))
+	return int(v), nil
+}
+
+// copyUint32 copies a length.
+func (x *redactor) copyUint32() (int64, error) {
+	v, err := x.r.ReadUint32()
+	if err != nil {
+		return 0, err
+	}
+	x.writeUint32(v)
+	return int64(v), nil
+}
+
+func (x *redactor) writeUint32(v uint32) {
+	x.w.Write(binary.BigEndian.AppendUint32(nil, v))
+}
+
+func (x *redactor) writeUint64(v uint64) {
+	x.w.Write(binary.BigEndian.AppendUint64(nil, v))
+}
@@ -0,0 +1,126 @@
+package hprof
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newRedactTestHprof returns a dump with String data in byte[] and char[]
+// arrays and an int[].
+func newRedactTestHprof() []byte {
+	b := newTestHprofBuilder()
+	b.loadClass(0x10, "com/example/User")
+	b.classDumpStatics(0x10, 0, 0, 16, []testStatic{{name: "COUNT", typ: TypeInt, value: []byte{0, 0, 0, 7}}},
+		testField{name: "name", typ: TypeObject}, testField{name: "email", typ: TypeObject})
+	b.instanceDump(0x1000, 0x10, refBytes(0x2000, 0x2001))
+	b.primitiveArrayDump(0x2000, TypeByte, 11, []byte("secret-name"))
+	b.primitiveArrayDump(0x2001, TypeChar, 4, []byte{0, 'm', 0, 'a', 0, 'i', 0, 'l'})
+	b.primitiveArrayDump(0x2002, TypeByte, 11, []byte("secret-name"))
+	b.primitiveArrayDump(0x2003, TypeInt, 2, []byte{0, 0, 0, 42, 0, 0, 0, 43})
+	b.rootJNIGlobal(0x1000)
+	return b.bytes()
+}
+
+// redactTestHprof redacts data.
+func redactTestHprof(t *testing.T, data []byte, opts RedactOptions) ([]byte, *RedactStats) {
+	var out bytes.Buffer
+	stats, err := Redact(&out, bytes.NewReader(data), opts)
+	require.NoError(t, err)
+	return out.Bytes(), stats
+}
+
+func TestRedact_Zero(t *testing.T) {
+	data := newRedactTestHprof()
+	redacted, stats := redactTestHprof(t, data, RedactOptions{})
+
+	require.Len(t, redacted, len(data))
+	assert.Equal(t, int64(3), stats.Arrays)
+	assert.Equal(t, int64(11+8+11), stats.Bytes)
+	assert.NotContains(t, string(redacted), "secret-name")
+	assert.NotContains(t, string(redacted), "\x00m\x00a\x00i\x00l")
+	assert.Contains(t, string(redacted), "\x00\x00\x00\x2a\x00\x00\x00\x2b")
+
+	// Only the array contents differ
+	i := bytes.Index(data, []byte("secret-name"))
+	assert.Equal(t, data[:i], redacted[:i])
+	assert.Equal(t, make([]byte, 11), redacted[i:i+11])
+
+	// The structure and sizes are intact
+	before, err := parseTestHprof(data)
+	require.NoError(t, err)
+	after, err := parseTestHprof(redacted)
+	require.NoError(t, err)
+	assert.Equal(t, before.TotalInstances, after.TotalInstances)
+	assert.Equal(t, before.TotalHeapSize, after.TotalHeapSize)
+	assert.Equal(t, before.RefGraph.GetRetainedSize(0x1000), after.RefGraph.GetRetainedSize(0x1000))
+}
+
+func TestRedact_Hash(t *testing.T) {
+	data := newRedactTestHprof()
+	key := []byte("shared-key")
+	redacted, stats := redactTestHprof(t, data, RedactOptions{Mode: RedactHash, Key: key})
+	require.Len(t, redacted, len(data))
+	assert.Equal(t, int64(3), stats.Arrays)
+	assert.NotContains(t, string(redacted), "secret-name")
+
+	// Equal arrays stay equal, and String text stays printable
+	i := bytes.Index(data, []byte("secret-name"))
+	j := bytes.LastIndex(data, []byte("secret-name"))
+	first, second := redacted[i:i+11], redacted[j:j+11]
+	assert.Equal(t, first, second)
+	assert.Regexp(t, "^[0-9a-f]{11}$", string(first))
+
+	// The same key hashes alike, another key differently
+	again, _ := redactTestHprof(t, data, RedactOptions{Mode: RedactHash, Key: key})
+	assert.Equal(t, redacted, again)
+	other, _ := redactTestHprof(t, data, RedactOptions{Mode: RedactHash, Key: []byte("other-key")})
+	assert.NotEqual(t, first, other[i:i+11])
+
+	_, err := parseTestHprof(redacted)
+	require.NoError(t, err)
+}
+
+func TestRedact_AllArrays(t *testing.T) {
+	data := newRedactTestHprof()
+	redacted, stats := redactTestHprof(t, data, RedactOptions{AllArrays: true})
+	assert.Equal(t, int64(4), stats.Arrays)
+	assert.NotContains(t, string(redacted), "\x00\x00\x00\x2a\x00\x00\x00\x2b")
+	// Static field values are kept
+	assert.Contains(t, string(redacted), "\x00\x00\x00\x07")
+}
+
+func TestRedact_UnknownTag(t *testing.T) {
+	b := newTestHprofBuilder()
+	b.primitiveArrayDump(0x2000, TypeByte, 6, []byte("secret"))
+	b.heap.Write([]byte{0x42, 1, 2, 3})
+	data := b.bytes()
+
+	_, err := Redact(&bytes.Buffer{}, bytes.NewReader(data), RedactOptions{})
+	assert.ErrorIs(t, err, ErrBadTag)
+
+	redacted, stats := redactTestHprof(t, data, RedactOptions{CopyUnknown: true})
+	require.Len(t, redacted, len(data))
+	assert.Equal(t, int64(3), stats.UnparsedBytes)
+	assert.NotContains(t, string(redacted), "secret")
+}
+
+func TestRedact_Malformed(t *testing.T) {
+	data := newRedactTestHprof()
+	_, err := Redact(&bytes.Buffer{}, bytes.NewReader(data[:len(data)-5]), RedactOptions{})
+	assert.ErrorIs(t, err, ErrTruncated)
+
+	_, err = Redact(&bytes.Buffer{}, bytes.NewReader(data), RedactOptions{Mode: "scramble"})
+	assert.Error(t, err)
+
+	// A 4-byte ID dump
+	var buf bytes.Buffer
+	buf.WriteString("JAVA PROFILE 1.0.2\x00")
+	binary.Write(&buf, binary.BigEndian, uint32(4))
+	binary.Write(&buf, binary.BigEndian, uint64(0))
+	redacted, _ := redactTestHprof(t, buf.Bytes(), RedactOptions{})
+	assert.Equal(t, buf.Bytes(), redacted)
+}
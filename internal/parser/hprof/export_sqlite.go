@@ -0,0 +1,288 @@
+package hprof
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"time"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// SQLiteExportFileName is the default file name of the SQLite heap export.
+const SQLiteExportFileName = "heap.sqlite"
+
+// sqliteSchema creates the export tables. Object and class IDs are stored as
+// signed 64-bit integers (SQLite has no unsigned type); heap addresses never
+// use the top bit in practice. Indexes are created after the bulk load.
+var sqliteSchema = []string{
+	`CREATE TABLE classes (
+		class_id INTEGER PRIMARY KEY,
+		name TEXT NOT NULL,
+		instance_count INTEGER NOT NULL,
+		shallow_size INTEGER NOT NULL,
+		retained_size INTEGER NOT NULL
+	)`,
+	`CREATE TABLE objects (
+		object_id INTEGER PRIMARY KEY,
+		class_id INTEGER NOT NULL,
+		shallow_size INTEGER NOT NULL,
+		retained_size INTEGER NOT NULL,
+		reachable INTEGER NOT NULL
+	)`,
+	`CREATE TABLE refs (
+		from_id INTEGER NOT NULL,
+		to_id INTEGER NOT NULL,
+		field_name TEXT NOT NULL
+	)`,
+	`CREATE TABLE dominators (
+		object_id INTEGER PRIMARY KEY,
+		dominator_id INTEGER
+	)`,
+	`CREATE TABLE gc_roots (
+		object_id INTEGER NOT NULL,
+		root_type TEXT NOT NULL,
+		thread_id INTEGER NOT NULL
+	)`,
+}
+
+// sqliteIndexes supports the common queries: instances of a class, biggest
+// objects, who references an object and what an object dominates.
+var sqliteIndexes = []string{
+	`CREATE INDEX idx_classes_name ON classes(name)`,
+	`CREATE INDEX idx_objects_class ON objects(class_id)`,
+	`CREATE INDEX idx_objects_retained ON objects(retained_size)`,
+	`CREATE INDEX idx_refs_from ON refs(from_id)`,
+	`CREATE INDEX idx_refs_to ON refs(to_id)`,
+	`CREATE INDEX idx_dominators_dominator ON dominators(dominator_id)`,
+	`CREATE INDEX idx_gc_roots_object ON gc_roots(object_id)`,
+}
+
+// SQLiteExportOptions configures the SQLite export.
+type SQLiteExportOptions struct {
+	// SkipReferences omits the refs table, which is usually the largest.
+	SkipReferences bool
+	// BatchSize is the number of rows inserted per transaction.
+	BatchSize int
+}
+
+// DefaultSQLiteExportOptions returns the default SQLite export options.
+func DefaultSQLiteExportOptions() SQLiteExportOptions {
+	return SQLiteExportOptions{
+		BatchSize: 50000,
+	}
+}
+
+// SQLiteExportStats contains statistics about a SQLite export.
+type SQLiteExportStats struct {
+	Classes    int
+	Objects    int
+	References int
+	Dominators int
+	GCRoots    int
+	FileSize   int64
+	Duration   time.Duration
+}
+
+// ExportSQLite writes the graph's classes, objects, references, dominators and
+// GC roots into a new SQLite database at filename, replacing any existing file.
+// The dominator tree is computed first if needed.
+func (g *ReferenceGraph) ExportSQLite(ctx context.Context, filename string, opts SQLiteExportOptions) (*SQLiteExportStats, error) {
+	start := time.Now()
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = DefaultSQLiteExportOptions().BatchSize
+	}
+	if !g.dominatorComputed {
+		g.ComputeDominatorTree()
+	}
+
+	if err := os.Remove(filename); err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to remove existing export: %w", err)
+	}
+	gdb, err := gorm.Open(sqlite.Open(filename), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open sqlite database: %w", err)
+	}
+	db, err := gdb.DB()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open sqlite database: %w", err)
+	}
+	defer db.Close()
+	// A single connection keeps the pragmas below in effect for every statement
+	db.SetMaxOpenConns(1)
+
+	// The file is rebuilt from scratch on failure, so durability is not needed
+	for _, stmt := range append([]string{
+		`PRAGMA journal_mode = OFF`,
+		`PRAGMA synchronous = OFF`,
+	}, sqliteSchema...) {
+		if _, err := db.ExecContext(ctx, stmt); err != nil {
+			return nil, fmt.Errorf("failed to create sqlite schema: %w", err)
+		}
+	}
+
+	stats := &SQLiteExportStats{}
+	w := &sqliteBatchWriter{ctx: ctx, db: db, batchSize: opts.BatchSize}
+
+	classStats := g.GetAllClassStats()
+	classRetained := g.GetClassRetainedSizes()
+	err = w.insert(`INSERT INTO classes VALUES (?, ?, ?, ?, ?)`, func(emit func(args ...any) error) error {
+		for classID, name := range g.classNames {
+			cs := classStats[classID]
+			if err := emit(int64(classID), name, cs.InstanceCount, cs.TotalSize, classRetained[name]); err != nil {
+				return err
+			}
+			stats.Classes++
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to export classes: %w", err)
+	}
+
+	err = w.insert(`INSERT INTO objects VALUES (?, ?, ?, ?, ?)`, func(emit func(args ...any) error) error {
+		for objID, classID := range g.objectClass {
+			reachable := 0
+			if g.reachableObjects[objID] {
+				reachable = 1
+			}
+			if err := emit(int64(objID), int64(classID), g.objectSize[objID], g.retainedSizes[objID], reachable); err != nil {
+				return err
+			}
+			stats.Objects++
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to export objects: %w", err)
+	}
+
+	if !opts.SkipReferences {
+		err = w.insert(`INSERT INTO refs VALUES (?, ?, ?)`, func(emit func(args ...any) error) error {
+			for _, refs := range g.outgoingRefs {
+				for _, ref := range refs {
+					if err := emit(int64(ref.FromObjectID), int64(ref.ToObjectID), ref.FieldName); err != nil {
+						return err
+					}
+					stats.References++
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to export references: %w", err)
+		}
+	}
+
+	// Top-level objects have a NULL dominator
+	err = w.insert(`INSERT INTO dominators VALUES (?, ?)`, func(emit func(args ...any) error) error {
+		for objID, domID := range g.dominators {
+			var dom sql.NullInt64
+			if domID != superRootID {
+				dom = sql.NullInt64{Int64: int64(domID), Valid: true}
+			}
+			if err := emit(int64(objID), dom); err != nil {
+				return err
+			}
+			stats.Dominators++
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to export dominators: %w", err)
+	}
+
+	err = w.insert(`INSERT INTO gc_roots VALUES (?, ?, ?)`, func(emit func(args ...any) error) error {
+		for _, root := range g.gcRoots {
+			if err := emit(int64(root.ObjectID), string(root.Type), int64(root.ThreadID)); err != nil {
+				return err
+			}
+			stats.GCRoots++
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to export GC roots: %w", err)
+	}
+
+	for _, stmt := range sqliteIndexes {
+		if _, err := db.ExecContext(ctx, stmt); err != nil {
+			return nil, fmt.Errorf("failed to create sqlite index: %w", err)
+		}
+	}
+	if _, err := db.ExecContext(ctx, `ANALYZE`); err != nil {
+		return nil, fmt.Errorf("failed to analyze sqlite database: %w", err)
+	}
+	if err := db.Close(); err != nil {
+		return nil, fmt.Errorf("failed to close sqlite database: %w", err)
+	}
+
+	if info, err := os.Stat(filename); err == nil {
+		stats.FileSize = info.Size()
+	}
+	stats.Duration = time.Since(start)
+	return stats, nil
+}
+
+// sqliteBatchWriter inserts rows with a prepared statement, committing every
+// batchSize rows so that a single transaction does not grow unbounded.
+type sqliteBatchWriter struct {
+	ctx       context.Context
+	db        *sql.DB
+	batchSize int
+}
+
+// insert runs query once per row emitted by rows.
+func (w *sqliteBatchWriter) insert(query string, rows func(emit func(args ...any) error) error) error {
+	var (
+		tx      *sql.Tx
+		stmt    *sql.Stmt
+		pending int
+	)
+	commit := func() error {
+		if tx == nil {
+			return nil
+		}
+		stmt.Close()
+		err := tx.Commit()
+		tx, stmt, pending = nil, nil, 0
+		return err
+	}
+
+	err := rows(func(args ...any) error {
+		if tx == nil {
+			if err := w.ctx.Err(); err != nil {
+				return err
+			}
+			var err error
+			if tx, err = w.db.BeginTx(w.ctx, nil); err != nil {
+				return err
+			}
+			if stmt, err = tx.PrepareContext(w.ctx, query); err != nil {
+				tx.Rollback()
+				tx = nil
+				return err
+			}
+		}
+		if _, err := stmt.ExecContext(w.ctx, args...); err != nil {
+			return err
+		}
+		if pending++; pending >= w.batchSize {
+			return commit()
+		}
+		return nil
+	})
+	if err != nil {
+		if tx != nil {
+			stmt.Close()
+			tx.Rollback()
+		}
+		return err
+	}
+	return commit()
+}
@@ -0,0 +1,65 @@
+package hprof
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func TestExportSQLite(t *testing.T) {
+	g := newRollupTestGraph()
+	filename := filepath.Join(t.TempDir(), SQLiteExportFileName)
+
+	opts := DefaultSQLiteExportOptions()
+	opts.BatchSize = 2 // exercise intermediate commits
+	stats, err := g.ExportSQLite(context.Background(), filename, opts)
+	require.NoError(t, err)
+	assert.Equal(t, 4, stats.Classes)
+	assert.Equal(t, 5, stats.Objects)
+	assert.Equal(t, 3, stats.References)
+	assert.Equal(t, 5, stats.Dominators)
+	assert.Equal(t, 2, stats.GCRoots)
+	assert.Positive(t, stats.FileSize)
+
+	gdb, err := gorm.Open(sqlite.Open(filename), &gorm.Config{})
+	require.NoError(t, err)
+	db, err := gdb.DB()
+	require.NoError(t, err)
+	defer db.Close()
+
+	// Retained size per class via a join
+	var retained int64
+	require.NoError(t, db.QueryRow(`SELECT SUM(o.retained_size) FROM objects o
+		JOIN classes c ON c.class_id = o.class_id WHERE c.name = 'java.util.HashMap'`).Scan(&retained))
+	assert.Equal(t, int64(48+4096), retained)
+
+	var dom sql.NullInt64
+	require.NoError(t, db.QueryRow(`SELECT dominator_id FROM dominators WHERE object_id = 400`).Scan(&dom))
+	assert.Equal(t, int64(200), dom.Int64)
+	require.NoError(t, db.QueryRow(`SELECT dominator_id FROM dominators WHERE object_id = 300`).Scan(&dom))
+	assert.False(t, dom.Valid, "top-level objects have no dominator")
+
+	var field string
+	require.NoError(t, db.QueryRow(`SELECT field_name FROM refs WHERE to_id = 600`).Scan(&field))
+	assert.Equal(t, "buf", field)
+
+	// Re-exporting replaces the file
+	opts.SkipReferences = true
+	stats, err = g.ExportSQLite(context.Background(), filename, opts)
+	require.NoError(t, err)
+	assert.Zero(t, stats.References)
+}
+
+func TestExportSQLite_Canceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := newRollupTestGraph().ExportSQLite(ctx, filepath.Join(t.TempDir(), SQLiteExportFileName), DefaultSQLiteExportOptions())
+	assert.ErrorIs(t, err, context.Canceled)
+}
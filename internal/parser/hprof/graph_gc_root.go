@@ -33,6 +33,30 @@ type PathNode struct {
 	ClassName string `json:"class_name"`
 	FieldName string `json:"field_name,omitempty"`
 	Size      int64  `json:"size"`
+	// RetainedSize is filled in by callers that have retained sizes at hand.
+	RetainedSize int64 `json:"retained_size,omitempty"`
+}
+
+// PathExclusion selects java.lang.ref.Reference kinds whose referent field is
+// not followed when searching paths to GC roots, like MAT's "exclude weak/soft
+// references" options.
+type PathExclusion uint8
+
+const (
+	// ExcludeWeakRefs skips referents of java.lang.ref.WeakReference subclasses.
+	ExcludeWeakRefs PathExclusion = 1 << iota
+	// ExcludeSoftRefs skips referents of java.lang.ref.SoftReference subclasses.
+	ExcludeSoftRefs
+	// ExcludePhantomRefs skips referents of PhantomReference and FinalReference subclasses.
+	ExcludePhantomRefs
+)
+
+// referenceClassExclusions maps the java.lang.ref classes to their exclusion flag.
+var referenceClassExclusions = map[string]PathExclusion{
+	"java.lang.ref.WeakReference":    ExcludeWeakRefs,
+	"java.lang.ref.SoftReference":    ExcludeSoftRefs,
+	"java.lang.ref.PhantomReference": ExcludePhantomRefs,
+	"java.lang.ref.FinalReference":   ExcludePhantomRefs,
 }
 
 // GCRootPath represents a path from a GC Root to an object.
@@ -69,6 +93,12 @@ func (g *ReferenceGraph) GetGCRootType(objectID uint64) GCRootType {
 // OPTIMIZATION: Uses iterative deepening DFS instead of BFS with visited map copying.
 // This reduces memory allocation from O(paths * depth * objects) to O(depth).
 func (g *ReferenceGraph) FindPathsToGCRoot(objectID uint64, maxPaths, maxDepth int) []*GCRootPath {
+	return g.FindPathsToGCRootExcluding(objectID, maxPaths, maxDepth, 0)
+}
+
+// FindPathsToGCRootExcluding is FindPathsToGCRoot without following the
+// referent fields of the Reference kinds selected by exclude.
+func (g *ReferenceGraph) FindPathsToGCRootExcluding(objectID uint64, maxPaths, maxDepth int, exclude PathExclusion) []*GCRootPath {
 	if maxPaths <= 0 {
 		maxPaths = 3
 	}
@@ -76,20 +106,56 @@ func (g *ReferenceGraph) FindPathsToGCRoot(objectID uint64, maxPaths, maxDepth i
 		maxDepth = 15
 	}
 
+	var skip func(ref *ObjectReference) bool
+	if exclude != 0 {
+		kinds := make(map[uint64]PathExclusion)
+		skip = func(ref *ObjectReference) bool {
+			return ref.FieldName == "referent" && g.referenceKind(ref.FromClassID, kinds)&exclude != 0
+		}
+	}
+
 	var paths []*GCRootPath
 
 	// Use iterative deepening DFS for memory efficiency
 	// Start with shorter paths first (like BFS) but with O(depth) memory
 	for targetDepth := 1; targetDepth <= maxDepth && len(paths) < maxPaths; targetDepth++ {
-		g.findPathsDFS(objectID, targetDepth, maxPaths-len(paths), &paths)
+		g.findPathsDFS(objectID, targetDepth, maxPaths-len(paths), skip, &paths)
 	}
 
 	return paths
 }
 
-// findPathsDFS performs depth-limited DFS to find paths to GC roots.
+// referenceKind returns the exclusion flag of the java.lang.ref class a class
+// extends, walking the <superclass> references of class objects. Results are
+// memoized in kinds.
+func (g *ReferenceGraph) referenceKind(classID uint64, kinds map[uint64]PathExclusion) PathExclusion {
+	if kind, ok := kinds[classID]; ok {
+		return kind
+	}
+
+	var kind PathExclusion
+	for cid, depth := classID, 0; cid != 0 && depth < 64; depth++ {
+		if k, ok := referenceClassExclusions[g.classNames[cid]]; ok {
+			kind = k
+			break
+		}
+		super := uint64(0)
+		for _, ref := range g.outgoingRefs[cid] {
+			if ref.FieldName == "<superclass>" {
+				super = ref.ToObjectID
+				break
+			}
+		}
+		cid = super
+	}
+	kinds[classID] = kind
+	return kind
+}
+
+// findPathsDFS performs depth-limited DFS to find paths to GC roots of exactly
+// maxDepth objects; shorter paths were found by earlier iterations.
 // Uses a single visited set and path slice, avoiding repeated allocations.
-func (g *ReferenceGraph) findPathsDFS(startID uint64, maxDepth, maxPaths int, paths *[]*GCRootPath) {
+func (g *ReferenceGraph) findPathsDFS(startID uint64, maxDepth, maxPaths int, skip func(ref *ObjectReference) bool, paths *[]*GCRootPath) {
 	if maxPaths <= 0 {
 		return
 	}
@@ -117,7 +183,7 @@ func (g *ReferenceGraph) findPathsDFS(startID uint64, maxDepth, maxPaths int, pa
 		frame := &stack[len(stack)-1]
 
 		// Check if current node is a GC root
-		if rootType, isRoot := g.gcRootSet[frame.objID]; isRoot && frame.refIndex == 0 {
+		if rootType, isRoot := g.gcRootSet[frame.objID]; isRoot && frame.refIndex == 0 && len(stack) == maxDepth {
 			// Build and add path (reverse order: from GC root to target)
 			pathNodes := make([]*PathNode, len(*pathSlice))
 			for i, objID := range *pathSlice {
@@ -133,7 +199,7 @@ func (g *ReferenceGraph) findPathsDFS(startID uint64, maxDepth, maxPaths int, pa
 			for i := 0; i < len(pathNodes)-1; i++ {
 				// Find the reference from pathNodes[i] to pathNodes[i+1]
 				for _, ref := range g.outgoingRefs[pathNodes[i].ObjectID] {
-					if ref.ToObjectID == pathNodes[i+1].ObjectID {
+					if ref.ToObjectID == pathNodes[i+1].ObjectID && (skip == nil || !skip(&ref)) {
 						pathNodes[i+1].FieldName = ref.FieldName
 						break
 					}
@@ -159,10 +225,10 @@ func (g *ReferenceGraph) findPathsDFS(startID uint64, maxDepth, maxPaths int, pa
 		refs := g.incomingRefs[frame.objID]
 		foundNext := false
 		for frame.refIndex < len(refs) {
-			ref := refs[frame.refIndex]
+			ref := &refs[frame.refIndex]
 			frame.refIndex++
 
-			if !visited[ref.FromObjectID] {
+			if !visited[ref.FromObjectID] && (skip == nil || !skip(ref)) {
 				// Push new frame
 				visited[ref.FromObjectID] = true
 				*pathSlice = append(*pathSlice, ref.FromObjectID)
@@ -302,6 +368,43 @@ func (g *ReferenceGraph) GetGCRootsList() []*GCRootInfo {
 	return result
 }
 
+// GetGCRootInfo returns the information of the GC root objectID, counting
+// Class objects as sticky class roots like GetGCRootsList does.
+func (g *ReferenceGraph) GetGCRootInfo(objectID uint64) (*GCRootInfo, bool) {
+	rootType, ok := g.gcRootSet[objectID]
+	if !ok {
+		if !g.classObjectIDs[objectID] {
+			return nil, false
+		}
+		rootType = GCRootStickyClass
+	}
+	classID, ok := g.objectClass[objectID]
+	if !ok {
+		return nil, false
+	}
+	g.ComputeDominatorTree()
+
+	className := g.classNames[classID]
+	if className == "" {
+		className = "Unknown"
+	}
+	info := &GCRootInfo{
+		ObjectID:     objectID,
+		ClassName:    className,
+		RootType:     rootType,
+		ShallowSize:  g.objectSize[objectID],
+		RetainedSize: g.GetRetainedSize(objectID),
+	}
+	for _, root := range g.gcRoots {
+		if root.ObjectID == objectID {
+			info.ThreadID = root.ThreadID
+			info.FrameIndex = root.FrameIndex
+			break
+		}
+	}
+	return info, true
+}
+
 // GetGCRootsSummary returns GC roots grouped by class name (like IDEA).
 func (g *ReferenceGraph) GetGCRootsSummary() []*GCRootSummary {
 	// Ensure dominator tree is computed
@@ -3,6 +3,7 @@ package hprof
 
 import (
 	"sync"
+	"time"
 
 	"github.com/perf-analysis/pkg/utils"
 )
@@ -43,6 +44,10 @@ type ReferenceGraph struct {
 	classRetainedSizesAttributed map[uint64]int64
 	// dominatorComputed indicates if dominator tree has been computed
 	dominatorComputed bool
+	// dominatorChildren maps dominator objectID -> dominated children (lazy built, sorted by retained size)
+	dominatorChildren map[uint64][]uint64
+	// dominatorChildrenOnce ensures dominatorChildren is built only once
+	dominatorChildrenOnce sync.Once
 	// reachableObjects tracks objects reachable from GC roots (populated during dominator computation)
 	reachableObjects map[uint64]bool
 	// classToObjects maps classID -> list of objectIDs (lazy built for optimization)
@@ -53,6 +58,15 @@ type ReferenceGraph struct {
 	classToObjectsOnce sync.Once
 	// logger is used for debug logging. If nil, debug logs are suppressed.
 	logger utils.Logger
+	// maxWorkers caps the workers of the dominator computation. 0 uses the whole shared pool.
+	maxWorkers int
+	// localityOrder renumbers objects in BFS order before computing dominators.
+	localityOrder bool
+	// dominatorAlgorithm forces the dominator algorithm. Auto selects it from the graph size.
+	dominatorAlgorithm DominatorAlgorithm
+	// retainerExactBudget is the time multi-level retainer analysis may spend
+	// walking every instance of a class. 0 samples large classes.
+	retainerExactBudget time.Duration
 
 	// Retained size calculation strategy (pluggable)
 	retainedSizeCalculatorRegistry *RetainedSizeCalculatorRegistry
@@ -147,6 +161,30 @@ func (g *ReferenceGraph) SetLogger(logger utils.Logger) {
 	g.logger = logger
 }
 
+// SetMaxWorkers caps the workers of the dominator computation, 0 for no cap.
+func (g *ReferenceGraph) SetMaxWorkers(n int) {
+	g.maxWorkers = n
+}
+
+// SetLocalityOrder renumbers objects in BFS order, with sorted references,
+// before computing dominators, for cache locality on large graphs.
+func (g *ReferenceGraph) SetLocalityOrder(enabled bool) {
+	g.localityOrder = enabled
+}
+
+// SetDominatorAlgorithm forces the algorithm computing the dominator tree,
+// DominatorAlgorithmAuto to select it with SelectDominatorAlgorithm.
+func (g *ReferenceGraph) SetDominatorAlgorithm(algorithm DominatorAlgorithm) {
+	g.dominatorAlgorithm = algorithm
+}
+
+// SetRetainerExactBudget lets multi-level retainer analysis walk every
+// instance of a class for up to budget, instead of a sample of large classes.
+// Classes that take longer fall back to the sample. 0 always samples.
+func (g *ReferenceGraph) SetRetainerExactBudget(budget time.Duration) {
+	g.retainerExactBudget = budget
+}
+
 // debugf logs a debug message if logger is configured.
 func (g *ReferenceGraph) debugf(format string, args ...interface{}) {
 	if g.logger != nil {
@@ -154,6 +192,13 @@ func (g *ReferenceGraph) debugf(format string, args ...interface{}) {
 	}
 }
 
+// warnf logs a warning if logger is configured.
+func (g *ReferenceGraph) warnf(format string, args ...interface{}) {
+	if g.logger != nil {
+		g.logger.Warn(format, args...)
+	}
+}
+
 // NewReferenceGraph creates a new reference graph.
 func NewReferenceGraph() *ReferenceGraph {
 	return NewReferenceGraphWithCapacity(0)
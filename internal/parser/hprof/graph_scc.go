@@ -0,0 +1,208 @@
+package hprof
+
+import (
+	"fmt"
+)
+
+// SCCIndex holds the strongly connected components of a reference graph and
+// its condensation: the DAG with an edge from one component to another when
+// an object of the first references an object of the second. An object
+// reaches another exactly when its component reaches theirs, so
+// reachability queries explore the condensation instead of the objects.
+type SCCIndex struct {
+	g *ReferenceGraph
+	// component is the component of every object index. Components are
+	// numbered in the order Tarjan's algorithm completes them, so the
+	// components reachable from a component have lower numbers.
+	component []int32
+	// sizes is the number of objects of every component
+	sizes []int32
+	// offsets and targets are the condensation edges of every component
+	offsets csrOffsets
+	targets []int32
+}
+
+// sccFrame is a node of the explicit call stack of Tarjan's algorithm and
+// the position of the next reference to follow.
+type sccFrame struct {
+	v    int32
+	edge int
+}
+
+// ComputeSCCs computes the strongly connected components of g with an
+// iterative Tarjan's algorithm, following every reference of the graph.
+func (g *ReferenceGraph) ComputeSCCs() (*SCCIndex, error) {
+	g.buildOutgoingRefsByIndex()
+	n := len(g.indexToObjectID)
+	if err := checkDominatorCapacity(n); err != nil {
+		return nil, err
+	}
+
+	x := &SCCIndex{g: g, component: make([]int32, n)}
+	order := make([]int32, n) // discovery order from 1, 0 for unvisited nodes
+	low := make([]int32, n)
+	onStack := make([]bool, n)
+	var stack []int32
+	var calls []sccFrame
+	var counter int32
+	visit := func(v int32) {
+		counter++
+		order[v], low[v] = counter, counter
+		stack = append(stack, v)
+		onStack[v] = true
+		calls = append(calls, sccFrame{v: v})
+	}
+
+	for root := int32(0); root < int32(n); root++ {
+		if order[root] != 0 {
+			continue
+		}
+		visit(root)
+		for len(calls) > 0 {
+			f := &calls[len(calls)-1]
+			refs := g.outgoingRefsByIndex[f.v]
+			if f.edge < len(refs) {
+				w := int32(refs[f.edge].ToIndex)
+				f.edge++
+				if order[w] == 0 {
+					visit(w)
+				} else if onStack[w] && order[w] < low[f.v] {
+					low[f.v] = order[w]
+				}
+				continue
+			}
+
+			v := f.v
+			calls = calls[:len(calls)-1]
+			if len(calls) > 0 {
+				if p := calls[len(calls)-1].v; low[v] < low[p] {
+					low[p] = low[v]
+				}
+			}
+			if low[v] != order[v] {
+				continue
+			}
+			// v is the root of a component: pop its objects
+			c := int32(len(x.sizes))
+			var size int32
+			for {
+				w := stack[len(stack)-1]
+				stack = stack[:len(stack)-1]
+				onStack[w] = false
+				x.component[w] = c
+				size++
+				if w == v {
+					break
+				}
+			}
+			x.sizes = append(x.sizes, size)
+		}
+	}
+
+	x.buildCondensation()
+	return x, nil
+}
+
+// buildCondensation builds the deduplicated edges between components.
+func (x *SCCIndex) buildCondensation() {
+	count := len(x.sizes)
+
+	// Group the objects by component
+	start := make([]int32, count+1)
+	for c, size := range x.sizes {
+		start[c+1] = start[c] + size
+	}
+	members := make([]int32, len(x.component))
+	next := append([]int32(nil), start[:count]...)
+	for v, c := range x.component {
+		members[next[c]] = int32(v)
+		next[c]++
+	}
+
+	// last is the latest component an edge to each component was added for
+	last := make([]int32, count)
+	for c := range last {
+		last[c] = -1
+	}
+	counts := make([]int32, count)
+	for c := int32(0); c < int32(count); c++ {
+		for _, v := range members[start[c]:start[c+1]] {
+			for _, ref := range x.g.outgoingRefsByIndex[v] {
+				d := x.component[ref.ToIndex]
+				if d != c && last[d] != c {
+					last[d] = c
+					x.targets = append(x.targets, d)
+					counts[c]++
+				}
+			}
+		}
+	}
+	x.offsets = newCSROffsets(counts)
+}
+
+// ComponentCount returns the number of strongly connected components.
+func (x *SCCIndex) ComponentCount() int {
+	return len(x.sizes)
+}
+
+// componentOf returns the component of an object.
+func (x *SCCIndex) componentOf(objectID uint64) (int32, error) {
+	idx, ok := x.g.objectIDToIndex[objectID]
+	if !ok {
+		return 0, fmt.Errorf("object not found: 0x%x", objectID)
+	}
+	return x.component[idx], nil
+}
+
+// Reachable reports, for every object of from and every object of to,
+// whether the second is reachable from the first through references.
+// reachable[i][j] is set when to[j] is reachable from from[i]; every object
+// reaches itself. Each object of from costs a traversal of the part of the
+// condensation that may lead to the objects of to.
+func (x *SCCIndex) Reachable(from, to []uint64) ([][]bool, error) {
+	sources := make([]int32, len(from))
+	for i, id := range from {
+		c, err := x.componentOf(id)
+		if err != nil {
+			return nil, err
+		}
+		sources[i] = c
+	}
+	targets := make([]int32, len(to))
+	minTarget := int32(len(x.sizes))
+	for j, id := range to {
+		c, err := x.componentOf(id)
+		if err != nil {
+			return nil, err
+		}
+		targets[j] = c
+		minTarget = min(minTarget, c)
+	}
+
+	// visited holds the number of the last traversal reaching each component
+	visited := make([]int32, len(x.sizes))
+	var queue []int32
+	reachable := make([][]bool, len(from))
+	for i, source := range sources {
+		stamp := int32(i + 1)
+		visited[source] = stamp
+		queue = append(queue[:0], source)
+		for len(queue) > 0 {
+			c := queue[len(queue)-1]
+			queue = queue[:len(queue)-1]
+			begin, end := x.offsets.span(c)
+			for _, d := range x.targets[begin:end] {
+				// Components below every target only reach lower ones
+				if d >= minTarget && visited[d] != stamp {
+					visited[d] = stamp
+					queue = append(queue, d)
+				}
+			}
+		}
+		reachable[i] = make([]bool, len(to))
+		for j, target := range targets {
+			reachable[i][j] = visited[target] == stamp
+		}
+	}
+	return reachable, nil
+}
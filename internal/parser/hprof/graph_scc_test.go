@@ -0,0 +1,72 @@
+package hprof
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newSCCTestGraph returns a graph with two cycles: 1 -> 2 -> 3 -> 1 leading to
+// 4 <-> 5, which leads to 6; 7 references 1 and 8 is isolated.
+func newSCCTestGraph() *ReferenceGraph {
+	g := NewReferenceGraphWithCapacity(8)
+	g.SetClassName(1000, "com.example.Node")
+	for id := uint64(1); id <= 8; id++ {
+		g.SetObjectInfo(id, 1000, 16)
+	}
+	for _, edge := range [][2]uint64{{1, 2}, {2, 3}, {3, 1}, {3, 4}, {4, 5}, {5, 4}, {5, 6}, {7, 1}, {2, 4}} {
+		g.AddReference(ObjectReference{FromObjectID: edge[0], ToObjectID: edge[1], FieldName: "next", FromClassID: 1000})
+	}
+	return g
+}
+
+func TestReferenceGraph_ComputeSCCs(t *testing.T) {
+	g := newSCCTestGraph()
+	scc, err := g.ComputeSCCs()
+	require.NoError(t, err)
+	// {1,2,3}, {4,5}, {6}, {7}, {8}
+	assert.Equal(t, 5, scc.ComponentCount())
+
+	component := func(id uint64) int32 {
+		c, err := scc.componentOf(id)
+		require.NoError(t, err)
+		return c
+	}
+	assert.Equal(t, component(1), component(2))
+	assert.Equal(t, component(1), component(3))
+	assert.Equal(t, component(4), component(5))
+	assert.NotEqual(t, component(1), component(4))
+	// Reachable components are numbered lower
+	assert.Less(t, component(4), component(1))
+	assert.Less(t, component(6), component(4))
+	assert.Less(t, component(1), component(7))
+
+	// Edges between components are deduplicated: 2 -> 4 and 3 -> 4
+	begin, end := scc.offsets.span(component(1))
+	assert.Equal(t, []int32{component(4)}, scc.targets[begin:end])
+}
+
+func TestSCCIndex_Reachable(t *testing.T) {
+	scc, err := newSCCTestGraph().ComputeSCCs()
+	require.NoError(t, err)
+
+	reachable, err := scc.Reachable([]uint64{7, 4, 8, 3}, []uint64{1, 6, 5, 8})
+	require.NoError(t, err)
+	assert.Equal(t, [][]bool{
+		{true, true, true, false},
+		{false, true, true, false},
+		{false, false, false, true},
+		{true, true, true, false},
+	}, reachable)
+
+	_, err = scc.Reachable([]uint64{1}, []uint64{99})
+	assert.Error(t, err)
+}
+
+func TestHeapSnapshot_Reachability(t *testing.T) {
+	snap := NewHeapSnapshot(newSCCTestGraph(), nil, nil)
+	reachable, err := snap.Reachability([]uint64{6, 7}, []uint64{7, 6})
+	require.NoError(t, err)
+	assert.Equal(t, [][]bool{{false, true}, {true, true}}, reachable)
+}
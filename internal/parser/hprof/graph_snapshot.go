@@ -0,0 +1,417 @@
+// Package hprof provides parsing functionality for Java HPROF heap dump files.
+package hprof
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+)
+
+// RefGraphFileName is the serialized reference graph in a task directory.
+const RefGraphFileName = "refgraph.bin"
+
+// HeapSnapshot is a frozen, read-only view of an analyzed heap for serving
+// concurrent queries (e.g. the web UI).
+//
+// ReferenceGraph builds several indexes lazily on first use (class-to-objects,
+// dominator tree, object index, ...), so concurrent queries against a plain
+// graph race with each other. NewHeapSnapshot builds all of them up front;
+// afterwards every snapshot method is a pure read and safe for concurrent use.
+// Slices returned by the snapshot are copies and may be modified by callers.
+type HeapSnapshot struct {
+	graph   *ReferenceGraph
+	builder *BiggestObjectsBuilder
+	bytes   int64
+	// objects reads field values from the heap dump (nil without an object index)
+	objects *ObjectReader
+
+	// The SCC index is only needed by reachability queries, so it is
+	// computed by the first of them rather than by NewHeapSnapshot.
+	sccOnce sync.Once
+	scc     *SCCIndex
+	sccErr  error
+}
+
+// Rough resident cost of a frozen graph, including lazily built indexes.
+// Per object: class/size/dominator/retained-size map entries plus index arrays.
+// Per reference: the incoming and outgoing ObjectReference copies plus indexed refs.
+const (
+	snapshotBytesPerObject    = 320
+	snapshotBytesPerReference = 128
+)
+
+// NewHeapSnapshot freezes g into a HeapSnapshot.
+// The snapshot takes ownership of g: callers must not modify g afterwards.
+// classLayouts and strings are optional and enable field inspection.
+func NewHeapSnapshot(g *ReferenceGraph, classLayouts map[uint64]*ClassFieldLayout, strings map[uint64]string) *HeapSnapshot {
+	g.freeze()
+
+	refCount := 0
+	for _, refs := range g.outgoingRefs {
+		refCount += len(refs)
+	}
+	return &HeapSnapshot{
+		graph:   g,
+		builder: NewBiggestObjectsBuilder(g, classLayouts, strings),
+		bytes:   int64(len(g.objectClass))*snapshotBytesPerObject + int64(refCount)*snapshotBytesPerReference,
+	}
+}
+
+// EstimatedBytes returns a rough estimate of the memory held by the snapshot.
+func (s *HeapSnapshot) EstimatedBytes() int64 {
+	return s.bytes
+}
+
+// Snapshot returns a frozen snapshot of the analysis result's reference graph,
+// or nil if the graph was not retained.
+func (r *HeapAnalysisResult) Snapshot() *HeapSnapshot {
+	if r.RefGraph == nil {
+		return nil
+	}
+	snapshot := NewHeapSnapshot(r.RefGraph, r.ClassLayouts, r.Strings)
+	if r.ObjectIndex != nil {
+		snapshot.AttachObjectIndex(r.ObjectIndex)
+	}
+	return snapshot
+}
+
+// LoadHeapSnapshot loads the reference graph of an analyzed heap dump from its
+// task directory. Class layouts and the object index are attached when present.
+func LoadHeapSnapshot(taskDir string) (*HeapSnapshot, error) {
+	g, err := LoadReferenceGraph(taskDir)
+	if err != nil {
+		return nil, err
+	}
+
+	var classLayouts map[uint64]*ClassFieldLayout
+	if data, err := os.ReadFile(filepath.Join(taskDir, ClassLayoutsFileName)); err == nil {
+		json.Unmarshal(data, &classLayouts)
+	}
+
+	snapshot := NewHeapSnapshot(g, classLayouts, nil)
+	// Field values are read from the heap dump when it was indexed
+	if index, err := ReadObjectIndexFile(filepath.Join(taskDir, ObjectIndexFileName)); err == nil {
+		snapshot.AttachObjectIndex(index)
+	}
+	return snapshot, nil
+}
+
+// LoadReferenceGraph loads the reference graph of an analyzed heap dump from
+// its task directory. Unlike LoadHeapSnapshot, the graph is not frozen, so
+// its dominators can be recomputed, e.g. by DiffAnalyzer.DiffObjects.
+func LoadReferenceGraph(taskDir string) (*ReferenceGraph, error) {
+	g, err := DeserializeReferenceGraphFromFile(filepath.Join(taskDir, RefGraphFileName))
+	if err != nil {
+		return nil, fmt.Errorf("failed to load reference graph: %w", err)
+	}
+	return g, nil
+}
+
+// AttachObjectIndex enables reading field values and array contents from the
+// heap dump the index was built from. It must be called before the snapshot
+// is shared.
+func (s *HeapSnapshot) AttachObjectIndex(index *ObjectIndex) {
+	s.objects = NewObjectReader(index, s.builder.classLayouts)
+}
+
+// freeze eagerly computes everything that read paths would otherwise build lazily.
+func (g *ReferenceGraph) freeze() {
+	g.ComputeDominatorTree()
+	g.buildClassToObjectsIndex()
+	g.buildClassNameToIDIndex()
+	g.BuildFieldNameIndex()
+	g.buildObjectIndex()
+	g.buildIndexedIncomingRefs()
+	g.buildDominatorByIndex()
+	g.buildOutgoingRefsByIndex()
+	g.buildIncomingRefsByIndex()
+	g.buildDominatorChildren()
+}
+
+// Reachability reports whether every object of to is reachable from every
+// object of from; see SCCIndex.Reachable.
+func (s *HeapSnapshot) Reachability(from, to []uint64) ([][]bool, error) {
+	s.sccOnce.Do(func() {
+		s.scc, s.sccErr = s.graph.ComputeSCCs()
+	})
+	if s.sccErr != nil {
+		return nil, s.sccErr
+	}
+	return s.scc.Reachable(from, to)
+}
+
+// ObjectCount returns the number of objects in the snapshot.
+func (s *HeapSnapshot) ObjectCount() int {
+	return s.graph.GetObjectCount()
+}
+
+// ReachableObjectCount returns the number of objects reachable from GC roots.
+func (s *HeapSnapshot) ReachableObjectCount() int {
+	return s.graph.GetReachableObjectCount()
+}
+
+// ClassName returns the name of a class.
+func (s *HeapSnapshot) ClassName(classID uint64) string {
+	return s.graph.GetClassName(classID)
+}
+
+// ObjectClassID returns the class ID of an object.
+func (s *HeapSnapshot) ObjectClassID(objectID uint64) (uint64, bool) {
+	return s.graph.GetObjectClassID(objectID)
+}
+
+// ObjectSize returns the shallow size of an object.
+func (s *HeapSnapshot) ObjectSize(objectID uint64) int64 {
+	return s.graph.GetObjectSize(objectID)
+}
+
+// RetainedSize returns the retained size of an object.
+func (s *HeapSnapshot) RetainedSize(objectID uint64) int64 {
+	return s.graph.GetRetainedSize(objectID)
+}
+
+// ClassNames returns the names of the classes with instances, sorted.
+func (s *HeapSnapshot) ClassNames() []string {
+	names := make([]string, 0, len(s.graph.classToObjects))
+	for classID, objects := range s.graph.classToObjects {
+		if len(objects) > 0 {
+			names = append(names, s.graph.GetClassName(classID))
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ClassHistogram returns the instance count, shallow and retained size of
+// every class with instances, sorted by shallow size descending.
+func (s *HeapSnapshot) ClassHistogram() []*ClassStats {
+	g := s.graph
+	var totalSize int64
+	histogram := make([]*ClassStats, 0, len(g.classToObjects))
+	for classID, objects := range g.classToObjects {
+		if len(objects) == 0 {
+			continue
+		}
+		stats := &ClassStats{
+			ClassName:     g.GetClassName(classID),
+			InstanceCount: int64(len(objects)),
+			RetainedSize:  g.classRetainedSizes[classID],
+		}
+		for _, objectID := range objects {
+			stats.TotalSize += g.objectSize[objectID]
+		}
+		stats.ShallowSize = stats.TotalSize
+		stats.setClassLoader(g, s.builder.classLayouts, classID)
+		stats.AvgSize = float64(stats.TotalSize) / float64(stats.InstanceCount)
+		totalSize += stats.TotalSize
+		histogram = append(histogram, stats)
+	}
+	for _, stats := range histogram {
+		if totalSize > 0 {
+			stats.Percentage = float64(stats.TotalSize) * 100 / float64(totalSize)
+		}
+	}
+	sort.Slice(histogram, func(i, j int) bool {
+		if histogram[i].TotalSize != histogram[j].TotalSize {
+			return histogram[i].TotalSize > histogram[j].TotalSize
+		}
+		return histogram[i].ClassName < histogram[j].ClassName
+	})
+	return histogram
+}
+
+// ClassRetainers returns the classes and fields referencing instances of a
+// class, or nil if the class has no instances.
+func (s *HeapSnapshot) ClassRetainers(className string, topN int) *ClassRetainers {
+	return s.graph.ComputeRetainersForClass(className, topN)
+}
+
+// DominatorRetainers returns the classes whose instances dominate the
+// instances of a class, or nil if the class has no reachable instances.
+func (s *HeapSnapshot) DominatorRetainers(className string, topN int) *ClassRetainers {
+	return s.graph.ComputeDominatorRetainers(className, topN)
+}
+
+// ClassRetainedSizes returns the retained size of every class, keyed by class name.
+func (s *HeapSnapshot) ClassRetainedSizes() map[string]int64 {
+	return s.graph.GetClassRetainedSizes()
+}
+
+// IsGCRoot reports whether an object is a GC root.
+func (s *HeapSnapshot) IsGCRoot(objectID uint64) bool {
+	return s.graph.IsGCRoot(objectID)
+}
+
+// IsReachable reports whether an object is reachable from GC roots.
+func (s *HeapSnapshot) IsReachable(objectID uint64) bool {
+	return s.graph.IsObjectReachable(objectID)
+}
+
+// IncomingRefs returns the references pointing to an object.
+func (s *HeapSnapshot) IncomingRefs(objectID uint64) []ObjectReference {
+	return append([]ObjectReference(nil), s.graph.GetIncomingRefs(objectID)...)
+}
+
+// OutgoingRefs returns the references held by an object.
+func (s *HeapSnapshot) OutgoingRefs(objectID uint64) []ObjectReference {
+	return append([]ObjectReference(nil), s.graph.GetOutgoingRefs(objectID)...)
+}
+
+// PathsToGCRoot finds up to maxPaths paths of at most maxDepth from GC roots to an object.
+func (s *HeapSnapshot) PathsToGCRoot(objectID uint64, maxPaths, maxDepth int) []*GCRootPath {
+	return s.graph.FindPathsToGCRoot(objectID, maxPaths, maxDepth)
+}
+
+// PathsToGCRootExcluding is PathsToGCRoot without following the referents of
+// the Reference kinds selected by exclude.
+func (s *HeapSnapshot) PathsToGCRootExcluding(objectID uint64, maxPaths, maxDepth int, exclude PathExclusion) []*GCRootPath {
+	return s.graph.FindPathsToGCRootExcluding(objectID, maxPaths, maxDepth, exclude)
+}
+
+// GCRootsList returns all GC roots sorted by retained size descending.
+func (s *HeapSnapshot) GCRootsList() []*GCRootInfo {
+	return s.graph.GetGCRootsList()
+}
+
+// GCRootsSummary returns GC roots grouped by class.
+func (s *HeapSnapshot) GCRootsSummary() []*GCRootSummary {
+	return s.graph.GetGCRootsSummary()
+}
+
+// GCRoot returns the information of a GC root, or false if objectID is not
+// a GC root.
+func (s *HeapSnapshot) GCRoot(objectID uint64) (*GCRootInfo, bool) {
+	return s.graph.GetGCRootInfo(objectID)
+}
+
+// RetainedObjectsByGCRoot returns the objects directly referenced by a GC root.
+func (s *HeapSnapshot) RetainedObjectsByGCRoot(rootObjectID uint64, maxObjects int) []*GCRootInfo {
+	return s.graph.GetRetainedObjectsByGCRoot(rootObjectID, maxObjects)
+}
+
+// DominatorRoots returns the top-level objects of the dominator tree,
+// sorted by retained size descending.
+func (s *HeapSnapshot) DominatorRoots() []uint64 {
+	return s.DominatorChildren(superRootID)
+}
+
+// DominatorChildren returns the objects immediately dominated by objectID,
+// sorted by retained size descending.
+func (s *HeapSnapshot) DominatorChildren(objectID uint64) []uint64 {
+	return append([]uint64(nil), s.graph.GetDominatorChildren(objectID)...)
+}
+
+// DominatorTreeSlice returns the top of the dominator tree in depth-first order.
+func (s *HeapSnapshot) DominatorTreeSlice(maxDepth, maxChildren int) []*DominatorTreeNode {
+	return s.graph.GetDominatorTreeSlice(maxDepth, maxChildren)
+}
+
+// DominatorTree returns the index-based form of the snapshot's dominator tree.
+// Each call builds a new tree; callers should cache the result.
+func (s *HeapSnapshot) DominatorTree() *DominatorTree {
+	return NewDominatorTree(s.graph)
+}
+
+// ObjectFields returns the fields of an object for tree expansion. With an
+// object index attached, primitive fields carry their values and references
+// to Strings a preview of their text.
+func (s *HeapSnapshot) ObjectFields(objectID uint64) []*ObjectFieldDetail {
+	fields := s.builder.GetObjectFields(objectID)
+	if s.objects == nil || len(fields) == 0 {
+		return fields
+	}
+	content, err := s.objects.ReadObject(objectID, 0)
+	if err != nil || content.Kind != ObjectKindInstance {
+		return fields
+	}
+	values := make(map[string]*FieldValue, len(content.Fields))
+	for _, v := range content.Fields {
+		if _, ok := values[v.Name]; !ok {
+			values[v.Name] = v
+		}
+	}
+	for _, field := range fields {
+		if v, ok := values[field.Name]; ok {
+			field.Value = v.Value
+			field.StringValue = v.StringValue
+		}
+	}
+	return fields
+}
+
+// ObjectContent reads the field values of an instance, or up to maxElements
+// elements of an array, from the heap dump. It fails when no object index is
+// attached or the heap dump is no longer available.
+func (s *HeapSnapshot) ObjectContent(objectID uint64, maxElements int) (*ObjectContent, error) {
+	if err := s.checkObjectReadable(objectID); err != nil {
+		return nil, err
+	}
+	classID := s.graph.objectClass[objectID]
+	content, err := s.objects.ReadObject(objectID, maxElements)
+	if err != nil {
+		return nil, err
+	}
+	if name := s.graph.GetClassName(classID); name != "" {
+		content.ClassName = name
+	}
+	content.ShallowSize = s.graph.objectSize[objectID]
+	content.RetainedSize = s.graph.GetRetainedSize(objectID)
+	for _, values := range [][]*FieldValue{content.Fields, content.Elements} {
+		for _, v := range values {
+			if v.RefID == 0 {
+				continue
+			}
+			if refClassID, ok := s.graph.objectClass[v.RefID]; ok {
+				v.RefClass = s.graph.GetClassName(refClassID)
+				v.ShallowSize = s.graph.objectSize[v.RefID]
+				v.RetainedSize = s.graph.GetRetainedSize(v.RefID)
+			}
+		}
+	}
+	return content, nil
+}
+
+// ObjectPayload reads up to maxBytes bytes of the contents of a primitive
+// array or String from offset on, see ObjectReader.ReadPayload.
+func (s *HeapSnapshot) ObjectPayload(objectID uint64, offset int64, maxBytes int) (*ObjectPayload, error) {
+	if err := s.checkObjectReadable(objectID); err != nil {
+		return nil, err
+	}
+	return s.objects.ReadPayload(objectID, offset, maxBytes)
+}
+
+// WriteObjectPayload writes the whole contents of a primitive array or
+// String to w, returning their size.
+func (s *HeapSnapshot) WriteObjectPayload(w io.Writer, objectID uint64) (int64, error) {
+	if err := s.checkObjectReadable(objectID); err != nil {
+		return 0, err
+	}
+	return s.objects.WritePayload(w, objectID)
+}
+
+// checkObjectReadable fails when the contents of an object cannot be read
+// from the heap dump.
+func (s *HeapSnapshot) checkObjectReadable(objectID uint64) error {
+	if s.objects == nil {
+		return fmt.Errorf("object contents are not available: heap dump was not indexed")
+	}
+	if _, ok := s.graph.objectClass[objectID]; !ok {
+		return fmt.Errorf("object 0x%x not found", objectID)
+	}
+	return nil
+}
+
+// ObjectInfo returns basic information about an object, or nil if it does not exist.
+func (s *HeapSnapshot) ObjectInfo(objectID uint64) *ObjectFieldDetail {
+	return s.builder.GetObjectInfo(objectID)
+}
+
+// BiggestObjectsByClass returns the biggest instances of a class.
+// sortBy is "retained" or "shallow".
+func (s *HeapSnapshot) BiggestObjectsByClass(className string, topN int, sortBy string) []*BiggestObject {
+	return s.builder.BuildBiggestObjectsByClass(className, topN, sortBy)
+}
@@ -0,0 +1,150 @@
+package hprof
+
+import (
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHeapSnapshot_Queries(t *testing.T) {
+	snap := NewHeapSnapshot(newRollupTestGraph(), nil, nil)
+
+	assert.Equal(t, 5, snap.ObjectCount())
+	assert.Equal(t, int64(5*snapshotBytesPerObject+3*snapshotBytesPerReference), snap.EstimatedBytes())
+	assert.Equal(t, "com.example.Cache", snap.ClassName(1000))
+	assert.Equal(t, int64(32+48+4096), snap.RetainedSize(300))
+	assert.Equal(t, []uint64{300, 500}, snap.DominatorRoots())
+	assert.Equal(t, []uint64{200}, snap.DominatorChildren(300))
+
+	paths := snap.PathsToGCRoot(400, 3, 10)
+	require.NotEmpty(t, paths)
+	assert.Equal(t, uint64(300), paths[0].Path[0].ObjectID)
+
+	objects := snap.BiggestObjectsByClass("byte[]", 10, "retained")
+	require.Len(t, objects, 2)
+	assert.Equal(t, uint64(400), objects[0].ObjectID)
+}
+
+func TestHeapSnapshot_PathsToGCRootExcluding(t *testing.T) {
+	g := NewReferenceGraphWithCapacity(16)
+	g.SetClassName(5000, "com.example.CacheRef")
+	g.SetClassName(5001, "java.lang.ref.WeakReference")
+	g.SetClassName(5002, "java.util.HashMap")
+	g.SetClassName(5003, "byte[]")
+	g.SetObjectInfo(100, 5002, 48)
+	g.SetObjectInfo(700, 5000, 32)
+	g.SetObjectInfo(150, 5002, 48)
+	g.SetObjectInfo(900, 5002, 48)
+	g.SetObjectInfo(800, 5003, 1024)
+
+	// 100 -> weak ref 700 -> 800 and 150 -> 900 -> 800
+	g.AddReference(ObjectReference{FromObjectID: 5000, ToObjectID: 5001, FromClassID: 5000, FieldName: "<superclass>"})
+	g.AddReference(ObjectReference{FromObjectID: 100, ToObjectID: 700, FromClassID: 5002, FieldName: "ref"})
+	g.AddReference(ObjectReference{FromObjectID: 700, ToObjectID: 800, FromClassID: 5000, FieldName: "referent"})
+	g.AddReference(ObjectReference{FromObjectID: 150, ToObjectID: 900, FromClassID: 5002, FieldName: "map"})
+	g.AddReference(ObjectReference{FromObjectID: 900, ToObjectID: 800, FromClassID: 5002, FieldName: "value"})
+	g.AddGCRoot(&GCRoot{ObjectID: 100, Type: GCRootJNIGlobal})
+	g.AddGCRoot(&GCRoot{ObjectID: 150, Type: GCRootStickyClass})
+	snap := NewHeapSnapshot(g, nil, nil)
+
+	// Each path is reported once even though the search deepens past it
+	paths := snap.PathsToGCRoot(800, 5, 10)
+	require.Len(t, paths, 2)
+	assert.Equal(t, "referent", paths[0].Path[2].FieldName)
+
+	paths = snap.PathsToGCRootExcluding(800, 5, 10, ExcludeWeakRefs)
+	require.Len(t, paths, 1)
+	assert.Equal(t, GCRootStickyClass, paths[0].RootType)
+	assert.Equal(t, "value", paths[0].Path[2].FieldName)
+
+	assert.Len(t, snap.PathsToGCRootExcluding(800, 5, 10, ExcludeSoftRefs|ExcludePhantomRefs), 2)
+}
+
+func TestHeapSnapshot_ReturnsCopies(t *testing.T) {
+	snap := NewHeapSnapshot(newRollupTestGraph(), nil, nil)
+
+	refs := snap.IncomingRefs(400)
+	require.Len(t, refs, 1)
+	refs[0].FromObjectID = 0
+	assert.Equal(t, uint64(200), snap.IncomingRefs(400)[0].FromObjectID)
+
+	roots := snap.DominatorRoots()
+	roots[0] = 0
+	assert.Equal(t, uint64(300), snap.DominatorRoots()[0])
+}
+
+// TestHeapSnapshot_ConcurrentQueries exercises every query path in parallel;
+// run with -race to detect lazy initialization on read paths.
+func TestHeapSnapshot_ConcurrentQueries(t *testing.T) {
+	snap := NewHeapSnapshot(newRollupTestGraph(), nil, nil)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 20; j++ {
+				snap.RetainedSize(400)
+				snap.ReachableObjectCount()
+				snap.ClassRetainedSizes()
+				snap.PathsToGCRoot(600, 3, 10)
+				snap.GCRootsSummary()
+				snap.RetainedObjectsByGCRoot(300, 10)
+				snap.DominatorTreeSlice(3, 10)
+				snap.ObjectFields(300)
+				snap.ObjectInfo(200)
+				snap.BiggestObjectsByClass("byte[]", 10, "shallow")
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestHeapAnalysisResult_Snapshot(t *testing.T) {
+	assert.Nil(t, (&HeapAnalysisResult{}).Snapshot())
+
+	result := &HeapAnalysisResult{RefGraph: newRollupTestGraph()}
+	snap := result.Snapshot()
+	require.NotNil(t, snap)
+	assert.True(t, snap.IsGCRoot(500))
+	assert.True(t, snap.IsReachable(600))
+}
+
+func TestHeapSnapshot_ClassHistogram(t *testing.T) {
+	snap := NewHeapSnapshot(newRollupTestGraph(), nil, nil)
+
+	histogram := snap.ClassHistogram()
+	require.Len(t, histogram, 4)
+	assert.Equal(t, "byte[]", histogram[0].ClassName)
+	assert.Equal(t, int64(2), histogram[0].InstanceCount)
+	assert.Equal(t, int64(4096+2048), histogram[0].TotalSize)
+	assert.Equal(t, float64(3072), histogram[0].AvgSize)
+	assert.InDelta(t, 6144*100.0/(32+48+4096+120+2048), histogram[0].Percentage, 0.001)
+	assert.Equal(t, "java.lang.Thread", histogram[1].ClassName)
+
+	assert.Equal(t, []string{"byte[]", "com.example.Cache", "java.lang.Thread", "java.util.HashMap"}, snap.ClassNames())
+
+	retainers := snap.ClassRetainers("byte[]", 10)
+	require.NotNil(t, retainers)
+	require.Len(t, retainers.Retainers, 2)
+	assert.Equal(t, "java.util.HashMap", retainers.Retainers[0].RetainerClass)
+	assert.Equal(t, "table", retainers.Retainers[0].FieldName)
+	assert.Nil(t, snap.ClassRetainers("com.example.Missing", 10))
+}
+
+func TestLoadHeapSnapshot(t *testing.T) {
+	taskDir := t.TempDir()
+	_, err := newRollupTestGraph().SerializeToFile(filepath.Join(taskDir, RefGraphFileName), DefaultSerializeOptions())
+	require.NoError(t, err)
+
+	snap, err := LoadHeapSnapshot(taskDir)
+	require.NoError(t, err)
+	assert.Equal(t, 5, snap.ObjectCount())
+	assert.Equal(t, int64(32+48+4096), snap.RetainedSize(300))
+
+	_, err = LoadHeapSnapshot(t.TempDir())
+	assert.Error(t, err)
+}
@@ -0,0 +1,230 @@
+package hprof
+
+import (
+	"bytes"
+	"encoding/binary"
+)
+
+// testHprofBuilder assembles minimal HPROF files for tests.
+// All IDs are written as 8-byte identifiers.
+type testHprofBuilder struct {
+	buf     bytes.Buffer
+	heap    bytes.Buffer
+	nextStr uint64
+	strIDs  map[string]uint64
+}
+
+// newTestHprofBuilder creates a builder with the standard header already written.
+func newTestHprofBuilder() *testHprofBuilder {
+	b := &testHprofBuilder{nextStr: 1, strIDs: make(map[string]uint64)}
+	b.buf.WriteString("JAVA PROFILE 1.0.2")
+	b.buf.WriteByte(0)
+	binary.Write(&b.buf, binary.BigEndian, uint32(8))
+	binary.Write(&b.buf, binary.BigEndian, uint64(0))
+	return b
+}
+
+// record writes a top-level record with the given tag and body.
+func (b *testHprofBuilder) record(tag RecordTag, body []byte) {
+	b.buf.WriteByte(byte(tag))
+	binary.Write(&b.buf, binary.BigEndian, uint32(0))
+	binary.Write(&b.buf, binary.BigEndian, uint32(len(body)))
+	b.buf.Write(body)
+}
+
+// str interns a string and emits a STRING record on first use.
+func (b *testHprofBuilder) str(s string) uint64 {
+	if id, ok := b.strIDs[s]; ok {
+		return id
+	}
+	id := 0x100000 + b.nextStr
+	b.nextStr++
+	b.strIDs[s] = id
+	var body bytes.Buffer
+	binary.Write(&body, binary.BigEndian, id)
+	body.WriteString(s)
+	b.record(TagString, body.Bytes())
+	return id
+}
+
+// loadClass emits a LOAD_CLASS record binding classID to a JVM class name.
+func (b *testHprofBuilder) loadClass(classID uint64, name string) {
+	b.loadClassSerial(0, classID, name)
+}
+
+// loadClassSerial emits a LOAD_CLASS record with a class serial number.
+func (b *testHprofBuilder) loadClassSerial(serial uint32, classID uint64, name string) {
+	nameID := b.str(name)
+	var body bytes.Buffer
+	binary.Write(&body, binary.BigEndian, serial)
+	binary.Write(&body, binary.BigEndian, classID)
+	binary.Write(&body, binary.BigEndian, uint32(0))
+	binary.Write(&body, binary.BigEndian, nameID)
+	b.record(TagLoadClass, body.Bytes())
+}
+
+// testField describes an instance field for classDump.
+type testField struct {
+	name string
+	typ  BasicType
+}
+
+// classDump appends a CLASS_DUMP sub-record with the given instance fields.
+func (b *testHprofBuilder) classDump(classID, superID uint64, instanceSize uint32, fields ...testField) {
+	b.classDumpLoader(classID, superID, 0, instanceSize, fields...)
+}
+
+// testStatic is a static field of a test class with its raw value.
+type testStatic struct {
+	name  string
+	typ   BasicType
+	value []byte
+}
+
+// classDumpLoader is classDump for a class defined by a class loader.
+func (b *testHprofBuilder) classDumpLoader(classID, superID, loaderID uint64, instanceSize uint32, fields ...testField) {
+	b.classDumpStatics(classID, superID, loaderID, instanceSize, nil, fields...)
+}
+
+// classDumpStatics is classDumpLoader for a class with static fields.
+func (b *testHprofBuilder) classDumpStatics(classID, superID, loaderID uint64, instanceSize uint32, statics []testStatic, fields ...testField) {
+	staticIDs := make([]uint64, len(statics))
+	for i, sf := range statics {
+		staticIDs[i] = b.str(sf.name)
+	}
+	nameIDs := make([]uint64, len(fields))
+	for i, f := range fields {
+		nameIDs[i] = b.str(f.name)
+	}
+	h := &b.heap
+	h.WriteByte(byte(HeapTagClassDump))
+	binary.Write(h, binary.BigEndian, classID)
+	binary.Write(h, binary.BigEndian, uint32(0))
+	binary.Write(h, binary.BigEndian, superID)
+	binary.Write(h, binary.BigEndian, loaderID)
+	for i := 0; i < 4; i++ { // signers, protection domain, 2 reserved
+		binary.Write(h, binary.BigEndian, uint64(0))
+	}
+	binary.Write(h, binary.BigEndian, instanceSize)
+	binary.Write(h, binary.BigEndian, uint16(0)) // constant pool
+	binary.Write(h, binary.BigEndian, uint16(len(statics)))
+	for i, sf := range statics {
+		binary.Write(h, binary.BigEndian, staticIDs[i])
+		h.WriteByte(byte(sf.typ))
+		h.Write(sf.value)
+	}
+	binary.Write(h, binary.BigEndian, uint16(len(fields)))
+	for i, f := range fields {
+		binary.Write(h, binary.BigEndian, nameIDs[i])
+		h.WriteByte(byte(f.typ))
+	}
+}
+
+// instanceDump appends an INSTANCE_DUMP sub-record with raw field data.
+func (b *testHprofBuilder) instanceDump(objectID, classID uint64, data []byte) {
+	h := &b.heap
+	h.WriteByte(byte(HeapTagInstanceDump))
+	binary.Write(h, binary.BigEndian, objectID)
+	binary.Write(h, binary.BigEndian, uint32(0))
+	binary.Write(h, binary.BigEndian, classID)
+	binary.Write(h, binary.BigEndian, uint32(len(data)))
+	h.Write(data)
+}
+
+// objectArrayDump appends an OBJECT_ARRAY_DUMP sub-record.
+func (b *testHprofBuilder) objectArrayDump(objectID, arrayClassID uint64, elems ...uint64) {
+	h := &b.heap
+	h.WriteByte(byte(HeapTagObjectArrayDump))
+	binary.Write(h, binary.BigEndian, objectID)
+	binary.Write(h, binary.BigEndian, uint32(0))
+	binary.Write(h, binary.BigEndian, uint32(len(elems)))
+	binary.Write(h, binary.BigEndian, arrayClassID)
+	for _, e := range elems {
+		binary.Write(h, binary.BigEndian, e)
+	}
+}
+
+// primitiveArrayDump appends a PRIMITIVE_ARRAY_DUMP sub-record with raw element data.
+func (b *testHprofBuilder) primitiveArrayDump(objectID uint64, elemType BasicType, length int, data []byte) {
+	h := &b.heap
+	h.WriteByte(byte(HeapTagPrimitiveArrayDump))
+	binary.Write(h, binary.BigEndian, objectID)
+	binary.Write(h, binary.BigEndian, uint32(0))
+	binary.Write(h, binary.BigEndian, uint32(length))
+	h.WriteByte(byte(elemType))
+	if data == nil {
+		data = make([]byte, length*BasicTypeSize(elemType, 8))
+	}
+	h.Write(data)
+}
+
+// rootStickyClass appends a ROOT_STICKY_CLASS sub-record.
+func (b *testHprofBuilder) rootStickyClass(objectID uint64) {
+	b.heap.WriteByte(byte(HeapTagRootStickyClass))
+	binary.Write(&b.heap, binary.BigEndian, objectID)
+}
+
+// rootJNIGlobal appends a ROOT_JNI_GLOBAL sub-record.
+func (b *testHprofBuilder) rootJNIGlobal(objectID uint64) {
+	b.heap.WriteByte(byte(HeapTagRootJNIGlobal))
+	binary.Write(&b.heap, binary.BigEndian, objectID)
+	binary.Write(&b.heap, binary.BigEndian, uint64(0))
+}
+
+// stackFrame emits a STACK_FRAME record.
+func (b *testHprofBuilder) stackFrame(frameID uint64, method, signature, sourceFile string, classSerial uint32, line int32) {
+	var body bytes.Buffer
+	binary.Write(&body, binary.BigEndian, frameID)
+	binary.Write(&body, binary.BigEndian, b.str(method))
+	binary.Write(&body, binary.BigEndian, b.str(signature))
+	binary.Write(&body, binary.BigEndian, b.str(sourceFile))
+	binary.Write(&body, binary.BigEndian, classSerial)
+	binary.Write(&body, binary.BigEndian, line)
+	b.record(TagStackFrame, body.Bytes())
+}
+
+// stackTrace emits a STACK_TRACE record, top frame first.
+func (b *testHprofBuilder) stackTrace(serial, threadSerial uint32, frameIDs ...uint64) {
+	var body bytes.Buffer
+	binary.Write(&body, binary.BigEndian, serial)
+	binary.Write(&body, binary.BigEndian, threadSerial)
+	binary.Write(&body, binary.BigEndian, uint32(len(frameIDs)))
+	for _, id := range frameIDs {
+		binary.Write(&body, binary.BigEndian, id)
+	}
+	b.record(TagStackTrace, body.Bytes())
+}
+
+// rootThreadObject appends a ROOT_THREAD_OBJECT sub-record.
+func (b *testHprofBuilder) rootThreadObject(objectID uint64, threadSerial, traceSerial uint32) {
+	b.heap.WriteByte(byte(HeapTagRootThreadObject))
+	binary.Write(&b.heap, binary.BigEndian, objectID)
+	binary.Write(&b.heap, binary.BigEndian, threadSerial)
+	binary.Write(&b.heap, binary.BigEndian, traceSerial)
+}
+
+// rootJavaFrame appends a ROOT_JAVA_FRAME sub-record.
+func (b *testHprofBuilder) rootJavaFrame(objectID uint64, threadSerial, frameIndex uint32) {
+	b.heap.WriteByte(byte(HeapTagRootJavaFrame))
+	binary.Write(&b.heap, binary.BigEndian, objectID)
+	binary.Write(&b.heap, binary.BigEndian, threadSerial)
+	binary.Write(&b.heap, binary.BigEndian, frameIndex)
+}
+
+// bytes flushes pending heap sub-records as one HEAP_DUMP_SEGMENT and returns the file.
+func (b *testHprofBuilder) bytes() []byte {
+	if b.heap.Len() > 0 {
+		b.record(TagHeapDumpSegment, b.heap.Bytes())
+		b.heap.Reset()
+	}
+	return b.buf.Bytes()
+}
+
+// refBytes encodes object IDs as 8-byte big-endian field data.
+func refBytes(ids ...uint64) []byte {
+	out := make([]byte, 8*len(ids))
+	for i, id := range ids {
+		binary.BigEndian.PutUint64(out[i*8:], id)
+	}
+	return out
+}
@@ -3,10 +3,10 @@ package hprof
 
 import (
 	"context"
-	"runtime"
 	"sync"
 	"time"
 
+	"github.com/perf-analysis/pkg/parallel"
 	"github.com/perf-analysis/pkg/utils"
 )
 
@@ -21,7 +21,7 @@ type ParallelConfig struct {
 	Enabled bool
 
 	// MaxWorkers is the maximum number of concurrent workers.
-	// Default: runtime.NumCPU()
+	// Default: parallel.AvailableCPUs()
 	MaxWorkers int
 
 	// RetainerWorkers is the number of workers for retainer analysis.
@@ -32,6 +32,14 @@ type ParallelConfig struct {
 	// Default: MaxWorkers / 2 (graph generation is memory intensive)
 	GraphWorkers int
 
+	// DominatorWorkers caps the workers of the dominator tree computation.
+	// Default: 0 (the whole shared pool)
+	DominatorWorkers int
+
+	// SerializeWorkers caps the workers compressing the serialized reference graph.
+	// Default: 0 (the whole shared pool)
+	SerializeWorkers int
+
 	// Timeout is the maximum time for the entire analysis.
 	// Default: 5 minutes. Set to 0 for no timeout.
 	Timeout time.Duration
@@ -43,7 +51,7 @@ type ParallelConfig struct {
 
 // DefaultParallelConfig returns the default parallel configuration.
 func DefaultParallelConfig() ParallelConfig {
-	numCPU := runtime.NumCPU()
+	numCPU := parallel.AvailableCPUs()
 	return ParallelConfig{
 		Enabled:         true,
 		MaxWorkers:      numCPU,
@@ -108,7 +116,7 @@ type ParallelAnalyzer struct {
 // NewParallelAnalyzer creates a new parallel analyzer.
 func NewParallelAnalyzer(refGraph *ReferenceGraph, config ParallelConfig) *ParallelAnalyzer {
 	if config.MaxWorkers <= 0 {
-		config.MaxWorkers = runtime.NumCPU()
+		config.MaxWorkers = parallel.AvailableCPUs()
 	}
 	if config.RetainerWorkers <= 0 {
 		config.RetainerWorkers = config.MaxWorkers
@@ -138,7 +146,7 @@ func (pa *ParallelAnalyzer) debugf(format string, args ...interface{}) {
 // AnalyzeRetainersParallel analyzes retainers for multiple classes in parallel.
 func (pa *ParallelAnalyzer) AnalyzeRetainersParallel(ctx context.Context, classes []*ClassStats, topN int) map[string]*ClassRetainers {
 	if !pa.config.Enabled || len(classes) == 0 {
-		return pa.analyzeRetainersSequential(classes, topN)
+		return pa.analyzeRetainersSequential(ctx, classes, topN)
 	}
 
 	poolConfig := PoolConfig{
@@ -149,11 +157,11 @@ func (pa *ParallelAnalyzer) AnalyzeRetainersParallel(ctx context.Context, classe
 
 	pool := NewWorkerPool[*ClassStats, *ClassRetainers](poolConfig)
 	results := pool.ExecuteFunc(ctx, classes, func(ctx context.Context, cls *ClassStats) (*ClassRetainers, error) {
-		retainers := pa.refGraph.ComputeMultiLevelRetainers(cls.ClassName, 5, topN)
+		retainers, err := pa.refGraph.ComputeMultiLevelRetainersContext(ctx, cls.ClassName, 5, topN)
 		if retainers != nil && len(retainers.Retainers) > 0 {
 			retainers.RetainedSize = pa.refGraph.GetClassRetainedSize(cls.ClassName)
 		}
-		return retainers, nil
+		return retainers, err
 	})
 
 	// Collect non-nil results
@@ -167,10 +175,14 @@ func (pa *ParallelAnalyzer) AnalyzeRetainersParallel(ctx context.Context, classe
 }
 
 // analyzeRetainersSequential is the fallback sequential implementation.
-func (pa *ParallelAnalyzer) analyzeRetainersSequential(classes []*ClassStats, topN int) map[string]*ClassRetainers {
+// It returns the classes analyzed before ctx is done.
+func (pa *ParallelAnalyzer) analyzeRetainersSequential(ctx context.Context, classes []*ClassStats, topN int) map[string]*ClassRetainers {
 	results := make(map[string]*ClassRetainers)
 	for _, cls := range classes {
-		retainers := pa.refGraph.ComputeMultiLevelRetainers(cls.ClassName, 5, topN)
+		retainers, err := pa.refGraph.ComputeMultiLevelRetainersContext(ctx, cls.ClassName, 5, topN)
+		if err != nil {
+			break
+		}
 		if retainers != nil && len(retainers.Retainers) > 0 {
 			retainers.RetainedSize = pa.refGraph.GetClassRetainedSize(cls.ClassName)
 			results[cls.ClassName] = retainers
@@ -229,7 +241,7 @@ func (pa *ParallelAnalyzer) generateGraphsSequential(classes []*ClassStats, maxD
 // AnalyzeBusinessRetainersParallel analyzes business retainers for multiple classes in parallel.
 func (pa *ParallelAnalyzer) AnalyzeBusinessRetainersParallel(ctx context.Context, classes []*ClassStats, maxDepth, topN int) map[string][]*BusinessRetainer {
 	if !pa.config.Enabled || len(classes) == 0 {
-		return pa.analyzeBusinessRetainersSequential(classes, maxDepth, topN)
+		return pa.analyzeBusinessRetainersSequential(ctx, classes, maxDepth, topN)
 	}
 
 	poolConfig := PoolConfig{
@@ -240,7 +252,7 @@ func (pa *ParallelAnalyzer) AnalyzeBusinessRetainersParallel(ctx context.Context
 
 	pool := NewWorkerPool[*ClassStats, []*BusinessRetainer](poolConfig)
 	results := pool.ExecuteFunc(ctx, classes, func(ctx context.Context, cls *ClassStats) ([]*BusinessRetainer, error) {
-		return pa.refGraph.ComputeBusinessRetainers(cls.ClassName, maxDepth, topN), nil
+		return pa.refGraph.ComputeBusinessRetainersContext(ctx, cls.ClassName, maxDepth, topN)
 	})
 
 	// Collect non-empty results
@@ -254,10 +266,14 @@ func (pa *ParallelAnalyzer) AnalyzeBusinessRetainersParallel(ctx context.Context
 }
 
 // analyzeBusinessRetainersSequential is the fallback sequential implementation.
-func (pa *ParallelAnalyzer) analyzeBusinessRetainersSequential(classes []*ClassStats, maxDepth, topN int) map[string][]*BusinessRetainer {
+// It returns the classes analyzed before ctx is done.
+func (pa *ParallelAnalyzer) analyzeBusinessRetainersSequential(ctx context.Context, classes []*ClassStats, maxDepth, topN int) map[string][]*BusinessRetainer {
 	results := make(map[string][]*BusinessRetainer)
 	for _, cls := range classes {
-		businessRetainers := pa.refGraph.ComputeBusinessRetainers(cls.ClassName, maxDepth, topN)
+		businessRetainers, err := pa.refGraph.ComputeBusinessRetainersContext(ctx, cls.ClassName, maxDepth, topN)
+		if err != nil {
+			break
+		}
 		if len(businessRetainers) > 0 {
 			results[cls.ClassName] = businessRetainers
 		}
@@ -314,7 +330,7 @@ func (pa *ParallelAnalyzer) RunFullAnalysis(ctx context.Context, topClasses []*C
 
 	if !pa.config.Enabled {
 		// Sequential fallback
-		seqResult := pa.runFullAnalysisSequential(topClasses, opts)
+		seqResult := pa.runFullAnalysisSequential(ctx, topClasses, opts)
 		seqResult.Stats.TotalDuration = time.Since(startTime)
 		seqResult.Stats.ParallelEnabled = false
 		return seqResult
@@ -408,7 +424,7 @@ func (pa *ParallelAnalyzer) RunFullAnalysis(ctx context.Context, topClasses []*C
 }
 
 // runFullAnalysisSequential runs all analysis sequentially.
-func (pa *ParallelAnalyzer) runFullAnalysisSequential(topClasses []*ClassStats, opts AnalysisOptions) *FullAnalysisResult {
+func (pa *ParallelAnalyzer) runFullAnalysisSequential(ctx context.Context, topClasses []*ClassStats, opts AnalysisOptions) *FullAnalysisResult {
 	startTime := time.Now()
 
 	result := &FullAnalysisResult{
@@ -420,7 +436,7 @@ func (pa *ParallelAnalyzer) runFullAnalysisSequential(topClasses []*ClassStats,
 	// Retainer analysis
 	retainerStart := time.Now()
 	topForRetainers := limitSlice(topClasses, opts.MaxRetainerClasses)
-	result.ClassRetainers = pa.analyzeRetainersSequential(topForRetainers, opts.TopRetainersN)
+	result.ClassRetainers = pa.analyzeRetainersSequential(ctx, topForRetainers, opts.TopRetainersN)
 	retainerDuration := time.Since(retainerStart)
 
 	// Reference graphs
@@ -432,7 +448,7 @@ func (pa *ParallelAnalyzer) runFullAnalysisSequential(topClasses []*ClassStats,
 	// Business retainers
 	businessStart := time.Now()
 	topForBusiness := limitSlice(topClasses, opts.MaxBusinessClasses)
-	result.BusinessRetainers = pa.analyzeBusinessRetainersSequential(topForBusiness, opts.BusinessMaxDepth, opts.TopRetainersN)
+	result.BusinessRetainers = pa.analyzeBusinessRetainersSequential(ctx, topForBusiness, opts.BusinessMaxDepth, opts.TopRetainersN)
 	businessDuration := time.Since(businessStart)
 
 	// Populate stats
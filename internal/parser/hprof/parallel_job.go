@@ -0,0 +1,451 @@
+// Package hprof provides parsing functionality for Java HPROF heap dump files.
+package hprof
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+
+	"github.com/perf-analysis/pkg/telemetry"
+	"github.com/perf-analysis/pkg/utils"
+)
+
+// ============================================================================
+// Analysis Job
+// ============================================================================
+//
+// An AnalysisJob runs heap analysis as a pipeline of stages:
+//
+//	Queued -> Parsing -> Dominating -> Serializing -> Done
+//	                                              \-> Failed (at any stage)
+//
+// Every transition is persisted to job.json in the task directory so that other
+// processes (e.g. serve mode) can show progress. The output of each completed
+// stage is kept in memory, so a failed job can be retried from the failed stage
+// without reparsing the heap dump.
+
+// JobState is the state of an analysis job.
+type JobState string
+
+const (
+	// JobQueued means the job has been created but not started.
+	JobQueued JobState = "queued"
+	// JobParsing means HPROF records are being parsed.
+	JobParsing JobState = "parsing"
+	// JobDominating means the dominator tree and derived analyses are being computed.
+	JobDominating JobState = "dominating"
+	// JobSerializing means the reference graph and dominator tree are being written
+	// to refgraph.bin and domtree.bin.
+	JobSerializing JobState = "serializing"
+	// JobDone means all stages completed.
+	JobDone JobState = "done"
+	// JobFailed means a stage failed; see JobStatus.FailedStage.
+	JobFailed JobState = "failed"
+)
+
+// jobStages are the work stages of a job in execution order.
+var jobStages = []JobState{JobParsing, JobDominating, JobSerializing}
+
+// JobFileName is the name of the job status file in the task directory.
+const JobFileName = "job.json"
+
+// JobStageStatus records the timing of one stage.
+type JobStageStatus struct {
+	Stage      JobState   `json:"stage"`
+	StartedAt  time.Time  `json:"started_at"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+	DurationMs int64      `json:"duration_ms"`
+	Attempts   int        `json:"attempts"`
+	Error      string     `json:"error,omitempty"`
+}
+
+// JobStatus is the persisted state of an analysis job.
+type JobStatus struct {
+	ID          string            `json:"id"`
+	InputFile   string            `json:"input_file,omitempty"`
+	State       JobState          `json:"state"`
+	FailedStage JobState          `json:"failed_stage,omitempty"`
+	Error       string            `json:"error,omitempty"`
+	CreatedAt   time.Time         `json:"created_at"`
+	UpdatedAt   time.Time         `json:"updated_at"`
+	Stages      []*JobStageStatus `json:"stages"`
+}
+
+// Stage returns the status of a stage, or nil if it has not started.
+func (s JobStatus) Stage(stage JobState) *JobStageStatus {
+	for _, st := range s.Stages {
+		if st.Stage == stage {
+			return st
+		}
+	}
+	return nil
+}
+
+// AnalysisJobConfig configures an analysis job.
+type AnalysisJobConfig struct {
+	// ID identifies the job (typically the task UUID).
+	ID string
+	// TaskDir is where job.json, refgraph.bin and domtree.bin are written.
+	TaskDir string
+	// InputFile is the heap dump path, recorded in job.json and refgraph.bin metadata.
+	InputFile string
+	// ParserOptions configures parsing and analysis (nil = DefaultParserOptions).
+	ParserOptions *ParserOptions
+	// SerializeOptions configures refgraph.bin output.
+	SerializeOptions SerializeOptions
+	// SkipSerialize skips writing refgraph.bin and domtree.bin; the Serializing stage completes immediately.
+	SkipSerialize bool
+	// StageTimeouts bounds the duration of stages; stages without a timeout run until ctx is done.
+	StageTimeouts map[JobState]time.Duration
+}
+
+// AnalysisJob runs heap analysis in resumable stages and persists its status.
+// A job is driven by a single goroutine; Status may be called concurrently.
+type AnalysisJob struct {
+	config AnalysisJobConfig
+	parser *Parser
+	logger utils.Logger
+
+	mu     sync.Mutex
+	status JobStatus
+
+	// Stage outputs
+	done   map[JobState]bool
+	state  *parserState
+	result *HeapAnalysisResult
+	stats  *SerializationStats
+}
+
+// NewAnalysisJob creates a queued job and persists its initial status.
+func NewAnalysisJob(config AnalysisJobConfig) (*AnalysisJob, error) {
+	if config.TaskDir == "" {
+		return nil, errors.New("analysis job requires a task directory")
+	}
+	if config.ParserOptions == nil {
+		config.ParserOptions = DefaultParserOptions()
+	}
+	if config.SerializeOptions.SourceFile == "" {
+		config.SerializeOptions.SourceFile = config.InputFile
+	}
+	if err := os.MkdirAll(config.TaskDir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create task directory: %w", err)
+	}
+
+	now := time.Now()
+	j := &AnalysisJob{
+		config: config,
+		parser: NewParser(config.ParserOptions),
+		logger: config.ParserOptions.Logger,
+		done:   make(map[JobState]bool, len(jobStages)),
+		status: JobStatus{
+			ID:        config.ID,
+			InputFile: config.InputFile,
+			State:     JobQueued,
+			CreatedAt: now,
+			UpdatedAt: now,
+		},
+	}
+	if err := j.persist(); err != nil {
+		return nil, err
+	}
+	return j, nil
+}
+
+// Status returns a copy of the job's current status.
+func (j *AnalysisJob) Status() JobStatus {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	status := j.status
+	status.Stages = make([]*JobStageStatus, len(j.status.Stages))
+	for i, st := range j.status.Stages {
+		stCopy := *st
+		status.Stages[i] = &stCopy
+	}
+	return status
+}
+
+// Result returns the analysis result once the Dominating stage has completed.
+func (j *AnalysisJob) Result() *HeapAnalysisResult {
+	return j.result
+}
+
+// SerializationStats returns refgraph.bin statistics once the Serializing stage
+// has completed, or nil if serialization was skipped.
+func (j *AnalysisJob) SerializationStats() *SerializationStats {
+	return j.stats
+}
+
+// Run executes all stages that have not completed yet and returns the analysis result.
+// input is only read by the Parsing stage, so it may be nil when resuming a job
+// whose parsing already completed.
+func (j *AnalysisJob) Run(ctx context.Context, input io.Reader) (*HeapAnalysisResult, error) {
+	for i, stage := range jobStages {
+		if j.done[stage] {
+			continue
+		}
+		j.reportProgress(stage, i)
+		if err := j.runStage(ctx, stage, input); err != nil {
+			return j.result, err
+		}
+	}
+
+	j.transition(func(s *JobStatus) {
+		s.State = JobDone
+		s.FailedStage = ""
+		s.Error = ""
+	})
+	j.reportProgress(JobDone, len(jobStages))
+	return j.result, nil
+}
+
+// Retry resumes a failed job at the stage that failed.
+// input is required only if parsing failed.
+func (j *AnalysisJob) Retry(ctx context.Context, input io.Reader) (*HeapAnalysisResult, error) {
+	if state := j.Status().State; state != JobFailed {
+		return nil, fmt.Errorf("cannot retry job in state %s", state)
+	}
+	return j.Run(ctx, input)
+}
+
+// runStage executes one stage, recording its timing and outcome.
+func (j *AnalysisJob) runStage(ctx context.Context, stage JobState, input io.Reader) error {
+	start := time.Now()
+	j.transition(func(s *JobStatus) {
+		s.State = stage
+		s.FailedStage = ""
+		s.Error = ""
+		st := s.Stage(stage)
+		if st == nil {
+			st = &JobStageStatus{Stage: stage}
+			s.Stages = append(s.Stages, st)
+		}
+		st.StartedAt = start
+		st.FinishedAt = nil
+		st.DurationMs = 0
+		st.Attempts++
+		st.Error = ""
+	})
+
+	timeout := j.config.StageTimeouts[stage]
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	ctx, span := telemetry.StartSpan(ctx, "hprof.job."+string(stage),
+		attribute.String("hprof.job.id", j.config.ID))
+	var err error
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		err = ctxErr
+	} else {
+		switch stage {
+		case JobParsing:
+			err = j.parse(ctx, input)
+		case JobDominating:
+			err = j.dominate(ctx)
+		case JobSerializing:
+			err = j.serialize(ctx)
+		}
+	}
+	if timeout > 0 && errors.Is(err, context.DeadlineExceeded) {
+		err = fmt.Errorf("timed out after %v: %w", timeout, err)
+	}
+	telemetry.EndSpan(span, err)
+
+	finished := time.Now()
+	j.transition(func(s *JobStatus) {
+		st := s.Stage(stage)
+		st.FinishedAt = &finished
+		st.DurationMs = finished.Sub(start).Milliseconds()
+		if err != nil {
+			st.Error = err.Error()
+			s.State = JobFailed
+			s.FailedStage = stage
+			s.Error = err.Error()
+		}
+	})
+
+	if err != nil {
+		j.warnf("Analysis job %s failed in stage %s: %v", j.config.ID, stage, err)
+		return fmt.Errorf("%s stage failed: %w", stage, err)
+	}
+	j.done[stage] = true
+	j.debugf("Analysis job %s: stage %s completed in %v", j.config.ID, stage, finished.Sub(start))
+	return nil
+}
+
+// parse runs the Parsing stage.
+func (j *AnalysisJob) parse(ctx context.Context, input io.Reader) error {
+	if input == nil {
+		return errors.New("no input to parse")
+	}
+	timer := utils.NewTimer("HPROF Parse", utils.WithLogger(j.logger), utils.WithEnabled(j.logger != nil))
+	state, err := j.parser.parseState(ctx, input, timer)
+	if err != nil {
+		return err
+	}
+	timer.PrintSummary()
+	j.state = state
+	return nil
+}
+
+// dominate runs the Dominating stage: dominator tree and result building.
+// The parser state is released once the result is built.
+func (j *AnalysisJob) dominate(ctx context.Context) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+
+	timer := utils.NewTimer("Build result", utils.WithLogger(j.logger), utils.WithEnabled(j.logger != nil))
+	result, err := j.parser.buildResult(ctx, j.state, timer)
+	timer.PrintSummary()
+	if err != nil {
+		return err
+	}
+	j.result = result
+	j.state = nil
+	return nil
+}
+
+// serialize runs the Serializing stage.
+func (j *AnalysisJob) serialize(ctx context.Context) error {
+	if j.config.SkipSerialize || j.result.RefGraph == nil {
+		return nil
+	}
+	g := j.result.RefGraph
+	opts := j.config.SerializeOptions
+	if opts.Workers == 0 {
+		opts.Workers = j.parser.opts.ParallelConfig.SerializeWorkers
+	}
+	_, span := telemetry.StartSpan(ctx, "hprof.serialize_refgraph")
+	stats, err := g.SerializeToFileContext(ctx, filepath.Join(j.config.TaskDir, "refgraph.bin"), opts)
+	if stats != nil {
+		span.SetAttributes(attribute.Int64("hprof.objects", stats.Objects),
+			attribute.Int64("hprof.serialized_bytes", stats.CompressedSize))
+	}
+	telemetry.EndSpan(span, err)
+	if err != nil {
+		return err
+	}
+	if g.dominatorComputed {
+		if err := NewDominatorTree(g).WriteFile(filepath.Join(j.config.TaskDir, DominatorTreeFileName)); err != nil {
+			return fmt.Errorf("failed to write dominator tree: %w", err)
+		}
+	}
+	if err := j.writeObjectIndex(); err != nil {
+		return err
+	}
+	j.stats = stats
+	return nil
+}
+
+// writeObjectIndex writes class_layouts.json and objindex.bin, which let the
+// web UI read field values from the heap dump. The index is skipped when the
+// input file is unknown or is not the parsed stream itself (e.g. compressed).
+func (j *AnalysisJob) writeObjectIndex() error {
+	if len(j.result.ClassLayouts) > 0 {
+		data, err := json.Marshal(j.result.ClassLayouts)
+		if err != nil {
+			return fmt.Errorf("failed to encode class layouts: %w", err)
+		}
+		if err := os.WriteFile(filepath.Join(j.config.TaskDir, ClassLayoutsFileName), data, 0644); err != nil {
+			return fmt.Errorf("failed to write class layouts: %w", err)
+		}
+	}
+
+	index := j.result.ObjectIndex
+	if index == nil || j.config.InputFile == "" {
+		return nil
+	}
+	if err := index.SetSource(j.config.InputFile); err != nil {
+		j.warnf("Skipping object index: %v", err)
+		return nil
+	}
+	if err := index.CheckSource(); err != nil {
+		j.warnf("Skipping object index: %v", err)
+		return nil
+	}
+	if err := index.WriteFile(filepath.Join(j.config.TaskDir, ObjectIndexFileName)); err != nil {
+		return fmt.Errorf("failed to write object index: %w", err)
+	}
+	return nil
+}
+
+// transition applies a status update and persists it. Persistence errors are
+// logged only, since losing a progress update must not fail the analysis.
+func (j *AnalysisJob) transition(update func(*JobStatus)) {
+	j.mu.Lock()
+	update(&j.status)
+	j.status.UpdatedAt = time.Now()
+	j.mu.Unlock()
+
+	if err := j.persist(); err != nil {
+		j.warnf("Failed to persist job status: %v", err)
+	}
+}
+
+// persist atomically writes the job status to the task directory.
+func (j *AnalysisJob) persist() error {
+	status := j.Status()
+	data, err := json.MarshalIndent(&status, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode job status: %w", err)
+	}
+
+	filename := filepath.Join(j.config.TaskDir, JobFileName)
+	tmpFile := filename + ".tmp"
+	if err := os.WriteFile(tmpFile, data, 0644); err != nil {
+		return fmt.Errorf("failed to write job status: %w", err)
+	}
+	if err := os.Rename(tmpFile, filename); err != nil {
+		os.Remove(tmpFile)
+		return fmt.Errorf("failed to write job status: %w", err)
+	}
+	return nil
+}
+
+// reportProgress forwards stage changes to the parser's progress callback.
+func (j *AnalysisJob) reportProgress(stage JobState, completed int) {
+	if cb := j.config.ParserOptions.ParallelConfig.ProgressCallback; cb != nil {
+		cb(string(stage), completed, len(jobStages))
+	}
+}
+
+// debugf logs a debug message if logger is configured.
+func (j *AnalysisJob) debugf(format string, args ...interface{}) {
+	if j.logger != nil {
+		j.logger.Debug(format, args...)
+	}
+}
+
+// warnf logs a warning if logger is configured.
+func (j *AnalysisJob) warnf(format string, args ...interface{}) {
+	if j.logger != nil {
+		j.logger.Warn(format, args...)
+	}
+}
+
+// LoadJobStatus reads the job status persisted in a task directory.
+// Returns an error satisfying os.IsNotExist if the task has no job file.
+func LoadJobStatus(taskDir string) (*JobStatus, error) {
+	data, err := os.ReadFile(filepath.Join(taskDir, JobFileName))
+	if err != nil {
+		return nil, err
+	}
+	var status JobStatus
+	if err := json.Unmarshal(data, &status); err != nil {
+		return nil, fmt.Errorf("failed to decode job status: %w", err)
+	}
+	return &status, nil
+}
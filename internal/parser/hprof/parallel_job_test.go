@@ -0,0 +1,158 @@
+package hprof
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// newJobTestHprof returns a small heap dump with a GC root holding an array.
+func newJobTestHprof() []byte {
+	b := newTestHprofBuilder()
+	b.loadClass(0x10, "[Ljava/lang/Object;")
+	b.classDump(0x10, 0, 0)
+	b.objectArrayDump(0x1000, 0x10)
+	b.objectArrayDump(0x1001, 0x10, 0x1000)
+	b.rootJNIGlobal(0x1001)
+	return b.bytes()
+}
+
+func TestAnalysisJob_Run(t *testing.T) {
+	taskDir := t.TempDir()
+	job, err := NewAnalysisJob(AnalysisJobConfig{ID: "task-1", TaskDir: taskDir, SerializeOptions: FastSerializeOptions()})
+	require.NoError(t, err)
+
+	status, err := LoadJobStatus(taskDir)
+	require.NoError(t, err)
+	assert.Equal(t, JobQueued, status.State)
+
+	result, err := job.Run(context.Background(), bytes.NewReader(newJobTestHprof()))
+	require.NoError(t, err)
+	require.NotNil(t, result)
+	assert.NotNil(t, job.SerializationStats())
+	assert.FileExists(t, filepath.Join(taskDir, "refgraph.bin"))
+	assert.FileExists(t, filepath.Join(taskDir, DominatorTreeFileName))
+
+	status, err = LoadJobStatus(taskDir)
+	require.NoError(t, err)
+	assert.Equal(t, "task-1", status.ID)
+	assert.Equal(t, JobDone, status.State)
+	require.Len(t, status.Stages, 3)
+	for i, stage := range []JobState{JobParsing, JobDominating, JobSerializing} {
+		assert.Equal(t, stage, status.Stages[i].Stage)
+		assert.Equal(t, 1, status.Stages[i].Attempts)
+		assert.NotNil(t, status.Stages[i].FinishedAt)
+	}
+
+	_, err = job.Retry(context.Background(), nil)
+	assert.Error(t, err, "completed jobs cannot be retried")
+}
+
+func TestAnalysisJob_RetryFailedStage(t *testing.T) {
+	taskDir := t.TempDir()
+	// A directory in place of refgraph.bin makes serialization fail
+	blocker := filepath.Join(taskDir, "refgraph.bin")
+	require.NoError(t, os.Mkdir(blocker, 0755))
+
+	job, err := NewAnalysisJob(AnalysisJobConfig{ID: "task-2", TaskDir: taskDir, SerializeOptions: FastSerializeOptions()})
+	require.NoError(t, err)
+
+	_, err = job.Run(context.Background(), bytes.NewReader(newJobTestHprof()))
+	require.Error(t, err)
+	assert.NotNil(t, job.Result(), "result of completed stages is kept")
+
+	status, err := LoadJobStatus(taskDir)
+	require.NoError(t, err)
+	assert.Equal(t, JobFailed, status.State)
+	assert.Equal(t, JobSerializing, status.FailedStage)
+	assert.NotEmpty(t, status.Stage(JobSerializing).Error)
+
+	// Retry without input: parsing must not run again
+	require.NoError(t, os.Remove(blocker))
+	_, err = job.Retry(context.Background(), nil)
+	require.NoError(t, err)
+
+	final := job.Status()
+	assert.Equal(t, JobDone, final.State)
+	assert.Empty(t, final.FailedStage)
+	assert.Equal(t, 1, final.Stage(JobParsing).Attempts)
+	assert.Equal(t, 1, final.Stage(JobDominating).Attempts)
+	assert.Equal(t, 2, final.Stage(JobSerializing).Attempts)
+	assert.Empty(t, final.Stage(JobSerializing).Error)
+	assert.FileExists(t, blocker)
+}
+
+func TestAnalysisJob_ParseFailure(t *testing.T) {
+	job, err := NewAnalysisJob(AnalysisJobConfig{ID: "task-3", TaskDir: t.TempDir()})
+	require.NoError(t, err)
+
+	_, err = job.Run(context.Background(), bytes.NewReader([]byte("not a heap dump")))
+	require.Error(t, err)
+	assert.Equal(t, JobParsing, job.Status().FailedStage)
+
+	_, err = job.Retry(context.Background(), nil)
+	require.Error(t, err, "retrying parsing requires input")
+	assert.Equal(t, 2, job.Status().Stage(JobParsing).Attempts)
+}
+
+func TestAnalysisJob_StageTimeout(t *testing.T) {
+	job, err := NewAnalysisJob(AnalysisJobConfig{
+		ID:            "task-4",
+		TaskDir:       t.TempDir(),
+		StageTimeouts: map[JobState]time.Duration{JobDominating: time.Nanosecond},
+	})
+	require.NoError(t, err)
+
+	_, err = job.Run(context.Background(), bytes.NewReader(newJobTestHprof()))
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Contains(t, err.Error(), "timed out after 1ns")
+	assert.Equal(t, JobDominating, job.Status().FailedStage)
+	assert.Nil(t, job.Result())
+}
+
+func TestParser_ParseCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := NewParser(DefaultParserOptions()).Parse(ctx, bytes.NewReader(newJobTestHprof()))
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func TestAnalysisJob_Spans(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	defer otel.SetTracerProvider(previous)
+
+	job, err := NewAnalysisJob(AnalysisJobConfig{ID: "task-3", TaskDir: t.TempDir(), SerializeOptions: FastSerializeOptions()})
+	require.NoError(t, err)
+	_, err = job.Run(context.Background(), bytes.NewReader(newJobTestHprof()))
+	require.NoError(t, err)
+
+	parents := make(map[string]string)
+	spans := recorder.Ended()
+	for _, span := range spans {
+		for _, parent := range spans {
+			if span.Parent().SpanID() == parent.SpanContext().SpanID() {
+				parents[span.Name()] = parent.Name()
+			}
+		}
+	}
+	assert.Equal(t, map[string]string{
+		"hprof.parse_records":              "hprof.job.parsing",
+		"hprof.process_deferred_instances": "hprof.job.parsing",
+		"hprof.build_result":               "hprof.job.dominating",
+		"hprof.compute_dominators":         "hprof.build_result",
+		"hprof.serialize_refgraph":         "hprof.job.serializing",
+	}, parents)
+	assert.Len(t, spans, 8)
+}
@@ -2,10 +2,14 @@ package hprof
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
+	"math"
 	"strings"
+	"time"
 
+	"github.com/perf-analysis/pkg/telemetry"
 	"github.com/perf-analysis/pkg/utils"
 )
 
@@ -19,7 +23,8 @@ const (
 	// SizeModeNonCompressed uses non-compressed oops (16-byte header, 8-byte refs).
 	// This matches MAT's behavior.
 	SizeModeNonCompressed
-	// SizeModeAuto automatically detects based on heap size (not implemented yet).
+	// SizeModeAuto detects the layout from the ID size and object addresses of
+	// the dump (see detectJVMLayout).
 	SizeModeAuto
 )
 
@@ -42,6 +47,9 @@ type ParserOptions struct {
 	// SizeMode controls how shallow sizes are calculated.
 	// Default is SizeModeCompressedOops to match IDEA behavior.
 	SizeMode SizeCalculationMode
+	// Layout overrides SizeMode with the object layout of the JVM that wrote
+	// the dump, e.g. for compact headers or 16-byte alignment.
+	Layout *JVMLayout
 	// FastMode skips deep analysis (business retainers, multi-level retainers, reference graphs).
 	// Only computes class histogram, basic retainer info, and dominator tree.
 	// This can reduce analysis time by 70-90% for large heaps.
@@ -57,20 +65,38 @@ type ParserOptions struct {
 	// Verbose enables verbose debug output including detailed retained size analysis.
 	// This is typically enabled via the -v command line flag.
 	Verbose bool
+	// RollupBiggestObjects replaces JDK objects in the Biggest Objects view with their
+	// nearest non-JDK dominator, so business objects owning large arrays are shown instead.
+	RollupBiggestObjects bool
+	// IndexObjectOffsets records the file offset of every object record so field values
+	// and array contents can be read back from the heap dump later (requires AnalyzeRetainers).
+	IndexObjectOffsets bool
+	// LocalityOrder renumbers objects in BFS order before computing dominators, for
+	// cache locality on large heaps (see BenchmarkDominatorLocalityOrder).
+	LocalityOrder bool
+	// DominatorAlgorithm forces the dominator algorithm. The default,
+	// DominatorAlgorithmAuto, selects it from the size of the heap.
+	DominatorAlgorithm DominatorAlgorithm
+	// RetainerExactBudget is the time retainer analysis may spend per class
+	// counting the retainers of every instance instead of a sample; classes
+	// exceeding it fall back to sampling. 0 always samples large classes.
+	RetainerExactBudget time.Duration
 }
 
 // DefaultParserOptions returns default parser options.
 func DefaultParserOptions() *ParserOptions {
 	return &ParserOptions{
-		TopClassesN:        50,  // 0 means no limit - return all classes
-		AnalyzeStrings:     true,
-		AnalyzeArrays:      true,
-		MaxLargestObjects:  100, // Increased to show more objects in Biggest Objects view
-		AnalyzeRetainers:   true,
-		TopRetainersN:      10,
-		ParallelConfig:     DefaultParallelConfig(),
-		SizeMode:           SizeModeCompressedOops, // Default to IDEA-compatible mode
-		IncludeUnreachable: true,                   // Default to include all objects (like IDEA)
+		TopClassesN:         50, // 0 means no limit - return all classes
+		AnalyzeStrings:      true,
+		AnalyzeArrays:       true,
+		MaxLargestObjects:   100, // Increased to show more objects in Biggest Objects view
+		AnalyzeRetainers:    true,
+		TopRetainersN:       10,
+		ParallelConfig:      DefaultParallelConfig(),
+		SizeMode:            SizeModeCompressedOops, // Default to IDEA-compatible mode
+		IncludeUnreachable:  true,                   // Default to include all objects (like IDEA)
+		IndexObjectOffsets:  true,
+		RetainerExactBudget: 2 * time.Second,
 	}
 }
 
@@ -119,10 +145,22 @@ type parserState struct {
 	classLayouts map[uint64]*ClassFieldLayout // classID -> field layout
 	// Deferred reference extraction for instances parsed before their CLASS_DUMP
 	deferredInstances []deferredInstance
-	// Size calculation mode
-	sizeMode SizeCalculationMode
+	// Object layout used for shallow sizes, nil until detected in SizeModeAuto
+	layout      *JVMLayout
+	layoutHints layoutHints
+	// Number of reference fields of instances per class, for shallow sizes
+	refFieldCounts map[uint64]int
 	// java.lang.Class classID - used to properly categorize Class objects
 	javaLangClassID uint64
+	// Array length histograms (nil when array analysis is disabled)
+	arrayHistograms *ArrayHistogramCollector
+	// Threads, stack traces and stack frames for the thread overview
+	threadStacks *threadStackCollector
+	// Allocation sites and CPU samples of the legacy hprof agent (nil when absent)
+	allocSites *allocSitesRecord
+	cpuSamples *cpuSamplesRecord
+	// Record offsets of objects (nil when not indexing)
+	objectIndex *objectIndexBuilder
 	// Debug counters
 	classDumpCount    int64
 	instanceDumpCount int64
@@ -133,61 +171,26 @@ type parserState struct {
 	deferredCount     int64 // count of deferred instances
 }
 
-// objectHeaderSize returns the size of object header in JVM.
-// For HotSpot JVM with compressed oops (default for heaps < 32GB):
-// - mark word (8 bytes) + klass pointer (4 bytes) = 12 bytes
-// For HotSpot JVM without compressed oops (heaps >= 32GB):
-// - mark word (8 bytes) + klass pointer (8 bytes) = 16 bytes
-func objectHeaderSize(mode SizeCalculationMode) int64 {
-	if mode == SizeModeNonCompressed {
-		return 16 // MAT-compatible: 8 (mark) + 8 (klass)
+// sizeLayout returns the layout to size objects with; while detecting the
+// layout, class objects are provisionally sized as with compressed oops.
+func (s *parserState) sizeLayout() *JVMLayout {
+	if s.layout == nil {
+		return &LayoutCompressedOops
 	}
-	return 12 // IDEA-compatible: 8 (mark) + 4 (compressed klass)
+	return s.layout
 }
 
-// referenceSize returns the size of a reference field in JVM.
-func referenceSize(mode SizeCalculationMode) int64 {
-	if mode == SizeModeNonCompressed {
-		return 8 // MAT-compatible: non-compressed oops
+// resolveLayout detects the layout from the hints collected so far and
+// resizes the class objects provisionally sized before.
+func (s *parserState) resolveLayout() {
+	layout := detectJVMLayout(s.reader.IDSize(), &s.layoutHints)
+	s.layout = &layout
+	if s.refGraph == nil {
+		return
+	}
+	for classID, cl := range s.classLayouts {
+		s.refGraph.SetObjectInfo(classID, classID, layout.classObjectSize(len(cl.StaticFields)))
 	}
-	return 4 // IDEA-compatible: compressed oops
-}
-
-// classObjectShallowSize returns the shallow size of a java.lang.Class object.
-// Class objects have a complex internal structure that varies by JVM version.
-// MAT calculates this as: object header + internal fields
-// For HotSpot JVM, Class objects contain:
-// - Object header (12/16 bytes depending on compressed oops)
-// - Various internal fields (classLoader, module, name, etc.)
-// - Static field values storage
-func classObjectShallowSize(mode SizeCalculationMode, staticFieldsCount int) int64 {
-	// Base Class object size (header + core fields)
-	baseSize := objectHeaderSize(mode)
-	refSize := referenceSize(mode)
-
-	// Core fields in java.lang.Class (approximate):
-	// - classLoader, module, name, packageName, etc. (reference fields)
-	// - Various int/boolean fields for flags
-	// Estimate: ~15 reference fields + ~20 bytes of primitives
-	coreFields := int64(15)*refSize + 20
-
-	// Static fields are stored in the Class object
-	staticStorage := int64(staticFieldsCount) * refSize
-
-	return alignTo8(baseSize + coreFields + staticStorage)
-}
-
-// arrayHeaderSize returns the size of array header in JVM.
-// Array header = object header + array length (4 bytes)
-// For compressed oops: 12 + 4 = 16 bytes
-// For non-compressed: 16 + 4 = 20 bytes (aligned to 24)
-func arrayHeaderSize(mode SizeCalculationMode) int64 {
-	return objectHeaderSize(mode) + 4
-}
-
-// alignTo8 aligns a size to 8-byte boundary (JVM object alignment).
-func alignTo8(size int64) int64 {
-	return (size + 7) &^ 7
 }
 
 // FieldDescriptor describes a field in a class.
@@ -207,22 +210,76 @@ func newParserState(r *Reader, opts *ParserOptions) *parserState {
 		classFields:       make(map[uint64][]FieldDescriptor),
 		classLayouts:      make(map[uint64]*ClassFieldLayout),
 		deferredInstances: make([]deferredInstance, 0),
-		sizeMode:          opts.SizeMode,
+		refFieldCounts:    make(map[uint64]int),
+		threadStacks:      newThreadStackCollector(),
+	}
+	switch {
+	case opts.Layout != nil:
+		layout := *opts.Layout
+		state.layout = &layout
+	case opts.SizeMode == SizeModeNonCompressed:
+		state.layout = &LayoutUncompressedOops
+	case opts.SizeMode != SizeModeAuto:
+		state.layout = &LayoutCompressedOops
+	}
+	if opts.AnalyzeArrays {
+		state.arrayHistograms = NewArrayHistogramCollector()
 	}
 	if opts.AnalyzeRetainers {
 		state.refGraph = NewReferenceGraph()
+		state.refGraph.SetMaxWorkers(opts.ParallelConfig.DominatorWorkers)
+		state.refGraph.SetLocalityOrder(opts.LocalityOrder)
+		state.refGraph.SetDominatorAlgorithm(opts.DominatorAlgorithm)
+		state.refGraph.SetRetainerExactBudget(opts.RetainerExactBudget)
 		if opts.Logger != nil {
-			state.refGraph.SetLogger(opts.Logger)
+			state.refGraph.SetLogger(utils.Scoped(opts.Logger, utils.ScopeDominator))
+		}
+		if opts.IndexObjectOffsets {
+			state.objectIndex = &objectIndexBuilder{}
 		}
 	}
 	return state
 }
 
+// indexObject records the offset of the object record whose ID was just read.
+func (s *parserState) indexObject(objectID uint64) {
+	if s.objectIndex != nil {
+		// Step back over the object ID and the sub-record tag
+		s.objectIndex.add(objectID, s.reader.Offset()-int64(s.reader.IDSize())-1)
+	}
+}
+
 // Parse parses an HPROF file and returns analysis results.
-func (p *Parser) Parse(ctx context.Context, r io.Reader) (*HeapAnalysisResult, error) {
+func (p *Parser) Parse(ctx context.Context, r io.Reader) (result *HeapAnalysisResult, err error) {
+	ctx, span := telemetry.StartSpan(ctx, "hprof.parse")
+	defer func() { telemetry.EndSpan(span, err) }()
+
 	// Create timer for performance tracking (uses dependency injection via Logger)
 	timer := utils.NewTimer("HPROF Parse", utils.WithLogger(p.opts.Logger), utils.WithEnabled(p.opts.Logger != nil))
 
+	// Phase 1: Parse all records
+	state, err := p.parseState(ctx, r, timer)
+	if err != nil {
+		return nil, err
+	}
+
+	// Phase 2: Build result (includes dominator tree computation and analysis)
+	timer.TimeFunc("Build result", func() {
+		result, err = p.buildResult(ctx, state, timer)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Print timing summary
+	timer.PrintSummary()
+
+	return result, nil
+}
+
+// parseState reads the HPROF header and all records into a parser state,
+// without computing dominators or building the analysis result.
+func (p *Parser) parseState(ctx context.Context, r io.Reader, timer *utils.Timer) (*parserState, error) {
 	reader := NewReader(r)
 	state := newParserState(reader, p.opts)
 
@@ -232,10 +289,15 @@ func (p *Parser) Parse(ctx context.Context, r io.Reader) (*HeapAnalysisResult, e
 		return nil, fmt.Errorf("failed to read header: %w", err)
 	}
 	state.header = header
+	if state.layout == nil && reader.IDSize() == 4 {
+		state.resolveLayout()
+	}
 
-	// Phase 1: Parse all records
 	pt := timer.Start("Parse HPROF records")
-	if err := p.parseRecords(ctx, state); err != nil {
+	recordsCtx, span := telemetry.StartSpan(ctx, "hprof.parse_records")
+	err = p.parseRecords(recordsCtx, state)
+	telemetry.EndSpan(span, err)
+	if err != nil {
 		return nil, fmt.Errorf("failed to parse records: %w", err)
 	}
 	pt.Stop()
@@ -243,26 +305,33 @@ func (p *Parser) Parse(ctx context.Context, r io.Reader) (*HeapAnalysisResult, e
 	// Process deferred instances (those parsed before their CLASS_DUMP)
 	// This ensures all references are extracted even when INSTANCE_DUMP appears before CLASS_DUMP
 	timer.TimeFunc("Process deferred instances", func() {
+		_, span := telemetry.StartSpan(ctx, "hprof.process_deferred_instances")
+		defer span.End()
 		p.processDeferredInstances(state)
 	})
 
 	// Fix Class object categorization: all Class objects should be instances of java.lang.Class
 	p.fixClassObjectCategorization(state)
 
-	// Phase 2: Build result (includes dominator tree computation and analysis)
-	var result *HeapAnalysisResult
-	timer.TimeFunc("Build result", func() {
-		result = p.buildResult(state, timer)
-	})
-
-	// Print timing summary
-	timer.PrintSummary()
+	if state.layout == nil {
+		// A dump without objects besides classes
+		state.resolveLayout()
+	}
+	p.debugf("JVM layout: %s", state.layout.Name)
 
-	return result, nil
+	return state, nil
 }
 
-// parseRecords parses all records in the HPROF file.
-func (p *Parser) parseRecords(ctx context.Context, state *parserState) error {
+// parseRecords parses all records in the HPROF file. Malformed records
+// return the errors of core_errors.go; a panic on a record the parser does
+// not guard against is returned as an error too.
+func (p *Parser) parseRecords(ctx context.Context, state *parserState) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic parsing record at byte %d: %v", state.reader.Offset(), r)
+		}
+	}()
+
 	for {
 		// Check context cancellation
 		select {
@@ -288,6 +357,14 @@ func (p *Parser) parseRecords(ctx context.Context, state *parserState) error {
 			if err := p.parseLoadClassRecord(state); err != nil {
 				return err
 			}
+		case TagStackFrame:
+			if err := p.parseStackFrameRecord(state); err != nil {
+				return err
+			}
+		case TagStackTrace:
+			if err := p.parseStackTraceRecord(state, length); err != nil {
+				return err
+			}
 		case TagHeapDump, TagHeapDumpSegment:
 			if err := p.parseHeapDumpRecord(ctx, state, length); err != nil {
 				return err
@@ -296,6 +373,14 @@ func (p *Parser) parseRecords(ctx context.Context, state *parserState) error {
 			if err := p.parseHeapSummaryRecord(state); err != nil {
 				return err
 			}
+		case TagAllocSites:
+			if err := p.parseAllocSitesRecord(state, length); err != nil {
+				return err
+			}
+		case TagCPUSamples:
+			if err := p.parseCPUSamplesRecord(state, length); err != nil {
+				return err
+			}
 		default:
 			// Skip unknown records
 			if err := state.reader.Skip(int64(length)); err != nil {
@@ -307,16 +392,16 @@ func (p *Parser) parseRecords(ctx context.Context, state *parserState) error {
 
 // parseStringRecord parses a STRING record.
 func (p *Parser) parseStringRecord(state *parserState, length uint32) error {
+	strLen := int(length) - state.reader.IDSize()
+	if strLen < 0 {
+		return fmt.Errorf("%w: STRING record of %d bytes at byte %d", ErrInconsistentLength, length, state.reader.Offset())
+	}
+
 	id, err := state.reader.ReadID()
 	if err != nil {
 		return err
 	}
 
-	strLen := int(length) - state.reader.IDSize()
-	if strLen < 0 {
-		return fmt.Errorf("invalid string length: %d", strLen)
-	}
-
 	strBytes, err := state.reader.ReadBytes(strLen)
 	if err != nil {
 		return err
@@ -330,7 +415,8 @@ func (p *Parser) parseStringRecord(state *parserState, length uint32) error {
 func (p *Parser) parseLoadClassRecord(state *parserState) error {
 	state.loadClassCount++
 	// Class serial number (4 bytes)
-	if _, err := state.reader.ReadUint32(); err != nil {
+	classSerial, err := state.reader.ReadUint32()
+	if err != nil {
 		return err
 	}
 
@@ -352,6 +438,80 @@ func (p *Parser) parseLoadClassRecord(state *parserState) error {
 	}
 
 	state.classNames[classID] = nameID
+	state.threadStacks.addClassSerial(classSerial, classID)
+	return nil
+}
+
+// parseStackFrameRecord parses a STACK_FRAME record.
+func (p *Parser) parseStackFrameRecord(state *parserState) error {
+	frameID, err := state.reader.ReadID()
+	if err != nil {
+		return err
+	}
+
+	// Method name, method signature and source file name string IDs
+	var ids [3]uint64
+	for i := range ids {
+		if ids[i], err = state.reader.ReadID(); err != nil {
+			return err
+		}
+	}
+
+	classSerial, err := state.reader.ReadUint32()
+	if err != nil {
+		return err
+	}
+
+	// Line number: > 0 a line, or one of the LineNumber* markers
+	lineNumber, err := state.reader.ReadUint32()
+	if err != nil {
+		return err
+	}
+
+	state.threadStacks.addFrame(frameID, &stackFrameRecord{
+		methodNameID: ids[0],
+		signatureID:  ids[1],
+		sourceFileID: ids[2],
+		classSerial:  classSerial,
+		lineNumber:   int32(lineNumber),
+	})
+	return nil
+}
+
+// parseStackTraceRecord parses a STACK_TRACE record of length bytes.
+func (p *Parser) parseStackTraceRecord(state *parserState, length uint32) error {
+	serial, err := state.reader.ReadUint32()
+	if err != nil {
+		return err
+	}
+
+	threadSerial, err := state.reader.ReadUint32()
+	if err != nil {
+		return err
+	}
+
+	numFrames, err := state.reader.ReadUint32()
+	if err != nil {
+		return err
+	}
+
+	if int64(numFrames)*int64(state.reader.IDSize()) != int64(length)-12 {
+		return fmt.Errorf("%w: %d frames in STACK_TRACE record of %d bytes at byte %d",
+			ErrInconsistentLength, numFrames, length, state.reader.Offset())
+	}
+	frameIDs := make([]uint64, 0, numFrames)
+	for i := uint32(0); i < numFrames; i++ {
+		frameID, err := state.reader.ReadID()
+		if err != nil {
+			return err
+		}
+		frameIDs = append(frameIDs, frameID)
+	}
+
+	state.threadStacks.addTrace(serial, &stackTraceRecord{
+		threadSerial: threadSerial,
+		frameIDs:     frameIDs,
+	})
 	return nil
 }
 
@@ -377,7 +537,7 @@ func (p *Parser) parseHeapDumpRecord(ctx context.Context, state *parserState, le
 		n, err := p.parseHeapDumpSubRecord(state, tag, endPos-bytesRead)
 		if err != nil {
 			// For unknown tags, try to skip remaining bytes and continue
-			if isUnknownTagError(err) {
+			if errors.Is(err, ErrBadTag) {
 				state.unknownTagCount++
 				remaining := endPos - bytesRead
 				state.skippedBytes += remaining
@@ -392,19 +552,14 @@ func (p *Parser) parseHeapDumpRecord(ctx context.Context, state *parserState, le
 		}
 		bytesRead += n
 	}
+	if bytesRead > endPos {
+		return fmt.Errorf("%w: sub-records overrun their heap dump record by %d bytes at byte %d",
+			ErrInconsistentLength, bytesRead-endPos, state.reader.Offset())
+	}
 
 	return nil
 }
 
-// isUnknownTagError checks if the error is due to an unknown tag.
-func isUnknownTagError(err error) bool {
-	if err == nil {
-		return false
-	}
-	return strings.Contains(err.Error(), "unknown heap dump tag") ||
-		strings.Contains(err.Error(), "skipping unknown tag")
-}
-
 // parseHeapDumpSubRecord parses a sub-record within a heap dump.
 // remainingBytes is the number of bytes remaining in the heap dump record.
 func (p *Parser) parseHeapDumpSubRecord(state *parserState, tag HeapDumpTag, remainingBytes int64) (int64, error) {
@@ -571,6 +726,7 @@ func (p *Parser) parseHeapDumpSubRecord(state *parserState, tag HeapDumpTag, rem
 				FrameIndex: int(frameIndex),
 			})
 		}
+		state.threadStacks.addLocal(threadSerial, objectID, frameIndex, GCRootJNILocal)
 		return int64(idSize + 8), nil
 
 	case HeapTagRootJavaFrame:
@@ -594,6 +750,7 @@ func (p *Parser) parseHeapDumpSubRecord(state *parserState, tag HeapDumpTag, rem
 				FrameIndex: int(frameIndex),
 			})
 		}
+		state.threadStacks.addLocal(threadSerial, objectID, frameIndex, GCRootJavaFrame)
 		return int64(idSize + 8), nil
 
 	case HeapTagRootNativeStack:
@@ -667,6 +824,10 @@ func (p *Parser) parseHeapDumpSubRecord(state *parserState, tag HeapDumpTag, rem
 		if err != nil {
 			return 0, err
 		}
+		traceSerial, err := state.reader.ReadUint32()
+		if err != nil {
+			return 0, err
+		}
 		if state.refGraph != nil {
 			state.refGraph.AddGCRoot(&GCRoot{
 				ObjectID: objectID,
@@ -674,10 +835,7 @@ func (p *Parser) parseHeapDumpSubRecord(state *parserState, tag HeapDumpTag, rem
 				ThreadID: uint64(threadSerial),
 			})
 		}
-		// Skip remaining 4 bytes (stack trace serial)
-		if err := state.reader.Skip(4); err != nil {
-			return 0, err
-		}
+		state.threadStacks.addThread(threadSerial, objectID, traceSerial)
 		return int64(idSize + 8), nil
 
 	case HeapTagClassDump:
@@ -694,7 +852,7 @@ func (p *Parser) parseHeapDumpSubRecord(state *parserState, tag HeapDumpTag, rem
 
 	default:
 		// For unknown tags, we cannot determine the size, so we signal to skip remaining
-		return 0, fmt.Errorf("skipping unknown tag: 0x%02X", tag)
+		return 0, fmt.Errorf("%w 0x%02X at byte %d", ErrBadTag, byte(tag), state.reader.Offset()-1)
 	}
 }
 
@@ -853,6 +1011,7 @@ func (p *Parser) parseClassDump(state *parserState) (int64, error) {
 	bytesRead += 2
 
 	// Read static fields - these contain references from the Class object to other objects
+	var staticFields []StaticFieldInfo
 	for i := 0; i < int(staticFieldsCount); i++ {
 		// Field name ID
 		fieldNameID, err := state.reader.ReadID()
@@ -870,41 +1029,32 @@ func (p *Parser) parseClassDump(state *parserState) (int64, error) {
 
 		// Value
 		valueSize := BasicTypeSize(BasicType(typeByte), idSize)
+		value, err := state.reader.ReadBytes(valueSize)
+		if err != nil {
+			return 0, err
+		}
+		bytesRead += int64(valueSize)
 
-		// If this is an object reference, extract it for the reference graph
-		if BasicType(typeByte) == TypeObject && state.refGraph != nil {
-			var refID uint64
-			if idSize == 4 {
-				val, err := state.reader.ReadUint32()
-				if err != nil {
-					return 0, err
-				}
-				refID = uint64(val)
-			} else {
-				val, err := state.reader.ReadUint64()
-				if err != nil {
-					return 0, err
-				}
-				refID = val
-			}
-			bytesRead += int64(valueSize)
-
-			// Add reference from the Class object to the static field value
-			if refID != 0 {
-				fieldName := state.strings[fieldNameID]
-				state.refGraph.AddReference(ObjectReference{
-					FromObjectID: classID,
-					ToObjectID:   refID,
-					FieldName:    fieldName,
-					FromClassID:  classID,
-				})
-			}
+		field := StaticFieldInfo{
+			NameID: fieldNameID,
+			Name:   state.strings[fieldNameID],
+			Type:   BasicType(typeByte),
+		}
+		if field.Type == TypeObject {
+			field.RefID = decodeID(value, idSize)
 		} else {
-			// Skip non-object fields
-			if err := state.reader.Skip(int64(valueSize)); err != nil {
-				return 0, err
-			}
-			bytesRead += int64(valueSize)
+			field.Value = decodePrimitive(field.Type, value)
+		}
+		staticFields = append(staticFields, field)
+
+		// Add reference from the Class object to the static field value
+		if field.Type == TypeObject && field.RefID != 0 && state.refGraph != nil {
+			state.refGraph.AddReference(ObjectReference{
+				FromObjectID: classID,
+				ToObjectID:   field.RefID,
+				FieldName:    field.Name,
+				FromClassID:  classID,
+			})
 		}
 	}
 
@@ -965,8 +1115,10 @@ func (p *Parser) parseClassDump(state *parserState) (int64, error) {
 	layout := &ClassFieldLayout{
 		ClassID:      classID,
 		ClassName:    className,
-		SuperClassID: superClassID,
-		InstanceSize: int(instanceSize),
+		SuperClassID:  superClassID,
+		InstanceSize:  int(instanceSize),
+		ClassLoaderID: classLoaderID,
+		StaticFields:  staticFields,
 	}
 	// Convert FieldDescriptors to FieldInfo with names
 	offset := 0
@@ -988,8 +1140,10 @@ func (p *Parser) parseClassDump(state *parserState) (int64, error) {
 			state.refGraph.SetClassName(classID, className)
 		}
 		// Register the Class object itself with proper size calculation
-		// Use the new classObjectShallowSize function for accurate sizing
-		classObjectSize := classObjectShallowSize(state.sizeMode, int(staticFieldsCount))
+		if state.layout == nil {
+			state.layoutHints.add(classID)
+		}
+		classObjectSize := state.sizeLayout().classObjectSize(int(staticFieldsCount))
 		// IMPORTANT: Class objects should be categorized as instances of java.lang.Class
 		// We defer this until we know the java.lang.Class classID
 		// For now, register with self as classID, will be fixed in post-processing
@@ -1014,6 +1168,7 @@ func (p *Parser) parseInstanceDump(state *parserState) (int64, error) {
 		return 0, err
 	}
 	bytesRead += int64(idSize)
+	state.indexObject(objectID)
 
 	// Stack trace serial number
 	if _, err := state.reader.ReadUint32(); err != nil {
@@ -1051,21 +1206,24 @@ func (p *Parser) parseInstanceDump(state *parserState) (int64, error) {
 	bytesRead += int64(dataSize)
 
 	// Calculate JVM heap shallow size (not HPROF record size)
-	// Shallow size = object header + instance field data, aligned to 8 bytes
+	// Shallow size = object header + instance field data, aligned
 	// The dataSize from HPROF is the actual instance field data size
+	if state.layout == nil {
+		state.resolveLayout()
+	}
 	var shallowSize int64
 	if info, ok := state.classInfo[classID]; ok {
 		info.InstanceCount++
 		// Use the instanceSize from CLASS_DUMP which is the JVM's reported instance size
-		// This already includes all instance fields from the class hierarchy
-		// Add object header and align to 8 bytes
-		shallowSize = alignTo8(objectHeaderSize(state.sizeMode) + int64(info.InstanceSize))
+		// This already includes all instance fields from the class hierarchy,
+		// with references as large as IDs
+		shallowSize = state.layout.instanceSize(int64(info.InstanceSize), p.refFieldCount(state, classID), idSize)
 		info.TotalSize += shallowSize
 		state.totalHeapSize += shallowSize
 	} else {
 		// Class info not found (CLASS_DUMP not yet processed for this class)
-		// Estimate: object header + field data, aligned to 8 bytes
-		shallowSize = alignTo8(objectHeaderSize(state.sizeMode) + int64(dataSize))
+		// Estimate: object header + field data, references as large as IDs
+		shallowSize = state.layout.instanceSize(int64(dataSize), 0, idSize)
 		state.totalHeapSize += shallowSize
 
 		// Try to get class name from LOAD_CLASS records
@@ -1168,6 +1326,10 @@ func (p *Parser) extractReferencesWithFields(state *parserState, objectID, class
 	}
 }
 
+// maxClassHierarchyDepth bounds the walks up superclass chains, which are
+// cyclic in corrupt dumps.
+const maxClassHierarchyDepth = 64
+
 // getClassHierarchyFields returns all fields for a class and its superclasses.
 // In HPROF, instance data contains fields in order: current class fields first, then superclass fields.
 // This is the reverse of what you might expect!
@@ -1178,7 +1340,7 @@ func (p *Parser) getClassHierarchyFields(state *parserState, classID uint64) []F
 	// Collect class hierarchy from current class to root
 	var classHierarchy []uint64
 	currentClassID := classID
-	for currentClassID != 0 {
+	for currentClassID != 0 && len(classHierarchy) < maxClassHierarchyDepth {
 		classHierarchy = append(classHierarchy, currentClassID)
 		if info, ok := state.classInfo[currentClassID]; ok {
 			currentClassID = info.SuperClassID
@@ -1198,6 +1360,22 @@ func (p *Parser) getClassHierarchyFields(state *parserState, classID uint64) []F
 	return allFields
 }
 
+// refFieldCount returns the number of reference fields of the instances of
+// a class, superclass fields included.
+func (p *Parser) refFieldCount(state *parserState, classID uint64) int {
+	if count, ok := state.refFieldCounts[classID]; ok {
+		return count
+	}
+	count := 0
+	for _, f := range p.getClassHierarchyFields(state, classID) {
+		if f.Type == TypeObject {
+			count++
+		}
+	}
+	state.refFieldCounts[classID] = count
+	return count
+}
+
 // processDeferredInstances processes instances that were deferred because their CLASS_DUMP
 // hadn't been parsed yet. This should be called after all records are parsed.
 func (p *Parser) processDeferredInstances(state *parserState) {
@@ -1245,6 +1423,7 @@ func (p *Parser) parseObjectArrayDump(state *parserState) (int64, error) {
 		return 0, err
 	}
 	bytesRead += int64(idSize)
+	state.indexObject(arrayObjectID)
 
 	// Stack trace serial number
 	if _, err := state.reader.ReadUint32(); err != nil {
@@ -1282,10 +1461,11 @@ func (p *Parser) parseObjectArrayDump(state *parserState) (int64, error) {
 	bytesRead += elemBytes
 
 	// Calculate JVM heap shallow size for object array
-	// For object arrays, we use the HPROF-recorded element size (idSize) as the reference size
-	// This reflects the actual JVM memory layout at dump time
-	// Shallow size = array header (object header + 4 bytes length) + element references, aligned to 8 bytes
-	shallowSize := alignTo8(arrayHeaderSize(state.sizeMode) + int64(numElements)*int64(idSize))
+	// Shallow size = array header (object header + 4 bytes length) + element references, aligned
+	if state.layout == nil {
+		state.resolveLayout()
+	}
+	shallowSize := state.layout.arraySize(int64(numElements), state.layout.ReferenceSize)
 	state.totalHeapSize += shallowSize
 	state.totalInstances++
 
@@ -1305,6 +1485,9 @@ func (p *Parser) parseObjectArrayDump(state *parserState) (int64, error) {
 			TotalSize:     shallowSize,
 		}
 	}
+	if state.arrayHistograms != nil {
+		state.arrayHistograms.Add(className, int(numElements), shallowSize)
+	}
 
 	// Register class name in reference graph
 	if state.refGraph != nil && className != "" {
@@ -1352,6 +1535,7 @@ func (p *Parser) parsePrimitiveArrayDump(state *parserState) (int64, error) {
 		return 0, err
 	}
 	bytesRead += int64(idSize)
+	state.indexObject(arrayObjectID)
 
 	// Stack trace serial number
 	if _, err := state.reader.ReadUint32(); err != nil {
@@ -1382,13 +1566,19 @@ func (p *Parser) parsePrimitiveArrayDump(state *parserState) (int64, error) {
 	bytesRead += dataBytes
 
 	// Calculate JVM heap shallow size for primitive array
-	// Shallow size = array header (object header + 4 bytes length) + element data, aligned to 8 bytes
-	shallowSize := alignTo8(arrayHeaderSize(state.sizeMode) + dataBytes)
+	// Shallow size = array header (object header + 4 bytes length) + element data, aligned
+	if state.layout == nil {
+		state.resolveLayout()
+	}
+	shallowSize := state.layout.arraySize(int64(numElements), int64(elemSize))
 	state.totalHeapSize += shallowSize
 	state.totalInstances++
 
 	// Get array type name
 	typeName := primitiveArrayTypeName(BasicType(elemType))
+	if state.arrayHistograms != nil {
+		state.arrayHistograms.Add(typeName, int(numElements), shallowSize)
+	}
 
 	// Get or create class ID for this primitive array type
 	var classID uint64
@@ -1451,6 +1641,113 @@ func (p *Parser) parseHeapSummaryRecord(state *parserState) error {
 	return nil
 }
 
+// parseAllocSitesRecord parses an ALLOC_SITES record of length bytes. Each
+// record holds the whole table, so the last one is kept.
+func (p *Parser) parseAllocSitesRecord(state *parserState, length uint32) error {
+	r := state.reader
+	flags, err := r.ReadUint16()
+	if err != nil {
+		return err
+	}
+	cutoff, err := r.ReadUint32()
+	if err != nil {
+		return err
+	}
+	var totals [2]uint32
+	for i := range totals {
+		if totals[i], err = r.ReadUint32(); err != nil {
+			return err
+		}
+	}
+	var allocTotals [2]uint64
+	for i := range allocTotals {
+		if allocTotals[i], err = r.ReadUint64(); err != nil {
+			return err
+		}
+	}
+	count, err := r.ReadUint32()
+	if err != nil {
+		return err
+	}
+	// 34 bytes of totals, then 25 bytes per site
+	if int64(count)*25 != int64(length)-34 {
+		return fmt.Errorf("%w: %d sites in ALLOC_SITES record of %d bytes at byte %d",
+			ErrInconsistentLength, count, length, r.Offset())
+	}
+
+	rec := &allocSitesRecord{
+		sites: &AllocationSites{
+			Flags:               flags,
+			CutoffRatio:         math.Float32frombits(cutoff),
+			TotalLiveBytes:      int64(totals[0]),
+			TotalLiveInstances:  int64(totals[1]),
+			TotalAllocBytes:     int64(allocTotals[0]),
+			TotalAllocInstances: int64(allocTotals[1]),
+			Sites:               make([]*AllocationSite, 0, count),
+		},
+	}
+	for i := uint32(0); i < count; i++ {
+		arrayType, err := r.ReadByte()
+		if err != nil {
+			return err
+		}
+		// Class serial, stack trace serial, live bytes and instances,
+		// allocated bytes and instances
+		var fields [6]uint32
+		for j := range fields {
+			if fields[j], err = r.ReadUint32(); err != nil {
+				return err
+			}
+		}
+		rec.classSerials = append(rec.classSerials, fields[0])
+		rec.arrayTypes = append(rec.arrayTypes, BasicType(arrayType))
+		rec.sites.Sites = append(rec.sites.Sites, &AllocationSite{
+			StackTraceSerial: fields[1],
+			LiveBytes:        int64(fields[2]),
+			LiveInstances:    int64(fields[3]),
+			AllocBytes:       int64(fields[4]),
+			AllocInstances:   int64(fields[5]),
+		})
+	}
+	state.allocSites = rec
+	return nil
+}
+
+// parseCPUSamplesRecord parses a CPU_SAMPLES record of length bytes, adding
+// its samples to those of previous records.
+func (p *Parser) parseCPUSamplesRecord(state *parserState, length uint32) error {
+	r := state.reader
+	total, err := r.ReadUint32()
+	if err != nil {
+		return err
+	}
+	count, err := r.ReadUint32()
+	if err != nil {
+		return err
+	}
+	if int64(count)*8 != int64(length)-8 {
+		return fmt.Errorf("%w: %d traces in CPU_SAMPLES record of %d bytes at byte %d",
+			ErrInconsistentLength, count, length, r.Offset())
+	}
+
+	if state.cpuSamples == nil {
+		state.cpuSamples = &cpuSamplesRecord{samples: make(map[uint32]int64)}
+	}
+	state.cpuSamples.total += int64(total)
+	for i := uint32(0); i < count; i++ {
+		samples, err := r.ReadUint32()
+		if err != nil {
+			return err
+		}
+		serial, err := r.ReadUint32()
+		if err != nil {
+			return err
+		}
+		state.cpuSamples.samples[serial] += int64(samples)
+	}
+	return nil
+}
+
 // getClassName returns the class name for a class ID.
 func (p *Parser) getClassName(state *parserState, classID uint64) string {
 	if nameID, ok := state.classNames[classID]; ok {
@@ -1463,8 +1760,10 @@ func (p *Parser) getClassName(state *parserState, classID uint64) string {
 
 // buildResult builds the final analysis result.
 // This delegates to ResultBuilder for cleaner separation of concerns.
-func (p *Parser) buildResult(state *parserState, timer *utils.Timer) *HeapAnalysisResult {
-	builder := NewResultBuilder(state, p.opts, timer)
+func (p *Parser) buildResult(ctx context.Context, state *parserState, timer *utils.Timer) (*HeapAnalysisResult, error) {
+	ctx, span := telemetry.StartSpan(ctx, "hprof.build_result")
+	defer span.End()
+	builder := NewResultBuilder(ctx, state, p.opts, timer)
 	return builder.Build()
 }
 
@@ -0,0 +1,88 @@
+package hprof
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+)
+
+// Fuzz targets for the record and sub-record parsers. They run their seed
+// corpus as part of go test; fuzz them with
+//
+//	go test ./internal/parser/hprof -run '^$' -fuzz '^FuzzParse$'
+//	go test ./internal/parser/hprof -run '^$' -fuzz FuzzParseHeapDumpRecord
+
+// newFuzzSeedHprof returns a small dump using every kind of record the
+// parser decodes.
+func newFuzzSeedHprof() []byte {
+	b := newTestHprofBuilder()
+	b.loadClassSerial(1, 0x10, "java/lang/Thread")
+	b.loadClassSerial(2, 0x20, "com/example/Node")
+	b.loadClass(0x30, "[Lcom/example/Node;")
+	b.stackFrame(0xF1, "run", "()V", "Thread.java", 1, 42)
+	b.stackTrace(7, 1, 0xF1)
+
+	b.classDump(0x10, 0, 0)
+	b.classDumpStatics(0x20, 0, 0, 16, []testStatic{{name: "head", typ: TypeObject, value: refBytes(0x2000)}},
+		testField{name: "next", typ: TypeObject}, testField{name: "value", typ: TypeLong})
+	b.classDump(0x30, 0, 0)
+	b.instanceDump(0x1000, 0x10, nil)
+	b.instanceDump(0x2000, 0x20, append(refBytes(0x2001), make([]byte, 8)...))
+	b.instanceDump(0x2001, 0x20, append(refBytes(0x2000), make([]byte, 8)...))
+	b.objectArrayDump(0x3000, 0x30, 0x2000, 0, 0x2001)
+	b.primitiveArrayDump(0x4000, TypeChar, 3, []byte{0, 'a', 0, 'b', 0, 'c'})
+	b.rootThreadObject(0x1000, 1, 7)
+	b.rootJavaFrame(0x3000, 1, 0)
+	b.rootStickyClass(0x20)
+	b.rootJNIGlobal(0x4000)
+	return b.bytes()
+}
+
+// checkMalformedError fails the test for errors outside of the error
+// taxonomy of malformed dumps.
+func checkMalformedError(t *testing.T, err error) {
+	if err == nil {
+		return
+	}
+	for _, target := range []error{ErrTruncated, ErrBadTag, ErrInconsistentLength, ErrBadHeader} {
+		if errors.Is(err, target) {
+			return
+		}
+	}
+	t.Fatalf("untyped error: %v", err)
+}
+
+func FuzzParse(f *testing.F) {
+	data := newFuzzSeedHprof()
+	f.Add(data)
+	f.Add(data[:len(data)/2])
+	f.Add(data[:31])
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		_, err := NewParser(nil).Parse(context.Background(), bytes.NewReader(data))
+		checkMalformedError(t, err)
+	})
+}
+
+func FuzzParseHeapDumpRecord(f *testing.F) {
+	b := newTestHprofBuilder()
+	b.classDump(0x20, 0, 16, testField{name: "next", typ: TypeObject}, testField{name: "value", typ: TypeLong})
+	b.instanceDump(0x2000, 0x20, append(refBytes(0x2001), make([]byte, 8)...))
+	b.objectArrayDump(0x3000, 0x30, 0x2000)
+	b.primitiveArrayDump(0x4000, TypeInt, 2, nil)
+	b.rootThreadObject(0x1000, 1, 7)
+	b.rootJNIGlobal(0x4000)
+	f.Add(b.heap.Bytes())
+	f.Add([]byte{byte(HeapTagInstanceDump), 1, 2, 3})
+	f.Add([]byte{0xFF, 0, 0})
+
+	f.Fuzz(func(t *testing.T, body []byte) {
+		reader := NewReader(bytes.NewReader(body))
+		state := newParserState(reader, DefaultParserOptions())
+		p := NewParser(nil)
+		err := p.parseHeapDumpRecord(context.Background(), state, uint32(len(body)))
+		checkMalformedError(t, err)
+		p.processDeferredInstances(state)
+	})
+}
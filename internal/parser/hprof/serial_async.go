@@ -109,7 +109,7 @@ func (s *AsyncSerializer) runSerializationJob(ctx context.Context, g *ReferenceG
 	}
 	
 	// Perform serialization
-	stats, err := g.SerializeToFile(filename, opts)
+	stats, err := g.SerializeToFileContext(ctx, filename, opts)
 	
 	job.result.Stats = stats
 	job.result.Error = err
@@ -0,0 +1,682 @@
+// Package hprof provides parsing functionality for Java HPROF heap dump files.
+package hprof
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	pb "github.com/perf-analysis/internal/parser/hprof/proto"
+	"github.com/perf-analysis/pkg/compression"
+	"github.com/perf-analysis/pkg/parallel"
+	"google.golang.org/protobuf/proto"
+)
+
+// Chunked layout of refgraph.bin (v3 with FeatureChunked):
+//
+//	Magic(4) | Version(1) | Compression(1) | EnvelopeLen(4) | Envelope |
+//	Chunk... | Manifest | ManifestLen(4) | FooterMagic(4)
+//
+// Each chunk is compressed independently, so loaders can seek to and decode
+// only the chunks they need (e.g. class table + retained sizes for a fast
+// overview) and load the rest later on demand.
+
+const (
+	// ChunkFooterMagic terminates chunked reference graph files.
+	ChunkFooterMagic = "RGCM"
+
+	// chunkFooterSize is ManifestLen(4) + FooterMagic(4).
+	chunkFooterSize = 8
+
+	// DefaultChunkEntries is the default maximum number of objects or edges per chunk.
+	DefaultChunkEntries = 1 << 20
+)
+
+// ChunkKind identifies the contents of a chunk.
+type ChunkKind int32
+
+const (
+	// ChunkClassTable holds class names.
+	ChunkClassTable = ChunkKind(pb.RefGraphChunkKind_REF_GRAPH_CHUNK_CLASS_TABLE)
+	// ChunkFieldNames holds the field name string table used by edges.
+	ChunkFieldNames = ChunkKind(pb.RefGraphChunkKind_REF_GRAPH_CHUNK_FIELD_NAMES)
+	// ChunkObjects holds object class IDs and shallow sizes.
+	ChunkObjects = ChunkKind(pb.RefGraphChunkKind_REF_GRAPH_CHUNK_OBJECTS)
+	// ChunkEdges holds object references.
+	ChunkEdges = ChunkKind(pb.RefGraphChunkKind_REF_GRAPH_CHUNK_EDGES)
+	// ChunkGCRoots holds GC roots.
+	ChunkGCRoots = ChunkKind(pb.RefGraphChunkKind_REF_GRAPH_CHUNK_GC_ROOTS)
+	// ChunkDominators holds immediate dominators.
+	ChunkDominators = ChunkKind(pb.RefGraphChunkKind_REF_GRAPH_CHUNK_DOMINATORS)
+	// ChunkRetainedSizes holds object and class retained sizes.
+	ChunkRetainedSizes = ChunkKind(pb.RefGraphChunkKind_REF_GRAPH_CHUNK_RETAINED_SIZES)
+)
+
+// AllChunkKinds lists every chunk kind in file order.
+var AllChunkKinds = []ChunkKind{
+	ChunkClassTable, ChunkFieldNames, ChunkObjects, ChunkEdges,
+	ChunkGCRoots, ChunkDominators, ChunkRetainedSizes,
+}
+
+// String returns the chunk kind name.
+func (k ChunkKind) String() string {
+	switch k {
+	case ChunkClassTable:
+		return "class_table"
+	case ChunkFieldNames:
+		return "field_names"
+	case ChunkObjects:
+		return "objects"
+	case ChunkEdges:
+		return "edges"
+	case ChunkGCRoots:
+		return "gc_roots"
+	case ChunkDominators:
+		return "dominators"
+	case ChunkRetainedSizes:
+		return "retained_sizes"
+	default:
+		return fmt.Sprintf("unknown(%d)", int32(k))
+	}
+}
+
+// MarshalText encodes the chunk kind by name.
+func (k ChunkKind) MarshalText() ([]byte, error) {
+	return []byte(k.String()), nil
+}
+
+// ChunkInfo describes one chunk of a chunked reference graph file.
+type ChunkInfo struct {
+	Kind      ChunkKind `json:"kind"`
+	Part      int       `json:"part"`
+	Offset    int64     `json:"offset"`
+	Length    int64     `json:"length"`
+	RawLength int64     `json:"raw_length"`
+	Entries   int64     `json:"entries"`
+}
+
+// pendingChunk is a chunk message waiting to be marshaled and compressed.
+type pendingChunk struct {
+	kind    ChunkKind
+	part    int
+	entries int
+	msg     proto.Message
+
+	data     []byte
+	rawLen   int
+	checksum uint32
+}
+
+// serializeChunked writes the graph in the chunked layout.
+// Chunks are marshaled and compressed in parallel.
+func (g *ReferenceGraph) serializeChunked(ctx context.Context, opts SerializeOptions, startTime time.Time) ([]byte, *SerializationStats, error) {
+	stats := &SerializationStats{}
+	chunks, metadata, fieldNameCount := g.buildChunks(opts, stats)
+	stats.UniqueFieldNames = fieldNameCount
+	metadata.CreatedAt = startTime.UnixMilli()
+	metadata.SourceFile = opts.SourceFile
+
+	if err := ctx.Err(); err != nil {
+		return nil, nil, err
+	}
+	if err := compressChunks(ctx, chunks, opts); err != nil {
+		return nil, nil, err
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString(MagicBytes)
+	buf.WriteByte(byte(SerializerVersion))
+	buf.WriteByte(byte(opts.Compression))
+
+	includesDominators := opts.IncludeDominatorData && g.dominatorComputed
+	env := buildEnvelope(opts, includesDominators, startTime)
+	env.Features |= uint64(FeatureChunked)
+	envelopeBytes, err := proto.Marshal(env)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to marshal envelope: %w", err)
+	}
+	buf.Write(binary.BigEndian.AppendUint32(nil, uint32(len(envelopeBytes))))
+	buf.Write(envelopeBytes)
+
+	manifest := &pb.ChunkManifest{Metadata: metadata}
+	for _, c := range chunks {
+		manifest.Chunks = append(manifest.Chunks, &pb.ChunkEntry{
+			Kind:      pb.RefGraphChunkKind(c.kind),
+			Part:      uint32(c.part),
+			Offset:    uint64(buf.Len()),
+			Length:    uint64(len(c.data)),
+			RawLength: uint64(c.rawLen),
+			Entries:   uint64(c.entries),
+			Checksum:  c.checksum,
+		})
+		buf.Write(c.data)
+		stats.RawSize += int64(c.rawLen)
+	}
+
+	manifestBytes, err := proto.Marshal(manifest)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to marshal chunk manifest: %w", err)
+	}
+	buf.Write(manifestBytes)
+	buf.Write(binary.BigEndian.AppendUint32(nil, uint32(len(manifestBytes))))
+	buf.WriteString(ChunkFooterMagic)
+
+	result := buf.Bytes()
+	stats.CompressedSize = int64(len(result))
+	if stats.CompressedSize > 0 {
+		stats.CompressionRatio = float64(stats.RawSize) / float64(stats.CompressedSize)
+	}
+	stats.Duration = time.Since(startTime)
+	return result, stats, nil
+}
+
+// buildChunks splits the graph into chunk messages in file order.
+func (g *ReferenceGraph) buildChunks(opts SerializeOptions, stats *SerializationStats) ([]*pendingChunk, *pb.GraphMetadata, int) {
+	maxEntries := opts.ChunkEntries
+	if maxEntries <= 0 {
+		maxEntries = DefaultChunkEntries
+	}
+	var chunks []*pendingChunk
+
+	// Class table
+	classes := &pb.ReferenceGraphProto{ClassNames: make([]*pb.ClassNameEntry, 0, len(g.classNames))}
+	for classID, className := range g.classNames {
+		classes.ClassNames = append(classes.ClassNames, &pb.ClassNameEntry{ClassId: classID, ClassName: className})
+	}
+	stats.Classes = int64(len(classes.ClassNames))
+	chunks = append(chunks, &pendingChunk{kind: ChunkClassTable, entries: len(classes.ClassNames), msg: classes})
+
+	// Objects, split into parts
+	var objectChunks []*pendingChunk
+	var objects []*pb.ObjectInfoProto
+	var totalHeapSize int64
+	flushObjects := func() {
+		objectChunks = append(objectChunks, &pendingChunk{
+			kind: ChunkObjects, part: len(objectChunks), entries: len(objects),
+			msg: &pb.ReferenceGraphProto{Objects: objects},
+		})
+		objects = nil
+	}
+	for objID, classID := range g.objectClass {
+		size := g.objectSize[objID]
+		totalHeapSize += size
+		objects = append(objects, &pb.ObjectInfoProto{ObjectId: objID, ClassId: classID, Size: size})
+		if len(objects) >= maxEntries {
+			flushObjects()
+		}
+	}
+	if len(objects) > 0 || len(objectChunks) == 0 {
+		flushObjects()
+	}
+	stats.Objects = int64(len(g.objectClass))
+
+	// Edges, split into parts; field names are collected along the way
+	fieldNameToIdx := make(map[string]uint32)
+	fieldNames := []string{""} // Index 0 is empty string
+	var edgeChunks []*pendingChunk
+	var edges []*pb.ObjectReferenceProto
+	flushEdges := func() {
+		edgeChunks = append(edgeChunks, &pendingChunk{
+			kind: ChunkEdges, part: len(edgeChunks), entries: len(edges),
+			msg: &pb.ReferenceGraphProto{References: edges},
+		})
+		edges = nil
+	}
+	for _, refs := range g.outgoingRefs {
+		for _, ref := range refs {
+			idx := uint32(0)
+			if ref.FieldName != "" {
+				var ok bool
+				if idx, ok = fieldNameToIdx[ref.FieldName]; !ok {
+					idx = uint32(len(fieldNames))
+					fieldNameToIdx[ref.FieldName] = idx
+					fieldNames = append(fieldNames, ref.FieldName)
+				}
+			}
+			edges = append(edges, &pb.ObjectReferenceProto{
+				FromObjectId: ref.FromObjectID,
+				ToObjectId:   ref.ToObjectID,
+				FromClassId:  ref.FromClassID,
+				FieldNameIdx: idx,
+			})
+			stats.References++
+			if len(edges) >= maxEntries {
+				flushEdges()
+			}
+		}
+	}
+	if len(edges) > 0 || len(edgeChunks) == 0 {
+		flushEdges()
+	}
+
+	chunks = append(chunks, &pendingChunk{kind: ChunkFieldNames, entries: len(fieldNames), msg: &pb.StringTable{Strings: fieldNames}})
+	chunks = append(chunks, objectChunks...)
+	chunks = append(chunks, edgeChunks...)
+
+	// GC roots
+	roots := &pb.ReferenceGraphProto{GcRoots: make([]*pb.GCRootProto, 0, len(g.gcRoots))}
+	for _, root := range g.gcRoots {
+		roots.GcRoots = append(roots.GcRoots, &pb.GCRootProto{
+			ObjectId:   root.ObjectID,
+			Type:       gcRootTypeToProto(root.Type),
+			ThreadId:   root.ThreadID,
+			FrameIndex: int32(root.FrameIndex),
+		})
+	}
+	stats.GCRoots = int64(len(roots.GcRoots))
+	chunks = append(chunks, &pendingChunk{kind: ChunkGCRoots, entries: len(roots.GcRoots), msg: roots})
+
+	// Dominator data is split so that retained sizes can be loaded without the tree
+	if opts.IncludeDominatorData && g.dominatorComputed {
+		doms := &pb.DominatorDataProto{Computed: true, Dominators: make([]*pb.DominatorEntry, 0, len(g.dominators))}
+		for objID, domID := range g.dominators {
+			doms.Dominators = append(doms.Dominators, &pb.DominatorEntry{ObjectId: objID, DominatorId: domID})
+		}
+		chunks = append(chunks, &pendingChunk{
+			kind: ChunkDominators, entries: len(doms.Dominators),
+			msg: &pb.ReferenceGraphProto{DominatorData: doms},
+		})
+
+		sizes := &pb.DominatorDataProto{Computed: true, RetainedSizes: make([]*pb.RetainedSizeEntry, 0, len(g.retainedSizes))}
+		for objID, size := range g.retainedSizes {
+			sizes.RetainedSizes = append(sizes.RetainedSizes, &pb.RetainedSizeEntry{ObjectId: objID, RetainedSize: size})
+		}
+		for classID, size := range g.classRetainedSizes {
+			sizes.ClassRetainedSizes = append(sizes.ClassRetainedSizes, &pb.ClassRetainedSizeEntry{ClassId: classID, RetainedSize: size})
+		}
+		for classID, size := range g.classRetainedSizesAttributed {
+			sizes.ClassRetainedSizesAttributed = append(sizes.ClassRetainedSizesAttributed, &pb.ClassRetainedSizeEntry{ClassId: classID, RetainedSize: size})
+		}
+		for classObjID := range g.classObjectIDs {
+			sizes.ClassObjectIds = append(sizes.ClassObjectIds, classObjID)
+		}
+		chunks = append(chunks, &pendingChunk{
+			kind: ChunkRetainedSizes, entries: len(sizes.RetainedSizes),
+			msg: &pb.ReferenceGraphProto{DominatorData: sizes},
+		})
+	}
+
+	metadata := &pb.GraphMetadata{
+		TotalObjects:    stats.Objects,
+		TotalReferences: stats.References,
+		TotalGcRoots:    stats.GCRoots,
+		TotalHeapSize:   totalHeapSize,
+	}
+	return chunks, metadata, len(fieldNames)
+}
+
+// defaultChunkWorkers caps the workers (de)compressing chunks by default.
+const defaultChunkWorkers = 8
+
+// forEachChunk calls fn on chunks 0..n-1 on up to maxWorkers workers of the
+// shared pool (0 = defaultChunkWorkers). Each worker owns a compressor made
+// by newComp, since encoders are not shared safely. It returns the first
+// error of fn, or the error of ctx if it is done.
+func forEachChunk(ctx context.Context, n, maxWorkers int, newComp func() (compression.Compressor, error), fn func(comp compression.Compressor, i int) error) error {
+	if maxWorkers <= 0 {
+		maxWorkers = defaultChunkWorkers
+	}
+	pool := parallel.Shared()
+	comps := make([]compression.Compressor, pool.Workers(maxWorkers))
+	defer func() {
+		for _, comp := range comps {
+			if comp != nil {
+				compression.Close(comp)
+			}
+		}
+	}()
+
+	var mu sync.Mutex
+	var firstErr error
+	setErr := func(err error) {
+		mu.Lock()
+		if firstErr == nil {
+			firstErr = err
+		}
+		mu.Unlock()
+	}
+
+	ctxErr := pool.Range(ctx, n, len(comps), func(worker, start, end int) {
+		if comps[worker] == nil {
+			comp, err := newComp()
+			if err != nil {
+				setErr(err)
+				return
+			}
+			comps[worker] = comp
+		}
+		for i := start; i < end; i++ {
+			if err := fn(comps[worker], i); err != nil {
+				setErr(err)
+			}
+		}
+	})
+	if firstErr != nil {
+		return firstErr
+	}
+	return ctxErr
+}
+
+// compressChunks marshals and compresses chunks in parallel.
+func compressChunks(ctx context.Context, chunks []*pendingChunk, opts SerializeOptions) error {
+	newComp := func() (compression.Compressor, error) {
+		comp, err := compression.New(opts.Compression, opts.CompressionLevel)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create compressor: %w", err)
+		}
+		return comp, nil
+	}
+	return forEachChunk(ctx, len(chunks), opts.Workers, newComp, func(comp compression.Compressor, i int) error {
+		c := chunks[i]
+		raw, err := proto.Marshal(c.msg)
+		if err != nil {
+			return fmt.Errorf("failed to marshal %s chunk %d: %w", c.kind, c.part, err)
+		}
+		c.rawLen = len(raw)
+		if c.data, err = comp.Compress(raw); err != nil {
+			return fmt.Errorf("failed to compress %s chunk %d: %w", c.kind, c.part, err)
+		}
+		c.checksum = crc32.ChecksumIEEE(c.data)
+		c.msg = nil // release the message as soon as it is encoded
+		return nil
+	})
+}
+
+// ChunkedGraphReader loads a chunked reference graph incrementally.
+// Chunks are read on demand, so callers can start with a cheap subset
+// (e.g. class table + retained sizes) and resume loading the remaining
+// chunks into the same graph later. It is safe for concurrent use.
+type ChunkedGraphReader struct {
+	mu sync.Mutex
+
+	r        io.ReaderAt
+	closer   io.Closer
+	info     *RefGraphFileInfo
+	manifest *pb.ChunkManifest
+
+	graph      *ReferenceGraph
+	fieldNames []string
+	loaded     map[*pb.ChunkEntry]bool
+}
+
+// OpenChunkedReferenceGraph opens a chunked refgraph.bin file.
+// Only the header and manifest are read; call Load to read chunks.
+func OpenChunkedReferenceGraph(filename string) (*ChunkedGraphReader, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open file: %w", err)
+	}
+	st, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("failed to stat file: %w", err)
+	}
+
+	reader, err := NewChunkedGraphReader(f, st.Size())
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	reader.closer = f
+	return reader, nil
+}
+
+// NewChunkedGraphReader reads the header and manifest of chunked reference graph data.
+func NewChunkedGraphReader(r io.ReaderAt, size int64) (*ChunkedGraphReader, error) {
+	info, err := readChunkedHeader(r, size)
+	if err != nil {
+		return nil, err
+	}
+	if !info.Has(FeatureChunked) {
+		return nil, fmt.Errorf("reference graph written by %s is not chunked", info.Writer())
+	}
+
+	var footer [chunkFooterSize]byte
+	if size < int64(info.stringTableOffset)+chunkFooterSize {
+		return nil, fmt.Errorf("data too short")
+	}
+	if _, err := r.ReadAt(footer[:], size-chunkFooterSize); err != nil {
+		return nil, fmt.Errorf("failed to read chunk footer: %w", err)
+	}
+	if string(footer[4:]) != ChunkFooterMagic {
+		return nil, fmt.Errorf("invalid chunk footer magic: %q", string(footer[4:]))
+	}
+
+	manifestLen := int64(binary.BigEndian.Uint32(footer[:4]))
+	chunksEnd := size - chunkFooterSize - manifestLen
+	if chunksEnd < int64(info.stringTableOffset) {
+		return nil, fmt.Errorf("invalid chunk manifest length")
+	}
+	manifestBytes := make([]byte, manifestLen)
+	if _, err := r.ReadAt(manifestBytes, chunksEnd); err != nil {
+		return nil, fmt.Errorf("failed to read chunk manifest: %w", err)
+	}
+	var manifest pb.ChunkManifest
+	if err := proto.Unmarshal(manifestBytes, &manifest); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal chunk manifest: %w", err)
+	}
+	for _, c := range manifest.Chunks {
+		if c.Offset < uint64(info.stringTableOffset) || c.Offset+c.Length > uint64(chunksEnd) {
+			return nil, fmt.Errorf("%s chunk %d out of bounds", ChunkKind(c.Kind), c.Part)
+		}
+	}
+
+	estimatedObjects := 0
+	if manifest.Metadata != nil {
+		estimatedObjects = int(manifest.Metadata.TotalObjects)
+	}
+	return &ChunkedGraphReader{
+		r:        r,
+		info:     info,
+		manifest: &manifest,
+		graph:    NewReferenceGraphWithCapacity(estimatedObjects),
+		loaded:   make(map[*pb.ChunkEntry]bool),
+	}, nil
+}
+
+// readChunkedHeader reads and parses the fixed header and envelope.
+func readChunkedHeader(r io.ReaderAt, size int64) (*RefGraphFileInfo, error) {
+	var fixed [10]byte
+	if size < int64(len(fixed)) {
+		return nil, fmt.Errorf("data too short")
+	}
+	if _, err := r.ReadAt(fixed[:], 0); err != nil {
+		return nil, fmt.Errorf("failed to read header: %w", err)
+	}
+	if string(fixed[:4]) == MagicBytes && fixed[4] < envelopeFormatVersion {
+		return ReadRefGraphFileInfo(fixed[:])
+	}
+
+	// Header + envelope + the 4 bytes ReadRefGraphFileInfo expects after it
+	headerLen := int64(len(fixed)) + int64(binary.BigEndian.Uint32(fixed[6:10])) + 4
+	if headerLen > size {
+		headerLen = size
+	}
+	header := make([]byte, headerLen)
+	if _, err := r.ReadAt(header, 0); err != nil {
+		return nil, fmt.Errorf("failed to read header: %w", err)
+	}
+	return ReadRefGraphFileInfo(header)
+}
+
+// Info returns the file header information.
+func (cr *ChunkedGraphReader) Info() *RefGraphFileInfo {
+	return cr.info
+}
+
+// Metadata returns the graph totals recorded in the manifest.
+func (cr *ChunkedGraphReader) Metadata() *pb.GraphMetadata {
+	return cr.manifest.Metadata
+}
+
+// Chunks describes the chunks of the file in file order.
+func (cr *ChunkedGraphReader) Chunks() []ChunkInfo {
+	result := make([]ChunkInfo, 0, len(cr.manifest.Chunks))
+	for _, c := range cr.manifest.Chunks {
+		result = append(result, ChunkInfo{
+			Kind:      ChunkKind(c.Kind),
+			Part:      int(c.Part),
+			Offset:    int64(c.Offset),
+			Length:    int64(c.Length),
+			RawLength: int64(c.RawLength),
+			Entries:   int64(c.Entries),
+		})
+	}
+	return result
+}
+
+// Loaded reports whether all chunks of the given kind have been loaded.
+// Kinds absent from the file count as loaded.
+func (cr *ChunkedGraphReader) Loaded(kind ChunkKind) bool {
+	cr.mu.Lock()
+	defer cr.mu.Unlock()
+	for _, c := range cr.manifest.Chunks {
+		if ChunkKind(c.Kind) == kind && !cr.loaded[c] {
+			return false
+		}
+	}
+	return true
+}
+
+// Graph returns the graph populated by the chunks loaded so far.
+func (cr *ChunkedGraphReader) Graph() *ReferenceGraph {
+	return cr.graph
+}
+
+// Load reads the given chunk kinds (all kinds if none are given) into the
+// graph and returns it. Chunks loaded by earlier calls are skipped, so Load
+// can be called repeatedly to resume loading. Loading edges also loads the
+// field name table they refer to.
+func (cr *ChunkedGraphReader) Load(kinds ...ChunkKind) (*ReferenceGraph, error) {
+	if len(kinds) == 0 {
+		kinds = AllChunkKinds
+	}
+	want := make(map[ChunkKind]bool, len(kinds)+1)
+	for _, k := range kinds {
+		want[k] = true
+	}
+	if want[ChunkEdges] {
+		want[ChunkFieldNames] = true
+	}
+
+	cr.mu.Lock()
+	defer cr.mu.Unlock()
+
+	var pending []*pb.ChunkEntry
+	for _, c := range cr.manifest.Chunks {
+		if want[ChunkKind(c.Kind)] && !cr.loaded[c] {
+			pending = append(pending, c)
+		}
+	}
+	if len(pending) == 0 {
+		return cr.graph, nil
+	}
+
+	decoded, err := cr.decodeChunks(pending)
+	if err != nil {
+		return nil, err
+	}
+
+	// Field names must be known before edges are restored
+	sort.SliceStable(decoded, func(i, j int) bool {
+		return decoded[i].entry.Kind == pb.RefGraphChunkKind_REF_GRAPH_CHUNK_FIELD_NAMES &&
+			decoded[j].entry.Kind != pb.RefGraphChunkKind_REF_GRAPH_CHUNK_FIELD_NAMES
+	})
+	for _, d := range decoded {
+		if d.strings != nil {
+			cr.fieldNames = d.strings.Strings
+		} else {
+			restoreGraphProto(cr.graph, d.graph, cr.fieldNames)
+		}
+		cr.loaded[d.entry] = true
+	}
+	return cr.graph, nil
+}
+
+// decodedChunk is a chunk read from disk and unmarshaled.
+type decodedChunk struct {
+	entry   *pb.ChunkEntry
+	graph   *pb.ReferenceGraphProto
+	strings *pb.StringTable
+}
+
+// decodeChunks reads, verifies, decompresses and unmarshals chunks in parallel.
+func (cr *ChunkedGraphReader) decodeChunks(entries []*pb.ChunkEntry) ([]*decodedChunk, error) {
+	decoded := make([]*decodedChunk, len(entries))
+	newComp := func() (compression.Compressor, error) {
+		comp, err := compression.New(cr.info.Compression, compression.LevelDefault)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create decompressor: %w", err)
+		}
+		return comp, nil
+	}
+	err := forEachChunk(context.Background(), len(entries), 0, newComp, func(comp compression.Compressor, i int) error {
+		d, err := cr.decodeChunk(entries[i], comp)
+		if err != nil {
+			return err
+		}
+		decoded[i] = d
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return decoded, nil
+}
+
+// decodeChunk reads and decodes a single chunk.
+func (cr *ChunkedGraphReader) decodeChunk(entry *pb.ChunkEntry, comp compression.Compressor) (*decodedChunk, error) {
+	kind := ChunkKind(entry.Kind)
+	data := make([]byte, entry.Length)
+	if _, err := cr.r.ReadAt(data, int64(entry.Offset)); err != nil {
+		return nil, fmt.Errorf("failed to read %s chunk %d: %w", kind, entry.Part, err)
+	}
+	if crc32.ChecksumIEEE(data) != entry.Checksum {
+		return nil, fmt.Errorf("%s chunk %d is corrupt: checksum mismatch", kind, entry.Part)
+	}
+	raw, err := comp.Decompress(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decompress %s chunk %d: %w", kind, entry.Part, err)
+	}
+
+	d := &decodedChunk{entry: entry}
+	var msg proto.Message
+	if kind == ChunkFieldNames {
+		d.strings = &pb.StringTable{}
+		msg = d.strings
+	} else {
+		d.graph = &pb.ReferenceGraphProto{}
+		msg = d.graph
+	}
+	if err := proto.Unmarshal(raw, msg); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal %s chunk %d: %w", kind, entry.Part, err)
+	}
+	return d, nil
+}
+
+// Close releases the underlying file, if the reader owns one.
+func (cr *ChunkedGraphReader) Close() error {
+	if cr.closer != nil {
+		return cr.closer.Close()
+	}
+	return nil
+}
+
+// deserializeChunked loads every chunk of in-memory chunked data.
+func deserializeChunked(data []byte) (*ReferenceGraph, error) {
+	reader, err := NewChunkedGraphReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, err
+	}
+	return reader.Load()
+}
@@ -0,0 +1,174 @@
+package hprof
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// newChunkedTestGraph creates a graph with dominator data spread over several chunks.
+func newChunkedTestGraph(t *testing.T) *ReferenceGraph {
+	t.Helper()
+	g := NewReferenceGraphWithCapacity(16)
+	g.SetClassName(1000, "com.example.Holder")
+	g.SetClassName(2000, "byte[]")
+	g.SetObjectInfo(1, 1000, 16)
+	g.AddGCRoot(&GCRoot{ObjectID: 1, Type: GCRootJavaFrame})
+	for id := uint64(2); id <= 10; id++ {
+		g.SetObjectInfo(id, 2000, int64(id*8))
+		g.AddReference(ObjectReference{FromObjectID: 1, ToObjectID: id, FromClassID: 1000, FieldName: "data"})
+	}
+	g.ComputeDominatorTree()
+	return g
+}
+
+func TestSerializeChunked_RoundTrip(t *testing.T) {
+	g := newChunkedTestGraph(t)
+	opts := DefaultSerializeOptions()
+	opts.ChunkEntries = 4
+
+	data, stats, err := g.Serialize(opts)
+	if err != nil {
+		t.Fatalf("Serialize failed: %v", err)
+	}
+	if stats.Objects != 10 || stats.References != 9 {
+		t.Errorf("unexpected stats: %+v", stats)
+	}
+
+	info, err := ReadRefGraphFileInfo(data)
+	if err != nil {
+		t.Fatalf("ReadRefGraphFileInfo failed: %v", err)
+	}
+	if !info.Has(FeatureChunked) || !info.Has(FeatureDominators) {
+		t.Errorf("unexpected features: 0x%x", uint64(info.Features))
+	}
+
+	loaded, err := DeserializeReferenceGraph(data)
+	if err != nil {
+		t.Fatalf("Deserialize failed: %v", err)
+	}
+	if len(loaded.objectClass) != 10 {
+		t.Errorf("expected 10 objects, got %d", len(loaded.objectClass))
+	}
+	if refs := loaded.outgoingRefs[1]; len(refs) != 9 || refs[0].FieldName != "data" {
+		t.Errorf("unexpected references: %d", len(refs))
+	}
+	if got, want := loaded.GetRetainedSize(1), g.GetRetainedSize(1); got != want {
+		t.Errorf("retained size of root = %d, want %d", got, want)
+	}
+	if !loaded.IsObjectReachable(10) {
+		t.Error("object 10 should be reachable after reload")
+	}
+}
+
+func TestChunkedGraphReader_PartialLoad(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "refgraph.bin")
+	opts := DefaultSerializeOptions()
+	opts.ChunkEntries = 4
+	if _, err := newChunkedTestGraph(t).SerializeToFile(filename, opts); err != nil {
+		t.Fatalf("SerializeToFile failed: %v", err)
+	}
+
+	reader, err := OpenChunkedReferenceGraph(filename)
+	if err != nil {
+		t.Fatalf("OpenChunkedReferenceGraph failed: %v", err)
+	}
+	defer reader.Close()
+
+	if reader.Metadata().GetTotalObjects() != 10 {
+		t.Errorf("manifest TotalObjects = %d", reader.Metadata().GetTotalObjects())
+	}
+	objectParts := 0
+	for _, c := range reader.Chunks() {
+		if c.Kind == ChunkObjects {
+			objectParts++
+		}
+	}
+	if objectParts != 3 {
+		t.Errorf("expected 10 objects split into 3 chunks, got %d", objectParts)
+	}
+
+	// Fast startup: class table and retained sizes only
+	g, err := reader.Load(ChunkClassTable, ChunkRetainedSizes)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if len(g.objectClass) != 0 || len(g.outgoingRefs) != 0 {
+		t.Error("objects and edges should not be loaded yet")
+	}
+	if g.GetRetainedSize(1) == 0 {
+		t.Error("retained sizes should be available")
+	}
+	if g.GetClassRetainedSize("byte[]") == 0 {
+		t.Error("class retained sizes should be available")
+	}
+	if reader.Loaded(ChunkEdges) {
+		t.Error("edges reported as loaded")
+	}
+
+	// Resume: edges pull in the field name table
+	if _, err := reader.Load(ChunkObjects, ChunkEdges); err != nil {
+		t.Fatalf("resumed Load failed: %v", err)
+	}
+	if !reader.Loaded(ChunkFieldNames) || !reader.Loaded(ChunkEdges) {
+		t.Error("edges and field names should be loaded")
+	}
+	if refs := g.outgoingRefs[1]; len(refs) != 9 || refs[0].FieldName != "data" {
+		t.Errorf("unexpected references after resume: %d", len(refs))
+	}
+	if len(g.objectClass) != 10 {
+		t.Errorf("expected 10 objects, got %d", len(g.objectClass))
+	}
+}
+
+func TestChunkedGraphReader_Corruption(t *testing.T) {
+	data, _, err := newChunkedTestGraph(t).Serialize(DefaultSerializeOptions())
+	if err != nil {
+		t.Fatalf("Serialize failed: %v", err)
+	}
+
+	reader, err := NewChunkedGraphReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatalf("NewChunkedGraphReader failed: %v", err)
+	}
+	var edges ChunkInfo
+	for _, c := range reader.Chunks() {
+		if c.Kind == ChunkEdges {
+			edges = c
+		}
+	}
+
+	corrupt := append([]byte{}, data...)
+	corrupt[edges.Offset] ^= 0xff
+	reader, err = NewChunkedGraphReader(bytes.NewReader(corrupt), int64(len(corrupt)))
+	if err != nil {
+		t.Fatalf("NewChunkedGraphReader failed: %v", err)
+	}
+
+	// Chunks other than the damaged one still load
+	if _, err := reader.Load(ChunkClassTable, ChunkObjects); err != nil {
+		t.Errorf("undamaged chunks should load: %v", err)
+	}
+	if _, err := reader.Load(ChunkEdges); err == nil || !strings.Contains(err.Error(), "checksum") {
+		t.Errorf("expected checksum error, got %v", err)
+	}
+}
+
+func TestChunkedGraphReader_RejectsMonolithic(t *testing.T) {
+	opts := DefaultSerializeOptions()
+	opts.Chunked = false
+	data, _, err := newChunkedTestGraph(t).Serialize(opts)
+	if err != nil {
+		t.Fatalf("Serialize failed: %v", err)
+	}
+	if _, err := NewChunkedGraphReader(bytes.NewReader(data), int64(len(data))); err == nil {
+		t.Error("expected error for non-chunked file")
+	}
+
+	opts = LegacySerializeOptions()
+	opts.Chunked = true
+	if _, _, err := newChunkedTestGraph(t).Serialize(opts); err == nil {
+		t.Error("expected error for chunked v2 file")
+	}
+}
@@ -0,0 +1,258 @@
+// Package hprof provides parsing functionality for Java HPROF heap dump files.
+package hprof
+
+import (
+	"encoding/binary"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	pb "github.com/perf-analysis/internal/parser/hprof/proto"
+	"google.golang.org/protobuf/proto"
+)
+
+// File layout of refgraph.bin:
+//
+//	v1: Magic(4) | Version(1) | StringTableLen(4) | StringTable | gzip(payload)
+//	v2: Magic(4) | Version(1) | Compression(1) | StringTableLen(4) | StringTable | payload
+//	v3+: Magic(4) | Version(1) | Compression(1) | EnvelopeLen(4) | Envelope |
+//	     StringTableLen(4) | StringTable | payload
+//
+// v3 files with FeatureChunked replace the string table and payload with
+// independently compressed chunks (see serial_chunked.go).
+//
+// From v3 on the envelope position is fixed, so readers can always identify
+// the writer of a newer file even when they cannot decode its payload.
+
+const (
+	// envelopeFormatVersion is the first format version carrying a RefGraphEnvelope.
+	envelopeFormatVersion = 3
+
+	// MinReaderVersion is the oldest reader format version able to load files
+	// written by this build.
+	MinReaderVersion = 3
+)
+
+// AnalyzerVersion identifies the analyzer build that writes refgraph.bin files.
+// It is injected at build time via -ldflags.
+var AnalyzerVersion = "dev"
+
+// RefGraphFeature is a bit flag describing optional payload features.
+type RefGraphFeature uint64
+
+const (
+	// FeatureDominators indicates precomputed dominator data is included.
+	FeatureDominators = RefGraphFeature(pb.RefGraphFeature_REF_GRAPH_FEATURE_DOMINATORS)
+	// FeatureFieldNameTable indicates field names are stored in a string table.
+	FeatureFieldNameTable = RefGraphFeature(pb.RefGraphFeature_REF_GRAPH_FEATURE_FIELD_NAME_TABLE)
+	// FeatureZstd indicates the payload is zstd compressed.
+	FeatureZstd = RefGraphFeature(pb.RefGraphFeature_REF_GRAPH_FEATURE_ZSTD)
+	// FeatureChunked indicates the payload is split into chunks listed in a manifest.
+	FeatureChunked = RefGraphFeature(pb.RefGraphFeature_REF_GRAPH_FEATURE_CHUNKED)
+
+	// knownFeatures is the set of features this build can decode.
+	knownFeatures = FeatureDominators | FeatureFieldNameTable | FeatureZstd | FeatureChunked
+)
+
+// RefGraphFileInfo describes the header of a serialized reference graph.
+type RefGraphFileInfo struct {
+	FormatVersion    uint32
+	MinReaderVersion uint32
+	Features         RefGraphFeature
+	WriterVersion    string // empty for files written before the envelope existed
+	CreatedAt        time.Time
+	Compression      CompressionType
+	// Legacy is true for pre-envelope files (v1/v2) loaded through the migration path.
+	Legacy bool
+
+	// stringTableOffset is the offset of the string table length field
+	// (the first chunk for chunked files).
+	stringTableOffset int
+}
+
+// Has reports whether the file has the given feature.
+func (fi *RefGraphFileInfo) Has(f RefGraphFeature) bool {
+	return fi.Features&f != 0
+}
+
+// Writer returns a human-readable description of the analyzer that wrote the file.
+func (fi *RefGraphFileInfo) Writer() string {
+	if fi.WriterVersion != "" {
+		return fmt.Sprintf("analyzer %s (format v%d)", fi.WriterVersion, fi.FormatVersion)
+	}
+	if fi.Legacy {
+		return fmt.Sprintf("pre-envelope analyzer (format v%d)", fi.FormatVersion)
+	}
+	return fmt.Sprintf("unknown analyzer (format v%d)", fi.FormatVersion)
+}
+
+// IncompatibleFormatError is returned when a refgraph.bin file cannot be loaded
+// by this build. It identifies which analyzer version produced the file.
+type IncompatibleFormatError struct {
+	Info            *RefGraphFileInfo
+	UnknownFeatures RefGraphFeature
+	Reason          string
+}
+
+// Error implements the error interface.
+func (e *IncompatibleFormatError) Error() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "incompatible reference graph file written by %s", e.Info.Writer())
+	if e.Reason != "" {
+		sb.WriteString(": ")
+		sb.WriteString(e.Reason)
+	}
+	fmt.Fprintf(&sb, " (this build: analyzer %s, reads formats v1-v%d)", AnalyzerVersion, SerializerVersion)
+	return sb.String()
+}
+
+// buildEnvelope creates the envelope for a file written with the given options.
+func buildEnvelope(opts SerializeOptions, includesDominators bool, createdAt time.Time) *pb.RefGraphEnvelope {
+	features := FeatureFieldNameTable
+	if includesDominators {
+		features |= FeatureDominators
+	}
+	if opts.Compression == CompressionZstd {
+		features |= FeatureZstd
+	}
+	return &pb.RefGraphEnvelope{
+		FormatVersion:    SerializerVersion,
+		MinReaderVersion: MinReaderVersion,
+		Features:         uint64(features),
+		WriterVersion:    AnalyzerVersion,
+		CreatedAt:        createdAt.UnixMilli(),
+	}
+}
+
+// ReadRefGraphFileInfo parses the header of serialized reference graph data.
+// Pre-envelope files (v1/v2) are migrated to an equivalent RefGraphFileInfo.
+// Files this build cannot load yield an *IncompatibleFormatError.
+func ReadRefGraphFileInfo(data []byte) (*RefGraphFileInfo, error) {
+	if len(data) < 10 { // Magic(4) + Version(1) + CompressionType(1) + StringTableLen(4)
+		return nil, fmt.Errorf("data too short")
+	}
+
+	// Verify magic bytes
+	if string(data[:4]) != MagicBytes {
+		return nil, fmt.Errorf("invalid magic bytes: expected %q, got %q", MagicBytes, string(data[:4]))
+	}
+
+	version := uint32(data[4])
+	if version < envelopeFormatVersion {
+		return migrateLegacyHeader(data, version)
+	}
+
+	info := &RefGraphFileInfo{
+		FormatVersion: version,
+		Compression:   CompressionType(data[5]),
+	}
+
+	// The envelope layout is stable across versions, so decode it before
+	// deciding whether the payload is readable.
+	envLen := int(binary.BigEndian.Uint32(data[6:10]))
+	if 10+envLen > len(data) {
+		return nil, &IncompatibleFormatError{Info: info, Reason: "truncated envelope"}
+	}
+	var env pb.RefGraphEnvelope
+	if err := proto.Unmarshal(data[10:10+envLen], &env); err != nil {
+		return nil, &IncompatibleFormatError{Info: info, Reason: fmt.Sprintf("invalid envelope: %v", err)}
+	}
+	info.MinReaderVersion = env.MinReaderVersion
+	info.Features = RefGraphFeature(env.Features)
+	info.WriterVersion = env.WriterVersion
+	if env.CreatedAt > 0 {
+		info.CreatedAt = time.UnixMilli(env.CreatedAt)
+	}
+	info.stringTableOffset = 10 + envLen
+
+	if info.MinReaderVersion > SerializerVersion {
+		return nil, &IncompatibleFormatError{
+			Info:   info,
+			Reason: fmt.Sprintf("requires reader format v%d or newer", info.MinReaderVersion),
+		}
+	}
+	if unknown := info.Features &^ knownFeatures; unknown != 0 {
+		return nil, &IncompatibleFormatError{
+			Info:            info,
+			UnknownFeatures: unknown,
+			Reason:          fmt.Sprintf("uses unsupported features 0x%x", uint64(unknown)),
+		}
+	}
+	if len(data) < info.stringTableOffset+4 {
+		return nil, fmt.Errorf("data too short")
+	}
+
+	return info, nil
+}
+
+// migrateLegacyHeader builds file info for files written before the envelope existed.
+// Version 1 has no compression byte and is always gzip; version 2 adds the compression byte.
+func migrateLegacyHeader(data []byte, version uint32) (*RefGraphFileInfo, error) {
+	info := &RefGraphFileInfo{
+		FormatVersion:    version,
+		MinReaderVersion: version,
+		Features:         FeatureFieldNameTable,
+		Legacy:           true,
+	}
+
+	switch version {
+	case 1:
+		info.Compression = CompressionGzip
+		info.stringTableOffset = 5
+	case 2:
+		info.Compression = CompressionType(data[5])
+		info.stringTableOffset = 6
+	default:
+		return nil, &IncompatibleFormatError{Info: info, Reason: "unknown format version"}
+	}
+
+	if info.Compression == CompressionZstd {
+		info.Features |= FeatureZstd
+	}
+	return info, nil
+}
+
+// ReadRefGraphFileInfoFromFile reads the header of a refgraph.bin file.
+func ReadRefGraphFileInfoFromFile(filename string) (*RefGraphFileInfo, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file: %w", err)
+	}
+	return ReadRefGraphFileInfo(data)
+}
+
+// MigrateReferenceGraphFile rewrites a refgraph.bin file in the current format.
+// It returns the info of the original file; files already in the current
+// format are left untouched.
+func MigrateReferenceGraphFile(filename string, opts SerializeOptions) (*RefGraphFileInfo, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file: %w", err)
+	}
+
+	info, err := ReadRefGraphFileInfo(data)
+	if err != nil {
+		return nil, err
+	}
+	if info.FormatVersion == SerializerVersion {
+		return info, nil
+	}
+
+	g, err := DeserializeReferenceGraph(data)
+	if err != nil {
+		return info, err
+	}
+
+	// Write to a temp file first so a failed migration never destroys the original
+	tmpFile := filename + ".migrating"
+	if _, err := g.SerializeToFile(tmpFile, opts); err != nil {
+		os.Remove(tmpFile)
+		return info, err
+	}
+	if err := os.Rename(tmpFile, filename); err != nil {
+		os.Remove(tmpFile)
+		return info, fmt.Errorf("failed to replace file: %w", err)
+	}
+	return info, nil
+}
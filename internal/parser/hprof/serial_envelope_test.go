@@ -0,0 +1,177 @@
+package hprof
+
+import (
+	"encoding/binary"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	pb "github.com/perf-analysis/internal/parser/hprof/proto"
+	"google.golang.org/protobuf/proto"
+)
+
+// newEnvelopeTestGraph creates a tiny graph for format tests.
+func newEnvelopeTestGraph() *ReferenceGraph {
+	g := NewReferenceGraphWithCapacity(4)
+	g.SetClassName(1000, "com.example.Holder")
+	g.SetClassName(2000, "byte[]")
+	g.SetObjectInfo(1, 1000, 16)
+	g.SetObjectInfo(2, 2000, 64)
+	g.AddReference(ObjectReference{FromObjectID: 1, ToObjectID: 2, FromClassID: 1000, FieldName: "data"})
+	g.AddGCRoot(&GCRoot{ObjectID: 1, Type: GCRootJavaFrame})
+	return g
+}
+
+// withEnvelope re-encodes a current-format file with a modified envelope and version byte.
+func withEnvelope(t *testing.T, data []byte, version byte, modify func(env *pb.RefGraphEnvelope)) []byte {
+	t.Helper()
+	envLen := int(binary.BigEndian.Uint32(data[6:10]))
+	var env pb.RefGraphEnvelope
+	if err := proto.Unmarshal(data[10:10+envLen], &env); err != nil {
+		t.Fatalf("Unmarshal envelope failed: %v", err)
+	}
+	modify(&env)
+	envBytes, err := proto.Marshal(&env)
+	if err != nil {
+		t.Fatalf("Marshal envelope failed: %v", err)
+	}
+
+	out := append([]byte{}, data[:6]...)
+	out[4] = version
+	out = binary.BigEndian.AppendUint32(out, uint32(len(envBytes)))
+	out = append(out, envBytes...)
+	return append(out, data[10+envLen:]...)
+}
+
+func TestRefGraphFileInfo_CurrentFormat(t *testing.T) {
+	data, _, err := newEnvelopeTestGraph().Serialize(DefaultSerializeOptions())
+	if err != nil {
+		t.Fatalf("Serialize failed: %v", err)
+	}
+
+	info, err := ReadRefGraphFileInfo(data)
+	if err != nil {
+		t.Fatalf("ReadRefGraphFileInfo failed: %v", err)
+	}
+	if info.FormatVersion != SerializerVersion || info.Legacy {
+		t.Errorf("unexpected version info: %+v", info)
+	}
+	if info.WriterVersion != AnalyzerVersion {
+		t.Errorf("WriterVersion = %q, want %q", info.WriterVersion, AnalyzerVersion)
+	}
+	if !info.Has(FeatureZstd) || !info.Has(FeatureFieldNameTable) || info.Has(FeatureDominators) {
+		t.Errorf("unexpected features: 0x%x", uint64(info.Features))
+	}
+
+	g, err := DeserializeReferenceGraph(data)
+	if err != nil {
+		t.Fatalf("Deserialize failed: %v", err)
+	}
+	if len(g.objectClass) != 2 {
+		t.Errorf("expected 2 objects, got %d", len(g.objectClass))
+	}
+}
+
+func TestRefGraphFileInfo_LegacyFormats(t *testing.T) {
+	v2, _, err := newEnvelopeTestGraph().Serialize(LegacySerializeOptions())
+	if err != nil {
+		t.Fatalf("Serialize failed: %v", err)
+	}
+	if v2[4] != 2 {
+		t.Fatalf("legacy options should write format v2, got v%d", v2[4])
+	}
+
+	// Version 1 has no compression byte and is always gzip
+	v1 := append([]byte{}, v2[:4]...)
+	v1 = append(v1, 1)
+	v1 = append(v1, v2[6:]...)
+
+	for name, data := range map[string][]byte{"v1": v1, "v2": v2} {
+		t.Run(name, func(t *testing.T) {
+			info, err := ReadRefGraphFileInfo(data)
+			if err != nil {
+				t.Fatalf("ReadRefGraphFileInfo failed: %v", err)
+			}
+			if !info.Legacy || info.WriterVersion != "" {
+				t.Errorf("expected migrated legacy info, got %+v", info)
+			}
+			if !strings.Contains(info.Writer(), "pre-envelope") {
+				t.Errorf("Writer() = %q", info.Writer())
+			}
+
+			g, err := DeserializeReferenceGraph(data)
+			if err != nil {
+				t.Fatalf("Deserialize failed: %v", err)
+			}
+			if refs := g.outgoingRefs[1]; len(refs) != 1 || refs[0].FieldName != "data" {
+				t.Errorf("unexpected references: %+v", refs)
+			}
+		})
+	}
+}
+
+func TestRefGraphFileInfo_Incompatible(t *testing.T) {
+	data, _, err := newEnvelopeTestGraph().Serialize(DefaultSerializeOptions())
+	if err != nil {
+		t.Fatalf("Serialize failed: %v", err)
+	}
+
+	t.Run("newer reader required", func(t *testing.T) {
+		newer := withEnvelope(t, data, SerializerVersion+1, func(env *pb.RefGraphEnvelope) {
+			env.FormatVersion = SerializerVersion + 1
+			env.MinReaderVersion = SerializerVersion + 1
+			env.WriterVersion = "9.9.9"
+		})
+
+		_, err := DeserializeReferenceGraph(newer)
+		var incompatible *IncompatibleFormatError
+		if !errors.As(err, &incompatible) {
+			t.Fatalf("expected IncompatibleFormatError, got %v", err)
+		}
+		if incompatible.Info.WriterVersion != "9.9.9" {
+			t.Errorf("WriterVersion = %q", incompatible.Info.WriterVersion)
+		}
+		if !strings.Contains(err.Error(), "analyzer 9.9.9") {
+			t.Errorf("error should name the writer: %v", err)
+		}
+	})
+
+	t.Run("unknown feature", func(t *testing.T) {
+		unknown := withEnvelope(t, data, SerializerVersion, func(env *pb.RefGraphEnvelope) {
+			env.Features |= 1 << 40
+		})
+
+		_, err := DeserializeReferenceGraph(unknown)
+		var incompatible *IncompatibleFormatError
+		if !errors.As(err, &incompatible) {
+			t.Fatalf("expected IncompatibleFormatError, got %v", err)
+		}
+		if incompatible.UnknownFeatures != 1<<40 {
+			t.Errorf("UnknownFeatures = 0x%x", uint64(incompatible.UnknownFeatures))
+		}
+	})
+}
+
+func TestMigrateReferenceGraphFile(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "refgraph.bin")
+	if _, err := newEnvelopeTestGraph().SerializeToFile(filename, LegacySerializeOptions()); err != nil {
+		t.Fatalf("SerializeToFile failed: %v", err)
+	}
+
+	before, err := MigrateReferenceGraphFile(filename, DefaultSerializeOptions())
+	if err != nil {
+		t.Fatalf("MigrateReferenceGraphFile failed: %v", err)
+	}
+	if before.FormatVersion != 2 {
+		t.Errorf("original FormatVersion = %d, want 2", before.FormatVersion)
+	}
+
+	after, err := ReadRefGraphFileInfoFromFile(filename)
+	if err != nil {
+		t.Fatalf("ReadRefGraphFileInfoFromFile failed: %v", err)
+	}
+	if after.FormatVersion != SerializerVersion || after.Legacy {
+		t.Errorf("file was not migrated: %+v", after)
+	}
+}
@@ -3,6 +3,8 @@ package hprof
 
 import (
 	"bytes"
+	"context"
+	"encoding/binary"
 	"fmt"
 	"os"
 	"time"
@@ -14,7 +16,8 @@ import (
 const (
 	// SerializerVersion is the current serialization format version
 	// Version 2: Added support for zstd compression
-	SerializerVersion = 2
+	// Version 3: Added RefGraphEnvelope (writer version, feature flags)
+	SerializerVersion = 3
 	
 	// Magic bytes for file format identification
 	MagicBytes = "REFG"
@@ -33,6 +36,20 @@ type SerializeOptions struct {
 	
 	// SourceFile is the original hprof file name (for metadata)
 	SourceFile string
+
+	// FormatVersion selects the file format to write (0 = SerializerVersion).
+	// Version 2 omits the envelope so that older readers can load the file.
+	FormatVersion uint32
+
+	// Chunked splits the payload into independently compressed chunks with a
+	// manifest, so loaders can read only the chunks they need.
+	Chunked bool
+
+	// ChunkEntries caps the number of objects or edges per chunk (0 = DefaultChunkEntries).
+	ChunkEntries int
+
+	// Workers caps the workers compressing chunks (0 = up to 8 of the shared pool).
+	Workers int
 }
 
 // DefaultSerializeOptions returns default serialization options.
@@ -43,6 +60,7 @@ func DefaultSerializeOptions() SerializeOptions {
 		Compression:          CompressionZstd,
 		CompressionLevel:     CompressionDefault,
 		SourceFile:           "",
+		Chunked:              true,
 	}
 }
 
@@ -53,16 +71,18 @@ func FastSerializeOptions() SerializeOptions {
 		Compression:          CompressionZstd,
 		CompressionLevel:     CompressionFastest,
 		SourceFile:           "",
+		Chunked:              true,
 	}
 }
 
-// LegacySerializeOptions returns options compatible with older versions (gzip).
+// LegacySerializeOptions returns options compatible with older versions (gzip, format v2).
 func LegacySerializeOptions() SerializeOptions {
 	return SerializeOptions{
 		IncludeDominatorData: true,
 		Compression:          CompressionGzip,
 		CompressionLevel:     CompressionDefault,
 		SourceFile:           "",
+		FormatVersion:        2,
 	}
 }
 
@@ -82,8 +102,27 @@ type SerializationStats struct {
 // Serialize serializes the ReferenceGraph to a compressed protobuf format.
 // Returns the compressed bytes and serialization statistics.
 func (g *ReferenceGraph) Serialize(opts SerializeOptions) ([]byte, *SerializationStats, error) {
+	return g.SerializeContext(context.Background(), opts)
+}
+
+// SerializeContext is Serialize stopping with the error of ctx when ctx is done.
+func (g *ReferenceGraph) SerializeContext(ctx context.Context, opts SerializeOptions) ([]byte, *SerializationStats, error) {
 	startTime := time.Now()
 	stats := &SerializationStats{}
+
+	formatVersion := opts.FormatVersion
+	if formatVersion == 0 {
+		formatVersion = SerializerVersion
+	}
+	if formatVersion != 2 && formatVersion != SerializerVersion {
+		return nil, nil, fmt.Errorf("unsupported format version for writing: %d", formatVersion)
+	}
+	if opts.Chunked {
+		if formatVersion < envelopeFormatVersion {
+			return nil, nil, fmt.Errorf("chunked serialization requires format v%d or newer", envelopeFormatVersion)
+		}
+		return g.serializeChunked(ctx, opts, startTime)
+	}
 	
 	// Build string table for field name deduplication
 	fieldNameToIdx := make(map[string]uint32)
@@ -104,13 +143,16 @@ func (g *ReferenceGraph) Serialize(opts SerializeOptions) ([]byte, *Serializatio
 	
 	// Build protobuf message
 	pbGraph := &pb.ReferenceGraphProto{
-		Version: SerializerVersion,
+		Version: formatVersion,
 	}
 	
 	// 1. Serialize objects (objectClass + objectSize)
 	pbGraph.Objects = make([]*pb.ObjectInfoProto, 0, len(g.objectClass))
 	var totalHeapSize int64
 	for objID, classID := range g.objectClass {
+		if canceled(ctx, len(pbGraph.Objects)) {
+			return nil, nil, ctx.Err()
+		}
 		size := g.objectSize[objID]
 		totalHeapSize += size
 		pbGraph.Objects = append(pbGraph.Objects, &pb.ObjectInfoProto{
@@ -138,6 +180,9 @@ func (g *ReferenceGraph) Serialize(opts SerializeOptions) ([]byte, *Serializatio
 	}
 	pbGraph.References = make([]*pb.ObjectReferenceProto, 0, totalRefs)
 	for _, refs := range g.outgoingRefs {
+		if canceled(ctx, len(pbGraph.References)) {
+			return nil, nil, ctx.Err()
+		}
 		for _, ref := range refs {
 			pbGraph.References = append(pbGraph.References, &pb.ObjectReferenceProto{
 				FromObjectId: ref.FromObjectID,
@@ -162,8 +207,13 @@ func (g *ReferenceGraph) Serialize(opts SerializeOptions) ([]byte, *Serializatio
 	}
 	stats.GCRoots = int64(len(pbGraph.GcRoots))
 	
+	if err := ctx.Err(); err != nil {
+		return nil, nil, err
+	}
+
 	// 5. Serialize dominator data if requested and computed
-	if opts.IncludeDominatorData && g.dominatorComputed {
+	includesDominators := opts.IncludeDominatorData && g.dominatorComputed
+	if includesDominators {
 		domData := &pb.DominatorDataProto{
 			Computed: true,
 		}
@@ -219,10 +269,14 @@ func (g *ReferenceGraph) Serialize(opts SerializeOptions) ([]byte, *Serializatio
 		TotalReferences: stats.References,
 		TotalGcRoots:    stats.GCRoots,
 		TotalHeapSize:   totalHeapSize,
-		CreatedAt:       time.Now().UnixMilli(),
+		CreatedAt:       startTime.UnixMilli(),
 		SourceFile:      opts.SourceFile,
 	}
 	
+	if err := ctx.Err(); err != nil {
+		return nil, nil, err
+	}
+
 	// Marshal to protobuf bytes
 	rawBytes, err := proto.Marshal(pbGraph)
 	if err != nil {
@@ -237,11 +291,23 @@ func (g *ReferenceGraph) Serialize(opts SerializeOptions) ([]byte, *Serializatio
 	buf.WriteString(MagicBytes)
 	
 	// Write version
-	buf.WriteByte(byte(SerializerVersion))
+	buf.WriteByte(byte(formatVersion))
 	
 	// Write compression type (1 byte)
 	buf.WriteByte(byte(opts.Compression))
 	
+	// Write envelope (length-prefixed, uncompressed) for v3+
+	if formatVersion >= envelopeFormatVersion {
+		envelopeBytes, err := proto.Marshal(buildEnvelope(opts, includesDominators, startTime))
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to marshal envelope: %w", err)
+		}
+		var envLen [4]byte
+		binary.BigEndian.PutUint32(envLen[:], uint32(len(envelopeBytes)))
+		buf.Write(envLen[:])
+		buf.Write(envelopeBytes)
+	}
+	
 	// Write string table (for field names)
 	stringTableProto := &pb.StringTable{Strings: fieldNames}
 	stringTableBytes, err := proto.Marshal(stringTableProto)
@@ -257,6 +323,10 @@ func (g *ReferenceGraph) Serialize(opts SerializeOptions) ([]byte, *Serializatio
 	buf.WriteByte(byte(stLen))
 	buf.Write(stringTableBytes)
 	
+	if err := ctx.Err(); err != nil {
+		return nil, nil, err
+	}
+
 	// Compress main data using the specified compressor
 	var compressor Compressor
 	switch opts.Compression {
@@ -287,7 +357,13 @@ func (g *ReferenceGraph) Serialize(opts SerializeOptions) ([]byte, *Serializatio
 
 // SerializeToFile serializes the ReferenceGraph to a file.
 func (g *ReferenceGraph) SerializeToFile(filename string, opts SerializeOptions) (*SerializationStats, error) {
-	data, stats, err := g.Serialize(opts)
+	return g.SerializeToFileContext(context.Background(), filename, opts)
+}
+
+// SerializeToFileContext is SerializeToFile stopping with the error of ctx,
+// without writing the file, when ctx is done.
+func (g *ReferenceGraph) SerializeToFileContext(ctx context.Context, filename string, opts SerializeOptions) (*SerializationStats, error) {
+	data, stats, err := g.SerializeContext(ctx, opts)
 	if err != nil {
 		return nil, err
 	}
@@ -300,34 +376,18 @@ func (g *ReferenceGraph) SerializeToFile(filename string, opts SerializeOptions)
 }
 
 // Deserialize deserializes a ReferenceGraph from compressed protobuf bytes.
-// Supports both version 1 (gzip only) and version 2 (gzip or zstd).
+// Supports version 1 (gzip only), version 2 (gzip or zstd) and version 3 (envelope).
+// Files written by a newer, incompatible analyzer yield an *IncompatibleFormatError.
 func DeserializeReferenceGraph(data []byte) (*ReferenceGraph, error) {
-	if len(data) < 10 { // Magic(4) + Version(1) + CompressionType(1) + StringTableLen(4)
-		return nil, fmt.Errorf("data too short")
-	}
-	
-	// Verify magic bytes
-	if string(data[:4]) != MagicBytes {
-		return nil, fmt.Errorf("invalid magic bytes: expected %q, got %q", MagicBytes, string(data[:4]))
+	info, err := ReadRefGraphFileInfo(data)
+	if err != nil {
+		return nil, err
 	}
-	
-	// Read version
-	version := data[4]
-	
-	var compressionType CompressionType
-	var headerOffset int
-	
-	if version == 1 {
-		// Version 1: no compression type byte, always gzip
-		compressionType = CompressionGzip
-		headerOffset = 5
-	} else if version == 2 {
-		// Version 2: has compression type byte
-		compressionType = CompressionType(data[5])
-		headerOffset = 6
-	} else {
-		return nil, fmt.Errorf("unsupported version: %d", version)
+	if info.Has(FeatureChunked) {
+		return deserializeChunked(data)
 	}
+	compressionType := info.Compression
+	headerOffset := info.stringTableOffset
 	
 	// Read string table length
 	stLen := uint32(data[headerOffset])<<24 | uint32(data[headerOffset+1])<<16 | 
@@ -349,7 +409,6 @@ func DeserializeReferenceGraph(data []byte) (*ReferenceGraph, error) {
 	compressedData := data[stringTableStart+int(stLen):]
 	
 	var rawBytes []byte
-	var err error
 	
 	switch compressionType {
 	case CompressionZstd:
@@ -377,10 +436,14 @@ func DeserializeReferenceGraph(data []byte) (*ReferenceGraph, error) {
 		return nil, fmt.Errorf("failed to unmarshal protobuf: %w", err)
 	}
 	
-	// Build ReferenceGraph
-	estimatedObjects := len(pbGraph.Objects)
-	g := NewReferenceGraphWithCapacity(estimatedObjects)
-	
+	g := NewReferenceGraphWithCapacity(len(pbGraph.Objects))
+	restoreGraphProto(g, &pbGraph, fieldNames)
+	return g, nil
+}
+
+// restoreGraphProto adds the contents of a (possibly partial) graph message to g.
+// fieldNames resolves reference field name indexes.
+func restoreGraphProto(g *ReferenceGraph, pbGraph *pb.ReferenceGraphProto, fieldNames []string) {
 	// 1. Restore class names
 	for _, entry := range pbGraph.ClassNames {
 		g.classNames[entry.ClassId] = entry.ClassName
@@ -422,8 +485,10 @@ func DeserializeReferenceGraph(data []byte) (*ReferenceGraph, error) {
 		domData := pbGraph.DominatorData
 		g.dominatorComputed = true
 		
+		// Objects with a dominator are reachable
 		for _, entry := range domData.Dominators {
 			g.dominators[entry.ObjectId] = entry.DominatorId
+			g.reachableObjects[entry.ObjectId] = true
 		}
 		
 		for _, entry := range domData.RetainedSizes {
@@ -441,19 +506,17 @@ func DeserializeReferenceGraph(data []byte) (*ReferenceGraph, error) {
 		for _, classObjID := range domData.ClassObjectIds {
 			g.classObjectIDs[classObjID] = true
 		}
-		
-		// Rebuild reachable objects from dominators
-		g.reachableObjects = make(map[uint64]bool, len(g.dominators))
-		for objID := range g.dominators {
-			g.reachableObjects[objID] = true
-		}
 	}
-	
-	return g, nil
 }
 
 // DeserializeFromFile deserializes a ReferenceGraph from a file.
+// Chunked files are read chunk by chunk instead of being loaded into memory at once.
 func DeserializeReferenceGraphFromFile(filename string) (*ReferenceGraph, error) {
+	if reader, err := OpenChunkedReferenceGraph(filename); err == nil {
+		defer reader.Close()
+		return reader.Load()
+	}
+
 	data, err := os.ReadFile(filename)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read file: %w", err)
@@ -128,6 +128,10 @@ type ClassStats struct {
 	Percentage    float64 `json:"percentage"`
 	ShallowSize   int64   `json:"shallow_size"`
 	RetainedSize  int64   `json:"retained_size,omitempty"`
+	// ClassLoaderID is the loader defining the class, 0 for the bootstrap loader
+	ClassLoaderID uint64 `json:"class_loader_id,omitempty"`
+	// ClassLoader is the class name of the defining loader
+	ClassLoader string `json:"class_loader,omitempty"`
 }
 
 // HeapAnalysisResult holds the complete analysis result.
@@ -135,23 +139,53 @@ type HeapAnalysisResult struct {
 	Header           *Header                       `json:"header"`
 	Summary          *HeapSummary                  `json:"summary"`
 	TopClasses       []*ClassStats                 `json:"top_classes"`
+	// AllClasses holds every class sorted like TopClasses, which is its first TopClassesN entries
+	AllClasses []*ClassStats `json:"-"`
 	TotalClasses     int                           `json:"total_classes"`
 	TotalInstances   int64                         `json:"total_instances"`
 	TotalHeapSize    int64                         `json:"total_heap_size"`
+	// JVMLayout is the object layout shallow sizes were computed with
+	JVMLayout *JVMLayout `json:"jvm_layout,omitempty"`
 	LargestObjects   []*ObjectInfo                 `json:"largest_objects,omitempty"`
 	BiggestObjects   []*BiggestObject              `json:"biggest_objects,omitempty"`
 	GCRootsAnalysis  *GCRootsAnalysis              `json:"gc_roots_analysis,omitempty"`
 	StringStats      *StringStats                  `json:"string_stats,omitempty"`
+	// ClassLoaders groups the classes defined by class loaders by loader class
+	ClassLoaders []*ClassLoaderStats `json:"class_loaders,omitempty"`
+	// WeakReachability sizes the objects only reachable through soft or weak references
+	WeakReachability *WeakReachability `json:"weak_reachability,omitempty"`
+	// RetentionMotifs holds recurring retention patterns such as growing listener lists
+	RetentionMotifs *RetentionMotifs `json:"retention_motifs,omitempty"`
+	// FrameworkStats holds the HTTP sessions, caches and ORM sessions of common frameworks
+	FrameworkStats *FrameworkStats `json:"framework_stats,omitempty"`
+	// HumongousObjects holds the objects G1 allocates in regions of their own
+	HumongousObjects *HumongousObjects `json:"humongous_objects,omitempty"`
+	// ObjectCycles holds the largest reference cycles of the heap
+	ObjectCycles *ObjectCycles `json:"object_cycles,omitempty"`
+	// MemoryOwnership attributes the heap to the teams owning its packages
+	MemoryOwnership *MemoryOwnership `json:"memory_ownership,omitempty"`
 	ArrayStats       *ArrayStats                   `json:"array_stats,omitempty"`
+	// ArrayLengthHistograms holds per-array-class length distributions
+	ArrayLengthHistograms []*ArrayLengthHistogram `json:"array_length_histograms,omitempty"`
 	ClassRetainers   map[string]*ClassRetainers    `json:"class_retainers,omitempty"`
 	ReferenceGraphs  map[string]*ReferenceGraphData `json:"reference_graphs,omitempty"`
 	BusinessRetainers map[string][]*BusinessRetainer `json:"business_retainers,omitempty"`
+	// DominatorTree holds the top of the dominator tree, flattened depth-first
+	DominatorTree []*DominatorTreeNode `json:"dominator_tree,omitempty"`
+	// AllocationSites holds the allocation site table of the legacy hprof agent
+	AllocationSites *AllocationSites `json:"allocation_sites,omitempty"`
+	// CPUSamples holds the CPU sampling profile of the legacy hprof agent
+	CPUSamples *CPUSamples `json:"cpu_samples,omitempty"`
+	// Threads holds the thread overview with stacks and stack locals (written to threads.json)
+	Threads *ThreadOverview `json:"-"`
 	// ClassLayouts holds field layout information for classes (used by BiggestObjectsBuilder)
 	ClassLayouts     map[uint64]*ClassFieldLayout  `json:"-"`
 	// Strings holds string table (used by BiggestObjectsBuilder)
 	Strings          map[uint64]string             `json:"-"`
 	// RefGraph holds the reference graph for advanced analysis (not serialized to JSON)
 	RefGraph         *ReferenceGraph               `json:"-"`
+	// ObjectIndex maps object IDs to their records in the heap dump (written to objindex.bin)
+	ObjectIndex *ObjectIndex `json:"-"`
 }
 
 // GCRootsAnalysis holds GC roots analysis data for persistence.
@@ -199,6 +233,37 @@ type StringStats struct {
 	DuplicateWaste   int64   `json:"duplicate_waste"`
 	AvgLength        float64 `json:"avg_length"`
 	MaxLength        int     `json:"max_length"`
+	// TopDuplicates are the values wasting the most bytes in copies
+	TopDuplicates []*DuplicateString `json:"top_duplicates,omitempty"`
+	// InternCandidates are the fields whose values interning would save the most bytes
+	InternCandidates []*StringInternCandidate `json:"intern_candidates,omitempty"`
+	// Truncated is set when only the first Strings were read
+	Truncated bool `json:"truncated,omitempty"`
+}
+
+// DuplicateString is a value held by several java.lang.String objects.
+type DuplicateString struct {
+	Value string `json:"value"` // cut after StringPreviewLength characters
+	Count int64  `json:"count"`
+	Waste int64  `json:"waste"` // bytes of the copies but one
+}
+
+// StringInternCandidate is a field referencing many copies of few String
+// values, such as the enum-like codes of domain objects. Interning or
+// deduplicating the values of the field would save Savings bytes. A String
+// referenced by several fields is counted for each.
+type StringInternCandidate struct {
+	// ClassName is the class of the referencing objects; Field is "[]" for
+	// the elements of arrays
+	ClassName string `json:"class_name"`
+	Field     string `json:"field"`
+	Static    bool   `json:"static,omitempty"`
+	Count     int64  `json:"count"`    // Strings referenced
+	Distinct  int64  `json:"distinct"` // distinct values
+	Size      int64  `json:"size"`     // bytes of the Strings and their value arrays
+	Savings   int64  `json:"savings"`  // bytes of the copies but one of every value
+	// TopValues are the values saving the most bytes
+	TopValues []*DuplicateString `json:"top_values,omitempty"`
 }
 
 // ArrayStats holds array-related statistics.
@@ -210,27 +275,19 @@ type ArrayStats struct {
 	ByType           map[string]int64 `json:"by_type"`
 }
 
-// BiggestObject represents a large object with its field values.
+// BiggestObject represents a large object.
 type BiggestObject struct {
-	ObjectID     uint64         `json:"object_id"`
-	ClassName    string         `json:"class_name"`
-	ShallowSize  int64          `json:"shallow_size"`
-	RetainedSize int64          `json:"retained_size"`
-	Fields       []*ObjectField `json:"fields,omitempty"`
-	GCRootPath   *GCRootPath    `json:"gc_root_path,omitempty"`
-}
-
-// ObjectField represents a field value in an object.
-type ObjectField struct {
-	Name         string      `json:"name"`
-	Type         string      `json:"type"`
-	Value        interface{} `json:"value,omitempty"`
-	RefID        uint64      `json:"ref_id,omitempty"`
-	RefClass     string      `json:"ref_class,omitempty"`
-	ShallowSize  int64       `json:"shallow_size,omitempty"`
-	RetainedSize int64       `json:"retained_size,omitempty"`
-	HasChildren  bool        `json:"has_children,omitempty"`
-	IsStatic     bool        `json:"is_static,omitempty"`
+	ObjectID     uint64 `json:"object_id"`
+	ClassName    string `json:"class_name"`
+	ShallowSize  int64  `json:"shallow_size"`
+	RetainedSize int64  `json:"retained_size"`
+	// HasChildren reports whether the object references other objects, whose
+	// fields are loaded lazily from the heap snapshot
+	HasChildren bool        `json:"has_children,omitempty"`
+	GCRootPath  *GCRootPath `json:"gc_root_path,omitempty"`
+	// RolledUpObjects is the number of JDK objects attributed to this object
+	// when dominator rollup is enabled.
+	RolledUpObjects int64 `json:"rolled_up_objects,omitempty"`
 }
 
 // ObjectFieldDetail represents a field with detailed information for tree expansion.
@@ -245,6 +302,8 @@ type ObjectFieldDetail struct {
 	RetainedSize int64       `json:"retained_size,omitempty"`
 	HasChildren  bool        `json:"has_children"`
 	IsStatic     bool        `json:"is_static,omitempty"`
+	// StringValue previews the text of a referenced java.lang.String
+	StringValue *string `json:"string_value,omitempty"`
 }
 
 // ClassFieldLayout describes the field layout of a class for field value extraction.
@@ -253,6 +312,8 @@ type ClassFieldLayout struct {
 	ClassName     string
 	SuperClassID  uint64
 	InstanceSize  int
+	// ClassLoaderID is the class loader defining the class, 0 for the bootstrap loader
+	ClassLoaderID  uint64 `json:",omitempty"`
 	InstanceFields []FieldInfo
 	StaticFields   []StaticFieldInfo
 }
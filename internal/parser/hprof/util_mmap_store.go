@@ -244,6 +244,158 @@ func (a *MmapArray[T]) Sync() error {
 	return a.file.Sync()
 }
 
+// ============================================================================
+// MmapSlice - Fixed-length zero-copy memory-mapped slice
+// ============================================================================
+
+// MmapSlice is a fixed-length memory-mapped array exposed as a plain Go slice.
+// Unlike MmapArray it never grows, so the slice returned by Slice can be
+// indexed directly without locking or copying. The slice is only valid until
+// Close is called.
+type MmapSlice[T any] struct {
+	file   *os.File
+	data   []byte
+	elems  []T
+	closed bool
+}
+
+// NewMmapSlice creates a zero-initialized memory-mapped slice of length elements
+// backed by a temporary file in dir (os.TempDir() if empty).
+func NewMmapSlice[T any](dir, name string, length int) (*MmapSlice[T], error) {
+	var zero T
+	elemSize := int64(unsafe.Sizeof(zero))
+
+	file, err := os.CreateTemp(dir, name+"_*.mmap")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temp file: %w", err)
+	}
+
+	// mmap requires a non-empty mapping, so round up to at least one page
+	pageSize := int64(os.Getpagesize())
+	fileSize := int64(length) * elemSize
+	fileSize = ((fileSize + pageSize - 1) / pageSize) * pageSize
+	if fileSize < pageSize {
+		fileSize = pageSize
+	}
+
+	if err := file.Truncate(fileSize); err != nil {
+		file.Close()
+		os.Remove(file.Name())
+		return nil, fmt.Errorf("failed to truncate file: %w", err)
+	}
+
+	data, err := syscall.Mmap(int(file.Fd()), 0, int(fileSize),
+		syscall.PROT_READ|syscall.PROT_WRITE, syscall.MAP_SHARED)
+	if err != nil {
+		file.Close()
+		os.Remove(file.Name())
+		return nil, fmt.Errorf("failed to mmap: %w", err)
+	}
+
+	// Mappings are page aligned, which satisfies the alignment of any element type
+	return &MmapSlice[T]{
+		file:  file,
+		data:  data,
+		elems: unsafe.Slice((*T)(unsafe.Pointer(&data[0])), length),
+	}, nil
+}
+
+// Slice returns the mapped elements.
+func (s *MmapSlice[T]) Slice() []T {
+	return s.elems
+}
+
+// Len returns the number of elements.
+func (s *MmapSlice[T]) Len() int {
+	return len(s.elems)
+}
+
+// Bytes returns the size of the mapping in bytes.
+func (s *MmapSlice[T]) Bytes() int64 {
+	return int64(len(s.data))
+}
+
+// Close unmaps the slice and deletes the backing file.
+func (s *MmapSlice[T]) Close() error {
+	if s.closed {
+		return nil
+	}
+	s.closed = true
+	s.elems = nil
+
+	var errs []error
+	if err := syscall.Munmap(s.data); err != nil {
+		errs = append(errs, fmt.Errorf("munmap: %w", err))
+	}
+	s.data = nil
+
+	filename := s.file.Name()
+	if err := s.file.Close(); err != nil {
+		errs = append(errs, fmt.Errorf("close: %w", err))
+	}
+	if err := os.Remove(filename); err != nil {
+		errs = append(errs, fmt.Errorf("remove: %w", err))
+	}
+
+	if len(errs) > 0 {
+		return errs[0]
+	}
+	return nil
+}
+
+// mmapSpill allocates per-object arrays in memory-mapped files and releases
+// them together. A nil *mmapSpill allocates on the Go heap.
+type mmapSpill struct {
+	dir     string
+	closers []func() error
+	bytes   int64
+	err     error // first mapping failure; later arrays fall back to the heap
+}
+
+// newMmapSpill creates a spill allocator writing to dir.
+func newMmapSpill(dir string) *mmapSpill {
+	return &mmapSpill{dir: dir}
+}
+
+// spillSlice returns a zero-initialized slice of length n, memory-mapped when
+// sp is non-nil. If the mapping fails it falls back to a heap allocation.
+func spillSlice[T any](sp *mmapSpill, name string, n int) []T {
+	if sp == nil || sp.err != nil {
+		return make([]T, n)
+	}
+	m, err := NewMmapSlice[T](sp.dir, name, n)
+	if err != nil {
+		sp.err = err
+		return make([]T, n)
+	}
+	sp.closers = append(sp.closers, m.Close)
+	sp.bytes += m.Bytes()
+	return m.Slice()
+}
+
+// Bytes returns the total size of the mapped arrays.
+func (sp *mmapSpill) Bytes() int64 {
+	if sp == nil {
+		return 0
+	}
+	return sp.bytes
+}
+
+// Close unmaps all arrays; slices obtained from spillSlice become invalid.
+func (sp *mmapSpill) Close() error {
+	if sp == nil {
+		return nil
+	}
+	var firstErr error
+	for _, closeFn := range sp.closers {
+		if err := closeFn(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	sp.closers = nil
+	return firstErr
+}
+
 // ============================================================================
 // MmapObjectStore - Memory-mapped object storage
 // ============================================================================
@@ -0,0 +1,78 @@
+package hprof
+
+import (
+	"context"
+	"os"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMmapSlice_ZeroCopy(t *testing.T) {
+	dir := t.TempDir()
+	m, err := NewMmapSlice[int64](dir, "test", 1000)
+	require.NoError(t, err)
+
+	s := m.Slice()
+	require.Len(t, s, 1000)
+	assert.Zero(t, s[999])
+	s[42] = 7
+	assert.Equal(t, int64(7), m.Slice()[42])
+	assert.GreaterOrEqual(t, m.Bytes(), int64(8000))
+
+	require.NoError(t, m.Close())
+	require.NoError(t, m.Close())
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Empty(t, entries, "backing file should be removed on Close")
+}
+
+func TestSpillSlice_HeapFallback(t *testing.T) {
+	assert.Len(t, spillSlice[int32](nil, "heap", 10), 10)
+
+	sp := newMmapSpill("/nonexistent-spill-dir")
+	s := spillSlice[atomic.Int64](sp, "retained", 10)
+	require.Len(t, s, 10)
+	s[3].Add(5)
+	assert.Equal(t, int64(5), s[3].Load())
+	assert.Error(t, sp.err)
+	assert.Zero(t, sp.Bytes())
+	assert.NoError(t, sp.Close())
+}
+
+func TestComputeHierarchicalDominators_Spill(t *testing.T) {
+	expected := newRollupTestGraph()
+	expected.ComputeDominatorTree()
+
+	dir := t.TempDir()
+	config := DefaultHierarchicalDominatorConfig()
+	config.MmapConfig.TempDir = dir
+	config.MmapSpillThreshold = 1
+	assert.True(t, config.shouldSpill(6))
+
+	g := newRollupTestGraph()
+	ComputeHierarchicalDominators(context.Background(), g, config)
+
+	for _, objID := range []uint64{200, 300, 400, 500, 600} {
+		assert.Equal(t, expected.GetRetainedSize(objID), g.GetRetainedSize(objID), "retained size of %d", objID)
+		assert.Equal(t, expected.dominators[objID], g.dominators[objID], "dominator of %d", objID)
+	}
+
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Empty(t, entries, "spill files should be removed after computation")
+}
+
+func TestHierarchicalDominatorConfig_ShouldSpill(t *testing.T) {
+	config := DefaultHierarchicalDominatorConfig()
+	assert.False(t, config.shouldSpill(1000))
+	assert.True(t, config.shouldSpill(config.MmapSpillThreshold))
+
+	config.MmapSpillThreshold = 0
+	assert.False(t, config.shouldSpill(1<<30))
+
+	config.UseMmap = true
+	assert.True(t, config.shouldSpill(1))
+}
@@ -0,0 +1,208 @@
+package jfr
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// maxElementDepth bounds the nesting of the elements of metadata events;
+// the metadata of JDKs nest them 4 deep.
+const maxElementDepth = 32
+
+// class is a type declared in the metadata of a chunk: an event type, a
+// type of constants, e.g. jdk.types.StackTrace, or a primitive type.
+type class struct {
+	id     int64
+	name   string
+	fields []*field
+
+	// fieldIndex maps field names to their index in fields
+	fieldIndex map[string]int
+}
+
+// field is a field of a class.
+type field struct {
+	name  string
+	class *class
+
+	// constantPool fields hold the key of a constant of their class
+	constantPool bool
+	// array fields hold an array of values of their class
+	array bool
+}
+
+// field returns the index of the field of a class with the given name,
+// -1 if the class has none.
+func (c *class) field(name string) int {
+	if i, ok := c.fieldIndex[name]; ok {
+		return i
+	}
+	return -1
+}
+
+// metadata holds the classes declared by the metadata event of a chunk.
+type metadata struct {
+	classes map[int64]*class
+	byName  map[string]*class
+}
+
+// element is a node of the element tree of metadata events.
+type element struct {
+	name     string
+	attrs    map[string]string
+	children []*element
+}
+
+// readMetadata reads the metadata event at the current offset of r.
+func readMetadata(r *reader) (*metadata, error) {
+	// Event header: size, type, start time, duration and metadata ID
+	if _, err := r.int(); err != nil {
+		return nil, err
+	}
+	typeID, err := r.long()
+	if err != nil {
+		return nil, err
+	}
+	if typeID != eventMetadata {
+		return nil, fmt.Errorf("unexpected event type %d at metadata offset", typeID)
+	}
+	for i := 0; i < 3; i++ {
+		if _, err := r.long(); err != nil {
+			return nil, err
+		}
+	}
+
+	n, err := r.count()
+	if err != nil {
+		return nil, err
+	}
+	strings := make([]string, n)
+	for i := range strings {
+		v, err := r.string()
+		if err != nil {
+			return nil, err
+		}
+		s, ok := v.(string)
+		if !ok {
+			return nil, fmt.Errorf("constant string in metadata")
+		}
+		strings[i] = s
+	}
+
+	root, err := readElement(r, strings, 0)
+	if err != nil {
+		return nil, err
+	}
+	return buildMetadata(root)
+}
+
+// readElement reads an element and its children.
+func readElement(r *reader, strings []string, depth int) (*element, error) {
+	if depth > maxElementDepth {
+		return nil, fmt.Errorf("metadata nested deeper than %d elements", maxElementDepth)
+	}
+	str := func() (string, error) {
+		i, err := r.int()
+		if err != nil {
+			return "", err
+		}
+		if i < 0 || int(i) >= len(strings) {
+			return "", fmt.Errorf("invalid metadata string index %d", i)
+		}
+		return strings[i], nil
+	}
+
+	name, err := str()
+	if err != nil {
+		return nil, err
+	}
+	e := &element{name: name, attrs: make(map[string]string)}
+
+	n, err := r.count()
+	if err != nil {
+		return nil, err
+	}
+	for i := 0; i < n; i++ {
+		key, err := str()
+		if err != nil {
+			return nil, err
+		}
+		value, err := str()
+		if err != nil {
+			return nil, err
+		}
+		e.attrs[key] = value
+	}
+
+	n, err = r.count()
+	if err != nil {
+		return nil, err
+	}
+	for i := 0; i < n; i++ {
+		child, err := readElement(r, strings, depth+1)
+		if err != nil {
+			return nil, err
+		}
+		e.children = append(e.children, child)
+	}
+	return e, nil
+}
+
+// buildMetadata builds the classes declared by the class elements of the
+// metadata element of root.
+func buildMetadata(root *element) (*metadata, error) {
+	md := &metadata{
+		classes: make(map[int64]*class),
+		byName:  make(map[string]*class),
+	}
+
+	type fieldDecl struct {
+		field   *field
+		classID int64
+	}
+	var decls []fieldDecl
+
+	for _, e := range root.children {
+		if e.name != "metadata" {
+			continue
+		}
+		for _, ce := range e.children {
+			if ce.name != "class" {
+				continue
+			}
+			id, err := strconv.ParseInt(ce.attrs["id"], 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid class id %q", ce.attrs["id"])
+			}
+			c := &class{id: id, name: ce.attrs["name"], fieldIndex: make(map[string]int)}
+			for _, fe := range ce.children {
+				if fe.name != "field" {
+					continue
+				}
+				classID, err := strconv.ParseInt(fe.attrs["class"], 10, 64)
+				if err != nil {
+					return nil, fmt.Errorf("invalid class of field %s.%s", c.name, fe.attrs["name"])
+				}
+				f := &field{
+					name:         fe.attrs["name"],
+					constantPool: fe.attrs["constantPool"] == "true",
+					array:        fe.attrs["dimension"] == "1",
+				}
+				c.fieldIndex[f.name] = len(c.fields)
+				c.fields = append(c.fields, f)
+				decls = append(decls, fieldDecl{field: f, classID: classID})
+			}
+			md.classes[id] = c
+			md.byName[c.name] = c
+		}
+	}
+
+	for _, decl := range decls {
+		c, ok := md.classes[decl.classID]
+		if !ok {
+			return nil, fmt.Errorf("field %s of undeclared class %d", decl.field.name, decl.classID)
+		}
+		decl.field.class = c
+	}
+	return md, nil
+}
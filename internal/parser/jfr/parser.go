@@ -0,0 +1,601 @@
+// Package jfr parses Java Flight Recorder recordings (.jfr files), of the
+// JDK or of async-profiler, into the samples of CPU, allocation and lock
+// flame graphs.
+package jfr
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/perf-analysis/pkg/model"
+)
+
+const (
+	// chunkHeaderSize is the size of the header of chunks
+	chunkHeaderSize = 68
+
+	// Type IDs of the metadata and constant pool events
+	eventMetadata     = 0
+	eventConstantPool = 1
+
+	// featureCompressedInts is the chunk feature flag of LEB128 integers
+	featureCompressedInts = 1
+
+	// cancelCheckInterval is the number of events between context checks
+	cancelCheckInterval = 10000
+)
+
+// chunkMagic starts every chunk of a recording.
+var chunkMagic = []byte("FLR\x00")
+
+// ErrInvalidFormat is returned when the input is not a JFR recording.
+var ErrInvalidFormat = errors.New("invalid JFR format")
+
+// Event types analyzed, by the profile they are added to.
+const (
+	EventExecutionSample             = "jdk.ExecutionSample"
+	EventObjectAllocationInNewTLAB   = "jdk.ObjectAllocationInNewTLAB"
+	EventObjectAllocationOutsideTLAB = "jdk.ObjectAllocationOutsideTLAB"
+	EventObjectAllocationSample      = "jdk.ObjectAllocationSample"
+	EventJavaMonitorEnter            = "jdk.JavaMonitorEnter"
+	EventThreadPark                  = "jdk.ThreadPark"
+	EventThreadDump                  = "jdk.ThreadDump"
+)
+
+// Frame suffixes of the allocated or contended classes ending the stacks of
+// allocation and lock samples, as in the collapsed output of async-profiler.
+const (
+	suffixInstance    = "_[i]"
+	suffixOutsideTLAB = "_[k]"
+)
+
+// Profile holds the samples of a kind of events, aggregated by thread and
+// stack. Call stacks are ordered from the root to the leaf.
+type Profile struct {
+	Samples []*model.Sample
+	// Timeline holds the samples aggregated by thread, stack and period of
+	// Parser.TimelineResolution, their Timestamp the start of the period.
+	// It is empty if the resolution is not set.
+	Timeline []*model.Sample
+	// Events is the number of events
+	Events int64
+	// Total is the sum of the values of the samples
+	Total int64
+}
+
+// Recording holds the profiles of a JFR recording.
+type Recording struct {
+	StartTime time.Time
+	Duration  time.Duration
+
+	// CPU holds the jdk.ExecutionSample events, valued 1 each
+	CPU *Profile
+	// Allocation holds the allocation events, valued by bytes allocated;
+	// their stacks end with the allocated class
+	Allocation *Profile
+	// Lock holds the jdk.JavaMonitorEnter and jdk.ThreadPark events, of
+	// threads blocked on monitors or parked on locks, valued by nanoseconds
+	// blocked; their stacks end with the class of the lock
+	Lock *Profile
+
+	// ThreadDumps holds the text of the jdk.ThreadDump events, in the
+	// format of jstack
+	ThreadDumps []string
+}
+
+// Parser parses JFR recordings.
+type Parser struct {
+	// TimelineResolution is the period by which events are also aggregated
+	// into the timelines of the profiles; 0 disables timelines.
+	TimelineResolution time.Duration
+}
+
+// NewParser creates a new JFR parser.
+func NewParser() *Parser {
+	return &Parser{}
+}
+
+// IsJFR reports whether data starts like a JFR recording.
+func IsJFR(data []byte) bool {
+	return bytes.HasPrefix(data, chunkMagic)
+}
+
+// Parse parses a recording of one or more chunks.
+func (p *Parser) Parse(ctx context.Context, r io.Reader) (*Recording, error) {
+	rec := &Recording{CPU: &Profile{}, Allocation: &Profile{}, Lock: &Profile{}}
+
+	header := make([]byte, chunkHeaderSize)
+	for n := 0; ; n++ {
+		if _, err := io.ReadFull(r, header); err != nil {
+			if n > 0 && err == io.EOF {
+				break
+			}
+			if err == io.EOF || err == io.ErrUnexpectedEOF {
+				return nil, fmt.Errorf("%w: truncated chunk header", ErrInvalidFormat)
+			}
+			return nil, err
+		}
+		if !IsJFR(header) {
+			return nil, fmt.Errorf("%w: bad magic in chunk %d", ErrInvalidFormat, n)
+		}
+
+		size := int64(binary.BigEndian.Uint64(header[8:]))
+		if size < chunkHeaderSize {
+			return nil, fmt.Errorf("%w: invalid size %d of chunk %d", ErrInvalidFormat, size, n)
+		}
+		// Read the chunk through a LimitReader, for invalid sizes not to
+		// allocate more than the input holds
+		body, err := io.ReadAll(io.LimitReader(r, size-chunkHeaderSize))
+		if err != nil {
+			return nil, err
+		}
+		if int64(len(body)) != size-chunkHeaderSize {
+			return nil, fmt.Errorf("%w: chunk %d truncated", ErrInvalidFormat, n)
+		}
+
+		c, err := newChunk(append(header, body...))
+		if err != nil {
+			return nil, fmt.Errorf("%w: chunk %d: %v", ErrInvalidFormat, n, err)
+		}
+		c.resolution = int64(p.TimelineResolution)
+		if err := c.parse(ctx, rec); err != nil {
+			if ctx.Err() != nil {
+				return nil, err
+			}
+			return nil, fmt.Errorf("%w: chunk %d: %v", ErrInvalidFormat, n, err)
+		}
+
+		if rec.StartTime.IsZero() || c.startTime.Before(rec.StartTime) {
+			rec.StartTime = c.startTime
+		}
+		rec.Duration += c.duration
+		header = make([]byte, chunkHeaderSize)
+	}
+
+	return rec, nil
+}
+
+// chunk is a self-contained part of a recording, with its own metadata and
+// constant pools.
+type chunk struct {
+	r  *reader
+	md *metadata
+
+	startTime      time.Time
+	duration       time.Duration
+	startTicks     int64
+	ticksPerSecond int64
+
+	// resolution is the period of the timelines in nanoseconds, 0 if none
+	resolution int64
+
+	// pools holds the constants by class ID and key
+	pools map[int64]map[int64]interface{}
+
+	// Caches of resolved constants, by key
+	stacks  map[int64][]string
+	threads map[int64]*model.Sample
+	classes map[int64]string
+}
+
+// newChunk reads the header and metadata of a chunk.
+func newChunk(buf []byte) (*chunk, error) {
+	h := buf[:chunkHeaderSize]
+	major := binary.BigEndian.Uint16(h[4:])
+	if major < 1 || major > 2 {
+		return nil, fmt.Errorf("unsupported version %d.%d", major, binary.BigEndian.Uint16(h[6:]))
+	}
+
+	c := &chunk{
+		r:              &reader{buf: buf},
+		startTime:      time.Unix(0, int64(binary.BigEndian.Uint64(h[32:]))),
+		duration:       time.Duration(binary.BigEndian.Uint64(h[40:])),
+		startTicks:     int64(binary.BigEndian.Uint64(h[48:])),
+		ticksPerSecond: int64(binary.BigEndian.Uint64(h[56:])),
+		pools:          make(map[int64]map[int64]interface{}),
+		stacks:         make(map[int64][]string),
+		threads:        make(map[int64]*model.Sample),
+		classes:        make(map[int64]string),
+	}
+	if major >= 2 {
+		c.r.compressed = binary.BigEndian.Uint32(h[64:])&featureCompressedInts != 0
+	}
+
+	metadataOffset := int64(binary.BigEndian.Uint64(h[24:]))
+	if metadataOffset < chunkHeaderSize || metadataOffset >= int64(len(buf)) {
+		return nil, fmt.Errorf("invalid metadata offset %d", metadataOffset)
+	}
+	if err := c.r.seek(int(metadataOffset)); err != nil {
+		return nil, err
+	}
+	md, err := readMetadata(c.r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read metadata: %w", err)
+	}
+	c.md = md
+	return c, nil
+}
+
+// parse adds the events of the chunk to rec: it reads the constant pools
+// first, as events may come before the constants they refer to.
+func (c *chunk) parse(ctx context.Context, rec *Recording) error {
+	if err := c.forEachEvent(ctx, func(typeID int64) error {
+		if typeID != eventConstantPool {
+			return nil
+		}
+		return c.readConstantPool()
+	}); err != nil {
+		return fmt.Errorf("failed to read constant pools: %w", err)
+	}
+
+	handlers := c.handlers(rec)
+	aggregated := make(map[sampleKey]*model.Sample)
+	return c.forEachEvent(ctx, func(typeID int64) error {
+		handler, ok := handlers[typeID]
+		if !ok {
+			return nil
+		}
+		v, err := readValue(c.r, c.md.classes[typeID], false, 0)
+		if err != nil {
+			return fmt.Errorf("failed to read %s event: %w", c.md.classes[typeID].name, err)
+		}
+		handler(v.(*object), aggregated)
+		return nil
+	})
+}
+
+// forEachEvent calls fn with the type of each event of the chunk, the
+// reader positioned after the type. fn does not need to read whole events.
+func (c *chunk) forEachEvent(ctx context.Context, fn func(typeID int64) error) error {
+	pos := chunkHeaderSize
+	for n := 0; pos < len(c.r.buf); n++ {
+		if n%cancelCheckInterval == 0 {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+		}
+
+		if err := c.r.seek(pos); err != nil {
+			return err
+		}
+		size, err := c.r.int()
+		if err != nil {
+			return err
+		}
+		if size <= 0 || int(size) > len(c.r.buf)-pos {
+			return fmt.Errorf("invalid size %d of event at offset %d", size, pos)
+		}
+		typeID, err := c.r.long()
+		if err != nil {
+			return err
+		}
+
+		// Bound the reads of fn to the event
+		r := c.r
+		c.r = &reader{buf: r.buf[:pos+int(size)], pos: r.pos, compressed: r.compressed}
+		err = fn(typeID)
+		c.r = r
+		if err != nil {
+			return err
+		}
+		pos += int(size)
+	}
+	return nil
+}
+
+// readConstantPool reads a constant pool event, after its type.
+func (c *chunk) readConstantPool() error {
+	// Start time, duration, delta to the previous constant pool event and
+	// flush or checkpoint type
+	for i := 0; i < 3; i++ {
+		if _, err := c.r.long(); err != nil {
+			return err
+		}
+	}
+	if _, err := c.r.byte(); err != nil {
+		return err
+	}
+
+	pools, err := c.r.count()
+	if err != nil {
+		return err
+	}
+	for i := 0; i < pools; i++ {
+		classID, err := c.r.long()
+		if err != nil {
+			return err
+		}
+		cls, ok := c.md.classes[classID]
+		if !ok {
+			return fmt.Errorf("constants of undeclared class %d", classID)
+		}
+		pool := c.pools[classID]
+		if pool == nil {
+			pool = make(map[int64]interface{})
+			c.pools[classID] = pool
+		}
+
+		n, err := c.r.count()
+		if err != nil {
+			return err
+		}
+		for j := 0; j < n; j++ {
+			key, err := c.r.long()
+			if err != nil {
+				return err
+			}
+			v, err := readValue(c.r, cls, false, 0)
+			if err != nil {
+				return fmt.Errorf("failed to read constant of %s: %w", cls.name, err)
+			}
+			pool[key] = v
+		}
+	}
+	return nil
+}
+
+// sampleKey identifies the samples aggregated together. Samples of the
+// timelines are aggregated by period too, others have a period of -1.
+type sampleKey struct {
+	profile *Profile
+	thread  int64
+	stack   int64
+	leaf    string
+	period  int64
+}
+
+// eventHandler adds an event to its profile.
+type eventHandler func(event *object, aggregated map[sampleKey]*model.Sample)
+
+// handlers returns the handlers of the event types of the chunk analyzed.
+func (c *chunk) handlers(rec *Recording) map[int64]eventHandler {
+	handlers := make(map[int64]eventHandler)
+	add := func(name string, handler eventHandler) {
+		if cls, ok := c.md.byName[name]; ok {
+			handlers[cls.id] = handler
+		}
+	}
+
+	add(EventExecutionSample, func(e *object, agg map[sampleKey]*model.Sample) {
+		c.add(agg, rec.CPU, e.get("startTime"), e.get("sampledThread"), e.get("stackTrace"), "", 1)
+	})
+
+	// Allocations in new TLABs are valued by the size of the TLAB, which
+	// samples allocations in proportion to their size
+	add(EventObjectAllocationInNewTLAB, func(e *object, agg map[sampleKey]*model.Sample) {
+		size := c.long(e.get("tlabSize"))
+		if size <= 0 {
+			size = c.long(e.get("allocationSize"))
+		}
+		leaf := c.className(e.get("objectClass")) + suffixInstance
+		c.add(agg, rec.Allocation, e.get("startTime"), e.get("eventThread"), e.get("stackTrace"), leaf, size)
+	})
+	add(EventObjectAllocationOutsideTLAB, func(e *object, agg map[sampleKey]*model.Sample) {
+		leaf := c.className(e.get("objectClass")) + suffixOutsideTLAB
+		c.add(agg, rec.Allocation, e.get("startTime"), e.get("eventThread"), e.get("stackTrace"), leaf, c.long(e.get("allocationSize")))
+	})
+	add(EventObjectAllocationSample, func(e *object, agg map[sampleKey]*model.Sample) {
+		leaf := c.className(e.get("objectClass")) + suffixInstance
+		c.add(agg, rec.Allocation, e.get("startTime"), e.get("eventThread"), e.get("stackTrace"), leaf, c.long(e.get("weight")))
+	})
+
+	add(EventJavaMonitorEnter, func(e *object, agg map[sampleKey]*model.Sample) {
+		leaf := c.className(e.get("monitorClass")) + suffixInstance
+		c.add(agg, rec.Lock, e.get("startTime"), e.get("eventThread"), e.get("stackTrace"), leaf, c.nanos(e.get("duration")))
+	})
+	add(EventThreadPark, func(e *object, agg map[sampleKey]*model.Sample) {
+		leaf := c.className(e.get("parkedClass")) + suffixInstance
+		c.add(agg, rec.Lock, e.get("startTime"), e.get("eventThread"), e.get("stackTrace"), leaf, c.nanos(e.get("duration")))
+	})
+
+	add(EventThreadDump, func(e *object, _ map[sampleKey]*model.Sample) {
+		if dump := c.string(e.get("result")); dump != "" {
+			rec.ThreadDumps = append(rec.ThreadDumps, dump)
+		}
+	})
+
+	return handlers
+}
+
+// add adds an event of a thread and stack trace, started at a time in
+// ticks, to a profile and to its timeline.
+func (c *chunk) add(agg map[sampleKey]*model.Sample, p *Profile, start, thread, stack interface{}, leaf string, value int64) {
+	if value <= 0 {
+		return
+	}
+	p.Events++
+	p.Total += value
+
+	key := sampleKey{profile: p, thread: -1, stack: -1, leaf: leaf, period: -1}
+	if ref, ok := thread.(constRef); ok {
+		key.thread = ref.key
+	}
+	if ref, ok := stack.(constRef); ok {
+		key.stack = ref.key
+	}
+	sample := c.aggregate(agg, key, thread, stack, value)
+	if sample != nil {
+		p.Samples = append(p.Samples, sample)
+	}
+
+	if c.resolution > 0 {
+		t := c.time(start)
+		key.period = t - t%c.resolution
+		if sample := c.aggregate(agg, key, thread, stack, value); sample != nil {
+			sample.Timestamp = key.period
+			p.Timeline = append(p.Timeline, sample)
+		}
+	}
+}
+
+// aggregate adds value to the sample of key, and returns the sample if it
+// is new.
+func (c *chunk) aggregate(agg map[sampleKey]*model.Sample, key sampleKey, thread, stack interface{}, value int64) *model.Sample {
+	if sample, ok := agg[key]; ok {
+		sample.Value += value
+		return nil
+	}
+
+	sample := &model.Sample{CallStack: c.stack(stack), Value: value}
+	if t := c.thread(thread); t != nil {
+		sample.ThreadName, sample.TID = t.ThreadName, t.TID
+	}
+	if key.leaf != "" {
+		sample.CallStack = append(sample.CallStack[:len(sample.CallStack):len(sample.CallStack)], key.leaf)
+	}
+	agg[key] = sample
+	return sample
+}
+
+// resolve returns the constant of a constant reference, else v.
+func (c *chunk) resolve(v interface{}) interface{} {
+	switch ref := v.(type) {
+	case constRef:
+		return c.pools[ref.class.id][ref.key]
+	case stringRef:
+		if cls, ok := c.md.byName["java.lang.String"]; ok {
+			return c.pools[cls.id][int64(ref)]
+		}
+		return nil
+	default:
+		return v
+	}
+}
+
+// object returns the object of a value or constant, nil if none.
+func (c *chunk) object(v interface{}) *object {
+	o, _ := c.resolve(v).(*object)
+	return o
+}
+
+// string returns the string of a value, constant or symbol.
+func (c *chunk) string(v interface{}) string {
+	switch v := c.resolve(v).(type) {
+	case string:
+		return v
+	case *object:
+		// Symbols hold their string in a field
+		if s, ok := c.resolve(v.get("string")).(string); ok {
+			return s
+		}
+	}
+	return ""
+}
+
+// long returns the integer of a value, 0 if not an integer.
+func (c *chunk) long(v interface{}) int64 {
+	n, _ := c.resolve(v).(int64)
+	return n
+}
+
+// nanos converts a duration in ticks to nanoseconds.
+func (c *chunk) nanos(v interface{}) int64 {
+	ticks := c.long(v)
+	if c.ticksPerSecond <= 0 || c.ticksPerSecond == int64(time.Second) {
+		return ticks
+	}
+	return int64(float64(ticks) * float64(time.Second) / float64(c.ticksPerSecond))
+}
+
+// time converts a time in ticks to Unix nanoseconds.
+func (c *chunk) time(v interface{}) int64 {
+	ticks := c.long(v) - c.startTicks
+	if c.ticksPerSecond > 0 && c.ticksPerSecond != int64(time.Second) {
+		ticks = int64(float64(ticks) * float64(time.Second) / float64(c.ticksPerSecond))
+	}
+	return c.startTime.UnixNano() + ticks
+}
+
+// className returns the name of a class constant, with dots as package
+// separators.
+func (c *chunk) className(v interface{}) string {
+	ref, isRef := v.(constRef)
+	if isRef {
+		if name, ok := c.classes[ref.key]; ok {
+			return name
+		}
+	}
+	name := strings.ReplaceAll(c.string(c.object(v).get("name")), "/", ".")
+	if name == "" {
+		name = "unknown"
+	}
+	if isRef {
+		c.classes[ref.key] = name
+	}
+	return name
+}
+
+// thread returns the name and ID of a thread constant, nil if unknown.
+func (c *chunk) thread(v interface{}) *model.Sample {
+	ref, isRef := v.(constRef)
+	if isRef {
+		if t, ok := c.threads[ref.key]; ok {
+			return t
+		}
+	}
+	o := c.object(v)
+	if o == nil {
+		return nil
+	}
+
+	t := &model.Sample{ThreadName: c.string(o.get("javaName")), TID: int(c.long(o.get("osThreadId")))}
+	if t.ThreadName == "" {
+		t.ThreadName = c.string(o.get("osName"))
+	}
+	if t.TID == 0 {
+		t.TID = int(c.long(o.get("javaThreadId")))
+	}
+	if isRef {
+		c.threads[ref.key] = t
+	}
+	return t
+}
+
+// nativeFrameTypes are the descriptions of the types of native frames.
+var nativeFrameTypes = map[string]bool{"Native": true, "C++": true, "Kernel": true}
+
+// stack returns the frames of a stack trace constant, from the root to the
+// leaf. Java frames are named "java/lang/Thread.run", native frames by their
+// symbol, as in the collapsed output of async-profiler.
+func (c *chunk) stack(v interface{}) []string {
+	ref, isRef := v.(constRef)
+	if isRef {
+		if frames, ok := c.stacks[ref.key]; ok {
+			return frames
+		}
+	}
+
+	var frames []string
+	if o := c.object(v); o != nil {
+		values, _ := o.get("frames").([]interface{})
+		frames = make([]string, 0, len(values))
+		for i := len(values) - 1; i >= 0; i-- {
+			frame := c.object(values[i])
+			method := c.object(frame.get("method"))
+			if method == nil {
+				continue
+			}
+			name := c.string(method.get("name"))
+			if nativeFrameTypes[c.string(c.object(frame.get("type")).get("description"))] {
+				// async-profiler names the library of native frames as
+				// their class
+				frames = append(frames, name)
+				continue
+			}
+			if class := c.string(c.object(method.get("type")).get("name")); class != "" {
+				name = class + "." + name
+			}
+			frames = append(frames, name)
+		}
+	}
+	if isRef {
+		c.stacks[ref.key] = frames
+	}
+	return frames
+}
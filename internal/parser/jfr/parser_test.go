@@ -0,0 +1,491 @@
+package jfr
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"os"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// chunkWriter writes JFR chunks with compressed integers for tests.
+type chunkWriter struct {
+	strings []string
+	index   map[string]int
+}
+
+func putVarint(b *bytes.Buffer, v int64) {
+	u := uint64(v)
+	for i := 0; i < 8; i++ {
+		if u < 0x80 {
+			b.WriteByte(byte(u))
+			return
+		}
+		b.WriteByte(byte(u) | 0x80)
+		u >>= 7
+	}
+	b.WriteByte(byte(u))
+}
+
+func putString(b *bytes.Buffer, s string) {
+	b.WriteByte(stringUTF8)
+	putVarint(b, int64(len(s)))
+	b.WriteString(s)
+}
+
+// event prefixes an event with its size, as a padded 5-byte varint like
+// async-profiler writes them.
+func event(typeID int64, body func(b *bytes.Buffer)) []byte {
+	var b bytes.Buffer
+	putVarint(&b, typeID)
+	body(&b)
+	size := uint32(b.Len() + 5)
+	out := []byte{byte(size) | 0x80, byte(size>>7) | 0x80, byte(size>>14) | 0x80, byte(size>>21) | 0x80, byte(size >> 28)}
+	return append(out, b.Bytes()...)
+}
+
+func (w *chunkWriter) str(s string) int64 {
+	if w.index == nil {
+		w.index = make(map[string]int)
+	}
+	if i, ok := w.index[s]; ok {
+		return int64(i)
+	}
+	w.index[s] = len(w.strings)
+	w.strings = append(w.strings, s)
+	return int64(len(w.strings) - 1)
+}
+
+// testElement is an element of the metadata written by chunkWriter.
+type testElement struct {
+	name     string
+	attrs    [][2]string
+	children []testElement
+}
+
+func (w *chunkWriter) element(b *bytes.Buffer, e testElement) {
+	putVarint(b, w.str(e.name))
+	putVarint(b, int64(len(e.attrs)))
+	for _, a := range e.attrs {
+		putVarint(b, w.str(a[0]))
+		putVarint(b, w.str(a[1]))
+	}
+	putVarint(b, int64(len(e.children)))
+	for _, c := range e.children {
+		w.element(b, c)
+	}
+}
+
+// Class IDs of the test metadata.
+const (
+	idBoolean = iota + 1
+	idLong
+	idInt
+	idString
+	idThread
+	idSymbol
+	idClass
+	idMethod
+	idFrameType
+	idStackFrame
+	idStackTrace
+	idExecutionSample
+	idAllocation
+	idMonitorEnter
+	idThreadDump
+)
+
+func testClass(id int, name string, fields ...testElement) testElement {
+	return testElement{name: "class", attrs: [][2]string{{"id", strconv.Itoa(id)}, {"name", name}}, children: fields}
+}
+
+func testField(name string, classID int, constantPool, array bool) testElement {
+	attrs := [][2]string{{"name", name}, {"class", strconv.Itoa(classID)}}
+	if constantPool {
+		attrs = append(attrs, [2]string{"constantPool", "true"})
+	}
+	if array {
+		attrs = append(attrs, [2]string{"dimension", "1"})
+	}
+	return testElement{name: "field", attrs: attrs}
+}
+
+func (w *chunkWriter) metadata() []byte {
+	root := testElement{name: "root", children: []testElement{
+		{name: "metadata", children: []testElement{
+			testClass(idBoolean, "boolean"),
+			testClass(idLong, "long"),
+			testClass(idInt, "int"),
+			testClass(idString, "java.lang.String"),
+			testClass(idThread, "java.lang.Thread",
+				testField("javaName", idString, false, false),
+				testField("osThreadId", idLong, false, false)),
+			testClass(idSymbol, "jdk.types.Symbol", testField("string", idString, false, false)),
+			testClass(idClass, "java.lang.Class", testField("name", idSymbol, true, false)),
+			testClass(idMethod, "jdk.types.Method",
+				testField("type", idClass, true, false),
+				testField("name", idSymbol, true, false)),
+			testClass(idFrameType, "jdk.types.FrameType", testField("description", idString, false, false)),
+			testClass(idStackFrame, "jdk.types.StackFrame",
+				testField("method", idMethod, true, false),
+				testField("lineNumber", idInt, false, false),
+				testField("type", idFrameType, true, false)),
+			testClass(idStackTrace, "jdk.types.StackTrace",
+				testField("truncated", idBoolean, false, false),
+				testField("frames", idStackFrame, false, true)),
+			testClass(idExecutionSample, EventExecutionSample,
+				testField("startTime", idLong, false, false),
+				testField("sampledThread", idThread, true, false),
+				testField("stackTrace", idStackTrace, true, false)),
+			testClass(idAllocation, EventObjectAllocationInNewTLAB,
+				testField("startTime", idLong, false, false),
+				testField("eventThread", idThread, true, false),
+				testField("stackTrace", idStackTrace, true, false),
+				testField("objectClass", idClass, true, false),
+				testField("allocationSize", idLong, false, false),
+				testField("tlabSize", idLong, false, false)),
+			testClass(idMonitorEnter, EventJavaMonitorEnter,
+				testField("startTime", idLong, false, false),
+				testField("duration", idLong, false, false),
+				testField("eventThread", idThread, true, false),
+				testField("stackTrace", idStackTrace, true, false),
+				testField("monitorClass", idClass, true, false)),
+			testClass(idThreadDump, EventThreadDump,
+				testField("startTime", idLong, false, false),
+				testField("result", idString, false, false)),
+		}},
+		{name: "region"},
+	}}
+
+	var tree bytes.Buffer
+	w.element(&tree, root)
+	return event(eventMetadata, func(b *bytes.Buffer) {
+		putVarint(b, 0) // start time
+		putVarint(b, 0) // duration
+		putVarint(b, 1) // metadata ID
+		putVarint(b, int64(len(w.strings)))
+		for _, s := range w.strings {
+			putString(b, s)
+		}
+		b.Write(tree.Bytes())
+	})
+}
+
+// constantPool writes the constants of the test recording: thread 1 "main"
+// and thread 2 "worker", and stack traces 1 main -> Service.handle and 2
+// main -> Service.handle -> Cache.get -> malloc (native).
+func constantPool() []byte {
+	return event(eventConstantPool, func(b *bytes.Buffer) {
+		putVarint(b, 0) // start time
+		putVarint(b, 0) // duration
+		putVarint(b, 0) // delta
+		b.WriteByte(1)  // flush
+		putVarint(b, 7) // pools
+
+		putVarint(b, idString)
+		putVarint(b, 1)
+		putVarint(b, 1)
+		putString(b, "worker")
+
+		putVarint(b, idThread)
+		putVarint(b, 2)
+		putVarint(b, 1)
+		putString(b, "main")
+		putVarint(b, 101)
+		putVarint(b, 2)
+		b.WriteByte(stringConstant)
+		putVarint(b, 1)
+		putVarint(b, 102)
+
+		symbols := []string{"", "com/example/App", "main", "com/example/Service", "handle", "com/example/Cache", "get", "malloc", "java/util/HashMap"}
+		putVarint(b, idSymbol)
+		putVarint(b, int64(len(symbols)-1))
+		for i := 1; i < len(symbols); i++ {
+			putVarint(b, int64(i))
+			putString(b, symbols[i])
+		}
+
+		classes := []int64{1, 3, 5, 8} // symbol keys of class names
+		putVarint(b, idClass)
+		putVarint(b, int64(len(classes)+1))
+		for i, symbol := range classes {
+			putVarint(b, int64(i+1))
+			putVarint(b, symbol)
+		}
+		putVarint(b, 9) // native library
+		putVarint(b, 0)
+
+		// Methods: key, class key, name symbol key
+		methods := [][3]int64{{1, 1, 2}, {2, 2, 4}, {3, 3, 6}, {4, 9, 7}}
+		putVarint(b, idMethod)
+		putVarint(b, int64(len(methods)))
+		for _, m := range methods {
+			putVarint(b, m[0])
+			putVarint(b, m[1])
+			putVarint(b, m[2])
+		}
+
+		putVarint(b, idFrameType)
+		putVarint(b, 2)
+		putVarint(b, 1)
+		putString(b, "JIT compiled")
+		putVarint(b, 2)
+		putString(b, "Native")
+
+		frames := func(methods ...int64) {
+			b.WriteByte(0) // not truncated
+			putVarint(b, int64(len(methods)))
+			for i := len(methods) - 1; i >= 0; i-- { // leaf first
+				putVarint(b, methods[i])
+				putVarint(b, 10) // line number
+				if methods[i] == 4 {
+					putVarint(b, 2)
+				} else {
+					putVarint(b, 1)
+				}
+			}
+		}
+		putVarint(b, idStackTrace)
+		putVarint(b, 2)
+		putVarint(b, 1)
+		frames(1, 2)
+		putVarint(b, 2)
+		frames(1, 2, 3, 4)
+	})
+}
+
+// testChunk returns a chunk of the test recording, with the events in the
+// middle, followed by the constant pool and metadata.
+func testChunk(events ...[]byte) []byte {
+	w := &chunkWriter{}
+	var body bytes.Buffer
+	for _, e := range events {
+		body.Write(e)
+	}
+	cpOffset := chunkHeaderSize + body.Len()
+	body.Write(constantPool())
+	metadataOffset := chunkHeaderSize + body.Len()
+	body.Write(w.metadata())
+
+	header := make([]byte, chunkHeaderSize)
+	copy(header, chunkMagic)
+	binary.BigEndian.PutUint16(header[4:], 2)
+	binary.BigEndian.PutUint16(header[6:], 1)
+	binary.BigEndian.PutUint64(header[8:], uint64(chunkHeaderSize+body.Len()))
+	binary.BigEndian.PutUint64(header[16:], uint64(cpOffset))
+	binary.BigEndian.PutUint64(header[24:], uint64(metadataOffset))
+	binary.BigEndian.PutUint64(header[32:], uint64(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC).UnixNano()))
+	binary.BigEndian.PutUint64(header[40:], uint64(10*time.Second))
+	binary.BigEndian.PutUint64(header[56:], 1_000_000) // microsecond ticks
+	binary.BigEndian.PutUint32(header[64:], featureCompressedInts)
+	return append(header, body.Bytes()...)
+}
+
+func executionSample(thread, stack int64) []byte {
+	return executionSampleAt(thread, stack, 1000)
+}
+
+// executionSampleAt returns an execution sample at a time in microsecond
+// ticks since the start of the chunk.
+func executionSampleAt(thread, stack, ticks int64) []byte {
+	return event(idExecutionSample, func(b *bytes.Buffer) {
+		putVarint(b, ticks)
+		putVarint(b, thread)
+		putVarint(b, stack)
+	})
+}
+
+func allocation(thread, stack, class, size, tlab int64) []byte {
+	return event(idAllocation, func(b *bytes.Buffer) {
+		putVarint(b, 1000)
+		putVarint(b, thread)
+		putVarint(b, stack)
+		putVarint(b, class)
+		putVarint(b, size)
+		putVarint(b, tlab)
+	})
+}
+
+func monitorEnter(thread, stack, class, durationTicks int64) []byte {
+	return event(idMonitorEnter, func(b *bytes.Buffer) {
+		putVarint(b, 1000)
+		putVarint(b, durationTicks)
+		putVarint(b, thread)
+		putVarint(b, stack)
+		putVarint(b, class)
+	})
+}
+
+func threadDump(text string) []byte {
+	return event(idThreadDump, func(b *bytes.Buffer) {
+		putVarint(b, 1000)
+		putString(b, text)
+	})
+}
+
+func TestParser_Parse(t *testing.T) {
+	data := testChunk(
+		executionSample(1, 1),
+		executionSample(1, 1),
+		executionSample(2, 2),
+		allocation(2, 2, 4, 24, 16384),
+		allocation(2, 2, 4, 32, 0),
+		monitorEnter(1, 1, 3, 1500),
+	)
+	// Recordings may hold several chunks
+	data = append(data, testChunk(executionSample(1, 1))...)
+
+	rec, err := NewParser().Parse(context.Background(), bytes.NewReader(data))
+	require.NoError(t, err)
+
+	assert.Equal(t, time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), rec.StartTime.UTC())
+	assert.Equal(t, 20*time.Second, rec.Duration)
+
+	// Samples are aggregated by thread and stack within chunks
+	require.Len(t, rec.CPU.Samples, 3)
+	assert.Equal(t, int64(4), rec.CPU.Events)
+	assert.Equal(t, int64(4), rec.CPU.Total)
+	main := rec.CPU.Samples[0]
+	assert.Equal(t, "main", main.ThreadName)
+	assert.Equal(t, 101, main.TID)
+	assert.Equal(t, int64(2), main.Value)
+	assert.Equal(t, []string{"com/example/App.main", "com/example/Service.handle"}, main.CallStack)
+
+	worker := rec.CPU.Samples[1]
+	assert.Equal(t, "worker", worker.ThreadName, "thread name stored as a string constant")
+	assert.Equal(t, []string{"com/example/App.main", "com/example/Service.handle", "com/example/Cache.get", "malloc"},
+		worker.CallStack, "native frames named by their symbol")
+
+	// Allocations in new TLABs are valued by TLAB size, and end with the
+	// allocated class
+	require.Len(t, rec.Allocation.Samples, 1)
+	assert.Equal(t, int64(2), rec.Allocation.Events)
+	assert.Equal(t, int64(16384+32), rec.Allocation.Total)
+	alloc := rec.Allocation.Samples[0]
+	assert.Equal(t, "java.util.HashMap_[i]", alloc.CallStack[len(alloc.CallStack)-1])
+	assert.Len(t, worker.CallStack, 4, "stacks shared with other samples are not modified")
+
+	// Lock durations are converted from ticks to nanoseconds
+	require.Len(t, rec.Lock.Samples, 1)
+	assert.Equal(t, int64(1500*time.Microsecond), rec.Lock.Total)
+	assert.Equal(t, []string{"com/example/App.main", "com/example/Service.handle", "com.example.Cache_[i]"},
+		rec.Lock.Samples[0].CallStack)
+}
+
+func TestParser_AsyncProfilerRecording(t *testing.T) {
+	f, err := os.Open("../../../test/profile-cpu.jfr")
+	require.NoError(t, err)
+	defer f.Close()
+
+	rec, err := NewParser().Parse(context.Background(), f)
+	require.NoError(t, err)
+
+	assert.Equal(t, int64(2173), rec.CPU.Events)
+	assert.Equal(t, rec.CPU.Events, rec.CPU.Total)
+	assert.Zero(t, rec.Allocation.Events)
+	assert.Zero(t, rec.Lock.Events)
+	assert.InDelta(t, time.Minute.Seconds(), rec.Duration.Seconds(), 1)
+
+	var found bool
+	for _, s := range rec.CPU.Samples {
+		require.NotEmpty(t, s.ThreadName)
+		if s.ThreadName == "OpentelemetryAgent-TencentLogger-LogDaemon-Thread" && s.TID == 30 &&
+			s.CallStack[0] == "java/lang/Thread.run" {
+			found = true
+		}
+	}
+	assert.True(t, found)
+}
+
+func TestParser_InvalidInput(t *testing.T) {
+	p := NewParser()
+	ctx := context.Background()
+
+	tests := map[string][]byte{
+		"empty":           nil,
+		"not JFR":         []byte("[main tid=1];main 1\n"),
+		"short header":    []byte("FLR\x00\x00\x02"),
+		"truncated chunk": testChunk(executionSample(1, 1))[:200],
+	}
+	for name, data := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := p.Parse(ctx, bytes.NewReader(data))
+			assert.ErrorIs(t, err, ErrInvalidFormat)
+		})
+	}
+
+	t.Run("corrupt event", func(t *testing.T) {
+		data := testChunk(executionSample(1, 1))
+		data[chunkHeaderSize+4] = 0 // event size of 0x80... 0 bytes
+		data[chunkHeaderSize] = 0x80
+		data[chunkHeaderSize+1] = 0x80
+		data[chunkHeaderSize+2] = 0x80
+		data[chunkHeaderSize+3] = 0x80
+		_, err := p.Parse(ctx, bytes.NewReader(data))
+		assert.ErrorIs(t, err, ErrInvalidFormat)
+	})
+
+	t.Run("canceled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(ctx)
+		cancel()
+		_, err := p.Parse(ctx, bytes.NewReader(testChunk(executionSample(1, 1))))
+		assert.ErrorIs(t, err, context.Canceled)
+	})
+}
+
+func TestIsJFR(t *testing.T) {
+	assert.True(t, IsJFR(testChunk()))
+	assert.False(t, IsJFR([]byte("FLR")))
+}
+
+func TestParser_ThreadDumps(t *testing.T) {
+	dump := "Full thread dump OpenJDK 64-Bit Server VM:\n\n\"main\" #1 prio=5 runnable\n"
+	data := testChunk(threadDump(dump), executionSample(1, 1), threadDump(dump))
+
+	rec, err := NewParser().Parse(context.Background(), bytes.NewReader(data))
+	require.NoError(t, err)
+	assert.Equal(t, []string{dump, dump}, rec.ThreadDumps)
+	assert.Equal(t, int64(1), rec.CPU.Events)
+}
+
+func TestParser_Timeline(t *testing.T) {
+	data := testChunk(
+		executionSampleAt(1, 1, 100_000),
+		executionSampleAt(1, 1, 900_000),
+		executionSampleAt(1, 1, 1_500_000),
+		executionSampleAt(2, 2, 2_200_000),
+		monitorEnter(1, 1, 3, 1500),
+	)
+
+	p := NewParser()
+	p.TimelineResolution = time.Second
+	rec, err := p.Parse(context.Background(), bytes.NewReader(data))
+	require.NoError(t, err)
+
+	// The timeline does not change the aggregated samples
+	require.Len(t, rec.CPU.Samples, 2)
+	assert.Equal(t, int64(3), rec.CPU.Samples[0].Value)
+	assert.Zero(t, rec.CPU.Samples[0].Timestamp)
+
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC).UnixNano()
+	require.Len(t, rec.CPU.Timeline, 3)
+	assert.Equal(t, start, rec.CPU.Timeline[0].Timestamp)
+	assert.Equal(t, int64(2), rec.CPU.Timeline[0].Value)
+	assert.Equal(t, start+int64(time.Second), rec.CPU.Timeline[1].Timestamp)
+	assert.Equal(t, int64(1), rec.CPU.Timeline[1].Value)
+	assert.Equal(t, start+int64(2*time.Second), rec.CPU.Timeline[2].Timestamp)
+	assert.Equal(t, "worker", rec.CPU.Timeline[2].ThreadName)
+
+	require.Len(t, rec.Lock.Timeline, 1)
+	assert.Equal(t, "com.example.Cache_[i]", rec.Lock.Timeline[0].CallStack[2])
+
+	// Timelines are disabled by default
+	rec, err = NewParser().Parse(context.Background(), bytes.NewReader(data))
+	require.NoError(t, err)
+	assert.Empty(t, rec.CPU.Timeline)
+}
@@ -0,0 +1,200 @@
+package jfr
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"unicode/utf16"
+)
+
+// errTruncated is returned when a chunk ends in the middle of a value.
+var errTruncated = errors.New("truncated data")
+
+// String encodings of JFR.
+const (
+	stringNull     = 0
+	stringEmpty    = 1
+	stringConstant = 2
+	stringUTF8     = 3
+	stringUTF16    = 4
+	stringLatin1   = 5
+)
+
+// reader reads the values of a chunk. Integers are LEB128 encoded when the
+// chunk has compressed integers, as in all the recordings of JDK 11+ and
+// async-profiler, else big-endian.
+type reader struct {
+	buf        []byte
+	pos        int
+	compressed bool
+}
+
+// stringRef is a string stored in the constant pool of java.lang.String.
+type stringRef int64
+
+// seek moves to an offset of the chunk.
+func (r *reader) seek(pos int) error {
+	if pos < 0 || pos > len(r.buf) {
+		return fmt.Errorf("offset %d out of chunk of %d bytes", pos, len(r.buf))
+	}
+	r.pos = pos
+	return nil
+}
+
+func (r *reader) byte() (byte, error) {
+	if r.pos >= len(r.buf) {
+		return 0, errTruncated
+	}
+	b := r.buf[r.pos]
+	r.pos++
+	return b, nil
+}
+
+func (r *reader) bytes(n int) ([]byte, error) {
+	if n < 0 || n > len(r.buf)-r.pos {
+		return nil, errTruncated
+	}
+	b := r.buf[r.pos : r.pos+n]
+	r.pos += n
+	return b, nil
+}
+
+// varint reads a LEB128 integer: 7 bits per byte for the first 8 bytes, and
+// all the bits of the 9th.
+func (r *reader) varint() (int64, error) {
+	var v uint64
+	for i := 0; i < 8; i++ {
+		b, err := r.byte()
+		if err != nil {
+			return 0, err
+		}
+		v |= uint64(b&0x7f) << (7 * i)
+		if b&0x80 == 0 {
+			return int64(v), nil
+		}
+	}
+	b, err := r.byte()
+	if err != nil {
+		return 0, err
+	}
+	return int64(v | uint64(b)<<56), nil
+}
+
+func (r *reader) long() (int64, error) {
+	if r.compressed {
+		return r.varint()
+	}
+	b, err := r.bytes(8)
+	if err != nil {
+		return 0, err
+	}
+	return int64(binary.BigEndian.Uint64(b)), nil
+}
+
+func (r *reader) int() (int32, error) {
+	if r.compressed {
+		v, err := r.varint()
+		return int32(v), err
+	}
+	b, err := r.bytes(4)
+	if err != nil {
+		return 0, err
+	}
+	return int32(binary.BigEndian.Uint32(b)), nil
+}
+
+func (r *reader) short() (int16, error) {
+	if r.compressed {
+		v, err := r.varint()
+		return int16(v), err
+	}
+	b, err := r.bytes(2)
+	if err != nil {
+		return 0, err
+	}
+	return int16(binary.BigEndian.Uint16(b)), nil
+}
+
+// count reads the length of an array or pool, bounded by the bytes left as
+// every element takes at least one.
+func (r *reader) count() (int, error) {
+	n, err := r.int()
+	if err != nil {
+		return 0, err
+	}
+	if n < 0 || int(n) > len(r.buf)-r.pos {
+		return 0, fmt.Errorf("invalid count %d", n)
+	}
+	return int(n), nil
+}
+
+func (r *reader) float() (float64, error) {
+	b, err := r.bytes(4)
+	if err != nil {
+		return 0, err
+	}
+	return float64(math.Float32frombits(binary.BigEndian.Uint32(b))), nil
+}
+
+func (r *reader) double() (float64, error) {
+	b, err := r.bytes(8)
+	if err != nil {
+		return 0, err
+	}
+	return math.Float64frombits(binary.BigEndian.Uint64(b)), nil
+}
+
+// string reads a string in any of the encodings of JFR, a string or a
+// stringRef.
+func (r *reader) string() (interface{}, error) {
+	encoding, err := r.byte()
+	if err != nil {
+		return "", err
+	}
+
+	switch encoding {
+	case stringNull, stringEmpty:
+		return "", nil
+	case stringConstant:
+		key, err := r.long()
+		return stringRef(key), err
+	case stringUTF8:
+		n, err := r.count()
+		if err != nil {
+			return "", err
+		}
+		b, err := r.bytes(n)
+		return string(b), err
+	case stringUTF16:
+		n, err := r.count()
+		if err != nil {
+			return "", err
+		}
+		chars := make([]uint16, n)
+		for i := range chars {
+			c, err := r.short()
+			if err != nil {
+				return "", err
+			}
+			chars[i] = uint16(c)
+		}
+		return string(utf16.Decode(chars)), nil
+	case stringLatin1:
+		n, err := r.count()
+		if err != nil {
+			return "", err
+		}
+		b, err := r.bytes(n)
+		if err != nil {
+			return "", err
+		}
+		runes := make([]rune, len(b))
+		for i, c := range b {
+			runes[i] = rune(c)
+		}
+		return string(runes), nil
+	default:
+		return "", fmt.Errorf("unknown string encoding %d", encoding)
+	}
+}
@@ -0,0 +1,98 @@
+package jfr
+
+import (
+	"fmt"
+)
+
+// maxValueDepth bounds the nesting of the values of inline classes.
+const maxValueDepth = 16
+
+// Values read from chunks are int64, float64, bool, string, stringRef,
+// constRef, *object or []interface{} for arrays.
+
+// object is a value of a class with fields, e.g. a stack frame.
+type object struct {
+	class  *class
+	fields []interface{}
+}
+
+// get returns the value of a field of o, nil if o has no such field.
+func (o *object) get(name string) interface{} {
+	if o == nil {
+		return nil
+	}
+	if i := o.class.field(name); i >= 0 {
+		return o.fields[i]
+	}
+	return nil
+}
+
+// constRef is the key of a constant of a class, resolved once all the
+// constant pools of the chunk are read.
+type constRef struct {
+	class *class
+	key   int64
+}
+
+// readField reads the value of a field.
+func readField(r *reader, f *field, depth int) (interface{}, error) {
+	if !f.array {
+		return readValue(r, f.class, f.constantPool, depth)
+	}
+
+	n, err := r.count()
+	if err != nil {
+		return nil, err
+	}
+	values := make([]interface{}, n)
+	for i := range values {
+		if values[i], err = readValue(r, f.class, f.constantPool, depth); err != nil {
+			return nil, err
+		}
+	}
+	return values, nil
+}
+
+// readValue reads a value of a class, or the key of a constant of the class.
+func readValue(r *reader, c *class, constant bool, depth int) (interface{}, error) {
+	if constant {
+		key, err := r.long()
+		return constRef{class: c, key: key}, err
+	}
+
+	switch c.name {
+	case "boolean":
+		b, err := r.byte()
+		return b != 0, err
+	case "byte":
+		b, err := r.byte()
+		return int64(int8(b)), err
+	case "char", "short":
+		v, err := r.short()
+		return int64(v), err
+	case "int":
+		v, err := r.int()
+		return int64(v), err
+	case "long":
+		return r.long()
+	case "float":
+		return r.float()
+	case "double":
+		return r.double()
+	case "java.lang.String":
+		return r.string()
+	}
+
+	if depth >= maxValueDepth {
+		return nil, fmt.Errorf("values of %s nested deeper than %d", c.name, maxValueDepth)
+	}
+	o := &object{class: c, fields: make([]interface{}, len(c.fields))}
+	for i, f := range c.fields {
+		v, err := readField(r, f, depth+1)
+		if err != nil {
+			return nil, err
+		}
+		o.fields[i] = v
+	}
+	return o, nil
+}
@@ -0,0 +1,172 @@
+package jstack
+
+import (
+	"sort"
+)
+
+// StateUnknown is the state counted for threads without a Java state, i.e.
+// JVM internal threads.
+const StateUnknown = "UNKNOWN"
+
+// Deadlock is a cycle of threads, each blocked on a lock the next holds.
+type Deadlock struct {
+	// Threads of the cycle, starting at the smallest name
+	Threads []DeadlockThread
+}
+
+// DeadlockThread is a thread of a deadlock.
+type DeadlockThread struct {
+	Thread *Thread
+	// WaitingFor is the lock the thread is blocked on
+	WaitingFor Lock
+	// HeldBy is the thread holding WaitingFor, the next of the cycle
+	HeldBy *Thread
+}
+
+// Monitor is a lock threads are blocked on.
+type Monitor struct {
+	Lock
+	Owner   *Thread // nil if the owner is not known
+	Waiters []*Thread
+}
+
+// Blocker returns the lock the thread is blocked on, if any.
+func (t *Thread) Blocker() *Lock {
+	if t.WaitingToLock != nil {
+		return t.WaitingToLock
+	}
+	return t.ParkingFor
+}
+
+// StateCounts returns the number of threads in each state.
+func (d *ThreadDump) StateCounts() map[string]int {
+	counts := make(map[string]int)
+	for _, t := range d.Threads {
+		state := t.State
+		if state == "" {
+			state = StateUnknown
+		}
+		counts[state]++
+	}
+	return counts
+}
+
+// owners returns the threads holding each lock, by address. A monitor a
+// thread waits on in Object.wait is released, although still listed as
+// locked by its caller.
+func (d *ThreadDump) owners() map[string]*Thread {
+	owners := make(map[string]*Thread)
+	for _, t := range d.Threads {
+		for _, lock := range t.Locked {
+			if t.WaitingOn != nil && t.WaitingOn.Address == lock.Address {
+				continue
+			}
+			owners[lock.Address] = t
+		}
+	}
+	return owners
+}
+
+// waitsFor returns the wait-for graph of the dump: the thread holding the
+// lock each blocked thread is blocked on.
+func (d *ThreadDump) waitsFor() map[*Thread]*Thread {
+	owners := d.owners()
+	graph := make(map[*Thread]*Thread)
+	for _, t := range d.Threads {
+		if lock := t.Blocker(); lock != nil {
+			if owner := owners[lock.Address]; owner != nil && owner != t {
+				graph[t] = owner
+			}
+		}
+	}
+	return graph
+}
+
+// Deadlocks returns the cycles of the wait-for graph of the dump.
+func (d *ThreadDump) Deadlocks() []Deadlock {
+	graph := d.waitsFor()
+
+	// Every thread waits for at most one other, so that following the
+	// graph from each thread either ends or enters a single cycle
+	const (
+		unvisited = iota
+		visiting
+		done
+	)
+	color := make(map[*Thread]int, len(graph))
+	var deadlocks []Deadlock
+	for _, start := range d.Threads {
+		var path []*Thread
+		t := start
+		for t != nil && color[t] == unvisited {
+			color[t] = visiting
+			path = append(path, t)
+			t = graph[t]
+		}
+		if t != nil && color[t] == visiting {
+			deadlocks = append(deadlocks, newDeadlock(graph, t))
+		}
+		for _, p := range path {
+			color[p] = done
+		}
+	}
+
+	sort.Slice(deadlocks, func(i, j int) bool {
+		return deadlocks[i].Threads[0].Thread.Name < deadlocks[j].Threads[0].Thread.Name
+	})
+	return deadlocks
+}
+
+// newDeadlock returns the deadlock of the cycle of graph through t.
+func newDeadlock(graph map[*Thread]*Thread, t *Thread) Deadlock {
+	first := t
+	for next := graph[t]; next != t; next = graph[next] {
+		if next.Name < first.Name {
+			first = next
+		}
+	}
+
+	var deadlock Deadlock
+	for cur := first; ; {
+		next := graph[cur]
+		deadlock.Threads = append(deadlock.Threads, DeadlockThread{
+			Thread:     cur,
+			WaitingFor: *cur.Blocker(),
+			HeldBy:     next,
+		})
+		if cur = next; cur == first {
+			break
+		}
+	}
+	return deadlock
+}
+
+// HotMonitors returns the locks threads are blocked on, most waiters first.
+func (d *ThreadDump) HotMonitors() []Monitor {
+	owners := d.owners()
+	monitors := make(map[string]*Monitor)
+	for _, t := range d.Threads {
+		lock := t.Blocker()
+		if lock == nil {
+			continue
+		}
+		m, ok := monitors[lock.Address]
+		if !ok {
+			m = &Monitor{Lock: *lock, Owner: owners[lock.Address]}
+			monitors[lock.Address] = m
+		}
+		m.Waiters = append(m.Waiters, t)
+	}
+
+	result := make([]Monitor, 0, len(monitors))
+	for _, m := range monitors {
+		result = append(result, *m)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if len(result[i].Waiters) != len(result[j].Waiters) {
+			return len(result[i].Waiters) > len(result[j].Waiters)
+		}
+		return result[i].Address < result[j].Address
+	})
+	return result
+}
@@ -0,0 +1,81 @@
+package jstack
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestThreadDump_StateCounts(t *testing.T) {
+	dump := parseDump(t, deadlockDump)[0]
+	assert.Equal(t, map[string]int{
+		"WAITING":    3,
+		"BLOCKED":    2,
+		"RUNNABLE":   1,
+		StateUnknown: 1,
+	}, dump.StateCounts())
+}
+
+func TestThreadDump_Deadlocks(t *testing.T) {
+	dump := parseDump(t, deadlockDump)[0]
+
+	deadlocks := dump.Deadlocks()
+	require.Len(t, deadlocks, 1)
+	threads := deadlocks[0].Threads
+	require.Len(t, threads, 2)
+	assert.Equal(t, "worker-1", threads[0].Thread.Name)
+	assert.Equal(t, "0x00000007000b0000", threads[0].WaitingFor.Address)
+	assert.Equal(t, "worker-2", threads[0].HeldBy.Name)
+	assert.Equal(t, "worker-2", threads[1].Thread.Name)
+	assert.Equal(t, "worker-1", threads[1].HeldBy.Name)
+}
+
+func TestThreadDump_DeadlocksThreeThreads(t *testing.T) {
+	lock := func(addr string) *Lock { return &Lock{Address: addr, Class: "java.lang.Object"} }
+	a := &Thread{Name: "c", WaitingToLock: lock("0x1"), Locked: []Lock{*lock("0x3")}}
+	b := &Thread{Name: "a", WaitingToLock: lock("0x2"), Locked: []Lock{*lock("0x1")}}
+	c := &Thread{Name: "b", ParkingFor: lock("0x3"), Locked: []Lock{*lock("0x2")}}
+	// A thread blocked on the cycle is not part of it
+	d := &Thread{Name: "d", WaitingToLock: lock("0x1")}
+	dump := &ThreadDump{Threads: []*Thread{d, a, b, c}}
+
+	deadlocks := dump.Deadlocks()
+	require.Len(t, deadlocks, 1)
+	var names []string
+	for _, t := range deadlocks[0].Threads {
+		names = append(names, t.Thread.Name)
+	}
+	assert.Equal(t, []string{"a", "b", "c"}, names)
+}
+
+func TestThreadDump_NoDeadlockOnWait(t *testing.T) {
+	// Object.wait releases the monitor the thread waits on
+	waiter := &Thread{
+		Name:          "waiter",
+		WaitingOn:     &Lock{Address: "0x1"},
+		WaitingToLock: &Lock{Address: "0x2"},
+		Locked:        []Lock{{Address: "0x1"}},
+	}
+	holder := &Thread{Name: "holder", WaitingToLock: &Lock{Address: "0x1"}, Locked: []Lock{{Address: "0x2"}}}
+	dump := &ThreadDump{Threads: []*Thread{waiter, holder}}
+
+	assert.Empty(t, dump.Deadlocks())
+}
+
+func TestThreadDump_HotMonitors(t *testing.T) {
+	dump := parseDump(t, deadlockDump)[0]
+
+	monitors := dump.HotMonitors()
+	require.Len(t, monitors, 3)
+	hot := monitors[0]
+	assert.Equal(t, "0x00000007000d0000", hot.Address)
+	assert.Equal(t, "java.util.concurrent.locks.ReentrantLock$NonfairSync", hot.Class)
+	require.NotNil(t, hot.Owner)
+	assert.Equal(t, "pool-1", hot.Owner.Name)
+	require.Len(t, hot.Waiters, 2)
+	assert.Equal(t, "pool-2", hot.Waiters[0].Name)
+
+	assert.Equal(t, "0x00000007000b0000", monitors[1].Address)
+	assert.Equal(t, "worker-2", monitors[1].Owner.Name)
+}
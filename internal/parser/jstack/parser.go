@@ -0,0 +1,228 @@
+// Package jstack parses Java thread dumps, as printed by jstack, jcmd
+// Thread.print or the jdk.ThreadDump events of JFR recordings, and analyzes
+// their thread states and lock contention.
+package jstack
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// cancelCheckInterval is the number of lines between context checks.
+const cancelCheckInterval = 10000
+
+// ErrNoThreads is returned when the input holds no thread.
+var ErrNoThreads = errors.New("no threads in thread dump")
+
+// ThreadDump is a snapshot of the threads of a JVM.
+type ThreadDump struct {
+	// Time is the line preceding the "Full thread dump" header, the time
+	// of the dump for jstack
+	Time    string
+	Threads []*Thread
+}
+
+// Thread is a thread of a thread dump.
+type Thread struct {
+	Name   string
+	ID     int64  // Java thread ID, 0 for JVM internal threads
+	NID    string // native thread ID
+	Daemon bool
+
+	// State is the java.lang.Thread.State of the thread, empty for JVM
+	// internal threads
+	State       string
+	StateDetail string
+
+	// Stack holds the methods of the thread stack, root first
+	Stack []string
+
+	// WaitingToLock is the monitor the thread is blocked on
+	WaitingToLock *Lock
+	// ParkingFor is the java.util.concurrent lock the thread is parked on
+	ParkingFor *Lock
+	// WaitingOn is the monitor the thread waits on in Object.wait, which
+	// releases it
+	WaitingOn *Lock
+	// Locked holds the monitors and ownable synchronizers the thread holds
+	Locked []Lock
+}
+
+// Lock is a monitor or java.util.concurrent lock.
+type Lock struct {
+	Address string
+	Class   string
+}
+
+// Parser parses thread dumps.
+type Parser struct{}
+
+// NewParser creates a new thread dump parser.
+func NewParser() *Parser {
+	return &Parser{}
+}
+
+// Parse parses the thread dumps of r. Input without a "Full thread dump"
+// header is parsed as a single dump.
+func (p *Parser) Parse(ctx context.Context, r io.Reader) ([]*ThreadDump, error) {
+	var (
+		dumps    []*ThreadDump
+		dump     *ThreadDump
+		thread   *Thread
+		prev     string
+		skipping bool
+	)
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for n := 0; scanner.Scan(); n++ {
+		if n%cancelCheckInterval == 0 {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+		}
+
+		line := strings.TrimSpace(scanner.Text())
+		switch {
+		case strings.HasPrefix(line, "Full thread dump"):
+			dump = &ThreadDump{Time: prev}
+			dumps = append(dumps, dump)
+			thread, skipping = nil, false
+
+		case skipping:
+			// The deadlocks found by jstack repeat the threads involved
+
+		case strings.HasPrefix(line, "Found one Java-level deadlock") ||
+			(strings.HasPrefix(line, "Found ") && strings.Contains(line, "Java-level deadlocks")):
+			thread, skipping = nil, true
+
+		case strings.HasPrefix(line, `"`):
+			t := parseHeader(line)
+			if t == nil {
+				break
+			}
+			if dump == nil {
+				dump = &ThreadDump{}
+				dumps = append(dumps, dump)
+			}
+			thread = t
+			dump.Threads = append(dump.Threads, thread)
+
+		case thread != nil:
+			parseThreadLine(thread, line)
+		}
+		if line != "" {
+			prev = line
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	for _, d := range dumps {
+		for _, t := range d.Threads {
+			// Frames are printed innermost first
+			for i, j := 0, len(t.Stack)-1; i < j; i, j = i+1, j-1 {
+				t.Stack[i], t.Stack[j] = t.Stack[j], t.Stack[i]
+			}
+		}
+	}
+
+	result := dumps[:0]
+	for _, d := range dumps {
+		if len(d.Threads) > 0 {
+			result = append(result, d)
+		}
+	}
+	if len(result) == 0 {
+		return nil, ErrNoThreads
+	}
+	return result, nil
+}
+
+// parseHeader parses the header line of a thread, such as:
+//
+//	"main" #1 prio=5 os_prio=0 tid=0x00007f nid=0x1a03 waiting on condition [0x00007f]
+//
+// It returns nil for lines that are not thread headers.
+func parseHeader(line string) *Thread {
+	end := strings.LastIndex(line, `"`)
+	if end <= 0 {
+		return nil
+	}
+	t := &Thread{Name: line[1:end]}
+	for _, field := range strings.Fields(line[end+1:]) {
+		switch {
+		case field == "daemon":
+			t.Daemon = true
+		case strings.HasPrefix(field, "#"):
+			if id, err := strconv.ParseInt(field[1:], 10, 64); err == nil {
+				t.ID = id
+			}
+		case strings.HasPrefix(field, "nid="):
+			t.NID = field[len("nid="):]
+		}
+	}
+	return t
+}
+
+// parseThreadLine parses a state, frame or lock line of a thread.
+func parseThreadLine(t *Thread, line string) {
+	switch {
+	case strings.HasPrefix(line, "java.lang.Thread.State:"):
+		state := strings.TrimSpace(line[len("java.lang.Thread.State:"):])
+		if i := strings.Index(state, " ("); i >= 0 {
+			t.StateDetail = strings.TrimSuffix(state[i+2:], ")")
+			state = state[:i]
+		}
+		t.State = state
+
+	case strings.HasPrefix(line, "at "):
+		frame := line[len("at "):]
+		if i := strings.Index(frame, "("); i >= 0 {
+			frame = frame[:i]
+		}
+		t.Stack = append(t.Stack, frame)
+
+	case strings.HasPrefix(line, "- waiting to lock "),
+		strings.HasPrefix(line, "- waiting to re-lock in wait() "):
+		t.WaitingToLock = parseLock(line)
+
+	case strings.HasPrefix(line, "- parking to wait for "):
+		t.ParkingFor = parseLock(line)
+
+	case strings.HasPrefix(line, "- waiting on "):
+		t.WaitingOn = parseLock(line)
+
+	case strings.HasPrefix(line, "- locked "),
+		// Ownable synchronizers, listed after "Locked ownable synchronizers:"
+		strings.HasPrefix(line, "- <"):
+		if lock := parseLock(line); lock != nil {
+			t.Locked = append(t.Locked, *lock)
+		}
+	}
+}
+
+// parseLock parses the lock of a line such as "- waiting to lock
+// <0x000000076ab62208> (a java.lang.Object)". It returns nil if the line has no lock address, e.g. for "<no object
+// reference available>".
+func parseLock(line string) *Lock {
+	start := strings.Index(line, "<0x")
+	if start < 0 {
+		return nil
+	}
+	end := strings.Index(line[start:], ">")
+	if end < 0 {
+		return nil
+	}
+	lock := &Lock{Address: line[start+1 : start+end]}
+	rest := line[start+end+1:]
+	if i := strings.Index(rest, "(a "); i >= 0 {
+		lock.Class = strings.TrimSuffix(strings.TrimSpace(rest[i+len("(a "):]), ")")
+	}
+	return lock
+}
@@ -0,0 +1,176 @@
+package jstack
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// deadlockDump is a jstack dump of two threads deadlocked on monitors, a
+// thread waiting on a monitor and two threads parked on a ReentrantLock.
+const deadlockDump = `2024-05-01 10:00:00
+Full thread dump OpenJDK 64-Bit Server VM (17.0.2+8 mixed mode, sharing):
+
+Threads class SMR info:
+_java_thread_list=0x00007f, length=12, elements={
+0x00007f01, 0x00007f02
+}
+
+"main" #1 prio=5 os_prio=0 cpu=50.00ms elapsed=10.00s tid=0x00007f01 nid=0x1a03 in Object.wait()  [0x00007f]
+   java.lang.Thread.State: WAITING (on object monitor)
+	at java.lang.Object.wait(java.base@17.0.2/Native Method)
+	- waiting on <0x00000007000a0000> (a java.lang.Object)
+	at java.lang.Object.wait(java.base@17.0.2/Object.java:338)
+	at app.Main.main(Main.java:20)
+	- locked <0x00000007000a0000> (a java.lang.Object)
+
+   Locked ownable synchronizers:
+	- None
+
+"worker-1" #20 prio=5 os_prio=0 tid=0x00007f02 nid=0x1a10 waiting for monitor entry  [0x00007f]
+   java.lang.Thread.State: BLOCKED (on object monitor)
+	at app.Transfer.debit(Transfer.java:30)
+	- waiting to lock <0x00000007000b0000> (a app.Account)
+	- locked <0x00000007000c0000> (a app.Account)
+	at app.Transfer.run(Transfer.java:12)
+	at java.lang.Thread.run(java.base@17.0.2/Thread.java:833)
+
+"worker-2" #21 prio=5 os_prio=0 tid=0x00007f03 nid=0x1a11 waiting for monitor entry  [0x00007f]
+   java.lang.Thread.State: BLOCKED (on object monitor)
+	at app.Transfer.debit(Transfer.java:30)
+	- waiting to lock <0x00000007000c0000> (a app.Account)
+	- locked <0x00000007000b0000> (a app.Account)
+	at app.Transfer.run(Transfer.java:12)
+	at java.lang.Thread.run(java.base@17.0.2/Thread.java:833)
+
+"pool-1" #30 daemon prio=5 os_prio=0 tid=0x00007f04 nid=0x1a20 runnable  [0x00007f]
+   java.lang.Thread.State: RUNNABLE
+	at app.Cache.refresh(Cache.java:40)
+
+   Locked ownable synchronizers:
+	- <0x00000007000d0000> (a java.util.concurrent.locks.ReentrantLock$NonfairSync)
+
+"pool-2" #31 daemon prio=5 os_prio=0 tid=0x00007f05 nid=0x1a21 waiting on condition  [0x00007f]
+   java.lang.Thread.State: WAITING (parking)
+	at jdk.internal.misc.Unsafe.park(java.base@17.0.2/Native Method)
+	- parking to wait for  <0x00000007000d0000> (a java.util.concurrent.locks.ReentrantLock$NonfairSync)
+	at app.Cache.get(Cache.java:20)
+
+"pool-3" #32 daemon prio=5 os_prio=0 tid=0x00007f06 nid=0x1a22 waiting on condition  [0x00007f]
+   java.lang.Thread.State: WAITING (parking)
+	at jdk.internal.misc.Unsafe.park(java.base@17.0.2/Native Method)
+	- parking to wait for  <0x00000007000d0000> (a java.util.concurrent.locks.ReentrantLock$NonfairSync)
+	at app.Cache.get(Cache.java:20)
+
+"VM Thread" os_prio=0 cpu=5.00ms elapsed=10.00s tid=0x00007f07 nid=0x1a04 runnable
+
+JNI global refs: 15, weak refs: 0
+
+
+Found one Java-level deadlock:
+=============================
+"worker-1":
+  waiting to lock monitor 0x00007f10 (object 0x00000007000b0000, a app.Account),
+  which is held by "worker-2"
+
+Java stack information for the threads listed above:
+===================================================
+"worker-1":
+	at app.Transfer.debit(Transfer.java:30)
+	- waiting to lock <0x00000007000b0000> (a app.Account)
+
+Found 1 deadlock.
+`
+
+func parseDump(t *testing.T, text string) []*ThreadDump {
+	t.Helper()
+	dumps, err := NewParser().Parse(context.Background(), strings.NewReader(text))
+	require.NoError(t, err)
+	return dumps
+}
+
+func TestParser_Parse(t *testing.T) {
+	dumps := parseDump(t, deadlockDump)
+	require.Len(t, dumps, 1)
+
+	dump := dumps[0]
+	assert.Equal(t, "2024-05-01 10:00:00", dump.Time)
+	require.Len(t, dump.Threads, 7, "threads of the deadlock report are not repeated")
+
+	main := dump.Threads[0]
+	assert.Equal(t, "main", main.Name)
+	assert.Equal(t, int64(1), main.ID)
+	assert.Equal(t, "0x1a03", main.NID)
+	assert.False(t, main.Daemon)
+	assert.Equal(t, "WAITING", main.State)
+	assert.Equal(t, "on object monitor", main.StateDetail)
+	assert.Equal(t, []string{"app.Main.main", "java.lang.Object.wait", "java.lang.Object.wait"}, main.Stack)
+	assert.Equal(t, &Lock{Address: "0x00000007000a0000", Class: "java.lang.Object"}, main.WaitingOn)
+	assert.Equal(t, []Lock{{Address: "0x00000007000a0000", Class: "java.lang.Object"}}, main.Locked)
+
+	worker := dump.Threads[1]
+	assert.Equal(t, "BLOCKED", worker.State)
+	assert.Equal(t, &Lock{Address: "0x00000007000b0000", Class: "app.Account"}, worker.WaitingToLock)
+
+	pool1 := dump.Threads[3]
+	assert.True(t, pool1.Daemon)
+	assert.Equal(t, []Lock{{Address: "0x00000007000d0000", Class: "java.util.concurrent.locks.ReentrantLock$NonfairSync"}}, pool1.Locked)
+
+	pool2 := dump.Threads[4]
+	assert.Equal(t, "parking", pool2.StateDetail)
+	assert.Equal(t, "0x00000007000d0000", pool2.ParkingFor.Address)
+
+	vm := dump.Threads[6]
+	assert.Equal(t, "VM Thread", vm.Name)
+	assert.Equal(t, int64(0), vm.ID)
+	assert.Empty(t, vm.State)
+}
+
+func TestParser_ParseMultipleDumps(t *testing.T) {
+	text := deadlockDump + "\n2024-05-01 10:00:10\n" + strings.Replace(deadlockDump, "2024-05-01 10:00:00\n", "", 1)
+	dumps := parseDump(t, text)
+	require.Len(t, dumps, 2)
+	assert.Equal(t, "2024-05-01 10:00:10", dumps[1].Time)
+	assert.Len(t, dumps[1].Threads, 7)
+}
+
+func TestParser_ParseWithoutHeader(t *testing.T) {
+	dumps := parseDump(t, `"worker" #5 prio=5 nid=0x10 runnable
+   java.lang.Thread.State: RUNNABLE
+	at app.Worker.run(Worker.java:1)
+`)
+	require.Len(t, dumps, 1)
+	require.Len(t, dumps[0].Threads, 1)
+	assert.Equal(t, []string{"app.Worker.run"}, dumps[0].Threads[0].Stack)
+}
+
+func TestParser_ParseNoThreads(t *testing.T) {
+	_, err := NewParser().Parse(context.Background(), strings.NewReader("not a thread dump\n"))
+	assert.ErrorIs(t, err, ErrNoThreads)
+}
+
+func TestParser_ParseCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := NewParser().Parse(ctx, strings.NewReader(deadlockDump))
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func TestParseLock(t *testing.T) {
+	tests := []struct {
+		line string
+		want *Lock
+	}{
+		{"- locked <0x000000076ab62208> (a java.lang.Object)", &Lock{Address: "0x000000076ab62208", Class: "java.lang.Object"}},
+		{"- parking to wait for  <0x01> (a java.util.concurrent.locks.AbstractQueuedSynchronizer$ConditionObject)",
+			&Lock{Address: "0x01", Class: "java.util.concurrent.locks.AbstractQueuedSynchronizer$ConditionObject"}},
+		{"- waiting on <0x02>", &Lock{Address: "0x02"}},
+		{"- waiting on <no object reference available>", nil},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, parseLock(tt.line), tt.line)
+	}
+}
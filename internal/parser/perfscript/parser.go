@@ -0,0 +1,208 @@
+// Package perfscript parses the output of `perf script` into timestamped
+// samples, one per perf sample, for flame graphs of time windows.
+package perfscript
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/perf-analysis/internal/parser"
+	"github.com/perf-analysis/internal/symbol"
+	"github.com/perf-analysis/pkg/model"
+	"github.com/perf-analysis/pkg/profiling"
+)
+
+const (
+	// cancelCheckInterval is the number of lines between context checks
+	cancelCheckInterval = 10000
+
+	// detectLines is the number of lines IsPerfScript looks at
+	detectLines = 20
+)
+
+var (
+	// headerRegex matches the first line of a sample:
+	// "java 4321/4330 [002] 83367.826506: 10101010 cpu-clock:pppH:"; the
+	// TID, CPU and period are optional, and the cgroup follows the event
+	// with -F+cgroup, e.g. "cpu-clock:pppH: /docker/4f1e..."
+	headerRegex = regexp.MustCompile(`^(\S.*?)\s+(\d+)(?:/(\d+))?\s+(?:\[\d+\]\s+)?(\d+)\.(\d+):\s+(?:\d+\s+)?\S+:(?:\s+(/\S*))?`)
+
+	// frameRegex matches a frame of a sample: "    7f3a1c2b4e10 malloc+0x20 (/usr/lib64/libc.so.6)"
+	frameRegex = regexp.MustCompile(`^\s+([0-9a-fA-F]+)\s+(.*?)(?:\s+\(([^()]*)\))?$`)
+
+	// offsetRegex matches the offset of symbols, e.g. "+0x20"
+	offsetRegex = regexp.MustCompile(`\+0x[0-9a-fA-F]+$`)
+)
+
+// Parser parses the output of perf script. Samples are valued 1 and keep
+// the time of the perf sample; their call stacks are ordered from the root
+// to the leaf, frames named by their symbol.
+type Parser struct {
+	// Resolver names the frames perf could not symbolize, if set
+	Resolver *symbol.Resolver
+}
+
+// NewParser creates a new perf script parser.
+func NewParser() *Parser {
+	return &Parser{}
+}
+
+// IsPerfScript reports whether data starts like the output of perf script.
+func IsPerfScript(data []byte) bool {
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for n := 0; n < detectLines && scanner.Scan(); n++ {
+		line := scanner.Text()
+		if strings.TrimSpace(line) == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		return headerRegex.MatchString(line)
+	}
+	return false
+}
+
+// Parse parses the output of perf script.
+func (p *Parser) Parse(ctx context.Context, reader io.Reader) (*model.ParseResult, error) {
+	result := &model.ParseResult{
+		Samples:     make([]*model.Sample, 0),
+		ThreadStats: make(map[string]*model.ThreadInfo),
+		TopFuncs:    make(model.TopFuncsMap),
+	}
+
+	var sample *model.Sample
+	flush := func() {
+		if sample == nil || len(sample.CallStack) == 0 {
+			sample = nil
+			return
+		}
+		// perf prints the leaf first
+		stack := sample.CallStack
+		for i, j := 0, len(stack)-1; i < j; i, j = i+1, j-1 {
+			stack[i], stack[j] = stack[j], stack[i]
+		}
+		result.Samples = append(result.Samples, sample)
+		if !profiling.IsSwapperThread(sample.ThreadName) {
+			result.TotalSamples += sample.Value
+		}
+
+		key := strconv.Itoa(sample.TID)
+		stats := result.ThreadStats[key]
+		if stats == nil {
+			stats = &model.ThreadInfo{TID: sample.TID, ThreadName: sample.ThreadName}
+			result.ThreadStats[key] = stats
+		}
+		stats.Samples += sample.Value
+		sample = nil
+	}
+
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for n := 0; scanner.Scan(); n++ {
+		if n%cancelCheckInterval == 0 {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+		}
+
+		line := scanner.Text()
+		switch {
+		case strings.TrimSpace(line) == "":
+			flush()
+		case strings.HasPrefix(line, "#"):
+		case line[0] != ' ' && line[0] != '\t':
+			flush()
+			sample = parseHeader(line)
+		case sample != nil:
+			if frame := p.parseFrame(ctx, line); frame != "" {
+				sample.CallStack = append(sample.CallStack, frame)
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read input: %w", err)
+	}
+	flush()
+
+	if len(result.Samples) == 0 {
+		return nil, parser.ErrEmptyInput
+	}
+	for _, stats := range result.ThreadStats {
+		stats.Percentage = float64(stats.Samples) * 100 / float64(len(result.Samples))
+	}
+	return result, nil
+}
+
+// SupportedFormats returns the formats supported by this parser.
+func (p *Parser) SupportedFormats() []string {
+	return []string{"perf-script"}
+}
+
+// Name returns the name of this parser.
+func (p *Parser) Name() string {
+	return "perfscript"
+}
+
+// parseHeader parses the first line of a sample, nil if it is not one.
+func parseHeader(line string) *model.Sample {
+	m := headerRegex.FindStringSubmatch(line)
+	if m == nil {
+		return nil
+	}
+	pid, _ := strconv.Atoi(m[2])
+	tid := pid
+	if m[3] != "" {
+		tid, _ = strconv.Atoi(m[3])
+	}
+	sec, _ := strconv.ParseInt(m[4], 10, 64)
+	// The fraction of seconds is printed with 6 or 9 digits
+	frac := (m[5] + "000000000")[:9]
+	nsec, _ := strconv.ParseInt(frac, 10, 64)
+
+	return &model.Sample{
+		ThreadName: m[1],
+		TID:        tid,
+		PID:        pid,
+		Cgroup:     m[6],
+		Value:      1,
+		Timestamp:  sec*1e9 + nsec,
+	}
+}
+
+// parseFrame returns the name of the frame of a line of a sample: its
+// symbol without offset, else the symbol found by the resolver, else the
+// library in brackets.
+func (p *Parser) parseFrame(ctx context.Context, line string) string {
+	m := frameRegex.FindStringSubmatch(line)
+	if m == nil {
+		return ""
+	}
+	name := offsetRegex.ReplaceAllString(m[2], "")
+	if name != "" && name != "[unknown]" {
+		return name
+	}
+
+	// The library may end with the offset of the frame in it (-F+dsoff)
+	module := m[3]
+	frame := symbol.Frame{Module: module}
+	if offset := offsetRegex.FindString(module); offset != "" {
+		frame.Module = strings.TrimSuffix(module, offset)
+		frame.Offset, _ = strconv.ParseUint(offset[len("+0x"):], 16, 64)
+		frame.HasOffset = true
+	}
+	if p.Resolver != nil {
+		frame.Address, _ = strconv.ParseUint(m[1], 16, 64)
+		if name, ok := p.Resolver.Resolve(ctx, frame); ok {
+			return name
+		}
+	}
+	if frame.Module == "" || frame.Module == "[unknown]" {
+		return "[unknown]"
+	}
+	return "[" + filepath.Base(frame.Module) + "]"
+}
@@ -0,0 +1,106 @@
+package perfscript
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/perf-analysis/internal/parser"
+	"github.com/perf-analysis/internal/symbol"
+)
+
+const perfScript = `# ========
+# captured on    : Wed May  1 10:00:00 2024
+# ========
+#
+java 4321/4330 [002] 83367.826506:   10101010 cpu-clock:pppH:
+	    7f3a1c2b4e10 malloc+0x20 (/usr/lib64/libc.so.6)
+	    7f3a1c000123 Interpreter (/usr/lib/jvm/lib/server/libjvm.so)
+	    7f3a1c000456 [unknown] (/tmp/perf-4321.map)
+	    7f3a1c000789 start_thread+0xd9 (/usr/lib64/libpthread.so.0)
+
+java 4321/4331 [003] 83368.100000:   10101010 cpu-clock:pppH:
+	    7f3a1c2b4e10 malloc+0x20 (/usr/lib64/libc.so.6)
+	    7f3a1c000789 start_thread+0xd9 (/usr/lib64/libpthread.so.0)
+
+swapper     0 [000] 83368.500000123: cpu-clock:
+	ffffffff8100a1b2 native_safe_halt+0xe ([kernel.kallsyms])
+
+`
+
+func TestParser_Parse(t *testing.T) {
+	result, err := NewParser().Parse(context.Background(), strings.NewReader(perfScript))
+	require.NoError(t, err)
+
+	require.Len(t, result.Samples, 3)
+	assert.Equal(t, int64(2), result.TotalSamples, "swapper samples are idle")
+
+	s := result.Samples[0]
+	assert.Equal(t, "java", s.ThreadName)
+	assert.Equal(t, 4330, s.TID)
+	assert.Equal(t, 4321, s.PID)
+	assert.Empty(t, s.Cgroup)
+	assert.Equal(t, int64(1), s.Value)
+	assert.Equal(t, int64(83367826506000), s.Timestamp)
+	assert.Equal(t, []string{"start_thread", "[perf-4321.map]", "Interpreter", "malloc"}, s.CallStack)
+
+	swapper := result.Samples[2]
+	assert.Equal(t, 0, swapper.TID)
+	assert.Equal(t, int64(83368500000123), swapper.Timestamp)
+	assert.Equal(t, []string{"native_safe_halt"}, swapper.CallStack)
+
+	require.Contains(t, result.ThreadStats, "4331")
+	assert.Equal(t, int64(1), result.ThreadStats["4331"].Samples)
+}
+
+func TestParser_Resolver(t *testing.T) {
+	vmlinux := filepath.Join(t.TempDir(), "kallsyms")
+	require.NoError(t, os.WriteFile(vmlinux, []byte("ffffffff81001000 T do_syscall_64\nffffffff81002000 T ksys_read\n"), 0644))
+
+	const script = `java 4321/4330 [002] 83367.826506: cpu-clock:
+	ffffffff81002010 [unknown] ([kernel.kallsyms])
+	ffffffff81001080 [unknown] ([kernel.kallsyms])
+	    7f3a1c001150 [unknown] (/usr/lib64/libfoo.so+0x1150)
+	    7f3a1c000789 start_thread+0xd9 (/usr/lib64/libpthread.so.0)
+`
+	p := NewParser()
+	p.Resolver = symbol.NewResolver(symbol.Config{Vmlinux: vmlinux})
+	result, err := p.Parse(context.Background(), strings.NewReader(script))
+	require.NoError(t, err)
+
+	require.Len(t, result.Samples, 1)
+	assert.Equal(t, []string{"start_thread", "[libfoo.so]", "do_syscall_64", "ksys_read"}, result.Samples[0].CallStack)
+}
+
+func TestParser_Cgroup(t *testing.T) {
+	const script = `java 4321/4330 [002] 83367.826506:   10101010 cpu-clock:pppH: /docker/4f1e2a
+	    7f3a1c2b4e10 malloc+0x20 (/usr/lib64/libc.so.6)
+
+nginx 812/812 [001] 83367.900000: cpu-clock: /system.slice/nginx.service
+	    7f3a1c2b4e10 epoll_wait+0x20 (/usr/lib64/libc.so.6)
+`
+	result, err := NewParser().Parse(context.Background(), strings.NewReader(script))
+	require.NoError(t, err)
+
+	require.Len(t, result.Samples, 2)
+	assert.Equal(t, 4321, result.Samples[0].PID)
+	assert.Equal(t, "/docker/4f1e2a", result.Samples[0].Cgroup)
+	assert.Equal(t, 812, result.Samples[1].PID)
+	assert.Equal(t, "/system.slice/nginx.service", result.Samples[1].Cgroup)
+}
+
+func TestParser_Empty(t *testing.T) {
+	_, err := NewParser().Parse(context.Background(), strings.NewReader("# only comments\n"))
+	assert.ErrorIs(t, err, parser.ErrEmptyInput)
+}
+
+func TestIsPerfScript(t *testing.T) {
+	assert.True(t, IsPerfScript([]byte(perfScript)))
+	assert.False(t, IsPerfScript([]byte("java-4321/4330;start_thread;malloc 12\n")))
+	assert.False(t, IsPerfScript(nil))
+}
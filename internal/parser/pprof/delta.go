@@ -0,0 +1,83 @@
+package pprof
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/google/pprof/profile"
+)
+
+// StackGrowth is the change of the value of a call stack between two
+// profiles of the same type.
+type StackGrowth struct {
+	Stack         string // collapsed stack, root first
+	BaselineValue int64
+	CurrentValue  int64
+	Growth        int64
+}
+
+// MergeProfiles merges profiles of the same type, e.g. heap profiles of the
+// instances of a service, into one profile. Profiles of different sample
+// types cannot be merged.
+func MergeProfiles(parsers []*Parser) (*Parser, error) {
+	profiles := make([]*profile.Profile, 0, len(parsers))
+	for _, p := range parsers {
+		if p == nil || p.profile == nil {
+			return nil, fmt.Errorf("profile not loaded")
+		}
+		profiles = append(profiles, p.profile)
+	}
+	if len(profiles) == 0 {
+		return nil, fmt.Errorf("no profiles to merge")
+	}
+
+	merged, err := profile.Merge(profiles)
+	if err != nil {
+		return nil, fmt.Errorf("failed to merge profiles: %w", err)
+	}
+	return &Parser{profile: merged}, nil
+}
+
+// StackDeltas returns the change of the value of every call stack between a
+// baseline and a current profile, e.g. the inuse growth between two heap
+// profiles, largest growth first. Stacks whose value did not change are
+// omitted.
+func StackDeltas(baseline, current *Parser, sampleType SampleType) ([]StackGrowth, error) {
+	baselineCollapsed, err := baseline.ToCollapsed(sampleType)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get baseline data: %w", err)
+	}
+	currentCollapsed, err := current.ToCollapsed(sampleType)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get current data: %w", err)
+	}
+
+	deltas := make([]StackGrowth, 0, len(currentCollapsed))
+	for stack, value := range currentCollapsed {
+		if growth := value - baselineCollapsed[stack]; growth != 0 {
+			deltas = append(deltas, StackGrowth{
+				Stack:         stack,
+				BaselineValue: baselineCollapsed[stack],
+				CurrentValue:  value,
+				Growth:        growth,
+			})
+		}
+	}
+	for stack, value := range baselineCollapsed {
+		if _, ok := currentCollapsed[stack]; !ok {
+			deltas = append(deltas, StackGrowth{
+				Stack:         stack,
+				BaselineValue: value,
+				Growth:        -value,
+			})
+		}
+	}
+
+	sort.Slice(deltas, func(i, j int) bool {
+		if deltas[i].Growth != deltas[j].Growth {
+			return deltas[i].Growth > deltas[j].Growth
+		}
+		return deltas[i].Stack < deltas[j].Stack
+	})
+	return deltas, nil
+}
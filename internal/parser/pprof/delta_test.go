@@ -0,0 +1,121 @@
+package pprof
+
+import (
+	"testing"
+
+	"github.com/google/pprof/profile"
+)
+
+// createHeapProfile creates a heap profile of one sample per stack, root
+// first, of the given inuse_space values.
+func createHeapProfile(stacks [][]string, values []int64) *Parser {
+	prof := &profile.Profile{
+		SampleType: []*profile.ValueType{
+			{Type: "inuse_objects", Unit: "count"},
+			{Type: "inuse_space", Unit: "bytes"},
+		},
+		PeriodType: &profile.ValueType{Type: "space", Unit: "bytes"},
+	}
+
+	functions := make(map[string]*profile.Function)
+	locations := make(map[string]*profile.Location)
+	for i, stack := range stacks {
+		sample := &profile.Sample{Value: []int64{1, values[i]}}
+		for j := len(stack) - 1; j >= 0; j-- {
+			name := stack[j]
+			loc, ok := locations[name]
+			if !ok {
+				fn := &profile.Function{ID: uint64(len(functions) + 1), Name: name}
+				functions[name] = fn
+				prof.Function = append(prof.Function, fn)
+				loc = &profile.Location{ID: uint64(len(locations) + 1), Line: []profile.Line{{Function: fn}}}
+				locations[name] = loc
+				prof.Location = append(prof.Location, loc)
+			}
+			sample.Location = append(sample.Location, loc)
+		}
+		prof.Sample = append(prof.Sample, sample)
+	}
+	return &Parser{profile: prof}
+}
+
+func TestStackDeltas(t *testing.T) {
+	baseline := createHeapProfile(
+		[][]string{{"main", "cache.Put"}, {"main", "buf.Grow"}, {"main", "old.Alloc"}},
+		[]int64{100, 50, 30},
+	)
+	current := createHeapProfile(
+		[][]string{{"main", "cache.Put"}, {"main", "buf.Grow"}, {"main", "new.Alloc"}},
+		[]int64{400, 50, 80},
+	)
+
+	deltas, err := StackDeltas(baseline, current, SampleTypeInuseSpace)
+	if err != nil {
+		t.Fatalf("StackDeltas() error = %v", err)
+	}
+
+	want := []StackGrowth{
+		{Stack: "main;cache.Put", BaselineValue: 100, CurrentValue: 400, Growth: 300},
+		{Stack: "main;new.Alloc", CurrentValue: 80, Growth: 80},
+		{Stack: "main;old.Alloc", BaselineValue: 30, Growth: -30},
+	}
+	if len(deltas) != len(want) {
+		t.Fatalf("StackDeltas() = %+v, want %+v", deltas, want)
+	}
+	for i := range want {
+		if deltas[i] != want[i] {
+			t.Errorf("StackDeltas()[%d] = %+v, want %+v", i, deltas[i], want[i])
+		}
+	}
+}
+
+func TestStackDeltas_MissingSampleType(t *testing.T) {
+	baseline := createHeapProfile([][]string{{"main"}}, []int64{1})
+	current := createHeapProfile([][]string{{"main"}}, []int64{2})
+
+	if _, err := StackDeltas(baseline, current, SampleTypeGoroutine); err == nil {
+		t.Error("StackDeltas() with a missing sample type should return error")
+	}
+}
+
+func TestMergeProfiles(t *testing.T) {
+	a := createHeapProfile([][]string{{"main", "cache.Put"}, {"main", "buf.Grow"}}, []int64{100, 50})
+	b := createHeapProfile([][]string{{"main", "cache.Put"}}, []int64{200})
+
+	merged, err := MergeProfiles([]*Parser{a, b})
+	if err != nil {
+		t.Fatalf("MergeProfiles() error = %v", err)
+	}
+
+	if got := merged.GetTotalSamples(SampleTypeInuseSpace); got != 350 {
+		t.Errorf("GetTotalSamples(inuse_space) = %d, want 350", got)
+	}
+	collapsed, err := merged.ToCollapsed(SampleTypeInuseSpace)
+	if err != nil {
+		t.Fatalf("ToCollapsed() error = %v", err)
+	}
+	if collapsed["main;cache.Put"] != 300 {
+		t.Errorf("collapsed[main;cache.Put] = %d, want 300", collapsed["main;cache.Put"])
+	}
+}
+
+func TestMergeProfiles_DifferentTypes(t *testing.T) {
+	heap := createHeapProfile([][]string{{"main"}}, []int64{1})
+	cpu := &Parser{profile: &profile.Profile{
+		SampleType: []*profile.ValueType{{Type: "samples", Unit: "count"}, {Type: "cpu", Unit: "nanoseconds"}},
+		PeriodType: &profile.ValueType{Type: "cpu", Unit: "nanoseconds"},
+	}}
+
+	if _, err := MergeProfiles([]*Parser{heap, cpu}); err == nil {
+		t.Error("MergeProfiles() of different sample types should return error")
+	}
+}
+
+func TestMergeProfiles_NotLoaded(t *testing.T) {
+	if _, err := MergeProfiles(nil); err == nil {
+		t.Error("MergeProfiles() of no profiles should return error")
+	}
+	if _, err := MergeProfiles([]*Parser{NewParser()}); err == nil {
+		t.Error("MergeProfiles() of an unloaded profile should return error")
+	}
+}
@@ -0,0 +1,144 @@
+package pprof
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/google/pprof/profile"
+
+	"github.com/perf-analysis/pkg/model"
+	"github.com/perf-analysis/pkg/profiling"
+)
+
+// Label keys of the samples of exported profiles.
+const (
+	LabelThread = "thread"
+	LabelTID    = "tid"
+)
+
+// JavaFrame is a frame of an async-profiler collapsed stack.
+type JavaFrame struct {
+	// Function is the frame name without its line and type suffix, e.g.
+	// com/example/App.main
+	Function string
+	// File is the source file of Java methods, e.g. com/example/App.java
+	File string
+	// Line is the line number of frames collected with --lines, else 0
+	Line int64
+	// Inlined frames were inlined by the JIT into their caller
+	Inlined bool
+}
+
+// ParseJavaFrame parses a frame of an async-profiler collapsed stack, e.g.
+// "com/example/App.main:42_[j]".
+func ParseJavaFrame(name string) JavaFrame {
+	function, typ, annotated := profiling.SplitFrameType(name)
+	frame := JavaFrame{Function: function, Inlined: typ == profiling.FrameInlined}
+	if i := strings.LastIndexByte(frame.Function, ':'); i > 0 && frame.Function[i-1] != ':' {
+		if line, err := strconv.ParseInt(frame.Function[i+1:], 10, 64); err == nil && line > 0 {
+			frame.Function, frame.Line = frame.Function[:i], line
+		}
+	}
+
+	java := typ != profiling.FrameKernel
+	if !annotated {
+		java = profiling.IsJavaMethod(frame.Function)
+	}
+	if dot := strings.LastIndexByte(frame.Function, '.'); java && dot > 0 {
+		class := frame.Function[:dot]
+		if i := strings.IndexByte(class, '$'); i > 0 {
+			class = class[:i]
+		}
+		frame.File = strings.ReplaceAll(class, ".", "/") + ".java"
+	}
+	return frame
+}
+
+// FromSamples builds a pprof profile from call stacks, e.g. the collapsed
+// Java stacks of async-profiler, so they can be read by go tool pprof. The
+// sample values have the given type and unit, e.g. samples and count, and
+// samples are labeled with their thread. Frames inlined by the JIT are
+// lines of the location of their caller.
+func FromSamples(samples []*model.Sample, sampleType, unit string) *profile.Profile {
+	valueType := &profile.ValueType{Type: sampleType, Unit: unit}
+	p := &profile.Profile{
+		SampleType: []*profile.ValueType{valueType},
+		PeriodType: valueType,
+		Period:     1,
+	}
+
+	type functionKey struct{ name, file string }
+	functions := make(map[functionKey]*profile.Function)
+	locations := make(map[string]*profile.Location)
+	function := func(frame JavaFrame) *profile.Function {
+		key := functionKey{frame.Function, frame.File}
+		fn := functions[key]
+		if fn == nil {
+			fn = &profile.Function{
+				ID:         uint64(len(p.Function) + 1),
+				Name:       frame.Function,
+				SystemName: frame.Function,
+				Filename:   frame.File,
+			}
+			functions[key] = fn
+			p.Function = append(p.Function, fn)
+		}
+		return fn
+	}
+
+	var start, end int64
+	for _, s := range samples {
+		if len(s.CallStack) == 0 || s.Value == 0 {
+			continue
+		}
+		if s.Timestamp > 0 {
+			if start == 0 || s.Timestamp < start {
+				start = s.Timestamp
+			}
+			end = max(end, s.Timestamp)
+		}
+
+		// Group the frames of the stack, root first, into locations: a
+		// frame and the frames inlined into it
+		var groups [][]string
+		for _, name := range s.CallStack {
+			if len(groups) > 0 && ParseJavaFrame(name).Inlined {
+				groups[len(groups)-1] = append(groups[len(groups)-1], name)
+			} else {
+				groups = append(groups, []string{name})
+			}
+		}
+
+		sample := &profile.Sample{
+			Location: make([]*profile.Location, 0, len(groups)),
+			Value:    []int64{s.Value},
+		}
+		// Locations of samples are leaf first
+		for i := len(groups) - 1; i >= 0; i-- {
+			key := strings.Join(groups[i], ";")
+			loc := locations[key]
+			if loc == nil {
+				loc = &profile.Location{ID: uint64(len(p.Location) + 1)}
+				// Lines of locations are leaf first too
+				for j := len(groups[i]) - 1; j >= 0; j-- {
+					frame := ParseJavaFrame(groups[i][j])
+					loc.Line = append(loc.Line, profile.Line{Function: function(frame), Line: frame.Line})
+				}
+				locations[key] = loc
+				p.Location = append(p.Location, loc)
+			}
+			sample.Location = append(sample.Location, loc)
+		}
+		if s.ThreadName != "" {
+			sample.Label = map[string][]string{LabelThread: {s.ThreadName}}
+		}
+		if s.TID > 0 {
+			sample.NumLabel = map[string][]int64{LabelTID: {int64(s.TID)}}
+		}
+		p.Sample = append(p.Sample, sample)
+	}
+
+	// Timestamps may be relative to boot, only their range is kept
+	p.DurationNanos = end - start
+	return p
+}
@@ -0,0 +1,108 @@
+package pprof
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/perf-analysis/pkg/model"
+)
+
+func TestParseJavaFrame(t *testing.T) {
+	tests := []struct {
+		name string
+		want JavaFrame
+	}{
+		{"com/example/App.main_[j]", JavaFrame{Function: "com/example/App.main", File: "com/example/App.java"}},
+		{"com/example/App.main:42_[j]", JavaFrame{Function: "com/example/App.main", File: "com/example/App.java", Line: 42}},
+		{"com/example/App$Inner.run_[i]", JavaFrame{Function: "com/example/App$Inner.run", File: "com/example/App.java", Inlined: true}},
+		{"java/lang/Thread.run", JavaFrame{Function: "java/lang/Thread.run", File: "java/lang/Thread.java"}},
+		{"Interpreter_[0]", JavaFrame{Function: "Interpreter"}},
+		{"do_syscall_64_[k]", JavaFrame{Function: "do_syscall_64"}},
+		{"malloc", JavaFrame{Function: "malloc"}},
+		{"JavaThread::run", JavaFrame{Function: "JavaThread::run"}},
+		{"[unknown]", JavaFrame{Function: "[unknown]"}},
+	}
+	for _, tt := range tests {
+		if got := ParseJavaFrame(tt.name); got != tt.want {
+			t.Errorf("ParseJavaFrame(%q) = %+v, want %+v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestFromSamples(t *testing.T) {
+	samples := []*model.Sample{
+		{ThreadName: "main", TID: 7, Value: 30, Timestamp: 1000,
+			CallStack: []string{"java/lang/Thread.run_[j]", "com/example/App.main:42_[j]", "com/example/App.parse_[i]"}},
+		{ThreadName: "main", TID: 7, Value: 10, Timestamp: 3000,
+			CallStack: []string{"java/lang/Thread.run_[j]", "malloc"}},
+		{ThreadName: "idle", Value: 5, CallStack: nil},
+	}
+
+	p := FromSamples(samples, "samples", "count")
+	if err := p.CheckValid(); err != nil {
+		t.Fatalf("CheckValid() error = %v", err)
+	}
+	if len(p.Sample) != 2 {
+		t.Fatalf("len(Sample) = %d, want 2", len(p.Sample))
+	}
+	if got := p.SampleType[0]; got.Type != "samples" || got.Unit != "count" {
+		t.Errorf("SampleType = %+v, want samples/count", got)
+	}
+	if p.DurationNanos != 2000 {
+		t.Errorf("DurationNanos = %d, want 2000", p.DurationNanos)
+	}
+
+	// Locations are shared between samples and leaf first
+	first, second := p.Sample[0], p.Sample[1]
+	if len(first.Location) != 2 || len(second.Location) != 2 {
+		t.Fatalf("locations = %d, %d, want 2, 2", len(first.Location), len(second.Location))
+	}
+	if first.Location[1] != second.Location[1] {
+		t.Error("Thread.run should be a single location")
+	}
+	if got := second.Location[0].Line[0].Function.Name; got != "malloc" {
+		t.Errorf("leaf of the second sample = %s, want malloc", got)
+	}
+	if got := first.Label[LabelThread]; len(got) != 1 || got[0] != "main" {
+		t.Errorf("thread label = %v, want main", got)
+	}
+	if got := first.NumLabel[LabelTID]; len(got) != 1 || got[0] != 7 {
+		t.Errorf("tid label = %v, want 7", got)
+	}
+
+	// The inlined frame is the first line of the location of its caller
+	lines := first.Location[0].Line
+	if len(lines) != 2 || lines[0].Function.Name != "com/example/App.parse" || lines[1].Function.Name != "com/example/App.main" {
+		t.Fatalf("inlined location lines = %+v", lines)
+	}
+	if lines[1].Line != 42 || lines[1].Function.Filename != "com/example/App.java" {
+		t.Errorf("caller line = %d in %s, want 42 in com/example/App.java", lines[1].Line, lines[1].Function.Filename)
+	}
+	if len(p.Function) != 4 {
+		t.Errorf("len(Function) = %d, want 4", len(p.Function))
+	}
+}
+
+func TestFromSamples_RoundTrip(t *testing.T) {
+	samples := []*model.Sample{
+		{Value: 3, CallStack: []string{"main", "work"}},
+		{Value: 2, CallStack: []string{"main"}},
+	}
+
+	var buf bytes.Buffer
+	if err := FromSamples(samples, "cpu", "nanoseconds").Write(&buf); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	parser := NewParser()
+	if err := parser.Parse(&buf); err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+
+	collapsed, err := parser.ToCollapsed(SampleTypeCPU)
+	if err != nil {
+		t.Fatalf("ToCollapsed() error = %v", err)
+	}
+	if collapsed["main;work"] != 3 || collapsed["main"] != 2 || len(collapsed) != 2 {
+		t.Errorf("ToCollapsed() = %v", collapsed)
+	}
+}
@@ -0,0 +1,174 @@
+package pprof
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// creationFrames is the number of root frames of a goroutine stack, from
+// its start function, reported as its creation stack.
+const creationFrames = 3
+
+// maxLeakClusters is the number of growing goroutine clusters reported.
+const maxLeakClusters = 20
+
+// GoroutineCluster is a set of goroutines created at the same place and
+// blocked at the same point, tracked across goroutine profiles.
+type GoroutineCluster struct {
+	CreatedBy     string   `json:"created_by"`          // start function of the goroutines
+	CreationStack []string `json:"creation_stack"`      // root frames of SampleStack from the start function
+	BlockingPoint string   `json:"blocking_point"`      // innermost frame outside the runtime
+	WaitCall      string   `json:"wait_call,omitempty"` // runtime function the goroutines wait in
+	SampleStack   string   `json:"sample_stack"`        // most common stack, root first
+	Counts        []int64  `json:"counts"`              // goroutines in each profile
+	CountDelta    int64    `json:"count_delta"`         // from the first to the last profile
+	Increasing    bool     `json:"increasing"`          // counts never decrease and grow overall
+}
+
+// goroutineClusterKey identifies the goroutine clusters.
+type goroutineClusterKey struct {
+	start    string
+	blocking string
+	wait     string
+}
+
+// GoroutineClusters clusters the goroutine stacks of all profiles by where
+// the goroutines were created, i.e. their start function, and where they
+// are blocked, largest growth first. Stacks of a cluster may differ in the
+// frames between these, e.g. for retries.
+func (d *LeakDetector) GoroutineClusters() ([]GoroutineCluster, error) {
+	if len(d.profiles) < 2 {
+		return nil, fmt.Errorf("at least 2 profiles required for leak detection, got %d", len(d.profiles))
+	}
+
+	clusters := make(map[goroutineClusterKey]*GoroutineCluster)
+	// The stacks of each cluster in the latest profile having any, and
+	// their goroutines
+	stacks := make(map[goroutineClusterKey]map[string]int64)
+	stacksProfile := make(map[goroutineClusterKey]int)
+	for i, parser := range d.profiles {
+		collapsed, err := parser.ToCollapsed(SampleTypeGoroutine)
+		if err != nil {
+			collapsed, err = parser.ToCollapsed(SampleTypeSamples)
+			if err != nil {
+				return nil, fmt.Errorf("failed to get goroutine data: %w", err)
+			}
+		}
+
+		for stack, count := range collapsed {
+			frames := strings.Split(stack, ";")
+			creation := goroutineCreationStack(frames)
+			blocking, wait := goroutineBlockingPoint(frames)
+			key := goroutineClusterKey{blocking: blocking, wait: wait}
+			if len(creation) > 0 {
+				key.start = creation[0]
+			}
+
+			c, ok := clusters[key]
+			if !ok {
+				c = &GoroutineCluster{
+					CreatedBy:     key.start,
+					BlockingPoint: blocking,
+					WaitCall:      wait,
+					Counts:        make([]int64, len(d.profiles)),
+				}
+				clusters[key] = c
+			}
+			c.Counts[i] += count
+
+			if p, ok := stacksProfile[key]; !ok || p < i {
+				stacks[key] = make(map[string]int64)
+				stacksProfile[key] = i
+			}
+			stacks[key][stack] += count
+		}
+	}
+
+	result := make([]GoroutineCluster, 0, len(clusters))
+	for key, c := range clusters {
+		var best int64
+		for stack, count := range stacks[key] {
+			if count > best || (count == best && stack < c.SampleStack) {
+				c.SampleStack, best = stack, count
+			}
+		}
+		c.CreationStack = goroutineCreationStack(strings.Split(c.SampleStack, ";"))
+		c.CountDelta = c.Counts[len(c.Counts)-1] - c.Counts[0]
+		c.Increasing = c.CountDelta > 0
+		for i := 1; i < len(c.Counts); i++ {
+			if c.Counts[i] < c.Counts[i-1] {
+				c.Increasing = false
+			}
+		}
+		result = append(result, *c)
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].CountDelta != result[j].CountDelta {
+			return result[i].CountDelta > result[j].CountDelta
+		}
+		if result[i].CreatedBy != result[j].CreatedBy {
+			return result[i].CreatedBy < result[j].CreatedBy
+		}
+		return result[i].BlockingPoint < result[j].BlockingPoint
+	})
+	return result, nil
+}
+
+// leakClusters returns the clusters whose goroutine counts increase
+// monotonically, largest growth first.
+func (d *LeakDetector) leakClusters() []GoroutineCluster {
+	clusters, err := d.GoroutineClusters()
+	if err != nil {
+		return nil
+	}
+	var result []GoroutineCluster
+	for _, c := range clusters {
+		if c.Increasing {
+			result = append(result, c)
+		}
+		if len(result) == maxLeakClusters {
+			break
+		}
+	}
+	return result
+}
+
+// goroutineCreationStack returns the root frames of a goroutine stack, root
+// first, from its start function.
+func goroutineCreationStack(frames []string) []string {
+	start := 0
+	for start < len(frames) && frames[start] == "runtime.goexit" {
+		start++
+	}
+	end := min(start+creationFrames, len(frames))
+	return frames[start:end]
+}
+
+// goroutineBlockingPoint returns the innermost frame of a goroutine stack,
+// root first, outside the runtime and sync packages, and the runtime
+// function it calls to wait, if any.
+func goroutineBlockingPoint(frames []string) (blocking, wait string) {
+	for i := len(frames) - 1; i >= 0; i-- {
+		if !isRuntimeFrame(frames[i]) {
+			if i+1 < len(frames) {
+				wait = frames[i+1]
+			}
+			return frames[i], wait
+		}
+	}
+	if len(frames) == 0 {
+		return "", ""
+	}
+	return frames[len(frames)-1], ""
+}
+
+// isRuntimeFrame reports whether a function is part of the Go runtime or of
+// the standard synchronization primitives goroutines block in.
+func isRuntimeFrame(funcName string) bool {
+	return strings.HasPrefix(funcName, "runtime.") ||
+		strings.HasPrefix(funcName, "internal/") ||
+		strings.HasPrefix(funcName, "sync.") ||
+		strings.HasPrefix(funcName, "sync/atomic.")
+}
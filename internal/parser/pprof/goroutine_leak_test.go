@@ -0,0 +1,153 @@
+package pprof
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/pprof/profile"
+)
+
+// createGoroutineProfile creates a goroutine profile of one sample per
+// stack, root first, of the given goroutine counts.
+func createGoroutineProfile(stacks [][]string, counts []int64) *Parser {
+	prof := &profile.Profile{
+		SampleType: []*profile.ValueType{{Type: "goroutine", Unit: "count"}},
+		PeriodType: &profile.ValueType{Type: "goroutine", Unit: "count"},
+	}
+
+	locations := make(map[string]*profile.Location)
+	for i, stack := range stacks {
+		sample := &profile.Sample{Value: []int64{counts[i]}}
+		for j := len(stack) - 1; j >= 0; j-- {
+			loc, ok := locations[stack[j]]
+			if !ok {
+				fn := &profile.Function{ID: uint64(len(prof.Function) + 1), Name: stack[j]}
+				prof.Function = append(prof.Function, fn)
+				loc = &profile.Location{ID: uint64(len(prof.Location) + 1), Line: []profile.Line{{Function: fn}}}
+				prof.Location = append(prof.Location, loc)
+				locations[stack[j]] = loc
+			}
+			sample.Location = append(sample.Location, loc)
+		}
+		prof.Sample = append(prof.Sample, sample)
+	}
+	return &Parser{profile: prof}
+}
+
+var (
+	leakingStack = []string{"runtime.goexit", "main.startWorkers.gowrap1", "main.worker", "main.waitJob", "runtime.chanrecv1", "runtime.gopark"}
+	// leakingStackDeeper has the creation and blocking point of leakingStack
+	leakingStackDeeper = []string{"runtime.goexit", "main.startWorkers.gowrap1", "main.worker", "main.retry", "main.waitJob", "runtime.chanrecv1", "runtime.gopark"}
+	serverStack        = []string{"runtime.goexit", "main.main", "net/http.(*Server).ListenAndServe", "net/http.(*Server).Serve", "net.(*TCPListener).Accept", "internal/poll.(*FD).Accept", "runtime.gopark"}
+	poolStack          = []string{"runtime.goexit", "main.pool.gowrap2", "main.(*pool).run", "sync.(*WaitGroup).Wait", "runtime.semacquire"}
+)
+
+func TestLeakDetector_GoroutineClusters(t *testing.T) {
+	d := NewLeakDetector()
+	start := time.Now()
+	d.AddParsedProfile(createGoroutineProfile(
+		[][]string{leakingStack, serverStack, poolStack},
+		[]int64{10, 1, 8},
+	), start)
+	d.AddParsedProfile(createGoroutineProfile(
+		[][]string{leakingStack, leakingStackDeeper, serverStack, poolStack},
+		[]int64{50, 20, 1, 2},
+	), start.Add(time.Minute))
+	d.AddParsedProfile(createGoroutineProfile(
+		[][]string{leakingStack, leakingStackDeeper, serverStack, poolStack},
+		[]int64{90, 40, 1, 12},
+	), start.Add(2*time.Minute))
+
+	clusters, err := d.GoroutineClusters()
+	if err != nil {
+		t.Fatalf("GoroutineClusters() error = %v", err)
+	}
+	if len(clusters) != 3 {
+		t.Fatalf("GoroutineClusters() returned %d clusters, want 3: %+v", len(clusters), clusters)
+	}
+
+	leak := clusters[0]
+	if leak.CreatedBy != "main.startWorkers.gowrap1" {
+		t.Errorf("CreatedBy = %q, want main.startWorkers.gowrap1", leak.CreatedBy)
+	}
+	if leak.BlockingPoint != "main.waitJob" || leak.WaitCall != "runtime.chanrecv1" {
+		t.Errorf("blocking point = %q in %q, want main.waitJob in runtime.chanrecv1", leak.BlockingPoint, leak.WaitCall)
+	}
+	wantCounts := []int64{10, 70, 130}
+	for i, want := range wantCounts {
+		if leak.Counts[i] != want {
+			t.Errorf("Counts = %v, want %v", leak.Counts, wantCounts)
+			break
+		}
+	}
+	if leak.CountDelta != 120 || !leak.Increasing {
+		t.Errorf("CountDelta = %d, Increasing = %v, want 120, true", leak.CountDelta, leak.Increasing)
+	}
+	if leak.SampleStack != "runtime.goexit;main.startWorkers.gowrap1;main.worker;main.waitJob;runtime.chanrecv1;runtime.gopark" {
+		t.Errorf("SampleStack = %q, want the most common stack of the latest profile", leak.SampleStack)
+	}
+
+	// The pool grew overall, but not monotonically
+	pool := clusters[1]
+	if pool.BlockingPoint != "main.(*pool).run" || pool.WaitCall != "sync.(*WaitGroup).Wait" {
+		t.Errorf("pool blocking point = %q in %q", pool.BlockingPoint, pool.WaitCall)
+	}
+	if pool.CountDelta != 4 || pool.Increasing {
+		t.Errorf("pool CountDelta = %d, Increasing = %v, want 4, false", pool.CountDelta, pool.Increasing)
+	}
+
+	server := clusters[2]
+	if server.CountDelta != 0 || server.Increasing {
+		t.Errorf("server CountDelta = %d, Increasing = %v, want 0, false", server.CountDelta, server.Increasing)
+	}
+}
+
+func TestLeakDetector_DetectGoroutineLeak_Clusters(t *testing.T) {
+	d := NewLeakDetector()
+	start := time.Now()
+	d.AddParsedProfile(createGoroutineProfile([][]string{leakingStack, serverStack}, []int64{10, 1}), start)
+	d.AddParsedProfile(createGoroutineProfile([][]string{leakingStack, serverStack}, []int64{500, 1}), start.Add(time.Minute))
+
+	report, err := d.DetectGoroutineLeak()
+	if err != nil {
+		t.Fatalf("DetectGoroutineLeak() error = %v", err)
+	}
+	if len(report.LeakClusters) != 1 {
+		t.Fatalf("LeakClusters = %+v, want the leaking cluster only", report.LeakClusters)
+	}
+	if report.LeakClusters[0].CountDelta != 490 {
+		t.Errorf("CountDelta = %d, want 490", report.LeakClusters[0].CountDelta)
+	}
+	if len(report.Recommendations) == 0 || report.Recommendations[0] !=
+		"Goroutines created by 'main.startWorkers.gowrap1' keep piling up blocked at 'main.waitJob' (+490); make sure they can exit" {
+		t.Errorf("Recommendations = %v, want the leaking cluster first", report.Recommendations)
+	}
+}
+
+func TestLeakDetector_GoroutineClusters_InsufficientProfiles(t *testing.T) {
+	d := NewLeakDetector()
+	d.AddParsedProfile(createGoroutineProfile([][]string{leakingStack}, []int64{1}), time.Now())
+	if _, err := d.GoroutineClusters(); err == nil {
+		t.Error("GoroutineClusters() with one profile should return error")
+	}
+}
+
+func TestGoroutineBlockingPoint(t *testing.T) {
+	tests := []struct {
+		frames       []string
+		wantBlocking string
+		wantWait     string
+	}{
+		{leakingStack, "main.waitJob", "runtime.chanrecv1"},
+		{serverStack, "net.(*TCPListener).Accept", "internal/poll.(*FD).Accept"},
+		{[]string{"runtime.goexit", "main.spin"}, "main.spin", ""},
+		{[]string{"runtime.goexit", "runtime.gcBgMarkWorker", "runtime.gopark"}, "runtime.gopark", ""},
+		{nil, "", ""},
+	}
+	for _, tt := range tests {
+		blocking, wait := goroutineBlockingPoint(tt.frames)
+		if blocking != tt.wantBlocking || wait != tt.wantWait {
+			t.Errorf("goroutineBlockingPoint(%v) = %q, %q, want %q, %q", tt.frames, blocking, wait, tt.wantBlocking, tt.wantWait)
+		}
+	}
+}
@@ -47,6 +47,10 @@ type LeakReport struct {
 	Conclusion       string       `json:"conclusion"`
 	Severity         string       `json:"severity"` // "none", "low", "medium", "high", "critical"
 	Recommendations  []string     `json:"recommendations,omitempty"`
+
+	// LeakClusters are the goroutine clusters whose counts increase
+	// monotonically across the profiles, of goroutine leak reports.
+	LeakClusters []GoroutineCluster `json:"leak_clusters,omitempty"`
 }
 
 // LeakDetector detects memory and goroutine leaks by comparing multiple profile snapshots.
@@ -140,7 +144,19 @@ func (d *LeakDetector) DetectGoroutineLeak() (*LeakReport, error) {
 		}
 	}
 
-	return d.compareProfiles(LeakTypeGoroutine, baselineCollapsed, currentCollapsed, baselineTime, currentTime)
+	report, err := d.compareProfiles(LeakTypeGoroutine, baselineCollapsed, currentCollapsed, baselineTime, currentTime)
+	if err != nil {
+		return nil, err
+	}
+
+	report.LeakClusters = d.leakClusters()
+	if len(report.LeakClusters) > 0 && report.Severity != "none" {
+		top := report.LeakClusters[0]
+		report.Recommendations = append([]string{fmt.Sprintf(
+			"Goroutines created by '%s' keep piling up blocked at '%s' (+%d); make sure they can exit",
+			top.CreatedBy, top.BlockingPoint, top.CountDelta)}, report.Recommendations...)
+	}
+	return report, nil
 }
 
 // compareProfiles compares two profile snapshots and generates a leak report.
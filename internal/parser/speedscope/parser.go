@@ -0,0 +1,294 @@
+// Package speedscope reads and describes the speedscope file format
+// (https://www.speedscope.app/file-format-schema.json), to import profiles
+// exported for or by speedscope.
+package speedscope
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+
+	"github.com/perf-analysis/internal/parser"
+	"github.com/perf-analysis/pkg/model"
+)
+
+// Schema is the $schema of speedscope files.
+const Schema = "https://www.speedscope.app/file-format-schema.json"
+
+// detectSize is the size of the start of the input IsSpeedscope looks at.
+const detectSize = 4096
+
+// Profile types.
+const (
+	ProfileTypeSampled = "sampled"
+	ProfileTypeEvented = "evented"
+)
+
+// Event types of evented profiles: opening and closing a frame.
+const (
+	EventOpen  = "O"
+	EventClose = "C"
+)
+
+// File is a speedscope file.
+type File struct {
+	Schema             string     `json:"$schema"`
+	Shared             Shared     `json:"shared"`
+	Profiles           []*Profile `json:"profiles"`
+	Name               string     `json:"name,omitempty"`
+	ActiveProfileIndex int        `json:"activeProfileIndex,omitempty"`
+	Exporter           string     `json:"exporter,omitempty"`
+}
+
+// Shared holds the frames shared by the profiles of a file.
+type Shared struct {
+	Frames []*Frame `json:"frames"`
+}
+
+// Frame is a function of a speedscope file.
+type Frame struct {
+	Name string `json:"name"`
+	File string `json:"file,omitempty"`
+	Line int    `json:"line,omitempty"`
+	Col  int    `json:"col,omitempty"`
+}
+
+// Profile is a sampled or an evented profile of a speedscope file, usually
+// of one thread.
+type Profile struct {
+	Type       string  `json:"type"`
+	Name       string  `json:"name"`
+	Unit       string  `json:"unit"`
+	StartValue float64 `json:"startValue"`
+	EndValue   float64 `json:"endValue"`
+
+	// Samples of sampled profiles: stacks of frame indexes from the root to
+	// the leaf, with their weights
+	Samples [][]int   `json:"samples,omitempty"`
+	Weights []float64 `json:"weights,omitempty"`
+
+	// Events of evented profiles
+	Events []*Event `json:"events,omitempty"`
+}
+
+// Event opens or closes a frame of an evented profile.
+type Event struct {
+	Type  string  `json:"type"`
+	Frame int     `json:"frame"`
+	At    float64 `json:"at"`
+}
+
+// unitScales converts the time units of speedscope to nanoseconds.
+var unitScales = map[string]float64{
+	"nanoseconds":  1,
+	"microseconds": 1e3,
+	"milliseconds": 1e6,
+	"seconds":      1e9,
+}
+
+// Parser parses speedscope files. Each profile gives samples of a thread
+// named after the profile, unless the file has a single profile: its
+// samples have no thread, so exports of flame graphs import back as they
+// were. Time values are converted to nanoseconds.
+type Parser struct {
+	// Unit is the unit of the values of the last parsed file: "ns" for
+	// times, "bytes", or empty for sample counts
+	Unit string
+}
+
+// NewParser creates a new speedscope parser.
+func NewParser() *Parser {
+	return &Parser{}
+}
+
+// IsSpeedscope reports whether data starts like a speedscope file.
+func IsSpeedscope(data []byte) bool {
+	if len(data) > detectSize {
+		data = data[:detectSize]
+	}
+	data = bytes.TrimSpace(data)
+	return len(data) > 0 && data[0] == '{' && bytes.Contains(data, []byte("speedscope.app/file-format-schema.json"))
+}
+
+// Parse parses a speedscope file.
+func (p *Parser) Parse(ctx context.Context, reader io.Reader) (*model.ParseResult, error) {
+	var file File
+	if err := json.NewDecoder(reader).Decode(&file); err != nil {
+		return nil, fmt.Errorf("%w: %v", parser.ErrInvalidFormat, err)
+	}
+
+	result := &model.ParseResult{
+		Samples:     make([]*model.Sample, 0),
+		ThreadStats: make(map[string]*model.ThreadInfo),
+		TopFuncs:    make(model.TopFuncsMap),
+	}
+	p.Unit = ""
+	unitSet := false
+	for i, profile := range file.Profiles {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		unit, scale := profileUnit(profile.Unit)
+		if unitSet && unit != p.Unit {
+			return nil, fmt.Errorf("%w: profiles with units %q and %q", parser.ErrInvalidFormat, p.Unit, unit)
+		}
+		p.Unit, unitSet = unit, true
+
+		thread := ""
+		if len(file.Profiles) > 1 {
+			thread = profile.Name
+			if thread == "" {
+				thread = fmt.Sprintf("profile-%d", i)
+			}
+		}
+
+		var samples []*model.Sample
+		var err error
+		switch profile.Type {
+		case ProfileTypeSampled:
+			samples, err = sampledSamples(profile, file.Shared.Frames, scale)
+		case ProfileTypeEvented:
+			samples, err = eventedSamples(profile, file.Shared.Frames, scale)
+		default:
+			err = fmt.Errorf("%w: unknown profile type %q", parser.ErrInvalidFormat, profile.Type)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("profile %q: %w", profile.Name, err)
+		}
+
+		for _, s := range samples {
+			s.ThreadName = thread
+			s.TID = i + 1
+			result.Samples = append(result.Samples, s)
+			result.TotalSamples += s.Value
+
+			key := strconv.Itoa(s.TID)
+			stats := result.ThreadStats[key]
+			if stats == nil {
+				stats = &model.ThreadInfo{TID: s.TID, ThreadName: thread}
+				result.ThreadStats[key] = stats
+			}
+			stats.Samples += s.Value
+		}
+	}
+
+	if result.TotalSamples == 0 {
+		return nil, parser.ErrEmptyInput
+	}
+	for _, stats := range result.ThreadStats {
+		stats.Percentage = float64(stats.Samples) * 100 / float64(result.TotalSamples)
+	}
+	return result, nil
+}
+
+// SupportedFormats returns the formats supported by this parser.
+func (p *Parser) SupportedFormats() []string {
+	return []string{"speedscope"}
+}
+
+// Name returns the name of this parser.
+func (p *Parser) Name() string {
+	return "speedscope"
+}
+
+// profileUnit returns the unit of the values of a profile and the factor
+// converting them to it.
+func profileUnit(unit string) (string, float64) {
+	if scale, ok := unitScales[unit]; ok {
+		return "ns", scale
+	}
+	if unit == "bytes" {
+		return "bytes", 1
+	}
+	return "", 1
+}
+
+// stack returns the names of frames by index.
+func stack(indexes []int, frames []*Frame) ([]string, error) {
+	names := make([]string, len(indexes))
+	for i, index := range indexes {
+		if index < 0 || index >= len(frames) {
+			return nil, fmt.Errorf("%w: frame index %d out of range", parser.ErrInvalidStackFrame, index)
+		}
+		names[i] = frames[index].Name
+	}
+	return names, nil
+}
+
+// sampledSamples returns the samples of a sampled profile. Samples without
+// weights weigh 1.
+func sampledSamples(profile *Profile, frames []*Frame, scale float64) ([]*model.Sample, error) {
+	if profile.Weights != nil && len(profile.Weights) != len(profile.Samples) {
+		return nil, fmt.Errorf("%w: %d samples but %d weights", parser.ErrInvalidFormat, len(profile.Samples), len(profile.Weights))
+	}
+	samples := make([]*model.Sample, 0, len(profile.Samples))
+	for i, indexes := range profile.Samples {
+		if len(indexes) == 0 {
+			continue
+		}
+		callStack, err := stack(indexes, frames)
+		if err != nil {
+			return nil, err
+		}
+		weight := 1.0
+		if profile.Weights != nil {
+			weight = profile.Weights[i]
+		}
+		if value := int64(weight * scale); value > 0 {
+			samples = append(samples, &model.Sample{CallStack: callStack, Value: value})
+		}
+	}
+	return samples, nil
+}
+
+// eventedSamples returns the samples of an evented profile: the time spent
+// in each stack between two events.
+func eventedSamples(profile *Profile, frames []*Frame, scale float64) ([]*model.Sample, error) {
+	events := append([]*Event(nil), profile.Events...)
+	sort.SliceStable(events, func(i, j int) bool { return events[i].At < events[j].At })
+
+	values := make(map[string]int64)
+	stacks := make(map[string][]string)
+	var order []string
+	var open []int
+	last := profile.StartValue
+	for _, e := range events {
+		if len(open) > 0 && e.At > last {
+			callStack, err := stack(open, frames)
+			if err != nil {
+				return nil, err
+			}
+			key := fmt.Sprint(open)
+			if _, ok := stacks[key]; !ok {
+				stacks[key] = callStack
+				order = append(order, key)
+			}
+			values[key] += int64((e.At - last) * scale)
+		}
+		last = e.At
+
+		switch e.Type {
+		case EventOpen:
+			open = append(open, e.Frame)
+		case EventClose:
+			if len(open) == 0 || open[len(open)-1] != e.Frame {
+				return nil, fmt.Errorf("%w: frame %d closed at %v is not the innermost open frame", parser.ErrInvalidFormat, e.Frame, e.At)
+			}
+			open = open[:len(open)-1]
+		default:
+			return nil, fmt.Errorf("%w: unknown event type %q", parser.ErrInvalidFormat, e.Type)
+		}
+	}
+
+	samples := make([]*model.Sample, 0, len(order))
+	for _, key := range order {
+		if values[key] > 0 {
+			samples = append(samples, &model.Sample{CallStack: stacks[key], Value: values[key]})
+		}
+	}
+	return samples, nil
+}
@@ -0,0 +1,130 @@
+package speedscope
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/perf-analysis/internal/parser"
+)
+
+const sampledInput = `{
+  "$schema": "https://www.speedscope.app/file-format-schema.json",
+  "shared": {"frames": [{"name": "main"}, {"name": "parse"}, {"name": "write", "file": "io.go", "line": 12}]},
+  "profiles": [{
+    "type": "sampled", "name": "app", "unit": "milliseconds", "startValue": 0, "endValue": 5,
+    "samples": [[0, 1], [0, 2], [0, 1], []],
+    "weights": [2, 1.5, 1, 3]
+  }]
+}`
+
+func TestIsSpeedscope(t *testing.T) {
+	assert.True(t, IsSpeedscope([]byte(sampledInput)))
+	assert.True(t, IsSpeedscope([]byte("\n  "+sampledInput)))
+	assert.False(t, IsSpeedscope([]byte("main;parse 10\n")))
+	assert.False(t, IsSpeedscope([]byte(`{"traceEvents": []}`)))
+	assert.False(t, IsSpeedscope(nil))
+}
+
+func TestParser_Parse_Sampled(t *testing.T) {
+	p := NewParser()
+	result, err := p.Parse(context.Background(), strings.NewReader(sampledInput))
+	require.NoError(t, err)
+
+	// Milliseconds are converted to nanoseconds, the empty stack is skipped
+	assert.Equal(t, "ns", p.Unit)
+	require.Len(t, result.Samples, 3)
+	assert.Equal(t, []string{"main", "parse"}, result.Samples[0].CallStack)
+	assert.Equal(t, int64(2_000_000), result.Samples[0].Value)
+	assert.Equal(t, []string{"main", "write"}, result.Samples[1].CallStack)
+	assert.Equal(t, int64(1_500_000), result.Samples[1].Value)
+	assert.Equal(t, int64(4_500_000), result.TotalSamples)
+
+	// A single profile has no thread name
+	assert.Empty(t, result.Samples[0].ThreadName)
+	require.Len(t, result.ThreadStats, 1)
+	assert.InDelta(t, 100, result.ThreadStats["1"].Percentage, 0.001)
+}
+
+func TestParser_Parse_Evented(t *testing.T) {
+	input := `{
+  "$schema": "https://www.speedscope.app/file-format-schema.json",
+  "shared": {"frames": [{"name": "main"}, {"name": "parse"}]},
+  "profiles": [{
+    "type": "evented", "name": "main thread", "unit": "none", "startValue": 0, "endValue": 10,
+    "events": [
+      {"type": "O", "frame": 0, "at": 0},
+      {"type": "O", "frame": 1, "at": 2},
+      {"type": "C", "frame": 1, "at": 7},
+      {"type": "C", "frame": 0, "at": 10}
+    ]
+  }]
+}`
+	p := NewParser()
+	result, err := p.Parse(context.Background(), strings.NewReader(input))
+	require.NoError(t, err)
+
+	assert.Empty(t, p.Unit)
+	require.Len(t, result.Samples, 2)
+	assert.Equal(t, []string{"main"}, result.Samples[0].CallStack)
+	assert.Equal(t, int64(5), result.Samples[0].Value)
+	assert.Equal(t, []string{"main", "parse"}, result.Samples[1].CallStack)
+	assert.Equal(t, int64(5), result.Samples[1].Value)
+}
+
+func TestParser_Parse_MultipleProfiles(t *testing.T) {
+	input := `{
+  "$schema": "https://www.speedscope.app/file-format-schema.json",
+  "shared": {"frames": [{"name": "run"}, {"name": "work"}]},
+  "profiles": [
+    {"type": "sampled", "name": "worker-1", "unit": "bytes", "startValue": 0, "endValue": 30,
+     "samples": [[0, 1]], "weights": [30]},
+    {"type": "sampled", "name": "", "unit": "bytes", "startValue": 0, "endValue": 10,
+     "samples": [[0]], "weights": [10]}
+  ]
+}`
+	p := NewParser()
+	result, err := p.Parse(context.Background(), strings.NewReader(input))
+	require.NoError(t, err)
+
+	assert.Equal(t, "bytes", p.Unit)
+	require.Len(t, result.Samples, 2)
+	assert.Equal(t, "worker-1", result.Samples[0].ThreadName)
+	assert.Equal(t, 1, result.Samples[0].TID)
+	assert.Equal(t, "profile-1", result.Samples[1].ThreadName)
+	assert.Equal(t, 2, result.Samples[1].TID)
+	assert.InDelta(t, 75, result.ThreadStats["1"].Percentage, 0.001)
+	assert.InDelta(t, 25, result.ThreadStats["2"].Percentage, 0.001)
+}
+
+func TestParser_Parse_Errors(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  error
+	}{
+		{"not json", "main;parse 10", parser.ErrInvalidFormat},
+		{"no samples", `{"shared": {"frames": []}, "profiles": []}`, parser.ErrEmptyInput},
+		{"frame out of range", `{"shared": {"frames": [{"name": "main"}]}, "profiles": [
+			{"type": "sampled", "unit": "none", "samples": [[0, 3]], "weights": [1]}]}`, parser.ErrInvalidStackFrame},
+		{"weights mismatch", `{"shared": {"frames": [{"name": "main"}]}, "profiles": [
+			{"type": "sampled", "unit": "none", "samples": [[0]], "weights": [1, 2]}]}`, parser.ErrInvalidFormat},
+		{"unknown type", `{"shared": {"frames": []}, "profiles": [{"type": "other", "unit": "none"}]}`, parser.ErrInvalidFormat},
+		{"mixed units", `{"shared": {"frames": [{"name": "main"}]}, "profiles": [
+			{"type": "sampled", "unit": "bytes", "samples": [[0]]},
+			{"type": "sampled", "unit": "seconds", "samples": [[0]]}]}`, parser.ErrInvalidFormat},
+		{"unbalanced events", `{"shared": {"frames": [{"name": "main"}, {"name": "parse"}]}, "profiles": [
+			{"type": "evented", "unit": "none", "events": [{"type": "O", "frame": 0, "at": 0}, {"type": "C", "frame": 1, "at": 1}]}]}`, parser.ErrInvalidFormat},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewParser().Parse(context.Background(), strings.NewReader(tt.input))
+			require.Error(t, err)
+			assert.True(t, errors.Is(err, tt.want), "got %v", err)
+		})
+	}
+}
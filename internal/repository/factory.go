@@ -101,6 +101,10 @@ func NewGormDB(cfg *DBConfig) (*gorm.DB, error) {
 type Repositories struct {
 	Task       TaskRepository
 	Result     ResultRepository
+	Summary    SummaryRepository
+	Baseline   BaselineRepository
+	Lease      LeaseRepository
+	DeadLetter DeadLetterRepository
 	Suggestion SuggestionRepository
 	MasterTask MasterTaskRepository
 	gormDB     *gorm.DB
@@ -113,12 +117,35 @@ func NewRepositories(gormDB *gorm.DB, dbType string, version string) *Repositori
 
 	repos.Task = NewGormTaskRepository(gormDB)
 	repos.Result = NewGormResultRepository(gormDB, version)
+	repos.Summary = NewGormSummaryRepository(gormDB)
+	repos.Baseline = NewGormBaselineRepository(gormDB)
+	repos.Lease = NewGormLeaseRepository(gormDB)
+	repos.DeadLetter = NewGormDeadLetterRepository(gormDB)
 	repos.Suggestion = NewGormSuggestionRepository(gormDB)
 	repos.MasterTask = NewGormMasterTaskRepository(gormDB)
 
 	return repos
 }
 
+// Migrate creates or updates the tables owned by the analyzer. The task,
+// result and suggestion tables are shared with the platform, which manages
+// them.
+func (r *Repositories) Migrate(ctx context.Context) error {
+	if err := r.gormDB.WithContext(ctx).AutoMigrate(&AnalysisSummary{}); err != nil {
+		return fmt.Errorf("failed to migrate analysis summaries: %w", err)
+	}
+	if err := r.gormDB.WithContext(ctx).AutoMigrate(&AnalysisBaseline{}); err != nil {
+		return fmt.Errorf("failed to migrate analysis baselines: %w", err)
+	}
+	if err := r.gormDB.WithContext(ctx).AutoMigrate(&TaskLease{}); err != nil {
+		return fmt.Errorf("failed to migrate task leases: %w", err)
+	}
+	if err := r.gormDB.WithContext(ctx).AutoMigrate(&DeadLetter{}); err != nil {
+		return fmt.Errorf("failed to migrate dead letters: %w", err)
+	}
+	return nil
+}
+
 // Close closes the database connection.
 func (r *Repositories) Close() error {
 	if r.gormDB != nil {
@@ -144,6 +144,117 @@ func (r *GormTaskRepository) LockTaskForAnalysis(ctx context.Context, id int64)
 	return true, nil
 }
 
+// GormLeaseRepository implements LeaseRepository using GORM.
+type GormLeaseRepository struct {
+	db *gorm.DB
+}
+
+// NewGormLeaseRepository creates a new GormLeaseRepository.
+func NewGormLeaseRepository(db *gorm.DB) *GormLeaseRepository {
+	return &GormLeaseRepository{db: db}
+}
+
+// AcquireLease leases a task to owner for ttl. The lease row is created if
+// missing, or taken over if expired; both are single statements, so of
+// concurrent instances only one wins.
+func (r *GormLeaseRepository) AcquireLease(ctx context.Context, task *model.Task, owner string, ttl time.Duration) (*model.TaskLease, error) {
+	now := time.Now()
+	lease := &TaskLease{
+		TaskID:     task.ID,
+		TID:        task.TaskUUID,
+		Owner:      owner,
+		ExpiresAt:  now.Add(ttl),
+		Attempts:   1,
+		AcquiredAt: now,
+	}
+
+	res := r.db.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(lease)
+	if res.Error != nil {
+		return nil, fmt.Errorf("failed to create lease: %w", res.Error)
+	}
+	if res.RowsAffected == 1 {
+		return lease.ToModel(), nil
+	}
+
+	res = r.db.WithContext(ctx).
+		Model(&TaskLease{}).
+		Where("task_id = ? AND expires_at < ?", task.ID, now).
+		Updates(map[string]interface{}{
+			"owner":       owner,
+			"expires_at":  now.Add(ttl),
+			"attempts":    gorm.Expr("attempts + 1"),
+			"acquired_at": now,
+		})
+	if res.Error != nil {
+		return nil, fmt.Errorf("failed to take over lease: %w", res.Error)
+	}
+	if res.RowsAffected == 0 {
+		return nil, nil
+	}
+
+	if err := r.db.WithContext(ctx).Where("task_id = ?", task.ID).First(lease).Error; err != nil {
+		return nil, fmt.Errorf("failed to get lease: %w", err)
+	}
+	return lease.ToModel(), nil
+}
+
+// RenewLease extends the lease of owner on a task by ttl.
+func (r *GormLeaseRepository) RenewLease(ctx context.Context, taskID int64, owner string, ttl time.Duration) error {
+	res := r.db.WithContext(ctx).
+		Model(&TaskLease{}).
+		Where("task_id = ? AND owner = ?", taskID, owner).
+		Update("expires_at", time.Now().Add(ttl))
+
+	if res.Error != nil {
+		return fmt.Errorf("failed to renew lease: %w", res.Error)
+	}
+	if res.RowsAffected == 0 {
+		return fmt.Errorf("lease of task %d %w", taskID, ErrLeaseLost)
+	}
+
+	return nil
+}
+
+// ReleaseLease deletes the lease of owner on a task.
+func (r *GormLeaseRepository) ReleaseLease(ctx context.Context, taskID int64, owner string) error {
+	res := r.db.WithContext(ctx).
+		Where("task_id = ? AND owner = ?", taskID, owner).
+		Delete(&TaskLease{})
+
+	if res.Error != nil {
+		return fmt.Errorf("failed to release lease: %w", res.Error)
+	}
+	if res.RowsAffected == 0 {
+		return fmt.Errorf("lease of task %d %w", taskID, ErrLeaseLost)
+	}
+
+	return nil
+}
+
+// GetExpiredLeases retrieves the expired leases of running tasks, oldest
+// first.
+func (r *GormLeaseRepository) GetExpiredLeases(ctx context.Context, limit int) ([]*model.TaskLease, error) {
+	var leases []TaskLease
+
+	err := r.db.WithContext(ctx).
+		Joins("JOIN hotmethod_task ON hotmethod_task.id = analysis_task_leases.task_id").
+		Where("analysis_task_leases.expires_at < ? AND hotmethod_task.analysis_status = ?", time.Now(), model.AnalysisStatusRunning).
+		Order("analysis_task_leases.expires_at").
+		Limit(limit).
+		Find(&leases).Error
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to query expired leases: %w", err)
+	}
+
+	result := make([]*model.TaskLease, len(leases))
+	for i := range leases {
+		result[i] = leases[i].ToModel()
+	}
+
+	return result, nil
+}
+
 // GormResultRepository implements ResultRepository using GORM.
 type GormResultRepository struct {
 	db      *gorm.DB
@@ -155,7 +266,8 @@ func NewGormResultRepository(db *gorm.DB, version string) *GormResultRepository
 	return &GormResultRepository{db: db, version: version}
 }
 
-// SaveResult saves an analysis result to the database.
+// SaveResult saves an analysis result to the database, replacing the
+// previous result of the task.
 func (r *GormResultRepository) SaveResult(ctx context.Context, result *model.AnalysisResult) error {
 	containersInfoJSON, err := json.Marshal(result.ContainersInfo)
 	if err != nil {
@@ -174,7 +286,14 @@ func (r *GormResultRepository) SaveResult(ctx context.Context, result *model.Ana
 		Version:        r.version,
 	}
 
-	if err := r.db.WithContext(ctx).Create(record).Error; err != nil {
+	// A task taken over from a crashed instance may already have a result
+	err = r.db.WithContext(ctx).
+		Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "tid"}},
+			DoUpdates: clause.AssignmentColumns([]string{"containers_info", "result", "version"}),
+		}).
+		Create(record).Error
+	if err != nil {
 		return fmt.Errorf("failed to save analysis result: %w", err)
 	}
 
@@ -227,6 +346,284 @@ func (r *GormResultRepository) UpdateResult(ctx context.Context, result *model.A
 	return nil
 }
 
+// Summary query limits.
+const (
+	defaultSummaryLimit = 30
+	maxSummaryLimit     = 1000
+)
+
+// GormSummaryRepository implements SummaryRepository using GORM.
+type GormSummaryRepository struct {
+	db *gorm.DB
+}
+
+// NewGormSummaryRepository creates a new GormSummaryRepository.
+func NewGormSummaryRepository(db *gorm.DB) *GormSummaryRepository {
+	return &GormSummaryRepository{db: db}
+}
+
+// SaveSummary saves the summary of an analysis. Tasks may be analyzed again,
+// e.g. when redelivered by a queue, so the summary replaces the previous one.
+func (r *GormSummaryRepository) SaveSummary(ctx context.Context, summary *model.AnalysisSummary) error {
+	record, err := NewAnalysisSummary(summary)
+	if err != nil {
+		return fmt.Errorf("failed to marshal summary: %w", err)
+	}
+
+	err = r.db.WithContext(ctx).
+		Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "tid"}},
+			DoUpdates: clause.AssignmentColumns(summaryColumns),
+		}).
+		Create(record).Error
+	if err != nil {
+		return fmt.Errorf("failed to save analysis summary: %w", err)
+	}
+
+	return nil
+}
+
+// summaryColumns are the columns of a summary replaced by SaveSummary.
+var summaryColumns = []string{
+	"type", "profiler_type", "service", "user_name", "total_samples", "total_heap_size",
+	"total_objects", "top_classes", "top_funcs", "suggestions", "health_score", "baseline_tid", "regressions", "analyzed_at",
+}
+
+// GetSummary retrieves the summary of a task.
+func (r *GormSummaryRepository) GetSummary(ctx context.Context, taskUUID string) (*model.AnalysisSummary, error) {
+	var record AnalysisSummary
+
+	err := r.db.WithContext(ctx).Where("tid = ?", taskUUID).First(&record).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("summary of task %s %w", taskUUID, ErrNotFound)
+		}
+		return nil, fmt.Errorf("failed to get summary: %w", err)
+	}
+
+	return record.ToModel()
+}
+
+// ListSummaries retrieves the summaries matching a query, newest first. The
+// limit defaults to 30 and is capped to 1000.
+func (r *GormSummaryRepository) ListSummaries(ctx context.Context, query *model.SummaryQuery) ([]*model.AnalysisSummary, error) {
+	db := r.db.WithContext(ctx)
+	if query.Service != "" {
+		db = db.Where("service = ?", query.Service)
+	}
+	if query.UserName != "" {
+		db = db.Where("user_name = ?", query.UserName)
+	}
+	if query.TaskType != nil {
+		db = db.Where("type = ?", *query.TaskType)
+	}
+	if !query.Since.IsZero() {
+		db = db.Where("analyzed_at >= ?", query.Since)
+	}
+	if !query.Until.IsZero() {
+		db = db.Where("analyzed_at < ?", query.Until)
+	}
+
+	limit := query.Limit
+	if limit <= 0 {
+		limit = defaultSummaryLimit
+	}
+	if limit > maxSummaryLimit {
+		limit = maxSummaryLimit
+	}
+
+	var records []AnalysisSummary
+	err := db.Order("analyzed_at DESC").Order("id DESC").Limit(limit).Find(&records).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to query summaries: %w", err)
+	}
+
+	summaries := make([]*model.AnalysisSummary, len(records))
+	for i := range records {
+		summary, err := records[i].ToModel()
+		if err != nil {
+			return nil, fmt.Errorf("failed to unmarshal summary of task %s: %w", records[i].TID, err)
+		}
+		summaries[i] = summary
+	}
+
+	return summaries, nil
+}
+
+// GormBaselineRepository implements BaselineRepository using GORM.
+type GormBaselineRepository struct {
+	db *gorm.DB
+}
+
+// NewGormBaselineRepository creates a new GormBaselineRepository.
+func NewGormBaselineRepository(db *gorm.DB) *GormBaselineRepository {
+	return &GormBaselineRepository{db: db}
+}
+
+// SetBaseline makes a task the baseline of its service for its task type,
+// replacing the previous one.
+func (r *GormBaselineRepository) SetBaseline(ctx context.Context, baseline *model.Baseline) error {
+	record := &AnalysisBaseline{
+		Service:   baseline.Service,
+		Type:      baseline.TaskType,
+		TID:       baseline.TaskUUID,
+		CreatedAt: baseline.CreatedAt,
+	}
+
+	err := r.db.WithContext(ctx).
+		Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "service"}, {Name: "type"}},
+			DoUpdates: clause.AssignmentColumns([]string{"tid", "created_at"}),
+		}).
+		Create(record).Error
+	if err != nil {
+		return fmt.Errorf("failed to save baseline: %w", err)
+	}
+
+	return nil
+}
+
+// GetBaseline retrieves the baseline of a service for a task type.
+func (r *GormBaselineRepository) GetBaseline(ctx context.Context, service string, taskType model.TaskType) (*model.Baseline, error) {
+	var record AnalysisBaseline
+
+	err := r.db.WithContext(ctx).Where("service = ? AND type = ?", service, taskType).First(&record).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("baseline of service %s %w", service, ErrNotFound)
+		}
+		return nil, fmt.Errorf("failed to get baseline: %w", err)
+	}
+
+	return record.ToModel(), nil
+}
+
+// ListBaselines retrieves the baselines of a service, of all services if
+// service is empty.
+func (r *GormBaselineRepository) ListBaselines(ctx context.Context, service string) ([]*model.Baseline, error) {
+	db := r.db.WithContext(ctx)
+	if service != "" {
+		db = db.Where("service = ?", service)
+	}
+
+	var records []AnalysisBaseline
+	if err := db.Order("service").Order("type").Find(&records).Error; err != nil {
+		return nil, fmt.Errorf("failed to query baselines: %w", err)
+	}
+
+	baselines := make([]*model.Baseline, len(records))
+	for i := range records {
+		baselines[i] = records[i].ToModel()
+	}
+	return baselines, nil
+}
+
+// DeleteBaseline deletes the baselines a task is.
+func (r *GormBaselineRepository) DeleteBaseline(ctx context.Context, taskUUID string) error {
+	res := r.db.WithContext(ctx).Where("tid = ?", taskUUID).Delete(&AnalysisBaseline{})
+	if res.Error != nil {
+		return fmt.Errorf("failed to delete baseline: %w", res.Error)
+	}
+	if res.RowsAffected == 0 {
+		return fmt.Errorf("baseline of task %s %w", taskUUID, ErrNotFound)
+	}
+	return nil
+}
+
+// Dead letter query limits.
+const (
+	defaultDeadLetterLimit = 100
+	maxDeadLetterLimit     = 1000
+)
+
+// GormDeadLetterRepository implements DeadLetterRepository using GORM.
+type GormDeadLetterRepository struct {
+	db *gorm.DB
+}
+
+// NewGormDeadLetterRepository creates a new GormDeadLetterRepository.
+func NewGormDeadLetterRepository(db *gorm.DB) *GormDeadLetterRepository {
+	return &GormDeadLetterRepository{db: db}
+}
+
+// SaveDeadLetter saves a dead-lettered task. A task failing again after it
+// was retried from the dead letters replaces its previous dead letter.
+func (r *GormDeadLetterRepository) SaveDeadLetter(ctx context.Context, letter *model.DeadLetter) error {
+	record, err := NewDeadLetter(letter)
+	if err != nil {
+		return fmt.Errorf("failed to marshal dead letter: %w", err)
+	}
+
+	err = r.db.WithContext(ctx).
+		Clauses(clause.OnConflict{
+			Columns: []clause.Column{{Name: "tid"}},
+			DoUpdates: clause.AssignmentColumns([]string{
+				"task_id", "type", "profiler_type", "source", "error", "attempts", "artifacts", "failed_at",
+			}),
+		}).
+		Create(record).Error
+	if err != nil {
+		return fmt.Errorf("failed to save dead letter: %w", err)
+	}
+
+	return nil
+}
+
+// GetDeadLetter retrieves the dead letter of a task.
+func (r *GormDeadLetterRepository) GetDeadLetter(ctx context.Context, taskUUID string) (*model.DeadLetter, error) {
+	var record DeadLetter
+
+	err := r.db.WithContext(ctx).Where("tid = ?", taskUUID).First(&record).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("dead letter of task %s %w", taskUUID, ErrNotFound)
+		}
+		return nil, fmt.Errorf("failed to get dead letter: %w", err)
+	}
+
+	return record.ToModel()
+}
+
+// ListDeadLetters retrieves dead letters, newest first. The limit defaults
+// to 100 and is capped to 1000.
+func (r *GormDeadLetterRepository) ListDeadLetters(ctx context.Context, limit int) ([]*model.DeadLetter, error) {
+	if limit <= 0 {
+		limit = defaultDeadLetterLimit
+	}
+	if limit > maxDeadLetterLimit {
+		limit = maxDeadLetterLimit
+	}
+
+	var records []DeadLetter
+	err := r.db.WithContext(ctx).Order("failed_at DESC").Order("id DESC").Limit(limit).Find(&records).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to query dead letters: %w", err)
+	}
+
+	letters := make([]*model.DeadLetter, len(records))
+	for i := range records {
+		letter, err := records[i].ToModel()
+		if err != nil {
+			return nil, fmt.Errorf("failed to unmarshal dead letter of task %s: %w", records[i].TID, err)
+		}
+		letters[i] = letter
+	}
+
+	return letters, nil
+}
+
+// DeleteDeadLetter deletes the dead letter of a task.
+func (r *GormDeadLetterRepository) DeleteDeadLetter(ctx context.Context, taskUUID string) error {
+	res := r.db.WithContext(ctx).Where("tid = ?", taskUUID).Delete(&DeadLetter{})
+	if res.Error != nil {
+		return fmt.Errorf("failed to delete dead letter: %w", res.Error)
+	}
+	if res.RowsAffected == 0 {
+		return fmt.Errorf("dead letter of task %s %w", taskUUID, ErrNotFound)
+	}
+	return nil
+}
+
 // GormSuggestionRepository implements SuggestionRepository using GORM.
 type GormSuggestionRepository struct {
 	db *gorm.DB
@@ -237,7 +634,8 @@ func NewGormSuggestionRepository(db *gorm.DB) *GormSuggestionRepository {
 	return &GormSuggestionRepository{db: db}
 }
 
-// SaveSuggestions saves multiple suggestions to the database.
+// SaveSuggestions saves multiple suggestions to the database, replacing
+// the previous suggestions of their tasks.
 func (r *GormSuggestionRepository) SaveSuggestions(ctx context.Context, suggestions []model.Suggestion) error {
 	if len(suggestions) == 0 {
 		return nil
@@ -246,6 +644,18 @@ func (r *GormSuggestionRepository) SaveSuggestions(ctx context.Context, suggesti
 	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
 		now := time.Now()
 
+		// Replace the suggestions of a previous analysis of the tasks, e.g.
+		// by an instance which crashed before completing them
+		tids := make(map[string]bool)
+		for _, sug := range suggestions {
+			if sug.TaskUUID != "" && !tids[sug.TaskUUID] {
+				tids[sug.TaskUUID] = true
+				if err := tx.Where("tid = ?", sug.TaskUUID).Delete(&AnalysisSuggestion{}).Error; err != nil {
+					return fmt.Errorf("failed to delete previous suggestions: %w", err)
+				}
+			}
+		}
+
 		for _, sug := range suggestions {
 			if sug.Suggestion == "" {
 				continue
@@ -3,6 +3,7 @@ package repository
 import (
 	"context"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -26,6 +27,10 @@ func setupTestDB(t *testing.T) *gorm.DB {
 		&AnalysisSuggestion{},
 		&AnalysisSuggestionRule{},
 		&MultipleTask{},
+		&AnalysisSummary{},
+		&AnalysisBaseline{},
+		&TaskLease{},
+		&DeadLetter{},
 	)
 	require.NoError(t, err)
 
@@ -371,6 +376,328 @@ func TestGormMasterTaskRepository(t *testing.T) {
 	})
 }
 
+func TestGormLeaseRepository(t *testing.T) {
+	db := setupTestDB(t)
+	repo := NewGormLeaseRepository(db)
+	ctx := context.Background()
+
+	task := &HotmethodTask{
+		TID:            "leased-task",
+		Status:         model.TaskStatusCompleted,
+		AnalysisStatus: model.AnalysisStatusRunning,
+	}
+	require.NoError(t, db.Create(task).Error)
+
+	t.Run("AcquireLease_Success", func(t *testing.T) {
+		lease, err := repo.AcquireLease(ctx, task.ToModel(), "node-a", time.Minute)
+		require.NoError(t, err)
+		require.NotNil(t, lease)
+		assert.Equal(t, "node-a", lease.Owner)
+		assert.Equal(t, 1, lease.Attempts)
+	})
+
+	t.Run("AcquireLease_HeldByOther", func(t *testing.T) {
+		lease, err := repo.AcquireLease(ctx, task.ToModel(), "node-b", time.Minute)
+		require.NoError(t, err)
+		assert.Nil(t, lease)
+
+		expired, err := repo.GetExpiredLeases(ctx, 10)
+		require.NoError(t, err)
+		assert.Empty(t, expired)
+	})
+
+	t.Run("TakeOverExpired", func(t *testing.T) {
+		// node-a crashes: its lease is no longer renewed
+		require.NoError(t, repo.RenewLease(ctx, task.ID, "node-a", -time.Second))
+
+		expired, err := repo.GetExpiredLeases(ctx, 10)
+		require.NoError(t, err)
+		require.Len(t, expired, 1)
+		assert.Equal(t, "leased-task", expired[0].TaskUUID)
+
+		lease, err := repo.AcquireLease(ctx, task.ToModel(), "node-b", time.Minute)
+		require.NoError(t, err)
+		require.NotNil(t, lease)
+		assert.Equal(t, "node-b", lease.Owner)
+		assert.Equal(t, 2, lease.Attempts)
+	})
+
+	t.Run("LostLease", func(t *testing.T) {
+		assert.ErrorIs(t, repo.RenewLease(ctx, task.ID, "node-a", time.Minute), ErrLeaseLost)
+		assert.ErrorIs(t, repo.ReleaseLease(ctx, task.ID, "node-a"), ErrLeaseLost)
+	})
+
+	t.Run("ReleaseLease_Success", func(t *testing.T) {
+		require.NoError(t, repo.ReleaseLease(ctx, task.ID, "node-b"))
+
+		lease, err := repo.AcquireLease(ctx, task.ToModel(), "node-a", time.Minute)
+		require.NoError(t, err)
+		require.NotNil(t, lease)
+		assert.Equal(t, 1, lease.Attempts)
+	})
+
+	t.Run("GetExpiredLeases_OnlyRunningTasks", func(t *testing.T) {
+		require.NoError(t, repo.RenewLease(ctx, task.ID, "node-a", -time.Second))
+		require.NoError(t, db.Model(task).Update("analysis_status", model.AnalysisStatusCompleted).Error)
+
+		expired, err := repo.GetExpiredLeases(ctx, 10)
+		require.NoError(t, err)
+		assert.Empty(t, expired)
+	})
+}
+
+func TestGormResultRepository_SaveResultTwice(t *testing.T) {
+	db := setupTestDB(t)
+	repo := NewGormResultRepository(db, "v1")
+	ctx := context.Background()
+
+	result := &model.AnalysisResult{
+		TaskUUID: "retried-task",
+		Result:   map[string]model.NamespaceResult{"": {TotalRecords: 1}},
+	}
+	require.NoError(t, repo.SaveResult(ctx, result))
+
+	result.Result[""] = model.NamespaceResult{TotalRecords: 2}
+	require.NoError(t, repo.SaveResult(ctx, result))
+
+	saved, err := repo.GetResultByTaskUUID(ctx, "retried-task")
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), saved.Result[""].TotalRecords)
+}
+
+func TestGormSuggestionRepository_SaveSuggestionsTwice(t *testing.T) {
+	db := setupTestDB(t)
+	repo := NewGormSuggestionRepository(db)
+	ctx := context.Background()
+
+	suggestions := []model.Suggestion{
+		{TaskUUID: "retried-task", Suggestion: "first"},
+		{TaskUUID: "retried-task", Suggestion: "second"},
+	}
+	require.NoError(t, repo.SaveSuggestions(ctx, suggestions))
+	require.NoError(t, repo.SaveSuggestions(ctx, suggestions))
+
+	saved, err := repo.GetSuggestionsByTaskUUID(ctx, "retried-task")
+	require.NoError(t, err)
+	assert.Len(t, saved, 2)
+}
+
+func TestGormDeadLetterRepository(t *testing.T) {
+	db := setupTestDB(t)
+	repo := NewGormDeadLetterRepository(db)
+	ctx := context.Background()
+
+	base := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	save := func(tid, reason string, at time.Time) {
+		t.Helper()
+		require.NoError(t, repo.SaveDeadLetter(ctx, &model.DeadLetter{
+			TaskID:    1,
+			TaskUUID:  tid,
+			TaskType:  model.TaskTypeJavaHeap,
+			Source:    "sqs/dumps",
+			Error:     reason,
+			Attempts:  3,
+			Artifacts: []string{tid + "/partial/summary.json"},
+			FailedAt:  at,
+		}))
+	}
+	save("task-1", "download failed", base)
+	save("task-2", "parse error", base.Add(time.Hour))
+
+	t.Run("GetDeadLetter", func(t *testing.T) {
+		letter, err := repo.GetDeadLetter(ctx, "task-1")
+		require.NoError(t, err)
+		assert.Equal(t, "download failed", letter.Error)
+		assert.Equal(t, "sqs/dumps", letter.Source)
+		assert.Equal(t, 3, letter.Attempts)
+		assert.Equal(t, []string{"task-1/partial/summary.json"}, letter.Artifacts)
+
+		_, err = repo.GetDeadLetter(ctx, "missing")
+		assert.ErrorIs(t, err, ErrNotFound)
+	})
+
+	t.Run("ListDeadLetters", func(t *testing.T) {
+		letters, err := repo.ListDeadLetters(ctx, 0)
+		require.NoError(t, err)
+		require.Len(t, letters, 2)
+		assert.Equal(t, "task-2", letters[0].TaskUUID, "newest first")
+
+		letters, err = repo.ListDeadLetters(ctx, 1)
+		require.NoError(t, err)
+		assert.Len(t, letters, 1)
+	})
+
+	t.Run("SaveDeadLetter_Replaces", func(t *testing.T) {
+		save("task-1", "timeout", base.Add(2*time.Hour))
+
+		letter, err := repo.GetDeadLetter(ctx, "task-1")
+		require.NoError(t, err)
+		assert.Equal(t, "timeout", letter.Error)
+
+		letters, err := repo.ListDeadLetters(ctx, 0)
+		require.NoError(t, err)
+		assert.Len(t, letters, 2)
+	})
+
+	t.Run("DeleteDeadLetter", func(t *testing.T) {
+		require.NoError(t, repo.DeleteDeadLetter(ctx, "task-2"))
+		_, err := repo.GetDeadLetter(ctx, "task-2")
+		assert.ErrorIs(t, err, ErrNotFound)
+
+		assert.ErrorIs(t, repo.DeleteDeadLetter(ctx, "task-2"), ErrNotFound)
+	})
+}
+
+func TestGormSummaryRepository(t *testing.T) {
+	db := setupTestDB(t)
+	repo := NewGormSummaryRepository(db)
+	ctx := context.Background()
+
+	base := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	save := func(tid, service string, taskType model.TaskType, heap int64, at time.Time) {
+		t.Helper()
+		require.NoError(t, repo.SaveSummary(ctx, &model.AnalysisSummary{
+			TaskUUID:      tid,
+			TaskType:      taskType,
+			Service:       service,
+			TotalHeapSize: heap,
+			TopClasses:    []model.HeapClassStats{{ClassName: "byte[]", TotalSize: heap / 2}},
+			Suggestions:   []string{"check caches"},
+			AnalyzedAt:    at,
+		}))
+	}
+	save("heap-1", "checkout", model.TaskTypeJavaHeap, 100, base)
+	save("heap-2", "checkout", model.TaskTypeJavaHeap, 200, base.Add(time.Hour))
+	save("heap-3", "search", model.TaskTypeJavaHeap, 300, base.Add(2*time.Hour))
+	save("cpu-1", "checkout", model.TaskTypeJava, 0, base.Add(3*time.Hour))
+
+	t.Run("GetSummary_Success", func(t *testing.T) {
+		summary, err := repo.GetSummary(ctx, "heap-2")
+		require.NoError(t, err)
+		assert.Equal(t, "checkout", summary.Service)
+		assert.Equal(t, int64(200), summary.TotalHeapSize)
+		require.Len(t, summary.TopClasses, 1)
+		assert.Equal(t, "byte[]", summary.TopClasses[0].ClassName)
+		assert.Equal(t, []string{"check caches"}, summary.Suggestions)
+	})
+
+	t.Run("GetSummary_NotFound", func(t *testing.T) {
+		_, err := repo.GetSummary(ctx, "missing")
+		assert.ErrorIs(t, err, ErrNotFound)
+	})
+
+	t.Run("SaveSummary_HealthAndRegressions", func(t *testing.T) {
+		score := 64
+		require.NoError(t, repo.SaveSummary(ctx, &model.AnalysisSummary{
+			TaskUUID:    "cpu-1",
+			TaskType:    model.TaskTypeJava,
+			Service:     "checkout",
+			HealthScore: &score,
+			BaselineTID: "cpu-0",
+			Regressions: []model.Regression{{Kind: model.RegressionNewHotspot, Name: "parse", Current: 12}},
+			AnalyzedAt:  base.Add(3 * time.Hour),
+		}))
+
+		summary, err := repo.GetSummary(ctx, "cpu-1")
+		require.NoError(t, err)
+		require.NotNil(t, summary.HealthScore)
+		assert.Equal(t, 64, *summary.HealthScore)
+		assert.Equal(t, "cpu-0", summary.BaselineTID)
+		assert.Equal(t, []model.Regression{{Kind: model.RegressionNewHotspot, Name: "parse", Current: 12}}, summary.Regressions)
+
+		summary, err = repo.GetSummary(ctx, "heap-2")
+		require.NoError(t, err)
+		assert.Nil(t, summary.HealthScore, "not rated")
+	})
+
+	t.Run("SaveSummary_Replaces", func(t *testing.T) {
+		save("heap-1", "checkout", model.TaskTypeJavaHeap, 150, base)
+
+		summary, err := repo.GetSummary(ctx, "heap-1")
+		require.NoError(t, err)
+		assert.Equal(t, int64(150), summary.TotalHeapSize)
+
+		var count int64
+		require.NoError(t, db.Model(&AnalysisSummary{}).Where("tid = ?", "heap-1").Count(&count).Error)
+		assert.Equal(t, int64(1), count)
+	})
+
+	t.Run("ListSummaries_Filters", func(t *testing.T) {
+		heap := model.TaskTypeJavaHeap
+		summaries, err := repo.ListSummaries(ctx, &model.SummaryQuery{Service: "checkout", TaskType: &heap})
+		require.NoError(t, err)
+		require.Len(t, summaries, 2)
+		assert.Equal(t, "heap-2", summaries[0].TaskUUID, "newest first")
+		assert.Equal(t, "heap-1", summaries[1].TaskUUID)
+
+		summaries, err = repo.ListSummaries(ctx, &model.SummaryQuery{
+			Since: base.Add(time.Hour),
+			Until: base.Add(3 * time.Hour),
+		})
+		require.NoError(t, err)
+		require.Len(t, summaries, 2)
+		assert.Equal(t, "heap-3", summaries[0].TaskUUID)
+		assert.Equal(t, "heap-2", summaries[1].TaskUUID)
+	})
+
+	t.Run("ListSummaries_Limit", func(t *testing.T) {
+		summaries, err := repo.ListSummaries(ctx, &model.SummaryQuery{Limit: 1})
+		require.NoError(t, err)
+		require.Len(t, summaries, 1)
+		assert.Equal(t, "cpu-1", summaries[0].TaskUUID)
+	})
+}
+
+func TestGormBaselineRepository(t *testing.T) {
+	db := setupTestDB(t)
+	repo := NewGormBaselineRepository(db)
+	ctx := context.Background()
+
+	at := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	set := func(service string, taskType model.TaskType, tid string) {
+		t.Helper()
+		require.NoError(t, repo.SetBaseline(ctx, &model.Baseline{Service: service, TaskType: taskType, TaskUUID: tid, CreatedAt: at}))
+	}
+	set("checkout", model.TaskTypeJavaHeap, "heap-1")
+	set("checkout", model.TaskTypeJava, "cpu-1")
+	set("search", model.TaskTypeJavaHeap, "heap-9")
+
+	t.Run("GetBaseline", func(t *testing.T) {
+		baseline, err := repo.GetBaseline(ctx, "checkout", model.TaskTypeJavaHeap)
+		require.NoError(t, err)
+		assert.Equal(t, "heap-1", baseline.TaskUUID)
+
+		_, err = repo.GetBaseline(ctx, "checkout", model.TaskTypeGCLog)
+		assert.ErrorIs(t, err, ErrNotFound)
+	})
+
+	t.Run("SetBaseline_Replaces", func(t *testing.T) {
+		set("checkout", model.TaskTypeJavaHeap, "heap-2")
+
+		baseline, err := repo.GetBaseline(ctx, "checkout", model.TaskTypeJavaHeap)
+		require.NoError(t, err)
+		assert.Equal(t, "heap-2", baseline.TaskUUID)
+	})
+
+	t.Run("ListBaselines", func(t *testing.T) {
+		baselines, err := repo.ListBaselines(ctx, "checkout")
+		require.NoError(t, err)
+		require.Len(t, baselines, 2)
+
+		baselines, err = repo.ListBaselines(ctx, "")
+		require.NoError(t, err)
+		assert.Len(t, baselines, 3)
+	})
+
+	t.Run("DeleteBaseline", func(t *testing.T) {
+		require.NoError(t, repo.DeleteBaseline(ctx, "heap-9"))
+		_, err := repo.GetBaseline(ctx, "search", model.TaskTypeJavaHeap)
+		assert.ErrorIs(t, err, ErrNotFound)
+
+		assert.ErrorIs(t, repo.DeleteBaseline(ctx, "heap-9"), ErrNotFound)
+	})
+}
+
 func strPtr(s string) *string {
 	return &s
 }
@@ -96,6 +96,216 @@ func (r *GeneralAnalysisResult) ToModel() (*model.AnalysisResult, error) {
 	return result, nil
 }
 
+// AnalysisSummary represents the analysis_summaries table. Unlike the other
+// tables, it is owned and migrated by the analyzer.
+type AnalysisSummary struct {
+	ID            int64              `gorm:"column:id;primaryKey;autoIncrement"`
+	TID           string             `gorm:"column:tid;type:varchar(64);uniqueIndex"`
+	Type          model.TaskType     `gorm:"column:type;index:idx_summary_service_time,priority:2"`
+	ProfilerType  model.ProfilerType `gorm:"column:profiler_type"`
+	Service       string             `gorm:"column:service;type:varchar(256);index:idx_summary_service_time,priority:1"`
+	UserName      string             `gorm:"column:user_name;type:varchar(128);index"`
+	TotalSamples  int64              `gorm:"column:total_samples"`
+	TotalHeapSize int64              `gorm:"column:total_heap_size"`
+	TotalObjects  int64              `gorm:"column:total_objects"`
+	TopClasses    JSONField          `gorm:"column:top_classes;type:json"`
+	TopFuncs      JSONField          `gorm:"column:top_funcs;type:json"`
+	Suggestions   JSONField          `gorm:"column:suggestions;type:json"`
+	HealthScore   *int               `gorm:"column:health_score"`
+	BaselineTID   string             `gorm:"column:baseline_tid;type:varchar(64)"`
+	Regressions   JSONField          `gorm:"column:regressions;type:json"`
+	AnalyzedAt    time.Time          `gorm:"column:analyzed_at;index:idx_summary_service_time,priority:3"`
+}
+
+// TableName returns the table name for AnalysisSummary.
+func (AnalysisSummary) TableName() string {
+	return "analysis_summaries"
+}
+
+// NewAnalysisSummary converts model.AnalysisSummary to AnalysisSummary.
+func NewAnalysisSummary(summary *model.AnalysisSummary) (*AnalysisSummary, error) {
+	record := &AnalysisSummary{
+		TID:           summary.TaskUUID,
+		Type:          summary.TaskType,
+		ProfilerType:  summary.ProfilerType,
+		Service:       summary.Service,
+		UserName:      summary.UserName,
+		TotalSamples:  summary.TotalSamples,
+		TotalHeapSize: summary.TotalHeapSize,
+		TotalObjects:  summary.TotalObjects,
+		HealthScore:   summary.HealthScore,
+		BaselineTID:   summary.BaselineTID,
+		AnalyzedAt:    summary.AnalyzedAt,
+	}
+
+	var err error
+	if record.TopClasses, err = json.Marshal(summary.TopClasses); err != nil {
+		return nil, err
+	}
+	if record.TopFuncs, err = json.Marshal(summary.TopFuncs); err != nil {
+		return nil, err
+	}
+	if record.Suggestions, err = json.Marshal(summary.Suggestions); err != nil {
+		return nil, err
+	}
+	if record.Regressions, err = json.Marshal(summary.Regressions); err != nil {
+		return nil, err
+	}
+	return record, nil
+}
+
+// ToModel converts AnalysisSummary to model.AnalysisSummary.
+func (s *AnalysisSummary) ToModel() (*model.AnalysisSummary, error) {
+	summary := &model.AnalysisSummary{
+		TaskUUID:      s.TID,
+		TaskType:      s.Type,
+		ProfilerType:  s.ProfilerType,
+		Service:       s.Service,
+		UserName:      s.UserName,
+		TotalSamples:  s.TotalSamples,
+		TotalHeapSize: s.TotalHeapSize,
+		TotalObjects:  s.TotalObjects,
+		HealthScore:   s.HealthScore,
+		BaselineTID:   s.BaselineTID,
+		AnalyzedAt:    s.AnalyzedAt,
+	}
+
+	if s.TopClasses != nil {
+		if err := json.Unmarshal(s.TopClasses, &summary.TopClasses); err != nil {
+			return nil, err
+		}
+	}
+	if s.TopFuncs != nil {
+		if err := json.Unmarshal(s.TopFuncs, &summary.TopFuncs); err != nil {
+			return nil, err
+		}
+	}
+	if s.Suggestions != nil {
+		if err := json.Unmarshal(s.Suggestions, &summary.Suggestions); err != nil {
+			return nil, err
+		}
+	}
+	if s.Regressions != nil {
+		if err := json.Unmarshal(s.Regressions, &summary.Regressions); err != nil {
+			return nil, err
+		}
+	}
+
+	return summary, nil
+}
+
+// TaskLease represents the analysis_task_leases table, owned and migrated
+// by the analyzer like AnalysisSummary.
+type TaskLease struct {
+	TaskID     int64     `gorm:"column:task_id;primaryKey;autoIncrement:false"`
+	TID        string    `gorm:"column:tid;type:varchar(64)"`
+	Owner      string    `gorm:"column:owner;type:varchar(128)"`
+	ExpiresAt  time.Time `gorm:"column:expires_at;index"`
+	Attempts   int       `gorm:"column:attempts"`
+	AcquiredAt time.Time `gorm:"column:acquired_at"`
+}
+
+// TableName returns the table name for TaskLease.
+func (TaskLease) TableName() string {
+	return "analysis_task_leases"
+}
+
+// ToModel converts TaskLease to model.TaskLease.
+func (l *TaskLease) ToModel() *model.TaskLease {
+	return &model.TaskLease{
+		TaskID:    l.TaskID,
+		TaskUUID:  l.TID,
+		Owner:     l.Owner,
+		ExpiresAt: l.ExpiresAt,
+		Attempts:  l.Attempts,
+	}
+}
+
+// AnalysisBaseline represents the analysis_baselines table, owned and
+// migrated by the analyzer like AnalysisSummary. A service has a baseline
+// per task type.
+type AnalysisBaseline struct {
+	ID        int64          `gorm:"column:id;primaryKey;autoIncrement"`
+	Service   string         `gorm:"column:service;type:varchar(256);uniqueIndex:idx_baseline_service_type,priority:1"`
+	Type      model.TaskType `gorm:"column:type;uniqueIndex:idx_baseline_service_type,priority:2"`
+	TID       string         `gorm:"column:tid;type:varchar(64);index"`
+	CreatedAt time.Time      `gorm:"column:created_at"`
+}
+
+// TableName returns the table name for AnalysisBaseline.
+func (AnalysisBaseline) TableName() string {
+	return "analysis_baselines"
+}
+
+// ToModel converts AnalysisBaseline to model.Baseline.
+func (b *AnalysisBaseline) ToModel() *model.Baseline {
+	return &model.Baseline{
+		Service:   b.Service,
+		TaskType:  b.Type,
+		TaskUUID:  b.TID,
+		CreatedAt: b.CreatedAt,
+	}
+}
+
+// DeadLetter represents the analysis_dead_letters table, owned and migrated
+// by the analyzer like AnalysisSummary.
+type DeadLetter struct {
+	ID           int64              `gorm:"column:id;primaryKey;autoIncrement"`
+	TID          string             `gorm:"column:tid;type:varchar(64);uniqueIndex"`
+	TaskID       int64              `gorm:"column:task_id"`
+	Type         model.TaskType     `gorm:"column:type"`
+	ProfilerType model.ProfilerType `gorm:"column:profiler_type"`
+	Source       string             `gorm:"column:source;type:varchar(256)"`
+	Error        string             `gorm:"column:error;type:text"`
+	Attempts     int                `gorm:"column:attempts"`
+	Artifacts    JSONField          `gorm:"column:artifacts;type:json"`
+	FailedAt     time.Time          `gorm:"column:failed_at;index"`
+}
+
+// TableName returns the table name for DeadLetter.
+func (DeadLetter) TableName() string {
+	return "analysis_dead_letters"
+}
+
+// NewDeadLetter converts model.DeadLetter to DeadLetter.
+func NewDeadLetter(letter *model.DeadLetter) (*DeadLetter, error) {
+	artifacts, err := json.Marshal(letter.Artifacts)
+	if err != nil {
+		return nil, err
+	}
+	return &DeadLetter{
+		TID:          letter.TaskUUID,
+		TaskID:       letter.TaskID,
+		Type:         letter.TaskType,
+		ProfilerType: letter.ProfilerType,
+		Source:       letter.Source,
+		Error:        letter.Error,
+		Attempts:     letter.Attempts,
+		Artifacts:    artifacts,
+		FailedAt:     letter.FailedAt,
+	}, nil
+}
+
+// ToModel converts DeadLetter to model.DeadLetter.
+func (d *DeadLetter) ToModel() (*model.DeadLetter, error) {
+	letter := &model.DeadLetter{
+		TaskID:       d.TaskID,
+		TaskUUID:     d.TID,
+		TaskType:     d.Type,
+		ProfilerType: d.ProfilerType,
+		Source:       d.Source,
+		Error:        d.Error,
+		Attempts:     d.Attempts,
+		FailedAt:     d.FailedAt,
+	}
+	if d.Artifacts != nil {
+		if err := json.Unmarshal(d.Artifacts, &letter.Artifacts); err != nil {
+			return nil, err
+		}
+	}
+	return letter, nil
+}
+
 // AnalysisSuggestion represents the analysis_suggestions table.
 type AnalysisSuggestion struct {
 	ID           int64     `gorm:"column:id;primaryKey;autoIncrement"`
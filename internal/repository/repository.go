@@ -3,6 +3,8 @@ package repository
 
 import (
 	"context"
+	"errors"
+	"time"
 
 	"github.com/perf-analysis/pkg/model"
 )
@@ -30,7 +32,8 @@ type TaskRepository interface {
 
 // ResultRepository defines the interface for analysis result operations.
 type ResultRepository interface {
-	// SaveResult saves an analysis result to the database.
+	// SaveResult saves an analysis result to the database, replacing the
+	// previous result of the task.
 	SaveResult(ctx context.Context, result *model.AnalysisResult) error
 
 	// GetResultByTaskUUID retrieves the analysis result for a task.
@@ -40,9 +43,76 @@ type ResultRepository interface {
 	UpdateResult(ctx context.Context, result *model.AnalysisResult) error
 }
 
+// SummaryRepository defines the interface for analysis summary operations.
+type SummaryRepository interface {
+	// SaveSummary saves the summary of an analysis, replacing the previous
+	// summary of the same task.
+	SaveSummary(ctx context.Context, summary *model.AnalysisSummary) error
+
+	// GetSummary retrieves the summary of a task.
+	GetSummary(ctx context.Context, taskUUID string) (*model.AnalysisSummary, error)
+
+	// ListSummaries retrieves the summaries matching a query, newest first.
+	ListSummaries(ctx context.Context, query *model.SummaryQuery) ([]*model.AnalysisSummary, error)
+}
+
+// BaselineRepository defines the interface for the operations on the
+// baselines of services, which later analyses are compared with.
+type BaselineRepository interface {
+	// SetBaseline makes a task the baseline of its service for its task
+	// type, replacing the previous one.
+	SetBaseline(ctx context.Context, baseline *model.Baseline) error
+
+	// GetBaseline retrieves the baseline of a service for a task type.
+	GetBaseline(ctx context.Context, service string, taskType model.TaskType) (*model.Baseline, error)
+
+	// ListBaselines retrieves the baselines of a service, of all services
+	// if service is empty.
+	ListBaselines(ctx context.Context, service string) ([]*model.Baseline, error)
+
+	// DeleteBaseline deletes the baselines a task is.
+	DeleteBaseline(ctx context.Context, taskUUID string) error
+}
+
+// LeaseRepository defines the interface for task lease operations, to share
+// the tasks of the database between analyzer instances.
+type LeaseRepository interface {
+	// AcquireLease leases a task to owner for ttl, taking over an expired
+	// lease. It returns nil if another owner holds the lease.
+	AcquireLease(ctx context.Context, task *model.Task, owner string, ttl time.Duration) (*model.TaskLease, error)
+
+	// RenewLease extends the lease of owner on a task by ttl.
+	RenewLease(ctx context.Context, taskID int64, owner string, ttl time.Duration) error
+
+	// ReleaseLease releases the lease of owner on a task.
+	ReleaseLease(ctx context.Context, taskID int64, owner string) error
+
+	// GetExpiredLeases retrieves the expired leases of running tasks, whose
+	// owner stopped renewing them.
+	GetExpiredLeases(ctx context.Context, limit int) ([]*model.TaskLease, error)
+}
+
+// DeadLetterRepository defines the interface for the operations on tasks
+// whose analysis failed for good.
+type DeadLetterRepository interface {
+	// SaveDeadLetter saves a dead-lettered task, replacing the previous dead
+	// letter of the same task.
+	SaveDeadLetter(ctx context.Context, letter *model.DeadLetter) error
+
+	// GetDeadLetter retrieves the dead letter of a task.
+	GetDeadLetter(ctx context.Context, taskUUID string) (*model.DeadLetter, error)
+
+	// ListDeadLetters retrieves dead letters, newest first.
+	ListDeadLetters(ctx context.Context, limit int) ([]*model.DeadLetter, error)
+
+	// DeleteDeadLetter deletes the dead letter of a task.
+	DeleteDeadLetter(ctx context.Context, taskUUID string) error
+}
+
 // SuggestionRepository defines the interface for suggestion operations.
 type SuggestionRepository interface {
-	// SaveSuggestions saves multiple suggestions to the database.
+	// SaveSuggestions saves multiple suggestions to the database, replacing
+	// the previous suggestions of their tasks.
 	SaveSuggestions(ctx context.Context, suggestions []model.Suggestion) error
 
 	// GetSuggestionsByTaskUUID retrieves suggestions for a task.
@@ -70,6 +140,16 @@ type MasterTaskRepository interface {
 	CheckAndCompleteIfReady(ctx context.Context, masterTID string) error
 }
 
+var (
+	// ErrNotFound is wrapped by the errors of lookups of missing summaries,
+	// baselines and dead letters.
+	ErrNotFound = errors.New("not found")
+
+	// ErrLeaseLost is wrapped by the errors of lease operations of an owner
+	// whose lease expired and was taken over.
+	ErrLeaseLost = errors.New("lease lost")
+)
+
 // MasterTask represents a master task that may have sub-tasks.
 type MasterTask struct {
 	TID                 string                       `json:"tid" db:"tid"`
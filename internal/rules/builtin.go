@@ -0,0 +1,315 @@
+package rules
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/perf-analysis/pkg/model"
+)
+
+// maxEvidence bounds the evidence items of a suggestion.
+const maxEvidence = 5
+
+// BuiltinRules returns the built-in rules.
+func BuiltinRules() []Rule {
+	return []Rule{
+		&ObjectRule{
+			Meta: Meta{
+				RuleName: "heap.huge_hashmap",
+				Severity: SeverityWarning,
+				Message:  "{name} 对象 {object} 保留了 {value} MB 堆内存 ({percent}%)，建议检查是否存在无界缓存或未清理的集合",
+				DocURL:   "https://docs.oracle.com/en/java/javase/17/docs/api/java.base/java/util/HashMap.html",
+			},
+			Pattern:   regexp.MustCompile(`^java\.util\.(HashMap|LinkedHashMap|Hashtable|HashSet|LinkedHashSet|concurrent\.ConcurrentHashMap)$`),
+			Threshold: 10,
+		},
+		&DuplicateStringsRule{
+			Meta: Meta{
+				RuleName: "heap.duplicate_strings",
+				Severity: SeverityWarning,
+				DocURL:   "https://openjdk.org/jeps/192",
+			},
+			Threshold: 5,
+		},
+		&ClassLoaderLeakRule{
+			Meta: Meta{
+				RuleName: "heap.classloader_leak",
+				Severity: SeverityCritical,
+				DocURL:   "https://cwiki.apache.org/confluence/display/TOMCAT/MemoryLeakProtection",
+			},
+			MinInstances:        3,
+			MinClassesPerLoader: 20,
+		},
+		&HotSpotRule{
+			Meta: Meta{
+				RuleName: "cpu.regex_hotspot",
+				Severity: SeverityWarning,
+				Message:  "正则表达式相关函数 CPU 占用率较高 ({value}%)，最热的是 {name}，建议预编译并复用 Pattern，或用字符串操作替代简单的正则",
+				DocURL:   "https://docs.oracle.com/en/java/javase/17/docs/api/java.base/java/util/regex/Pattern.html",
+			},
+			Pattern:   regexp.MustCompile(`java[./]util[./]regex[./]`),
+			Threshold: 5,
+		},
+		&ThreadShareRule{
+			Meta: Meta{
+				RuleName: "cpu.gc_threads",
+				Severity: SeverityWarning,
+				Message:  "GC 线程占用了 {value}% 的 CPU 样本 ({count} 个线程)，建议检查堆大小、对象分配速率和 GC 配置",
+				DocURL:   "https://docs.oracle.com/en/java/javase/17/gctuning/",
+			},
+			Pattern:   regexp.MustCompile(`^(GC Thread|GC task thread|G1 |Gang worker|Concurrent Mark-Sweep|Z(Worker|Driver|Director)|Shenandoah)`),
+			Threshold: 20,
+		},
+	}
+}
+
+// HotSpotRule matches CPU profiles whose functions matching Pattern take at
+// least Threshold percent of the samples by self time. Its message
+// placeholders are {name}, the hottest matching function, {value}, their
+// total percentage, and {count}, their number.
+type HotSpotRule struct {
+	Meta
+	Pattern   *regexp.Regexp
+	Threshold float64
+}
+
+// Match implements Rule.
+func (r *HotSpotRule) Match(resp *model.AnalysisResponse) []model.SuggestionItem {
+	cpu := cpuData(resp)
+	if cpu == nil {
+		return nil
+	}
+	var total float64
+	var evidence []model.SuggestionEvidence
+	for name, v := range cpu.TopFuncs {
+		if r.Pattern.MatchString(name) {
+			total += v.Self
+			evidence = append(evidence, model.SuggestionEvidence{Kind: model.EvidenceFunction, Name: name, Value: v.Self})
+		}
+	}
+	if len(evidence) == 0 || total < r.Threshold {
+		return nil
+	}
+	count := len(evidence)
+	evidence = topEvidence(evidence)
+	return []model.SuggestionItem{r.suggestion(evidence[0].Name, map[string]string{
+		"name":  evidence[0].Name,
+		"value": formatPercent(total),
+		"count": fmt.Sprint(count),
+	}, evidence)}
+}
+
+// ThreadShareRule matches CPU profiles whose threads with a name matching
+// Pattern take at least Threshold percent of the samples. Its message
+// placeholders are {name}, the busiest matching thread, {value}, their
+// total percentage, and {count}, their number.
+type ThreadShareRule struct {
+	Meta
+	Pattern   *regexp.Regexp
+	Threshold float64
+}
+
+// Match implements Rule.
+func (r *ThreadShareRule) Match(resp *model.AnalysisResponse) []model.SuggestionItem {
+	cpu := cpuData(resp)
+	if cpu == nil {
+		return nil
+	}
+	var total float64
+	var evidence []model.SuggestionEvidence
+	for _, t := range cpu.ThreadStats {
+		if r.Pattern.MatchString(t.ThreadName) {
+			total += t.Percentage
+			evidence = append(evidence, model.SuggestionEvidence{
+				Kind:   model.EvidenceThread,
+				Name:   t.ThreadName,
+				Value:  t.Percentage,
+				Detail: fmt.Sprintf("tid %d", t.TID),
+			})
+		}
+	}
+	if len(evidence) == 0 || total < r.Threshold {
+		return nil
+	}
+	count := len(evidence)
+	evidence = topEvidence(evidence)
+	return []model.SuggestionItem{r.suggestion("", map[string]string{
+		"name":  evidence[0].Name,
+		"value": formatPercent(total),
+		"count": fmt.Sprint(count),
+	}, evidence)}
+}
+
+// ClassRule matches the classes of heap dumps with a name matching Pattern
+// whose instances take at least Threshold percent of the heap. It suggests
+// per class, with the message placeholders {name}, the class, {value}, the
+// size of its instances in MB, {percent}, their share of the heap, and
+// {count}, their number.
+type ClassRule struct {
+	Meta
+	Pattern   *regexp.Regexp
+	Threshold float64
+}
+
+// Match implements Rule.
+func (r *ClassRule) Match(resp *model.AnalysisResponse) []model.SuggestionItem {
+	heap := heapData(resp)
+	if heap == nil {
+		return nil
+	}
+	var suggestions []model.SuggestionItem
+	for _, cls := range heap.TopClasses {
+		if cls.Percentage < r.Threshold || !r.Pattern.MatchString(cls.ClassName) {
+			continue
+		}
+		suggestions = append(suggestions, r.suggestion(cls.ClassName, map[string]string{
+			"name":    cls.ClassName,
+			"value":   formatMB(cls.TotalSize),
+			"percent": formatPercent(cls.Percentage),
+			"count":   fmt.Sprint(cls.InstanceCount),
+		}, []model.SuggestionEvidence{{
+			Kind:   model.EvidenceClass,
+			Name:   cls.ClassName,
+			Value:  float64(cls.TotalSize),
+			Detail: fmt.Sprintf("%d instances", cls.InstanceCount),
+		}}))
+	}
+	return suggestions
+}
+
+// ObjectRule matches the biggest objects of heap dumps whose class matches
+// Pattern and which retain at least Threshold percent of the heap. It
+// suggests per object, with the message placeholders {name}, the class,
+// {object}, the object ID, {value}, its retained size in MB, and {percent},
+// its share of the heap.
+type ObjectRule struct {
+	Meta
+	Pattern   *regexp.Regexp
+	Threshold float64
+}
+
+// Match implements Rule.
+func (r *ObjectRule) Match(resp *model.AnalysisResponse) []model.SuggestionItem {
+	heap := heapData(resp)
+	if heap == nil || heap.TotalHeapSize == 0 {
+		return nil
+	}
+	var suggestions []model.SuggestionItem
+	for _, obj := range heap.BiggestObjects {
+		percent := float64(obj.RetainedSize) / float64(heap.TotalHeapSize) * 100
+		if percent < r.Threshold || !r.Pattern.MatchString(obj.ClassName) {
+			continue
+		}
+		suggestions = append(suggestions, r.suggestion(obj.ClassName, map[string]string{
+			"name":    obj.ClassName,
+			"object":  obj.ObjectID,
+			"value":   formatMB(obj.RetainedSize),
+			"percent": formatPercent(percent),
+		}, []model.SuggestionEvidence{{
+			Kind:   model.EvidenceObject,
+			Name:   obj.ObjectID,
+			Value:  float64(obj.RetainedSize),
+			Detail: obj.ClassName,
+		}}))
+	}
+	return suggestions
+}
+
+// DuplicateStringsRule matches heap dumps whose duplicated String values
+// waste at least Threshold percent of the heap. Its message placeholders are
+// {value}, the wasted MB, {percent}, their share of the heap, and {count},
+// the number of copies.
+type DuplicateStringsRule struct {
+	Meta
+	Threshold float64
+}
+
+// Match implements Rule.
+func (r *DuplicateStringsRule) Match(resp *model.AnalysisResponse) []model.SuggestionItem {
+	heap := heapData(resp)
+	if heap == nil || heap.StringStats == nil || heap.TotalHeapSize == 0 {
+		return nil
+	}
+	stats := heap.StringStats
+	percent := float64(stats.DuplicateWaste) / float64(heap.TotalHeapSize) * 100
+	if stats.DuplicateCount == 0 || percent < r.Threshold {
+		return nil
+	}
+	evidence := make([]model.SuggestionEvidence, 0, maxEvidence)
+	for i, d := range stats.TopDuplicates {
+		if i == maxEvidence {
+			break
+		}
+		evidence = append(evidence, model.SuggestionEvidence{
+			Kind:   model.EvidenceString,
+			Name:   d.Value,
+			Value:  float64(d.Waste),
+			Detail: fmt.Sprintf("%d copies", d.Count),
+		})
+	}
+	meta := r.Meta
+	if meta.Message == "" {
+		meta.Message = "重复的 String 值浪费了 {value} MB 堆内存 ({percent}%，{count} 个副本)，建议对高频值使用 String.intern() 或常量，或开启 -XX:+UseStringDeduplication"
+	}
+	return []model.SuggestionItem{meta.suggestion("java.lang.String", map[string]string{
+		"value":   formatMB(stats.DuplicateWaste),
+		"percent": formatPercent(percent),
+		"count":   fmt.Sprint(stats.DuplicateCount),
+	}, evidence)}
+}
+
+// ClassLoaderLeakRule matches heap dumps with at least MinInstances class
+// loaders of a class defining MinClassesPerLoader classes each on average,
+// as left by redeployed applications leaking their class loader. It
+// suggests per loader class, with the message placeholders {name}, the
+// loader class, {count}, its instances, and {value}, the classes they define.
+type ClassLoaderLeakRule struct {
+	Meta
+	MinInstances        int
+	MinClassesPerLoader int
+}
+
+// Match implements Rule.
+func (r *ClassLoaderLeakRule) Match(resp *model.AnalysisResponse) []model.SuggestionItem {
+	heap := heapData(resp)
+	if heap == nil {
+		return nil
+	}
+	meta := r.Meta
+	if meta.Message == "" {
+		meta.Message = "类加载器 {name} 有 {count} 个实例，共定义了 {value} 个类，可能存在类加载器泄漏（如应用重新部署后旧的类加载器未被回收），建议检查线程、ThreadLocal 和静态注册表对应用类的引用"
+	}
+	var suggestions []model.SuggestionItem
+	for _, loader := range heap.ClassLoaders {
+		if loader.Instances < r.MinInstances || loader.DefinedClasses < r.MinClassesPerLoader*loader.Instances {
+			continue
+		}
+		suggestions = append(suggestions, meta.suggestion(loader.ClassName, map[string]string{
+			"name":  loader.ClassName,
+			"count": fmt.Sprint(loader.Instances),
+			"value": fmt.Sprint(loader.DefinedClasses),
+		}, []model.SuggestionEvidence{{
+			Kind:   model.EvidenceClass,
+			Name:   loader.ClassName,
+			Value:  float64(loader.DefinedClasses),
+			Detail: fmt.Sprintf("%d instances", loader.Instances),
+		}}))
+	}
+	return suggestions
+}
+
+// topEvidence returns the maxEvidence items of highest value.
+func topEvidence(evidence []model.SuggestionEvidence) []model.SuggestionEvidence {
+	sort.Slice(evidence, func(i, j int) bool {
+		if evidence[i].Value != evidence[j].Value {
+			return evidence[i].Value > evidence[j].Value
+		}
+		return strings.Compare(evidence[i].Name, evidence[j].Name) < 0
+	})
+	if len(evidence) > maxEvidence {
+		evidence = evidence[:maxEvidence]
+	}
+	return evidence
+}
@@ -0,0 +1,106 @@
+package rules
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/perf-analysis/pkg/model"
+)
+
+// Points deducted from health scores per suggestion of a rule, by severity.
+var severityPoints = map[string]float64{
+	SeverityInfo:     2,
+	SeverityWarning:  10,
+	SeverityCritical: 25,
+}
+
+// Bounds of the deductions of health scores.
+const (
+	maxRulePoints   = 60 // all the suggestions of rules together
+	maxMetricPoints = 20 // each key metric
+)
+
+// Lowest scores of the grades of health scores.
+const (
+	healthGoodScore    = 80
+	healthWarningScore = 50
+)
+
+// Thresholds of the key metrics of health scores: metrics deduct nothing up
+// to their low bound, and maxMetricPoints from their high bound, linearly.
+var (
+	liveHeapThresholds = [2]float64{70, 95} // % of the heap reachable
+	topClassThresholds = [2]float64{30, 80} // % of the heap in the largest class
+	hotSpotThresholds  = [2]float64{15, 50} // % of the samples in the hottest function
+)
+
+// HealthScore rates an analysis result from 100 down, by deducting points
+// for the suggestions of rules, by severity, and for the key metrics of the
+// result: the share of the heap of heap dumps still reachable and held by
+// the largest class, and the share of the samples of CPU profiles in the
+// hottest function.
+func HealthScore(resp *model.AnalysisResponse) *model.HealthScore {
+	var deductions []model.HealthDeduction
+	deduct := func(reason string, points float64) {
+		if points > 0 {
+			deductions = append(deductions, model.HealthDeduction{Reason: reason, Points: math.Round(points*10) / 10})
+		}
+	}
+
+	// Suggestions of rules, bounded as many of them tell the same story
+	var rulePoints float64
+	for _, s := range resp.Suggestions {
+		if s.Rule == "" {
+			continue
+		}
+		points := math.Min(severityPoints[s.Severity], maxRulePoints-rulePoints)
+		rulePoints += points
+		deduct(fmt.Sprintf("%s: %s", s.Severity, s.Rule), points)
+	}
+
+	if heap := heapData(resp); heap != nil && heap.TotalHeapSize > 0 {
+		if heap.LiveBytes > 0 {
+			live := float64(heap.LiveBytes) / float64(heap.TotalHeapSize) * 100
+			deduct(fmt.Sprintf("%s%% of the heap is reachable", formatPercent(live)), metricPoints(live, liveHeapThresholds))
+		}
+		if len(heap.TopClasses) > 0 {
+			top := heap.TopClasses[0]
+			deduct(fmt.Sprintf("%s holds %s%% of the heap", top.ClassName, formatPercent(top.Percentage)), metricPoints(top.Percentage, topClassThresholds))
+		}
+	}
+	if cpu := cpuData(resp); cpu != nil {
+		var hottest string
+		var self float64
+		for name, v := range cpu.TopFuncs {
+			if v.Self > self || (v.Self == self && name < hottest) {
+				hottest, self = name, v.Self
+			}
+		}
+		if hottest != "" {
+			deduct(fmt.Sprintf("%s takes %s%% of the samples", hottest, formatPercent(self)), metricPoints(self, hotSpotThresholds))
+		}
+	}
+
+	var total float64
+	for _, d := range deductions {
+		total += d.Points
+	}
+	score := int(math.Round(math.Max(0, 100-total)))
+	health := &model.HealthScore{Score: score, Grade: model.HealthCritical, Deductions: deductions}
+	switch {
+	case score >= healthGoodScore:
+		health.Grade = model.HealthGood
+	case score >= healthWarningScore:
+		health.Grade = model.HealthWarning
+	}
+	return health
+}
+
+// metricPoints returns the points deducted for the value of a key metric.
+func metricPoints(value float64, thresholds [2]float64) float64 {
+	low, high := thresholds[0], thresholds[1]
+	if value <= low {
+		return 0
+	}
+	return maxMetricPoints * math.Min(1, (value-low)/(high-low))
+}
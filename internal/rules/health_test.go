@@ -0,0 +1,72 @@
+package rules
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/perf-analysis/pkg/model"
+)
+
+func TestHealthScore_Good(t *testing.T) {
+	resp := &model.AnalysisResponse{Data: &model.CPUProfilingData{
+		TopFuncs: model.TopFuncsMap{"a": {Self: 10}, "b": {Self: 5}},
+	}}
+
+	health := HealthScore(resp)
+	assert.Equal(t, 100, health.Score)
+	assert.Equal(t, model.HealthGood, health.Grade)
+	assert.Empty(t, health.Deductions)
+}
+
+func TestHealthScore_CPU(t *testing.T) {
+	resp := cpuResponse()
+	Default().Apply(resp)
+
+	require.NotNil(t, resp.Health)
+	assert.Equal(t, []model.HealthDeduction{
+		{Reason: "warning: cpu.regex_hotspot", Points: 10},
+		{Reason: "warning: cpu.gc_threads", Points: 10},
+		{Reason: "com.example.App.run takes 30% of the samples", Points: 8.6}, // (30-15)/35*20
+	}, resp.Health.Deductions)
+	assert.Equal(t, 71, resp.Health.Score)
+	assert.Equal(t, model.HealthWarning, resp.Health.Grade)
+}
+
+func TestHealthScore_Heap(t *testing.T) {
+	resp := heapResponse()
+	resp.Data.(*model.HeapAnalysisData).LiveBytes = 90 << 20
+	Default().Apply(resp)
+
+	require.NotNil(t, resp.Health)
+	assert.Equal(t, []model.HealthDeduction{
+		{Reason: "warning: heap.huge_hashmap", Points: 10},
+		{Reason: "warning: heap.duplicate_strings", Points: 10},
+		{Reason: "critical: heap.classloader_leak", Points: 25},
+		{Reason: "90% of the heap is reachable", Points: 16}, // (90-70)/25*20
+		{Reason: "byte[] holds 40% of the heap", Points: 4},  // (40-30)/50*20
+	}, resp.Health.Deductions)
+	assert.Equal(t, 35, resp.Health.Score)
+	assert.Equal(t, model.HealthCritical, resp.Health.Grade)
+}
+
+func TestHealthScore_RulePointsBounded(t *testing.T) {
+	resp := &model.AnalysisResponse{Data: &model.CPUProfilingData{}}
+	for i := 0; i < 5; i++ {
+		resp.Suggestions = append(resp.Suggestions, model.SuggestionItem{Rule: "r", Severity: SeverityCritical})
+	}
+	// Suggestions of analyzers, without rule, are not rated
+	resp.Suggestions = append(resp.Suggestions, model.SuggestionItem{Suggestion: "check GC"})
+
+	health := HealthScore(resp)
+	require.Len(t, health.Deductions, 3)
+	assert.Equal(t, 10.0, health.Deductions[2].Points)
+	assert.Equal(t, 100-maxRulePoints, health.Score)
+}
+
+func TestEngine_Apply_NoData(t *testing.T) {
+	resp := &model.AnalysisResponse{}
+	Default().Apply(resp)
+	assert.Nil(t, resp.Health)
+}
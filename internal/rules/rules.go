@@ -0,0 +1,178 @@
+// Package rules turns analysis results into suggestions with pluggable
+// rules: a built-in rule set for heap dumps and CPU profiles, and custom rule
+// sets loaded from YAML files.
+package rules
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/perf-analysis/pkg/model"
+)
+
+// Severities of suggestions, from the least to the most severe.
+const (
+	SeverityInfo     = "info"
+	SeverityWarning  = "warning"
+	SeverityCritical = "critical"
+)
+
+// Rule produces suggestions from an analysis result.
+type Rule interface {
+	// Name identifies the rule, e.g. "heap.huge_hashmap".
+	Name() string
+
+	// Match returns the suggestions of the rule for an analysis result,
+	// none if the rule does not apply to it.
+	Match(resp *model.AnalysisResponse) []model.SuggestionItem
+}
+
+// Engine evaluates a set of rules against analysis results.
+type Engine struct {
+	rules []Rule
+}
+
+// NewEngine creates an engine evaluating the given rules.
+func NewEngine(rules ...Rule) *Engine {
+	e := &Engine{}
+	e.Add(rules...)
+	return e
+}
+
+// Default creates an engine evaluating the built-in rules.
+func Default() *Engine {
+	return NewEngine(BuiltinRules()...)
+}
+
+// Add adds rules to the engine. A rule replaces the rule of the same name.
+func (e *Engine) Add(rules ...Rule) {
+	for _, rule := range rules {
+		if i := e.index(rule.Name()); i >= 0 {
+			e.rules[i] = rule
+			continue
+		}
+		e.rules = append(e.rules, rule)
+	}
+}
+
+// Remove removes the rules of the given names, if any.
+func (e *Engine) Remove(names ...string) {
+	for _, name := range names {
+		if i := e.index(name); i >= 0 {
+			e.rules = append(e.rules[:i], e.rules[i+1:]...)
+		}
+	}
+}
+
+// Load adds the rules of a rule set, after removing the rules it disables.
+func (e *Engine) Load(set *RuleSet) {
+	e.Remove(set.Disable...)
+	e.Add(set.Rules...)
+}
+
+// Rules returns the rules of the engine, in evaluation order.
+func (e *Engine) Rules() []Rule {
+	return append([]Rule(nil), e.rules...)
+}
+
+// Evaluate returns the suggestions of the rules for an analysis result, in
+// the order of the rules. Suggestions are tagged with the name of their rule
+// and default to SeverityInfo.
+func (e *Engine) Evaluate(resp *model.AnalysisResponse) []model.SuggestionItem {
+	if resp == nil || resp.Data == nil {
+		return nil
+	}
+	var suggestions []model.SuggestionItem
+	for _, rule := range e.rules {
+		for _, s := range rule.Match(resp) {
+			if s.Rule == "" {
+				s.Rule = rule.Name()
+			}
+			if s.Severity == "" {
+				s.Severity = SeverityInfo
+			}
+			suggestions = append(suggestions, s)
+		}
+	}
+	return suggestions
+}
+
+// Apply appends the suggestions of the rules to an analysis result, and
+// rates its health.
+func (e *Engine) Apply(resp *model.AnalysisResponse) {
+	if resp == nil || resp.Data == nil {
+		return
+	}
+	if suggestions := e.Evaluate(resp); len(suggestions) > 0 {
+		resp.Suggestions = append(resp.Suggestions, suggestions...)
+	}
+	resp.Health = HealthScore(resp)
+}
+
+// index returns the position of the rule of a name, -1 if none.
+func (e *Engine) index(name string) int {
+	for i, rule := range e.rules {
+		if rule.Name() == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// Meta holds the description of a rule shared by all rule types.
+type Meta struct {
+	RuleName string
+	Severity string
+	// Message is the suggestion text, where placeholders like {name} are
+	// replaced by the values of the match documented by each rule type
+	Message string
+	DocURL  string
+}
+
+// Name returns the name of the rule.
+func (m *Meta) Name() string {
+	return m.RuleName
+}
+
+// suggestion returns a suggestion of the rule about an item, e.g. a function.
+func (m *Meta) suggestion(item string, vars map[string]string, evidence []model.SuggestionEvidence) model.SuggestionItem {
+	pairs := make([]string, 0, 2*len(vars))
+	for k, v := range vars {
+		pairs = append(pairs, "{"+k+"}", v)
+	}
+	return model.SuggestionItem{
+		Suggestion: strings.NewReplacer(pairs...).Replace(m.Message),
+		FuncName:   item,
+		Rule:       m.RuleName,
+		Severity:   m.Severity,
+		Evidence:   evidence,
+		DocURL:     m.DocURL,
+	}
+}
+
+// cpuData returns the CPU profile of a result, nil if none.
+func cpuData(resp *model.AnalysisResponse) *model.CPUProfilingData {
+	data := resp.Data
+	if jfr, ok := data.(*model.JFRData); ok {
+		data = jfr.Primary()
+	}
+	cpu, _ := data.(*model.CPUProfilingData)
+	return cpu
+}
+
+// heapData returns the heap dump analysis of a result, nil if none.
+func heapData(resp *model.AnalysisResponse) *model.HeapAnalysisData {
+	heap, _ := resp.Data.(*model.HeapAnalysisData)
+	return heap
+}
+
+// formatPercent formats a percentage with up to 2 decimals.
+func formatPercent(pct float64) string {
+	s := strings.TrimRight(strconv.FormatFloat(pct, 'f', 2, 64), "0")
+	return strings.TrimRight(s, ".")
+}
+
+// formatMB formats bytes in megabytes.
+func formatMB(bytes int64) string {
+	return strconv.FormatFloat(float64(bytes)/(1024*1024), 'f', 2, 64)
+}
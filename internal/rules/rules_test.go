@@ -0,0 +1,165 @@
+package rules
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/perf-analysis/pkg/model"
+)
+
+func cpuResponse() *model.AnalysisResponse {
+	return &model.AnalysisResponse{
+		Data: &model.CPUProfilingData{
+			TotalSamples: 1000,
+			TopFuncs: model.TopFuncsMap{
+				"java.util.regex.Pattern$CharProperty.match": {Self: 4},
+				"java.util.regex.Matcher.search":             {Self: 3},
+				"com.example.App.run":                        {Self: 30},
+			},
+			ThreadStats: []model.ThreadInfo{
+				{TID: 10, ThreadName: "main", Samples: 500, Percentage: 50},
+				{TID: 11, ThreadName: "GC Thread#0", Samples: 150, Percentage: 15},
+				{TID: 12, ThreadName: "GC Thread#1", Samples: 100, Percentage: 10},
+				{TID: 13, ThreadName: "C2 CompilerThread0", Samples: 50, Percentage: 5},
+			},
+		},
+	}
+}
+
+func heapResponse() *model.AnalysisResponse {
+	return &model.AnalysisResponse{
+		Data: &model.HeapAnalysisData{
+			TotalHeapSize: 100 << 20,
+			TopClasses: []model.HeapClassStats{
+				{ClassName: "byte[]", InstanceCount: 1000, TotalSize: 40 << 20, Percentage: 40},
+				{ClassName: "java.util.HashMap$Node", InstanceCount: 500, TotalSize: 5 << 20, Percentage: 5},
+			},
+			BiggestObjects: []model.HeapBiggestObject{
+				{ObjectID: "0x1000", ClassName: "java.util.HashMap", RetainedSize: 30 << 20},
+				{ObjectID: "0x2000", ClassName: "java.util.ArrayList", RetainedSize: 20 << 20},
+				{ObjectID: "0x3000", ClassName: "java.util.concurrent.ConcurrentHashMap", RetainedSize: 5 << 20},
+			},
+			StringStats: &model.HeapStringStats{
+				TotalCount:     10000,
+				DuplicateCount: 6000,
+				DuplicateWaste: 8 << 20,
+				TopDuplicates: []model.HeapDuplicateString{
+					{Value: "OK", Count: 5000, Waste: 6 << 20},
+					{Value: "FAILED", Count: 1000, Waste: 2 << 20},
+				},
+			},
+			ClassLoaders: []model.HeapClassLoaderStats{
+				{ClassName: "org.apache.catalina.loader.ParallelWebappClassLoader", Instances: 4, DefinedClasses: 2000},
+				{ClassName: "jdk.internal.loader.ClassLoaders$AppClassLoader", Instances: 1, DefinedClasses: 5000},
+				{ClassName: "com.example.PluginLoader", Instances: 10, DefinedClasses: 30},
+			},
+		},
+	}
+}
+
+func ruleNames(suggestions []model.SuggestionItem) []string {
+	names := make([]string, len(suggestions))
+	for i, s := range suggestions {
+		names[i] = s.Rule
+	}
+	return names
+}
+
+func TestEngine_AddRemove(t *testing.T) {
+	e := NewEngine(
+		&HotSpotRule{Meta: Meta{RuleName: "a"}},
+		&HotSpotRule{Meta: Meta{RuleName: "b"}},
+	)
+	replacement := &ThreadShareRule{Meta: Meta{RuleName: "a"}}
+	e.Add(replacement, &ClassRule{Meta: Meta{RuleName: "c"}})
+
+	rules := e.Rules()
+	require.Len(t, rules, 3)
+	assert.Same(t, replacement, rules[0])
+	assert.Equal(t, "b", rules[1].Name())
+	assert.Equal(t, "c", rules[2].Name())
+
+	e.Remove("b", "unknown")
+	require.Len(t, e.Rules(), 2)
+	assert.Equal(t, "c", e.Rules()[1].Name())
+}
+
+func TestEngine_Evaluate_CPU(t *testing.T) {
+	suggestions := Default().Evaluate(cpuResponse())
+	require.Equal(t, []string{"cpu.regex_hotspot", "cpu.gc_threads"}, ruleNames(suggestions))
+
+	regex := suggestions[0]
+	assert.Equal(t, SeverityWarning, regex.Severity)
+	assert.Equal(t, "java.util.regex.Pattern$CharProperty.match", regex.FuncName)
+	assert.Contains(t, regex.Suggestion, "(7%)")
+	assert.NotEmpty(t, regex.DocURL)
+	require.Len(t, regex.Evidence, 2)
+	assert.Equal(t, model.EvidenceFunction, regex.Evidence[0].Kind)
+	assert.Equal(t, 4.0, regex.Evidence[0].Value)
+
+	gc := suggestions[1]
+	assert.Contains(t, gc.Suggestion, "25%")
+	assert.Contains(t, gc.Suggestion, "2 个线程")
+	require.Len(t, gc.Evidence, 2)
+	assert.Equal(t, "GC Thread#0", gc.Evidence[0].Name)
+	assert.Equal(t, "tid 11", gc.Evidence[0].Detail)
+}
+
+func TestEngine_Evaluate_Heap(t *testing.T) {
+	suggestions := Default().Evaluate(heapResponse())
+	require.Equal(t, []string{"heap.huge_hashmap", "heap.duplicate_strings", "heap.classloader_leak"}, ruleNames(suggestions))
+
+	hashMap := suggestions[0]
+	assert.Equal(t, "java.util.HashMap", hashMap.FuncName)
+	assert.Contains(t, hashMap.Suggestion, "0x1000")
+	assert.Contains(t, hashMap.Suggestion, "30.00 MB")
+	assert.Equal(t, []model.SuggestionEvidence{{Kind: model.EvidenceObject, Name: "0x1000", Value: 30 << 20, Detail: "java.util.HashMap"}}, hashMap.Evidence)
+
+	strs := suggestions[1]
+	assert.Contains(t, strs.Suggestion, "8.00 MB")
+	assert.Contains(t, strs.Suggestion, "(8%")
+	require.Len(t, strs.Evidence, 2)
+	assert.Equal(t, "OK", strs.Evidence[0].Name)
+	assert.Equal(t, "5000 copies", strs.Evidence[0].Detail)
+
+	loader := suggestions[2]
+	assert.Equal(t, SeverityCritical, loader.Severity)
+	assert.Equal(t, "org.apache.catalina.loader.ParallelWebappClassLoader", loader.FuncName)
+}
+
+func TestEngine_Evaluate_NoData(t *testing.T) {
+	e := Default()
+	assert.Nil(t, e.Evaluate(nil))
+	assert.Nil(t, e.Evaluate(&model.AnalysisResponse{}))
+	assert.Empty(t, e.Evaluate(&model.AnalysisResponse{Data: &model.CPUProfilingData{}}))
+	assert.Empty(t, e.Evaluate(&model.AnalysisResponse{Data: &model.HeapAnalysisData{}}))
+}
+
+func TestEngine_Apply(t *testing.T) {
+	resp := heapResponse()
+	resp.Suggestions = []model.SuggestionItem{{Suggestion: "existing"}}
+
+	e := NewEngine(&ClassRule{
+		Meta:      Meta{RuleName: "heap.byte_arrays", Message: "{name}: {count} 个实例, {value} MB ({percent}%)"},
+		Pattern:   regexp.MustCompile(`^byte\[\]$`),
+		Threshold: 30,
+	})
+	e.Apply(resp)
+
+	require.Len(t, resp.Suggestions, 2)
+	assert.Equal(t, "existing", resp.Suggestions[0].Suggestion)
+	s := resp.Suggestions[1]
+	assert.Equal(t, "byte[]: 1000 个实例, 40.00 MB (40%)", s.Suggestion)
+	assert.Equal(t, "heap.byte_arrays", s.Rule)
+	assert.Equal(t, SeverityInfo, s.Severity)
+}
+
+func TestFormatPercent(t *testing.T) {
+	assert.Equal(t, "7", formatPercent(7))
+	assert.Equal(t, "12.5", formatPercent(12.5))
+	assert.Equal(t, "0.33", formatPercent(1.0/3))
+	assert.Equal(t, "100", formatPercent(100))
+}
@@ -0,0 +1,141 @@
+package rules
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Rule types of rule set files.
+const (
+	TypeHotSpot          = "hotspot"
+	TypeThreadShare      = "thread_share"
+	TypeClass            = "class"
+	TypeObject           = "object"
+	TypeDuplicateStrings = "duplicate_strings"
+	TypeClassLoaderLeak  = "classloader_leak"
+)
+
+// RuleSet is a set of custom rules, loaded from a YAML file like:
+//
+//	rules:
+//	  - name: cpu.json_hotspot
+//	    type: hotspot
+//	    pattern: 'com\.fasterxml\.jackson\.'
+//	    threshold: 10
+//	    severity: warning
+//	    message: "JSON 序列化占用了 {value}% 的 CPU，建议复用 ObjectMapper"
+//	    doc_url: https://github.com/FasterXML/jackson-docs
+//	disable:
+//	  - heap.duplicate_strings
+//
+// A rule replaces the built-in rule of the same name, and disable removes
+// rules by name.
+type RuleSet struct {
+	Rules   []Rule
+	Disable []string
+}
+
+// ruleSpec is a rule of a rule set file.
+type ruleSpec struct {
+	Name         string  `yaml:"name"`
+	Type         string  `yaml:"type"`
+	Pattern      string  `yaml:"pattern"`
+	Threshold    float64 `yaml:"threshold"`
+	MinInstances int     `yaml:"min_instances"`
+	MinClasses   int     `yaml:"min_classes"`
+	Severity     string  `yaml:"severity"`
+	Message      string  `yaml:"message"`
+	DocURL       string  `yaml:"doc_url"`
+}
+
+// ruleSetSpec is a rule set file.
+type ruleSetSpec struct {
+	Rules   []ruleSpec `yaml:"rules"`
+	Disable []string   `yaml:"disable"`
+}
+
+// LoadFile loads a rule set from a YAML file.
+func LoadFile(path string) (*RuleSet, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open rule set: %w", err)
+	}
+	defer f.Close()
+
+	set, err := LoadRuleSet(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return set, nil
+}
+
+// LoadRuleSet loads a rule set from YAML.
+func LoadRuleSet(r io.Reader) (*RuleSet, error) {
+	var spec ruleSetSpec
+	dec := yaml.NewDecoder(r)
+	dec.KnownFields(true)
+	if err := dec.Decode(&spec); err != nil && err != io.EOF {
+		return nil, fmt.Errorf("failed to parse rule set: %w", err)
+	}
+
+	set := &RuleSet{Disable: spec.Disable}
+	for _, rs := range spec.Rules {
+		rule, err := rs.build()
+		if err != nil {
+			return nil, err
+		}
+		set.Rules = append(set.Rules, rule)
+	}
+	return set, nil
+}
+
+// build returns the rule of a spec.
+func (rs *ruleSpec) build() (Rule, error) {
+	if rs.Name == "" {
+		return nil, fmt.Errorf("rule without name")
+	}
+	meta := Meta{RuleName: rs.Name, Severity: rs.Severity, Message: rs.Message, DocURL: rs.DocURL}
+	switch meta.Severity {
+	case "":
+		meta.Severity = SeverityInfo
+	case SeverityInfo, SeverityWarning, SeverityCritical:
+	default:
+		return nil, fmt.Errorf("rule %q: unknown severity %q", rs.Name, rs.Severity)
+	}
+
+	var pattern *regexp.Regexp
+	switch rs.Type {
+	case TypeHotSpot, TypeThreadShare, TypeClass, TypeObject:
+		if rs.Pattern == "" {
+			return nil, fmt.Errorf("rule %q: pattern is required for type %s", rs.Name, rs.Type)
+		}
+		var err error
+		if pattern, err = regexp.Compile(rs.Pattern); err != nil {
+			return nil, fmt.Errorf("rule %q: invalid pattern: %w", rs.Name, err)
+		}
+		if meta.Message == "" {
+			return nil, fmt.Errorf("rule %q: message is required for type %s", rs.Name, rs.Type)
+		}
+	}
+
+	switch rs.Type {
+	case TypeHotSpot:
+		return &HotSpotRule{Meta: meta, Pattern: pattern, Threshold: rs.Threshold}, nil
+	case TypeThreadShare:
+		return &ThreadShareRule{Meta: meta, Pattern: pattern, Threshold: rs.Threshold}, nil
+	case TypeClass:
+		return &ClassRule{Meta: meta, Pattern: pattern, Threshold: rs.Threshold}, nil
+	case TypeObject:
+		return &ObjectRule{Meta: meta, Pattern: pattern, Threshold: rs.Threshold}, nil
+	case TypeDuplicateStrings:
+		return &DuplicateStringsRule{Meta: meta, Threshold: rs.Threshold}, nil
+	case TypeClassLoaderLeak:
+		return &ClassLoaderLeakRule{Meta: meta, MinInstances: rs.MinInstances, MinClassesPerLoader: rs.MinClasses}, nil
+	default:
+		return nil, fmt.Errorf("rule %q: unknown type %q", rs.Name, rs.Type)
+	}
+}
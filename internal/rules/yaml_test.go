@@ -0,0 +1,112 @@
+package rules
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testRuleSet = `
+rules:
+  - name: cpu.json_hotspot
+    type: hotspot
+    pattern: 'com\.fasterxml\.jackson\.'
+    threshold: 10
+    severity: warning
+    message: "JSON 序列化占用了 {value}% 的 CPU"
+    doc_url: https://github.com/FasterXML/jackson-docs
+  - name: heap.duplicate_strings
+    type: duplicate_strings
+    threshold: 1
+  - name: heap.classloader_leak
+    type: classloader_leak
+    min_instances: 5
+    min_classes: 1
+    severity: critical
+disable:
+  - cpu.gc_threads
+`
+
+func TestLoadRuleSet(t *testing.T) {
+	set, err := LoadRuleSet(strings.NewReader(testRuleSet))
+	require.NoError(t, err)
+	require.Len(t, set.Rules, 3)
+	assert.Equal(t, []string{"cpu.gc_threads"}, set.Disable)
+
+	hotspot, ok := set.Rules[0].(*HotSpotRule)
+	require.True(t, ok)
+	assert.Equal(t, "cpu.json_hotspot", hotspot.Name())
+	assert.Equal(t, SeverityWarning, hotspot.Severity)
+	assert.Equal(t, 10.0, hotspot.Threshold)
+	assert.True(t, hotspot.Pattern.MatchString("com.fasterxml.jackson.core.JsonGenerator.writeString"))
+	assert.Equal(t, "https://github.com/FasterXML/jackson-docs", hotspot.DocURL)
+
+	strs, ok := set.Rules[1].(*DuplicateStringsRule)
+	require.True(t, ok)
+	assert.Equal(t, SeverityInfo, strs.Severity)
+	assert.Equal(t, 1.0, strs.Threshold)
+
+	loader, ok := set.Rules[2].(*ClassLoaderLeakRule)
+	require.True(t, ok)
+	assert.Equal(t, 5, loader.MinInstances)
+	assert.Equal(t, 1, loader.MinClassesPerLoader)
+}
+
+func TestLoadRuleSet_Empty(t *testing.T) {
+	set, err := LoadRuleSet(strings.NewReader(""))
+	require.NoError(t, err)
+	assert.Empty(t, set.Rules)
+	assert.Empty(t, set.Disable)
+}
+
+func TestLoadRuleSet_Invalid(t *testing.T) {
+	tests := []struct {
+		name string
+		yaml string
+		err  string
+	}{
+		{"no name", "rules:\n  - type: duplicate_strings", "rule without name"},
+		{"unknown type", "rules:\n  - name: x\n    type: magic", `rule "x": unknown type "magic"`},
+		{"unknown severity", "rules:\n  - name: x\n    type: duplicate_strings\n    severity: fatal", `rule "x": unknown severity "fatal"`},
+		{"no pattern", "rules:\n  - name: x\n    type: hotspot\n    message: m", `rule "x": pattern is required`},
+		{"bad pattern", "rules:\n  - name: x\n    type: class\n    pattern: '('\n    message: m", `rule "x": invalid pattern`},
+		{"no message", "rules:\n  - name: x\n    type: object\n    pattern: 'a'", `rule "x": message is required`},
+		{"unknown field", "rules:\n  - name: x\n    type: hotspot\n    treshold: 5", "failed to parse rule set"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := LoadRuleSet(strings.NewReader(tt.yaml))
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.err)
+		})
+	}
+}
+
+func TestEngine_Load(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rules.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(testRuleSet), 0644))
+
+	set, err := LoadFile(path)
+	require.NoError(t, err)
+
+	e := Default()
+	e.Load(set)
+
+	names := make([]string, 0, len(e.Rules()))
+	for _, rule := range e.Rules() {
+		names = append(names, rule.Name())
+	}
+	assert.Equal(t, []string{"heap.huge_hashmap", "heap.duplicate_strings", "heap.classloader_leak", "cpu.regex_hotspot", "cpu.json_hotspot"}, names)
+
+	// The custom classloader rule requires 5 instances of a loader
+	suggestions := e.Evaluate(heapResponse())
+	assert.Equal(t, []string{"heap.huge_hashmap", "heap.duplicate_strings", "heap.classloader_leak"}, ruleNames(suggestions))
+	assert.Equal(t, "com.example.PluginLoader", suggestions[2].FuncName)
+
+	_, err = LoadFile(filepath.Join(t.TempDir(), "missing.yaml"))
+	assert.Error(t, err)
+}
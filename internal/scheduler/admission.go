@@ -0,0 +1,60 @@
+package scheduler
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/perf-analysis/internal/parser/hprof"
+	"github.com/perf-analysis/internal/storage"
+	"github.com/perf-analysis/pkg/model"
+)
+
+// MemoryEstimator estimates the peak memory of the analysis of a task, for
+// admission control.
+type MemoryEstimator interface {
+	// EstimateMemory returns the estimated memory in bytes, 0 if unknown.
+	EstimateMemory(ctx context.Context, task *Task) (int64, error)
+}
+
+// HeapDumpEstimator estimates the memory of heap dump analyses from the size
+// of the dump and the object density of its first bytes. Other tasks need
+// little memory, and are estimated at 0.
+type HeapDumpEstimator struct {
+	storage     storage.Storage
+	sampleBytes int64
+}
+
+// NewHeapDumpEstimator creates an estimator sampling the first sampleBytes
+// of the dumps in storage.
+func NewHeapDumpEstimator(storage storage.Storage, sampleBytes int64) *HeapDumpEstimator {
+	return &HeapDumpEstimator{storage: storage, sampleBytes: sampleBytes}
+}
+
+// EstimateMemory estimates the memory of the analysis of a heap dump task.
+// Dumps in storages which cannot tell object sizes are estimated at 0.
+func (e *HeapDumpEstimator) EstimateMemory(ctx context.Context, task *Task) (int64, error) {
+	if task.Type != model.TaskTypeJavaHeap {
+		return 0, nil
+	}
+	sizer, ok := e.storage.(storage.Sizer)
+	if !ok {
+		return 0, nil
+	}
+
+	size, err := sizer.Size(ctx, task.ResultFile)
+	if err != nil {
+		return 0, err
+	}
+
+	reader, err := e.storage.Download(ctx, task.ResultFile)
+	if err != nil {
+		return 0, err
+	}
+	defer reader.Close()
+
+	sample, err := hprof.SampleHeapDump(reader, e.sampleBytes)
+	if err != nil {
+		return 0, fmt.Errorf("failed to sample heap dump: %w", err)
+	}
+	return hprof.EstimateParseMemory(sample.EstimateObjects(size)), nil
+}
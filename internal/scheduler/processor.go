@@ -3,16 +3,24 @@ package scheduler
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
+	"time"
 
 	"github.com/perf-analysis/internal/advisor"
 	"github.com/perf-analysis/internal/analyzer"
+	"github.com/perf-analysis/internal/notify"
+	"github.com/perf-analysis/internal/parser/hprof"
 	"github.com/perf-analysis/internal/repository"
+	"github.com/perf-analysis/internal/rules"
 	"github.com/perf-analysis/internal/storage"
+	"github.com/perf-analysis/internal/symbol"
 	"github.com/perf-analysis/pkg/config"
+	apperrors "github.com/perf-analysis/pkg/errors"
 	"github.com/perf-analysis/pkg/model"
+	"github.com/perf-analysis/pkg/telemetry"
 	"github.com/perf-analysis/pkg/utils"
 )
 
@@ -23,6 +31,8 @@ type DefaultTaskProcessor struct {
 	rawDataStorage  storage.Storage // Optional separate storage for raw data
 	repos           *repository.Repositories
 	analyzerFactory *analyzer.Factory
+	notifier        *notify.Notifier
+	suggestionRules *rules.Engine
 	logger          utils.Logger
 }
 
@@ -32,6 +42,7 @@ type ProcessorConfig struct {
 	Storage        storage.Storage
 	RawDataStorage storage.Storage
 	Repos          *repository.Repositories
+	Notifier       *notify.Notifier // Optional, notified of task results
 	Logger         utils.Logger
 }
 
@@ -47,6 +58,34 @@ func NewDefaultTaskProcessor(cfg *ProcessorConfig) *DefaultTaskProcessor {
 	}
 
 	analyzerConfig := analyzer.DefaultBaseAnalyzerConfig()
+	suggestionRules := rules.Default()
+	if cfg.Config != nil {
+		symbols := symbol.Config{
+			Vmlinux:        cfg.Config.Analysis.Symbols.Vmlinux,
+			Dirs:           cfg.Config.Analysis.Symbols.Dirs,
+			DebuginfodURLs: cfg.Config.Analysis.Symbols.DebuginfodURLs,
+			CacheDir:       cfg.Config.Analysis.Symbols.CacheDir,
+		}
+		if symbols.Enabled() {
+			analyzerConfig.Symbols = symbol.NewResolver(symbols)
+		}
+
+		for _, path := range cfg.Config.Analysis.RuleFiles {
+			set, err := rules.LoadFile(path)
+			if err != nil {
+				cfg.Logger.Warn("Failed to load suggestion rules: %v", err)
+				continue
+			}
+			suggestionRules.Load(set)
+		}
+
+		timeouts := cfg.Config.Analysis.Timeouts
+		analyzerConfig.HeapStageTimeouts = map[hprof.JobState]time.Duration{
+			hprof.JobParsing:     time.Duration(timeouts.HeapParse) * time.Second,
+			hprof.JobDominating:  time.Duration(timeouts.HeapDominators) * time.Second,
+			hprof.JobSerializing: time.Duration(timeouts.HeapSerialize) * time.Second,
+		}
+	}
 
 	return &DefaultTaskProcessor{
 		config:          cfg.Config,
@@ -54,32 +93,46 @@ func NewDefaultTaskProcessor(cfg *ProcessorConfig) *DefaultTaskProcessor {
 		rawDataStorage:  rawDataStorage,
 		repos:           cfg.Repos,
 		analyzerFactory: analyzer.NewFactory(analyzerConfig),
+		notifier:        cfg.Notifier,
+		suggestionRules: suggestionRules,
 		logger:          cfg.Logger,
 	}
 }
 
 // Process processes a single analysis task.
-func (p *DefaultTaskProcessor) Process(ctx context.Context, task *Task, rules []model.SuggestionRule) error {
+func (p *DefaultTaskProcessor) Process(ctx context.Context, task *Task, rules []model.SuggestionRule) (err error) {
 	p.logger.Info("Starting analysis for task %s (Type: %d, Profiler: %d)",
 		task.UUID, task.Type, task.ProfilerType)
 
+	var summary *model.AnalysisSummary
+	defer func() { p.notify(task, summary, err) }()
+
 	// Create task directory
 	taskDir := p.config.GetTaskDir(task.UUID)
 	if err := os.MkdirAll(taskDir, 0755); err != nil {
 		return fmt.Errorf("failed to create task directory: %w", err)
 	}
 
-	// Clean up task directory after processing
+	// Clean up task directory after processing, keeping the output files of
+	// the last attempt of failed tasks
 	defer func() {
+		if err != nil && !task.retryable(err) {
+			if artifacts := p.savePartialResults(ctx, task, taskDir); len(artifacts) > 0 {
+				err = &PartialResultError{Err: err, Artifacts: artifacts}
+			}
+		}
 		if err := os.RemoveAll(taskDir); err != nil {
 			p.logger.Warn("Failed to clean up task directory %s: %v", taskDir, err)
 		}
 	}()
 
-	// Download result file
-	localFile := filepath.Join(taskDir, filepath.Base(task.ResultFile))
-	if err := p.downloadResultFile(ctx, task, localFile); err != nil {
-		return fmt.Errorf("failed to download result file: %w", err)
+	// Download result file, unless it is streamed from storage
+	localFile := ""
+	if !p.streamsInput(task) {
+		localFile = filepath.Join(taskDir, filepath.Base(task.ResultFile))
+		if err := p.downloadResultFile(ctx, task, localFile); err != nil {
+			return apperrors.Wrap(apperrors.CodeDownloadError, "failed to download result file", err)
+		}
 	}
 
 	// Create the appropriate analyzer
@@ -100,14 +153,32 @@ func (p *DefaultTaskProcessor) Process(ctx context.Context, task *Task, rules []
 	}
 
 	// Execute analysis
-	result, err := p.executeAnalysis(ctx, a, analysisCtx)
+	analyzeCtx := ctx
+	if timeout := p.analysisTimeout(); timeout > 0 {
+		var cancel context.CancelFunc
+		analyzeCtx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	result, err := p.executeAnalysis(analyzeCtx, a, analysisCtx)
 	if err != nil {
 		return fmt.Errorf("analysis failed: %w", err)
 	}
+	if result.Response != nil {
+		p.suggestionRules.Apply(result.Response)
+		result.Suggestions = result.Response.Suggestions
+	}
 
 	// Save results
 	if err := p.saveResults(ctx, task, result, analysisCtx); err != nil {
-		return fmt.Errorf("failed to save results: %w", err)
+		return apperrors.Wrap(apperrors.CodeDatabaseError, "failed to save results", err)
+	}
+
+	// Save the summary kept for historical comparisons
+	summary = buildSummary(task, result)
+	p.compareWithBaseline(ctx, summary, result)
+	if err := p.saveSummary(ctx, summary); err != nil {
+		p.logger.Warn("Failed to save analysis summary: %v", err)
+		// Don't fail the task for summary errors
 	}
 
 	// Generate and save suggestions
@@ -125,20 +196,78 @@ func (p *DefaultTaskProcessor) Process(ctx context.Context, task *Task, rules []
 
 	// Update task status to completed
 	if err := p.repos.Task.UpdateAnalysisStatus(ctx, task.ID, model.AnalysisStatusCompleted); err != nil {
-		return fmt.Errorf("failed to update task status: %w", err)
+		return apperrors.Wrap(apperrors.CodeDatabaseError, "failed to update task status", err)
 	}
 
 	p.logger.Info("Task %s analysis completed successfully", task.UUID)
 	return nil
 }
 
+// partialResultMaxSize bounds the size of the output files of failed
+// analyses kept in storage.
+const partialResultMaxSize = 64 << 20
+
+// savePartialResults uploads the output files a failed analysis left in the
+// task directory, up to partialResultMaxSize each, and returns their storage
+// keys. The input file is not uploaded again.
+func (p *DefaultTaskProcessor) savePartialResults(ctx context.Context, task *Task, taskDir string) []string {
+	input := filepath.Base(task.ResultFile)
+
+	var keys []string
+	filepath.WalkDir(taskDir, func(path string, d os.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return nil
+		}
+		rel, err := filepath.Rel(taskDir, path)
+		if err != nil || rel == input {
+			return nil
+		}
+		if info, err := d.Info(); err != nil || info.Size() > partialResultMaxSize {
+			return nil
+		}
+
+		key := fmt.Sprintf("%s/partial/%s", task.UUID, filepath.ToSlash(rel))
+		if err := p.storage.UploadFile(ctx, key, path); err != nil {
+			p.logger.Warn("Failed to upload partial result %s: %v", rel, err)
+			return nil
+		}
+		keys = append(keys, key)
+		return nil
+	})
+	return keys
+}
+
 // downloadResultFile downloads the result file from storage.
-func (p *DefaultTaskProcessor) downloadResultFile(ctx context.Context, task *Task, localPath string) error {
+func (p *DefaultTaskProcessor) downloadResultFile(ctx context.Context, task *Task, localPath string) (err error) {
+	ctx, span := telemetry.StartSpan(ctx, "task.download")
+	defer func() { telemetry.EndSpan(span, err) }()
 	return p.rawDataStorage.DownloadFile(ctx, task.ResultFile, localPath)
 }
 
+// analysisTimeout returns the timeout of the analysis of a task, 0 for none.
+func (p *DefaultTaskProcessor) analysisTimeout() time.Duration {
+	if p.config == nil {
+		return 0
+	}
+	return time.Duration(p.config.Analysis.Timeouts.Analysis) * time.Second
+}
+
+// streamsInput reports whether the input of a task is analyzed as it is read
+// from storage. Heap dumps are parsed in a single pass, so large dumps need
+// not be staged on local disk; their object index is not built then.
+func (p *DefaultTaskProcessor) streamsInput(task *Task) bool {
+	return p.config.Storage.StreamInput && task.Type == model.TaskTypeJavaHeap
+}
+
 // executeAnalysis runs the analyzer on the input file.
-func (p *DefaultTaskProcessor) executeAnalysis(ctx context.Context, a analyzer.Analyzer, analysisCtx *AnalysisContext) (*AnalysisResult, error) {
+func (p *DefaultTaskProcessor) executeAnalysis(ctx context.Context, a analyzer.Analyzer, analysisCtx *AnalysisContext) (_ *AnalysisResult, err error) {
+	ctx, span := telemetry.StartSpan(ctx, "task.analyze")
+	defer func() { telemetry.EndSpan(span, err) }()
+
+	if analysisCtx.LocalFile == "" {
+		return p.executeStreamingAnalysis(ctx, a, analysisCtx)
+	}
+
 	// Read and parse the input file
 	file, err := os.Open(analysisCtx.LocalFile)
 	if err != nil {
@@ -178,8 +307,39 @@ func (p *DefaultTaskProcessor) executeAnalysis(ctx context.Context, a analyzer.A
 	}, nil
 }
 
+// executeStreamingAnalysis runs the analyzer on the input read from storage.
+func (p *DefaultTaskProcessor) executeStreamingAnalysis(ctx context.Context, a analyzer.Analyzer, analysisCtx *AnalysisContext) (*AnalysisResult, error) {
+	reader, err := p.rawDataStorage.Download(ctx, analysisCtx.Task.ResultFile)
+	if err != nil {
+		return nil, apperrors.Wrap(apperrors.CodeDownloadError, "failed to open input stream", err)
+	}
+	defer reader.Close()
+
+	req := &model.AnalysisRequest{
+		TaskUUID:      analysisCtx.Task.UUID,
+		TaskType:      analysisCtx.Task.Type,
+		ProfilerType:  analysisCtx.Task.ProfilerType,
+		OutputDir:     analysisCtx.TaskDir,
+		RequestParams: analysisCtx.Task.RequestParams,
+	}
+
+	resp, err := a.AnalyzeFromReader(ctx, req, reader)
+	if err != nil {
+		return nil, err
+	}
+
+	return &AnalysisResult{
+		Response:     resp,
+		TotalRecords: resp.TotalRecords,
+		Suggestions:  resp.Suggestions,
+	}, nil
+}
+
 // saveResults uploads generated files and saves results to database.
-func (p *DefaultTaskProcessor) saveResults(ctx context.Context, task *Task, result *AnalysisResult, analysisCtx *AnalysisContext) error {
+func (p *DefaultTaskProcessor) saveResults(ctx context.Context, task *Task, result *AnalysisResult, analysisCtx *AnalysisContext) (err error) {
+	ctx, span := telemetry.StartSpan(ctx, "task.save_results")
+	defer func() { telemetry.EndSpan(span, err) }()
+
 	// Upload generated files from OutputFiles
 	uploadedFiles := make(map[string]string)
 	for _, file := range result.Response.OutputFiles {
@@ -221,8 +381,14 @@ func (p *DefaultTaskProcessor) saveResults(ctx context.Context, task *Task, resu
 	flameGraphFile := ""
 	callGraphFile := ""
 
-	if result.Response.Data != nil {
-		switch data := result.Response.Data.(type) {
+	// Recordings of several profiles are saved by their primary one
+	responseData := result.Response.Data
+	if jfr, ok := responseData.(*model.JFRData); ok {
+		responseData = jfr.Primary()
+	}
+
+	if responseData != nil {
+		switch data := responseData.(type) {
 		case *model.CPUProfilingData:
 			topFuncsJSON, _ := json.Marshal(data.TopFuncs)
 			topFuncs = string(topFuncsJSON)
@@ -237,6 +403,44 @@ func (p *DefaultTaskProcessor) saveResults(ctx context.Context, task *Task, resu
 			activeThreadsJSON = string(threadsJSON)
 			flameGraphFile = uploadedFiles["Allocation Flame Graph"]
 			callGraphFile = uploadedFiles["Allocation Call Graph"]
+		case *model.LockContentionData:
+			topFuncsJSON, _ := json.Marshal(data.TopContended)
+			topFuncs = string(topFuncsJSON)
+			var threadsJSON []byte
+			if len(data.ThreadLatencies) > 0 {
+				threadsJSON, _ = json.Marshal(data.ThreadLatencies)
+			} else {
+				threadsJSON, _ = json.Marshal(data.ThreadStats)
+			}
+			activeThreadsJSON = string(threadsJSON)
+			flameGraphFile = uploadedFiles["Lock Flame Graph"]
+			callGraphFile = uploadedFiles["Lock Call Graph"]
+		case *model.WallClockData:
+			topFuncsJSON, _ := json.Marshal(data.TopFuncs)
+			topFuncs = string(topFuncsJSON)
+			threadsJSON, _ := json.Marshal(data.ThreadLatencies)
+			activeThreadsJSON = string(threadsJSON)
+			flameGraphFile = uploadedFiles["Wall Clock Flame Graph"]
+			callGraphFile = uploadedFiles["Wall Clock Call Graph"]
+		case *model.OffCPUData:
+			topFuncsJSON, _ := json.Marshal(data.TopFuncs)
+			topFuncs = string(topFuncsJSON)
+			threadsJSON, _ := json.Marshal(data.ThreadLatencies)
+			activeThreadsJSON = string(threadsJSON)
+			flameGraphFile = uploadedFiles["Off-CPU Flame Graph"]
+			callGraphFile = uploadedFiles["Off-CPU Call Graph"]
+		case *model.ThreadDumpData:
+			summaryJSON, _ := json.Marshal(data.Summary())
+			activeThreadsJSON = string(summaryJSON)
+			monitorsJSON, _ := json.Marshal(data.HotMonitors)
+			topFuncs = string(monitorsJSON)
+			flameGraphFile = uploadedFiles["Thread Dump Analysis"]
+		case *model.GCLogData:
+			summaryJSON, _ := json.Marshal(data.Summary())
+			activeThreadsJSON = string(summaryJSON)
+			pauseTypesJSON, _ := json.Marshal(data.PauseTypes)
+			topFuncs = string(pauseTypesJSON)
+			flameGraphFile = uploadedFiles["GC Log Analysis"]
 		case *model.HeapAnalysisData:
 			// For heap analysis, use summary as JSON
 			summaryJSON, _ := json.Marshal(data.Summary())
@@ -278,6 +482,92 @@ func (p *DefaultTaskProcessor) saveResults(ctx context.Context, task *Task, resu
 	return p.repos.Result.SaveResult(ctx, analysisResult)
 }
 
+// saveSummary saves the headline metrics of an analysis.
+func (p *DefaultTaskProcessor) saveSummary(ctx context.Context, summary *model.AnalysisSummary) error {
+	if p.repos.Summary == nil {
+		return nil
+	}
+	return p.repos.Summary.SaveSummary(ctx, summary)
+}
+
+// notify notifies the webhooks of the result of a task: its summary when it
+// completed, else err. Failed attempts which are retried are not notified.
+func (p *DefaultTaskProcessor) notify(task *Task, summary *model.AnalysisSummary, err error) {
+	if p.notifier == nil || (err != nil && task.retryable(err)) {
+		return
+	}
+	if err == nil {
+		p.notifier.Notify(notify.CompletedEvent(summary))
+		return
+	}
+
+	service := task.RequestParams.Service
+	if service == "" {
+		service = task.RequestParams.ContainerName
+	}
+	p.notifier.Notify(&notify.Event{
+		Type:         notify.EventFailed,
+		TaskUUID:     task.UUID,
+		TaskType:     task.Type,
+		ProfilerType: task.ProfilerType,
+		Service:      service,
+		UserName:     task.UserName,
+		Error:        err.Error(),
+	})
+}
+
+// buildSummary returns the summary of the analysis of a task. Analyses are
+// grouped by service, the container name when the task names no service.
+func buildSummary(task *Task, result *AnalysisResult) *model.AnalysisSummary {
+	summary := model.SummarizeResponse(result.Response, model.DefaultSummaryTopN)
+	summary.TaskUUID = task.UUID
+	summary.TaskType = task.Type
+	summary.ProfilerType = task.ProfilerType
+	summary.UserName = task.UserName
+	summary.Service = task.RequestParams.Service
+	if summary.Service == "" {
+		summary.Service = task.RequestParams.ContainerName
+	}
+	return summary
+}
+
+// compareWithBaseline sets the regressions of an analysis from the baseline
+// of its service, if any, and suggests them.
+func (p *DefaultTaskProcessor) compareWithBaseline(ctx context.Context, summary *model.AnalysisSummary, result *AnalysisResult) {
+	if p.repos.Baseline == nil || p.repos.Summary == nil || summary.Service == "" {
+		return
+	}
+	baseline, err := p.repos.Baseline.GetBaseline(ctx, summary.Service, summary.TaskType)
+	if err != nil {
+		if !errors.Is(err, repository.ErrNotFound) {
+			p.logger.Warn("Failed to get baseline of service %s: %v", summary.Service, err)
+		}
+		return
+	}
+	if baseline.TaskUUID == summary.TaskUUID {
+		return
+	}
+	baseSummary, err := p.repos.Summary.GetSummary(ctx, baseline.TaskUUID)
+	if err != nil {
+		p.logger.Warn("Failed to get summary of baseline %s: %v", baseline.TaskUUID, err)
+		return
+	}
+
+	summary.BaselineTID = baseline.TaskUUID
+	summary.Regressions = model.DetectRegressions(baseSummary, summary, model.DefaultRegressionThresholds())
+	for _, regression := range summary.Regressions {
+		result.Suggestions = append(result.Suggestions, model.SuggestionItem{
+			Suggestion: regression.Message,
+			FuncName:   regression.Name,
+			Rule:       "baseline." + regression.Kind,
+			Severity:   rules.SeverityWarning,
+		})
+	}
+	if len(summary.Regressions) > 0 {
+		p.logger.Info("Task %s has %d regressions from baseline %s", summary.TaskUUID, len(summary.Regressions), baseline.TaskUUID)
+	}
+}
+
 // generateSuggestions generates and saves analysis suggestions.
 func (p *DefaultTaskProcessor) generateSuggestions(ctx context.Context, task *Task, result *AnalysisResult, rules []model.SuggestionRule) error {
 	// Create advisor
@@ -294,6 +584,8 @@ func (p *DefaultTaskProcessor) generateSuggestions(ctx context.Context, task *Ta
 	for _, sug := range result.Suggestions {
 		suggestions = append(suggestions, model.Suggestion{
 			TaskUUID:   task.UUID,
+			Type:       sug.Rule,
+			Severity:   sug.Severity,
 			Suggestion: sug.Suggestion,
 			FuncName:   sug.FuncName,
 			Namespace:  sug.Namespace,
@@ -0,0 +1,150 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"syscall"
+	"time"
+
+	apperrors "github.com/perf-analysis/pkg/errors"
+	"github.com/perf-analysis/pkg/model"
+)
+
+// IsTransient reports whether a failed analysis may succeed when retried:
+// downloads from storage, database operations and timeouts fail
+// transiently, as do analyses running out of memory while other analyses
+// hold it. Analysis errors, e.g. of malformed input, are permanent.
+func IsTransient(err error) bool {
+	return apperrors.IsDownloadError(err) ||
+		apperrors.IsDatabaseError(err) ||
+		errors.Is(err, apperrors.ErrTimeout) ||
+		errors.Is(err, context.DeadlineExceeded) ||
+		errors.Is(err, syscall.ENOMEM)
+}
+
+// PartialResultError is the error of an analysis which failed after writing
+// output files, kept in storage for inspection.
+type PartialResultError struct {
+	Err error
+
+	// Artifacts are the storage keys of the output files
+	Artifacts []string
+}
+
+// Error implements the error interface.
+func (e *PartialResultError) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap returns the error of the analysis.
+func (e *PartialResultError) Unwrap() error {
+	return e.Err
+}
+
+// retryable reports whether a failed attempt of a task is retried: its error
+// is transient and it has attempts left.
+func (t *Task) retryable(err error) bool {
+	return IsTransient(err) && t.Attempts < t.MaxAttempts
+}
+
+// retryDelay returns the delay before the next attempt of a task, doubling
+// with every attempt up to the max backoff.
+func (s *Scheduler) retryDelay(task *Task) time.Duration {
+	maxDelay := s.config.MaxRetryBackoff
+	delay := s.config.RetryBackoff
+	for i := 1; i < task.Attempts && (maxDelay <= 0 || delay < maxDelay); i++ {
+		delay *= 2
+	}
+	if maxDelay > 0 && delay > maxDelay {
+		delay = maxDelay
+	}
+	return delay
+}
+
+// fail handles a failed attempt of a task: it is retried after a backoff if
+// the failure is transient, else dead-lettered. Tasks interrupted by the
+// shutdown are nacked, for their source to deliver them again.
+func (s *Scheduler) fail(ctx context.Context, task *Task, err error) {
+	if errors.Is(err, context.Canceled) {
+		s.nack(ctx, task, err.Error())
+		return
+	}
+	if task.retryable(err) {
+		s.retryLater(ctx, task, s.retryDelay(task))
+		return
+	}
+	s.deadLetter(ctx, task, err)
+}
+
+// retryLater queues a task again after delay. The task stays in flight at
+// its source meanwhile, e.g. its lease or message visibility is extended;
+// if the scheduler stops first, the source delivers it again.
+func (s *Scheduler) retryLater(ctx context.Context, task *Task, delay time.Duration) {
+	s.logger.Warn("Retrying task %d in %v (attempt %d of %d)", task.ID, delay, task.Attempts+1, task.MaxAttempts)
+
+	s.queueMu.Lock()
+	s.retrying++
+	s.queueMu.Unlock()
+
+	s.wg.Add(1)
+	go func() {
+		defer func() {
+			s.queueMu.Lock()
+			s.retrying--
+			s.queueMu.Unlock()
+			s.wg.Done()
+		}()
+
+		timer := time.NewTimer(delay)
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+			task.Attempts++
+			s.submit(ctx, task)
+		case <-ctx.Done():
+		case <-s.stopCh:
+		}
+	}()
+}
+
+// deadLetter keeps a task failing for good with its error and the output
+// files of its last attempt, and removes it from its source. Without a dead
+// letter repository, or if saving fails, the task is nacked.
+func (s *Scheduler) deadLetter(ctx context.Context, task *Task, err error) {
+	if s.deadLetters == nil {
+		s.nack(ctx, task, err.Error())
+		return
+	}
+
+	letter := &model.DeadLetter{
+		TaskID:       task.ID,
+		TaskUUID:     task.UUID,
+		TaskType:     task.Type,
+		ProfilerType: task.ProfilerType,
+		Error:        err.Error(),
+		Attempts:     task.Attempts,
+		FailedAt:     time.Now(),
+	}
+	if task.event != nil {
+		letter.Source = fmt.Sprintf("%s/%s", task.event.SourceType, task.event.SourceName)
+	}
+	var partial *PartialResultError
+	if errors.As(err, &partial) {
+		letter.Artifacts = partial.Artifacts
+	}
+
+	if saveErr := s.deadLetters.SaveDeadLetter(ctx, letter); saveErr != nil {
+		s.logger.Error("Failed to dead-letter task %d: %v", task.ID, saveErr)
+		s.nack(ctx, task, err.Error())
+		return
+	}
+	s.logger.Warn("Dead-lettered task %d after %d attempts: %v", task.ID, task.Attempts, err)
+
+	if task.event == nil {
+		return
+	}
+	if dlErr := s.aggregator.DeadLetter(ctx, task.event, err.Error()); dlErr != nil {
+		s.logger.Error("Failed to dead-letter task %d at its source: %v", task.ID, dlErr)
+	}
+}
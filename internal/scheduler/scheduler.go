@@ -3,13 +3,18 @@ package scheduler
 
 import (
 	"context"
+	"fmt"
+	"strconv"
 	"sync"
 	"time"
 
+	"go.opentelemetry.io/otel/attribute"
+
 	"github.com/perf-analysis/internal/repository"
 	"github.com/perf-analysis/internal/scheduler/source"
 	"github.com/perf-analysis/pkg/config"
 	"github.com/perf-analysis/pkg/model"
+	"github.com/perf-analysis/pkg/telemetry"
 	"github.com/perf-analysis/pkg/utils"
 )
 
@@ -24,9 +29,30 @@ type Task struct {
 	MasterTaskTID *string
 	COSBucket     string
 	RequestParams model.RequestParams
-	Priority      int // Higher value = higher priority
+	Priority      int           // Higher value = higher priority
+	Class         PriorityClass // Priority class competing for workers
+	Tenant        string        // Tenant whose concurrency limit applies, the user name
+	Memory        int64         // Estimated memory of the analysis, reserved while it runs
+	Attempts      int           // Attempt of the analysis, from 1, counting the deliveries of the source
+	MaxAttempts   int           // Attempts before the task is dead-lettered
+
+	// event is the source event of the task, acked or nacked once processed
+	event *source.TaskEvent
 }
 
+// PriorityClass groups the tasks competing for workers. Queued interactive
+// tasks are dispatched before batch tasks, and batch tasks never use the
+// worker slots reserved for interactive ones.
+type PriorityClass string
+
+const (
+	PriorityInteractive PriorityClass = "interactive"
+	PriorityBatch       PriorityClass = "batch"
+)
+
+// priorityClasses lists the priority classes in dispatch order.
+var priorityClasses = []PriorityClass{PriorityInteractive, PriorityBatch}
+
 // TaskProcessor defines the interface for processing tasks.
 type TaskProcessor interface {
 	// Process processes a single task.
@@ -35,30 +61,66 @@ type TaskProcessor interface {
 
 // SchedulerConfig holds scheduler configuration.
 type SchedulerConfig struct {
-	PollInterval  time.Duration // How often to poll for new tasks
-	WorkerCount   int           // Number of concurrent workers
-	PrioritySlots int           // Reserved slots for high priority tasks
-	TaskBatchSize int           // Max tasks to fetch per poll
+	PollInterval        time.Duration    // How often to poll for new tasks
+	WorkerCount         int              // Number of concurrent workers
+	PrioritySlots       int              // Reserved slots for interactive tasks
+	TaskBatchSize       int              // Max tasks to fetch per poll
+	BatchTaskTypes      []model.TaskType // Task types in the batch priority class
+	TenantMaxConcurrent int              // Max concurrent tasks per tenant, 0 for no limit
+	TenantLimits        map[string]int   // Per-tenant overrides of TenantMaxConcurrent
+	MemoryBudget        int64            // Memory of the tasks running at once, 0 for no admission control
+	MaxAttempts         int              // Attempts of tasks failing with transient errors, 0 or 1 for no retries
+	RetryBackoff        time.Duration    // Delay before the first retry, doubled after
+	MaxRetryBackoff     time.Duration    // Max delay between retries, 0 for no max
 }
 
 // DefaultSchedulerConfig returns default scheduler configuration.
 func DefaultSchedulerConfig() *SchedulerConfig {
 	return &SchedulerConfig{
-		PollInterval:  2 * time.Second,
-		WorkerCount:   5,
-		PrioritySlots: 2,
-		TaskBatchSize: 10,
+		PollInterval:   2 * time.Second,
+		WorkerCount:    5,
+		PrioritySlots:  2,
+		TaskBatchSize:  10,
+		BatchTaskTypes: []model.TaskType{model.TaskTypeJavaHeap},
 	}
 }
 
 // FromConfig creates scheduler config from application config.
+// Unknown batch task types are ignored; config.Validate rejects them.
 func FromConfig(cfg *config.SchedulerConfig) *SchedulerConfig {
+	batchTypes := make([]model.TaskType, 0, len(cfg.BatchTaskTypes))
+	for _, name := range cfg.BatchTaskTypes {
+		if taskType, ok := model.ParseTaskType(name); ok {
+			batchTypes = append(batchTypes, taskType)
+		}
+	}
+
 	return &SchedulerConfig{
-		PollInterval:  time.Duration(cfg.PollInterval) * time.Second,
-		WorkerCount:   cfg.WorkerCount,
-		PrioritySlots: cfg.PrioritySlots,
-		TaskBatchSize: cfg.TaskBatchSize,
+		PollInterval:        time.Duration(cfg.PollInterval) * time.Second,
+		WorkerCount:         cfg.WorkerCount,
+		PrioritySlots:       cfg.PrioritySlots,
+		TaskBatchSize:       cfg.TaskBatchSize,
+		BatchTaskTypes:      batchTypes,
+		TenantMaxConcurrent: cfg.TenantMaxConcurrent,
+		TenantLimits:        cfg.TenantLimits,
+		MemoryBudget:        memoryBudget(&cfg.Admission),
+		MaxAttempts:         cfg.Retry.MaxAttempts,
+		RetryBackoff:        time.Duration(cfg.Retry.Backoff) * time.Second,
+		MaxRetryBackoff:     time.Duration(cfg.Retry.MaxBackoff) * time.Second,
+	}
+}
+
+// memoryBudget returns the memory budget of admission control: the
+// configured budget, or a fraction of the memory limit of the cgroup or
+// host. It is 0, disabling admission control, if the limit is unknown.
+func memoryBudget(cfg *config.AdmissionConfig) int64 {
+	if !cfg.Enabled {
+		return 0
+	}
+	if cfg.MemoryBudgetMB > 0 {
+		return int64(cfg.MemoryBudgetMB) << 20
 	}
+	return int64(float64(utils.MemoryLimit()) * cfg.MemoryFraction)
 }
 
 // Scheduler manages task scheduling and worker pool.
@@ -72,11 +134,22 @@ type Scheduler struct {
 	suggestionRepo repository.SuggestionRepository
 
 	workerPool chan struct{}          // Semaphore for worker count
-	taskQueue  chan *Task             // Task queue
 	wg         sync.WaitGroup         // Wait group for workers
 	mu         sync.Mutex             // Mutex for rules cache
 	rules      []model.SuggestionRule // Cached rules
 
+	queueMu        sync.Mutex                // Mutex for the queues and counters below
+	queues         map[PriorityClass][]*Task // Tasks waiting for a worker, by class
+	queueSize      int                       // Max queued tasks per class
+	activeByClass  map[PriorityClass]int     // Tasks being processed, by class
+	activeByTenant map[string]int            // Tasks being processed, by tenant
+	reservedMemory int64                     // Estimated memory of the tasks being processed
+	wakeCh         chan struct{}             // Signals queued tasks or freed workers
+
+	estimator   MemoryEstimator                 // Estimates task memory for admission control
+	deadLetters repository.DeadLetterRepository // Keeps the tasks failing for good
+	retrying    int                             // Tasks waiting to be retried, under queueMu
+
 	running bool
 	stopCh  chan struct{}
 }
@@ -90,6 +163,11 @@ func New(config *SchedulerConfig, aggregator *source.Aggregator, processor TaskP
 		logger = utils.NewDefaultLogger(utils.LevelInfo, nil)
 	}
 
+	queueSize := config.TaskBatchSize * 2
+	if queueSize < 1 {
+		queueSize = 1
+	}
+
 	return &Scheduler{
 		config:         config,
 		aggregator:     aggregator,
@@ -97,11 +175,29 @@ func New(config *SchedulerConfig, aggregator *source.Aggregator, processor TaskP
 		processor:      processor,
 		logger:         logger,
 		workerPool:     make(chan struct{}, config.WorkerCount),
-		taskQueue:      make(chan *Task, config.TaskBatchSize*2),
+		queues:         make(map[PriorityClass][]*Task),
+		queueSize:      queueSize,
+		activeByClass:  make(map[PriorityClass]int),
+		activeByTenant: make(map[string]int),
+		wakeCh:         make(chan struct{}, 1),
 		stopCh:         make(chan struct{}),
 	}
 }
 
+// SetMemoryEstimator sets the estimator of the memory of tasks, enabling
+// admission control when the config has a memory budget. This must be
+// called before Start.
+func (s *Scheduler) SetMemoryEstimator(estimator MemoryEstimator) {
+	s.estimator = estimator
+}
+
+// SetDeadLetterRepository sets the repository of the tasks failing for good.
+// Without it, they are nacked like other failed tasks. This must be called
+// before Start.
+func (s *Scheduler) SetDeadLetterRepository(repo repository.DeadLetterRepository) {
+	s.deadLetters = repo
+}
+
 // Start starts the scheduler.
 func (s *Scheduler) Start(ctx context.Context) error {
 	s.logger.Info("Starting scheduler with %d workers", s.config.WorkerCount)
@@ -141,39 +237,152 @@ func (s *Scheduler) Stop() {
 	s.logger.Info("Scheduler stopped")
 }
 
-// shouldAcceptTask determines if a task should be accepted based on priority.
+// classify returns the priority class of a task. Tasks with a priority are
+// interactive, whatever their type.
+func (s *Scheduler) classify(task *Task) PriorityClass {
+	if task.Priority > 0 {
+		return PriorityInteractive
+	}
+	for _, taskType := range s.config.BatchTaskTypes {
+		if task.Type == taskType {
+			return PriorityBatch
+		}
+	}
+	return PriorityInteractive
+}
+
+// shouldAcceptTask determines if a task can be queued, i.e. if the queue of
+// its priority class is not full. Callers hold queueMu.
 func (s *Scheduler) shouldAcceptTask(task *Task) bool {
-	activeWorkers := s.config.WorkerCount - len(s.workerPool)
-	reservedSlots := s.config.WorkerCount - s.config.PrioritySlots
+	return len(s.queues[task.Class]) < s.queueSize
+}
 
-	// High priority tasks can always be accepted if there's capacity
-	if task.Priority > 0 {
-		return activeWorkers < s.config.WorkerCount
+// enqueue queues a task until a worker may process it. It returns false if
+// the queue of the task's class is full.
+func (s *Scheduler) enqueue(task *Task) bool {
+	s.queueMu.Lock()
+	if !s.shouldAcceptTask(task) {
+		s.queueMu.Unlock()
+		return false
+	}
+	s.queues[task.Class] = append(s.queues[task.Class], task)
+	s.queueMu.Unlock()
+
+	s.wake()
+	return true
+}
+
+// wake signals the process loop to dispatch queued tasks.
+func (s *Scheduler) wake() {
+	select {
+	case s.wakeCh <- struct{}{}:
+	default:
 	}
+}
 
-	// Normal priority tasks can only use non-reserved slots
-	return activeWorkers < reservedSlots
+// batchSlots returns the number of workers batch tasks may use: the workers
+// not reserved for interactive tasks, at least one.
+func (s *Scheduler) batchSlots() int {
+	slots := s.config.WorkerCount - s.config.PrioritySlots
+	if slots < 1 {
+		slots = 1
+	}
+	return slots
 }
 
-// processLoop processes queued tasks.
+// tenantAtLimit reports whether a tenant runs as many tasks as it may. Tasks
+// without a tenant are not limited. Callers hold queueMu.
+func (s *Scheduler) tenantAtLimit(tenant string) bool {
+	if tenant == "" {
+		return false
+	}
+	limit, ok := s.config.TenantLimits[tenant]
+	if !ok {
+		limit = s.config.TenantMaxConcurrent
+	}
+	return limit > 0 && s.activeByTenant[tenant] >= limit
+}
+
+// memoryAvailable reports whether the estimated memory of a task fits in the
+// memory budget, next to the tasks running. A task always fits when none
+// runs. Callers hold queueMu.
+func (s *Scheduler) memoryAvailable(task *Task) bool {
+	return s.config.MemoryBudget <= 0 || s.reservedMemory == 0 ||
+		s.reservedMemory+task.Memory <= s.config.MemoryBudget
+}
+
+// nextTask dequeues the next task to process and takes a worker slot for it,
+// or returns nil if no queued task may run now.
+//
+// Interactive tasks go first. Within a class the task with the highest
+// priority goes first, the oldest among equals. Tasks of tenants at their
+// concurrency limit, or needing more memory than is free, stay queued
+// without blocking other tasks.
+func (s *Scheduler) nextTask() *Task {
+	s.queueMu.Lock()
+	defer s.queueMu.Unlock()
+
+	if len(s.workerPool) == 0 {
+		return nil
+	}
+	for _, class := range priorityClasses {
+		if class == PriorityBatch && s.activeByClass[class] >= s.batchSlots() {
+			continue
+		}
+
+		queue := s.queues[class]
+		next := -1
+		for i, task := range queue {
+			if s.tenantAtLimit(task.Tenant) || !s.memoryAvailable(task) {
+				continue
+			}
+			if next < 0 || task.Priority > queue[next].Priority {
+				next = i
+			}
+		}
+		if next < 0 {
+			continue
+		}
+
+		task := queue[next]
+		s.queues[class] = append(queue[:next], queue[next+1:]...)
+		<-s.workerPool
+		s.activeByClass[class]++
+		s.activeByTenant[task.Tenant]++
+		s.reservedMemory += task.Memory
+		return task
+	}
+	return nil
+}
+
+// release returns the worker slot of a processed task.
+func (s *Scheduler) release(task *Task) {
+	s.queueMu.Lock()
+	s.activeByClass[task.Class]--
+	if s.activeByTenant[task.Tenant]--; s.activeByTenant[task.Tenant] == 0 {
+		delete(s.activeByTenant, task.Tenant)
+	}
+	s.reservedMemory -= task.Memory
+	s.queueMu.Unlock()
+
+	s.workerPool <- struct{}{}
+	s.wake()
+}
+
+// processLoop dispatches queued tasks to workers as slots free up.
 func (s *Scheduler) processLoop(ctx context.Context) {
 	for {
+		for task := s.nextTask(); task != nil; task = s.nextTask() {
+			s.wg.Add(1)
+			go s.processTask(ctx, task)
+		}
+
 		select {
 		case <-ctx.Done():
 			return
 		case <-s.stopCh:
 			return
-		case task := <-s.taskQueue:
-			// Acquire a worker slot
-			select {
-			case <-s.workerPool:
-				s.wg.Add(1)
-				go s.processTask(ctx, task)
-			case <-ctx.Done():
-				return
-			case <-s.stopCh:
-				return
-			}
+		case <-s.wakeCh:
 		}
 	}
 }
@@ -181,12 +390,12 @@ func (s *Scheduler) processLoop(ctx context.Context) {
 // processTask processes a single task.
 func (s *Scheduler) processTask(ctx context.Context, task *Task) {
 	defer func() {
-		s.workerPool <- struct{}{} // Release worker slot
+		s.release(task)
 		s.wg.Done()
 	}()
 
-	s.logger.Info("Processing task %d (UUID: %s, Type: %d, Profiler: %d)",
-		task.ID, task.UUID, task.Type, task.ProfilerType)
+	s.logger.Info("Processing task %d (UUID: %s, Type: %d, Profiler: %d, Class: %s)",
+		task.ID, task.UUID, task.Type, task.ProfilerType, task.Class)
 
 	// Get cached rules
 	s.mu.Lock()
@@ -194,16 +403,46 @@ func (s *Scheduler) processTask(ctx context.Context, task *Task) {
 	s.mu.Unlock()
 
 	// Process the task
+	ctx, span := telemetry.StartSpan(ctx, "task.process",
+		attribute.Int64("task.id", task.ID),
+		attribute.String("task.uuid", task.UUID),
+		attribute.String("task.type", task.Type.String()),
+		attribute.String("task.profiler", task.ProfilerType.String()),
+		attribute.String("task.class", string(task.Class)))
 	startTime := time.Now()
 	err := s.processor.Process(ctx, task, rules)
 	duration := time.Since(startTime)
+	telemetry.EndSpan(span, err)
 
 	if err != nil {
-		s.logger.Error("Task %d failed after %v: %v", task.ID, duration, err)
+		s.logger.Error("Task %d failed after %v (attempt %d): %v", task.ID, duration, task.Attempts, err)
+		s.fail(ctx, task, err)
 		return
 	}
 
 	s.logger.Info("Task %d completed successfully in %v", task.ID, duration)
+	s.ack(ctx, task)
+}
+
+// ack acknowledges a processed task to its source.
+func (s *Scheduler) ack(ctx context.Context, task *Task) {
+	if task.event == nil {
+		return
+	}
+	if err := s.aggregator.Ack(ctx, task.event); err != nil {
+		s.logger.Error("Failed to ack task %d: %v", task.ID, err)
+	}
+}
+
+// nack reports a task that was not processed to its source, which may
+// deliver it again.
+func (s *Scheduler) nack(ctx context.Context, task *Task, reason string) {
+	if task.event == nil {
+		return
+	}
+	if err := s.aggregator.Nack(ctx, task.event, reason); err != nil {
+		s.logger.Error("Failed to nack task %d: %v", task.ID, err)
+	}
 }
 
 // sourceEventLoop receives task events from the aggregator and queues them for processing.
@@ -229,28 +468,52 @@ func (s *Scheduler) sourceEventLoop(ctx context.Context) {
 			// Convert TaskEvent to Task
 			task := s.convertEventToTask(event)
 
-			// Check if we should accept this task
-			if !s.shouldAcceptTask(task) {
-				s.logger.Debug("Skipping task %d due to priority constraints", task.ID)
+			if s.estimator != nil && s.config.MemoryBudget > 0 {
+				// Estimating reads the task input: do not hold up other tasks
+				s.wg.Add(1)
+				go s.admit(ctx, task)
 				continue
 			}
-
-			// Queue the task
-			select {
-			case s.taskQueue <- task:
-				s.logger.Info("Queued task %d (UUID: %s) from source %s/%s",
-					task.ID, task.UUID, event.SourceType, event.SourceName)
-			default:
-				// Queue full, nack the event so it can be retried
-				s.logger.Warn("Task queue full, nacking task %d", task.ID)
-				if err := s.aggregator.Nack(ctx, event, "task queue full"); err != nil {
-					s.logger.Error("Failed to nack event: %v", err)
-				}
-			}
+			s.submit(ctx, task)
 		}
 	}
 }
 
+// submit queues a task, or nacks it if its queue is full.
+func (s *Scheduler) submit(ctx context.Context, task *Task) {
+	if !s.enqueue(task) {
+		// Queue full, nack the event so it can be retried
+		s.logger.Warn("Task queue for %s tasks full, nacking task %d", task.Class, task.ID)
+		s.nack(ctx, task, "task queue full")
+		return
+	}
+	s.logger.Info("Queued %s task %d (UUID: %s) from source %s/%s",
+		task.Class, task.ID, task.UUID, task.event.SourceType, task.event.SourceName)
+}
+
+// admit estimates the memory of a task and queues it, or rejects it if it
+// needs more than the whole memory budget. Tasks whose memory cannot be
+// estimated are admitted.
+func (s *Scheduler) admit(ctx context.Context, task *Task) {
+	defer s.wg.Done()
+
+	memory, err := s.estimator.EstimateMemory(ctx, task)
+	if err != nil {
+		s.logger.Warn("Failed to estimate memory of task %d, admitting it: %v", task.ID, err)
+	}
+	if memory > s.config.MemoryBudget {
+		reason := fmt.Sprintf("estimated memory %dMB exceeds the memory budget of %dMB",
+			memory>>20, s.config.MemoryBudget>>20)
+		s.logger.Warn("Rejecting task %d: %s", task.ID, reason)
+		s.nack(ctx, task, reason)
+		return
+	}
+
+	task.Memory = memory
+	s.logger.Debug("Task %d needs an estimated %dMB", task.ID, memory>>20)
+	s.submit(ctx, task)
+}
+
 // refreshRules fetches and caches analysis rules.
 func (s *Scheduler) refreshRules(ctx context.Context) {
 	if s.suggestionRepo == nil {
@@ -284,24 +547,59 @@ func (s *Scheduler) convertEventToTask(event *source.TaskEvent) *Task {
 		COSBucket:     t.COSBucket,
 		RequestParams: t.RequestParams,
 		Priority:      event.Priority,
+		Tenant:        t.UserName,
+		Attempts:      1,
+		MaxAttempts:   s.config.MaxAttempts,
+		event:         event,
 	}
+	if attempts, err := strconv.Atoi(event.GetMetadata("attempts")); err == nil && attempts > 1 {
+		task.Attempts = attempts
+	}
+	task.Class = s.classify(task)
 	return task
 }
 
 // Stats returns current scheduler statistics.
 func (s *Scheduler) Stats() SchedulerStats {
-	return SchedulerStats{
-		ActiveWorkers: s.config.WorkerCount - len(s.workerPool),
-		TotalWorkers:  s.config.WorkerCount,
-		QueuedTasks:   len(s.taskQueue),
-		Running:       s.running,
+	s.queueMu.Lock()
+	defer s.queueMu.Unlock()
+
+	stats := SchedulerStats{
+		ActiveWorkers:  s.config.WorkerCount - len(s.workerPool),
+		TotalWorkers:   s.config.WorkerCount,
+		Running:        s.running,
+		Classes:        make(map[PriorityClass]ClassStats, len(priorityClasses)),
+		ReservedMemory: s.reservedMemory,
+		MemoryBudget:   s.config.MemoryBudget,
+		RetryingTasks:  s.retrying,
+	}
+	for _, class := range priorityClasses {
+		stats.QueuedTasks += len(s.queues[class])
+		stats.Classes[class] = ClassStats{
+			ActiveTasks: s.activeByClass[class],
+			QueuedTasks: len(s.queues[class]),
+		}
 	}
+	return stats
 }
 
 // SchedulerStats holds scheduler statistics.
 type SchedulerStats struct {
-	ActiveWorkers int  `json:"active_workers"`
-	TotalWorkers  int  `json:"total_workers"`
-	QueuedTasks   int  `json:"queued_tasks"`
-	Running       bool `json:"running"`
+	ActiveWorkers int                          `json:"active_workers"`
+	TotalWorkers  int                          `json:"total_workers"`
+	QueuedTasks   int                          `json:"queued_tasks"`
+	Running       bool                         `json:"running"`
+	Classes       map[PriorityClass]ClassStats `json:"classes"`
+	RetryingTasks int                          `json:"retrying_tasks"`
+
+	// ReservedMemory is the estimated memory of the running tasks, out of
+	// MemoryBudget when admission control is enabled
+	ReservedMemory int64 `json:"reserved_memory,omitempty"`
+	MemoryBudget   int64 `json:"memory_budget,omitempty"`
+}
+
+// ClassStats holds the statistics of a priority class.
+type ClassStats struct {
+	ActiveTasks int `json:"active_tasks"`
+	QueuedTasks int `json:"queued_tasks"`
 }
@@ -1,9 +1,16 @@
 package scheduler
 
 import (
+	"bytes"
 	"context"
+	"encoding/binary"
+	"fmt"
 	"io"
+	"os"
+	"path/filepath"
+	"sync"
 	"sync/atomic"
+	"syscall"
 	"testing"
 	"time"
 
@@ -11,7 +18,11 @@ import (
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
 
+	"github.com/perf-analysis/internal/parser/hprof"
 	"github.com/perf-analysis/internal/scheduler/source"
+	"github.com/perf-analysis/internal/storage"
+	"github.com/perf-analysis/pkg/config"
+	apperrors "github.com/perf-analysis/pkg/errors"
 	"github.com/perf-analysis/pkg/model"
 	"github.com/perf-analysis/pkg/utils"
 )
@@ -253,3 +264,467 @@ func TestScheduler_ConvertEventToTask_Priority(t *testing.T) {
 		assert.Equal(t, 0, task.Priority) // Normal priority
 	})
 }
+
+// memoryQueue is a source.Queue delivering a fixed list of messages.
+type memoryQueue struct {
+	mu       sync.Mutex
+	messages []*source.QueueMessage
+	acked    []string
+	nacked   map[string]time.Duration
+}
+
+func (q *memoryQueue) Receive(ctx context.Context, max int, _ time.Duration) ([]*source.QueueMessage, error) {
+	q.mu.Lock()
+	msgs := q.messages
+	q.messages = nil
+	q.mu.Unlock()
+	if len(msgs) == 0 {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+	return msgs, nil
+}
+
+func (q *memoryQueue) Ack(_ context.Context, msg *source.QueueMessage) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.acked = append(q.acked, msg.ID)
+	return nil
+}
+
+func (q *memoryQueue) SetVisibility(_ context.Context, msg *source.QueueMessage, d time.Duration) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.nacked[msg.ID] = d
+	return nil
+}
+
+func (q *memoryQueue) Ping(context.Context) error { return nil }
+func (q *memoryQueue) Close() error               { return nil }
+
+func TestScheduler_AcksQueueMessages(t *testing.T) {
+	queue := &memoryQueue{
+		messages: []*source.QueueMessage{
+			{ID: "m1", Body: []byte(`{"task": {"id": 1, "tid": "ok"}}`)},
+			{ID: "m2", Body: []byte(`{"task": {"id": 2, "tid": "fails"}}`)},
+			{ID: "m3", Body: []byte(`not json`)},
+		},
+		nacked: make(map[string]time.Duration),
+	}
+	logger := utils.NewDefaultLogger(utils.LevelDebug, io.Discard)
+	opts := &source.QueueOptions{BatchSize: 10, VisibilityTimeout: time.Minute, NackDelay: 5 * time.Second}
+	src := source.NewQueueSource("memory", "test", queue, opts, logger)
+	aggregator := source.NewAggregator([]source.TaskSource{src}, 10, logger)
+
+	processor := &MockTaskProcessor{}
+	processor.On("Process", mock.Anything, mock.MatchedBy(func(task *Task) bool { return task.UUID == "ok" }), mock.Anything).Return(nil)
+	processor.On("Process", mock.Anything, mock.MatchedBy(func(task *Task) bool { return task.UUID == "fails" }), mock.Anything).Return(assert.AnError)
+
+	s := New(&SchedulerConfig{WorkerCount: 2, TaskBatchSize: 5}, aggregator, processor, nil, logger)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	require.NoError(t, s.Start(ctx))
+
+	require.Eventually(t, func() bool {
+		queue.mu.Lock()
+		defer queue.mu.Unlock()
+		return len(queue.acked) == 2 && len(queue.nacked) == 1
+	}, 5*time.Second, 10*time.Millisecond)
+
+	cancel()
+	s.Stop()
+	require.NoError(t, aggregator.Stop())
+
+	// The malformed message is dropped, the failed task is delivered again
+	assert.ElementsMatch(t, []string{"m1", "m3"}, queue.acked)
+	assert.Equal(t, map[string]time.Duration{"m2": 5 * time.Second}, queue.nacked)
+}
+
+// newDispatchScheduler returns a scheduler with idle workers, as after
+// Start, that does not receive tasks.
+func newDispatchScheduler(config *SchedulerConfig) *Scheduler {
+	logger := utils.NewDefaultLogger(utils.LevelDebug, io.Discard)
+	s := New(config, source.NewAggregator(nil, 10, logger), &MockTaskProcessor{}, nil, logger)
+	for i := 0; i < config.WorkerCount; i++ {
+		s.workerPool <- struct{}{}
+	}
+	return s
+}
+
+func queueTask(t *testing.T, s *Scheduler, id int64, taskType model.TaskType, tenant string, priority int) *Task {
+	task := &Task{ID: id, Type: taskType, Tenant: tenant, Priority: priority}
+	task.Class = s.classify(task)
+	require.True(t, s.enqueue(task))
+	return task
+}
+
+// dispatchIDs dequeues the tasks that may run now and returns their IDs.
+func dispatchIDs(s *Scheduler) []int64 {
+	var ids []int64
+	for task := s.nextTask(); task != nil; task = s.nextTask() {
+		ids = append(ids, task.ID)
+	}
+	return ids
+}
+
+func TestScheduler_Classify(t *testing.T) {
+	s := New(nil, nil, &MockTaskProcessor{}, nil, nil)
+
+	assert.Equal(t, PriorityBatch, s.classify(&Task{Type: model.TaskTypeJavaHeap}))
+	assert.Equal(t, PriorityInteractive, s.classify(&Task{Type: model.TaskTypeJava}))
+	assert.Equal(t, PriorityInteractive, s.classify(&Task{Type: model.TaskTypePProfCPU}))
+	// An explicit priority makes any task interactive
+	assert.Equal(t, PriorityInteractive, s.classify(&Task{Type: model.TaskTypeJavaHeap, Priority: 1}))
+}
+
+func TestScheduler_InteractiveBeforeBatch(t *testing.T) {
+	s := newDispatchScheduler(&SchedulerConfig{
+		WorkerCount:    3,
+		PrioritySlots:  1,
+		TaskBatchSize:  10,
+		BatchTaskTypes: []model.TaskType{model.TaskTypeJavaHeap},
+	})
+
+	// A flood of heap dumps queued before two CPU profiles
+	for id := int64(1); id <= 5; id++ {
+		queueTask(t, s, id, model.TaskTypeJavaHeap, "", 0)
+	}
+	queueTask(t, s, 10, model.TaskTypeJava, "", 0)
+	queueTask(t, s, 11, model.TaskTypePProfCPU, "", 2)
+
+	// Interactive tasks go first, highest priority first; a single batch
+	// task fits in the unreserved slots left
+	assert.Equal(t, []int64{11, 10, 1}, dispatchIDs(s))
+
+	stats := s.Stats()
+	assert.Equal(t, 3, stats.ActiveWorkers)
+	assert.Equal(t, 4, stats.QueuedTasks)
+	assert.Equal(t, ClassStats{ActiveTasks: 1, QueuedTasks: 4}, stats.Classes[PriorityBatch])
+	assert.Equal(t, ClassStats{ActiveTasks: 2}, stats.Classes[PriorityInteractive])
+}
+
+func TestScheduler_BatchCannotUseReservedSlots(t *testing.T) {
+	s := newDispatchScheduler(&SchedulerConfig{
+		WorkerCount:    3,
+		PrioritySlots:  1,
+		TaskBatchSize:  10,
+		BatchTaskTypes: []model.TaskType{model.TaskTypeJavaHeap},
+	})
+
+	var batch []*Task
+	for id := int64(1); id <= 4; id++ {
+		batch = append(batch, queueTask(t, s, id, model.TaskTypeJavaHeap, "", 0))
+	}
+	assert.Equal(t, []int64{1, 2}, dispatchIDs(s))
+
+	// The reserved slot is kept for the interactive task arriving later
+	queueTask(t, s, 10, model.TaskTypeJava, "", 0)
+	assert.Equal(t, []int64{10}, dispatchIDs(s))
+
+	// A batch task finishing frees a slot for the next one
+	s.release(batch[0])
+	assert.Equal(t, []int64{3}, dispatchIDs(s))
+}
+
+func TestScheduler_TenantLimits(t *testing.T) {
+	s := newDispatchScheduler(&SchedulerConfig{
+		WorkerCount:         4,
+		TaskBatchSize:       10,
+		TenantMaxConcurrent: 1,
+		TenantLimits:        map[string]int{"big": 2},
+	})
+
+	a1 := queueTask(t, s, 1, model.TaskTypeJava, "a", 0)
+	queueTask(t, s, 2, model.TaskTypeJava, "a", 0)
+	queueTask(t, s, 3, model.TaskTypeJava, "big", 0)
+	queueTask(t, s, 4, model.TaskTypeJava, "big", 0)
+	queueTask(t, s, 5, model.TaskTypeJava, "big", 0)
+
+	// Tenant a is limited to one task, big to two; the queued tasks of a do
+	// not hold back big
+	assert.Equal(t, []int64{1, 3, 4}, dispatchIDs(s))
+
+	s.release(a1)
+	assert.Equal(t, []int64{2}, dispatchIDs(s))
+	assert.Equal(t, 1, s.Stats().QueuedTasks)
+}
+
+func TestScheduler_QueueFullPerClass(t *testing.T) {
+	s := newDispatchScheduler(&SchedulerConfig{
+		WorkerCount:    1,
+		TaskBatchSize:  1,
+		BatchTaskTypes: []model.TaskType{model.TaskTypeJavaHeap},
+	})
+
+	queueTask(t, s, 1, model.TaskTypeJavaHeap, "", 0)
+	queueTask(t, s, 2, model.TaskTypeJavaHeap, "", 0)
+	assert.False(t, s.enqueue(&Task{ID: 3, Type: model.TaskTypeJavaHeap, Class: PriorityBatch}))
+
+	// A full batch queue does not turn away interactive tasks
+	assert.True(t, s.enqueue(&Task{ID: 4, Type: model.TaskTypeJava, Class: PriorityInteractive}))
+}
+
+func TestFromConfig_ClassesAndTenants(t *testing.T) {
+	cfg := FromConfig(&config.SchedulerConfig{
+		WorkerCount:         4,
+		BatchTaskTypes:      []string{"java_heap", "bolt"},
+		TenantMaxConcurrent: 2,
+		TenantLimits:        map[string]int{"team-a": 3},
+	})
+
+	assert.Equal(t, []model.TaskType{model.TaskTypeJavaHeap, model.TaskTypeBolt}, cfg.BatchTaskTypes)
+	assert.Equal(t, 2, cfg.TenantMaxConcurrent)
+	assert.Equal(t, map[string]int{"team-a": 3}, cfg.TenantLimits)
+}
+
+func TestScheduler_MemoryBudget(t *testing.T) {
+	s := newDispatchScheduler(&SchedulerConfig{
+		WorkerCount:   4,
+		TaskBatchSize: 10,
+		MemoryBudget:  10 << 30,
+	})
+
+	queueMemoryTask := func(id int64, memory int64) *Task {
+		task := &Task{ID: id, Type: model.TaskTypeJavaHeap, Memory: memory}
+		task.Class = s.classify(task)
+		require.True(t, s.enqueue(task))
+		return task
+	}
+	first := queueMemoryTask(1, 6<<30)
+	queueMemoryTask(2, 6<<30)
+	queueMemoryTask(3, 3<<30)
+
+	// The second large dump waits for memory, without holding back the
+	// small one
+	assert.Equal(t, []int64{1, 3}, dispatchIDs(s))
+	assert.Equal(t, int64(9<<30), s.Stats().ReservedMemory)
+
+	s.release(first)
+	assert.Equal(t, []int64{2}, dispatchIDs(s))
+	assert.Equal(t, int64(9<<30), s.Stats().ReservedMemory)
+}
+
+// fixedEstimator estimates the memory of tasks by ID.
+type fixedEstimator map[int64]int64
+
+func (e fixedEstimator) EstimateMemory(_ context.Context, task *Task) (int64, error) {
+	memory, ok := e[task.ID]
+	if !ok {
+		return 0, assert.AnError
+	}
+	return memory, nil
+}
+
+func TestScheduler_Admit(t *testing.T) {
+	s := newDispatchScheduler(&SchedulerConfig{
+		WorkerCount:   1,
+		TaskBatchSize: 10,
+		MemoryBudget:  10 << 30,
+	})
+	s.SetMemoryEstimator(fixedEstimator{1: 4 << 30, 2: 12 << 30})
+
+	admit := func(id int64) *Task {
+		event := source.NewTaskEvent(&model.Task{ID: id, Type: model.TaskTypeJavaHeap}, source.SourceTypeDB, "test")
+		task := s.convertEventToTask(event)
+		s.wg.Add(1)
+		s.admit(context.Background(), task)
+		return task
+	}
+
+	assert.Equal(t, int64(4<<30), admit(1).Memory)
+	assert.Equal(t, 1, s.Stats().QueuedTasks)
+
+	// Larger than the whole budget: rejected
+	admit(2)
+	assert.Equal(t, 1, s.Stats().QueuedTasks)
+
+	// Not estimated: admitted
+	assert.Equal(t, int64(0), admit(3).Memory)
+	assert.Equal(t, 2, s.Stats().QueuedTasks)
+}
+
+// testHeapDump returns a heap dump of n instances without fields.
+func testHeapDump(n int) []byte {
+	var heap bytes.Buffer
+	for i := 0; i < n; i++ {
+		heap.WriteByte(0x21) // INSTANCE_DUMP
+		binary.Write(&heap, binary.BigEndian, uint64(0x1000+i))
+		binary.Write(&heap, binary.BigEndian, uint32(0))
+		binary.Write(&heap, binary.BigEndian, uint64(0x10))
+		binary.Write(&heap, binary.BigEndian, uint32(0))
+	}
+
+	var dump bytes.Buffer
+	dump.WriteString("JAVA PROFILE 1.0.2\x00")
+	binary.Write(&dump, binary.BigEndian, uint32(8))
+	binary.Write(&dump, binary.BigEndian, uint64(0))
+	dump.WriteByte(0x1C) // HEAP_DUMP_SEGMENT
+	binary.Write(&dump, binary.BigEndian, uint32(0))
+	binary.Write(&dump, binary.BigEndian, uint32(heap.Len()))
+	dump.Write(heap.Bytes())
+	return dump.Bytes()
+}
+
+func TestHeapDumpEstimator(t *testing.T) {
+	dir := t.TempDir()
+	store, err := storage.NewLocalStorage(dir)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "small.hprof"), testHeapDump(1000), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "large.hprof"), testHeapDump(100000), 0644))
+
+	estimator := NewHeapDumpEstimator(store, 4096)
+	ctx := context.Background()
+
+	small, err := estimator.EstimateMemory(ctx, &Task{Type: model.TaskTypeJavaHeap, ResultFile: "small.hprof"})
+	require.NoError(t, err)
+	large, err := estimator.EstimateMemory(ctx, &Task{Type: model.TaskTypeJavaHeap, ResultFile: "large.hprof"})
+	require.NoError(t, err)
+
+	// Extrapolated from the first 4KB of the large dump
+	assert.InEpsilon(t, hprof.EstimateParseMemory(100000), large, 0.01)
+	assert.Greater(t, large, small)
+
+	// Only heap dumps are estimated
+	memory, err := estimator.EstimateMemory(ctx, &Task{Type: model.TaskTypeJava, ResultFile: "small.hprof"})
+	require.NoError(t, err)
+	assert.Zero(t, memory)
+
+	_, err = estimator.EstimateMemory(ctx, &Task{Type: model.TaskTypeJavaHeap, ResultFile: "missing.hprof"})
+	assert.Error(t, err)
+}
+
+func TestFromConfig_Admission(t *testing.T) {
+	cfg := FromConfig(&config.SchedulerConfig{
+		WorkerCount: 1,
+		Admission:   config.AdmissionConfig{Enabled: true, MemoryBudgetMB: 4096},
+	})
+	assert.Equal(t, int64(4<<30), cfg.MemoryBudget)
+
+	cfg = FromConfig(&config.SchedulerConfig{
+		WorkerCount: 1,
+		Admission:   config.AdmissionConfig{MemoryBudgetMB: 4096},
+	})
+	assert.Zero(t, cfg.MemoryBudget, "admission control disabled")
+}
+
+// deadLetterStore is a repository.DeadLetterRepository in memory.
+type deadLetterStore struct {
+	mu      sync.Mutex
+	letters []*model.DeadLetter
+}
+
+func (d *deadLetterStore) SaveDeadLetter(_ context.Context, letter *model.DeadLetter) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.letters = append(d.letters, letter)
+	return nil
+}
+
+func (d *deadLetterStore) GetDeadLetter(context.Context, string) (*model.DeadLetter, error) {
+	return nil, nil
+}
+
+func (d *deadLetterStore) ListDeadLetters(context.Context, int) ([]*model.DeadLetter, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.letters, nil
+}
+
+func (d *deadLetterStore) DeleteDeadLetter(context.Context, string) error {
+	return nil
+}
+
+func TestScheduler_RetryAndDeadLetter(t *testing.T) {
+	queue := &memoryQueue{
+		messages: []*source.QueueMessage{
+			{ID: "m1", Body: []byte(`{"task": {"id": 1, "tid": "flaky"}}`)},
+			{ID: "m2", Body: []byte(`{"task": {"id": 2, "tid": "broken"}}`)},
+			{ID: "m3", Body: []byte(`{"task": {"id": 3, "tid": "unreachable"}}`), Attempts: 2},
+		},
+		nacked: make(map[string]time.Duration),
+	}
+	logger := utils.NewDefaultLogger(utils.LevelDebug, io.Discard)
+	opts := &source.QueueOptions{BatchSize: 10, VisibilityTimeout: time.Minute, NackDelay: 5 * time.Second}
+	src := source.NewQueueSource("memory", "test", queue, opts, logger)
+	aggregator := source.NewAggregator([]source.TaskSource{src}, 10, logger)
+
+	downloadErr := apperrors.Wrap(apperrors.CodeDownloadError, "failed to download result file", assert.AnError)
+	processor := &MockTaskProcessor{}
+	processor.On("Process", mock.Anything, mock.MatchedBy(func(task *Task) bool { return task.UUID == "flaky" && task.Attempts == 1 }), mock.Anything).Return(downloadErr)
+	processor.On("Process", mock.Anything, mock.MatchedBy(func(task *Task) bool { return task.UUID == "flaky" && task.Attempts == 2 }), mock.Anything).Return(nil)
+	processor.On("Process", mock.Anything, mock.MatchedBy(func(task *Task) bool { return task.UUID == "broken" }), mock.Anything).
+		Return(&PartialResultError{Err: assert.AnError, Artifacts: []string{"broken/partial/histogram.json"}})
+	processor.On("Process", mock.Anything, mock.MatchedBy(func(task *Task) bool { return task.UUID == "unreachable" }), mock.Anything).Return(downloadErr)
+
+	s := New(&SchedulerConfig{WorkerCount: 2, TaskBatchSize: 5, MaxAttempts: 3, RetryBackoff: time.Millisecond}, aggregator, processor, nil, logger)
+	deadLetters := &deadLetterStore{}
+	s.SetDeadLetterRepository(deadLetters)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	require.NoError(t, s.Start(ctx))
+
+	require.Eventually(t, func() bool {
+		queue.mu.Lock()
+		defer queue.mu.Unlock()
+		return len(queue.acked) == 3
+	}, 5*time.Second, 10*time.Millisecond)
+
+	cancel()
+	s.Stop()
+	require.NoError(t, aggregator.Stop())
+
+	// The flaky task succeeds on its second attempt, the others are removed
+	// from the queue once dead-lettered
+	assert.ElementsMatch(t, []string{"m1", "m2", "m3"}, queue.acked)
+	assert.Empty(t, queue.nacked)
+	assert.Equal(t, int32(5), processor.GetProcessedCount())
+
+	letters, _ := deadLetters.ListDeadLetters(ctx, 0)
+	require.Len(t, letters, 2)
+	byTask := make(map[string]*model.DeadLetter)
+	for _, letter := range letters {
+		byTask[letter.TaskUUID] = letter
+	}
+
+	broken := byTask["broken"]
+	require.NotNil(t, broken)
+	assert.Equal(t, 1, broken.Attempts, "permanent errors are not retried")
+	assert.Equal(t, "memory/test", broken.Source)
+	assert.Equal(t, []string{"broken/partial/histogram.json"}, broken.Artifacts)
+
+	unreachable := byTask["unreachable"]
+	require.NotNil(t, unreachable)
+	assert.Equal(t, 3, unreachable.Attempts, "attempts count the deliveries of the queue")
+	assert.Contains(t, unreachable.Error, "DOWNLOAD_ERROR")
+}
+
+func TestScheduler_RetryDelay(t *testing.T) {
+	s := newDispatchScheduler(&SchedulerConfig{
+		WorkerCount:     1,
+		RetryBackoff:    10 * time.Second,
+		MaxRetryBackoff: time.Minute,
+	})
+
+	for attempts, want := range map[int]time.Duration{
+		1:  10 * time.Second,
+		2:  20 * time.Second,
+		3:  40 * time.Second,
+		4:  time.Minute,
+		40: time.Minute,
+	} {
+		assert.Equal(t, want, s.retryDelay(&Task{Attempts: attempts}), "attempt %d", attempts)
+	}
+}
+
+func TestIsTransient(t *testing.T) {
+	assert.True(t, IsTransient(apperrors.Wrap(apperrors.CodeDownloadError, "failed to download result file", assert.AnError)))
+	assert.True(t, IsTransient(apperrors.Wrap(apperrors.CodeDatabaseError, "failed to save results", assert.AnError)))
+	assert.True(t, IsTransient(fmt.Errorf("analysis failed: %w", context.DeadlineExceeded)))
+	assert.True(t, IsTransient(&PartialResultError{Err: fmt.Errorf("mmap: %w", syscall.ENOMEM)}))
+
+	assert.False(t, IsTransient(fmt.Errorf("analysis failed: %w", assert.AnError)))
+	assert.False(t, IsTransient(context.Canceled))
+}
@@ -185,6 +185,19 @@ func (a *Aggregator) Nack(ctx context.Context, event *TaskEvent, reason string)
 	return src.Nack(ctx, event, reason)
 }
 
+// DeadLetter removes a task failing for good from its source, when the
+// source supports it, else nacks it.
+func (a *Aggregator) DeadLetter(ctx context.Context, event *TaskEvent, reason string) error {
+	src := a.GetSourceForEvent(event)
+	if src == nil {
+		return nil
+	}
+	if dl, ok := src.(DeadLetterer); ok {
+		return dl.DeadLetter(ctx, event, reason)
+	}
+	return src.Nack(ctx, event, reason)
+}
+
 // HealthCheck performs health checks on all sources.
 func (a *Aggregator) HealthCheck(ctx context.Context) error {
 	for _, src := range a.sources {
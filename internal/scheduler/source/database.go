@@ -2,6 +2,10 @@ package source
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
 	"sync"
 	"time"
 
@@ -25,17 +29,39 @@ type DatabaseOptions struct {
 
 	// BatchSize is the maximum number of tasks to fetch per poll.
 	BatchSize int
+
+	// LeaseTTL is how long a task stays leased to this instance without a
+	// heartbeat. Leases are renewed while tasks are analyzed; the tasks of
+	// a crashed instance are taken over by others once its leases expire.
+	LeaseTTL time.Duration
+
+	// MaxAttempts is the number of times a task is leased before it is
+	// failed, so a task crashing its analyzer is not retried forever.
+	MaxAttempts int
+
+	// Owner identifies this instance in the leases; unique per instance.
+	Owner string
 }
 
 // DefaultDatabaseOptions returns the default options.
 func DefaultDatabaseOptions() *DatabaseOptions {
+	owner := "perf-analyzer"
+	if host, err := os.Hostname(); err == nil {
+		owner = host
+	}
 	return &DatabaseOptions{
 		PollInterval: 2 * time.Second,
 		BatchSize:    10,
+		LeaseTTL:     time.Minute,
+		MaxAttempts:  3,
+		Owner:        owner + "-" + strconv.Itoa(os.Getpid()),
 	}
 }
 
 // DatabaseSource implements TaskSource for database-based task fetching.
+//
+// With a lease repository, instances sharing the database lease the tasks
+// they analyze: see DatabaseOptions.LeaseTTL.
 type DatabaseSource struct {
 	name    string
 	options *DatabaseOptions
@@ -43,19 +69,28 @@ type DatabaseSource struct {
 
 	taskRepo       repository.TaskRepository
 	suggestionRepo repository.SuggestionRepository
+	leaseRepo      repository.LeaseRepository
 
 	taskChan chan *TaskEvent
 	stopCh   chan struct{}
 
-	mu      sync.RWMutex
-	running bool
+	mu       sync.RWMutex
+	running  bool
+	inFlight map[int64]struct{}
 }
 
 // NewDatabaseSource creates a new database source from configuration.
 func NewDatabaseSource(cfg *SourceConfig) (TaskSource, error) {
+	defaults := DefaultDatabaseOptions()
 	opts := &DatabaseOptions{
-		PollInterval: cfg.GetDuration("poll_interval", 2*time.Second),
-		BatchSize:    cfg.GetInt("batch_size", 10),
+		PollInterval: cfg.GetDuration("poll_interval", defaults.PollInterval),
+		BatchSize:    cfg.GetInt("batch_size", defaults.BatchSize),
+		LeaseTTL:     cfg.GetDuration("lease_ttl", defaults.LeaseTTL),
+		MaxAttempts:  cfg.GetInt("max_attempts", defaults.MaxAttempts),
+		Owner:        cfg.GetString("owner", defaults.Owner),
+	}
+	if opts.LeaseTTL <= 0 {
+		return nil, fmt.Errorf("database source %s: lease_ttl must be positive", cfg.Name)
 	}
 
 	return &DatabaseSource{
@@ -63,6 +98,7 @@ func NewDatabaseSource(cfg *SourceConfig) (TaskSource, error) {
 		options:  opts,
 		taskChan: make(chan *TaskEvent, opts.BatchSize*2),
 		stopCh:   make(chan struct{}),
+		inFlight: make(map[int64]struct{}),
 	}, nil
 }
 
@@ -84,6 +120,7 @@ func NewDatabaseSourceWithDeps(name string, opts *DatabaseOptions, taskRepo repo
 		suggestionRepo: suggestionRepo,
 		taskChan:       make(chan *TaskEvent, opts.BatchSize*2),
 		stopCh:         make(chan struct{}),
+		inFlight:       make(map[int64]struct{}),
 	}
 }
 
@@ -94,6 +131,12 @@ func (s *DatabaseSource) SetRepositories(taskRepo repository.TaskRepository, sug
 	s.suggestionRepo = suggestionRepo
 }
 
+// SetLeaseRepository sets the lease repository, enabling task leasing.
+// This must be called before Start.
+func (s *DatabaseSource) SetLeaseRepository(leaseRepo repository.LeaseRepository) {
+	s.leaseRepo = leaseRepo
+}
+
 // SetLogger sets the logger.
 func (s *DatabaseSource) SetLogger(logger utils.Logger) {
 	s.logger = logger
@@ -128,13 +171,20 @@ func (s *DatabaseSource) Start(ctx context.Context) error {
 	if s.logger != nil {
 		s.logger.Info("Database source %s starting with poll_interval=%v, batch_size=%d",
 			s.name, s.options.PollInterval, s.options.BatchSize)
+		if s.leaseRepo != nil {
+			s.logger.Info("Database source %s leasing tasks as %s (lease_ttl=%v)", s.name, s.options.Owner, s.options.LeaseTTL)
+		}
 	}
 
 	go s.pollLoop(ctx)
+	if s.leaseRepo != nil {
+		go s.heartbeatLoop(ctx)
+	}
 	return nil
 }
 
-// Stop stops the database source.
+// Stop stops the database source. The leases of the tasks in flight are no
+// longer renewed, and are taken over by other instances once expired.
 func (s *DatabaseSource) Stop() error {
 	s.mu.Lock()
 	if !s.running {
@@ -154,20 +204,38 @@ func (s *DatabaseSource) Tasks() <-chan *TaskEvent {
 }
 
 // Ack acknowledges a task has been processed successfully.
-// For database source, this updates the task status to completed.
+// For database source, this updates the task status to completed and
+// releases its lease. Results are saved idempotently, so a task completed
+// after its lease was taken over is still completed.
 func (s *DatabaseSource) Ack(ctx context.Context, event *TaskEvent) error {
 	if s.taskRepo == nil || event.Task == nil {
 		return nil
 	}
-	return s.taskRepo.UpdateAnalysisStatus(ctx, event.Task.ID, model.AnalysisStatusCompleted)
+	if err := s.taskRepo.UpdateAnalysisStatus(ctx, event.Task.ID, model.AnalysisStatusCompleted); err != nil {
+		return err
+	}
+	if err := s.releaseLease(ctx, event.Task.ID); err != nil && !errors.Is(err, repository.ErrLeaseLost) {
+		return err
+	}
+	return nil
 }
 
 // Nack indicates a task processing failed.
-// For database source, this updates the task status to failed.
+// For database source, this updates the task status to failed, unless its
+// lease was taken over by another instance which is now analyzing it.
 func (s *DatabaseSource) Nack(ctx context.Context, event *TaskEvent, reason string) error {
 	if s.taskRepo == nil || event.Task == nil {
 		return nil
 	}
+	if err := s.releaseLease(ctx, event.Task.ID); err != nil {
+		if errors.Is(err, repository.ErrLeaseLost) {
+			if s.logger != nil {
+				s.logger.Warn("Database source %s not failing task %d: %v", s.name, event.Task.ID, err)
+			}
+			return nil
+		}
+		return err
+	}
 	return s.taskRepo.UpdateAnalysisStatusWithInfo(ctx, event.Task.ID, model.AnalysisStatusFailed, reason)
 }
 
@@ -207,6 +275,10 @@ func (s *DatabaseSource) poll(ctx context.Context) {
 		return
 	}
 
+	if s.leaseRepo != nil && !s.takeOverExpired(ctx) {
+		return
+	}
+
 	tasks, err := s.taskRepo.GetPendingTasks(ctx, s.options.BatchSize)
 	if err != nil {
 		if s.logger != nil {
@@ -216,35 +288,201 @@ func (s *DatabaseSource) poll(ctx context.Context) {
 	}
 
 	for _, task := range tasks {
+		if s.leaseRepo != nil && s.channelFull() {
+			return // Leave the tasks to instances with free workers
+		}
+
+		// Lease the task before locking it: an instance crashing in between
+		// leaves a pending task, leased until the lease expires
+		var lease *model.TaskLease
+		if s.leaseRepo != nil {
+			lease, err = s.leaseRepo.AcquireLease(ctx, task, s.options.Owner, s.options.LeaseTTL)
+			if err != nil {
+				if s.logger != nil {
+					s.logger.Error("Database source %s failed to lease task %d: %v", s.name, task.ID, err)
+				}
+				continue
+			}
+			if lease == nil {
+				continue // Task leased by another instance
+			}
+		}
+
 		// Try to lock the task
 		locked, err := s.taskRepo.LockTaskForAnalysis(ctx, task.ID)
 		if err != nil {
 			if s.logger != nil {
 				s.logger.Error("Database source %s failed to lock task %d: %v", s.name, task.ID, err)
 			}
+			s.releaseLease(ctx, task.ID)
 			continue
 		}
 		if !locked {
+			s.releaseLease(ctx, task.ID)
 			continue // Task already locked by another instance
 		}
 
-		// Create and emit task event
-		event := NewTaskEvent(task, SourceTypeDB, s.name).
-			WithMetadata("locked_at", time.Now().Format(time.RFC3339))
+		if !s.emit(ctx, task, lease) {
+			return
+		}
+	}
+}
 
-		select {
-		case s.taskChan <- event:
+// takeOverExpired leases the running tasks whose lease expired, and emits
+// them again. Tasks leased MaxAttempts times are failed instead. It returns
+// false if the source is stopping.
+func (s *DatabaseSource) takeOverExpired(ctx context.Context) bool {
+	expired, err := s.leaseRepo.GetExpiredLeases(ctx, s.options.BatchSize)
+	if err != nil {
+		if s.logger != nil {
+			s.logger.Error("Database source %s failed to fetch expired leases: %v", s.name, err)
+		}
+		return true
+	}
+
+	for _, old := range expired {
+		if s.channelFull() {
+			return true
+		}
+
+		task, err := s.taskRepo.GetTaskByID(ctx, old.TaskID)
+		if err != nil {
 			if s.logger != nil {
-				s.logger.Debug("Database source %s emitted task %s", s.name, task.TaskUUID)
+				s.logger.Error("Database source %s failed to fetch task %d: %v", s.name, old.TaskID, err)
+			}
+			continue
+		}
+
+		lease, err := s.leaseRepo.AcquireLease(ctx, task, s.options.Owner, s.options.LeaseTTL)
+		if err != nil {
+			if s.logger != nil {
+				s.logger.Error("Database source %s failed to take over task %d: %v", s.name, task.ID, err)
+			}
+			continue
+		}
+		if lease == nil {
+			continue // Taken over by another instance
+		}
+
+		if s.logger != nil {
+			s.logger.Warn("Database source %s took over task %s from %s (attempt %d)", s.name, task.TaskUUID, old.Owner, lease.Attempts)
+		}
+
+		if s.options.MaxAttempts > 0 && lease.Attempts > s.options.MaxAttempts {
+			reason := fmt.Sprintf("abandoned after %d attempts", s.options.MaxAttempts)
+			if err := s.taskRepo.UpdateAnalysisStatusWithInfo(ctx, task.ID, model.AnalysisStatusFailed, reason); err != nil && s.logger != nil {
+				s.logger.Error("Database source %s failed to fail task %d: %v", s.name, task.ID, err)
 			}
+			s.releaseLease(ctx, task.ID)
+			continue
+		}
+
+		if !s.emit(ctx, task, lease) {
+			return false
+		}
+	}
+	return true
+}
+
+// emit sends a locked task to the task channel. It returns false if the
+// source is stopping.
+func (s *DatabaseSource) emit(ctx context.Context, task *model.Task, lease *model.TaskLease) bool {
+	// Create and emit task event
+	event := NewTaskEvent(task, SourceTypeDB, s.name).
+		WithMetadata("locked_at", time.Now().Format(time.RFC3339))
+	if lease != nil {
+		event.WithMetadata("attempts", strconv.Itoa(lease.Attempts))
+		s.mu.Lock()
+		s.inFlight[task.ID] = struct{}{}
+		s.mu.Unlock()
+	}
+
+	select {
+	case s.taskChan <- event:
+		if s.logger != nil {
+			s.logger.Debug("Database source %s emitted task %s", s.name, task.TaskUUID)
+		}
+	case <-ctx.Done():
+		return false
+	case <-s.stopCh:
+		return false
+	default:
+		// Channel full, task will be picked up in next poll
+		if s.logger != nil {
+			s.logger.Warn("Database source %s task channel full, task %d will retry", s.name, task.ID)
+		}
+		if lease != nil {
+			// Expire the lease for the task to be taken over
+			s.mu.Lock()
+			delete(s.inFlight, task.ID)
+			s.mu.Unlock()
+			if err := s.leaseRepo.RenewLease(ctx, task.ID, s.options.Owner, 0); err != nil && s.logger != nil {
+				s.logger.Error("Database source %s failed to expire lease of task %d: %v", s.name, task.ID, err)
+			}
+		}
+	}
+	return true
+}
+
+// channelFull reports whether the task channel is full. Only the poll loop
+// sends to it, so a task leased while it is not full is emitted.
+func (s *DatabaseSource) channelFull() bool {
+	return len(s.taskChan) == cap(s.taskChan)
+}
+
+// releaseLease stops renewing the lease of a task and releases it. It does
+// nothing without a lease repository.
+func (s *DatabaseSource) releaseLease(ctx context.Context, taskID int64) error {
+	if s.leaseRepo == nil {
+		return nil
+	}
+
+	s.mu.Lock()
+	delete(s.inFlight, taskID)
+	s.mu.Unlock()
+
+	err := s.leaseRepo.ReleaseLease(ctx, taskID, s.options.Owner)
+	if err != nil && !errors.Is(err, repository.ErrLeaseLost) && s.logger != nil {
+		s.logger.Error("Database source %s failed to release lease of task %d: %v", s.name, taskID, err)
+	}
+	return err
+}
+
+// heartbeatLoop renews the leases of the tasks in flight, well before they
+// expire.
+func (s *DatabaseSource) heartbeatLoop(ctx context.Context) {
+	ticker := time.NewTicker(s.options.LeaseTTL / 3)
+	defer ticker.Stop()
+
+	for {
+		select {
 		case <-ctx.Done():
 			return
 		case <-s.stopCh:
 			return
-		default:
-			// Channel full, task will be picked up in next poll
-			if s.logger != nil {
-				s.logger.Warn("Database source %s task channel full, task %d will retry", s.name, task.ID)
+		case <-ticker.C:
+			s.mu.RLock()
+			taskIDs := make([]int64, 0, len(s.inFlight))
+			for taskID := range s.inFlight {
+				taskIDs = append(taskIDs, taskID)
+			}
+			s.mu.RUnlock()
+
+			for _, taskID := range taskIDs {
+				err := s.leaseRepo.RenewLease(ctx, taskID, s.options.Owner, s.options.LeaseTTL)
+				if err == nil {
+					continue
+				}
+				if errors.Is(err, repository.ErrLeaseLost) {
+					// Another instance took the task over; whichever
+					// finishes first saves the results
+					s.mu.Lock()
+					delete(s.inFlight, taskID)
+					s.mu.Unlock()
+				}
+				if s.logger != nil {
+					s.logger.Warn("Database source %s failed to renew lease of task %d: %v", s.name, taskID, err)
+				}
 			}
 		}
 	}
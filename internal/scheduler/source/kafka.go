@@ -2,11 +2,14 @@ package source
 
 import (
 	"context"
-	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
 	"sync"
+	"time"
 
-	"github.com/perf-analysis/pkg/model"
-	"github.com/perf-analysis/pkg/utils"
+	"github.com/segmentio/kafka-go"
 )
 
 // SourceTypeKafka is the source type constant for Kafka source.
@@ -28,240 +31,226 @@ type KafkaOptions struct {
 	// ConsumerGroup is the consumer group ID.
 	ConsumerGroup string
 
-	// AutoCommit enables automatic offset commit.
-	AutoCommit bool
-
-	// MaxPollRecords is the maximum number of records to poll at once.
-	MaxPollRecords int
+	// PollWait is how long a receive waits for a message.
+	PollWait time.Duration
 }
 
 // DefaultKafkaOptions returns the default options.
 func DefaultKafkaOptions() *KafkaOptions {
 	return &KafkaOptions{
-		Brokers:        []string{"localhost:9092"},
-		Topic:          "perf-tasks",
-		ConsumerGroup:  "perf-analyzer",
-		AutoCommit:     false,
-		MaxPollRecords: 100,
+		Brokers:       []string{"localhost:9092"},
+		Topic:         "perf-tasks",
+		ConsumerGroup: "perf-analyzer",
+		PollWait:      5 * time.Second,
 	}
 }
 
-// KafkaMessage represents a message from Kafka containing task data.
-type KafkaMessage struct {
-	Task   *model.Task `json:"task"`
-	Offset int64       `json:"-"` // Kafka offset for acknowledgment
-}
-
-// KafkaSource implements TaskSource for Kafka-based task consumption.
-type KafkaSource struct {
-	name    string
-	options *KafkaOptions
-	logger  utils.Logger
-
-	taskChan chan *TaskEvent
-	stopCh   chan struct{}
-
-	mu      sync.RWMutex
-	running bool
-
-	// consumer would be the actual Kafka consumer (e.g., sarama, confluent-kafka-go)
-	// consumer kafka.Consumer
-}
-
 // NewKafkaSource creates a new Kafka source from configuration.
 func NewKafkaSource(cfg *SourceConfig) (TaskSource, error) {
+	defaults := DefaultKafkaOptions()
 	opts := &KafkaOptions{
-		Brokers:        cfg.GetStringSlice("brokers", []string{"localhost:9092"}),
-		Topic:          cfg.GetString("topic", "perf-tasks"),
-		ConsumerGroup:  cfg.GetString("consumer_group", "perf-analyzer"),
-		AutoCommit:     cfg.GetBool("auto_commit", false),
-		MaxPollRecords: cfg.GetInt("max_poll_records", 100),
-	}
-
-	return &KafkaSource{
-		name:     cfg.Name,
-		options:  opts,
-		taskChan: make(chan *TaskEvent, opts.MaxPollRecords),
-		stopCh:   make(chan struct{}),
-	}, nil
-}
-
-// NewKafkaSourceWithOptions creates a new Kafka source with explicit options.
-func NewKafkaSourceWithOptions(name string, opts *KafkaOptions, logger utils.Logger) *KafkaSource {
-	if opts == nil {
-		opts = DefaultKafkaOptions()
+		Brokers:       cfg.GetStringSlice("brokers", defaults.Brokers),
+		Topic:         cfg.GetString("topic", defaults.Topic),
+		ConsumerGroup: cfg.GetString("consumer_group", defaults.ConsumerGroup),
+		PollWait:      cfg.GetDuration("poll_wait", defaults.PollWait),
 	}
-	if logger == nil {
-		logger = utils.NewDefaultLogger(utils.LevelInfo, nil)
+	if len(opts.Brokers) == 0 {
+		return nil, errors.New("kafka source requires brokers")
 	}
 
-	return &KafkaSource{
-		name:     name,
-		options:  opts,
-		logger:   logger,
-		taskChan: make(chan *TaskEvent, opts.MaxPollRecords),
-		stopCh:   make(chan struct{}),
+	queueOpts, err := queueOptionsFromConfig(cfg, 0)
+	if err != nil {
+		return nil, err
 	}
+	return NewQueueSource(SourceTypeKafka, cfg.Name, NewKafkaQueue(opts), queueOpts, nil), nil
 }
 
-// SetLogger sets the logger.
-func (s *KafkaSource) SetLogger(logger utils.Logger) {
-	s.logger = logger
+// KafkaQueue implements Queue with a Kafka consumer group.
+//
+// Kafka has no per-message acknowledgment: the group commits an offset per
+// partition. An offset is committed only once all messages before it are
+// acked, so unacked messages are consumed again after a restart or a
+// rebalance. Visibility timeouts are tracked locally: a message not acked in
+// time is delivered again by this consumer.
+type KafkaQueue struct {
+	options *KafkaOptions
+	reader  kafkaReader
+
+	mu sync.Mutex
+	// pending holds the uncommitted messages of each partition, by offset
+	pending map[int][]*kafkaPending
 }
 
-// Type returns the source type.
-func (s *KafkaSource) Type() SourceType {
-	return SourceTypeKafka
+// kafkaReader is the consumer group reader of a KafkaQueue, a *kafka.Reader.
+type kafkaReader interface {
+	FetchMessage(ctx context.Context) (kafka.Message, error)
+	CommitMessages(ctx context.Context, msgs ...kafka.Message) error
+	Close() error
 }
 
-// Name returns the source instance name.
-func (s *KafkaSource) Name() string {
-	return s.name
+// kafkaBatchWait is how long a receive waits for each message after the
+// first one of a batch. The reader prefetches messages in the background, so
+// those of a backlog are returned without waiting.
+const kafkaBatchWait = 10 * time.Millisecond
+
+// kafkaPending is a received message that is not committed yet.
+type kafkaPending struct {
+	msg      kafka.Message
+	attempts int
+	acked    bool
+	// visibleAt is when the message is delivered again unless acked
+	visibleAt time.Time
 }
 
-// Start starts the Kafka consumer.
-func (s *KafkaSource) Start(ctx context.Context) error {
-	s.mu.Lock()
-	if s.running {
-		s.mu.Unlock()
-		return nil
+// NewKafkaQueue creates a Kafka queue consuming opts.Topic.
+func NewKafkaQueue(opts *KafkaOptions) *KafkaQueue {
+	if opts == nil {
+		opts = DefaultKafkaOptions()
 	}
-	s.running = true
-	s.mu.Unlock()
-
-	if s.logger != nil {
-		s.logger.Info("Kafka source %s starting with brokers=%v, topic=%s, group=%s",
-			s.name, s.options.Brokers, s.options.Topic, s.options.ConsumerGroup)
+	return &KafkaQueue{
+		options: opts,
+		reader: kafka.NewReader(kafka.ReaderConfig{
+			Brokers: opts.Brokers,
+			Topic:   opts.Topic,
+			GroupID: opts.ConsumerGroup,
+			// Commit synchronously, on ack only
+			CommitInterval: 0,
+		}),
+		pending: make(map[int][]*kafkaPending),
 	}
+}
 
-	// TODO: Initialize actual Kafka consumer here
-	// Example with sarama:
-	// config := sarama.NewConfig()
-	// config.Consumer.Group.Rebalance.Strategy = sarama.BalanceStrategyRoundRobin
-	// consumer, err := sarama.NewConsumerGroup(s.options.Brokers, s.options.ConsumerGroup, config)
-	// if err != nil {
-	//     return err
-	// }
-	// s.consumer = consumer
+// Receive returns the messages whose visibility timeout expired, and fills
+// the batch up to max with the next messages of the topic. It waits up to
+// the poll wait for a first message only.
+func (q *KafkaQueue) Receive(ctx context.Context, max int, visibilityTimeout time.Duration) ([]*QueueMessage, error) {
+	msgs := q.redeliver(max, visibilityTimeout)
+	for len(msgs) < max {
+		wait := q.options.PollWait
+		if len(msgs) > 0 {
+			wait = kafkaBatchWait
+		}
+		fetchCtx, cancel := context.WithTimeout(ctx, wait)
+		msg, err := q.reader.FetchMessage(fetchCtx)
+		cancel()
+		if err != nil {
+			if len(msgs) > 0 || (errors.Is(err, context.DeadlineExceeded) && ctx.Err() == nil) {
+				// Errors other than timeouts recur on the next receive
+				break
+			}
+			return nil, err
+		}
 
-	go s.consumeLoop(ctx)
-	return nil
+		p := &kafkaPending{msg: msg, attempts: 1, visibleAt: time.Now().Add(visibilityTimeout)}
+		q.mu.Lock()
+		q.pending[msg.Partition] = append(q.pending[msg.Partition], p)
+		q.mu.Unlock()
+		msgs = append(msgs, p.message())
+	}
+	return msgs, nil
 }
 
-// Stop stops the Kafka consumer.
-func (s *KafkaSource) Stop() error {
-	s.mu.Lock()
-	if !s.running {
-		s.mu.Unlock()
-		return nil
+// redeliver returns up to max pending messages that became visible again.
+func (q *KafkaQueue) redeliver(max int, visibilityTimeout time.Duration) []*QueueMessage {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	now := time.Now()
+	var msgs []*QueueMessage
+	for _, partition := range q.partitions() {
+		for _, p := range q.pending[partition] {
+			if len(msgs) == max {
+				return msgs
+			}
+			if p.acked || now.Before(p.visibleAt) {
+				continue
+			}
+			p.attempts++
+			p.visibleAt = now.Add(visibilityTimeout)
+			msgs = append(msgs, p.message())
+		}
 	}
-	s.running = false
-	s.mu.Unlock()
-
-	close(s.stopCh)
-
-	// TODO: Close actual Kafka consumer
-	// if s.consumer != nil {
-	//     return s.consumer.Close()
-	// }
-
-	return nil
+	return msgs
 }
 
-// Tasks returns the task event channel.
-func (s *KafkaSource) Tasks() <-chan *TaskEvent {
-	return s.taskChan
+// partitions returns the partitions with pending messages, sorted.
+func (q *KafkaQueue) partitions() []int {
+	partitions := make([]int, 0, len(q.pending))
+	for partition := range q.pending {
+		partitions = append(partitions, partition)
+	}
+	sort.Ints(partitions)
+	return partitions
 }
 
-// Ack acknowledges a task has been processed successfully.
-// For Kafka source, this commits the message offset.
-func (s *KafkaSource) Ack(ctx context.Context, event *TaskEvent) error {
-	// TODO: Commit Kafka offset
-	// offset := event.AckToken.(int64)
-	// return s.consumer.CommitOffset(s.options.Topic, partition, offset)
+// Ack marks a message processed and commits the offsets of the partition up
+// to the first message that is still unacked.
+func (q *KafkaQueue) Ack(ctx context.Context, msg *QueueMessage) error {
+	p, ok := msg.handle.(*kafkaPending)
+	if !ok {
+		return fmt.Errorf("message %s was not received from kafka", msg.ID)
+	}
 
-	if s.logger != nil {
-		s.logger.Debug("Kafka source %s acked task %s", s.name, event.ID)
+	q.mu.Lock()
+	p.acked = true
+	partition := p.msg.Partition
+	pending := q.pending[partition]
+	done := 0
+	for done < len(pending) && pending[done].acked {
+		done++
 	}
-	return nil
-}
+	if done == 0 {
+		q.mu.Unlock()
+		return nil
+	}
+	last := pending[done-1].msg
+	q.pending[partition] = pending[done:]
+	if len(q.pending[partition]) == 0 {
+		delete(q.pending, partition)
+	}
+	q.mu.Unlock()
 
-// Nack indicates a task processing failed.
-// For Kafka source, this could send to a dead letter queue or retry topic.
-func (s *KafkaSource) Nack(ctx context.Context, event *TaskEvent, reason string) error {
-	// TODO: Send to dead letter queue or retry topic
-	// dlqTopic := s.options.Topic + ".dlq"
-	// return s.producer.Send(dlqTopic, event.Task)
+	return q.reader.CommitMessages(ctx, last)
+}
 
-	if s.logger != nil {
-		s.logger.Warn("Kafka source %s nacked task %s: %s", s.name, event.ID, reason)
+// SetVisibility delivers the message again after d, unless it is acked.
+func (q *KafkaQueue) SetVisibility(_ context.Context, msg *QueueMessage, d time.Duration) error {
+	p, ok := msg.handle.(*kafkaPending)
+	if !ok {
+		return fmt.Errorf("message %s was not received from kafka", msg.ID)
 	}
-	return nil
-}
 
-// HealthCheck checks the Kafka connection.
-func (s *KafkaSource) HealthCheck(ctx context.Context) error {
-	// TODO: Check Kafka broker connectivity
-	// return s.consumer.Ping()
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	p.visibleAt = time.Now().Add(d)
 	return nil
 }
 
-// consumeLoop continuously consumes messages from Kafka.
-func (s *KafkaSource) consumeLoop(ctx context.Context) {
-	// TODO: Implement actual Kafka consumption
-	// This is a placeholder showing the expected flow
-
-	for {
-		select {
-		case <-ctx.Done():
-			return
-		case <-s.stopCh:
-			return
-		default:
-			// TODO: Poll messages from Kafka
-			// messages, err := s.consumer.Poll(100)
-			// if err != nil {
-			//     s.logger.Error("Kafka poll error: %v", err)
-			//     continue
-			// }
-			//
-			// for _, msg := range messages {
-			//     task, err := s.parseMessage(msg)
-			//     if err != nil {
-			//         s.logger.Error("Failed to parse Kafka message: %v", err)
-			//         continue
-			//     }
-			//
-			//     event := NewTaskEvent(task, SourceTypeKafka, s.name).
-			//         WithAckToken(msg.Offset).
-			//         WithMetadata("partition", strconv.Itoa(int(msg.Partition))).
-			//         WithMetadata("offset", strconv.FormatInt(msg.Offset, 10))
-			//
-			//     select {
-			//     case s.taskChan <- event:
-			//     case <-ctx.Done():
-			//         return
-			//     case <-s.stopCh:
-			//         return
-			//     }
-			// }
-
-			// Placeholder: wait for stop signal
-			select {
-			case <-ctx.Done():
-				return
-			case <-s.stopCh:
-				return
-			}
+// Ping checks that a broker is reachable.
+func (q *KafkaQueue) Ping(ctx context.Context) error {
+	var lastErr error
+	for _, broker := range q.options.Brokers {
+		conn, err := kafka.DialContext(ctx, "tcp", broker)
+		if err != nil {
+			lastErr = err
+			continue
 		}
+		return conn.Close()
 	}
+	return fmt.Errorf("no kafka broker reachable: %w", lastErr)
 }
 
-// parseMessage parses a Kafka message into a Task.
-func (s *KafkaSource) parseMessage(data []byte) (*model.Task, error) {
-	var msg KafkaMessage
-	if err := json.Unmarshal(data, &msg); err != nil {
-		return nil, err
+// Close closes the consumer, leaving the consumer group.
+func (q *KafkaQueue) Close() error {
+	return q.reader.Close()
+}
+
+// message returns the QueueMessage of a pending message.
+func (p *kafkaPending) message() *QueueMessage {
+	return &QueueMessage{
+		ID:       strconv.Itoa(p.msg.Partition) + "/" + strconv.FormatInt(p.msg.Offset, 10),
+		Body:     p.msg.Value,
+		Attempts: p.attempts,
+		handle:   p,
 	}
-	return msg.Task, nil
 }
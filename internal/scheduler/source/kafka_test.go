@@ -0,0 +1,156 @@
+package source
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeKafkaReader serves queued messages and records the commits.
+type fakeKafkaReader struct {
+	mu        sync.Mutex
+	messages  []kafka.Message
+	committed []kafka.Message
+}
+
+func (r *fakeKafkaReader) FetchMessage(ctx context.Context) (kafka.Message, error) {
+	r.mu.Lock()
+	if len(r.messages) > 0 {
+		msg := r.messages[0]
+		r.messages = r.messages[1:]
+		r.mu.Unlock()
+		return msg, nil
+	}
+	r.mu.Unlock()
+	<-ctx.Done()
+	return kafka.Message{}, ctx.Err()
+}
+
+func (r *fakeKafkaReader) CommitMessages(_ context.Context, msgs ...kafka.Message) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.committed = append(r.committed, msgs...)
+	return nil
+}
+
+func (r *fakeKafkaReader) Close() error {
+	return nil
+}
+
+// committedOffsets returns the partitions and offsets committed so far.
+func (r *fakeKafkaReader) committedOffsets() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var offsets []string
+	for _, msg := range r.committed {
+		offsets = append(offsets, (&kafkaPending{msg: msg}).message().ID)
+	}
+	return offsets
+}
+
+// newTestKafkaQueue returns a queue over a fake reader holding msgs.
+func newTestKafkaQueue(msgs ...kafka.Message) (*KafkaQueue, *fakeKafkaReader) {
+	reader := &fakeKafkaReader{messages: msgs}
+	return &KafkaQueue{
+		options: &KafkaOptions{PollWait: 20 * time.Millisecond},
+		reader:  reader,
+		pending: make(map[int][]*kafkaPending),
+	}, reader
+}
+
+func TestKafkaQueue_Receive(t *testing.T) {
+	q, _ := newTestKafkaQueue(
+		kafka.Message{Partition: 0, Offset: 0, Value: []byte("a")},
+		kafka.Message{Partition: 0, Offset: 1, Value: []byte("b")},
+		kafka.Message{Partition: 1, Offset: 0, Value: []byte("c")},
+	)
+	ctx := context.Background()
+
+	msgs, err := q.Receive(ctx, 2, time.Hour)
+	require.NoError(t, err)
+	require.Len(t, msgs, 2)
+	assert.Equal(t, "0/0", msgs[0].ID)
+	assert.Equal(t, []byte("b"), msgs[1].Body)
+	assert.Equal(t, 1, msgs[0].Attempts)
+
+	// A partial batch is returned once the topic has no more messages
+	msgs, err = q.Receive(ctx, 10, time.Hour)
+	require.NoError(t, err)
+	require.Len(t, msgs, 1)
+	assert.Equal(t, "1/0", msgs[0].ID)
+
+	msgs, err = q.Receive(ctx, 10, time.Hour)
+	require.NoError(t, err)
+	assert.Empty(t, msgs)
+
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	_, err = q.Receive(cancelled, 1, time.Hour)
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func TestKafkaQueue_Ack(t *testing.T) {
+	q, reader := newTestKafkaQueue(
+		kafka.Message{Partition: 0, Offset: 10},
+		kafka.Message{Partition: 0, Offset: 11},
+		kafka.Message{Partition: 0, Offset: 12},
+		kafka.Message{Partition: 1, Offset: 5},
+	)
+	ctx := context.Background()
+	msgs, err := q.Receive(ctx, 4, time.Hour)
+	require.NoError(t, err)
+	require.Len(t, msgs, 4)
+
+	// Offsets after an unacked message are not committed
+	require.NoError(t, q.Ack(ctx, msgs[1]))
+	assert.Empty(t, reader.committedOffsets())
+
+	// Acking the first message commits the acked prefix, up to offset 11
+	require.NoError(t, q.Ack(ctx, msgs[0]))
+	assert.Equal(t, []string{"0/11"}, reader.committedOffsets())
+
+	// Partitions are committed independently
+	require.NoError(t, q.Ack(ctx, msgs[3]))
+	assert.Equal(t, []string{"0/11", "1/5"}, reader.committedOffsets())
+	require.NoError(t, q.Ack(ctx, msgs[2]))
+	assert.Equal(t, []string{"0/11", "1/5", "0/12"}, reader.committedOffsets())
+	assert.Empty(t, q.pending)
+
+	assert.Error(t, q.Ack(ctx, &QueueMessage{ID: "foreign"}))
+}
+
+func TestKafkaQueue_Redelivery(t *testing.T) {
+	q, reader := newTestKafkaQueue(
+		kafka.Message{Partition: 0, Offset: 0, Value: []byte("a")},
+		kafka.Message{Partition: 0, Offset: 1, Value: []byte("b")},
+	)
+	ctx := context.Background()
+	msgs, err := q.Receive(ctx, 2, time.Hour)
+	require.NoError(t, err)
+	require.Len(t, msgs, 2)
+
+	// Messages are invisible until their visibility timeout expires
+	again, err := q.Receive(ctx, 2, time.Hour)
+	require.NoError(t, err)
+	assert.Empty(t, again)
+
+	// An expired message is delivered again, an acked one is not
+	require.NoError(t, q.SetVisibility(ctx, msgs[0], 0))
+	require.NoError(t, q.SetVisibility(ctx, msgs[1], 0))
+	require.NoError(t, q.Ack(ctx, msgs[1]))
+	again, err = q.Receive(ctx, 2, time.Hour)
+	require.NoError(t, err)
+	require.Len(t, again, 1)
+	assert.Equal(t, "0/0", again[0].ID)
+	assert.Equal(t, 2, again[0].Attempts)
+	assert.Empty(t, reader.committedOffsets())
+
+	// Acking the redelivered message commits both
+	require.NoError(t, q.Ack(ctx, again[0]))
+	assert.Equal(t, []string{"0/1"}, reader.committedOffsets())
+}
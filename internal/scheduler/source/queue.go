@@ -0,0 +1,410 @@
+package source
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/perf-analysis/pkg/model"
+	"github.com/perf-analysis/pkg/utils"
+)
+
+// Queue is a message queue delivering tasks at least once.
+//
+// A received message is invisible to other consumers until it is acked or
+// its visibility timeout expires; an expired message is delivered again, to
+// this or another consumer. Tasks may therefore be processed more than once,
+// but are not lost when an analyzer dies while processing them.
+type Queue interface {
+	// Receive waits for messages, up to max, and returns them invisible for
+	// visibilityTimeout. It may return no messages when none arrived within
+	// the queue's poll wait.
+	Receive(ctx context.Context, max int, visibilityTimeout time.Duration) ([]*QueueMessage, error)
+
+	// Ack deletes a processed message from the queue.
+	Ack(ctx context.Context, msg *QueueMessage) error
+
+	// SetVisibility makes a received message visible again after d: 0
+	// releases it for redelivery now, a visibility timeout keeps it while
+	// its task is still being processed.
+	SetVisibility(ctx context.Context, msg *QueueMessage, d time.Duration) error
+
+	// Ping checks the connection to the queue.
+	Ping(ctx context.Context) error
+
+	// Close releases the connection to the queue.
+	Close() error
+}
+
+// QueueMessage is a message received from a Queue.
+type QueueMessage struct {
+	// ID identifies the message in the queue.
+	ID string
+
+	// Body is the message payload, a TaskMessage in JSON.
+	Body []byte
+
+	// Attempts is the number of times the message was delivered, 0 if the
+	// queue does not know.
+	Attempts int
+
+	// handle is the queue specific receipt of the message.
+	handle interface{}
+}
+
+// TaskMessage is the payload of the messages of a queue source.
+type TaskMessage struct {
+	Task     *model.Task `json:"task"`
+	Priority int         `json:"priority,omitempty"`
+}
+
+// QueueOptions holds the options shared by the queue sources.
+type QueueOptions struct {
+	// BatchSize is the maximum number of messages to receive at once.
+	BatchSize int
+
+	// VisibilityTimeout is how long a received message stays invisible to
+	// other consumers. It is extended while the task is being processed.
+	VisibilityTimeout time.Duration
+
+	// NackDelay is how long a nacked message stays invisible before it is
+	// delivered again.
+	NackDelay time.Duration
+}
+
+// DefaultQueueOptions returns the default options.
+func DefaultQueueOptions() *QueueOptions {
+	return &QueueOptions{
+		BatchSize:         10,
+		VisibilityTimeout: 5 * time.Minute,
+		NackDelay:         30 * time.Second,
+	}
+}
+
+// queueOptionsFromConfig reads the options shared by the queue sources. The
+// visibility timeout must be positive, and at least minVisibilityTimeout for
+// queues with coarser timeouts.
+func queueOptionsFromConfig(cfg *SourceConfig, minVisibilityTimeout time.Duration) (*QueueOptions, error) {
+	defaults := DefaultQueueOptions()
+	opts := &QueueOptions{
+		BatchSize:         cfg.GetInt("batch_size", defaults.BatchSize),
+		VisibilityTimeout: cfg.GetDuration("visibility_timeout", defaults.VisibilityTimeout),
+		NackDelay:         cfg.GetDuration("nack_delay", defaults.NackDelay),
+	}
+	if opts.VisibilityTimeout <= 0 {
+		return nil, fmt.Errorf("%s source %s: visibility_timeout must be positive", cfg.Type, cfg.Name)
+	}
+	if opts.VisibilityTimeout < minVisibilityTimeout {
+		return nil, fmt.Errorf("%s source %s: visibility_timeout must be at least %v", cfg.Type, cfg.Name, minVisibilityTimeout)
+	}
+	if opts.NackDelay < 0 {
+		return nil, fmt.Errorf("%s source %s: nack_delay must not be negative", cfg.Type, cfg.Name)
+	}
+	return opts, nil
+}
+
+// minHeartbeatInterval bounds how often the visibility of the messages in
+// flight is extended.
+const minHeartbeatInterval = time.Millisecond
+
+// receiveRetryDelay is the wait before receiving again after a failure.
+const receiveRetryDelay = 5 * time.Second
+
+// requeueTimeout bounds releasing the messages not yet emitted when the
+// source stops.
+const requeueTimeout = 5 * time.Second
+
+// QueueSource implements TaskSource on top of a Queue.
+//
+// The visibility of the messages it emitted is extended until the
+// scheduler acks or nacks them, so long analyses are not delivered twice.
+type QueueSource struct {
+	name       string
+	sourceType SourceType
+	queue      Queue
+	options    *QueueOptions
+	logger     utils.Logger
+
+	taskChan chan *TaskEvent
+	cancel   context.CancelFunc
+	wg       sync.WaitGroup
+
+	mu       sync.RWMutex
+	running  bool
+	inFlight map[*QueueMessage]struct{}
+}
+
+// NewQueueSource creates a task source consuming the given queue.
+func NewQueueSource(sourceType SourceType, name string, queue Queue, opts *QueueOptions, logger utils.Logger) *QueueSource {
+	if opts == nil {
+		opts = DefaultQueueOptions()
+	}
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = 1
+	}
+	if opts.VisibilityTimeout <= 0 {
+		opts.VisibilityTimeout = DefaultQueueOptions().VisibilityTimeout
+	}
+
+	return &QueueSource{
+		name:       name,
+		sourceType: sourceType,
+		queue:      queue,
+		options:    opts,
+		logger:     logger,
+		taskChan:   make(chan *TaskEvent, opts.BatchSize),
+		inFlight:   make(map[*QueueMessage]struct{}),
+	}
+}
+
+// SetLogger sets the logger.
+func (s *QueueSource) SetLogger(logger utils.Logger) {
+	s.logger = logger
+}
+
+// Type returns the source type.
+func (s *QueueSource) Type() SourceType {
+	return s.sourceType
+}
+
+// Name returns the source instance name.
+func (s *QueueSource) Name() string {
+	return s.name
+}
+
+// Start starts receiving messages.
+func (s *QueueSource) Start(ctx context.Context) error {
+	s.mu.Lock()
+	if s.running {
+		s.mu.Unlock()
+		return nil
+	}
+	s.running = true
+	// Cancelling interrupts receives waiting for messages
+	ctx, s.cancel = context.WithCancel(ctx)
+	s.mu.Unlock()
+
+	if s.logger != nil {
+		s.logger.Info("%s source %s starting (visibility timeout %v)", s.sourceType, s.name, s.options.VisibilityTimeout)
+	}
+
+	s.wg.Add(2)
+	go s.receiveLoop(ctx)
+	go s.heartbeatLoop(ctx)
+	return nil
+}
+
+// Stop stops receiving messages and closes the queue. Messages still in
+// flight are delivered again once their visibility timeout expires.
+func (s *QueueSource) Stop() error {
+	s.mu.Lock()
+	if !s.running {
+		s.mu.Unlock()
+		return nil
+	}
+	s.running = false
+	s.mu.Unlock()
+
+	s.cancel()
+	s.wg.Wait()
+	return s.queue.Close()
+}
+
+// Tasks returns the task event channel.
+func (s *QueueSource) Tasks() <-chan *TaskEvent {
+	return s.taskChan
+}
+
+// Ack deletes the message of a processed task from the queue.
+func (s *QueueSource) Ack(ctx context.Context, event *TaskEvent) error {
+	msg, err := s.release(event)
+	if err != nil {
+		return err
+	}
+	if err := s.queue.Ack(ctx, msg); err != nil {
+		return fmt.Errorf("failed to ack message %s: %w", msg.ID, err)
+	}
+	if s.logger != nil {
+		s.logger.Debug("%s source %s acked task %s", s.sourceType, s.name, event.ID)
+	}
+	return nil
+}
+
+// Nack releases the message of a task for redelivery after the nack delay.
+func (s *QueueSource) Nack(ctx context.Context, event *TaskEvent, reason string) error {
+	msg, err := s.release(event)
+	if err != nil {
+		return err
+	}
+	if err := s.queue.SetVisibility(ctx, msg, s.options.NackDelay); err != nil {
+		return fmt.Errorf("failed to nack message %s: %w", msg.ID, err)
+	}
+	if s.logger != nil {
+		s.logger.Warn("%s source %s nacked task %s: %s", s.sourceType, s.name, event.ID, reason)
+	}
+	return nil
+}
+
+// DeadLetter deletes the message of a dead-lettered task from the queue: the
+// scheduler keeps the task, which would fail again if delivered again.
+func (s *QueueSource) DeadLetter(ctx context.Context, event *TaskEvent, reason string) error {
+	msg, err := s.release(event)
+	if err != nil {
+		return err
+	}
+	if err := s.queue.Ack(ctx, msg); err != nil {
+		return fmt.Errorf("failed to delete message %s: %w", msg.ID, err)
+	}
+	if s.logger != nil {
+		s.logger.Warn("%s source %s removed dead-lettered task %s: %s", s.sourceType, s.name, event.ID, reason)
+	}
+	return nil
+}
+
+// HealthCheck checks the connection to the queue.
+func (s *QueueSource) HealthCheck(ctx context.Context) error {
+	return s.queue.Ping(ctx)
+}
+
+// release stops extending the visibility of the message of an event.
+func (s *QueueSource) release(event *TaskEvent) (*QueueMessage, error) {
+	msg, ok := event.AckToken.(*QueueMessage)
+	if !ok {
+		return nil, fmt.Errorf("task %s was not received from %s source %s", event.ID, s.sourceType, s.name)
+	}
+	s.mu.Lock()
+	delete(s.inFlight, msg)
+	s.mu.Unlock()
+	return msg, nil
+}
+
+// receiveLoop receives messages and emits their tasks.
+func (s *QueueSource) receiveLoop(ctx context.Context) {
+	defer s.wg.Done()
+
+	for ctx.Err() == nil {
+		msgs, err := s.queue.Receive(ctx, s.options.BatchSize, s.options.VisibilityTimeout)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			if s.logger != nil {
+				s.logger.Error("%s source %s failed to receive messages: %v", s.sourceType, s.name, err)
+			}
+			select {
+			case <-time.After(receiveRetryDelay):
+			case <-ctx.Done():
+				return
+			}
+			continue
+		}
+
+		events := make([]*TaskEvent, 0, len(msgs))
+		for _, msg := range msgs {
+			event, err := s.parseMessage(msg)
+			if err != nil {
+				// A malformed message would fail on every delivery
+				if s.logger != nil {
+					s.logger.Error("%s source %s dropping message %s: %v", s.sourceType, s.name, msg.ID, err)
+				}
+				if err := s.queue.Ack(ctx, msg); err != nil && s.logger != nil {
+					s.logger.Error("%s source %s failed to drop message %s: %v", s.sourceType, s.name, msg.ID, err)
+				}
+				continue
+			}
+			events = append(events, event)
+		}
+
+		// The whole batch is heartbeated from now on: the messages waiting
+		// for the scheduler must not be delivered again meanwhile
+		s.mu.Lock()
+		for _, event := range events {
+			s.inFlight[event.AckToken.(*QueueMessage)] = struct{}{}
+		}
+		s.mu.Unlock()
+
+		for i, event := range events {
+			select {
+			case s.taskChan <- event:
+				if s.logger != nil {
+					s.logger.Debug("%s source %s emitted task %s", s.sourceType, s.name, event.ID)
+				}
+			case <-ctx.Done():
+				s.requeue(events[i:])
+				return
+			}
+		}
+	}
+}
+
+// requeue releases the messages of events that were never emitted for
+// redelivery now.
+func (s *QueueSource) requeue(events []*TaskEvent) {
+	// The source's context is done
+	ctx, cancel := context.WithTimeout(context.Background(), requeueTimeout)
+	defer cancel()
+
+	for _, event := range events {
+		msg, err := s.release(event)
+		if err != nil {
+			continue
+		}
+		if err := s.queue.SetVisibility(ctx, msg, 0); err != nil && s.logger != nil {
+			s.logger.Warn("%s source %s failed to release message %s: %v", s.sourceType, s.name, msg.ID, err)
+		}
+	}
+}
+
+// heartbeatLoop extends the visibility of the messages in flight, before
+// their visibility timeout expires.
+func (s *QueueSource) heartbeatLoop(ctx context.Context) {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(max(s.options.VisibilityTimeout/2, minHeartbeatInterval))
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.mu.RLock()
+			msgs := make([]*QueueMessage, 0, len(s.inFlight))
+			for msg := range s.inFlight {
+				msgs = append(msgs, msg)
+			}
+			s.mu.RUnlock()
+
+			for _, msg := range msgs {
+				if err := s.queue.SetVisibility(ctx, msg, s.options.VisibilityTimeout); err != nil && s.logger != nil {
+					s.logger.Warn("%s source %s failed to extend visibility of message %s: %v", s.sourceType, s.name, msg.ID, err)
+				}
+			}
+		}
+	}
+}
+
+// parseMessage converts a message into a task event.
+func (s *QueueSource) parseMessage(msg *QueueMessage) (*TaskEvent, error) {
+	var payload TaskMessage
+	if err := json.Unmarshal(msg.Body, &payload); err != nil {
+		return nil, fmt.Errorf("invalid task message: %w", err)
+	}
+	if payload.Task == nil {
+		return nil, fmt.Errorf("invalid task message: missing task")
+	}
+
+	event := NewTaskEvent(payload.Task, s.sourceType, s.name).
+		WithAckToken(msg).
+		WithMetadata("message_id", msg.ID)
+	if payload.Priority > 0 {
+		event.Priority = payload.Priority
+	}
+	if msg.Attempts > 0 {
+		event.WithMetadata("attempts", strconv.Itoa(msg.Attempts))
+	}
+	return event, nil
+}
@@ -0,0 +1,236 @@
+package source
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/perf-analysis/pkg/model"
+)
+
+func TestQueueOptionsFromConfig(t *testing.T) {
+	cfg := &SourceConfig{Type: SourceTypeKafka, Name: "tasks"}
+	opts, err := queueOptionsFromConfig(cfg, 0)
+	require.NoError(t, err)
+	assert.Equal(t, DefaultQueueOptions(), opts)
+
+	tests := []struct {
+		name    string
+		options map[string]interface{}
+		min     time.Duration
+		wantErr string
+	}{
+		{"zero visibility timeout", map[string]interface{}{"visibility_timeout": "0s"}, 0, "visibility_timeout must be positive"},
+		{"negative visibility timeout", map[string]interface{}{"visibility_timeout": "-1m"}, 0, "visibility_timeout must be positive"},
+		{"sub-second SQS visibility timeout", map[string]interface{}{"visibility_timeout": "500ms"}, sqsMinVisibilityTimeout, "at least 1s"},
+		{"negative nack delay", map[string]interface{}{"nack_delay": "-1s"}, 0, "nack_delay must not be negative"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := queueOptionsFromConfig(&SourceConfig{Type: SourceTypeKafka, Name: "tasks", Options: tt.options}, tt.min)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.wantErr)
+		})
+	}
+
+	// Sub-second timeouts are fine for queues tracking them locally
+	opts, err = queueOptionsFromConfig(&SourceConfig{Options: map[string]interface{}{"visibility_timeout": "1ns"}}, 0)
+	require.NoError(t, err)
+	assert.Equal(t, time.Nanosecond, opts.VisibilityTimeout)
+}
+
+// fakeQueue delivers the messages sent to it and records acks and
+// visibility changes.
+type fakeQueue struct {
+	incoming chan *QueueMessage
+
+	mu         sync.Mutex
+	acked      []string
+	visibility map[string][]time.Duration
+	closed     bool
+}
+
+func newFakeQueue() *fakeQueue {
+	return &fakeQueue{incoming: make(chan *QueueMessage, 10), visibility: make(map[string][]time.Duration)}
+}
+
+func (q *fakeQueue) Receive(ctx context.Context, max int, _ time.Duration) ([]*QueueMessage, error) {
+	var msgs []*QueueMessage
+	select {
+	case msg := <-q.incoming:
+		msgs = append(msgs, msg)
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	// Batch the messages already sent
+	for len(msgs) < max {
+		select {
+		case msg := <-q.incoming:
+			msgs = append(msgs, msg)
+		default:
+			return msgs, nil
+		}
+	}
+	return msgs, nil
+}
+
+func (q *fakeQueue) Ack(_ context.Context, msg *QueueMessage) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.acked = append(q.acked, msg.ID)
+	return nil
+}
+
+func (q *fakeQueue) SetVisibility(_ context.Context, msg *QueueMessage, d time.Duration) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.visibility[msg.ID] = append(q.visibility[msg.ID], d)
+	return nil
+}
+
+func (q *fakeQueue) Ping(context.Context) error { return nil }
+
+func (q *fakeQueue) Close() error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.closed = true
+	return nil
+}
+
+// ackedIDs returns the IDs of the acked messages.
+func (q *fakeQueue) ackedIDs() []string {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return append([]string(nil), q.acked...)
+}
+
+// visibilityOf returns the visibility changes of a message.
+func (q *fakeQueue) visibilityOf(id string) []time.Duration {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return append([]time.Duration(nil), q.visibility[id]...)
+}
+
+// taskMessage returns a message carrying a task.
+func taskMessage(t *testing.T, id, taskUUID string, attempts int) *QueueMessage {
+	body, err := json.Marshal(&TaskMessage{Task: &model.Task{TaskUUID: taskUUID}, Priority: 2})
+	require.NoError(t, err)
+	return &QueueMessage{ID: id, Body: body, Attempts: attempts}
+}
+
+// startQueueSource starts a source over a fake queue.
+func startQueueSource(t *testing.T, opts *QueueOptions) (*QueueSource, *fakeQueue) {
+	queue := newFakeQueue()
+	s := NewQueueSource(SourceTypeKafka, "tasks", queue, opts, nil)
+	require.NoError(t, s.Start(context.Background()))
+	t.Cleanup(func() { s.Stop() })
+	return s, queue
+}
+
+// nextTask waits for the next task event of a source.
+func nextTask(t *testing.T, s *QueueSource) *TaskEvent {
+	select {
+	case event := <-s.Tasks():
+		return event
+	case <-time.After(5 * time.Second):
+		t.Fatal("no task received")
+		return nil
+	}
+}
+
+func TestQueueSource_AckNack(t *testing.T) {
+	s, queue := startQueueSource(t, &QueueOptions{BatchSize: 1, VisibilityTimeout: time.Hour, NackDelay: 30 * time.Second})
+	ctx := context.Background()
+
+	queue.incoming <- taskMessage(t, "m1", "task-1", 3)
+	event := nextTask(t, s)
+	assert.Equal(t, "task-1", event.ID)
+	assert.Equal(t, 2, event.Priority)
+	assert.Equal(t, "m1", event.GetMetadata("message_id"))
+	assert.Equal(t, "3", event.GetMetadata("attempts"))
+	require.NoError(t, s.Ack(ctx, event))
+	assert.Equal(t, []string{"m1"}, queue.ackedIDs())
+
+	// A nacked message is visible again after the nack delay
+	queue.incoming <- taskMessage(t, "m2", "task-2", 1)
+	event = nextTask(t, s)
+	require.NoError(t, s.Nack(ctx, event, "analyzer busy"))
+	assert.Equal(t, []time.Duration{30 * time.Second}, queue.visibilityOf("m2"))
+	assert.Equal(t, []string{"m1"}, queue.ackedIDs())
+
+	// A dead-lettered message is deleted
+	queue.incoming <- taskMessage(t, "m3", "task-3", 5)
+	event = nextTask(t, s)
+	require.NoError(t, s.DeadLetter(ctx, event, "failed too often"))
+	assert.Equal(t, []string{"m1", "m3"}, queue.ackedIDs())
+
+	assert.Error(t, s.Ack(ctx, &TaskEvent{ID: "other"}))
+	require.NoError(t, s.Stop())
+	assert.True(t, queue.closed)
+}
+
+func TestQueueSource_DropsMalformedMessages(t *testing.T) {
+	s, queue := startQueueSource(t, &QueueOptions{BatchSize: 1, VisibilityTimeout: time.Hour})
+
+	queue.incoming <- &QueueMessage{ID: "bad", Body: []byte("not json")}
+	queue.incoming <- &QueueMessage{ID: "empty", Body: []byte("{}")}
+	queue.incoming <- taskMessage(t, "good", "task-1", 1)
+	assert.Equal(t, "task-1", nextTask(t, s).ID)
+	assert.Equal(t, []string{"bad", "empty"}, queue.ackedIDs())
+}
+
+func TestQueueSource_Heartbeat(t *testing.T) {
+	const visibility = 20 * time.Millisecond
+	s, queue := startQueueSource(t, &QueueOptions{BatchSize: 1, VisibilityTimeout: visibility})
+
+	// The visibility of a task in flight is extended until it is acked
+	queue.incoming <- taskMessage(t, "m1", "task-1", 1)
+	event := nextTask(t, s)
+	require.Eventually(t, func() bool { return len(queue.visibilityOf("m1")) >= 2 }, 5*time.Second, time.Millisecond)
+	assert.Equal(t, visibility, queue.visibilityOf("m1")[0])
+
+	require.NoError(t, s.Ack(context.Background(), event))
+	extended := len(queue.visibilityOf("m1"))
+	time.Sleep(5 * visibility)
+	// At most one heartbeat was already under way
+	assert.LessOrEqual(t, len(queue.visibilityOf("m1")), extended+1)
+}
+
+func TestQueueSource_HeartbeatsWaitingBatch(t *testing.T) {
+	const visibility = 20 * time.Millisecond
+	queue := newFakeQueue()
+	for _, id := range []string{"m1", "m2", "m3", "m4"} {
+		queue.incoming <- taskMessage(t, id, "task-"+id, 1)
+	}
+	s := NewQueueSource(SourceTypeKafka, "tasks", queue, &QueueOptions{BatchSize: 2, VisibilityTimeout: visibility}, nil)
+	require.NoError(t, s.Start(context.Background()))
+
+	// Nothing takes the tasks: the first batch fills the task channel, the
+	// second one waits in the source, still invisible in the queue
+	require.Eventually(t, func() bool {
+		return len(queue.visibilityOf("m3")) >= 2 && len(queue.visibilityOf("m4")) >= 2
+	}, 5*time.Second, time.Millisecond)
+	assert.Equal(t, visibility, queue.visibilityOf("m4")[0])
+
+	// Stopping releases the waiting messages for redelivery now, and leaves
+	// the emitted ones until their visibility timeout expires
+	require.NoError(t, s.Stop())
+	for _, id := range []string{"m3", "m4"} {
+		changes := queue.visibilityOf(id)
+		assert.Equal(t, time.Duration(0), changes[len(changes)-1], id)
+	}
+	for _, id := range []string{"m1", "m2"} {
+		assert.NotContains(t, queue.visibilityOf(id), time.Duration(0), id)
+	}
+}
+
+func TestNewQueueSource_Defaults(t *testing.T) {
+	s := NewQueueSource(SourceTypeRedis, "tasks", newFakeQueue(), &QueueOptions{}, nil)
+	assert.Equal(t, 1, s.options.BatchSize)
+	assert.Equal(t, DefaultQueueOptions().VisibilityTimeout, s.options.VisibilityTimeout)
+}
@@ -0,0 +1,234 @@
+package source
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// SourceTypeRedis is the source type constant for Redis Streams source.
+const SourceTypeRedis SourceType = "redis"
+
+func init() {
+	// Register the Redis Streams source strategy
+	Register(SourceTypeRedis, NewRedisSource)
+}
+
+// redisMessageField is the stream entry field holding the TaskMessage, e.g.
+//
+//	XADD perf-tasks * message '{"task": {...}}'
+const redisMessageField = "message"
+
+// RedisOptions holds Redis Streams source specific configuration.
+type RedisOptions struct {
+	// Addr is the Redis server address.
+	Addr string
+
+	// Password authenticates to the server, if set.
+	Password string
+
+	// DB is the database number.
+	DB int
+
+	// Stream is the stream to consume from.
+	Stream string
+
+	// Group is the consumer group, created with the stream if missing.
+	Group string
+
+	// Consumer names this analyzer in the group. It must be unique among
+	// the analyzers consuming the stream.
+	Consumer string
+
+	// PollWait is how long a receive blocks waiting for entries.
+	PollWait time.Duration
+}
+
+// DefaultRedisOptions returns the default options.
+func DefaultRedisOptions() *RedisOptions {
+	consumer := "perf-analyzer"
+	if host, err := os.Hostname(); err == nil {
+		consumer = host
+	}
+	return &RedisOptions{
+		Addr:     "localhost:6379",
+		Stream:   "perf-tasks",
+		Group:    "perf-analyzer",
+		Consumer: consumer + "-" + strconv.Itoa(os.Getpid()),
+		PollWait: 5 * time.Second,
+	}
+}
+
+// NewRedisSource creates a new Redis Streams source from configuration.
+func NewRedisSource(cfg *SourceConfig) (TaskSource, error) {
+	defaults := DefaultRedisOptions()
+	opts := &RedisOptions{
+		Addr:     cfg.GetString("addr", defaults.Addr),
+		Password: cfg.GetString("password", defaults.Password),
+		DB:       cfg.GetInt("db", defaults.DB),
+		Stream:   cfg.GetString("stream", defaults.Stream),
+		Group:    cfg.GetString("group", defaults.Group),
+		Consumer: cfg.GetString("consumer", defaults.Consumer),
+		PollWait: cfg.GetDuration("poll_wait", defaults.PollWait),
+	}
+
+	queueOpts, err := queueOptionsFromConfig(cfg, 0)
+	if err != nil {
+		return nil, err
+	}
+	return NewQueueSource(SourceTypeRedis, cfg.Name, NewRedisQueue(opts), queueOpts, nil), nil
+}
+
+// RedisQueue implements Queue with a Redis stream and a consumer group.
+//
+// Received entries stay in the pending list of the group until acked.
+// Entries idle for longer than the visibility timeout are claimed again,
+// by any consumer of the group.
+type RedisQueue struct {
+	options *RedisOptions
+	client  *redis.Client
+
+	// groupReady is set once the consumer group exists
+	groupReady bool
+	// visibilityTimeout is the idle time after which entries are claimed
+	visibilityTimeout atomic.Int64
+}
+
+// NewRedisQueue creates a Redis queue consuming opts.Stream.
+func NewRedisQueue(opts *RedisOptions) *RedisQueue {
+	if opts == nil {
+		opts = DefaultRedisOptions()
+	}
+	return &RedisQueue{
+		options: opts,
+		client: redis.NewClient(&redis.Options{
+			Addr:     opts.Addr,
+			Password: opts.Password,
+			DB:       opts.DB,
+		}),
+	}
+}
+
+// Receive claims the entries idle for longer than visibilityTimeout, or
+// else waits for new entries.
+func (q *RedisQueue) Receive(ctx context.Context, max int, visibilityTimeout time.Duration) ([]*QueueMessage, error) {
+	if err := q.ensureGroup(ctx); err != nil {
+		return nil, err
+	}
+	q.visibilityTimeout.Store(int64(visibilityTimeout))
+
+	claimed, _, err := q.client.XAutoClaim(ctx, &redis.XAutoClaimArgs{
+		Stream:   q.options.Stream,
+		Group:    q.options.Group,
+		Consumer: q.options.Consumer,
+		MinIdle:  visibilityTimeout,
+		Start:    "0-0",
+		Count:    int64(max),
+	}).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim idle entries: %w", err)
+	}
+	if len(claimed) > 0 {
+		return q.messages(ctx, claimed, true), nil
+	}
+
+	streams, err := q.client.XReadGroup(ctx, &redis.XReadGroupArgs{
+		Group:    q.options.Group,
+		Consumer: q.options.Consumer,
+		Streams:  []string{q.options.Stream, ">"},
+		Count:    int64(max),
+		Block:    q.options.PollWait,
+	}).Result()
+	if errors.Is(err, redis.Nil) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read stream: %w", err)
+	}
+
+	var msgs []*QueueMessage
+	for _, stream := range streams {
+		msgs = append(msgs, q.messages(ctx, stream.Messages, false)...)
+	}
+	return msgs, nil
+}
+
+// messages converts stream entries. Delivery counts of claimed entries are
+// read from the pending list; a failure to read them is not fatal.
+func (q *RedisQueue) messages(ctx context.Context, entries []redis.XMessage, claimed bool) []*QueueMessage {
+	attempts := make(map[string]int)
+	if claimed {
+		pending, err := q.client.XPendingExt(ctx, &redis.XPendingExtArgs{
+			Stream:   q.options.Stream,
+			Group:    q.options.Group,
+			Start:    entries[0].ID,
+			End:      entries[len(entries)-1].ID,
+			Count:    int64(len(entries)),
+			Consumer: q.options.Consumer,
+		}).Result()
+		if err == nil {
+			for _, p := range pending {
+				attempts[p.ID] = int(p.RetryCount)
+			}
+		}
+	}
+
+	msgs := make([]*QueueMessage, 0, len(entries))
+	for _, entry := range entries {
+		body, _ := entry.Values[redisMessageField].(string)
+		msg := &QueueMessage{ID: entry.ID, Body: []byte(body), Attempts: 1}
+		if claimed {
+			msg.Attempts = attempts[entry.ID]
+		}
+		msgs = append(msgs, msg)
+	}
+	return msgs
+}
+
+// ensureGroup creates the stream and the consumer group if missing. New
+// groups read the entries added from now on.
+func (q *RedisQueue) ensureGroup(ctx context.Context) error {
+	if q.groupReady {
+		return nil
+	}
+	err := q.client.XGroupCreateMkStream(ctx, q.options.Stream, q.options.Group, "$").Err()
+	if err != nil && !strings.HasPrefix(err.Error(), "BUSYGROUP") {
+		return fmt.Errorf("failed to create consumer group %s: %w", q.options.Group, err)
+	}
+	q.groupReady = true
+	return nil
+}
+
+// Ack removes an entry from the pending list of the group. Entries are not
+// deleted from the stream, which producers should cap with MAXLEN.
+func (q *RedisQueue) Ack(ctx context.Context, msg *QueueMessage) error {
+	return q.client.XAck(ctx, q.options.Stream, q.options.Group, msg.ID).Err()
+}
+
+// SetVisibility sets the idle time of a pending entry, so that it is claimed
+// again after d.
+func (q *RedisQueue) SetVisibility(ctx context.Context, msg *QueueMessage, d time.Duration) error {
+	idle := time.Duration(q.visibilityTimeout.Load()) - d
+	if idle < 0 {
+		idle = 0
+	}
+	return q.client.Do(ctx, "XCLAIM", q.options.Stream, q.options.Group, q.options.Consumer, 0, msg.ID,
+		"IDLE", idle.Milliseconds(), "JUSTID").Err()
+}
+
+// Ping checks the connection to the server.
+func (q *RedisQueue) Ping(ctx context.Context) error {
+	return q.client.Ping(ctx).Err()
+}
+
+// Close closes the connection to the server.
+func (q *RedisQueue) Close() error {
+	return q.client.Close()
+}
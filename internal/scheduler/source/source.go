@@ -1,6 +1,7 @@
 // Package source provides task source abstractions for the scheduler.
-// It implements the Strategy Pattern where each source type (database, kafka, http)
-// is a concrete strategy implementing the TaskSource interface.
+// It implements the Strategy Pattern where each source type (database, http,
+// and the kafka, redis and sqs queues) is a concrete strategy implementing
+// the TaskSource interface.
 package source
 
 import (
@@ -15,7 +16,7 @@ import (
 type SourceType string
 
 // TaskSource defines the strategy interface for task sources.
-// Each concrete implementation (database, kafka, http) implements this interface.
+// Each concrete implementation (database, http, kafka, redis, sqs) implements this interface.
 type TaskSource interface {
 	// Type returns the source type constant defined by the strategy.
 	Type() SourceType
@@ -42,9 +43,17 @@ type TaskSource interface {
 	HealthCheck(ctx context.Context) error
 }
 
+// DeadLetterer is implemented by the sources removing the tasks which failed
+// for good, once the scheduler dead-lettered them, instead of delivering them
+// again like nacked tasks. The scheduler nacks them at other sources.
+type DeadLetterer interface {
+	// DeadLetter removes a dead-lettered task from the source.
+	DeadLetter(ctx context.Context, event *TaskEvent, reason string) error
+}
+
 // SourceConfig holds the configuration for a task source.
 type SourceConfig struct {
-	// Type is the source type (database, kafka, http).
+	// Type is the source type (database, http, kafka, redis, sqs).
 	Type SourceType `yaml:"type" mapstructure:"type"`
 
 	// Name is the unique name for this source instance.
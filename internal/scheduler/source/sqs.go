@@ -0,0 +1,164 @@
+package source
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	"github.com/aws/aws-sdk-go-v2/service/sqs/types"
+)
+
+// SourceTypeSQS is the source type constant for Amazon SQS source.
+const SourceTypeSQS SourceType = "sqs"
+
+func init() {
+	// Register the SQS source strategy
+	Register(SourceTypeSQS, NewSQSSource)
+}
+
+// sqsMaxMessages is the most messages SQS returns per receive.
+const sqsMaxMessages = 10
+
+// sqsMinVisibilityTimeout is the shortest visibility timeout of the sources:
+// SQS timeouts are whole seconds, shorter ones would be 0.
+const sqsMinVisibilityTimeout = time.Second
+
+// SQSOptions holds SQS source specific configuration.
+type SQSOptions struct {
+	// QueueURL is the URL of the queue to consume from.
+	QueueURL string
+
+	// Region is the AWS region; empty for the region of the environment.
+	Region string
+
+	// Endpoint overrides the SQS endpoint, e.g. for a local emulator.
+	Endpoint string
+
+	// PollWait is how long a receive waits for messages, at most 20s.
+	PollWait time.Duration
+}
+
+// DefaultSQSOptions returns the default options.
+func DefaultSQSOptions() *SQSOptions {
+	return &SQSOptions{
+		PollWait: 20 * time.Second,
+	}
+}
+
+// NewSQSSource creates a new SQS source from configuration. Credentials are
+// read from the environment, as by the AWS CLI.
+func NewSQSSource(cfg *SourceConfig) (TaskSource, error) {
+	defaults := DefaultSQSOptions()
+	opts := &SQSOptions{
+		QueueURL: cfg.GetString("queue_url", defaults.QueueURL),
+		Region:   cfg.GetString("region", defaults.Region),
+		Endpoint: cfg.GetString("endpoint", defaults.Endpoint),
+		PollWait: cfg.GetDuration("poll_wait", defaults.PollWait),
+	}
+	queueOpts, err := queueOptionsFromConfig(cfg, sqsMinVisibilityTimeout)
+	if err != nil {
+		return nil, err
+	}
+
+	queue, err := NewSQSQueue(context.Background(), opts)
+	if err != nil {
+		return nil, err
+	}
+	return NewQueueSource(SourceTypeSQS, cfg.Name, queue, queueOpts, nil), nil
+}
+
+// SQSQueue implements Queue with an Amazon SQS queue, whose visibility
+// timeout and redelivery are native.
+type SQSQueue struct {
+	options *SQSOptions
+	client  *sqs.Client
+}
+
+// NewSQSQueue creates an SQS queue consuming opts.QueueURL.
+func NewSQSQueue(ctx context.Context, opts *SQSOptions) (*SQSQueue, error) {
+	if opts == nil || opts.QueueURL == "" {
+		return nil, errors.New("sqs source requires queue_url")
+	}
+
+	var loadOpts []func(*awsconfig.LoadOptions) error
+	if opts.Region != "" {
+		loadOpts = append(loadOpts, awsconfig.WithRegion(opts.Region))
+	}
+	awsCfg, err := awsconfig.LoadDefaultConfig(ctx, loadOpts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load AWS configuration: %w", err)
+	}
+
+	client := sqs.NewFromConfig(awsCfg, func(o *sqs.Options) {
+		if opts.Endpoint != "" {
+			o.BaseEndpoint = aws.String(opts.Endpoint)
+		}
+	})
+	return &SQSQueue{options: opts, client: client}, nil
+}
+
+// Receive long polls the queue for messages.
+func (q *SQSQueue) Receive(ctx context.Context, max int, visibilityTimeout time.Duration) ([]*QueueMessage, error) {
+	if max > sqsMaxMessages {
+		max = sqsMaxMessages
+	}
+	out, err := q.client.ReceiveMessage(ctx, &sqs.ReceiveMessageInput{
+		QueueUrl:                    aws.String(q.options.QueueURL),
+		MaxNumberOfMessages:         int32(max),
+		VisibilityTimeout:           int32(visibilityTimeout.Seconds()),
+		WaitTimeSeconds:             int32(q.options.PollWait.Seconds()),
+		MessageSystemAttributeNames: []types.MessageSystemAttributeName{types.MessageSystemAttributeNameApproximateReceiveCount},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	msgs := make([]*QueueMessage, 0, len(out.Messages))
+	for _, m := range out.Messages {
+		attempts, _ := strconv.Atoi(m.Attributes[string(types.MessageSystemAttributeNameApproximateReceiveCount)])
+		msgs = append(msgs, &QueueMessage{
+			ID:       aws.ToString(m.MessageId),
+			Body:     []byte(aws.ToString(m.Body)),
+			Attempts: attempts,
+			handle:   aws.ToString(m.ReceiptHandle),
+		})
+	}
+	return msgs, nil
+}
+
+// Ack deletes a message from the queue.
+func (q *SQSQueue) Ack(ctx context.Context, msg *QueueMessage) error {
+	_, err := q.client.DeleteMessage(ctx, &sqs.DeleteMessageInput{
+		QueueUrl:      aws.String(q.options.QueueURL),
+		ReceiptHandle: aws.String(msg.handle.(string)),
+	})
+	return err
+}
+
+// SetVisibility changes the visibility timeout of a received message.
+func (q *SQSQueue) SetVisibility(ctx context.Context, msg *QueueMessage, d time.Duration) error {
+	_, err := q.client.ChangeMessageVisibility(ctx, &sqs.ChangeMessageVisibilityInput{
+		QueueUrl:          aws.String(q.options.QueueURL),
+		ReceiptHandle:     aws.String(msg.handle.(string)),
+		VisibilityTimeout: int32(d.Seconds()),
+	})
+	return err
+}
+
+// Ping checks that the queue is accessible.
+func (q *SQSQueue) Ping(ctx context.Context) error {
+	_, err := q.client.GetQueueAttributes(ctx, &sqs.GetQueueAttributesInput{
+		QueueUrl: aws.String(q.options.QueueURL),
+	})
+	return err
+}
+
+// Close does nothing: the client holds no connection of its own.
+func (q *SQSQueue) Close() error {
+	return nil
+}
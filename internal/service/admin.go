@@ -0,0 +1,87 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/perf-analysis/pkg/utils"
+)
+
+// adminShutdownTimeout bounds the wait for admin requests on shutdown.
+const adminShutdownTimeout = 5 * time.Second
+
+// adminHandler returns the admin endpoints:
+//
+//	/admin/log-level      GET the log levels, PUT or POST to change one
+//	/api/summaries        GET the summaries of past analyses
+//	/api/summaries/{tid}  GET the summary of the analysis of a task
+//	/api/baselines        GET the baselines of services
+//	/api/baselines/{tid}  PUT to make a task the baseline of its service, DELETE to unset it
+//	/api/dead-letters     GET the tasks whose analysis failed for good
+//	/api/dead-letters/{tid}  GET or DELETE the dead letter of a task
+func (s *Service) adminHandler() http.Handler {
+	mux := http.NewServeMux()
+	if levels := s.logLevels(); levels != nil {
+		mux.Handle("/admin/log-level", utils.LogLevelHandler(levels))
+	}
+	if s.db != nil && s.db.Summary != nil {
+		mux.HandleFunc("GET /api/summaries", s.handleListSummaries)
+		mux.HandleFunc("GET /api/summaries/{tid}", s.handleGetSummary)
+	}
+	if s.db != nil && s.db.Summary != nil && s.db.Baseline != nil {
+		mux.HandleFunc("GET /api/baselines", s.handleListBaselines)
+		mux.HandleFunc("PUT /api/baselines/{tid}", s.handleSetBaseline)
+		mux.HandleFunc("DELETE /api/baselines/{tid}", s.handleDeleteBaseline)
+	}
+	if s.db != nil && s.db.DeadLetter != nil {
+		mux.HandleFunc("GET /api/dead-letters", s.handleListDeadLetters)
+		mux.HandleFunc("GET /api/dead-letters/{tid}", s.handleGetDeadLetter)
+		mux.HandleFunc("DELETE /api/dead-letters/{tid}", s.handleDeleteDeadLetter)
+	}
+	return mux
+}
+
+// logLevels returns the levels of the service logger, if it has any.
+func (s *Service) logLevels() *utils.LogLevels {
+	if l, ok := s.logger.(interface{ Levels() *utils.LogLevels }); ok {
+		return l.Levels()
+	}
+	return nil
+}
+
+// startAdmin starts serving the admin endpoints in the background.
+func (s *Service) startAdmin() error {
+	listener, err := net.Listen("tcp", s.config.Admin.Addr)
+	if err != nil {
+		return fmt.Errorf("failed to listen on admin address %s: %w", s.config.Admin.Addr, err)
+	}
+	s.admin = &http.Server{
+		Handler:      s.adminHandler(),
+		ReadTimeout:  30 * time.Second,
+		WriteTimeout: 30 * time.Second,
+	}
+	go func() {
+		if err := s.admin.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			s.logger.Error("Admin server failed: %v", err)
+		}
+	}()
+	s.logger.Info("Admin endpoints listening on %s", listener.Addr())
+	return nil
+}
+
+// stopAdmin stops serving the admin endpoints.
+func (s *Service) stopAdmin() {
+	if s.admin == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), adminShutdownTimeout)
+	defer cancel()
+	if err := s.admin.Shutdown(ctx); err != nil {
+		s.logger.Error("Failed to stop admin server: %v", err)
+	}
+	s.admin = nil
+}
@@ -0,0 +1,81 @@
+package service
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/perf-analysis/internal/repository"
+	"github.com/perf-analysis/pkg/model"
+)
+
+// handleListBaselines serves the baselines of a service, of all services if
+// none is given:
+//
+//	GET /api/baselines?service=checkout
+func (s *Service) handleListBaselines(w http.ResponseWriter, r *http.Request) {
+	baselines, err := s.db.Baseline.ListBaselines(r.Context(), r.URL.Query().Get("service"))
+	if err != nil {
+		s.logger.Error("Failed to list baselines: %v", err)
+		http.Error(w, "failed to list baselines", http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, map[string]interface{}{"baselines": baselines})
+}
+
+// handleSetBaseline makes the analysis of a task the baseline of its service
+// for its task type. The later analyses of the service are compared with it,
+// and their regressions set in their summaries:
+//
+//	PUT /api/baselines/{tid}
+func (s *Service) handleSetBaseline(w http.ResponseWriter, r *http.Request) {
+	summary, err := s.db.Summary.GetSummary(r.Context(), r.PathValue("tid"))
+	if errors.Is(err, repository.ErrNotFound) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		s.logger.Error("Failed to get analysis summary: %v", err)
+		http.Error(w, "failed to get summary", http.StatusInternalServerError)
+		return
+	}
+	if summary.Service == "" {
+		http.Error(w, "the task has no service", http.StatusBadRequest)
+		return
+	}
+
+	baseline := &model.Baseline{
+		Service:   summary.Service,
+		TaskType:  summary.TaskType,
+		TaskUUID:  summary.TaskUUID,
+		CreatedAt: time.Now(),
+	}
+	if err := s.db.Baseline.SetBaseline(r.Context(), baseline); err != nil {
+		s.logger.Error("Failed to set baseline: %v", err)
+		http.Error(w, "failed to set baseline", http.StatusInternalServerError)
+		return
+	}
+	s.logger.Info("Task %s is the baseline of service %s", baseline.TaskUUID, baseline.Service)
+
+	writeJSON(w, baseline)
+}
+
+// handleDeleteBaseline stops comparing the analyses of a service with the
+// analysis of a task:
+//
+//	DELETE /api/baselines/{tid}
+func (s *Service) handleDeleteBaseline(w http.ResponseWriter, r *http.Request) {
+	err := s.db.Baseline.DeleteBaseline(r.Context(), r.PathValue("tid"))
+	if errors.Is(err, repository.ErrNotFound) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		s.logger.Error("Failed to delete baseline: %v", err)
+		http.Error(w, "failed to delete baseline", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
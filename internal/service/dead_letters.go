@@ -0,0 +1,70 @@
+package service
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/perf-analysis/internal/repository"
+)
+
+// handleListDeadLetters serves the tasks whose analysis failed for good,
+// newest first, with their error, attempts and the storage keys of the
+// output files of their last attempt:
+//
+//	GET /api/dead-letters?limit=50
+func (s *Service) handleListDeadLetters(w http.ResponseWriter, r *http.Request) {
+	limit := 0
+	if value := r.URL.Query().Get("limit"); value != "" {
+		var err error
+		if limit, err = strconv.Atoi(value); err != nil || limit < 1 {
+			http.Error(w, "invalid limit: "+value, http.StatusBadRequest)
+			return
+		}
+	}
+
+	letters, err := s.db.DeadLetter.ListDeadLetters(r.Context(), limit)
+	if err != nil {
+		s.logger.Error("Failed to list dead letters: %v", err)
+		http.Error(w, "failed to list dead letters", http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, map[string]interface{}{"dead_letters": letters})
+}
+
+// handleGetDeadLetter serves the dead letter of a task:
+//
+//	GET /api/dead-letters/{tid}
+func (s *Service) handleGetDeadLetter(w http.ResponseWriter, r *http.Request) {
+	letter, err := s.db.DeadLetter.GetDeadLetter(r.Context(), r.PathValue("tid"))
+	if errors.Is(err, repository.ErrNotFound) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		s.logger.Error("Failed to get dead letter: %v", err)
+		http.Error(w, "failed to get dead letter", http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, letter)
+}
+
+// handleDeleteDeadLetter discards the dead letter of a task, once handled:
+//
+//	DELETE /api/dead-letters/{tid}
+func (s *Service) handleDeleteDeadLetter(w http.ResponseWriter, r *http.Request) {
+	err := s.db.DeadLetter.DeleteDeadLetter(r.Context(), r.PathValue("tid"))
+	if errors.Is(err, repository.ErrNotFound) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		s.logger.Error("Failed to delete dead letter: %v", err)
+		http.Error(w, "failed to delete dead letter", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -4,7 +4,10 @@ package service
 import (
 	"context"
 	"fmt"
+	"net/http"
 
+	"github.com/perf-analysis/internal/analyzer"
+	"github.com/perf-analysis/internal/notify"
 	"github.com/perf-analysis/internal/repository"
 	"github.com/perf-analysis/internal/scheduler"
 	"github.com/perf-analysis/internal/scheduler/source"
@@ -21,11 +24,17 @@ type Service struct {
 	storage   storage.Storage
 	scheduler *scheduler.Scheduler
 
+	// notifier notifies webhooks of task results, nil when none is configured
+	notifier *notify.Notifier
+
 	// sources holds all task sources
 	sources []source.TaskSource
 	// aggregator aggregates multiple sources into a single channel
 	aggregator *source.Aggregator
 
+	// admin serves the admin endpoints, when enabled
+	admin *http.Server
+
 	running bool
 }
 
@@ -37,7 +46,7 @@ func New(cfg *config.Config, logger utils.Logger) (*Service, error) {
 
 	return &Service{
 		config: cfg,
-		logger: logger,
+		logger: utils.Scoped(logger, utils.ScopeService),
 	}, nil
 }
 
@@ -86,6 +95,10 @@ func (s *Service) initDatabase() error {
 	s.db = repository.NewRepositories(gormDB, s.config.Database.Type, s.config.Analysis.Version)
 	s.logger.Info("Database connection established")
 
+	if err := s.db.Migrate(context.Background()); err != nil {
+		return err
+	}
+
 	return nil
 }
 
@@ -108,23 +121,50 @@ func (s *Service) initStorage() error {
 func (s *Service) initScheduler() error {
 	s.logger.Info("Initializing scheduler...")
 
+	if dir := s.config.Analysis.PluginDir; dir != "" {
+		plugins, err := analyzer.LoadPlugins(dir)
+		if err != nil {
+			return err
+		}
+		for _, path := range plugins {
+			s.logger.Info("Loaded analyzer plugin %s", path)
+		}
+	}
+
 	// Initialize task sources from configuration
 	if err := s.initSources(); err != nil {
 		return fmt.Errorf("failed to initialize sources: %w", err)
 	}
 
+	s.notifier = notify.New(&s.config.Notifications, s.logger)
+	if s.notifier != nil {
+		s.logger.Info("Notifying %d webhooks of task results", len(s.config.Notifications.Webhooks))
+	}
+
 	// Create task processor
 	processorConfig := &scheduler.ProcessorConfig{
-		Config:  s.config,
-		Storage: s.storage,
-		Repos:   s.db,
-		Logger:  s.logger,
+		Config:   s.config,
+		Storage:  s.storage,
+		Repos:    s.db,
+		Notifier: s.notifier,
+		Logger:   s.logger,
 	}
 	processor := scheduler.NewDefaultTaskProcessor(processorConfig)
 
 	// Create scheduler with aggregator
 	schedulerConfig := scheduler.FromConfig(&s.config.Scheduler)
 	s.scheduler = scheduler.New(schedulerConfig, s.aggregator, processor, s.db.Suggestion, s.logger)
+	s.scheduler.SetDeadLetterRepository(s.db.DeadLetter)
+
+	if admission := s.config.Scheduler.Admission; admission.Enabled {
+		if schedulerConfig.MemoryBudget <= 0 {
+			s.logger.Warn("Admission control disabled: memory limit unknown, set scheduler.admission.memory_budget_mb")
+		} else {
+			sampleBytes := int64(admission.SampleMB) << 20
+			s.scheduler.SetMemoryEstimator(scheduler.NewHeapDumpEstimator(s.storage, sampleBytes))
+			s.logger.Info("Admission control enabled with a memory budget of %dMB", schedulerConfig.MemoryBudget>>20)
+		}
+	}
 
 	s.logger.Info("Scheduler initialized")
 	return nil
@@ -174,14 +214,11 @@ func (s *Service) initSources() error {
 	for _, src := range sources {
 		if dbSource, ok := src.(*source.DatabaseSource); ok {
 			dbSource.SetRepositories(s.db.Task, s.db.Suggestion)
-			dbSource.SetLogger(s.logger)
+			dbSource.SetLeaseRepository(s.db.Lease)
 		}
-		// Set logger for other source types
-		if kafkaSource, ok := src.(*source.KafkaSource); ok {
-			kafkaSource.SetLogger(s.logger)
-		}
-		if httpSource, ok := src.(*source.HTTPSource); ok {
-			httpSource.SetLogger(s.logger)
+		// Set logger for all source types
+		if loggable, ok := src.(interface{ SetLogger(utils.Logger) }); ok {
+			loggable.SetLogger(s.logger)
 		}
 	}
 
@@ -202,7 +239,14 @@ func (s *Service) initSources() error {
 func (s *Service) Start(ctx context.Context) error {
 	s.logger.Info("Starting service...")
 
+	if s.config.Admin.Enabled {
+		if err := s.startAdmin(); err != nil {
+			return err
+		}
+	}
+
 	if err := s.scheduler.Start(ctx); err != nil {
+		s.stopAdmin()
 		return fmt.Errorf("failed to start scheduler: %w", err)
 	}
 
@@ -220,6 +264,11 @@ func (s *Service) Stop() error {
 		s.scheduler.Stop()
 	}
 
+	// Deliver the notifications of the last tasks
+	s.notifier.Close()
+
+	s.stopAdmin()
+
 	if s.aggregator != nil {
 		if err := s.aggregator.Stop(); err != nil {
 			s.logger.Error("Failed to stop aggregator: %v", err)
@@ -2,12 +2,21 @@ package service
 
 import (
 	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+	testifymock "github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
 
+	"github.com/perf-analysis/internal/mock"
+	"github.com/perf-analysis/internal/repository"
 	"github.com/perf-analysis/pkg/config"
+	"github.com/perf-analysis/pkg/model"
 	"github.com/perf-analysis/pkg/utils"
 )
 
@@ -104,3 +113,155 @@ func TestService_HealthCheck_NoComponents(t *testing.T) {
 	err = svc.HealthCheck(context.Background())
 	assert.NoError(t, err)
 }
+
+func TestService_AdminLogLevel(t *testing.T) {
+	logger := utils.NewDefaultLogger(utils.LevelInfo, nil)
+	svc, err := New(&config.Config{}, logger)
+	require.NoError(t, err)
+
+	req := httptest.NewRequest(http.MethodPut, "/admin/log-level", strings.NewReader(`{"scope": "parser", "level": "debug"}`))
+	rec := httptest.NewRecorder()
+	svc.adminHandler().ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, utils.LevelDebug, logger.Levels().Level(utils.ScopeParser))
+	assert.Equal(t, utils.LevelInfo, logger.Levels().Level(utils.ScopeService))
+}
+
+func TestService_Summaries(t *testing.T) {
+	svc, err := New(&config.Config{}, nil)
+	require.NoError(t, err)
+
+	summaries := &mock.MockSummaryRepository{}
+	svc.db = &repository.Repositories{Summary: summaries}
+
+	heap := model.TaskTypeJavaHeap
+	summaries.On("ListSummaries", testifymock.Anything, &model.SummaryQuery{
+		Service:  "checkout",
+		TaskType: &heap,
+		Since:    time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC),
+		Limit:    30,
+	}).Return([]*model.AnalysisSummary{{TaskUUID: "heap-2"}, {TaskUUID: "heap-1"}}, nil)
+	summaries.On("GetSummary", testifymock.Anything, "heap-1").Return(&model.AnalysisSummary{TaskUUID: "heap-1"}, nil)
+	summaries.On("GetSummary", testifymock.Anything, "missing").Return(nil, repository.ErrNotFound)
+
+	serve := func(target string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		svc.adminHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, target, nil))
+		return rec
+	}
+
+	t.Run("List", func(t *testing.T) {
+		rec := serve("/api/summaries?service=checkout&task_type=java_heap&since=2026-01-01T00:00:00Z&limit=30")
+		require.Equal(t, http.StatusOK, rec.Code)
+
+		var body struct {
+			Summaries []model.AnalysisSummary `json:"summaries"`
+		}
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+		require.Len(t, body.Summaries, 2)
+		assert.Equal(t, "heap-2", body.Summaries[0].TaskUUID)
+	})
+
+	t.Run("List_BadQuery", func(t *testing.T) {
+		assert.Equal(t, http.StatusBadRequest, serve("/api/summaries?task_type=cobol").Code)
+		assert.Equal(t, http.StatusBadRequest, serve("/api/summaries?since=yesterday").Code)
+		assert.Equal(t, http.StatusBadRequest, serve("/api/summaries?limit=-1").Code)
+	})
+
+	t.Run("Get", func(t *testing.T) {
+		rec := serve("/api/summaries/heap-1")
+		require.Equal(t, http.StatusOK, rec.Code)
+		assert.Contains(t, rec.Body.String(), `"tid":"heap-1"`)
+	})
+
+	t.Run("Get_NotFound", func(t *testing.T) {
+		assert.Equal(t, http.StatusNotFound, serve("/api/summaries/missing").Code)
+	})
+}
+
+func TestService_Baselines(t *testing.T) {
+	svc, err := New(&config.Config{}, nil)
+	require.NoError(t, err)
+
+	summaries := &mock.MockSummaryRepository{}
+	baselines := &mock.MockBaselineRepository{}
+	svc.db = &repository.Repositories{Summary: summaries, Baseline: baselines}
+
+	summaries.On("GetSummary", testifymock.Anything, "heap-1").Return(&model.AnalysisSummary{
+		TaskUUID: "heap-1", TaskType: model.TaskTypeJavaHeap, Service: "checkout",
+	}, nil)
+	summaries.On("GetSummary", testifymock.Anything, "adhoc").Return(&model.AnalysisSummary{TaskUUID: "adhoc"}, nil)
+	summaries.On("GetSummary", testifymock.Anything, "missing").Return(nil, repository.ErrNotFound)
+	baselines.On("SetBaseline", testifymock.Anything, testifymock.MatchedBy(func(b *model.Baseline) bool {
+		return b.Service == "checkout" && b.TaskType == model.TaskTypeJavaHeap && b.TaskUUID == "heap-1"
+	})).Return(nil)
+	baselines.On("ListBaselines", testifymock.Anything, "checkout").Return([]*model.Baseline{
+		{Service: "checkout", TaskType: model.TaskTypeJavaHeap, TaskUUID: "heap-1"},
+	}, nil)
+	baselines.On("DeleteBaseline", testifymock.Anything, "heap-1").Return(nil)
+	baselines.On("DeleteBaseline", testifymock.Anything, "missing").Return(repository.ErrNotFound)
+
+	serve := func(method, target string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		svc.adminHandler().ServeHTTP(rec, httptest.NewRequest(method, target, nil))
+		return rec
+	}
+
+	rec := serve(http.MethodPut, "/api/baselines/heap-1")
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"service":"checkout"`)
+	baselines.AssertCalled(t, "SetBaseline", testifymock.Anything, testifymock.Anything)
+
+	assert.Equal(t, http.StatusBadRequest, serve(http.MethodPut, "/api/baselines/adhoc").Code, "no service")
+	assert.Equal(t, http.StatusNotFound, serve(http.MethodPut, "/api/baselines/missing").Code)
+
+	rec = serve(http.MethodGet, "/api/baselines?service=checkout")
+	require.Equal(t, http.StatusOK, rec.Code)
+	var body struct {
+		Baselines []model.Baseline `json:"baselines"`
+	}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+	require.Len(t, body.Baselines, 1)
+	assert.Equal(t, "heap-1", body.Baselines[0].TaskUUID)
+
+	assert.Equal(t, http.StatusNoContent, serve(http.MethodDelete, "/api/baselines/heap-1").Code)
+	assert.Equal(t, http.StatusNotFound, serve(http.MethodDelete, "/api/baselines/missing").Code)
+}
+
+func TestService_DeadLetters(t *testing.T) {
+	svc, err := New(&config.Config{}, nil)
+	require.NoError(t, err)
+
+	letters := &mock.MockDeadLetterRepository{}
+	svc.db = &repository.Repositories{DeadLetter: letters}
+
+	letters.On("ListDeadLetters", testifymock.Anything, 0).Return([]*model.DeadLetter{
+		{TaskUUID: "heap-1", Error: "parse error", Attempts: 1, Artifacts: []string{"heap-1/partial/histogram.json"}},
+	}, nil)
+	letters.On("GetDeadLetter", testifymock.Anything, "heap-1").Return(&model.DeadLetter{TaskUUID: "heap-1"}, nil)
+	letters.On("GetDeadLetter", testifymock.Anything, "missing").Return(nil, repository.ErrNotFound)
+	letters.On("DeleteDeadLetter", testifymock.Anything, "heap-1").Return(nil)
+	letters.On("DeleteDeadLetter", testifymock.Anything, "missing").Return(repository.ErrNotFound)
+
+	serve := func(method, target string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		svc.adminHandler().ServeHTTP(rec, httptest.NewRequest(method, target, nil))
+		return rec
+	}
+
+	rec := serve(http.MethodGet, "/api/dead-letters")
+	require.Equal(t, http.StatusOK, rec.Code)
+	var body struct {
+		DeadLetters []model.DeadLetter `json:"dead_letters"`
+	}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+	require.Len(t, body.DeadLetters, 1)
+	assert.Equal(t, []string{"heap-1/partial/histogram.json"}, body.DeadLetters[0].Artifacts)
+
+	assert.Equal(t, http.StatusBadRequest, serve(http.MethodGet, "/api/dead-letters?limit=0").Code)
+	assert.Equal(t, http.StatusOK, serve(http.MethodGet, "/api/dead-letters/heap-1").Code)
+	assert.Equal(t, http.StatusNotFound, serve(http.MethodGet, "/api/dead-letters/missing").Code)
+	assert.Equal(t, http.StatusNoContent, serve(http.MethodDelete, "/api/dead-letters/heap-1").Code)
+	assert.Equal(t, http.StatusNotFound, serve(http.MethodDelete, "/api/dead-letters/missing").Code)
+}
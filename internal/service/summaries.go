@@ -0,0 +1,107 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/perf-analysis/internal/repository"
+	"github.com/perf-analysis/pkg/model"
+)
+
+// handleListSummaries serves the summaries of past analyses, newest first,
+// e.g. the heap sizes of the last 30 dumps of a service:
+//
+//	GET /api/summaries?service=checkout&task_type=java_heap&limit=30
+//
+// Summaries are filtered by service, user, task_type (e.g. "java_heap"),
+// since and until (RFC 3339 times), and limited by limit.
+func (s *Service) handleListSummaries(w http.ResponseWriter, r *http.Request) {
+	query, err := parseSummaryQuery(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	summaries, err := s.db.Summary.ListSummaries(r.Context(), query)
+	if err != nil {
+		s.logger.Error("Failed to list analysis summaries: %v", err)
+		http.Error(w, "failed to list summaries", http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, map[string]interface{}{"summaries": summaries})
+}
+
+// handleGetSummary serves the summary of the analysis of a task:
+//
+//	GET /api/summaries/{tid}
+func (s *Service) handleGetSummary(w http.ResponseWriter, r *http.Request) {
+	summary, err := s.db.Summary.GetSummary(r.Context(), r.PathValue("tid"))
+	if errors.Is(err, repository.ErrNotFound) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		s.logger.Error("Failed to get analysis summary: %v", err)
+		http.Error(w, "failed to get summary", http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, summary)
+}
+
+// parseSummaryQuery parses the query parameters of the summary listing.
+func parseSummaryQuery(values url.Values) (*model.SummaryQuery, error) {
+	query := &model.SummaryQuery{
+		Service:  values.Get("service"),
+		UserName: values.Get("user"),
+	}
+
+	if name := values.Get("task_type"); name != "" {
+		taskType, ok := model.ParseTaskType(name)
+		if !ok {
+			return nil, fmt.Errorf("unknown task_type: %s", name)
+		}
+		query.TaskType = &taskType
+	}
+
+	var err error
+	if query.Since, err = parseQueryTime(values, "since"); err != nil {
+		return nil, err
+	}
+	if query.Until, err = parseQueryTime(values, "until"); err != nil {
+		return nil, err
+	}
+
+	if limit := values.Get("limit"); limit != "" {
+		if query.Limit, err = strconv.Atoi(limit); err != nil || query.Limit < 1 {
+			return nil, fmt.Errorf("invalid limit: %s", limit)
+		}
+	}
+
+	return query, nil
+}
+
+// parseQueryTime parses an RFC 3339 time parameter, zero when absent.
+func parseQueryTime(values url.Values, key string) (time.Time, error) {
+	value := values.Get(key)
+	if value == "" {
+		return time.Time{}, nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid %s, want an RFC 3339 time: %s", key, value)
+	}
+	return t, nil
+}
+
+// writeJSON writes v as the JSON response.
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
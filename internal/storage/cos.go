@@ -138,6 +138,15 @@ func (s *COSStorage) Exists(ctx context.Context, key string) (bool, error) {
 	return ok, nil
 }
 
+// Size returns the size of the object at the specified key.
+func (s *COSStorage) Size(ctx context.Context, key string) (int64, error) {
+	resp, err := s.client.Object.Head(ctx, key, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get object size from COS: %w", err)
+	}
+	return resp.ContentLength, nil
+}
+
 // GetURL returns the public URL for the specified key.
 func (s *COSStorage) GetURL(key string) string {
 	return fmt.Sprintf("%s://%s.cos.%s.%s/%s", s.scheme, s.bucket, s.region, s.domain, key)
@@ -103,7 +103,7 @@ func TestValidateConfig(t *testing.T) {
 
 	t.Run("InvalidStorageType", func(t *testing.T) {
 		cfg := &config.StorageConfig{
-			Type: "s3",
+			Type: "azure",
 		}
 		err := ValidateConfig(cfg)
 		assert.Error(t, err)
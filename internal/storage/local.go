@@ -191,6 +191,21 @@ func (s *LocalStorage) Exists(ctx context.Context, key string) (bool, error) {
 	return true, nil
 }
 
+// Size returns the size of the file at the specified key.
+func (s *LocalStorage) Size(ctx context.Context, key string) (int64, error) {
+	select {
+	case <-ctx.Done():
+		return 0, ctx.Err()
+	default:
+	}
+
+	info, err := os.Stat(s.getFullPath(key))
+	if err != nil {
+		return 0, fmt.Errorf("failed to stat file: %w", err)
+	}
+	return info.Size(), nil
+}
+
 // GetURL returns the file path for local storage.
 func (s *LocalStorage) GetURL(key string) string {
 	return s.getFullPath(key)
@@ -210,6 +210,20 @@ func TestLocalStorage_Exists(t *testing.T) {
 	})
 }
 
+func TestLocalStorage_Size(t *testing.T) {
+	tempDir := t.TempDir()
+	storage, err := NewLocalStorage(tempDir)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(tempDir, "dump.hprof"), make([]byte, 1234), 0644))
+
+	size, err := storage.Size(context.Background(), "dump.hprof")
+	require.NoError(t, err)
+	assert.Equal(t, int64(1234), size)
+
+	_, err = storage.Size(context.Background(), "missing.hprof")
+	assert.Error(t, err)
+}
+
 func TestLocalStorage_GetURL(t *testing.T) {
 	tempDir := t.TempDir()
 	storage, err := NewLocalStorage(tempDir)
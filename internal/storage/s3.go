@@ -0,0 +1,521 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/smithy-go"
+)
+
+const (
+	// DefaultS3PartSize is the default size of the parts of multipart
+	// transfers.
+	DefaultS3PartSize = 64 << 20
+	// minS3PartSize is the smallest part S3 accepts, but for the last one.
+	minS3PartSize = 5 << 20
+	// maxS3Parts is the most parts of a multipart upload.
+	maxS3Parts = 10000
+	// DefaultS3Concurrency is the default number of parts transferred at
+	// once.
+	DefaultS3Concurrency = 4
+	// s3ReadRetries is how many times a download resumes after a failure.
+	s3ReadRetries = 3
+)
+
+// S3Config holds the configuration of S3 and S3 compatible storages.
+type S3Config struct {
+	Bucket string
+	Region string
+	// Endpoint overrides the AWS endpoint, e.g. "http://minio:9000"
+	Endpoint string
+	// PathStyle addresses buckets as endpoint/bucket rather than
+	// bucket.endpoint, as MinIO needs
+	PathStyle bool
+	// AccessKeyID and SecretAccessKey are static credentials; without them
+	// credentials are read from the environment, as by the AWS CLI
+	AccessKeyID     string
+	SecretAccessKey string
+	// PartSize is the size of the parts of multipart transfers, in bytes
+	PartSize int64
+	// Concurrency is the number of parts transferred at once
+	Concurrency int
+}
+
+// S3Storage implements Storage interface for Amazon S3 and S3 compatible
+// storages, such as MinIO and Google Cloud Storage.
+//
+// Transfers are streamed in parts: uploads of unknown size are split into
+// multipart uploads, and downloads resume where they failed, so large heap
+// dumps are neither buffered in memory nor staged on local disk.
+type S3Storage struct {
+	client      *s3.Client
+	bucket      string
+	region      string
+	endpoint    string
+	pathStyle   bool
+	partSize    int64
+	concurrency int
+}
+
+// NewS3Storage creates a new S3Storage instance.
+func NewS3Storage(cfg *S3Config) (*S3Storage, error) {
+	if cfg.Bucket == "" {
+		return nil, fmt.Errorf("bucket is required for S3 storage")
+	}
+	if cfg.Region == "" && cfg.Endpoint == "" {
+		return nil, fmt.Errorf("region or endpoint is required for S3 storage")
+	}
+	if (cfg.AccessKeyID == "") != (cfg.SecretAccessKey == "") {
+		return nil, fmt.Errorf("both access key ID and secret access key are required for S3 storage")
+	}
+
+	region := cfg.Region
+	if region == "" {
+		// Signing needs a region, which S3 compatible servers ignore
+		region = "us-east-1"
+	}
+	partSize := cfg.PartSize
+	if partSize <= 0 {
+		partSize = DefaultS3PartSize
+	}
+	if partSize < minS3PartSize {
+		return nil, fmt.Errorf("S3 part size must be at least %d bytes", minS3PartSize)
+	}
+	concurrency := cfg.Concurrency
+	if concurrency <= 0 {
+		concurrency = DefaultS3Concurrency
+	}
+
+	loadOpts := []func(*awsconfig.LoadOptions) error{awsconfig.WithRegion(region)}
+	if cfg.AccessKeyID != "" {
+		loadOpts = append(loadOpts, awsconfig.WithCredentialsProvider(
+			credentials.NewStaticCredentialsProvider(cfg.AccessKeyID, cfg.SecretAccessKey, "")))
+	}
+	awsCfg, err := awsconfig.LoadDefaultConfig(context.Background(), loadOpts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load AWS configuration: %w", err)
+	}
+
+	client := s3.NewFromConfig(awsCfg, func(o *s3.Options) {
+		o.UsePathStyle = cfg.PathStyle
+		if cfg.Endpoint != "" {
+			o.BaseEndpoint = aws.String(cfg.Endpoint)
+			// S3 compatible servers may not support the checksums AWS adds
+			o.RequestChecksumCalculation = aws.RequestChecksumCalculationWhenRequired
+			o.ResponseChecksumValidation = aws.ResponseChecksumValidationWhenRequired
+		}
+	})
+
+	return &S3Storage{
+		client:      client,
+		bucket:      cfg.Bucket,
+		region:      region,
+		endpoint:    strings.TrimSuffix(cfg.Endpoint, "/"),
+		pathStyle:   cfg.PathStyle,
+		partSize:    partSize,
+		concurrency: concurrency,
+	}, nil
+}
+
+// Upload uploads data from reader to the specified key. Data larger than a
+// part is uploaded as a multipart upload while it is read.
+func (s *S3Storage) Upload(ctx context.Context, key string, reader io.Reader) error {
+	first := make([]byte, s.partSize)
+	n, err := io.ReadFull(reader, first)
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		_, err = s.client.PutObject(ctx, &s3.PutObjectInput{
+			Bucket:        aws.String(s.bucket),
+			Key:           aws.String(key),
+			Body:          bytes.NewReader(first[:n]),
+			ContentLength: aws.Int64(int64(n)),
+		})
+		if err != nil {
+			return fmt.Errorf("failed to upload to S3: %w", err)
+		}
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read upload data: %w", err)
+	}
+
+	if err := s.uploadMultipart(ctx, key, first, reader); err != nil {
+		return fmt.Errorf("failed to upload to S3: %w", err)
+	}
+	return nil
+}
+
+// uploadMultipart uploads first and the rest of reader as a multipart
+// upload, holding at most concurrency parts in memory.
+func (s *S3Storage) uploadMultipart(ctx context.Context, key string, first []byte, reader io.Reader) error {
+	created, err := s.client.CreateMultipartUpload(ctx, &s3.CreateMultipartUploadInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return err
+	}
+	uploadID := created.UploadId
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		mu       sync.Mutex
+		wg       sync.WaitGroup
+		parts    []types.CompletedPart
+		firstErr error
+	)
+	fail := func(err error) {
+		mu.Lock()
+		if firstErr == nil {
+			firstErr = err
+		}
+		mu.Unlock()
+		cancel()
+	}
+
+	// buffers holds the part buffers not in flight; at most concurrency
+	// buffers are allocated
+	buffers := make(chan []byte, s.concurrency)
+	allocated := 1
+	nextBuffer := func() []byte {
+		if allocated < s.concurrency {
+			select {
+			case buf := <-buffers:
+				return buf
+			default:
+				allocated++
+				return make([]byte, s.partSize)
+			}
+		}
+		select {
+		case buf := <-buffers:
+			return buf
+		case <-ctx.Done():
+			return nil
+		}
+	}
+
+	uploadPart := func(number int32, buf []byte, n int) {
+		defer wg.Done()
+		defer func() { buffers <- buf }()
+
+		out, err := s.client.UploadPart(ctx, &s3.UploadPartInput{
+			Bucket:        aws.String(s.bucket),
+			Key:           aws.String(key),
+			UploadId:      uploadID,
+			PartNumber:    aws.Int32(number),
+			Body:          bytes.NewReader(buf[:n]),
+			ContentLength: aws.Int64(int64(n)),
+		})
+		if err != nil {
+			fail(fmt.Errorf("failed to upload part %d: %w", number, err))
+			return
+		}
+		mu.Lock()
+		parts = append(parts, types.CompletedPart{ETag: out.ETag, PartNumber: aws.Int32(number)})
+		mu.Unlock()
+	}
+
+	buf, n := first, len(first)
+	for number := int32(1); ; number++ {
+		if number > maxS3Parts {
+			fail(fmt.Errorf("upload exceeds %d parts of %d bytes", maxS3Parts, s.partSize))
+			break
+		}
+		wg.Add(1)
+		go uploadPart(number, buf, n)
+		if n < len(buf) {
+			// A short part is the last one
+			break
+		}
+
+		if buf = nextBuffer(); buf == nil {
+			break
+		}
+		n, err = io.ReadFull(reader, buf)
+		if err == io.EOF {
+			break
+		}
+		if err != nil && err != io.ErrUnexpectedEOF {
+			fail(fmt.Errorf("failed to read upload data: %w", err))
+			break
+		}
+	}
+	wg.Wait()
+
+	if firstErr == nil {
+		firstErr = ctx.Err()
+	}
+	if firstErr != nil {
+		// Parts of unfinished uploads are stored, and billed, until aborted
+		_, _ = s.client.AbortMultipartUpload(context.WithoutCancel(ctx), &s3.AbortMultipartUploadInput{
+			Bucket:   aws.String(s.bucket),
+			Key:      aws.String(key),
+			UploadId: uploadID,
+		})
+		return firstErr
+	}
+
+	sort.Slice(parts, func(i, j int) bool { return *parts[i].PartNumber < *parts[j].PartNumber })
+	_, err = s.client.CompleteMultipartUpload(ctx, &s3.CompleteMultipartUploadInput{
+		Bucket:          aws.String(s.bucket),
+		Key:             aws.String(key),
+		UploadId:        uploadID,
+		MultipartUpload: &types.CompletedMultipartUpload{Parts: parts},
+	})
+	return err
+}
+
+// UploadFile uploads a local file to the specified key.
+func (s *S3Storage) UploadFile(ctx context.Context, key string, localPath string) error {
+	file, err := os.Open(localPath)
+	if err != nil {
+		return fmt.Errorf("failed to open local file: %w", err)
+	}
+	defer file.Close()
+
+	return s.Upload(ctx, key, file)
+}
+
+// Download downloads data from the specified key. The returned reader streams
+// the object and resumes from where it stopped when the connection fails.
+func (s *S3Storage) Download(ctx context.Context, key string) (io.ReadCloser, error) {
+	out, err := s.client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to download from S3: %w", err)
+	}
+	return &s3Reader{
+		ctx:     ctx,
+		storage: s,
+		key:     key,
+		etag:    out.ETag,
+		body:    out.Body,
+	}, nil
+}
+
+// s3Reader reads an object, resuming with ranged requests on read errors.
+// The ETag of the first response pins the object version.
+type s3Reader struct {
+	ctx     context.Context
+	storage *S3Storage
+	key     string
+	etag    *string
+
+	body    io.ReadCloser
+	offset  int64
+	retries int
+	err     error
+}
+
+// Read reads from the object.
+func (r *s3Reader) Read(p []byte) (int, error) {
+	for r.err == nil {
+		n, err := r.body.Read(p)
+		r.offset += int64(n)
+		if n > 0 || err == nil || err == io.EOF {
+			// An error after data is returned again by the next read
+			if err != io.EOF {
+				err = nil
+			}
+			return n, err
+		}
+		if r.retries >= s3ReadRetries || r.ctx.Err() != nil {
+			r.err = fmt.Errorf("failed to read %s from S3 at offset %d: %w", r.key, r.offset, err)
+			break
+		}
+		r.err = r.resume()
+	}
+	return 0, r.err
+}
+
+// resume reopens the object at the current offset.
+func (r *s3Reader) resume() error {
+	r.retries++
+	r.body.Close()
+
+	out, err := r.storage.client.GetObject(r.ctx, &s3.GetObjectInput{
+		Bucket:  aws.String(r.storage.bucket),
+		Key:     aws.String(r.key),
+		IfMatch: r.etag,
+		Range:   aws.String(fmt.Sprintf("bytes=%d-", r.offset)),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to resume %s from S3 at offset %d: %w", r.key, r.offset, err)
+	}
+	r.body = out.Body
+	return nil
+}
+
+// Close closes the object.
+func (r *s3Reader) Close() error {
+	return r.body.Close()
+}
+
+// DownloadFile downloads data from the specified key to a local file, in
+// parts downloaded concurrently.
+func (s *S3Storage) DownloadFile(ctx context.Context, key string, localPath string) error {
+	head, err := s.client.HeadObject(ctx, &s3.HeadObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to download file from S3: %w", err)
+	}
+	size := aws.ToInt64(head.ContentLength)
+
+	// Ensure parent directory exists
+	if err := os.MkdirAll(filepath.Dir(localPath), 0755); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+	file, err := os.Create(localPath)
+	if err != nil {
+		return fmt.Errorf("failed to create local file: %w", err)
+	}
+
+	err = s.downloadParts(ctx, key, head.ETag, size, file)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(localPath)
+		return fmt.Errorf("failed to download file from S3: %w", err)
+	}
+	return nil
+}
+
+// downloadParts downloads the parts of an object into w concurrently.
+func (s *S3Storage) downloadParts(ctx context.Context, key string, etag *string, size int64, w io.WriterAt) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	offsets := make(chan int64)
+	errs := make(chan error, s.concurrency)
+	var wg sync.WaitGroup
+	for i := 0; i < s.concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for offset := range offsets {
+				if err := s.downloadPart(ctx, key, etag, offset, min(s.partSize, size-offset), w); err != nil {
+					errs <- err
+					cancel()
+					return
+				}
+			}
+		}()
+	}
+
+feed:
+	for offset := int64(0); offset < size; offset += s.partSize {
+		select {
+		case offsets <- offset:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(offsets)
+	wg.Wait()
+
+	select {
+	case err := <-errs:
+		return err
+	default:
+		return ctx.Err()
+	}
+}
+
+// downloadPart downloads length bytes of an object at offset into w.
+func (s *S3Storage) downloadPart(ctx context.Context, key string, etag *string, offset, length int64, w io.WriterAt) error {
+	out, err := s.client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket:  aws.String(s.bucket),
+		Key:     aws.String(key),
+		IfMatch: etag,
+		Range:   aws.String(fmt.Sprintf("bytes=%d-%d", offset, offset+length-1)),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to download part at offset %d: %w", offset, err)
+	}
+	defer out.Body.Close()
+
+	n, err := io.Copy(io.NewOffsetWriter(w, offset), out.Body)
+	if err != nil {
+		return fmt.Errorf("failed to download part at offset %d: %w", offset, err)
+	}
+	if n != length {
+		return fmt.Errorf("short part at offset %d: got %d of %d bytes", offset, n, length)
+	}
+	return nil
+}
+
+// Delete deletes the object at the specified key.
+func (s *S3Storage) Delete(ctx context.Context, key string) error {
+	_, err := s.client.DeleteObject(ctx, &s3.DeleteObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete from S3: %w", err)
+	}
+	return nil
+}
+
+// Exists checks if an object exists at the specified key.
+func (s *S3Storage) Exists(ctx context.Context, key string) (bool, error) {
+	_, err := s.client.HeadObject(ctx, &s3.HeadObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		var apiErr smithy.APIError
+		if errors.As(err, &apiErr) && (apiErr.ErrorCode() == "NotFound" || apiErr.ErrorCode() == "NoSuchKey") {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to check existence in S3: %w", err)
+	}
+	return true, nil
+}
+
+// Size returns the size of the object at the specified key.
+func (s *S3Storage) Size(ctx context.Context, key string) (int64, error) {
+	head, err := s.client.HeadObject(ctx, &s3.HeadObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to get object size from S3: %w", err)
+	}
+	return aws.ToInt64(head.ContentLength), nil
+}
+
+// GetURL returns the URL for the specified key.
+func (s *S3Storage) GetURL(key string) string {
+	switch {
+	case s.endpoint != "" && s.pathStyle:
+		return fmt.Sprintf("%s/%s/%s", s.endpoint, s.bucket, key)
+	case s.endpoint != "":
+		scheme, host, ok := strings.Cut(s.endpoint, "://")
+		if !ok {
+			return fmt.Sprintf("https://%s.%s/%s", s.bucket, s.endpoint, key)
+		}
+		return fmt.Sprintf("%s://%s.%s/%s", scheme, s.bucket, host, key)
+	default:
+		return fmt.Sprintf("https://%s.s3.%s.amazonaws.com/%s", s.bucket, s.region, key)
+	}
+}
@@ -0,0 +1,347 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/perf-analysis/pkg/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeS3 is an in-memory S3 server for path-style requests, supporting the
+// operations S3Storage uses.
+type fakeS3 struct {
+	mu      sync.Mutex
+	objects map[string][]byte
+	uploads map[string]map[int][]byte
+	nextID  int
+
+	// parts counts the parts uploaded
+	parts int
+	// truncateGets is the number of GETs to cut after half the body
+	truncateGets int
+}
+
+func newFakeS3(t *testing.T) (*fakeS3, *httptest.Server) {
+	f := &fakeS3{objects: make(map[string][]byte), uploads: make(map[string]map[int][]byte)}
+	server := httptest.NewServer(f)
+	t.Cleanup(server.Close)
+	return f, server
+}
+
+func etagOf(data []byte) string {
+	sum := md5.Sum(data)
+	return `"` + hex.EncodeToString(sum[:]) + `"`
+}
+
+func (f *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	// Path is /bucket/key
+	key := strings.SplitN(strings.TrimPrefix(r.URL.Path, "/"), "/", 2)[1]
+	query := r.URL.Query()
+	body, _ := io.ReadAll(r.Body)
+
+	switch {
+	case r.Method == http.MethodPost && query.Has("uploads"):
+		f.nextID++
+		id := strconv.Itoa(f.nextID)
+		f.uploads[id] = make(map[int][]byte)
+		fmt.Fprintf(w, `<InitiateMultipartUploadResult><Bucket>b</Bucket><Key>%s</Key><UploadId>%s</UploadId></InitiateMultipartUploadResult>`, key, id)
+
+	case r.Method == http.MethodPut && query.Has("uploadId"):
+		number, _ := strconv.Atoi(query.Get("partNumber"))
+		f.uploads[query.Get("uploadId")][number] = body
+		f.parts++
+		w.Header().Set("ETag", etagOf(body))
+
+	case r.Method == http.MethodPost && query.Has("uploadId"):
+		parts := f.uploads[query.Get("uploadId")]
+		numbers := make([]int, 0, len(parts))
+		for number := range parts {
+			numbers = append(numbers, number)
+		}
+		sort.Ints(numbers)
+		var data []byte
+		for _, number := range numbers {
+			data = append(data, parts[number]...)
+		}
+		f.objects[key] = data
+		delete(f.uploads, query.Get("uploadId"))
+		fmt.Fprintf(w, `<CompleteMultipartUploadResult><Key>%s</Key><ETag>%s</ETag></CompleteMultipartUploadResult>`, key, etagOf(data))
+
+	case r.Method == http.MethodDelete && query.Has("uploadId"):
+		delete(f.uploads, query.Get("uploadId"))
+		w.WriteHeader(http.StatusNoContent)
+
+	case r.Method == http.MethodPut:
+		f.objects[key] = body
+		w.Header().Set("ETag", etagOf(body))
+
+	case r.Method == http.MethodHead, r.Method == http.MethodGet:
+		data, ok := f.objects[key]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			if r.Method == http.MethodGet {
+				fmt.Fprint(w, `<Error><Code>NoSuchKey</Code></Error>`)
+			}
+			return
+		}
+		w.Header().Set("ETag", etagOf(data))
+		if match := r.Header.Get("If-Match"); match != "" && match != etagOf(data) {
+			w.WriteHeader(http.StatusPreconditionFailed)
+			return
+		}
+
+		status := http.StatusOK
+		if rng := r.Header.Get("Range"); rng != "" {
+			var start, end int
+			spec := strings.TrimPrefix(rng, "bytes=")
+			from, to, _ := strings.Cut(spec, "-")
+			start, _ = strconv.Atoi(from)
+			end = len(data) - 1
+			if to != "" {
+				end, _ = strconv.Atoi(to)
+			}
+			w.Header().Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", start, end, len(data)))
+			data = data[start : end+1]
+			status = http.StatusPartialContent
+		}
+		w.Header().Set("Content-Length", strconv.Itoa(len(data)))
+		w.WriteHeader(status)
+		if r.Method == http.MethodHead {
+			return
+		}
+		if f.truncateGets > 0 {
+			f.truncateGets--
+			w.Write(data[:len(data)/2])
+			w.(http.Flusher).Flush()
+			panic(http.ErrAbortHandler)
+		}
+		w.Write(data)
+
+	case r.Method == http.MethodDelete:
+		delete(f.objects, key)
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func newTestS3Storage(t *testing.T, endpoint string) *S3Storage {
+	s, err := NewS3Storage(&S3Config{
+		Bucket:          "test-bucket",
+		Endpoint:        endpoint,
+		PathStyle:       true,
+		AccessKeyID:     "test-id",
+		SecretAccessKey: "test-key",
+		PartSize:        minS3PartSize,
+		Concurrency:     2,
+	})
+	require.NoError(t, err)
+	return s
+}
+
+func testData(size int) []byte {
+	data := make([]byte, size)
+	for i := range data {
+		data[i] = byte(i * 7)
+	}
+	return data
+}
+
+func TestNewS3Storage_Validation(t *testing.T) {
+	t.Run("MissingBucket", func(t *testing.T) {
+		_, err := NewS3Storage(&S3Config{Region: "us-east-1"})
+		assert.ErrorContains(t, err, "bucket is required")
+	})
+
+	t.Run("MissingRegionAndEndpoint", func(t *testing.T) {
+		_, err := NewS3Storage(&S3Config{Bucket: "test-bucket"})
+		assert.ErrorContains(t, err, "region or endpoint is required")
+	})
+
+	t.Run("PartialCredentials", func(t *testing.T) {
+		_, err := NewS3Storage(&S3Config{Bucket: "test-bucket", Region: "us-east-1", AccessKeyID: "test-id"})
+		assert.ErrorContains(t, err, "both access key ID and secret access key")
+	})
+
+	t.Run("PartSizeTooSmall", func(t *testing.T) {
+		_, err := NewS3Storage(&S3Config{Bucket: "test-bucket", Region: "us-east-1", PartSize: 1 << 20})
+		assert.ErrorContains(t, err, "part size must be at least")
+	})
+}
+
+func TestS3Storage_UploadSmall(t *testing.T) {
+	fake, server := newFakeS3(t)
+	s := newTestS3Storage(t, server.URL)
+	ctx := context.Background()
+
+	require.NoError(t, s.Upload(ctx, "task/small.txt", strings.NewReader("hello")))
+	assert.Equal(t, []byte("hello"), fake.objects["task/small.txt"])
+	assert.Zero(t, fake.parts)
+}
+
+func TestS3Storage_UploadMultipart(t *testing.T) {
+	fake, server := newFakeS3(t)
+	s := newTestS3Storage(t, server.URL)
+	ctx := context.Background()
+
+	// Stream of unknown size: 2 full parts and a short one
+	data := testData(2*minS3PartSize + 1000)
+	require.NoError(t, s.Upload(ctx, "task/heap.hprof", io.MultiReader(bytes.NewReader(data))))
+
+	assert.Equal(t, 3, fake.parts)
+	assert.Empty(t, fake.uploads)
+	assert.True(t, bytes.Equal(data, fake.objects["task/heap.hprof"]))
+}
+
+func TestS3Storage_UploadMultipartExactParts(t *testing.T) {
+	fake, server := newFakeS3(t)
+	s := newTestS3Storage(t, server.URL)
+
+	data := testData(2 * minS3PartSize)
+	require.NoError(t, s.Upload(context.Background(), "exact.bin", bytes.NewReader(data)))
+
+	assert.Equal(t, 2, fake.parts)
+	assert.True(t, bytes.Equal(data, fake.objects["exact.bin"]))
+}
+
+func TestS3Storage_DownloadResumes(t *testing.T) {
+	fake, server := newFakeS3(t)
+	s := newTestS3Storage(t, server.URL)
+	data := testData(1 << 20)
+	fake.objects["heap.hprof"] = data
+	fake.truncateGets = 2
+
+	reader, err := s.Download(context.Background(), "heap.hprof")
+	require.NoError(t, err)
+	defer reader.Close()
+
+	got, err := io.ReadAll(reader)
+	require.NoError(t, err)
+	assert.True(t, bytes.Equal(data, got))
+	assert.Zero(t, fake.truncateGets)
+}
+
+func TestS3Storage_DownloadFile(t *testing.T) {
+	fake, server := newFakeS3(t)
+	s := newTestS3Storage(t, server.URL)
+	data := testData(2*minS3PartSize + 123)
+	fake.objects["heap.hprof"] = data
+
+	localPath := filepath.Join(t.TempDir(), "sub", "heap.hprof")
+	require.NoError(t, s.DownloadFile(context.Background(), "heap.hprof", localPath))
+
+	got, err := os.ReadFile(localPath)
+	require.NoError(t, err)
+	assert.True(t, bytes.Equal(data, got))
+}
+
+func TestS3Storage_DownloadFileMissing(t *testing.T) {
+	_, server := newFakeS3(t)
+	s := newTestS3Storage(t, server.URL)
+
+	localPath := filepath.Join(t.TempDir(), "missing")
+	assert.Error(t, s.DownloadFile(context.Background(), "missing", localPath))
+	assert.NoFileExists(t, localPath)
+}
+
+func TestS3Storage_ExistsAndDelete(t *testing.T) {
+	fake, server := newFakeS3(t)
+	s := newTestS3Storage(t, server.URL)
+	ctx := context.Background()
+	fake.objects["result.json"] = []byte("{}")
+
+	ok, err := s.Exists(ctx, "result.json")
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	require.NoError(t, s.Delete(ctx, "result.json"))
+
+	ok, err = s.Exists(ctx, "result.json")
+	require.NoError(t, err)
+	assert.False(t, ok)
+}
+
+func TestS3Storage_Size(t *testing.T) {
+	fake, server := newFakeS3(t)
+	s := newTestS3Storage(t, server.URL)
+	fake.objects["dump.hprof"] = make([]byte, 1234)
+
+	size, err := s.Size(context.Background(), "dump.hprof")
+	require.NoError(t, err)
+	assert.Equal(t, int64(1234), size)
+
+	_, err = s.Size(context.Background(), "missing.hprof")
+	assert.Error(t, err)
+}
+
+func TestS3Storage_GetURL(t *testing.T) {
+	s, err := NewS3Storage(&S3Config{Bucket: "b", Region: "eu-west-1"})
+	require.NoError(t, err)
+	assert.Equal(t, "https://b.s3.eu-west-1.amazonaws.com/k/f.json", s.GetURL("k/f.json"))
+
+	s, err = NewS3Storage(&S3Config{Bucket: "b", Endpoint: "http://minio:9000/", PathStyle: true})
+	require.NoError(t, err)
+	assert.Equal(t, "http://minio:9000/b/k/f.json", s.GetURL("k/f.json"))
+
+	s, err = NewS3Storage(&S3Config{Bucket: "b", Endpoint: "https://storage.googleapis.com"})
+	require.NoError(t, err)
+	assert.Equal(t, "https://b.storage.googleapis.com/k/f.json", s.GetURL("k/f.json"))
+}
+
+func TestValidateConfig_S3Types(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     config.StorageConfig
+		wantErr string
+	}{
+		{"S3WithDefaultCredentials", config.StorageConfig{Type: "s3", Bucket: "b", Region: "us-east-1"}, ""},
+		{"S3MissingBucket", config.StorageConfig{Type: "s3", Region: "us-east-1"}, "s3 bucket is required"},
+		{"S3MissingRegion", config.StorageConfig{Type: "s3", Bucket: "b"}, "region or endpoint is required"},
+		{"S3PartialCredentials", config.StorageConfig{Type: "s3", Bucket: "b", Region: "us-east-1", SecretID: "id"}, "must be set together"},
+		{"S3SmallParts", config.StorageConfig{Type: "s3", Bucket: "b", Region: "us-east-1", PartSizeMB: 1}, "part size must be at least 5 MB"},
+		{"MinIO", config.StorageConfig{Type: "minio", Bucket: "b", Endpoint: "http://minio:9000", SecretID: "id", SecretKey: "key"}, ""},
+		{"MinIOMissingEndpoint", config.StorageConfig{Type: "minio", Bucket: "b", SecretID: "id", SecretKey: "key"}, "minio endpoint is required"},
+		{"GCS", config.StorageConfig{Type: "gcs", Bucket: "b", SecretID: "id", SecretKey: "key"}, ""},
+		{"GCSMissingCredentials", config.StorageConfig{Type: "gcs", Bucket: "b"}, "gcs credentials are required"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateConfig(&tt.cfg)
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorContains(t, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestS3ConfigFrom(t *testing.T) {
+	minio := s3ConfigFrom(&config.StorageConfig{Type: "minio", Bucket: "b", Endpoint: "http://minio:9000", PartSizeMB: 16})
+	assert.True(t, minio.PathStyle)
+	assert.Equal(t, int64(16<<20), minio.PartSize)
+
+	gcs := s3ConfigFrom(&config.StorageConfig{Type: "gcs", Bucket: "b"})
+	assert.Equal(t, gcsEndpoint, gcs.Endpoint)
+	assert.Equal(t, "auto", gcs.Region)
+}
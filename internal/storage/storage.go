@@ -33,14 +33,28 @@ type Storage interface {
 	GetURL(key string) string
 }
 
+// Sizer is implemented by the storages which can tell the size of an object
+// without downloading it.
+type Sizer interface {
+	// Size returns the size in bytes of the object at the specified key.
+	Size(ctx context.Context, key string) (int64, error)
+}
+
 // StorageType represents the type of storage backend.
 type StorageType string
 
 const (
 	StorageTypeLocal StorageType = "local"
 	StorageTypeCOS   StorageType = "cos"
+	StorageTypeS3    StorageType = "s3"
+	StorageTypeMinIO StorageType = "minio"
+	StorageTypeGCS   StorageType = "gcs"
 )
 
+// gcsEndpoint is the S3 compatible XML API of Google Cloud Storage, which
+// authenticates with HMAC keys.
+const gcsEndpoint = "https://storage.googleapis.com"
+
 // NewStorage creates a new Storage instance based on the configuration.
 func NewStorage(cfg *config.StorageConfig) (Storage, error) {
 	if err := ValidateConfig(cfg); err != nil {
@@ -59,11 +73,42 @@ func NewStorage(cfg *config.StorageConfig) (Storage, error) {
 			Domain:    cfg.Domain,
 			Scheme:    cfg.Scheme,
 		})
+	case StorageTypeS3, StorageTypeMinIO, StorageTypeGCS:
+		return NewS3Storage(s3ConfigFrom(cfg))
 	default:
 		return NewLocalStorage(cfg.LocalPath)
 	}
 }
 
+// s3ConfigFrom returns the S3 driver configuration of the S3 compatible
+// storage types.
+func s3ConfigFrom(cfg *config.StorageConfig) *S3Config {
+	s3cfg := &S3Config{
+		Bucket:          cfg.Bucket,
+		Region:          cfg.Region,
+		Endpoint:        cfg.Endpoint,
+		PathStyle:       cfg.PathStyle,
+		AccessKeyID:     cfg.SecretID,
+		SecretAccessKey: cfg.SecretKey,
+		PartSize:        int64(cfg.PartSizeMB) << 20,
+		Concurrency:     cfg.Concurrency,
+	}
+
+	switch StorageType(cfg.Type) {
+	case StorageTypeMinIO:
+		// MinIO serves buckets on paths unless configured with a domain
+		s3cfg.PathStyle = true
+	case StorageTypeGCS:
+		if s3cfg.Endpoint == "" {
+			s3cfg.Endpoint = gcsEndpoint
+		}
+		if s3cfg.Region == "" {
+			s3cfg.Region = "auto"
+		}
+	}
+	return s3cfg
+}
+
 // ValidateConfig validates the storage configuration.
 func ValidateConfig(cfg *config.StorageConfig) error {
 	if cfg == nil {
@@ -77,7 +122,9 @@ func ValidateConfig(cfg *config.StorageConfig) error {
 		storageType = StorageTypeLocal
 	}
 
-	if storageType != StorageTypeCOS && storageType != StorageTypeLocal {
+	switch storageType {
+	case StorageTypeLocal, StorageTypeCOS, StorageTypeS3, StorageTypeMinIO, StorageTypeGCS:
+	default:
 		return fmt.Errorf("unsupported storage type: %s", cfg.Type)
 	}
 
@@ -93,6 +140,28 @@ func ValidateConfig(cfg *config.StorageConfig) error {
 		}
 	}
 
+	if storageType == StorageTypeS3 || storageType == StorageTypeMinIO || storageType == StorageTypeGCS {
+		if cfg.Bucket == "" {
+			return fmt.Errorf("%s bucket is required", storageType)
+		}
+		if storageType == StorageTypeS3 && cfg.Region == "" && cfg.Endpoint == "" {
+			return fmt.Errorf("s3 region or endpoint is required")
+		}
+		if storageType == StorageTypeMinIO && cfg.Endpoint == "" {
+			return fmt.Errorf("minio endpoint is required")
+		}
+		// S3 falls back to the AWS credential chain, the others do not
+		if storageType != StorageTypeS3 && (cfg.SecretID == "" || cfg.SecretKey == "") {
+			return fmt.Errorf("%s credentials are required", storageType)
+		}
+		if (cfg.SecretID == "") != (cfg.SecretKey == "") {
+			return fmt.Errorf("%s secret_id and secret_key must be set together", storageType)
+		}
+		if cfg.PartSizeMB != 0 && int64(cfg.PartSizeMB)<<20 < minS3PartSize {
+			return fmt.Errorf("%s part size must be at least %d MB", storageType, minS3PartSize>>20)
+		}
+	}
+
 	if storageType == StorageTypeLocal {
 		if cfg.LocalPath == "" {
 			return fmt.Errorf("local storage path is required")
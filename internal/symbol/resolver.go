@@ -0,0 +1,287 @@
+// Package symbol resolves the unsymbolized addresses of perf samples into
+// function names, from a local vmlinux or kallsyms, from directories of
+// symbol files and from debuginfod servers.
+package symbol
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// KernelModule is the module perf gives kernel frames.
+const KernelModule = "[kernel.kallsyms]"
+
+const (
+	// maxCachedFrames bounds the cache of resolved frames
+	maxCachedFrames = 1 << 20
+	// debuginfodTimeout bounds a download from a debuginfod server
+	debuginfodTimeout = 2 * time.Minute
+)
+
+// buildIDPaths are the paths of the symbol file of a build ID in a symbol
+// directory, with %s the first two hex digits of the build ID and the rest:
+// the layouts of /usr/lib/debug and of the perf build ID cache.
+var buildIDPaths = []string{
+	".build-id/%s/%s.debug",
+	".build-id/%s/%s",
+	".build-id/%s/%s/debug",
+	".build-id/%s/%s/elf",
+	"%s/%s.debug",
+}
+
+// Config configures the sources of symbols.
+type Config struct {
+	// Vmlinux is the kernel image with symbols, or a kallsyms or System.map
+	// file, resolving kernel frames
+	Vmlinux string
+	// Dirs are directories of symbol files, looked up by build ID in the
+	// layout of /usr/lib/debug/.build-id and of ~/.debug, then by module
+	// path as with perf --symfs
+	Dirs []string
+	// DebuginfodURLs are debuginfod servers queried by build ID
+	DebuginfodURLs []string
+	// CacheDir keeps the files downloaded from debuginfod servers, by
+	// default in the temporary directory
+	CacheDir string
+	// BuildIDs maps module paths to their build ID, e.g. as listed by perf
+	// buildid-list
+	BuildIDs map[string]string
+	// HTTPClient queries debuginfod servers, http.DefaultClient if nil
+	HTTPClient *http.Client
+}
+
+// Enabled reports whether the configuration has a source of symbols.
+func (c *Config) Enabled() bool {
+	return c != nil && (c.Vmlinux != "" || len(c.Dirs) > 0 || len(c.DebuginfodURLs) > 0)
+}
+
+// Frame is an unsymbolized frame of a sample.
+type Frame struct {
+	// Address is the instruction address of the frame in the process
+	Address uint64
+	// Module is the path of the binary of the frame, or KernelModule
+	Module string
+	// Offset is the offset of the frame in the module file, when known,
+	// e.g. printed by perf script -F+dsoff
+	Offset    uint64
+	HasOffset bool
+}
+
+// Resolver resolves frames into function names, caching symbol tables and
+// resolved frames. It is safe for concurrent use.
+type Resolver struct {
+	config Config
+
+	mu     sync.Mutex
+	tables map[string]*Table // by module, nil if it has no symbols
+	frames map[Frame]string  // resolved frames, empty if unresolved
+}
+
+// NewResolver creates a resolver of the sources of a configuration.
+func NewResolver(config Config) *Resolver {
+	if config.HTTPClient == nil {
+		config.HTTPClient = http.DefaultClient
+	}
+	if config.CacheDir == "" {
+		config.CacheDir = filepath.Join(os.TempDir(), "perf-analysis-debuginfod")
+	}
+	return &Resolver{
+		config: config,
+		tables: make(map[string]*Table),
+		frames: make(map[Frame]string),
+	}
+}
+
+// Resolve returns the function of a frame, false if no source knows it.
+func (r *Resolver) Resolve(ctx context.Context, f Frame) (string, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if name, ok := r.frames[f]; ok {
+		return name, name != ""
+	}
+	table, ok := r.tables[f.Module]
+	if !ok {
+		table = r.loadTable(ctx, f.Module)
+		r.tables[f.Module] = table
+	}
+
+	var name string
+	if table != nil {
+		if addr, ok := table.address(f); ok {
+			name, _ = table.Lookup(addr)
+		}
+	}
+	if len(r.frames) >= maxCachedFrames {
+		r.frames = make(map[Frame]string)
+	}
+	r.frames[f] = name
+	return name, name != ""
+}
+
+// loadTable returns the symbols of a module from the first source that has
+// them, nil if none has.
+func (r *Resolver) loadTable(ctx context.Context, module string) *Table {
+	if module == KernelModule || strings.HasPrefix(module, "[kernel") {
+		if r.config.Vmlinux == "" {
+			return nil
+		}
+		table, err := loadTable(r.config.Vmlinux)
+		if err != nil {
+			return nil
+		}
+		return table
+	}
+	if module == "" || strings.HasPrefix(module, "[") {
+		// [vdso], [unknown], ...
+		return nil
+	}
+
+	buildID := r.config.BuildIDs[module]
+	if !isBuildID(buildID) {
+		// Build IDs name files and URLs
+		buildID = ""
+	}
+	for _, path := range r.candidates(module, buildID) {
+		if table, err := loadTable(path); err == nil && table.Len() > 0 && matchesBuildID(table, buildID) {
+			return table
+		}
+	}
+	if buildID != "" {
+		for _, url := range r.config.DebuginfodURLs {
+			path, err := r.download(ctx, url, buildID)
+			if err != nil {
+				continue
+			}
+			if table, err := loadTable(path); err == nil && table.Len() > 0 {
+				return table
+			}
+		}
+	}
+	return nil
+}
+
+// candidates returns the local files that may hold the symbols of a
+// module, best first.
+func (r *Resolver) candidates(module, buildID string) []string {
+	var paths []string
+	if buildID != "" {
+		for _, dir := range r.config.Dirs {
+			for _, layout := range buildIDPaths {
+				paths = append(paths, filepath.Join(dir, fmt.Sprintf(layout, buildID[:2], buildID[2:])))
+			}
+		}
+	}
+	for _, dir := range r.config.Dirs {
+		paths = append(paths, filepath.Join(dir, module))
+	}
+	if buildID != "" {
+		paths = append(paths, r.cachePath(buildID))
+	}
+	// The module itself, when analyzing on the profiled host
+	return append(paths, module)
+}
+
+// matchesBuildID reports whether a table may be of the binary of a build
+// ID: files without a build ID cannot be told apart.
+func matchesBuildID(t *Table, buildID string) bool {
+	return buildID == "" || t.BuildID == "" || strings.EqualFold(t.BuildID, buildID)
+}
+
+// isBuildID reports whether s is a hex encoded build ID.
+func isBuildID(s string) bool {
+	if len(s) < 4 {
+		return false
+	}
+	for _, c := range s {
+		if (c < '0' || c > '9') && (c < 'a' || c > 'f') && (c < 'A' || c > 'F') {
+			return false
+		}
+	}
+	return true
+}
+
+// cachePath is the path of the debuginfo of a build ID in the cache.
+func (r *Resolver) cachePath(buildID string) string {
+	return filepath.Join(r.config.CacheDir, buildID, "debuginfo")
+}
+
+// download fetches the debuginfo, else the executable, of a build ID from a
+// debuginfod server into the cache.
+func (r *Resolver) download(ctx context.Context, url, buildID string) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, debuginfodTimeout)
+	defer cancel()
+
+	var lastErr error
+	for _, artifact := range []string{"debuginfo", "executable"} {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimSuffix(url, "/")+"/buildid/"+buildID+"/"+artifact, nil)
+		if err != nil {
+			return "", err
+		}
+		resp, err := r.config.HTTPClient.Do(req)
+		if err != nil {
+			return "", err
+		}
+		if resp.StatusCode != http.StatusOK {
+			resp.Body.Close()
+			lastErr = fmt.Errorf("debuginfod %s: %s of %s: %s", url, artifact, buildID, resp.Status)
+			continue
+		}
+		path, err := r.save(resp.Body, buildID)
+		resp.Body.Close()
+		return path, err
+	}
+	return "", lastErr
+}
+
+// save writes a downloaded symbol file to the cache.
+func (r *Resolver) save(body io.Reader, buildID string) (string, error) {
+	dir := filepath.Dir(r.cachePath(buildID))
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", err
+	}
+	f, err := os.CreateTemp(dir, "debuginfo-*.tmp")
+	if err != nil {
+		return "", err
+	}
+	if _, err := io.Copy(f, body); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return "", err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(f.Name())
+		return "", err
+	}
+	if err := os.Rename(f.Name(), r.cachePath(buildID)); err != nil {
+		os.Remove(f.Name())
+		return "", err
+	}
+	return r.cachePath(buildID), nil
+}
+
+// ParseBuildIDList parses the output of perf buildid-list: a build ID and a
+// module path per line.
+func ParseBuildIDList(r io.Reader) (map[string]string, error) {
+	buildIDs := make(map[string]string)
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		id, path, ok := strings.Cut(strings.TrimSpace(scanner.Text()), " ")
+		if !ok || strings.HasPrefix(id, "#") {
+			continue
+		}
+		buildIDs[strings.TrimSpace(path)] = strings.ToLower(id)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return buildIDs, nil
+}
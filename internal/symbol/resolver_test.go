@@ -0,0 +1,144 @@
+package symbol
+
+import (
+	"context"
+	"debug/elf"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResolver_Kernel(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "kallsyms")
+	require.NoError(t, os.WriteFile(path, []byte(testKallsyms), 0644))
+	r := NewResolver(Config{Vmlinux: path})
+
+	name, ok := r.Resolve(context.Background(), Frame{Address: 0xffffffff81001010, Module: KernelModule})
+	assert.True(t, ok)
+	assert.Equal(t, "do_syscall_64", name)
+
+	_, ok = r.Resolve(context.Background(), Frame{Address: 0x10, Module: "[vdso]"})
+	assert.False(t, ok)
+}
+
+func TestResolver_SymbolDir(t *testing.T) {
+	data, err := os.ReadFile(writeTestELF(t, t.TempDir(), elf.ET_EXEC, []byte{0xab, 0xcd, 0xef, 0x01}, testSymbols))
+	require.NoError(t, err)
+	addr := testSymbols[1].addr
+
+	// A symbol file by build ID, and one by module path
+	dir := t.TempDir()
+	byID := filepath.Join(dir, ".build-id", "ab", "cdef01.debug")
+	require.NoError(t, os.MkdirAll(filepath.Dir(byID), 0755))
+	require.NoError(t, os.WriteFile(byID, data, 0644))
+	byPath := filepath.Join(dir, "opt", "other", "server")
+	require.NoError(t, os.MkdirAll(filepath.Dir(byPath), 0755))
+	require.NoError(t, os.WriteFile(byPath, data, 0644))
+
+	r := NewResolver(Config{
+		Dirs:     []string{dir},
+		BuildIDs: map[string]string{"/opt/app/server": "abcdef01"},
+	})
+	for _, module := range []string{"/opt/app/server", "/opt/other/server"} {
+		name, ok := r.Resolve(context.Background(), Frame{Address: addr, Module: module})
+		assert.True(t, ok, module)
+		assert.Equal(t, "handle_request", name, module)
+	}
+
+	_, ok := r.Resolve(context.Background(), Frame{Address: addr, Module: "/opt/missing"})
+	assert.False(t, ok)
+}
+
+func TestResolver_Debuginfod(t *testing.T) {
+	data, err := os.ReadFile(writeTestELF(t, t.TempDir(), elf.ET_EXEC, []byte{0xab, 0xcd, 0xef, 0x01}, testSymbols))
+	require.NoError(t, err)
+	addr := testSymbols[1].addr
+
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		if r.URL.Path != "/buildid/abcdef01/debuginfo" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write(data)
+	}))
+	defer server.Close()
+
+	cacheDir := t.TempDir()
+	config := Config{
+		DebuginfodURLs: []string{server.URL},
+		CacheDir:       cacheDir,
+		BuildIDs:       map[string]string{"/opt/app/server": "abcdef01", "/opt/app/lib.so": "99999999"},
+	}
+	r := NewResolver(config)
+	frame := Frame{Address: addr, Module: "/opt/app/server"}
+	name, ok := r.Resolve(context.Background(), frame)
+	require.True(t, ok)
+	assert.Equal(t, "handle_request", name)
+	assert.FileExists(t, filepath.Join(cacheDir, "abcdef01", "debuginfo"))
+
+	// Resolved frames and tables are cached
+	r.Resolve(context.Background(), frame)
+	r.Resolve(context.Background(), Frame{Address: addr + 1, Module: "/opt/app/server"})
+	assert.Equal(t, int32(1), requests.Load())
+
+	// Unknown build IDs are asked for the debuginfo, then the executable
+	_, ok = r.Resolve(context.Background(), Frame{Address: addr, Module: "/opt/app/lib.so"})
+	assert.False(t, ok)
+	assert.Equal(t, int32(3), requests.Load())
+
+	// A new resolver finds the download in the cache
+	r = NewResolver(config)
+	name, ok = r.Resolve(context.Background(), frame)
+	assert.True(t, ok)
+	assert.Equal(t, "handle_request", name)
+	assert.Equal(t, int32(3), requests.Load())
+}
+
+func TestResolver_InvalidBuildID(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		http.NotFound(w, r)
+	}))
+	defer server.Close()
+
+	r := NewResolver(Config{
+		DebuginfodURLs: []string{server.URL},
+		CacheDir:       t.TempDir(),
+		BuildIDs:       map[string]string{"/opt/app/server": "../../etc"},
+	})
+	_, ok := r.Resolve(context.Background(), Frame{Address: 0x1000, Module: "/opt/app/server"})
+	assert.False(t, ok)
+	assert.Zero(t, requests.Load())
+}
+
+func TestParseBuildIDList(t *testing.T) {
+	input := `ABCDEF0123456789 /usr/lib64/libc.so.6
+0123456789abcdef [kernel.kallsyms]
+
+ffff0000 /opt/app/my server
+`
+	buildIDs, err := ParseBuildIDList(strings.NewReader(input))
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{
+		"/usr/lib64/libc.so.6": "abcdef0123456789",
+		"[kernel.kallsyms]":    "0123456789abcdef",
+		"/opt/app/my server":   "ffff0000",
+	}, buildIDs)
+}
+
+func TestConfig_Enabled(t *testing.T) {
+	assert.False(t, (*Config)(nil).Enabled())
+	assert.False(t, (&Config{CacheDir: "/tmp"}).Enabled())
+	assert.True(t, (&Config{Vmlinux: "/boot/vmlinux"}).Enabled())
+	assert.True(t, (&Config{DebuginfodURLs: []string{"http://localhost"}}).Enabled())
+}
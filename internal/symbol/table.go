@@ -0,0 +1,202 @@
+package symbol
+
+import (
+	"bufio"
+	"bytes"
+	"debug/elf"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// noteTypeGNUBuildID is the type of the ELF note holding the GNU build ID.
+const noteTypeGNUBuildID = 3
+
+// tableSymbol is a function of a symbol table.
+type tableSymbol struct {
+	addr uint64
+	size uint64 // 0 if unknown: the symbol ends at the next one
+	name string
+}
+
+// segment is a loadable segment of an ELF file, mapping file offsets to
+// virtual addresses.
+type segment struct {
+	off, vaddr, size uint64
+}
+
+// Table is the symbol table of a binary, looked up by address.
+type Table struct {
+	symbols []tableSymbol // sorted by address
+	// segments map module offsets to addresses, nil for tables of
+	// absolute addresses such as kallsyms
+	segments []segment
+	// absolute tables are looked up by the addresses of samples: kernels
+	// and executables loaded at their link address
+	absolute bool
+	// BuildID is the GNU build ID of ELF files, hex encoded, if any
+	BuildID string
+}
+
+// LoadELF reads the function symbols of an ELF file, e.g. a vmlinux, a
+// debuginfo file or a binary that was not stripped.
+func LoadELF(path string) (*Table, error) {
+	f, err := elf.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	t := &Table{absolute: f.Type == elf.ET_EXEC}
+	for _, load := range []func() ([]elf.Symbol, error){f.Symbols, f.DynamicSymbols} {
+		syms, err := load()
+		if err != nil && !errors.Is(err, elf.ErrNoSymbols) {
+			return nil, fmt.Errorf("failed to read symbols of %s: %w", path, err)
+		}
+		for _, s := range syms {
+			if elf.ST_TYPE(s.Info) == elf.STT_FUNC && s.Value != 0 && s.Name != "" {
+				t.symbols = append(t.symbols, tableSymbol{addr: s.Value, size: s.Size, name: s.Name})
+			}
+		}
+	}
+	for _, p := range f.Progs {
+		if p.Type == elf.PT_LOAD && p.Flags&elf.PF_X != 0 {
+			t.segments = append(t.segments, segment{off: p.Off, vaddr: p.Vaddr, size: p.Filesz})
+		}
+	}
+	t.BuildID = buildID(f)
+	t.sort()
+	return t, nil
+}
+
+// LoadKallsyms reads symbols in the format of /proc/kallsyms or of a
+// System.map: "ffffffff81000000 T _text" per line.
+func LoadKallsyms(r io.Reader) (*Table, error) {
+	t := &Table{absolute: true}
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 3 {
+			continue
+		}
+		// Only text symbols
+		if kind := strings.ToLower(fields[1]); kind != "t" && kind != "w" {
+			continue
+		}
+		addr, err := strconv.ParseUint(fields[0], 16, 64)
+		if err != nil || addr == 0 {
+			continue
+		}
+		t.symbols = append(t.symbols, tableSymbol{addr: addr, name: fields[2]})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	t.sort()
+	return t, nil
+}
+
+// loadTable reads the symbols of a file: an ELF file, or a kallsyms or
+// System.map text file.
+func loadTable(path string) (*Table, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	magic := make([]byte, len(elf.ELFMAG))
+	if _, err := io.ReadFull(f, magic); err == nil && string(magic) == elf.ELFMAG {
+		return LoadELF(path)
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+	return LoadKallsyms(f)
+}
+
+// Len returns the number of symbols of the table.
+func (t *Table) Len() int {
+	return len(t.symbols)
+}
+
+// Lookup returns the function containing an address of the table.
+func (t *Table) Lookup(addr uint64) (string, bool) {
+	i := sort.Search(len(t.symbols), func(i int) bool { return t.symbols[i].addr > addr }) - 1
+	if i < 0 {
+		return "", false
+	}
+	s := t.symbols[i]
+	if s.size > 0 && addr >= s.addr+s.size {
+		return "", false
+	}
+	return s.name, true
+}
+
+// address returns the address in the table of a frame: its offset in the
+// module translated by the segments, or its address for absolute tables.
+func (t *Table) address(f Frame) (uint64, bool) {
+	if f.HasOffset {
+		for _, s := range t.segments {
+			if f.Offset >= s.off && f.Offset < s.off+s.size {
+				return f.Offset - s.off + s.vaddr, true
+			}
+		}
+		if t.segments == nil {
+			return f.Offset, true
+		}
+	}
+	return f.Address, t.absolute
+}
+
+// sort sorts the symbols by address, keeping the first of aliases.
+func (t *Table) sort() {
+	sort.SliceStable(t.symbols, func(i, j int) bool { return t.symbols[i].addr < t.symbols[j].addr })
+	unique := t.symbols[:0]
+	for _, s := range t.symbols {
+		if n := len(unique); n > 0 && unique[n-1].addr == s.addr {
+			continue
+		}
+		unique = append(unique, s)
+	}
+	t.symbols = unique
+}
+
+// buildID returns the GNU build ID of an ELF file, empty if it has none.
+func buildID(f *elf.File) string {
+	for _, s := range f.Sections {
+		if s.Type != elf.SHT_NOTE {
+			continue
+		}
+		data, err := s.Data()
+		if err != nil {
+			continue
+		}
+		// Notes: name size, descriptor size, type, then the name and the
+		// descriptor, each padded to 4 bytes
+		for len(data) >= 12 {
+			nameSize := f.ByteOrder.Uint32(data[0:4])
+			descSize := f.ByteOrder.Uint32(data[4:8])
+			noteType := f.ByteOrder.Uint32(data[8:12])
+			nameEnd := 12 + align4(nameSize)
+			descEnd := nameEnd + align4(descSize)
+			if descEnd > uint64(len(data)) {
+				break
+			}
+			name := bytes.TrimRight(data[12:12+uint64(nameSize)], "\x00")
+			if noteType == noteTypeGNUBuildID && string(name) == "GNU" {
+				return hex.EncodeToString(data[nameEnd : nameEnd+uint64(descSize)])
+			}
+			data = data[descEnd:]
+		}
+	}
+	return ""
+}
+
+func align4(n uint32) uint64 {
+	return (uint64(n) + 3) &^ 3
+}
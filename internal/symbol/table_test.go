@@ -0,0 +1,193 @@
+package symbol
+
+import (
+	"bytes"
+	"debug/elf"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testKallsyms = `ffffffff81000000 T _text
+ffffffff81001000 T do_syscall_64
+ffffffff81002000 t __x64_sys_read
+ffffffff81002000 t __x64_sys_read_alias
+ffffffff81003000 D jiffies
+0000000000000000 T zero
+ffffffffc0a01000 t ext4_readdir	[ext4]
+`
+
+// testSymbol is a function of a test ELF file.
+type testSymbol struct {
+	name       string
+	addr, size uint64
+}
+
+// Text segment of test ELF files
+const (
+	testTextOffset = 0x1000
+	testTextAddr   = 0x401000
+	testTextSize   = 0x1000
+)
+
+// writeTestELF writes an ELF file with function symbols, a text segment and
+// a build ID, returning its path.
+func writeTestELF(t *testing.T, dir string, typ elf.Type, buildID []byte, symbols []testSymbol) string {
+	t.Helper()
+
+	shstrtab := []byte("\x00.shstrtab\x00.strtab\x00.symtab\x00.note.gnu.build-id\x00")
+	strtab := []byte{0}
+	var symtab bytes.Buffer
+	binary.Write(&symtab, binary.LittleEndian, elf.Sym64{})
+	for _, s := range symbols {
+		binary.Write(&symtab, binary.LittleEndian, elf.Sym64{
+			Name:  uint32(len(strtab)),
+			Info:  elf.ST_INFO(elf.STB_GLOBAL, elf.STT_FUNC),
+			Shndx: uint16(elf.SHN_ABS),
+			Value: s.addr,
+			Size:  s.size,
+		})
+		strtab = append(append(strtab, s.name...), 0)
+	}
+	var note bytes.Buffer
+	binary.Write(&note, binary.LittleEndian, []uint32{4, uint32(len(buildID)), noteTypeGNUBuildID})
+	note.WriteString("GNU\x00")
+	note.Write(buildID)
+
+	headerSize := uint64(binary.Size(elf.Header64{}) + binary.Size(elf.Prog64{}))
+	sections := []struct {
+		name, typ, link, entsize uint32
+		data                     []byte
+	}{
+		{1, uint32(elf.SHT_STRTAB), 0, 0, shstrtab},
+		{11, uint32(elf.SHT_STRTAB), 0, 0, strtab},
+		{19, uint32(elf.SHT_SYMTAB), 2, uint32(binary.Size(elf.Sym64{})), symtab.Bytes()},
+		{27, uint32(elf.SHT_NOTE), 0, 0, note.Bytes()},
+	}
+	var body bytes.Buffer
+	headers := []elf.Section64{{}}
+	for _, s := range sections {
+		headers = append(headers, elf.Section64{
+			Name: s.name, Type: s.typ, Link: s.link, Entsize: uint64(s.entsize),
+			Off: headerSize + uint64(body.Len()), Size: uint64(len(s.data)), Addralign: 1,
+		})
+		body.Write(s.data)
+	}
+
+	var out bytes.Buffer
+	header := elf.Header64{
+		Type: uint16(typ), Machine: uint16(elf.EM_X86_64), Version: uint32(elf.EV_CURRENT),
+		Phoff: uint64(binary.Size(elf.Header64{})), Shoff: headerSize + uint64(body.Len()),
+		Ehsize: uint16(binary.Size(elf.Header64{})), Phentsize: uint16(binary.Size(elf.Prog64{})), Phnum: 1,
+		Shentsize: uint16(binary.Size(elf.Section64{})), Shnum: uint16(len(headers)), Shstrndx: 1,
+	}
+	copy(header.Ident[:], elf.ELFMAG)
+	header.Ident[elf.EI_CLASS] = byte(elf.ELFCLASS64)
+	header.Ident[elf.EI_DATA] = byte(elf.ELFDATA2LSB)
+	header.Ident[elf.EI_VERSION] = byte(elf.EV_CURRENT)
+	binary.Write(&out, binary.LittleEndian, header)
+	binary.Write(&out, binary.LittleEndian, elf.Prog64{
+		Type: uint32(elf.PT_LOAD), Flags: uint32(elf.PF_R | elf.PF_X),
+		Off: testTextOffset, Vaddr: testTextAddr, Filesz: testTextSize, Memsz: testTextSize,
+	})
+	out.Write(body.Bytes())
+	binary.Write(&out, binary.LittleEndian, headers)
+
+	path := filepath.Join(dir, "binary")
+	require.NoError(t, os.WriteFile(path, out.Bytes(), 0644))
+	return path
+}
+
+var testSymbols = []testSymbol{
+	{"main", 0x401000, 0x100},
+	{"handle_request", 0x401100, 0x200},
+	{"parse_header", 0x401400, 0x80},
+}
+
+func TestLoadKallsyms(t *testing.T) {
+	table, err := LoadKallsyms(strings.NewReader(testKallsyms))
+	require.NoError(t, err)
+
+	// Data symbols, null addresses and aliases are skipped
+	assert.Equal(t, 4, table.Len())
+
+	tests := []struct {
+		addr uint64
+		want string
+		ok   bool
+	}{
+		{0xffffffff81001000, "do_syscall_64", true},
+		{0xffffffff81001abc, "do_syscall_64", true},
+		{0xffffffff81002010, "__x64_sys_read", true},
+		{0xffffffffc0a01010, "ext4_readdir", true},
+		{0xffffffff80000000, "", false},
+	}
+	for _, tt := range tests {
+		got, ok := table.Lookup(tt.addr)
+		assert.Equal(t, tt.ok, ok, "%x", tt.addr)
+		assert.Equal(t, tt.want, got, "%x", tt.addr)
+	}
+}
+
+func TestLoadELF(t *testing.T) {
+	path := writeTestELF(t, t.TempDir(), elf.ET_EXEC, []byte{0xab, 0xcd, 0xef, 0x01}, testSymbols)
+
+	table, err := LoadELF(path)
+	require.NoError(t, err)
+	assert.Equal(t, 3, table.Len())
+	assert.True(t, table.absolute)
+	assert.Equal(t, "abcdef01", table.BuildID)
+
+	tests := []struct {
+		addr uint64
+		want string
+		ok   bool
+	}{
+		{0x401000, "main", true},
+		{0x401150, "handle_request", true},
+		{0x401300, "", false}, // between symbols
+		{0x401410, "parse_header", true},
+		{0x400000, "", false},
+	}
+	for _, tt := range tests {
+		got, ok := table.Lookup(tt.addr)
+		assert.Equal(t, tt.ok, ok, "%x", tt.addr)
+		assert.Equal(t, tt.want, got, "%x", tt.addr)
+	}
+}
+
+func TestTable_Address(t *testing.T) {
+	dir := t.TempDir()
+	exec, err := LoadELF(writeTestELF(t, dir, elf.ET_EXEC, nil, testSymbols))
+	require.NoError(t, err)
+	shared, err := LoadELF(writeTestELF(t, dir, elf.ET_DYN, nil, testSymbols))
+	require.NoError(t, err)
+
+	// Offsets in the file are translated by the text segment
+	addr, ok := shared.address(Frame{Address: 0x7f0000001150, Offset: 0x1150, HasOffset: true})
+	assert.True(t, ok)
+	assert.Equal(t, uint64(0x401150), addr)
+
+	// Without offset, only executables are looked up by address
+	_, ok = shared.address(Frame{Address: 0x7f0000001150})
+	assert.False(t, ok)
+	addr, ok = exec.address(Frame{Address: 0x401150})
+	assert.True(t, ok)
+	assert.Equal(t, uint64(0x401150), addr)
+}
+
+func TestLoadTable_DetectsFormat(t *testing.T) {
+	path := t.TempDir() + "/System.map"
+	require.NoError(t, os.WriteFile(path, []byte(testKallsyms), 0644))
+
+	table, err := loadTable(path)
+	require.NoError(t, err)
+	name, ok := table.Lookup(0xffffffff81001004)
+	assert.True(t, ok)
+	assert.Equal(t, "do_syscall_64", name)
+}
@@ -0,0 +1,61 @@
+package webui
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// SetSnapshotCacheConfig replaces the heap snapshot cache limits.
+// It must be called before Start; resident snapshots are dropped.
+func (s *Server) SetSnapshotCacheConfig(config SnapshotManagerConfig) {
+	s.refGraphService = NewRefGraphServiceWithConfig(s.dataDir, config)
+}
+
+// Snapshots returns the heap snapshot cache, so that other front ends (e.g. the
+// gRPC API) can share resident snapshots with the web UI.
+func (s *Server) Snapshots() *SnapshotManager {
+	return s.refGraphService.Snapshots()
+}
+
+// handleAdminCache inspects or flushes the heap snapshot cache.
+//
+//	GET    /api/admin/cache            - cache statistics and resident snapshots
+//	DELETE /api/admin/cache            - flush all snapshots
+//	DELETE /api/admin/cache?task=<id>  - evict one task's snapshot
+func (s *Server) handleAdminCache(w http.ResponseWriter, r *http.Request) {
+	snapshots := s.refGraphService.Snapshots()
+
+	switch r.Method {
+	case http.MethodGet:
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Access-Control-Allow-Origin", "*")
+		json.NewEncoder(w).Encode(snapshots.Stats())
+
+	case http.MethodDelete:
+		var result CacheFlushResponse
+		if taskID := r.URL.Query().Get("task"); taskID != "" {
+			if !snapshots.Evict(taskID) {
+				http.Error(w, "Snapshot not cached: "+taskID, http.StatusNotFound)
+				return
+			}
+			result.Evicted = []string{taskID}
+			if s.logger != nil {
+				s.logger.Info("Snapshot evicted from cache: %s", taskID)
+			}
+		} else {
+			flushed := snapshots.Flush()
+			result.Flushed = &flushed
+			if s.logger != nil {
+				s.logger.Info("Snapshot cache flushed: %d snapshots", flushed)
+			}
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Access-Control-Allow-Origin", "*")
+		json.NewEncoder(w).Encode(result)
+
+	default:
+		w.Header().Set("Allow", "GET, DELETE")
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
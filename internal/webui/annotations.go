@@ -0,0 +1,259 @@
+package webui
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// AnnotationsFileName is the frame annotations file in a task directory.
+const AnnotationsFileName = "annotations.json"
+
+// Limits of frame annotations.
+const (
+	maxAnnotationBody    = 64 << 10
+	maxAnnotations       = 500
+	maxAnnotationNoteLen = 4 << 10
+	maxAnnotationPathLen = 512
+)
+
+// Number of matches of flame graph search responses.
+const (
+	flameSearchDefaultLimit = 50
+	flameSearchMaxLimit     = 1000
+)
+
+// FrameAnnotation is a user note attached to a flame graph frame of a task.
+type FrameAnnotation struct {
+	ID int `json:"id"`
+	// Type is the flame graph type of the frame, e.g. cpu or memory
+	Type string `json:"type,omitempty"`
+	// Path holds the frame names from below the root to the frame, as
+	// returned by flame graph searches
+	Path []string `json:"path"`
+	Note string   `json:"note"`
+	// Pinned annotations are listed first and highlighted in the flame graph
+	Pinned    bool   `json:"pinned,omitempty"`
+	CreatedAt string `json:"created_at,omitempty"`
+	UpdatedAt string `json:"updated_at,omitempty"`
+}
+
+// normalize trims the note and validates the frame path and the limits.
+func (a *FrameAnnotation) normalize() error {
+	a.Note = strings.TrimSpace(a.Note)
+	if len(a.Path) == 0 {
+		return fmt.Errorf("path must name a frame")
+	}
+	if len(a.Path) > maxAnnotationPathLen {
+		return fmt.Errorf("path must have at most %d frames", maxAnnotationPathLen)
+	}
+	if a.Note == "" {
+		return fmt.Errorf("note must not be empty")
+	}
+	if len(a.Note) > maxAnnotationNoteLen {
+		return fmt.Errorf("note must be at most %d bytes", maxAnnotationNoteLen)
+	}
+	if a.Type != "" {
+		fgType, ok := ParseFlameGraphType(a.Type)
+		if !ok {
+			return fmt.Errorf("unknown flame graph type %q", a.Type)
+		}
+		a.Type = string(fgType)
+	}
+	return nil
+}
+
+// loadAnnotations reads the frame annotations of a task, none if it has no
+// annotations file.
+func loadAnnotations(taskDir string) ([]*FrameAnnotation, error) {
+	data, err := os.ReadFile(filepath.Join(taskDir, AnnotationsFileName))
+	if os.IsNotExist(err) {
+		return []*FrameAnnotation{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var annotations []*FrameAnnotation
+	if err := json.Unmarshal(data, &annotations); err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", AnnotationsFileName, err)
+	}
+	return annotations, nil
+}
+
+// saveAnnotations atomically writes the frame annotations of a task.
+func saveAnnotations(taskDir string, annotations []*FrameAnnotation) error {
+	data, err := json.MarshalIndent(annotations, "", "  ")
+	if err != nil {
+		return err
+	}
+	defer preserveModTime(taskDir)()
+	filename := filepath.Join(taskDir, AnnotationsFileName)
+	tmpFile := filename + ".tmp"
+	if err := os.WriteFile(tmpFile, data, 0644); err != nil {
+		return err
+	}
+	if err := os.Rename(tmpFile, filename); err != nil {
+		os.Remove(tmpFile)
+		return err
+	}
+	return nil
+}
+
+// handleAnnotations reads and edits the frame annotations of a task.
+//
+//	GET    /api/annotations?task=<id>          - annotations, pinned first
+//	POST   /api/annotations?task=<id>          - add the annotation of the JSON body
+//	PUT    /api/annotations?task=<id>&id=<n>   - replace the note and pinning of an annotation
+//	DELETE /api/annotations?task=<id>&id=<n>   - remove an annotation
+func (s *Server) handleAnnotations(w http.ResponseWriter, r *http.Request) {
+	var req annotationRequest
+	if err := decodeQuery(r, &req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	taskDir, err := s.existingTaskDir(req.Task)
+	if err != nil {
+		http.Error(w, "Task not found: "+req.Task, http.StatusNotFound)
+		return
+	}
+
+	// Serialize read-modify-write cycles of concurrent edits
+	s.annotationsMu.Lock()
+	defer s.annotationsMu.Unlock()
+
+	annotations, err := loadAnnotations(taskDir)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to load annotations: %v", err), http.StatusInternalServerError)
+		return
+	}
+	index := -1
+	for i, a := range annotations {
+		if a.ID == req.ID {
+			index = i
+		}
+	}
+	if (r.Method == http.MethodPut || r.Method == http.MethodDelete) && index < 0 {
+		http.Error(w, fmt.Sprintf("Annotation not found: %d", req.ID), http.StatusNotFound)
+		return
+	}
+
+	var result any
+	switch r.Method {
+	case http.MethodGet:
+		pinned := make([]*FrameAnnotation, 0, len(annotations))
+		var others []*FrameAnnotation
+		for _, a := range annotations {
+			if a.Pinned {
+				pinned = append(pinned, a)
+			} else {
+				others = append(others, a)
+			}
+		}
+		result = append(pinned, others...)
+
+	case http.MethodPost, http.MethodPut:
+		var a FrameAnnotation
+		decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxAnnotationBody))
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(&a); err != nil && err != io.EOF {
+			http.Error(w, "Invalid annotation: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		now := time.Now().UTC().Format(time.RFC3339)
+		if r.Method == http.MethodPut {
+			// Only the note and the pinning of an annotation change
+			existing := *annotations[index]
+			existing.Note, existing.Pinned = a.Note, a.Pinned
+			a = existing
+		} else {
+			if len(annotations) >= maxAnnotations {
+				http.Error(w, fmt.Sprintf("A task has at most %d annotations", maxAnnotations), http.StatusBadRequest)
+				return
+			}
+			a.ID = 1
+			for _, existing := range annotations {
+				a.ID = max(a.ID, existing.ID+1)
+			}
+			a.CreatedAt = now
+		}
+		if err := a.normalize(); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		a.UpdatedAt = now
+		if r.Method == http.MethodPut {
+			annotations[index] = &a
+		} else {
+			annotations = append(annotations, &a)
+		}
+		if err := saveAnnotations(taskDir, annotations); err != nil {
+			http.Error(w, fmt.Sprintf("Failed to save annotations: %v", err), http.StatusInternalServerError)
+			return
+		}
+		result = &a
+
+	case http.MethodDelete:
+		removed := annotations[index]
+		annotations = append(annotations[:index], annotations[index+1:]...)
+		if err := saveAnnotations(taskDir, annotations); err != nil {
+			http.Error(w, fmt.Sprintf("Failed to save annotations: %v", err), http.StatusInternalServerError)
+			return
+		}
+		result = removed
+
+	default:
+		w.Header().Set("Allow", "GET, POST, PUT, DELETE")
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	json.NewEncoder(w).Encode(result)
+}
+
+// handleFlameGraphSearch returns the frames of a flame graph whose name
+// matches a regular expression, with their call paths.
+func (s *Server) handleFlameGraphSearch(w http.ResponseWriter, r *http.Request) {
+	var req flameSearchRequest
+	if err := decodeQuery(r, &req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	fgType, ok := ParseFlameGraphType(req.Type)
+	if !ok {
+		http.Error(w, "Unknown flame graph type: "+req.Type, http.StatusBadRequest)
+		return
+	}
+	pattern := req.Pattern
+	if req.IgnoreCase {
+		pattern = "(?i)" + pattern
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		http.Error(w, "Invalid pattern: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	limit := req.Limit
+	if limit <= 0 {
+		limit = flameSearchDefaultLimit
+	}
+	limit = min(limit, flameSearchMaxLimit)
+
+	taskID := s.resolveTask(req.Task)
+	fg, err := s.fgService.GetFlameGraph(r.Context(), taskID, fgType)
+	if err != nil {
+		http.Error(w, "Failed to load flame graph: "+err.Error(), http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	json.NewEncoder(w).Encode(fg.Search(re, limit))
+}
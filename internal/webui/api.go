@@ -0,0 +1,701 @@
+package webui
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/perf-analysis/internal/flamegraph"
+	"github.com/perf-analysis/internal/parser/hprof"
+	"github.com/perf-analysis/internal/parser/speedscope"
+	"github.com/perf-analysis/pkg/model"
+)
+
+// API prefixes. Every JSON route is served under both; the unversioned prefix
+// is kept for existing clients and the embedded frontend.
+const (
+	apiPrefix   = "/api"
+	apiV1Prefix = "/api/v1"
+)
+
+// apiRoute describes one JSON API endpoint. The route table drives both mux
+// registration and the OpenAPI document served at /api/openapi.json.
+type apiRoute struct {
+	Method  string
+	Path    string // relative to the API prefix, e.g. "/refgraph/info"
+	Tag     string
+	Summary string
+	// Request is the zero value of the query parameter struct (query tags), or nil.
+	Request any
+	// Body is the zero value of the JSON request body type, or nil.
+	Body any
+	// Response is the zero value of the response type, or nil for free-form JSON
+	// read from analysis output files.
+	Response any
+	// TableExport marks routes that also serve text/csv and text/tab-separated-values.
+	TableExport bool
+	// Recompute marks routes that run expensive computations on demand, drop
+	// cached results or modify task data; they are disabled in read-only mode.
+	Recompute bool
+	// Volatile marks routes whose responses do not derive from task artifacts
+	// alone; they are served with Cache-Control: no-store instead of ETags.
+	Volatile bool
+	Handler  http.HandlerFunc
+}
+
+// apiRoutes returns the JSON API route table.
+func (s *Server) apiRoutes() []apiRoute {
+	return []apiRoute{
+		{Method: http.MethodGet, Path: "/tasks", Tag: "tasks", Summary: "List analysis tasks with their metadata, newest first",
+			Request: tasksRequest{}, Response: []TaskInfo{}, Volatile: true, Handler: s.handleListTasks},
+		{Method: http.MethodGet, Path: "/tasks/meta", Tag: "tasks", Summary: "Task metadata (name, tags, source host, dump time, notes)",
+			Request: taskMetaRequest{}, Response: TaskMeta{}, Volatile: true, Handler: s.handleTaskMeta},
+		{Method: http.MethodPut, Path: "/tasks/meta", Tag: "tasks", Summary: "Replace task metadata",
+			Request: taskMetaRequest{}, Body: TaskMeta{}, Response: TaskMeta{}, Recompute: true, Volatile: true, Handler: s.handleTaskMeta},
+		{Method: http.MethodPatch, Path: "/tasks/meta", Tag: "tasks", Summary: "Update task metadata fields and add or remove tags",
+			Request: taskMetaRequest{}, Body: TaskMetaPatch{}, Response: TaskMeta{}, Recompute: true, Volatile: true, Handler: s.handleTaskMeta},
+		{Method: http.MethodDelete, Path: "/tasks/meta", Tag: "tasks", Summary: "Remove task metadata",
+			Request: taskMetaRequest{}, Response: TaskMeta{}, Recompute: true, Volatile: true, Handler: s.handleTaskMeta},
+		{Method: http.MethodGet, Path: "/job", Tag: "tasks", Summary: "Analysis job status with stage timings",
+			Request: taskRequest{}, Response: hprof.JobStatus{}, Handler: s.handleJobStatus},
+		{Method: http.MethodGet, Path: "/progress", Tag: "tasks", Summary: "Analysis progress as a text/event-stream of progress events",
+			Request: taskRequest{}, Response: ProgressEvent{}, Volatile: true, Handler: s.handleProgress},
+		{Method: http.MethodGet, Path: "/summary", Tag: "tasks", Summary: "Analysis summary (summary.json)",
+			Request: taskRequest{}, Handler: s.handleSummary},
+		{Method: http.MethodGet, Path: "/logs", Tag: "tasks", Summary: "Last lines of the analyzer log",
+			Request: logRequest{}, Response: LogEvent{}, Volatile: true, Handler: s.handleLogs},
+		{Method: http.MethodGet, Path: "/logs/stream", Tag: "tasks", Summary: "Live tail of the analyzer log over a WebSocket, as LogEvent text messages",
+			Request: logRequest{}, Response: LogEvent{}, Volatile: true, Handler: s.handleLogStream},
+
+		{Method: http.MethodGet, Path: "/flamegraph", Tag: "profiles", Summary: "Flame graph data",
+			Request: flameViewRequest{}, Handler: s.handleFlameGraph},
+		{Method: http.MethodGet, Path: "/flamegraph/diff", Tag: "profiles", Summary: "Differential flame graph of two tasks, with the most changed functions",
+			Request: flameDiffRequest{}, Response: FlameGraphDiffResponse{}, Handler: s.handleFlameGraphDiff},
+		{Method: http.MethodGet, Path: "/flamegraph/search", Tag: "profiles", Summary: "Frames of a flame graph matching a regular expression, with their call paths and cumulative weight",
+			Request: flameSearchRequest{}, Response: flamegraph.FrameSearchResult{}, Handler: s.handleFlameGraphSearch},
+		{Method: http.MethodGet, Path: "/flamegraph/speedscope", Tag: "profiles", Summary: "Flame graph as a speedscope file, for https://www.speedscope.app",
+			Request: flameViewRequest{}, Response: speedscope.File{}, Handler: s.handleFlameGraphSpeedscope},
+		{Method: http.MethodGet, Path: "/flamegraph/trace", Tag: "profiles", Summary: "Flame graph as Chrome trace events, for https://ui.perfetto.dev",
+			Request: flameViewRequest{}, Response: flamegraph.TraceFile{}, Handler: s.handleFlameGraphTrace},
+		{Method: http.MethodGet, Path: "/annotations", Tag: "profiles", Summary: "Notes attached to flame graph frames of a task, pinned first",
+			Request: annotationRequest{}, Response: []FrameAnnotation{}, Volatile: true, Handler: s.handleAnnotations},
+		{Method: http.MethodPost, Path: "/annotations", Tag: "profiles", Summary: "Attach a note to a flame graph frame",
+			Request: annotationRequest{}, Body: FrameAnnotation{}, Response: FrameAnnotation{}, Recompute: true, Volatile: true, Handler: s.handleAnnotations},
+		{Method: http.MethodPut, Path: "/annotations", Tag: "profiles", Summary: "Update the note and pinning of a frame annotation",
+			Request: annotationRequest{}, Body: FrameAnnotation{}, Response: FrameAnnotation{}, Recompute: true, Volatile: true, Handler: s.handleAnnotations},
+		{Method: http.MethodDelete, Path: "/annotations", Tag: "profiles", Summary: "Remove a frame annotation",
+			Request: annotationRequest{}, Response: FrameAnnotation{}, Recompute: true, Volatile: true, Handler: s.handleAnnotations},
+		{Method: http.MethodGet, Path: "/callgraph", Tag: "profiles", Summary: "Call graph data",
+			Request: flameGraphRequest{}, Handler: s.handleCallGraph},
+		{Method: http.MethodGet, Path: "/pprof/leak-report", Tag: "profiles", Summary: "pprof leak detection reports, with growing goroutine clusters",
+			Request: leakReportRequest{}, Response: LeakReportResponse{}, Handler: s.handlePProfLeakReport},
+		{Method: http.MethodGet, Path: "/pprof/batch-analysis", Tag: "profiles", Summary: "Complete pprof batch analysis result",
+			Request: taskRequest{}, Handler: s.handlePProfBatchAnalysis},
+		{Method: http.MethodGet, Path: "/threaddump", Tag: "profiles", Summary: "Java thread dump analysis: thread states, deadlocks and hot monitors",
+			Request: taskRequest{}, Response: model.ThreadDumpData{}, Handler: s.handleThreadDump},
+		{Method: http.MethodGet, Path: "/memory-story", Tag: "profiles", Summary: "Allocation sites of a task linked to its heap dump classes and CPU hotspots",
+			Request: taskRequest{}, Response: MemoryStoryResponse{}, Handler: s.handleMemoryStory},
+		{Method: http.MethodGet, Path: "/timeline", Tag: "profiles", Summary: "Values over time of the top functions of a timestamped profile",
+			Request: timelineRequest{}, Response: TimelineResponse{}, Handler: s.handleTimeline},
+		{Method: http.MethodGet, Path: "/flamegraph/window", Tag: "profiles", Summary: "Flame graph of a time window of a timestamped profile",
+			Request: flameWindowRequest{}, Handler: s.handleFlameGraphWindow},
+
+		{Method: http.MethodGet, Path: "/retainers", Tag: "heap", Summary: "Class retainer analysis",
+			Request: tableRequest{}, TableExport: true, Handler: s.handleRetainers},
+		{Method: http.MethodGet, Path: "/biggest-objects", Tag: "heap", Summary: "Biggest objects from the analysis report",
+			Request: biggestObjectsRequest{}, Handler: s.handleBiggestObjects},
+		{Method: http.MethodGet, Path: "/class-histogram", Tag: "heap", Summary: "Class histogram",
+			Request: tableRequest{}, TableExport: true, Handler: s.handleClassHistogram},
+		{Method: http.MethodGet, Path: "/classes", Tag: "heap", Summary: "Paged, sorted and filtered class histogram with package rollup",
+			Request: classesRequest{}, Response: ClassesPage{}, Handler: s.handleClasses},
+		{Method: http.MethodGet, Path: "/classes/columns", Tag: "heap", Summary: "Whole class histogram as streamed column arrays",
+			Request: classColumnsRequest{}, Response: ClassColumns{}, Handler: s.handleClassColumns},
+		{Method: http.MethodGet, Path: "/heap/diff", Tag: "heap", Summary: "Class-level comparison of two heap analysis tasks",
+			Request: heapDiffRequest{}, Response: HeapDiffResponse{}, TableExport: true, Recompute: true, Handler: s.handleHeapDiff},
+		{Method: http.MethodGet, Path: "/heap/diff/objects", Tag: "heap", Summary: "Object-level comparison of the retained sizes of two heap analysis tasks, with incrementally updated dominators",
+			Request: heapObjectDiffRequest{}, Response: HeapObjectDiffResponse{}, Recompute: true, Handler: s.handleHeapObjectDiff},
+		{Method: http.MethodGet, Path: "/heap/threads", Tag: "heap", Summary: "Thread overview with stack frames and stack locals",
+			Request: taskRequest{}, Response: hprof.ThreadOverview{}, Handler: s.handleHeapThreads},
+		{Method: http.MethodGet, Path: "/dominator-tree", Tag: "heap", Summary: "Top slice of the dominator tree",
+			Request: tableRequest{}, TableExport: true, Handler: s.handleDominatorTree},
+		{Method: http.MethodGet, Path: "/heap/ownership", Tag: "heap", Summary: "Retained memory by the teams owning package prefixes",
+			Request: tableRequest{}, Response: hprof.MemoryOwnership{}, TableExport: true, Handler: s.handleHeapOwnership},
+
+		{Method: http.MethodGet, Path: "/refgraph/fields", Tag: "refgraph", Summary: "Fields of an object",
+			Request: objectRequest{}, Response: []ObjectFieldResponse{}, Handler: s.handleRefGraphFields},
+		{Method: http.MethodGet, Path: "/refgraph/object", Tag: "refgraph", Summary: "Field values or array elements of an object, read from the heap dump",
+			Request: objectContentRequest{}, Response: ObjectContentResponse{}, Handler: s.handleRefGraphObjectContent},
+		{Method: http.MethodGet, Path: "/refgraph/payload", Tag: "refgraph", Summary: "Raw contents of a primitive array or String as hex and text, or as a download",
+			Request: objectPayloadRequest{}, Response: ObjectPayloadResponse{}, Handler: s.handleRefGraphObjectPayload},
+		{Method: http.MethodGet, Path: "/refgraph/class", Tag: "refgraph", Summary: "Hierarchy, fields and static field values of a class",
+			Request: classMetadataRequest{}, Response: ClassMetadataResponse{}, Handler: s.handleRefGraphClass},
+		{Method: http.MethodGet, Path: "/refgraph/info", Tag: "refgraph", Summary: "Basic information about an object",
+			Request: objectRequest{}, Response: ObjectInfoResponse{}, Handler: s.handleRefGraphObjectInfo},
+		{Method: http.MethodGet, Path: "/refgraph/gc-roots", Tag: "refgraph", Summary: "Paths from GC roots to an object",
+			Request: gcRootPathsRequest{}, Response: []hprof.GCRootPath{}, Handler: s.handleRefGraphGCRoots},
+		{Method: http.MethodGet, Path: "/refgraph/gc-roots-summary", Tag: "refgraph", Summary: "GC roots grouped by class",
+			Request: taskRequest{}, Handler: s.handleRefGraphGCRootsSummary},
+		{Method: http.MethodGet, Path: "/refgraph/gc-roots-list", Tag: "refgraph", Summary: "All GC roots by retained size",
+			Request: taskRequest{}, Response: []*hprof.GCRootInfo{}, Handler: s.handleRefGraphGCRootsList},
+		{Method: http.MethodGet, Path: "/refgraph/gc-root-retained", Tag: "refgraph", Summary: "Objects exclusively retained through a GC root, paged",
+			Request: gcRootRetainedRequest{}, Response: GCRootRetainedPage{}, Recompute: true, Handler: s.handleRefGraphGCRootRetained},
+		{Method: http.MethodGet, Path: "/refgraph/retainers", Tag: "refgraph", Summary: "Objects referencing an object",
+			Request: objectLimitRequest{}, Response: []*ObjectRetainerInfo{}, Handler: s.handleRefGraphRetainers},
+		{Method: http.MethodGet, Path: "/refgraph/biggest-by-class", Tag: "refgraph", Summary: "Biggest instances of a class",
+			Request: classObjectsRequest{}, Response: []ClassObjectResponse{}, Handler: s.handleRefGraphBiggestByClass},
+		{Method: http.MethodGet, Path: "/refgraph/instances", Tag: "refgraph", Summary: "All instances of a class by size, cursor-paged",
+			Request: classInstancesRequest{}, Response: ClassInstancesResponse{}, Handler: s.handleRefGraphInstances},
+		{Method: http.MethodGet, Path: "/refgraph/dominator-retainers", Tag: "refgraph", Summary: "Classes whose instances dominate the instances of a class",
+			Request: classRetainersRequest{}, Response: hprof.ClassRetainers{}, Recompute: true, Handler: s.handleRefGraphDominatorRetainers},
+		{Method: http.MethodGet, Path: "/refgraph/manifest", Tag: "refgraph", Summary: "Chunk manifest of refgraph.bin and top classes",
+			Request: manifestRequest{}, Response: RefGraphManifest{}, Handler: s.handleRefGraphManifest},
+		{Method: http.MethodGet, Path: "/refgraph/reachability", Tag: "refgraph", Summary: "Whether each object of a set is reachable from each object of another",
+			Request: reachabilityRequest{}, Response: ReachabilityResponse{}, Recompute: true, Handler: s.handleRefGraphReachability},
+
+		{Method: http.MethodGet, Path: "/domtree/children", Tag: "domtree", Summary: "Objects immediately dominated by an object",
+			Request: domTreeChildrenRequest{}, Response: []*hprof.DominatorTreeNode{}, Handler: s.handleDomTreeChildren},
+		{Method: http.MethodGet, Path: "/domtree/chain", Tag: "domtree", Summary: "Dominator chain from the top level down to an object",
+			Request: objectRequest{}, Response: []*hprof.DominatorTreeNode{}, Handler: s.handleDomTreeChain},
+		{Method: http.MethodGet, Path: "/domtree/treemap", Tag: "domtree", Summary: "Depth-limited retained-size treemap of the heap or of a dominator subtree",
+			Request: domTreeTreemapRequest{}, Response: hprof.TreemapNode{}, Recompute: true, Handler: s.handleDomTreeTreemap},
+		{Method: http.MethodGet, Path: "/domtree/histogram", Tag: "domtree", Summary: "Class histogram of the objects retained by an object",
+			Request: domTreeHistogramRequest{}, Response: hprof.SubtreeHistogram{}, Recompute: true, Handler: s.handleDomTreeHistogram},
+		{Method: http.MethodGet, Path: "/domtree/retained-set", Tag: "domtree", Summary: "Retained set of a selection of objects",
+			Request: retainedSetRequest{}, Response: hprof.RetainedSet{}, Recompute: true, Handler: s.handleDomTreeRetainedSet},
+
+		{Method: http.MethodGet, Path: "/query", Tag: "query", Summary: "Run an OQL-style query against the heap snapshot",
+			Request: queryRequest{}, Response: QueryResponse{}, Recompute: true, Handler: s.handleQuery},
+		{Method: http.MethodGet, Path: "/query/retained-set", Tag: "query", Summary: "Retained set of all objects matched by a query",
+			Request: queryRequest{}, Response: hprof.RetainedSet{}, Recompute: true, Handler: s.handleQueryRetainedSet},
+
+		{Method: http.MethodGet, Path: "/admin/cache", Tag: "admin", Summary: "Heap snapshot cache statistics",
+			Response: SnapshotCacheStats{}, Volatile: true, Handler: s.handleAdminCache},
+		{Method: http.MethodDelete, Path: "/admin/cache", Tag: "admin", Summary: "Flush the snapshot cache or evict one task",
+			Request: taskRequest{}, Response: CacheFlushResponse{}, Recompute: true, Volatile: true, Handler: s.handleAdminCache},
+	}
+}
+
+// registerAPIRoutes registers the route table under /api and /api/v1.
+// Routes sharing a path (different methods) share one handler. Every route
+// goes through the compression and ETag middleware.
+func (s *Server) registerAPIRoutes(mux *http.ServeMux) {
+	routes := s.apiRoutes()
+
+	// Methods disabled in read-only mode, by path
+	recompute := make(map[string]map[string]bool)
+	for _, route := range routes {
+		if !route.Recompute {
+			continue
+		}
+		if recompute[route.Path] == nil {
+			recompute[route.Path] = make(map[string]bool)
+		}
+		recompute[route.Path][route.Method] = true
+	}
+
+	registered := make(map[string]bool)
+	for _, route := range routes {
+		if registered[route.Path] {
+			continue
+		}
+		registered[route.Path] = true
+		handler := route.Handler
+		if methods := recompute[route.Path]; methods != nil {
+			handler = s.withReadOnly(methods, handler)
+		}
+		handler = s.withCompression(s.withETag(route, handler))
+		mux.HandleFunc(apiPrefix+route.Path, handler)
+		mux.HandleFunc(apiV1Prefix+route.Path, handler)
+	}
+	mux.HandleFunc(apiPrefix+"/openapi.json", s.handleOpenAPI)
+}
+
+// resolveTask returns taskID, or the most recent task when it is empty.
+func (s *Server) resolveTask(taskID string) string {
+	if taskID == "" {
+		return s.getDefaultTask()
+	}
+	return taskID
+}
+
+// Request types. Fields are bound from query parameters by decodeQuery;
+// the query, required and doc tags also describe the parameters in OpenAPI.
+
+// taskRequest selects a task.
+type taskRequest struct {
+	Task string `query:"task" doc:"Task ID; defaults to the most recent task"`
+}
+
+// tasksRequest filters the task list.
+type tasksRequest struct {
+	Tag   string `query:"tag" doc:"Only tasks with this tag (case-insensitive)"`
+	Query string `query:"q" doc:"Only tasks whose ID, name, source host, notes or tags contain this text"`
+}
+
+// logRequest selects the analyzer log of a task.
+type logRequest struct {
+	taskRequest
+	Lines int `query:"lines" doc:"Number of trailing lines to return first (default 500, max 10000)"`
+}
+
+// taskMetaRequest names the task whose metadata is read or edited.
+type taskMetaRequest struct {
+	Task string `query:"task" required:"true" doc:"Task ID"`
+}
+
+// objectRequest selects an object of a task.
+type objectRequest struct {
+	taskRequest
+	ID string `query:"id" required:"true" doc:"Object ID, hex with or without 0x"`
+}
+
+// classMetadataRequest selects a class by name or by class object ID.
+type classMetadataRequest struct {
+	taskRequest
+	Class string `query:"class" doc:"Fully qualified class name"`
+	ID    string `query:"id" doc:"Class object ID, hex with or without 0x; takes precedence over class"`
+}
+
+// objectContentRequest selects an object and limits the number of array elements.
+type objectContentRequest struct {
+	objectRequest
+	MaxElements int `query:"max_elements" doc:"Maximum number of array elements (default 100)"`
+}
+
+// objectPayloadRequest selects a window of the contents of an array or String.
+type objectPayloadRequest struct {
+	objectRequest
+	Offset   int64 `query:"offset" doc:"Byte offset of the window"`
+	Limit    int   `query:"limit" doc:"Maximum number of bytes (default 4096)"`
+	Download bool  `query:"download" doc:"Return the whole contents as application/octet-stream"`
+}
+
+// objectLimitRequest selects an object and limits the number of results.
+type objectLimitRequest struct {
+	objectRequest
+	Max int `query:"max" doc:"Maximum number of results"`
+}
+
+// gcRootRetainedRequest selects one page of the objects retained by a GC root.
+type gcRootRetainedRequest struct {
+	objectLimitRequest
+	After string `query:"after" doc:"Cursor: the next field of the previous page"`
+}
+
+// gcRootPathsRequest selects the paths from GC roots to an object.
+type gcRootPathsRequest struct {
+	objectRequest
+	MaxPaths int `query:"max_paths" doc:"Maximum number of paths (default 3)"`
+	MaxDepth int `query:"max_depth" doc:"Maximum path length (default 15)"`
+
+	ExcludeWeak    bool `query:"exclude_weak" doc:"Do not follow WeakReference referents"`
+	ExcludeSoft    bool `query:"exclude_soft" doc:"Do not follow SoftReference referents"`
+	ExcludePhantom bool `query:"exclude_phantom" doc:"Do not follow PhantomReference and FinalReference referents"`
+}
+
+// exclusion returns the Reference kinds the request excludes.
+func (r gcRootPathsRequest) exclusion() hprof.PathExclusion {
+	var exclude hprof.PathExclusion
+	if r.ExcludeWeak {
+		exclude |= hprof.ExcludeWeakRefs
+	}
+	if r.ExcludeSoft {
+		exclude |= hprof.ExcludeSoftRefs
+	}
+	if r.ExcludePhantom {
+		exclude |= hprof.ExcludePhantomRefs
+	}
+	return exclude
+}
+
+// classObjectsRequest selects the biggest instances of a class.
+type classObjectsRequest struct {
+	taskRequest
+	Class string `query:"class" required:"true" doc:"Fully qualified class name"`
+	Top   int    `query:"top" doc:"Number of objects (default 50)"`
+	Sort  string `query:"sort" doc:"Sort order: retained (default) or shallow"`
+}
+
+// classInstancesRequest selects one page of the instances of a class.
+type classInstancesRequest struct {
+	taskRequest
+	Class string `query:"class" required:"true" doc:"Fully qualified class name"`
+	Sort  string `query:"sort" doc:"Sort order: retained (default) or shallow"`
+	After string `query:"after" doc:"Cursor returned as next by the previous page"`
+	Limit int    `query:"limit" doc:"Number of instances (default 100, max 1000)"`
+}
+
+// classRetainersRequest selects the retainers of a class.
+type classRetainersRequest struct {
+	taskRequest
+	Class string `query:"class" required:"true" doc:"Fully qualified class name"`
+	Top   int    `query:"top" doc:"Number of retainer classes (default 20)"`
+}
+
+// manifestRequest selects the refgraph manifest of a task.
+type manifestRequest struct {
+	taskRequest
+	Top int `query:"top" doc:"Number of top classes by retained size (default 50)"`
+}
+
+// classesRequest selects one page of the full class histogram.
+type classesRequest struct {
+	taskRequest
+	Offset int    `query:"offset" doc:"Index of the first row"`
+	Limit  int    `query:"limit" doc:"Number of rows (default 200, max 1000)"`
+	Sort   string `query:"sort" doc:"Sort key: shallow (default), retained, count or name"`
+	Order  string `query:"order" doc:"Sort order: desc (default) or asc"`
+	Filter string `query:"filter" doc:"Regular expression matched against class names"`
+	Loader string `query:"loader" doc:"Restrict to the classes defined by a class loader ID, or bootstrap"`
+	Group  string `query:"group" doc:"Set to package or loader to roll classes up by package or defining class loader"`
+}
+
+// classColumnsRequest selects the columns of the whole class histogram.
+type classColumnsRequest struct {
+	taskRequest
+	Sort    string `query:"sort" doc:"Sort key: shallow (default), retained, count or name"`
+	Order   string `query:"order" doc:"Sort order: desc (default) or asc"`
+	Filter  string `query:"filter" doc:"Regular expression matched against class names"`
+	Loader  string `query:"loader" doc:"Restrict to the classes defined by a class loader ID, or bootstrap"`
+	Columns string `query:"columns" doc:"Comma-separated columns: class_name, instance_count, total_size, retained_size (default all)"`
+}
+
+// heapDiffRequest selects the two tasks of a heap comparison.
+type heapDiffRequest struct {
+	Base             string `query:"base" required:"true" doc:"Base (older) task ID"`
+	Target           string `query:"target" required:"true" doc:"Target (newer) task ID"`
+	IncludeUnchanged bool   `query:"include_unchanged" doc:"Also list classes that did not change"`
+	Format           string `query:"format" doc:"csv or tsv; JSON unless the Accept header asks for a table"`
+}
+
+// heapObjectDiffRequest selects the two tasks of an object-level heap
+// comparison.
+type heapObjectDiffRequest struct {
+	Base             string `query:"base" required:"true" doc:"Base (older) task ID"`
+	Target           string `query:"target" required:"true" doc:"Target (newer) task ID"`
+	IncludeUnchanged bool   `query:"include_unchanged" doc:"Also list objects whose retained size did not change"`
+	Top              int    `query:"top" doc:"Number of most changed objects (default 100, max 1000)"`
+}
+
+// domTreeChildrenRequest selects one level of the dominator tree.
+type domTreeChildrenRequest struct {
+	taskRequest
+	ID    string `query:"id" doc:"Dominator object ID; the top level when empty"`
+	Limit int    `query:"limit" doc:"Maximum number of children (default 100)"`
+}
+
+// domTreeTreemapRequest selects a treemap of the dominator tree.
+type domTreeTreemapRequest struct {
+	taskRequest
+	ID         string  `query:"id" doc:"Root object ID; the whole heap when empty"`
+	Depth      int     `query:"depth" doc:"Levels below the root (default 4, max 8)"`
+	Children   int     `query:"children" doc:"Maximum children per node before aggregating the rest (default 20, max 200)"`
+	MinPercent float64 `query:"min_percent" doc:"Children under this percentage of their parent's retained size are aggregated (default 0.5)"`
+}
+
+// domTreeHistogramRequest selects the dominator subtree of an object.
+type domTreeHistogramRequest struct {
+	taskRequest
+	ID  string `query:"id" required:"true" doc:"Object ID, hex with or without 0x"`
+	Top int    `query:"top" doc:"Number of classes by shallow size (default 50)"`
+}
+
+// retainedSetRequest selects a set of objects.
+type retainedSetRequest struct {
+	taskRequest
+	IDs []string `query:"ids" required:"true" doc:"Comma-separated object IDs"`
+}
+
+// reachabilityRequest selects the objects to check reachability between.
+type reachabilityRequest struct {
+	taskRequest
+	From []string `query:"from" required:"true" doc:"Comma-separated object IDs to start from"`
+	To   []string `query:"to" required:"true" doc:"Comma-separated object IDs to reach"`
+}
+
+// queryRequest is an OQL-style query against a task's heap snapshot.
+type queryRequest struct {
+	taskRequest
+	Query string `query:"q" required:"true" doc:"Query, e.g. SELECT * FROM java.lang.String s WHERE s.@retainedHeapSize > 1024"`
+	Limit int    `query:"limit" doc:"Maximum number of rows returned (default 1000)"`
+}
+
+// flameGraphRequest selects a flame or call graph of a task.
+type flameGraphRequest struct {
+	taskRequest
+	Type string `query:"type" doc:"Graph type, e.g. cpu, memory, tracing, pprof-goroutine"`
+}
+
+// flameViewRequest selects a flame graph of a task and how to show it.
+type flameViewRequest struct {
+	flameGraphRequest
+	CollapseInlined bool `query:"collapse_inlined" doc:"Merge the frames inlined by the JIT (async-profiler _[i] frames) into their caller"`
+}
+
+// flameDiffRequest selects the flame graphs of two tasks to compare.
+type flameDiffRequest struct {
+	Base   string `query:"base" required:"true" doc:"Base (older) task ID"`
+	Target string `query:"target" required:"true" doc:"Target (newer) task ID"`
+	Type   string `query:"type" doc:"Graph type, e.g. cpu, memory, tracing, pprof-goroutine"`
+	Top    int    `query:"top" doc:"Number of most changed functions (default 100, max 1000)"`
+}
+
+// flameSearchRequest searches the frames of a flame graph of a task.
+type flameSearchRequest struct {
+	taskRequest
+	Type       string `query:"type" doc:"Graph type, e.g. cpu, memory, tracing, pprof-goroutine"`
+	Pattern    string `query:"pattern" required:"true" doc:"Regular expression (RE2) matched against frame names"`
+	IgnoreCase bool   `query:"ignore_case" doc:"Match case-insensitively"`
+	Limit      int    `query:"limit" doc:"Number of heaviest matching frames to return (default 50, max 1000)"`
+}
+
+// annotationRequest selects the frame annotations of a task.
+type annotationRequest struct {
+	Task string `query:"task" required:"true" doc:"Task ID"`
+	ID   int    `query:"id" doc:"Annotation ID, for updates and removals"`
+}
+
+// timelineRequest selects the timeline of a profile of a task.
+type timelineRequest struct {
+	taskRequest
+	Type string `query:"type" doc:"Profile: cpu (default), memory or lock"`
+	Top  int    `query:"top" doc:"Number of top functions (default 10, max 50)"`
+}
+
+// flameWindowRequest selects a time window of a profile of a task.
+type flameWindowRequest struct {
+	taskRequest
+	Type string  `query:"type" doc:"Profile: cpu (default), memory or lock"`
+	From float64 `query:"from" doc:"Start of the window, in seconds since the start of the timeline"`
+	To   float64 `query:"to" doc:"End of the window, in seconds since the start of the timeline; the end of the timeline if 0"`
+}
+
+// tableRequest selects an analysis table, optionally as CSV/TSV.
+type tableRequest struct {
+	taskRequest
+	Format string `query:"format" doc:"csv or tsv; JSON unless the Accept header asks for a table"`
+}
+
+// biggestObjectsRequest filters the biggest objects of the analysis report.
+type biggestObjectsRequest struct {
+	taskRequest
+	Class string `query:"class" doc:"Only objects of this class"`
+	Sort  string `query:"sort" doc:"Sort order: retained (default) or shallow"`
+}
+
+// leakReportRequest selects pprof leak reports.
+type leakReportRequest struct {
+	taskRequest
+	Type string `query:"type" doc:"Leak report type; all reports when empty"`
+}
+
+// Response types.
+
+// TaskInfo describes an analysis task directory.
+type TaskInfo struct {
+	ID        string         `json:"id"`
+	CreatedAt string         `json:"created_at"`
+	HasData   bool           `json:"has_data"`
+	JobState  hprof.JobState `json:"job_state,omitempty"`
+	Meta      *TaskMeta      `json:"meta,omitempty"`
+	// MemoryStory is set if the task has two of a CPU profile, an
+	// allocation profile and a heap dump, linked by /memory-story
+	MemoryStory bool `json:"memory_story,omitempty"`
+	// Timelines are the profiles of the task with a timeline, served by
+	// /timeline and /flamegraph/window
+	Timelines []string `json:"timelines,omitempty"`
+	// Health is the health score of summary.json, if rated
+	Health *model.HealthScore `json:"health,omitempty"`
+}
+
+// ObjectFieldResponse is a field of an object, with the referenced object ID as a hex string.
+type ObjectFieldResponse struct {
+	Name         string      `json:"name"`
+	Type         string      `json:"type"`
+	Value        interface{} `json:"value,omitempty"`
+	RefID        string      `json:"ref_id,omitempty"`
+	RefClass     string      `json:"ref_class,omitempty"`
+	ShallowSize  int64       `json:"shallow_size,omitempty"`
+	RetainedSize int64       `json:"retained_size,omitempty"`
+	HasChildren  bool        `json:"has_children"`
+	// StringValue previews the text of a referenced java.lang.String
+	StringValue *string `json:"string_value,omitempty"`
+}
+
+// ObjectContentResponse holds the field values or array elements of an object.
+type ObjectContentResponse struct {
+	ObjectID        string                `json:"object_id"`
+	ClassName       string                `json:"class_name"`
+	Kind            string                `json:"kind"`
+	ShallowSize     int64                 `json:"shallow_size"`
+	RetainedSize    int64                 `json:"retained_size"`
+	Fields          []ObjectFieldResponse `json:"fields,omitempty"`
+	ElementType     string                `json:"element_type,omitempty"`
+	Length          int                   `json:"length"`
+	Elements        []ObjectFieldResponse `json:"elements,omitempty"`
+	Truncated       bool                  `json:"truncated,omitempty"`
+	StringValue     *string               `json:"string_value,omitempty"`
+	StringTruncated bool                  `json:"string_truncated,omitempty"`
+}
+
+// ObjectPayloadResponse holds a window of the raw contents of a primitive
+// array or String.
+type ObjectPayloadResponse struct {
+	ObjectID    string `json:"object_id"`
+	ArrayID     string `json:"array_id"`
+	ClassName   string `json:"class_name"`
+	ElementType string `json:"element_type"`
+	Size        int64  `json:"size"`
+	Offset      int64  `json:"offset"`
+	Length      int    `json:"length"`
+	Hex         string `json:"hex"`
+	Text        string `json:"text"`
+	Truncated   bool   `json:"truncated,omitempty"`
+}
+
+// ClassMetadataResponse describes a class, with string class and object IDs.
+type ClassMetadataResponse struct {
+	ClassID       string                `json:"class_id"`
+	ClassName     string                `json:"class_name"`
+	ClassLoaderID string                `json:"class_loader_id,omitempty"`
+	InstanceSize  int                   `json:"instance_size"`
+	InstanceCount int64                 `json:"instance_count"`
+	ShallowSize   int64                 `json:"shallow_size"`
+	RetainedSize  int64                 `json:"retained_size"`
+	Superclasses  []ClassRefResponse    `json:"superclasses,omitempty"`
+	Subclasses    []ClassRefResponse    `json:"subclasses,omitempty"`
+	Fields        []*hprof.ClassField   `json:"fields,omitempty"`
+	StaticFields  []ObjectFieldResponse `json:"static_fields,omitempty"`
+}
+
+// ClassRefResponse names a class.
+type ClassRefResponse struct {
+	ClassID   string `json:"class_id"`
+	ClassName string `json:"class_name"`
+}
+
+// ObjectInfoResponse is basic information about an object.
+type ObjectInfoResponse struct {
+	ObjectID     string `json:"object_id"`
+	ClassName    string `json:"class_name"`
+	ShallowSize  int64  `json:"shallow_size"`
+	RetainedSize int64  `json:"retained_size"`
+	HasChildren  bool   `json:"has_children"`
+}
+
+// ClassObjectResponse is an instance of a class with its sizes.
+type ClassObjectResponse struct {
+	ObjectID     string `json:"object_id"`
+	ClassName    string `json:"class_name"`
+	ShallowSize  int64  `json:"shallow_size"`
+	RetainedSize int64  `json:"retained_size"`
+}
+
+// ClassInstancesResponse is one page of the instances of a class.
+type ClassInstancesResponse struct {
+	ClassName string             `json:"class_name"`
+	Total     int64              `json:"total"`
+	Items     []InstanceResponse `json:"items"`
+	// Next is the cursor of the following page, empty on the last page
+	Next string `json:"next,omitempty"`
+}
+
+// InstanceResponse is an instance of a class with its sizes.
+type InstanceResponse struct {
+	ObjectID     string `json:"object_id"`
+	ShallowSize  int64  `json:"shallow_size"`
+	RetainedSize int64  `json:"retained_size"`
+	Reachable    bool   `json:"reachable"`
+}
+
+// ReachabilityResponse reports whether each object of To is reachable from
+// each object of From: Reachable[i][j] is set when To[j] is reachable from
+// From[i].
+type ReachabilityResponse struct {
+	From      []string `json:"from"`
+	To        []string `json:"to"`
+	Reachable [][]bool `json:"reachable"`
+}
+
+// CacheFlushResponse reports the snapshots dropped from the cache.
+type CacheFlushResponse struct {
+	Evicted []string `json:"evicted,omitempty"`
+	Flushed *int     `json:"flushed,omitempty"`
+}
+
+// LeakReportResponse holds pprof leak reports keyed by report type.
+type LeakReportResponse struct {
+	LeakReports map[string]json.RawMessage `json:"leak_reports"`
+}
+
+// decodeQuery binds query parameters to the fields of the struct dst points to,
+// using the query tag as the parameter name. Embedded structs are bound too.
+// Fields keep their current value when a parameter is absent, so callers set
+// defaults before decoding. Slices are read from comma-separated values.
+func decodeQuery(r *http.Request, dst any) error {
+	return decodeQueryValue(r, reflect.ValueOf(dst).Elem())
+}
+
+func decodeQueryValue(r *http.Request, v reflect.Value) error {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.Anonymous && field.Type.Kind() == reflect.Struct {
+			if err := decodeQueryValue(r, v.Field(i)); err != nil {
+				return err
+			}
+			continue
+		}
+
+		name := field.Tag.Get("query")
+		if name == "" {
+			continue
+		}
+		raw := r.URL.Query().Get(name)
+		if raw == "" {
+			if field.Tag.Get("required") == "true" {
+				return fmt.Errorf("parameter %q is required", name)
+			}
+			continue
+		}
+
+		fv := v.Field(i)
+		switch fv.Kind() {
+		case reflect.String:
+			fv.SetString(raw)
+		case reflect.Int, reflect.Int32, reflect.Int64:
+			n, err := strconv.ParseInt(raw, 10, 64)
+			if err != nil {
+				return fmt.Errorf("parameter %q must be an integer", name)
+			}
+			fv.SetInt(n)
+		case reflect.Float32, reflect.Float64:
+			f, err := strconv.ParseFloat(raw, 64)
+			if err != nil {
+				return fmt.Errorf("parameter %q must be a number", name)
+			}
+			fv.SetFloat(f)
+		case reflect.Bool:
+			b, err := strconv.ParseBool(raw)
+			if err != nil {
+				return fmt.Errorf("parameter %q must be a boolean", name)
+			}
+			fv.SetBool(b)
+		case reflect.Slice:
+			var items []string
+			for _, item := range strings.Split(raw, ",") {
+				if item = strings.TrimSpace(item); item != "" {
+					items = append(items, item)
+				}
+			}
+			if len(items) == 0 && field.Tag.Get("required") == "true" {
+				return fmt.Errorf("parameter %q is required", name)
+			}
+			fv.Set(reflect.ValueOf(items))
+		}
+	}
+	return nil
+}
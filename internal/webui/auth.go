@@ -0,0 +1,151 @@
+package webui
+
+import (
+	"crypto/subtle"
+	"encoding/base64"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// Environment variables read by AccessConfigFromEnv.
+const (
+	EnvAuthUser     = "PERF_ANALYSIS_UI_USER"
+	EnvAuthPassword = "PERF_ANALYSIS_UI_PASSWORD"
+	EnvAuthToken    = "PERF_ANALYSIS_UI_TOKEN"
+	EnvReadOnly     = "PERF_ANALYSIS_UI_READ_ONLY"
+)
+
+// authCookieName holds the access token once a browser opened the UI with
+// ?token=, so that fetch and EventSource requests are authorized too.
+const authCookieName = "perf_analysis_token"
+
+// AccessConfig controls who may use the web UI and what they may do.
+// The zero value allows anonymous, unrestricted access.
+type AccessConfig struct {
+	// Username and Password enable HTTP basic auth when Username is set
+	Username string
+	Password string
+	// Token enables bearer token auth: an "Authorization: Bearer" header,
+	// a token query parameter or the cookie set from it
+	Token string
+	// ReadOnly disables endpoints that trigger recomputation or modify task data (see apiRoute.Recompute)
+	ReadOnly bool
+}
+
+// AccessConfigFromEnv loads the access configuration from environment variables.
+func AccessConfigFromEnv() AccessConfig {
+	readOnly := strings.ToLower(os.Getenv(EnvReadOnly))
+	return AccessConfig{
+		Username: os.Getenv(EnvAuthUser),
+		Password: os.Getenv(EnvAuthPassword),
+		Token:    os.Getenv(EnvAuthToken),
+		ReadOnly: readOnly == "true" || readOnly == "1",
+	}
+}
+
+// AuthEnabled reports whether requests must be authenticated.
+func (c AccessConfig) AuthEnabled() bool {
+	return c.Username != "" || c.Token != ""
+}
+
+// SetAccessConfig sets authentication and read-only mode. It must be called before Start.
+func (s *Server) SetAccessConfig(config AccessConfig) {
+	s.access = config
+}
+
+// AccessConfig returns the access configuration of the server.
+func (s *Server) AccessConfig() AccessConfig {
+	return s.access
+}
+
+// withAuth rejects unauthenticated requests when authentication is enabled.
+// Basic auth and token auth are alternatives; either one is sufficient.
+func (s *Server) withAuth(next http.Handler) http.Handler {
+	if !s.access.AuthEnabled() {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if s.authorized(w, r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if s.access.Username != "" {
+			w.Header().Set("WWW-Authenticate", `Basic realm="perf-analysis"`)
+		}
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+	})
+}
+
+// AuthorizedHeader reports whether an Authorization header value holds
+// valid basic auth or bearer token credentials. The gRPC API checks the
+// authorization metadata with it.
+func (c AccessConfig) AuthorizedHeader(header string) bool {
+	if c.Username != "" {
+		if user, password, ok := parseBasicAuth(header); ok &&
+			secureEqual(user, c.Username) && secureEqual(password, c.Password) {
+			return true
+		}
+	}
+	if c.Token != "" {
+		if token, ok := strings.CutPrefix(header, "Bearer "); ok && secureEqual(token, c.Token) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseBasicAuth parses the credentials of a basic auth header value.
+func parseBasicAuth(header string) (user, password string, ok bool) {
+	const prefix = "Basic "
+	if len(header) < len(prefix) || !strings.EqualFold(header[:len(prefix)], prefix) {
+		return "", "", false
+	}
+	decoded, err := base64.StdEncoding.DecodeString(header[len(prefix):])
+	if err != nil {
+		return "", "", false
+	}
+	return strings.Cut(string(decoded), ":")
+}
+
+// authorized checks the request's credentials. A valid ?token= parameter
+// also sets the token cookie for subsequent requests.
+func (s *Server) authorized(w http.ResponseWriter, r *http.Request) bool {
+	if s.access.AuthorizedHeader(r.Header.Get("Authorization")) {
+		return true
+	}
+
+	if s.access.Token == "" {
+		return false
+	}
+	if token := r.URL.Query().Get("token"); token != "" && secureEqual(token, s.access.Token) {
+		http.SetCookie(w, &http.Cookie{
+			Name:     authCookieName,
+			Value:    token,
+			Path:     "/",
+			HttpOnly: true,
+			SameSite: http.SameSiteStrictMode,
+		})
+		return true
+	}
+	if cookie, err := r.Cookie(authCookieName); err == nil && secureEqual(cookie.Value, s.access.Token) {
+		return true
+	}
+	return false
+}
+
+// withReadOnly rejects the given methods of a route in read-only mode.
+func (s *Server) withReadOnly(methods map[string]bool, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if s.access.ReadOnly && methods[r.Method] {
+			http.Error(w, "Disabled in read-only mode", http.StatusForbidden)
+			return
+		}
+		next(w, r)
+	}
+}
+
+// secureEqual compares secrets in constant time.
+func secureEqual(a, b string) bool {
+	return subtle.ConstantTimeCompare([]byte(a), []byte(b)) == 1
+}
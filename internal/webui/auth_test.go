@@ -0,0 +1,134 @@
+package webui
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newAuthTestHandler returns the API of a server over an empty data
+// directory, behind its authentication middleware.
+func newAuthTestHandler(t *testing.T, access AccessConfig) http.Handler {
+	s := NewServer(t.TempDir(), 0, nil)
+	s.SetAccessConfig(access)
+	mux := http.NewServeMux()
+	s.registerAPIRoutes(mux)
+	return s.withAuth(mux)
+}
+
+func TestServer_withAuth(t *testing.T) {
+	handler := newAuthTestHandler(t, AccessConfig{Username: "admin", Password: "secret", Token: "tok"})
+
+	tests := []struct {
+		name      string
+		prepare   func(r *http.Request)
+		wantCode  int
+		challenge bool
+	}{
+		{"missing credentials", func(r *http.Request) {}, http.StatusUnauthorized, true},
+		{"basic auth", func(r *http.Request) { r.SetBasicAuth("admin", "secret") }, http.StatusOK, false},
+		{"wrong password", func(r *http.Request) { r.SetBasicAuth("admin", "wrong") }, http.StatusUnauthorized, true},
+		{"wrong user", func(r *http.Request) { r.SetBasicAuth("root", "secret") }, http.StatusUnauthorized, true},
+		{"bearer token", func(r *http.Request) { r.Header.Set("Authorization", "Bearer tok") }, http.StatusOK, false},
+		{"wrong bearer token", func(r *http.Request) { r.Header.Set("Authorization", "Bearer nope") }, http.StatusUnauthorized, true},
+		{"wrong cookie", func(r *http.Request) { r.AddCookie(&http.Cookie{Name: authCookieName, Value: "nope"}) }, http.StatusUnauthorized, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/api/tasks", nil)
+			tt.prepare(r)
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+			assert.Equal(t, tt.wantCode, w.Code)
+			if tt.challenge {
+				assert.Equal(t, `Basic realm="perf-analysis"`, w.Header().Get("WWW-Authenticate"))
+			}
+		})
+	}
+}
+
+func TestServer_withAuth_TokenCookie(t *testing.T) {
+	handler := newAuthTestHandler(t, AccessConfig{Token: "tok"})
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/tasks?token=tok", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	cookies := w.Result().Cookies()
+	require.Len(t, cookies, 1)
+	assert.Equal(t, authCookieName, cookies[0].Name)
+	assert.True(t, cookies[0].HttpOnly)
+
+	// Later requests of the browser only carry the cookie
+	r := httptest.NewRequest(http.MethodGet, "/api/tasks", nil)
+	r.AddCookie(cookies[0])
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	// Without basic auth configured, no basic auth challenge is sent
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/tasks?token=nope", nil))
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Empty(t, w.Header().Get("WWW-Authenticate"))
+	assert.Empty(t, w.Result().Cookies())
+}
+
+func TestServer_withAuth_Disabled(t *testing.T) {
+	handler := newAuthTestHandler(t, AccessConfig{})
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/tasks", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.False(t, AccessConfig{Password: "secret"}.AuthEnabled())
+}
+
+func TestServer_withReadOnly(t *testing.T) {
+	handler := newAuthTestHandler(t, AccessConfig{ReadOnly: true})
+
+	tests := []struct {
+		method   string
+		target   string
+		disabled bool
+	}{
+		{http.MethodGet, "/api/query?task=t&q=select+*", true},
+		{http.MethodGet, "/api/v1/query/retained-set?task=t", true},
+		{http.MethodDelete, "/api/admin/cache", true},
+		{http.MethodPut, "/api/tasks/meta?task=t", true},
+		{http.MethodPatch, "/api/tasks/meta?task=t", true},
+		{http.MethodDelete, "/api/tasks/meta?task=t", true},
+		{http.MethodGet, "/api/heap/diff?base=a&target=b", true},
+		{http.MethodGet, "/api/heap/diff/objects?base=a&target=b", true},
+		{http.MethodGet, "/api/refgraph/gc-root-retained?task=t&id=0x1", true},
+		{http.MethodGet, "/api/refgraph/dominator-retainers?task=t&class=C", true},
+		{http.MethodGet, "/api/refgraph/reachability?task=t&from=0x1&to=0x2", true},
+		{http.MethodGet, "/api/domtree/treemap?task=t", true},
+		{http.MethodGet, "/api/v1/domtree/histogram?task=t&id=0x1", true},
+		{http.MethodGet, "/api/admin/cache", false},
+		{http.MethodGet, "/api/tasks", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.method+" "+tt.target, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, httptest.NewRequest(tt.method, tt.target, nil))
+			if tt.disabled {
+				assert.Equal(t, http.StatusForbidden, w.Code)
+			} else {
+				assert.Equal(t, http.StatusOK, w.Code)
+			}
+		})
+	}
+}
+
+func TestAccessConfig_AuthorizedHeader(t *testing.T) {
+	config := AccessConfig{Username: "admin", Password: "secret", Token: "tok"}
+	assert.True(t, config.AuthorizedHeader("Basic YWRtaW46c2VjcmV0"))
+	assert.True(t, config.AuthorizedHeader("basic YWRtaW46c2VjcmV0"))
+	assert.True(t, config.AuthorizedHeader("Bearer tok"))
+	assert.False(t, config.AuthorizedHeader("Basic not-base64!"))
+	assert.False(t, config.AuthorizedHeader("Bearer "))
+	assert.False(t, config.AuthorizedHeader(""))
+	assert.False(t, AccessConfig{}.AuthorizedHeader("Bearer "))
+}
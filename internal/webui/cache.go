@@ -0,0 +1,333 @@
+package webui
+
+import (
+	"bufio"
+	"compress/gzip"
+	"compress/zlib"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/fs"
+	"net"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// minCompressSize is the response size below which compression is skipped.
+const minCompressSize = 1024
+
+// Content codings supported by withCompression, in order of preference.
+const (
+	encodingGzip    = "gzip"
+	encodingDeflate = "deflate"
+)
+
+// negotiateEncoding picks a content coding from the Accept-Encoding header,
+// or "" for identity.
+func negotiateEncoding(r *http.Request) string {
+	accepted := make(map[string]bool)
+	for _, part := range strings.Split(r.Header.Get("Accept-Encoding"), ",") {
+		coding, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		if q, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			if v, err := strconv.ParseFloat(q, 64); err == nil && v == 0 {
+				continue
+			}
+		}
+		accepted[strings.ToLower(coding)] = true
+	}
+	for _, coding := range []string{encodingGzip, encodingDeflate} {
+		if accepted[coding] || accepted["*"] {
+			return coding
+		}
+	}
+	return ""
+}
+
+// withCompression compresses responses with gzip or deflate when the client
+// accepts it. Small responses and event streams are sent uncompressed.
+func (s *Server) withCompression(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		encoding := negotiateEncoding(r)
+		if r.Method == http.MethodHead {
+			encoding = ""
+		}
+
+		cw := &compressWriter{ResponseWriter: w, encoding: encoding, status: http.StatusOK}
+		defer cw.Close()
+		next(cw, r)
+	}
+}
+
+// compressWriter buffers the start of a response to decide whether it is
+// worth compressing, then streams it through the encoder or unchanged.
+type compressWriter struct {
+	http.ResponseWriter
+	encoding    string // negotiated coding, "" for identity
+	status      int
+	wroteHeader bool
+	decided     bool
+	buf         []byte
+	encoder     io.WriteCloser
+}
+
+func (cw *compressWriter) WriteHeader(status int) {
+	if cw.wroteHeader {
+		return
+	}
+	cw.wroteHeader = true
+	cw.status = status
+	// Bodiless responses need no buffering
+	if status == http.StatusNotModified || status == http.StatusNoContent || status < http.StatusOK {
+		cw.decide(false)
+	}
+}
+
+func (cw *compressWriter) Write(p []byte) (int, error) {
+	cw.wroteHeader = true
+	if !cw.decided {
+		cw.buf = append(cw.buf, p...)
+		if len(cw.buf) < minCompressSize {
+			return len(p), nil
+		}
+		if err := cw.start(); err != nil {
+			return 0, err
+		}
+		return len(p), nil
+	}
+	if cw.encoder != nil {
+		return cw.encoder.Write(p)
+	}
+	return cw.ResponseWriter.Write(p)
+}
+
+// Flush sends buffered data; a response flushed before reaching
+// minCompressSize is sent uncompressed.
+func (cw *compressWriter) Flush() {
+	if !cw.decided {
+		if err := cw.start(); err != nil {
+			return
+		}
+	}
+	if f, ok := cw.encoder.(interface{ Flush() error }); ok {
+		f.Flush()
+	}
+	if f, ok := cw.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Hijack lets the connection be taken over, e.g. for WebSocket upgrades.
+func (cw *compressWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	if h, ok := cw.ResponseWriter.(http.Hijacker); ok {
+		return h.Hijack()
+	}
+	return nil, nil, fmt.Errorf("hijacking not supported")
+}
+
+// Unwrap exposes the underlying writer to http.ResponseController.
+func (cw *compressWriter) Unwrap() http.ResponseWriter {
+	return cw.ResponseWriter
+}
+
+// start compresses the response if it is large enough and of a compressible
+// type, then writes the header and the buffered data.
+func (cw *compressWriter) start() error {
+	h := cw.Header()
+	compress := cw.encoding != "" && len(cw.buf) >= minCompressSize &&
+		h.Get("Content-Encoding") == "" &&
+		!strings.HasPrefix(h.Get("Content-Type"), "text/event-stream")
+	cw.decide(compress)
+
+	if len(cw.buf) == 0 {
+		return nil
+	}
+	buf := cw.buf
+	cw.buf = nil
+	var err error
+	if cw.encoder != nil {
+		_, err = cw.encoder.Write(buf)
+	} else {
+		_, err = cw.ResponseWriter.Write(buf)
+	}
+	return err
+}
+
+// decide fixes the encoding and writes the response header.
+func (cw *compressWriter) decide(compress bool) {
+	if cw.decided {
+		return
+	}
+	cw.decided = true
+
+	// Handlers may have set their own Vary (e.g. Accept for table exports)
+	h := cw.Header()
+	if !strings.Contains(strings.Join(h.Values("Vary"), ","), "Accept-Encoding") {
+		h.Add("Vary", "Accept-Encoding")
+	}
+	if compress {
+		h.Set("Content-Encoding", cw.encoding)
+		h.Del("Content-Length")
+		if cw.encoding == encodingGzip {
+			cw.encoder = gzip.NewWriter(cw.ResponseWriter)
+		} else {
+			// HTTP's deflate coding is the zlib format
+			cw.encoder = zlib.NewWriter(cw.ResponseWriter)
+		}
+	}
+	cw.ResponseWriter.WriteHeader(cw.status)
+}
+
+// Close sends a response that stayed below minCompressSize and finishes the
+// compressed stream.
+func (cw *compressWriter) Close() error {
+	if !cw.decided {
+		if !cw.wroteHeader {
+			// Nothing written: let net/http send its default response
+			return nil
+		}
+		if err := cw.start(); err != nil {
+			return err
+		}
+	}
+	if cw.encoder != nil {
+		return cw.encoder.Close()
+	}
+	return nil
+}
+
+// withETag serves routes derived from task artifacts with strong ETags and
+// answers matching If-None-Match requests with 304 Not Modified without
+// running the handler. Volatile routes are marked no-store instead.
+func (s *Server) withETag(route apiRoute, next http.HandlerFunc) http.HandlerFunc {
+	if route.Volatile {
+		return func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Cache-Control", "no-store")
+			next(w, r)
+		}
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			next(w, r)
+			return
+		}
+
+		etag := s.computeETag(r)
+		// Clients revalidate every time; private since authentication may be enabled
+		w.Header().Set("Cache-Control", "private, no-cache")
+		if etagMatches(r.Header.Get("If-None-Match"), etag) {
+			w.Header().Set("ETag", etag)
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		next(&etagWriter{ResponseWriter: w, etag: etag}, r)
+	}
+}
+
+// computeETag fingerprints a request: the task artifacts it reads, the
+// request itself and the negotiated encoding. It covers the tasks named by
+// the task, base and target parameters, or the default task.
+func (s *Server) computeETag(r *http.Request) string {
+	h := sha256.New()
+	fmt.Fprintf(h, "%s\n%s\n%s\n%s\n%s\n", s.etagSeed, r.URL.Path, r.URL.Query().Encode(),
+		r.Header.Get("Accept"), negotiateEncoding(r))
+
+	query := r.URL.Query()
+	var tasks []string
+	for _, param := range []string{"task", "base", "target"} {
+		if taskID := query.Get(param); taskID != "" {
+			tasks = append(tasks, taskID)
+		}
+	}
+	if len(tasks) == 0 {
+		tasks = append(tasks, s.resolveTask(""))
+	}
+	for _, taskID := range tasks {
+		fmt.Fprintf(h, "task %s\n", taskID)
+		s.writeArtifactFingerprint(h, taskID)
+	}
+
+	// Compressed and identity representations must not share a strong ETag
+	encoding := negotiateEncoding(r)
+	etag := hex.EncodeToString(h.Sum(nil)[:16])
+	if encoding != "" {
+		return `"` + etag + "-" + encoding + `"`
+	}
+	return `"` + etag + `"`
+}
+
+// writeArtifactFingerprint writes the name, size and modification time of
+// every file in a task directory.
+func (s *Server) writeArtifactFingerprint(w io.Writer, taskID string) {
+	taskDir := filepath.Join(s.dataDir, filepath.Clean("/"+taskID))
+	err := filepath.WalkDir(taskDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		info, err := d.Info()
+		if err != nil {
+			return nil
+		}
+		rel, _ := filepath.Rel(taskDir, path)
+		fmt.Fprintf(w, "%s %d %d\n", rel, info.Size(), info.ModTime().UnixNano())
+		return nil
+	})
+	if err != nil {
+		fmt.Fprintf(w, "error %v\n", err)
+	}
+}
+
+// etagMatches reports whether an If-None-Match header matches etag.
+func etagMatches(ifNoneMatch, etag string) bool {
+	if ifNoneMatch == "" {
+		return false
+	}
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == "*" || candidate == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// etagWriter sets the ETag header on successful responses only.
+type etagWriter struct {
+	http.ResponseWriter
+	etag        string
+	wroteHeader bool
+}
+
+func (ew *etagWriter) WriteHeader(status int) {
+	if !ew.wroteHeader {
+		ew.wroteHeader = true
+		if status == http.StatusOK {
+			ew.Header().Set("ETag", ew.etag)
+		} else {
+			ew.Header().Del("Cache-Control")
+		}
+	}
+	ew.ResponseWriter.WriteHeader(status)
+}
+
+func (ew *etagWriter) Write(p []byte) (int, error) {
+	if !ew.wroteHeader {
+		ew.WriteHeader(http.StatusOK)
+	}
+	return ew.ResponseWriter.Write(p)
+}
+
+// Flush implements http.Flusher.
+func (ew *etagWriter) Flush() {
+	if f, ok := ew.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Unwrap exposes the underlying writer to http.ResponseController.
+func (ew *etagWriter) Unwrap() http.ResponseWriter {
+	return ew.ResponseWriter
+}
@@ -0,0 +1,293 @@
+package webui
+
+import (
+	"bufio"
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNegotiateEncoding(t *testing.T) {
+	tests := []struct {
+		acceptEncoding string
+		want           string
+	}{
+		{"", ""},
+		{"gzip", encodingGzip},
+		{"deflate", encodingDeflate},
+		{"deflate, gzip", encodingGzip},
+		{"GZIP", encodingGzip},
+		{"gzip;q=0, deflate", encodingDeflate},
+		{"gzip; q=0.5", encodingGzip},
+		{"*", encodingGzip},
+		{"br", ""},
+		{"identity", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.acceptEncoding, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			r.Header.Set("Accept-Encoding", tt.acceptEncoding)
+			assert.Equal(t, tt.want, negotiateEncoding(r))
+		})
+	}
+}
+
+// decodeBody decompresses a response body of the given content coding.
+func decodeBody(t *testing.T, encoding string, body io.Reader) string {
+	var r io.Reader = body
+	var err error
+	switch encoding {
+	case encodingGzip:
+		r, err = gzip.NewReader(body)
+	case encodingDeflate:
+		r, err = zlib.NewReader(body)
+	}
+	require.NoError(t, err)
+	data, err := io.ReadAll(r)
+	require.NoError(t, err)
+	return string(data)
+}
+
+func TestServer_withCompression(t *testing.T) {
+	s := NewServer(t.TempDir(), 0, nil)
+	large := strings.Repeat("heap ", minCompressSize)
+	small := "small"
+
+	tests := []struct {
+		name           string
+		method         string
+		acceptEncoding string
+		handler        http.HandlerFunc
+		wantEncoding   string
+		wantBody       string
+	}{
+		{"gzip", http.MethodGet, "gzip", writeBody("application/json", large), encodingGzip, large},
+		{"deflate", http.MethodGet, "deflate", writeBody("application/json", large), encodingDeflate, large},
+		{"not accepted", http.MethodGet, "", writeBody("application/json", large), "", large},
+		{"below threshold", http.MethodGet, "gzip", writeBody("application/json", small), "", small},
+		{"HEAD", http.MethodHead, "gzip", writeBody("application/json", large), "", large},
+		{"event stream", http.MethodGet, "gzip", writeBody("text/event-stream", large), "", large},
+		{"many small writes", http.MethodGet, "gzip", func(w http.ResponseWriter, r *http.Request) {
+			for i := 0; i < minCompressSize; i++ {
+				io.WriteString(w, "heap ")
+			}
+		}, encodingGzip, large},
+		{"already encoded", http.MethodGet, "gzip", func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Encoding", "br")
+			io.WriteString(w, large)
+		}, "br", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(tt.method, "/api/classes", nil)
+			r.Header.Set("Accept-Encoding", tt.acceptEncoding)
+			w := httptest.NewRecorder()
+			s.withCompression(tt.handler)(w, r)
+
+			assert.Equal(t, http.StatusOK, w.Code)
+			assert.Equal(t, tt.wantEncoding, w.Header().Get("Content-Encoding"))
+			assert.Equal(t, []string{"Accept-Encoding"}, w.Header().Values("Vary"))
+			if tt.wantEncoding != "br" {
+				assert.Equal(t, tt.wantBody, decodeBody(t, tt.wantEncoding, w.Body))
+			}
+		})
+	}
+}
+
+// writeBody returns a handler writing body as contentType.
+func writeBody(contentType, body string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", contentType)
+		io.WriteString(w, body)
+	}
+}
+
+func TestServer_withCompression_Status(t *testing.T) {
+	s := NewServer(t.TempDir(), 0, nil)
+	serve := func(handler http.HandlerFunc) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodGet, "/api/classes", nil)
+		r.Header.Set("Accept-Encoding", "gzip")
+		w := httptest.NewRecorder()
+		s.withCompression(handler)(w, r)
+		return w
+	}
+
+	// Bodiless responses are sent as they are
+	w := serve(func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusNotModified) })
+	assert.Equal(t, http.StatusNotModified, w.Code)
+	assert.Empty(t, w.Header().Get("Content-Encoding"))
+	assert.Zero(t, w.Body.Len())
+
+	// Errors keep their status and a Vary set by the handler
+	w = serve(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Vary", "Accept")
+		http.Error(w, strings.Repeat("x", 2*minCompressSize), http.StatusNotFound)
+	})
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Equal(t, encodingGzip, w.Header().Get("Content-Encoding"))
+	assert.Equal(t, []string{"Accept", "Accept-Encoding"}, w.Header().Values("Vary"))
+
+	// A handler writing nothing gets net/http's default response
+	w = serve(func(w http.ResponseWriter, r *http.Request) {})
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Empty(t, w.Header().Values("Vary"))
+	assert.Zero(t, w.Body.Len())
+}
+
+func TestServer_withCompression_Flush(t *testing.T) {
+	s := NewServer(t.TempDir(), 0, nil)
+
+	// Server-sent events reach the client as they are flushed, through the
+	// ETag writer too
+	for _, volatile := range []bool{false, true} {
+		r := httptest.NewRequest(http.MethodGet, "/api/progress", nil)
+		r.Header.Set("Accept-Encoding", "gzip")
+		w := httptest.NewRecorder()
+		route := apiRoute{Volatile: volatile}
+		s.withCompression(s.withETag(route, func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "text/event-stream")
+			io.WriteString(w, "event: progress\ndata: {}\n\n")
+			require.NoError(t, http.NewResponseController(w).Flush())
+		}))(w, r)
+
+		assert.True(t, w.Flushed)
+		assert.Empty(t, w.Header().Get("Content-Encoding"))
+		assert.Equal(t, "event: progress\ndata: {}\n\n", w.Body.String())
+	}
+
+	// A compressed response flushed midway stays one valid stream
+	r := httptest.NewRequest(http.MethodGet, "/api/classes", nil)
+	r.Header.Set("Accept-Encoding", "gzip")
+	w := httptest.NewRecorder()
+	large := strings.Repeat("heap ", minCompressSize)
+	s.withCompression(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, large)
+		w.(http.Flusher).Flush()
+		io.WriteString(w, large)
+	})(w, r)
+	assert.True(t, w.Flushed)
+	assert.Equal(t, large+large, decodeBody(t, encodingGzip, w.Body))
+}
+
+func TestServer_withCompression_Hijack(t *testing.T) {
+	s := NewServer(t.TempDir(), 0, nil)
+
+	for _, volatile := range []bool{false, true} {
+		handler := s.withCompression(s.withETag(apiRoute{Volatile: volatile}, func(w http.ResponseWriter, r *http.Request) {
+			conn, rw, err := http.NewResponseController(w).Hijack()
+			if !assert.NoError(t, err) {
+				return
+			}
+			defer conn.Close()
+			rw.WriteString("HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n\r\nhello")
+			rw.Flush()
+		}))
+		srv := httptest.NewServer(handler)
+
+		conn, err := net.Dial("tcp", srv.Listener.Addr().String())
+		require.NoError(t, err)
+		conn.SetDeadline(time.Now().Add(5 * time.Second))
+		io.WriteString(conn, "GET /api/logs/stream HTTP/1.1\r\nHost: localhost\r\nAccept-Encoding: gzip\r\n\r\n")
+		br := bufio.NewReader(conn)
+		resp, err := http.ReadResponse(br, nil)
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusSwitchingProtocols, resp.StatusCode)
+		assert.Empty(t, resp.Header.Get("Content-Encoding"))
+		rest, _ := io.ReadAll(br)
+		assert.Equal(t, "hello", string(rest))
+		conn.Close()
+		srv.Close()
+	}
+}
+
+func TestServer_withETag(t *testing.T) {
+	dataDir := t.TempDir()
+	taskDir := filepath.Join(dataDir, "task-1")
+	require.NoError(t, os.Mkdir(taskDir, 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(taskDir, "summary.json"), []byte("{}"), 0o644))
+	s := NewServer(dataDir, 0, nil)
+
+	calls := 0
+	handler := s.withETag(apiRoute{}, func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if r.URL.Query().Get("fail") != "" {
+			http.Error(w, "not found", http.StatusNotFound)
+			return
+		}
+		io.WriteString(w, "{}")
+	})
+	serve := func(method, target string, header http.Header) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(method, target, nil)
+		for k, v := range header {
+			r.Header[k] = v
+		}
+		w := httptest.NewRecorder()
+		handler(w, r)
+		return w
+	}
+
+	w := serve(http.MethodGet, "/api/summary?task=task-1", nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	etag := w.Header().Get("ETag")
+	require.NotEmpty(t, etag)
+	assert.Equal(t, "private, no-cache", w.Header().Get("Cache-Control"))
+
+	// A repeated request is revalidated without running the handler
+	for _, ifNoneMatch := range []string{etag, "W/" + etag, `"other", ` + etag, "*"} {
+		calls = 0
+		w = serve(http.MethodGet, "/api/summary?task=task-1", http.Header{"If-None-Match": {ifNoneMatch}})
+		assert.Equal(t, http.StatusNotModified, w.Code, ifNoneMatch)
+		assert.Equal(t, etag, w.Header().Get("ETag"))
+		assert.Zero(t, calls)
+	}
+	w = serve(http.MethodGet, "/api/summary?task=task-1", http.Header{"If-None-Match": {`"other"`}})
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	// The ETag changes with the request, the encoding and the task artifacts
+	assert.NotEqual(t, etag, serve(http.MethodGet, "/api/summary?task=task-1&top=5", nil).Header().Get("ETag"))
+	gzipETag := serve(http.MethodGet, "/api/summary?task=task-1", http.Header{"Accept-Encoding": {"gzip"}}).Header().Get("ETag")
+	assert.True(t, strings.HasSuffix(gzipETag, `-gzip"`), gzipETag)
+	later := time.Now().Add(time.Hour)
+	require.NoError(t, os.Chtimes(filepath.Join(taskDir, "summary.json"), later, later))
+	assert.NotEqual(t, etag, serve(http.MethodGet, "/api/summary?task=task-1", nil).Header().Get("ETag"))
+
+	// Errors are not cached
+	w = serve(http.MethodGet, "/api/summary?task=task-1&fail=1", nil)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Empty(t, w.Header().Get("ETag"))
+	assert.Empty(t, w.Header().Get("Cache-Control"))
+
+	// Other methods are passed through
+	w = serve(http.MethodPost, "/api/summary?task=task-1", http.Header{"If-None-Match": {"*"}})
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Empty(t, w.Header().Get("ETag"))
+}
+
+func TestServer_withETag_Volatile(t *testing.T) {
+	s := NewServer(t.TempDir(), 0, nil)
+	called := false
+	handler := s.withETag(apiRoute{Volatile: true}, func(w http.ResponseWriter, r *http.Request) {
+		called = true
+		io.WriteString(w, "[]")
+	})
+
+	r := httptest.NewRequest(http.MethodGet, "/api/tasks", nil)
+	r.Header.Set("If-None-Match", "*")
+	w := httptest.NewRecorder()
+	handler(w, r)
+	assert.True(t, called)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "no-store", w.Header().Get("Cache-Control"))
+	assert.Empty(t, w.Header().Get("ETag"))
+}
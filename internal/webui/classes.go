@@ -0,0 +1,453 @@
+package webui
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/perf-analysis/internal/parser/hprof"
+)
+
+// maxClassesPageSize caps the limit parameter of /api/classes.
+const maxClassesPageSize = 1000
+
+// ClassesPage is one page of the class histogram, or of its package or
+// class loader rollup.
+type ClassesPage struct {
+	// Total is the number of rows matching the filter
+	Total  int `json:"total"`
+	Offset int `json:"offset"`
+	Limit  int `json:"limit"`
+
+	TotalClasses   int   `json:"total_classes"`
+	TotalInstances int64 `json:"total_instances"`
+	TotalSize      int64 `json:"total_size"`
+
+	Classes  []*hprof.ClassStats `json:"classes,omitempty"`
+	Packages []*PackageStats     `json:"packages,omitempty"`
+	Loaders  []*LoaderStats      `json:"loaders,omitempty"`
+}
+
+// PackageStats aggregates the classes of one package. Retained sizes of
+// classes in the same package may overlap, so RetainedSize is an upper bound.
+type PackageStats struct {
+	Package       string `json:"package"`
+	ClassCount    int    `json:"class_count"`
+	InstanceCount int64  `json:"instance_count"`
+	ShallowSize   int64  `json:"shallow_size"`
+	RetainedSize  int64  `json:"retained_size"`
+}
+
+// LoaderStats aggregates the classes defined by one class loader, e.g. one
+// application deployed in a servlet container. Like for packages,
+// RetainedSize is an upper bound.
+type LoaderStats struct {
+	// LoaderID is the class loader object, empty for the bootstrap loader
+	LoaderID      string `json:"loader_id,omitempty"`
+	LoaderClass   string `json:"loader_class"`
+	ClassCount    int    `json:"class_count"`
+	InstanceCount int64  `json:"instance_count"`
+	ShallowSize   int64  `json:"shallow_size"`
+	RetainedSize  int64  `json:"retained_size"`
+}
+
+// bootstrapLoader selects the classes of the bootstrap loader in the loader
+// parameter of /api/classes.
+const bootstrapLoader = "bootstrap"
+
+// ClassColumns is the class histogram in columnar form: one array per
+// column, the i-th class being at index i of every array. Columns not
+// selected by the request are omitted.
+type ClassColumns struct {
+	Rows           int   `json:"rows"`
+	TotalClasses   int   `json:"total_classes"`
+	TotalInstances int64 `json:"total_instances"`
+	TotalSize      int64 `json:"total_size"`
+
+	Columns struct {
+		ClassName     []string `json:"class_name,omitempty"`
+		InstanceCount []int64  `json:"instance_count,omitempty"`
+		TotalSize     []int64  `json:"total_size,omitempty"`
+		RetainedSize  []int64  `json:"retained_size,omitempty"`
+	} `json:"columns"`
+}
+
+// classColumn is a column of /api/classes/columns. Columns without a number
+// function hold the class name.
+type classColumn struct {
+	name   string
+	number func(*hprof.ClassStats) int64
+}
+
+// classColumns are the columns of /api/classes/columns, in response order.
+var classColumns = []classColumn{
+	{name: "class_name"},
+	{name: "instance_count", number: func(c *hprof.ClassStats) int64 { return c.InstanceCount }},
+	{name: "total_size", number: func(c *hprof.ClassStats) int64 { return c.TotalSize }},
+	{name: "retained_size", number: func(c *hprof.ClassStats) int64 { return c.RetainedSize }},
+}
+
+// classColumnsFlushRows is the number of values written between flushes.
+const classColumnsFlushRows = 8192
+
+// cachedHistogram is a parsed class_histogram.json, reused until the file changes.
+type cachedHistogram struct {
+	modTime   time.Time
+	histogram *classHistogramFile
+}
+
+// classHistogramFile mirrors the class_histogram.json written by the analyzer.
+type classHistogramFile struct {
+	TotalClasses   int                 `json:"total_classes"`
+	TotalInstances int64               `json:"total_instances"`
+	TotalSize      int64               `json:"total_size"`
+	Classes        []*hprof.ClassStats `json:"classes"`
+}
+
+// handleClasses returns a page of the full class histogram with server-side
+// sorting, regex and class loader filtering and optional package or class
+// loader rollup.
+func (s *Server) handleClasses(w http.ResponseWriter, r *http.Request) {
+	req := classesRequest{Limit: 200, Sort: "shallow", Order: "desc"}
+	if err := decodeQuery(r, &req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if req.Offset < 0 || req.Limit <= 0 || req.Limit > maxClassesPageSize {
+		http.Error(w, "Invalid offset or limit parameter", http.StatusBadRequest)
+		return
+	}
+
+	var filter *regexp.Regexp
+	if req.Filter != "" {
+		var err error
+		if filter, err = regexp.Compile(req.Filter); err != nil {
+			http.Error(w, fmt.Sprintf("Invalid filter: %v", err), http.StatusBadRequest)
+			return
+		}
+	}
+
+	histogram, err := s.loadClassHistogram(s.resolveTask(req.Task))
+	if err != nil {
+		http.Error(w, "Class histogram not found", http.StatusNotFound)
+		return
+	}
+
+	page, err := pageClasses(histogram, req, filter)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	json.NewEncoder(w).Encode(page)
+}
+
+// handleClassColumns streams the whole class histogram, sorted and
+// filtered like /api/classes, as a ClassColumns object. Values are written
+// column by column without building the response in memory, and the
+// repeated field names of row-oriented JSON are avoided.
+func (s *Server) handleClassColumns(w http.ResponseWriter, r *http.Request) {
+	req := classColumnsRequest{Sort: "shallow", Order: "desc"}
+	if err := decodeQuery(r, &req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	selected := make(map[string]bool)
+	if req.Columns != "" {
+		for _, name := range strings.Split(req.Columns, ",") {
+			name = strings.TrimSpace(name)
+			if !slices.ContainsFunc(classColumns, func(c classColumn) bool { return c.name == name }) {
+				http.Error(w, fmt.Sprintf("Invalid column %q", name), http.StatusBadRequest)
+				return
+			}
+			selected[name] = true
+		}
+	}
+
+	var filter *regexp.Regexp
+	if req.Filter != "" {
+		var err error
+		if filter, err = regexp.Compile(req.Filter); err != nil {
+			http.Error(w, fmt.Sprintf("Invalid filter: %v", err), http.StatusBadRequest)
+			return
+		}
+	}
+
+	histogram, err := s.loadClassHistogram(s.resolveTask(req.Task))
+	if err != nil {
+		http.Error(w, "Class histogram not found", http.StatusNotFound)
+		return
+	}
+
+	page, err := pageClasses(histogram, classesRequest{
+		Limit:  max(len(histogram.Classes), 1),
+		Sort:   req.Sort,
+		Order:  req.Order,
+		Filter: req.Filter,
+		Loader: req.Loader,
+	}, filter)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	writeClassColumns(w, page, selected)
+}
+
+// writeClassColumns writes the classes of a page as a ClassColumns object,
+// flushing regularly so clients can start parsing early. An empty selection
+// writes every column.
+func writeClassColumns(w http.ResponseWriter, page *ClassesPage, selected map[string]bool) {
+	flusher, _ := w.(http.Flusher)
+	bw := bufio.NewWriterSize(w, 64*1024)
+	fmt.Fprintf(bw, `{"rows":%d,"total_classes":%d,"total_instances":%d,"total_size":%d,"columns":{`,
+		len(page.Classes), page.TotalClasses, page.TotalInstances, page.TotalSize)
+
+	var buf []byte
+	first := true
+	for _, column := range classColumns {
+		if len(selected) > 0 && !selected[column.name] {
+			continue
+		}
+		if !first {
+			bw.WriteByte(',')
+		}
+		first = false
+		fmt.Fprintf(bw, "%q:[", column.name)
+		for i, cls := range page.Classes {
+			buf = buf[:0]
+			if i > 0 {
+				buf = append(buf, ',')
+			}
+			if column.number != nil {
+				buf = strconv.AppendInt(buf, column.number(cls), 10)
+			} else {
+				name, _ := json.Marshal(cls.ClassName)
+				buf = append(buf, name...)
+			}
+			bw.Write(buf)
+			if flusher != nil && (i+1)%classColumnsFlushRows == 0 {
+				bw.Flush()
+				flusher.Flush()
+			}
+		}
+		bw.WriteByte(']')
+	}
+	bw.WriteString("}}\n")
+	bw.Flush()
+}
+
+// loadClassHistogram returns the parsed class histogram of a task.
+func (s *Server) loadClassHistogram(taskID string) (*classHistogramFile, error) {
+	taskDir, err := s.existingTaskDir(taskID)
+	if err != nil {
+		return nil, err
+	}
+	filename := filepath.Join(taskDir, "class_histogram.json")
+	info, err := os.Stat(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	s.histogramsMu.Lock()
+	cached, ok := s.histograms[taskID]
+	s.histogramsMu.Unlock()
+	if ok && cached.modTime.Equal(info.ModTime()) {
+		return cached.histogram, nil
+	}
+
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	var histogram classHistogramFile
+	if err := json.Unmarshal(data, &histogram); err != nil {
+		return nil, err
+	}
+
+	s.histogramsMu.Lock()
+	s.histograms[taskID] = &cachedHistogram{modTime: info.ModTime(), histogram: &histogram}
+	s.histogramsMu.Unlock()
+	return &histogram, nil
+}
+
+// pageClasses filters, sorts and pages a histogram. The cached histogram is
+// not modified.
+func pageClasses(histogram *classHistogramFile, req classesRequest, filter *regexp.Regexp) (*ClassesPage, error) {
+	page := &ClassesPage{
+		Offset:         req.Offset,
+		Limit:          req.Limit,
+		TotalClasses:   histogram.TotalClasses,
+		TotalInstances: histogram.TotalInstances,
+		TotalSize:      histogram.TotalSize,
+	}
+
+	var loaderID uint64
+	if req.Loader != "" && req.Loader != bootstrapLoader {
+		var err error
+		if loaderID, err = parseObjectID(req.Loader); err != nil {
+			return nil, fmt.Errorf("invalid loader %q", req.Loader)
+		}
+	}
+
+	classes := make([]*hprof.ClassStats, 0, len(histogram.Classes))
+	for _, cls := range histogram.Classes {
+		if req.Loader != "" && cls.ClassLoaderID != loaderID {
+			continue
+		}
+		if filter == nil || filter.MatchString(cls.ClassName) {
+			classes = append(classes, cls)
+		}
+	}
+
+	desc := req.Order != "asc"
+	switch req.Group {
+	case "":
+		value, err := sortValue(req.Sort, func(c *hprof.ClassStats) [3]int64 {
+			return [3]int64{c.TotalSize, c.RetainedSize, c.InstanceCount}
+		})
+		if err != nil {
+			return nil, err
+		}
+		sortRows(classes, value, func(c *hprof.ClassStats) string { return c.ClassName }, desc)
+		page.Total = len(classes)
+		page.Classes = pageSlice(classes, req.Offset, req.Limit)
+	case "package":
+		packages := rollupPackages(classes)
+		value, err := sortValue(req.Sort, func(p *PackageStats) [3]int64 {
+			return [3]int64{p.ShallowSize, p.RetainedSize, p.InstanceCount}
+		})
+		if err != nil {
+			return nil, err
+		}
+		sortRows(packages, value, func(p *PackageStats) string { return p.Package }, desc)
+		page.Total = len(packages)
+		page.Packages = pageSlice(packages, req.Offset, req.Limit)
+	case "loader":
+		loaders := rollupLoaders(classes)
+		value, err := sortValue(req.Sort, func(l *LoaderStats) [3]int64 {
+			return [3]int64{l.ShallowSize, l.RetainedSize, l.InstanceCount}
+		})
+		if err != nil {
+			return nil, err
+		}
+		sortRows(loaders, value, func(l *LoaderStats) string { return l.LoaderClass + "@" + l.LoaderID }, desc)
+		page.Total = len(loaders)
+		page.Loaders = pageSlice(loaders, req.Offset, req.Limit)
+	default:
+		return nil, fmt.Errorf("invalid group %q", req.Group)
+	}
+	return page, nil
+}
+
+// sortValue returns the numeric sort value of a sort key, given the row's
+// shallow, retained and instance count values. The name key returns nil.
+func sortValue[T any](key string, values func(T) [3]int64) (func(T) int64, error) {
+	var index int
+	switch key {
+	case "shallow":
+		index = 0
+	case "retained":
+		index = 1
+	case "count":
+		index = 2
+	case "name":
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("invalid sort key %q", key)
+	}
+	return func(row T) int64 { return values(row)[index] }, nil
+}
+
+// sortRows sorts rows by value (or by name when value is nil).
+// Ties are always broken by ascending name.
+func sortRows[T any](rows []T, value func(T) int64, name func(T) string, desc bool) {
+	sort.SliceStable(rows, func(i, j int) bool {
+		ni, nj := name(rows[i]), name(rows[j])
+		if value == nil {
+			if desc {
+				return ni > nj
+			}
+			return ni < nj
+		}
+		if vi, vj := value(rows[i]), value(rows[j]); vi != vj {
+			if desc {
+				return vi > vj
+			}
+			return vi < vj
+		}
+		return ni < nj
+	})
+}
+
+// rollupPackages aggregates classes by package. Array classes count toward
+// their element type's package; primitives and the default package roll up
+// to "(default)".
+func rollupPackages(classes []*hprof.ClassStats) []*PackageStats {
+	byPackage := make(map[string]*PackageStats)
+	var packages []*PackageStats
+	for _, cls := range classes {
+		pkg := hprof.ClassPackage(cls.ClassName)
+		stats, ok := byPackage[pkg]
+		if !ok {
+			stats = &PackageStats{Package: pkg}
+			byPackage[pkg] = stats
+			packages = append(packages, stats)
+		}
+		stats.ClassCount++
+		stats.InstanceCount += cls.InstanceCount
+		stats.ShallowSize += cls.TotalSize
+		stats.RetainedSize += cls.RetainedSize
+	}
+	return packages
+}
+
+// rollupLoaders aggregates classes by defining class loader. Each loader
+// instance is a row, so redeployed applications show up separately.
+func rollupLoaders(classes []*hprof.ClassStats) []*LoaderStats {
+	byLoader := make(map[uint64]*LoaderStats)
+	var loaders []*LoaderStats
+	for _, cls := range classes {
+		stats, ok := byLoader[cls.ClassLoaderID]
+		if !ok {
+			stats = &LoaderStats{LoaderClass: "<bootstrap>"}
+			if cls.ClassLoaderID != 0 {
+				stats.LoaderID = formatObjectID(cls.ClassLoaderID)
+				stats.LoaderClass = cls.ClassLoader
+			}
+			byLoader[cls.ClassLoaderID] = stats
+			loaders = append(loaders, stats)
+		}
+		stats.ClassCount++
+		stats.InstanceCount += cls.InstanceCount
+		stats.ShallowSize += cls.TotalSize
+		stats.RetainedSize += cls.RetainedSize
+	}
+	return loaders
+}
+
+// pageSlice returns items[offset:offset+limit], clamped to the slice.
+func pageSlice[T any](items []T, offset, limit int) []T {
+	if offset >= len(items) {
+		return []T{}
+	}
+	end := offset + limit
+	if end > len(items) {
+		end = len(items)
+	}
+	return items[offset:end]
+}
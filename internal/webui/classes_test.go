@@ -0,0 +1,281 @@
+package webui
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/perf-analysis/internal/parser/hprof"
+)
+
+// testClassHistogram has bootstrap, web application and application class
+// loader classes in three packages, with ties on the instance count.
+func testClassHistogram() *classHistogramFile {
+	const webapp, app = 0x100, 0x200
+	return &classHistogramFile{
+		TotalClasses:   6,
+		TotalInstances: 182,
+		TotalSize:      8848,
+		Classes: []*hprof.ClassStats{
+			{ClassName: "java.lang.String", InstanceCount: 100, TotalSize: 2400, RetainedSize: 5000},
+			{ClassName: "java.lang.String[]", InstanceCount: 10, TotalSize: 800, RetainedSize: 900},
+			{ClassName: "com.example.Cache", InstanceCount: 1, TotalSize: 32, RetainedSize: 10000,
+				ClassLoaderID: webapp, ClassLoader: "org.apache.catalina.loader.ParallelWebappClassLoader"},
+			{ClassName: "com.example.Entry", InstanceCount: 50, TotalSize: 1600, RetainedSize: 1600,
+				ClassLoaderID: webapp, ClassLoader: "org.apache.catalina.loader.ParallelWebappClassLoader"},
+			{ClassName: "int[]", InstanceCount: 20, TotalSize: 4000, RetainedSize: 4000},
+			{ClassName: "Main", InstanceCount: 1, TotalSize: 16, RetainedSize: 16,
+				ClassLoaderID: app, ClassLoader: "jdk.internal.loader.ClassLoaders$AppClassLoader"},
+		},
+	}
+}
+
+// newClassesTestServer returns the API routes of a server with one task,
+// task-1, holding histogram.
+func newClassesTestServer(t *testing.T, histogram *classHistogramFile) http.Handler {
+	dataDir := t.TempDir()
+	taskDir := filepath.Join(dataDir, "task-1")
+	require.NoError(t, os.Mkdir(taskDir, 0o755))
+	data, err := json.Marshal(histogram)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(taskDir, "class_histogram.json"), data, 0o644))
+
+	s := NewServer(dataDir, 0, nil)
+	mux := http.NewServeMux()
+	s.registerAPIRoutes(mux)
+	return mux
+}
+
+func TestServer_handleClasses(t *testing.T) {
+	h := newClassesTestServer(t, testClassHistogram())
+
+	tests := []struct {
+		name      string
+		query     string
+		wantTotal int
+		want      []string
+	}{
+		{"default", "", 6, []string{"int[]", "java.lang.String", "com.example.Entry", "java.lang.String[]", "com.example.Cache", "Main"}},
+		{"page", "offset=2&limit=2", 6, []string{"com.example.Entry", "java.lang.String[]"}},
+		{"last page", "offset=5&limit=2", 6, []string{"Main"}},
+		{"past the end", "offset=6", 6, nil},
+		{"retained", "sort=retained", 6, []string{"com.example.Cache", "java.lang.String", "int[]", "com.example.Entry", "java.lang.String[]", "Main"}},
+		{"count ascending, ties by name", "sort=count&order=asc", 6, []string{"Main", "com.example.Cache", "java.lang.String[]", "int[]", "com.example.Entry", "java.lang.String"}},
+		{"count descending, ties by name", "sort=count&offset=4", 6, []string{"Main", "com.example.Cache"}},
+		{"name", "sort=name&order=asc", 6, []string{"Main", "com.example.Cache", "com.example.Entry", "int[]", "java.lang.String", "java.lang.String[]"}},
+		{"name descending", "sort=name&limit=2", 6, []string{"java.lang.String[]", "java.lang.String"}},
+		{"filter", `filter=^java\.lang\.`, 2, []string{"java.lang.String", "java.lang.String[]"}},
+		{"filter arrays", `filter=\[\]$&sort=name&order=asc`, 2, []string{"int[]", "java.lang.String[]"}},
+		{"filter unanchored", "filter=Str", 2, []string{"java.lang.String", "java.lang.String[]"}},
+		{"filter without match", "filter=^org\\.", 0, nil},
+		{"loader", "loader=0x100", 2, []string{"com.example.Entry", "com.example.Cache"}},
+		{"bootstrap loader", "loader=bootstrap", 3, []string{"int[]", "java.lang.String", "java.lang.String[]"}},
+		{"loader and filter", "loader=bootstrap&filter=String&limit=1", 2, []string{"java.lang.String"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/classes?task=task-1&"+tt.query, nil))
+			require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+			var page ClassesPage
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &page))
+			assert.Equal(t, tt.wantTotal, page.Total)
+			assert.Equal(t, 6, page.TotalClasses)
+			assert.Equal(t, int64(8848), page.TotalSize)
+			var names []string
+			for _, cls := range page.Classes {
+				names = append(names, cls.ClassName)
+			}
+			assert.Equal(t, tt.want, names)
+		})
+	}
+}
+
+func TestServer_handleClasses_Rollup(t *testing.T) {
+	h := newClassesTestServer(t, testClassHistogram())
+	get := func(query string) ClassesPage {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/classes?task=task-1&"+query, nil))
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		var page ClassesPage
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &page))
+		assert.Empty(t, page.Classes)
+		return page
+	}
+
+	// Arrays count toward their element type's package, primitives and the
+	// default package roll up together
+	page := get("group=package")
+	assert.Equal(t, 3, page.Total)
+	assert.Equal(t, []*PackageStats{
+		{Package: "(default)", ClassCount: 2, InstanceCount: 21, ShallowSize: 4016, RetainedSize: 4016},
+		{Package: "java.lang", ClassCount: 2, InstanceCount: 110, ShallowSize: 3200, RetainedSize: 5900},
+		{Package: "com.example", ClassCount: 2, InstanceCount: 51, ShallowSize: 1632, RetainedSize: 11600},
+	}, page.Packages)
+
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{"group=package&sort=retained", []string{"com.example", "java.lang", "(default)"}},
+		{"group=package&sort=count&order=asc", []string{"(default)", "com.example", "java.lang"}},
+		{"group=package&sort=name&order=asc", []string{"(default)", "com.example", "java.lang"}},
+		{"group=package&offset=1&limit=1", []string{"java.lang"}},
+		{`group=package&filter=^com\.`, []string{"com.example"}},
+		{"group=package&loader=bootstrap", []string{"(default)", "java.lang"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			var packages []string
+			for _, pkg := range get(tt.query).Packages {
+				packages = append(packages, pkg.Package)
+			}
+			assert.Equal(t, tt.want, packages)
+		})
+	}
+
+	page = get("group=loader")
+	assert.Equal(t, 3, page.Total)
+	assert.Equal(t, []*LoaderStats{
+		{LoaderClass: "<bootstrap>", ClassCount: 3, InstanceCount: 130, ShallowSize: 7200, RetainedSize: 9900},
+		{LoaderID: "0x100", LoaderClass: "org.apache.catalina.loader.ParallelWebappClassLoader", ClassCount: 2, InstanceCount: 51, ShallowSize: 1632, RetainedSize: 11600},
+		{LoaderID: "0x200", LoaderClass: "jdk.internal.loader.ClassLoaders$AppClassLoader", ClassCount: 1, InstanceCount: 1, ShallowSize: 16, RetainedSize: 16},
+	}, page.Loaders)
+}
+
+func TestServer_handleClasses_Errors(t *testing.T) {
+	h := newClassesTestServer(t, testClassHistogram())
+
+	tests := []struct {
+		query string
+		want  int
+	}{
+		{"limit=0", http.StatusBadRequest},
+		{"limit=1001", http.StatusBadRequest},
+		{"offset=-1", http.StatusBadRequest},
+		{"offset=x", http.StatusBadRequest},
+		{"filter=(", http.StatusBadRequest},
+		{"sort=size", http.StatusBadRequest},
+		{"group=module", http.StatusBadRequest},
+		{"group=package&sort=size", http.StatusBadRequest},
+		{"loader=zz", http.StatusBadRequest},
+		{"limit=1000", http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/classes?task=task-1&"+tt.query, nil))
+			assert.Equal(t, tt.want, w.Code, w.Body.String())
+		})
+	}
+
+	// Task IDs must name a task directory
+	for _, task := range []string{"missing", "..", "task-1%2F..%2Ftask-1", "%2Ftmp"} {
+		for _, path := range []string{"/api/classes", "/api/classes/columns"} {
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path+"?task="+task, nil))
+			assert.Equal(t, http.StatusNotFound, w.Code, path+"?task="+task)
+		}
+	}
+}
+
+func TestServer_loadClassHistogram_Reload(t *testing.T) {
+	dataDir := t.TempDir()
+	taskDir := filepath.Join(dataDir, "task-1")
+	require.NoError(t, os.Mkdir(taskDir, 0o755))
+	filename := filepath.Join(taskDir, "class_histogram.json")
+	require.NoError(t, os.WriteFile(filename, []byte(`{"total_classes": 1}`), 0o644))
+	s := NewServer(dataDir, 0, nil)
+
+	first, err := s.loadClassHistogram("task-1")
+	require.NoError(t, err)
+	again, err := s.loadClassHistogram("task-1")
+	require.NoError(t, err)
+	assert.Same(t, first, again)
+
+	// A rewritten histogram is parsed again
+	require.NoError(t, os.WriteFile(filename, []byte(`{"total_classes": 2}`), 0o644))
+	info, err := os.Stat(filename)
+	require.NoError(t, err)
+	later := info.ModTime().Add(time.Second)
+	require.NoError(t, os.Chtimes(filename, later, later))
+	reloaded, err := s.loadClassHistogram("task-1")
+	require.NoError(t, err)
+	assert.Equal(t, 2, reloaded.TotalClasses)
+}
+
+func TestServer_handleClassColumns(t *testing.T) {
+	h := newClassesTestServer(t, testClassHistogram())
+
+	tests := []struct {
+		name  string
+		query string
+		want  string
+	}{
+		{"all columns", "sort=name&order=asc&filter=^com", `{"rows":2,"total_classes":6,"total_instances":182,"total_size":8848,"columns":{` +
+			`"class_name":["com.example.Cache","com.example.Entry"],"instance_count":[1,50],"total_size":[32,1600],"retained_size":[10000,1600]}}`},
+		{"selected columns in response order", "columns=retained_size,%20class_name&sort=retained&loader=0x100",
+			`{"rows":2,"total_classes":6,"total_instances":182,"total_size":8848,"columns":{` +
+				`"class_name":["com.example.Cache","com.example.Entry"],"retained_size":[10000,1600]}}`},
+		{"default sort", "columns=class_name&loader=bootstrap", `{"rows":3,"total_classes":6,"total_instances":182,"total_size":8848,"columns":{` +
+			`"class_name":["int[]","java.lang.String","java.lang.String[]"]}}`},
+		{"no match", "columns=instance_count&filter=^org", `{"rows":0,"total_classes":6,"total_instances":182,"total_size":8848,"columns":{` +
+			`"instance_count":[]}}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/classes/columns?task=task-1&"+tt.query, nil))
+			require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+			assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+			assert.JSONEq(t, tt.want, w.Body.String())
+			assert.Equal(t, tt.want+"\n", w.Body.String())
+		})
+	}
+
+	for _, query := range []string{"columns=size", "columns=class_name,", "filter=(", "sort=size", "loader=zz"} {
+		t.Run(query, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/classes/columns?task=task-1&"+query, nil))
+			assert.Equal(t, http.StatusBadRequest, w.Code)
+		})
+	}
+}
+
+func TestServer_handleClassColumns_Large(t *testing.T) {
+	// Enough rows to flush midway, with names that need escaping
+	histogram := &classHistogramFile{}
+	for i := 0; i < 2*classColumnsFlushRows+1; i++ {
+		histogram.Classes = append(histogram.Classes, &hprof.ClassStats{
+			ClassName: fmt.Sprintf("com.example.\"Q\"$%05d", i), InstanceCount: int64(i), TotalSize: int64(i),
+		})
+		histogram.TotalClasses++
+	}
+	h := newClassesTestServer(t, histogram)
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/classes/columns?task=task-1&sort=count&order=asc", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.True(t, w.Flushed)
+
+	var columns ClassColumns
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &columns))
+	rows := len(histogram.Classes)
+	assert.Equal(t, rows, columns.Rows)
+	require.Len(t, columns.Columns.ClassName, rows)
+	require.Len(t, columns.Columns.InstanceCount, rows)
+	require.Len(t, columns.Columns.RetainedSize, rows)
+	for i := 0; i < rows; i++ {
+		assert.Equal(t, histogram.Classes[i].ClassName, columns.Columns.ClassName[i])
+		assert.Equal(t, int64(i), columns.Columns.InstanceCount[i])
+	}
+}
@@ -0,0 +1,91 @@
+package webui
+
+import (
+	"encoding/json"
+	"math"
+	"net/http"
+	"sort"
+
+	"github.com/perf-analysis/internal/flamegraph"
+)
+
+// Number of functions of flame graph diff responses.
+const (
+	flameDiffDefaultTop = 100
+	flameDiffMaxTop     = 1000
+)
+
+// FlameGraphDiffResponse is the differential flame graph of two tasks, with
+// the functions whose share of the samples changed the most.
+type FlameGraphDiffResponse struct {
+	BaseTask   string `json:"base_task"`
+	TargetTask string `json:"target_task"`
+	Type       string `json:"type"`
+	*flamegraph.DiffFlameGraph
+	// Functions holds the functions with the largest change of self time,
+	// regressions and improvements alike
+	Functions []*flamegraph.FunctionDiff `json:"functions"`
+}
+
+// handleFlameGraphDiff compares the flame graphs of two tasks, for the
+// red/blue rendering of regressions and improvements.
+func (s *Server) handleFlameGraphDiff(w http.ResponseWriter, r *http.Request) {
+	var req flameDiffRequest
+	if err := decodeQuery(r, &req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	fgType, ok := ParseFlameGraphType(req.Type)
+	if !ok {
+		http.Error(w, "Unknown flame graph type: "+req.Type, http.StatusBadRequest)
+		return
+	}
+	top := req.Top
+	if top <= 0 {
+		top = flameDiffDefaultTop
+	}
+	top = min(top, flameDiffMaxTop)
+
+	diff, err := s.fgService.GetDiffFlameGraph(r.Context(), req.Base, req.Target, fgType)
+	if err != nil {
+		http.Error(w, "Failed to load flame graphs: "+err.Error(), http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	json.NewEncoder(w).Encode(&FlameGraphDiffResponse{
+		BaseTask:   req.Base,
+		TargetTask: req.Target,
+		Type:       string(fgType),
+		DiffFlameGraph: &flamegraph.DiffFlameGraph{
+			Root:             diff.Prune(staticReportMinFlameFraction),
+			BaseTotalSamples: diff.BaseTotalSamples,
+			TotalSamples:     diff.TotalSamples,
+			MaxDepth:         diff.MaxDepth,
+		},
+		Functions: topChangedFunctions(diff.Functions(), top),
+	})
+}
+
+// topChangedFunctions returns the n functions with the largest change of
+// self time, most regressed first.
+func topChangedFunctions(funcs []*flamegraph.FunctionDiff, n int) []*flamegraph.FunctionDiff {
+	changed := make([]*flamegraph.FunctionDiff, 0, len(funcs))
+	for _, f := range funcs {
+		if f.DeltaSelfPercent != 0 || f.DeltaTotalPercent != 0 {
+			changed = append(changed, f)
+		}
+	}
+	if len(changed) <= n {
+		return changed
+	}
+	sort.SliceStable(changed, func(i, j int) bool {
+		return math.Abs(changed[i].DeltaSelfPercent) > math.Abs(changed[j].DeltaSelfPercent)
+	})
+	changed = changed[:n]
+	sort.SliceStable(changed, func(i, j int) bool {
+		return changed[i].DeltaSelfPercent > changed[j].DeltaSelfPercent
+	})
+	return changed
+}
@@ -0,0 +1,52 @@
+package webui
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/perf-analysis/internal/flamegraph"
+)
+
+// handleFlameGraphSpeedscope downloads a flame graph as a speedscope file.
+func (s *Server) handleFlameGraphSpeedscope(w http.ResponseWriter, r *http.Request) {
+	s.exportFlameGraph(w, r, ".speedscope.json", func(fg *flamegraph.FlameGraph, name string) any {
+		return flamegraph.ToSpeedscope(fg, name)
+	})
+}
+
+// handleFlameGraphTrace downloads a flame graph as Chrome trace events.
+func (s *Server) handleFlameGraphTrace(w http.ResponseWriter, r *http.Request) {
+	s.exportFlameGraph(w, r, ".trace.json", func(fg *flamegraph.FlameGraph, name string) any {
+		return flamegraph.ToTraceEvents(fg, name)
+	})
+}
+
+// exportFlameGraph downloads the flame graph of a request converted to
+// another format, as a file named after the task and the graph type.
+func (s *Server) exportFlameGraph(w http.ResponseWriter, r *http.Request, extension string, convert func(fg *flamegraph.FlameGraph, name string) any) {
+	var req flameViewRequest
+	if err := decodeQuery(r, &req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	fgType, ok := ParseFlameGraphType(req.Type)
+	if !ok {
+		http.Error(w, "Unknown flame graph type: "+req.Type, http.StatusBadRequest)
+		return
+	}
+
+	taskID := s.resolveTask(req.Task)
+	fg, err := s.fgService.GetFlameGraph(r.Context(), taskID, fgType)
+	if err != nil {
+		http.Error(w, "Failed to load flame graph: "+err.Error(), http.StatusNotFound)
+		return
+	}
+	if req.CollapseInlined {
+		fg = fg.CollapseInlined()
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Content-Disposition", "attachment; filename=\""+taskID+"-"+string(fgType)+extension+"\"")
+	json.NewEncoder(w).Encode(convert(fg, taskID+" "+string(fgType)))
+}
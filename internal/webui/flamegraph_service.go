@@ -8,6 +8,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 	"sync"
 	"time"
 
@@ -37,6 +38,30 @@ const (
 	FlameGraphTypePProfMutex FlameGraphType = "pprof-mutex"
 )
 
+// ParseFlameGraphType returns the flame graph type of a type query
+// parameter, CPU if empty. It reports false for unknown types.
+func ParseFlameGraphType(s string) (FlameGraphType, bool) {
+	switch strings.ToLower(s) {
+	case "memory", "alloc", "heap":
+		return FlameGraphTypeMemory, true
+	case "tracing", "latency", "wall":
+		return FlameGraphTypeTracing, true
+	case "cpu", "":
+		return FlameGraphTypeCPU, true
+	case "pprof-goroutine", "goroutine":
+		return FlameGraphTypePProfGoroutine, true
+	case "pprof-heap-inuse", "heap-inuse", "inuse":
+		return FlameGraphTypePProfHeapInuse, true
+	case "pprof-heap-alloc", "heap-alloc":
+		return FlameGraphTypePProfHeapAlloc, true
+	case "pprof-block", "block":
+		return FlameGraphTypePProfBlock, true
+	case "pprof-mutex", "mutex":
+		return FlameGraphTypePProfMutex, true
+	}
+	return "", false
+}
+
 // FlameGraphLoader defines the interface for loading flame graph data.
 type FlameGraphLoader interface {
 	// Load loads flame graph data for a task.
@@ -66,6 +91,30 @@ func NewFlameGraphService(dataDir string) *FlameGraphService {
 	return svc
 }
 
+// newAllFlameGraphService creates a FlameGraphService with the loaders of all
+// flame graph types.
+func newAllFlameGraphService(dataDir string) *FlameGraphService {
+	svc := NewFlameGraphService(dataDir)
+	// Register additional loaders for memory and tracing
+	svc.RegisterLoader(NewMemoryFlameGraphLoader())
+	svc.RegisterLoader(NewTracingFlameGraphLoader())
+	// Register pprof loaders
+	svc.RegisterLoader(NewPProfGoroutineFlameGraphLoader())
+	svc.RegisterLoader(NewPProfHeapInuseFlameGraphLoader())
+	svc.RegisterLoader(NewPProfHeapAllocFlameGraphLoader())
+	svc.RegisterLoader(NewPProfBlockFlameGraphLoader())
+	svc.RegisterLoader(NewPProfMutexFlameGraphLoader())
+	return svc
+}
+
+// LoadTaskFlameGraph loads the flame graph of a type of a task directory,
+// e.g. to export it from the command line.
+func LoadTaskFlameGraph(ctx context.Context, taskDir string, fgType FlameGraphType) (*flamegraph.FlameGraph, error) {
+	taskDir = filepath.Clean(taskDir)
+	svc := newAllFlameGraphService(filepath.Dir(taskDir))
+	return svc.GetFlameGraph(ctx, filepath.Base(taskDir), fgType)
+}
+
 // RegisterLoader registers a flame graph loader for a specific type.
 func (s *FlameGraphService) RegisterLoader(loader FlameGraphLoader) {
 	s.loaders[loader.SupportedType()] = loader
@@ -98,6 +147,20 @@ func (s *FlameGraphService) GetFlameGraph(ctx context.Context, taskID string, fg
 	return fg, nil
 }
 
+// GetDiffFlameGraph compares the flame graphs of a type of two tasks: a
+// base task, e.g. before a change, and a target task.
+func (s *FlameGraphService) GetDiffFlameGraph(ctx context.Context, baseTaskID, targetTaskID string, fgType FlameGraphType) (*flamegraph.DiffFlameGraph, error) {
+	base, err := s.GetFlameGraph(ctx, baseTaskID, fgType)
+	if err != nil {
+		return nil, fmt.Errorf("base task %s: %w", baseTaskID, err)
+	}
+	target, err := s.GetFlameGraph(ctx, targetTaskID, fgType)
+	if err != nil {
+		return nil, fmt.Errorf("target task %s: %w", targetTaskID, err)
+	}
+	return flamegraph.NewDiffFlameGraph(base, target), nil
+}
+
 // InvalidateCache invalidates the cache for a task.
 func (s *FlameGraphService) InvalidateCache(taskID string) {
 	// Delete all type caches for this task
@@ -445,7 +508,7 @@ func (l *PProfMutexFlameGraphLoader) SupportedType() FlameGraphType {
 // Load loads mutex flame graph data for a task.
 func (l *PProfMutexFlameGraphLoader) Load(ctx context.Context, taskDir string) (*flamegraph.FlameGraph, error) {
 	subDirs := []string{"mutex", "."}
-	fileNames := []string{"mutex_flamegraph.json.gz", "collapsed_data.json.gz"}
+	fileNames := []string{"mutex_flamegraph.json.gz", "lock_data.json.gz", "collapsed_data.json.gz"}
 
 	for _, subDir := range subDirs {
 		dir := filepath.Join(taskDir, subDir)
@@ -0,0 +1,174 @@
+package webui
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/perf-analysis/internal/parser/hprof"
+	"github.com/perf-analysis/pkg/writer"
+)
+
+// Number of objects of object-level heap diff responses.
+const (
+	heapObjectDiffDefaultTop = 100
+	heapObjectDiffMaxTop     = 1000
+)
+
+// HeapDiffResponse is the class-level comparison of two heap analysis tasks.
+type HeapDiffResponse struct {
+	BaseTask   string `json:"base_task"`
+	TargetTask string `json:"target_task"`
+	*hprof.HeapDiff
+}
+
+// handleHeapDiff compares the class histograms of two tasks.
+// Supports CSV/TSV via Accept header or the format query parameter.
+func (s *Server) handleHeapDiff(w http.ResponseWriter, r *http.Request) {
+	var req heapDiffRequest
+	if err := decodeQuery(r, &req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	base, err := s.loadClassHistogram(req.Base)
+	if err != nil {
+		http.Error(w, "Class histogram not found for base task "+req.Base, http.StatusNotFound)
+		return
+	}
+	target, err := s.loadClassHistogram(req.Target)
+	if err != nil {
+		http.Error(w, "Class histogram not found for target task "+req.Target, http.StatusNotFound)
+		return
+	}
+
+	analyzer := &hprof.DiffAnalyzer{IncludeUnchanged: req.IncludeUnchanged}
+	diff := analyzer.Diff(base.Classes, target.Classes)
+
+	if format, ok := negotiateTableFormat(r); ok {
+		w.Header().Set("Content-Type", format.ContentType())
+		w.Header().Set("Content-Disposition", "attachment; filename=\"heap_diff_"+req.Base+"_"+req.Target+format.Extension()+"\"")
+		w.Header().Set("Access-Control-Allow-Origin", "*")
+		w.Header().Set("Vary", "Accept")
+		if err := writer.NewTableWriter(format).Write(buildHeapDiffTable(diff), w); err != nil && s.logger != nil {
+			s.logger.Warn("Failed to write heap diff export: %v", err)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Vary", "Accept")
+	json.NewEncoder(w).Encode(&HeapDiffResponse{BaseTask: req.Base, TargetTask: req.Target, HeapDiff: diff})
+}
+
+// HeapObjectDiffResponse is the object-level comparison of two heap analysis
+// tasks.
+type HeapObjectDiffResponse struct {
+	BaseTask   string `json:"base_task"`
+	TargetTask string `json:"target_task"`
+	// Dominators describes how the target dominators were derived from the base
+	Dominators *hprof.DominatorUpdate `json:"dominators"`
+	// Objects are sorted by absolute retained size delta descending
+	Objects []*ObjectDiffResponse `json:"objects"`
+}
+
+// ObjectDiffResponse is the change of the retained size of one object.
+type ObjectDiffResponse struct {
+	ObjectID       string                `json:"object_id"`
+	ClassName      string                `json:"class_name"`
+	Status         hprof.ClassDiffStatus `json:"status"`
+	BaseRetained   int64                 `json:"base_retained"`
+	TargetRetained int64                 `json:"target_retained"`
+	DeltaRetained  int64                 `json:"delta_retained"`
+}
+
+// handleHeapObjectDiff compares the retained sizes of the objects of two
+// tasks. The dominators of the target are derived incrementally from those
+// of the base, so both reference graphs are loaded from disk rather than
+// taken from the snapshot cache, whose graphs are frozen.
+func (s *Server) handleHeapObjectDiff(w http.ResponseWriter, r *http.Request) {
+	var req heapObjectDiffRequest
+	if err := decodeQuery(r, &req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	top := req.Top
+	if top <= 0 {
+		top = heapObjectDiffDefaultTop
+	}
+	top = min(top, heapObjectDiffMaxTop)
+
+	baseDir, err := s.existingTaskDir(req.Base)
+	if err != nil {
+		http.Error(w, "Task not found: "+req.Base, http.StatusNotFound)
+		return
+	}
+	targetDir, err := s.existingTaskDir(req.Target)
+	if err != nil {
+		http.Error(w, "Task not found: "+req.Target, http.StatusNotFound)
+		return
+	}
+	base, err := hprof.LoadReferenceGraph(baseDir)
+	if err != nil {
+		http.Error(w, "Reference graph not found for base task "+req.Base, http.StatusNotFound)
+		return
+	}
+	target, err := hprof.LoadReferenceGraph(targetDir)
+	if err != nil {
+		http.Error(w, "Reference graph not found for target task "+req.Target, http.StatusNotFound)
+		return
+	}
+
+	analyzer := &hprof.DiffAnalyzer{IncludeUnchanged: req.IncludeUnchanged}
+	diff, err := analyzer.DiffObjects(r.Context(), base, target, top)
+	if err != nil {
+		http.Error(w, "Failed to compare heaps: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	resp := &HeapObjectDiffResponse{
+		BaseTask:   req.Base,
+		TargetTask: req.Target,
+		Dominators: diff.Dominators,
+		Objects:    make([]*ObjectDiffResponse, 0, len(diff.Objects)),
+	}
+	for _, d := range diff.Objects {
+		resp.Objects = append(resp.Objects, &ObjectDiffResponse{
+			ObjectID:       formatObjectID(d.ObjectID),
+			ClassName:      d.ClassName,
+			Status:         d.Status,
+			BaseRetained:   d.BaseRetained,
+			TargetRetained: d.TargetRetained,
+			DeltaRetained:  d.DeltaRetained,
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// buildHeapDiffTable converts a heap diff into a table, one row per class.
+func buildHeapDiffTable(diff *hprof.HeapDiff) *writer.Table {
+	table := &writer.Table{
+		Header: []string{"class_name", "status", "base_instances", "target_instances", "delta_instances",
+			"base_size", "target_size", "delta_size", "delta_retained", "growth_percent"},
+		Rows: make([][]string, 0, len(diff.Classes)),
+	}
+	for _, d := range diff.Classes {
+		table.Rows = append(table.Rows, []string{
+			d.ClassName,
+			string(d.Status),
+			strconv.FormatInt(d.BaseInstances, 10),
+			strconv.FormatInt(d.TargetInstances, 10),
+			strconv.FormatInt(d.DeltaInstances, 10),
+			strconv.FormatInt(d.BaseSize, 10),
+			strconv.FormatInt(d.TargetSize, 10),
+			strconv.FormatInt(d.DeltaSize, 10),
+			strconv.FormatInt(d.DeltaRetained, 10),
+			strconv.FormatFloat(d.GrowthPercent, 'f', 2, 64),
+		})
+	}
+	return table
+}
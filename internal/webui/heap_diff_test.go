@@ -0,0 +1,74 @@
+package webui
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/perf-analysis/internal/parser/hprof"
+)
+
+// writeTestRefGraph saves g as the reference graph of a new task.
+func writeTestRefGraph(t *testing.T, dataDir, taskID string, g *hprof.ReferenceGraph) {
+	taskDir := filepath.Join(dataDir, taskID)
+	require.NoError(t, os.Mkdir(taskDir, 0o755))
+	_, err := g.SerializeToFile(filepath.Join(taskDir, hprof.RefGraphFileName), hprof.DefaultSerializeOptions())
+	require.NoError(t, err)
+}
+
+func TestServer_handleHeapObjectDiff(t *testing.T) {
+	// root(1) -> cache(2) -> entry(3); the target adds entry(4) to the cache
+	newGraph := func() *hprof.ReferenceGraph {
+		g := hprof.NewReferenceGraph()
+		g.SetClassName(10, "com.example.Root")
+		g.SetClassName(11, "com.example.Cache")
+		g.SetClassName(12, "com.example.Entry")
+		g.SetObjectInfo(1, 10, 16)
+		g.SetObjectInfo(2, 11, 32)
+		g.SetObjectInfo(3, 12, 100)
+		g.AddReference(hprof.ObjectReference{FromObjectID: 1, ToObjectID: 2, FromClassID: 10})
+		g.AddReference(hprof.ObjectReference{FromObjectID: 2, ToObjectID: 3, FromClassID: 11})
+		g.AddGCRoot(&hprof.GCRoot{ObjectID: 1, Type: hprof.GCRootJNIGlobal})
+		return g
+	}
+	dataDir := t.TempDir()
+	writeTestRefGraph(t, dataDir, "base", newGraph())
+	target := newGraph()
+	target.SetObjectInfo(4, 12, 200)
+	target.AddReference(hprof.ObjectReference{FromObjectID: 2, ToObjectID: 4, FromClassID: 11})
+	writeTestRefGraph(t, dataDir, "target", target)
+
+	s := NewServer(dataDir, 0, nil)
+	mux := http.NewServeMux()
+	s.registerAPIRoutes(mux)
+
+	t.Run("diff", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/heap/diff/objects?base=base&target=target&top=2", nil))
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+		var resp HeapObjectDiffResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		require.NotNil(t, resp.Dominators)
+		assert.Equal(t, 3, resp.Dominators.MatchedObjects)
+		assert.Equal(t, 1, resp.Dominators.NewObjects)
+		require.Len(t, resp.Objects, 2)
+		assert.Equal(t, "0x1", resp.Objects[0].ObjectID)
+		assert.Equal(t, hprof.ClassDiffGrown, resp.Objects[0].Status)
+		assert.Equal(t, int64(200), resp.Objects[0].DeltaRetained)
+	})
+
+	for _, query := range []string{"base=missing&target=target", "base=base&target=..", "base=base&target=%2Fetc"} {
+		t.Run(query, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/heap/diff/objects?"+query, nil))
+			assert.Equal(t, http.StatusNotFound, w.Code)
+		})
+	}
+}
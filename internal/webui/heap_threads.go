@@ -0,0 +1,27 @@
+package webui
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+)
+
+// handleHeapThreads returns the thread overview (threads.json) of a heap
+// analysis task: threads with their stack frames and stack locals.
+func (s *Server) handleHeapThreads(w http.ResponseWriter, r *http.Request) {
+	var req taskRequest
+	if err := decodeQuery(r, &req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	data, err := os.ReadFile(filepath.Join(s.taskDirFromRequest(r), "threads.json"))
+	if err != nil {
+		http.Error(w, "Thread overview not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Write(data)
+}
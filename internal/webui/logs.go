@@ -0,0 +1,275 @@
+package webui
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"golang.org/x/net/websocket"
+)
+
+// AnalysisLogFileName is the analyzer log in a task directory, written by analyze.
+const AnalysisLogFileName = "analysis.log"
+
+const (
+	// logPollInterval is how often a streamed log is checked for new lines.
+	logPollInterval = 500 * time.Millisecond
+	// defaultLogTailLines and maxLogTailLines bound the lines sent first.
+	defaultLogTailLines = 500
+	maxLogTailLines     = 10000
+	// maxLogTailBytes is how far back from the end the initial tail reads.
+	maxLogTailBytes = 4 << 20
+	// maxLogReadBytes is the most read per poll; a longer line is split.
+	maxLogReadBytes = 256 << 10
+)
+
+// LogEvent is a batch of analyzer log lines, sent by /api/logs and
+// /api/logs/stream.
+type LogEvent struct {
+	Lines []string `json:"lines"`
+	// Reset is set when the log was truncated or replaced, e.g. because the
+	// task was analyzed again; earlier lines are stale
+	Reset bool `json:"reset,omitempty"`
+	// Missing is set while the task has no analysis log
+	Missing bool `json:"missing,omitempty"`
+}
+
+// logTailer reads lines appended to a log file.
+type logTailer struct {
+	filename string
+	offset   int64 // start of the first unread line
+	missing  bool
+}
+
+// tail returns the last n complete lines and positions the tailer after them.
+func (t *logTailer) tail(n int) (*LogEvent, error) {
+	f, err := os.Open(t.filename)
+	if os.IsNotExist(err) {
+		t.missing = true
+		return &LogEvent{Lines: []string{}, Missing: true}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	start := info.Size() - maxLogTailBytes
+	if start < 0 {
+		start = 0
+	}
+	data := make([]byte, info.Size()-start)
+	if _, err := io.ReadFull(io.NewSectionReader(f, start, int64(len(data))), data); err != nil {
+		return nil, err
+	}
+
+	// Stop after the last complete line; skip the first partial line of a
+	// window that starts mid-file
+	end := bytes.LastIndexByte(data, '\n') + 1
+	t.offset = start + int64(end)
+	data = data[:end]
+	if start > 0 {
+		if i := bytes.IndexByte(data, '\n'); i >= 0 {
+			data = data[i+1:]
+		}
+	}
+
+	lines := splitLogLines(data)
+	if len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+	return &LogEvent{Lines: lines}, nil
+}
+
+// poll returns the lines appended since the last call, or nil if there are none.
+func (t *logTailer) poll() (*LogEvent, error) {
+	f, err := os.Open(t.filename)
+	if os.IsNotExist(err) {
+		if t.missing {
+			return nil, nil
+		}
+		t.missing = true
+		t.offset = 0
+		return &LogEvent{Lines: []string{}, Reset: true, Missing: true}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	event := &LogEvent{Lines: []string{}}
+	if t.missing {
+		t.missing = false
+		event.Reset = true
+	}
+	if info.Size() < t.offset {
+		t.offset = 0
+		event.Reset = true
+	}
+
+	size := info.Size() - t.offset
+	if size > maxLogReadBytes {
+		size = maxLogReadBytes
+	}
+	data := make([]byte, size)
+	n, err := f.ReadAt(data, t.offset)
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+	data = data[:n]
+
+	end := bytes.LastIndexByte(data, '\n') + 1
+	if end == 0 && len(data) == maxLogReadBytes {
+		end = len(data)
+	}
+	t.offset += int64(end)
+	event.Lines = splitLogLines(data[:end])
+	if len(event.Lines) == 0 && !event.Reset {
+		return nil, nil
+	}
+	return event, nil
+}
+
+// splitLogLines splits complete lines, dropping line terminators.
+func splitLogLines(data []byte) []string {
+	lines := strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+	if len(lines) == 1 && lines[0] == "" {
+		return []string{}
+	}
+	for i, line := range lines {
+		lines[i] = strings.TrimSuffix(line, "\r")
+	}
+	return lines
+}
+
+// handleLogs returns the end of the analyzer log of a task.
+func (s *Server) handleLogs(w http.ResponseWriter, r *http.Request) {
+	tailer, lines, ok := s.logTailerFromRequest(w, r)
+	if !ok {
+		return
+	}
+	event, err := tailer.tail(lines)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to read log: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	json.NewEncoder(w).Encode(event)
+}
+
+// handleLogStream tails the analyzer log of a task over a WebSocket. The
+// last lines are sent first, then new lines as the log grows, each batch as
+// a JSON LogEvent text message.
+func (s *Server) handleLogStream(w http.ResponseWriter, r *http.Request) {
+	tailer, lines, ok := s.logTailerFromRequest(w, r)
+	if !ok {
+		return
+	}
+	server := websocket.Server{
+		Handshake: checkSameOrigin,
+		Handler: func(ws *websocket.Conn) {
+			// The stream outlives the server's read and write timeouts
+			ws.SetDeadline(time.Time{})
+			s.streamLog(ws, tailer, lines)
+		},
+	}
+	server.ServeHTTP(w, r)
+}
+
+// logTailerFromRequest validates a log request; it writes the error response
+// and returns false if the request is invalid.
+func (s *Server) logTailerFromRequest(w http.ResponseWriter, r *http.Request) (*logTailer, int, bool) {
+	var req logRequest
+	if err := decodeQuery(r, &req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return nil, 0, false
+	}
+	lines := req.Lines
+	if lines == 0 {
+		lines = defaultLogTailLines
+	}
+	if lines < 0 || lines > maxLogTailLines {
+		http.Error(w, fmt.Sprintf("lines must be between 1 and %d", maxLogTailLines), http.StatusBadRequest)
+		return nil, 0, false
+	}
+	taskID := s.resolveTask(req.Task)
+	taskDir, err := s.existingTaskDir(taskID)
+	if err != nil {
+		http.Error(w, "Task not found: "+taskID, http.StatusNotFound)
+		return nil, 0, false
+	}
+	return &logTailer{filename: filepath.Join(taskDir, AnalysisLogFileName)}, lines, true
+}
+
+// streamLog sends the log to a WebSocket client until it disconnects.
+func (s *Server) streamLog(ws *websocket.Conn, tailer *logTailer, lines int) {
+	defer ws.Close()
+
+	// The client sends nothing; reading detects when it goes away
+	closed := make(chan struct{})
+	go func() {
+		io.Copy(io.Discard, ws)
+		close(closed)
+	}()
+
+	event, err := tailer.tail(lines)
+	if err != nil {
+		if s.logger != nil {
+			s.logger.Warn("Failed to read log %s: %v", tailer.filename, err)
+		}
+		return
+	}
+	if websocket.JSON.Send(ws, event) != nil {
+		return
+	}
+
+	ticker := time.NewTicker(logPollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-closed:
+			return
+		case <-ticker.C:
+		}
+		event, err := tailer.poll()
+		if err != nil {
+			if s.logger != nil {
+				s.logger.Warn("Failed to read log %s: %v", tailer.filename, err)
+			}
+			return
+		}
+		if event != nil && websocket.JSON.Send(ws, event) != nil {
+			return
+		}
+	}
+}
+
+// checkSameOrigin accepts WebSocket handshakes from pages of this server
+// only, so other sites cannot read logs with the user's credentials.
+func checkSameOrigin(config *websocket.Config, r *http.Request) error {
+	origin, err := url.Parse(r.Header.Get("Origin"))
+	if err != nil || origin.Host == "" {
+		return fmt.Errorf("missing origin")
+	}
+	if !strings.EqualFold(origin.Host, r.Host) {
+		return fmt.Errorf("cross-origin request from %s", origin.Host)
+	}
+	config.Origin = origin
+	return nil
+}
@@ -0,0 +1,55 @@
+package webui
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/websocket"
+)
+
+func TestServer_handleLogStream_OutlivesServerTimeouts(t *testing.T) {
+	dataDir := t.TempDir()
+	taskDir := filepath.Join(dataDir, "task-1")
+	require.NoError(t, os.Mkdir(taskDir, 0o755))
+	logFile := filepath.Join(taskDir, AnalysisLogFileName)
+	require.NoError(t, os.WriteFile(logFile, []byte("first\n"), 0o644))
+
+	// Serve the API routes, whose middleware wraps the response writer
+	s := NewServer(dataDir, 0, nil)
+	mux := http.NewServeMux()
+	s.registerAPIRoutes(mux)
+	srv := httptest.NewUnstartedServer(s.withAuth(mux))
+	srv.Config.ReadTimeout = 200 * time.Millisecond
+	srv.Config.WriteTimeout = 200 * time.Millisecond
+	srv.Start()
+	defer srv.Close()
+
+	url := "ws" + strings.TrimPrefix(srv.URL, "http") + "/api/logs/stream?task=task-1"
+	ws, err := websocket.Dial(url, "", srv.URL)
+	require.NoError(t, err)
+	defer ws.Close()
+
+	var event LogEvent
+	require.NoError(t, websocket.JSON.Receive(ws, &event))
+	assert.Equal(t, []string{"first"}, event.Lines)
+
+	// Append after both server timeouts have passed
+	time.Sleep(500 * time.Millisecond)
+	f, err := os.OpenFile(logFile, os.O_APPEND|os.O_WRONLY, 0)
+	require.NoError(t, err)
+	_, err = f.WriteString("second\n")
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	require.NoError(t, ws.SetReadDeadline(time.Now().Add(5*time.Second)))
+	event = LogEvent{}
+	require.NoError(t, websocket.JSON.Receive(ws, &event))
+	assert.Equal(t, []string{"second"}, event.Lines)
+}
@@ -0,0 +1,430 @@
+package webui
+
+import (
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/perf-analysis/internal/flamegraph"
+	"github.com/perf-analysis/internal/parser/hprof"
+)
+
+// Number of allocation sites, classes and CPU hotspots of a memory story.
+const (
+	memoryStoryTopSites    = 30
+	memoryStoryTopClasses  = 30
+	memoryStoryTopHotspots = 30
+)
+
+// Files of the profiles a memory story is built from, as written by the
+// analyzers (CPU and allocation flame graphs, class histogram of a heap dump).
+var (
+	memoryStoryCPUFiles   = []string{"collapsed_data.json.gz", "cpu_flamegraph.json.gz"}
+	memoryStoryAllocFiles = []string{"alloc_data.json.gz", "memory_flamegraph.json.gz", "alloc_flamegraph.json.gz"}
+	memoryStoryHeapFiles  = []string{"class_histogram.json"}
+)
+
+// MemoryStoryResponse links the allocation profile, the heap dump and the
+// CPU profile of a task: which stacks allocate the classes filling the heap,
+// and how much CPU time their allocating methods take.
+type MemoryStoryResponse struct {
+	Task     string `json:"task"`
+	HasCPU   bool   `json:"has_cpu"`
+	HasAlloc bool   `json:"has_alloc"`
+	HasHeap  bool   `json:"has_heap"`
+
+	CPUTotal        int64  `json:"cpu_total,omitempty"`
+	AllocationTotal int64  `json:"allocation_total,omitempty"`
+	AllocationUnit  string `json:"allocation_unit,omitempty"`
+	HeapTotalSize   int64  `json:"heap_total_size,omitempty"`
+
+	// Sites are the top allocation sites, by allocated amount
+	Sites []MemoryStorySite `json:"sites,omitempty"`
+	// Classes are the classes of the heap and of the allocation profile,
+	// by retained size, then by allocated amount
+	Classes []MemoryStoryClass `json:"classes,omitempty"`
+	// Hotspots are the top CPU functions by self time, with the amount
+	// allocated under them
+	Hotspots []MemoryStoryHotspot `json:"hotspots,omitempty"`
+}
+
+// MemoryStorySite is an allocation site: a method allocating a class.
+type MemoryStorySite struct {
+	Frame string `json:"frame"` // the allocating method
+	Class string `json:"class"`
+	// Stack is the heaviest stack of the site, root first, ending with the
+	// allocating method
+	Stack      []string `json:"stack,omitempty"`
+	Allocated  int64    `json:"allocated"`
+	Percentage float64  `json:"percentage"`
+
+	// CPUTotalPct and CPUSelfPct are the CPU time of the allocating method
+	CPUTotalPct float64 `json:"cpu_total_pct,omitempty"`
+	CPUSelfPct  float64 `json:"cpu_self_pct,omitempty"`
+	// Heap is the class in the heap dump, nil if it has no live instance
+	Heap *hprof.ClassStats `json:"heap,omitempty"`
+}
+
+// MemoryStoryClass is a class of the heap dump or of the allocation profile.
+type MemoryStoryClass struct {
+	ClassName     string  `json:"class_name"`
+	Allocated     int64   `json:"allocated"`
+	AllocationPct float64 `json:"allocation_pct"`
+	InstanceCount int64   `json:"instance_count"`
+	ShallowSize   int64   `json:"shallow_size"`
+	RetainedSize  int64   `json:"retained_size,omitempty"`
+	HeapPct       float64 `json:"heap_pct"`
+	// TopSites are the methods allocating the class the most
+	TopSites []string `json:"top_sites,omitempty"`
+}
+
+// MemoryStoryHotspot is a CPU hotspot.
+type MemoryStoryHotspot struct {
+	Name        string  `json:"name"`
+	CPUSelfPct  float64 `json:"cpu_self_pct"`
+	CPUTotalPct float64 `json:"cpu_total_pct"`
+	// Allocated is the amount allocated by the function and its callees
+	Allocated     int64   `json:"allocated,omitempty"`
+	AllocationPct float64 `json:"allocation_pct,omitempty"`
+}
+
+// hasMemoryStory reports whether a task directory has at least two of a CPU
+// profile, an allocation profile and a class histogram.
+func hasMemoryStory(taskDir string) bool {
+	count := 0
+	for _, files := range [][]string{memoryStoryCPUFiles, memoryStoryAllocFiles, memoryStoryHeapFiles} {
+		for _, name := range files {
+			if _, err := os.Stat(filepath.Join(taskDir, name)); err == nil {
+				count++
+				break
+			}
+		}
+	}
+	return count >= 2
+}
+
+// handleMemoryStory returns the memory story of a task.
+func (s *Server) handleMemoryStory(w http.ResponseWriter, r *http.Request) {
+	var req taskRequest
+	if err := decodeQuery(r, &req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	taskID := s.resolveTask(req.Task)
+
+	cpu, _ := s.fgService.GetFlameGraph(r.Context(), taskID, FlameGraphTypeCPU)
+	alloc, _ := s.fgService.GetFlameGraph(r.Context(), taskID, FlameGraphTypeMemory)
+	histogram, _ := s.loadClassHistogram(taskID)
+
+	story := buildMemoryStory(cpu, alloc, histogram)
+	story.Task = taskID
+	sources := 0
+	for _, has := range []bool{story.HasCPU, story.HasAlloc, story.HasHeap} {
+		if has {
+			sources++
+		}
+	}
+	if sources < 2 {
+		http.Error(w, "Memory story needs two of a CPU profile, an allocation profile and a heap dump", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	json.NewEncoder(w).Encode(story)
+}
+
+// buildMemoryStory links the profiles of a task, any of which may be nil.
+func buildMemoryStory(cpu, alloc *flamegraph.FlameGraph, histogram *classHistogramFile) *MemoryStoryResponse {
+	// Wall-clock and off-CPU profiles are written like CPU profiles, with
+	// values in nanoseconds
+	story := &MemoryStoryResponse{
+		HasCPU:   cpu != nil && cpu.Root != nil && cpu.TotalSamples > 0 && cpu.Unit != "ns",
+		HasAlloc: alloc != nil && alloc.Root != nil && alloc.TotalSamples > 0,
+		HasHeap:  histogram != nil && len(histogram.Classes) > 0,
+	}
+
+	var cpuFrames map[string]*frameStats
+	if story.HasCPU {
+		story.CPUTotal = cpu.TotalSamples
+		cpuFrames = collectFrameStats(cpu.Root)
+	}
+	heapClasses := make(map[string]*hprof.ClassStats)
+	if story.HasHeap {
+		story.HeapTotalSize = histogram.TotalSize
+		for _, c := range histogram.Classes {
+			heapClasses[c.ClassName] = c
+		}
+	}
+
+	var sites []*allocationSite
+	if story.HasAlloc {
+		story.AllocationTotal = alloc.TotalSamples
+		story.AllocationUnit = alloc.Unit
+		sites = collectAllocationSites(alloc.Root)
+	}
+
+	// Allocation sites, linked to the heap and to the CPU time of the
+	// allocating methods
+	for i, site := range sites {
+		if i == memoryStoryTopSites {
+			break
+		}
+		entry := MemoryStorySite{
+			Frame:      site.frame,
+			Class:      site.class,
+			Stack:      site.stack,
+			Allocated:  site.allocated,
+			Percentage: percentOf(site.allocated, story.AllocationTotal),
+			Heap:       heapClasses[site.class],
+		}
+		if stats, ok := cpuFrames[frameKey(site.frame)]; ok {
+			entry.CPUTotalPct = percentOf(stats.total, story.CPUTotal)
+			entry.CPUSelfPct = percentOf(stats.self, story.CPUTotal)
+		}
+		story.Sites = append(story.Sites, entry)
+	}
+
+	story.Classes = memoryStoryClasses(story, sites, histogram)
+
+	if story.HasCPU {
+		var allocFrames map[string]*frameStats
+		if story.HasAlloc {
+			allocFrames = collectFrameStats(alloc.Root)
+		}
+		story.Hotspots = memoryStoryHotspots(story, cpuFrames, allocFrames)
+	}
+	return story
+}
+
+// memoryStoryClasses returns the top classes of the heap and of the
+// allocation profile.
+func memoryStoryClasses(story *MemoryStoryResponse, sites []*allocationSite, histogram *classHistogramFile) []MemoryStoryClass {
+	byName := make(map[string]*MemoryStoryClass)
+	get := func(name string) *MemoryStoryClass {
+		c, ok := byName[name]
+		if !ok {
+			c = &MemoryStoryClass{ClassName: name}
+			byName[name] = c
+		}
+		return c
+	}
+
+	// Sites are sorted by allocated amount: the first ones of each class
+	// are its top sites
+	for _, site := range sites {
+		c := get(site.class)
+		c.Allocated += site.allocated
+		if len(c.TopSites) < 3 {
+			c.TopSites = append(c.TopSites, site.frame)
+		}
+	}
+	if story.HasHeap {
+		// Besides the allocated classes, only the largest classes of the
+		// histogram can be top classes
+		for i, h := range histogram.Classes {
+			if _, ok := byName[h.ClassName]; !ok && i >= memoryStoryTopClasses {
+				continue
+			}
+			c := get(h.ClassName)
+			c.InstanceCount = h.InstanceCount
+			c.ShallowSize = h.ShallowSize
+			if c.ShallowSize == 0 {
+				c.ShallowSize = h.TotalSize
+			}
+			c.RetainedSize = h.RetainedSize
+		}
+	}
+
+	classes := make([]MemoryStoryClass, 0, len(byName))
+	for _, c := range byName {
+		c.AllocationPct = percentOf(c.Allocated, story.AllocationTotal)
+		c.HeapPct = percentOf(max(c.RetainedSize, c.ShallowSize), story.HeapTotalSize)
+		classes = append(classes, *c)
+	}
+	sort.Slice(classes, func(i, j int) bool {
+		a, b := classes[i], classes[j]
+		if sa, sb := max(a.RetainedSize, a.ShallowSize), max(b.RetainedSize, b.ShallowSize); sa != sb {
+			return sa > sb
+		}
+		if a.Allocated != b.Allocated {
+			return a.Allocated > b.Allocated
+		}
+		return a.ClassName < b.ClassName
+	})
+
+	// Keep the top classes of the heap and of the allocation profile
+	keep := make(map[string]bool)
+	for i := 0; i < len(classes) && i < memoryStoryTopClasses; i++ {
+		keep[classes[i].ClassName] = true
+	}
+	byAllocated := append([]MemoryStoryClass(nil), classes...)
+	sort.SliceStable(byAllocated, func(i, j int) bool { return byAllocated[i].Allocated > byAllocated[j].Allocated })
+	for i := 0; i < len(byAllocated) && i < memoryStoryTopClasses && byAllocated[i].Allocated > 0; i++ {
+		keep[byAllocated[i].ClassName] = true
+	}
+	result := classes[:0]
+	for _, c := range classes {
+		if keep[c.ClassName] {
+			result = append(result, c)
+		}
+	}
+	return result
+}
+
+// memoryStoryHotspots returns the top CPU functions by self time, with the
+// amount allocated under them.
+func memoryStoryHotspots(story *MemoryStoryResponse, cpuFrames, allocFrames map[string]*frameStats) []MemoryStoryHotspot {
+	hotspots := make([]MemoryStoryHotspot, 0, len(cpuFrames))
+	for _, stats := range cpuFrames {
+		if stats.self == 0 {
+			continue
+		}
+		h := MemoryStoryHotspot{
+			Name:        stats.name,
+			CPUSelfPct:  percentOf(stats.self, story.CPUTotal),
+			CPUTotalPct: percentOf(stats.total, story.CPUTotal),
+		}
+		if a, ok := allocFrames[frameKey(stats.name)]; ok {
+			h.Allocated = a.total
+			h.AllocationPct = percentOf(a.total, story.AllocationTotal)
+		}
+		hotspots = append(hotspots, h)
+	}
+	sort.Slice(hotspots, func(i, j int) bool {
+		if hotspots[i].CPUSelfPct != hotspots[j].CPUSelfPct {
+			return hotspots[i].CPUSelfPct > hotspots[j].CPUSelfPct
+		}
+		return hotspots[i].Name < hotspots[j].Name
+	})
+	if len(hotspots) > memoryStoryTopHotspots {
+		hotspots = hotspots[:memoryStoryTopHotspots]
+	}
+	return hotspots
+}
+
+// frameStats are the total and self values of a function in a flame graph.
+// The total counts recursive calls once.
+type frameStats struct {
+	name  string
+	total int64
+	self  int64
+}
+
+// frameKey returns the name of a frame matched across profiles: without the
+// frame type suffix of async-profiler, e.g. "_[j]" of JIT-compiled frames.
+func frameKey(name string) string {
+	if i := strings.LastIndex(name, "_["); i > 0 && strings.HasSuffix(name, "]") {
+		return name[:i]
+	}
+	return name
+}
+
+// collectFrameStats returns the statistics of the functions of a flame graph
+// by frameKey.
+func collectFrameStats(root *flamegraph.Node) map[string]*frameStats {
+	stats := make(map[string]*frameStats)
+	onPath := make(map[string]int)
+	var visit func(n *flamegraph.Node)
+	visit = func(n *flamegraph.Node) {
+		key := frameKey(n.Name)
+		s, ok := stats[key]
+		if !ok {
+			s = &frameStats{name: key}
+			stats[key] = s
+		}
+		if onPath[key] == 0 {
+			s.total += n.Value
+		}
+		s.self += n.Self
+		onPath[key]++
+		for _, child := range n.Children {
+			visit(child)
+		}
+		onPath[key]--
+	}
+	for _, child := range root.Children {
+		visit(child)
+	}
+	return stats
+}
+
+// allocationSite is a method allocating a class, in an allocation profile.
+type allocationSite struct {
+	frame     string
+	class     string
+	allocated int64
+	stack     []string
+	heaviest  int64 // the value of stack
+}
+
+// allocatedClass returns the class of the leaf frame of an allocation
+// stack, e.g. "java.lang.String" of "java.lang.String_[i]".
+func allocatedClass(frame string) (string, bool) {
+	for _, suffix := range []string{"_[i]", "_[k]"} {
+		if strings.HasSuffix(frame, suffix) {
+			return strings.ReplaceAll(strings.TrimSuffix(frame, suffix), "/", "."), true
+		}
+	}
+	return "", false
+}
+
+// collectAllocationSites returns the allocation sites of an allocation
+// flame graph, the most allocating first.
+func collectAllocationSites(root *flamegraph.Node) []*allocationSite {
+	sites := make(map[[2]string]*allocationSite)
+	var path []string
+	var visit func(n *flamegraph.Node)
+	visit = func(n *flamegraph.Node) {
+		if class, ok := allocatedClass(n.Name); ok && len(path) > 0 {
+			value := n.Value
+			frame := frameKey(path[len(path)-1])
+			key := [2]string{frame, class}
+			site, ok := sites[key]
+			if !ok {
+				site = &allocationSite{frame: frame, class: class}
+				sites[key] = site
+			}
+			site.allocated += value
+			if value > site.heaviest {
+				site.heaviest = value
+				site.stack = append([]string(nil), path...)
+			}
+			return
+		}
+		path = append(path, n.Name)
+		for _, child := range n.Children {
+			visit(child)
+		}
+		path = path[:len(path)-1]
+	}
+	for _, child := range root.Children {
+		visit(child)
+	}
+
+	result := make([]*allocationSite, 0, len(sites))
+	for _, site := range sites {
+		result = append(result, site)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].allocated != result[j].allocated {
+			return result[i].allocated > result[j].allocated
+		}
+		if result[i].frame != result[j].frame {
+			return result[i].frame < result[j].frame
+		}
+		return result[i].class < result[j].class
+	})
+	return result
+}
+
+// percentOf returns value as a percentage of total.
+func percentOf(value, total int64) float64 {
+	if total <= 0 {
+		return 0
+	}
+	return float64(value) * 100 / float64(total)
+}
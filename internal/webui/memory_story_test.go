@@ -0,0 +1,211 @@
+package webui
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/perf-analysis/internal/flamegraph"
+	"github.com/perf-analysis/internal/parser/hprof"
+)
+
+// fgNode returns a flame graph node.
+func fgNode(name string, value, self int64, children ...*flamegraph.Node) *flamegraph.Node {
+	return &flamegraph.Node{Name: name, Value: value, Self: self, Children: children}
+}
+
+// testAllocProfile allocates Strings in Service.load, also when called from
+// Parser.parse, and in Parser.parse itself.
+func testAllocProfile() *flamegraph.FlameGraph {
+	return &flamegraph.FlameGraph{
+		TotalSamples: 1000,
+		Unit:         "bytes",
+		Root: fgNode("root", 1000, 0,
+			fgNode("main_[j]", 1000, 0,
+				fgNode("Service.load_[j]", 700, 0,
+					fgNode("java/lang/String_[i]", 500, 500),
+					fgNode("byte[]_[k]", 200, 200)),
+				fgNode("Parser.parse_[j]", 300, 0,
+					fgNode("java/lang/String_[i]", 100, 100),
+					fgNode("Service.load_[j]", 200, 0,
+						fgNode("java/lang/String_[i]", 200, 200))))),
+	}
+}
+
+// testCPUProfile spends its time in a recursive Service.load and in
+// Parser.parse.
+func testCPUProfile() *flamegraph.FlameGraph {
+	return &flamegraph.FlameGraph{
+		TotalSamples: 100,
+		Root: fgNode("root", 100, 0,
+			fgNode("main", 100, 0,
+				fgNode("Service.load", 60, 40,
+					fgNode("Service.load", 20, 20)),
+				fgNode("Parser.parse", 40, 40))),
+	}
+}
+
+// testStoryHistogram has a retained cache and the Strings allocated by the
+// allocation profile.
+func testStoryHistogram() *classHistogramFile {
+	return &classHistogramFile{
+		TotalClasses: 2,
+		TotalSize:    10000,
+		Classes: []*hprof.ClassStats{
+			{ClassName: "com.example.Cache", InstanceCount: 1, TotalSize: 32, ShallowSize: 32, RetainedSize: 6000},
+			{ClassName: "java.lang.String", InstanceCount: 50, TotalSize: 2000, RetainedSize: 3000},
+		},
+	}
+}
+
+func TestBuildMemoryStory(t *testing.T) {
+	histogram := testStoryHistogram()
+	story := buildMemoryStory(testCPUProfile(), testAllocProfile(), histogram)
+
+	assert.True(t, story.HasCPU)
+	assert.True(t, story.HasAlloc)
+	assert.True(t, story.HasHeap)
+	assert.Equal(t, int64(100), story.CPUTotal)
+	assert.Equal(t, int64(1000), story.AllocationTotal)
+	assert.Equal(t, "bytes", story.AllocationUnit)
+	assert.Equal(t, int64(10000), story.HeapTotalSize)
+
+	// Sites are keyed by allocating method and class, the frame type
+	// suffix removed, and keep their heaviest stack
+	assert.Equal(t, []MemoryStorySite{
+		{Frame: "Service.load", Class: "java.lang.String", Stack: []string{"main_[j]", "Service.load_[j]"},
+			Allocated: 700, Percentage: 70, CPUTotalPct: 60, CPUSelfPct: 60, Heap: histogram.Classes[1]},
+		{Frame: "Service.load", Class: "byte[]", Stack: []string{"main_[j]", "Service.load_[j]"},
+			Allocated: 200, Percentage: 20, CPUTotalPct: 60, CPUSelfPct: 60},
+		{Frame: "Parser.parse", Class: "java.lang.String", Stack: []string{"main_[j]", "Parser.parse_[j]"},
+			Allocated: 100, Percentage: 10, CPUTotalPct: 40, CPUSelfPct: 40, Heap: histogram.Classes[1]},
+	}, story.Sites)
+
+	// Classes are sorted by heap size, then by allocated amount; the shallow
+	// size falls back to the total size of the histogram
+	assert.Equal(t, []MemoryStoryClass{
+		{ClassName: "com.example.Cache", InstanceCount: 1, ShallowSize: 32, RetainedSize: 6000, HeapPct: 60},
+		{ClassName: "java.lang.String", Allocated: 800, AllocationPct: 80, InstanceCount: 50, ShallowSize: 2000,
+			RetainedSize: 3000, HeapPct: 30, TopSites: []string{"Service.load", "Parser.parse"}},
+		{ClassName: "byte[]", Allocated: 200, AllocationPct: 20, TopSites: []string{"Service.load"}},
+	}, story.Classes)
+
+	// Hotspots count recursive calls once and allocations under callees
+	assert.Equal(t, []MemoryStoryHotspot{
+		{Name: "Service.load", CPUSelfPct: 60, CPUTotalPct: 60, Allocated: 900, AllocationPct: 90},
+		{Name: "Parser.parse", CPUSelfPct: 40, CPUTotalPct: 40, Allocated: 300, AllocationPct: 30},
+	}, story.Hotspots)
+}
+
+func TestBuildMemoryStory_Sources(t *testing.T) {
+	wall := testCPUProfile()
+	wall.Unit = "ns"
+	empty := &flamegraph.FlameGraph{Root: fgNode("root", 0, 0)}
+
+	tests := []struct {
+		name                         string
+		cpu, alloc                   *flamegraph.FlameGraph
+		histogram                    *classHistogramFile
+		wantCPU, wantAlloc, wantHeap bool
+		wantSites, wantClasses       int
+		wantHotspots                 int
+	}{
+		{"none", nil, nil, nil, false, false, false, 0, 0, 0},
+		{"empty profiles", empty, empty, &classHistogramFile{}, false, false, false, 0, 0, 0},
+		{"cpu and heap", testCPUProfile(), nil, testStoryHistogram(), true, false, true, 0, 2, 2},
+		{"alloc and heap", nil, testAllocProfile(), testStoryHistogram(), false, true, true, 3, 3, 0},
+		{"cpu and alloc", testCPUProfile(), testAllocProfile(), nil, true, true, false, 3, 2, 2},
+		{"wall clock is not cpu", wall, testAllocProfile(), nil, false, true, false, 3, 2, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			story := buildMemoryStory(tt.cpu, tt.alloc, tt.histogram)
+			assert.Equal(t, tt.wantCPU, story.HasCPU)
+			assert.Equal(t, tt.wantAlloc, story.HasAlloc)
+			assert.Equal(t, tt.wantHeap, story.HasHeap)
+			assert.Len(t, story.Sites, tt.wantSites)
+			assert.Len(t, story.Classes, tt.wantClasses)
+			assert.Len(t, story.Hotspots, tt.wantHotspots)
+		})
+	}
+}
+
+func TestBuildMemoryStory_TopClasses(t *testing.T) {
+	// Small allocated classes are kept besides the largest heap classes
+	histogram := &classHistogramFile{TotalSize: 1 << 20}
+	for i := 0; i < 2*memoryStoryTopClasses; i++ {
+		histogram.Classes = append(histogram.Classes, &hprof.ClassStats{
+			ClassName: "com.example.C" + string(rune('A'+i)), TotalSize: int64(10000 - i),
+		})
+	}
+	story := buildMemoryStory(nil, testAllocProfile(), histogram)
+
+	require.Len(t, story.Classes, memoryStoryTopClasses+2)
+	assert.Equal(t, "com.example.CA", story.Classes[0].ClassName)
+	assert.Equal(t, "java.lang.String", story.Classes[memoryStoryTopClasses].ClassName)
+	assert.Equal(t, "byte[]", story.Classes[memoryStoryTopClasses+1].ClassName)
+}
+
+func TestFrameKey(t *testing.T) {
+	tests := map[string]string{
+		"Service.load_[j]":       "Service.load",
+		"Service.load_[i]":       "Service.load",
+		"Service.load":           "Service.load",
+		"_[j]":                   "_[j]",
+		"operator[]":             "operator[]",
+		"java/lang/String[]_[k]": "java/lang/String[]",
+	}
+	for name, want := range tests {
+		assert.Equal(t, want, frameKey(name), name)
+	}
+}
+
+func TestServer_handleMemoryStory(t *testing.T) {
+	dataDir := t.TempDir()
+	taskDir := filepath.Join(dataDir, "task-1")
+	require.NoError(t, os.Mkdir(taskDir, 0o755))
+	data, err := json.Marshal(testStoryHistogram())
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(taskDir, "class_histogram.json"), data, 0o644))
+
+	s := NewServer(dataDir, 0, nil)
+	mux := http.NewServeMux()
+	s.registerAPIRoutes(mux)
+	get := func() *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/memory-story?task=task-1", nil))
+		return w
+	}
+
+	// A heap dump alone tells no story
+	assert.False(t, hasMemoryStory(taskDir))
+	assert.Equal(t, http.StatusNotFound, get().Code)
+
+	f, err := os.Create(filepath.Join(taskDir, "alloc_data.json.gz"))
+	require.NoError(t, err)
+	gz := gzip.NewWriter(f)
+	require.NoError(t, json.NewEncoder(gz).Encode(testAllocProfile()))
+	require.NoError(t, gz.Close())
+	require.NoError(t, f.Close())
+	assert.True(t, hasMemoryStory(taskDir))
+
+	w := get()
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var story MemoryStoryResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &story))
+	assert.Equal(t, "task-1", story.Task)
+	assert.False(t, story.HasCPU)
+	assert.True(t, story.HasAlloc)
+	assert.True(t, story.HasHeap)
+	require.NotEmpty(t, story.Sites)
+	assert.Equal(t, "java.lang.String", story.Sites[0].Class)
+	require.NotNil(t, story.Sites[0].Heap)
+	assert.Equal(t, int64(3000), story.Sites[0].Heap.RetainedSize)
+}
@@ -0,0 +1,274 @@
+package webui
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// openAPIVersion is the version of the /api/v1 interface reported in the document.
+const openAPIVersion = "1.0.0"
+
+var (
+	rawMessageType = reflect.TypeOf(json.RawMessage{})
+	timeType       = reflect.TypeOf(time.Time{})
+)
+
+// handleOpenAPI serves the OpenAPI 3 document generated from the route table.
+func (s *Server) handleOpenAPI(w http.ResponseWriter, r *http.Request) {
+	s.openAPIOnce.Do(func() {
+		s.openAPIDoc, s.openAPIErr = json.MarshalIndent(buildOpenAPI(s.apiRoutes()), "", "  ")
+	})
+	if s.openAPIErr != nil {
+		http.Error(w, "Failed to build OpenAPI document", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Write(s.openAPIDoc)
+}
+
+// buildOpenAPI generates an OpenAPI 3.0 document for the /api/v1 routes.
+// Parameters come from the request structs' query tags and response schemas
+// from the response types' json tags.
+func buildOpenAPI(routes []apiRoute) map[string]any {
+	schemas := newSchemaBuilder()
+	paths := make(map[string]any)
+
+	for _, route := range routes {
+		op := map[string]any{
+			"summary":     route.Summary,
+			"tags":        []string{route.Tag},
+			"operationId": operationID(route),
+			"responses": map[string]any{
+				"200": schemas.response(route),
+				"400": map[string]any{"description": "Invalid parameters"},
+				"404": map[string]any{"description": "Task or object not found"},
+			},
+		}
+		if route.Recompute {
+			op["responses"].(map[string]any)["403"] = map[string]any{"description": "Disabled in read-only mode"}
+		}
+		if route.Request != nil {
+			op["parameters"] = queryParameters(reflect.TypeOf(route.Request))
+		}
+		if route.Body != nil {
+			op["requestBody"] = map[string]any{
+				"required": true,
+				"content": map[string]any{
+					"application/json": map[string]any{"schema": schemas.schema(reflect.TypeOf(route.Body))},
+				},
+			}
+		}
+
+		path := apiV1Prefix + route.Path
+		item, ok := paths[path].(map[string]any)
+		if !ok {
+			item = make(map[string]any)
+			paths[path] = item
+		}
+		item[strings.ToLower(route.Method)] = op
+	}
+
+	return map[string]any{
+		"openapi": "3.0.3",
+		"info": map[string]any{
+			"title":       "Perf Analysis API",
+			"description": "JSON API of the perf-analysis web UI. Routes are also served without the /v1 prefix.",
+			"version":     openAPIVersion,
+		},
+		"paths":      paths,
+		"components": map[string]any{"schemas": schemas.components},
+	}
+}
+
+// operationID derives an operation ID such as getRefgraphGcRoots from a route.
+func operationID(route apiRoute) string {
+	var sb strings.Builder
+	sb.WriteString(strings.ToLower(route.Method))
+	for _, part := range strings.FieldsFunc(route.Path, func(r rune) bool {
+		return r == '/' || r == '-'
+	}) {
+		sb.WriteString(strings.ToUpper(part[:1]) + part[1:])
+	}
+	return sb.String()
+}
+
+// queryParameters describes the query-tagged fields of a request struct.
+func queryParameters(t reflect.Type) []map[string]any {
+	var params []map[string]any
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.Anonymous && field.Type.Kind() == reflect.Struct {
+			params = append(params, queryParameters(field.Type)...)
+			continue
+		}
+		name := field.Tag.Get("query")
+		if name == "" {
+			continue
+		}
+
+		schema := map[string]any{"type": "string"}
+		switch field.Type.Kind() {
+		case reflect.Int, reflect.Int32, reflect.Int64:
+			schema = map[string]any{"type": "integer"}
+		case reflect.Float32, reflect.Float64:
+			schema = map[string]any{"type": "number"}
+		case reflect.Bool:
+			schema = map[string]any{"type": "boolean"}
+		case reflect.Slice:
+			schema = map[string]any{"type": "array", "items": map[string]any{"type": "string"}}
+		}
+		param := map[string]any{
+			"name":        name,
+			"in":          "query",
+			"description": field.Tag.Get("doc"),
+			"required":    field.Tag.Get("required") == "true",
+			"schema":      schema,
+		}
+		if field.Type.Kind() == reflect.Slice {
+			param["style"] = "form"
+			param["explode"] = false
+		}
+		params = append(params, param)
+	}
+	return params
+}
+
+// schemaBuilder converts Go types to OpenAPI schemas, collecting named
+// struct types into components.
+type schemaBuilder struct {
+	components map[string]any
+	names      map[reflect.Type]string
+}
+
+func newSchemaBuilder() *schemaBuilder {
+	return &schemaBuilder{
+		components: make(map[string]any),
+		names:      make(map[reflect.Type]string),
+	}
+}
+
+// response describes the 200 response of a route.
+func (b *schemaBuilder) response(route apiRoute) map[string]any {
+	schema := map[string]any{"type": "object", "description": "Analysis output as written by the analyzer"}
+	if route.Response != nil {
+		schema = b.schema(reflect.TypeOf(route.Response))
+	}
+
+	content := map[string]any{"application/json": map[string]any{"schema": schema}}
+	if route.TableExport {
+		table := map[string]any{"schema": map[string]any{"type": "string"}}
+		content["text/csv"] = table
+		content["text/tab-separated-values"] = table
+	}
+	return map[string]any{"description": "OK", "content": content}
+}
+
+// schema returns the schema of t, as a $ref for named structs.
+func (b *schemaBuilder) schema(t reflect.Type) map[string]any {
+	switch t {
+	case rawMessageType:
+		return map[string]any{}
+	case timeType:
+		return map[string]any{"type": "string", "format": "date-time"}
+	}
+
+	switch t.Kind() {
+	case reflect.Ptr:
+		return b.schema(t.Elem())
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint8, reflect.Uint16:
+		return map[string]any{"type": "integer", "format": "int32"}
+	case reflect.Int, reflect.Int64, reflect.Uint, reflect.Uint32, reflect.Uint64:
+		return map[string]any{"type": "integer", "format": "int64"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number"}
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return map[string]any{"type": "string", "format": "byte"}
+		}
+		return map[string]any{"type": "array", "items": b.schema(t.Elem())}
+	case reflect.Map:
+		return map[string]any{"type": "object", "additionalProperties": b.schema(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return b.structSchema(t)
+		}
+		return map[string]any{"$ref": "#/components/schemas/" + b.component(t)}
+	}
+	// Interfaces and anything else accept any JSON value
+	return map[string]any{}
+}
+
+// component registers a named struct type and returns its component name.
+// The name is reserved before the properties are built so recursive types terminate.
+func (b *schemaBuilder) component(t reflect.Type) string {
+	if name, ok := b.names[t]; ok {
+		return name
+	}
+	name := t.Name()
+	if _, taken := b.components[name]; taken {
+		pkg := t.PkgPath()
+		pkg = pkg[strings.LastIndex(pkg, "/")+1:]
+		name = strings.ToUpper(pkg[:1]) + pkg[1:] + name
+	}
+	b.names[t] = name
+	b.components[name] = nil
+	b.components[name] = b.structSchema(t)
+	return name
+}
+
+// structSchema describes the JSON-encoded fields of a struct.
+// Fields without omitempty are listed as required.
+func (b *schemaBuilder) structSchema(t reflect.Type) map[string]any {
+	properties := make(map[string]any)
+	var required []string
+	b.addFields(t, properties, &required)
+
+	schema := map[string]any{"type": "object", "properties": properties}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+	return schema
+}
+
+func (b *schemaBuilder) addFields(t reflect.Type, properties map[string]any, required *[]string) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+
+		// Untagged embedded structs are flattened, as encoding/json does
+		if field.Anonymous && name == "" {
+			ft := field.Type
+			if ft.Kind() == reflect.Ptr {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				b.addFields(ft, properties, required)
+				continue
+			}
+		}
+		if !field.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+
+		properties[name] = b.schema(field.Type)
+		if !strings.Contains(opts, "omitempty") {
+			*required = append(*required, name)
+		}
+	}
+}
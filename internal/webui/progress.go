@@ -0,0 +1,241 @@
+package webui
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/perf-analysis/internal/parser/hprof"
+	"github.com/perf-analysis/pkg/utils"
+)
+
+// progressPollInterval is how often /api/progress checks job.json of tasks
+// analyzed by another process.
+const progressPollInterval = time.Second
+
+// jobStageCount is the number of work stages of a heap analysis job
+// (parsing, dominating, serializing).
+const jobStageCount = 3
+
+// ProgressEvent is one analysis progress update, streamed by /api/progress.
+type ProgressEvent struct {
+	TaskID    string  `json:"task_id"`
+	Phase     string  `json:"phase"`
+	Current   int     `json:"current"`
+	Total     int     `json:"total"`
+	Percent   float64 `json:"percent"`
+	ElapsedMs int64   `json:"elapsed_ms"`
+	EtaMs     int64   `json:"eta_ms,omitempty"`
+	Done      bool    `json:"done"`
+	Error     string  `json:"error,omitempty"`
+}
+
+// NewProgressEvent converts a progress update of a task into an event.
+func NewProgressEvent(taskID string, update utils.ProgressUpdate) *ProgressEvent {
+	event := &ProgressEvent{
+		TaskID:    taskID,
+		Phase:     update.Phase,
+		Current:   update.Current,
+		Total:     update.Total,
+		Percent:   update.Percent,
+		ElapsedMs: update.Elapsed.Milliseconds(),
+		EtaMs:     update.ETA.Milliseconds(),
+		Done:      update.Done,
+	}
+	if update.Err != nil {
+		event.Error = update.Err.Error()
+	}
+	return event
+}
+
+// ProgressHub fans out progress of analyses running in this process (analyze
+// --serve) to /api/progress subscribers, and remembers the last event of each
+// task for late subscribers.
+type ProgressHub struct {
+	mu   sync.Mutex
+	last map[string]*ProgressEvent
+	subs map[chan *ProgressEvent]string
+}
+
+// NewProgressHub creates an empty progress hub.
+func NewProgressHub() *ProgressHub {
+	return &ProgressHub{
+		last: make(map[string]*ProgressEvent),
+		subs: make(map[chan *ProgressEvent]string),
+	}
+}
+
+// Publish records an event and sends it to the task's subscribers. Slow
+// subscribers miss intermediate events but still see the last one.
+func (h *ProgressHub) Publish(event *ProgressEvent) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.last[event.TaskID] = event
+	for ch, taskID := range h.subs {
+		if taskID != event.TaskID {
+			continue
+		}
+		select {
+		case ch <- event:
+		default:
+		}
+	}
+}
+
+// Last returns the last event published for a task, or nil.
+func (h *ProgressHub) Last(taskID string) *ProgressEvent {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.last[taskID]
+}
+
+// Subscribe returns a channel receiving the task's events and a function
+// that unsubscribes.
+func (h *ProgressHub) Subscribe(taskID string) (<-chan *ProgressEvent, func()) {
+	ch := make(chan *ProgressEvent, 16)
+	h.mu.Lock()
+	h.subs[ch] = taskID
+	h.mu.Unlock()
+
+	return ch, func() {
+		h.mu.Lock()
+		delete(h.subs, ch)
+		h.mu.Unlock()
+	}
+}
+
+// Progress returns the hub that in-process analyses publish progress to.
+func (s *Server) Progress() *ProgressHub {
+	return s.progress
+}
+
+// handleProgress streams analysis progress of a task as server-sent events.
+// Analyses running in this process are streamed from the progress hub; tasks
+// analyzed by another process are followed through their job.json. The stream
+// ends after the event with done set.
+func (s *Server) handleProgress(w http.ResponseWriter, r *http.Request) {
+	var req taskRequest
+	if err := decodeQuery(r, &req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	taskID := s.resolveTask(req.Task)
+	if taskID == "" {
+		http.Error(w, "No task specified", http.StatusBadRequest)
+		return
+	}
+	taskDir, err := s.existingTaskDir(taskID)
+	if err != nil {
+		http.Error(w, "Task not found: "+taskID, http.StatusNotFound)
+		return
+	}
+
+	events, unsubscribe := s.progress.Subscribe(taskID)
+	defer unsubscribe()
+
+	// The stream outlives the server's write timeout
+	rc := http.NewResponseController(w)
+	rc.SetWriteDeadline(time.Time{})
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	// send writes an event and reports whether the stream should continue.
+	send := func(event *ProgressEvent) bool {
+		data, err := json.Marshal(event)
+		if err != nil {
+			return false
+		}
+		fmt.Fprintf(w, "event: progress\ndata: %s\n\n", data)
+		return rc.Flush() == nil && !event.Done
+	}
+
+	var lastJobUpdate time.Time
+	poll := func() *ProgressEvent {
+		if last := s.progress.Last(taskID); last != nil {
+			// Resend a final event a full subscriber channel may have dropped
+			if last.Done {
+				return last
+			}
+			return nil
+		}
+		event, updatedAt := jobProgressEvent(taskID, taskDir)
+		if event == nil || (!event.Done && !updatedAt.After(lastJobUpdate)) {
+			return nil
+		}
+		lastJobUpdate = updatedAt
+		return event
+	}
+
+	if last := s.progress.Last(taskID); last != nil {
+		if !send(last) {
+			return
+		}
+	} else if event := poll(); event != nil && !send(event) {
+		return
+	}
+
+	ticker := time.NewTicker(progressPollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case event := <-events:
+			if !send(event) {
+				return
+			}
+		case <-ticker.C:
+			if event := poll(); event != nil && !send(event) {
+				return
+			}
+		}
+	}
+}
+
+// jobProgressEvent derives a progress event from a task's job.json, along with
+// the job's last update time. Tasks without a job file are done once their
+// summary.json exists; otherwise nil is returned.
+func jobProgressEvent(taskID, taskDir string) (*ProgressEvent, time.Time) {
+	job, err := hprof.LoadJobStatus(taskDir)
+	if err != nil {
+		if _, err := os.Stat(filepath.Join(taskDir, "summary.json")); err == nil {
+			return &ProgressEvent{TaskID: taskID, Phase: string(hprof.JobDone), Percent: 100, Done: true}, time.Time{}
+		}
+		return nil, time.Time{}
+	}
+
+	event := &ProgressEvent{
+		TaskID:    taskID,
+		Phase:     string(job.State),
+		Total:     jobStageCount,
+		ElapsedMs: job.UpdatedAt.Sub(job.CreatedAt).Milliseconds(),
+		Error:     job.Error,
+	}
+	for _, stage := range job.Stages {
+		if stage.FinishedAt != nil && stage.Error == "" {
+			event.Current++
+		}
+	}
+	event.Percent = float64(event.Current) * 100 / float64(event.Total)
+	switch job.State {
+	case hprof.JobDone:
+		// summary.json is written after the job; wait for it so the UI can load the task
+		if _, err := os.Stat(filepath.Join(taskDir, "summary.json")); err != nil {
+			event.Phase = "finalizing"
+			break
+		}
+		event.Done = true
+		event.Percent = 100
+	case hprof.JobFailed:
+		event.Done = true
+	}
+	return event, job.UpdatedAt
+}
@@ -0,0 +1,61 @@
+package webui
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServer_handleProgress(t *testing.T) {
+	dataDir := t.TempDir()
+	for _, id := range []string{"analyzed", "running"} {
+		require.NoError(t, os.Mkdir(filepath.Join(dataDir, id), 0o755))
+	}
+	require.NoError(t, os.WriteFile(filepath.Join(dataDir, "analyzed", "summary.json"), []byte("{}"), 0o644))
+	s := NewServer(dataDir, 0, nil)
+	s.Progress().Publish(&ProgressEvent{TaskID: "running", Phase: "failed", Done: true, Error: "out of memory"})
+	mux := http.NewServeMux()
+	s.registerAPIRoutes(mux)
+
+	tests := []struct {
+		task      string
+		wantPhase string
+		wantError string
+	}{
+		{"analyzed", "done", ""},
+		{"running", "failed", "out of memory"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.task, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/progress?task="+tt.task, nil))
+			require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+			assert.Equal(t, "text/event-stream", w.Header().Get("Content-Type"))
+
+			data, ok := strings.CutPrefix(w.Body.String(), "event: progress\ndata: ")
+			require.True(t, ok, w.Body.String())
+			var event ProgressEvent
+			require.NoError(t, json.Unmarshal([]byte(strings.TrimSpace(data)), &event))
+			assert.Equal(t, tt.task, event.TaskID)
+			assert.Equal(t, tt.wantPhase, event.Phase)
+			assert.Equal(t, tt.wantError, event.Error)
+			assert.True(t, event.Done)
+		})
+	}
+
+	// Task IDs must name a task directory
+	for _, task := range []string{"missing", "..", "%2Fetc", "analyzed%2F..%2Fanalyzed"} {
+		t.Run(task, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/progress?task="+task, nil))
+			assert.Equal(t, http.StatusNotFound, w.Code)
+		})
+	}
+}
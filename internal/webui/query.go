@@ -0,0 +1,73 @@
+package webui
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/perf-analysis/internal/parser/hprof"
+)
+
+// maxQueryRows caps the limit parameter of /api/query.
+const maxQueryRows = 10000
+
+// QueryResponse is the result of a query with its execution time.
+type QueryResponse struct {
+	*hprof.QueryResult
+	ElapsedMs int64 `json:"elapsed_ms"`
+}
+
+// handleQuery runs an OQL-style query against a task's heap snapshot.
+func (s *Server) handleQuery(w http.ResponseWriter, r *http.Request) {
+	req := queryRequest{Limit: 1000}
+	if err := decodeQuery(r, &req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if req.Limit <= 0 || req.Limit > maxQueryRows {
+		http.Error(w, "Invalid limit parameter", http.StatusBadRequest)
+		return
+	}
+
+	start := time.Now()
+	result, err := s.refGraphService.Query(s.resolveTask(req.Task), req.Query, req.Limit)
+	if err != nil {
+		writeQueryError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	json.NewEncoder(w).Encode(&QueryResponse{QueryResult: result, ElapsedMs: time.Since(start).Milliseconds()})
+}
+
+// handleQueryRetainedSet returns the retained set of all objects matched by a query.
+func (s *Server) handleQueryRetainedSet(w http.ResponseWriter, r *http.Request) {
+	var req queryRequest
+	if err := decodeQuery(r, &req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	set, err := s.refGraphService.QueryRetainedSet(s.resolveTask(req.Task), req.Query)
+	if err != nil {
+		writeQueryError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	json.NewEncoder(w).Encode(set)
+}
+
+// writeQueryError reports a malformed query as 400 and anything else (such as
+// a missing reference graph) as 404.
+func writeQueryError(w http.ResponseWriter, err error) {
+	var qerr *hprof.QueryError
+	if errors.As(err, &qerr) {
+		http.Error(w, qerr.Error(), http.StatusBadRequest)
+		return
+	}
+	http.Error(w, err.Error(), http.StatusNotFound)
+}
@@ -2,10 +2,11 @@
 package webui
 
 import (
-	"encoding/json"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
+	"sort"
 	"strconv"
 	"sync"
 
@@ -17,31 +18,38 @@ import (
 type RefGraphService struct {
 	dataDir string
 
-	// Cache for loaded reference graphs (keyed by task ID)
-	mu     sync.RWMutex
-	cache  map[string]*refGraphCacheEntry
-	maxCacheSize int
-}
+	// Loaded heap snapshots (keyed by task ID), kept resident with an LRU policy
+	snapshots *SnapshotManager
 
-// refGraphCacheEntry holds a cached reference graph and its builder.
-type refGraphCacheEntry struct {
-	refGraph *hprof.ReferenceGraph
-	builder  *hprof.BiggestObjectsBuilder
+	// Loaded dominator trees (keyed by task ID); these are small compared to snapshots
+	domTreesMu sync.Mutex
+	domTrees   map[string]*hprof.DominatorTree
 }
 
 // NewRefGraphService creates a new RefGraphService.
 func NewRefGraphService(dataDir string) *RefGraphService {
-	return &RefGraphService{
-		dataDir:      dataDir,
-		cache:        make(map[string]*refGraphCacheEntry),
-		maxCacheSize: 3, // Keep at most 3 graphs in memory
+	return NewRefGraphServiceWithConfig(dataDir, DefaultSnapshotManagerConfig())
+}
+
+// NewRefGraphServiceWithConfig creates a new RefGraphService with custom snapshot cache limits.
+func NewRefGraphServiceWithConfig(dataDir string, config SnapshotManagerConfig) *RefGraphService {
+	s := &RefGraphService{
+		dataDir:  dataDir,
+		domTrees: make(map[string]*hprof.DominatorTree),
 	}
+	s.snapshots = NewSnapshotManager(config, s.loadSnapshot)
+	return s
+}
+
+// Snapshots returns the snapshot cache.
+func (s *RefGraphService) Snapshots() *SnapshotManager {
+	return s.snapshots
 }
 
 // GetObjectFields returns the fields of a specific object for tree expansion.
 // This is the main API for lazy loading child objects in the Biggest Objects view.
 func (s *RefGraphService) GetObjectFields(taskID string, objectIDStr string) ([]*hprof.ObjectFieldDetail, error) {
-	entry, err := s.getOrLoadGraph(taskID)
+	snapshot, err := s.snapshots.Get(taskID)
 	if err != nil {
 		return nil, err
 	}
@@ -51,13 +59,61 @@ func (s *RefGraphService) GetObjectFields(taskID string, objectIDStr string) ([]
 		return nil, fmt.Errorf("invalid object ID: %w", err)
 	}
 
-	fields := entry.builder.GetObjectFields(objectID)
+	fields := snapshot.ObjectFields(objectID)
 	return fields, nil
 }
 
+// GetObjectContent returns the field values or array elements of an object,
+// read from the heap dump.
+func (s *RefGraphService) GetObjectContent(taskID string, objectIDStr string, maxElements int) (*hprof.ObjectContent, error) {
+	snapshot, err := s.snapshots.Get(taskID)
+	if err != nil {
+		return nil, err
+	}
+
+	objectID, err := parseObjectID(objectIDStr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid object ID: %w", err)
+	}
+
+	return snapshot.ObjectContent(objectID, maxElements)
+}
+
+// GetObjectPayload returns up to maxBytes bytes of the contents of a
+// primitive array or String from offset on.
+func (s *RefGraphService) GetObjectPayload(taskID string, objectIDStr string, offset int64, maxBytes int) (*hprof.ObjectPayload, error) {
+	snapshot, err := s.snapshots.Get(taskID)
+	if err != nil {
+		return nil, err
+	}
+
+	objectID, err := parseObjectID(objectIDStr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid object ID: %w", err)
+	}
+
+	return snapshot.ObjectPayload(objectID, offset, maxBytes)
+}
+
+// WriteObjectPayload writes the whole contents of a primitive array or
+// String to w.
+func (s *RefGraphService) WriteObjectPayload(w io.Writer, taskID string, objectIDStr string) (int64, error) {
+	snapshot, err := s.snapshots.Get(taskID)
+	if err != nil {
+		return 0, err
+	}
+
+	objectID, err := parseObjectID(objectIDStr)
+	if err != nil {
+		return 0, fmt.Errorf("invalid object ID: %w", err)
+	}
+
+	return snapshot.WriteObjectPayload(w, objectID)
+}
+
 // GetObjectInfo returns basic information about an object.
 func (s *RefGraphService) GetObjectInfo(taskID string, objectIDStr string) (*hprof.ObjectFieldDetail, error) {
-	entry, err := s.getOrLoadGraph(taskID)
+	snapshot, err := s.snapshots.Get(taskID)
 	if err != nil {
 		return nil, err
 	}
@@ -67,7 +123,7 @@ func (s *RefGraphService) GetObjectInfo(taskID string, objectIDStr string) (*hpr
 		return nil, fmt.Errorf("invalid object ID: %w", err)
 	}
 
-	info := entry.builder.GetObjectInfo(objectID)
+	info := snapshot.ObjectInfo(objectID)
 	if info == nil {
 		return nil, fmt.Errorf("object not found: %s", objectIDStr)
 	}
@@ -76,7 +132,7 @@ func (s *RefGraphService) GetObjectInfo(taskID string, objectIDStr string) (*hpr
 
 // GetBiggestObjectsByClass returns the biggest objects for a specific class.
 func (s *RefGraphService) GetBiggestObjectsByClass(taskID string, className string, topN int, sortBy string) ([]*hprof.BiggestObject, error) {
-	entry, err := s.getOrLoadGraph(taskID)
+	snapshot, err := s.snapshots.Get(taskID)
 	if err != nil {
 		return nil, err
 	}
@@ -88,13 +144,71 @@ func (s *RefGraphService) GetBiggestObjectsByClass(taskID string, className stri
 		sortBy = "retained"
 	}
 
-	objects := entry.builder.BuildBiggestObjectsByClass(className, topN, sortBy)
+	objects := snapshot.BiggestObjectsByClass(className, topN, sortBy)
 	return objects, nil
 }
 
-// GetGCRootPaths returns the GC root paths for a specific object.
-func (s *RefGraphService) GetGCRootPaths(taskID string, objectIDStr string, maxPaths int, maxDepth int) ([]hprof.GCRootPath, error) {
-	entry, err := s.getOrLoadGraph(taskID)
+// GetClassMetadata returns the metadata of a class, selected by class object
+// ID or, when idStr is empty, by name.
+func (s *RefGraphService) GetClassMetadata(taskID string, className string, idStr string) (*hprof.ClassMetadata, error) {
+	snapshot, err := s.snapshots.Get(taskID)
+	if err != nil {
+		return nil, err
+	}
+
+	var classID uint64
+	if idStr != "" {
+		if classID, err = parseObjectID(idStr); err != nil {
+			return nil, fmt.Errorf("invalid class ID: %w", err)
+		}
+	} else {
+		var ok bool
+		if classID, ok = snapshot.ClassID(className); !ok {
+			return nil, fmt.Errorf("class not found: %s", className)
+		}
+	}
+	return snapshot.ClassMetadata(classID)
+}
+
+// GetClassInstances returns the page of the instances of a class following
+// the cursor afterStr, or the first page when it is empty.
+func (s *RefGraphService) GetClassInstances(taskID string, className string, sortBy string, afterStr string, limit int) (*hprof.ClassInstancesPage, error) {
+	snapshot, err := s.snapshots.Get(taskID)
+	if err != nil {
+		return nil, err
+	}
+
+	var after *hprof.InstanceCursor
+	if afterStr != "" {
+		cursor, err := hprof.ParseInstanceCursor(afterStr)
+		if err != nil {
+			return nil, err
+		}
+		after = &cursor
+	}
+	return snapshot.ClassInstances(className, sortBy, after, limit)
+}
+
+// GetDominatorRetainers returns the classes whose instances dominate the
+// instances of a class.
+func (s *RefGraphService) GetDominatorRetainers(taskID string, className string, topN int) (*hprof.ClassRetainers, error) {
+	snapshot, err := s.snapshots.Get(taskID)
+	if err != nil {
+		return nil, err
+	}
+
+	retainers := snapshot.DominatorRetainers(className, topN)
+	if retainers == nil {
+		return nil, fmt.Errorf("no reachable instances of %s", className)
+	}
+	return retainers, nil
+}
+
+// GetGCRootPaths returns the GC root paths for a specific object, with the
+// retained size of every hop. Referents of the Reference kinds selected by
+// exclude are not followed.
+func (s *RefGraphService) GetGCRootPaths(taskID string, objectIDStr string, maxPaths int, maxDepth int, exclude hprof.PathExclusion) ([]hprof.GCRootPath, error) {
+	snapshot, err := s.snapshots.Get(taskID)
 	if err != nil {
 		return nil, err
 	}
@@ -111,12 +225,15 @@ func (s *RefGraphService) GetGCRootPaths(taskID string, objectIDStr string, maxP
 		maxDepth = 15
 	}
 
-	paths := entry.refGraph.FindPathsToGCRoot(objectID, maxPaths, maxDepth)
+	paths := snapshot.PathsToGCRootExcluding(objectID, maxPaths, maxDepth, exclude)
 	
 	// Convert to value slice
 	result := make([]hprof.GCRootPath, 0, len(paths))
 	for _, p := range paths {
 		if p != nil {
+			for _, node := range p.Path {
+				node.RetainedSize = snapshot.RetainedSize(node.ObjectID)
+			}
 			result = append(result, *p)
 		}
 	}
@@ -125,7 +242,7 @@ func (s *RefGraphService) GetGCRootPaths(taskID string, objectIDStr string, maxP
 
 // GetRetainers returns the retainers for a specific object.
 func (s *RefGraphService) GetRetainers(taskID string, objectIDStr string, maxRetainers int) ([]*ObjectRetainerInfo, error) {
-	entry, err := s.getOrLoadGraph(taskID)
+	snapshot, err := s.snapshots.Get(taskID)
 	if err != nil {
 		return nil, err
 	}
@@ -140,7 +257,7 @@ func (s *RefGraphService) GetRetainers(taskID string, objectIDStr string, maxRet
 	}
 
 	// Get incoming references (who holds this object)
-	incomingRefs := entry.refGraph.GetIncomingRefs(objectID)
+	incomingRefs := snapshot.IncomingRefs(objectID)
 	
 	result := make([]*ObjectRetainerInfo, 0, len(incomingRefs))
 	for i, ref := range incomingRefs {
@@ -150,10 +267,10 @@ func (s *RefGraphService) GetRetainers(taskID string, objectIDStr string, maxRet
 		
 		info := &ObjectRetainerInfo{
 			ObjectID:     formatObjectID(ref.FromObjectID),
-			ClassName:    entry.refGraph.GetClassName(ref.FromClassID),
+			ClassName:    snapshot.ClassName(ref.FromClassID),
 			FieldName:    ref.FieldName,
-			ShallowSize:  entry.refGraph.GetObjectSize(ref.FromObjectID),
-			RetainedSize: entry.refGraph.GetRetainedSize(ref.FromObjectID),
+			ShallowSize:  snapshot.ObjectSize(ref.FromObjectID),
+			RetainedSize: snapshot.RetainedSize(ref.FromObjectID),
 		}
 		result = append(result, info)
 	}
@@ -163,27 +280,41 @@ func (s *RefGraphService) GetRetainers(taskID string, objectIDStr string, maxRet
 
 // GetGCRootsSummary returns GC roots grouped by class (like IDEA).
 func (s *RefGraphService) GetGCRootsSummary(taskID string) ([]*hprof.GCRootSummary, error) {
-	entry, err := s.getOrLoadGraph(taskID)
+	snapshot, err := s.snapshots.Get(taskID)
 	if err != nil {
 		return nil, err
 	}
 	
-	return entry.refGraph.GetGCRootsSummary(), nil
+	return snapshot.GCRootsSummary(), nil
 }
 
 // GetGCRootsList returns all GC roots with their information.
 func (s *RefGraphService) GetGCRootsList(taskID string) ([]*hprof.GCRootInfo, error) {
-	entry, err := s.getOrLoadGraph(taskID)
+	snapshot, err := s.snapshots.Get(taskID)
 	if err != nil {
 		return nil, err
 	}
 	
-	return entry.refGraph.GetGCRootsList(), nil
+	return snapshot.GCRootsList(), nil
+}
+
+// GCRootRetainedPage is one page of the objects exclusively retained through
+// a GC root: its dominator subtree, in depth-first order.
+type GCRootRetainedPage struct {
+	Root *hprof.GCRootInfo `json:"root"`
+	// Objects and RetainedSize cover the whole subtree, the root included
+	Objects      int   `json:"objects"`
+	RetainedSize int64 `json:"retained_size"`
+
+	Items []*hprof.DominatorTreeNode `json:"items"`
+	// Next is the cursor of the following page, empty on the last page
+	Next string `json:"next,omitempty"`
 }
 
-// GetRetainedObjectsByGCRoot returns objects retained by a specific GC root.
-func (s *RefGraphService) GetRetainedObjectsByGCRoot(taskID string, objectIDStr string, maxObjects int) ([]*hprof.GCRootInfo, error) {
-	entry, err := s.getOrLoadGraph(taskID)
+// GetGCRootRetained returns up to limit objects retained by a GC root,
+// starting after the object afterStr, or at the root when it is empty.
+func (s *RefGraphService) GetGCRootRetained(taskID string, objectIDStr string, afterStr string, limit int) (*GCRootRetainedPage, error) {
+	snapshot, err := s.snapshots.Get(taskID)
 	if err != nil {
 		return nil, err
 	}
@@ -192,8 +323,44 @@ func (s *RefGraphService) GetRetainedObjectsByGCRoot(taskID string, objectIDStr
 	if err != nil {
 		return nil, fmt.Errorf("invalid object ID: %w", err)
 	}
+	var after uint64
+	if afterStr != "" {
+		if after, err = parseObjectID(afterStr); err != nil {
+			return nil, fmt.Errorf("invalid cursor: %w", err)
+		}
+	}
+
+	root, ok := snapshot.GCRoot(objectID)
+	if !ok {
+		return nil, fmt.Errorf("object is not a GC root: %s", objectIDStr)
+	}
+	tree, err := s.getDominatorTree(taskID)
+	if err != nil {
+		return nil, err
+	}
+	it, err := tree.Subtree(objectID, after)
+	if err != nil {
+		return nil, err
+	}
 
-	return entry.refGraph.GetRetainedObjectsByGCRoot(objectID, maxObjects), nil
+	set := tree.RetainedSet([]uint64{objectID})
+	page := &GCRootRetainedPage{
+		Root:         root,
+		Objects:      set.Objects,
+		RetainedSize: set.ShallowSize,
+		Items:        make([]*hprof.DominatorTreeNode, 0, limit),
+	}
+	for len(page.Items) < limit {
+		node, ok := it.Next()
+		if !ok {
+			return page, nil
+		}
+		page.Items = append(page.Items, node)
+	}
+	if _, ok := it.Next(); ok {
+		page.Next = formatObjectID(page.Items[len(page.Items)-1].ObjectID)
+	}
+	return page, nil
 }
 
 // HasRefGraph checks if a reference graph file exists for the given task.
@@ -204,86 +371,288 @@ func (s *RefGraphService) HasRefGraph(taskID string) bool {
 	return err == nil
 }
 
-// ClearCache clears the reference graph cache.
-func (s *RefGraphService) ClearCache() {
-	s.mu.Lock()
-	defer s.mu.Unlock()
-	s.cache = make(map[string]*refGraphCacheEntry)
+// RefGraphManifest summarizes a chunked reference graph for fast startup.
+// It is built from the manifest, class table and retained sizes only.
+type RefGraphManifest struct {
+	WriterVersion      string               `json:"writer_version"`
+	TotalObjects       int64                `json:"total_objects"`
+	TotalReferences    int64                `json:"total_references"`
+	TotalGCRoots       int64                `json:"total_gc_roots"`
+	TotalHeapSize      int64                `json:"total_heap_size"`
+	Chunks             []hprof.ChunkInfo    `json:"chunks"`
+	ClassRetainedSizes []*ClassRetainedSize `json:"class_retained_sizes"`
 }
 
-// ObjectRetainerInfo represents information about an object that retains another object.
-type ObjectRetainerInfo struct {
-	ObjectID     string `json:"object_id"`
+// ClassRetainedSize is the retained size of all instances of a class.
+type ClassRetainedSize struct {
 	ClassName    string `json:"class_name"`
-	FieldName    string `json:"field_name"`
-	ShallowSize  int64  `json:"shallow_size"`
 	RetainedSize int64  `json:"retained_size"`
 }
 
-// getOrLoadGraph loads a reference graph from cache or disk.
-func (s *RefGraphService) getOrLoadGraph(taskID string) (*refGraphCacheEntry, error) {
-	// Check cache first
-	s.mu.RLock()
-	entry, ok := s.cache[taskID]
-	s.mu.RUnlock()
-	
-	if ok {
-		return entry, nil
+// GetGraphManifest returns the chunk manifest and top classes by retained size
+// without loading objects or edges. Only chunked refgraph.bin files are supported.
+func (s *RefGraphService) GetGraphManifest(taskID string, topN int) (*RefGraphManifest, error) {
+	refGraphFile := filepath.Join(s.getTaskDir(taskID), "refgraph.bin")
+	reader, err := hprof.OpenChunkedReferenceGraph(refGraphFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open reference graph: %w", err)
+	}
+	defer reader.Close()
+
+	refGraph, err := reader.Load(hprof.ChunkClassTable, hprof.ChunkRetainedSizes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load reference graph chunks: %w", err)
+	}
+
+	meta := reader.Metadata()
+	manifest := &RefGraphManifest{
+		WriterVersion:   reader.Info().WriterVersion,
+		TotalObjects:    meta.GetTotalObjects(),
+		TotalReferences: meta.GetTotalReferences(),
+		TotalGCRoots:    meta.GetTotalGcRoots(),
+		TotalHeapSize:   meta.GetTotalHeapSize(),
+		Chunks:          reader.Chunks(),
+	}
+
+	if reader.Loaded(hprof.ChunkRetainedSizes) {
+		for name, size := range refGraph.GetClassRetainedSizes() {
+			manifest.ClassRetainedSizes = append(manifest.ClassRetainedSizes, &ClassRetainedSize{ClassName: name, RetainedSize: size})
+		}
+		sort.Slice(manifest.ClassRetainedSizes, func(i, j int) bool {
+			return manifest.ClassRetainedSizes[i].RetainedSize > manifest.ClassRetainedSizes[j].RetainedSize
+		})
+		if topN > 0 && len(manifest.ClassRetainedSizes) > topN {
+			manifest.ClassRetainedSizes = manifest.ClassRetainedSizes[:topN]
+		}
+	}
+	return manifest, nil
+}
+
+// GetDominatedChildren returns the objects immediately dominated by an object,
+// sorted by retained size descending. An empty object ID returns the top level.
+// It uses the persisted dominator tree and does not load the reference graph.
+func (s *RefGraphService) GetDominatedChildren(taskID string, objectIDStr string, limit int) ([]*hprof.DominatorTreeNode, error) {
+	tree, err := s.getDominatorTree(taskID)
+	if err != nil {
+		return nil, err
+	}
+
+	if objectIDStr == "" {
+		return tree.Roots(limit), nil
+	}
+	objectID, err := parseObjectID(objectIDStr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid object ID: %w", err)
+	}
+	if !tree.Contains(objectID) {
+		return nil, fmt.Errorf("object not in dominator tree: %s", objectIDStr)
 	}
+	return tree.Children(objectID, limit), nil
+}
 
-	// Load from disk
-	return s.loadGraph(taskID)
+// GetDominatorChain returns the dominator chain of an object, from its
+// top-level dominator down to the object itself.
+func (s *RefGraphService) GetDominatorChain(taskID string, objectIDStr string) ([]*hprof.DominatorTreeNode, error) {
+	tree, err := s.getDominatorTree(taskID)
+	if err != nil {
+		return nil, err
+	}
+
+	objectID, err := parseObjectID(objectIDStr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid object ID: %w", err)
+	}
+	chain := tree.Chain(objectID)
+	if chain == nil {
+		return nil, fmt.Errorf("object not in dominator tree: %s", objectIDStr)
+	}
+	return chain, nil
 }
 
-// loadGraph loads a reference graph from disk and caches it.
-func (s *RefGraphService) loadGraph(taskID string) (*refGraphCacheEntry, error) {
-	s.mu.Lock()
-	defer s.mu.Unlock()
+// GetTreemap returns the retained-size treemap of an object's dominator
+// subtree, or of the whole heap when objectIDStr is empty.
+func (s *RefGraphService) GetTreemap(taskID string, objectIDStr string, opts hprof.TreemapOptions) (*hprof.TreemapNode, error) {
+	tree, err := s.getDominatorTree(taskID)
+	if err != nil {
+		return nil, err
+	}
 
-	// Double-check cache after acquiring write lock
-	if entry, ok := s.cache[taskID]; ok {
-		return entry, nil
+	if objectIDStr == "" {
+		return tree.Treemap(0, opts), nil
 	}
+	objectID, err := parseObjectID(objectIDStr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid object ID: %w", err)
+	}
+	treemap := tree.Treemap(objectID, opts)
+	if treemap == nil {
+		return nil, fmt.Errorf("object not in dominator tree: %s", objectIDStr)
+	}
+	return treemap, nil
+}
 
-	taskDir := s.getTaskDir(taskID)
-	refGraphFile := filepath.Join(taskDir, "refgraph.bin")
+// GetSubtreeHistogram returns the class histogram of the objects retained
+// by an object.
+func (s *RefGraphService) GetSubtreeHistogram(taskID string, objectIDStr string, topN int) (*hprof.SubtreeHistogram, error) {
+	tree, err := s.getDominatorTree(taskID)
+	if err != nil {
+		return nil, err
+	}
 
-	// Check if file exists
-	if _, err := os.Stat(refGraphFile); os.IsNotExist(err) {
-		return nil, fmt.Errorf("reference graph not found for task %s", taskID)
+	objectID, err := parseObjectID(objectIDStr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid object ID: %w", err)
 	}
+	return tree.SubtreeHistogram(objectID, topN)
+}
 
-	// Deserialize the reference graph
-	refGraph, err := hprof.DeserializeReferenceGraphFromFile(refGraphFile)
+// GetRetainedSet returns the retained set summary of a selection of objects.
+func (s *RefGraphService) GetRetainedSet(taskID string, objectIDStrs []string) (*hprof.RetainedSet, error) {
+	tree, err := s.getDominatorTree(taskID)
 	if err != nil {
-		return nil, fmt.Errorf("failed to load reference graph: %w", err)
+		return nil, err
 	}
 
-	// Load class layouts if available
-	var classLayouts map[uint64]*hprof.ClassFieldLayout
-	classLayoutsFile := filepath.Join(taskDir, "class_layouts.json")
-	if data, err := os.ReadFile(classLayoutsFile); err == nil {
-		json.Unmarshal(data, &classLayouts)
+	objectIDs, err := parseObjectIDs(objectIDStrs)
+	if err != nil {
+		return nil, err
 	}
+	return tree.RetainedSet(objectIDs), nil
+}
+
+// maxReachabilityObjects caps each side of a reachability query: every
+// object to start from costs a traversal of the heap's condensed graph.
+const maxReachabilityObjects = 500
 
-	// Create builder
-	builder := hprof.NewBiggestObjectsBuilder(refGraph, classLayouts, nil)
+// GetReachability reports whether each object of to is reachable from each
+// object of from.
+func (s *RefGraphService) GetReachability(taskID string, fromStrs, toStrs []string) (*ReachabilityResponse, error) {
+	if len(fromStrs) > maxReachabilityObjects || len(toStrs) > maxReachabilityObjects {
+		return nil, fmt.Errorf("at most %d objects on each side", maxReachabilityObjects)
+	}
+	snapshot, err := s.snapshots.Get(taskID)
+	if err != nil {
+		return nil, err
+	}
 
-	entry := &refGraphCacheEntry{
-		refGraph: refGraph,
-		builder:  builder,
+	from, err := parseObjectIDs(fromStrs)
+	if err != nil {
+		return nil, err
+	}
+	to, err := parseObjectIDs(toStrs)
+	if err != nil {
+		return nil, err
+	}
+	reachable, err := snapshot.Reachability(from, to)
+	if err != nil {
+		return nil, err
 	}
 
-	// Evict oldest entry if cache is full
-	if len(s.cache) >= s.maxCacheSize {
-		for k := range s.cache {
-			delete(s.cache, k)
-			break // Just delete one
+	resp := &ReachabilityResponse{Reachable: reachable}
+	for _, id := range from {
+		resp.From = append(resp.From, formatObjectID(id))
+	}
+	for _, id := range to {
+		resp.To = append(resp.To, formatObjectID(id))
+	}
+	return resp, nil
+}
+
+// Query runs an OQL-style query against a task's heap snapshot.
+// maxRows caps the rows returned; 0 uses the engine default.
+func (s *RefGraphService) Query(taskID string, query string, maxRows int) (*hprof.QueryResult, error) {
+	snapshot, err := s.snapshots.Get(taskID)
+	if err != nil {
+		return nil, err
+	}
+
+	engine := hprof.NewQueryEngine(snapshot)
+	if maxRows > 0 {
+		engine.MaxRows = maxRows
+	}
+	return engine.Execute(query)
+}
+
+// QueryRetainedSet returns the retained set of all objects matched by a query,
+// honoring the query's LIMIT but not the row cap of Query.
+func (s *RefGraphService) QueryRetainedSet(taskID string, query string) (*hprof.RetainedSet, error) {
+	snapshot, err := s.snapshots.Get(taskID)
+	if err != nil {
+		return nil, err
+	}
+
+	engine := hprof.NewQueryEngine(snapshot)
+	engine.MaxRows = 0
+	result, err := engine.Execute(query)
+	if err != nil {
+		return nil, err
+	}
+
+	tree, err := s.getDominatorTree(taskID)
+	if err != nil {
+		return nil, err
+	}
+	objectIDs := make([]uint64, 0, len(result.Rows))
+	for _, row := range result.Rows {
+		objectIDs = append(objectIDs, row.ObjectID)
+	}
+	return tree.RetainedSet(objectIDs), nil
+}
+
+// getDominatorTree returns a task's dominator tree, reading domtree.bin if present.
+// Tasks analyzed before domtree.bin existed fall back to the heap snapshot.
+func (s *RefGraphService) getDominatorTree(taskID string) (*hprof.DominatorTree, error) {
+	s.domTreesMu.Lock()
+	tree, ok := s.domTrees[taskID]
+	s.domTreesMu.Unlock()
+	if ok {
+		return tree, nil
+	}
+
+	tree, err := hprof.ReadDominatorTreeFile(filepath.Join(s.getTaskDir(taskID), hprof.DominatorTreeFileName))
+	if os.IsNotExist(err) {
+		snapshot, snapErr := s.snapshots.Get(taskID)
+		if snapErr != nil {
+			return nil, snapErr
 		}
+		tree, err = snapshot.DominatorTree(), nil
 	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load dominator tree: %w", err)
+	}
+
+	s.domTreesMu.Lock()
+	s.domTrees[taskID] = tree
+	s.domTreesMu.Unlock()
+	return tree, nil
+}
+
+// ClearCache clears the reference graph cache.
+func (s *RefGraphService) ClearCache() {
+	s.snapshots.Flush()
 
-	s.cache[taskID] = entry
-	return entry, nil
+	s.domTreesMu.Lock()
+	s.domTrees = make(map[string]*hprof.DominatorTree)
+	s.domTreesMu.Unlock()
+}
+
+// ObjectRetainerInfo represents information about an object that retains another object.
+type ObjectRetainerInfo struct {
+	ObjectID     string `json:"object_id"`
+	ClassName    string `json:"class_name"`
+	FieldName    string `json:"field_name"`
+	ShallowSize  int64  `json:"shallow_size"`
+	RetainedSize int64  `json:"retained_size"`
+}
+
+// loadSnapshot loads a task's reference graph from disk and freezes it into a
+// snapshot, so concurrent requests never race on lazy indexes.
+func (s *RefGraphService) loadSnapshot(taskID string) (*hprof.HeapSnapshot, error) {
+	taskDir := s.getTaskDir(taskID)
+	if _, err := os.Stat(filepath.Join(taskDir, hprof.RefGraphFileName)); os.IsNotExist(err) {
+		return nil, fmt.Errorf("reference graph not found for task %s", taskID)
+	}
+	return hprof.LoadHeapSnapshot(taskDir)
 }
 
 // getTaskDir returns the task directory path.
@@ -307,6 +676,19 @@ func parseObjectID(s string) (uint64, error) {
 	return strconv.ParseUint(s, 10, 64)
 }
 
+// parseObjectIDs parses a list of object IDs.
+func parseObjectIDs(strs []string) ([]uint64, error) {
+	objectIDs := make([]uint64, 0, len(strs))
+	for _, idStr := range strs {
+		objectID, err := parseObjectID(idStr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid object ID %q: %w", idStr, err)
+		}
+		objectIDs = append(objectIDs, objectID)
+	}
+	return objectIDs, nil
+}
+
 // formatObjectID formats an object ID as a hex string.
 func formatObjectID(id uint64) string {
 	return fmt.Sprintf("0x%x", id)
@@ -4,6 +4,7 @@ import (
 	"compress/gzip"
 	"context"
 	"embed"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"html/template"
@@ -13,9 +14,13 @@ import (
 	"os"
 	"path/filepath"
 	"sort"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 
+	"github.com/perf-analysis/internal/parser/hprof"
+	"github.com/perf-analysis/pkg/model"
 	"github.com/perf-analysis/pkg/utils"
 )
 
@@ -33,27 +38,42 @@ type Server struct {
 	server          *http.Server
 	refGraphService *RefGraphService
 	fgService       *FlameGraphService
+	progress        *ProgressHub
+	access          AccessConfig
+	// etagSeed makes ETags of a previous server run (and build) stale
+	etagSeed string
+
+	// Parsed class histograms served by /api/classes, keyed by task
+	histogramsMu sync.Mutex
+	histograms   map[string]*cachedHistogram
+
+	// Parsed timelines of profiles, keyed by task and profile
+	timelinesMu sync.Mutex
+	timelines   map[string]*cachedTimeline
+
+	// Serializes edits of task metadata files
+	taskMetaMu sync.Mutex
+	// Serializes edits of frame annotation files
+	annotationsMu sync.Mutex
+
+	// OpenAPI document, generated once from the route table
+	openAPIOnce sync.Once
+	openAPIDoc  []byte
+	openAPIErr  error
 }
 
 // NewServer creates a new web UI server
 func NewServer(dataDir string, port int, logger utils.Logger) *Server {
-	fgService := NewFlameGraphService(dataDir)
-	// Register additional loaders for memory and tracing
-	fgService.RegisterLoader(NewMemoryFlameGraphLoader())
-	fgService.RegisterLoader(NewTracingFlameGraphLoader())
-	// Register pprof loaders
-	fgService.RegisterLoader(NewPProfGoroutineFlameGraphLoader())
-	fgService.RegisterLoader(NewPProfHeapInuseFlameGraphLoader())
-	fgService.RegisterLoader(NewPProfHeapAllocFlameGraphLoader())
-	fgService.RegisterLoader(NewPProfBlockFlameGraphLoader())
-	fgService.RegisterLoader(NewPProfMutexFlameGraphLoader())
-
 	return &Server{
 		dataDir:         dataDir,
 		port:            port,
-		logger:          logger,
+		logger:          utils.Scoped(logger, utils.ScopeWebUI),
 		refGraphService: NewRefGraphService(dataDir),
-		fgService:       fgService,
+		fgService:       newAllFlameGraphService(dataDir),
+		progress:        NewProgressHub(),
+		etagSeed:        strconv.FormatInt(time.Now().UnixNano(), 36),
+		histograms:      make(map[string]*cachedHistogram),
+		timelines:       make(map[string]*cachedTimeline),
 	}
 }
 
@@ -70,41 +90,27 @@ func (s *Server) Start() error {
 	staticHandler := http.FileServer(http.FS(staticSubFS))
 	mux.Handle("/static/", http.StripPrefix("/static/", staticHandler))
 
-	// API routes
-	mux.HandleFunc("/api/summary", s.handleSummary)
-	mux.HandleFunc("/api/flamegraph", s.handleFlameGraph)
-	mux.HandleFunc("/api/callgraph", s.handleCallGraph)
-	mux.HandleFunc("/api/tasks", s.handleListTasks)
-	mux.HandleFunc("/api/retainers", s.handleRetainers)
-	mux.HandleFunc("/api/biggest-objects", s.handleBiggestObjects)
-	mux.HandleFunc("/api/object-fields", s.handleObjectFields)
-	
-	// Enhanced heap analysis APIs (using ReferenceGraph)
-	mux.HandleFunc("/api/refgraph/fields", s.handleRefGraphFields)
-	mux.HandleFunc("/api/refgraph/info", s.handleRefGraphObjectInfo)
-	mux.HandleFunc("/api/refgraph/gc-roots", s.handleRefGraphGCRoots)
-	mux.HandleFunc("/api/refgraph/gc-roots-summary", s.handleRefGraphGCRootsSummary)
-	mux.HandleFunc("/api/refgraph/gc-roots-list", s.handleRefGraphGCRootsList)
-	mux.HandleFunc("/api/refgraph/gc-root-retained", s.handleRefGraphGCRootRetained)
-	mux.HandleFunc("/api/refgraph/retainers", s.handleRefGraphRetainers)
-	mux.HandleFunc("/api/refgraph/biggest-by-class", s.handleRefGraphBiggestByClass)
-
-	// pprof analysis APIs
-	mux.HandleFunc("/api/pprof/leak-report", s.handlePProfLeakReport)
-	mux.HandleFunc("/api/pprof/batch-analysis", s.handlePProfBatchAnalysis)
+	// API routes (/api and /api/v1, see apiRoutes) and /api/openapi.json
+	s.registerAPIRoutes(mux)
 
 	// Page routes
 	mux.HandleFunc("/", s.handleIndex)
 
 	s.server = &http.Server{
 		Addr:         fmt.Sprintf(":%d", s.port),
-		Handler:      mux,
+		Handler:      s.withAuth(mux),
 		ReadTimeout:  30 * time.Second,
 		WriteTimeout: 30 * time.Second,
 	}
 
 	s.logger.Info("Starting web server at http://localhost:%d", s.port)
 	s.logger.Info("Serving data from: %s", s.dataDir)
+	if s.access.AuthEnabled() {
+		s.logger.Info("Authentication required")
+	}
+	if s.access.ReadOnly {
+		s.logger.Info("Read-only mode: recomputing endpoints are disabled")
+	}
 	s.logger.Info("Press Ctrl+C to stop")
 
 	return s.server.ListenAndServe()
@@ -130,8 +136,9 @@ func (s *Server) handleIndex(w http.ResponseWriter, r *http.Request) {
 	}
 
 	data := map[string]interface{}{
-		"DataDir": s.dataDir,
-		"Port":    s.port,
+		"DataDir":  s.dataDir,
+		"Port":     s.port,
+		"ReadOnly": s.access.ReadOnly,
 	}
 
 	w.Header().Set("Content-Type", "text/html; charset=utf-8")
@@ -178,32 +185,16 @@ func (s *Server) handleSummary(w http.ResponseWriter, r *http.Request) {
 // - pprof-block: Go pprof block flame graph
 // - pprof-mutex: Go pprof mutex flame graph
 func (s *Server) handleFlameGraph(w http.ResponseWriter, r *http.Request) {
-	taskID := r.URL.Query().Get("task")
-	if taskID == "" {
-		taskID = s.getDefaultTask()
+	var req flameViewRequest
+	if err := decodeQuery(r, &req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
 	}
+	taskID := s.resolveTask(req.Task)
 
 	// Determine flame graph type
-	fgTypeStr := r.URL.Query().Get("type")
-	fgType := FlameGraphTypeCPU // default
-	switch strings.ToLower(fgTypeStr) {
-	case "memory", "alloc", "heap":
-		fgType = FlameGraphTypeMemory
-	case "tracing", "latency", "wall":
-		fgType = FlameGraphTypeTracing
-	case "cpu", "":
-		fgType = FlameGraphTypeCPU
-	case "pprof-goroutine", "goroutine":
-		fgType = FlameGraphTypePProfGoroutine
-	case "pprof-heap-inuse", "heap-inuse", "inuse":
-		fgType = FlameGraphTypePProfHeapInuse
-	case "pprof-heap-alloc", "heap-alloc":
-		fgType = FlameGraphTypePProfHeapAlloc
-	case "pprof-block", "block":
-		fgType = FlameGraphTypePProfBlock
-	case "pprof-mutex", "mutex":
-		fgType = FlameGraphTypePProfMutex
-	default:
+	fgType, ok := ParseFlameGraphType(req.Type)
+	if !ok {
 		// Unknown type, try to find any .json.gz file (legacy behavior)
 		s.handleFlameGraphLegacy(w, r, taskID)
 		return
@@ -217,6 +208,10 @@ func (s *Server) handleFlameGraph(w http.ResponseWriter, r *http.Request) {
 		s.handleFlameGraphLegacy(w, r, taskID)
 		return
 	}
+	if req.CollapseInlined {
+		// The service caches the flame graph: collapse a copy
+		fg = fg.CollapseInlined()
+	}
 
 	w.Header().Set("Content-Type", "application/json")
 	w.Header().Set("Access-Control-Allow-Origin", "*")
@@ -393,19 +388,19 @@ func (s *Server) handleCallGraph(w http.ResponseWriter, r *http.Request) {
 
 // handleListTasks lists all available tasks
 func (s *Server) handleListTasks(w http.ResponseWriter, r *http.Request) {
+	var req tasksRequest
+	if err := decodeQuery(r, &req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
 	entries, err := os.ReadDir(s.dataDir)
 	if err != nil {
 		http.Error(w, "Failed to list tasks", http.StatusInternalServerError)
 		return
 	}
 
-	type TaskInfo struct {
-		ID        string `json:"id"`
-		CreatedAt string `json:"created_at"`
-		HasData   bool   `json:"has_data"`
-	}
-
-	var tasks []TaskInfo
+	tasks := []TaskInfo{}
 	for _, entry := range entries {
 		if !entry.IsDir() {
 			continue
@@ -421,11 +416,28 @@ func (s *Server) handleListTasks(w http.ResponseWriter, r *http.Request) {
 		}
 
 		_, hasData := os.Stat(summaryFile)
-		tasks = append(tasks, TaskInfo{
-			ID:        entry.Name(),
-			CreatedAt: createdAt,
-			HasData:   hasData == nil,
-		})
+		task := TaskInfo{
+			ID:          entry.Name(),
+			CreatedAt:   createdAt,
+			HasData:     hasData == nil,
+			MemoryStory: hasMemoryStory(taskDir),
+			Timelines:   taskTimelines(taskDir),
+		}
+		if task.HasData {
+			task.Health = loadHealthScore(summaryFile)
+		}
+		if job, err := hprof.LoadJobStatus(taskDir); err == nil {
+			task.JobState = job.State
+		}
+		if meta, err := loadTaskMeta(taskDir); err == nil {
+			task.Meta = meta
+		} else if s.logger != nil {
+			s.logger.Warn("Ignoring task metadata of %s: %v", entry.Name(), err)
+		}
+		if !matchesTaskFilter(&task, req.Tag, req.Query) {
+			continue
+		}
+		tasks = append(tasks, task)
 	}
 
 	// Sort by creation time (newest first)
@@ -438,6 +450,51 @@ func (s *Server) handleListTasks(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(tasks)
 }
 
+// loadHealthScore returns the health score of a summary.json, nil if none.
+func loadHealthScore(summaryFile string) *model.HealthScore {
+	f, err := os.Open(summaryFile)
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+
+	var summary struct {
+		Health *model.HealthScore `json:"health"`
+	}
+	if err := json.NewDecoder(f).Decode(&summary); err != nil {
+		return nil
+	}
+	return summary.Health
+}
+
+// handleJobStatus returns the analysis job status (stages and timings) of a task
+func (s *Server) handleJobStatus(w http.ResponseWriter, r *http.Request) {
+	var req taskRequest
+	if err := decodeQuery(r, &req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	taskID := s.resolveTask(req.Task)
+	if taskID == "" {
+		http.Error(w, "No task specified", http.StatusBadRequest)
+		return
+	}
+
+	status, err := hprof.LoadJobStatus(filepath.Join(s.dataDir, taskID))
+	if err != nil {
+		if os.IsNotExist(err) {
+			http.Error(w, "No analysis job for task: "+taskID, http.StatusNotFound)
+			return
+		}
+		http.Error(w, fmt.Sprintf("Failed to load job status: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	json.NewEncoder(w).Encode(status)
+}
+
 // getDefaultTask returns the most recent task ID
 func (s *Server) getDefaultTask() string {
 	entries, err := os.ReadDir(s.dataDir)
@@ -479,6 +536,12 @@ func (s *Server) handleRetainers(w http.ResponseWriter, r *http.Request) {
 		taskDir = s.dataDir
 	}
 
+	// Serve the flattened retainer table for CSV/TSV clients
+	if format, ok := negotiateTableFormat(r); ok {
+		s.serveTableExport(w, taskDir, "class_retainers", format)
+		return
+	}
+
 	// Try multiple sources for retainer data
 	var data []byte
 	var err error
@@ -630,150 +693,136 @@ func (s *Server) handleBiggestObjects(w http.ResponseWriter, r *http.Request) {
 	w.Write(data)
 }
 
-// handleObjectFields returns the fields of a specific object for tree expansion
-func (s *Server) handleObjectFields(w http.ResponseWriter, r *http.Request) {
-	taskID := r.URL.Query().Get("task")
-	if taskID == "" {
-		taskID = s.getDefaultTask()
+// handleRefGraphFields returns the fields of a specific object using ReferenceGraph.
+// The Biggest Objects tree expands every object through it.
+func (s *Server) handleRefGraphFields(w http.ResponseWriter, r *http.Request) {
+	var req objectRequest
+	if err := decodeQuery(r, &req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
 	}
 
-	objectIDStr := r.URL.Query().Get("id")
-	if objectIDStr == "" {
-		http.Error(w, "Object ID is required", http.StatusBadRequest)
+	fields, err := s.refGraphService.GetObjectFields(s.resolveTask(req.Task), req.ID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
 		return
 	}
 
-	taskDir := filepath.Join(s.dataDir, taskID)
-	if taskID == "" {
-		taskDir = s.dataDir
+	// Convert to JSON-friendly format with string object IDs
+	response := make([]ObjectFieldResponse, 0, len(fields))
+	for _, f := range fields {
+		fr := ObjectFieldResponse{
+			Name:         f.Name,
+			Type:         f.Type,
+			Value:        f.Value,
+			RefClass:     f.RefClass,
+			ShallowSize:  f.ShallowSize,
+			RetainedSize: f.RetainedSize,
+			HasChildren:  f.HasChildren,
+			StringValue:  f.StringValue,
+		}
+		if f.RefID != 0 {
+			fr.RefID = formatObjectID(f.RefID)
+		}
+		response = append(response, fr)
 	}
 
-	// Try to read from object_fields cache file
-	// Format: object_fields_<objectID>.json
-	cacheFile := filepath.Join(taskDir, "object_fields_"+objectIDStr+".json")
-	data, err := os.ReadFile(cacheFile)
-	if err == nil {
-		w.Header().Set("Content-Type", "application/json")
-		w.Header().Set("Access-Control-Allow-Origin", "*")
-		w.Write(data)
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	json.NewEncoder(w).Encode(response)
+}
+
+// maxObjectElements caps the array elements returned by the object endpoint.
+const maxObjectElements = 10000
+
+// handleRefGraphObjectContent returns the field values or array elements of an object.
+func (s *Server) handleRefGraphObjectContent(w http.ResponseWriter, r *http.Request) {
+	var req objectContentRequest
+	if err := decodeQuery(r, &req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
-
-	// If cache doesn't exist, try to get from summary.json biggest_objects
-	summaryFile := filepath.Join(taskDir, "summary.json")
-	summaryData, err := os.ReadFile(summaryFile)
-	if err != nil {
-		http.Error(w, "Summary data not found", http.StatusNotFound)
+	if req.MaxElements < 0 || req.MaxElements > maxObjectElements {
+		http.Error(w, fmt.Sprintf("max_elements must be between 0 and %d", maxObjectElements), http.StatusBadRequest)
 		return
 	}
 
-	var summary map[string]interface{}
-	if err := json.Unmarshal(summaryData, &summary); err != nil {
-		http.Error(w, "Failed to parse summary", http.StatusInternalServerError)
+	content, err := s.refGraphService.GetObjectContent(s.resolveTask(req.Task), req.ID, req.MaxElements)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
 		return
 	}
 
-	// Look for the object in biggest_objects
-	if dataSection, ok := summary["data"].(map[string]interface{}); ok {
-		if biggestObjects, ok := dataSection["biggest_objects"].([]interface{}); ok {
-			for _, obj := range biggestObjects {
-				if objMap, ok := obj.(map[string]interface{}); ok {
-					if objID, ok := objMap["object_id"].(string); ok && objID == objectIDStr {
-						// Found the object, return its fields
-						if fields, ok := objMap["fields"]; ok {
-							// Enhance fields with has_children info
-							if fieldsList, ok := fields.([]interface{}); ok {
-								for _, f := range fieldsList {
-									if fieldMap, ok := f.(map[string]interface{}); ok {
-										// If it has ref_id, it potentially has children
-										if _, hasRef := fieldMap["ref_id"]; hasRef {
-											fieldMap["has_children"] = true
-										}
-									}
-								}
-							}
-							data, _ = json.Marshal(fields)
-							w.Header().Set("Content-Type", "application/json")
-							w.Header().Set("Access-Control-Allow-Origin", "*")
-							w.Write(data)
-							return
-						}
-					}
-					// Also check nested fields for the object
-					if fields, ok := objMap["fields"].([]interface{}); ok {
-						for _, f := range fields {
-							if fieldMap, ok := f.(map[string]interface{}); ok {
-								if refID, ok := fieldMap["ref_id"].(string); ok && refID == objectIDStr {
-									// This is a child object, return empty fields for now
-									// In production, we'd need to look up this object's fields
-									data = []byte("[]")
-									w.Header().Set("Content-Type", "application/json")
-									w.Header().Set("Access-Control-Allow-Origin", "*")
-									w.Write(data)
-									return
-								}
-							}
-						}
-					}
-				}
-			}
-		}
+	response := ObjectContentResponse{
+		ObjectID:        formatObjectID(content.ObjectID),
+		ClassName:       content.ClassName,
+		Kind:            content.Kind,
+		ShallowSize:     content.ShallowSize,
+		RetainedSize:    content.RetainedSize,
+		Fields:          fieldValueResponses(content.Fields),
+		ElementType:     content.ElementType,
+		Length:          content.Length,
+		Elements:        fieldValueResponses(content.Elements),
+		Truncated:       content.Truncated,
+		StringValue:     content.StringValue,
+		StringTruncated: content.StringTruncated,
 	}
 
-	// Object not found - return empty array instead of error for better UX
 	w.Header().Set("Content-Type", "application/json")
 	w.Header().Set("Access-Control-Allow-Origin", "*")
-	w.Write([]byte("[]"))
+	json.NewEncoder(w).Encode(response)
 }
 
-// handleRefGraphFields returns the fields of a specific object using ReferenceGraph.
-// This enables deep object exploration beyond the initial biggest_objects.json data.
-func (s *Server) handleRefGraphFields(w http.ResponseWriter, r *http.Request) {
-	taskID := r.URL.Query().Get("task")
-	if taskID == "" {
-		taskID = s.getDefaultTask()
+// handleRefGraphObjectPayload returns a window of the raw contents of a
+// primitive array or String, or the whole contents as a download.
+func (s *Server) handleRefGraphObjectPayload(w http.ResponseWriter, r *http.Request) {
+	var req objectPayloadRequest
+	if err := decodeQuery(r, &req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if req.Limit < 0 || req.Limit > hprof.MaxPayloadBytes {
+		http.Error(w, fmt.Sprintf("limit must be between 0 and %d", hprof.MaxPayloadBytes), http.StatusBadRequest)
+		return
 	}
+	if req.Offset < 0 {
+		http.Error(w, "offset must not be negative", http.StatusBadRequest)
+		return
+	}
+	taskID := s.resolveTask(req.Task)
 
-	objectIDStr := r.URL.Query().Get("id")
-	if objectIDStr == "" {
-		http.Error(w, "Object ID is required", http.StatusBadRequest)
+	if req.Download {
+		// Read the head first, so errors are reported before the body starts
+		if _, err := s.refGraphService.GetObjectPayload(taskID, req.ID, 0, 1); err != nil {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "application/octet-stream")
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", strings.TrimPrefix(req.ID, "0x")+".bin"))
+		w.Header().Set("Access-Control-Allow-Origin", "*")
+		if _, err := s.refGraphService.WriteObjectPayload(w, taskID, req.ID); err != nil {
+			s.logger.Warn("Failed to write payload of %s: %v", req.ID, err)
+		}
 		return
 	}
 
-	fields, err := s.refGraphService.GetObjectFields(taskID, objectIDStr)
+	payload, err := s.refGraphService.GetObjectPayload(taskID, req.ID, req.Offset, req.Limit)
 	if err != nil {
-		// Fall back to legacy method if refgraph not available
-		s.handleObjectFields(w, r)
+		http.Error(w, err.Error(), http.StatusNotFound)
 		return
 	}
 
-	// Convert to JSON-friendly format with string object IDs
-	type FieldResponse struct {
-		Name         string      `json:"name"`
-		Type         string      `json:"type"`
-		Value        interface{} `json:"value,omitempty"`
-		RefID        string      `json:"ref_id,omitempty"`
-		RefClass     string      `json:"ref_class,omitempty"`
-		ShallowSize  int64       `json:"shallow_size,omitempty"`
-		RetainedSize int64       `json:"retained_size,omitempty"`
-		HasChildren  bool        `json:"has_children"`
-	}
-
-	response := make([]FieldResponse, 0, len(fields))
-	for _, f := range fields {
-		fr := FieldResponse{
-			Name:         f.Name,
-			Type:         f.Type,
-			Value:        f.Value,
-			RefClass:     f.RefClass,
-			ShallowSize:  f.ShallowSize,
-			RetainedSize: f.RetainedSize,
-			HasChildren:  f.HasChildren,
-		}
-		if f.RefID != 0 {
-			fr.RefID = formatObjectID(f.RefID)
-		}
-		response = append(response, fr)
+	response := ObjectPayloadResponse{
+		ObjectID:    formatObjectID(payload.ObjectID),
+		ArrayID:     formatObjectID(payload.ArrayID),
+		ClassName:   payload.ClassName,
+		ElementType: payload.ElementType,
+		Size:        payload.Size,
+		Offset:      payload.Offset,
+		Length:      len(payload.Data),
+		Hex:         hex.EncodeToString(payload.Data),
+		Text:        payload.Text,
+		Truncated:   payload.Truncated,
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -781,32 +830,34 @@ func (s *Server) handleRefGraphFields(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(response)
 }
 
-// handleRefGraphObjectInfo returns basic information about an object.
-func (s *Server) handleRefGraphObjectInfo(w http.ResponseWriter, r *http.Request) {
-	taskID := r.URL.Query().Get("task")
-	if taskID == "" {
-		taskID = s.getDefaultTask()
-	}
-
-	objectIDStr := r.URL.Query().Get("id")
-	if objectIDStr == "" {
-		http.Error(w, "Object ID is required", http.StatusBadRequest)
+// handleRefGraphClass returns the metadata of a class.
+func (s *Server) handleRefGraphClass(w http.ResponseWriter, r *http.Request) {
+	var req classMetadataRequest
+	if err := decodeQuery(r, &req); err != nil || (req.Class == "" && req.ID == "") {
+		http.Error(w, "Invalid parameters: class or id is required", http.StatusBadRequest)
 		return
 	}
 
-	info, err := s.refGraphService.GetObjectInfo(taskID, objectIDStr)
+	meta, err := s.refGraphService.GetClassMetadata(s.resolveTask(req.Task), req.Class, req.ID)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusNotFound)
 		return
 	}
 
-	// Convert to JSON-friendly format
-	response := map[string]interface{}{
-		"object_id":     formatObjectID(info.RefID),
-		"class_name":    info.RefClass,
-		"shallow_size":  info.ShallowSize,
-		"retained_size": info.RetainedSize,
-		"has_children":  info.HasChildren,
+	response := ClassMetadataResponse{
+		ClassID:       formatObjectID(meta.ClassID),
+		ClassName:     meta.ClassName,
+		InstanceSize:  meta.InstanceSize,
+		InstanceCount: meta.InstanceCount,
+		ShallowSize:   meta.ShallowSize,
+		RetainedSize:  meta.RetainedSize,
+		Superclasses:  classRefResponses(meta.Superclasses),
+		Subclasses:    classRefResponses(meta.Subclasses),
+		Fields:        meta.Fields,
+		StaticFields:  fieldValueResponses(meta.StaticFields),
+	}
+	if meta.ClassLoaderID != 0 {
+		response.ClassLoaderID = formatObjectID(meta.ClassLoaderID)
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -814,34 +865,80 @@ func (s *Server) handleRefGraphObjectInfo(w http.ResponseWriter, r *http.Request
 	json.NewEncoder(w).Encode(response)
 }
 
-// handleRefGraphGCRoots returns the GC root paths for a specific object.
-func (s *Server) handleRefGraphGCRoots(w http.ResponseWriter, r *http.Request) {
-	taskID := r.URL.Query().Get("task")
-	if taskID == "" {
-		taskID = s.getDefaultTask()
+// classRefResponses converts class references to string class IDs.
+func classRefResponses(refs []*hprof.ClassRef) []ClassRefResponse {
+	if len(refs) == 0 {
+		return nil
+	}
+	response := make([]ClassRefResponse, 0, len(refs))
+	for _, ref := range refs {
+		response = append(response, ClassRefResponse{ClassID: formatObjectID(ref.ClassID), ClassName: ref.ClassName})
 	}
+	return response
+}
 
-	objectIDStr := r.URL.Query().Get("id")
-	if objectIDStr == "" {
-		http.Error(w, "Object ID is required", http.StatusBadRequest)
+// fieldValueResponses converts field values to JSON-friendly format with string object IDs.
+func fieldValueResponses(values []*hprof.FieldValue) []ObjectFieldResponse {
+	if len(values) == 0 {
+		return nil
+	}
+	response := make([]ObjectFieldResponse, 0, len(values))
+	for _, v := range values {
+		fr := ObjectFieldResponse{
+			Name:         v.Name,
+			Type:         v.Type,
+			Value:        v.Value,
+			RefClass:     v.RefClass,
+			ShallowSize:  v.ShallowSize,
+			RetainedSize: v.RetainedSize,
+			StringValue:  v.StringValue,
+		}
+		if v.RefID != 0 {
+			fr.RefID = formatObjectID(v.RefID)
+		}
+		response = append(response, fr)
+	}
+	return response
+}
+
+// handleRefGraphObjectInfo returns basic information about an object.
+func (s *Server) handleRefGraphObjectInfo(w http.ResponseWriter, r *http.Request) {
+	var req objectRequest
+	if err := decodeQuery(r, &req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
-	maxPaths := 3
-	if mp := r.URL.Query().Get("max_paths"); mp != "" {
-		if n, err := parseInt(mp); err == nil && n > 0 {
-			maxPaths = n
-		}
+	info, err := s.refGraphService.GetObjectInfo(s.resolveTask(req.Task), req.ID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
 	}
 
-	maxDepth := 15
-	if md := r.URL.Query().Get("max_depth"); md != "" {
-		if n, err := parseInt(md); err == nil && n > 0 {
-			maxDepth = n
-		}
+	// Convert to JSON-friendly format
+	response := ObjectInfoResponse{
+		ObjectID:     formatObjectID(info.RefID),
+		ClassName:    info.RefClass,
+		ShallowSize:  info.ShallowSize,
+		RetainedSize: info.RetainedSize,
+		HasChildren:  info.HasChildren,
 	}
 
-	paths, err := s.refGraphService.GetGCRootPaths(taskID, objectIDStr, maxPaths, maxDepth)
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	json.NewEncoder(w).Encode(response)
+}
+
+// handleRefGraphGCRoots returns the GC root paths for a specific object.
+func (s *Server) handleRefGraphGCRoots(w http.ResponseWriter, r *http.Request) {
+	var req gcRootPathsRequest
+	if err := decodeQuery(r, &req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Non-positive limits fall back to the service defaults
+	paths, err := s.refGraphService.GetGCRootPaths(s.resolveTask(req.Task), req.ID, req.MaxPaths, req.MaxDepth, req.exclusion())
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusNotFound)
 		return
@@ -882,14 +979,16 @@ func (s *Server) handleRefGraphGCRootsSummary(w http.ResponseWriter, r *http.Req
 	json.NewEncoder(w).Encode(summary)
 }
 
-// handleRefGraphGCRootsList returns all GC roots with their information.
-func (s *Server) handleRefGraphGCRootsList(w http.ResponseWriter, r *http.Request) {
-	taskID := r.URL.Query().Get("task")
-	if taskID == "" {
-		taskID = s.getDefaultTask()
+// handleRefGraphManifest returns the chunk manifest of refgraph.bin together with
+// the top classes by retained size, without loading objects or edges.
+func (s *Server) handleRefGraphManifest(w http.ResponseWriter, r *http.Request) {
+	req := manifestRequest{Top: 50}
+	if err := decodeQuery(r, &req); err != nil || req.Top <= 0 {
+		http.Error(w, "Invalid top parameter", http.StatusBadRequest)
+		return
 	}
 
-	roots, err := s.refGraphService.GetGCRootsList(taskID)
+	manifest, err := s.refGraphService.GetGraphManifest(s.resolveTask(req.Task), req.Top)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusNotFound)
 		return
@@ -897,30 +996,39 @@ func (s *Server) handleRefGraphGCRootsList(w http.ResponseWriter, r *http.Reques
 
 	w.Header().Set("Content-Type", "application/json")
 	w.Header().Set("Access-Control-Allow-Origin", "*")
-	json.NewEncoder(w).Encode(roots)
+	json.NewEncoder(w).Encode(manifest)
 }
 
-// handleRefGraphGCRootRetained returns objects retained by a specific GC root.
-func (s *Server) handleRefGraphGCRootRetained(w http.ResponseWriter, r *http.Request) {
-	taskID := r.URL.Query().Get("task")
-	if taskID == "" {
-		taskID = s.getDefaultTask()
+// handleDomTreeChildren returns the objects immediately dominated by an object
+// (or the top level when no id is given) from the persisted dominator tree.
+func (s *Server) handleDomTreeChildren(w http.ResponseWriter, r *http.Request) {
+	req := domTreeChildrenRequest{Limit: 100}
+	if err := decodeQuery(r, &req); err != nil || req.Limit <= 0 {
+		http.Error(w, "Invalid limit parameter", http.StatusBadRequest)
+		return
 	}
 
-	objectIDStr := r.URL.Query().Get("id")
-	if objectIDStr == "" {
-		http.Error(w, "Object ID is required", http.StatusBadRequest)
+	children, err := s.refGraphService.GetDominatedChildren(s.resolveTask(req.Task), req.ID, req.Limit)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
 		return
 	}
 
-	maxObjects := 50
-	if mo := r.URL.Query().Get("max"); mo != "" {
-		if n, err := parseInt(mo); err == nil && n > 0 {
-			maxObjects = n
-		}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	json.NewEncoder(w).Encode(children)
+}
+
+// handleDomTreeChain returns the dominator chain of an object, used for the
+// breadcrumbs of the dominator tree explorer.
+func (s *Server) handleDomTreeChain(w http.ResponseWriter, r *http.Request) {
+	var req objectRequest
+	if err := decodeQuery(r, &req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
 	}
 
-	objects, err := s.refGraphService.GetRetainedObjectsByGCRoot(taskID, objectIDStr, maxObjects)
+	chain, err := s.refGraphService.GetDominatorChain(s.resolveTask(req.Task), req.ID)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusNotFound)
 		return
@@ -928,30 +1036,56 @@ func (s *Server) handleRefGraphGCRootRetained(w http.ResponseWriter, r *http.Req
 
 	w.Header().Set("Content-Type", "application/json")
 	w.Header().Set("Access-Control-Allow-Origin", "*")
-	json.NewEncoder(w).Encode(objects)
+	json.NewEncoder(w).Encode(chain)
 }
 
-// handleRefGraphRetainers returns the objects that retain a specific object.
-func (s *Server) handleRefGraphRetainers(w http.ResponseWriter, r *http.Request) {
-	taskID := r.URL.Query().Get("task")
-	if taskID == "" {
-		taskID = s.getDefaultTask()
+// Upper bounds of the /api/domtree/treemap parameters, keeping responses small.
+const (
+	maxTreemapDepth    = 8
+	maxTreemapChildren = 200
+)
+
+// handleDomTreeTreemap returns the retained-size treemap of the heap or of
+// one object's dominator subtree.
+func (s *Server) handleDomTreeTreemap(w http.ResponseWriter, r *http.Request) {
+	req := domTreeTreemapRequest{
+		Depth:      hprof.DefaultTreemapDepth,
+		Children:   hprof.DefaultTreemapChildren,
+		MinPercent: hprof.DefaultTreemapMinPercent,
+	}
+	if err := decodeQuery(r, &req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if req.Depth <= 0 || req.Depth > maxTreemapDepth || req.Children <= 0 || req.Children > maxTreemapChildren || req.MinPercent < 0 {
+		http.Error(w, "Invalid depth, children or min_percent parameter", http.StatusBadRequest)
+		return
 	}
 
-	objectIDStr := r.URL.Query().Get("id")
-	if objectIDStr == "" {
-		http.Error(w, "Object ID is required", http.StatusBadRequest)
+	treemap, err := s.refGraphService.GetTreemap(s.resolveTask(req.Task), req.ID, hprof.TreemapOptions{
+		MaxDepth:    req.Depth,
+		MaxChildren: req.Children,
+		MinPercent:  req.MinPercent,
+	})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
 		return
 	}
 
-	maxRetainers := 20
-	if mr := r.URL.Query().Get("max"); mr != "" {
-		if n, err := parseInt(mr); err == nil && n > 0 {
-			maxRetainers = n
-		}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	json.NewEncoder(w).Encode(treemap)
+}
+
+// handleDomTreeHistogram returns the class histogram of an object's dominator subtree.
+func (s *Server) handleDomTreeHistogram(w http.ResponseWriter, r *http.Request) {
+	req := domTreeHistogramRequest{Top: 50}
+	if err := decodeQuery(r, &req); err != nil || req.Top <= 0 {
+		http.Error(w, "Invalid parameters: object ID and a positive top are required", http.StatusBadRequest)
+		return
 	}
 
-	retainers, err := s.refGraphService.GetRetainers(taskID, objectIDStr, maxRetainers)
+	histogram, err := s.refGraphService.GetSubtreeHistogram(s.resolveTask(req.Task), req.ID, req.Top)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusNotFound)
 		return
@@ -959,51 +1093,128 @@ func (s *Server) handleRefGraphRetainers(w http.ResponseWriter, r *http.Request)
 
 	w.Header().Set("Content-Type", "application/json")
 	w.Header().Set("Access-Control-Allow-Origin", "*")
-	json.NewEncoder(w).Encode(retainers)
+	json.NewEncoder(w).Encode(histogram)
 }
 
-// handleRefGraphBiggestByClass returns the biggest objects for a specific class.
-func (s *Server) handleRefGraphBiggestByClass(w http.ResponseWriter, r *http.Request) {
-	taskID := r.URL.Query().Get("task")
-	if taskID == "" {
-		taskID = s.getDefaultTask()
+// handleDomTreeRetainedSet returns the retained set summary of a comma-separated
+// selection of object IDs (ids parameter).
+func (s *Server) handleDomTreeRetainedSet(w http.ResponseWriter, r *http.Request) {
+	var req retainedSetRequest
+	if err := decodeQuery(r, &req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
 	}
 
-	className := r.URL.Query().Get("class")
-	if className == "" {
-		http.Error(w, "Class name is required", http.StatusBadRequest)
+	set, err := s.refGraphService.GetRetainedSet(s.resolveTask(req.Task), req.IDs)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
-	topN := 50
-	if tn := r.URL.Query().Get("top"); tn != "" {
-		if n, err := parseInt(tn); err == nil && n > 0 {
-			topN = n
-		}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	json.NewEncoder(w).Encode(set)
+}
+
+// handleRefGraphReachability reports whether the objects of the to parameter
+// are reachable from the objects of the from parameter.
+func (s *Server) handleRefGraphReachability(w http.ResponseWriter, r *http.Request) {
+	var req reachabilityRequest
+	if err := decodeQuery(r, &req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
 	}
 
-	sortBy := r.URL.Query().Get("sort")
-	if sortBy == "" {
-		sortBy = "retained"
+	resp, err := s.refGraphService.GetReachability(s.resolveTask(req.Task), req.From, req.To)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
 	}
 
-	objects, err := s.refGraphService.GetBiggestObjectsByClass(taskID, className, topN, sortBy)
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// handleRefGraphGCRootsList returns all GC roots with their information.
+func (s *Server) handleRefGraphGCRootsList(w http.ResponseWriter, r *http.Request) {
+	var req taskRequest
+	if err := decodeQuery(r, &req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	roots, err := s.refGraphService.GetGCRootsList(s.resolveTask(req.Task))
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusNotFound)
 		return
 	}
 
-	// Convert to JSON-friendly format
-	type ObjectResponse struct {
-		ObjectID     string `json:"object_id"`
-		ClassName    string `json:"class_name"`
-		ShallowSize  int64  `json:"shallow_size"`
-		RetainedSize int64  `json:"retained_size"`
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	json.NewEncoder(w).Encode(roots)
+}
+
+// maxGCRootRetainedPageSize caps the max parameter of /api/refgraph/gc-root-retained.
+const maxGCRootRetainedPageSize = 1000
+
+// handleRefGraphGCRootRetained returns one page of the objects exclusively
+// retained through a GC root, with exact totals for the whole set.
+func (s *Server) handleRefGraphGCRootRetained(w http.ResponseWriter, r *http.Request) {
+	req := gcRootRetainedRequest{objectLimitRequest: objectLimitRequest{Max: 50}}
+	if err := decodeQuery(r, &req); err != nil || req.Max <= 0 || req.Max > maxGCRootRetainedPageSize {
+		http.Error(w, "Invalid parameters: object ID and a max between 1 and 1000 are required", http.StatusBadRequest)
+		return
 	}
 
-	response := make([]ObjectResponse, 0, len(objects))
+	page, err := s.refGraphService.GetGCRootRetained(s.resolveTask(req.Task), req.ID, req.After, req.Max)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	json.NewEncoder(w).Encode(page)
+}
+
+// handleRefGraphRetainers returns the objects that retain a specific object.
+func (s *Server) handleRefGraphRetainers(w http.ResponseWriter, r *http.Request) {
+	req := objectLimitRequest{Max: 20}
+	if err := decodeQuery(r, &req); err != nil || req.Max <= 0 {
+		http.Error(w, "Invalid parameters: object ID and a positive max are required", http.StatusBadRequest)
+		return
+	}
+
+	retainers, err := s.refGraphService.GetRetainers(s.resolveTask(req.Task), req.ID, req.Max)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	json.NewEncoder(w).Encode(retainers)
+}
+
+// handleRefGraphBiggestByClass returns the biggest objects for a specific class.
+func (s *Server) handleRefGraphBiggestByClass(w http.ResponseWriter, r *http.Request) {
+	req := classObjectsRequest{Top: 50, Sort: "retained"}
+	if err := decodeQuery(r, &req); err != nil || req.Top <= 0 {
+		http.Error(w, "Invalid parameters: class name and a positive top are required", http.StatusBadRequest)
+		return
+	}
+
+	objects, err := s.refGraphService.GetBiggestObjectsByClass(s.resolveTask(req.Task), req.Class, req.Top, req.Sort)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	// Convert to JSON-friendly format
+	response := make([]ClassObjectResponse, 0, len(objects))
 	for _, obj := range objects {
-		response = append(response, ObjectResponse{
+		response = append(response, ClassObjectResponse{
 			ObjectID:     formatObjectID(obj.ObjectID),
 			ClassName:    obj.ClassName,
 			ShallowSize:  obj.ShallowSize,
@@ -1016,6 +1227,62 @@ func (s *Server) handleRefGraphBiggestByClass(w http.ResponseWriter, r *http.Req
 	json.NewEncoder(w).Encode(response)
 }
 
+// maxClassInstancesPageSize caps the limit parameter of /api/refgraph/instances.
+const maxClassInstancesPageSize = 1000
+
+// handleRefGraphInstances returns one page of the instances of a class.
+func (s *Server) handleRefGraphInstances(w http.ResponseWriter, r *http.Request) {
+	req := classInstancesRequest{Sort: "retained", Limit: 100}
+	if err := decodeQuery(r, &req); err != nil || req.Limit <= 0 || req.Limit > maxClassInstancesPageSize {
+		http.Error(w, "Invalid parameters: class name and a limit between 1 and 1000 are required", http.StatusBadRequest)
+		return
+	}
+
+	page, err := s.refGraphService.GetClassInstances(s.resolveTask(req.Task), req.Class, req.Sort, req.After, req.Limit)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	response := ClassInstancesResponse{
+		ClassName: page.ClassName,
+		Total:     page.Total,
+		Items:     make([]InstanceResponse, 0, len(page.Items)),
+		Next:      page.Next,
+	}
+	for _, item := range page.Items {
+		response.Items = append(response.Items, InstanceResponse{
+			ObjectID:     formatObjectID(item.ObjectID),
+			ShallowSize:  item.ShallowSize,
+			RetainedSize: item.RetainedSize,
+			Reachable:    item.Reachable,
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	json.NewEncoder(w).Encode(response)
+}
+
+// handleRefGraphDominatorRetainers returns the dominator-based retainers of a class.
+func (s *Server) handleRefGraphDominatorRetainers(w http.ResponseWriter, r *http.Request) {
+	req := classRetainersRequest{Top: 20}
+	if err := decodeQuery(r, &req); err != nil || req.Top <= 0 {
+		http.Error(w, "Invalid parameters: class name and a positive top are required", http.StatusBadRequest)
+		return
+	}
+
+	retainers, err := s.refGraphService.GetDominatorRetainers(s.resolveTask(req.Task), req.Class, req.Top)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	json.NewEncoder(w).Encode(retainers)
+}
+
 // parseInt parses an integer from a string.
 func parseInt(s string) (int, error) {
 	var n int
@@ -1052,31 +1319,24 @@ func (s *Server) handlePProfLeakReport(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	var batchResult map[string]interface{}
-	if err := json.Unmarshal(data, &batchResult); err != nil {
+	var response LeakReportResponse
+	if err := json.Unmarshal(data, &response); err != nil {
 		http.Error(w, "Failed to parse batch analysis", http.StatusInternalServerError)
 		return
 	}
-
-	// Extract leak reports
-	leakReports, ok := batchResult["leak_reports"].(map[string]interface{})
-	if !ok {
-		leakReports = make(map[string]interface{})
+	if response.LeakReports == nil {
+		response.LeakReports = make(map[string]json.RawMessage)
 	}
 
 	// Filter by type if specified
 	if leakType != "all" {
-		if report, exists := leakReports[leakType]; exists {
-			leakReports = map[string]interface{}{leakType: report}
+		if report, exists := response.LeakReports[leakType]; exists {
+			response.LeakReports = map[string]json.RawMessage{leakType: report}
 		} else {
-			leakReports = make(map[string]interface{})
+			response.LeakReports = make(map[string]json.RawMessage)
 		}
 	}
 
-	response := map[string]interface{}{
-		"leak_reports": leakReports,
-	}
-
 	w.Header().Set("Content-Type", "application/json")
 	w.Header().Set("Access-Control-Allow-Origin", "*")
 	json.NewEncoder(w).Encode(response)
@@ -1106,3 +1366,23 @@ func (s *Server) handlePProfBatchAnalysis(w http.ResponseWriter, r *http.Request
 	w.Header().Set("Access-Control-Allow-Origin", "*")
 	w.Write(data)
 }
+
+// handleThreadDump returns the thread dump analysis of a java-threaddump task.
+func (s *Server) handleThreadDump(w http.ResponseWriter, r *http.Request) {
+	taskID := r.URL.Query().Get("task")
+
+	taskDir := s.dataDir
+	if taskID != "" {
+		taskDir = filepath.Join(s.dataDir, taskID)
+	}
+
+	data, err := os.ReadFile(filepath.Join(taskDir, "threaddump.json"))
+	if err != nil {
+		http.Error(w, "Thread dump analysis not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Write(data)
+}
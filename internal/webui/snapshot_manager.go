@@ -0,0 +1,246 @@
+package webui
+
+import (
+	"container/list"
+	"sync"
+	"time"
+
+	"github.com/perf-analysis/internal/parser/hprof"
+)
+
+// SnapshotManagerConfig limits how many heap snapshots stay resident.
+type SnapshotManagerConfig struct {
+	// MaxSnapshots is the maximum number of resident snapshots (0 = unlimited).
+	MaxSnapshots int
+	// MaxBytes is the maximum estimated memory of resident snapshots (0 = unlimited).
+	// The most recently used snapshot is always kept, even if it exceeds the budget.
+	MaxBytes int64
+}
+
+// DefaultSnapshotManagerConfig returns the default snapshot cache limits.
+func DefaultSnapshotManagerConfig() SnapshotManagerConfig {
+	return SnapshotManagerConfig{
+		MaxSnapshots: 3,
+	}
+}
+
+// SnapshotLoader loads the heap snapshot of a task.
+type SnapshotLoader func(taskID string) (*hprof.HeapSnapshot, error)
+
+// SnapshotManager keeps recently viewed heap snapshots resident using an LRU
+// policy bounded by snapshot count and estimated memory.
+// Concurrent requests for the same task share a single load.
+type SnapshotManager struct {
+	config SnapshotManagerConfig
+	load   SnapshotLoader
+
+	mu       sync.Mutex
+	lru      *list.List // front = most recently used; values are *snapshotEntry
+	entries  map[string]*list.Element
+	inflight map[string]*snapshotLoad
+	bytes    int64
+
+	hits      int64
+	misses    int64
+	evictions int64
+}
+
+// snapshotEntry is a resident snapshot.
+type snapshotEntry struct {
+	taskID       string
+	snapshot     *hprof.HeapSnapshot
+	bytes        int64
+	loadedAt     time.Time
+	lastAccess   time.Time
+	loadDuration time.Duration
+	hits         int64
+}
+
+// snapshotLoad tracks a load in progress.
+type snapshotLoad struct {
+	done     chan struct{}
+	snapshot *hprof.HeapSnapshot
+	err      error
+	// stale is set when the task was evicted during the load: the snapshot
+	// is returned to the requests waiting for it but not cached
+	stale bool
+}
+
+// SnapshotCacheEntry describes a resident snapshot.
+type SnapshotCacheEntry struct {
+	TaskID         string    `json:"task_id"`
+	EstimatedBytes int64     `json:"estimated_bytes"`
+	Objects        int       `json:"objects"`
+	LoadedAt       time.Time `json:"loaded_at"`
+	LastAccess     time.Time `json:"last_access"`
+	LoadMillis     int64     `json:"load_ms"`
+	Hits           int64     `json:"hits"`
+}
+
+// SnapshotCacheStats describes the state of the snapshot cache.
+type SnapshotCacheStats struct {
+	MaxSnapshots int                   `json:"max_snapshots"`
+	MaxBytes     int64                 `json:"max_bytes"`
+	Snapshots    int                   `json:"snapshots"`
+	Bytes        int64                 `json:"bytes"`
+	Hits         int64                 `json:"hits"`
+	Misses       int64                 `json:"misses"`
+	Evictions    int64                 `json:"evictions"`
+	Loading      []string              `json:"loading,omitempty"`
+	Entries      []*SnapshotCacheEntry `json:"entries"`
+}
+
+// NewSnapshotManager creates a snapshot manager using load to read snapshots from disk.
+func NewSnapshotManager(config SnapshotManagerConfig, load SnapshotLoader) *SnapshotManager {
+	return &SnapshotManager{
+		config:   config,
+		load:     load,
+		lru:      list.New(),
+		entries:  make(map[string]*list.Element),
+		inflight: make(map[string]*snapshotLoad),
+	}
+}
+
+// Get returns the snapshot of a task, loading it if it is not resident.
+func (m *SnapshotManager) Get(taskID string) (*hprof.HeapSnapshot, error) {
+	m.mu.Lock()
+	if elem, ok := m.entries[taskID]; ok {
+		entry := elem.Value.(*snapshotEntry)
+		entry.lastAccess = time.Now()
+		entry.hits++
+		m.hits++
+		m.lru.MoveToFront(elem)
+		m.mu.Unlock()
+		return entry.snapshot, nil
+	}
+
+	// Another request is already loading this task; wait for it
+	if inflight, ok := m.inflight[taskID]; ok {
+		m.mu.Unlock()
+		<-inflight.done
+		return inflight.snapshot, inflight.err
+	}
+
+	m.misses++
+	inflight := &snapshotLoad{done: make(chan struct{})}
+	m.inflight[taskID] = inflight
+	m.mu.Unlock()
+
+	start := time.Now()
+	inflight.snapshot, inflight.err = m.load(taskID)
+	loadDuration := time.Since(start)
+
+	m.mu.Lock()
+	if m.inflight[taskID] == inflight {
+		delete(m.inflight, taskID)
+	}
+	if inflight.err == nil && !inflight.stale {
+		m.insertLocked(&snapshotEntry{
+			taskID:       taskID,
+			snapshot:     inflight.snapshot,
+			bytes:        inflight.snapshot.EstimatedBytes(),
+			loadedAt:     time.Now(),
+			lastAccess:   time.Now(),
+			loadDuration: loadDuration,
+		})
+	}
+	m.mu.Unlock()
+	close(inflight.done)
+
+	return inflight.snapshot, inflight.err
+}
+
+// insertLocked adds an entry and evicts least recently used entries over the limits.
+func (m *SnapshotManager) insertLocked(entry *snapshotEntry) {
+	m.entries[entry.taskID] = m.lru.PushFront(entry)
+	m.bytes += entry.bytes
+
+	for m.lru.Len() > 1 && m.overLimitLocked() {
+		m.removeLocked(m.lru.Back())
+		m.evictions++
+	}
+}
+
+// overLimitLocked reports whether the cache exceeds its configured limits.
+func (m *SnapshotManager) overLimitLocked() bool {
+	if m.config.MaxSnapshots > 0 && m.lru.Len() > m.config.MaxSnapshots {
+		return true
+	}
+	return m.config.MaxBytes > 0 && m.bytes > m.config.MaxBytes
+}
+
+// removeLocked drops an entry from the cache.
+func (m *SnapshotManager) removeLocked(elem *list.Element) {
+	entry := m.lru.Remove(elem).(*snapshotEntry)
+	delete(m.entries, entry.taskID)
+	m.bytes -= entry.bytes
+}
+
+// Evict removes a task's snapshot from the cache. Returns false if it was not resident.
+// A load of the task in progress is not cached, and later requests load the task again.
+func (m *SnapshotManager) Evict(taskID string) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if inflight, ok := m.inflight[taskID]; ok {
+		inflight.stale = true
+		delete(m.inflight, taskID)
+	}
+	elem, ok := m.entries[taskID]
+	if !ok {
+		return false
+	}
+	m.removeLocked(elem)
+	return true
+}
+
+// Flush removes all snapshots from the cache and returns how many were dropped.
+// Loads in progress are not cached.
+func (m *SnapshotManager) Flush() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for taskID, inflight := range m.inflight {
+		inflight.stale = true
+		delete(m.inflight, taskID)
+	}
+
+	n := m.lru.Len()
+	m.lru.Init()
+	m.entries = make(map[string]*list.Element)
+	m.bytes = 0
+	return n
+}
+
+// Stats returns the cache state with entries ordered from most to least recently used.
+func (m *SnapshotManager) Stats() *SnapshotCacheStats {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	stats := &SnapshotCacheStats{
+		MaxSnapshots: m.config.MaxSnapshots,
+		MaxBytes:     m.config.MaxBytes,
+		Snapshots:    m.lru.Len(),
+		Bytes:        m.bytes,
+		Hits:         m.hits,
+		Misses:       m.misses,
+		Evictions:    m.evictions,
+		Entries:      make([]*SnapshotCacheEntry, 0, m.lru.Len()),
+	}
+	for taskID := range m.inflight {
+		stats.Loading = append(stats.Loading, taskID)
+	}
+	for elem := m.lru.Front(); elem != nil; elem = elem.Next() {
+		entry := elem.Value.(*snapshotEntry)
+		stats.Entries = append(stats.Entries, &SnapshotCacheEntry{
+			TaskID:         entry.taskID,
+			EstimatedBytes: entry.bytes,
+			Objects:        entry.snapshot.ObjectCount(),
+			LoadedAt:       entry.loadedAt,
+			LastAccess:     entry.lastAccess,
+			LoadMillis:     entry.loadDuration.Milliseconds(),
+			Hits:           entry.hits,
+		})
+	}
+	return stats
+}
@@ -0,0 +1,203 @@
+package webui
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/perf-analysis/internal/parser/hprof"
+)
+
+// newTestHeapSnapshot returns a snapshot of a heap of n objects.
+func newTestHeapSnapshot(n int) *hprof.HeapSnapshot {
+	g := hprof.NewReferenceGraphWithCapacity(n)
+	g.SetClassName(1, "byte[]")
+	for i := 1; i <= n; i++ {
+		g.SetObjectInfo(uint64(i), 1, 16)
+		g.AddGCRoot(&hprof.GCRoot{ObjectID: uint64(i), Type: hprof.GCRootJNIGlobal})
+	}
+	g.ComputeDominatorTree()
+	return hprof.NewHeapSnapshot(g, nil, nil)
+}
+
+// stubLoader loads fixed snapshots by task and counts the loads.
+type stubLoader struct {
+	snapshots map[string]*hprof.HeapSnapshot
+	loads     atomic.Int64
+}
+
+func (l *stubLoader) load(taskID string) (*hprof.HeapSnapshot, error) {
+	l.loads.Add(1)
+	snapshot, ok := l.snapshots[taskID]
+	if !ok {
+		return nil, errors.New("task not found: " + taskID)
+	}
+	return snapshot, nil
+}
+
+// residentTasks returns the cached tasks, most recently used first.
+func residentTasks(m *SnapshotManager) []string {
+	var tasks []string
+	for _, entry := range m.Stats().Entries {
+		tasks = append(tasks, entry.TaskID)
+	}
+	return tasks
+}
+
+func TestSnapshotManager_MaxSnapshots(t *testing.T) {
+	loader := &stubLoader{snapshots: map[string]*hprof.HeapSnapshot{
+		"a": newTestHeapSnapshot(1), "b": newTestHeapSnapshot(1), "c": newTestHeapSnapshot(1),
+	}}
+	m := NewSnapshotManager(SnapshotManagerConfig{MaxSnapshots: 2}, loader.load)
+
+	for _, task := range []string{"a", "b", "a", "c"} {
+		_, err := m.Get(task)
+		require.NoError(t, err)
+	}
+	// b was the least recently used when c was loaded
+	assert.Equal(t, []string{"c", "a"}, residentTasks(m))
+	stats := m.Stats()
+	assert.Equal(t, int64(1), stats.Evictions)
+	assert.Equal(t, int64(1), stats.Hits)
+	assert.Equal(t, int64(3), stats.Misses)
+	assert.Equal(t, int64(3), loader.loads.Load())
+}
+
+func TestSnapshotManager_MaxBytes(t *testing.T) {
+	small, large := newTestHeapSnapshot(1), newTestHeapSnapshot(1000)
+	require.Greater(t, large.EstimatedBytes(), 2*small.EstimatedBytes())
+	loader := &stubLoader{snapshots: map[string]*hprof.HeapSnapshot{
+		"a": small, "b": newTestHeapSnapshot(1), "large": large,
+	}}
+	m := NewSnapshotManager(SnapshotManagerConfig{MaxBytes: 2 * small.EstimatedBytes()}, loader.load)
+
+	for _, task := range []string{"a", "b", "a"} {
+		_, err := m.Get(task)
+		require.NoError(t, err)
+	}
+	assert.Equal(t, []string{"a", "b"}, residentTasks(m))
+	assert.Equal(t, 2*small.EstimatedBytes(), m.Stats().Bytes)
+
+	// The most recent snapshot is kept even when it exceeds the budget alone
+	_, err := m.Get("large")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"large"}, residentTasks(m))
+	assert.Equal(t, large.EstimatedBytes(), m.Stats().Bytes)
+	assert.Equal(t, int64(2), m.Stats().Evictions)
+}
+
+func TestSnapshotManager_SharedLoad(t *testing.T) {
+	release := make(chan struct{})
+	snapshot := newTestHeapSnapshot(1)
+	var loads atomic.Int64
+	m := NewSnapshotManager(DefaultSnapshotManagerConfig(), func(taskID string) (*hprof.HeapSnapshot, error) {
+		loads.Add(1)
+		<-release
+		return snapshot, nil
+	})
+
+	const requests = 8
+	var wg sync.WaitGroup
+	results := make([]*hprof.HeapSnapshot, requests)
+	for i := 0; i < requests; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			results[i], _ = m.Get("task")
+		}(i)
+	}
+	require.Eventually(t, func() bool { return len(m.Stats().Loading) == 1 }, time.Second, time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	assert.Equal(t, int64(1), loads.Load())
+	for _, result := range results {
+		assert.Same(t, snapshot, result)
+	}
+	assert.Empty(t, m.Stats().Loading)
+}
+
+func TestSnapshotManager_FailedLoad(t *testing.T) {
+	loader := &stubLoader{snapshots: map[string]*hprof.HeapSnapshot{}}
+	m := NewSnapshotManager(DefaultSnapshotManagerConfig(), loader.load)
+
+	_, err := m.Get("missing")
+	assert.Error(t, err)
+	_, err = m.Get("missing")
+	assert.Error(t, err)
+	// Failures are not cached: every request tries again
+	assert.Equal(t, int64(2), loader.loads.Load())
+	assert.Empty(t, residentTasks(m))
+}
+
+func TestSnapshotManager_EvictDuringLoad(t *testing.T) {
+	for name, drop := range map[string]func(m *SnapshotManager){
+		"evict": func(m *SnapshotManager) { m.Evict("task") },
+		"flush": func(m *SnapshotManager) { m.Flush() },
+	} {
+		t.Run(name, func(t *testing.T) {
+			release := make(chan struct{})
+			stale, fresh := newTestHeapSnapshot(1), newTestHeapSnapshot(2)
+			var loads atomic.Int64
+			m := NewSnapshotManager(DefaultSnapshotManagerConfig(), func(taskID string) (*hprof.HeapSnapshot, error) {
+				if loads.Add(1) == 1 {
+					<-release
+					return stale, nil
+				}
+				return fresh, nil
+			})
+
+			done := make(chan *hprof.HeapSnapshot)
+			go func() {
+				snapshot, _ := m.Get("task")
+				done <- snapshot
+			}()
+			require.Eventually(t, func() bool { return len(m.Stats().Loading) == 1 }, time.Second, time.Millisecond)
+			drop(m)
+
+			// Requests after the eviction do not wait for the stale load
+			again := make(chan *hprof.HeapSnapshot, 1)
+			go func() {
+				snapshot, _ := m.Get("task")
+				again <- snapshot
+			}()
+			select {
+			case snapshot := <-again:
+				assert.Same(t, fresh, snapshot)
+			case <-time.After(time.Second):
+				close(release)
+				t.Fatal("request waited for the load of the evicted snapshot")
+			}
+
+			// The stale load completes for its caller but is not cached
+			close(release)
+			assert.Same(t, stale, <-done)
+			snapshot, err := m.Get("task")
+			require.NoError(t, err)
+			assert.Same(t, fresh, snapshot)
+			assert.Equal(t, int64(2), loads.Load())
+			assert.Equal(t, []string{"task"}, residentTasks(m))
+		})
+	}
+}
+
+func TestSnapshotManager_Evict(t *testing.T) {
+	loader := &stubLoader{snapshots: map[string]*hprof.HeapSnapshot{"a": newTestHeapSnapshot(1), "b": newTestHeapSnapshot(1)}}
+	m := NewSnapshotManager(SnapshotManagerConfig{}, loader.load)
+	for _, task := range []string{"a", "b"} {
+		_, err := m.Get(task)
+		require.NoError(t, err)
+	}
+
+	assert.True(t, m.Evict("a"))
+	assert.False(t, m.Evict("a"))
+	assert.Equal(t, []string{"b"}, residentTasks(m))
+	assert.Equal(t, 1, m.Flush())
+	assert.Empty(t, residentTasks(m))
+	assert.Zero(t, m.Stats().Bytes)
+}
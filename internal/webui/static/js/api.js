@@ -12,6 +12,64 @@ const API = {
         return response.json();
     },
 
+    // Fetch the metadata (name, tags, source host, dump time, notes) of a task
+    async getTaskMeta(taskId) {
+        const response = await fetch(`/api/tasks/meta?task=${encodeURIComponent(taskId)}`);
+        if (!response.ok) {
+            throw new Error((await response.text()).trim() || `HTTP ${response.status}`);
+        }
+        return response.json();
+    },
+
+    // Replace the metadata of a task
+    async saveTaskMeta(taskId, meta) {
+        const response = await fetch(`/api/tasks/meta?task=${encodeURIComponent(taskId)}`, {
+            method: 'PUT',
+            headers: { 'Content-Type': 'application/json' },
+            body: JSON.stringify(meta)
+        });
+        if (!response.ok) {
+            throw new Error((await response.text()).trim() || `HTTP ${response.status}`);
+        }
+        return response.json();
+    },
+
+    // Remove the metadata of a task
+    async deleteTaskMeta(taskId) {
+        const response = await fetch(`/api/tasks/meta?task=${encodeURIComponent(taskId)}`, { method: 'DELETE' });
+        if (!response.ok) {
+            throw new Error((await response.text()).trim() || `HTTP ${response.status}`);
+        }
+        return response.json();
+    },
+
+    // Follow analysis progress of a task over server-sent events; returns the
+    // EventSource so the caller can close it
+    watchProgress(taskId, onEvent) {
+        const source = new EventSource(`/api/progress?task=${encodeURIComponent(taskId)}`);
+        source.addEventListener('progress', (e) => onEvent(JSON.parse(e.data)));
+        return source;
+    },
+
+    // Tail the analyzer log of a task over a WebSocket; onEvent receives each
+    // batch of lines. Returns the WebSocket so the caller can close it
+    watchLogs(taskId, onEvent, lines = 500) {
+        const scheme = window.location.protocol === 'https:' ? 'wss' : 'ws';
+        const params = new URLSearchParams({ task: taskId, lines });
+        const socket = new WebSocket(`${scheme}://${window.location.host}/api/logs/stream?${params}`);
+        socket.addEventListener('message', (e) => onEvent(JSON.parse(e.data)));
+        return socket;
+    },
+
+    // Fetch analysis job status (state and stage timings) for a task
+    async getJobStatus(taskId) {
+        const response = await fetch(`/api/job?task=${taskId}`);
+        if (!response.ok) {
+            throw new Error(`HTTP ${response.status}`);
+        }
+        return response.json();
+    },
+
     // Fetch summary data for a task
     async getSummary(taskId) {
         const response = await fetch(`/api/summary?task=${taskId}`);
@@ -23,11 +81,15 @@ const API = {
 
     // Fetch flame graph data for a task
     // type: 'cpu' (default), 'memory', 'alloc', 'tracing'
-    async getFlameGraph(taskId, type = '') {
+    // collapseInlined merges the frames inlined by the JIT into their caller
+    async getFlameGraph(taskId, type = '', collapseInlined = false) {
         let url = `/api/flamegraph?task=${taskId}`;
         if (type) {
             url += `&type=${type}`;
         }
+        if (collapseInlined) {
+            url += '&collapse_inlined=true';
+        }
         const response = await fetch(url);
         if (!response.ok) {
             throw new Error(`HTTP ${response.status}`);
@@ -58,6 +120,190 @@ const API = {
         return response.json();
     },
 
+    // Fetch a page of the full class histogram
+    // query: { offset, limit, sort, order, filter, loader, group }
+    async getClasses(taskId, query = {}) {
+        const params = new URLSearchParams({ task: taskId });
+        for (const [key, value] of Object.entries(query)) {
+            if (value !== undefined && value !== null && value !== '') {
+                params.set(key, value);
+            }
+        }
+        const response = await fetch(`/api/classes?${params}`);
+        if (!response.ok) {
+            throw new Error((await response.text()).trim() || `HTTP ${response.status}`);
+        }
+        return response.json();
+    },
+
+    // Fetch the whole class histogram as column arrays
+    // query: { sort, order, filter, columns }
+    async getClassColumns(taskId, query = {}) {
+        const params = new URLSearchParams({ task: taskId });
+        for (const [key, value] of Object.entries(query)) {
+            if (value !== undefined && value !== null && value !== '') {
+                params.set(key, value);
+            }
+        }
+        const response = await fetch(`/api/classes/columns?${params}`);
+        if (!response.ok) {
+            throw new Error((await response.text()).trim() || `HTTP ${response.status}`);
+        }
+        return response.json();
+    },
+
+    // Fetch the class-level comparison of two heap analysis tasks
+    async getHeapDiff(baseTaskId, targetTaskId, includeUnchanged = false) {
+        const params = new URLSearchParams({ base: baseTaskId, target: targetTaskId });
+        if (includeUnchanged) params.set('include_unchanged', 'true');
+        const response = await fetch(`/api/heap/diff?${params}`);
+        if (!response.ok) {
+            throw new Error((await response.text()).trim() || `HTTP ${response.status}`);
+        }
+        return response.json();
+    },
+
+    // Fetch the object-level comparison of two heap analysis tasks
+    async getHeapObjectDiff(baseTaskId, targetTaskId, top = 100) {
+        const params = new URLSearchParams({ base: baseTaskId, target: targetTaskId, top });
+        const response = await fetch(`/api/heap/diff/objects?${params}`);
+        if (!response.ok) {
+            throw new Error((await response.text()).trim() || `HTTP ${response.status}`);
+        }
+        return response.json();
+    },
+
+    // Fetch thread stacks of a heap dump (from threads.json)
+    async getHeapThreads(taskId) {
+        const response = await fetch(`/api/heap/threads?task=${encodeURIComponent(taskId)}`);
+        if (!response.ok) {
+            throw new Error((await response.text()).trim() || `HTTP ${response.status}`);
+        }
+        return response.json();
+    },
+
+    // Fetch the retained memory by owning team
+    async getHeapOwnership(taskId) {
+        const params = new URLSearchParams({ task: taskId });
+        const response = await fetch(`/api/heap/ownership?${params}`);
+        if (!response.ok) {
+            throw new Error((await response.text()).trim() || `HTTP ${response.status}`);
+        }
+        return response.json();
+    },
+
+    // Fetch the analysis of Java thread dumps (from threaddump.json)
+    async getThreadDump(taskId) {
+        const response = await fetch(`/api/threaddump?task=${encodeURIComponent(taskId)}`);
+        if (!response.ok) {
+            throw new Error((await response.text()).trim() || `HTTP ${response.status}`);
+        }
+        return response.json();
+    },
+
+    // Fetch the allocation sites of a task linked to its heap dump and CPU profile
+    async getMemoryStory(taskId) {
+        const response = await fetch(`/api/memory-story?task=${encodeURIComponent(taskId)}`);
+        if (!response.ok) {
+            throw new Error((await response.text()).trim() || `HTTP ${response.status}`);
+        }
+        return response.json();
+    },
+
+    // Fetch the values over time of the top functions of a timestamped profile
+    async getTimeline(taskId, type = 'cpu', top = 10) {
+        const params = new URLSearchParams({ task: taskId, type, top });
+        const response = await fetch(`/api/timeline?${params}`);
+        if (!response.ok) {
+            throw new Error((await response.text()).trim() || `HTTP ${response.status}`);
+        }
+        return response.json();
+    },
+
+    // Fetch the flame graph of a time window (seconds since the start of the timeline)
+    async getFlameGraphWindow(taskId, type, from, to) {
+        const params = new URLSearchParams({ task: taskId, type, from, to });
+        const response = await fetch(`/api/flamegraph/window?${params}`);
+        if (!response.ok) {
+            throw new Error((await response.text()).trim() || `HTTP ${response.status}`);
+        }
+        return response.json();
+    },
+
+    // Search the frames of a flame graph with a regular expression
+    async searchFlameGraph(taskId, type, pattern, limit = 50) {
+        const params = new URLSearchParams({ task: taskId, type, pattern, ignore_case: 'true', limit });
+        const response = await fetch(`/api/flamegraph/search?${params}`);
+        if (!response.ok) {
+            throw new Error((await response.text()).trim() || `HTTP ${response.status}`);
+        }
+        return response.json();
+    },
+
+    // Fetch the frame annotations of a task, pinned first
+    async getAnnotations(taskId) {
+        const response = await fetch(`/api/annotations?task=${encodeURIComponent(taskId)}`);
+        if (!response.ok) {
+            throw new Error((await response.text()).trim() || `HTTP ${response.status}`);
+        }
+        return response.json();
+    },
+
+    // Attach a note to a frame; or update the note and pinning of an annotation if it has an id
+    async saveAnnotation(taskId, annotation) {
+        const params = new URLSearchParams({ task: taskId });
+        if (annotation.id) params.set('id', annotation.id);
+        const response = await fetch(`/api/annotations?${params}`, {
+            method: annotation.id ? 'PUT' : 'POST',
+            headers: { 'Content-Type': 'application/json' },
+            body: JSON.stringify(annotation)
+        });
+        if (!response.ok) {
+            throw new Error((await response.text()).trim() || `HTTP ${response.status}`);
+        }
+        return response.json();
+    },
+
+    // Remove a frame annotation
+    async deleteAnnotation(taskId, id) {
+        const params = new URLSearchParams({ task: taskId, id });
+        const response = await fetch(`/api/annotations?${params}`, { method: 'DELETE' });
+        if (!response.ok) {
+            throw new Error((await response.text()).trim() || `HTTP ${response.status}`);
+        }
+        return response.json();
+    },
+
+    // Fetch the differential flame graph of two tasks
+    async getFlameGraphDiff(baseTaskId, targetTaskId, type) {
+        const params = new URLSearchParams({ base: baseTaskId, target: targetTaskId, type });
+        const response = await fetch(`/api/flamegraph/diff?${params}`);
+        if (!response.ok) {
+            throw new Error((await response.text()).trim() || `HTTP ${response.status}`);
+        }
+        return response.json();
+    },
+
+    // Fetch field values or array elements of an object (read from the heap dump)
+    async getObjectContent(taskId, objectId, maxElements = 100) {
+        const params = new URLSearchParams({ task: taskId, id: objectId, max_elements: maxElements });
+        const response = await fetch(`/api/refgraph/object?${params}`);
+        if (!response.ok) {
+            throw new Error((await response.text()).trim() || `HTTP ${response.status}`);
+        }
+        return response.json();
+    },
+
+    // Fetch a window of the raw bytes of a primitive array or String, as hex and text
+    async getObjectPayload(taskId, objectId, offset = 0, limit = 4096) {
+        const params = new URLSearchParams({ task: taskId, id: objectId, offset, limit });
+        const response = await fetch(`/api/refgraph/payload?${params}`);
+        if (!response.ok) {
+            throw new Error((await response.text()).trim() || `HTTP ${response.status}`);
+        }
+        return response.json();
+    },
+
     // Fetch GC roots summary (from gc_roots.json or refgraph)
     async getGCRootsSummary(taskId) {
         const response = await fetch(`/api/refgraph/gc-roots-summary?task=${taskId}`);
@@ -77,14 +323,49 @@ const API = {
     },
 
     // Fetch objects retained by a specific GC root
-    async getGCRootRetained(taskId, objectId, maxObjects = 50) {
-        const response = await fetch(`/api/refgraph/gc-root-retained?task=${taskId}&id=${objectId}&max=${maxObjects}`);
+    async getGCRootRetained(taskId, objectId, maxObjects = 50, after = '') {
+        let url = `/api/refgraph/gc-root-retained?task=${taskId}&id=${objectId}&max=${maxObjects}`;
+        if (after) {
+            url += `&after=${after}`;
+        }
+        const response = await fetch(url);
         if (!response.ok) {
             throw new Error(`HTTP ${response.status}`);
         }
         return response.json();
     },
 
+    // Fetch the hierarchy, fields and static field values of a class
+    async getClassMetadata(taskId, className) {
+        const params = new URLSearchParams({ task: taskId, class: className });
+        const response = await fetch(`/api/refgraph/class?${params}`);
+        if (!response.ok) {
+            throw new Error((await response.text()).trim() || `HTTP ${response.status}`);
+        }
+        return response.json();
+    },
+
+    // Fetch a page of the instances of a class; after is the next cursor of the previous page
+    async getClassInstances(taskId, className, sort = 'retained', after = '', limit = 100) {
+        const params = new URLSearchParams({ task: taskId, class: className, sort, limit });
+        if (after) params.set('after', after);
+        const response = await fetch(`/api/refgraph/instances?${params}`);
+        if (!response.ok) {
+            throw new Error((await response.text()).trim() || `HTTP ${response.status}`);
+        }
+        return response.json();
+    },
+
+    // Fetch the classes whose instances dominate the instances of a class
+    async getDominatorRetainers(taskId, className, top = 20) {
+        const params = new URLSearchParams({ task: taskId, class: className, top });
+        const response = await fetch(`/api/refgraph/dominator-retainers?${params}`);
+        if (!response.ok) {
+            throw new Error((await response.text()).trim() || `HTTP ${response.status}`);
+        }
+        return response.json();
+    },
+
     // Fetch object fields using refgraph
     async getObjectFields(taskId, objectId) {
         const response = await fetch(`/api/refgraph/fields?task=${taskId}&id=${objectId}`);
@@ -92,6 +373,105 @@ const API = {
             throw new Error(`HTTP ${response.status}`);
         }
         return response.json();
+    },
+
+    // Fetch paths from GC roots to an object
+    // options: { maxPaths, maxDepth, excludeWeak, excludeSoft, excludePhantom }
+    async getGCRootPaths(taskId, objectId, options = {}) {
+        const params = new URLSearchParams({ task: taskId, id: objectId });
+        if (options.maxPaths) params.set('max_paths', options.maxPaths);
+        if (options.maxDepth) params.set('max_depth', options.maxDepth);
+        if (options.excludeWeak) params.set('exclude_weak', 'true');
+        if (options.excludeSoft) params.set('exclude_soft', 'true');
+        if (options.excludePhantom) params.set('exclude_phantom', 'true');
+        const response = await fetch(`/api/refgraph/gc-roots?${params}`);
+        if (!response.ok) {
+            throw new Error(`HTTP ${response.status}`);
+        }
+        return response.json();
+    },
+
+    // Fetch objects immediately dominated by an object (top level when objectId is empty)
+    async getDomTreeChildren(taskId, objectId = '', limit = 100) {
+        let url = `/api/domtree/children?task=${taskId}&limit=${limit}`;
+        if (objectId) {
+            url += `&id=${objectId}`;
+        }
+        const response = await fetch(url);
+        if (!response.ok) {
+            throw new Error(`HTTP ${response.status}`);
+        }
+        return response.json();
+    },
+
+    // Fetch the dominator chain from the top level down to an object
+    async getDomTreeChain(taskId, objectId) {
+        const response = await fetch(`/api/domtree/chain?task=${taskId}&id=${objectId}`);
+        if (!response.ok) {
+            throw new Error(`HTTP ${response.status}`);
+        }
+        return response.json();
+    },
+
+    // Fetch the retained-size treemap of the heap (objectId empty) or of a dominator subtree
+    async getHeapTreemap(taskId, objectId = '', depth = 4) {
+        const params = new URLSearchParams({ task: taskId, depth });
+        if (objectId) params.set('id', objectId);
+        const response = await fetch(`/api/domtree/treemap?${params}`);
+        if (!response.ok) {
+            throw new Error((await response.text()).trim() || `HTTP ${response.status}`);
+        }
+        return response.json();
+    },
+
+    // Fetch the class histogram of the objects retained by an object
+    async getSubtreeHistogram(taskId, objectId, top = 50) {
+        const params = new URLSearchParams({ task: taskId, id: objectId, top });
+        const response = await fetch(`/api/domtree/histogram?${params}`);
+        if (!response.ok) {
+            throw new Error((await response.text()).trim() || `HTTP ${response.status}`);
+        }
+        return response.json();
+    },
+
+    // Fetch the retained set summary of a selection of objects
+    async getRetainedSet(taskId, objectIds) {
+        const params = new URLSearchParams({ task: taskId, ids: objectIds.join(',') });
+        const response = await fetch(`/api/domtree/retained-set?${params}`);
+        if (!response.ok) {
+            throw new Error((await response.text()).trim() || `HTTP ${response.status}`);
+        }
+        return response.json();
+    },
+
+    // Check whether each object of toIds is reachable from each object of fromIds
+    async getReachability(taskId, fromIds, toIds) {
+        const params = new URLSearchParams({ task: taskId, from: fromIds.join(','), to: toIds.join(',') });
+        const response = await fetch(`/api/refgraph/reachability?${params}`);
+        if (!response.ok) {
+            throw new Error((await response.text()).trim() || `HTTP ${response.status}`);
+        }
+        return response.json();
+    },
+
+    // Run an OQL-style query against the heap snapshot
+    async runQuery(taskId, query, limit = 1000) {
+        const params = new URLSearchParams({ task: taskId, q: query, limit });
+        const response = await fetch(`/api/query?${params}`);
+        if (!response.ok) {
+            throw new Error((await response.text()).trim() || `HTTP ${response.status}`);
+        }
+        return response.json();
+    },
+
+    // Fetch the retained set of all objects matched by a query
+    async getQueryRetainedSet(taskId, query) {
+        const params = new URLSearchParams({ task: taskId, q: query });
+        const response = await fetch(`/api/query/retained-set?${params}`);
+        if (!response.ok) {
+            throw new Error((await response.text()).trim() || `HTTP ${response.status}`);
+        }
+        return response.json();
     }
 };
 
@@ -0,0 +1,258 @@
+/**
+ * Flame Annotations Module
+ * 火焰图帧搜索与注释模块
+ *
+ * 职责：
+ * - 服务端正则搜索（/api/flamegraph/search）：列出匹配帧的调用路径及累计权重
+ * - 帧注释（/api/annotations）：为帧添加备注、置顶、编辑和删除，随任务持久化
+ * - 将注释同步给 FlameGraph，在火焰图中描边标记并在悬停提示中显示
+ */
+
+const FlameAnnotations = (function() {
+    'use strict';
+
+    // ============================================
+    // 私有状态
+    // ============================================
+
+    let currentTaskId = '';
+    let currentType = '';
+    let annotations = [];
+    let searchResult = null;
+    let isSearching = false;
+
+    // ============================================
+    // 私有方法
+    // ============================================
+
+    function setHtml(id, html) {
+        const el = document.getElementById(id);
+        if (el) el.innerHTML = html;
+    }
+
+    function setStatus(message) {
+        setHtml('flameAnnotationStatus', message ? Utils.escapeHtml(message) : '');
+    }
+
+    /**
+     * 调用路径的显示文本：只保留最后几帧，如 … › parse › json.Decode
+     */
+    function formatPath(path, maxFrames = 4) {
+        const frames = path.length > maxFrames ? ['…', ...path.slice(-maxFrames)] : path;
+        return frames.map(f => Utils.escapeHtml(f)).join(' <span class="text-muted">›</span> ');
+    }
+
+    function formatPercent(value, total) {
+        return total > 0 ? `${(value * 100 / total).toFixed(2)}%` : '-';
+    }
+
+    /**
+     * 渲染服务端搜索结果
+     */
+    function renderSearchResults() {
+        if (!searchResult) {
+            setHtml('flameSearchResults', '');
+            return;
+        }
+        const r = searchResult;
+        const header = `
+            <div class="flex flex-wrap items-center gap-3 mb-2 text-sm text-secondary">
+                <span class="font-medium">🔎 /${Utils.escapeHtml(r.pattern.replace(/^\(\?i\)/, ''))}/</span>
+                <span>${Utils.formatNumber(r.match_count)} matching frames</span>
+                <span>Cumulative weight: <strong>${Utils.formatNumber(r.matched_value)}</strong> (${(r.matched_percent || 0).toFixed(2)}%)</span>
+                <button onclick="FlameAnnotations.clearSearch()" class="px-2 py-0.5 bg-elevated text-base rounded text-xs border border-theme hover:bg-muted">✕</button>
+            </div>`;
+        if (!r.matches.length) {
+            setHtml('flameSearchResults', header + '<div class="text-sm text-muted">No frame matches the pattern</div>');
+            return;
+        }
+        const rows = r.matches.map((m, i) => `
+            <tr>
+                <td class="font-mono break-all" title="${Utils.escapeHtml(m.path.join(' › '))}">${formatPath(m.path)}</td>
+                <td class="text-right whitespace-nowrap">${Utils.formatNumber(m.value)}</td>
+                <td class="text-right whitespace-nowrap">${formatPercent(m.value, r.total_value)}</td>
+                <td class="text-right whitespace-nowrap">
+                    <button onclick="FlameAnnotations.annotateMatch(${i})" class="px-2 py-0.5 bg-elevated text-base rounded text-xs border border-theme hover:bg-muted" title="Attach a note to this frame">📝 Note</button>
+                </td>
+            </tr>`).join('');
+        setHtml('flameSearchResults', header + `
+            <div class="overflow-x-auto max-h-72 overflow-y-auto">
+                <table class="w-full flame-annotation-table">
+                    <tbody>${rows}</tbody>
+                </table>
+            </div>`);
+    }
+
+    /**
+     * 渲染注释列表（置顶在前）
+     */
+    function renderAnnotations() {
+        const count = document.getElementById('flameAnnotationCount');
+        if (count) count.textContent = annotations.length;
+        if (!annotations.length) {
+            setHtml('flameAnnotationList', '<div class="text-sm text-muted">No notes yet. Click a frame and use “Annotate Clicked Frame”, or add notes from the path search results.</div>');
+            return;
+        }
+        setHtml('flameAnnotationList', annotations.map(a => `
+            <div class="flame-annotation ${a.pinned ? 'pinned' : ''}">
+                <div class="flex items-start justify-between gap-2">
+                    <button onclick="FlameAnnotations.focus(${a.id})" class="font-mono text-xs text-left break-all hover:text-primary" title="${Utils.escapeHtml(a.path.join(' › '))}">
+                        ${formatPath(a.path, 3)}
+                    </button>
+                    <div class="flex gap-1 flex-shrink-0">
+                        ${a.type ? `<span class="text-xs text-muted">${Utils.escapeHtml(a.type)}</span>` : ''}
+                        <button onclick="FlameAnnotations.togglePin(${a.id})" class="text-xs" title="${a.pinned ? 'Unpin' : 'Pin'}">${a.pinned ? '📌' : '📍'}</button>
+                        <button onclick="FlameAnnotations.edit(${a.id})" class="text-xs" title="Edit note">✏️</button>
+                        <button onclick="FlameAnnotations.remove(${a.id})" class="text-xs" title="Remove note">🗑️</button>
+                    </div>
+                </div>
+                <div class="text-sm mt-1 whitespace-pre-wrap">${Utils.escapeHtml(a.note)}</div>
+            </div>
+        `).join(''));
+    }
+
+    async function reload() {
+        try {
+            annotations = (await API.getAnnotations(currentTaskId)) || [];
+        } catch (error) {
+            console.error('[FlameAnnotations] Failed to load annotations:', error);
+            annotations = [];
+            setStatus(`Failed to load notes: ${error.message}`);
+        }
+        renderAnnotations();
+        FlameGraph.setAnnotations(annotations);
+    }
+
+    async function save(annotation) {
+        try {
+            await API.saveAnnotation(currentTaskId, annotation);
+            setStatus('');
+        } catch (error) {
+            console.error('[FlameAnnotations] Failed to save annotation:', error);
+            setStatus(`Failed to save note: ${error.message}`);
+        }
+        await reload();
+    }
+
+    async function annotatePath(path) {
+        const note = prompt(`Note for ${path[path.length - 1]}:`);
+        if (note === null || !note.trim()) return;
+        await save({ type: currentType, path, note });
+    }
+
+    function findAnnotation(id) {
+        return annotations.find(a => a.id === id);
+    }
+
+    // ============================================
+    // 公共方法
+    // ============================================
+
+    /**
+     * 火焰图加载后调用：加载任务的注释
+     */
+    async function load(taskId, type) {
+        currentTaskId = taskId;
+        currentType = type || 'cpu';
+        searchResult = null;
+        renderSearchResults();
+        setStatus('');
+        await reload();
+    }
+
+    /**
+     * 以搜索框内容为正则在服务端搜索帧，列出匹配路径
+     */
+    async function searchPaths() {
+        const pattern = document.getElementById('searchInput')?.value.trim();
+        if (!pattern || !currentTaskId || isSearching) return;
+        isSearching = true;
+        setHtml('flameSearchResults', '<div class="text-sm text-muted">Searching…</div>');
+        try {
+            searchResult = await API.searchFlameGraph(currentTaskId, currentType, pattern);
+            renderSearchResults();
+        } catch (error) {
+            console.error('[FlameAnnotations] Search failed:', error);
+            searchResult = null;
+            setHtml('flameSearchResults', `<div class="text-sm text-danger">⚠️ ${Utils.escapeHtml(error.message)}</div>`);
+        } finally {
+            isSearching = false;
+        }
+    }
+
+    function clearSearch() {
+        searchResult = null;
+        renderSearchResults();
+    }
+
+    /**
+     * 为搜索结果中的帧添加备注
+     */
+    async function annotateMatch(index) {
+        if (!searchResult || !searchResult.matches[index]) return;
+        await annotatePath(searchResult.matches[index].path);
+    }
+
+    /**
+     * 为最近点击的帧添加备注
+     */
+    async function annotateSelected() {
+        const path = FlameGraph.getSelectedFramePath();
+        if (!path) {
+            setStatus('Click a frame in the flame graph first');
+            return;
+        }
+        await annotatePath(path);
+    }
+
+    async function edit(id) {
+        const a = findAnnotation(id);
+        if (!a) return;
+        const note = prompt(`Note for ${a.path[a.path.length - 1]}:`, a.note);
+        if (note === null || !note.trim()) return;
+        await save({ ...a, note });
+    }
+
+    async function togglePin(id) {
+        const a = findAnnotation(id);
+        if (!a) return;
+        await save({ ...a, pinned: !a.pinned });
+    }
+
+    async function remove(id) {
+        const a = findAnnotation(id);
+        if (!a || !confirm(`Remove the note on ${a.path[a.path.length - 1]}?`)) return;
+        try {
+            await API.deleteAnnotation(currentTaskId, id);
+            setStatus('');
+        } catch (error) {
+            console.error('[FlameAnnotations] Failed to remove annotation:', error);
+            setStatus(`Failed to remove note: ${error.message}`);
+        }
+        await reload();
+    }
+
+    /**
+     * 在火焰图中高亮注释的帧
+     */
+    function focus(id) {
+        const a = findAnnotation(id);
+        if (a) FlameGraph.searchFor(a.path[a.path.length - 1]);
+    }
+
+    // ============================================
+    // 导出公共接口
+    // ============================================
+
+    return {
+        load,
+        searchPaths,
+        clearSearch,
+        annotateMatch,
+        annotateSelected,
+        edit,
+        togglePin,
+        remove,
+        focus
+    };
+})();
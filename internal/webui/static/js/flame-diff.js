@@ -0,0 +1,308 @@
+/**
+ * Flame Diff Module
+ * 差分火焰图模块：比较两个任务的火焰图
+ *
+ * 职责：
+ * - 选择基线任务（base）和目标任务（target，默认当前任务）
+ * - 从 /api/flamegraph/diff 加载差分火焰图，按占比变化着色：红色为回退，蓝色为改进
+ * - 展示变化最大的函数，表格可按列排序
+ */
+
+const FlameDiff = (function() {
+    'use strict';
+
+    // ============================================
+    // 私有状态
+    // ============================================
+
+    let baseTaskId = '';
+    let targetTaskId = '';
+    let graphType = 'cpu';
+    let diffData = null;
+    let maxDelta = 0;               // 着色用：帧占比变化的最大绝对值
+    let sortKey = 'delta_self_percent';
+    let sortDesc = true;
+    let isLoading = false;
+
+    const TYPE_LABELS = {
+        'cpu': 'CPU',
+        'memory': 'Allocation',
+        'tracing': 'Wall clock',
+        'pprof-heap-inuse': 'Heap in use',
+        'pprof-heap-alloc': 'Heap allocations',
+        'pprof-goroutine': 'Goroutines',
+        'pprof-block': 'Block',
+        'pprof-mutex': 'Mutex'
+    };
+
+    // 表格列：key 为 FunctionDiff 字段
+    const COLUMNS = [
+        { key: 'name', label: 'Function', align: 'left' },
+        { key: 'base_self_percent', label: 'Self Before' },
+        { key: 'self_percent', label: 'Self After' },
+        { key: 'delta_self_percent', label: 'Δ Self' },
+        { key: 'base_total_percent', label: 'Total Before' },
+        { key: 'total_percent', label: 'Total After' },
+        { key: 'delta_total_percent', label: 'Δ Total' }
+    ];
+
+    // ============================================
+    // 私有方法
+    // ============================================
+
+    function setHtml(id, html) {
+        const el = document.getElementById(id);
+        if (el) el.innerHTML = html;
+    }
+
+    function formatPercent(value) {
+        return `${(value || 0).toFixed(2)}%`;
+    }
+
+    /**
+     * 带符号的百分点，如 +1.25 pp / -0.40 pp
+     */
+    function formatDelta(value) {
+        if (!value) return '0.00 pp';
+        return `${value > 0 ? '+' : ''}${value.toFixed(2)} pp`;
+    }
+
+    /**
+     * 帧颜色：回退为红色，改进为蓝色，变化越大颜色越深
+     */
+    function deltaColor(delta) {
+        const ratio = maxDelta > 0 ? Math.min(Math.abs(delta) / maxDelta, 1) : 0;
+        const hue = delta >= 0 ? 0 : 220;
+        return `hsl(${hue}, 80%, ${Math.round(95 - ratio * 40)}%)`;
+    }
+
+    function computeMaxDelta(node) {
+        let result = Math.abs(node.delta || 0);
+        (node.children || []).forEach(child => {
+            result = Math.max(result, computeMaxDelta(child));
+        });
+        return result;
+    }
+
+    /**
+     * 填充任务下拉框
+     */
+    async function populateTaskSelects() {
+        const baseSelect = document.getElementById('flameDiffBase');
+        const targetSelect = document.getElementById('flameDiffTarget');
+        const typeSelect = document.getElementById('flameDiffType');
+        if (!baseSelect || !targetSelect) return;
+
+        if (typeSelect) {
+            typeSelect.innerHTML = Object.entries(TYPE_LABELS)
+                .map(([type, label]) => `<option value="${type}">${label}</option>`)
+                .join('');
+            typeSelect.value = graphType;
+        }
+
+        try {
+            const tasks = (await API.getTasks()) || [];
+            const options = tasks
+                .filter(task => task.has_data)
+                .map(task => `<option value="${Utils.escapeHtml(task.id)}">${Utils.escapeHtml(task.id)}</option>`)
+                .join('');
+            baseSelect.innerHTML = '<option value="">Select base task…</option>' + options;
+            targetSelect.innerHTML = options;
+            baseSelect.value = baseTaskId;
+            targetSelect.value = targetTaskId;
+        } catch (error) {
+            console.error('[FlameDiff] Failed to load tasks:', error);
+            showMessage(`⚠️ Failed to load tasks: ${Utils.escapeHtml(error.message)}`);
+        }
+    }
+
+    function showMessage(html) {
+        setHtml('flameDiffGraph', `<div class="loading">${html}</div>`);
+        setHtml('flameDiffTableBody', '');
+    }
+
+    /**
+     * 渲染汇总：两个任务的样本数
+     */
+    function renderSummary() {
+        if (!diffData) {
+            setHtml('flameDiffSummary', '');
+            return;
+        }
+        setHtml('flameDiffSummary', `
+            <span><span class="font-medium">Base:</span> ${Utils.formatNumber(diffData.base_total_samples || 0)} samples</span>
+            <span><span class="font-medium">Target:</span> ${Utils.formatNumber(diffData.total_samples || 0)} samples</span>
+            <span class="text-muted">Frames compare shares of all samples, in percentage points (pp)</span>
+        `);
+    }
+
+    /**
+     * 帧的悬停提示：前后样本数、占比及变化
+     */
+    function frameLabel(d) {
+        const data = d.data;
+        const baseTotal = diffData.base_total_samples || 0;
+        const total = diffData.total_samples || 0;
+        const basePct = baseTotal > 0 ? (data.base_value || 0) * 100 / baseTotal : 0;
+        const pct = total > 0 ? (data.value || 0) * 100 / total : 0;
+        return `${data.name}\n` +
+            `Before: ${Utils.formatNumber(data.base_value || 0)} (${formatPercent(basePct)})\n` +
+            `After: ${Utils.formatNumber(data.value || 0)} (${formatPercent(pct)})\n` +
+            `Change: ${formatDelta(data.delta)}`;
+    }
+
+    /**
+     * 使用 d3-flamegraph 渲染差分火焰图（宽度为目标任务的样本数）
+     */
+    function renderGraph() {
+        const container = document.getElementById('flameDiffGraph');
+        if (!container || !diffData) return;
+        container.innerHTML = '';
+        if (!diffData.root || !diffData.root.value) {
+            container.innerHTML = '<div class="loading">The target task has no samples</div>';
+            return;
+        }
+        maxDelta = computeMaxDelta(diffData.root);
+
+        const flame = flamegraph()
+            .width(container.clientWidth || 1200)
+            .cellHeight(18)
+            .transitionDuration(300)
+            .minFrameSize(1)
+            .sort(true)
+            .title('')
+            .inverted(true)
+            .selfValue(false)
+            .label(frameLabel)
+            .setColorMapper(d => deltaColor(d.data.delta || 0));
+        d3.select(container).datum(diffData.root).call(flame);
+    }
+
+    /**
+     * 渲染变化最大的函数表格
+     */
+    function renderTable() {
+        if (!diffData) return;
+        setHtml('flameDiffTableHead', `<tr class="bg-muted">${COLUMNS.map(c => `
+            <th onclick="FlameDiff.sortBy('${c.key}')"
+                class="flame-diff-sortable px-4 py-3 text-xs font-semibold text-muted uppercase tracking-wider ${c.align === 'left' ? 'text-left' : 'text-right'}">
+                ${c.label}${c.key === sortKey ? (sortDesc ? ' ▼' : ' ▲') : ''}
+            </th>`).join('')}</tr>`);
+
+        const funcs = (diffData.functions || []).slice().sort((a, b) => {
+            const va = a[sortKey];
+            const vb = b[sortKey];
+            const cmp = typeof va === 'string' ? va.localeCompare(vb) : (va || 0) - (vb || 0);
+            return sortDesc ? -cmp : cmp;
+        });
+        if (funcs.length === 0) {
+            setHtml('flameDiffTableBody', `<tr><td colspan="${COLUMNS.length}" class="text-center py-10 text-muted">No function changed</td></tr>`);
+            return;
+        }
+
+        const deltaCell = value => `
+            <td class="text-right font-mono ${value > 0 ? 'flame-diff-regression' : value < 0 ? 'flame-diff-improvement' : ''}">${formatDelta(value)}</td>`;
+        setHtml('flameDiffTableBody', funcs.map(f => `
+            <tr>
+                <td class="font-mono break-all" title="${Utils.escapeHtml(f.name)}">${Utils.escapeHtml(f.name)}</td>
+                <td class="text-right">${formatPercent(f.base_self_percent)}</td>
+                <td class="text-right">${formatPercent(f.self_percent)}</td>
+                ${deltaCell(f.delta_self_percent)}
+                <td class="text-right">${formatPercent(f.base_total_percent)}</td>
+                <td class="text-right">${formatPercent(f.total_percent)}</td>
+                ${deltaCell(f.delta_total_percent)}
+            </tr>
+        `).join(''));
+    }
+
+    // ============================================
+    // 公共方法
+    // ============================================
+
+    /**
+     * 面板打开时调用：目标任务默认为当前任务
+     * @param {string} taskId - 当前任务 ID
+     * @param {string} type - 火焰图类型，如 cpu、memory、pprof-goroutine
+     */
+    function load(taskId, type) {
+        if (!targetTaskId) targetTaskId = taskId || '';
+        if (type && !diffData) graphType = type;
+        populateTaskSelects();
+        if (!diffData) {
+            renderSummary();
+            showMessage('Select a base task to compare against');
+        }
+    }
+
+    /**
+     * 使用下拉框中选择的任务进行对比
+     */
+    async function compare() {
+        if (isLoading) return;
+
+        baseTaskId = document.getElementById('flameDiffBase')?.value || '';
+        targetTaskId = document.getElementById('flameDiffTarget')?.value || targetTaskId;
+        graphType = document.getElementById('flameDiffType')?.value || graphType;
+        if (!baseTaskId || !targetTaskId) {
+            showMessage('Select both a base and a target task');
+            return;
+        }
+        if (baseTaskId === targetTaskId) {
+            showMessage('Base and target are the same task');
+            return;
+        }
+
+        isLoading = true;
+        diffData = null;
+        renderSummary();
+        showMessage('Loading flame graphs');
+        try {
+            diffData = await API.getFlameGraphDiff(baseTaskId, targetTaskId, graphType);
+            renderSummary();
+            renderGraph();
+            renderTable();
+        } catch (error) {
+            console.error('[FlameDiff] Failed to compare flame graphs:', error);
+            showMessage(`⚠️ Failed to compare flame graphs: ${Utils.escapeHtml(error.message)}`);
+        } finally {
+            isLoading = false;
+        }
+    }
+
+    /**
+     * 交换基线和目标任务
+     */
+    function swap() {
+        const baseSelect = document.getElementById('flameDiffBase');
+        const targetSelect = document.getElementById('flameDiffTarget');
+        if (!baseSelect || !targetSelect) return;
+        const base = baseSelect.value;
+        baseSelect.value = targetSelect.value;
+        targetSelect.value = base;
+        if (diffData) compare();
+    }
+
+    /**
+     * 按列排序，再次点击同一列切换升降序
+     */
+    function sortBy(key) {
+        if (key === sortKey) {
+            sortDesc = !sortDesc;
+        } else {
+            sortKey = key;
+            sortDesc = key !== 'name';
+        }
+        renderTable();
+    }
+
+    // ============================================
+    // 导出公共接口
+    // ============================================
+
+    return {
+        load,
+        compare,
+        swap,
+        sortBy
+    };
+})();
@@ -0,0 +1,126 @@
+/**
+ * Flame Processes Module
+ * 多进程火焰图模块
+ *
+ * 职责：
+ * - 当性能数据覆盖多个进程/容器（cgroup）时，提供进程选择器，切换到单个进程或容器的火焰图
+ * - 渲染跨进程热点函数表：每个热点函数在各进程中的占比
+ */
+
+const FlameProcesses = (function() {
+    'use strict';
+
+    // 跨进程热点函数表最多显示的进程列数
+    const MAX_PROCESS_COLUMNS = 6;
+
+    // ============================================
+    // 私有方法
+    // ============================================
+
+    function setHtml(id, html) {
+        const el = document.getElementById(id);
+        if (el) el.innerHTML = html;
+    }
+
+    function processLabel(g) {
+        return `${g.name} [${g.pid}]`;
+    }
+
+    /**
+     * 渲染进程选择器：容器（cgroup）在前，进程在后
+     */
+    function renderSelector(groups) {
+        const cgroups = groups.filter(g => g.kind === 'cgroup');
+        const processes = groups.filter(g => g.kind === 'process');
+        const option = (g, text) => `<option value="${Utils.escapeHtml(g.key)}">${Utils.escapeHtml(text)} — ${g.percentage.toFixed(1)}%</option>`;
+
+        let html = '<option value="">🌐 All processes</option>';
+        if (cgroups.length > 0) {
+            html += `<optgroup label="Containers (cgroups)">${cgroups.map(g =>
+                option(g, `${g.name} · ${g.process_count} processes`)).join('')}</optgroup>`;
+        }
+        html += `<optgroup label="Processes">${processes.map(g =>
+            option(g, `${processLabel(g)} · ${g.thread_count} threads${g.cgroup ? ' · ' + g.cgroup : ''}`)).join('')}</optgroup>`;
+        setHtml('flameProcessSelect', html);
+
+        const count = document.getElementById('flameProcessCount');
+        if (count) {
+            count.textContent = `${processes.length} processes` + (cgroups.length ? `, ${cgroups.length} cgroups` : '');
+        }
+    }
+
+    /**
+     * 渲染跨进程热点函数表：行是全局热点函数，列是样本最多的进程，
+     * 单元格是函数在该进程样本中的占比
+     */
+    function renderFunctions(groups, topFunctions) {
+        const columns = groups.filter(g => g.kind === 'process').slice(0, MAX_PROCESS_COLUMNS);
+        const rows = topFunctions.filter(f => f.processes && f.processes.length > 0).slice(0, 20);
+        if (rows.length === 0) {
+            setHtml('flameProcessFunctions', '');
+            return;
+        }
+
+        const header = columns.map(g =>
+            `<th class="text-right whitespace-nowrap" title="${Utils.escapeHtml(g.cgroup || '')}">${Utils.escapeHtml(processLabel(g))}</th>`).join('');
+        const body = rows.map(f => {
+            const byPid = new Map(f.processes.map(p => [p.pid, p]));
+            const cells = columns.map(g => {
+                const p = byPid.get(g.pid);
+                return `<td class="text-right whitespace-nowrap">${p ? p.percentage.toFixed(2) + '%' : '<span class="text-muted">-</span>'}</td>`;
+            }).join('');
+            return `
+                <tr>
+                    <td class="font-mono break-all">
+                        <a href="#" onclick="FlameGraph.searchFor(${Utils.escapeHtml(JSON.stringify(f.name))}); return false;">${Utils.escapeHtml(f.name)}</a>
+                    </td>
+                    <td class="text-right whitespace-nowrap">${f.percentage.toFixed(2)}%</td>
+                    <td class="text-right whitespace-nowrap">${f.processes.length}</td>
+                    ${cells}
+                </tr>`;
+        }).join('');
+
+        setHtml('flameProcessFunctions', `
+            <div class="flex items-center gap-2 mb-2">
+                <h3 class="text-sm font-semibold text-base">🗂️ Top Functions Across Processes</h3>
+                <span class="text-xs text-muted">share of each process's samples</span>
+            </div>
+            <div class="overflow-x-auto max-h-96 overflow-y-auto">
+                <table class="w-full flame-annotation-table">
+                    <thead>
+                        <tr>
+                            <th class="text-left">Function</th>
+                            <th class="text-right">Total</th>
+                            <th class="text-right">Processes</th>
+                            ${header}
+                        </tr>
+                    </thead>
+                    <tbody>${body}</tbody>
+                </table>
+            </div>`);
+    }
+
+    // ============================================
+    // 公共 API
+    // ============================================
+
+    /**
+     * 根据火焰图的线程分析数据显示或隐藏多进程视图
+     */
+    function render(threadAnalysis) {
+        const section = document.getElementById('flameProcessSection');
+        const groups = (threadAnalysis && threadAnalysis.process_groups) || [];
+        if (groups.length === 0) {
+            if (section) section.classList.add('hidden');
+            setHtml('flameProcessFunctions', '');
+            return;
+        }
+        if (section) section.classList.remove('hidden');
+        renderSelector(groups);
+        renderFunctions(groups, threadAnalysis.top_functions || []);
+    }
+
+    return {
+        render
+    };
+})();
@@ -18,6 +18,15 @@ const FlameGraph = (function() {
     let hasThreadData = false;       // Whether thread flame graph data is available
     let originalApiData = null;      // Original API response data
 
+    // Frame annotations state
+    let currentTaskId = '';
+    let currentType = '';
+    let frameAnnotations = new Map(); // Frame path key -> annotation
+    let selectedFramePath = null;     // Path of the last clicked frame
+
+    // Frames inlined by the JIT (async-profiler _[i] frames) merged into their caller
+    let collapseInlined = false;
+
     // System function patterns for filtering
     const SYSTEM_PATTERNS = {
         jvm: [
@@ -90,6 +99,15 @@ const FlameGraph = (function() {
             const value = d.value || 0;
             const self = d.data.self || 0;
             const module = d.data.module || '';
+            const state = d.data.state || '';
+            // Wall-clock and lock flame graphs hold nanoseconds rather than
+            // samples, heap growth flame graphs bytes
+            const unit = originalApiData ? originalApiData.unit : '';
+            const formatValue = unit === 'ns'
+                ? v => Utils.formatDuration(Math.round(v / 1e6))
+                : unit === 'bytes'
+                    ? v => Utils.formatBytes(v)
+                    : v => v.toLocaleString();
             
             // Parse function name for structured display
             const parsed = Utils.parseMethodName(name);
@@ -135,14 +153,14 @@ const FlameGraph = (function() {
             html += '<div class="tippy-stats">';
             html += `<div class="tippy-stat-row">`;
             html += `<span class="tippy-stat-label">Total</span>`;
-            html += `<span class="tippy-stat-value">${value.toLocaleString()}</span>`;
+            html += `<span class="tippy-stat-value">${formatValue(value)}</span>`;
             html += `<span class="tippy-stat-pct">${totalPct.toFixed(2)}%</span>`;
             html += `</div>`;
             
             if (self > 0) {
                 html += `<div class="tippy-stat-row">`;
                 html += `<span class="tippy-stat-label">Self</span>`;
-                html += `<span class="tippy-stat-value">${self.toLocaleString()}</span>`;
+                html += `<span class="tippy-stat-value">${formatValue(self)}</span>`;
                 html += `<span class="tippy-stat-pct">${selfPct.toFixed(2)}%</span>`;
                 html += `</div>`;
                 // Progress bar
@@ -151,6 +169,17 @@ const FlameGraph = (function() {
             }
             html += '</div>';
             
+            // User note attached to the frame
+            const annotation = frameAnnotations.get(framePathKey(framePath(d)));
+            if (annotation) {
+                html += `<div class="tippy-module">${annotation.pinned ? '📌' : '📝'} ${Utils.escapeHtml(annotation.note)}</div>`;
+            }
+            
+            // Thread state of wall-clock profiles, wait reason of off-CPU ones
+            if (state) {
+                html += `<div class="tippy-module">State: ${Utils.escapeHtml(state)}</div>`;
+            }
+            
             // Module
             if (module) {
                 html += `<div class="tippy-module">${Utils.escapeHtml(module)}</div>`;
@@ -308,6 +337,7 @@ const FlameGraph = (function() {
             value: node.value || 0,
             self: node.self || 0,
             module: node.module || '',
+            state: node.state || '',
             children: []
         };
         if (node.children && Array.isArray(node.children)) {
@@ -324,6 +354,7 @@ const FlameGraph = (function() {
             value: node.value,
             self: node.self || 0,
             module: node.module || '',
+            state: node.state || '',
             children: node.children ? node.children.map(c => deepCloneFlameData(c)) : []
         };
     }
@@ -461,6 +492,26 @@ const FlameGraph = (function() {
         return node.value;
     }
 
+    // Frame names from below the root to a frame, as in annotations
+    function framePath(d) {
+        if (!d || typeof d.ancestors !== 'function') return [];
+        return d.ancestors().reverse().slice(1).map(n => n.data.name);
+    }
+
+    function framePathKey(path) {
+        return path.join('\n');
+    }
+
+    // Outline the frames with an annotation
+    function applyAnnotationHighlight() {
+        d3.select('#flamegraph').selectAll('.d3-flame-graph g').each(function(d) {
+            const annotation = frameAnnotations.get(framePathKey(framePath(d)));
+            d3.select(this)
+                .classed('annotated', !!annotation)
+                .classed('pinned', !!(annotation && annotation.pinned));
+        });
+    }
+
     // Apply search highlight
     function applySearchHighlight(term) {
         if (!term) return 0;
@@ -535,10 +586,14 @@ const FlameGraph = (function() {
         async load(taskId, type = '') {
             const container = document.getElementById('flamegraph');
             container.innerHTML = '<div class="loading">Loading flame graph</div>';
+            currentTaskId = taskId;
+            currentType = type;
+            selectedFramePath = null;
 
             try {
-                const data = await API.getFlameGraph(taskId, type);
+                const data = await API.getFlameGraph(taskId, type, collapseInlined);
                 originalApiData = data;
+                this.updateFrameTypes(data.thread_analysis && data.thread_analysis.frame_types);
                 flameGraphData = transformFlameData(data);
                 originalFlameGraphData = deepCloneFlameData(flameGraphData);
 
@@ -564,6 +619,12 @@ const FlameGraph = (function() {
                 }
 
                 this.render();
+                if (typeof FlameAnnotations !== 'undefined') {
+                    FlameAnnotations.load(taskId, type);
+                }
+                if (typeof FlameProcesses !== 'undefined') {
+                    FlameProcesses.render(data.thread_analysis);
+                }
             } catch (err) {
                 console.error('Failed to load flame graph:', err);
                 container.innerHTML = '<div class="loading">Failed to load flame graph: ' + err.message + '</div>';
@@ -619,6 +680,13 @@ const FlameGraph = (function() {
                 const style = getComputedStyle(document.documentElement);
                 const isDarkMode = document.documentElement.getAttribute('data-theme') === 'dark';
                 
+                // Frames of wall-clock and off-CPU profiles are colored by
+                // thread state or wait reason
+                const stateColor = getStateColor(d.data.state, style);
+                if (stateColor) {
+                    return stateColor;
+                }
+                
                 // Check special types and return type-specific colors
                 const typeColor = getTypeSpecificColor(name, style);
                 if (typeColor) {
@@ -647,6 +715,33 @@ const FlameGraph = (function() {
                 }
             });
             
+            // Helper function to get thread state and wait reason colors
+            function getStateColor(state, style) {
+                const defaults = {
+                    // Thread states of wall-clock profiles
+                    running: '76 175 80',
+                    sleeping: '158 158 158',
+                    waiting: '33 150 243',
+                    blocked: '244 67 54',
+                    io: '255 152 0',
+                    // Wait reasons of off-CPU profiles
+                    lock: '244 67 54',
+                    sleep: '158 158 158',
+                    poll: '33 150 243',
+                    network: '0 188 212',
+                    disk: '255 152 0',
+                    pipe: '121 134 203',
+                    child: '141 110 99',
+                    page_fault: '156 39 176',
+                    preempted: '255 193 7'
+                };
+                if (!state || !defaults[state]) {
+                    return null;
+                }
+                const rgb = style.getPropertyValue(`--color-flame-state-${state}`).trim() || defaults[state];
+                return `rgb(${rgb.split(' ').join(', ')})`;
+            }
+            
             // Helper function to get type-specific colors
             function getTypeSpecificColor(name, style) {
                 // JVM/Java system functions
@@ -757,12 +852,15 @@ const FlameGraph = (function() {
                 return;
             }
 
-            // Re-apply search after zoom
+            applyAnnotationHighlight();
+
+            // Re-apply search and annotation outlines after zoom
             d3.select('#flamegraph').on('click', () => {
                 container.classList.add('zooming');
                 setTimeout(() => {
                     container.classList.remove('zooming');
                     this.reapplySearch();
+                    applyAnnotationHighlight();
                 }, 420);
             });
 
@@ -782,6 +880,62 @@ const FlameGraph = (function() {
         handleClick(d) {
             const container = document.getElementById('flamegraph');
             container.classList.add('zooming');
+            selectedFramePath = framePath(d);
+        },
+
+        // Frame annotations: set by FlameAnnotations, outlined in the graph
+        setAnnotations(annotations) {
+            frameAnnotations = new Map();
+            (annotations || []).forEach(a => frameAnnotations.set(framePathKey(a.path), a));
+            applyAnnotationHighlight();
+        },
+
+        // Path of the last clicked frame, null if none
+        getSelectedFramePath() {
+            return selectedFramePath && selectedFramePath.length ? selectedFramePath : null;
+        },
+
+        // Task and flame graph type currently shown
+        getContext() {
+            return { taskId: currentTaskId, type: currentType };
+        },
+
+        // Show how the sampled code ran, JIT-compiled, interpreted or
+        // native, and the inlined frames toggle, for annotated stacks
+        updateFrameTypes(frameTypes) {
+            const section = document.getElementById('flameFrameTypes');
+            if (!section) return;
+            if (!frameTypes || frameTypes.length === 0) {
+                section.classList.add('hidden');
+                return;
+            }
+            section.classList.remove('hidden');
+            document.getElementById('flame-frame-types').textContent = frameTypes
+                .map(ft => `${ft.type} ${ft.percentage.toFixed(1)}%`)
+                .join(' · ');
+            document.getElementById('collapseInlinedToggle').checked = collapseInlined;
+        },
+
+        // Collapse or expand the frames inlined by the JIT, and reload
+        setCollapseInlined(collapse) {
+            collapseInlined = collapse;
+            if (currentTaskId) {
+                this.load(currentTaskId, currentType);
+            }
+        },
+
+        // Download the flame graph shown in another format: speedscope, or
+        // trace for Chrome trace events read by Perfetto
+        exportAs(format) {
+            if (!currentTaskId) return;
+            const params = new URLSearchParams({ task: currentTaskId, type: currentType || 'cpu' });
+            if (collapseInlined) {
+                params.set('collapse_inlined', 'true');
+            }
+            const link = document.createElement('a');
+            link.href = `/api/flamegraph/${format}?${params}`;
+            link.download = '';
+            link.click();
         },
 
         search() {
@@ -1039,6 +1193,10 @@ const FlameGraph = (function() {
 
         selectThread(tid) {
             const selectedText = document.getElementById('flameThreadSelectedText');
+            const processSelect = document.getElementById('flameProcessSelect');
+            if (processSelect) {
+                processSelect.value = '';
+            }
 
             if (tid === '' || tid === null || tid === undefined) {
                 // Switch to global view
@@ -1082,6 +1240,38 @@ const FlameGraph = (function() {
             }
         },
 
+        // Render the flame graph of a process or cgroup of a multi-process
+        // profile, or of all processes if key is empty
+        selectProcess(key) {
+            const groups = (originalApiData && originalApiData.thread_analysis &&
+                originalApiData.thread_analysis.process_groups) || [];
+            const group = groups.find(g => g.key === key);
+            const source = group && group.flame_root ? { root: group.flame_root } : originalApiData;
+            if (!source) {
+                return;
+            }
+
+            // Process and thread views are exclusive
+            if (selectedThreadTid !== null) {
+                selectedThreadTid = null;
+                const selectedText = document.getElementById('flameThreadSelectedText');
+                if (selectedText) {
+                    selectedText.innerHTML = '<span class="global-icon">🌐</span> Global View (All Threads)';
+                }
+            }
+            const select = document.getElementById('flameProcessSelect');
+            if (select) {
+                select.value = group ? key : '';
+            }
+
+            flameGraphData = transformFlameData(source);
+            originalFlameGraphData = deepCloneFlameData(flameGraphData);
+            flameFilters.clear();
+            this.clearFiltersUI();
+            this.clearSearch();
+            this.render();
+        },
+
         clearFiltersUI() {
             const chips = document.querySelectorAll('#flameFilterSection .filter-chip');
             chips.forEach(chip => chip.classList.remove('active'));
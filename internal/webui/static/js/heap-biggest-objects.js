@@ -18,6 +18,7 @@ const HeapBiggestObjects = (function() {
     // 私有状态
     // ============================================
     
+    let currentTaskId = null;
     let biggestObjects = [];
     let filteredObjects = [];
     let currentSort = { field: 'retained', asc: false };
@@ -121,7 +122,7 @@ const HeapBiggestObjects = (function() {
         const nodeId = node.object_id || node.ref_id;
         const nodeKey = parentId ? `${parentId}:${nodeId}` : nodeId;
         const state = treeState.get(nodeKey) || { expanded: false, children: [], loaded: false };
-        const hasChildren = node.has_children || (node.fields && node.fields.length > 0);
+        const hasChildren = node.has_children;
         const isExpanded = state.expanded;
         
         const indent = depth * 16;
@@ -135,6 +136,9 @@ const HeapBiggestObjects = (function() {
             displayName = `<span class="text-blue-600 font-medium">${escapeHtml(node.name)}</span>`;
             if (node.ref_class) {
                 displayName += ` = ${formatClassName(node.ref_class)}`;
+                if (node.string_value !== undefined && node.string_value !== null) {
+                    displayName += ` <span class="text-green-600">"${escapeHtml(node.string_value)}"</span>`;
+                }
             } else if (node.value !== undefined && node.value !== null) {
                 displayName += ` = <span class="text-purple-600">${escapeHtml(String(node.value))}</span>`;
             }
@@ -178,7 +182,7 @@ const HeapBiggestObjects = (function() {
     function renderTopLevelObject(obj, index) {
         const nodeId = obj.object_id;
         const state = treeState.get(nodeId) || { expanded: false, children: [], loaded: false };
-        const hasChildren = (obj.fields && obj.fields.length > 0) || obj.has_children;
+        const hasChildren = obj.has_children;
         const isExpanded = state.expanded;
         
         const retainedPercent = biggestObjects.length > 0 && biggestObjects[0].retained_size > 0 
@@ -197,6 +201,9 @@ const HeapBiggestObjects = (function() {
                         <span class="text-[10px] text-gray-400 flex-shrink-0">@${formatObjectId(obj.object_id).substring(0, 8)}</span>
                     </div>
                     <div class="flex-shrink-0 flex items-center gap-1">
+                        <button class="px-1.5 py-0.5 text-[10px] bg-green-50 text-green-600 rounded hover:bg-green-100 transition-colors" onclick="event.stopPropagation(); HeapInspector.show('${escapeHtml(nodeId)}')" title="Inspect field values">
+                            Inspect
+                        </button>
                         <button class="px-1.5 py-0.5 text-[10px] bg-blue-50 text-blue-600 rounded hover:bg-blue-100 transition-colors" onclick="event.stopPropagation(); HeapBiggestObjects.showGCRoots('${escapeHtml(nodeId)}')" title="GC Root Paths">
                             GC Roots
                         </button>
@@ -346,10 +353,6 @@ const HeapBiggestObjects = (function() {
                     valA = a.class_name || '';
                     valB = b.class_name || '';
                     return currentSort.asc ? valA.localeCompare(valB) : valB.localeCompare(valA);
-                case 'fields':
-                    valA = (a.fields || []).length;
-                    valB = (b.fields || []).length;
-                    break;
                 default:
                     valA = a.retained_size || 0;
                     valB = b.retained_size || 0;
@@ -382,27 +385,11 @@ const HeapBiggestObjects = (function() {
 
     /**
      * 加载对象字段（懒加载）
-     * 优先使用 ReferenceGraph API 进行深度展开，回退到静态数据
+     * 所有层级都通过 ReferenceGraph API 从堆快照展开
      */
     async function loadObjectFields(objectId) {
         try {
-            // 首先尝试使用 ReferenceGraph API（支持深度展开）
-            const refGraphResponse = await fetch(`/api/refgraph/fields?id=${encodeURIComponent(objectId)}`);
-            if (refGraphResponse.ok) {
-                const fields = await refGraphResponse.json();
-                if (Array.isArray(fields) && fields.length > 0) {
-                    console.log(`[HeapBiggestObjects] Loaded ${fields.length} fields from ReferenceGraph for ${objectId}`);
-                    return fields;
-                }
-            }
-            
-            // 回退到静态 API
-            const response = await fetch(`/api/object-fields?id=${encodeURIComponent(objectId)}`);
-            if (!response.ok) {
-                console.warn(`Failed to load fields for ${objectId}: ${response.status}`);
-                return [];
-            }
-            const fields = await response.json();
+            const fields = await API.getObjectFields(currentTaskId || '', objectId);
             return Array.isArray(fields) ? fields : [];
         } catch (error) {
             console.error(`Error loading fields for ${objectId}:`, error);
@@ -464,6 +451,7 @@ const HeapBiggestObjects = (function() {
      * 从 API 加载 Biggest Objects 数据
      */
     async function loadBiggestObjects(taskId) {
+        currentTaskId = taskId;
         const container = document.getElementById('biggestObjectsList');
         if (container) {
             container.innerHTML = `
@@ -492,13 +480,7 @@ const HeapBiggestObjects = (function() {
             // Initialize tree state for top-level objects
             treeState.clear();
             for (const obj of biggestObjects) {
-                const hasChildren = (obj.fields && obj.fields.length > 0);
-                treeState.set(obj.object_id, {
-                    expanded: false,
-                    children: obj.fields || [],
-                    loaded: hasChildren,
-                    hasChildren: hasChildren
-                });
+                treeState.set(obj.object_id, { expanded: false, children: [], loaded: false });
             }
             
             console.log('[HeapBiggestObjects] Loaded', biggestObjects.length, 'objects');
@@ -552,22 +534,9 @@ const HeapBiggestObjects = (function() {
             
             // Load children if not loaded
             if (!state.loaded) {
-                // First check if this is a top-level object with fields already loaded
-                const topObj = biggestObjects.find(o => o.object_id === objectId);
-                if (topObj && topObj.fields && topObj.fields.length > 0) {
-                    state.children = topObj.fields.map(f => ({
-                        ...f,
-                        // Use has_children from backend if available, otherwise infer from ref_id
-                        has_children: f.has_children !== undefined ? f.has_children : (f.ref_id ? true : false)
-                    }));
-                    state.loaded = true;
-                } else {
-                    // Load from API
-                    renderList(); // Show loading state
-                    const fields = await loadObjectFields(objectId);
-                    state.children = fields;
-                    state.loaded = true;
-                }
+                renderList(); // Show loading state
+                state.children = await loadObjectFields(objectId);
+                state.loaded = true;
             }
         }
 
@@ -591,13 +560,7 @@ const HeapBiggestObjects = (function() {
         // Reset tree state for filtered objects
         treeState.clear();
         for (const obj of filteredObjects) {
-            const hasChildren = (obj.fields && obj.fields.length > 0);
-            treeState.set(obj.object_id, {
-                expanded: false,
-                children: obj.fields || [],
-                loaded: hasChildren,
-                hasChildren: hasChildren
-            });
+            treeState.set(obj.object_id, { expanded: false, children: [], loaded: false });
         }
         sortObjects();
         renderList();
@@ -621,20 +584,22 @@ const HeapBiggestObjects = (function() {
     /**
      * 展开所有（只展开第一层）
      */
-    function expandAll() {
+    async function expandAll() {
+        const pending = [];
         for (const obj of filteredObjects) {
             const state = treeState.get(obj.object_id);
-            if (state) {
+            if (state && obj.has_children) {
                 state.expanded = true;
-                if (!state.loaded && obj.fields) {
-                    state.children = obj.fields.map(f => ({
-                        ...f,
-                        has_children: f.has_children !== undefined ? f.has_children : (f.ref_id ? true : false)
+                if (!state.loaded) {
+                    pending.push(loadObjectFields(obj.object_id).then(fields => {
+                        state.children = fields;
+                        state.loaded = true;
                     }));
-                    state.loaded = true;
                 }
             }
         }
+        renderList(); // Show loading state
+        await Promise.all(pending);
         renderList();
     }
 
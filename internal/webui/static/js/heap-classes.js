@@ -0,0 +1,340 @@
+/**
+ * Heap All Classes Module
+ * 全量类列表模块：服务端分页 + 虚拟滚动，浏览全部类（数万个）
+ *
+ * 职责：
+ * - 从 /api/classes 按需分页加载（排序、正则过滤、包 / 类加载器聚合都在服务端完成）
+ * - 虚拟滚动：只渲染可见区域的行，滚动时加载缺失的页
+ * - 包视图中点击包名，切换到该包下的类；类加载器视图中点击加载器，切换到它定义的类
+ */
+
+const HeapClasses = (function() {
+    'use strict';
+
+    const ROW_HEIGHT = 32;
+    const PAGE_SIZE = 200;
+    const OVERSCAN = 10;
+
+    // ============================================
+    // 私有状态
+    // ============================================
+
+    let currentTaskId = null;
+    let query = { sort: 'shallow', order: 'desc', filter: '', group: '', loader: '' };
+    let total = 0;
+    let totals = null;              // { total_classes, total_size, ... }
+    let pages = new Map();          // page index -> rows
+    let pending = new Set();        // 正在加载的页
+    let generation = 0;             // 查询变化时递增，丢弃过期响应
+    let renderScheduled = false;
+
+    // ============================================
+    // 私有方法
+    // ============================================
+
+    function getViewport() {
+        return document.getElementById('classesViewport');
+    }
+
+    /**
+     * 加载一页数据
+     */
+    async function loadPage(pageIndex) {
+        if (pages.has(pageIndex) || pending.has(pageIndex)) return;
+
+        const gen = generation;
+        pending.add(pageIndex);
+        try {
+            const page = await API.getClasses(currentTaskId, {
+                ...query,
+                offset: pageIndex * PAGE_SIZE,
+                limit: PAGE_SIZE
+            });
+            if (gen !== generation) return;
+
+            total = page.total;
+            totals = page;
+            pages.set(pageIndex, rowsOf(page) || []);
+            updateStats();
+            scheduleRender();
+        } catch (error) {
+            if (gen !== generation) return;
+            console.error('[HeapClasses] Failed to load classes:', error);
+            showMessage(`Failed to load classes: ${Utils.escapeHtml(error.message)}`);
+        } finally {
+            if (gen === generation) pending.delete(pageIndex);
+        }
+    }
+
+    /**
+     * 当前视图的行
+     */
+    function rowsOf(page) {
+        if (query.group === 'package') return page.packages;
+        if (query.group === 'loader') return page.loaders;
+        return page.classes;
+    }
+
+    /**
+     * 重置数据并从头加载
+     */
+    function reload() {
+        generation++;
+        pages.clear();
+        pending.clear();
+        total = 0;
+
+        const viewport = getViewport();
+        if (viewport) viewport.scrollTop = 0;
+        updateSortIndicators();
+        showMessage('<div class="loading-spinner"></div>');
+        loadPage(0);
+    }
+
+    function showMessage(html) {
+        const rows = document.getElementById('classesRows');
+        const spacer = document.getElementById('classesSpacer');
+        if (spacer) spacer.style.height = '0px';
+        if (rows) rows.innerHTML = `<div class="classes-message">${html}</div>`;
+    }
+
+    function scheduleRender() {
+        if (renderScheduled) return;
+        renderScheduled = true;
+        requestAnimationFrame(() => {
+            renderScheduled = false;
+            renderVisible();
+        });
+    }
+
+    /**
+     * 渲染可见区域，并加载缺失的页
+     */
+    function renderVisible() {
+        const viewport = getViewport();
+        const rowsEl = document.getElementById('classesRows');
+        const spacer = document.getElementById('classesSpacer');
+        if (!viewport || !rowsEl || !spacer) return;
+
+        if (pages.size > 0 && total === 0) {
+            showMessage(query.filter ? 'No classes match the filter' : 'No class data available');
+            return;
+        }
+        spacer.style.height = `${total * ROW_HEIGHT}px`;
+
+        const first = Math.max(0, Math.floor(viewport.scrollTop / ROW_HEIGHT) - OVERSCAN);
+        const last = Math.min(total, Math.ceil((viewport.scrollTop + viewport.clientHeight) / ROW_HEIGHT) + OVERSCAN);
+
+        const html = [];
+        for (let i = first; i < last; i++) {
+            const pageIndex = Math.floor(i / PAGE_SIZE);
+            const page = pages.get(pageIndex);
+            if (!page) {
+                loadPage(pageIndex);
+                html.push(renderPlaceholder(i));
+                continue;
+            }
+            const row = page[i % PAGE_SIZE];
+            if (!row) continue;
+            if (query.group === 'package') html.push(renderPackageRow(row, i));
+            else if (query.group === 'loader') html.push(renderLoaderRow(row, i));
+            else html.push(renderClassRow(row, i));
+        }
+        rowsEl.innerHTML = html.join('');
+    }
+
+    function rowStyle(index) {
+        return `top: ${index * ROW_HEIGHT}px; height: ${ROW_HEIGHT}px;`;
+    }
+
+    function renderPlaceholder(index) {
+        return `<div class="classes-row placeholder" style="${rowStyle(index)}"><span class="classes-index">${index + 1}</span><span>…</span></div>`;
+    }
+
+    function sizeBar(value, max) {
+        const width = max > 0 ? Math.min((value / max) * 100, 100) : 0;
+        return `<div class="size-bar-bg" style="width: ${width}%"></div>`;
+    }
+
+    function renderClassRow(cls, index) {
+        const totalSize = totals?.total_size || 0;
+        return `
+            <div class="classes-row" style="${rowStyle(index)}">
+                <span class="classes-index">${index + 1}</span>
+                <span class="classes-name" title="${Utils.escapeHtml(cls.class_name)}${cls.class_loader ? ` (${Utils.escapeHtml(cls.class_loader)})` : ''}">${Utils.escapeHtml(cls.class_name)} <button class="domtree-action" onclick="HeapInspector.showClass('${Utils.escapeHtml(cls.class_name)}')" title="Class details">ℹ️</button></span>
+                <span class="classes-num">${Utils.formatNumber(cls.instance_count || 0)}</span>
+                <span class="classes-num size-cell">${sizeBar(cls.total_size || 0, totalSize)}<span class="size-value">${Utils.formatBytes(cls.total_size || 0)}</span></span>
+                <span class="classes-num size-cell retained-cell">${sizeBar(cls.retained_size || 0, totalSize)}<span class="size-value">${cls.retained_size ? Utils.formatBytes(cls.retained_size) : '-'}</span></span>
+            </div>
+        `;
+    }
+
+    function renderPackageRow(pkg, index) {
+        const totalSize = totals?.total_size || 0;
+        return `
+            <div class="classes-row package" style="${rowStyle(index)}" onclick="HeapClasses.openPackage('${Utils.escapeHtml(pkg.package)}')">
+                <span class="classes-index">${index + 1}</span>
+                <span class="classes-name" title="${Utils.escapeHtml(pkg.package)}">📦 ${Utils.escapeHtml(pkg.package)} <span class="classes-count">(${Utils.formatNumber(pkg.class_count)} classes)</span></span>
+                <span class="classes-num">${Utils.formatNumber(pkg.instance_count || 0)}</span>
+                <span class="classes-num size-cell">${sizeBar(pkg.shallow_size || 0, totalSize)}<span class="size-value">${Utils.formatBytes(pkg.shallow_size || 0)}</span></span>
+                <span class="classes-num size-cell retained-cell">${sizeBar(pkg.retained_size || 0, totalSize)}<span class="size-value">${pkg.retained_size ? Utils.formatBytes(pkg.retained_size) : '-'}</span></span>
+            </div>
+        `;
+    }
+
+    function renderLoaderRow(loader, index) {
+        const totalSize = totals?.total_size || 0;
+        const id = loader.loader_id || 'bootstrap';
+        return `
+            <div class="classes-row package" style="${rowStyle(index)}" onclick="HeapClasses.openLoader('${Utils.escapeHtml(id)}')">
+                <span class="classes-index">${index + 1}</span>
+                <span class="classes-name" title="${Utils.escapeHtml(loader.loader_class)} ${Utils.escapeHtml(id)}">🧩 ${Utils.escapeHtml(loader.loader_class)} <span class="classes-count">${loader.loader_id ? `@${Utils.escapeHtml(loader.loader_id)} ` : ''}(${Utils.formatNumber(loader.class_count)} classes)</span></span>
+                <span class="classes-num">${Utils.formatNumber(loader.instance_count || 0)}</span>
+                <span class="classes-num size-cell">${sizeBar(loader.shallow_size || 0, totalSize)}<span class="size-value">${Utils.formatBytes(loader.shallow_size || 0)}</span></span>
+                <span class="classes-num size-cell retained-cell">${sizeBar(loader.retained_size || 0, totalSize)}<span class="size-value">${loader.retained_size ? Utils.formatBytes(loader.retained_size) : '-'}</span></span>
+            </div>
+        `;
+    }
+
+    function updateStats() {
+        const stats = document.getElementById('classesStats');
+        if (!stats || !totals) return;
+        const unit = query.group === 'package' ? 'packages' : query.group === 'loader' ? 'class loaders' : 'classes';
+        const scope = query.loader ? ` defined by ${query.loader}` : '';
+        stats.textContent = `${Utils.formatNumber(total)} ${unit}${scope} matching · ${Utils.formatNumber(totals.total_classes || 0)} classes · ${Utils.formatBytes(totals.total_size || 0)}`;
+    }
+
+    function updateSortIndicators() {
+        document.querySelectorAll('#classesHeader [data-sort]').forEach(el => {
+            const active = el.dataset.sort === query.sort;
+            el.classList.toggle('active', active);
+            const arrow = el.querySelector('.sort-arrow');
+            if (arrow) arrow.textContent = active ? (query.order === 'asc' ? '▲' : '▼') : '';
+        });
+        document.getElementById('classesGroupFlat')?.classList.toggle('active', query.group === '');
+        document.getElementById('classesGroupPackage')?.classList.toggle('active', query.group === 'package');
+        document.getElementById('classesGroupLoader')?.classList.toggle('active', query.group === 'loader');
+    }
+
+    /**
+     * 正则元字符转义
+     */
+    function escapeRegExp(text) {
+        return text.replace(/[.*+?^${}()|[\]\\]/g, '\\$&');
+    }
+
+    function getCurrentTaskId() {
+        if (typeof App !== 'undefined' && App.getCurrentTask) {
+            const taskId = App.getCurrentTask();
+            if (taskId) return taskId;
+        }
+        const urlParams = new URLSearchParams(window.location.search);
+        return urlParams.get('task') || window.currentTaskId || null;
+    }
+
+    // ============================================
+    // 公共方法
+    // ============================================
+
+    /**
+     * 初始化模块
+     */
+    function init() {
+        HeapCore.on('dataLoaded', function() {
+            currentTaskId = null;
+        });
+
+        const viewport = getViewport();
+        if (viewport) {
+            viewport.addEventListener('scroll', scheduleRender, { passive: true });
+        }
+    }
+
+    /**
+     * 加载任务的类列表（面板打开时调用）
+     */
+    function load(taskId) {
+        taskId = taskId || getCurrentTaskId();
+        if (!taskId) return;
+        if (taskId === currentTaskId) {
+            scheduleRender();
+            return;
+        }
+        currentTaskId = taskId;
+        reload();
+    }
+
+    /**
+     * 按字段排序（再次点击切换升降序）
+     */
+    function sort(field) {
+        if (query.sort === field) {
+            query.order = query.order === 'asc' ? 'desc' : 'asc';
+        } else {
+            query.sort = field;
+            query.order = field === 'name' ? 'asc' : 'desc';
+        }
+        reload();
+    }
+
+    /**
+     * 应用正则过滤
+     */
+    function filter(pattern) {
+        query.filter = (pattern || '').trim();
+        reload();
+    }
+
+    /**
+     * 切换平铺 / 包聚合 / 类加载器聚合视图，并清除类加载器范围
+     */
+    function setGroup(group) {
+        query.group = group === 'package' || group === 'loader' ? group : '';
+        query.loader = '';
+        reload();
+    }
+
+    /**
+     * 打开某个包下的类
+     */
+    function openPackage(pkg) {
+        const pattern = pkg === '(default)' ? '^[^.]+$' : `^${escapeRegExp(pkg)}\\.[^.]+$`;
+        const input = document.getElementById('classesFilter');
+        if (input) input.value = pattern;
+        query.group = '';
+        filter(pattern);
+    }
+
+    /**
+     * 打开某个类加载器定义的类
+     */
+    function openLoader(loader) {
+        query.loader = loader;
+        query.group = '';
+        reload();
+    }
+
+    // ============================================
+    // 模块注册
+    // ============================================
+
+    const module = {
+        init,
+        load,
+        sort,
+        filter,
+        setGroup,
+        openPackage,
+        openLoader
+    };
+
+    // 自动注册到核心模块
+    if (typeof HeapCore !== 'undefined') {
+        HeapCore.registerModule('classes', module);
+    }
+
+    return module;
+})();
+
+// 导出到全局
+window.HeapClasses = HeapClasses;
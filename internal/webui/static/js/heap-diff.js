@@ -0,0 +1,365 @@
+/**
+ * Heap Diff Module
+ * 堆对比模块：比较两个任务的 Class Histogram
+ *
+ * 职责：
+ * - 选择基线任务（base）和目标任务（target，默认当前任务）
+ * - 从 /api/heap/diff 加载类级别的增量（新增类、消失类、增长、缩减）
+ * - 以增长条展示大小变化，支持按状态过滤和导出 CSV
+ * - 按需从 /api/heap/diff/objects 加载对象级的保留大小变化（增量支配树）
+ */
+
+const HeapDiff = (function() {
+    'use strict';
+
+    // ============================================
+    // 私有状态
+    // ============================================
+
+    let baseTaskId = '';
+    let targetTaskId = '';
+    let diffData = null;
+    let objectDiffData = null;
+    let statusFilter = '';          // '' = 全部
+    let isLoading = false;
+
+    const STATUS_LABELS = {
+        new: 'New',
+        removed: 'Removed',
+        grown: 'Grown',
+        shrunk: 'Shrunk',
+        unchanged: 'Unchanged'
+    };
+
+    // ============================================
+    // 私有方法
+    // ============================================
+
+    /**
+     * 带符号的字节数，如 +1.2 MB / -300 B
+     */
+    function formatDeltaBytes(value) {
+        if (!value) return '0 B';
+        return (value > 0 ? '+' : '-') + Utils.formatBytes(Math.abs(value));
+    }
+
+    function formatDeltaNumber(value) {
+        if (!value) return '0';
+        return (value > 0 ? '+' : '-') + Utils.formatNumber(Math.abs(value));
+    }
+
+    /**
+     * 填充任务下拉框
+     */
+    async function populateTaskSelects() {
+        const baseSelect = document.getElementById('heapDiffBase');
+        const targetSelect = document.getElementById('heapDiffTarget');
+        if (!baseSelect || !targetSelect) return;
+
+        try {
+            const tasks = (await API.getTasks()) || [];
+            const options = tasks
+                .filter(task => task.has_data)
+                .map(task => `<option value="${Utils.escapeHtml(task.id)}">${Utils.escapeHtml(task.id)}</option>`)
+                .join('');
+            baseSelect.innerHTML = '<option value="">Select base task…</option>' + options;
+            targetSelect.innerHTML = options;
+            baseSelect.value = baseTaskId;
+            targetSelect.value = targetTaskId;
+        } catch (error) {
+            console.error('[HeapDiff] Failed to load tasks:', error);
+            HeapCore.showNotification(`Failed to load tasks: ${error.message}`, 'error');
+        }
+    }
+
+    function showMessage(html) {
+        const tbody = document.getElementById('heapDiffTableBody');
+        if (tbody) {
+            tbody.innerHTML = `<tr><td colspan="6" class="text-center py-10 text-muted">${html}</td></tr>`;
+        }
+    }
+
+    /**
+     * 渲染汇总卡片
+     */
+    function renderSummary() {
+        const container = document.getElementById('heapDiffSummary');
+        if (!container) return;
+        if (!diffData) {
+            container.innerHTML = '';
+            return;
+        }
+
+        const card = (label, value, cls = '') => `
+            <div class="heap-diff-card ${cls}">
+                <div class="heap-diff-card-label">${label}</div>
+                <div class="heap-diff-card-value">${value}</div>
+            </div>
+        `;
+        container.innerHTML = [
+            card('Heap size', `${Utils.formatBytes(diffData.base_total_size || 0)} → ${Utils.formatBytes(diffData.target_total_size || 0)}`),
+            card('Size delta', formatDeltaBytes(diffData.delta_total_size), diffData.delta_total_size > 0 ? 'grown' : 'shrunk'),
+            card('Instances delta', formatDeltaNumber(diffData.delta_total_instances)),
+            card('New classes', Utils.formatNumber(diffData.new_classes || 0), 'new'),
+            card('Removed classes', Utils.formatNumber(diffData.removed_classes || 0), 'removed'),
+            card('Grown / Shrunk', `${Utils.formatNumber(diffData.grown_classes || 0)} / ${Utils.formatNumber(diffData.shrunk_classes || 0)}`)
+        ].join('');
+    }
+
+    /**
+     * 渲染类增量表格
+     */
+    function renderTable() {
+        const tbody = document.getElementById('heapDiffTableBody');
+        if (!tbody || !diffData) return;
+
+        const classes = (diffData.classes || []).filter(d => !statusFilter || d.status === statusFilter);
+        if (classes.length === 0) {
+            showMessage(statusFilter ? 'No classes with this status' : 'The two heaps have identical class histograms');
+            return;
+        }
+
+        const maxDelta = classes.reduce((max, d) => Math.max(max, Math.abs(d.delta_size || 0)), 0);
+        tbody.innerHTML = classes.map((d, i) => {
+            const width = maxDelta > 0 ? (Math.abs(d.delta_size || 0) / maxDelta) * 100 : 0;
+            const growth = d.status === 'new' ? '—' : `${d.growth_percent > 0 ? '+' : ''}${(d.growth_percent || 0).toFixed(1)}%`;
+            return `
+                <tr>
+                    <td class="text-center text-muted">${i + 1}</td>
+                    <td class="heap-diff-class" title="${Utils.escapeHtml(d.class_name)}">
+                        <span class="heap-diff-status ${d.status}">${STATUS_LABELS[d.status] || d.status}</span>
+                        ${Utils.escapeHtml(d.class_name)}
+                    </td>
+                    <td class="text-right">${Utils.formatNumber(d.base_instances || 0)} → ${Utils.formatNumber(d.target_instances || 0)}</td>
+                    <td class="text-right">${Utils.formatBytes(d.base_size || 0)} → ${Utils.formatBytes(d.target_size || 0)}</td>
+                    <td class="heap-diff-delta ${d.delta_size >= 0 ? 'positive' : 'negative'}">
+                        <div class="heap-diff-bar" style="width: ${width}%"></div>
+                        <span class="size-value">${formatDeltaBytes(d.delta_size)}</span>
+                    </td>
+                    <td class="text-right">${growth}</td>
+                </tr>
+            `;
+        }).join('');
+    }
+
+    function showObjectsMessage(html) {
+        const tbody = document.getElementById('heapDiffObjectsBody');
+        if (tbody) {
+            tbody.innerHTML = `<tr><td colspan="5" class="text-center py-10 text-muted">${html}</td></tr>`;
+        }
+    }
+
+    /**
+     * 渲染对象级对比：支配树更新统计和保留大小变化最大的对象
+     */
+    function renderObjects() {
+        const summary = document.getElementById('heapDiffObjectsSummary');
+        const tbody = document.getElementById('heapDiffObjectsBody');
+        if (!summary || !tbody) return;
+        if (!objectDiffData) {
+            summary.textContent = '';
+            return;
+        }
+
+        const update = objectDiffData.dominators || {};
+        const dominators = update.full_recompute
+            ? 'dominators computed from scratch (too many objects changed)'
+            : `dominators: ${Utils.formatNumber(update.reused_objects || 0)} reused, ${Utils.formatNumber(update.recomputed_objects || 0)} recomputed`;
+        summary.textContent = `${Utils.formatNumber(update.matched_objects || 0)} matched, ` +
+            `${Utils.formatNumber(update.new_objects || 0)} new, ${Utils.formatNumber(update.removed_objects || 0)} removed objects · ${dominators}`;
+
+        const objects = objectDiffData.objects || [];
+        if (objects.length === 0) {
+            showObjectsMessage('No object changed its retained size');
+            return;
+        }
+        const maxDelta = objects.reduce((max, d) => Math.max(max, Math.abs(d.delta_retained || 0)), 0);
+        tbody.innerHTML = objects.map((d, i) => {
+            const width = maxDelta > 0 ? (Math.abs(d.delta_retained || 0) / maxDelta) * 100 : 0;
+            return `
+                <tr>
+                    <td class="text-center text-muted">${i + 1}</td>
+                    <td class="heap-diff-class" title="${Utils.escapeHtml(d.class_name)}">
+                        <span class="heap-diff-status ${d.status}">${STATUS_LABELS[d.status] || d.status}</span>
+                        ${Utils.escapeHtml(d.class_name)}
+                    </td>
+                    <td class="text-muted">${Utils.escapeHtml(d.object_id)}</td>
+                    <td class="text-right">${Utils.formatBytes(d.base_retained || 0)} → ${Utils.formatBytes(d.target_retained || 0)}</td>
+                    <td class="heap-diff-delta ${d.delta_retained >= 0 ? 'positive' : 'negative'}">
+                        <div class="heap-diff-bar" style="width: ${width}%"></div>
+                        <span class="size-value">${formatDeltaBytes(d.delta_retained)}</span>
+                    </td>
+                </tr>
+            `;
+        }).join('');
+    }
+
+    function updateFilterButtons() {
+        document.querySelectorAll('#heapDiffFilters [data-status]').forEach(el => {
+            el.classList.toggle('active', el.dataset.status === statusFilter);
+        });
+    }
+
+    function updateExportLink() {
+        const link = document.getElementById('heapDiffExport');
+        if (!link) return;
+        if (diffData) {
+            const params = new URLSearchParams({ base: baseTaskId, target: targetTaskId, format: 'csv' });
+            link.href = `/api/heap/diff?${params}`;
+            link.classList.remove('disabled');
+        } else {
+            link.removeAttribute('href');
+            link.classList.add('disabled');
+        }
+    }
+
+    function getCurrentTaskId() {
+        if (typeof App !== 'undefined' && App.getCurrentTask) {
+            const taskId = App.getCurrentTask();
+            if (taskId) return taskId;
+        }
+        const urlParams = new URLSearchParams(window.location.search);
+        return urlParams.get('task') || window.currentTaskId || null;
+    }
+
+    // ============================================
+    // 公共方法
+    // ============================================
+
+    /**
+     * 初始化模块
+     */
+    function init() {
+        HeapCore.on('dataLoaded', function() {
+            targetTaskId = '';
+            diffData = null;
+            objectDiffData = null;
+        });
+    }
+
+    /**
+     * 面板打开时调用：目标任务默认为当前任务
+     */
+    function load(taskId) {
+        taskId = taskId || getCurrentTaskId() || '';
+        if (!targetTaskId) targetTaskId = taskId;
+        populateTaskSelects();
+        renderSummary();
+        updateExportLink();
+        if (!diffData) {
+            showMessage('Select a base task to compare against');
+        }
+        renderObjects();
+        if (!objectDiffData) {
+            showObjectsMessage('Compare two tasks, then compare their objects');
+        }
+    }
+
+    /**
+     * 使用下拉框中选择的任务进行对比
+     */
+    async function compare() {
+        if (isLoading) return;
+
+        baseTaskId = document.getElementById('heapDiffBase')?.value || '';
+        targetTaskId = document.getElementById('heapDiffTarget')?.value || targetTaskId;
+        if (!baseTaskId || !targetTaskId) {
+            HeapCore.showNotification('Select both a base and a target task', 'warning');
+            return;
+        }
+        if (baseTaskId === targetTaskId) {
+            HeapCore.showNotification('Base and target are the same task', 'warning');
+            return;
+        }
+
+        isLoading = true;
+        diffData = null;
+        objectDiffData = null;
+        renderSummary();
+        renderObjects();
+        updateExportLink();
+        showMessage('<div class="loading-spinner"></div>');
+        showObjectsMessage('Compare objects to see the retained size changes of individual objects');
+        try {
+            diffData = await API.getHeapDiff(baseTaskId, targetTaskId);
+            renderSummary();
+            renderTable();
+        } catch (error) {
+            console.error('[HeapDiff] Failed to compare heaps:', error);
+            showMessage(`⚠️ Failed to compare heaps: ${Utils.escapeHtml(error.message)}`);
+        } finally {
+            isLoading = false;
+            updateExportLink();
+        }
+    }
+
+    /**
+     * 对象级对比：需要两个任务的引用图，计算开销较大，按需加载
+     */
+    async function compareObjects() {
+        if (isLoading) return;
+        if (!diffData) {
+            HeapCore.showNotification('Compare two tasks first', 'warning');
+            return;
+        }
+
+        isLoading = true;
+        objectDiffData = null;
+        renderObjects();
+        showObjectsMessage('<div class="loading-spinner"></div>');
+        try {
+            objectDiffData = await API.getHeapObjectDiff(baseTaskId, targetTaskId);
+            renderObjects();
+        } catch (error) {
+            console.error('[HeapDiff] Failed to compare objects:', error);
+            showObjectsMessage(`⚠️ Failed to compare objects: ${Utils.escapeHtml(error.message)}`);
+        } finally {
+            isLoading = false;
+        }
+    }
+
+    /**
+     * 交换基线和目标任务
+     */
+    function swap() {
+        const baseSelect = document.getElementById('heapDiffBase');
+        const targetSelect = document.getElementById('heapDiffTarget');
+        if (!baseSelect || !targetSelect) return;
+        const base = baseSelect.value;
+        baseSelect.value = targetSelect.value;
+        targetSelect.value = base;
+        if (diffData) compare();
+    }
+
+    /**
+     * 按状态过滤（'' = 全部）
+     */
+    function setFilter(status) {
+        statusFilter = status || '';
+        updateFilterButtons();
+        renderTable();
+    }
+
+    // ============================================
+    // 模块注册
+    // ============================================
+
+    const module = {
+        init,
+        load,
+        compare,
+        compareObjects,
+        swap,
+        setFilter
+    };
+
+    // 自动注册到核心模块
+    if (typeof HeapCore !== 'undefined') {
+        HeapCore.registerModule('diff', module);
+    }
+
+    return module;
+})();
+
+// 导出到全局
+window.HeapDiff = HeapDiff;
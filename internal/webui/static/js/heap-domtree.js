@@ -0,0 +1,330 @@
+/**
+ * Heap Dominator Tree Module
+ * 支配树浏览模块：MAT 风格的 Dominator Tree 视图
+ *
+ * 职责：
+ * - 从 /api/domtree/children 按层懒加载支配树
+ * - 按 Retained Size 降序展示，支持逐级展开
+ * - 聚焦某个对象时，用 /api/domtree/chain 渲染支配链面包屑
+ */
+
+const HeapDomTree = (function() {
+    'use strict';
+
+    const PAGE_SIZE = 100;
+
+    // ============================================
+    // 私有状态
+    // ============================================
+
+    let currentTaskId = null;
+    let focusId = '';               // 当前聚焦对象（空 = 顶层）
+    let chain = [];                 // 聚焦对象的支配链（顶层 -> 对象）
+    let childrenCache = new Map();  // objectId -> children nodes
+    let limits = new Map();         // objectId -> 已请求的子节点数量
+    let expanded = new Set();       // 展开的对象
+    let totalRetained = 0;
+    let isLoading = false;
+
+    // ============================================
+    // 私有方法
+    // ============================================
+
+    /**
+     * 对象 ID 转为 API 使用的十六进制形式
+     */
+    function formatId(objectId) {
+        return '0x' + Number(objectId).toString(16);
+    }
+
+    /**
+     * 加载某个对象（或顶层）的支配子节点
+     */
+    async function loadChildren(objectId) {
+        const key = objectId || '';
+        const limit = limits.get(key) || PAGE_SIZE;
+        const children = await API.getDomTreeChildren(currentTaskId, key, limit);
+        childrenCache.set(key, children || []);
+        limits.set(key, limit);
+        return childrenCache.get(key);
+    }
+
+    /**
+     * 聚焦到某个对象并刷新面包屑和表格
+     */
+    async function focus(objectId) {
+        if (!currentTaskId || isLoading) return;
+
+        isLoading = true;
+        try {
+            showLoadingState();
+            focusId = objectId || '';
+            expanded.clear();
+            UrlState.update({ domFocus: focusId });
+            chain = focusId ? await API.getDomTreeChain(currentTaskId, focusId) : [];
+            const roots = await loadChildren(focusId);
+            if (!focusId) {
+                totalRetained = roots.reduce((sum, n) => sum + (n.retained_size || 0), 0);
+            }
+            renderBreadcrumbs();
+            renderTable();
+        } catch (error) {
+            console.error('[HeapDomTree] Failed to load dominator tree:', error);
+            showErrorState(error.message);
+        } finally {
+            isLoading = false;
+        }
+    }
+
+    /**
+     * 显示加载状态
+     */
+    function showLoadingState() {
+        const tbody = document.getElementById('domTreeTableBody');
+        if (tbody) {
+            tbody.innerHTML = `
+                <tr>
+                    <td colspan="5" class="loading-state" style="text-align: center; padding: 40px;">
+                        <div class="loading-spinner"></div>
+                        <div style="margin-top: 10px;">Loading dominator tree...</div>
+                    </td>
+                </tr>
+            `;
+        }
+    }
+
+    /**
+     * 显示错误状态
+     */
+    function showErrorState(message) {
+        const tbody = document.getElementById('domTreeTableBody');
+        if (tbody) {
+            tbody.innerHTML = `
+                <tr>
+                    <td colspan="5" class="error-state" style="text-align: center; padding: 40px; color: #f44336;">
+                        <div class="icon">⚠️</div>
+                        <div>Failed to load dominator tree: ${Utils.escapeHtml(message)}</div>
+                        <div style="font-size: 12px; color: #808080; margin-top: 8px;">
+                            The dominator tree requires domtree.bin or the reference graph of the task
+                        </div>
+                    </td>
+                </tr>
+            `;
+        }
+    }
+
+    /**
+     * 渲染支配链面包屑
+     */
+    function renderBreadcrumbs() {
+        const container = document.getElementById('domTreeBreadcrumbs');
+        if (!container) return;
+
+        const crumbs = [`<a class="domtree-crumb" onclick="HeapDomTree.focus('')">🌐 All roots</a>`];
+        chain.forEach((node, i) => {
+            const id = formatId(node.object_id);
+            const label = `${Utils.escapeHtml(Utils.getShortClassName(node.class_name))} @ ${id}`;
+            if (i === chain.length - 1) {
+                crumbs.push(`<span class="domtree-crumb current" title="${Utils.escapeHtml(node.class_name)}">${label}</span>`);
+            } else {
+                crumbs.push(`<a class="domtree-crumb" onclick="HeapDomTree.focus('${id}')" title="${Utils.escapeHtml(node.class_name)}">${label}</a>`);
+            }
+        });
+        container.innerHTML = crumbs.join('<span class="domtree-crumb-sep">›</span>');
+    }
+
+    /**
+     * 渲染支配树表格
+     */
+    function renderTable() {
+        const tbody = document.getElementById('domTreeTableBody');
+        if (!tbody) return;
+
+        const roots = childrenCache.get(focusId) || [];
+        if (roots.length === 0) {
+            tbody.innerHTML = `
+                <tr>
+                    <td colspan="5" class="no-data-message" style="text-align: center; padding: 40px;">
+                        <div class="icon">🌲</div>
+                        <div>${focusId ? 'This object does not dominate any other object' : 'No dominator tree data available'}</div>
+                    </td>
+                </tr>
+            `;
+            return;
+        }
+        tbody.innerHTML = renderRows(focusId, roots, 0);
+    }
+
+    /**
+     * 递归渲染节点及已展开的子节点
+     */
+    function renderRows(parentKey, nodes, level) {
+        let html = nodes.map(node => {
+            const id = formatId(node.object_id);
+            const isExpanded = expanded.has(id);
+            const children = childrenCache.get(id);
+            const hasChildren = node.retained_size > node.shallow_size;
+            const percentage = totalRetained > 0 ? (node.retained_size / totalRetained) * 100 : (node.percentage || 0);
+
+            let row = `
+                <tr class="domtree-row" data-id="${id}">
+                    <td>
+                        <button class="expand-btn" onclick="HeapDomTree.toggle('${id}')" ${hasChildren ? '' : 'disabled'}>
+                            ${hasChildren ? (isExpanded ? '▼' : '▶') : '─'}
+                        </button>
+                    </td>
+                    <td style="padding-left: ${12 + level * 20}px;">
+                        <a class="domtree-class" onclick="HeapDomTree.focus('${id}')" title="${Utils.escapeHtml(node.class_name)} (focus)">
+                            ${Utils.escapeHtml(Utils.getShortClassName(node.class_name))}
+                        </a>
+                        <code class="object-id">${id}</code>
+                        <button class="domtree-action" onclick="HeapInspector.show('${id}')" title="Inspect fields">🔍</button>
+                        <button class="domtree-action" onclick="HeapRootPaths.show('${id}')" title="Paths to GC root">🧭</button>
+                    </td>
+                    <td>${Utils.formatBytes(node.shallow_size || 0)}</td>
+                    <td class="size-cell retained-cell">
+                        <div class="size-bar-bg" style="width: ${Math.min(percentage, 100)}%"></div>
+                        <span class="size-value">${Utils.formatBytes(node.retained_size || 0)}</span>
+                    </td>
+                    <td>${percentage.toFixed(2)}%</td>
+                </tr>
+            `;
+            if (isExpanded && children) {
+                row += renderRows(id, children, level + 1);
+            }
+            return row;
+        }).join('');
+
+        if (nodes.length >= (limits.get(parentKey) || PAGE_SIZE)) {
+            html += `
+                <tr class="domtree-more">
+                    <td></td>
+                    <td colspan="4" style="padding-left: ${12 + level * 20}px;">
+                        <a onclick="HeapDomTree.loadMore('${parentKey}')">Show ${PAGE_SIZE} more…</a>
+                    </td>
+                </tr>
+            `;
+        }
+        return html;
+    }
+
+    /**
+     * 获取当前 taskId
+     */
+    function getCurrentTaskId() {
+        if (typeof App !== 'undefined' && App.getCurrentTask) {
+            const taskId = App.getCurrentTask();
+            if (taskId) return taskId;
+        }
+        const urlParams = new URLSearchParams(window.location.search);
+        return urlParams.get('task') || window.currentTaskId || null;
+    }
+
+    // ============================================
+    // 公共方法
+    // ============================================
+
+    /**
+     * 初始化模块
+     */
+    function init() {
+        // 任务切换时清空缓存，下次打开面板时重新加载
+        HeapCore.on('dataLoaded', function() {
+            currentTaskId = null;
+            childrenCache.clear();
+            limits.clear();
+            expanded.clear();
+        });
+    }
+
+    /**
+     * 加载支配树（面板打开时调用），可直接聚焦到某个对象（如分享链接中的对象）
+     */
+    function load(taskId, objectId) {
+        taskId = taskId || getCurrentTaskId();
+        if (!taskId) return;
+        if (objectId) {
+            if (taskId !== currentTaskId) {
+                currentTaskId = taskId;
+                childrenCache.clear();
+                limits.clear();
+            }
+            focus(objectId);
+            return;
+        }
+        if (taskId === currentTaskId && childrenCache.has(focusId)) {
+            return;
+        }
+        currentTaskId = taskId;
+        childrenCache.clear();
+        limits.clear();
+        focus('');
+    }
+
+    /**
+     * 展开/折叠某个对象的支配子节点
+     */
+    async function toggle(objectId) {
+        if (expanded.has(objectId)) {
+            expanded.delete(objectId);
+            renderTable();
+            return;
+        }
+        try {
+            if (!childrenCache.has(objectId)) {
+                await loadChildren(objectId);
+            }
+            expanded.add(objectId);
+            renderTable();
+        } catch (error) {
+            console.error('[HeapDomTree] Failed to load children:', error);
+            HeapCore.showNotification(`Failed to load children: ${error.message}`, 'error');
+        }
+    }
+
+    /**
+     * 加载更多子节点
+     */
+    async function loadMore(objectId) {
+        const key = objectId || '';
+        limits.set(key, (limits.get(key) || PAGE_SIZE) + PAGE_SIZE);
+        try {
+            await loadChildren(key);
+            renderTable();
+        } catch (error) {
+            console.error('[HeapDomTree] Failed to load more children:', error);
+        }
+    }
+
+    /**
+     * 刷新数据
+     */
+    function refresh() {
+        const taskId = currentTaskId || getCurrentTaskId();
+        currentTaskId = null;
+        load(taskId);
+    }
+
+    // ============================================
+    // 模块注册
+    // ============================================
+
+    const module = {
+        init,
+        load,
+        focus,
+        toggle,
+        loadMore,
+        refresh
+    };
+
+    // 自动注册到核心模块
+    if (typeof HeapCore !== 'undefined') {
+        HeapCore.registerModule('domtree', module);
+    }
+
+    return module;
+})();
+
+// 导出到全局
+window.HeapDomTree = HeapDomTree;
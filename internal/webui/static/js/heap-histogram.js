@@ -395,6 +395,7 @@ const HeapHistogram = (function() {
         
         currentPage = 1;
         render(currentData);
+        UrlState.update({ classFilter: searchTerm });
     }
 
     /**
@@ -405,6 +406,7 @@ const HeapHistogram = (function() {
         if (searchInput) {
             searchInput.value = '';
         }
+        UrlState.update({ classFilter: '' });
         currentData = HeapCore.getState('classData');
         currentPage = 1;
         render(currentData);
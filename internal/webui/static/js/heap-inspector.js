@@ -0,0 +1,577 @@
+/**
+ * Heap Inspector Module
+ * 对象查看器：展示对象的字段值（含基本类型与 String 内容）和数组元素
+ *
+ * 职责：
+ * - 从 /api/refgraph/object 读取对象内容（后端按需从堆转储中解码）
+ * - 以类型徽标展示字段，大数组截断并支持加载更多
+ * - 点击引用字段跳转到被引用对象，支持返回
+ * - 从 /api/refgraph/class 读取类元数据：继承链、字段声明与静态字段值
+ * - 从 /api/refgraph/instances 按游标分页加载类的全部实例
+ * - 从 /api/domtree/histogram 按类统计对象支配的子树
+ * - 从 /api/refgraph/payload 读取基本类型数组与 String 的原始字节（十六进制 + 文本），支持下载
+ */
+
+const HeapInspector = (function() {
+    'use strict';
+
+    // ============================================
+    // 私有状态
+    // ============================================
+
+    let overlay = null;
+    let history = [];               // 已访问对象 ID 或 class: 前缀的类名，最后一个为当前项
+    let content = null;
+    let maxElements = 100;
+    let requestSeq = 0;             // 丢弃过期请求的响应
+    let instances = null;           // 类视图的实例分页：{ sort, items, total, next, loading }
+    let histogram = null;           // 对象视图的支配子树类统计：{ result, loading, error }
+    let payload = null;             // 数组与 String 的原始字节：{ result, loading, error }
+
+    const MAX_ELEMENTS_LIMIT = 10000;
+    const PREVIEW_CHARS = 120;      // 字段中 String 预览的显示长度
+    const CLASS_PREFIX = 'class:';  // 历史中类条目的前缀
+    const INSTANCES_PAGE_SIZE = 100;
+    const HISTOGRAM_TOP = 50;
+    const PAYLOAD_BYTES = 4096;     // 原始字节的显示上限
+
+    // 类型徽标分组
+    const TYPE_GROUPS = {
+        boolean: 'bool',
+        char: 'char',
+        byte: 'int', short: 'int', int: 'int', long: 'int',
+        float: 'float', double: 'float',
+        object: 'ref'
+    };
+
+    // ============================================
+    // 私有方法
+    // ============================================
+
+    function getCurrentTaskId() {
+        if (typeof App !== 'undefined' && App.getCurrentTask) {
+            const taskId = App.getCurrentTask();
+            if (taskId) return taskId;
+        }
+        const urlParams = new URLSearchParams(window.location.search);
+        return urlParams.get('task') || window.currentTaskId || null;
+    }
+
+    function ensureOverlay() {
+        if (overlay) return overlay;
+        overlay = document.createElement('div');
+        overlay.className = 'heap-inspector-overlay';
+        overlay.onclick = (e) => {
+            if (e.target === overlay) close();
+        };
+        overlay.innerHTML = `
+            <div class="heap-inspector">
+                <div class="heap-inspector-header">
+                    <button class="heap-inspector-back" onclick="HeapInspector.back()" title="Back">←</button>
+                    <div class="heap-inspector-title" id="heapInspectorTitle"></div>
+                    <button class="heap-inspector-close" onclick="HeapInspector.close()" title="Close">✕</button>
+                </div>
+                <div class="heap-inspector-body" id="heapInspectorBody"></div>
+            </div>
+        `;
+        document.body.appendChild(overlay);
+        document.addEventListener('keydown', (e) => {
+            if (e.key === 'Escape' && overlay && overlay.style.display !== 'none') close();
+        });
+        return overlay;
+    }
+
+    function typeBadge(type) {
+        const group = TYPE_GROUPS[type] || 'ref';
+        return `<span class="heap-inspector-type type-${group}">${Utils.escapeHtml(type)}</span>`;
+    }
+
+    function truncate(text, limit) {
+        return text.length > limit ? text.substring(0, limit) + '…' : text;
+    }
+
+    /**
+     * 渲染字段值：引用可点击跳转，String 显示预览
+     */
+    function renderValue(item) {
+        if (item.type !== 'object') {
+            if (item.value === undefined || item.value === null) {
+                return '<span class="text-muted">?</span>';
+            }
+            const text = item.type === 'char' ? `'${item.value}'` : String(item.value);
+            return `<span class="heap-inspector-primitive">${Utils.escapeHtml(text)}</span>`;
+        }
+        if (!item.ref_id) {
+            return '<span class="heap-inspector-null">null</span>';
+        }
+        const className = item.ref_class || '<unknown>';
+        let html = `
+            <a class="heap-inspector-ref" onclick="HeapInspector.show('${item.ref_id}')" title="${Utils.escapeHtml(className)}">
+                ${Utils.escapeHtml(Utils.getShortClassName(className))}
+                <span class="object-id">${Utils.escapeHtml(item.ref_id)}</span>
+            </a>
+        `;
+        if (item.string_value !== undefined && item.string_value !== null) {
+            html += ` <span class="heap-inspector-string" title="${Utils.escapeHtml(item.string_value)}">"${Utils.escapeHtml(truncate(item.string_value, PREVIEW_CHARS))}"</span>`;
+        }
+        if (item.retained_size) {
+            html += ` <span class="heap-inspector-size">${Utils.formatBytes(item.retained_size)}</span>`;
+        }
+        return html;
+    }
+
+    function classLink(className) {
+        if (!className) return '<span class="text-muted">&lt;unknown&gt;</span>';
+        return `<a class="heap-inspector-ref" onclick="HeapInspector.showClass('${Utils.escapeHtml(className)}')" title="${Utils.escapeHtml(className)}">${Utils.escapeHtml(className)}</a>`;
+    }
+
+    function renderRows(items) {
+        return items.map(item => `
+            <tr>
+                <td class="heap-inspector-name">${Utils.escapeHtml(item.name)}</td>
+                <td>${typeBadge(item.type)}</td>
+                <td class="heap-inspector-value">${renderValue(item)}</td>
+            </tr>
+        `).join('');
+    }
+
+    function render() {
+        const title = document.getElementById('heapInspectorTitle');
+        const body = document.getElementById('heapInspectorBody');
+        if (!title || !body || !content) return;
+
+        const backButton = overlay.querySelector('.heap-inspector-back');
+        backButton.disabled = history.length < 2;
+
+        if (content.kind === 'class') {
+            renderClass(title, body);
+            return;
+        }
+
+        const className = content.class_name || '<unknown>';
+        title.innerHTML = `
+            <a class="heap-inspector-class heap-inspector-ref" onclick="HeapInspector.showClass('${Utils.escapeHtml(className)}')" title="${Utils.escapeHtml(className)}">${Utils.escapeHtml(className)}</a>
+            <span class="object-id">${Utils.escapeHtml(content.object_id)}</span>
+            <div class="heap-inspector-meta">
+                Shallow ${Utils.formatBytes(content.shallow_size || 0)} · Retained ${Utils.formatBytes(content.retained_size || 0)}
+                ${content.kind !== 'instance' ? ` · Length ${Utils.formatNumber(content.length || 0)}` : ''}
+                · <a onclick="HeapInspector.close(); HeapRootPaths.show('${content.object_id}')">Paths to GC root</a>
+                ${content.retained_size > content.shallow_size && !histogram ? ` · <a onclick="HeapInspector.showHistogram()">Retained classes</a>` : ''}
+                ${hasPayload() && !payload ? ` · <a onclick="HeapInspector.showPayload()">Raw bytes</a>` : ''}
+            </div>
+        `;
+
+        let html = '';
+        if (content.string_value !== undefined && content.string_value !== null) {
+            html += `
+                <div class="heap-inspector-section">Value</div>
+                <pre class="heap-inspector-text">${Utils.escapeHtml(content.string_value)}</pre>
+                ${content.string_truncated ? '<div class="heap-inspector-note">Text truncated</div>' : ''}
+            `;
+        }
+
+        if (content.kind === 'instance') {
+            const fields = content.fields || [];
+            html += `<div class="heap-inspector-section">Fields (${fields.length})</div>`;
+            html += fields.length > 0
+                ? `<table class="heap-inspector-table"><tbody>${renderRows(fields)}</tbody></table>`
+                : '<div class="heap-inspector-note">No instance fields</div>';
+        } else {
+            const elements = content.elements || [];
+            html += `<div class="heap-inspector-section">Elements (${Utils.formatNumber(elements.length)} of ${Utils.formatNumber(content.length || 0)})</div>`;
+            if (elements.length > 0) {
+                html += `<table class="heap-inspector-table"><tbody>${renderRows(elements)}</tbody></table>`;
+            }
+            if (content.truncated) {
+                html += maxElements < MAX_ELEMENTS_LIMIT
+                    ? `<button class="heap-inspector-more" onclick="HeapInspector.showMore()">Show more</button>`
+                    : `<div class="heap-inspector-note">Showing the first ${Utils.formatNumber(MAX_ELEMENTS_LIMIT)} elements</div>`;
+            }
+        }
+        html += renderPayload();
+        html += renderHistogram();
+        body.innerHTML = html;
+    }
+
+    /**
+     * 基本类型数组与 String 有原始字节可读
+     */
+    function hasPayload() {
+        return content.kind === 'primitive_array' || (content.string_value !== undefined && content.string_value !== null);
+    }
+
+    /**
+     * 十六进制转储：每行 16 字节，含偏移和可打印 ASCII
+     */
+    function hexDump(hex, offset) {
+        const lines = [];
+        for (let i = 0; i < hex.length; i += 32) {
+            const bytes = hex.substring(i, i + 32).match(/../g);
+            const ascii = bytes.map(b => {
+                const c = parseInt(b, 16);
+                return c >= 0x20 && c < 0x7f ? String.fromCharCode(c) : '.';
+            }).join('');
+            lines.push(`${(offset + i / 2).toString(16).padStart(8, '0')}  ${bytes.join(' ').padEnd(47)}  |${ascii}|`);
+        }
+        return lines.join('\n');
+    }
+
+    /**
+     * 渲染数组或 String 的原始字节
+     */
+    function renderPayload() {
+        if (!payload) return '';
+        if (payload.loading) {
+            return '<div class="heap-inspector-section">Raw bytes</div><div class="heap-inspector-note">Loading…</div>';
+        }
+        if (payload.error) {
+            return `<div class="heap-inspector-section">Raw bytes</div><div class="heap-inspector-note">⚠️ ${Utils.escapeHtml(payload.error)}</div>`;
+        }
+        const result = payload.result;
+        const download = `/api/refgraph/payload?${new URLSearchParams({ task: getCurrentTaskId(), id: content.object_id, download: 'true' })}`;
+        let html = `
+            <div class="heap-inspector-section">
+                Raw bytes (${Utils.formatBytes(result.size || 0)} of ${Utils.escapeHtml(result.element_type)}[])
+                · <a href="${download}" download>Download</a>
+            </div>
+            <pre class="heap-inspector-text">${Utils.escapeHtml(hexDump(result.hex || '', result.offset || 0))}</pre>
+        `;
+        if (result.text) {
+            html += `<pre class="heap-inspector-text">${Utils.escapeHtml(result.text)}</pre>`;
+        }
+        if (result.truncated) {
+            html += `<div class="heap-inspector-note">Showing the first ${Utils.formatBytes(result.length || 0)}; download for the whole contents</div>`;
+        }
+        return html;
+    }
+
+    /**
+     * 渲染对象支配子树的类统计
+     */
+    function renderHistogram() {
+        if (!histogram) return '';
+        if (histogram.loading) {
+            return '<div class="heap-inspector-section">Retained classes</div><div class="heap-inspector-note">Loading…</div>';
+        }
+        if (histogram.error) {
+            return `<div class="heap-inspector-section">Retained classes</div><div class="heap-inspector-note">⚠️ ${Utils.escapeHtml(histogram.error)}</div>`;
+        }
+        const result = histogram.result;
+        const classes = result.classes || [];
+        let html = `
+            <div class="heap-inspector-section">
+                Retained classes (${Utils.formatNumber(result.class_count || 0)})
+                · ${Utils.formatNumber(result.objects || 0)} objects · ${Utils.formatBytes(result.shallow_size || 0)}
+            </div>
+        `;
+        if (classes.length > 0) {
+            html += `<table class="heap-inspector-table"><tbody>${classes.map(c => `
+                <tr>
+                    <td class="heap-inspector-value">${classLink(c.class_name)}</td>
+                    <td class="heap-inspector-size">${Utils.formatNumber(c.count || 0)} objects</td>
+                    <td class="heap-inspector-size">Shallow ${Utils.formatBytes(c.shallow_size || 0)}</td>
+                    <td class="heap-inspector-size">Retained ${Utils.formatBytes(c.retained_size || 0)}</td>
+                </tr>
+            `).join('')}</tbody></table>`;
+        }
+        if (classes.length < (result.class_count || 0)) {
+            html += `<div class="heap-inspector-note">Showing the top ${classes.length} classes by shallow size</div>`;
+        }
+        return html;
+    }
+
+    /**
+     * 渲染类元数据：继承链、子类、实例字段与静态字段
+     */
+    function renderClass(title, body) {
+        title.innerHTML = `
+            <span class="heap-inspector-class" title="${Utils.escapeHtml(content.class_name)}">${Utils.escapeHtml(content.class_name)}</span>
+            <span class="object-id">${Utils.escapeHtml(content.class_id)}</span>
+            <div class="heap-inspector-meta">
+                ${Utils.formatNumber(content.instance_count || 0)} instances
+                · Shallow ${Utils.formatBytes(content.shallow_size || 0)} · Retained ${Utils.formatBytes(content.retained_size || 0)}
+                · Instance size ${Utils.formatBytes(content.instance_size || 0)}
+                ${content.class_loader_id ? ` · <a onclick="HeapInspector.show('${content.class_loader_id}')">Class loader</a>` : ''}
+            </div>
+        `;
+
+        const superclasses = content.superclasses || [];
+        const subclasses = content.subclasses || [];
+        let html = '<div class="heap-inspector-section">Hierarchy</div>';
+        html += superclasses.length > 0
+            ? `<div class="heap-inspector-value">${superclasses.map(c => classLink(c.class_name)).reverse().join(' → ')} → ${Utils.escapeHtml(content.class_name)}</div>`
+            : '<div class="heap-inspector-note">No superclass</div>';
+        if (subclasses.length > 0) {
+            html += `<div class="heap-inspector-note">Subclasses (${subclasses.length}): ${subclasses.map(c => classLink(c.class_name)).join(', ')}</div>`;
+        }
+
+        const fields = content.fields || [];
+        html += `<div class="heap-inspector-section">Fields (${fields.length})</div>`;
+        html += fields.length > 0
+            ? `<table class="heap-inspector-table"><tbody>${fields.map(f => `
+                <tr>
+                    <td class="heap-inspector-name">${Utils.escapeHtml(f.name)}</td>
+                    <td>${typeBadge(f.type)}</td>
+                    <td class="heap-inspector-value">${f.declaring_class === content.class_name ? '' : classLink(f.declaring_class)}</td>
+                </tr>
+            `).join('')}</tbody></table>`
+            : '<div class="heap-inspector-note">No instance fields</div>';
+
+        const statics = content.static_fields || [];
+        html += `<div class="heap-inspector-section">Static fields (${statics.length})</div>`;
+        html += statics.length > 0
+            ? `<table class="heap-inspector-table"><tbody>${renderRows(statics)}</tbody></table>`
+            : '<div class="heap-inspector-note">No static fields</div>';
+        html += renderInstances();
+        body.innerHTML = html;
+    }
+
+    /**
+     * 渲染类视图的实例列表，按 retained/shallow 排序，游标分页加载更多
+     */
+    function renderInstances() {
+        if (!instances) return '';
+        const sortLink = (sort, label) => instances.sort === sort
+            ? `<strong>${label}</strong>`
+            : `<a class="heap-inspector-ref" onclick="HeapInspector.sortInstances('${sort}')">${label}</a>`;
+        let html = `
+            <div class="heap-inspector-section">
+                Instances (${Utils.formatNumber(instances.items.length)} of ${Utils.formatNumber(instances.total)})
+                · ${sortLink('retained', 'Retained')} / ${sortLink('shallow', 'Shallow')}
+            </div>
+        `;
+        if (instances.items.length > 0) {
+            html += `<table class="heap-inspector-table"><tbody>${instances.items.map(item => `
+                <tr>
+                    <td class="heap-inspector-value">
+                        <a class="heap-inspector-ref" onclick="HeapInspector.show('${item.object_id}')">
+                            <span class="object-id">${Utils.escapeHtml(item.object_id)}</span>
+                        </a>
+                        ${item.reachable ? '' : '<span class="heap-inspector-null">unreachable</span>'}
+                    </td>
+                    <td class="heap-inspector-size">Shallow ${Utils.formatBytes(item.shallow_size || 0)}</td>
+                    <td class="heap-inspector-size">Retained ${Utils.formatBytes(item.retained_size || 0)}</td>
+                </tr>
+            `).join('')}</tbody></table>`;
+        }
+        if (instances.loading) {
+            html += '<div class="heap-inspector-note">Loading…</div>';
+        } else if (instances.next) {
+            html += `<button class="heap-inspector-more" onclick="HeapInspector.moreInstances()">Load more</button>`;
+        }
+        return html;
+    }
+
+    /**
+     * 加载类实例的下一页（首页时 next 为空）
+     */
+    async function fetchInstances(className) {
+        const taskId = getCurrentTaskId();
+        if (!taskId || !instances || instances.loading) return;
+        const page = instances;
+        page.loading = true;
+        try {
+            const result = await API.getClassInstances(taskId, className, page.sort, page.next, INSTANCES_PAGE_SIZE);
+            if (page !== instances) return;
+            page.items = page.items.concat(result.items || []);
+            page.total = result.total || 0;
+            page.next = result.next || '';
+        } catch (error) {
+            console.error('[HeapInspector] Failed to load instances:', error);
+            page.next = '';
+        } finally {
+            page.loading = false;
+        }
+        if (page === instances && content && content.kind === 'class') render();
+    }
+
+    async function fetchCurrent() {
+        const taskId = getCurrentTaskId();
+        const entry = history[history.length - 1];
+        const body = document.getElementById('heapInspectorBody');
+        if (!taskId || !entry) return;
+
+        const seq = ++requestSeq;
+        const isClass = entry.startsWith(CLASS_PREFIX);
+        instances = null;
+        histogram = null;
+        payload = null;
+        if (!isClass) UrlState.update({ object: entry });
+        if (body && !content) body.innerHTML = '<div class="text-center py-10"><div class="loading-spinner"></div></div>';
+        try {
+            const result = isClass
+                ? { ...(await API.getClassMetadata(taskId, entry.substring(CLASS_PREFIX.length))), kind: 'class' }
+                : await API.getObjectContent(taskId, entry, maxElements);
+            if (seq !== requestSeq) return;
+            content = result;
+            render();
+            if (isClass) {
+                instances = { sort: 'retained', items: [], total: result.instance_count || 0, next: '', loading: false };
+                fetchInstances(result.class_name);
+            }
+        } catch (error) {
+            if (seq !== requestSeq) return;
+            console.error('[HeapInspector] Failed to load object:', error);
+            content = null;
+            document.getElementById('heapInspectorTitle').innerHTML = `<span class="object-id">${Utils.escapeHtml(entry)}</span>`;
+            overlay.querySelector('.heap-inspector-back').disabled = history.length < 2;
+            if (body) body.innerHTML = `<div class="text-center py-10 text-muted">⚠️ ${Utils.escapeHtml(error.message)}</div>`;
+        }
+    }
+
+    // ============================================
+    // 公共方法
+    // ============================================
+
+    /**
+     * 初始化模块
+     */
+    function init() {
+        HeapCore.on('dataLoaded', function() {
+            close();
+        });
+    }
+
+    /**
+     * 打开查看器并显示对象（在已打开时压入历史）
+     */
+    function show(objectId) {
+        if (!objectId) return;
+        // 数字 ID（如 Biggest Objects 的 object_id）转为十六进制
+        if (typeof objectId === 'number' || /^\d+$/.test(objectId)) {
+            objectId = '0x' + Number(objectId).toString(16);
+        }
+        ensureOverlay().style.display = '';
+        if (history[history.length - 1] !== objectId) {
+            history.push(objectId);
+        }
+        content = null;
+        maxElements = 100;
+        fetchCurrent();
+    }
+
+    /**
+     * 打开查看器并显示类元数据（在已打开时压入历史）
+     */
+    function showClass(className) {
+        if (!className) return;
+        ensureOverlay().style.display = '';
+        const entry = CLASS_PREFIX + className;
+        if (history[history.length - 1] !== entry) {
+            history.push(entry);
+        }
+        content = null;
+        fetchCurrent();
+    }
+
+    /**
+     * 类视图：加载更多实例
+     */
+    function moreInstances() {
+        if (content && content.kind === 'class') fetchInstances(content.class_name);
+    }
+
+    /**
+     * 类视图：切换实例排序并从首页重新加载
+     */
+    function sortInstances(sort) {
+        if (!content || content.kind !== 'class') return;
+        instances = { sort, items: [], total: instances ? instances.total : 0, next: '', loading: false };
+        render();
+        fetchInstances(content.class_name);
+    }
+
+    /**
+     * 对象视图：按类统计对象支配的子树
+     */
+    async function showHistogram() {
+        const taskId = getCurrentTaskId();
+        if (!taskId || !content || content.kind === 'class' || histogram) return;
+        const current = histogram = { result: null, loading: true, error: '' };
+        render();
+        try {
+            current.result = await API.getSubtreeHistogram(taskId, content.object_id, HISTOGRAM_TOP);
+        } catch (error) {
+            console.error('[HeapInspector] Failed to load retained classes:', error);
+            current.error = error.message;
+        } finally {
+            current.loading = false;
+        }
+        if (current === histogram && content && content.kind !== 'class') render();
+    }
+
+    /**
+     * 对象视图：加载数组或 String 的原始字节
+     */
+    async function showPayload() {
+        const taskId = getCurrentTaskId();
+        if (!taskId || !content || content.kind === 'class' || payload) return;
+        const current = payload = { result: null, loading: true, error: '' };
+        render();
+        try {
+            current.result = await API.getObjectPayload(taskId, content.object_id, 0, PAYLOAD_BYTES);
+        } catch (error) {
+            console.error('[HeapInspector] Failed to load raw bytes:', error);
+            current.error = error.message;
+        } finally {
+            current.loading = false;
+        }
+        if (current === payload && content && content.kind !== 'class') render();
+    }
+
+    /**
+     * 返回上一项
+     */
+    function back() {
+        if (history.length < 2) return;
+        history.pop();
+        content = null;
+        maxElements = 100;
+        fetchCurrent();
+    }
+
+    /**
+     * 数组加载更多元素
+     */
+    function showMore() {
+        maxElements = Math.min(maxElements * 10, MAX_ELEMENTS_LIMIT);
+        fetchCurrent();
+    }
+
+    /**
+     * 关闭查看器并清空历史
+     */
+    function close() {
+        if (overlay) overlay.style.display = 'none';
+        if (history.length > 0) UrlState.update({ object: '' });
+        history = [];
+        content = null;
+    }
+
+    // ============================================
+    // 模块注册
+    // ============================================
+
+    const module = {
+        init,
+        show,
+        showClass,
+        moreInstances,
+        sortInstances,
+        showHistogram,
+        showPayload,
+        back,
+        showMore,
+        close
+    };
+
+    // 自动注册到核心模块
+    if (typeof HeapCore !== 'undefined') {
+        HeapCore.registerModule('inspector', module);
+    }
+
+    return module;
+})();
+
+// 导出到全局
+window.HeapInspector = HeapInspector;
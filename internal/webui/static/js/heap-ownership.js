@@ -0,0 +1,236 @@
+/**
+ * Heap Ownership Module
+ * 团队内存归属模块：按包前缀 -> 团队映射展示保留内存归属
+ *
+ * 职责：
+ * - 从 /api/heap/ownership 加载团队归属报告（analyze --ownership-map 生成）
+ * - 饼图展示各团队保留内存占比
+ * - 表格展示各团队的类数、实例数、浅/保留大小及主要类
+ */
+
+const HeapOwnership = (function() {
+    'use strict';
+
+    // ============================================
+    // 私有状态
+    // ============================================
+
+    let currentTaskId = null;
+    let report = null;
+    let chart = null;
+    let isLoading = false;
+
+    // ============================================
+    // 私有方法
+    // ============================================
+
+    function showMessage(html) {
+        const table = document.getElementById('heapOwnershipTable');
+        if (table) table.innerHTML = `<div class="text-center py-10 text-muted">${html}</div>`;
+        const container = document.getElementById('heapOwnershipChart');
+        if (container) container.style.display = 'none';
+    }
+
+    function renderStats() {
+        const stats = document.getElementById('heapOwnershipStats');
+        if (!stats) return;
+        stats.textContent = report
+            ? `${Utils.formatNumber((report.teams || []).length)} teams · ${Utils.formatBytes(report.total_size || 0)} retained`
+            : '';
+    }
+
+    function renderChart() {
+        const container = document.getElementById('heapOwnershipChart');
+        if (!container || !report || typeof echarts === 'undefined') return;
+        container.style.display = '';
+
+        if (chart) {
+            chart.dispose();
+        }
+        chart = echarts.init(container);
+
+        const isDark = document.documentElement.getAttribute('data-theme') === 'dark';
+        const textColor = isDark ? '#e5e7eb' : '#333';
+        const secondaryTextColor = isDark ? '#9ca3af' : '#666';
+        const data = (report.teams || []).filter(t => t.retained_size > 0).map(t => ({
+            name: t.team,
+            value: t.retained_size,
+            percentage: t.percentage
+        }));
+
+        chart.setOption({
+            title: {
+                text: 'Retained Memory by Team',
+                left: 'center',
+                top: 10,
+                textStyle: { fontSize: 14, fontWeight: 'bold', color: textColor }
+            },
+            tooltip: {
+                trigger: 'item',
+                formatter: function(params) {
+                    return `<strong>${Utils.escapeHtml(params.name)}</strong><br/>
+                        Retained: <strong>${Utils.formatBytes(params.value)}</strong><br/>
+                        Percentage: <strong>${params.data.percentage.toFixed(2)}%</strong>`;
+                }
+            },
+            legend: {
+                type: 'scroll',
+                orient: 'vertical',
+                right: 10,
+                top: 50,
+                bottom: 20,
+                textStyle: { fontSize: 10, color: secondaryTextColor }
+            },
+            series: [{
+                name: 'Teams',
+                type: 'pie',
+                radius: ['35%', '65%'],
+                center: ['40%', '55%'],
+                avoidLabelOverlap: true,
+                itemStyle: {
+                    borderRadius: 6,
+                    borderColor: isDark ? '#1f2937' : '#fff',
+                    borderWidth: 2
+                },
+                label: {
+                    show: true,
+                    formatter: '{b}: {d}%',
+                    fontSize: 10,
+                    color: secondaryTextColor
+                },
+                data
+            }]
+        });
+    }
+
+    function renderTable() {
+        const table = document.getElementById('heapOwnershipTable');
+        if (!table || !report) return;
+
+        const rows = (report.teams || []).map(t => {
+            const classes = (t.top_classes || []).slice(0, 3).map(c =>
+                `<span class="font-mono" title="${Utils.escapeHtml(c.class_name)}">${Utils.escapeHtml(Utils.getShortClassName(c.class_name))}</span> ${Utils.formatBytes(c.retained_size)}`
+            ).join(', ');
+            return `
+                <tr class="hover:bg-muted">
+                    <td class="px-4 py-2 text-sm font-medium">${Utils.escapeHtml(t.team)}</td>
+                    <td class="px-4 py-2 text-sm text-right">${Utils.formatNumber(t.class_count)}</td>
+                    <td class="px-4 py-2 text-sm text-right">${Utils.formatNumber(t.instance_count)}</td>
+                    <td class="px-4 py-2 text-sm text-right">${Utils.formatBytes(t.shallow_size)}</td>
+                    <td class="px-4 py-2 text-sm text-right">${Utils.formatBytes(t.retained_size)}</td>
+                    <td class="px-4 py-2 text-sm text-right">${t.percentage.toFixed(2)}%</td>
+                    <td class="px-4 py-2 text-xs text-muted">${classes}</td>
+                </tr>
+            `;
+        }).join('');
+
+        table.innerHTML = `
+            <table class="w-full">
+                <thead>
+                    <tr class="bg-muted text-left">
+                        <th class="px-4 py-2 text-xs font-semibold text-muted uppercase">Team</th>
+                        <th class="px-4 py-2 text-xs font-semibold text-muted uppercase text-right">Classes</th>
+                        <th class="px-4 py-2 text-xs font-semibold text-muted uppercase text-right">Instances</th>
+                        <th class="px-4 py-2 text-xs font-semibold text-muted uppercase text-right">Shallow</th>
+                        <th class="px-4 py-2 text-xs font-semibold text-muted uppercase text-right">Retained</th>
+                        <th class="px-4 py-2 text-xs font-semibold text-muted uppercase text-right">%</th>
+                        <th class="px-4 py-2 text-xs font-semibold text-muted uppercase">Top Classes</th>
+                    </tr>
+                </thead>
+                <tbody class="divide-y divide-theme">${rows}</tbody>
+            </table>
+        `;
+    }
+
+    function render() {
+        renderStats();
+        renderChart();
+        renderTable();
+    }
+
+    function getCurrentTaskId() {
+        if (typeof App !== 'undefined' && App.getCurrentTask) {
+            const taskId = App.getCurrentTask();
+            if (taskId) return taskId;
+        }
+        const urlParams = new URLSearchParams(window.location.search);
+        return urlParams.get('task') || window.currentTaskId || null;
+    }
+
+    // ============================================
+    // 公共方法
+    // ============================================
+
+    /**
+     * 初始化模块
+     */
+    function init() {
+        HeapCore.on('dataLoaded', function() {
+            currentTaskId = null;
+            report = null;
+        });
+        window.addEventListener('resize', resize);
+    }
+
+    /**
+     * 面板打开时调用：加载团队归属报告（同一任务只加载一次）
+     */
+    async function load(taskId) {
+        taskId = taskId || getCurrentTaskId();
+        if (!taskId || isLoading) return;
+        if (taskId === currentTaskId && report) {
+            render();
+            return;
+        }
+
+        isLoading = true;
+        currentTaskId = taskId;
+        report = null;
+        renderStats();
+        showMessage('<div class="loading-spinner"></div>');
+        try {
+            report = await API.getHeapOwnership(taskId);
+            render();
+        } catch (error) {
+            console.error('[HeapOwnership] Failed to load ownership:', error);
+            currentTaskId = null;
+            showMessage(`⚠️ ${Utils.escapeHtml(error.message)}`);
+        } finally {
+            isLoading = false;
+        }
+    }
+
+    /**
+     * 导出 CSV
+     */
+    function exportCSV() {
+        const taskId = currentTaskId || getCurrentTaskId();
+        if (!taskId) return;
+        window.open(`/api/heap/ownership?${new URLSearchParams({ task: taskId, format: 'csv' })}`);
+    }
+
+    function resize() {
+        if (chart) chart.resize();
+    }
+
+    // ============================================
+    // 模块注册
+    // ============================================
+
+    const module = {
+        init,
+        load,
+        exportCSV,
+        resize
+    };
+
+    // 自动注册到核心模块
+    if (typeof HeapCore !== 'undefined') {
+        HeapCore.registerModule('ownership', module);
+    }
+
+    return module;
+})();
+
+// 导出到全局
+window.HeapOwnership = HeapOwnership;
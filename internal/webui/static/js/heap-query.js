@@ -0,0 +1,325 @@
+/**
+ * Heap Query Console Module
+ * OQL 查询控制台：类似 MAT 的 OQL 面板
+ *
+ * 职责：
+ * - 通过 /api/query 执行 OQL 风格查询，表格展示结果
+ * - 查询历史（localStorage 持久化）
+ * - 将结果对象加入选择集，计算选择集 / 全部结果的 Retained Set
+ */
+
+const HeapQuery = (function() {
+    'use strict';
+
+    const HISTORY_KEY = 'perf-analysis-oql-history';
+    const HISTORY_SIZE = 30;
+    const ROW_LIMIT = 1000;
+
+    const EXAMPLES = [
+        'SELECT * FROM java.lang.String s WHERE s.@retainedHeapSize > 1024 ORDER BY s.@retainedHeapSize DESC LIMIT 100',
+        'SELECT * FROM INSTANCEOF java.util.AbstractMap ORDER BY @retainedHeapSize DESC LIMIT 50',
+        'SELECT @objectId, @className, @retainedHeapSize FROM "com\\.example\\..*" WHERE @gcRoot = true',
+        'SELECT s, s.value FROM java.lang.String s WHERE s.value = null'
+    ];
+
+    // ============================================
+    // 私有状态
+    // ============================================
+
+    let currentTaskId = null;
+    let lastQuery = '';
+    let result = null;
+    let selection = new Set();      // 选择集（十六进制对象 ID）
+    let isRunning = false;
+
+    // ============================================
+    // 私有方法
+    // ============================================
+
+    function formatId(objectId) {
+        return '0x' + Number(objectId).toString(16);
+    }
+
+    function loadHistory() {
+        try {
+            return JSON.parse(localStorage.getItem(HISTORY_KEY)) || [];
+        } catch (e) {
+            return [];
+        }
+    }
+
+    function saveHistory(query) {
+        const history = loadHistory().filter(q => q !== query);
+        history.unshift(query);
+        localStorage.setItem(HISTORY_KEY, JSON.stringify(history.slice(0, HISTORY_SIZE)));
+        renderHistory();
+    }
+
+    function renderHistory() {
+        const container = document.getElementById('queryHistory');
+        if (!container) return;
+
+        const history = loadHistory();
+        const items = history.length > 0 ? history : EXAMPLES;
+        container.innerHTML = `
+            <div class="query-history-title">${history.length > 0 ? 'History' : 'Examples'}</div>
+            ${items.map((q, i) => `
+                <div class="query-history-item" onclick="HeapQuery.useHistory(${i}, ${history.length > 0})" title="${Utils.escapeHtml(q)}">
+                    ${Utils.escapeHtml(q)}
+                </div>
+            `).join('')}
+        `;
+    }
+
+    function getEditor() {
+        return document.getElementById('queryEditor');
+    }
+
+    function showMessage(html) {
+        const container = document.getElementById('queryResults');
+        if (container) container.innerHTML = `<div class="text-center py-10 text-muted">${html}</div>`;
+    }
+
+    /**
+     * 格式化单元格：对象 ID 列显示十六进制，大小列显示字节
+     */
+    function formatCell(column, value) {
+        if (value === null || value === undefined) return '<span class="text-muted">null</span>';
+        if (column === '@usedHeapSize' || column === '@retainedHeapSize') {
+            return Utils.formatBytes(value);
+        }
+        if (typeof value === 'number' && (column === '@objectId' || !column.startsWith('@'))) {
+            return `<code class="object-id">${formatId(value)}</code>`;
+        }
+        return Utils.escapeHtml(String(value));
+    }
+
+    function renderResults() {
+        const container = document.getElementById('queryResults');
+        if (!container || !result) return;
+
+        const stats = document.getElementById('queryStats');
+        if (stats) {
+            const shown = result.rows.length;
+            stats.textContent = `${Utils.formatNumber(result.total)} objects` +
+                (result.truncated ? ` (showing ${Utils.formatNumber(shown)})` : '') +
+                ` · ${result.elapsed_ms} ms`;
+        }
+
+        if (result.rows.length === 0) {
+            showMessage('No objects match the query');
+            return;
+        }
+
+        container.innerHTML = `
+            <table class="w-full query-table">
+                <thead>
+                    <tr class="bg-muted text-left">
+                        <th class="w-10"><input type="checkbox" onchange="HeapQuery.selectAll(this.checked)" title="Add all rows to selection"></th>
+                        ${result.columns.map(c => `<th>${Utils.escapeHtml(c)}</th>`).join('')}
+                        <th class="w-16"></th>
+                    </tr>
+                </thead>
+                <tbody>
+                    ${result.rows.map(row => {
+                        const id = formatId(row.object_id);
+                        return `
+                            <tr>
+                                <td><input type="checkbox" ${selection.has(id) ? 'checked' : ''} onchange="HeapQuery.toggleSelection('${id}', this.checked)"></td>
+                                ${row.values.map((v, i) => `<td>${formatCell(result.columns[i], v)}</td>`).join('')}
+                                <td>
+                                    <button class="domtree-action" onclick="HeapInspector.show('${id}')" title="Inspect fields">🔍</button>
+                                    <button class="domtree-action" onclick="HeapRootPaths.show('${id}')" title="Paths to GC root">🧭</button>
+                                </td>
+                            </tr>
+                        `;
+                    }).join('')}
+                </tbody>
+            </table>
+        `;
+    }
+
+    function updateSelection() {
+        const label = document.getElementById('querySelectionCount');
+        if (label) label.textContent = `${Utils.formatNumber(selection.size)} selected`;
+    }
+
+    function renderRetainedSet(title, set) {
+        const container = document.getElementById('queryRetainedSet');
+        if (!container) return;
+        container.innerHTML = `
+            <div class="query-retained-set">
+                <strong>${Utils.escapeHtml(title)}</strong>
+                <span>${Utils.formatNumber(set.selected || 0)} objects selected</span>
+                <span>retain ${Utils.formatNumber(set.objects || 0)} objects</span>
+                <span>${Utils.formatBytes(set.shallow_size || 0)}</span>
+            </div>
+        `;
+    }
+
+    function getCurrentTaskId() {
+        if (typeof App !== 'undefined' && App.getCurrentTask) {
+            const taskId = App.getCurrentTask();
+            if (taskId) return taskId;
+        }
+        const urlParams = new URLSearchParams(window.location.search);
+        return urlParams.get('task') || window.currentTaskId || null;
+    }
+
+    // ============================================
+    // 公共方法
+    // ============================================
+
+    /**
+     * 初始化模块
+     */
+    function init() {
+        HeapCore.on('dataLoaded', function() {
+            currentTaskId = null;
+            result = null;
+            selection.clear();
+        });
+    }
+
+    /**
+     * 面板打开时调用
+     */
+    function load(taskId) {
+        currentTaskId = taskId || getCurrentTaskId();
+        renderHistory();
+        updateSelection();
+        if (!result) {
+            showMessage('Write a query and press Ctrl+Enter to run it');
+        }
+    }
+
+    /**
+     * 执行编辑器中的查询
+     */
+    async function run() {
+        const editor = getEditor();
+        const query = (editor?.value || '').trim();
+        if (!query || isRunning) return;
+        currentTaskId = currentTaskId || getCurrentTaskId();
+
+        isRunning = true;
+        showMessage('<div class="loading-spinner"></div>');
+        try {
+            result = await API.runQuery(currentTaskId, query, ROW_LIMIT);
+            lastQuery = query;
+            saveHistory(query);
+            renderResults();
+        } catch (error) {
+            result = null;
+            showMessage(`<span class="query-error">⚠️ ${Utils.escapeHtml(error.message)}</span>`);
+        } finally {
+            isRunning = false;
+        }
+    }
+
+    /**
+     * 编辑器按键：Ctrl/Cmd+Enter 执行
+     */
+    function onKeyDown(event) {
+        if (event.key === 'Enter' && (event.ctrlKey || event.metaKey)) {
+            event.preventDefault();
+            run();
+        }
+    }
+
+    /**
+     * 从历史（或示例）中填入查询
+     */
+    function useHistory(index, fromHistory) {
+        const items = fromHistory ? loadHistory() : EXAMPLES;
+        const editor = getEditor();
+        if (editor && items[index]) {
+            editor.value = items[index];
+            editor.focus();
+        }
+    }
+
+    function clearHistory() {
+        localStorage.removeItem(HISTORY_KEY);
+        renderHistory();
+    }
+
+    function toggleSelection(objectId, selected) {
+        if (selected) {
+            selection.add(objectId);
+        } else {
+            selection.delete(objectId);
+        }
+        updateSelection();
+    }
+
+    function selectAll(selected) {
+        (result?.rows || []).forEach(row => toggleSelection(formatId(row.object_id), selected));
+        renderResults();
+    }
+
+    function clearSelection() {
+        selection.clear();
+        updateSelection();
+        renderResults();
+        const container = document.getElementById('queryRetainedSet');
+        if (container) container.innerHTML = '';
+    }
+
+    /**
+     * 计算选择集的 Retained Set
+     */
+    async function retainedSetOfSelection() {
+        if (selection.size === 0) {
+            HeapCore.showNotification('Select objects from the results first', 'warning');
+            return;
+        }
+        try {
+            const set = await API.getRetainedSet(currentTaskId, Array.from(selection));
+            renderRetainedSet('Selection', set);
+        } catch (error) {
+            HeapCore.showNotification(`Failed to compute retained set: ${error.message}`, 'error');
+        }
+    }
+
+    /**
+     * 计算全部查询结果的 Retained Set（服务端重新执行查询，不受行数上限限制）
+     */
+    async function retainedSetOfResult() {
+        if (!lastQuery) return;
+        try {
+            const set = await API.getQueryRetainedSet(currentTaskId, lastQuery);
+            renderRetainedSet('All results', set);
+        } catch (error) {
+            HeapCore.showNotification(`Failed to compute retained set: ${error.message}`, 'error');
+        }
+    }
+
+    // ============================================
+    // 模块注册
+    // ============================================
+
+    const module = {
+        init,
+        load,
+        run,
+        onKeyDown,
+        useHistory,
+        clearHistory,
+        toggleSelection,
+        selectAll,
+        clearSelection,
+        retainedSetOfSelection,
+        retainedSetOfResult
+    };
+
+    // 自动注册到核心模块
+    if (typeof HeapCore !== 'undefined') {
+        HeapCore.registerModule('query', module);
+    }
+
+    return module;
+})();
+
+// 导出到全局
+window.HeapQuery = HeapQuery;
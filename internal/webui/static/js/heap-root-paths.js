@@ -0,0 +1,221 @@
+/**
+ * Heap Paths-to-GC-Root Module
+ * GC Root 路径查看模块：展示从 GC Root 到目标对象的引用链
+ *
+ * 职责：
+ * - 从 /api/refgraph/gc-roots 加载路径（可排除 weak/soft/phantom 引用）
+ * - 纵向渲染每条路径：类名、字段名、每一跳的 Shallow/Retained Size
+ * - 支持将路径复制为文本
+ */
+
+const HeapRootPaths = (function() {
+    'use strict';
+
+    // ============================================
+    // 私有状态
+    // ============================================
+
+    let currentTaskId = null;
+    let currentObjectId = '';
+    let paths = [];
+    let isLoading = false;
+
+    // ============================================
+    // 私有方法
+    // ============================================
+
+    /**
+     * 对象 ID 转为十六进制形式
+     */
+    function formatId(objectId) {
+        return '0x' + Number(objectId).toString(16);
+    }
+
+    /**
+     * 读取工具栏上的查询选项
+     */
+    function readOptions() {
+        return {
+            maxPaths: parseInt(document.getElementById('rootPathsMax')?.value || '5', 10),
+            excludeWeak: document.getElementById('rootPathsExcludeWeak')?.checked || false,
+            excludeSoft: document.getElementById('rootPathsExcludeSoft')?.checked || false,
+            excludePhantom: document.getElementById('rootPathsExcludePhantom')?.checked || false
+        };
+    }
+
+    /**
+     * 设置容器内容
+     */
+    function setContent(html) {
+        const container = document.getElementById('rootPathsContainer');
+        if (container) container.innerHTML = html;
+    }
+
+    /**
+     * 渲染所有路径
+     */
+    function render() {
+        if (paths.length === 0) {
+            setContent(`
+                <div class="no-data-message" style="text-align: center; padding: 40px;">
+                    <div class="icon">🧭</div>
+                    <div>No path to a GC root found for ${Utils.escapeHtml(currentObjectId)}</div>
+                    <div style="font-size: 12px; color: #808080; margin-top: 8px;">
+                        The object may be unreachable, reachable only through excluded references, or deeper than the search limit
+                    </div>
+                </div>
+            `);
+            return;
+        }
+
+        setContent(paths.map((path, i) => `
+            <div class="root-path">
+                <div class="root-path-header">
+                    <span>Path ${i + 1} · ${path.path.length} objects</span>
+                    <span class="gc-root-type">${Utils.escapeHtml(path.root_type || 'UNKNOWN')}</span>
+                </div>
+                ${path.path.map((node, hop) => renderHop(node, hop, path.path.length)).join('')}
+            </div>
+        `).join(''));
+    }
+
+    /**
+     * 渲染路径上的一跳
+     */
+    function renderHop(node, hop, length) {
+        const isRoot = hop === 0;
+        const isTarget = hop === length - 1;
+        const edge = hop > 0 ? `
+            <div class="root-path-edge">
+                <span class="root-path-arrow">↓</span>
+                <span class="root-path-field ${node.field_name === 'referent' ? 'weak' : ''}">${Utils.escapeHtml(node.field_name || '?')}</span>
+            </div>
+        ` : '';
+
+        return `
+            ${edge}
+            <div class="root-path-node ${isRoot ? 'root' : ''} ${isTarget ? 'target' : ''}">
+                <span class="root-path-icon">${isRoot ? '🌳' : isTarget ? '🎯' : '•'}</span>
+                <span class="root-path-class" title="${Utils.escapeHtml(node.class_name)}">${Utils.escapeHtml(node.class_name || 'unknown')}</span>
+                <code class="object-id">${formatId(node.object_id)}</code>
+                <span class="root-path-sizes">
+                    <span title="Shallow size">${Utils.formatBytes(node.size || 0)}</span>
+                    <span class="retained-size" title="Retained size">${Utils.formatBytes(node.retained_size || 0)}</span>
+                </span>
+            </div>
+        `;
+    }
+
+    /**
+     * 将路径转为文本（GC root 在上，目标对象在下）
+     */
+    function pathsToText() {
+        return paths.map((path, i) => {
+            const lines = [`Path ${i + 1} (${path.root_type || 'UNKNOWN'})`];
+            path.path.forEach((node, hop) => {
+                const field = hop > 0 ? `.${node.field_name || '?'} -> ` : '';
+                lines.push(`${'  '.repeat(hop)}${field}${node.class_name} @ ${formatId(node.object_id)}` +
+                    ` [shallow ${Utils.formatBytes(node.size || 0)}, retained ${Utils.formatBytes(node.retained_size || 0)}]`);
+            });
+            return lines.join('\n');
+        }).join('\n\n');
+    }
+
+    /**
+     * 获取当前 taskId
+     */
+    function getCurrentTaskId() {
+        if (typeof App !== 'undefined' && App.getCurrentTask) {
+            const taskId = App.getCurrentTask();
+            if (taskId) return taskId;
+        }
+        const urlParams = new URLSearchParams(window.location.search);
+        return urlParams.get('task') || window.currentTaskId || null;
+    }
+
+    // ============================================
+    // 公共方法
+    // ============================================
+
+    /**
+     * 初始化模块
+     */
+    function init() {
+        HeapCore.on('dataLoaded', function() {
+            currentObjectId = '';
+            paths = [];
+        });
+    }
+
+    /**
+     * 查询输入框中对象的路径
+     */
+    async function search() {
+        const input = document.getElementById('rootPathsObjectId');
+        const objectId = (input?.value || '').trim();
+        if (!objectId || isLoading) return;
+
+        currentTaskId = getCurrentTaskId();
+        currentObjectId = objectId;
+        isLoading = true;
+        UrlState.update({ rootPaths: objectId });
+        setContent(`
+            <div class="loading-state" style="text-align: center; padding: 40px;">
+                <div class="loading-spinner"></div>
+                <div style="margin-top: 10px;">Searching paths to GC roots...</div>
+            </div>
+        `);
+        try {
+            paths = await API.getGCRootPaths(currentTaskId, objectId, readOptions()) || [];
+            render();
+        } catch (error) {
+            console.error('[HeapRootPaths] Failed to load paths:', error);
+            paths = [];
+            setContent(`<div class="error-message">Failed to load paths: ${Utils.escapeHtml(error.message)}</div>`);
+        } finally {
+            isLoading = false;
+        }
+    }
+
+    /**
+     * 打开面板并查询某个对象（供其他模块调用）
+     */
+    function show(objectId) {
+        if (typeof App !== 'undefined' && App.showPanel) {
+            App.showPanel('heaprootpaths');
+        }
+        const input = document.getElementById('rootPathsObjectId');
+        if (input) input.value = objectId;
+        search();
+    }
+
+    /**
+     * 复制路径文本
+     */
+    function copyAsText(element) {
+        if (paths.length === 0) return;
+        Utils.copyToClipboard(pathsToText(), element);
+        HeapCore.showNotification('Paths copied to clipboard', 'success');
+    }
+
+    // ============================================
+    // 模块注册
+    // ============================================
+
+    const module = {
+        init,
+        search,
+        show,
+        copyAsText
+    };
+
+    // 自动注册到核心模块
+    if (typeof HeapCore !== 'undefined') {
+        HeapCore.registerModule('rootPaths', module);
+    }
+
+    return module;
+})();
+
+// 导出到全局
+window.HeapRootPaths = HeapRootPaths;
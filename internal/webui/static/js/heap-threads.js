@@ -0,0 +1,261 @@
+/**
+ * Heap Threads Module
+ * 线程栈模块：展示堆转储中的线程及其调用栈（MAT Thread Overview 风格）
+ *
+ * 职责：
+ * - 从 /api/heap/threads 加载线程概览
+ * - 按线程展示保留大小条（线程对象 + 栈上局部变量）
+ * - 展开线程查看栈帧，点击局部变量跳转到 Paths to GC Root
+ */
+
+const HeapThreads = (function() {
+    'use strict';
+
+    // ============================================
+    // 私有状态
+    // ============================================
+
+    let currentTaskId = null;
+    let overview = null;
+    let expanded = new Set();       // 已展开的 thread_serial
+    let searchQuery = '';
+    let isLoading = false;
+
+    // STACK_FRAME 记录中的特殊行号
+    const LINE_MARKERS = {
+        '-1': 'Unknown Source',
+        '-2': 'Compiled Code',
+        '-3': 'Native Method'
+    };
+
+    // ============================================
+    // 私有方法
+    // ============================================
+
+    function formatId(objectId) {
+        return '0x' + Number(objectId).toString(16);
+    }
+
+    /**
+     * 格式化栈帧：class.method(Source.java:123)
+     */
+    function formatFrame(frame) {
+        const method = `${frame.class_name || '<unknown class>'}.${frame.method_name || '<unknown method>'}`;
+        let location = LINE_MARKERS[String(frame.line_number)];
+        if (!location) {
+            location = frame.source_file || 'Unknown Source';
+            if (frame.line_number > 0) location += `:${frame.line_number}`;
+        }
+        return `${method}(${location})`;
+    }
+
+    function showMessage(html) {
+        const container = document.getElementById('heapThreadsList');
+        if (container) container.innerHTML = `<div class="text-center py-10 text-muted">${html}</div>`;
+    }
+
+    function renderStats() {
+        const stats = document.getElementById('heapThreadsStats');
+        if (!stats) return;
+        stats.textContent = overview
+            ? `${Utils.formatNumber(overview.total_threads || 0)} threads · ${Utils.formatBytes(overview.total_retained || 0)} retained`
+            : '';
+    }
+
+    function matchesSearch(thread) {
+        if (!searchQuery) return true;
+        if ((thread.class_name || '').toLowerCase().includes(searchQuery)) return true;
+        return (thread.frames || []).some(f => formatFrame(f).toLowerCase().includes(searchQuery));
+    }
+
+    function renderLocal(local) {
+        const id = formatId(local.object_id);
+        const className = local.class_name || '<unknown>';
+        return `
+            <div class="heap-thread-local" onclick="HeapRootPaths.show('${id}')" title="Paths to GC root">
+                <span class="heap-thread-local-root">${Utils.escapeHtml(local.root_type)}</span>
+                <span class="heap-thread-local-class" title="${Utils.escapeHtml(className)}">${Utils.escapeHtml(Utils.getShortClassName(className))}</span>
+                <span class="heap-thread-local-id">${id}</span>
+                <span class="heap-thread-local-size">${Utils.formatBytes(local.shallow_size || 0)} / ${Utils.formatBytes(local.retained_size || 0)}</span>
+                <button class="domtree-action" onclick="event.stopPropagation(); HeapInspector.show('${id}')" title="Inspect fields">🔍</button>
+            </div>
+        `;
+    }
+
+    function renderFrames(thread) {
+        const frames = thread.frames || [];
+        let html = '';
+        if (frames.length === 0) {
+            html += '<div class="heap-thread-empty">No stack trace recorded for this thread</div>';
+        }
+        html += frames.map(frame => `
+            <div class="heap-thread-frame">
+                <div class="heap-thread-frame-line">
+                    <span class="heap-thread-frame-depth">#${frame.depth}</span>
+                    <span class="heap-thread-frame-text">at ${Utils.escapeHtml(formatFrame(frame))}</span>
+                </div>
+                ${(frame.locals || []).map(renderLocal).join('')}
+            </div>
+        `).join('');
+        if (thread.unattributed_locals && thread.unattributed_locals.length > 0) {
+            html += `
+                <div class="heap-thread-frame">
+                    <div class="heap-thread-frame-line text-muted">Locals of unknown frames</div>
+                    ${thread.unattributed_locals.map(renderLocal).join('')}
+                </div>
+            `;
+        }
+        return html;
+    }
+
+    function renderThreads() {
+        const container = document.getElementById('heapThreadsList');
+        if (!container || !overview) return;
+
+        const threads = (overview.threads || []).filter(matchesSearch);
+        if (threads.length === 0) {
+            showMessage(searchQuery ? 'No threads match the search' : 'The heap dump contains no threads');
+            return;
+        }
+
+        const maxRetained = threads.reduce((max, t) => Math.max(max, t.total_retained_size || 0), 0);
+        container.innerHTML = threads.map(thread => {
+            const total = thread.total_retained_size || 0;
+            const width = maxRetained > 0 ? (total / maxRetained) * 100 : 0;
+            const ownWidth = total > 0 ? ((thread.retained_size || 0) / total) * 100 : 0;
+            const isOpen = expanded.has(thread.thread_serial);
+            const className = thread.class_name || 'Thread';
+            const localsCount = (thread.frames || []).reduce((n, f) => n + (f.locals || []).length, 0)
+                + (thread.unattributed_locals || []).length;
+            return `
+                <div class="heap-thread ${isOpen ? 'open' : ''}">
+                    <div class="heap-thread-header" onclick="HeapThreads.toggle(${thread.thread_serial})">
+                        <span class="heap-thread-toggle">${isOpen ? '▼' : '▶'}</span>
+                        <span class="heap-thread-name" title="${Utils.escapeHtml(className)}">
+                            ${Utils.escapeHtml(Utils.getShortClassName(className))}
+                            <span class="text-muted">#${thread.thread_serial}</span>
+                        </span>
+                        ${thread.object_id ? `<a class="heap-thread-id" onclick="event.stopPropagation(); HeapRootPaths.show('${formatId(thread.object_id)}')" title="Paths to GC root">${formatId(thread.object_id)}</a>` : ''}
+                        <span class="heap-thread-meta">${Utils.formatNumber((thread.frames || []).length)} frames · ${Utils.formatNumber(localsCount)} locals</span>
+                        <span class="heap-thread-retained" title="Thread object: ${Utils.formatBytes(thread.retained_size || 0)}, locals: ${Utils.formatBytes(thread.locals_retained_size || 0)}">
+                            <span class="heap-thread-bar" style="width: ${width}%">
+                                <span class="heap-thread-bar-own" style="width: ${ownWidth}%"></span>
+                            </span>
+                            <span class="size-value">${Utils.formatBytes(total)}</span>
+                        </span>
+                    </div>
+                    ${isOpen ? `<div class="heap-thread-body">${renderFrames(thread)}</div>` : ''}
+                </div>
+            `;
+        }).join('');
+    }
+
+    function getCurrentTaskId() {
+        if (typeof App !== 'undefined' && App.getCurrentTask) {
+            const taskId = App.getCurrentTask();
+            if (taskId) return taskId;
+        }
+        const urlParams = new URLSearchParams(window.location.search);
+        return urlParams.get('task') || window.currentTaskId || null;
+    }
+
+    // ============================================
+    // 公共方法
+    // ============================================
+
+    /**
+     * 初始化模块
+     */
+    function init() {
+        HeapCore.on('dataLoaded', function() {
+            currentTaskId = null;
+            overview = null;
+            expanded = new Set();
+        });
+    }
+
+    /**
+     * 面板打开时调用：加载线程概览（同一任务只加载一次）
+     */
+    async function load(taskId) {
+        taskId = taskId || getCurrentTaskId();
+        if (!taskId || isLoading) return;
+        if (taskId === currentTaskId && overview) {
+            renderStats();
+            renderThreads();
+            return;
+        }
+
+        isLoading = true;
+        currentTaskId = taskId;
+        overview = null;
+        expanded = new Set();
+        renderStats();
+        showMessage('<div class="loading-spinner"></div>');
+        try {
+            overview = await API.getHeapThreads(taskId);
+            // 默认展开保留最多的线程
+            if (overview.threads && overview.threads.length > 0) {
+                expanded.add(overview.threads[0].thread_serial);
+            }
+            renderStats();
+            renderThreads();
+        } catch (error) {
+            console.error('[HeapThreads] Failed to load threads:', error);
+            currentTaskId = null;
+            showMessage(`⚠️ Failed to load threads: ${Utils.escapeHtml(error.message)}`);
+        } finally {
+            isLoading = false;
+        }
+    }
+
+    /**
+     * 展开/折叠线程
+     */
+    function toggle(serial) {
+        if (expanded.has(serial)) {
+            expanded.delete(serial);
+        } else {
+            expanded.add(serial);
+        }
+        renderThreads();
+    }
+
+    /**
+     * 按线程类名或栈帧过滤
+     */
+    function search(query) {
+        searchQuery = (query || '').trim().toLowerCase();
+        renderThreads();
+    }
+
+    /**
+     * 展开/折叠全部线程
+     */
+    function expandAll(open) {
+        expanded = new Set(open && overview ? (overview.threads || []).map(t => t.thread_serial) : []);
+        renderThreads();
+    }
+
+    // ============================================
+    // 模块注册
+    // ============================================
+
+    const module = {
+        init,
+        load,
+        toggle,
+        search,
+        expandAll
+    };
+
+    // 自动注册到核心模块
+    if (typeof HeapCore !== 'undefined') {
+        HeapCore.registerModule('threads', module);
+    }
+
+    return module;
+})();
+
+// 导出到全局
+window.HeapThreads = HeapThreads;
@@ -4,7 +4,8 @@
  * 
  * 职责：
  * - 渲染 ECharts Treemap 图表
- * - 处理包级别的数据聚合
+ * - 基于支配树的保留大小 Treemap（/api/domtree/treemap），按包着色，点击可深入子树
+ * - 支配树不可用时回退到按包聚合的 Class Histogram
  * - 响应窗口大小变化
  */
 
@@ -20,6 +21,17 @@ const HeapTreemap = (function() {
     let cachedTopItems = null;  // 缓存数据用于重新渲染
     let cachedTotalSize = 0;
 
+    // 支配树 Treemap 状态
+    let currentTaskId = null;
+    let retainedTree = null;    // 当前根节点的 TreemapNode
+    let rootStack = [];         // 深入路径：[{ id, label }]
+    let isLoading = false;
+
+    const TREEMAP_DEPTH = 4;
+    const PACKAGE_COLORS = ['#5470c6', '#91cc75', '#fac858', '#ee6666', '#73c0de', '#3ba272',
+        '#fc8452', '#9a60b4', '#ea7ccc', '#2f4554', '#c23531', '#61a0a8'];
+    const OTHER_COLOR = '#9ca3af';
+
     // ============================================
     // 私有方法
     // ============================================
@@ -167,6 +179,210 @@ const HeapTreemap = (function() {
         };
     }
 
+    function formatId(objectId) {
+        return '0x' + Number(objectId).toString(16);
+    }
+
+    /**
+     * 包名 -> 固定颜色（同一个包在各层级颜色一致）
+     */
+    function packageColor(pkg) {
+        let hash = 0;
+        for (let i = 0; i < pkg.length; i++) {
+            hash = (hash * 31 + pkg.charCodeAt(i)) | 0;
+        }
+        return PACKAGE_COLORS[Math.abs(hash) % PACKAGE_COLORS.length];
+    }
+
+    /**
+     * TreemapNode -> ECharts 节点
+     */
+    function toChartNode(node) {
+        const isOther = node.other > 0;
+        const label = isOther
+            ? `${Utils.formatNumber(node.other)} more objects`
+            : Utils.getShortClassName(node.class_name);
+        const children = (node.children || []).map(toChartNode);
+        const chartNode = {
+            name: label,
+            value: node.retained_size,
+            node: node,
+            itemStyle: { color: isOther ? OTHER_COLOR : packageColor(node.package || '') }
+        };
+        if (children.length > 0) {
+            // 自身浅大小作为一个子块，保证子块面积之和等于保留大小
+            if (node.shallow_size > 0 && !isOther) {
+                children.push({
+                    name: 'self',
+                    value: node.shallow_size,
+                    node: { ...node, children: null, has_more: false, self: true },
+                    itemStyle: { color: packageColor(node.package || ''), opacity: 0.6 }
+                });
+            }
+            chartNode.children = children;
+        }
+        return chartNode;
+    }
+
+    function retainedTooltip(info) {
+        const node = info.data && info.data.node;
+        if (!node) return '';
+        if (node.other > 0) {
+            return `<div style="max-width: 400px;">
+                <strong>${Utils.formatNumber(node.other)} smaller objects</strong><br/>
+                Retained: ${Utils.formatBytes(node.retained_size)}
+            </div>`;
+        }
+        const total = retainedTree ? retainedTree.retained_size : 0;
+        const percent = total > 0 ? (node.retained_size / total * 100).toFixed(2) : '0.00';
+        return `<div style="max-width: 400px; word-break: break-all;">
+            <strong>${Utils.escapeHtml(node.class_name)}</strong>${node.self ? ' (self)' : ''}<br/>
+            ${node.object_id ? `Object: ${formatId(node.object_id)}<br/>` : ''}
+            Package: ${Utils.escapeHtml(node.package || '')}<br/>
+            Retained: ${Utils.formatBytes(node.retained_size)} (${percent}%)<br/>
+            Shallow: ${Utils.formatBytes(node.shallow_size)}
+            ${node.has_more ? '<br/><em>Click to drill into this subtree</em>' : ''}
+            ${node.object_id ? '<br/><em>Right-click for paths to GC root</em>' : ''}
+        </div>`;
+    }
+
+    /**
+     * 生成支配树 Treemap 的 ECharts 配置
+     */
+    function generateRetainedOption(tree) {
+        return {
+            tooltip: { formatter: retainedTooltip },
+            series: [{
+                type: 'treemap',
+                data: (tree.children || []).map(toChartNode),
+                width: '100%',
+                height: '92%',
+                top: 0,
+                roam: 'move',
+                nodeClick: 'zoomToNode',
+                leafDepth: 2,
+                visibleMin: 300,
+                breadcrumb: { show: true, height: 22, left: 'center', top: 'bottom' },
+                label: {
+                    show: true,
+                    formatter: params => `${params.name}\n${Utils.formatBytes(params.value)}`,
+                    fontSize: 11
+                },
+                upperLabel: { show: true, height: 20, color: '#fff' },
+                itemStyle: { borderColor: '#fff', borderWidth: 1, gapWidth: 1 },
+                levels: [
+                    { itemStyle: { borderColor: '#555', borderWidth: 2, gapWidth: 2 } },
+                    { itemStyle: { borderColorSaturation: 0.7, gapWidth: 1, borderWidth: 1 } }
+                ]
+            }]
+        };
+    }
+
+    /**
+     * 渲染深入路径面包屑
+     */
+    function renderRootPath() {
+        const container = document.getElementById('heapTreemapPath');
+        if (!container) return;
+        const crumbs = [`<a class="domtree-crumb" onclick="HeapTreemap.drillTo(0)">🌐 Heap</a>`];
+        rootStack.forEach((entry, i) => {
+            crumbs.push(`<a class="domtree-crumb" onclick="HeapTreemap.drillTo(${i + 1})">${Utils.escapeHtml(entry.label)}</a>`);
+        });
+        container.innerHTML = crumbs.join('<span class="domtree-crumb-sep">›</span>');
+    }
+
+    function renderLegend() {
+        const container = document.getElementById('heapTreemapLegend');
+        if (!container) return;
+        if (!retainedTree) {
+            container.innerHTML = '';
+            return;
+        }
+        // 统计前两层中各包的保留大小
+        const byPackage = new Map();
+        const visit = (node, depth) => {
+            if (depth > 2) return;
+            (node.children || []).forEach(child => {
+                if (child.other > 0) return;
+                if (depth === 1 || !child.children) {
+                    byPackage.set(child.package, (byPackage.get(child.package) || 0) + child.retained_size);
+                }
+                visit(child, depth + 1);
+            });
+        };
+        visit(retainedTree, 1);
+        container.innerHTML = Array.from(byPackage.entries())
+            .sort((a, b) => b[1] - a[1])
+            .slice(0, 12)
+            .map(([pkg]) => `<span class="heap-treemap-legend-item"><span class="heap-treemap-swatch" style="background: ${packageColor(pkg)}"></span>${Utils.escapeHtml(pkg)}</span>`)
+            .join('');
+    }
+
+    function showMessage(html) {
+        const container = document.getElementById('heapTreemapMessage');
+        if (container) {
+            container.innerHTML = html;
+            container.style.display = html ? '' : 'none';
+        }
+    }
+
+    /**
+     * 渲染支配树 Treemap
+     */
+    function renderRetained() {
+        if (!containerElement) {
+            containerElement = document.getElementById('heapTreemap');
+        }
+        if (!containerElement || !retainedTree || containerElement.clientWidth === 0) return;
+
+        if (treemapChart) {
+            treemapChart.dispose();
+        }
+        treemapChart = echarts.init(containerElement);
+        treemapChart.setOption(generateRetainedOption(retainedTree));
+        treemapChart.on('click', params => {
+            const node = params.data && params.data.node;
+            if (node && node.has_more && node.object_id) {
+                drillInto(node);
+            }
+        });
+        treemapChart.on('contextmenu', params => {
+            const node = params.data && params.data.node;
+            if (node && node.object_id && typeof HeapRootPaths !== 'undefined') {
+                params.event.event.preventDefault();
+                HeapRootPaths.show(formatId(node.object_id));
+            }
+        });
+        renderRootPath();
+        renderLegend();
+    }
+
+    /**
+     * 加载以 objectId 为根的支配树 Treemap（空 = 整个堆）
+     */
+    async function fetchRetained(objectId) {
+        if (isLoading) return;
+        isLoading = true;
+        showMessage('<div class="loading-spinner"></div>');
+        try {
+            retainedTree = await API.getHeapTreemap(currentTaskId, objectId ? formatId(objectId) : '', TREEMAP_DEPTH);
+            showMessage('');
+            renderRetained();
+        } catch (error) {
+            console.warn('[HeapTreemap] Dominator tree treemap unavailable:', error);
+            retainedTree = null;
+            showMessage(`⚠️ Retained-size treemap unavailable (${Utils.escapeHtml(error.message)}); showing the class histogram by package instead`);
+            render(null, cachedTotalSize);
+        } finally {
+            isLoading = false;
+        }
+    }
+
+    function drillInto(node) {
+        rootStack.push({ id: node.object_id, label: `${Utils.getShortClassName(node.class_name)}@${formatId(node.object_id)}` });
+        fetchRetained(node.object_id);
+    }
+
     /**
      * 处理窗口大小变化
      */
@@ -191,6 +407,9 @@ const HeapTreemap = (function() {
         
         // 监听数据加载事件
         HeapCore.on('dataLoaded', function(data) {
+            currentTaskId = null;
+            retainedTree = null;
+            rootStack = [];
             if (data.topItems && data.topItems.length > 0) {
                 render(data.topItems, data.heapData.total_heap_size || 0);
             }
@@ -264,12 +483,38 @@ const HeapTreemap = (function() {
         // 如果图表已存在，只需 resize
         if (treemapChart) {
             treemapChart.resize();
+        } else if (retainedTree) {
+            renderRetained();
         } else if (cachedTopItems) {
             // 图表不存在但有缓存数据，重新渲染
             render(null, cachedTotalSize);
         }
     }
 
+    /**
+     * 面板打开时调用：加载支配树 Treemap（同一任务只加载一次）
+     */
+    function load(taskId) {
+        taskId = taskId || (typeof App !== 'undefined' && App.getCurrentTask ? App.getCurrentTask() : null);
+        if (!taskId) return;
+        if (taskId === currentTaskId && retainedTree) {
+            renderRetained();
+            return;
+        }
+        currentTaskId = taskId;
+        rootStack = [];
+        fetchRetained(0);
+    }
+
+    /**
+     * 回到深入路径中的某一层（0 = 整个堆）
+     */
+    function drillTo(level) {
+        rootStack = rootStack.slice(0, level);
+        const entry = rootStack[rootStack.length - 1];
+        fetchRetained(entry ? entry.id : 0);
+    }
+
     /**
      * 销毁模块
      */
@@ -296,6 +541,8 @@ const HeapTreemap = (function() {
     
     const module = {
         init,
+        load,
+        drillTo,
         render,
         resize,
         destroy,
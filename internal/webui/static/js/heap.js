@@ -7,8 +7,16 @@
  * - HeapTreemap: Treemap 可视化
  * - HeapBiggestObjects: 最大对象分析
  * - HeapHistogram: Class Histogram 表格
+ * - HeapClasses: 全量类列表（服务端分页、虚拟滚动）
  * - HeapGCRoots: GC Roots 分析
  * - HeapMergedPaths: Merged Paths 分析（IDEA 风格）
+ * - HeapDomTree: Dominator Tree 浏览（MAT 风格）
+ * - HeapRootPaths: Paths to GC Root 查看
+ * - HeapDiff: 两个任务的堆对比
+ * - HeapQuery: OQL 查询控制台
+ * - HeapThreads: 线程栈与栈上局部变量
+ * - HeapOwnership: 按团队的内存归属
+ * - HeapInspector: 对象字段值查看器
  * 
  * 设计原则：
  * - 门面模式：提供统一的简化接口
@@ -32,7 +40,7 @@ const HeapAnalysis = (function() {
         
         // 子模块会在加载时自动注册到核心模块
         console.log('[HeapAnalysis] Initialized with modules:', 
-            Array.from(['treemap', 'biggestObjects', 'histogram', 'gcroots', 'mergedPaths'])
+            Array.from(['treemap', 'biggestObjects', 'histogram', 'classes', 'gcroots', 'mergedPaths', 'domtree', 'rootPaths', 'diff', 'query', 'threads', 'ownership', 'inspector'])
                 .filter(name => HeapCore.getModule(name))
                 .join(', ')
         );
@@ -0,0 +1,190 @@
+/**
+ * Log Tail Module
+ * 分析日志面板：通过 WebSocket 实时跟踪任务的分析日志（analysis.log）
+ *
+ * 职责：
+ * - 连接 /api/logs/stream，先显示最近的日志行，再追加新行
+ * - 按级别着色，支持关键字过滤与自动滚动
+ * - 连接意外断开时自动重连
+ */
+
+const LogTail = (function() {
+    'use strict';
+
+    // ============================================
+    // 私有状态
+    // ============================================
+
+    let taskId = null;
+    let socket = null;
+    let lines = [];
+    let filterText = '';
+    let reconnectTimer = null;
+
+    const MAX_LINES = 5000;         // 面板中保留的最大行数
+    const RECONNECT_DELAY = 3000;
+
+    const LEVEL_CLASSES = {
+        '[ERROR]': 'log-line-error',
+        '[WARN]': 'log-line-warn',
+        '[DEBUG]': 'log-line-debug'
+    };
+
+    // ============================================
+    // 私有方法
+    // ============================================
+
+    function setStatus(text, state) {
+        const status = document.getElementById('logTailStatus');
+        if (!status) return;
+        status.textContent = text;
+        status.className = `log-tail-status log-tail-status-${state}`;
+    }
+
+    function lineClass(line) {
+        for (const [marker, cls] of Object.entries(LEVEL_CLASSES)) {
+            if (line.includes(marker)) return cls;
+        }
+        return '';
+    }
+
+    function renderLine(line) {
+        return `<div class="log-line ${lineClass(line)}">${Utils.escapeHtml(line) || '&nbsp;'}</div>`;
+    }
+
+    function matches(line) {
+        return !filterText || line.toLowerCase().includes(filterText);
+    }
+
+    function isFollowing() {
+        const follow = document.getElementById('logTailFollow');
+        return !follow || follow.checked;
+    }
+
+    function scrollToEnd() {
+        const output = document.getElementById('logTailOutput');
+        if (output && isFollowing()) output.scrollTop = output.scrollHeight;
+    }
+
+    function render() {
+        const output = document.getElementById('logTailOutput');
+        if (!output) return;
+        const visible = lines.filter(matches);
+        output.innerHTML = visible.length > 0
+            ? visible.map(renderLine).join('')
+            : `<div class="text-muted">${lines.length > 0 ? 'No lines match the filter' : 'No log lines yet'}</div>`;
+        scrollToEnd();
+    }
+
+    /**
+     * 追加一批日志行（超出上限时丢弃最旧的行）
+     */
+    function append(event) {
+        if (event.reset) lines = [];
+        if (event.missing) {
+            setStatus('Waiting for log', 'waiting');
+        } else if (socket && socket.readyState === WebSocket.OPEN) {
+            setStatus('Live', 'live');
+        }
+
+        const wasEmpty = lines.length === 0;
+        lines.push(...(event.lines || []));
+        if (event.reset || wasEmpty || lines.length > MAX_LINES) {
+            if (lines.length > MAX_LINES) lines = lines.slice(lines.length - MAX_LINES);
+            render();
+            return;
+        }
+
+        const output = document.getElementById('logTailOutput');
+        if (!output) return;
+        const html = (event.lines || []).filter(matches).map(renderLine).join('');
+        if (html) {
+            output.insertAdjacentHTML('beforeend', html);
+            scrollToEnd();
+        }
+    }
+
+    function connect() {
+        clearTimeout(reconnectTimer);
+        const current = taskId;
+        setStatus('Connecting', 'waiting');
+        const ws = API.watchLogs(current, append);
+        socket = ws;
+        ws.addEventListener('open', () => setStatus('Live', 'live'));
+        ws.addEventListener('close', () => {
+            // 仅当该连接仍是当前连接时自动重连（关闭或切换任务后忽略）
+            if (socket !== ws) return;
+            socket = null;
+            setStatus('Disconnected, reconnecting', 'error');
+            reconnectTimer = setTimeout(() => {
+                if (taskId === current && !socket) connect();
+            }, RECONNECT_DELAY);
+        });
+    }
+
+    // ============================================
+    // 公共方法
+    // ============================================
+
+    /**
+     * 打开某个任务的日志（已在跟踪同一任务时不重复连接）
+     */
+    function open(newTaskId) {
+        if (!newTaskId) return;
+        if (newTaskId === taskId && socket) return;
+        close();
+        taskId = newTaskId;
+        lines = [];
+        render();
+        connect();
+    }
+
+    /**
+     * 断开连接
+     */
+    function close() {
+        clearTimeout(reconnectTimer);
+        const current = socket;
+        taskId = null;
+        socket = null;
+        if (current) current.close();
+        setStatus('Disconnected', 'idle');
+    }
+
+    /**
+     * 按关键字过滤日志行
+     */
+    function filter(text) {
+        filterText = (text || '').trim().toLowerCase();
+        render();
+    }
+
+    /**
+     * 清空面板（不影响日志文件）
+     */
+    function clear() {
+        lines = [];
+        render();
+    }
+
+    /**
+     * 重新连接当前任务
+     */
+    function reconnect() {
+        const current = taskId;
+        close();
+        open(current);
+    }
+
+    return {
+        open,
+        close,
+        filter,
+        clear,
+        reconnect,
+        scrollToEnd
+    };
+})();
+
+// 导出到全局
+window.LogTail = LogTail;
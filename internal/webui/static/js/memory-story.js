@@ -0,0 +1,249 @@
+/**
+ * Memory Story Module
+ * 内存全景模块：把同一任务的 CPU profile、分配 profile 和堆转储关联到一个页面
+ *
+ * 职责：
+ * - 从 /api/memory-story 加载关联结果
+ * - 分配热点：分配栈 → 分配的类在堆中的实例/保留大小 → 分配方法的 CPU 占比
+ * - 类视图：同时占据堆和分配量的类
+ * - CPU 热点：按自身 CPU 时间排序，附带其调用树下的分配量
+ */
+
+const MemoryStory = (function() {
+    'use strict';
+
+    // ============================================
+    // 私有状态
+    // ============================================
+
+    let currentTaskId = null;
+    let data = null;
+    let expanded = new Set();       // 已展开调用栈的分配热点序号
+    let isLoading = false;
+
+    // 堆占比和分配占比都超过该阈值的类视为可疑
+    const SUSPECT_PCT = 5;
+
+    // ============================================
+    // 私有方法
+    // ============================================
+
+    function setHtml(id, html) {
+        const el = document.getElementById(id);
+        if (el) el.innerHTML = html;
+    }
+
+    function showMessage(html) {
+        setHtml('memoryStorySources', '');
+        setHtml('memoryStorySites', `<div class="text-center py-10 text-muted">${html}</div>`);
+        setHtml('memoryStoryClasses', '');
+        setHtml('memoryStoryHotspots', '');
+    }
+
+    function formatAllocated(value) {
+        if (data.allocation_unit === 'bytes') return Utils.formatBytes(value);
+        return `${Utils.formatNumber(value)} samples`;
+    }
+
+    function pct(value) {
+        return `${(value || 0).toFixed(2)}%`;
+    }
+
+    function bar(value, cls) {
+        const width = Math.min(100, Math.max(0, value || 0));
+        return `<div class="memory-story-bar"><span class="${cls}" style="width: ${width}%"></span><span class="relative">${pct(value)}</span></div>`;
+    }
+
+    function renderSources() {
+        const source = (has, label) => `<span class="memory-story-source ${has ? 'present' : 'missing'}">${has ? '✅' : '➖'} ${label}</span>`;
+        setHtml('memoryStorySources', `
+            ${source(data.has_alloc, 'Allocation profile')}
+            ${source(data.has_heap, 'Heap dump')}
+            ${source(data.has_cpu, 'CPU profile')}
+        `);
+    }
+
+    function renderSites() {
+        const sites = data.sites || [];
+        if (!data.has_alloc) {
+            setHtml('memoryStorySites', '<div class="text-sm text-muted">The task has no allocation profile</div>');
+            return;
+        }
+        if (sites.length === 0) {
+            setHtml('memoryStorySites', '<div class="text-sm text-muted">No allocation site found</div>');
+            return;
+        }
+        setHtml('memoryStorySites', `
+            <table class="w-full text-sm">
+                <thead>
+                    <tr class="text-left text-xs text-muted uppercase">
+                        <th class="py-2">Allocating method → class</th>
+                        <th class="py-2 w-48">Allocated</th>
+                        ${data.has_heap ? '<th class="py-2 text-right">Live instances</th><th class="py-2 text-right">Retained</th>' : ''}
+                        ${data.has_cpu ? '<th class="py-2 w-40">CPU (method)</th>' : ''}
+                    </tr>
+                </thead>
+                <tbody>
+                    ${sites.map((site, i) => {
+                        const isOpen = expanded.has(i);
+                        const heap = site.heap;
+                        return `
+                            <tr class="border-t border-theme memory-story-site" onclick="MemoryStory.toggle(${i})">
+                                <td class="py-2 font-mono">
+                                    <span class="text-muted">${isOpen ? '▼' : '▶'}</span>
+                                    <span title="${Utils.escapeHtml(site.frame)}">${Utils.escapeHtml(site.frame)}</span>
+                                    <span class="text-muted">→</span>
+                                    <span class="font-semibold" title="${Utils.escapeHtml(site.class)}">${Utils.escapeHtml(Utils.getShortClassName(site.class))}</span>
+                                </td>
+                                <td class="py-2" title="${Utils.escapeHtml(formatAllocated(site.allocated))}">${bar(site.percentage, 'memory-story-bar-alloc')}</td>
+                                ${data.has_heap ? (heap
+                                    ? `<td class="py-2 text-right">${Utils.formatNumber(heap.instance_count)}</td>
+                                       <td class="py-2 text-right">${Utils.formatBytes(heap.retained_size || heap.shallow_size || heap.total_size)}</td>`
+                                    : '<td class="py-2 text-right text-muted" colspan="2">not in heap</td>') : ''}
+                                ${data.has_cpu ? `<td class="py-2" title="self ${pct(site.cpu_self_pct)}">${bar(site.cpu_total_pct, 'memory-story-bar-cpu')}</td>` : ''}
+                            </tr>
+                            ${isOpen ? `
+                                <tr>
+                                    <td colspan="5" class="pb-3">
+                                        <div class="heap-thread-body">
+                                            ${(site.stack || []).slice().reverse().map(frame => `
+                                                <div class="heap-thread-frame-line">
+                                                    <span class="heap-thread-frame-text">at ${Utils.escapeHtml(frame)}</span>
+                                                </div>
+                                            `).join('')}
+                                        </div>
+                                    </td>
+                                </tr>
+                            ` : ''}
+                        `;
+                    }).join('')}
+                </tbody>
+            </table>
+        `);
+    }
+
+    function renderClasses() {
+        const classes = data.classes || [];
+        if (classes.length === 0) {
+            setHtml('memoryStoryClasses', '<div class="text-sm text-muted">No class to show</div>');
+            return;
+        }
+        setHtml('memoryStoryClasses', `
+            <table class="w-full text-sm">
+                <thead>
+                    <tr class="text-left text-xs text-muted uppercase">
+                        <th class="py-2">Class</th>
+                        ${data.has_heap ? '<th class="py-2 w-40">Heap</th>' : ''}
+                        ${data.has_alloc ? '<th class="py-2 w-40">Allocated</th>' : ''}
+                        ${data.has_alloc ? '<th class="py-2">Top allocating methods</th>' : ''}
+                    </tr>
+                </thead>
+                <tbody>
+                    ${classes.map(c => {
+                        const suspect = data.has_heap && data.has_alloc && c.heap_pct >= SUSPECT_PCT && c.allocation_pct >= SUSPECT_PCT;
+                        return `
+                            <tr class="border-t border-theme ${suspect ? 'memory-story-suspect' : ''}">
+                                <td class="py-2 font-mono" title="${Utils.escapeHtml(c.class_name)}">
+                                    ${suspect ? '<span title="Large in the heap and heavily allocated">⚠️</span>' : ''}
+                                    ${Utils.escapeHtml(Utils.getShortClassName(c.class_name))}
+                                </td>
+                                ${data.has_heap ? `<td class="py-2" title="${Utils.formatNumber(c.instance_count)} instances, ${Utils.formatBytes(c.retained_size || c.shallow_size)}">${bar(c.heap_pct, 'memory-story-bar-heap')}</td>` : ''}
+                                ${data.has_alloc ? `<td class="py-2" title="${Utils.escapeHtml(formatAllocated(c.allocated))}">${bar(c.allocation_pct, 'memory-story-bar-alloc')}</td>` : ''}
+                                ${data.has_alloc ? `<td class="py-2 font-mono text-xs text-muted">${(c.top_sites || []).map(Utils.escapeHtml).join('<br>')}</td>` : ''}
+                            </tr>
+                        `;
+                    }).join('')}
+                </tbody>
+            </table>
+        `);
+    }
+
+    function renderHotspots() {
+        const hotspots = data.hotspots || [];
+        if (!data.has_cpu) {
+            setHtml('memoryStoryHotspots', '<div class="text-sm text-muted">The task has no CPU profile</div>');
+            return;
+        }
+        setHtml('memoryStoryHotspots', `
+            <table class="w-full text-sm">
+                <thead>
+                    <tr class="text-left text-xs text-muted uppercase">
+                        <th class="py-2">Function</th>
+                        <th class="py-2 w-40">CPU self</th>
+                        <th class="py-2 text-right">CPU total</th>
+                        ${data.has_alloc ? '<th class="py-2 w-40">Allocated below</th>' : ''}
+                    </tr>
+                </thead>
+                <tbody>
+                    ${hotspots.map(h => `
+                        <tr class="border-t border-theme">
+                            <td class="py-2 font-mono" title="${Utils.escapeHtml(h.name)}">${Utils.escapeHtml(h.name)}</td>
+                            <td class="py-2">${bar(h.cpu_self_pct, 'memory-story-bar-cpu')}</td>
+                            <td class="py-2 text-right">${pct(h.cpu_total_pct)}</td>
+                            ${data.has_alloc ? `<td class="py-2" title="${Utils.escapeHtml(formatAllocated(h.allocated || 0))}">${bar(h.allocation_pct, 'memory-story-bar-alloc')}</td>` : ''}
+                        </tr>
+                    `).join('')}
+                </tbody>
+            </table>
+        `);
+    }
+
+    function render() {
+        renderSources();
+        renderSites();
+        renderClasses();
+        renderHotspots();
+    }
+
+    // ============================================
+    // 公共方法
+    // ============================================
+
+    /**
+     * 面板打开时调用：加载关联结果（同一任务只加载一次）
+     */
+    async function load(taskId) {
+        if (!taskId || isLoading) return;
+        if (taskId === currentTaskId && data) {
+            render();
+            return;
+        }
+
+        isLoading = true;
+        currentTaskId = taskId;
+        data = null;
+        expanded = new Set();
+        showMessage('<div class="loading-spinner"></div>');
+        try {
+            data = await API.getMemoryStory(taskId);
+            render();
+        } catch (error) {
+            console.error('[MemoryStory] Failed to load memory story:', error);
+            currentTaskId = null;
+            showMessage(`⚠️ Failed to load memory story: ${Utils.escapeHtml(error.message)}`);
+        } finally {
+            isLoading = false;
+        }
+    }
+
+    /**
+     * 展开/折叠分配热点的调用栈
+     */
+    function toggle(index) {
+        if (expanded.has(index)) {
+            expanded.delete(index);
+        } else {
+            expanded.add(index);
+        }
+        renderSites();
+    }
+
+    // ============================================
+    // 导出公共接口
+    // ============================================
+
+    return {
+        load,
+        toggle
+    };
+})();
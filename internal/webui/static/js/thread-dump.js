@@ -0,0 +1,289 @@
+/**
+ * Thread Dump Module
+ * 线程转储模块：展示 jstack / JFR 线程转储的分析结果
+ *
+ * 职责：
+ * - 从 /api/threaddump 加载分析结果
+ * - 展示线程状态分布、死锁环、热点锁（按等待线程数排序）
+ * - 按名称、状态或栈帧过滤最新一次转储的线程，展开查看调用栈
+ */
+
+const ThreadDump = (function() {
+    'use strict';
+
+    // ============================================
+    // 私有状态
+    // ============================================
+
+    let currentTaskId = null;
+    let data = null;
+    let expanded = new Set();       // 已展开的线程名
+    let searchQuery = '';
+    let stateFilter = '';
+    let isLoading = false;
+
+    // 线程状态颜色
+    const STATE_CLASSES = {
+        'RUNNABLE': 'thread-dump-state-runnable',
+        'BLOCKED': 'thread-dump-state-blocked',
+        'WAITING': 'thread-dump-state-waiting',
+        'TIMED_WAITING': 'thread-dump-state-timed',
+        'NEW': 'thread-dump-state-other',
+        'TERMINATED': 'thread-dump-state-other',
+        'UNKNOWN': 'thread-dump-state-other'
+    };
+
+    // ============================================
+    // 私有方法
+    // ============================================
+
+    function stateClass(state) {
+        return STATE_CLASSES[state] || 'thread-dump-state-other';
+    }
+
+    function setHtml(id, html) {
+        const el = document.getElementById(id);
+        if (el) el.innerHTML = html;
+    }
+
+    function showMessage(html) {
+        setHtml('threadDumpThreads', `<div class="text-center py-10 text-muted">${html}</div>`);
+    }
+
+    /**
+     * 线程状态分布：按线程数降序的堆叠条 + 可点击的图例（点击按状态过滤）
+     */
+    function renderStates() {
+        const states = Object.entries(data.states || {}).sort((a, b) => b[1] - a[1] || a[0].localeCompare(b[0]));
+        const total = data.total_threads || 0;
+        const bar = states.map(([state, count]) => {
+            const width = total > 0 ? (count / total) * 100 : 0;
+            return `<span class="${stateClass(state)}" style="width: ${width}%" title="${Utils.escapeHtml(state)}: ${count}"></span>`;
+        }).join('');
+        const legend = states.map(([state, count]) => `
+            <button class="thread-dump-legend ${stateFilter === state ? 'active' : ''}" onclick="ThreadDump.filterState('${Utils.escapeHtml(state)}')">
+                <span class="thread-dump-swatch ${stateClass(state)}"></span>
+                ${Utils.escapeHtml(state)} <span class="font-semibold">${Utils.formatNumber(count)}</span>
+            </button>
+        `).join('');
+
+        setHtml('threadDumpStats', `${Utils.formatNumber(data.dump_count || 0)} dumps · ${Utils.formatNumber(total)} threads in the latest`);
+        setHtml('threadDumpStates', `
+            <div class="thread-dump-state-bar">${bar}</div>
+            <div class="flex flex-wrap gap-2 mt-3">${legend}</div>
+        `);
+    }
+
+    function renderDeadlocks() {
+        const deadlocks = data.deadlocks || [];
+        if (deadlocks.length === 0) {
+            setHtml('threadDumpDeadlocks', '<div class="text-sm text-muted">✅ No deadlock found</div>');
+            return;
+        }
+        setHtml('threadDumpDeadlocks', deadlocks.map((d, i) => `
+            <div class="thread-dump-deadlock">
+                <div class="text-sm font-semibold text-danger mb-2">
+                    💀 Deadlock ${i + 1} · ${d.threads.length} threads · found in ${d.dump_count} of ${data.dump_count} dumps
+                </div>
+                ${d.threads.map(t => `
+                    <div class="thread-dump-deadlock-edge">
+                        <a class="font-mono text-base" data-thread="${Utils.escapeHtml(t.name)}" onclick="ThreadDump.reveal(this.dataset.thread)">"${Utils.escapeHtml(t.name)}"</a>
+                        <span class="text-muted">waits for</span>
+                        <span class="font-mono" title="${Utils.escapeHtml(t.lock_class || '')}">${Utils.escapeHtml(Utils.getShortClassName(t.lock_class || 'lock'))} &lt;${Utils.escapeHtml(t.lock_address)}&gt;</span>
+                        <span class="text-muted">held by</span>
+                        <a class="font-mono text-base" data-thread="${Utils.escapeHtml(t.held_by)}" onclick="ThreadDump.reveal(this.dataset.thread)">"${Utils.escapeHtml(t.held_by)}"</a>
+                    </div>
+                `).join('')}
+            </div>
+        `).join(''));
+    }
+
+    function renderMonitors() {
+        const monitors = data.hot_monitors || [];
+        if (monitors.length === 0) {
+            setHtml('threadDumpMonitors', '<div class="text-sm text-muted">No thread is blocked on a lock</div>');
+            return;
+        }
+        const maxWaiters = monitors.reduce((max, m) => Math.max(max, m.max_waiters || 0), 0);
+        setHtml('threadDumpMonitors', `
+            <table class="w-full text-sm">
+                <thead>
+                    <tr class="text-left text-xs text-muted uppercase">
+                        <th class="py-2">Lock</th>
+                        <th class="py-2">Owner</th>
+                        <th class="py-2 w-64">Waiters</th>
+                        <th class="py-2 text-right">Dumps</th>
+                    </tr>
+                </thead>
+                <tbody>
+                    ${monitors.map(m => `
+                        <tr class="border-t border-theme" title="${Utils.escapeHtml((m.waiter_threads || []).join(', '))}">
+                            <td class="py-2 font-mono">
+                                <span title="${Utils.escapeHtml(m.class || '')}">${Utils.escapeHtml(Utils.getShortClassName(m.class || 'lock'))}</span>
+                                <span class="text-muted">&lt;${Utils.escapeHtml(m.address)}&gt;</span>
+                            </td>
+                            <td class="py-2 font-mono">${m.owner
+                                ? `<a data-thread="${Utils.escapeHtml(m.owner)}" onclick="ThreadDump.reveal(this.dataset.thread)">${Utils.escapeHtml(m.owner)}</a>`
+                                : '<span class="text-muted">unknown</span>'}</td>
+                            <td class="py-2">
+                                <div class="thread-dump-waiters">
+                                    <span class="thread-dump-waiters-bar" style="width: ${maxWaiters > 0 ? (m.max_waiters / maxWaiters) * 100 : 0}%"></span>
+                                    <span class="relative">${m.waiters} <span class="text-muted">(max ${m.max_waiters})</span></span>
+                                </div>
+                            </td>
+                            <td class="py-2 text-right">${m.dump_count}</td>
+                        </tr>
+                    `).join('')}
+                </tbody>
+            </table>
+        `);
+    }
+
+    function matches(thread) {
+        if (stateFilter && thread.state !== stateFilter) return false;
+        if (!searchQuery) return true;
+        if (thread.name.toLowerCase().includes(searchQuery)) return true;
+        return (thread.stack || []).some(f => f.toLowerCase().includes(searchQuery));
+    }
+
+    function renderThreads() {
+        const threads = (data.threads || []).filter(matches);
+        if (threads.length === 0) {
+            showMessage(searchQuery || stateFilter ? 'No threads match the filter' : 'The thread dump contains no threads');
+            return;
+        }
+
+        setHtml('threadDumpThreads', threads.map(thread => {
+            const isOpen = expanded.has(thread.name);
+            // 调用栈按 jstack 的顺序展示：最内层在上
+            const frames = (thread.stack || []).slice().reverse();
+            return `
+                <div class="heap-thread ${isOpen ? 'open' : ''}" data-thread="${Utils.escapeHtml(thread.name)}">
+                    <div class="heap-thread-header" onclick="ThreadDump.toggle(this.parentElement.dataset.thread)">
+                        <span class="heap-thread-toggle">${isOpen ? '▼' : '▶'}</span>
+                        <span class="heap-thread-name">
+                            ${Utils.escapeHtml(thread.name)}
+                            ${thread.id ? `<span class="text-muted">#${thread.id}</span>` : ''}
+                            ${thread.daemon ? '<span class="text-muted">daemon</span>' : ''}
+                        </span>
+                        <span class="thread-dump-state ${stateClass(thread.state)}">${Utils.escapeHtml(thread.state)}</span>
+                        <span class="heap-thread-meta">
+                            ${Utils.escapeHtml(thread.state_detail || '')}
+                            ${thread.blocked_on ? ` · blocked on &lt;${Utils.escapeHtml(thread.blocked_on)}&gt;` : ''}
+                            ${(thread.locked || []).length > 0 ? ` · holds ${thread.locked.length} lock${thread.locked.length > 1 ? 's' : ''}` : ''}
+                        </span>
+                        <span class="heap-thread-meta ml-auto">${Utils.formatNumber(frames.length)} frames</span>
+                    </div>
+                    ${isOpen ? `
+                        <div class="heap-thread-body">
+                            ${frames.length === 0 ? '<div class="heap-thread-empty">No Java stack</div>' : ''}
+                            ${frames.map(frame => `
+                                <div class="heap-thread-frame-line">
+                                    <span class="heap-thread-frame-text">at ${Utils.escapeHtml(frame)}</span>
+                                </div>
+                            `).join('')}
+                        </div>
+                    ` : ''}
+                </div>
+            `;
+        }).join(''));
+    }
+
+    function render() {
+        renderStates();
+        renderDeadlocks();
+        renderMonitors();
+        renderThreads();
+    }
+
+    // ============================================
+    // 公共方法
+    // ============================================
+
+    /**
+     * 面板打开时调用：加载分析结果（同一任务只加载一次）
+     */
+    async function load(taskId) {
+        if (!taskId || isLoading) return;
+        if (taskId === currentTaskId && data) {
+            render();
+            return;
+        }
+
+        isLoading = true;
+        currentTaskId = taskId;
+        data = null;
+        expanded = new Set();
+        stateFilter = '';
+        showMessage('<div class="loading-spinner"></div>');
+        try {
+            data = await API.getThreadDump(taskId);
+            // 默认展开死锁中的线程
+            (data.deadlocks || []).forEach(d => d.threads.forEach(t => expanded.add(t.name)));
+            render();
+        } catch (error) {
+            console.error('[ThreadDump] Failed to load thread dump:', error);
+            currentTaskId = null;
+            showMessage(`⚠️ Failed to load thread dump: ${Utils.escapeHtml(error.message)}`);
+        } finally {
+            isLoading = false;
+        }
+    }
+
+    /**
+     * 展开/折叠线程
+     */
+    function toggle(name) {
+        if (expanded.has(name)) {
+            expanded.delete(name);
+        } else {
+            expanded.add(name);
+        }
+        renderThreads();
+    }
+
+    /**
+     * 展开并滚动到线程（死锁、热点锁中的线程链接）
+     */
+    function reveal(name) {
+        searchQuery = '';
+        stateFilter = '';
+        const input = document.getElementById('threadDumpSearch');
+        if (input) input.value = '';
+        expanded.add(name);
+        renderStates();
+        renderThreads();
+        const el = Array.from(document.querySelectorAll('#threadDumpThreads .heap-thread'))
+            .find(node => node.dataset.thread === name);
+        if (el) el.scrollIntoView({ behavior: 'smooth', block: 'center' });
+    }
+
+    /**
+     * 按线程名或栈帧过滤
+     */
+    function search(query) {
+        searchQuery = (query || '').trim().toLowerCase();
+        renderThreads();
+    }
+
+    /**
+     * 按状态过滤，再次点击同一状态取消过滤
+     */
+    function filterState(state) {
+        stateFilter = stateFilter === state ? '' : state;
+        renderStates();
+        renderThreads();
+    }
+
+    // ============================================
+    // 导出公共接口
+    // ============================================
+
+    return {
+        load,
+        toggle,
+        reveal,
+        search,
+        filterState
+    };
+})();
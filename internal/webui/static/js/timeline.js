@@ -0,0 +1,290 @@
+/**
+ * Timeline Module
+ * 时间线模块：带时间戳的 profile（JFR、perf script）按时间窗口查看火焰图
+ *
+ * 职责：
+ * - 从 /api/timeline 加载 Top 函数随时间变化的序列，绘制堆叠面积图
+ * - 时间范围选择器（图表下方滑块）选择时间窗口
+ * - 从 /api/flamegraph/window 加载所选窗口的火焰图
+ */
+
+const Timeline = (function() {
+    'use strict';
+
+    // ============================================
+    // 私有状态
+    // ============================================
+
+    let currentTaskId = null;
+    let profile = 'cpu';
+    let profiles = [];
+    let data = null;
+    let chart = null;
+    let windowRange = null;         // 当前窗口 [from, to]，单位秒
+    let windowTimer = null;
+    let windowRequest = 0;          // 丢弃过期的窗口请求
+    let isLoading = false;
+
+    // 选择范围后加载火焰图的延迟（毫秒）
+    const WINDOW_DELAY = 300;
+
+    const PROFILE_LABELS = {
+        cpu: '🔥 CPU',
+        memory: '💾 Allocation',
+        lock: '🔒 Lock'
+    };
+
+    // ============================================
+    // 私有方法
+    // ============================================
+
+    function setHtml(id, html) {
+        const el = document.getElementById(id);
+        if (el) el.innerHTML = html;
+    }
+
+    function isDark() {
+        return document.documentElement.getAttribute('data-theme') === 'dark';
+    }
+
+    function formatValue(value) {
+        if (!data) return Utils.formatNumber(value);
+        if (data.unit === 'ns') return Utils.formatDuration(Math.round(value / 1e6));
+        if (profile === 'memory') return Utils.formatBytes(value);
+        return `${Utils.formatNumber(value)} samples`;
+    }
+
+    function formatSeconds(seconds) {
+        return `${seconds.toFixed(seconds < 10 ? 1 : 0)}s`;
+    }
+
+    // 图例中的函数名只保留最后一段路径，如 com/example/Service.handle → Service.handle
+    function shortName(name) {
+        const parts = name.split('/');
+        return parts[parts.length - 1];
+    }
+
+    function renderProfiles() {
+        setHtml('timelineProfiles', profiles.map(p => `
+            <button onclick="Timeline.setProfile('${p}')"
+                class="px-3 py-1.5 rounded-lg text-xs font-medium border border-theme transition-colors ${p === profile ? 'bg-primary text-white' : 'bg-elevated text-base hover:bg-muted'}">
+                ${PROFILE_LABELS[p] || p}
+            </button>
+        `).join(''));
+    }
+
+    function renderRange() {
+        if (!data || !windowRange) {
+            setHtml('timelineRange', '');
+            return;
+        }
+        const [from, to] = windowRange;
+        const full = from <= 0 && to >= data.duration_seconds;
+        setHtml('timelineRange', `
+            <span class="font-medium">⏱️ Window:</span>
+            <span class="font-mono">${formatSeconds(from)} – ${formatSeconds(to)}</span>
+            <span class="text-muted">(${full ? 'whole profile' : formatSeconds(to - from)})</span>
+        `);
+    }
+
+    function renderChart() {
+        const container = document.getElementById('timelineChart');
+        if (!container || !data) return;
+        if (chart) chart.dispose();
+        chart = echarts.init(container);
+
+        const resolution = data.resolution / 1e9;
+        const times = data.totals.map((_, i) => formatSeconds(i * resolution));
+        const functions = data.functions || [];
+
+        // 其余函数：总量减去 Top 函数之和
+        const others = data.totals.map((total, i) =>
+            total - functions.reduce((sum, f) => sum + f.values[i], 0));
+
+        const series = functions.map(f => ({
+            name: f.name,
+            type: 'line',
+            stack: 'total',
+            areaStyle: {},
+            symbol: 'none',
+            lineStyle: { width: 0.5 },
+            emphasis: { focus: 'series' },
+            data: f.values
+        }));
+        series.push({
+            name: 'Others',
+            type: 'line',
+            stack: 'total',
+            areaStyle: { color: isDark() ? '#4b5563' : '#d1d5db' },
+            itemStyle: { color: isDark() ? '#4b5563' : '#d1d5db' },
+            symbol: 'none',
+            lineStyle: { width: 0.5 },
+            data: others
+        });
+
+        const textColor = isDark() ? '#9ca3af' : '#666';
+        chart.setOption({
+            tooltip: {
+                trigger: 'axis',
+                confine: true,
+                formatter: function(params) {
+                    const rows = params
+                        .filter(p => p.value > 0)
+                        .sort((a, b) => b.value - a.value)
+                        .slice(0, 12)
+                        .map(p => `${p.marker}<span style="font-family: monospace; font-size: 11px;">${Utils.escapeHtml(p.seriesName)}</span>: <strong>${formatValue(p.value)}</strong>`);
+                    return `<strong>${params[0].axisValue}</strong><br/>${rows.join('<br/>')}`;
+                }
+            },
+            legend: {
+                type: 'scroll',
+                top: 0,
+                textStyle: { color: textColor, fontSize: 11 },
+                formatter: name => shortName(name)
+            },
+            grid: { left: 70, right: 30, top: 40, bottom: 70 },
+            xAxis: {
+                type: 'category',
+                boundaryGap: false,
+                data: times,
+                axisLabel: { color: textColor, fontSize: 10 }
+            },
+            yAxis: {
+                type: 'value',
+                axisLabel: { color: textColor, fontSize: 10, formatter: value => formatValue(value) }
+            },
+            dataZoom: [
+                { type: 'slider', xAxisIndex: 0, bottom: 10, height: 24 },
+                { type: 'inside', xAxisIndex: 0 }
+            ],
+            series: series
+        });
+
+        chart.on('datazoom', () => {
+            const option = chart.getOption();
+            const zoom = option.dataZoom[0];
+            const buckets = data.totals.length;
+            const first = zoom.startValue !== undefined ? zoom.startValue : Math.floor(zoom.start / 100 * (buckets - 1));
+            const last = zoom.endValue !== undefined ? zoom.endValue : Math.ceil(zoom.end / 100 * (buckets - 1));
+            selectWindow(first * resolution, (last + 1) * resolution);
+        });
+    }
+
+    function selectWindow(from, to) {
+        windowRange = [Math.max(0, from), Math.min(to, data.duration_seconds)];
+        renderRange();
+        clearTimeout(windowTimer);
+        windowTimer = setTimeout(loadWindow, WINDOW_DELAY);
+    }
+
+    async function loadWindow() {
+        if (!data || !windowRange) return;
+        const request = ++windowRequest;
+        const [from, to] = windowRange;
+        setHtml('timelineFlame', '<div class="loading">Loading flame graph</div>');
+        try {
+            const fg = await API.getFlameGraphWindow(currentTaskId, profile, from, to);
+            if (request !== windowRequest) return;
+            renderFlame(fg);
+        } catch (error) {
+            if (request !== windowRequest) return;
+            console.error('[Timeline] Failed to load window flame graph:', error);
+            setHtml('timelineFlame', `<div class="loading">⚠️ Failed to load flame graph: ${Utils.escapeHtml(error.message)}</div>`);
+        }
+    }
+
+    function renderFlame(fg) {
+        const container = document.getElementById('timelineFlame');
+        if (!container) return;
+        container.innerHTML = '';
+        if (!fg.root || !fg.root.value) {
+            container.innerHTML = '<div class="loading">No samples in the window</div>';
+            return;
+        }
+        setHtml('timelineWindowTotal', formatValue(fg.total_samples || fg.root.value));
+
+        const flame = flamegraph()
+            .width(container.clientWidth || 1200)
+            .cellHeight(18)
+            .transitionDuration(300)
+            .minFrameSize(1)
+            .sort(true)
+            .title('')
+            .inverted(true)
+            .selfValue(false)
+            .label(d => `${d.data.name}: ${formatValue(d.value)}`);
+        d3.select(container).datum(fg.root).call(flame);
+    }
+
+    async function loadSeries() {
+        isLoading = true;
+        data = null;
+        windowRange = null;
+        renderProfiles();
+        renderRange();
+        setHtml('timelineWindowTotal', '-');
+        setHtml('timelineChart', '<div class="loading">Loading timeline</div>');
+        setHtml('timelineFlame', '');
+        try {
+            data = await API.getTimeline(currentTaskId, profile);
+            setHtml('timelineChart', '');
+            renderChart();
+            selectWindow(0, data.duration_seconds);
+        } catch (error) {
+            console.error('[Timeline] Failed to load timeline:', error);
+            currentTaskId = null;
+            setHtml('timelineChart', `<div class="loading">⚠️ Failed to load timeline: ${Utils.escapeHtml(error.message)}</div>`);
+        } finally {
+            isLoading = false;
+        }
+    }
+
+    // ============================================
+    // 公共方法
+    // ============================================
+
+    /**
+     * 面板打开时调用：加载任务的时间线（同一任务只加载一次）
+     * @param {string} taskId - 任务 ID
+     * @param {string[]} available - 有时间线的 profile（cpu、memory、lock）
+     */
+    async function load(taskId, available) {
+        if (!taskId || isLoading) return;
+        if (taskId === currentTaskId && data) {
+            if (chart) chart.resize();
+            return;
+        }
+        currentTaskId = taskId;
+        profiles = available && available.length ? available : ['cpu'];
+        if (!profiles.includes(profile)) profile = profiles[0];
+        await loadSeries();
+    }
+
+    /**
+     * 切换 profile（CPU / 分配 / 锁）
+     */
+    async function setProfile(p) {
+        if (p === profile || isLoading) return;
+        profile = p;
+        await loadSeries();
+    }
+
+    /**
+     * 重置时间窗口为整个 profile
+     */
+    function resetWindow() {
+        if (!chart || !data) return;
+        chart.dispatchAction({ type: 'dataZoom', start: 0, end: 100 });
+        selectWindow(0, data.duration_seconds);
+    }
+
+    // ============================================
+    // 导出公共接口
+    // ============================================
+
+    return {
+        load,
+        setProfile,
+        resetWindow
+    };
+})();
@@ -0,0 +1,105 @@
+/**
+ * URL State - Keeps the shareable UI state in the page URL
+ *
+ * The selected task, view and the state of the heap views (class filter,
+ * dominator tree focus, paths-to-root object, inspected object) are mirrored
+ * in the query string, so a finding can be shared by copying the link.
+ * Opening such a link restores the state once the task has loaded.
+ */
+
+const UrlState = {
+    // Query parameters, by state key
+    params: {
+        task: 'task',
+        view: 'view',
+        profile: 'profile',     // pprof-all sub-type
+        classFilter: 'class',   // Class Histogram search
+        domFocus: 'focus',      // Dominator tree focus object
+        rootPaths: 'paths',     // Paths-to-GC-root object
+        object: 'object'        // Object inspector
+    },
+
+    // State holding object IDs, validated when read from a link
+    objectIds: ['domFocus', 'rootPaths', 'object'],
+
+    // State of the views of a task, cleared when the task changes
+    taskScoped: ['profile', 'classFilter', 'domFocus', 'rootPaths', 'object'],
+
+    // State read from the URL on page load, consumed by takeInitial()
+    initial: null,
+
+    /**
+     * Read the state from the current URL
+     * @returns {Object} state keys to values
+     */
+    read() {
+        const query = new URLSearchParams(window.location.search);
+        const state = {};
+        for (const [key, param] of Object.entries(this.params)) {
+            const value = query.get(param);
+            if (!value) continue;
+            if (this.objectIds.includes(key) && !/^(0x[0-9a-f]+|\d+)$/i.test(value)) continue;
+            state[key] = value;
+        }
+        return state;
+    },
+
+    /**
+     * Return the state the page was opened with, once
+     * @returns {Object} state keys to values
+     */
+    takeInitial() {
+        const state = this.initial || {};
+        this.initial = {};
+        return state;
+    },
+
+    /**
+     * Merge state into the URL without adding a history entry;
+     * empty values remove the parameter
+     * @param {Object} state - state keys to values
+     */
+    update(state) {
+        const url = new URL(window.location.href);
+        for (const [key, value] of Object.entries(state)) {
+            const param = this.params[key];
+            if (!param) continue;
+            if (value) {
+                url.searchParams.set(param, value);
+            } else {
+                url.searchParams.delete(param);
+            }
+        }
+        if (url.href !== window.location.href) {
+            history.replaceState(null, '', url);
+        }
+    },
+
+    /**
+     * Switch the URL to another task, dropping the heap view state
+     * @param {string} taskId - task ID
+     */
+    setTask(taskId) {
+        const state = { task: taskId };
+        for (const key of this.taskScoped) state[key] = '';
+        this.update(state);
+    },
+
+    /**
+     * Copy a link to the current state to the clipboard
+     * @param {HTMLElement} element - element showing the copy feedback
+     */
+    copyLink(element) {
+        Utils.copyToClipboard(window.location.href, element);
+        if (element) {
+            const label = element.textContent;
+            element.textContent = '✓';
+            setTimeout(() => { element.textContent = label; }, 1500);
+        }
+    }
+};
+
+UrlState.initial = UrlState.read();
+
+// Export for use in other modules
+window.UrlState = UrlState;
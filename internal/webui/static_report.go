@@ -0,0 +1,228 @@
+package webui
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/perf-analysis/internal/flamegraph"
+	"github.com/perf-analysis/internal/parser/hprof"
+)
+
+// staticReportMinFlameFraction drops flame graph frames below this fraction
+// of all samples from static reports; they are too narrow to see and would
+// only bloat the file.
+const staticReportMinFlameFraction = 0.001
+
+// StaticReportOptions controls the content of a static HTML report.
+type StaticReportOptions struct {
+	// TopN limits the classes and biggest objects listed (default 50)
+	TopN int
+}
+
+// StaticReport is the content of a standalone HTML report of an analysis
+// task, which can be attached to a ticket and opened without the server.
+type StaticReport struct {
+	TaskID       string
+	Title        string
+	TaskType     string
+	Metadata     map[string]any
+	TotalRecords int64
+	GeneratedAt  string
+
+	// Suggestions of the analysis; for heap dumps these are the leak suspects
+	Suggestions []staticReportSuggestion
+
+	// Heap dump results
+	Heap *staticReportHeap
+
+	// Flame graph of profiling results, pruned of invisible frames
+	FlameGraph     *flamegraph.Node
+	FlameGraphType FlameGraphType
+
+	// Differential flame graph of two profiles, for comparison reports
+	DiffFlameGraph   *flamegraph.DiffNode
+	BaseTotalSamples int64
+}
+
+type staticReportSuggestion struct {
+	Suggestion string `json:"suggestion"`
+	Func       string `json:"func"`
+}
+
+type staticReportHeap struct {
+	TotalClasses   int                 `json:"total_classes"`
+	TotalInstances int64               `json:"total_instances"`
+	TotalSize      int64               `json:"total_size"`
+	Classes        []*hprof.ClassStats `json:"classes"`
+	BiggestObjects []*staticReportObject
+}
+
+// staticReportObject is an entry of biggest_objects.json.
+type staticReportObject struct {
+	ObjectID     string            `json:"object_id"`
+	ClassName    string            `json:"class_name"`
+	ShallowSize  int64             `json:"shallow_size"`
+	RetainedSize int64             `json:"retained_size"`
+	GCRootPath   *hprof.GCRootPath `json:"gc_root_path"`
+}
+
+// IsHeap reports whether the report is of a heap dump analysis.
+func (r *StaticReport) IsHeap() bool {
+	return r.Heap != nil
+}
+
+// BuildStaticReport collects the results of the analysis in taskDir.
+func BuildStaticReport(ctx context.Context, taskDir string, opts StaticReportOptions) (*StaticReport, error) {
+	if opts.TopN <= 0 {
+		opts.TopN = 50
+	}
+
+	data, err := os.ReadFile(filepath.Join(taskDir, "summary.json"))
+	if os.IsNotExist(err) {
+		return nil, fmt.Errorf("no summary in %s: not an analysis task directory", taskDir)
+	}
+	if err != nil {
+		return nil, err
+	}
+	var summary struct {
+		TaskType     string                   `json:"task_type"`
+		TotalRecords int64                    `json:"total_records"`
+		Metadata     map[string]any           `json:"metadata"`
+		Suggestions  []staticReportSuggestion `json:"suggestions"`
+	}
+	if err := json.Unmarshal(data, &summary); err != nil {
+		return nil, fmt.Errorf("invalid summary in %s: %w", taskDir, err)
+	}
+
+	taskID := filepath.Base(filepath.Clean(taskDir))
+	report := &StaticReport{
+		TaskID:       taskID,
+		Title:        taskID,
+		TaskType:     summary.TaskType,
+		Metadata:     summary.Metadata,
+		TotalRecords: summary.TotalRecords,
+		GeneratedAt:  time.Now().Format(time.RFC3339),
+		Suggestions:  summary.Suggestions,
+	}
+	if meta, err := loadTaskMeta(taskDir); err == nil && meta != nil && meta.Name != "" {
+		report.Title = meta.Name
+	}
+
+	if report.Heap, err = loadStaticReportHeap(taskDir, opts.TopN); err != nil {
+		return nil, err
+	}
+	if report.Heap == nil {
+		report.loadFlameGraph(ctx, taskDir)
+	}
+	return report, nil
+}
+
+// BuildDiffStaticReport creates the report of the comparison of two
+// profiles, showing their differential flame graph.
+func BuildDiffStaticReport(title string, diff *flamegraph.DiffFlameGraph) *StaticReport {
+	return &StaticReport{
+		Title:            title,
+		GeneratedAt:      time.Now().Format(time.RFC3339),
+		DiffFlameGraph:   diff.Prune(staticReportMinFlameFraction),
+		BaseTotalSamples: diff.BaseTotalSamples,
+	}
+}
+
+// loadStaticReportHeap loads the class histogram and biggest objects of a
+// heap analysis. It returns nil for other analyses.
+func loadStaticReportHeap(taskDir string, topN int) (*staticReportHeap, error) {
+	data, err := os.ReadFile(filepath.Join(taskDir, "class_histogram.json"))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var heap staticReportHeap
+	if err := json.Unmarshal(data, &heap); err != nil {
+		return nil, fmt.Errorf("invalid class histogram in %s: %w", taskDir, err)
+	}
+	if len(heap.Classes) > topN {
+		heap.Classes = heap.Classes[:topN]
+	}
+
+	// Biggest objects are optional, e.g. quick analyses don't compute them
+	if data, err := os.ReadFile(filepath.Join(taskDir, "biggest_objects.json")); err == nil {
+		if err := json.Unmarshal(data, &heap.BiggestObjects); err != nil {
+			return nil, fmt.Errorf("invalid biggest objects in %s: %w", taskDir, err)
+		}
+		if len(heap.BiggestObjects) > topN {
+			heap.BiggestObjects = heap.BiggestObjects[:topN]
+		}
+	}
+	return &heap, nil
+}
+
+// loadFlameGraph loads the first flame graph the task has, trying the
+// types in the order of the web UI tabs.
+func (r *StaticReport) loadFlameGraph(ctx context.Context, taskDir string) {
+	svc := newAllFlameGraphService(filepath.Dir(filepath.Clean(taskDir)))
+	types := []FlameGraphType{
+		FlameGraphTypeCPU, FlameGraphTypeMemory, FlameGraphTypeTracing,
+		FlameGraphTypePProfHeapInuse, FlameGraphTypePProfHeapAlloc, FlameGraphTypePProfGoroutine,
+		FlameGraphTypePProfBlock, FlameGraphTypePProfMutex,
+	}
+	for _, fgType := range types {
+		fg, err := svc.GetFlameGraph(ctx, r.TaskID, fgType)
+		if err != nil || fg.Root == nil {
+			continue
+		}
+		minValue := int64(float64(fg.Root.Value) * staticReportMinFlameFraction)
+		r.FlameGraph = pruneFlameNode(fg.Root, minValue)
+		r.FlameGraphType = fgType
+		return
+	}
+}
+
+// pruneFlameNode copies the name and values of n and its descendants with at
+// least minValue samples.
+func pruneFlameNode(n *flamegraph.Node, minValue int64) *flamegraph.Node {
+	pruned := &flamegraph.Node{Name: n.Name, Value: n.Value, Self: n.Self}
+	for _, child := range n.Children {
+		if child.Value >= minValue && child.Value > 0 {
+			pruned.Children = append(pruned.Children, pruneFlameNode(child, minValue))
+		}
+	}
+	return pruned
+}
+
+// staticReportFuncs are the template functions of the static report.
+var staticReportFuncs = template.FuncMap{
+	"bytes": hprof.FormatBytesSize,
+	"rootPath": func(path *hprof.GCRootPath) string {
+		if path == nil {
+			return ""
+		}
+		// ROOT → a.Holder.field → b.Value: the field is of the previous object
+		parts := []string{string(path.RootType)}
+		for _, node := range path.Path {
+			if node.FieldName != "" && len(parts) > 1 {
+				parts[len(parts)-1] += "." + node.FieldName
+			}
+			parts = append(parts, node.ClassName)
+		}
+		return strings.Join(parts, " → ")
+	},
+}
+
+// WriteStaticReport renders the report as a single HTML file with its styles,
+// script and data embedded.
+func WriteStaticReport(w io.Writer, report *StaticReport) error {
+	tmpl, err := template.New("report.html").Funcs(staticReportFuncs).ParseFS(templatesFS, "templates/report.html")
+	if err != nil {
+		return fmt.Errorf("failed to parse report template: %w", err)
+	}
+	return tmpl.Execute(w, report)
+}
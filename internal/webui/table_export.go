@@ -0,0 +1,171 @@
+package webui
+
+import (
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/perf-analysis/pkg/writer"
+)
+
+// tableMediaTypes maps Accept media types to table formats.
+var tableMediaTypes = map[string]writer.TableFormat{
+	"text/csv":                  writer.TableFormatCSV,
+	"text/tab-separated-values": writer.TableFormatTSV,
+}
+
+// negotiateTableFormat returns the table format requested by the client.
+// The "format" query parameter (csv/tsv) wins over the Accept header so that
+// plain download links work. ok is false when JSON should be served.
+func negotiateTableFormat(r *http.Request) (writer.TableFormat, bool) {
+	if f := r.URL.Query().Get("format"); f != "" {
+		format, err := writer.ParseTableFormat(f)
+		return format, err == nil
+	}
+
+	// Media types are listed in preference order; the first table type wins
+	// unless JSON was listed before it.
+	for _, part := range strings.Split(r.Header.Get("Accept"), ",") {
+		mediaType := strings.TrimSpace(strings.SplitN(part, ";", 2)[0])
+		if format, ok := tableMediaTypes[mediaType]; ok {
+			return format, true
+		}
+		if mediaType == "application/json" {
+			return "", false
+		}
+	}
+	return "", false
+}
+
+// serveTableExport writes a table exported at analysis time (<name>.csv or <name>.tsv
+// in the task directory) in the requested format, converting between formats if needed.
+func (s *Server) serveTableExport(w http.ResponseWriter, taskDir, name string, format writer.TableFormat) {
+	for _, stored := range []writer.TableFormat{format, writer.TableFormatCSV, writer.TableFormatTSV} {
+		file, err := os.Open(filepath.Join(taskDir, name+stored.Extension()))
+		if err != nil {
+			continue
+		}
+		defer file.Close()
+
+		table, err := writer.ReadTable(file, stored)
+		if err != nil {
+			http.Error(w, "Failed to read table export", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", format.ContentType())
+		w.Header().Set("Content-Disposition", "attachment; filename=\""+name+format.Extension()+"\"")
+		w.Header().Set("Access-Control-Allow-Origin", "*")
+		w.Header().Set("Vary", "Accept")
+		if err := writer.NewTableWriter(format).Write(table, w); err != nil && s.logger != nil {
+			s.logger.Warn("Failed to write table export %s: %v", name, err)
+		}
+		return
+	}
+
+	http.Error(w, "Table export not found", http.StatusNotFound)
+}
+
+// taskDirFromRequest resolves the task directory from the "task" query parameter.
+func (s *Server) taskDirFromRequest(r *http.Request) string {
+	taskID := r.URL.Query().Get("task")
+	if taskID == "" {
+		taskID = s.getDefaultTask()
+	}
+	if taskID == "" {
+		return s.dataDir
+	}
+	return filepath.Join(s.dataDir, taskID)
+}
+
+// handleClassHistogram returns the class histogram for heap analysis.
+// Supports CSV/TSV via Accept header or the format query parameter.
+func (s *Server) handleClassHistogram(w http.ResponseWriter, r *http.Request) {
+	taskDir := s.taskDirFromRequest(r)
+
+	if format, ok := negotiateTableFormat(r); ok {
+		s.serveTableExport(w, taskDir, "class_histogram", format)
+		return
+	}
+
+	data, err := os.ReadFile(filepath.Join(taskDir, "class_histogram.json"))
+	if err != nil {
+		http.Error(w, "Class histogram not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Vary", "Accept")
+	w.Write(data)
+}
+
+// handleDominatorTree returns the top slice of the dominator tree for heap analysis.
+// Supports CSV/TSV via Accept header or the format query parameter.
+func (s *Server) handleDominatorTree(w http.ResponseWriter, r *http.Request) {
+	taskDir := s.taskDirFromRequest(r)
+
+	if format, ok := negotiateTableFormat(r); ok {
+		s.serveTableExport(w, taskDir, "dominator_tree", format)
+		return
+	}
+
+	data, err := os.ReadFile(filepath.Join(taskDir, "heap_analysis.json"))
+	if err != nil {
+		http.Error(w, "Dominator tree not found", http.StatusNotFound)
+		return
+	}
+
+	var report struct {
+		DominatorTree json.RawMessage `json:"dominator_tree"`
+	}
+	if err := json.Unmarshal(data, &report); err != nil {
+		http.Error(w, "Failed to parse heap report", http.StatusInternalServerError)
+		return
+	}
+	if len(report.DominatorTree) == 0 {
+		report.DominatorTree = json.RawMessage("[]")
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Vary", "Accept")
+	w.Write(report.DominatorTree)
+}
+
+// handleHeapOwnership returns the retained memory by team of an analysis run
+// with an ownership map. Supports CSV/TSV via Accept header or the format
+// query parameter.
+func (s *Server) handleHeapOwnership(w http.ResponseWriter, r *http.Request) {
+	taskDir := s.taskDirFromRequest(r)
+
+	if format, ok := negotiateTableFormat(r); ok {
+		s.serveTableExport(w, taskDir, "memory_ownership", format)
+		return
+	}
+
+	data, err := os.ReadFile(filepath.Join(taskDir, "heap_analysis.json"))
+	if err != nil {
+		http.Error(w, "Heap report not found", http.StatusNotFound)
+		return
+	}
+
+	var report struct {
+		MemoryOwnership json.RawMessage `json:"memory_ownership"`
+	}
+	if err := json.Unmarshal(data, &report); err != nil {
+		http.Error(w, "Failed to parse heap report", http.StatusInternalServerError)
+		return
+	}
+	if len(report.MemoryOwnership) == 0 {
+		http.Error(w, "Memory ownership not found: analyze with --ownership-map", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Vary", "Accept")
+	w.Write(report.MemoryOwnership)
+}
@@ -0,0 +1,319 @@
+package webui
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// TaskMetaFileName is the task metadata file in a task directory.
+const TaskMetaFileName = "task_meta.json"
+
+// Limits of task metadata, keeping task listings small.
+const (
+	maxTaskMetaBody  = 64 << 10
+	maxTaskTags      = 32
+	maxTaskTagLength = 64
+	maxTaskNameLen   = 200
+	maxTaskNotesLen  = 16 << 10
+)
+
+// errTaskNotFound is returned for task IDs that do not name a task directory.
+var errTaskNotFound = errors.New("task not found")
+
+// TaskMeta is user-supplied metadata of a task, used to name, tag and filter
+// collected dumps in serve mode.
+type TaskMeta struct {
+	Name string   `json:"name,omitempty"`
+	Tags []string `json:"tags,omitempty"`
+	// SourceHost is the host the dump or profile was collected on
+	SourceHost string `json:"source_host,omitempty"`
+	// DumpTimestamp is when the dump was taken (RFC 3339)
+	DumpTimestamp string `json:"dump_timestamp,omitempty"`
+	Notes         string `json:"notes,omitempty"`
+	UpdatedAt     string `json:"updated_at,omitempty"`
+}
+
+// TaskMetaPatch is a partial update of task metadata. Nil fields are left
+// unchanged; AddTags and RemoveTags edit the tags without replacing them.
+type TaskMetaPatch struct {
+	Name          *string   `json:"name"`
+	Tags          *[]string `json:"tags"`
+	AddTags       []string  `json:"add_tags"`
+	RemoveTags    []string  `json:"remove_tags"`
+	SourceHost    *string   `json:"source_host"`
+	DumpTimestamp *string   `json:"dump_timestamp"`
+	Notes         *string   `json:"notes"`
+}
+
+// apply merges the patch into meta.
+func (p *TaskMetaPatch) apply(meta *TaskMeta) {
+	if p.Name != nil {
+		meta.Name = *p.Name
+	}
+	if p.Tags != nil {
+		meta.Tags = *p.Tags
+	}
+	meta.Tags = append(meta.Tags, p.AddTags...)
+	if len(p.RemoveTags) > 0 {
+		remove := make(map[string]bool, len(p.RemoveTags))
+		for _, tag := range p.RemoveTags {
+			remove[strings.ToLower(strings.TrimSpace(tag))] = true
+		}
+		kept := meta.Tags[:0]
+		for _, tag := range meta.Tags {
+			if !remove[strings.ToLower(strings.TrimSpace(tag))] {
+				kept = append(kept, tag)
+			}
+		}
+		meta.Tags = kept
+	}
+	if p.SourceHost != nil {
+		meta.SourceHost = *p.SourceHost
+	}
+	if p.DumpTimestamp != nil {
+		meta.DumpTimestamp = *p.DumpTimestamp
+	}
+	if p.Notes != nil {
+		meta.Notes = *p.Notes
+	}
+}
+
+// normalize trims the fields, sorts and de-duplicates tags (case-insensitively)
+// and validates the limits and the dump timestamp.
+func (m *TaskMeta) normalize() error {
+	m.Name = strings.TrimSpace(m.Name)
+	m.SourceHost = strings.TrimSpace(m.SourceHost)
+	m.DumpTimestamp = strings.TrimSpace(m.DumpTimestamp)
+	m.Notes = strings.TrimSpace(m.Notes)
+
+	if len(m.Name) > maxTaskNameLen {
+		return fmt.Errorf("name must be at most %d bytes", maxTaskNameLen)
+	}
+	if len(m.Notes) > maxTaskNotesLen {
+		return fmt.Errorf("notes must be at most %d bytes", maxTaskNotesLen)
+	}
+	if m.DumpTimestamp != "" {
+		ts, err := time.Parse(time.RFC3339, m.DumpTimestamp)
+		if err != nil {
+			return fmt.Errorf("dump_timestamp must be an RFC 3339 time: %w", err)
+		}
+		m.DumpTimestamp = ts.Format(time.RFC3339)
+	}
+
+	seen := make(map[string]bool, len(m.Tags))
+	tags := make([]string, 0, len(m.Tags))
+	for _, tag := range m.Tags {
+		tag = strings.TrimSpace(tag)
+		if tag == "" || seen[strings.ToLower(tag)] {
+			continue
+		}
+		if len(tag) > maxTaskTagLength || strings.ContainsAny(tag, ",\n") {
+			return fmt.Errorf("invalid tag %q: tags are at most %d bytes without commas", tag, maxTaskTagLength)
+		}
+		seen[strings.ToLower(tag)] = true
+		tags = append(tags, tag)
+	}
+	if len(tags) > maxTaskTags {
+		return fmt.Errorf("at most %d tags are allowed", maxTaskTags)
+	}
+	sort.Slice(tags, func(i, j int) bool { return strings.ToLower(tags[i]) < strings.ToLower(tags[j]) })
+	m.Tags = tags
+	return nil
+}
+
+// HasTag reports whether the metadata carries a tag, ignoring case.
+func (m *TaskMeta) HasTag(tag string) bool {
+	if m == nil {
+		return false
+	}
+	for _, t := range m.Tags {
+		if strings.EqualFold(t, tag) {
+			return true
+		}
+	}
+	return false
+}
+
+// loadTaskMeta reads the metadata of a task, or returns nil if it has none.
+func loadTaskMeta(taskDir string) (*TaskMeta, error) {
+	data, err := os.ReadFile(filepath.Join(taskDir, TaskMetaFileName))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var meta TaskMeta
+	if err := json.Unmarshal(data, &meta); err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", TaskMetaFileName, err)
+	}
+	return &meta, nil
+}
+
+// saveTaskMeta atomically writes the metadata of a task.
+func saveTaskMeta(taskDir string, meta *TaskMeta) error {
+	data, err := json.MarshalIndent(meta, "", "  ")
+	if err != nil {
+		return err
+	}
+	defer preserveModTime(taskDir)()
+	filename := filepath.Join(taskDir, TaskMetaFileName)
+	tmpFile := filename + ".tmp"
+	if err := os.WriteFile(tmpFile, data, 0644); err != nil {
+		return err
+	}
+	if err := os.Rename(tmpFile, filename); err != nil {
+		os.Remove(tmpFile)
+		return err
+	}
+	return nil
+}
+
+// removeTaskMeta removes the metadata of a task.
+func removeTaskMeta(taskDir string) error {
+	defer preserveModTime(taskDir)()
+	err := os.Remove(filepath.Join(taskDir, TaskMetaFileName))
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// preserveModTime returns a function restoring the modification time of a
+// task directory. The task list reports it as the creation time and sorts by
+// it, so editing metadata must not move a task to the top.
+func preserveModTime(taskDir string) func() {
+	info, err := os.Stat(taskDir)
+	if err != nil {
+		return func() {}
+	}
+	return func() {
+		os.Chtimes(taskDir, time.Time{}, info.ModTime())
+	}
+}
+
+// existingTaskDir returns the directory of a task, rejecting IDs that are
+// not a single path element so writes cannot escape the data directory.
+func (s *Server) existingTaskDir(taskID string) (string, error) {
+	if taskID == "" || taskID == "." || taskID == ".." || filepath.Base(taskID) != taskID || strings.ContainsAny(taskID, `/\`) {
+		return "", errTaskNotFound
+	}
+	taskDir := filepath.Join(s.dataDir, taskID)
+	if info, err := os.Stat(taskDir); err != nil || !info.IsDir() {
+		return "", errTaskNotFound
+	}
+	return taskDir, nil
+}
+
+// handleTaskMeta reads and edits the metadata of a task.
+//
+//	GET    /api/tasks/meta?task=<id>  - metadata (empty object if none)
+//	PUT    /api/tasks/meta?task=<id>  - replace the metadata with the JSON body
+//	PATCH  /api/tasks/meta?task=<id>  - update the fields present in the JSON body
+//	DELETE /api/tasks/meta?task=<id>  - remove the metadata
+func (s *Server) handleTaskMeta(w http.ResponseWriter, r *http.Request) {
+	var req taskMetaRequest
+	if err := decodeQuery(r, &req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	taskDir, err := s.existingTaskDir(req.Task)
+	if err != nil {
+		http.Error(w, "Task not found: "+req.Task, http.StatusNotFound)
+		return
+	}
+
+	// Serialize read-modify-write cycles of concurrent edits
+	s.taskMetaMu.Lock()
+	defer s.taskMetaMu.Unlock()
+
+	meta, err := loadTaskMeta(taskDir)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to load task metadata: %v", err), http.StatusInternalServerError)
+		return
+	}
+	if meta == nil {
+		meta = &TaskMeta{}
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+
+	case http.MethodPut, http.MethodPatch:
+		body := http.MaxBytesReader(w, r.Body, maxTaskMetaBody)
+		decoder := json.NewDecoder(body)
+		decoder.DisallowUnknownFields()
+		if r.Method == http.MethodPut {
+			meta = &TaskMeta{}
+			err = decoder.Decode(meta)
+		} else {
+			var patch TaskMetaPatch
+			if err = decoder.Decode(&patch); err == nil {
+				patch.apply(meta)
+			}
+		}
+		if err != nil && err != io.EOF {
+			http.Error(w, "Invalid task metadata: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err := meta.normalize(); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		meta.UpdatedAt = time.Now().UTC().Format(time.RFC3339)
+		if err := saveTaskMeta(taskDir, meta); err != nil {
+			http.Error(w, fmt.Sprintf("Failed to save task metadata: %v", err), http.StatusInternalServerError)
+			return
+		}
+		if s.logger != nil {
+			s.logger.Info("Task metadata updated: %s", req.Task)
+		}
+
+	case http.MethodDelete:
+		if err := removeTaskMeta(taskDir); err != nil {
+			http.Error(w, fmt.Sprintf("Failed to remove task metadata: %v", err), http.StatusInternalServerError)
+			return
+		}
+		meta = &TaskMeta{}
+
+	default:
+		w.Header().Set("Allow", "GET, PUT, PATCH, DELETE")
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	json.NewEncoder(w).Encode(meta)
+}
+
+// matchesTaskFilter reports whether a task matches the tag and text filters
+// of the task list.
+func matchesTaskFilter(task *TaskInfo, tag, query string) bool {
+	if tag != "" && !task.Meta.HasTag(tag) {
+		return false
+	}
+	if query == "" {
+		return true
+	}
+	query = strings.ToLower(query)
+	fields := []string{task.ID}
+	if task.Meta != nil {
+		fields = append(fields, task.Meta.Name, task.Meta.SourceHost, task.Meta.Notes)
+		fields = append(fields, task.Meta.Tags...)
+	}
+	for _, field := range fields {
+		if strings.Contains(strings.ToLower(field), query) {
+			return true
+		}
+	}
+	return false
+}
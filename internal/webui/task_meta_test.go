@@ -0,0 +1,187 @@
+package webui
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTaskMetaTestServer returns the API routes of a server whose data
+// directory holds the given empty tasks.
+func newTaskMetaTestServer(t *testing.T, taskIDs ...string) (http.Handler, string) {
+	dataDir := t.TempDir()
+	for _, id := range taskIDs {
+		require.NoError(t, os.Mkdir(filepath.Join(dataDir, id), 0o755))
+	}
+	s := NewServer(dataDir, 0, nil)
+	mux := http.NewServeMux()
+	s.registerAPIRoutes(mux)
+	return mux, dataDir
+}
+
+// serveTaskMeta sends a task metadata request and decodes a successful reply.
+func serveTaskMeta(t *testing.T, h http.Handler, method, query, body string) (*httptest.ResponseRecorder, *TaskMeta) {
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(method, "/api/tasks/meta?"+query, strings.NewReader(body)))
+	if w.Code != http.StatusOK {
+		return w, nil
+	}
+	var meta TaskMeta
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &meta))
+	return w, &meta
+}
+
+func TestServer_handleTaskMeta(t *testing.T) {
+	h, dataDir := newTaskMetaTestServer(t, "task-1")
+	taskDir := filepath.Join(dataDir, "task-1")
+	created := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	require.NoError(t, os.Chtimes(taskDir, created, created))
+
+	// No metadata yet
+	w, meta := serveTaskMeta(t, h, http.MethodGet, "task=task-1", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, TaskMeta{}, *meta)
+
+	// PUT replaces and normalizes the metadata
+	w, meta = serveTaskMeta(t, h, http.MethodPut, "task=task-1", `{
+		"name": "  checkout leak ",
+		"tags": ["prod", " Leak", "PROD", ""],
+		"source_host": "app-1",
+		"dump_timestamp": "2026-01-02T04:04:05+01:00",
+		"notes": "after deploy"
+	}`)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, "checkout leak", meta.Name)
+	assert.Equal(t, []string{"Leak", "prod"}, meta.Tags)
+	assert.Equal(t, "2026-01-02T04:04:05+01:00", meta.DumpTimestamp)
+	assert.NotEmpty(t, meta.UpdatedAt)
+
+	// The metadata is saved without moving the task in the task list
+	saved, err := loadTaskMeta(taskDir)
+	require.NoError(t, err)
+	assert.Equal(t, meta, saved)
+	info, err := os.Stat(taskDir)
+	require.NoError(t, err)
+	assert.True(t, info.ModTime().Equal(created))
+
+	// PATCH updates only the fields present
+	w, meta = serveTaskMeta(t, h, http.MethodPatch, "task=task-1",
+		`{"name": "checkout", "add_tags": ["oom"], "remove_tags": ["LEAK"]}`)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, "checkout", meta.Name)
+	assert.Equal(t, []string{"oom", "prod"}, meta.Tags)
+	assert.Equal(t, "app-1", meta.SourceHost)
+	assert.Equal(t, "after deploy", meta.Notes)
+
+	w, meta = serveTaskMeta(t, h, http.MethodPatch, "task=task-1", `{"tags": [], "notes": ""}`)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Empty(t, meta.Tags)
+	assert.Empty(t, meta.Notes)
+	assert.Equal(t, "checkout", meta.Name)
+
+	_, got := serveTaskMeta(t, h, http.MethodGet, "task=task-1", "")
+	assert.Equal(t, meta, got)
+
+	// DELETE removes the metadata file
+	w, meta = serveTaskMeta(t, h, http.MethodDelete, "task=task-1", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, TaskMeta{}, *meta)
+	assert.NoFileExists(t, filepath.Join(taskDir, TaskMetaFileName))
+	w, _ = serveTaskMeta(t, h, http.MethodDelete, "task=task-1", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	info, err = os.Stat(taskDir)
+	require.NoError(t, err)
+	assert.True(t, info.ModTime().Equal(created))
+}
+
+func TestServer_handleTaskMeta_Errors(t *testing.T) {
+	tests := []struct {
+		name   string
+		method string
+		query  string
+		body   string
+		want   int
+	}{
+		{"missing task", http.MethodGet, "task=missing", "", http.StatusNotFound},
+		{"no task", http.MethodGet, "", "", http.StatusBadRequest},
+		{"parent directory", http.MethodPut, "task=..", `{}`, http.StatusNotFound},
+		{"nested path", http.MethodPut, "task=task-1%2F..%2Ftask-1", `{}`, http.StatusNotFound},
+		{"unknown field", http.MethodPut, "task=task-1", `{"owner": "me"}`, http.StatusBadRequest},
+		{"malformed body", http.MethodPatch, "task=task-1", `{"name":`, http.StatusBadRequest},
+		{"bad dump timestamp", http.MethodPut, "task=task-1", `{"dump_timestamp": "yesterday"}`, http.StatusBadRequest},
+		{"tag with comma", http.MethodPatch, "task=task-1", `{"add_tags": ["a,b"]}`, http.StatusBadRequest},
+		{"long tag", http.MethodPatch, "task=task-1", `{"add_tags": ["` + strings.Repeat("x", maxTaskTagLength+1) + `"]}`, http.StatusBadRequest},
+		{"duplicate tags", http.MethodPut, "task=task-1", `{"tags": ["` + strings.Repeat(`t", "`, maxTaskTags) + `t0"]}`, http.StatusOK},
+		{"long name", http.MethodPut, "task=task-1", `{"name": "` + strings.Repeat("x", maxTaskNameLen+1) + `"}`, http.StatusBadRequest},
+		{"body too large", http.MethodPut, "task=task-1", `{"notes": "` + strings.Repeat("x", maxTaskMetaBody) + `"}`, http.StatusBadRequest},
+		{"empty body", http.MethodPatch, "task=task-1", "", http.StatusOK},
+		{"method not allowed", http.MethodPost, "task=task-1", `{}`, http.StatusMethodNotAllowed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h, dataDir := newTaskMetaTestServer(t, "task-1")
+			w, _ := serveTaskMeta(t, h, tt.method, tt.query, tt.body)
+			assert.Equal(t, tt.want, w.Code, w.Body.String())
+			if tt.want != http.StatusOK {
+				assert.NoFileExists(t, filepath.Join(dataDir, "task-1", TaskMetaFileName))
+			}
+		})
+	}
+
+	t.Run("distinct tags over the limit", func(t *testing.T) {
+		h, _ := newTaskMetaTestServer(t, "task-1")
+		tags := make([]string, maxTaskTags+1)
+		for i := range tags {
+			tags[i] = "tag-" + strings.Repeat("x", i)
+		}
+		body, err := json.Marshal(TaskMeta{Tags: tags})
+		require.NoError(t, err)
+		w, _ := serveTaskMeta(t, h, http.MethodPut, "task=task-1", string(body))
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+}
+
+func TestServer_handleListTasks_Filter(t *testing.T) {
+	h, dataDir := newTaskMetaTestServer(t, "task-1", "task-2", "task-3")
+	require.NoError(t, saveTaskMeta(filepath.Join(dataDir, "task-1"), &TaskMeta{Name: "checkout", Tags: []string{"prod", "leak"}}))
+	require.NoError(t, saveTaskMeta(filepath.Join(dataDir, "task-2"), &TaskMeta{SourceHost: "staging-1", Tags: []string{"Staging"}}))
+	require.NoError(t, os.WriteFile(filepath.Join(dataDir, "task-3", TaskMetaFileName), []byte("{"), 0o644))
+
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{"", []string{"task-1", "task-2", "task-3"}},
+		{"tag=PROD", []string{"task-1"}},
+		{"tag=staging", []string{"task-2"}},
+		{"tag=none", nil},
+		{"q=CHECK", []string{"task-1"}},
+		{"q=staging-1", []string{"task-2"}},
+		{"q=leak", []string{"task-1"}},
+		{"q=task-3", []string{"task-3"}},
+		{"tag=prod&q=staging", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/tasks?"+tt.query, nil))
+			require.Equal(t, http.StatusOK, w.Code)
+
+			var tasks []TaskInfo
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &tasks))
+			var ids []string
+			for _, task := range tasks {
+				ids = append(ids, task.ID)
+			}
+			assert.ElementsMatch(t, tt.want, ids)
+		})
+	}
+}
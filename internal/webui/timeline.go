@@ -0,0 +1,200 @@
+package webui
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/perf-analysis/internal/flamegraph"
+)
+
+// Number of top functions of timeline responses.
+const (
+	timelineDefaultTop = 10
+	timelineMaxTop     = 50
+)
+
+// timelineFiles are the timelines written by the analyzers, by profile.
+var timelineFiles = []struct {
+	profile string
+	file    string
+}{
+	{"cpu", "timeline_data.json.gz"},
+	{"memory", "alloc_timeline_data.json.gz"},
+	{"lock", "lock_timeline_data.json.gz"},
+}
+
+// cachedTimeline is a parsed timeline, reused until the file changes.
+type cachedTimeline struct {
+	modTime  time.Time
+	timeline *flamegraph.Timeline
+}
+
+// TimelineResponse is the timeline of a profile: the values of its top
+// functions over time, for a stacked area chart.
+type TimelineResponse struct {
+	Task    string `json:"task"`
+	Profile string `json:"profile"`
+	// DurationSeconds is the time covered by the timeline
+	DurationSeconds float64 `json:"duration_seconds"`
+	*flamegraph.TimelineSeries
+}
+
+// taskTimelines returns the profiles of a task directory with a timeline.
+func taskTimelines(taskDir string) []string {
+	var profiles []string
+	for _, t := range timelineFiles {
+		if _, err := os.Stat(filepath.Join(taskDir, t.file)); err == nil {
+			profiles = append(profiles, t.profile)
+		}
+	}
+	return profiles
+}
+
+// timelineProfile returns the profile of a type query parameter, empty if
+// unknown.
+func timelineProfile(fgType string) string {
+	switch strings.ToLower(fgType) {
+	case "", "cpu":
+		return "cpu"
+	case "memory", "alloc":
+		return "memory"
+	case "lock":
+		return "lock"
+	}
+	return ""
+}
+
+// loadTimeline returns the parsed timeline of a profile of a task.
+func (s *Server) loadTimeline(taskID, profile string) (*flamegraph.Timeline, error) {
+	taskDir, err := s.existingTaskDir(taskID)
+	if err != nil {
+		return nil, err
+	}
+	var filename string
+	for _, t := range timelineFiles {
+		if t.profile == profile {
+			filename = filepath.Join(taskDir, t.file)
+		}
+	}
+	info, err := os.Stat(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	key := taskID + ":" + profile
+	s.timelinesMu.Lock()
+	cached, ok := s.timelines[key]
+	s.timelinesMu.Unlock()
+	if ok && cached.modTime.Equal(info.ModTime()) {
+		return cached.timeline, nil
+	}
+
+	timeline, err := flamegraph.ReadTimelineGzip(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	s.timelinesMu.Lock()
+	s.timelines[key] = &cachedTimeline{modTime: info.ModTime(), timeline: timeline}
+	s.timelinesMu.Unlock()
+	return timeline, nil
+}
+
+// requestTimeline returns the timeline selected by a request, or writes
+// the error.
+func (s *Server) requestTimeline(w http.ResponseWriter, taskID, fgType string) (string, *flamegraph.Timeline, bool) {
+	profile := timelineProfile(fgType)
+	if profile == "" {
+		http.Error(w, "Unknown profile type: "+fgType, http.StatusBadRequest)
+		return "", nil, false
+	}
+	timeline, err := s.loadTimeline(taskID, profile)
+	if err != nil {
+		if errors.Is(err, errTaskNotFound) {
+			http.Error(w, "Task not found: "+taskID, http.StatusNotFound)
+		} else if os.IsNotExist(err) {
+			http.Error(w, "The "+profile+" profile of the task has no timeline", http.StatusNotFound)
+		} else {
+			http.Error(w, "Failed to load timeline: "+err.Error(), http.StatusInternalServerError)
+		}
+		return "", nil, false
+	}
+	return profile, timeline, true
+}
+
+// handleTimeline returns the values over time of the top functions of a
+// profile.
+func (s *Server) handleTimeline(w http.ResponseWriter, r *http.Request) {
+	var req timelineRequest
+	if err := decodeQuery(r, &req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	taskID := s.resolveTask(req.Task)
+	top := req.Top
+	if top <= 0 {
+		top = timelineDefaultTop
+	}
+	top = min(top, timelineMaxTop)
+
+	profile, timeline, ok := s.requestTimeline(w, taskID, req.Type)
+	if !ok {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	json.NewEncoder(w).Encode(&TimelineResponse{
+		Task:            taskID,
+		Profile:         profile,
+		DurationSeconds: timeline.Duration().Seconds(),
+		TimelineSeries:  timeline.Series(top),
+	})
+}
+
+// handleFlameGraphWindow returns the flame graph of a time window of a
+// profile.
+func (s *Server) handleFlameGraphWindow(w http.ResponseWriter, r *http.Request) {
+	var req flameWindowRequest
+	if err := decodeQuery(r, &req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if req.To > 0 && req.To <= req.From {
+		http.Error(w, "to must be after from", http.StatusBadRequest)
+		return
+	}
+	taskID := s.resolveTask(req.Task)
+
+	_, timeline, ok := s.requestTimeline(w, taskID, req.Type)
+	if !ok {
+		return
+	}
+
+	samples := timeline.Window(seconds(req.From), seconds(req.To))
+	gen := flamegraph.NewGenerator(&flamegraph.GeneratorOptions{
+		MinPercent:           0.1,
+		IncludeModule:        true,
+		IncludeThreadInStack: true,
+	})
+	fg, err := gen.Generate(r.Context(), samples)
+	if err != nil {
+		http.Error(w, "Failed to generate flame graph: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	fg.Unit = timeline.Unit
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	json.NewEncoder(w).Encode(fg)
+}
+
+// seconds converts seconds to a duration.
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
@@ -0,0 +1,74 @@
+package webui
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/perf-analysis/internal/flamegraph"
+	"github.com/perf-analysis/pkg/model"
+)
+
+func TestServer_handleTimeline(t *testing.T) {
+	// main runs a for a second, then b
+	const start = int64(100 * time.Second)
+	samples := []*model.Sample{
+		{ThreadName: "main", TID: 1, CallStack: []string{"main", "a"}, Value: 2, Timestamp: start},
+		{ThreadName: "main", TID: 1, CallStack: []string{"main", "b"}, Value: 3, Timestamp: start + int64(1500*time.Millisecond)},
+	}
+	dataDir := t.TempDir()
+	taskDir := filepath.Join(dataDir, "task-1")
+	require.NoError(t, os.Mkdir(taskDir, 0o755))
+	require.NoError(t, flamegraph.WriteTimelineGzip(flamegraph.NewTimeline(samples, time.Second, 100),
+		filepath.Join(taskDir, "timeline_data.json.gz")))
+
+	s := NewServer(dataDir, 0, nil)
+	mux := http.NewServeMux()
+	s.registerAPIRoutes(mux)
+	get := func(target string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, target, nil))
+		return w
+	}
+
+	w := get("/api/timeline?task=task-1")
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var timeline TimelineResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &timeline))
+	assert.Equal(t, "task-1", timeline.Task)
+	assert.Equal(t, "cpu", timeline.Profile)
+	assert.Equal(t, 2.0, timeline.DurationSeconds)
+
+	w = get("/api/flamegraph/window?task=task-1&from=1&to=2")
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var fg flamegraph.FlameGraph
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &fg))
+	assert.Equal(t, int64(3), fg.TotalSamples)
+
+	tests := []struct {
+		query string
+		want  int
+	}{
+		{"task=task-1&type=lock", http.StatusNotFound},
+		{"task=task-1&type=heap", http.StatusBadRequest},
+		// Task IDs must name a task directory
+		{"task=missing", http.StatusNotFound},
+		{"task=..", http.StatusNotFound},
+		{"task=task-1%2F..%2Ftask-1", http.StatusNotFound},
+		{"task=%2Ftmp", http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			for _, path := range []string{"/api/timeline", "/api/flamegraph/window"} {
+				assert.Equal(t, tt.want, get(path+"?"+tt.query).Code, path)
+			}
+		})
+	}
+}
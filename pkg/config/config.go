@@ -9,6 +9,7 @@ import (
 
 	"github.com/spf13/viper"
 
+	"github.com/perf-analysis/pkg/model"
 	"github.com/perf-analysis/pkg/pprof"
 )
 
@@ -21,12 +22,15 @@ type Config struct {
 	Scheduler SchedulerConfig `mapstructure:"scheduler"`
 	Sources   []SourceConfig  `mapstructure:"sources"`
 	Log       LogConfig       `mapstructure:"log"`
+	Admin     AdminConfig     `mapstructure:"admin"`
 	Pprof     *pprof.Config   `mapstructure:"pprof"`
+
+	Notifications NotificationsConfig `mapstructure:"notifications"`
 }
 
 // SourceConfig holds configuration for a task source.
 type SourceConfig struct {
-	Type    string                 `mapstructure:"type"`    // database, kafka, http
+	Type    string                 `mapstructure:"type"`    // database, http, kafka, redis, sqs
 	Name    string                 `mapstructure:"name"`    // unique name for this source
 	Enabled bool                   `mapstructure:"enabled"` // whether this source is enabled
 	Options map[string]interface{} `mapstructure:"options"` // source-specific options
@@ -37,6 +41,38 @@ type AnalysisConfig struct {
 	Version   string `mapstructure:"version"`
 	DataDir   string `mapstructure:"data_dir"`
 	MaxWorker int    `mapstructure:"max_worker"`
+
+	// PluginDir is the directory of analyzer plugins (*.so files) loaded at
+	// startup, none if empty
+	PluginDir string `mapstructure:"plugin_dir"`
+
+	// RuleFiles are YAML rule sets adding, replacing or disabling the
+	// built-in suggestion rules
+	RuleFiles []string `mapstructure:"rule_files"`
+
+	// Symbols resolve the addresses perf could not symbolize in perf script
+	// samples
+	Symbols SymbolsConfig `mapstructure:"symbols"`
+
+	// Timeouts bound analyses, failing those exceeding the SLA of the
+	// service instead of holding a worker
+	Timeouts TimeoutsConfig `mapstructure:"timeouts"`
+}
+
+// TimeoutsConfig holds the timeouts of analyses, in seconds, 0 for none.
+type TimeoutsConfig struct {
+	Analysis       int `mapstructure:"analysis"`        // analysis of a task, saving of its results excluded
+	HeapParse      int `mapstructure:"heap_parse"`      // parsing of heap dumps
+	HeapDominators int `mapstructure:"heap_dominators"` // dominator tree and retainer analysis of heap dumps
+	HeapSerialize  int `mapstructure:"heap_serialize"`  // writing of the reference graph of heap dumps
+}
+
+// SymbolsConfig holds the sources of symbols of unsymbolized perf frames.
+type SymbolsConfig struct {
+	Vmlinux        string   `mapstructure:"vmlinux"`         // vmlinux, kallsyms or System.map of the profiled kernel
+	Dirs           []string `mapstructure:"dirs"`            // symbol directories, e.g. /usr/lib/debug or ~/.debug
+	DebuginfodURLs []string `mapstructure:"debuginfod_urls"` // debuginfod servers, queried by build ID
+	CacheDir       string   `mapstructure:"cache_dir"`       // keeps debuginfod downloads
 }
 
 // DatabaseConfig holds database connection configuration.
@@ -52,14 +88,19 @@ type DatabaseConfig struct {
 
 // StorageConfig holds object storage configuration.
 type StorageConfig struct {
-	Type      string `mapstructure:"type"` // cos or local
-	Bucket    string `mapstructure:"bucket"`
-	Region    string `mapstructure:"region"`
-	SecretID  string `mapstructure:"secret_id"`
-	SecretKey string `mapstructure:"secret_key"`
-	Domain    string `mapstructure:"domain"`     // e.g., "myqcloud.com"
-	Scheme    string `mapstructure:"scheme"`     // e.g., "https" or "http"
-	LocalPath string `mapstructure:"local_path"` // for local storage
+	Type        string `mapstructure:"type"` // local, cos, s3, minio or gcs
+	Bucket      string `mapstructure:"bucket"`
+	Region      string `mapstructure:"region"`
+	SecretID    string `mapstructure:"secret_id"` // access key ID for s3, minio and gcs
+	SecretKey   string `mapstructure:"secret_key"`
+	Domain      string `mapstructure:"domain"`       // e.g., "myqcloud.com"
+	Scheme      string `mapstructure:"scheme"`       // e.g., "https" or "http"
+	Endpoint    string `mapstructure:"endpoint"`     // e.g., "http://minio:9000"
+	PathStyle   bool   `mapstructure:"path_style"`   // address buckets as endpoint/bucket
+	PartSizeMB  int    `mapstructure:"part_size_mb"` // part size of multipart transfers
+	Concurrency int    `mapstructure:"concurrency"`  // parts transferred at once
+	StreamInput bool   `mapstructure:"stream_input"` // analyze heap dumps without a local copy
+	LocalPath   string `mapstructure:"local_path"`   // for local storage
 }
 
 // APMConfig holds APM callback configuration.
@@ -71,10 +112,35 @@ type APMConfig struct {
 
 // SchedulerConfig holds scheduler configuration.
 type SchedulerConfig struct {
-	PollInterval  int `mapstructure:"poll_interval"` // in seconds
-	WorkerCount   int `mapstructure:"worker_count"`
-	PrioritySlots int `mapstructure:"priority_slots"`
-	TaskBatchSize int `mapstructure:"task_batch_size"`
+	PollInterval        int            `mapstructure:"poll_interval"` // in seconds
+	WorkerCount         int            `mapstructure:"worker_count"`
+	PrioritySlots       int            `mapstructure:"priority_slots"`
+	TaskBatchSize       int            `mapstructure:"task_batch_size"`
+	BatchTaskTypes      []string       `mapstructure:"batch_task_types"`      // task types in the batch priority class, e.g. "java_heap"
+	TenantMaxConcurrent int            `mapstructure:"tenant_max_concurrent"` // per tenant, 0 for no limit
+	TenantLimits        map[string]int `mapstructure:"tenant_limits"`         // per-tenant overrides of tenant_max_concurrent
+
+	Admission AdmissionConfig `mapstructure:"admission"`
+	Retry     RetryConfig     `mapstructure:"retry"`
+}
+
+// RetryConfig holds the retry policy of failed analyses: transient failures,
+// e.g. of downloads from storage, are retried with exponential backoff, and
+// tasks failing for good are dead-lettered.
+type RetryConfig struct {
+	MaxAttempts int `mapstructure:"max_attempts"` // attempts of an analysis, 0 or 1 for no retries
+	Backoff     int `mapstructure:"backoff"`      // before the first retry, in seconds, doubled after
+	MaxBackoff  int `mapstructure:"max_backoff"`  // in seconds
+}
+
+// AdmissionConfig holds configuration of the admission control of heap dump
+// tasks: tasks wait until the memory they are estimated to need is free, and
+// are rejected if they need more than the whole budget.
+type AdmissionConfig struct {
+	Enabled        bool    `mapstructure:"enabled"`
+	MemoryBudgetMB int     `mapstructure:"memory_budget_mb"` // 0 for memory_fraction of the cgroup or host memory
+	MemoryFraction float64 `mapstructure:"memory_fraction"`
+	SampleMB       int     `mapstructure:"sample_mb"` // bytes of a dump read to estimate its object count
 }
 
 // LogConfig holds logging configuration.
@@ -84,6 +150,43 @@ type LogConfig struct {
 	Format     string `mapstructure:"format"` // json or text
 }
 
+// AdminConfig holds configuration of the admin HTTP endpoints, e.g. to
+// change log levels at runtime or query past analyses. They have no
+// authentication: keep the address private.
+type AdminConfig struct {
+	Enabled bool   `mapstructure:"enabled"`
+	Addr    string `mapstructure:"addr"`
+}
+
+// NotificationsConfig holds configuration of the notifications sent when
+// tasks complete or fail.
+type NotificationsConfig struct {
+	WebUIURL string          `mapstructure:"web_ui_url"` // e.g. "http://perf.example.com:8080", for links to results
+	Webhooks []WebhookConfig `mapstructure:"webhooks"`
+}
+
+// WebhookConfig holds configuration of a webhook notified of task results.
+type WebhookConfig struct {
+	URL     string            `mapstructure:"url"`
+	Format  string            `mapstructure:"format"`  // json, slack or feishu
+	Events  []string          `mapstructure:"events"`  // completed and/or failed, all when empty
+	Timeout int               `mapstructure:"timeout"` // in seconds
+	Headers map[string]string `mapstructure:"headers"`
+}
+
+// Webhook payload formats.
+const (
+	WebhookFormatJSON   = "json"
+	WebhookFormatSlack  = "slack"
+	WebhookFormatFeishu = "feishu"
+)
+
+// Webhook events.
+const (
+	WebhookEventCompleted = "completed"
+	WebhookEventFailed    = "failed"
+)
+
 // Load reads configuration from the specified file path.
 func Load(configPath string) (*Config, error) {
 	v := viper.New()
@@ -173,12 +276,22 @@ func setDefaults(v *viper.Viper) {
 	v.SetDefault("scheduler.worker_count", 5)
 	v.SetDefault("scheduler.priority_slots", 2)
 	v.SetDefault("scheduler.task_batch_size", 10)
+	v.SetDefault("scheduler.batch_task_types", []string{"java_heap"})
+	v.SetDefault("scheduler.admission.memory_fraction", 0.8)
+	v.SetDefault("scheduler.admission.sample_mb", 64)
+	v.SetDefault("scheduler.retry.max_attempts", 3)
+	v.SetDefault("scheduler.retry.backoff", 10)
+	v.SetDefault("scheduler.retry.max_backoff", 300)
 
 	// Log defaults
 	v.SetDefault("log.level", "info")
 	v.SetDefault("log.output_path", "./logs")
 	v.SetDefault("log.format", "text")
 
+	// Admin defaults
+	v.SetDefault("admin.enabled", false)
+	v.SetDefault("admin.addr", "127.0.0.1:8090")
+
 	// Pprof defaults
 	v.SetDefault("pprof.enabled", false)
 	v.SetDefault("pprof.mode", "http")
@@ -213,6 +326,61 @@ func (c *Config) Validate() error {
 	if c.Scheduler.WorkerCount < 1 {
 		return fmt.Errorf("worker count must be at least 1")
 	}
+	for _, name := range c.Scheduler.BatchTaskTypes {
+		if _, ok := model.ParseTaskType(name); !ok {
+			return fmt.Errorf("unknown batch task type: %s", name)
+		}
+	}
+	if c.Scheduler.TenantMaxConcurrent < 0 {
+		return fmt.Errorf("tenant max concurrent must not be negative")
+	}
+	for tenant, limit := range c.Scheduler.TenantLimits {
+		if limit < 0 {
+			return fmt.Errorf("concurrency limit of tenant %s must not be negative", tenant)
+		}
+	}
+	if admission := c.Scheduler.Admission; admission.Enabled {
+		if admission.MemoryBudgetMB < 0 {
+			return fmt.Errorf("admission memory budget must not be negative")
+		}
+		if admission.MemoryBudgetMB == 0 && (admission.MemoryFraction <= 0 || admission.MemoryFraction > 1) {
+			return fmt.Errorf("admission memory fraction must be in (0, 1]")
+		}
+		if admission.SampleMB < 1 {
+			return fmt.Errorf("admission sample must be at least 1MB")
+		}
+	}
+	if retry := c.Scheduler.Retry; retry.MaxAttempts < 0 || retry.Backoff < 0 || retry.MaxBackoff < 0 {
+		return fmt.Errorf("retry attempts and backoffs must not be negative")
+	}
+
+	// Validate log config
+	if c.Log.Format != "" && c.Log.Format != "text" && c.Log.Format != "json" {
+		return fmt.Errorf("unsupported log format: %s", c.Log.Format)
+	}
+
+	if c.Admin.Enabled && c.Admin.Addr == "" {
+		return fmt.Errorf("admin address is required")
+	}
+
+	for i, webhook := range c.Notifications.Webhooks {
+		if webhook.URL == "" {
+			return fmt.Errorf("webhook %d: url is required", i)
+		}
+		switch webhook.Format {
+		case "", WebhookFormatJSON, WebhookFormatSlack, WebhookFormatFeishu:
+		default:
+			return fmt.Errorf("webhook %d: unsupported format: %s", i, webhook.Format)
+		}
+		for _, event := range webhook.Events {
+			if event != WebhookEventCompleted && event != WebhookEventFailed {
+				return fmt.Errorf("webhook %d: unknown event: %s", i, event)
+			}
+		}
+		if webhook.Timeout < 0 {
+			return fmt.Errorf("webhook %d: timeout must not be negative", i)
+		}
+	}
 
 	return nil
 }
@@ -33,6 +33,11 @@ storage:
 	assert.Equal(t, 5, cfg.Analysis.MaxWorker)
 	assert.Equal(t, 2, cfg.Scheduler.PollInterval)
 	assert.Equal(t, 5, cfg.Scheduler.WorkerCount)
+	assert.Equal(t, []string{"java_heap"}, cfg.Scheduler.BatchTaskTypes)
+	assert.Zero(t, cfg.Scheduler.TenantMaxConcurrent)
+	assert.Equal(t, "text", cfg.Log.Format)
+	assert.False(t, cfg.Admin.Enabled)
+	assert.Equal(t, "127.0.0.1:8090", cfg.Admin.Addr)
 }
 
 func TestLoad_CustomValues(t *testing.T) {
@@ -150,6 +155,152 @@ func TestValidate_InvalidWorkerCount(t *testing.T) {
 	assert.Contains(t, err.Error(), "worker count must be at least 1")
 }
 
+func TestValidate_SchedulerClassesAndTenants(t *testing.T) {
+	valid := func() *Config {
+		return &Config{
+			Database:  DatabaseConfig{Type: "postgres", Host: "localhost"},
+			Scheduler: SchedulerConfig{WorkerCount: 1},
+		}
+	}
+
+	cfg := valid()
+	cfg.Scheduler.BatchTaskTypes = []string{"java_heap", "bolt"}
+	cfg.Scheduler.TenantMaxConcurrent = 2
+	cfg.Scheduler.TenantLimits = map[string]int{"team-a": 4}
+	assert.NoError(t, cfg.Validate())
+
+	cfg = valid()
+	cfg.Scheduler.BatchTaskTypes = []string{"heap"}
+	assert.ErrorContains(t, cfg.Validate(), "unknown batch task type: heap")
+
+	cfg = valid()
+	cfg.Scheduler.TenantMaxConcurrent = -1
+	assert.ErrorContains(t, cfg.Validate(), "tenant max concurrent must not be negative")
+
+	cfg = valid()
+	cfg.Scheduler.TenantLimits = map[string]int{"team-a": -1}
+	assert.ErrorContains(t, cfg.Validate(), "concurrency limit of tenant team-a")
+}
+
+func TestValidate_Admission(t *testing.T) {
+	valid := func() *Config {
+		return &Config{
+			Database: DatabaseConfig{Type: "postgres", Host: "localhost"},
+			Scheduler: SchedulerConfig{
+				WorkerCount: 1,
+				Admission:   AdmissionConfig{Enabled: true, MemoryFraction: 0.8, SampleMB: 64},
+			},
+		}
+	}
+
+	assert.NoError(t, valid().Validate())
+
+	cfg := valid()
+	cfg.Scheduler.Admission.MemoryBudgetMB = 4096
+	cfg.Scheduler.Admission.MemoryFraction = 0
+	assert.NoError(t, cfg.Validate())
+
+	cfg = valid()
+	cfg.Scheduler.Admission.MemoryFraction = 1.5
+	assert.ErrorContains(t, cfg.Validate(), "admission memory fraction")
+
+	cfg = valid()
+	cfg.Scheduler.Admission.MemoryBudgetMB = -1
+	assert.ErrorContains(t, cfg.Validate(), "admission memory budget must not be negative")
+
+	cfg = valid()
+	cfg.Scheduler.Admission.SampleMB = 0
+	assert.ErrorContains(t, cfg.Validate(), "admission sample")
+
+	cfg = valid()
+	cfg.Scheduler.Admission = AdmissionConfig{}
+	assert.NoError(t, cfg.Validate(), "disabled admission control is not validated")
+}
+
+func TestValidate_Retry(t *testing.T) {
+	cfg := &Config{
+		Database:  DatabaseConfig{Type: "postgres", Host: "localhost"},
+		Scheduler: SchedulerConfig{WorkerCount: 1, Retry: RetryConfig{MaxAttempts: 3, Backoff: 10, MaxBackoff: 300}},
+	}
+	assert.NoError(t, cfg.Validate())
+
+	cfg.Scheduler.Retry.Backoff = -1
+	assert.ErrorContains(t, cfg.Validate(), "retry attempts and backoffs must not be negative")
+
+	cfg.Scheduler.Retry = RetryConfig{}
+	assert.NoError(t, cfg.Validate(), "no retries")
+}
+
+func TestValidate_Webhooks(t *testing.T) {
+	valid := func() *Config {
+		return &Config{
+			Database:  DatabaseConfig{Type: "postgres", Host: "localhost"},
+			Scheduler: SchedulerConfig{WorkerCount: 1},
+			Notifications: NotificationsConfig{
+				Webhooks: []WebhookConfig{{URL: "https://hooks.slack.com/services/x", Format: "slack", Events: []string{"failed"}}},
+			},
+		}
+	}
+
+	assert.NoError(t, valid().Validate())
+
+	cfg := valid()
+	cfg.Notifications.Webhooks[0].Format = ""
+	assert.NoError(t, cfg.Validate(), "the format defaults to json")
+
+	cfg = valid()
+	cfg.Notifications.Webhooks[0].URL = ""
+	assert.ErrorContains(t, cfg.Validate(), "webhook 0: url is required")
+
+	cfg = valid()
+	cfg.Notifications.Webhooks[0].Format = "teams"
+	assert.ErrorContains(t, cfg.Validate(), "unsupported format: teams")
+
+	cfg = valid()
+	cfg.Notifications.Webhooks[0].Events = []string{"started"}
+	assert.ErrorContains(t, cfg.Validate(), "unknown event: started")
+}
+
+func TestLoadFromReader_Webhooks(t *testing.T) {
+	cfg, err := LoadFromReader("yaml", []byte(`
+notifications:
+  web_ui_url: http://perf.example.com
+  webhooks:
+    - url: https://open.feishu.cn/open-apis/bot/v2/hook/x
+      format: feishu
+      events: [completed, failed]
+      headers:
+        X-Token: secret
+`))
+	require.NoError(t, err)
+
+	assert.Equal(t, "http://perf.example.com", cfg.Notifications.WebUIURL)
+	require.Len(t, cfg.Notifications.Webhooks, 1)
+	webhook := cfg.Notifications.Webhooks[0]
+	assert.Equal(t, "feishu", webhook.Format)
+	assert.Equal(t, []string{"completed", "failed"}, webhook.Events)
+	assert.Equal(t, "secret", webhook.Headers["x-token"], "viper lowercases keys")
+}
+
+func TestValidate_InvalidLogFormat(t *testing.T) {
+	cfg := &Config{
+		Database: DatabaseConfig{
+			Type: "postgres",
+			Host: "localhost",
+		},
+		Scheduler: SchedulerConfig{
+			WorkerCount: 1,
+		},
+		Log: LogConfig{
+			Format: "xml",
+		},
+	}
+
+	err := cfg.Validate()
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "unsupported log format")
+}
+
 func TestGetTaskDir(t *testing.T) {
 	cfg := &Config{
 		Analysis: AnalysisConfig{
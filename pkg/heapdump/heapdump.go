@@ -0,0 +1,258 @@
+// Package heapdump is the Go API for analyzing Java HPROF heap dumps, for
+// services embedding heap analysis.
+//
+// It wraps the heap dump parser of the analyzer behind a small surface that
+// follows semantic versioning (see Version): the functions, methods and
+// types of a major version only gain fields and methods, so callers do not
+// depend on the internals of the parser.
+//
+//	dump, err := heapdump.Open(ctx, "heap.hprof", nil)
+//	if err != nil {
+//	    log.Fatal(err)
+//	}
+//	for _, class := range dump.Histogram()[:10] {
+//	    fmt.Printf("%s: %d instances, %d bytes\n", class.Name, class.Instances, class.ShallowSize)
+//	}
+//
+// A HeapDump is read-only and safe for concurrent use.
+package heapdump
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/perf-analysis/internal/parser/hprof"
+	"github.com/perf-analysis/pkg/utils"
+)
+
+// Version is the semantic version of the API of the package.
+const Version = "1.0.0"
+
+// Errors returned for unknown classes and objects.
+var (
+	ErrClassNotFound  = errors.New("class not found")
+	ErrObjectNotFound = errors.New("object not found")
+)
+
+// Options configure the parsing of heap dumps.
+type Options struct {
+	// ExcludeUnreachable leaves the objects unreachable from GC roots out of
+	// the class histogram
+	ExcludeUnreachable bool
+	// Logger receives the progress of parsing; nil discards it
+	Logger utils.Logger
+}
+
+// HeapDump is an analyzed heap dump.
+type HeapDump struct {
+	snapshot *hprof.HeapSnapshot
+}
+
+// Open analyzes a heap dump file, or loads the analysis of a heap dump from
+// the task directory the analyzer wrote it to, which is much faster. Field
+// values are read back from the heap dump file when queried, so it must
+// remain available.
+func Open(ctx context.Context, path string, opts *Options) (*HeapDump, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if info.IsDir() {
+		snapshot, err := hprof.LoadHeapSnapshot(path)
+		if err != nil {
+			return nil, err
+		}
+		return &HeapDump{snapshot: snapshot}, nil
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	result, err := parse(ctx, file, opts)
+	if err != nil {
+		return nil, err
+	}
+	if result.ObjectIndex != nil {
+		if err := result.ObjectIndex.SetSource(path); err != nil {
+			result.ObjectIndex = nil
+		}
+	}
+	return newHeapDump(result)
+}
+
+// Parse analyzes a heap dump read from r. Field values are not available to
+// queries, as the heap dump cannot be read again.
+func Parse(ctx context.Context, r io.Reader, opts *Options) (*HeapDump, error) {
+	result, err := parse(ctx, r, opts)
+	if err != nil {
+		return nil, err
+	}
+	result.ObjectIndex = nil
+	return newHeapDump(result)
+}
+
+// parse runs the parser with the options.
+func parse(ctx context.Context, r io.Reader, opts *Options) (*hprof.HeapAnalysisResult, error) {
+	if opts == nil {
+		opts = &Options{}
+	}
+	parserOpts := hprof.DefaultParserOptions()
+	parserOpts.IncludeUnreachable = !opts.ExcludeUnreachable
+	parserOpts.Logger = opts.Logger
+
+	result, err := hprof.NewParser(parserOpts).Parse(ctx, r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse heap dump: %w", err)
+	}
+	return result, nil
+}
+
+// newHeapDump freezes the reference graph of a parse result.
+func newHeapDump(result *hprof.HeapAnalysisResult) (*HeapDump, error) {
+	snapshot := result.Snapshot()
+	if snapshot == nil {
+		return nil, errors.New("heap dump has no reference graph")
+	}
+	return &HeapDump{snapshot: snapshot}, nil
+}
+
+// ObjectCount returns the number of objects of the heap dump.
+func (h *HeapDump) ObjectCount() int {
+	return h.snapshot.ObjectCount()
+}
+
+// Object returns an object of the heap dump.
+func (h *HeapDump) Object(id uint64) (Object, error) {
+	classID, ok := h.snapshot.ObjectClassID(id)
+	if !ok {
+		return Object{}, fmt.Errorf("%w: 0x%x", ErrObjectNotFound, id)
+	}
+	return Object{
+		ID:           id,
+		Class:        h.snapshot.ClassName(classID),
+		ShallowSize:  h.snapshot.ObjectSize(id),
+		RetainedSize: h.snapshot.RetainedSize(id),
+	}, nil
+}
+
+// Histogram returns the classes with instances, by shallow size descending.
+func (h *HeapDump) Histogram() []Class {
+	stats := h.snapshot.ClassHistogram()
+	histogram := make([]Class, len(stats))
+	for i, s := range stats {
+		histogram[i] = Class{
+			Name:         s.ClassName,
+			Instances:    s.InstanceCount,
+			ShallowSize:  s.TotalSize,
+			RetainedSize: s.RetainedSize,
+			Percentage:   s.Percentage,
+		}
+	}
+	return histogram
+}
+
+// Retainers returns up to limit classes and fields referencing instances of
+// a class, by size referenced descending.
+func (h *HeapDump) Retainers(className string, limit int) ([]Retainer, error) {
+	stats := h.snapshot.ClassRetainers(className, limit)
+	if stats == nil {
+		return nil, fmt.Errorf("%w: %s", ErrClassNotFound, className)
+	}
+	retainers := make([]Retainer, len(stats.Retainers))
+	for i, r := range stats.Retainers {
+		retainers[i] = Retainer{
+			Class:      r.RetainerClass,
+			Field:      r.FieldName,
+			Instances:  r.RetainedCount,
+			Size:       r.RetainedSize,
+			Percentage: r.Percentage,
+		}
+	}
+	return retainers, nil
+}
+
+// DominatorTree returns the objects immediately dominated by an object, by
+// retained size descending. Object ID 0 returns the top of the tree, the
+// objects dominated by no other object.
+func (h *HeapDump) DominatorTree(id uint64) ([]Object, error) {
+	var ids []uint64
+	if id == 0 {
+		ids = h.snapshot.DominatorRoots()
+	} else {
+		if _, ok := h.snapshot.ObjectClassID(id); !ok {
+			return nil, fmt.Errorf("%w: 0x%x", ErrObjectNotFound, id)
+		}
+		ids = h.snapshot.DominatorChildren(id)
+	}
+
+	objects := make([]Object, 0, len(ids))
+	for _, child := range ids {
+		if object, err := h.Object(child); err == nil {
+			objects = append(objects, object)
+		}
+	}
+	return objects, nil
+}
+
+// PathsToRoot returns the shortest paths from GC roots to an object.
+func (h *HeapDump) PathsToRoot(id uint64, opts PathOptions) ([]Path, error) {
+	if _, ok := h.snapshot.ObjectClassID(id); !ok {
+		return nil, fmt.Errorf("%w: 0x%x", ErrObjectNotFound, id)
+	}
+
+	var exclude hprof.PathExclusion
+	if opts.ExcludeWeak {
+		exclude |= hprof.ExcludeWeakRefs
+	}
+	if opts.ExcludeSoft {
+		exclude |= hprof.ExcludeSoftRefs
+	}
+	if opts.ExcludePhantom {
+		exclude |= hprof.ExcludePhantomRefs
+	}
+
+	found := h.snapshot.PathsToGCRootExcluding(id, opts.MaxPaths, opts.MaxDepth, exclude)
+	paths := make([]Path, len(found))
+	for i, p := range found {
+		paths[i] = newPath(p, h.snapshot.RetainedSize)
+	}
+	return paths, nil
+}
+
+// Query runs an OQL-style query, a subset of Eclipse MAT's OQL:
+//
+//	SELECT * | column [, column ...]
+//	FROM [INSTANCEOF] <class name | "class regex"> [alias]
+//	[WHERE condition [AND | OR condition ...]]
+//	[ORDER BY column [ASC | DESC]]
+//	[LIMIT n]
+//
+// Columns are @objectId, @className, @usedHeapSize, @retainedHeapSize,
+// @gcRoot or reference fields. Invalid queries return a *QueryError.
+func (h *HeapDump) Query(query string) (*QueryResult, error) {
+	result, err := hprof.NewQueryEngine(h.snapshot).Execute(query)
+	if err != nil {
+		var queryErr *hprof.QueryError
+		if errors.As(err, &queryErr) {
+			return nil, &QueryError{Pos: queryErr.Pos, Msg: queryErr.Msg}
+		}
+		return nil, err
+	}
+
+	rows := make([]Row, len(result.Rows))
+	for i, row := range result.Rows {
+		rows[i] = Row{ObjectID: row.ObjectID, Values: row.Values}
+	}
+	return &QueryResult{
+		Columns:   result.Columns,
+		Rows:      rows,
+		Total:     result.Total,
+		Truncated: result.Truncated,
+	}, nil
+}
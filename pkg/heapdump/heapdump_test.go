@@ -0,0 +1,179 @@
+package heapdump
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/perf-analysis/internal/parser/hprof"
+)
+
+// newTestHeapDump returns a heap dump of a cache, held by a JNI global,
+// heading a linked list of two entries:
+//
+//	0x1000 Cache -head-> 0x2000 Entry -next-> 0x3000 Entry
+func newTestHeapDump() []byte {
+	var buf, heap bytes.Buffer
+	write := func(w *bytes.Buffer, values ...interface{}) {
+		for _, v := range values {
+			binary.Write(w, binary.BigEndian, v)
+		}
+	}
+	record := func(tag hprof.RecordTag, body []byte) {
+		write(&buf, byte(tag), uint32(0), uint32(len(body)))
+		buf.Write(body)
+	}
+	str := func(id uint64, s string) {
+		record(hprof.TagString, append(binary.BigEndian.AppendUint64(nil, id), s...))
+	}
+	// class loads a class with a reference field, if fieldNameID is not 0
+	class := func(classID, nameID uint64, name string, fieldNameID uint64) {
+		str(nameID, name)
+		var body bytes.Buffer
+		write(&body, uint32(0), classID, uint32(0), nameID)
+		record(hprof.TagLoadClass, body.Bytes())
+
+		write(&heap, byte(hprof.HeapTagClassDump), classID, uint32(0), uint64(0), uint64(0),
+			uint64(0), uint64(0), uint64(0), uint64(0), uint32(8), uint16(0), uint16(0))
+		if fieldNameID == 0 {
+			write(&heap, uint16(0))
+		} else {
+			write(&heap, uint16(1), fieldNameID, byte(hprof.TypeObject))
+		}
+	}
+	instance := func(objectID, classID, ref uint64) {
+		write(&heap, byte(hprof.HeapTagInstanceDump), objectID, uint32(0), classID, uint32(8), ref)
+	}
+
+	buf.WriteString("JAVA PROFILE 1.0.2\x00")
+	write(&buf, uint32(8), uint64(0))
+	str(0x501, "head")
+	str(0x502, "next")
+	class(0x08, 0x510, "java/lang/Class", 0)
+	class(0x10, 0x511, "com/example/Cache", 0x501)
+	class(0x20, 0x512, "com/example/Entry", 0x502)
+	instance(0x1000, 0x10, 0x2000)
+	instance(0x2000, 0x20, 0x3000)
+	instance(0x3000, 0x20, 0)
+	write(&heap, byte(hprof.HeapTagRootJNIGlobal), uint64(0x1000), uint64(0))
+	record(hprof.TagHeapDumpSegment, heap.Bytes())
+	return buf.Bytes()
+}
+
+func parseTestHeapDump(t *testing.T) *HeapDump {
+	t.Helper()
+	dump, err := Parse(context.Background(), bytes.NewReader(newTestHeapDump()), nil)
+	require.NoError(t, err)
+	return dump
+}
+
+func TestHeapDump_Histogram(t *testing.T) {
+	dump := parseTestHeapDump(t)
+
+	classes := make(map[string]Class)
+	for _, class := range dump.Histogram() {
+		classes[class.Name] = class
+	}
+	require.Contains(t, classes, "com.example.Entry")
+	assert.Equal(t, int64(2), classes["com.example.Entry"].Instances)
+	assert.Equal(t, int64(1), classes["com.example.Cache"].Instances)
+	assert.Positive(t, classes["com.example.Entry"].ShallowSize)
+
+	object, err := dump.Object(0x2000)
+	require.NoError(t, err)
+	assert.Equal(t, "com.example.Entry", object.Class)
+	assert.Equal(t, object.ShallowSize*2, object.RetainedSize)
+
+	_, err = dump.Object(0x9999)
+	assert.ErrorIs(t, err, ErrObjectNotFound)
+}
+
+func TestHeapDump_Retainers(t *testing.T) {
+	dump := parseTestHeapDump(t)
+
+	retainers, err := dump.Retainers("com.example.Entry", 10)
+	require.NoError(t, err)
+	fields := make(map[string]int64)
+	for _, r := range retainers {
+		fields[r.Class+"."+r.Field] = r.Instances
+	}
+	assert.Equal(t, map[string]int64{"com.example.Cache.head": 1, "com.example.Entry.next": 1}, fields)
+
+	_, err = dump.Retainers("com.example.Missing", 10)
+	assert.ErrorIs(t, err, ErrClassNotFound)
+}
+
+func TestHeapDump_DominatorTree(t *testing.T) {
+	dump := parseTestHeapDump(t)
+
+	top, err := dump.DominatorTree(0)
+	require.NoError(t, err)
+	var topIDs []uint64
+	for _, object := range top {
+		topIDs = append(topIDs, object.ID)
+	}
+	assert.Contains(t, topIDs, uint64(0x1000))
+
+	children, err := dump.DominatorTree(0x1000)
+	require.NoError(t, err)
+	require.Len(t, children, 1)
+	assert.Equal(t, uint64(0x2000), children[0].ID)
+
+	_, err = dump.DominatorTree(0x9999)
+	assert.ErrorIs(t, err, ErrObjectNotFound)
+}
+
+func TestHeapDump_PathsToRoot(t *testing.T) {
+	dump := parseTestHeapDump(t)
+
+	paths, err := dump.PathsToRoot(0x3000, PathOptions{})
+	require.NoError(t, err)
+	require.Len(t, paths, 1)
+	assert.Equal(t, string(hprof.GCRootJNIGlobal), paths[0].RootType)
+
+	var ids []uint64
+	var fields []string
+	for _, step := range paths[0].Steps {
+		ids = append(ids, step.ID)
+		fields = append(fields, step.Field)
+	}
+	assert.Equal(t, []uint64{0x1000, 0x2000, 0x3000}, ids)
+	assert.Equal(t, []string{"", "head", "next"}, fields)
+
+	_, err = dump.PathsToRoot(0x9999, PathOptions{})
+	assert.ErrorIs(t, err, ErrObjectNotFound)
+}
+
+func TestHeapDump_Query(t *testing.T) {
+	dump := parseTestHeapDump(t)
+
+	result, err := dump.Query(`SELECT @objectId, next FROM com.example.Entry ORDER BY @objectId`)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"@objectId", "next"}, result.Columns)
+	assert.Equal(t, 2, result.Total)
+	require.Len(t, result.Rows, 2)
+	assert.Equal(t, []interface{}{uint64(0x2000), uint64(0x3000)}, result.Rows[0].Values)
+	assert.Equal(t, []interface{}{uint64(0x3000), nil}, result.Rows[1].Values)
+
+	_, err = dump.Query(`SELECT FROM`)
+	var queryErr *QueryError
+	assert.ErrorAs(t, err, &queryErr)
+}
+
+func TestOpen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "heap.hprof")
+	require.NoError(t, os.WriteFile(path, newTestHeapDump(), 0644))
+
+	dump, err := Open(context.Background(), path, &Options{ExcludeUnreachable: true})
+	require.NoError(t, err)
+	assert.GreaterOrEqual(t, dump.ObjectCount(), 3)
+
+	_, err = Open(context.Background(), filepath.Join(t.TempDir(), "missing.hprof"), nil)
+	assert.Error(t, err)
+}
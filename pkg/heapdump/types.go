@@ -0,0 +1,109 @@
+package heapdump
+
+import (
+	"fmt"
+
+	"github.com/perf-analysis/internal/parser/hprof"
+)
+
+// Object is an object of a heap dump.
+type Object struct {
+	ID           uint64 `json:"id"`
+	Class        string `json:"class"`
+	ShallowSize  int64  `json:"shallow_size"`
+	RetainedSize int64  `json:"retained_size"`
+}
+
+// Class is an entry of the class histogram of a heap dump.
+type Class struct {
+	Name         string `json:"name"`
+	Instances    int64  `json:"instances"`
+	ShallowSize  int64  `json:"shallow_size"`
+	RetainedSize int64  `json:"retained_size"`
+	// Percentage is the share of the heap held by the shallow size of the instances
+	Percentage float64 `json:"percentage"`
+}
+
+// Retainer is a class and field referencing instances of a class.
+type Retainer struct {
+	Class string `json:"class"`
+	Field string `json:"field,omitempty"`
+	// Instances is the number of instances of the class referenced
+	Instances int64 `json:"instances"`
+	// Size is the shallow size of the instances referenced
+	Size int64 `json:"size"`
+	// Percentage is the share of the size of the instances of the class
+	Percentage float64 `json:"percentage"`
+}
+
+// PathStep is an object of a path from a GC root.
+type PathStep struct {
+	Object
+	// Field is the field of the previous object referencing the object,
+	// empty for the GC root
+	Field string `json:"field,omitempty"`
+}
+
+// Path is a path from a GC root to an object, GC root first.
+type Path struct {
+	RootType string     `json:"root_type"`
+	Steps    []PathStep `json:"steps"`
+}
+
+// PathOptions configure the search of paths to GC roots.
+type PathOptions struct {
+	// MaxPaths is the number of paths returned (default 3)
+	MaxPaths int
+	// MaxDepth is the number of objects of the longest path (default 15)
+	MaxDepth int
+	// ExcludeWeak, ExcludeSoft and ExcludePhantom do not follow the referents
+	// of weak, soft and phantom (and final) references
+	ExcludeWeak    bool
+	ExcludeSoft    bool
+	ExcludePhantom bool
+}
+
+// QueryResult is the result of a query. Every row is one matching object.
+type QueryResult struct {
+	Columns []string `json:"columns"`
+	Rows    []Row    `json:"rows"`
+	// Total is the number of matching objects before LIMIT
+	Total     int  `json:"total"`
+	Truncated bool `json:"truncated"`
+}
+
+// Row is an object of a query result, with a value per column: object IDs
+// and sizes are integers, references object IDs or nil.
+type Row struct {
+	ObjectID uint64        `json:"object_id"`
+	Values   []interface{} `json:"values"`
+}
+
+// QueryError is returned for a query that cannot be parsed or evaluated.
+type QueryError struct {
+	// Pos is the byte offset of the offending token in the query
+	Pos int
+	Msg string
+}
+
+// Error implements the error interface.
+func (e *QueryError) Error() string {
+	return fmt.Sprintf("query error at position %d: %s", e.Pos, e.Msg)
+}
+
+// newPath converts a path of the parser.
+func newPath(p *hprof.GCRootPath, retainedSize func(uint64) int64) Path {
+	path := Path{RootType: string(p.RootType), Steps: make([]PathStep, len(p.Path))}
+	for i, node := range p.Path {
+		path.Steps[i] = PathStep{
+			Object: Object{
+				ID:           node.ObjectID,
+				Class:        node.ClassName,
+				ShallowSize:  node.Size,
+				RetainedSize: retainedSize(node.ObjectID),
+			},
+			Field: node.FieldName,
+		}
+	}
+	return path
+}
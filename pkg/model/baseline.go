@@ -0,0 +1,121 @@
+package model
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// Baseline is the analysis of a service that later analyses of the same type
+// are compared with, to detect regressions.
+type Baseline struct {
+	Service   string    `json:"service"`
+	TaskType  TaskType  `json:"task_type"`
+	TaskUUID  string    `json:"tid"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Kinds of regressions.
+const (
+	RegressionClassGrowth   = "class_growth"   // a class of the heap grew
+	RegressionNewHotspot    = "new_hotspot"    // a function became hot
+	RegressionHotspotGrowth = "hotspot_growth" // a hot function got hotter
+)
+
+// Regression is a change of an analysis from its baseline.
+type Regression struct {
+	Kind     string  `json:"kind"`
+	Name     string  `json:"name"`              // class or function
+	Baseline float64 `json:"baseline"`          // bytes or percentage of the samples
+	Current  float64 `json:"current"`           // bytes or percentage of the samples
+	Message  string  `json:"message,omitempty"` // describes the regression
+}
+
+// RegressionThresholds are the changes from a baseline reported as regressions.
+type RegressionThresholds struct {
+	// ClassGrowthBytes and ClassGrowthPercent are the growth of the size of
+	// a class, both required, the percentage only if it had instances
+	ClassGrowthBytes   int64
+	ClassGrowthPercent float64
+
+	// HotspotPercent is the share of the samples of a function absent from
+	// the top functions of the baseline
+	HotspotPercent float64
+	// HotspotGrowthPoints is the growth of the share of the samples of a
+	// function of the top functions of the baseline, in percentage points
+	HotspotGrowthPoints float64
+}
+
+// DefaultRegressionThresholds returns the default regression thresholds.
+func DefaultRegressionThresholds() RegressionThresholds {
+	return RegressionThresholds{
+		ClassGrowthBytes:    10 << 20,
+		ClassGrowthPercent:  20,
+		HotspotPercent:      5,
+		HotspotGrowthPoints: 5,
+	}
+}
+
+// DetectRegressions compares an analysis summary with the summary of its
+// baseline: the classes of heap dumps which grew and the functions of
+// profiles which got hot, largest changes first.
+func DetectRegressions(baseline, current *AnalysisSummary, thresholds RegressionThresholds) []Regression {
+	var regressions []Regression
+
+	baseClasses := make(map[string]int64, len(baseline.TopClasses))
+	for _, class := range baseline.TopClasses {
+		baseClasses[class.ClassName] = class.TotalSize
+	}
+	for _, class := range current.TopClasses {
+		base := baseClasses[class.ClassName]
+		growth := class.TotalSize - base
+		if growth < thresholds.ClassGrowthBytes {
+			continue
+		}
+		if base > 0 && float64(growth)/float64(base)*100 < thresholds.ClassGrowthPercent {
+			continue
+		}
+		regressions = append(regressions, Regression{
+			Kind:     RegressionClassGrowth,
+			Name:     class.ClassName,
+			Baseline: float64(base),
+			Current:  float64(class.TotalSize),
+			Message: fmt.Sprintf("类 %s 的实例相比基线 %s 增长了 %.1f MB (%.1f MB → %.1f MB)，建议检查是否存在内存泄漏",
+				class.ClassName, baseline.TaskUUID, float64(growth)/(1<<20), float64(base)/(1<<20), float64(class.TotalSize)/(1<<20)),
+		})
+	}
+
+	baseFuncs := make(map[string]float64, len(baseline.TopFuncs))
+	for _, item := range baseline.TopFuncs {
+		baseFuncs[item.Name] = item.Percentage
+	}
+	for _, item := range current.TopFuncs {
+		base, ok := baseFuncs[item.Name]
+		switch {
+		case !ok && item.Percentage >= thresholds.HotspotPercent:
+			regressions = append(regressions, Regression{
+				Kind:    RegressionNewHotspot,
+				Name:    item.Name,
+				Current: item.Percentage,
+				Message: fmt.Sprintf("函数 %s 是相比基线 %s 新出现的热点 (%.2f%%)，建议检查最近的代码或配置变更",
+					item.Name, baseline.TaskUUID, item.Percentage),
+			})
+		case ok && item.Percentage-base >= thresholds.HotspotGrowthPoints:
+			regressions = append(regressions, Regression{
+				Kind:     RegressionHotspotGrowth,
+				Name:     item.Name,
+				Baseline: base,
+				Current:  item.Percentage,
+				Message: fmt.Sprintf("函数 %s 的样本占比相比基线 %s 从 %.2f%% 增长到 %.2f%%，建议检查最近的代码或配置变更",
+					item.Name, baseline.TaskUUID, base, item.Percentage),
+			})
+		}
+	}
+
+	// Byte and percentage changes are not comparable, but a summary has
+	// either classes or functions
+	sort.SliceStable(regressions, func(i, j int) bool {
+		return regressions[i].Current-regressions[i].Baseline > regressions[j].Current-regressions[j].Baseline
+	})
+	return regressions
+}
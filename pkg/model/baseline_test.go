@@ -0,0 +1,77 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDetectRegressions_Heap(t *testing.T) {
+	baseline := &AnalysisSummary{
+		TaskUUID: "heap-1",
+		TopClasses: []HeapClassStats{
+			{ClassName: "byte[]", TotalSize: 100 << 20},
+			{ClassName: "java.lang.String", TotalSize: 40 << 20},
+			{ClassName: "com.example.Session", TotalSize: 1 << 20},
+		},
+	}
+	current := &AnalysisSummary{
+		TaskUUID: "heap-2",
+		TopClasses: []HeapClassStats{
+			{ClassName: "byte[]", TotalSize: 115 << 20},             // +15%: not a regression
+			{ClassName: "com.example.Session", TotalSize: 31 << 20}, // +30 MB
+			{ClassName: "com.example.Order", TotalSize: 12 << 20},   // new
+			{ClassName: "java.lang.String", TotalSize: 45 << 20},    // +5 MB: not a regression
+		},
+	}
+
+	regressions := DetectRegressions(baseline, current, DefaultRegressionThresholds())
+	require.Len(t, regressions, 2)
+
+	assert.Equal(t, RegressionClassGrowth, regressions[0].Kind)
+	assert.Equal(t, "com.example.Session", regressions[0].Name)
+	assert.Equal(t, float64(1<<20), regressions[0].Baseline)
+	assert.Equal(t, float64(31<<20), regressions[0].Current)
+	assert.Contains(t, regressions[0].Message, "heap-1")
+	assert.Contains(t, regressions[0].Message, "30.0 MB")
+
+	assert.Equal(t, "com.example.Order", regressions[1].Name)
+	assert.Zero(t, regressions[1].Baseline)
+}
+
+func TestDetectRegressions_CPU(t *testing.T) {
+	baseline := &AnalysisSummary{
+		TaskUUID: "cpu-1",
+		TopFuncs: []TopItem{
+			{Name: "main", Percentage: 30},
+			{Name: "parse", Percentage: 10},
+			{Name: "write", Percentage: 8},
+		},
+	}
+	current := &AnalysisSummary{
+		TaskUUID: "cpu-2",
+		TopFuncs: []TopItem{
+			{Name: "parse", Percentage: 25}, // +15 points
+			{Name: "main", Percentage: 20},
+			{Name: "compress", Percentage: 9}, // new
+			{Name: "write", Percentage: 11},   // +3 points: not a regression
+			{Name: "log", Percentage: 2},      // new but cold
+		},
+	}
+
+	regressions := DetectRegressions(baseline, current, DefaultRegressionThresholds())
+	assert.Equal(t, []Regression{
+		{Kind: RegressionHotspotGrowth, Name: "parse", Baseline: 10, Current: 25, Message: regressions[0].Message},
+		{Kind: RegressionNewHotspot, Name: "compress", Current: 9, Message: regressions[1].Message},
+	}, regressions)
+	assert.Contains(t, regressions[0].Message, "10.00% 增长到 25.00%")
+}
+
+func TestDetectRegressions_None(t *testing.T) {
+	summary := &AnalysisSummary{
+		TopFuncs:   []TopItem{{Name: "main", Percentage: 50}},
+		TopClasses: []HeapClassStats{{ClassName: "byte[]", TotalSize: 100 << 20}},
+	}
+	assert.Empty(t, DetectRegressions(summary, summary, DefaultRegressionThresholds()))
+}
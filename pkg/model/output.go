@@ -4,6 +4,7 @@ package model
 import (
 	"encoding/json"
 	"sort"
+	"time"
 )
 
 // AnalysisDataType represents the type of analysis data.
@@ -21,6 +22,12 @@ const (
 	DataTypePProfBlock     AnalysisDataType = "pprof_block"
 	DataTypePProfMutex     AnalysisDataType = "pprof_mutex"
 	DataTypePProfBatch     AnalysisDataType = "pprof_batch"
+	DataTypeLockContention AnalysisDataType = "lock_contention"
+	DataTypeJFR            AnalysisDataType = "jfr"
+	DataTypeWallClock      AnalysisDataType = "wall_clock"
+	DataTypeOffCPU         AnalysisDataType = "offcpu"
+	DataTypeThreadDump     AnalysisDataType = "thread_dump"
+	DataTypeGCLog          AnalysisDataType = "gc_log"
 )
 
 // OutputFile describes an output file generated by analysis.
@@ -60,6 +67,17 @@ type CPUProfilingData struct {
 	ThreadStats    []ThreadInfo `json:"thread_stats"`
 	TopFuncs       TopFuncsMap  `json:"top_funcs"`
 	TotalSamples   int64        `json:"total_samples"`
+
+	// FrameTypes is the share of the samples by type of their leaf frame,
+	// e.g. "jit" or "interpreted", for stacks annotated by async-profiler
+	FrameTypes []FrameTypeStats `json:"frame_types,omitempty"`
+}
+
+// FrameTypeStats is the share of the samples whose leaf frame is of a type.
+type FrameTypeStats struct {
+	Type       string  `json:"type"`
+	Samples    int64   `json:"samples"`
+	Percentage float64 `json:"percentage"`
 }
 
 // Type returns the analysis data type.
@@ -69,12 +87,16 @@ func (d *CPUProfilingData) Type() AnalysisDataType {
 
 // Summary returns a summary of the CPU profiling analysis.
 func (d *CPUProfilingData) Summary() map[string]interface{} {
-	return map[string]interface{}{
+	summary := map[string]interface{}{
 		"total_samples":   d.TotalSamples,
 		"thread_count":    len(d.ThreadStats),
 		"flamegraph_file": d.FlameGraphFile,
 		"callgraph_file":  d.CallGraphFile,
 	}
+	if len(d.FrameTypes) > 0 {
+		summary["frame_types"] = d.FrameTypes
+	}
+	return summary
 }
 
 // TopItems returns the top functions from CPU profiling.
@@ -143,6 +165,9 @@ type HeapClassStats struct {
 	RetainedSize  int64          `json:"retained_size,omitempty"` // Dominator tree retained size
 	Retainers     []HeapRetainer `json:"retainers,omitempty"`
 	GCRootPaths   []*GCRootPath  `json:"gc_root_paths,omitempty"` // Sample paths to GC roots
+	// RetainerMode is "exact" when the retainers count every instance,
+	// "sampled" when they are scaled from a sample
+	RetainerMode string `json:"retainer_mode,omitempty"`
 }
 
 // HeapRetainer describes what retains instances of a class.
@@ -211,21 +236,12 @@ type HeapBiggestObject struct {
 	ClassName    string              `json:"class_name"`
 	ShallowSize  int64               `json:"shallow_size"`
 	RetainedSize int64               `json:"retained_size"`
-	Fields       []HeapObjectField   `json:"fields,omitempty"`
-	GCRootPath   *HeapGCRootPath     `json:"gc_root_path,omitempty"`
-}
-
-// HeapObjectField represents a field value in an object.
-type HeapObjectField struct {
-	Name         string      `json:"name"`
-	Type         string      `json:"type"`
-	Value        interface{} `json:"value,omitempty"`
-	RefID        string      `json:"ref_id,omitempty"`
-	RefClass     string      `json:"ref_class,omitempty"`
-	ShallowSize  int64       `json:"shallow_size,omitempty"`
-	RetainedSize int64       `json:"retained_size,omitempty"`
-	HasChildren  bool        `json:"has_children,omitempty"`
-	IsStatic     bool        `json:"is_static,omitempty"`
+	// HasChildren reports whether the object's fields can be expanded
+	// through the refgraph API
+	HasChildren bool            `json:"has_children,omitempty"`
+	GCRootPath  *HeapGCRootPath `json:"gc_root_path,omitempty"`
+	// RolledUpObjects is the number of JDK objects attributed to this object.
+	RolledUpObjects int64 `json:"rolled_up_objects,omitempty"`
 }
 
 // HeapGCRootPath represents a path from GC Root to an object.
@@ -294,6 +310,323 @@ type HeapAnalysisData struct {
 	BiggestObjects    []HeapBiggestObject              `json:"biggest_objects,omitempty"`
 	ReferenceGraphs   map[string]*HeapReferenceGraph   `json:"reference_graphs,omitempty"`
 	BusinessRetainers map[string][]HeapBusinessRetainer `json:"business_retainers,omitempty"`
+
+	// StringStats counts the duplicated values of java.lang.String objects
+	StringStats *HeapStringStats `json:"string_stats,omitempty"`
+	// ClassLoaders groups the classes defined by class loaders by loader class
+	ClassLoaders []HeapClassLoaderStats `json:"class_loaders,omitempty"`
+	// WeakReachability sizes the objects only reachable through soft or weak references
+	WeakReachability *HeapWeakReachability `json:"weak_reachability,omitempty"`
+	// RetentionMotifs are recurring retention patterns such as growing listener lists
+	RetentionMotifs []HeapRetentionMotif `json:"retention_motifs,omitempty"`
+	// FrameworkStats reports the HTTP sessions, caches and ORM sessions of common frameworks
+	FrameworkStats *HeapFrameworkStats `json:"framework_stats,omitempty"`
+	// HumongousObjects are the objects G1 allocates in regions of their own
+	HumongousObjects *HeapHumongousObjects `json:"humongous_objects,omitempty"`
+	// ObjectCycles are the largest reference cycles of the heap
+	ObjectCycles *HeapObjectCycles `json:"object_cycles,omitempty"`
+	// MemoryOwnership attributes the heap to the teams owning its packages
+	MemoryOwnership *HeapMemoryOwnership `json:"memory_ownership,omitempty"`
+}
+
+// HeapStringStats holds the duplication of java.lang.String values.
+type HeapStringStats struct {
+	TotalCount     int64                 `json:"total_count"`
+	TotalSize      int64                 `json:"total_size"`
+	UniqueCount    int64                 `json:"unique_count"`
+	DuplicateCount int64                 `json:"duplicate_count"` // copies of values but one
+	DuplicateWaste int64                 `json:"duplicate_waste"` // bytes of the copies
+	TopDuplicates  []HeapDuplicateString `json:"top_duplicates,omitempty"`
+	// InternCandidates are the fields whose values interning would save the most bytes
+	InternCandidates []HeapStringInternCandidate `json:"intern_candidates,omitempty"`
+	// Truncated is set when only the first Strings were read
+	Truncated bool `json:"truncated,omitempty"`
+}
+
+// HeapDuplicateString is a value held by several String objects.
+type HeapDuplicateString struct {
+	Value string `json:"value"`
+	Count int64  `json:"count"`
+	Waste int64  `json:"waste"`
+}
+
+// HeapStringInternCandidate is a field referencing many copies of few String
+// values, and the bytes interning them would save.
+type HeapStringInternCandidate struct {
+	ClassName string                `json:"class_name"`
+	Field     string                `json:"field"`
+	Static    bool                  `json:"static,omitempty"`
+	Count     int64                 `json:"count"`
+	Distinct  int64                 `json:"distinct"`
+	Size      int64                 `json:"size"`
+	Savings   int64                 `json:"savings"`
+	TopValues []HeapDuplicateString `json:"top_values,omitempty"`
+}
+
+// HeapClassLoaderStats counts the class loaders of a class and the classes
+// they define.
+type HeapClassLoaderStats struct {
+	ClassName      string `json:"class_name"`
+	Instances      int    `json:"instances"`
+	DefinedClasses int    `json:"defined_classes"`
+}
+
+// HeapWeakReachability sizes the objects that are not strongly reachable and
+// are collected under memory pressure: soft caches rather than leaks.
+type HeapWeakReachability struct {
+	SoftObjects int64                    `json:"soft_objects"`
+	SoftSize    int64                    `json:"soft_size"`
+	WeakObjects int64                    `json:"weak_objects"`
+	WeakSize    int64                    `json:"weak_size"`
+	Classes     []HeapWeakReachableClass `json:"classes,omitempty"`
+}
+
+// HeapWeakReachableClass is the softly and weakly reachable part of a class.
+type HeapWeakReachableClass struct {
+	ClassName string `json:"class_name"`
+	SoftCount int64  `json:"soft_count"`
+	SoftSize  int64  `json:"soft_size"`
+	WeakCount int64  `json:"weak_count"`
+	WeakSize  int64  `json:"weak_size"`
+}
+
+// HeapHumongousObjects reports the objects larger than half a G1 region and
+// the unused end of their last region.
+type HeapHumongousObjects struct {
+	RegionSize int64                 `json:"region_size"`
+	Count      int64                 `json:"count"`
+	TotalSize  int64                 `json:"total_size"`
+	Regions    int64                 `json:"regions"`
+	Waste      int64                 `json:"waste"`
+	Classes    []HeapHumongousClass  `json:"classes,omitempty"`
+	Objects    []HeapHumongousObject `json:"objects,omitempty"`
+}
+
+// HeapHumongousClass is the humongous instances of a class.
+type HeapHumongousClass struct {
+	ClassName string `json:"class_name"`
+	Count     int64  `json:"count"`
+	TotalSize int64  `json:"total_size"`
+	Waste     int64  `json:"waste"`
+}
+
+// HeapHumongousObject is one humongous object.
+type HeapHumongousObject struct {
+	ObjectID  string `json:"object_id"`
+	ClassName string `json:"class_name"`
+	Size      int64  `json:"size"`
+	Regions   int64  `json:"regions"`
+	Waste     int64  `json:"waste"`
+}
+
+// HeapMemoryOwnership attributes the retained heap to the teams owning
+// package prefixes, without overlap between teams.
+type HeapMemoryOwnership struct {
+	TotalSize int64               `json:"total_size"`
+	Teams     []HeapTeamOwnership `json:"teams"`
+}
+
+// HeapTeamOwnership is the memory owned by one team.
+type HeapTeamOwnership struct {
+	Team          string               `json:"team"`
+	ClassCount    int                  `json:"class_count"`
+	InstanceCount int64                `json:"instance_count"`
+	ShallowSize   int64                `json:"shallow_size"`
+	RetainedSize  int64                `json:"retained_size"`
+	Percentage    float64              `json:"percentage"`
+	TopClasses    []HeapClassOwnership `json:"top_classes,omitempty"`
+}
+
+// HeapClassOwnership is the memory a team owns through one class.
+type HeapClassOwnership struct {
+	ClassName    string `json:"class_name"`
+	RetainedSize int64  `json:"retained_size"`
+}
+
+// HeapObjectCycles reports the reference cycles of the heap: groups of
+// objects that all reach each other.
+type HeapObjectCycles struct {
+	Count     int64             `json:"count"`
+	Objects   int64             `json:"objects"`
+	TotalSize int64             `json:"total_size"`
+	Cycles    []HeapObjectCycle `json:"cycles,omitempty"`
+}
+
+// HeapObjectCycle is one reference cycle.
+type HeapObjectCycle struct {
+	// ObjectID is the object of the cycle with the largest retained size
+	ObjectID  string `json:"object_id"`
+	Objects   int64  `json:"objects"`
+	TotalSize int64  `json:"total_size"`
+	// Entries is the number of objects of the cycle referenced from outside
+	// of it or GC roots
+	Entries int64            `json:"entries"`
+	Classes []HeapCycleClass `json:"classes"`
+}
+
+// HeapCycleClass is the objects of a class in a cycle.
+type HeapCycleClass struct {
+	ClassName string `json:"class_name"`
+	Count     int64  `json:"count"`
+	TotalSize int64  `json:"total_size"`
+}
+
+// HeapRetentionMotif is a retention pattern recurring in the collections
+// held by one field of one class.
+type HeapRetentionMotif struct {
+	Kind            string                      `json:"kind"`
+	Holder          string                      `json:"holder"`
+	CollectionClass string                      `json:"collection_class"`
+	Count           int                         `json:"count"`
+	Entries         int64                       `json:"entries"`
+	RetainedSize    int64                       `json:"retained_size"`
+	Examples        []HeapRetentionMotifExample `json:"examples,omitempty"`
+}
+
+// HeapRetentionMotifExample is one collection showing a retention pattern.
+type HeapRetentionMotifExample struct {
+	ObjectID      string `json:"object_id"`
+	HolderID      string `json:"holder_id"`
+	Entries       int    `json:"entries"`
+	RetainedSize  int64  `json:"retained_size"`
+	DuplicateKeys int    `json:"duplicate_keys,omitempty"`
+}
+
+// HeapFrameworkStats holds the framework reports of a heap; reports of
+// frameworks absent from the heap are nil.
+type HeapFrameworkStats struct {
+	Sessions            *HeapSessionStats            `json:"sessions,omitempty"`
+	Caches              *HeapCacheStats              `json:"caches,omitempty"`
+	PersistenceContexts *HeapPersistenceContextStats `json:"persistence_contexts,omitempty"`
+	Netty               *HeapNettyStats              `json:"netty,omitempty"`
+	Kafka               *HeapKafkaStats              `json:"kafka,omitempty"`
+	// FieldsRead is set when session IDs and cache weights were read from the dump
+	FieldsRead bool `json:"fields_read"`
+}
+
+// HeapSessionStats reports the HTTP sessions of servlet containers.
+type HeapSessionStats struct {
+	Count        int64             `json:"count"`
+	Attributes   int64             `json:"attributes"`
+	RetainedSize int64             `json:"retained_size"`
+	AverageSize  int64             `json:"average_size"`
+	Largest      []HeapSessionInfo `json:"largest,omitempty"`
+}
+
+// HeapSessionInfo is one HTTP session.
+type HeapSessionInfo struct {
+	ObjectID     string `json:"object_id"`
+	ClassName    string `json:"class_name"`
+	ID           string `json:"id,omitempty"`
+	Attributes   int    `json:"attributes"`
+	RetainedSize int64  `json:"retained_size"`
+}
+
+// HeapCacheStats reports the Guava and Caffeine caches.
+type HeapCacheStats struct {
+	Count        int64           `json:"count"`
+	Entries      int64           `json:"entries"`
+	RetainedSize int64           `json:"retained_size"`
+	Largest      []HeapCacheInfo `json:"largest,omitempty"`
+}
+
+// HeapCacheInfo is one Guava or Caffeine cache.
+type HeapCacheInfo struct {
+	ObjectID     string `json:"object_id"`
+	Library      string `json:"library"`
+	ClassName    string `json:"class_name"`
+	Holder       string `json:"holder,omitempty"`
+	Entries      int64  `json:"entries"`
+	Weight       int64  `json:"weight,omitempty"`
+	MaxWeight    int64  `json:"max_weight,omitempty"`
+	RetainedSize int64  `json:"retained_size"`
+}
+
+// HeapPersistenceContextStats reports the Hibernate persistence contexts.
+type HeapPersistenceContextStats struct {
+	Count        int64                        `json:"count"`
+	Entities     int64                        `json:"entities"`
+	Collections  int64                        `json:"collections"`
+	RetainedSize int64                        `json:"retained_size"`
+	Largest      []HeapPersistenceContextInfo `json:"largest,omitempty"`
+}
+
+// HeapPersistenceContextInfo is the persistence context of one ORM session.
+type HeapPersistenceContextInfo struct {
+	ObjectID     string `json:"object_id"`
+	SessionID    string `json:"session_id,omitempty"`
+	Entities     int    `json:"entities"`
+	Collections  int    `json:"collections"`
+	RetainedSize int64  `json:"retained_size"`
+}
+
+// HeapNettyStats reports the Netty pool arenas and leaked ByteBufs.
+type HeapNettyStats struct {
+	Pools            []HeapNettyPoolStats    `json:"pools"`
+	EventLoops       int64                   `json:"event_loops"`
+	BuffersChecked   int64                   `json:"buffers_checked"`
+	BuffersUnchecked int64                   `json:"buffers_unchecked,omitempty"`
+	LeakedBuffers    int64                   `json:"leaked_buffers"`
+	LeakedBytes      int64                   `json:"leaked_bytes"`
+	Leaks            []HeapNettyLeakedBuffer `json:"leaks,omitempty"`
+}
+
+// HeapNettyPoolStats is the heap or direct memory of the Netty arenas.
+type HeapNettyPoolStats struct {
+	Kind        string  `json:"kind"`
+	Arenas      int64   `json:"arenas"`
+	Chunks      int64   `json:"chunks"`
+	Capacity    int64   `json:"capacity"`
+	Used        int64   `json:"used"`
+	Utilization float64 `json:"utilization"`
+	HugeChunks  int64   `json:"huge_chunks"`
+	HugeSize    int64   `json:"huge_size"`
+}
+
+// HeapNettyLeakedBuffer is a ByteBuf that was never released.
+type HeapNettyLeakedBuffer struct {
+	ObjectID  string `json:"object_id"`
+	ClassName string `json:"class_name"`
+	RefCnt    int64  `json:"ref_cnt"`
+	Capacity  int64  `json:"capacity"`
+	Reachable bool   `json:"reachable"`
+}
+
+// HeapKafkaStats reports the records buffered by Kafka producers and consumers.
+type HeapKafkaStats struct {
+	Producers         int64                      `json:"producers"`
+	Fetchers          int64                      `json:"fetchers"`
+	Pools             []HeapKafkaMemoryPool      `json:"pools,omitempty"`
+	ProducerBatches   int64                      `json:"producer_batches"`
+	ProducerBytes     int64                      `json:"producer_bytes"`
+	CompletedFetches  int64                      `json:"completed_fetches"`
+	FetchedBytes      int64                      `json:"fetched_bytes"`
+	Records           int64                      `json:"records"`
+	RecordBytes       int64                      `json:"record_bytes"`
+	Partitions        []HeapKafkaPartitionBuffer `json:"partitions,omitempty"`
+	UnattributedBytes int64                      `json:"unattributed_bytes,omitempty"`
+}
+
+// HeapKafkaMemoryPool is a Kafka producer buffer pool or network memory pool.
+type HeapKafkaMemoryPool struct {
+	ObjectID  string `json:"object_id"`
+	ClassName string `json:"class_name"`
+	Kind      string `json:"kind"`
+	Capacity  int64  `json:"capacity"`
+	Available int64  `json:"available"`
+	Used      int64  `json:"used"`
+}
+
+// HeapKafkaPartitionBuffer is the memory buffered for one topic partition.
+type HeapKafkaPartitionBuffer struct {
+	Topic            string `json:"topic"`
+	Partition        int64  `json:"partition"`
+	ProducerBatches  int64  `json:"producer_batches"`
+	ProducerBytes    int64  `json:"producer_bytes"`
+	CompletedFetches int64  `json:"completed_fetches"`
+	FetchedBytes     int64  `json:"fetched_bytes"`
+	Records          int64  `json:"records"`
+	RecordBytes      int64  `json:"record_bytes"`
 }
 
 // Type returns the analysis data type.
@@ -426,6 +759,457 @@ func (d *TracingData) TopItems() []TopItem {
 	return items
 }
 
+// LockContentionData holds lock contention analysis data, of threads
+// blocked on monitors or locks.
+type LockContentionData struct {
+	FlameGraphFile string       `json:"flamegraph_file"`
+	CallGraphFile  string       `json:"callgraph_file"`
+	ThreadStats    []ThreadInfo `json:"thread_stats"`
+	TopContended   TopFuncsMap  `json:"top_contended"`
+	TotalEvents    int64        `json:"total_events"`
+	// TotalDelayNanos is the time threads spent blocked
+	TotalDelayNanos int64 `json:"total_delay_ns"`
+	// ThreadLatencies is the time each thread spent blocked, when known
+	ThreadLatencies []ThreadLatency `json:"thread_latencies,omitempty"`
+}
+
+// Type returns the analysis data type.
+func (d *LockContentionData) Type() AnalysisDataType {
+	return DataTypeLockContention
+}
+
+// Summary returns a summary of the lock contention analysis.
+func (d *LockContentionData) Summary() map[string]interface{} {
+	return map[string]interface{}{
+		"total_events":    d.TotalEvents,
+		"total_delay_ns":  d.TotalDelayNanos,
+		"thread_count":    len(d.ThreadStats),
+		"flamegraph_file": d.FlameGraphFile,
+		"callgraph_file":  d.CallGraphFile,
+	}
+}
+
+// TopItems returns the most contended call sites.
+func (d *LockContentionData) TopItems() []TopItem {
+	items := make([]TopItem, 0, len(d.TopContended))
+	for name, val := range d.TopContended {
+		items = append(items, TopItem{
+			Name:       name,
+			Percentage: val.Self,
+		})
+	}
+	sort.Slice(items, func(i, j int) bool {
+		return items[i].Percentage > items[j].Percentage
+	})
+	return items
+}
+
+// ThreadLatency holds the time a thread spent in a latency profile, e.g.
+// off the CPU or blocked on locks.
+type ThreadLatency struct {
+	TID        int    `json:"tid"`
+	ThreadName string `json:"thread_name"`
+	// Samples is the number of samples or events of the thread, 0 if the
+	// profile only has their time
+	Samples int64 `json:"samples,omitempty"`
+	// TotalNanos is the time of the thread, 0 if the profile has none
+	TotalNanos int64   `json:"total_ns,omitempty"`
+	Percentage float64 `json:"percentage"`
+	// States is the time of the thread in each thread state, in
+	// nanoseconds, or in samples if TotalNanos is 0
+	States map[string]int64 `json:"states,omitempty"`
+	// TopFrame is the leaf frame the thread spent the most time in
+	TopFrame string `json:"top_frame,omitempty"`
+}
+
+// WallClockData holds wall-clock profiling analysis data, of threads on and
+// off the CPU.
+type WallClockData struct {
+	FlameGraphFile string       `json:"flamegraph_file"`
+	CallGraphFile  string       `json:"callgraph_file"`
+	ThreadStats    []ThreadInfo `json:"thread_stats"`
+	TopFuncs       TopFuncsMap  `json:"top_funcs"`
+	TotalSamples   int64        `json:"total_samples"`
+	// IntervalNanos is the sampling interval the samples are weighted by
+	IntervalNanos int64 `json:"interval_ns,omitempty"`
+	TotalNanos    int64 `json:"total_ns"`
+	// States is the time of all threads in each thread state, in nanoseconds
+	States          map[string]int64 `json:"states,omitempty"`
+	ThreadLatencies []ThreadLatency  `json:"thread_latencies,omitempty"`
+}
+
+// Type returns the analysis data type.
+func (d *WallClockData) Type() AnalysisDataType {
+	return DataTypeWallClock
+}
+
+// Summary returns a summary of the wall-clock profiling analysis.
+func (d *WallClockData) Summary() map[string]interface{} {
+	return map[string]interface{}{
+		"total_samples":   d.TotalSamples,
+		"total_ns":        d.TotalNanos,
+		"interval_ns":     d.IntervalNanos,
+		"states":          d.States,
+		"thread_count":    len(d.ThreadStats),
+		"flamegraph_file": d.FlameGraphFile,
+		"callgraph_file":  d.CallGraphFile,
+	}
+}
+
+// TopItems returns the functions threads spent the most time in.
+func (d *WallClockData) TopItems() []TopItem {
+	items := make([]TopItem, 0, len(d.TopFuncs))
+	for name, val := range d.TopFuncs {
+		items = append(items, TopItem{
+			Name:       name,
+			Percentage: val.Self,
+		})
+	}
+	sort.Slice(items, func(i, j int) bool {
+		return items[i].Percentage > items[j].Percentage
+	})
+	return items
+}
+
+// BlockingStack is a call stack threads spent time off the CPU in.
+type BlockingStack struct {
+	CallStack  []string `json:"callstack"`
+	TotalNanos int64    `json:"total_ns"`
+	Percentage float64  `json:"percentage"`
+}
+
+// BlockingGroup holds the time a thread spent off the CPU for a reason,
+// e.g. waiting for a lock, with its top blocking stacks.
+type BlockingGroup struct {
+	ThreadName string          `json:"thread_name"`
+	Reason     string          `json:"reason"`
+	TotalNanos int64           `json:"total_ns"`
+	Percentage float64         `json:"percentage"`
+	TopStacks  []BlockingStack `json:"top_stacks"`
+}
+
+// OffCPUData holds off-CPU analysis data, of the time threads spent
+// blocked off the CPU.
+type OffCPUData struct {
+	FlameGraphFile string      `json:"flamegraph_file"`
+	CallGraphFile  string      `json:"callgraph_file"`
+	TopFuncs       TopFuncsMap `json:"top_funcs"`
+	TotalNanos     int64       `json:"total_ns"`
+	// Reasons is the time of all threads off the CPU for each wait reason,
+	// in nanoseconds
+	Reasons         map[string]int64 `json:"reasons,omitempty"`
+	ThreadLatencies []ThreadLatency  `json:"thread_latencies,omitempty"`
+	// BlockingGroups are the top blocking stacks by thread and wait
+	// reason, the longest groups first
+	BlockingGroups []BlockingGroup `json:"blocking_groups,omitempty"`
+}
+
+// Type returns the analysis data type.
+func (d *OffCPUData) Type() AnalysisDataType {
+	return DataTypeOffCPU
+}
+
+// Summary returns a summary of the off-CPU analysis.
+func (d *OffCPUData) Summary() map[string]interface{} {
+	return map[string]interface{}{
+		"total_ns":        d.TotalNanos,
+		"reasons":         d.Reasons,
+		"thread_count":    len(d.ThreadLatencies),
+		"flamegraph_file": d.FlameGraphFile,
+		"callgraph_file":  d.CallGraphFile,
+	}
+}
+
+// TopItems returns the functions threads spent the most time blocked in.
+func (d *OffCPUData) TopItems() []TopItem {
+	items := make([]TopItem, 0, len(d.TopFuncs))
+	for name, val := range d.TopFuncs {
+		items = append(items, TopItem{
+			Name:       name,
+			Percentage: val.Self,
+		})
+	}
+	sort.Slice(items, func(i, j int) bool {
+		return items[i].Percentage > items[j].Percentage
+	})
+	return items
+}
+
+// DumpedThread is a thread of a Java thread dump.
+type DumpedThread struct {
+	Name        string   `json:"name"`
+	ID          int64    `json:"id,omitempty"`
+	Daemon      bool     `json:"daemon,omitempty"`
+	State       string   `json:"state"`
+	StateDetail string   `json:"state_detail,omitempty"`
+	BlockedOn   string   `json:"blocked_on,omitempty"` // address of the lock the thread is blocked on
+	Locked      []string `json:"locked,omitempty"`     // addresses of the locks the thread holds
+	Stack       []string `json:"stack,omitempty"`      // root first
+}
+
+// DeadlockThread is a thread of a deadlock, blocked on a lock held by the
+// next thread of the cycle.
+type DeadlockThread struct {
+	Name        string   `json:"name"`
+	LockAddress string   `json:"lock_address"`
+	LockClass   string   `json:"lock_class,omitempty"`
+	HeldBy      string   `json:"held_by"`
+	Stack       []string `json:"stack,omitempty"` // root first
+}
+
+// Deadlock is a cycle of threads waiting for each other's locks.
+type Deadlock struct {
+	Threads []DeadlockThread `json:"threads"`
+	// DumpCount is the number of dumps the deadlock was found in
+	DumpCount int `json:"dump_count"`
+}
+
+// HotMonitor is a lock threads are blocked on.
+type HotMonitor struct {
+	Address string `json:"address"`
+	Class   string `json:"class,omitempty"`
+	Owner   string `json:"owner,omitempty"` // empty if not known
+	// Waiters is the number of threads blocked on the lock in the latest
+	// dump it appears in, and MaxWaiters the most of all dumps
+	Waiters       int      `json:"waiters"`
+	MaxWaiters    int      `json:"max_waiters"`
+	WaiterThreads []string `json:"waiter_threads,omitempty"`
+	// DumpCount is the number of dumps threads are blocked on the lock in
+	DumpCount int `json:"dump_count"`
+}
+
+// ThreadDumpData holds the analysis data of Java thread dumps.
+type ThreadDumpData struct {
+	DumpCount int `json:"dump_count"`
+	// TotalThreads is the number of threads of the latest dump, and States
+	// their number in each java.lang.Thread.State
+	TotalThreads int            `json:"total_threads"`
+	States       map[string]int `json:"states"`
+	// StateHistory holds the states of each dump, oldest first
+	StateHistory []map[string]int `json:"state_history,omitempty"`
+	Deadlocks    []Deadlock       `json:"deadlocks,omitempty"`
+	// HotMonitors are the locks of all dumps threads are blocked on, most
+	// waiters first
+	HotMonitors []HotMonitor `json:"hot_monitors,omitempty"`
+	// Threads are the threads of the latest dump
+	Threads []DumpedThread `json:"threads,omitempty"`
+}
+
+// Type returns the analysis data type.
+func (d *ThreadDumpData) Type() AnalysisDataType {
+	return DataTypeThreadDump
+}
+
+// Summary returns a summary of the thread dumps.
+func (d *ThreadDumpData) Summary() map[string]interface{} {
+	return map[string]interface{}{
+		"dump_count":    d.DumpCount,
+		"total_threads": d.TotalThreads,
+		"states":        d.States,
+		"deadlocks":     len(d.Deadlocks),
+		"hot_monitors":  len(d.HotMonitors),
+	}
+}
+
+// TopItems returns the hot monitors, by their number of waiters.
+func (d *ThreadDumpData) TopItems() []TopItem {
+	items := make([]TopItem, 0, len(d.HotMonitors))
+	for _, m := range d.HotMonitors {
+		name := m.Address
+		if m.Class != "" {
+			name = m.Class + "@" + m.Address
+		}
+		var percentage float64
+		if d.TotalThreads > 0 {
+			percentage = float64(m.Waiters) * 100.0 / float64(d.TotalThreads)
+		}
+		items = append(items, TopItem{
+			Name:       name,
+			Value:      int64(m.Waiters),
+			Percentage: percentage,
+			Extra:      map[string]interface{}{"owner": m.Owner},
+		})
+	}
+	return items
+}
+
+// GCPauseBucket is a bucket of the GC pause histogram.
+type GCPauseBucket struct {
+	UpperBoundMs float64 `json:"upper_bound_ms"` // 0 for the last, unbounded bucket
+	Count        int     `json:"count"`
+}
+
+// GCPauseType holds the statistics of a type of GC pauses.
+type GCPauseType struct {
+	Type    string  `json:"type"`
+	Count   int     `json:"count"`
+	TotalMs float64 `json:"total_ms"`
+	MaxMs   float64 `json:"max_ms"`
+}
+
+// GCHeapPoint is the heap occupancy around a collection.
+type GCHeapPoint struct {
+	UptimeSeconds float64 `json:"uptime_s"`
+	GCID          int     `json:"gc_id"`
+	Type          string  `json:"type"`
+	Before        int64   `json:"before"`
+	After         int64   `json:"after"`
+	Capacity      int64   `json:"capacity,omitempty"`
+}
+
+// GCHeapDumpCorrelation places a heap dump of the task in the GC log.
+type GCHeapDumpCorrelation struct {
+	File          string    `json:"file"`
+	Time          time.Time `json:"time"`
+	UptimeSeconds float64   `json:"uptime_s"`
+	// Correlated is false if the GC log is not decorated with both the
+	// time and the uptime, and the dump cannot be placed in it
+	Correlated bool `json:"correlated"`
+	// InLog reports whether the dump was taken while the log was written
+	InLog bool `json:"in_log"`
+	// PrecedingGC is the collection before the dump, and HeapAfterGC the
+	// heap occupancy it left, an estimate of the live set of the dump
+	PrecedingGCID   *int   `json:"preceding_gc_id,omitempty"`
+	PrecedingGCType string `json:"preceding_gc_type,omitempty"`
+	HeapAfterGC     int64  `json:"heap_after_gc,omitempty"`
+	// HeapBeforeNextGC is the heap occupancy at the collection after the
+	// dump
+	HeapBeforeNextGC int64 `json:"heap_before_next_gc,omitempty"`
+}
+
+// GCLogData holds the analysis data of JVM GC logs.
+type GCLogData struct {
+	Collector       string  `json:"collector,omitempty"`
+	DurationSeconds float64 `json:"duration_s"`
+
+	PauseCount   int     `json:"pause_count"`
+	TotalPauseMs float64 `json:"total_pause_ms"`
+	MaxPauseMs   float64 `json:"max_pause_ms"`
+	P50PauseMs   float64 `json:"p50_pause_ms"`
+	P90PauseMs   float64 `json:"p90_pause_ms"`
+	P95PauseMs   float64 `json:"p95_pause_ms"`
+	P99PauseMs   float64 `json:"p99_pause_ms"`
+	// Throughput is the percentage of the time the application was not
+	// paused
+	Throughput     float64         `json:"throughput"`
+	PauseHistogram []GCPauseBucket `json:"pause_histogram"`
+	PauseTypes     []GCPauseType   `json:"pause_types,omitempty"`
+	Causes         map[string]int  `json:"causes,omitempty"`
+
+	// Rates are in bytes per second. The promotion rate is only known if
+	// the log has the old generation sizes (gc+heap=debug or gc*)
+	AllocatedBytes     int64   `json:"allocated_bytes"`
+	AllocationRate     float64 `json:"allocation_rate"`
+	PeakAllocationRate float64 `json:"peak_allocation_rate"`
+	PromotedBytes      int64   `json:"promoted_bytes,omitempty"`
+	PromotionRate      float64 `json:"promotion_rate,omitempty"`
+	HasPromotion       bool    `json:"has_promotion"`
+	// LiveSetGrowthRate is the trend of the heap occupancy after the
+	// collections, a leak when steadily positive
+	LiveSetGrowthRate float64 `json:"live_set_growth_rate"`
+
+	// HeapTrend is the heap occupancy of the collections, downsampled for
+	// long logs
+	HeapTrend []GCHeapPoint           `json:"heap_trend,omitempty"`
+	HeapDumps []GCHeapDumpCorrelation `json:"heap_dumps,omitempty"`
+}
+
+// Type returns the analysis data type.
+func (d *GCLogData) Type() AnalysisDataType {
+	return DataTypeGCLog
+}
+
+// Summary returns a summary of the GC logs.
+func (d *GCLogData) Summary() map[string]interface{} {
+	return map[string]interface{}{
+		"collector":            d.Collector,
+		"duration_s":           d.DurationSeconds,
+		"pause_count":          d.PauseCount,
+		"max_pause_ms":         d.MaxPauseMs,
+		"p99_pause_ms":         d.P99PauseMs,
+		"throughput":           d.Throughput,
+		"allocation_rate":      d.AllocationRate,
+		"promotion_rate":       d.PromotionRate,
+		"live_set_growth_rate": d.LiveSetGrowthRate,
+		"heap_dumps":           len(d.HeapDumps),
+	}
+}
+
+// TopItems returns the types of pauses, by their total time.
+func (d *GCLogData) TopItems() []TopItem {
+	items := make([]TopItem, 0, len(d.PauseTypes))
+	for _, t := range d.PauseTypes {
+		var percentage float64
+		if d.TotalPauseMs > 0 {
+			percentage = t.TotalMs * 100.0 / d.TotalPauseMs
+		}
+		items = append(items, TopItem{
+			Name:       t.Type,
+			Value:      int64(t.Count),
+			Percentage: percentage,
+			Extra:      map[string]interface{}{"total_ms": t.TotalMs, "max_ms": t.MaxMs},
+		})
+	}
+	return items
+}
+
+// JFRData holds the analysis data of a Java Flight Recorder recording: its
+// CPU, allocation and lock profiles, nil when it has no such events.
+type JFRData struct {
+	CPU        *CPUProfilingData   `json:"cpu,omitempty"`
+	Allocation *AllocationData     `json:"allocation,omitempty"`
+	Lock       *LockContentionData `json:"lock,omitempty"`
+
+	StartTime     time.Time `json:"start_time"`
+	DurationNanos int64     `json:"duration_ns"`
+}
+
+// Type returns the analysis data type.
+func (d *JFRData) Type() AnalysisDataType {
+	return DataTypeJFR
+}
+
+// Primary returns the main profile of the recording: CPU, else allocation,
+// else lock. It returns nil if the recording has none.
+func (d *JFRData) Primary() AnalysisData {
+	switch {
+	case d.CPU != nil:
+		return d.CPU
+	case d.Allocation != nil:
+		return d.Allocation
+	case d.Lock != nil:
+		return d.Lock
+	default:
+		return nil
+	}
+}
+
+// Summary returns a summary of the recording and of its profiles.
+func (d *JFRData) Summary() map[string]interface{} {
+	summary := map[string]interface{}{
+		"start_time":  d.StartTime,
+		"duration_ns": d.DurationNanos,
+	}
+	if d.CPU != nil {
+		summary["cpu"] = d.CPU.Summary()
+	}
+	if d.Allocation != nil {
+		summary["allocation"] = d.Allocation.Summary()
+	}
+	if d.Lock != nil {
+		summary["lock"] = d.Lock.Summary()
+	}
+	return summary
+}
+
+// TopItems returns the top items of the primary profile.
+func (d *JFRData) TopItems() []TopItem {
+	if primary := d.Primary(); primary != nil {
+		return primary.TopItems()
+	}
+	return nil
+}
+
 // MarshalJSON implements custom JSON marshaling for AnalysisData.
 func MarshalAnalysisData(data AnalysisData) ([]byte, error) {
 	if data == nil {
@@ -520,6 +1304,42 @@ func UnmarshalAnalysisData(data []byte) (AnalysisData, error) {
 			return nil, err
 		}
 		result = &d
+	case DataTypeLockContention:
+		var d LockContentionData
+		if err := json.Unmarshal(wrapper.Data, &d); err != nil {
+			return nil, err
+		}
+		result = &d
+	case DataTypeJFR:
+		var d JFRData
+		if err := json.Unmarshal(wrapper.Data, &d); err != nil {
+			return nil, err
+		}
+		result = &d
+	case DataTypeWallClock:
+		var d WallClockData
+		if err := json.Unmarshal(wrapper.Data, &d); err != nil {
+			return nil, err
+		}
+		result = &d
+	case DataTypeOffCPU:
+		var d OffCPUData
+		if err := json.Unmarshal(wrapper.Data, &d); err != nil {
+			return nil, err
+		}
+		result = &d
+	case DataTypeThreadDump:
+		var d ThreadDumpData
+		if err := json.Unmarshal(wrapper.Data, &d); err != nil {
+			return nil, err
+		}
+		result = &d
+	case DataTypeGCLog:
+		var d GCLogData
+		if err := json.Unmarshal(wrapper.Data, &d); err != nil {
+			return nil, err
+		}
+		result = &d
 	default:
 		return nil, nil
 	}
@@ -600,6 +1420,34 @@ type PProfHeapData struct {
 	AllocObjects    *PProfMemoryStats `json:"alloc_objects"`
 	FlameGraphFiles map[string]string `json:"flamegraph_files"` // sample_type -> file path
 	HeapSummary     *PProfHeapSummary `json:"summary"`
+
+	// Analyses of several profiles: the profiles of the instances of a
+	// service merged into one, or the inuse growth between consecutive heap
+	// profiles of one instance, of which the latest is analyzed above.
+	ProfileCount        int              `json:"profile_count,omitempty"`
+	Merged              bool             `json:"merged,omitempty"`
+	Deltas              []PProfHeapDelta `json:"deltas,omitempty"`
+	DeltaFlameGraphFile string           `json:"delta_flamegraph_file,omitempty"` // inuse growth from the first to the latest profile
+}
+
+// PProfHeapDelta is the inuse growth between two consecutive heap profiles.
+type PProfHeapDelta struct {
+	BaselineFile  string             `json:"baseline_file"`
+	CurrentFile   string             `json:"current_file"`
+	BaselineTotal int64              `json:"baseline_total"` // inuse bytes
+	CurrentTotal  int64              `json:"current_total"`
+	TotalGrowth   int64              `json:"total_growth"`
+	Stacks        []PProfStackGrowth `json:"stacks"` // largest growth first
+}
+
+// PProfStackGrowth is the inuse growth of a call stack between two heap
+// profiles.
+type PProfStackGrowth struct {
+	Stack         []string `json:"stack"` // root first
+	BaselineValue int64    `json:"baseline_value"`
+	CurrentValue  int64    `json:"current_value"`
+	Growth        int64    `json:"growth"`
+	GrowthPercent float64  `json:"growth_percent"` // of the total growth
 }
 
 // PProfHeapSummary holds summary statistics for heap analysis.
@@ -626,6 +1474,18 @@ func (d *PProfHeapData) Summary() map[string]interface{} {
 		result["total_alloc_bytes"] = d.HeapSummary.TotalAllocBytes
 		result["total_alloc_objects"] = d.HeapSummary.TotalAllocObjects
 	}
+	if d.ProfileCount > 1 {
+		result["profile_count"] = d.ProfileCount
+		result["merged"] = d.Merged
+	}
+	if len(d.Deltas) > 0 {
+		var growth int64
+		for _, delta := range d.Deltas {
+			growth += delta.TotalGrowth
+		}
+		result["total_inuse_growth"] = growth
+		result["delta_flamegraph_file"] = d.DeltaFlameGraphFile
+	}
 	return result
 }
 
@@ -763,6 +1623,19 @@ type PProfLeakReport struct {
 	TotalGrowth        int64                 `json:"total_growth"`
 	TotalGrowthPercent float64               `json:"total_growth_percent"`
 	GrowthItems        []PProfLeakGrowthItem `json:"growth_items,omitempty"`
+
+	LeakClusters []PProfGoroutineCluster `json:"leak_clusters,omitempty"` // goroutine leak reports
+}
+
+// PProfGoroutineCluster is a set of goroutines created at the same place and
+// blocked at the same point, whose count grows across goroutine profiles.
+type PProfGoroutineCluster struct {
+	CreatedBy     string   `json:"created_by"`
+	CreationStack []string `json:"creation_stack"` // root first
+	BlockingPoint string   `json:"blocking_point"`
+	WaitCall      string   `json:"wait_call,omitempty"`
+	Counts        []int64  `json:"counts"` // goroutines in each profile
+	CountDelta    int64    `json:"count_delta"`
 }
 
 // PProfBatchData holds Go pprof batch analysis data (pprof-all mode).
@@ -74,8 +74,15 @@ type CallStackInfo struct {
 type Sample struct {
 	ThreadName string   `json:"thread_name"`
 	TID        int      `json:"tid,omitempty"`
+	PID        int      `json:"pid,omitempty"`    // process of the thread, 0 if unknown
+	Cgroup     string   `json:"cgroup,omitempty"` // cgroup of the process, e.g. of its container
 	CallStack  []string `json:"callstack"`
 	Value      int64    `json:"value"`
+	State      string   `json:"state,omitempty"` // thread state, e.g. of wall-clock samples
+	// Timestamp is the time of the sample in nanoseconds, 0 if unknown. It is
+	// relative to the clock of the profiler: Unix time for JFR recordings,
+	// time since boot for perf.
+	Timestamp int64 `json:"timestamp,omitempty"`
 }
 
 // ParseResult holds the result of parsing profiling data.
@@ -95,6 +102,7 @@ type AnalysisRequest struct {
 	TaskType      TaskType
 	ProfilerType  ProfilerType
 	InputFile     string
+	InputFiles    []string // all input files of analyses of several profiles, oldest first; InputFile is the first
 	OutputDir     string
 	ResultFile    string
 	UserName      string
@@ -112,6 +120,30 @@ type AnalysisResponse struct {
 	Data         AnalysisData     `json:"data"`
 	Suggestions  []SuggestionItem `json:"suggestions"`
 	Error        string           `json:"error,omitempty"`
+
+	// Health rates the result, set by the rules engine
+	Health *HealthScore `json:"health,omitempty"`
+}
+
+// Grades of health scores.
+const (
+	HealthGood     = "good"
+	HealthWarning  = "warning"
+	HealthCritical = "critical"
+)
+
+// HealthScore rates an analysis result from 0 (critical) to 100 (nothing
+// found), for triaging many tasks at a glance.
+type HealthScore struct {
+	Score      int               `json:"score"`
+	Grade      string            `json:"grade"`
+	Deductions []HealthDeduction `json:"deductions,omitempty"`
+}
+
+// HealthDeduction is a finding lowering a health score.
+type HealthDeduction struct {
+	Reason string  `json:"reason"`
+	Points float64 `json:"points"`
 }
 
 // SuggestionItem represents a single suggestion from analysis.
@@ -121,6 +153,30 @@ type SuggestionItem struct {
 	Namespace    string `json:"namespace,omitempty"`
 	CallStack    string `json:"callstack,omitempty"`
 	AISuggestion string `json:"ai_suggestion,omitempty"`
+
+	// Rule, Severity, Evidence and DocURL are set by the rules engine
+	Rule     string               `json:"rule,omitempty"`
+	Severity string               `json:"severity,omitempty"`
+	Evidence []SuggestionEvidence `json:"evidence,omitempty"`
+	DocURL   string               `json:"doc_url,omitempty"`
+}
+
+// Kinds of SuggestionEvidence.
+const (
+	EvidenceFunction = "function"
+	EvidenceClass    = "class"
+	EvidenceThread   = "thread"
+	EvidenceObject   = "object"
+	EvidenceString   = "string"
+)
+
+// SuggestionEvidence is an item of the analysis a suggestion is based on,
+// e.g. a class of the heap histogram, which the web UI links to.
+type SuggestionEvidence struct {
+	Kind   string  `json:"kind"`
+	Name   string  `json:"name"`
+	Value  float64 `json:"value,omitempty"` // bytes or percentage, per rule
+	Detail string  `json:"detail,omitempty"`
 }
 
 // AnalysisContext holds the context during analysis.
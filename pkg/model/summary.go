@@ -0,0 +1,113 @@
+package model
+
+import (
+	"time"
+)
+
+// DefaultSummaryTopN is the number of top classes or functions kept in a
+// summary.
+const DefaultSummaryTopN = 10
+
+// AnalysisSummary holds the headline metrics of an analysis. Summaries are
+// kept for every analysis, to compare the analyses of a service over time.
+type AnalysisSummary struct {
+	TaskUUID     string       `json:"tid"`
+	TaskType     TaskType     `json:"task_type"`
+	ProfilerType ProfilerType `json:"profiler_type"`
+	Service      string       `json:"service,omitempty"`
+	UserName     string       `json:"user_name,omitempty"`
+
+	// TotalSamples is the number of samples, allocations or records analyzed
+	TotalSamples int64 `json:"total_samples"`
+	// TotalHeapSize and TotalObjects are set for heap dumps
+	TotalHeapSize int64 `json:"total_heap_size,omitempty"`
+	TotalObjects  int64 `json:"total_objects,omitempty"`
+
+	// TopClasses are the largest classes of heap dumps, without retainers
+	TopClasses []HeapClassStats `json:"top_classes,omitempty"`
+	// TopFuncs are the hottest functions of profiles
+	TopFuncs    []TopItem `json:"top_funcs,omitempty"`
+	Suggestions []string  `json:"suggestions,omitempty"`
+	// HealthScore is the score of the health of the analysis, if rated
+	HealthScore *int `json:"health_score,omitempty"`
+
+	// BaselineTID is the task of the baseline of the service the analysis
+	// was compared with, and Regressions its changes from the baseline
+	BaselineTID string       `json:"baseline_tid,omitempty"`
+	Regressions []Regression `json:"regressions,omitempty"`
+
+	AnalyzedAt time.Time `json:"analyzed_at"`
+}
+
+// SummarizeResponse returns the metrics of an analysis response, keeping the
+// topN classes or functions. Task identity fields are left to the caller.
+func SummarizeResponse(resp *AnalysisResponse, topN int) *AnalysisSummary {
+	summary := &AnalysisSummary{
+		TaskUUID:     resp.TaskUUID,
+		TaskType:     resp.TaskType,
+		TotalSamples: int64(resp.TotalRecords),
+		AnalyzedAt:   time.Now(),
+	}
+
+	// Recordings of several profiles are summarized by their primary one
+	data := resp.Data
+	if jfr, ok := data.(*JFRData); ok {
+		data = jfr.Primary()
+	}
+
+	switch data := data.(type) {
+	case *HeapAnalysisData:
+		summary.TotalHeapSize = data.TotalHeapSize
+		summary.TotalObjects = data.TotalInstances
+		for i, class := range data.TopClasses {
+			if i == topN {
+				break
+			}
+			class.Retainers = nil
+			class.GCRootPaths = nil
+			summary.TopClasses = append(summary.TopClasses, class)
+		}
+	case *CPUProfilingData:
+		summary.TotalSamples = data.TotalSamples
+	case *AllocationData:
+		summary.TotalSamples = data.TotalAllocations
+	case *TracingData:
+		summary.TotalSamples = data.TotalSamples
+	case *LockContentionData:
+		summary.TotalSamples = data.TotalEvents
+	case *WallClockData:
+		summary.TotalSamples = data.TotalSamples
+	}
+
+	if _, heap := resp.Data.(*HeapAnalysisData); resp.Data != nil && !heap {
+		items := resp.Data.TopItems()
+		if len(items) > topN {
+			items = items[:topN]
+		}
+		summary.TopFuncs = items
+	}
+
+	for _, item := range resp.Suggestions {
+		if item.Suggestion != "" {
+			summary.Suggestions = append(summary.Suggestions, item.Suggestion)
+		}
+	}
+	if resp.Health != nil {
+		score := resp.Health.Score
+		summary.HealthScore = &score
+	}
+
+	return summary
+}
+
+// SummaryQuery selects analysis summaries. Zero fields do not filter.
+type SummaryQuery struct {
+	Service  string
+	UserName string
+	TaskType *TaskType
+	Since    time.Time
+	Until    time.Time
+
+	// Limit bounds the number of summaries returned, newest first
+	Limit int
+}
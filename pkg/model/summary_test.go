@@ -0,0 +1,99 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSummarizeResponse_Heap(t *testing.T) {
+	resp := &AnalysisResponse{
+		TaskUUID:     "heap-1",
+		TaskType:     TaskTypeJavaHeap,
+		TotalRecords: 42,
+		Data: &HeapAnalysisData{
+			TotalHeapSize:  1 << 30,
+			TotalInstances: 5000,
+			TopClasses: []HeapClassStats{
+				{ClassName: "byte[]", InstanceCount: 10, TotalSize: 900, Retainers: []HeapRetainer{{RetainerClass: "java.lang.String"}}},
+				{ClassName: "java.lang.String", InstanceCount: 20, TotalSize: 400},
+				{ClassName: "java.util.HashMap", InstanceCount: 5, TotalSize: 100},
+			},
+		},
+		Suggestions: []SuggestionItem{{Suggestion: "Check byte[] retention"}, {Suggestion: ""}},
+	}
+
+	summary := SummarizeResponse(resp, 2)
+
+	assert.Equal(t, "heap-1", summary.TaskUUID)
+	assert.Equal(t, TaskTypeJavaHeap, summary.TaskType)
+	assert.Equal(t, int64(42), summary.TotalSamples)
+	assert.Equal(t, int64(1<<30), summary.TotalHeapSize)
+	assert.Equal(t, int64(5000), summary.TotalObjects)
+	require.Len(t, summary.TopClasses, 2)
+	assert.Equal(t, "byte[]", summary.TopClasses[0].ClassName)
+	assert.Nil(t, summary.TopClasses[0].Retainers)
+	assert.Empty(t, summary.TopFuncs)
+	assert.Equal(t, []string{"Check byte[] retention"}, summary.Suggestions)
+	assert.False(t, summary.AnalyzedAt.IsZero())
+
+	// The response itself keeps its retainers
+	assert.NotNil(t, resp.Data.(*HeapAnalysisData).TopClasses[0].Retainers)
+}
+
+func TestSummarizeResponse_CPU(t *testing.T) {
+	resp := &AnalysisResponse{
+		TaskUUID: "cpu-1",
+		TaskType: TaskTypeJava,
+		Data: &CPUProfilingData{
+			TotalSamples: 1000,
+			TopFuncs: TopFuncsMap{
+				"main":  {Self: 10},
+				"parse": {Self: 60},
+				"write": {Self: 30},
+			},
+		},
+		Health: &HealthScore{Score: 72, Grade: HealthWarning},
+	}
+
+	summary := SummarizeResponse(resp, 2)
+
+	assert.Equal(t, int64(1000), summary.TotalSamples)
+	assert.Zero(t, summary.TotalHeapSize)
+	assert.Empty(t, summary.TopClasses)
+	require.Len(t, summary.TopFuncs, 2)
+	assert.Equal(t, "parse", summary.TopFuncs[0].Name)
+	assert.Equal(t, "write", summary.TopFuncs[1].Name)
+	require.NotNil(t, summary.HealthScore)
+	assert.Equal(t, 72, *summary.HealthScore)
+}
+
+func TestSummarizeResponse_NoData(t *testing.T) {
+	summary := SummarizeResponse(&AnalysisResponse{TaskUUID: "t", TotalRecords: 7}, DefaultSummaryTopN)
+	assert.Equal(t, int64(7), summary.TotalSamples)
+	assert.Empty(t, summary.TopFuncs)
+	assert.Empty(t, summary.TopClasses)
+	assert.Nil(t, summary.HealthScore)
+}
+
+func TestSummarizeResponse_JFR(t *testing.T) {
+	resp := &AnalysisResponse{
+		TaskUUID: "jfr-1",
+		TaskType: TaskTypeJava,
+		Data: &JFRData{
+			Allocation: &AllocationData{
+				TotalAllocations: 40,
+				TopAllocators:    TopFuncsMap{"java.lang.String_[i]": {Self: 75}},
+			},
+			Lock: &LockContentionData{TotalEvents: 3},
+		},
+	}
+
+	summary := SummarizeResponse(resp, DefaultSummaryTopN)
+
+	// Recordings without CPU samples are summarized by their allocations
+	assert.Equal(t, int64(40), summary.TotalSamples)
+	require.Len(t, summary.TopFuncs, 1)
+	assert.Equal(t, "java.lang.String_[i]", summary.TopFuncs[0].Name)
+}
@@ -25,6 +25,9 @@ const (
 	TaskTypePProfGoroutine TaskType = 12 // Go pprof Goroutine
 	TaskTypePProfBlock     TaskType = 13 // Go pprof Block
 	TaskTypePProfMutex     TaskType = 14 // Go pprof Mutex
+	TaskTypeOffCPU         TaskType = 15 // eBPF off-CPU time (bcc offcputime)
+	TaskTypeThreadDump     TaskType = 16 // Java thread dumps (jstack, JFR)
+	TaskTypeGCLog          TaskType = 17 // JVM unified GC logs
 )
 
 // String returns the string representation of TaskType.
@@ -60,11 +63,27 @@ func (t TaskType) String() string {
 		return "pprof_block"
 	case TaskTypePProfMutex:
 		return "pprof_mutex"
+	case TaskTypeOffCPU:
+		return "offcpu"
+	case TaskTypeThreadDump:
+		return "thread_dump"
+	case TaskTypeGCLog:
+		return "gc_log"
 	default:
 		return "unknown"
 	}
 }
 
+// ParseTaskType returns the TaskType of a name returned by TaskType.String.
+func ParseTaskType(name string) (TaskType, bool) {
+	for t := TaskTypeGeneric; t <= TaskTypeGCLog; t++ {
+		if t.String() == name {
+			return t, true
+		}
+	}
+	return 0, false
+}
+
 // ProfilerType represents the profiler type.
 type ProfilerType int
 
@@ -72,6 +91,9 @@ const (
 	ProfilerTypePerf       ProfilerType = 0 // perf / async-profiler CPU
 	ProfilerTypeAsyncAlloc ProfilerType = 1 // async-profiler allocation
 	ProfilerTypePProf      ProfilerType = 2 // Go pprof
+	ProfilerTypeJFR        ProfilerType = 3 // Java Flight Recorder
+	ProfilerTypeAsyncWall  ProfilerType = 4 // async-profiler wall-clock
+	ProfilerTypeAsyncLock  ProfilerType = 5 // async-profiler lock
 )
 
 // String returns the string representation of ProfilerType.
@@ -83,6 +105,12 @@ func (p ProfilerType) String() string {
 		return "async_alloc"
 	case ProfilerTypePProf:
 		return "pprof"
+	case ProfilerTypeJFR:
+		return "jfr"
+	case ProfilerTypeAsyncWall:
+		return "async_wall"
+	case ProfilerTypeAsyncLock:
+		return "async_lock"
 	default:
 		return "unknown"
 	}
@@ -135,6 +163,16 @@ type RequestParams struct {
 	ContainerType  int    `json:"container_type,omitempty"`
 	ContainerName  string `json:"container_name,omitempty"`
 	AnnotateEnable bool   `json:"annotate_enable,omitempty"`
+	Service        string `json:"service,omitempty"` // service profiled, to compare its analyses over time
+
+	// Interval is the sampling interval of wall-clock profiles in nanoseconds
+	Interval int64 `json:"interval,omitempty"`
+	// Total is set when the counts of collapsed stacks are nanoseconds
+	// (async-profiler --total) rather than numbers of samples or events
+	Total bool `json:"total,omitempty"`
+	// Merge is set when the input profiles are of different instances, to
+	// be merged into one rather than compared over time
+	Merge bool `json:"merge,omitempty"`
 }
 
 // UnmarshalJSON implements json.Unmarshaler for RequestParams.
@@ -173,11 +211,11 @@ func (t *Task) GetResourceType() string {
 		return "App"
 	case TaskTypeTracing:
 		return "Disk"
-	case TaskTypeMemLeak, TaskTypePProfMem, TaskTypeJavaHeap, TaskTypePhysMem, TaskTypeJeprof, TaskTypePProfHeap:
+	case TaskTypeMemLeak, TaskTypePProfMem, TaskTypeJavaHeap, TaskTypePhysMem, TaskTypeJeprof, TaskTypePProfHeap, TaskTypeGCLog:
 		return "Memory"
 	case TaskTypePProfGoroutine:
 		return "Goroutine"
-	case TaskTypePProfBlock, TaskTypePProfMutex:
+	case TaskTypePProfBlock, TaskTypePProfMutex, TaskTypeOffCPU, TaskTypeThreadDump:
 		return "Concurrency"
 	default:
 		return "Unknown"
@@ -196,3 +234,33 @@ func NewTask(id int64, taskUUID string, taskType TaskType, profilerType Profiler
 		CreateTime:     time.Now(),
 	}
 }
+
+// TaskLease is the claim of an analyzer instance on a task. The owner renews
+// it while analyzing the task; once it expires, e.g. because the owner
+// crashed, another instance may take the task over.
+type TaskLease struct {
+	TaskID    int64     `json:"task_id"`
+	TaskUUID  string    `json:"tid"`
+	Owner     string    `json:"owner"`
+	ExpiresAt time.Time `json:"expires_at"`
+	// Attempts is the number of times the task was leased
+	Attempts int `json:"attempts"`
+}
+
+// DeadLetter is a task whose analysis failed for good: with a permanent
+// error, or with transient errors on every attempt. It is kept with the
+// error and the output files of the last attempt, for inspection.
+type DeadLetter struct {
+	TaskID       int64        `json:"task_id"`
+	TaskUUID     string       `json:"tid"`
+	TaskType     TaskType     `json:"task_type"`
+	ProfilerType ProfilerType `json:"profiler_type"`
+	// Source is the task source, as "type/name"
+	Source   string `json:"source,omitempty"`
+	Error    string `json:"error"`
+	Attempts int    `json:"attempts"`
+	// Artifacts are the storage keys of the files written by the last
+	// attempt before it failed
+	Artifacts []string  `json:"artifacts,omitempty"`
+	FailedAt  time.Time `json:"failed_at"`
+}
@@ -22,6 +22,9 @@ func TestTaskType_String(t *testing.T) {
 		{TaskTypePhysMem, "phys_mem"},
 		{TaskTypeJeprof, "jeprof"},
 		{TaskTypeBolt, "bolt"},
+		{TaskTypeOffCPU, "offcpu"},
+		{TaskTypeThreadDump, "thread_dump"},
+		{TaskTypeGCLog, "gc_log"},
 		{TaskType(99), "unknown"},
 	}
 
@@ -32,6 +35,19 @@ func TestTaskType_String(t *testing.T) {
 	}
 }
 
+func TestParseTaskType(t *testing.T) {
+	for taskType := TaskTypeGeneric; taskType <= TaskTypeGCLog; taskType++ {
+		parsed, ok := ParseTaskType(taskType.String())
+		assert.True(t, ok, taskType.String())
+		assert.Equal(t, taskType, parsed)
+	}
+
+	_, ok := ParseTaskType("unknown")
+	assert.False(t, ok)
+	_, ok = ParseTaskType("")
+	assert.False(t, ok)
+}
+
 func TestProfilerType_String(t *testing.T) {
 	tests := []struct {
 		profilerType ProfilerType
@@ -40,6 +56,9 @@ func TestProfilerType_String(t *testing.T) {
 		{ProfilerTypePerf, "perf"},
 		{ProfilerTypeAsyncAlloc, "async_alloc"},
 		{ProfilerTypePProf, "pprof"},
+		{ProfilerTypeJFR, "jfr"},
+		{ProfilerTypeAsyncWall, "async_wall"},
+		{ProfilerTypeAsyncLock, "async_lock"},
 		{ProfilerType(99), "unknown"},
 	}
 
@@ -141,6 +160,9 @@ func TestTask_GetResourceType(t *testing.T) {
 		{TaskTypeMemLeak, "Memory"},
 		{TaskTypePProfMem, "Memory"},
 		{TaskTypeJavaHeap, "Memory"},
+		{TaskTypeOffCPU, "Concurrency"},
+		{TaskTypeThreadDump, "Concurrency"},
+		{TaskTypeGCLog, "Memory"},
 	}
 
 	for _, tt := range tests {
@@ -0,0 +1,174 @@
+package parallel
+
+import (
+	"context"
+	"math"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// ============================================================================
+// Available CPUs
+// ============================================================================
+
+// cgroupRoot is where the cgroup filesystem is mounted.
+var cgroupRoot = "/sys/fs/cgroup"
+
+// AvailableCPUs returns the number of CPUs the process may use: GOMAXPROCS,
+// bounded by the CPU quota of its cgroup, so that containers limited to a
+// few CPUs of a large host do not run a worker per host CPU.
+func AvailableCPUs() int {
+	cpus := runtime.GOMAXPROCS(0)
+	if quota, ok := cgroupCPUQuota(cgroupRoot); ok {
+		cpus = min(cpus, max(1, int(math.Ceil(quota))))
+	}
+	return cpus
+}
+
+// cgroupCPUQuota returns the CPU quota of the cgroup mounted at root, in
+// CPUs, reading cgroup v2 cpu.max or else cgroup v1 cpu.cfs_quota_us.
+func cgroupCPUQuota(root string) (float64, bool) {
+	if data, err := os.ReadFile(filepath.Join(root, "cpu.max")); err == nil {
+		// "max 100000" or "<quota> <period>"
+		fields := strings.Fields(string(data))
+		if len(fields) != 2 || fields[0] == "max" {
+			return 0, false
+		}
+		return quotaCPUs(fields[0], fields[1])
+	}
+
+	quota, err := os.ReadFile(filepath.Join(root, "cpu", "cpu.cfs_quota_us"))
+	if err != nil {
+		return 0, false
+	}
+	period, err := os.ReadFile(filepath.Join(root, "cpu", "cpu.cfs_period_us"))
+	if err != nil {
+		return 0, false
+	}
+	return quotaCPUs(strings.TrimSpace(string(quota)), strings.TrimSpace(string(period)))
+}
+
+// quotaCPUs divides a CPU quota by its period; negative quotas are unlimited.
+func quotaCPUs(quota, period string) (float64, bool) {
+	q, err := strconv.ParseInt(quota, 10, 64)
+	if err != nil || q <= 0 {
+		return 0, false
+	}
+	p, err := strconv.ParseInt(period, 10, 64)
+	if err != nil || p <= 0 {
+		return 0, false
+	}
+	return float64(q) / float64(p), true
+}
+
+// ============================================================================
+// Shared Pool
+// ============================================================================
+
+// chunksPerWorker is the number of chunks Range splits work into per worker,
+// so that workers finishing early take over chunks of slower workers.
+const chunksPerWorker = 4
+
+// Pool bounds the goroutines running parallel work. The goroutine calling
+// Run or Range always takes part in the work, and extra workers are only
+// started while the pool has free slots: phases running concurrently, or
+// nested in one another, share the CPUs instead of oversubscribing them,
+// and never wait on each other for slots.
+type Pool struct {
+	size  int
+	slots chan struct{}
+}
+
+// NewPool creates a pool running up to size workers per caller.
+func NewPool(size int) *Pool {
+	if size < 1 {
+		size = 1
+	}
+	return &Pool{size: size, slots: make(chan struct{}, size-1)}
+}
+
+// Shared returns the pool of the process, sized from AvailableCPUs.
+var Shared = sync.OnceValue(func() *Pool {
+	return NewPool(AvailableCPUs())
+})
+
+// Size returns the number of workers of the pool.
+func (p *Pool) Size() int {
+	return p.size
+}
+
+// Workers returns the number of workers a phase capped to limit workers may
+// run, the size of the pool if limit is 0. Workers are numbered from 0 to
+// Workers(limit)-1, so phases keep per-worker state in slices of that size.
+func (p *Pool) Workers(limit int) int {
+	if limit <= 0 || limit > p.size {
+		return p.size
+	}
+	return limit
+}
+
+// Run calls fn on up to Workers(workers) goroutines, the caller included,
+// and returns once all calls returned. Fewer workers run while the pool is
+// busy with other phases.
+func (p *Pool) Run(workers int, fn func(worker int)) {
+	workers = p.Workers(workers)
+
+	var wg sync.WaitGroup
+	for w := 1; w < workers && p.tryAcquire(); w++ {
+		wg.Add(1)
+		go func(worker int) {
+			defer func() {
+				<-p.slots
+				wg.Done()
+			}()
+			fn(worker)
+		}(w)
+	}
+	fn(0)
+	wg.Wait()
+}
+
+// tryAcquire takes a slot of the pool for an extra worker, if one is free.
+func (p *Pool) tryAcquire() bool {
+	select {
+	case p.slots <- struct{}{}:
+		return true
+	default:
+		return false
+	}
+}
+
+// ChunkSize returns the number of items of the chunks Range splits n items
+// into for the given number of workers.
+func ChunkSize(n, workers int) int {
+	return max(1, (n+workers*chunksPerWorker-1)/(workers*chunksPerWorker))
+}
+
+// Range calls fn on the chunks [start, end) of [0, n), of ChunkSize(n,
+// min(Workers(workers), n)) items, on up to Workers(workers) workers. Chunks
+// are handed out as workers become idle. Range stops handing out chunks
+// when ctx is done and returns its error.
+func (p *Pool) Range(ctx context.Context, n, workers int, fn func(worker, start, end int)) error {
+	if n <= 0 {
+		return ctx.Err()
+	}
+	workers = min(p.Workers(workers), n)
+	size := ChunkSize(n, workers)
+
+	var next atomic.Int64
+	p.Run(workers, func(worker int) {
+		for ctx.Err() == nil {
+			start := int(next.Add(int64(size))) - size
+			if start >= n {
+				return
+			}
+			fn(worker, start, min(start+size, n))
+		}
+	})
+	return ctx.Err()
+}
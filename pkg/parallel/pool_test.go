@@ -0,0 +1,103 @@
+package parallel
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCgroupCPUQuota(t *testing.T) {
+	write := func(t *testing.T, root, name, content string) {
+		path := filepath.Join(root, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+	}
+
+	v2 := t.TempDir()
+	write(t, v2, "cpu.max", "250000 100000\n")
+	quota, ok := cgroupCPUQuota(v2)
+	assert.True(t, ok)
+	assert.Equal(t, 2.5, quota)
+
+	unlimited := t.TempDir()
+	write(t, unlimited, "cpu.max", "max 100000\n")
+	_, ok = cgroupCPUQuota(unlimited)
+	assert.False(t, ok)
+
+	v1 := t.TempDir()
+	write(t, v1, "cpu/cpu.cfs_quota_us", "50000\n")
+	write(t, v1, "cpu/cpu.cfs_period_us", "100000\n")
+	quota, ok = cgroupCPUQuota(v1)
+	assert.True(t, ok)
+	assert.Equal(t, 0.5, quota)
+
+	write(t, v1, "cpu/cpu.cfs_quota_us", "-1\n")
+	_, ok = cgroupCPUQuota(v1)
+	assert.False(t, ok)
+
+	_, ok = cgroupCPUQuota(t.TempDir())
+	assert.False(t, ok)
+}
+
+func TestPool_Workers(t *testing.T) {
+	pool := NewPool(4)
+	assert.Equal(t, 4, pool.Workers(0))
+	assert.Equal(t, 2, pool.Workers(2))
+	assert.Equal(t, 4, pool.Workers(16))
+	assert.Equal(t, 1, NewPool(0).Workers(0))
+}
+
+func TestPool_Range(t *testing.T) {
+	pool := NewPool(4)
+	covered := make([]atomic.Int32, 1000)
+	var maxWorker atomic.Int32
+
+	err := pool.Range(context.Background(), len(covered), 3, func(worker, start, end int) {
+		if int32(worker) > maxWorker.Load() {
+			maxWorker.Store(int32(worker))
+		}
+		assert.LessOrEqual(t, end-start, ChunkSize(len(covered), 3))
+		for i := start; i < end; i++ {
+			covered[i].Add(1)
+		}
+	})
+	require.NoError(t, err)
+	for i := range covered {
+		assert.Equal(t, int32(1), covered[i].Load(), "item %d", i)
+	}
+	assert.Less(t, maxWorker.Load(), int32(3))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	calls := 0
+	err = pool.Range(ctx, 10, 0, func(int, int, int) { calls++ })
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Zero(t, calls)
+}
+
+func TestPool_RunNested(t *testing.T) {
+	pool := NewPool(2)
+	var running, peak atomic.Int32
+
+	// Nested phases share the slots of the pool instead of waiting for them
+	pool.Run(0, func(int) {
+		pool.Run(0, func(int) {
+			n := running.Add(1)
+			for {
+				p := peak.Load()
+				if n <= p || peak.CompareAndSwap(p, n) {
+					break
+				}
+			}
+			running.Add(-1)
+		})
+	})
+	// Every outer worker runs its nested phase, at least with itself
+	assert.LessOrEqual(t, peak.Load(), int32(3))
+	assert.Positive(t, peak.Load())
+}
@@ -3,7 +3,6 @@ package parallel
 
 import (
 	"context"
-	"runtime"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -16,13 +15,16 @@ import (
 // PoolConfig configures the worker pool behavior.
 type PoolConfig struct {
 	// MaxWorkers is the maximum number of concurrent workers.
-	// Default: min(runtime.NumCPU(), 8)
+	// Default: min(AvailableCPUs(), 8)
 	MaxWorkers int
 
 	// TaskBufferSize is the buffer size for the task channel.
-	// Default: MaxWorkers * 2
+	// Deprecated: tasks are handed out to workers in chunks by the pool.
 	TaskBufferSize int
 
+	// Pool runs the workers. Default: Shared()
+	Pool *Pool
+
 	// Timeout is the maximum time for the entire operation.
 	// Default: 0 (no timeout)
 	Timeout time.Duration
@@ -33,7 +35,7 @@ type PoolConfig struct {
 
 // DefaultPoolConfig returns a default pool configuration.
 func DefaultPoolConfig() PoolConfig {
-	workers := runtime.NumCPU()
+	workers := AvailableCPUs()
 	if workers > 8 {
 		workers = 8 // Cap at 8 to avoid excessive overhead
 	}
@@ -60,6 +62,20 @@ func (c PoolConfig) WithTimeout(d time.Duration) PoolConfig {
 	return c
 }
 
+// WithPool returns a new config running workers on the specified pool.
+func (c PoolConfig) WithPool(pool *Pool) PoolConfig {
+	c.Pool = pool
+	return c
+}
+
+// pool returns the pool running the workers.
+func (c PoolConfig) pool() *Pool {
+	if c.Pool != nil {
+		return c.Pool
+	}
+	return Shared()
+}
+
 // WithMetrics returns a new config with metrics collection enabled.
 func (c PoolConfig) WithMetrics() PoolConfig {
 	c.CollectMetrics = true
@@ -175,59 +191,26 @@ func (p *WorkerPool[T, R]) Execute(ctx context.Context, tasks []Task[T, R]) []Ta
 	// Create result slice with same length as tasks
 	results := make([]TaskResult[T, R], len(tasks))
 
-	// Create task channel
-	taskCh := make(chan int, p.config.TaskBufferSize)
-
-	// Start workers
-	var wg sync.WaitGroup
-	numWorkers := min(p.config.MaxWorkers, len(tasks))
-
-	for i := 0; i < numWorkers; i++ {
-		wg.Add(1)
-		go func() {
-			defer wg.Done()
-			for {
-				select {
-				case <-ctx.Done():
-					return
-				case idx, ok := <-taskCh:
-					if !ok {
-						return
-					}
-					task := tasks[idx]
-					taskStart := time.Now()
-					result, err := task.Execute(ctx)
-					duration := time.Since(taskStart)
-
-					results[idx] = TaskResult[T, R]{
-						Input:    task.Input(),
-						Result:   result,
-						Error:    err,
-						Duration: duration,
-					}
-
-					// Update metrics if enabled
-					if p.config.CollectMetrics {
-						p.updateMetrics(duration, err)
-					}
-				}
+	p.config.pool().Range(ctx, len(tasks), p.config.MaxWorkers, func(_, start, end int) {
+		for idx := start; idx < end && ctx.Err() == nil; idx++ {
+			task := tasks[idx]
+			taskStart := time.Now()
+			result, err := task.Execute(ctx)
+			duration := time.Since(taskStart)
+
+			results[idx] = TaskResult[T, R]{
+				Input:    task.Input(),
+				Result:   result,
+				Error:    err,
+				Duration: duration,
 			}
-		}()
-	}
 
-	// Submit tasks
-	go func() {
-		for i := range tasks {
-			select {
-			case <-ctx.Done():
-				break
-			case taskCh <- i:
+			// Update metrics if enabled
+			if p.config.CollectMetrics {
+				p.updateMetrics(duration, err)
 			}
 		}
-		close(taskCh)
-	}()
-
-	wg.Wait()
+	})
 
 	// Update total duration
 	if p.config.CollectMetrics {
@@ -306,41 +289,14 @@ func (p *ChunkProcessor[T, R]) ProcessChunks(
 		return zero
 	}
 
-	numWorkers := p.config.MaxWorkers
-	if numWorkers <= 0 {
-		numWorkers = DefaultPoolConfig().MaxWorkers
-	}
-	if numWorkers > len(items) {
-		numWorkers = len(items)
-	}
-
-	chunkSize := (len(items) + numWorkers - 1) / numWorkers
-	results := make([]R, numWorkers)
+	pool := p.config.pool()
+	workers := min(pool.Workers(p.config.MaxWorkers), len(items))
+	chunkSize := ChunkSize(len(items), workers)
+	results := make([]R, (len(items)+chunkSize-1)/chunkSize)
 
-	var wg sync.WaitGroup
-	for w := 0; w < numWorkers; w++ {
-		start := w * chunkSize
-		end := start + chunkSize
-		if end > len(items) {
-			end = len(items)
-		}
-		if start >= end {
-			continue
-		}
-
-		wg.Add(1)
-		go func(workerID int, chunk []T) {
-			defer wg.Done()
-			select {
-			case <-ctx.Done():
-				return
-			default:
-				results[workerID] = processor(ctx, chunk, workerID)
-			}
-		}(w, items[start:end])
-	}
-
-	wg.Wait()
+	pool.Range(ctx, len(items), workers, func(worker, start, end int) {
+		results[start/chunkSize] = processor(ctx, items[start:end], worker)
+	})
 	return reducer(results)
 }
 
@@ -435,10 +391,8 @@ func ParallelAggregate[T any, K comparable, V any](
 		return make(map[K]V)
 	}
 
-	numWorkers := config.MaxWorkers
-	if numWorkers <= 0 {
-		numWorkers = DefaultPoolConfig().MaxWorkers
-	}
+	pool := config.pool()
+	numWorkers := pool.Workers(config.MaxWorkers)
 
 	// Per-worker local maps
 	localMaps := make([]map[K]V, numWorkers)
@@ -446,40 +400,17 @@ func ParallelAggregate[T any, K comparable, V any](
 		localMaps[i] = make(map[K]V)
 	}
 
-	chunkSize := (len(items) + numWorkers - 1) / numWorkers
-
-	var wg sync.WaitGroup
-	for w := 0; w < numWorkers; w++ {
-		start := w * chunkSize
-		end := start + chunkSize
-		if end > len(items) {
-			end = len(items)
-		}
-		if start >= end {
-			continue
-		}
-
-		wg.Add(1)
-		go func(workerID int, chunk []T) {
-			defer wg.Done()
-			localMap := localMaps[workerID]
-			for _, item := range chunk {
-				select {
-				case <-ctx.Done():
-					return
-				default:
-					key, value := extractor(item)
-					if existing, ok := localMap[key]; ok {
-						localMap[key] = merger(existing, value)
-					} else {
-						localMap[key] = value
-					}
-				}
+	pool.Range(ctx, len(items), numWorkers, func(worker, start, end int) {
+		localMap := localMaps[worker]
+		for _, item := range items[start:end] {
+			key, value := extractor(item)
+			if existing, ok := localMap[key]; ok {
+				localMap[key] = merger(existing, value)
+			} else {
+				localMap[key] = value
 			}
-		}(w, items[start:end])
-	}
-
-	wg.Wait()
+		}
+	})
 
 	// Merge all local maps
 	result := make(map[K]V)
@@ -0,0 +1,69 @@
+package profiling
+
+import (
+	"strconv"
+	"strings"
+)
+
+// FrameType is how the code of a frame ran, as annotated by async-profiler
+// with a frame suffix, e.g. "_[j]", or guessed from the frame name.
+type FrameType string
+
+// Frame types.
+const (
+	FrameJIT         FrameType = "jit"         // "_[j]": compiled by C2 or Graal
+	FrameC1          FrameType = "c1"          // "_[1]": compiled by C1
+	FrameInlined     FrameType = "inlined"     // "_[i]": inlined into its compiled caller
+	FrameInterpreted FrameType = "interpreted" // "_[0]": run by the interpreter
+	FrameKernel      FrameType = "kernel"      // "_[k]": kernel function
+	FrameNative      FrameType = "native"      // native function, e.g. of libjvm.so
+	FrameJava        FrameType = "java"        // Java method of an unannotated stack
+)
+
+// frameSuffixes maps the frame suffixes of async-profiler to frame types.
+var frameSuffixes = map[string]FrameType{
+	"_[j]": FrameJIT,
+	"_[1]": FrameC1,
+	"_[i]": FrameInlined,
+	"_[0]": FrameInterpreted,
+	"_[k]": FrameKernel,
+}
+
+// SplitFrameType splits the frame type suffix of async-profiler from a
+// frame, e.g. "com/example/App.main_[j]" => ("com/example/App.main",
+// FrameJIT, true). Frames without suffix are returned unchanged.
+func SplitFrameType(frame string) (name string, typ FrameType, ok bool) {
+	if i := len(frame) - len("_[j]"); i > 0 {
+		if typ, ok := frameSuffixes[frame[i:]]; ok {
+			return frame[:i], typ, true
+		}
+	}
+	return frame, "", false
+}
+
+// ClassifyFrame returns the type of a frame: the type of its suffix, else
+// FrameJava for Java methods and FrameNative for other functions.
+func ClassifyFrame(frame string) FrameType {
+	name, _ := SplitFuncAndModule(frame)
+	name, typ, ok := SplitFrameType(name)
+	if ok {
+		return typ
+	}
+	// Frames collected with --lines end with their line
+	if i := strings.LastIndexByte(name, ':'); i > 0 && name[i-1] != ':' {
+		if _, err := strconv.Atoi(name[i+1:]); err == nil {
+			name = name[:i]
+		}
+	}
+	if IsJavaMethod(name) {
+		return FrameJava
+	}
+	return FrameNative
+}
+
+// IsJavaMethod reports whether a frame name without suffix nor line is a
+// Java method, named <package>/<class>.<method>: native functions have no
+// package, C++ ones have :: separators.
+func IsJavaMethod(name string) bool {
+	return strings.Contains(name, "/") && !strings.ContainsAny(name, ": ")
+}
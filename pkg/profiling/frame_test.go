@@ -0,0 +1,53 @@
+package profiling
+
+import "testing"
+
+func TestSplitFrameType(t *testing.T) {
+	tests := []struct {
+		frame    string
+		wantName string
+		wantType FrameType
+		wantOK   bool
+	}{
+		{"com/example/App.main_[j]", "com/example/App.main", FrameJIT, true},
+		{"com/example/App.main:42_[i]", "com/example/App.main:42", FrameInlined, true},
+		{"java/lang/String.hashCode_[0]", "java/lang/String.hashCode", FrameInterpreted, true},
+		{"java/util/HashMap.get_[1]", "java/util/HashMap.get", FrameC1, true},
+		{"do_syscall_64_[k]", "do_syscall_64", FrameKernel, true},
+		{"malloc", "malloc", "", false},
+		{"_[j]", "_[j]", "", false},
+		{"", "", "", false},
+	}
+
+	for _, tt := range tests {
+		name, typ, ok := SplitFrameType(tt.frame)
+		if name != tt.wantName || typ != tt.wantType || ok != tt.wantOK {
+			t.Errorf("SplitFrameType(%q) = (%q, %q, %v), want (%q, %q, %v)",
+				tt.frame, name, typ, ok, tt.wantName, tt.wantType, tt.wantOK)
+		}
+	}
+}
+
+func TestClassifyFrame(t *testing.T) {
+	tests := []struct {
+		frame string
+		want  FrameType
+	}{
+		{"com/example/App.main_[j]", FrameJIT},
+		{"com/example/App.main_[i]", FrameInlined},
+		{"com/example/App.main_[0]", FrameInterpreted},
+		{"ksys_read_[k]", FrameKernel},
+		{"com/example/App.main", FrameJava},
+		{"com/example/App.main:42", FrameJava},
+		{"Interpreter", FrameNative},
+		{"JavaThread::run", FrameNative},
+		{"malloc(/usr/lib64/libc.so.6)", FrameNative},
+		{"[unknown]", FrameNative},
+	}
+
+	for _, tt := range tests {
+		if got := ClassifyFrame(tt.frame); got != tt.want {
+			t.Errorf("ClassifyFrame(%q) = %q, want %q", tt.frame, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,112 @@
+package profiling
+
+import "strings"
+
+// Thread states of the samples of wall-clock profiles.
+const (
+	StateRunning  = "running"
+	StateSleeping = "sleeping"
+	StateWaiting  = "waiting"
+	StateBlocked  = "blocked"
+	StateIO       = "io"
+)
+
+// stateFrames maps the frames at which threads leave the CPU to the state
+// they are in. Java frames are keyed by their class and method names.
+var stateFrames = map[string]string{
+	// Sleeping
+	"Thread.sleep":       StateSleeping,
+	"Thread.sleep0":      StateSleeping,
+	"Thread.sleepNanos0": StateSleeping,
+	"JVM_Sleep":          StateSleeping,
+	"nanosleep":          StateSleeping,
+	"clock_nanosleep":    StateSleeping,
+	"usleep":             StateSleeping,
+
+	// Blocked on monitors and mutexes
+	"ObjectMonitor::enter":      StateBlocked,
+	"ObjectMonitor::EnterI":     StateBlocked,
+	"ObjectSynchronizer::enter": StateBlocked,
+	"pthread_mutex_lock":        StateBlocked,
+	"__lll_lock_wait":           StateBlocked,
+
+	// Waiting for other threads
+	"Unsafe.park":           StateWaiting,
+	"LockSupport.park":      StateWaiting,
+	"LockSupport.parkNanos": StateWaiting,
+	"Object.wait":           StateWaiting,
+	"Object.wait0":          StateWaiting,
+	"ObjectMonitor::wait":   StateWaiting,
+	"JVM_MonitorWait":       StateWaiting,
+
+	// Waiting for I/O
+	"EPoll.wait":                    StateIO,
+	"EPollArrayWrapper.epollWait":   StateIO,
+	"Net.poll":                      StateIO,
+	"Net.accept":                    StateIO,
+	"SocketDispatcher.read0":        StateIO,
+	"SocketInputStream.socketRead0": StateIO,
+	"FileDispatcherImpl.read0":      StateIO,
+	"FileInputStream.readBytes":     StateIO,
+	"epoll_wait":                    StateIO,
+	"epoll_pwait":                   StateIO,
+	"poll":                          StateIO,
+	"ppoll":                         StateIO,
+	"select":                        StateIO,
+	"accept":                        StateIO,
+	"accept4":                       StateIO,
+	"read":                          StateIO,
+	"recv":                          StateIO,
+	"recvfrom":                      StateIO,
+	"recvmsg":                       StateIO,
+}
+
+// weakStateFrames are the low-level primitives of the frames of stateFrames,
+// e.g. of Thread.sleep in some JDKs. They decide the state of a sample only
+// if no frame of stateFrames does.
+var weakStateFrames = map[string]string{
+	"pthread_cond_wait":            StateWaiting,
+	"pthread_cond_timedwait":       StateWaiting,
+	"__futex_abstimed_wait_common": StateWaiting,
+	"futex_wait":                   StateWaiting,
+}
+
+// ThreadState returns the state of a thread from a sample of its call
+// stack, root first: the state of the frame closest to the leaf at which
+// threads leave the CPU, e.g. StateSleeping for Thread.sleep. Only the
+// native frames below the leaf-most Java frame and that Java frame are
+// considered. It returns StateRunning for stacks of threads on the CPU.
+func ThreadState(stack []string) string {
+	weak := ""
+	for i := len(stack) - 1; i >= 0; i-- {
+		name, java := stateFrameName(stack[i])
+		if state, ok := stateFrames[name]; ok {
+			return state
+		}
+		if state, ok := weakStateFrames[name]; ok && weak == "" {
+			weak = state
+		}
+		if java {
+			break
+		}
+	}
+	if weak != "" {
+		return weak
+	}
+	return StateRunning
+}
+
+// stateFrameName returns the key of a frame in stateFrames, and whether it
+// is a Java frame: the class and method names of Java frames, e.g.
+// "Thread.sleep" for "java/lang/Thread.sleep", the function of others
+// without the frame type suffixes of async-profiler, e.g. "_[k]".
+func stateFrameName(frame string) (string, bool) {
+	name, _ := SplitFuncAndModule(frame)
+	if i := strings.LastIndex(name, "_["); i > 0 && strings.HasSuffix(name, "]") {
+		name = name[:i]
+	}
+	if i := strings.LastIndexByte(name, '/'); i >= 0 {
+		return name[i+1:], true
+	}
+	return name, false
+}
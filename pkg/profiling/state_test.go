@@ -0,0 +1,29 @@
+package profiling
+
+import "testing"
+
+func TestThreadState(t *testing.T) {
+	tests := []struct {
+		name     string
+		stack    []string
+		expected string
+	}{
+		{"empty", nil, StateRunning},
+		{"running java", []string{"java/lang/Thread.run", "com/example/Worker.compute"}, StateRunning},
+		{"sleep", []string{"java/lang/Thread.run", "java/lang/Thread.sleep", "JVM_Sleep", "pthread_cond_timedwait", "futex_wait_queue_me_[k]"}, StateSleeping},
+		{"park", []string{"java/lang/Thread.run", "jdk/internal/misc/Unsafe.park", "Unsafe_Park", "Parker::park", "pthread_cond_wait"}, StateWaiting},
+		{"monitor", []string{"java/lang/Thread.run", "com/example/Cache.get", "ObjectSynchronizer::enter", "ObjectMonitor::enter", "__lll_lock_wait"}, StateBlocked},
+		{"socket read", []string{"java/lang/Thread.run", "java/net/SocketInputStream.socketRead0", "NET_Timeout", "poll"}, StateIO},
+		{"epoll", []string{"sun/nio/ch/EPoll.wait_[j]", "epoll_wait", "do_epoll_wait_[k]"}, StateIO},
+		{"native wait", []string{"start_thread", "GCTaskThread::run", "pthread_cond_wait(libpthread.so)"}, StateWaiting},
+		{"java frame stops the search", []string{"java/lang/Thread.sleep", "com/example/Worker.compute", "memcpy"}, StateRunning},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ThreadState(tt.stack); got != tt.expected {
+				t.Errorf("ThreadState(%q) = %q, want %q", tt.stack, got, tt.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,107 @@
+package profiling
+
+import "strings"
+
+// Reasons threads wait off the CPU, of off-CPU profiles.
+const (
+	WaitLock      = "lock"
+	WaitSleep     = "sleep"
+	WaitPoll      = "poll"
+	WaitNetwork   = "network"
+	WaitDisk      = "disk"
+	WaitPipe      = "pipe"
+	WaitChild     = "child"
+	WaitPageFault = "page_fault"
+	WaitPreempted = "preempted"
+	WaitOther     = "other"
+)
+
+// waitFrames maps the kernel functions threads block in to the reason they
+// wait for.
+var waitFrames = map[string]string{
+	// Futexes, mutexes and semaphores
+	"futex_wait_queue_me":       WaitLock,
+	"futex_wait_queue":          WaitLock,
+	"futex_wait":                WaitLock,
+	"do_futex":                  WaitLock,
+	"__mutex_lock":              WaitLock,
+	"rwsem_down_read_slowpath":  WaitLock,
+	"rwsem_down_write_slowpath": WaitLock,
+	"__down_common":             WaitLock,
+
+	// Sleeps
+	"do_nanosleep":      WaitSleep,
+	"hrtimer_nanosleep": WaitSleep,
+
+	// Waits for file descriptors
+	"ep_poll":       WaitPoll,
+	"do_epoll_wait": WaitPoll,
+	"do_sys_poll":   WaitPoll,
+	"do_select":     WaitPoll,
+
+	// Sockets
+	"sk_wait_data":             WaitNetwork,
+	"sk_stream_wait_memory":    WaitNetwork,
+	"sk_stream_wait_connect":   WaitNetwork,
+	"inet_csk_accept":          WaitNetwork,
+	"tcp_recvmsg":              WaitNetwork,
+	"unix_stream_read_generic": WaitNetwork,
+
+	// Block devices and page cache
+	"io_schedule":           WaitDisk,
+	"io_schedule_timeout":   WaitDisk,
+	"wait_on_page_bit":      WaitDisk,
+	"folio_wait_bit_common": WaitDisk,
+	"jbd2_log_wait_commit":  WaitDisk,
+
+	// Pipes
+	"pipe_read":  WaitPipe,
+	"pipe_write": WaitPipe,
+	"pipe_wait":  WaitPipe,
+
+	// Child processes
+	"do_wait":      WaitChild,
+	"kernel_wait4": WaitChild,
+
+	// Page faults, e.g. of swapped out pages
+	"handle_mm_fault": WaitPageFault,
+	"exc_page_fault":  WaitPageFault,
+	"do_page_fault":   WaitPageFault,
+
+	// Involuntary context switches
+	"preempt_schedule":          WaitPreempted,
+	"preempt_schedule_common":   WaitPreempted,
+	"preempt_schedule_irq":      WaitPreempted,
+	"_cond_resched":             WaitPreempted,
+	"__cond_resched":            WaitPreempted,
+	"exit_to_user_mode_loop":    WaitPreempted,
+	"exit_to_user_mode_prepare": WaitPreempted,
+	"prepare_exit_to_usermode":  WaitPreempted,
+}
+
+// WaitReason returns the reason a thread waits off the CPU from a sample of
+// its call stack, root first: the reason of the kernel function closest to
+// the leaf which blocks, e.g. WaitLock for futex_wait_queue_me. It returns
+// WaitOther for stacks without such functions.
+func WaitReason(stack []string) string {
+	for i := len(stack) - 1; i >= 0; i-- {
+		if reason, ok := waitFrames[kernelFrameName(stack[i])]; ok {
+			return reason
+		}
+	}
+	return WaitOther
+}
+
+// kernelFrameName returns the key of a frame in waitFrames: its function
+// without module, frame type suffix, e.g. "_[k]", or compiler suffixes,
+// e.g. ".isra.0".
+func kernelFrameName(frame string) string {
+	name, _ := SplitFuncAndModule(frame)
+	if i := strings.LastIndex(name, "_["); i > 0 && strings.HasSuffix(name, "]") {
+		name = name[:i]
+	}
+	if i := strings.IndexByte(name, '.'); i > 0 {
+		name = name[:i]
+	}
+	return name
+}
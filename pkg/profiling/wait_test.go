@@ -0,0 +1,29 @@
+package profiling
+
+import "testing"
+
+func TestWaitReason(t *testing.T) {
+	tests := []struct {
+		name     string
+		stack    []string
+		expected string
+	}{
+		{"empty", nil, WaitOther},
+		{"user stack only", []string{"main", "pthread_cond_wait"}, WaitOther},
+		{"futex", []string{"java", "pthread_cond_wait", "entry_SYSCALL_64_after_hwframe", "do_futex", "futex_wait", "futex_wait_queue_me", "schedule", "__schedule", "finish_task_switch"}, WaitLock},
+		{"nanosleep", []string{"sleep", "__x64_sys_nanosleep", "hrtimer_nanosleep", "do_nanosleep", "schedule"}, WaitSleep},
+		{"epoll", []string{"epoll_wait", "do_epoll_wait", "ep_poll.isra.0", "schedule_hrtimeout_range"}, WaitPoll},
+		{"socket", []string{"recv", "-", "tcp_recvmsg", "sk_wait_data", "wait_woken", "schedule_timeout"}, WaitNetwork},
+		{"disk", []string{"read", "filemap_read", "folio_wait_bit_common", "io_schedule", "schedule"}, WaitDisk},
+		{"async-profiler kernel frames", []string{"java/io/FileInputStream.readBytes", "pipe_read_[k]", "schedule_[k]"}, WaitPipe},
+		{"preempted", []string{"compute", "asm_sysvec_apic_timer_interrupt", "irqentry_exit", "preempt_schedule_irq", "__schedule"}, WaitPreempted},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := WaitReason(tt.stack); got != tt.expected {
+				t.Errorf("WaitReason(%q) = %q, want %q", tt.stack, got, tt.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,38 @@
+package telemetry
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// InstrumentationName identifies the spans of this module.
+const InstrumentationName = "github.com/perf-analysis"
+
+// Tracer returns the tracer of this module from the global TracerProvider.
+// Spans are no-ops until Init enables tracing.
+func Tracer() trace.Tracer {
+	return otel.Tracer(InstrumentationName)
+}
+
+// StartSpan starts a span as a child of the span in ctx, if any.
+//
+//	ctx, span := telemetry.StartSpan(ctx, "hprof.parse_records")
+//	err := parseRecords(ctx)
+//	telemetry.EndSpan(span, err)
+func StartSpan(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return Tracer().Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// EndSpan ends a span, recording err and marking the span failed if err is
+// not nil.
+func EndSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
@@ -0,0 +1,54 @@
+package telemetry
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestStartSpan(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(trace.NewTracerProvider(trace.WithSpanProcessor(recorder)))
+	defer otel.SetTracerProvider(previous)
+
+	ctx, parent := StartSpan(context.Background(), "parent", attribute.String("task.id", "t1"))
+	_, child := StartSpan(ctx, "child")
+	EndSpan(child, errors.New("boom"))
+	EndSpan(parent, nil)
+
+	spans := recorder.Ended()
+	if len(spans) != 2 {
+		t.Fatalf("Expected 2 spans, got %d", len(spans))
+	}
+
+	childSpan, parentSpan := spans[0], spans[1]
+	if childSpan.Name() != "child" || parentSpan.Name() != "parent" {
+		t.Fatalf("Unexpected span names %q, %q", childSpan.Name(), parentSpan.Name())
+	}
+	if childSpan.Parent().SpanID() != parentSpan.SpanContext().SpanID() {
+		t.Error("Expected child span to be a child of parent span")
+	}
+	if childSpan.InstrumentationScope().Name != InstrumentationName {
+		t.Errorf("Expected instrumentation scope %q, got %q", InstrumentationName, childSpan.InstrumentationScope().Name)
+	}
+
+	if childSpan.Status().Code != codes.Error || childSpan.Status().Description != "boom" {
+		t.Errorf("Expected error status, got %+v", childSpan.Status())
+	}
+	if len(childSpan.Events()) != 1 || childSpan.Events()[0].Name != "exception" {
+		t.Errorf("Expected the error to be recorded, got events %+v", childSpan.Events())
+	}
+	if parentSpan.Status().Code != codes.Unset {
+		t.Errorf("Expected unset status, got %+v", parentSpan.Status())
+	}
+	if attrs := parentSpan.Attributes(); len(attrs) != 1 || attrs[0] != attribute.String("task.id", "t1") {
+		t.Errorf("Unexpected attributes %v", attrs)
+	}
+}
@@ -0,0 +1,146 @@
+package utils
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// LogLevels holds the levels of a logger and of the loggers derived from it:
+// a default level, and overrides for scopes, e.g. debug logs of the parser
+// only. It is safe for concurrent use, so levels can change at runtime.
+type LogLevels struct {
+	mu     sync.RWMutex
+	level  LogLevel
+	scopes map[string]LogLevel
+}
+
+// NewLogLevels creates levels with the given default level.
+func NewLogLevels(level LogLevel) *LogLevels {
+	return &LogLevels{level: level, scopes: make(map[string]LogLevel)}
+}
+
+// Level returns the level of scope: its override, or the default level.
+func (l *LogLevels) Level(scope string) LogLevel {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	if level, ok := l.scopes[scope]; ok && scope != "" {
+		return level
+	}
+	return l.level
+}
+
+// SetLevel sets the default level.
+func (l *LogLevels) SetLevel(level LogLevel) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.level = level
+}
+
+// SetScopeLevel overrides the level of a scope.
+func (l *LogLevels) SetScopeLevel(scope string, level LogLevel) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.scopes[scope] = level
+}
+
+// ResetScopeLevel removes the override of a scope, which gets the default
+// level again.
+func (l *LogLevels) ResetScopeLevel(scope string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	delete(l.scopes, scope)
+}
+
+// logLevelsState is the JSON representation of LogLevels.
+type logLevelsState struct {
+	Level  string            `json:"level"`
+	Scopes map[string]string `json:"scopes"`
+}
+
+func (l *LogLevels) state() logLevelsState {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	state := logLevelsState{Level: strings.ToLower(l.level.String()), Scopes: make(map[string]string, len(l.scopes))}
+	for scope, level := range l.scopes {
+		state.Scopes[scope] = strings.ToLower(level.String())
+	}
+	return state
+}
+
+// logLevelUpdate is the body of a log level change.
+type logLevelUpdate struct {
+	// Scope to change; empty for the default level
+	Scope string `json:"scope"`
+	// Level to set; empty to reset the level of Scope
+	Level string `json:"level"`
+}
+
+// apply changes the levels as requested.
+func (l *LogLevels) apply(update logLevelUpdate) error {
+	if update.Level == "" {
+		if update.Scope == "" {
+			return fmt.Errorf("missing level")
+		}
+		l.ResetScopeLevel(update.Scope)
+		return nil
+	}
+	level, ok := lookupLogLevel(update.Level)
+	if !ok {
+		return fmt.Errorf("unknown log level: %q (valid: debug, info, warn, error)", update.Level)
+	}
+	if update.Scope == "" {
+		l.SetLevel(level)
+	} else {
+		l.SetScopeLevel(update.Scope, level)
+	}
+	return nil
+}
+
+// lookupLogLevel parses a level name, unlike ParseLogLevel reporting
+// unknown names.
+func lookupLogLevel(name string) (LogLevel, bool) {
+	switch strings.ToLower(name) {
+	case "debug":
+		return LevelDebug, true
+	case "info":
+		return LevelInfo, true
+	case "warn", "warning":
+		return LevelWarn, true
+	case "error":
+		return LevelError, true
+	default:
+		return 0, false
+	}
+}
+
+// LogLevelHandler serves the levels for admin endpoints: GET returns them,
+// PUT or POST changes one with a body like {"scope": "parser", "level":
+// "debug"}. Without scope the default level changes; without level the
+// scope gets the default level again. Both return the resulting levels.
+func LogLevelHandler(levels *LogLevels) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+		case http.MethodPut, http.MethodPost:
+			var update logLevelUpdate
+			if err := json.NewDecoder(r.Body).Decode(&update); err != nil {
+				http.Error(w, "invalid request body: "+err.Error(), http.StatusBadRequest)
+				return
+			}
+			if err := levels.apply(update); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+		default:
+			w.Header().Set("Allow", "GET, PUT, POST")
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(levels.state())
+	})
+}
@@ -0,0 +1,87 @@
+package utils
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLogLevels(t *testing.T) {
+	levels := NewLogLevels(LevelInfo)
+	assert.Equal(t, LevelInfo, levels.Level(""))
+	assert.Equal(t, LevelInfo, levels.Level(ScopeParser))
+
+	levels.SetScopeLevel(ScopeParser, LevelDebug)
+	levels.SetLevel(LevelWarn)
+	assert.Equal(t, LevelWarn, levels.Level(""))
+	assert.Equal(t, LevelDebug, levels.Level(ScopeParser))
+	assert.Equal(t, LevelWarn, levels.Level(ScopeWebUI))
+
+	levels.ResetScopeLevel(ScopeParser)
+	assert.Equal(t, LevelWarn, levels.Level(ScopeParser))
+}
+
+func serveLogLevels(t *testing.T, levels *LogLevels, method, body string) (*httptest.ResponseRecorder, logLevelsState) {
+	t.Helper()
+	req := httptest.NewRequest(method, "/admin/log-level", strings.NewReader(body))
+	rec := httptest.NewRecorder()
+	LogLevelHandler(levels).ServeHTTP(rec, req)
+
+	var state logLevelsState
+	if rec.Code == http.StatusOK {
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &state))
+	}
+	return rec, state
+}
+
+func TestLogLevelHandler(t *testing.T) {
+	levels := NewLogLevels(LevelInfo)
+
+	rec, state := serveLogLevels(t, levels, http.MethodGet, "")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "info", state.Level)
+	assert.Empty(t, state.Scopes)
+
+	rec, state = serveLogLevels(t, levels, http.MethodPut, `{"scope": "dominator", "level": "DEBUG"}`)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, map[string]string{"dominator": "debug"}, state.Scopes)
+	assert.Equal(t, LevelDebug, levels.Level(ScopeDominator))
+
+	rec, state = serveLogLevels(t, levels, http.MethodPost, `{"level": "warn"}`)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "warn", state.Level)
+	assert.Equal(t, LevelWarn, levels.Level(""))
+
+	// Without level, the scope follows the default level again
+	rec, state = serveLogLevels(t, levels, http.MethodPut, `{"scope": "dominator"}`)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Empty(t, state.Scopes)
+}
+
+func TestLogLevelHandler_Errors(t *testing.T) {
+	levels := NewLogLevels(LevelInfo)
+
+	tests := []struct {
+		name   string
+		method string
+		body   string
+		code   int
+	}{
+		{"unknown level", http.MethodPut, `{"level": "verbose"}`, http.StatusBadRequest},
+		{"missing level", http.MethodPut, `{}`, http.StatusBadRequest},
+		{"invalid body", http.MethodPut, `level=debug`, http.StatusBadRequest},
+		{"method", http.MethodDelete, "", http.StatusMethodNotAllowed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec, _ := serveLogLevels(t, levels, tt.method, tt.body)
+			assert.Equal(t, tt.code, rec.Code)
+			assert.Equal(t, LevelInfo, levels.Level(""))
+		})
+	}
+}
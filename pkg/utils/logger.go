@@ -1,11 +1,14 @@
 package utils
 
 import (
+	"encoding/json"
 	"fmt"
 	"io"
 	"log"
 	"os"
 	"path/filepath"
+	"sort"
+	"strings"
 	"sync"
 	"time"
 )
@@ -50,10 +53,56 @@ type Logger interface {
 	WithFields(fields map[string]interface{}) Logger
 }
 
+// LogFormat is the output format of a DefaultLogger.
+type LogFormat string
+
+const (
+	// LogFormatText writes lines like "[time] [INFO] [scope] key=value message".
+	LogFormatText LogFormat = "text"
+	// LogFormatJSON writes one JSON object per line, for log collectors.
+	LogFormatJSON LogFormat = "json"
+)
+
+// ParseLogFormat parses a log format name; the empty string is text.
+func ParseLogFormat(s string) (LogFormat, error) {
+	switch strings.ToLower(s) {
+	case "", "text":
+		return LogFormatText, nil
+	case "json":
+		return LogFormatJSON, nil
+	default:
+		return "", fmt.Errorf("unknown log format: %q (valid: text, json)", s)
+	}
+}
+
+// Log scopes of the subsystems, see Scoped.
+const (
+	ScopeParser    = "parser"
+	ScopeDominator = "dominator"
+	ScopeWebUI     = "webui"
+	ScopeService   = "service"
+)
+
+// Scoped returns a logger for the subsystem scope. Loggers supporting scopes,
+// like DefaultLogger, tag messages with it and apply the level of the scope;
+// others get a scope field. A nil logger stays nil.
+func Scoped(logger Logger, scope string) Logger {
+	switch l := logger.(type) {
+	case nil:
+		return nil
+	case interface{ Scope(string) Logger }:
+		return l.Scope(scope)
+	default:
+		return l.WithField("scope", scope)
+	}
+}
+
 // DefaultLogger is a simple logger implementation.
 type DefaultLogger struct {
-	mu     sync.Mutex
-	level  LogLevel
+	mu     *sync.Mutex
+	levels *LogLevels
+	format LogFormat
+	scope  string
 	output io.Writer
 	fields map[string]interface{}
 	prefix string
@@ -62,7 +111,9 @@ type DefaultLogger struct {
 // NewDefaultLogger creates a new DefaultLogger.
 func NewDefaultLogger(level LogLevel, output io.Writer) *DefaultLogger {
 	return &DefaultLogger{
-		level:  level,
+		mu:     &sync.Mutex{},
+		levels: NewLogLevels(level),
+		format: LogFormatText,
 		output: output,
 		fields: make(map[string]interface{}),
 	}
@@ -83,11 +134,24 @@ func NewFileLogger(level LogLevel, logPath string) (*DefaultLogger, error) {
 	return NewDefaultLogger(level, file), nil
 }
 
-// SetLevel sets the log level.
+// SetLevel sets the log level. Loggers derived with WithField, Scope, etc.
+// share the levels, so this applies to them too.
 func (l *DefaultLogger) SetLevel(level LogLevel) {
+	l.levels.SetLevel(level)
+}
+
+// Levels returns the levels shared by the logger and the loggers derived
+// from it, e.g. to change them at runtime.
+func (l *DefaultLogger) Levels() *LogLevels {
+	return l.levels
+}
+
+// SetFormat sets the output format of the logger and of the loggers derived
+// from it afterwards.
+func (l *DefaultLogger) SetFormat(format LogFormat) {
 	l.mu.Lock()
 	defer l.mu.Unlock()
-	l.level = level
+	l.format = format
 }
 
 // Debug logs a debug message.
@@ -112,72 +176,125 @@ func (l *DefaultLogger) Error(msg string, args ...interface{}) {
 
 // WithField creates a new logger with the given field.
 func (l *DefaultLogger) WithField(key string, value interface{}) Logger {
-	newLogger := &DefaultLogger{
-		level:  l.level,
-		output: l.output,
-		fields: make(map[string]interface{}),
-		prefix: l.prefix,
-	}
-	for k, v := range l.fields {
-		newLogger.fields[k] = v
-	}
+	newLogger := l.clone(l.output)
 	newLogger.fields[key] = value
 	return newLogger
 }
 
 // WithFields creates a new logger with the given fields.
 func (l *DefaultLogger) WithFields(fields map[string]interface{}) Logger {
+	newLogger := l.clone(l.output)
+	for k, v := range fields {
+		newLogger.fields[k] = v
+	}
+	return newLogger
+}
+
+// Scope creates a new logger for a subsystem, see Scoped.
+func (l *DefaultLogger) Scope(scope string) Logger {
+	newLogger := l.clone(l.output)
+	newLogger.scope = scope
+	return newLogger
+}
+
+// Tee creates a new logger that also writes to w, e.g. a per-task log file.
+func (l *DefaultLogger) Tee(w io.Writer) *DefaultLogger {
+	output := w
+	if l.output != nil {
+		output = io.MultiWriter(l.output, w)
+	}
+	return l.clone(output)
+}
+
+// clone copies the logger with another output. The copy shares the levels
+// and the lock serializing writes.
+func (l *DefaultLogger) clone(output io.Writer) *DefaultLogger {
+	l.mu.Lock()
+	defer l.mu.Unlock()
 	newLogger := &DefaultLogger{
-		level:  l.level,
-		output: l.output,
-		fields: make(map[string]interface{}),
+		mu:     l.mu,
+		levels: l.levels,
+		format: l.format,
+		scope:  l.scope,
+		output: output,
+		fields: make(map[string]interface{}, len(l.fields)+1),
 		prefix: l.prefix,
 	}
 	for k, v := range l.fields {
 		newLogger.fields[k] = v
 	}
-	for k, v := range fields {
-		newLogger.fields[k] = v
-	}
 	return newLogger
 }
 
 func (l *DefaultLogger) log(level LogLevel, msg string, args ...interface{}) {
-	if level < l.level {
+	if l.output == nil || level < l.levels.Level(l.scope) {
 		return
 	}
 
 	l.mu.Lock()
 	defer l.mu.Unlock()
 
-	timestamp := time.Now().Format("2006-01-02 15:04:05.000")
+	now := time.Now()
 	formattedMsg := fmt.Sprintf(msg, args...)
 
-	// Build field string
-	fieldStr := ""
-	for k, v := range l.fields {
-		fieldStr += fmt.Sprintf(" %s=%v", k, v)
+	var logLine []byte
+	if l.format == LogFormatJSON {
+		entry := make(map[string]interface{}, len(l.fields)+4)
+		for k, v := range l.fields {
+			entry[k] = jsonLogValue(v)
+		}
+		entry["time"] = now.Format(time.RFC3339Nano)
+		entry["level"] = strings.ToLower(level.String())
+		entry["msg"] = formattedMsg
+		if l.scope != "" {
+			entry["scope"] = l.scope
+		}
+		data, err := json.Marshal(entry)
+		if err != nil {
+			data, _ = json.Marshal(map[string]string{"level": "error", "msg": "unencodable log entry: " + err.Error()})
+		}
+		logLine = append(data, '\n')
+	} else {
+		var sb strings.Builder
+		fmt.Fprintf(&sb, "[%s] [%s]", now.Format("2006-01-02 15:04:05.000"), level.String())
+		if l.scope != "" {
+			fmt.Fprintf(&sb, " [%s]", l.scope)
+		}
+		// Sorted for a stable output
+		keys := make([]string, 0, len(l.fields))
+		for k := range l.fields {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			fmt.Fprintf(&sb, " %s=%v", k, l.fields[k])
+		}
+		fmt.Fprintf(&sb, " %s\n", formattedMsg)
+		logLine = []byte(sb.String())
 	}
 
-	logLine := fmt.Sprintf("[%s] [%s]%s %s\n", timestamp, level.String(), fieldStr, formattedMsg)
+	_, _ = l.output.Write(logLine)
+}
 
-	_, _ = l.output.Write([]byte(logLine))
+// jsonLogValue returns a field value as it is encoded in JSON logs: errors
+// and other values without exported fields are logged as text.
+func jsonLogValue(v interface{}) interface{} {
+	switch v := v.(type) {
+	case error:
+		return v.Error()
+	case fmt.Stringer:
+		return v.String()
+	default:
+		return v
+	}
 }
 
-// ParseLogLevel parses a string to LogLevel.
+// ParseLogLevel parses a string to LogLevel, defaulting to LevelInfo.
 func ParseLogLevel(level string) LogLevel {
-	switch level {
-	case "debug", "DEBUG":
-		return LevelDebug
-	case "info", "INFO":
-		return LevelInfo
-	case "warn", "WARN", "warning", "WARNING":
-		return LevelWarn
-	case "error", "ERROR":
-		return LevelError
-	default:
-		return LevelInfo
+	if l, ok := lookupLogLevel(level); ok {
+		return l
 	}
+	return LevelInfo
 }
 
 // Global logger instance
@@ -2,6 +2,8 @@ package utils
 
 import (
 	"bytes"
+	"encoding/json"
+	"errors"
 	"strings"
 	"testing"
 
@@ -115,6 +117,22 @@ func TestDefaultLogger_WithFields(t *testing.T) {
 	assert.Contains(t, output, "user=admin")
 }
 
+func TestDefaultLogger_Tee(t *testing.T) {
+	buf := &bytes.Buffer{}
+	taskLog := &bytes.Buffer{}
+	logger := NewDefaultLogger(LevelInfo, buf).WithField("task_id", "123").(*DefaultLogger)
+
+	tee := logger.Tee(taskLog)
+	tee.Debug("filtered")
+	tee.Info("parsing")
+	logger.Info("not in task log")
+
+	assert.Contains(t, buf.String(), "parsing")
+	assert.Contains(t, taskLog.String(), "task_id=123 parsing")
+	assert.NotContains(t, taskLog.String(), "filtered")
+	assert.NotContains(t, taskLog.String(), "not in task log")
+}
+
 func TestDefaultLogger_Formatting(t *testing.T) {
 	buf := &bytes.Buffer{}
 	logger := NewDefaultLogger(LevelInfo, buf)
@@ -208,3 +226,85 @@ func TestDefaultLogger_TimestampFormat(t *testing.T) {
 	// Should start with timestamp in brackets
 	assert.True(t, strings.HasPrefix(lines[0], "["))
 }
+
+func TestParseLogFormat(t *testing.T) {
+	format, err := ParseLogFormat("")
+	assert.NoError(t, err)
+	assert.Equal(t, LogFormatText, format)
+
+	format, err = ParseLogFormat("JSON")
+	assert.NoError(t, err)
+	assert.Equal(t, LogFormatJSON, format)
+
+	_, err = ParseLogFormat("xml")
+	assert.Error(t, err)
+}
+
+func TestDefaultLogger_JSONFormat(t *testing.T) {
+	buf := &bytes.Buffer{}
+	logger := NewDefaultLogger(LevelInfo, buf)
+	logger.SetFormat(LogFormatJSON)
+
+	logger.Scope(ScopeParser).WithField("task_id", "123").WithField("err", errors.New("boom")).Warn("count: %d", 42)
+
+	var entry map[string]interface{}
+	assert.NoError(t, json.Unmarshal(buf.Bytes(), &entry))
+	assert.Equal(t, "warn", entry["level"])
+	assert.Equal(t, "parser", entry["scope"])
+	assert.Equal(t, "count: 42", entry["msg"])
+	assert.Equal(t, "123", entry["task_id"])
+	assert.Equal(t, "boom", entry["err"])
+	assert.NotEmpty(t, entry["time"])
+}
+
+func TestDefaultLogger_Scope(t *testing.T) {
+	buf := &bytes.Buffer{}
+	logger := NewDefaultLogger(LevelInfo, buf)
+	parser := Scoped(logger, ScopeParser)
+	webui := Scoped(logger, ScopeWebUI)
+
+	parser.Info("parsing")
+	assert.Contains(t, buf.String(), "[INFO] [parser] parsing")
+
+	// Scope levels apply to the loggers already derived
+	logger.Levels().SetScopeLevel(ScopeParser, LevelDebug)
+	parser.Debug("parser details")
+	webui.Debug("webui details")
+	assert.Contains(t, buf.String(), "parser details")
+	assert.NotContains(t, buf.String(), "webui details")
+
+	logger.Levels().ResetScopeLevel(ScopeParser)
+	parser.Debug("hidden again")
+	assert.NotContains(t, buf.String(), "hidden again")
+
+	// So does the default level
+	logger.SetLevel(LevelError)
+	webui.Warn("quiet")
+	assert.NotContains(t, buf.String(), "quiet")
+}
+
+func TestDefaultLogger_SortedFields(t *testing.T) {
+	buf := &bytes.Buffer{}
+	logger := NewDefaultLogger(LevelInfo, buf)
+
+	logger.WithFields(map[string]interface{}{"b": 2, "c": 3, "a": 1}).Info("sorted")
+
+	assert.Contains(t, buf.String(), " a=1 b=2 c=3 sorted")
+}
+
+func TestScoped_OtherLoggers(t *testing.T) {
+	assert.Nil(t, Scoped(nil, ScopeParser))
+
+	null := &NullLogger{}
+	assert.Equal(t, null, Scoped(null, ScopeParser))
+
+	buf := &bytes.Buffer{}
+	std := Scoped(NewStdLogger(LevelInfo, buf), ScopeService)
+	std.Info("started")
+	assert.Contains(t, buf.String(), "started")
+}
+
+func TestDefaultLogger_NilOutput(t *testing.T) {
+	logger := NewDefaultLogger(LevelInfo, nil)
+	assert.NotPanics(t, func() { logger.Info("discarded") })
+}
@@ -0,0 +1,69 @@
+package utils
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// cgroupUnlimited is the smallest cgroup v1 memory limit meaning no limit:
+// the page-aligned maximum int64.
+const cgroupUnlimited = 1 << 62
+
+// MemoryLimit returns the memory available to the process: the memory limit
+// of its cgroup, e.g. of its container, or the memory of the host when the
+// cgroup is not limited. It returns 0 if neither is known.
+func MemoryLimit() int64 {
+	return memoryLimit("/")
+}
+
+// memoryLimit reads the memory limit from the cgroup and proc files under
+// root.
+func memoryLimit(root string) int64 {
+	total := hostMemory(filepath.Join(root, "proc/meminfo"))
+
+	// cgroup v2, then v1
+	for _, file := range []string{
+		"sys/fs/cgroup/memory.max",
+		"sys/fs/cgroup/memory/memory.limit_in_bytes",
+	} {
+		data, err := os.ReadFile(filepath.Join(root, file))
+		if err != nil {
+			continue
+		}
+		limit, err := strconv.ParseInt(strings.TrimSpace(string(data)), 10, 64)
+		if err != nil || limit <= 0 || limit >= cgroupUnlimited {
+			// "max" in v2: not limited
+			break
+		}
+		if total > 0 && limit > total {
+			break
+		}
+		return limit
+	}
+	return total
+}
+
+// hostMemory reads MemTotal from a meminfo file, 0 if unknown.
+func hostMemory(path string) int64 {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) >= 2 && fields[0] == "MemTotal:" {
+			kb, err := strconv.ParseInt(fields[1], 10, 64)
+			if err != nil {
+				return 0
+			}
+			return kb << 10
+		}
+	}
+	return 0
+}
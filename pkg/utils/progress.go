@@ -0,0 +1,126 @@
+package utils
+
+import (
+	"sync"
+	"time"
+)
+
+// ProgressUpdate is a point-in-time view of a long-running operation.
+type ProgressUpdate struct {
+	// Phase is the name of the current phase
+	Phase   string
+	Current int
+	Total   int
+	// Percent is the completion of the current phase (0-100)
+	Percent float64
+	// Elapsed is the time since the reporter was created
+	Elapsed time.Duration
+	// ETA estimates the remaining time of the current phase; 0 when unknown
+	ETA time.Duration
+	// Done is set by the final update; Err is the operation's error, if any
+	Done bool
+	Err  error
+}
+
+// ProgressReporter turns (phase, current, total) progress callbacks into
+// ProgressUpdates with elapsed time and a linear ETA, throttled to at most one
+// update per interval within a phase. Phase changes and completion are always
+// reported. It is safe for concurrent use.
+type ProgressReporter struct {
+	mu         sync.Mutex
+	sink       func(ProgressUpdate)
+	clock      Clock
+	interval   time.Duration
+	start      time.Time
+	phase      string
+	phaseStart time.Time
+	lastEmit   time.Time
+	done       bool
+}
+
+// ProgressReporterOption configures a ProgressReporter.
+type ProgressReporterOption func(*ProgressReporter)
+
+// WithProgressClock sets a custom clock for testability.
+func WithProgressClock(clock Clock) ProgressReporterOption {
+	return func(r *ProgressReporter) {
+		r.clock = clock
+	}
+}
+
+// WithProgressInterval sets the minimum time between updates within a phase.
+func WithProgressInterval(interval time.Duration) ProgressReporterOption {
+	return func(r *ProgressReporter) {
+		r.interval = interval
+	}
+}
+
+// NewProgressReporter creates a reporter that sends updates to sink.
+func NewProgressReporter(sink func(ProgressUpdate), opts ...ProgressReporterOption) *ProgressReporter {
+	r := &ProgressReporter{
+		sink:     sink,
+		clock:    NewRealClock(),
+		interval: 250 * time.Millisecond,
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	r.start = r.clock.Now()
+	return r
+}
+
+// Report records progress of a phase. It matches the signature of
+// hprof.ParallelConfig.ProgressCallback.
+func (r *ProgressReporter) Report(phase string, current, total int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.done {
+		return
+	}
+
+	now := r.clock.Now()
+	if phase != r.phase {
+		r.phase = phase
+		r.phaseStart = now
+	} else if current < total && now.Sub(r.lastEmit) < r.interval {
+		return
+	}
+	r.lastEmit = now
+
+	update := ProgressUpdate{
+		Phase:   phase,
+		Current: current,
+		Total:   total,
+		Elapsed: now.Sub(r.start),
+	}
+	if total > 0 {
+		update.Percent = float64(current) * 100 / float64(total)
+		if current > 0 && current < total {
+			phaseElapsed := now.Sub(r.phaseStart)
+			update.ETA = time.Duration(float64(phaseElapsed) * float64(total-current) / float64(current))
+		}
+	}
+	r.sink(update)
+}
+
+// Done reports completion, successful when err is nil. Later calls to Report
+// and Done are ignored.
+func (r *ProgressReporter) Done(err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.done {
+		return
+	}
+	r.done = true
+
+	update := ProgressUpdate{
+		Phase:   r.phase,
+		Elapsed: r.clock.Since(r.start),
+		Done:    true,
+		Err:     err,
+	}
+	if err == nil {
+		update.Percent = 100
+	}
+	r.sink(update)
+}
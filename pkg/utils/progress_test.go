@@ -0,0 +1,61 @@
+package utils
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProgressReporter_PercentAndETA(t *testing.T) {
+	clock := NewMockClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	var updates []ProgressUpdate
+	r := NewProgressReporter(func(u ProgressUpdate) { updates = append(updates, u) }, WithProgressClock(clock))
+
+	r.Report("parsing", 0, 4)
+	clock.Advance(10 * time.Second)
+	r.Report("parsing", 1, 4)
+
+	require.Len(t, updates, 2)
+	assert.Equal(t, "parsing", updates[1].Phase)
+	assert.Equal(t, 25.0, updates[1].Percent)
+	assert.Equal(t, 10*time.Second, updates[1].Elapsed)
+	assert.Equal(t, 30*time.Second, updates[1].ETA)
+}
+
+func TestProgressReporter_Throttle(t *testing.T) {
+	clock := NewMockClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	var updates []ProgressUpdate
+	r := NewProgressReporter(func(u ProgressUpdate) { updates = append(updates, u) },
+		WithProgressClock(clock), WithProgressInterval(time.Second))
+
+	r.Report("parsing", 1, 10)
+	r.Report("parsing", 2, 10) // throttled
+	r.Report("parsing", 10, 10)
+	r.Report("dominating", 0, 1) // phase change
+	clock.Advance(time.Second)
+	r.Report("dominating", 0, 1)
+
+	require.Len(t, updates, 4)
+	assert.Equal(t, 100.0, updates[1].Percent)
+	assert.Equal(t, "dominating", updates[2].Phase)
+	assert.Zero(t, updates[3].ETA)
+}
+
+func TestProgressReporter_Done(t *testing.T) {
+	var updates []ProgressUpdate
+	r := NewProgressReporter(func(u ProgressUpdate) { updates = append(updates, u) })
+
+	r.Report("parsing", 1, 2)
+	failure := errors.New("boom")
+	r.Done(failure)
+	r.Done(nil)
+	r.Report("parsing", 2, 2)
+
+	require.Len(t, updates, 2)
+	assert.True(t, updates[1].Done)
+	assert.Equal(t, failure, updates[1].Err)
+	assert.Equal(t, "parsing", updates[1].Phase)
+}
@@ -0,0 +1,39 @@
+package utils
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseByteSize(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected int64
+	}{
+		{"4096", 4096},
+		{"100B", 100},
+		{"1KB", 1024},
+		{"1k", 1024},
+		{"500MB", 500 << 20},
+		{"500 mb", 500 << 20},
+		{"1.5GiB", 3 << 29},
+		{"2G", 2 << 30},
+		{"1TB", 1 << 40},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			size, err := ParseByteSize(tt.input)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, size)
+		})
+	}
+}
+
+func TestParseByteSize_Invalid(t *testing.T) {
+	for _, input := range []string{"", "MB", "ten MB", "-1KB", "5XB"} {
+		_, err := ParseByteSize(input)
+		assert.Error(t, err, input)
+	}
+}
@@ -0,0 +1,115 @@
+package writer
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// TableFormat is a delimited text format for tabular exports.
+type TableFormat string
+
+const (
+	// TableFormatCSV writes comma-separated values.
+	TableFormatCSV TableFormat = "csv"
+	// TableFormatTSV writes tab-separated values.
+	TableFormatTSV TableFormat = "tsv"
+)
+
+// ParseTableFormat parses a format name such as "csv" or "tsv".
+func ParseTableFormat(s string) (TableFormat, error) {
+	switch TableFormat(strings.ToLower(strings.TrimSpace(s))) {
+	case TableFormatCSV:
+		return TableFormatCSV, nil
+	case TableFormatTSV:
+		return TableFormatTSV, nil
+	default:
+		return "", fmt.Errorf("unsupported table format: %q", s)
+	}
+}
+
+// Extension returns the file extension for the format, including the dot.
+func (f TableFormat) Extension() string {
+	return "." + string(f)
+}
+
+// ContentType returns the HTTP content type for the format.
+func (f TableFormat) ContentType() string {
+	if f == TableFormatTSV {
+		return "text/tab-separated-values; charset=utf-8"
+	}
+	return "text/csv; charset=utf-8"
+}
+
+// delimiter returns the field delimiter for the format.
+func (f TableFormat) delimiter() rune {
+	if f == TableFormatTSV {
+		return '\t'
+	}
+	return ','
+}
+
+// Table is a header plus rows of string cells, ready for delimited export.
+type Table struct {
+	Header []string
+	Rows   [][]string
+}
+
+// TableWriter writes tables as CSV or TSV.
+type TableWriter struct {
+	Format TableFormat
+}
+
+// NewTableWriter creates a table writer for the given format.
+func NewTableWriter(format TableFormat) *TableWriter {
+	return &TableWriter{Format: format}
+}
+
+// Write writes the table to the writer.
+func (w *TableWriter) Write(table *Table, writer io.Writer) error {
+	cw := csv.NewWriter(writer)
+	cw.Comma = w.Format.delimiter()
+
+	if len(table.Header) > 0 {
+		if err := cw.Write(table.Header); err != nil {
+			return fmt.Errorf("failed to write header: %w", err)
+		}
+	}
+	if err := cw.WriteAll(table.Rows); err != nil {
+		return fmt.Errorf("failed to write rows: %w", err)
+	}
+	return nil
+}
+
+// WriteToFile writes the table to a file.
+func (w *TableWriter) WriteToFile(table *Table, filepath string) error {
+	file, err := os.Create(filepath)
+	if err != nil {
+		return fmt.Errorf("failed to create file: %w", err)
+	}
+	defer file.Close()
+
+	return w.Write(table, file)
+}
+
+// ReadTable reads a delimited table written by TableWriter.
+// The first record is treated as the header.
+func ReadTable(reader io.Reader, format TableFormat) (*Table, error) {
+	cr := csv.NewReader(reader)
+	cr.Comma = format.delimiter()
+	cr.FieldsPerRecord = -1
+
+	records, err := cr.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read table: %w", err)
+	}
+
+	table := &Table{}
+	if len(records) > 0 {
+		table.Header = records[0]
+		table.Rows = records[1:]
+	}
+	return table, nil
+}
@@ -0,0 +1,77 @@
+package writer
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func testTable() *Table {
+	return &Table{
+		Header: []string{"class_name", "instance_count"},
+		Rows: [][]string{
+			{"java.lang.String", "10"},
+			{"com.example.Foo, Bar", "2"},
+		},
+	}
+}
+
+func TestTableWriter_Write(t *testing.T) {
+	t.Run("csv quotes delimiters", func(t *testing.T) {
+		var buf bytes.Buffer
+		if err := NewTableWriter(TableFormatCSV).Write(testTable(), &buf); err != nil {
+			t.Fatalf("Write failed: %v", err)
+		}
+
+		expected := "class_name,instance_count\njava.lang.String,10\n\"com.example.Foo, Bar\",2\n"
+		if buf.String() != expected {
+			t.Errorf("got %q, want %q", buf.String(), expected)
+		}
+	})
+
+	t.Run("tsv", func(t *testing.T) {
+		var buf bytes.Buffer
+		if err := NewTableWriter(TableFormatTSV).Write(testTable(), &buf); err != nil {
+			t.Fatalf("Write failed: %v", err)
+		}
+
+		expected := "class_name\tinstance_count\njava.lang.String\t10\ncom.example.Foo, Bar\t2\n"
+		if buf.String() != expected {
+			t.Errorf("got %q, want %q", buf.String(), expected)
+		}
+	})
+}
+
+func TestTableWriter_RoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "table.csv")
+	if err := NewTableWriter(TableFormatCSV).WriteToFile(testTable(), path); err != nil {
+		t.Fatalf("WriteToFile failed: %v", err)
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	defer file.Close()
+
+	table, err := ReadTable(file, TableFormatCSV)
+	if err != nil {
+		t.Fatalf("ReadTable failed: %v", err)
+	}
+	if len(table.Header) != 2 || len(table.Rows) != 2 {
+		t.Fatalf("unexpected table shape: header=%v rows=%d", table.Header, len(table.Rows))
+	}
+	if table.Rows[1][0] != "com.example.Foo, Bar" {
+		t.Errorf("got %q, want %q", table.Rows[1][0], "com.example.Foo, Bar")
+	}
+}
+
+func TestParseTableFormat(t *testing.T) {
+	if f, err := ParseTableFormat(" TSV "); err != nil || f != TableFormatTSV {
+		t.Errorf("ParseTableFormat(TSV) = %q, %v", f, err)
+	}
+	if _, err := ParseTableFormat("xlsx"); err == nil {
+		t.Error("expected error for unsupported format")
+	}
+}